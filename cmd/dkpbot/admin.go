@@ -0,0 +1,459 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/audit"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/gdkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/ids"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// runAdmin implements `dkpbot admin`, a shell over the database for
+// operators to look up players, adjust DKP, close stuck auctions, and
+// inspect events when Discord is down. Given a command after its flags, it
+// runs that command alone.
+func runAdmin(args []string) error {
+	fs := flag.NewFlagSet("admin", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to configuration file")
+	actor := fs.String("actor", "", "Discord ID of the operator, recorded as the actor of every change (required)")
+	yes := fs.Bool("yes", false, "make changes without asking for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *actor == "" {
+		return errors.New("admin: -actor is required, so that changes can be traced to the operator")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, repos, err := openStore(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+	defer repos.Closer.Close()
+
+	sh, err := newAdminShell(cfg, repos, os.Stdin, os.Stdout)
+	if err != nil {
+		return err
+	}
+	sh.confirm = !*yes
+	ctx = event.WithActor(ctx, *actor)
+
+	if fs.NArg() > 0 {
+		return sh.exec(ctx, strings.Join(fs.Args(), " "))
+	}
+	fmt.Fprintln(sh.out, "Stop the bot before closing or canceling auctions: its leader holds open auctions in memory. Type help for the commands.")
+	return sh.run(ctx)
+}
+
+// adminShell runs the commands of `dkpbot admin`. Changes go through the
+// same managers as the bot's commands, so they are recorded as events
+// with the operator as their actor, and each is preceded by an
+// AdminCommandRun event.
+type adminShell struct {
+	in      *bufio.Scanner
+	out     io.Writer
+	confirm bool
+
+	events     event.Store
+	players    store.PlayerRepository
+	dkp        *dkp.Manager
+	auctions   *auction.Manager
+	projection *auction.Projection
+	audit      *audit.Log
+
+	// recovered reports whether the open auctions were loaded into
+	// auctions, which closing and canceling need.
+	recovered bool
+	user      string
+	host      string
+}
+
+// adminCommand is a command of adminShell.
+type adminCommand struct {
+	usage string
+	help  string
+	run   func(ctx context.Context, sh *adminShell, line string, args []string) error
+}
+
+// adminCommands maps the first word of a line to its command. It is filled
+// in by init, as help refers to it.
+var adminCommands map[string]adminCommand
+
+func init() {
+	adminCommands = map[string]adminCommand{
+		"help":     {usage: "help", help: "list the commands", run: adminHelp},
+		"players":  {usage: "players [name]", help: "list the players, or those whose character name contains name", run: adminPlayers},
+		"player":   {usage: "player <discord-id>", help: "show a player and their latest DKP changes", run: adminPlayer},
+		"award":    {usage: "award <discord-id> <amount> <reason>", help: "award DKP to a player", run: adminAward},
+		"deduct":   {usage: "deduct <discord-id> <amount> <reason>", help: "deduct DKP from a player", run: adminDeduct},
+		"auctions": {usage: "auctions", help: "list the auctions not yet closed or canceled", run: adminAuctions},
+		"close":    {usage: "close <auction-id>", help: "close an auction, awarding it to the highest bid; auctions without bids close without a winner", run: adminClose},
+		"cancel":   {usage: "cancel <auction-id>", help: "cancel an auction without a winner", run: adminCancel},
+		"events":   {usage: "events <aggregate-id>", help: "show the events of a player, auction, or other aggregate", run: adminEvents},
+		"recent":   {usage: "recent [n]", help: "show the latest n events, 20 by default", run: adminRecent},
+	}
+}
+
+func newAdminShell(cfg *config.Config, repos *store.Repositories, in io.Reader, out io.Writer) (*adminShell, error) {
+	logger, tp, clk := cliLogger(), noop.NewTracerProvider(), clock.Real{}
+	gen, err := ids.New(cfg.Database.IDs, clk)
+	if err != nil {
+		return nil, fmt.Errorf("creating ID generator: %w", err)
+	}
+	dkpMgr := dkp.NewManager(repos.Players, repos.Events, logger, tp,
+		dkp.WithCurrencies(repos.Balances, cfg.Currencies))
+	// Without guild settings, auctions closed without bids close without
+	// a winner rather than starting a roll no one could take part in.
+	auctionMgr := auction.NewManager(repos.Events, repos.Players, logger, tp, clk,
+		auction.WithGDKP(gdkp.NewService(repos.Events, cfg.GDKP, logger, tp, clk)),
+		auction.WithLedger(dkpMgr), auction.WithTax(cfg.Tax), auction.WithIDs(gen))
+
+	sh := &adminShell{
+		in:         bufio.NewScanner(in),
+		out:        out,
+		confirm:    true,
+		events:     repos.Events,
+		players:    repos.Players,
+		dkp:        dkpMgr,
+		auctions:   auctionMgr,
+		projection: auction.NewProjection(repos.Events, repos.Auctions, nil, logger, tp),
+		audit:      audit.NewLog(repos.Events, repos.Players, tp),
+		host:       "unknown",
+	}
+	if u, err := user.Current(); err == nil {
+		sh.user = u.Username
+	}
+	if h, err := os.Hostname(); err == nil {
+		sh.host = h
+	}
+	return sh, nil
+}
+
+// run reads commands until the input ends, quit or exit is entered, or
+// ctx is done. Failed commands are reported and do not end the shell.
+func (sh *adminShell) run(ctx context.Context) error {
+	for ctx.Err() == nil {
+		fmt.Fprint(sh.out, "dkpbot> ")
+		if !sh.in.Scan() {
+			fmt.Fprintln(sh.out)
+			return sh.in.Err()
+		}
+		line := strings.TrimSpace(sh.in.Text())
+		switch line {
+		case "":
+			continue
+		case "quit", "exit":
+			return nil
+		}
+		if err := sh.exec(ctx, line); err != nil {
+			fmt.Fprintln(sh.out, "error:", err)
+		}
+	}
+	return nil
+}
+
+// exec runs the command line.
+func (sh *adminShell) exec(ctx context.Context, line string) error {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return nil
+	}
+	cmd, ok := adminCommands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q; type help for the commands", args[0])
+	}
+	return cmd.run(ctx, sh, line, args[1:])
+}
+
+// change asks the operator to confirm the change described by prompt,
+// records the AdminCommandRun event for line, and makes the change with
+// apply. Nothing is recorded or changed if the operator declines.
+func (sh *adminShell) change(ctx context.Context, line, prompt string, apply func(context.Context) error) error {
+	if sh.confirm {
+		fmt.Fprintf(sh.out, "%s [y/N] ", prompt)
+		if !sh.in.Scan() {
+			return errors.New("no confirmation given")
+		}
+		if answer := strings.ToLower(strings.TrimSpace(sh.in.Text())); answer != "y" && answer != "yes" {
+			fmt.Fprintln(sh.out, "Nothing changed.")
+			return nil
+		}
+	}
+	data, err := json.Marshal(event.AdminCommandData{Command: line, User: sh.user, Host: sh.host})
+	if err != nil {
+		return fmt.Errorf("encoding admin command: %w", err)
+	}
+	if err := sh.events.Append(ctx, event.Event{AggregateID: event.AdminAggregateID, Type: event.AdminCommandRun, Data: data}); err != nil {
+		return fmt.Errorf("recording admin command: %w", err)
+	}
+	return apply(ctx)
+}
+
+func adminHelp(_ context.Context, sh *adminShell, _ string, _ []string) error {
+	names := make([]string, 0, len(adminCommands))
+	for name := range adminCommands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	tw := tabwriter.NewWriter(sh.out, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%s\n", adminCommands[name].usage, adminCommands[name].help)
+	}
+	fmt.Fprintln(tw, "quit\tleave the shell")
+	return tw.Flush()
+}
+
+func adminPlayers(ctx context.Context, sh *adminShell, _ string, args []string) error {
+	players, err := sh.dkp.ListPlayers(ctx)
+	if err != nil {
+		return err
+	}
+	filter := strings.ToLower(strings.Join(args, " "))
+	tw := tabwriter.NewWriter(sh.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DISCORD ID\tCHARACTER\tDKP\tARCHIVED")
+	for _, p := range players {
+		if !strings.Contains(strings.ToLower(p.CharacterName), filter) {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%t\n", p.DiscordID, p.CharacterName, p.DKP, p.Archived())
+	}
+	return tw.Flush()
+}
+
+func adminPlayer(ctx context.Context, sh *adminShell, _ string, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + adminCommands["player"].usage)
+	}
+	p, err := sh.dkp.GetPlayer(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(sh.out, "%s (Discord %s, player %s): %d DKP\n", p.CharacterName, p.DiscordID, p.ID, p.DKP)
+	if p.Archived() {
+		fmt.Fprintf(sh.out, "archived since %s\n", p.ArchivedAt.UTC().Format("2006-01-02 15:04"))
+	}
+	changes, err := sh.dkp.History(ctx, p)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(sh.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tCHANGE\tBALANCE\tREASON\tEVENT")
+	for _, c := range changes[max(0, len(changes)-10):] {
+		fmt.Fprintf(tw, "%s\t%+d\t%d\t%s\t%s\n", c.CreatedAt.UTC().Format("2006-01-02 15:04"), c.Amount, c.Balance, c.Reason, c.EventID)
+	}
+	return tw.Flush()
+}
+
+func adminAward(ctx context.Context, sh *adminShell, line string, args []string) error {
+	return adminChangeDKP(ctx, sh, line, args, "award")
+}
+
+func adminDeduct(ctx context.Context, sh *adminShell, line string, args []string) error {
+	return adminChangeDKP(ctx, sh, line, args, "deduct")
+}
+
+func adminChangeDKP(ctx context.Context, sh *adminShell, line string, args []string, name string) error {
+	if len(args) < 3 {
+		return errors.New("usage: " + adminCommands[name].usage)
+	}
+	amount, err := strconv.Atoi(args[1])
+	if err != nil || amount <= 0 {
+		return fmt.Errorf("amount %q is not a positive number", args[1])
+	}
+	reason := strings.Join(args[2:], " ")
+	p, err := sh.dkp.GetPlayer(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	apply, prompt := sh.dkp.AwardDKP, fmt.Sprintf("Award %d DKP to %s (%d DKP) for %q?", amount, p.CharacterName, p.DKP, reason)
+	if name == "deduct" {
+		apply, prompt = sh.dkp.DeductDKP, fmt.Sprintf("Deduct %d DKP from %s (%d DKP) for %q?", amount, p.CharacterName, p.DKP, reason)
+	}
+	return sh.change(ctx, line, prompt, func(ctx context.Context) error {
+		if err := apply(ctx, p.ID, amount, reason); err != nil {
+			return err
+		}
+		p, err := sh.dkp.GetPlayer(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(sh.out, "%s now has %d DKP.\n", p.CharacterName, p.DKP)
+		return nil
+	})
+}
+
+func adminAuctions(ctx context.Context, sh *adminShell, _ string, _ []string) error {
+	states, err := sh.auctions.ListOpenAuctions(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(sh.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tITEM\tSTATUS\tBIDS\tHIGHEST")
+	for _, s := range states {
+		highest := "-"
+		if h := s.HighestBid(); h != nil {
+			highest = fmt.Sprintf("%d %s", h.Amount, s.Currency())
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", s.ID, s.ItemName, s.Status, len(s.Bids), highest)
+	}
+	return tw.Flush()
+}
+
+func adminClose(ctx context.Context, sh *adminShell, line string, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + adminCommands["close"].usage)
+	}
+	a, err := sh.openAuction(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	prompt := fmt.Sprintf("Close auction %s for %s, which has no bids, without a winner?", a.ID, a.ItemName)
+	if bid := a.HighestBid(); bid != nil {
+		prompt = fmt.Sprintf("Close auction %s for %s at its highest bid of %d %s?", a.ID, a.ItemName, bid.Amount, a.State().Currency())
+	}
+	return sh.change(ctx, line, prompt, func(ctx context.Context) error {
+		result, err := sh.auctions.CloseAuction(ctx, a.ID)
+		if err != nil {
+			return err
+		}
+		if result.Message != "" {
+			fmt.Fprintln(sh.out, result.Message)
+		} else {
+			fmt.Fprintln(sh.out, "Closed without a winner.")
+		}
+		synced := []string{a.ID}
+		for _, s := range result.Started {
+			fmt.Fprintf(sh.out, "Queued auction %s for %s started in the freed slot.\n", s.ID, s.ItemName)
+			synced = append(synced, s.ID)
+		}
+		return sh.sync(ctx, synced...)
+	})
+}
+
+func adminCancel(ctx context.Context, sh *adminShell, line string, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + adminCommands["cancel"].usage)
+	}
+	a, err := sh.openAuction(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	prompt := fmt.Sprintf("Cancel auction %s for %s, with %d bids, without a winner?", a.ID, a.ItemName, len(a.State().Bids))
+	return sh.change(ctx, line, prompt, func(ctx context.Context) error {
+		if err := sh.auctions.CancelAuction(ctx, a.ID); err != nil {
+			return err
+		}
+		fmt.Fprintln(sh.out, "Canceled.")
+		return sh.sync(ctx, a.ID)
+	})
+}
+
+// openAuction returns the auction id, loading the open auctions into the
+// auction manager first if they are not.
+func (sh *adminShell) openAuction(ctx context.Context, id string) (*auction.Auction, error) {
+	if !sh.recovered {
+		if _, err := sh.auctions.RecoverOpenAuctions(ctx); err != nil {
+			return nil, fmt.Errorf("loading open auctions: %w", err)
+		}
+		sh.recovered = true
+	}
+	a, err := sh.auctions.ReplayAuction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.Status == "closed" || a.Status == "canceled" {
+		return nil, fmt.Errorf("auction %s is already %s", id, a.Status)
+	}
+	return a, nil
+}
+
+// sync saves the rows of the auctions ids, as the bot's projection would.
+func (sh *adminShell) sync(ctx context.Context, ids ...string) error {
+	for _, id := range ids {
+		if err := sh.projection.Sync(ctx, id); err != nil {
+			return fmt.Errorf("saving auction row %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func adminEvents(ctx context.Context, sh *adminShell, _ string, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + adminCommands["events"].usage)
+	}
+	events, err := sh.events.Load(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("no events for %s", args[0])
+	}
+	names, err := sh.names(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(sh.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tTIME\tTYPE\tSUMMARY")
+	for _, e := range events {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", e.Version, e.CreatedAt.UTC().Format("2006-01-02 15:04:05"), e.Type, audit.Describe(e, names))
+	}
+	return tw.Flush()
+}
+
+func adminRecent(ctx context.Context, sh *adminShell, _ string, args []string) error {
+	n := 20
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n <= 0 {
+			return fmt.Errorf("count %q is not a positive number", args[0])
+		}
+	}
+	entries, err := sh.audit.Query(ctx, event.Query{Limit: n})
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(sh.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tAGGREGATE\tSUMMARY")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Time.UTC().Format("2006-01-02 15:04:05"), e.AggregateID, e.Summary)
+	}
+	return tw.Flush()
+}
+
+// names maps player IDs to character names, for audit.Describe.
+func (sh *adminShell) names(ctx context.Context) (map[string]string, error) {
+	players, err := sh.players.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing players: %w", err)
+	}
+	names := make(map[string]string, len(players))
+	for _, p := range players {
+		names[p.ID] = p.CharacterName
+	}
+	return names, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+// newTestShell returns an admin shell over in-memory repositories holding
+// Gandalf with 100 DKP, which reads the operator's input from input, and
+// the buffer it writes to. Its changes are made by the operator "op".
+func newTestShell(t *testing.T, input string) (*adminShell, *strings.Builder, *store.Repositories, context.Context) {
+	t.Helper()
+	players := storetest.NewPlayers(store.Player{ID: "p1", DiscordID: "d1", CharacterName: "Gandalf", DKP: 100})
+	repos := &store.Repositories{
+		Players:  players,
+		Auctions: storetest.NewAuctions(),
+		Events:   eventtest.NewStore(),
+		Balances: storetest.NewBalances(players),
+	}
+	cfg := &config.Config{Database: config.DatabaseConfig{IDs: config.IDsConfig{Strategy: config.IDStrategyULID}}}
+	out := &strings.Builder{}
+	sh, err := newAdminShell(cfg, repos, strings.NewReader(input), out)
+	if err != nil {
+		t.Fatalf("newAdminShell() error = %v", err)
+	}
+	sh.user, sh.host = "ops", "bastion"
+	return sh, out, repos, event.WithActor(context.Background(), "op")
+}
+
+func TestAdminShell_DeclinedChangeRecordsNothing(t *testing.T) {
+	sh, out, repos, ctx := newTestShell(t, "award d1 10 raid\nn\nquit\n")
+
+	if err := sh.run(ctx); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if !strings.Contains(out.String(), `Award 10 DKP to Gandalf (100 DKP) for "raid"? [y/N] Nothing changed.`) {
+		t.Errorf("output = %q, want the declined confirmation", out)
+	}
+	repos.Players.(*storetest.Players).RequireDKP(t, "d1", 100)
+	repos.Events.(*eventtest.Store).RequireTypes(t)
+}
+
+func TestAdminShell_ChangeDKP(t *testing.T) {
+	sh, out, repos, ctx := newTestShell(t, "award d1 10 raid night\ny\ndeduct d1 25 late\nyes\nquit\n")
+
+	if err := sh.run(ctx); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	for _, want := range []string{"Gandalf now has 110 DKP.", "Gandalf now has 85 DKP."} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output = %q, want %q", out, want)
+		}
+	}
+	repos.Players.(*storetest.Players).RequireDKP(t, "d1", 85)
+
+	es := repos.Events.(*eventtest.Store)
+	es.RequireTypes(t, event.AdminCommandRun, event.DKPAwarded, event.AdminCommandRun, event.DKPDeducted)
+	events := es.Events()
+	for i, command := range map[int]string{0: "award d1 10 raid night", 2: "deduct d1 25 late"} {
+		e := events[i]
+		got := eventtest.Data[event.AdminCommandData](t, e)
+		if want := (event.AdminCommandData{Command: command, User: "ops", Host: "bastion"}); got != want || e.AggregateID != event.AdminAggregateID {
+			t.Errorf("event %d = %+v on %s, want %+v on %s", i, got, e.AggregateID, want, event.AdminAggregateID)
+		}
+	}
+	for _, e := range events {
+		if e.Actor != "op" {
+			t.Errorf("%s event actor = %q, want the operator", e.Type, e.Actor)
+		}
+	}
+	if d := eventtest.Data[event.DKPChangeData](t, events[1]); d.Amount != 10 || d.Reason != "raid night" {
+		t.Errorf("award = %+v, want 10 DKP for raid night", d)
+	}
+}
+
+func TestAdminShell_Close(t *testing.T) {
+	sh, out, repos, ctx := newTestShell(t, "y\n")
+
+	// The bot started the auction and took Gandalf's bid before it went
+	// down.
+	bot := auction.NewManager(repos.Events, repos.Players, slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clock.Real{})
+	a, err := bot.StartAuction(ctx, "Sword", "officer", 10, 0, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("StartAuction() error = %v", err)
+	}
+	if err := bot.PlaceBid(ctx, a.ID, "d1", 40); err != nil {
+		t.Fatalf("PlaceBid() error = %v", err)
+	}
+	es := repos.Events.(*eventtest.Store)
+	bids := len(es.Events())
+
+	if err := sh.exec(ctx, "close "+a.ID); err != nil {
+		t.Fatalf("exec(close) error = %v", err)
+	}
+	if want := "at its highest bid of 40 DKP? [y/N] "; !strings.Contains(out.String(), want) {
+		t.Errorf("output = %q, want the prompt to name the bid: %q", out, want)
+	}
+
+	events := es.Events()[bids:]
+	if len(events) < 2 || events[0].Type != event.AdminCommandRun || events[1].Type != event.AuctionClosed {
+		t.Fatalf("events after the bid = %+v, want the admin command, then the auction closed", events)
+	}
+	if got := eventtest.Data[event.AdminCommandData](t, events[0]).Command; got != "close "+a.ID {
+		t.Errorf("admin command = %q, want close %s", got, a.ID)
+	}
+	if d := eventtest.Data[event.AuctionClosedData](t, events[1]); d.WinnerID != "p1" || d.Amount != 40 || events[1].Actor != "op" {
+		t.Errorf("closed = %+v by %s, want Gandalf's 40 DKP by the operator", d, events[1].Actor)
+	}
+	if want := "closed! Winner: **p1** with **40 DKP**"; !strings.Contains(out.String(), want) {
+		t.Errorf("output = %q, want the result of closing: %q", out, want)
+	}
+	if got := repos.Auctions.(*storetest.Auctions).Auction(t, a.ID); got.Status != "closed" || got.WinnerID == nil || *got.WinnerID != "p1" {
+		t.Errorf("auction row = %+v, want closed and won by p1", got)
+	}
+
+	if err := sh.exec(ctx, "close "+a.ID); err == nil || !strings.Contains(err.Error(), "already closed") {
+		t.Errorf("second exec(close) error = %v, want the auction already closed", err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/retention"
+)
+
+const archiveUsage = "usage: dkpbot archive run [-config path] [-dry-run]"
+
+// runArchive implements `dkpbot archive run`, which moves events of finished
+// auctions older than retention.max_age into the archive table.
+func runArchive(args []string) error {
+	if len(args) == 0 || args[0] != "run" {
+		return errors.New(archiveUsage)
+	}
+
+	fs := flag.NewFlagSet("archive run", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to configuration file")
+	dryRun := fs.Bool("dry-run", false, "report what would be archived without moving anything")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, repos, err := openStore(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+	defer repos.Closer.Close()
+
+	archiver := retention.NewArchiver(repos.Events, repos.Archive, cfg.Retention.MaxAge, cliLogger(), noop.NewTracerProvider(), clock.Real{})
+	report, err := archiver.Run(ctx, *dryRun)
+	if err != nil {
+		return fmt.Errorf("archiving events: %w", err)
+	}
+
+	verb := "archived"
+	if *dryRun {
+		verb = "would archive"
+	}
+	fmt.Printf("%s %d events from %d auctions (%d candidates, %d skipped)\n",
+		verb, report.Events, report.Archived, report.Candidates, report.Skipped)
+	return nil
+}
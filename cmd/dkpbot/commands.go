@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// subcommands maps the first command-line argument to an administrative
+// task. Without a subcommand the binary runs the bot.
+var subcommands = map[string]func(args []string) error{
+	"admin":         runAdmin,
+	"archive":       runArchive,
+	"config":        runConfig,
+	"doctor":        runDoctor,
+	"export":        runExport,
+	"import":        runImport,
+	"reconcile":     runReconcile,
+	"replay":        runReplay,
+	"simulate":      runSimulate,
+	"verify-ledger": runVerifyLedger,
+}
+
+// runSubcommand dispatches os.Args to a subcommand. It reports false if the
+// arguments do not name one.
+func runSubcommand(args []string) (bool, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return false, nil
+	}
+	cmd, ok := subcommands[args[0]]
+	if !ok {
+		return true, fmt.Errorf("unknown command %q (available: %s)", args[0], strings.Join(subcommandNames(), ", "))
+	}
+	return true, cmd(args[1:])
+}
+
+func subcommandNames() []string {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// profileEnv names the variable selecting the config profile when no
+// -profile flag is given, so that containers can set it once for the bot
+// and its subcommands.
+const profileEnv = config.EnvPrefix + "_PROFILE"
+
+// profileFlag registers the -profile flag on fs.
+func profileFlag(fs *flag.FlagSet) *string {
+	return fs.String("profile", os.Getenv(profileEnv),
+		"defaults for a deployment: "+strings.Join(config.Profiles(), ", ")+" (default $"+profileEnv+")")
+}
+
+// openStore loads the config at path and opens the configured store.
+// It is shared by subcommands that operate on the database directly.
+func openStore(ctx context.Context, path string) (*config.Config, *store.Repositories, error) {
+	cfg, err := config.LoadProfile(path, os.Getenv(profileEnv))
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading config: %w", err)
+	}
+	if _, err := loadSecrets(ctx, cfg, cliLogger()); err != nil {
+		return nil, nil, err
+	}
+	repos, err := store.Open(ctx, cfg.Database, clock.Real{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening store (driver=%s): %w", cfg.Database.Driver, err)
+	}
+	return cfg, repos, nil
+}
+
+// cliLogger is the logger used by subcommands; they write to stderr so that
+// stdout can carry command output.
+func cliLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, nil))
+}
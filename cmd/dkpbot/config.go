@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+)
+
+const configUsage = "usage: dkpbot config check [-config path] [-profile name]\n" +
+	"       dkpbot config print [-config path] [-profile name]"
+
+// runConfig dispatches `dkpbot config <action>`, which lets deployment
+// pipelines catch misconfiguration before the bot connects to Discord.
+func runConfig(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "check":
+			return runConfigCheck(args)
+		case "print":
+			return runConfigPrint(args)
+		}
+	}
+	return errors.New(configUsage)
+}
+
+// runConfigCheck implements `dkpbot config check`, which loads and
+// validates the config, including DKPBOT_* overrides, and reports every
+// problem found.
+func runConfigCheck(args []string) error {
+	fs := flag.NewFlagSet("config check", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to configuration file")
+	profile := profileFlag(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if _, err := config.LoadProfile(*configPath, *profile); err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	fmt.Printf("%s: config OK\n", *configPath)
+	return nil
+}
+
+// runConfigPrint implements `dkpbot config print`, which writes the
+// effective configuration, with defaults and DKPBOT_* overrides applied and
+// secrets redacted, as YAML to stdout.
+func runConfigPrint(args []string) error {
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to configuration file")
+	profile := profileFlag(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadProfile(*configPath, *profile)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg.Redacted()); err != nil {
+		return fmt.Errorf("printing config: %w", err)
+	}
+	return enc.Close()
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/health"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/settings"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// runDoctor implements `dkpbot doctor`, a self-test to run before going
+// live. It checks the config, the secrets, the database and its schema, the
+// Discord token, and the bot's access to its guild and loot channel, and
+// fails if any check does.
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to configuration file")
+	timeout := fs.Duration("timeout", 30*time.Second, "time limit for all checks")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	ctx, cancel = context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	var d doctor
+	defer d.summary()
+
+	cfg, err := config.LoadProfile(*configPath, os.Getenv(profileEnv))
+	if !d.check("config", err, *configPath) {
+		return d.err()
+	}
+	logger := cliLogger()
+
+	if cfg.Secrets.Enabled() {
+		_, err := loadSecrets(ctx, cfg, logger)
+		d.check("secrets", err, cfg.Secrets.Provider)
+	}
+
+	lootChannel := cfg.GuildDefaults.LootChannel
+	repos, err := store.Open(ctx, cfg.Database, clock.Real{})
+	if d.check("database", err, fmt.Sprintf("%s@%s:%d/%s", cfg.Database.User, cfg.Database.Host, cfg.Database.Port, cfg.Database.DBName)) {
+		defer repos.Closer.Close()
+		d.check("database migrations", repos.Migrations(ctx), fmt.Sprintf("at %03d or later", store.SchemaVersion))
+		d.check("database schema", repos.Schema(ctx), "all migrations applied")
+
+		// The loot channel may have been changed with /settings.
+		if cfg.Discord.GuildID != "" {
+			gs, err := settings.NewService(repos.GuildSettings, settings.Defaults(cfg.GuildDefaults), logger).Get(ctx, cfg.Discord.GuildID)
+			if d.check("guild settings", err, "loaded") {
+				lootChannel = gs.LootChannel
+			}
+		}
+	}
+
+	d.discord(ctx, cfg.Discord, lootChannel)
+	return d.err()
+}
+
+// doctor prints the outcome of each check and counts the failures.
+type doctor struct {
+	checks, failed int
+}
+
+// check prints the outcome of the check name, with detail if it passed, and
+// reports whether it did.
+func (d *doctor) check(name string, err error, detail string) bool {
+	d.checks++
+	if err != nil {
+		d.failed++
+		fmt.Printf("FAIL  %s: %v\n", name, err)
+		return false
+	}
+	fmt.Printf("ok    %s: %s\n", name, detail)
+	return true
+}
+
+func (d *doctor) summary() {
+	fmt.Printf("%d checks, %d failed\n", d.checks, d.failed)
+}
+
+func (d *doctor) err() error {
+	if d.failed > 0 {
+		return fmt.Errorf("doctor: %d of %d checks failed", d.failed, d.checks)
+	}
+	return nil
+}
+
+// discord checks the token and, through the REST API, that the bot can
+// register commands in and post to its guild and loot channel.
+func (d *doctor) discord(ctx context.Context, cfg config.DiscordConfig, lootChannel string) {
+	token := cfg.Token
+	if cfg.TokenFunc != nil {
+		token = cfg.TokenFunc()
+	}
+	s, err := discordgo.New("Bot " + token)
+	if err != nil {
+		d.check("discord token", err, "")
+		return
+	}
+	me, err := s.User("@me", discordgo.WithContext(ctx))
+	if err != nil {
+		d.check("discord token", err, "")
+		return
+	}
+	d.check("discord token", nil, "logged in as "+me.Username)
+
+	_, err = s.ApplicationCommands(me.ID, cfg.GuildID, discordgo.WithContext(ctx))
+	d.check("discord commands", err, "bot may manage slash commands")
+
+	if cfg.GuildID == "" {
+		return
+	}
+	// The permission checks read a state filled from the REST API, as the
+	// gateway would fill it.
+	st := discordgo.NewState()
+	st.User = me
+	var guildName string
+	g, err := s.Guild(cfg.GuildID, discordgo.WithContext(ctx))
+	if err == nil {
+		guildName = g.Name
+		err = st.GuildAdd(g)
+	}
+	var m *discordgo.Member
+	if err == nil {
+		m, err = s.GuildMember(cfg.GuildID, me.ID, discordgo.WithContext(ctx))
+	}
+	if err == nil {
+		m.GuildID = cfg.GuildID
+		err = st.MemberAdd(m)
+	}
+	if err == nil {
+		err = health.CheckGuild(st, cfg.GuildID)
+	}
+	if !d.check("discord guild", err, guildName) || lootChannel == "" {
+		return
+	}
+
+	var channelName string
+	ch, err := s.Channel(lootChannel, discordgo.WithContext(ctx))
+	if err == nil {
+		channelName = "#" + ch.Name
+		err = st.ChannelAdd(ch)
+	}
+	if err == nil {
+		err = health.CheckChannel(st, lootChannel, health.RequiredPermissions)
+	}
+	d.check("loot channel", err, channelName)
+}
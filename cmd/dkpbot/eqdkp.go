@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/eqdkp"
+)
+
+// runImportEQDKP implements `dkpbot import eqdkp`, which migrates players,
+// balances, raids, and items from an EQDKP Plus XML export.
+func runImportEQDKP(args []string) error {
+	fs := flag.NewFlagSet("import eqdkp", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to configuration file")
+	dumpPath := fs.String("file", "", "EQDKP Plus XML export")
+	linksPath := fs.String("links", "", "optional CSV of character_name,discord_id pairs for characters not yet registered")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without writing anything")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *dumpPath == "" {
+		return errors.New(importUsage)
+	}
+
+	f, err := os.Open(filepath.Clean(*dumpPath))
+	if err != nil {
+		return fmt.Errorf("opening export: %w", err)
+	}
+	defer f.Close()
+	dump, err := eqdkp.Parse(f)
+	if err != nil {
+		return err
+	}
+
+	var links map[string]string
+	if *linksPath != "" {
+		if links, err = readLinks(*linksPath); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	_, repos, err := openStore(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+	defer repos.Closer.Close()
+
+	im := eqdkp.NewImporter(repos.Players, repos.Events, cliLogger(), noop.NewTracerProvider())
+	report, err := im.Import(ctx, dump, links, *dryRun)
+	if err != nil {
+		return fmt.Errorf("importing EQDKP export: %w", err)
+	}
+
+	verb := "imported"
+	if *dryRun {
+		verb = "would import"
+	}
+	fmt.Printf("%s %d characters (%d linked to registered players, %d created), %d raids, %d raid awards, %d items, %d adjustments\n",
+		verb, report.Players, report.Linked, report.Created, report.Raids, report.Awards, report.Items, report.Adjustments)
+	if len(report.Unlinked) > 0 {
+		fmt.Printf("characters without a Discord user: %s\n", strings.Join(report.Unlinked, ", "))
+	}
+	return nil
+}
+
+// readLinks reads a two-column CSV mapping character names to Discord IDs.
+func readLinks(path string) (map[string]string, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("opening links file: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 2
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading links file: %w", err)
+	}
+	links := make(map[string]string, len(records))
+	for _, rec := range records {
+		links[strings.TrimSpace(rec[0])] = strings.TrimSpace(rec[1])
+	}
+	return links, nil
+}
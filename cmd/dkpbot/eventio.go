@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/eventio"
+)
+
+const (
+	exportUsage = "usage: dkpbot export events [-config path] [-o file]"
+	importUsage = "usage: dkpbot import events [-config path] [-i file] [-dry-run]\n" +
+		"       dkpbot import eqdkp [-config path] -file dump.xml [-links file.csv] [-dry-run]\n" +
+		"       dkpbot import items [-config path] -file dump.{csv,json} [-format csv|json] [-dry-run]\n" +
+		"       dkpbot import players [-config path] -file players.csv [-dry-run]"
+)
+
+// runExport implements `dkpbot export events`, which writes the event log
+// as newline-delimited JSON to stdout or a file.
+func runExport(args []string) error {
+	if len(args) == 0 || args[0] != "events" {
+		return errors.New(exportUsage)
+	}
+
+	fs := flag.NewFlagSet("export events", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to configuration file")
+	outPath := fs.String("o", "-", "output file, or - for stdout")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	_, repos, err := openStore(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+	defer repos.Closer.Close()
+
+	var w io.Writer = os.Stdout
+	if *outPath != "-" {
+		f, err := os.Create(filepath.Clean(*outPath))
+		if err != nil {
+			return fmt.Errorf("creating output file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	n, err := eventio.Export(ctx, repos.Events, w)
+	if err != nil {
+		return fmt.Errorf("exporting events: %w", err)
+	}
+	cliLogger().Info("export complete", "events", n)
+	return nil
+}
+
+// runImport dispatches `dkpbot import <source>`.
+func runImport(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "events":
+			return runImportEvents(args)
+		case "eqdkp":
+			return runImportEQDKP(args)
+		case "items":
+			return runImportItems(args)
+		case "players":
+			return runImportPlayers(args)
+		}
+	}
+	return errors.New(importUsage)
+}
+
+// runImportEvents implements `dkpbot import events`, which appends the
+// events of an export stream to the configured store.
+func runImportEvents(args []string) error {
+
+	fs := flag.NewFlagSet("import events", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to configuration file")
+	inPath := fs.String("i", "-", "input file, or - for stdin")
+	dryRun := fs.Bool("dry-run", false, "validate the stream without writing anything")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	_, repos, err := openStore(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+	defer repos.Closer.Close()
+
+	var r io.Reader = os.Stdin
+	if *inPath != "-" {
+		f, err := os.Open(filepath.Clean(*inPath))
+		if err != nil {
+			return fmt.Errorf("opening input file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	report, err := eventio.Import(ctx, repos.Events, r, *dryRun)
+	if err != nil {
+		return fmt.Errorf("importing events: %w", err)
+	}
+
+	verb := "imported"
+	if *dryRun {
+		verb = "would import"
+	}
+	fmt.Printf("%s %d of %d events across %d aggregates (%d duplicates skipped)\n",
+		verb, report.Imported, report.Read, report.Aggregates, report.Duplicates)
+	return nil
+}
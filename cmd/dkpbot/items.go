@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/items"
+)
+
+// runImportItems implements `dkpbot import items`, which loads the item
+// catalog from a CSV or JSON game data dump.
+func runImportItems(args []string) error {
+	fs := flag.NewFlagSet("import items", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to configuration file")
+	dumpPath := fs.String("file", "", "item dump, in CSV or JSON")
+	format := fs.String("format", "", "dump format, csv or json (default from the file extension)")
+	dryRun := fs.Bool("dry-run", false, "validate the dump without writing anything")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *dumpPath == "" {
+		return errors.New(importUsage)
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*dumpPath)), ".")
+	}
+
+	cfg, err := config.LoadProfile(*configPath, os.Getenv(profileEnv))
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	f, err := os.Open(filepath.Clean(*dumpPath))
+	if err != nil {
+		return fmt.Errorf("opening item dump: %w", err)
+	}
+	defer f.Close()
+	dump, err := items.Parse(f, *format, cfg.Items.IconURL)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Printf("would import %d items\n", len(dump))
+		return nil
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	_, repos, err := openStore(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+	defer repos.Closer.Close()
+
+	catalog := items.NewCatalog(repos.Items, cliLogger(), noop.NewTracerProvider())
+	if err := catalog.Import(ctx, dump); err != nil {
+		return err
+	}
+	fmt.Printf("imported %d items\n", len(dump))
+	return nil
+}
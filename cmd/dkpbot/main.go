@@ -8,18 +8,51 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
+	"github.com/jensholdgaard/discord-dkp-bot/internal/api"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/audit"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/bank"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/bot"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/bot/commands"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/calendar"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/deadletter"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dispute"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/economy"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/eqdkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/export"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/gdkp"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/health"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/ids"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/itemcost"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/items"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/jobs"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/leader"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/leaderboard"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/merge"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/notes"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/notify"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/onboard"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/raffle"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/rolesync"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/roster"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/settings"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/standings"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/telemetry"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/textfilter"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/usage"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/wcl"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/wishlist"
 
 	// Register store drivers so they are available via store.Open.
 	_ "github.com/jensholdgaard/discord-dkp-bot/internal/store/entstore"
@@ -29,7 +62,16 @@ import (
 var version = "dev"
 
 func main() {
+	if handled, err := runSubcommand(os.Args[1:]); handled {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	configPath := flag.String("config", "config.yaml", "path to configuration file")
+	profile := profileFlag(flag.CommandLine)
 	showVersion := flag.Bool("version", false, "print version and exit")
 	flag.Parse()
 
@@ -38,18 +80,18 @@ func main() {
 		os.Exit(0)
 	}
 
-	if err := run(*configPath); err != nil {
+	if err := run(*configPath, *profile); err != nil {
 		slog.Error("fatal error", slog.Any("error", err))
 		os.Exit(1)
 	}
 }
 
-func run(configPath string) error {
+func run(configPath, profile string) error {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
 	// Load configuration.
-	cfg, err := config.Load(configPath)
+	cfg, err := config.LoadProfile(configPath, profile)
 	if err != nil {
 		return fmt.Errorf("loading config: %w", err)
 	}
@@ -69,6 +111,16 @@ func run(configPath string) error {
 	logger := tp.Logger
 	clk := clock.Real{}
 
+	// Credentials kept in a secrets provider replace those in the config
+	// file, and are refetched so that rotations take effect.
+	rotator, err := loadSecrets(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	if rotator != nil && cfg.Secrets.RefreshInterval > 0 {
+		go rotator.Run(ctx, cfg.Secrets.RefreshInterval)
+	}
+
 	// Open store using the configured driver (sqlx or ent).
 	repos, err := store.Open(ctx, cfg.Database, clk)
 	if err != nil {
@@ -78,9 +130,146 @@ func run(configPath string) error {
 
 	logger.InfoContext(ctx, "connected to database", slog.String("driver", cfg.Database.Driver))
 
-	// Initialize managers.
-	dkpMgr := dkp.NewManager(repos.Players, repos.Events, logger, tp.TracerProvider)
-	auctionMgr := auction.NewManager(repos.Events, repos.Players, logger, tp.TracerProvider, clk)
+	// With leader election, a replica writes only while it leads: until
+	// startBot acquires a fencing token, the store rejects its writes.
+	if cfg.LeaderElection.Enabled {
+		repos.Fence.Require()
+	}
+
+	// Domain metrics default to the configured guild for operations that do
+	// not originate from a Discord interaction.
+	recorder, err := metrics.New(tp.MeterProvider, cfg.Discord.GuildID)
+	if err != nil {
+		return fmt.Errorf("creating metrics: %w", err)
+	}
+
+	// Events appended by the managers are published on the in-process bus
+	// so that other components can react without polling the store.
+	// Subscribers that send messages or write to the database get queues
+	// of their own, so that a slow one holds up neither commands nor the
+	// others.
+	bus := event.NewBus(event.WithQueues(cfg.EventBus, recorder, logger))
+
+	// Events the store rejects are parked on disk and retried, so that a
+	// transient database error does not drop history. They are published
+	// once they are persisted.
+	queue, err := deadletter.OpenQueue(cfg.DeadLetter.Path)
+	if err != nil {
+		return fmt.Errorf("opening dead-letter queue: %w", err)
+	}
+	events := deadletter.NewStore(event.NewPublishingStore(repos.Events, bus), queue, cfg.DeadLetter.MaxAttempts, clk, logger, tp.TracerProvider)
+
+	// Initialize managers. State changes are deduplicated on the Discord
+	// interaction ID.
+	dedup := idempotency.NewGuard(repos.Idempotency, idempotency.WithStaleAfter(cfg.Idempotency.StaleAfter))
+	go dedup.Run(ctx, clk, cfg.Idempotency.TTL, cfg.Idempotency.PruneInterval, logger)
+	idGen, err := ids.New(cfg.Database.IDs, clk)
+	if err != nil {
+		return fmt.Errorf("creating ID generator: %w", err)
+	}
+	guildSettings := settings.NewService(repos.GuildSettings, settings.Defaults(cfg.GuildDefaults), logger)
+	dkpMgr := dkp.NewManager(repos.Players, events, logger, tp.TracerProvider,
+		dkp.WithIdempotency(dedup), dkp.WithMetrics(recorder),
+		dkp.WithCurrencies(repos.Balances, cfg.Currencies))
+	raids := gdkp.NewService(events, cfg.GDKP, logger, tp.TracerProvider, clk)
+	raidCalendar := calendar.NewService(events, dkpMgr, cfg.Calendar, logger, tp.TracerProvider, clk)
+	guildBank := bank.NewService(events, logger, tp.TracerProvider, clk)
+	raffles := raffle.NewService(events, dkpMgr, idGen, logger, tp.TracerProvider, clk)
+	itemCosts := itemcost.NewService(events, dkpMgr, logger, tp.TracerProvider)
+	disputes := dispute.NewService(events, idGen, logger, tp.TracerProvider, clk)
+	playerNotes := notes.NewService(repos.PlayerNotes, repos.Players, logger, tp.TracerProvider, clk)
+	auctionMgr := auction.NewManager(events, repos.Players, logger, tp.TracerProvider, clk,
+		auction.WithIdempotency(dedup), auction.WithMetrics(recorder),
+		auction.WithSettings(guildSettings, cfg.Discord.GuildID), auction.WithGDKP(raids),
+		auction.WithLedger(dkpMgr), auction.WithIDs(idGen),
+		auction.WithTax(cfg.Tax), auction.WithLootBans(dkpMgr), auction.WithLootBans(playerNotes))
+	auditLog := audit.NewLog(repos.Events, repos.Players, tp.TracerProvider)
+	exporter := export.NewExporter(repos.Players, repos.Events, tp.TracerProvider, export.WithArchive(repos.Archive))
+	importer := eqdkp.NewImporter(repos.Players, events, logger, tp.TracerProvider)
+	itemCatalog := items.NewCatalog(repos.Items, logger, tp.TracerProvider)
+	wishlists := wishlist.NewService(repos.Wishlists, repos.Players, itemCatalog, logger)
+
+	// The standings are kept in memory and rebuilt after DKP changes, so
+	// that /dkp-list and the leaderboard do not list every player each time.
+	// The database announces the events every replica appends, so that
+	// they are rebuilt after the changes of other replicas too.
+	appended := event.NewBus()
+	go func() {
+		if err := repos.Listen(ctx, appended); err != nil {
+			logger.ErrorContext(ctx, "listening for appended events failed", slog.Any("error", err))
+		}
+	}()
+	standingsView := standings.NewProjection(repos.Players, logger, tp.TracerProvider, clk)
+	go standingsView.Run(ctx, bus, appended)
+	// The auctions table follows the auction events; the leader repairs
+	// the rows it missed while down once it has started.
+	auctionProjection := auction.NewProjection(events, repos.Auctions, recorder, logger, tp.TracerProvider)
+	go auctionProjection.Run(ctx, bus)
+
+	// Long commands run as jobs, which the leader interrupts when it shuts
+	// down or steps down.
+	jobRunner := jobs.NewRunner(cfg.Discord.Jobs, idGen, clk, logger, tp.TracerProvider)
+	commandOpts := []commands.Option{
+		commands.WithJobs(jobRunner, cfg.Discord.Jobs.ReportTo == config.JobsReportDM),
+		commands.WithMetrics(recorder),
+		commands.WithClock(clk),
+		commands.WithUsage(usage.NewTracker(repos.Usage, recorder, logger, tp.TracerProvider, clk)),
+		commands.WithDeadLetters(events),
+		commands.WithSettings(guildSettings),
+		commands.WithItems(itemCatalog),
+		commands.WithItemResolver(items.NewLinkResolver(cfg.Items.LinkURL)),
+		commands.WithWishlist(wishlists),
+		commands.WithNotes(playerNotes),
+		commands.WithGDKP(raids),
+		commands.WithCalendar(raidCalendar),
+		commands.WithBank(guildBank),
+		commands.WithRaffles(raffles),
+		commands.WithItemCosts(itemCosts),
+		commands.WithDisputes(disputes),
+		commands.WithStandings(standingsView),
+		commands.WithEconomy(economy.NewProjection(events, dkpMgr, standingsView, tp.TracerProvider)),
+	}
+	// Optional integrations surface as extra slash commands.
+	if cfg.WarcraftLogs.Enabled() {
+		wclClient := wcl.NewClient(cfg.WarcraftLogs, &http.Client{Timeout: 30 * time.Second}, tp.TracerProvider)
+		commandOpts = append(commandOpts, commands.WithAttendance(
+			wcl.NewAttendance(wclClient, dkpMgr, cfg.WarcraftLogs, logger, tp.TracerProvider),
+		))
+	}
+
+	// The gateway supervisor outlives individual bots, so that readiness
+	// reflects the Discord connection of whichever bot is running.
+	gateway := bot.NewSupervisor(cfg.Discord.Gateway, clk, logger, recorder, auctionMgr.OpenAuctions)
+
+	// Players are sent messages about the events this replica appends,
+	// through whichever bot is running.
+	go notify.NewDispatcher(wishlists, gateway.Session, logger, tp.TracerProvider,
+		notify.WithOutbid(events, repos.Players, guildSettings, cfg.Discord.GuildID),
+	).Run(ctx, bus)
+
+	// The weekly leaderboard, roster review, and raid reminders are sent,
+	// balances reconciled, roles synchronized, and unsold banked items
+	// returned to the bank by the leader only.
+	leaderboardPoster := leaderboard.NewPoster(cfg.Leaderboard, standingsView, events, repos.Archive,
+		guildSettings, cfg.Discord.GuildID, gateway.Session, dedup, logger, tp.TracerProvider, clk)
+	rosterReviewer := roster.NewReviewer(cfg.Roster, repos.Players, events,
+		guildSettings, cfg.Discord.GuildID, gateway.Session, dedup, logger, tp.TracerProvider, clk)
+	commandOpts = append(commandOpts, commands.WithRoster(rosterReviewer),
+		commands.WithMerger(merge.NewMerger(repos.Players, events, logger, tp.TracerProvider, clk)),
+		commands.WithPlayerImport(onboard.NewImporter(repos.Players, events, logger, tp.TracerProvider)))
+	raidReminder := calendar.NewReminder(raidCalendar, cfg.Calendar.Reminder, gateway.Session, logger)
+	if cfg.TextFilter.Enabled() {
+		commandOpts = append(commandOpts, commands.WithTextFilter(textfilter.New(cfg.TextFilter)))
+	}
+	var roleSyncer *rolesync.Syncer
+	if cfg.RoleSync.Enabled() {
+		roleSyncer = rolesync.NewSyncer(cfg.RoleSync, repos.Players, cfg.Discord.GuildID, gateway.Session, logger, tp.TracerProvider)
+		commandOpts = append(commandOpts, commands.WithRoleSync(roleSyncer))
+	}
+
+	// Leadership is reported on /leaderz, in readiness, and as a gauge, so
+	// that it is clear which replica is active.
+	leaderStatus := leader.NewStatus(clk, recorder)
 
 	// Setup health checks.
 	healthHandler := health.NewHandler(clk,
@@ -88,12 +277,63 @@ func run(configPath string) error {
 			Name:  "database",
 			Check: repos.Ping,
 		},
+		health.Checker{
+			Name:  "migrations",
+			Check: repos.Migrations,
+		},
+		health.DiscordChecker(gateway.Check, gateway.Session, cfg.Discord.GuildID),
 	)
+	healthHandler.AddDetail(health.Detail{Name: "role", Value: leaderStatus.Role})
+	healthHandler.AddDetail(health.Detail{Name: "leader", Value: leaderStatus.Holder})
 
 	// Start HTTP server for health checks (runs on all replicas).
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", healthHandler.LivenessHandler())
 	mux.HandleFunc("/readyz", healthHandler.ReadinessHandler())
+	mux.HandleFunc("/leaderz", leaderStatus.Handler())
+	if tp.MetricsHandler != nil {
+		mux.Handle("/metrics", tp.MetricsHandler)
+	}
+	if tp.DebugHandler != nil {
+		mux.Handle("/debug/", tp.DebugHandler)
+	}
+
+	// The leader can be asked to hand over to another replica, for example
+	// before a rolling deploy, with SIGUSR1 or POST /admin/stepdown.
+	electionCtx, stepdown := leader.NewStepdown(ctx, leaderStatus, cfg.Server.ShutdownTimeout, logger)
+	if cfg.LeaderElection.Enabled {
+		usr1 := make(chan os.Signal, 1)
+		signal.Notify(usr1, syscall.SIGUSR1)
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-usr1:
+				}
+				if stepErr := stepdown.StepDown(ctx); stepErr != nil {
+					logger.WarnContext(ctx, "stepdown refused", slog.Any("error", stepErr))
+				}
+			}
+		}()
+	}
+
+	// The REST API reads from the store directly, so it is available on
+	// every replica. Writes go through the managers and are only accepted
+	// while this replica is running the bot.
+	if cfg.API.Enabled {
+		apiOpts := []api.Option{
+			api.WithManagers(dkpMgr, auctionMgr),
+			api.WithWriteGate(healthHandler.Ready),
+			api.WithBus(bus),
+			api.WithExporter(exporter),
+		}
+		if cfg.LeaderElection.Enabled {
+			apiOpts = append(apiOpts, api.WithStepdown(stepdown.StepDown))
+		}
+		api.NewServer(cfg.API, repos.Players, repos.Events, repos.Archive, logger, tp.TracerProvider, apiOpts...).Register(mux)
+		logger.InfoContext(ctx, "REST API enabled", slog.Int("keys", len(cfg.API.Keys)))
+	}
 
 	httpServer := &http.Server{
 		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
@@ -108,8 +348,59 @@ func run(configPath string) error {
 		}
 	}()
 
+	// A warm standby keeps a Discord session open and serves read-only
+	// commands while another replica leads, so that failover only has to
+	// promote it.
+	var standby *bot.Bot
+	if cfg.LeaderElection.Enabled && cfg.LeaderElection.Standby {
+		standbyOpts := append(slices.Clone(commandOpts), commands.WithStandby(dedup))
+		standby, err = bot.New(cfg.Discord, dkpMgr, auctionMgr, auditLog, exporter, importer, logger, tp.TracerProvider, standbyOpts...)
+		if err != nil {
+			return fmt.Errorf("creating standby bot: %w", err)
+		}
+		standby.Supervise(ctx, gateway)
+		if err = standby.StartStandby(ctx); err != nil {
+			return fmt.Errorf("starting standby bot: %w", err)
+		}
+		logger.InfoContext(ctx, "serving read-only commands as warm standby")
+	}
+
+	// drainOnStepdown finishes the interactions b is handling and persists
+	// queued events before a stepdown releases leadership.
+	drainOnStepdown := func(b *bot.Bot) {
+		stepdown.OnStepdown(func(ctx context.Context) error {
+			healthHandler.SetReady(false)
+			return b.Drain(ctx)
+		})
+		stepdown.OnStepdown(jobRunner.Shutdown)
+		stepdown.OnStepdown(func(ctx context.Context) error {
+			if _, retryErr := events.Retry(ctx); retryErr != nil {
+				return retryErr
+			}
+			if queued := events.Status().Letters; queued > 0 {
+				return fmt.Errorf("%d dead letters still queued", queued)
+			}
+			return nil
+		})
+	}
+
 	// startBot is the core work that only the leader should run.
 	startBot := func(ctx context.Context) {
+		// Writes carry a fencing token, so that if this replica is paused
+		// and loses its lease unnoticed, the store rejects its writes once
+		// another replica has taken over.
+		token, fenceErr := repos.Fence.Acquire(ctx, cfg.LeaderElection.LeaseName)
+		if fenceErr != nil {
+			logger.ErrorContext(ctx, "acquiring fencing token failed", slog.Any("error", fenceErr))
+			return
+		}
+		leaderStatus.SetFencingToken(token)
+		logger.InfoContext(ctx, "acquired fencing token", slog.Int64("token", token))
+
+		// Queued events are retried under the fencing token, so that only
+		// the leader writes them.
+		go events.Run(ctx, cfg.DeadLetter.RetryInterval)
+
 		// Recover in-flight auctions from the event store so that they
 		// survive leader failover.
 		if n, recoverErr := auctionMgr.RecoverOpenAuctions(ctx); recoverErr != nil {
@@ -117,18 +408,50 @@ func run(configPath string) error {
 		} else if n > 0 {
 			logger.InfoContext(ctx, "recovered open auctions", slog.Int("count", n))
 		}
+		go leaderboardPoster.Run(ctx)
+		if cfg.Roster.Enabled() {
+			go rosterReviewer.Run(ctx)
+		}
+		if cfg.Reconcile.Enabled {
+			go reconcileWeekly(ctx, cfg.Reconcile, dkpMgr, guildSettings, cfg.Discord.GuildID, clk, logger)
+		}
+		if roleSyncer != nil {
+			go roleSyncer.Run(ctx, bus)
+		}
+		go guildBank.Run(ctx, bus)
+		go raidReminder.Run(ctx)
+
+		if standby != nil {
+			// The standby is stopped once the election loop exits.
+			if botErr := standby.Promote(ctx); botErr != nil {
+				logger.ErrorContext(ctx, "promoting standby bot failed", slog.Any("error", botErr))
+				return
+			}
+			drainOnStepdown(standby)
+			go standby.RunScheduler(ctx, clk)
+			go reconcileAuctions(ctx, auctionProjection, guildSettings, cfg.Discord.GuildID, gateway.Session, logger)
+			healthHandler.SetReady(true)
+			logger.InfoContext(ctx, "dkpbot is running (leader, promoted from standby)", slog.String("version", version))
+			<-ctx.Done()
+			healthHandler.SetReady(false)
+			return
+		}
 
-		discordBot, botErr := bot.New(cfg.Discord, dkpMgr, auctionMgr, logger, tp.TracerProvider)
+		discordBot, botErr := bot.New(cfg.Discord, dkpMgr, auctionMgr, auditLog, exporter, importer, logger, tp.TracerProvider, commandOpts...)
 		if botErr != nil {
 			logger.ErrorContext(ctx, "creating bot failed", slog.Any("error", botErr))
 			return
 		}
+		discordBot.Supervise(ctx, gateway)
 
 		if botErr = discordBot.Start(ctx); botErr != nil {
 			logger.ErrorContext(ctx, "starting bot failed", slog.Any("error", botErr))
 			return
 		}
+		go discordBot.RunScheduler(ctx, clk)
+		go reconcileAuctions(ctx, auctionProjection, guildSettings, cfg.Discord.GuildID, gateway.Session, logger)
 
+		drainOnStepdown(discordBot)
 		healthHandler.SetReady(true)
 		logger.InfoContext(ctx, "dkpbot is running (leader)", slog.String("version", version))
 
@@ -144,22 +467,44 @@ func run(configPath string) error {
 	if cfg.LeaderElection.Enabled {
 		logger.InfoContext(ctx, "leader election enabled, waiting for leadership...")
 
-		if leaderErr := leader.Run(ctx, cfg.LeaderElection, logger, startBot, func() {
+		if leaderErr := leader.Run(electionCtx, cfg.LeaderElection, leaderStatus, logger, startBot, func() {
 			logger.Info("lost leadership, shutting down...")
 			cancel()
 		}); leaderErr != nil {
 			return fmt.Errorf("leader election: %w", leaderErr)
 		}
+		if standby != nil {
+			if stopErr := standby.Stop(); stopErr != nil {
+				logger.Error("standby bot shutdown error", slog.Any("error", stopErr))
+			}
+		}
 	} else {
 		// No leader election — run directly.
-		discordBot, botErr := bot.New(cfg.Discord, dkpMgr, auctionMgr, logger, tp.TracerProvider)
+		leaderStatus.Started(ctx)
+		go events.Run(ctx, cfg.DeadLetter.RetryInterval)
+		go leaderboardPoster.Run(ctx)
+		if cfg.Roster.Enabled() {
+			go rosterReviewer.Run(ctx)
+		}
+		if cfg.Reconcile.Enabled {
+			go reconcileWeekly(ctx, cfg.Reconcile, dkpMgr, guildSettings, cfg.Discord.GuildID, clk, logger)
+		}
+		if roleSyncer != nil {
+			go roleSyncer.Run(ctx, bus)
+		}
+		go guildBank.Run(ctx, bus)
+		go raidReminder.Run(ctx)
+		discordBot, botErr := bot.New(cfg.Discord, dkpMgr, auctionMgr, auditLog, exporter, importer, logger, tp.TracerProvider, commandOpts...)
 		if botErr != nil {
 			return fmt.Errorf("creating bot: %w", botErr)
 		}
+		discordBot.Supervise(ctx, gateway)
 
 		if botErr = discordBot.Start(ctx); botErr != nil {
 			return fmt.Errorf("starting bot: %w", botErr)
 		}
+		go discordBot.RunScheduler(ctx, clk)
+		go reconcileAuctions(ctx, auctionProjection, guildSettings, cfg.Discord.GuildID, gateway.Session, logger)
 
 		healthHandler.SetReady(true)
 		logger.InfoContext(ctx, "dkpbot is running", slog.String("version", version))
@@ -178,6 +523,9 @@ func run(configPath string) error {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer shutdownCancel()
 
+	if err := jobRunner.Shutdown(shutdownCtx); err != nil {
+		logger.Error("interrupted running jobs", slog.Any("error", err))
+	}
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
 		logger.Error("http server shutdown error", slog.Any("error", err))
 	}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/onboard"
+)
+
+// runImportPlayers implements `dkpbot import players`, which registers
+// players with starting balances from a CSV file.
+func runImportPlayers(args []string) error {
+	fs := flag.NewFlagSet("import players", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to configuration file")
+	filePath := fs.String("file", "", "CSV file with character_name, discord_id, and dkp columns")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without writing anything")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *filePath == "" {
+		return errors.New(importUsage)
+	}
+
+	f, err := os.Open(filepath.Clean(*filePath))
+	if err != nil {
+		return fmt.Errorf("opening players file: %w", err)
+	}
+	defer f.Close()
+	file, err := onboard.Parse(f)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	_, repos, err := openStore(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+	defer repos.Closer.Close()
+
+	im := onboard.NewImporter(repos.Players, repos.Events, cliLogger(), noop.NewTracerProvider())
+	report, err := im.Import(event.WithActor(ctx, onboard.Actor), file, *dryRun)
+	if err != nil {
+		return fmt.Errorf("importing players: %w", err)
+	}
+
+	verb := "registered"
+	if *dryRun {
+		verb = "would register"
+	}
+	fmt.Printf("%s %d players with %d DKP in all\n", verb, report.Created, report.DKP)
+	for _, e := range report.Errors {
+		fmt.Fprintf(os.Stderr, "skipped %s\n", e)
+	}
+	return nil
+}
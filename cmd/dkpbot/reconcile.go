@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"syscall"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/settings"
+)
+
+// runReconcile implements `dkpbot reconcile`, which recomputes each player's
+// balance from their DKP history and fails if any drifted balance is left
+// unfixed.
+func runReconcile(args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to configuration file")
+	fix := fs.Bool("fix", false, "set drifted balances to the sum of their history")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	_, repos, err := openStore(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+	defer repos.Closer.Close()
+
+	mgr := dkp.NewManager(repos.Players, repos.Events, cliLogger(), noop.NewTracerProvider())
+	report, err := mgr.Reconcile(ctx, *fix)
+	if err != nil {
+		return fmt.Errorf("reconciling balances: %w", err)
+	}
+
+	for _, d := range report.Drifts {
+		status := ""
+		switch {
+		case d.Fixed:
+			status = " (fixed)"
+		case d.Archived:
+			status = " (archived)"
+		case *fix:
+			status = " (changed while reconciling; run again)"
+		}
+		fmt.Printf("DRIFT %s (%s): stored %d, history %d (%+d)%s\n",
+			d.CharacterName, d.DiscordID, d.Stored, d.Computed, d.Stored-d.Computed, status)
+	}
+	fmt.Printf("reconciled %d players: %d drifted, %d fixed\n",
+		report.Players, len(report.Drifts), len(report.Drifts)-report.Unfixed())
+
+	if n := report.Unfixed(); n > 0 {
+		return fmt.Errorf("reconciliation found %d balances that do not match their history", n)
+	}
+	return nil
+}
+
+// reconcileWeekly reconciles player balances at each time scheduled by cfg,
+// in the timezone of guildID, until ctx is done. Only the leader should run
+// it.
+func reconcileWeekly(ctx context.Context, cfg config.ReconcileConfig, mgr *dkp.Manager, svc *settings.Service, guildID string, clk clock.Clock, logger *slog.Logger) {
+	for {
+		timer := clock.NewTimer(clk, cfg.Next(clk.Now(), svc.Location(ctx, guildID)).Sub(clk.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		report, err := mgr.Reconcile(ctx, cfg.Fix)
+		if err != nil {
+			logger.ErrorContext(ctx, "reconciling balances failed", slog.Any("error", err))
+			continue
+		}
+		logger.InfoContext(ctx, "balances reconciled",
+			slog.Int("players", report.Players),
+			slog.Int("drifted", len(report.Drifts)),
+			slog.Int("unfixed", report.Unfixed()),
+		)
+	}
+}
+
+// reconcileAuctions repairs the auction rows that disagree with the event
+// log and reports them in the officer channel of guildID, if one is set.
+// session returns the current Discord session, or nil while none is open.
+// Only the leader should run it, once it has started.
+func reconcileAuctions(ctx context.Context, p *auction.Projection, svc *settings.Service, guildID string, session func() *discordgo.Session, logger *slog.Logger) {
+	report, err := p.Reconcile(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "reconciling auctions failed", slog.Any("error", err))
+		return
+	}
+	logger.InfoContext(ctx, "auctions reconciled",
+		slog.Int("auctions", report.Checked),
+		slog.Int("discrepancies", len(report.Discrepancies)),
+	)
+	if len(report.Discrepancies) == 0 {
+		return
+	}
+	gs, err := svc.Get(ctx, guildID)
+	if err != nil {
+		logger.ErrorContext(ctx, "loading guild settings failed", slog.Any("error", err))
+		return
+	}
+	s := session()
+	if gs.OfficerChannel == "" || s == nil {
+		return
+	}
+	msg := &discordgo.MessageSend{Content: report.Summary()}
+	if _, err := s.ChannelMessageSendComplex(gs.OfficerChannel, msg, discordgo.WithContext(ctx)); err != nil {
+		logger.ErrorContext(ctx, "reporting auction discrepancies failed", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+const replayUsage = "usage: dkpbot replay auction [-config path] [-json] <auction-id>"
+
+// runReplay implements `dkpbot replay auction`, which prints the event
+// timeline of an auction with its replayed state after each event, to debug
+// auctions with an unexpected outcome. It only reads from the store.
+func runReplay(args []string) error {
+	if len(args) == 0 || args[0] != "auction" {
+		return errors.New(replayUsage)
+	}
+
+	fs := flag.NewFlagSet("replay auction", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to configuration file")
+	asJSON := fs.Bool("json", false, "print each step as a line of JSON with the full state")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New(replayUsage)
+	}
+	id := fs.Arg(0)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	_, repos, err := openStore(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+	defer repos.Closer.Close()
+
+	events, err := repos.Events.Load(ctx, id)
+	if err != nil {
+		return fmt.Errorf("loading auction: %w", err)
+	}
+	if len(events) == 0 {
+		// The events of archived auctions are gone; only their state is
+		// kept.
+		snap, err := repos.Archive.LoadSnapshot(ctx, id)
+		if err != nil {
+			return fmt.Errorf("auction %s has no events and no snapshot: %w", id, err)
+		}
+		fmt.Printf("auction %s was archived at version %d on %s; only its final state is kept:\n",
+			id, snap.Version, snap.CreatedAt.UTC().Format(time.RFC3339))
+		fmt.Println(string(snap.State))
+		return nil
+	}
+
+	players, err := repos.Players.List(ctx)
+	if err != nil {
+		return fmt.Errorf("listing players: %w", err)
+	}
+	names := make(map[string]string, len(players))
+	for _, p := range players {
+		names[p.ID] = p.CharacterName
+	}
+
+	steps := auction.Timeline(events)
+	if *asJSON {
+		err = writeStepsJSON(os.Stdout, steps)
+	} else {
+		err = writeSteps(os.Stdout, steps, names)
+	}
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, s := range steps {
+		if s.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d events of auction %s failed to replay or contradict its history", failed, len(steps), id)
+	}
+	return nil
+}
+
+// writeSteps writes steps as a readable timeline, naming players by their
+// character names where names has them.
+func writeSteps(w io.Writer, steps []auction.Step, names map[string]string) error {
+	player := func(id string) string {
+		if name, ok := names[id]; ok {
+			return fmt.Sprintf("%s (%s)", name, id)
+		}
+		return id
+	}
+	for _, s := range steps {
+		e := s.Event
+		actor := e.Actor
+		if actor == "" {
+			actor = "-"
+		}
+		fmt.Fprintf(w, "v%d  %s  %s  actor=%s  %s\n", e.Version, e.CreatedAt.UTC().Format(time.RFC3339), e.Type, actor, e.Data)
+		highest := "none"
+		if h := s.HighestBid; h != nil {
+			highest = fmt.Sprintf("%s @ %d", player(h.PlayerID), h.Amount)
+		}
+		fmt.Fprintf(w, "    status=%s bids=%d highest=%s", s.State.Status, len(s.State.Bids), highest)
+		if len(s.State.Skipped) > 0 {
+			skipped := make([]string, len(s.State.Skipped))
+			for i, id := range s.State.Skipped {
+				skipped[i] = player(id)
+			}
+			fmt.Fprintf(w, " skipped=%s", strings.Join(skipped, ","))
+		}
+		if len(s.State.Rolls) > 0 {
+			fmt.Fprintf(w, " rolls=%d", len(s.State.Rolls))
+		}
+		if s.State.ReserveNotMet {
+			fmt.Fprint(w, " reserve-not-met")
+		}
+		fmt.Fprintln(w)
+		if s.Err != nil {
+			for _, line := range strings.Split(s.Err.Error(), "\n") {
+				fmt.Fprintf(w, "    ERROR: %s\n", line)
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d events\n", len(steps))
+	return err
+}
+
+// writeStepsJSON writes each of steps as a line of JSON.
+func writeStepsJSON(w io.Writer, steps []auction.Step) error {
+	enc := json.NewEncoder(w)
+	for _, s := range steps {
+		line := struct {
+			Event      event.Event   `json:"event"`
+			State      auction.State `json:"state"`
+			HighestBid *auction.Bid  `json:"highest_bid,omitempty"`
+			Error      string        `json:"error,omitempty"`
+		}{Event: s.Event, State: s.State, HighestBid: s.HighestBid}
+		if s.Err != nil {
+			line.Error = s.Err.Error()
+		}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
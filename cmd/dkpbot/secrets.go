@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/secrets"
+)
+
+// loadSecrets fetches the secrets referenced by cfg.Secrets into cfg. The
+// returned Rotator, nil without a secrets provider, keeps them current
+// while it runs.
+func loadSecrets(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*secrets.Rotator, error) {
+	if !cfg.Secrets.Enabled() {
+		return nil, nil
+	}
+	provider, err := secrets.NewProvider(cfg.Secrets, &http.Client{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	rotator := secrets.NewRotator(provider, logger)
+	var token, password, key, chainKey *secrets.Secret
+	if ref := cfg.Secrets.DiscordToken; ref != "" {
+		token = rotator.Add("discord_token", ref)
+	}
+	if ref := cfg.Secrets.DatabasePassword; ref != "" {
+		password = rotator.Add("database_password", ref)
+	}
+	if ref := cfg.Secrets.EncryptionKey; ref != "" {
+		key = rotator.Add("encryption_key", ref)
+	}
+	if ref := cfg.Secrets.ChainKey; ref != "" {
+		chainKey = rotator.Add("chain_key", ref)
+	}
+	if err := rotator.Refresh(ctx); err != nil {
+		return nil, fmt.Errorf("fetching secrets from %s: %w", cfg.Secrets.Provider, err)
+	}
+
+	if token != nil {
+		cfg.Discord.Token, cfg.Discord.TokenFunc = token.Value(), token.Value
+	}
+	if password != nil {
+		cfg.Database.Password, cfg.Database.PasswordFunc = password.Value(), password.Value
+	}
+	if key != nil {
+		// Only the key fetched at startup is used: data sealed with it
+		// cannot be read with a rotated one.
+		cfg.Database.EncryptionKey = key.Value()
+	}
+	if chainKey != nil {
+		// Likewise, events chained with one key cannot be verified with
+		// another.
+		cfg.Database.ChainKey = chainKey.Value()
+	}
+	return rotator, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/simulate"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+// runSimulate implements `dkpbot simulate`, which places many concurrent
+// bids on simulated auctions, in memory or against the configured
+// database, and reports throughput, contention, and latency.
+func runSimulate(args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to configuration file, used with -store database")
+	storeKind := fs.String("store", "memory", "store to simulate against: memory, or database for the configured one (use a scratch database)")
+	var cfg simulate.Config
+	fs.IntVar(&cfg.Auctions, "auctions", 50, "open auctions to bid on")
+	fs.IntVar(&cfg.Players, "players", 200, "simulated players")
+	fs.IntVar(&cfg.Bids, "bids", 10000, "bids to place in total")
+	fs.IntVar(&cfg.Concurrency, "concurrency", 64, "players bidding at the same time")
+	fs.Uint64Var(&cfg.Seed, "seed", 1, "seed of the players and bids")
+	fs.DurationVar(&cfg.AppendLatency, "append-latency", 0, "latency added to each event append, to model a database in memory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	var (
+		events  event.Store
+		players store.PlayerRepository
+	)
+	switch *storeKind {
+	case "memory":
+		events, players = eventtest.NewStore(), storetest.NewPlayers()
+	case "database":
+		_, repos, err := openStore(ctx, *configPath)
+		if err != nil {
+			return err
+		}
+		defer repos.Closer.Close()
+		events, players = repos.Events, repos.Players
+	default:
+		return fmt.Errorf("unknown store %q (available: memory, database)", *storeKind)
+	}
+
+	// Auctions log every bid; only problems are of interest here.
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	slog.SetDefault(logger)
+	report, err := simulate.New(events, players, logger, noop.NewTracerProvider()).Run(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("simulating: %w", err)
+	}
+
+	fmt.Printf("placed %d bids on %d auctions by %d players, %d at a time, in %s: %.0f bids/s\n",
+		report.Bids, cfg.Auctions, cfg.Players, cfg.Concurrency, report.Elapsed.Round(time.Millisecond), report.Throughput())
+	fmt.Printf("accepted %d, lost a race %d\n", report.Accepted, report.Contended)
+	for _, code := range slices.Sorted(maps.Keys(report.Rejected)) {
+		fmt.Printf("rejected %s: %d\n", code, report.Rejected[code])
+	}
+	fmt.Printf("bid latency: %s\n", report.BidLatency)
+	fmt.Printf("append latency: %s\n", report.AppendLatency)
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// runVerifyLedger implements `dkpbot verify-ledger`, which recomputes the
+// hash chain of every aggregate, archived events included, and fails if any
+// event was modified, removed from the end of a chain, or unchained.
+func runVerifyLedger(args []string) error {
+	fs := flag.NewFlagSet("verify-ledger", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to configuration file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, repos, err := openStore(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+	defer repos.Closer.Close()
+
+	events, err := repos.Events.Query(ctx, event.Query{})
+	if err != nil {
+		return fmt.Errorf("loading events: %w", err)
+	}
+	archived, err := repos.Archive.Archived(ctx)
+	if err != nil {
+		return err
+	}
+	heads, err := repos.ChainHeads(ctx)
+	if err != nil {
+		return err
+	}
+	key, err := store.ParseChainKey(cfg.Database.ChainKey)
+	if err != nil {
+		return err
+	}
+
+	report := event.VerifyChain(key, append(events, archived...), heads)
+	for _, p := range report.Problems {
+		fmt.Println("TAMPERED", p)
+	}
+	fmt.Printf("verified %d events across %d aggregates (%d unchained legacy events, %d chained without the key)\n",
+		report.Verified, report.Aggregates, report.Unchained, report.Unkeyed)
+
+	if len(report.Problems) > 0 {
+		return fmt.Errorf("ledger verification failed: %d events do not match their hash chain", len(report.Problems))
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package announce words the announcements of auctions from templates
+// guilds can change with /settings, such as "**{winner}** won {item}!".
+// Placeholders in braces are replaced by the details of the auction.
+package announce
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxLength is the longest template accepted, leaving room in Discord's
+// 2000 character messages for long item and player names.
+const MaxLength = 500
+
+// Template describes an announcement guilds can word: its default text
+// and the placeholders it may use.
+type Template struct {
+	Default      string
+	Placeholders Placeholders
+}
+
+// The announcements guilds can word.
+var (
+	// Started announces the start of an auction.
+	Started = Template{
+		Default:      "Auction started!",
+		Placeholders: Placeholders{"item", "id", "min_bid", "duration", "currency"},
+	}
+	// Outbid tells a player they were outbid.
+	Outbid = Template{
+		Default:      "You were outbid on **{item}**: the highest bid is now {amount} {currency}. Bid again with `/bid auction-id:{id} amount:<{currency}>`.",
+		Placeholders: Placeholders{"item", "id", "amount", "currency"},
+	}
+	// Winner announces the winning bid of a closed auction.
+	Winner = Template{
+		Default:      "Auction `{id}` closed! Winner: **{winner}** with **{amount} {currency}**",
+		Placeholders: Placeholders{"item", "id", "winner", "amount", "currency"},
+	}
+	// NoBids announces an auction that closed without bids.
+	NoBids = Template{
+		Default:      "Auction `{id}` closed with no bids.",
+		Placeholders: Placeholders{"item", "id"},
+	}
+)
+
+var placeholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// Check returns an error if text is empty, too long, or uses a
+// placeholder t does not have.
+func (t Template) Check(text string) error {
+	switch {
+	case strings.TrimSpace(text) == "":
+		return fmt.Errorf("want some text")
+	case utf8.RuneCountInString(text) > MaxLength:
+		return fmt.Errorf("want at most %d characters, got %d", MaxLength, utf8.RuneCountInString(text))
+	}
+	for _, m := range placeholder.FindAllStringSubmatch(text, -1) {
+		if !slices.Contains(t.Placeholders, m[1]) {
+			return fmt.Errorf("unknown placeholder %s; want %s", m[0], t.Placeholders)
+		}
+	}
+	return nil
+}
+
+// Placeholders name the placeholders of a template, without braces.
+type Placeholders []string
+
+// String lists the placeholders in braces, such as "{item}, {id}".
+func (p Placeholders) String() string {
+	list := make([]string, len(p))
+	for i, name := range p {
+		list[i] = "{" + name + "}"
+	}
+	return strings.Join(list, ", ")
+}
+
+// Vars are the values of placeholders by name, without braces.
+type Vars map[string]string
+
+// Render returns text, or the default of t if text is empty, with its
+// placeholders replaced by vars. Text in braces that names no variable is
+// kept as is.
+func (t Template) Render(text string, vars Vars) string {
+	if text == "" {
+		text = t.Default
+	}
+	return placeholder.ReplaceAllStringFunc(text, func(m string) string {
+		if v, ok := vars[m[1:len(m)-1]]; ok {
+			return v
+		}
+		return m
+	})
+}
@@ -0,0 +1,47 @@
+package announce_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/announce"
+)
+
+func TestTemplate_Check(t *testing.T) {
+	for _, tmpl := range []announce.Template{announce.Started, announce.Outbid, announce.Winner, announce.NoBids} {
+		if err := tmpl.Check(tmpl.Default); err != nil {
+			t.Errorf("Check(%q) error = %v", tmpl.Default, err)
+		}
+	}
+
+	tests := []struct {
+		text    string
+		wantErr string
+	}{
+		{text: "**{winner}** gewinnt {item} für {amount} {currency}!"},
+		{text: "{winner} won {item}, {unknown} ignored", wantErr: "unknown placeholder {unknown}; want {item}, {id}, {winner}, {amount}, {currency}"},
+		{text: "  ", wantErr: "want some text"},
+		{text: strings.Repeat("x", announce.MaxLength+1), wantErr: "want at most 500 characters"},
+		{text: "Literal {Braces} and {} are fine"},
+	}
+	for _, tt := range tests {
+		err := announce.Winner.Check(tt.text)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("Check(%q) error = %v", tt.text, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("Check(%q) error = %v, want %q", tt.text, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTemplate_Render(t *testing.T) {
+	vars := announce.Vars{"id": "a1", "winner": "Frodo", "item": "Sting", "amount": "40", "currency": "DKP"}
+	got := announce.Winner.Render("**{winner}** won {item} for {amount} {currency}, {other} stays", vars)
+	if want := "**Frodo** won Sting for 40 DKP, {other} stays"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+	if got, want := announce.Winner.Render("", vars), "Auction `a1` closed! Winner: **Frodo** with **40 DKP**"; got != want {
+		t.Errorf("Render() of no text = %q, want the default %q", got, want)
+	}
+}
@@ -0,0 +1,18 @@
+package api
+
+import (
+	"net/http"
+)
+
+type stepdownResponse struct {
+	Status string `json:"status"`
+}
+
+// stepdown serves POST /admin/stepdown.
+func (s *Server) stepdown(w http.ResponseWriter, r *http.Request) {
+	if err := s.stepDown(r.Context()); err != nil {
+		s.writeFailure(w, r, "stepping down", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stepdownResponse{Status: "stepped_down"})
+}
@@ -0,0 +1,244 @@
+// Package api serves a JSON HTTP API over DKP standings and auction data so
+// that guild websites and tools can integrate with the bot.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/export"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// Server handles API requests.
+type Server struct {
+	cfg     config.APIConfig
+	players store.PlayerRepository
+	events  event.Store
+	archive event.Archive
+	logger  *slog.Logger
+	tracer  trace.Tracer
+
+	dkp      *dkp.Manager
+	auctions *auction.Manager
+	canWrite func() bool
+	bus      *event.Bus
+	exporter *export.Exporter
+	stepDown func(ctx context.Context) error
+}
+
+// Option configures optional Server collaborators.
+type Option func(*Server)
+
+// WithManagers enables the write endpoints, which act through the given
+// managers exactly as the equivalent slash commands do.
+func WithManagers(dkpMgr *dkp.Manager, auctionMgr *auction.Manager) Option {
+	return func(s *Server) {
+		s.dkp = dkpMgr
+		s.auctions = auctionMgr
+	}
+}
+
+// WithWriteGate makes write endpoints respond 503 Service Unavailable while
+// fn returns false. Auctions live in the memory of the leader, so replicas
+// that are not running the bot must not accept writes.
+func WithWriteGate(fn func() bool) Option {
+	return func(s *Server) { s.canWrite = fn }
+}
+
+// WithBus enables the live event stream, fed from bus.
+func WithBus(bus *event.Bus) Option {
+	return func(s *Server) { s.bus = bus }
+}
+
+// WithExporter enables the CSV export endpoint.
+func WithExporter(x *export.Exporter) Option {
+	return func(s *Server) { s.exporter = x }
+}
+
+// WithStepdown enables POST /admin/stepdown, which calls fn to hand
+// leadership over to another replica.
+func WithStepdown(fn func(ctx context.Context) error) Option {
+	return func(s *Server) { s.stepDown = fn }
+}
+
+// NewServer returns a new API Server. archive may be nil, in which case
+// archived auctions are reported as not found.
+func NewServer(cfg config.APIConfig, players store.PlayerRepository, events event.Store, archive event.Archive, logger *slog.Logger, tp trace.TracerProvider, opts ...Option) *Server {
+	s := &Server{
+		cfg:     cfg,
+		players: players,
+		events:  events,
+		archive: archive,
+		logger:  logger,
+		tracer:  tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/api"),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Register mounts the API routes on mux under /api/v1/. The stream and
+// export routes are only mounted when the Server was built WithBus and
+// WithExporter respectively, and write routes only when it was built
+// WithManagers. POST /admin/stepdown is mounted when it was built
+// WithStepdown, and the streaming overlay under /overlay when it was built
+// WithBus.
+func (s *Server) Register(mux *http.ServeMux) {
+	mux.Handle("GET /api/v1/players", s.authenticated(config.ScopeRead, s.listPlayers))
+	mux.Handle("GET /api/v1/players/{id}/history", s.authenticated(config.ScopeRead, s.playerHistory))
+	mux.Handle("GET /api/v1/auctions/{id}", s.authenticated(config.ScopeRead, s.getAuction))
+	if s.bus != nil {
+		mux.Handle("GET /api/v1/stream", s.authenticated(config.ScopeRead, s.stream))
+		mux.Handle("GET /overlay", withQueryKey(s.authenticated(config.ScopeRead, s.overlay)))
+		mux.Handle("GET /overlay/stream", withQueryKey(s.authenticated(config.ScopeRead, s.overlayStream)))
+	}
+	if s.exporter != nil {
+		mux.Handle("GET /api/v1/export/{kind}", s.authenticated(config.ScopeRead, s.exportFile))
+	}
+	if s.stepDown != nil {
+		mux.Handle("POST /admin/stepdown", s.authenticated(config.ScopeAdmin, s.stepdown))
+	}
+
+	if s.dkp == nil || s.auctions == nil {
+		return
+	}
+	mux.Handle("POST /api/v1/players", s.authenticated(config.ScopePlayersWrite, s.writable(s.registerPlayer)))
+	mux.Handle("POST /api/v1/players/{id}/dkp", s.authenticated(config.ScopeDKPWrite, s.writable(s.adjustDKP)))
+	mux.Handle("POST /api/v1/auctions", s.authenticated(config.ScopeAuctionWrite, s.writable(s.startAuction)))
+	mux.Handle("POST /api/v1/auctions/{id}/close", s.authenticated(config.ScopeAuctionWrite, s.writable(s.closeAuction)))
+}
+
+// authenticated wraps h so that it only runs for requests carrying a
+// configured API key that grants scope, presented either as a bearer token
+// or in the X-API-Key header.
+//
+// The key name is recorded as the event actor, prefixed with "api:", and
+// an Idempotency-Key header is honored the same way as a Discord
+// interaction ID.
+func (s *Server) authenticated(scope string, h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := s.tracer.Start(r.Context(), r.Pattern)
+		defer span.End()
+
+		key, ok := s.authenticate(r)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "missing or invalid API key")
+			return
+		}
+		span.SetAttributes(attribute.String("api.key", key.Name))
+		if !key.HasScope(scope) {
+			writeError(w, http.StatusForbidden, "API key lacks scope "+scope)
+			return
+		}
+
+		ctx = context.WithValue(ctx, keyCtx{}, key)
+		ctx = event.WithActor(ctx, "api:"+key.Name)
+		if k := r.Header.Get("Idempotency-Key"); k != "" {
+			ctx = idempotency.WithKey(ctx, "api:"+key.Name+":"+k)
+		}
+		h(w, r.WithContext(ctx))
+	})
+}
+
+type keyCtx struct{}
+
+// seesReserve reports whether the API key that authenticated the request
+// of ctx may see the hidden reserve price of auctions, which only admin
+// keys may: read keys are handed to public guild sites and overlays.
+func seesReserve(ctx context.Context) bool {
+	key, _ := ctx.Value(keyCtx{}).(config.APIKey)
+	return key.HasScope(config.ScopeAdmin)
+}
+
+// writable wraps h so that it is refused while the write gate is closed.
+func (s *Server) writable(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.canWrite != nil && !s.canWrite() {
+			writeError(w, http.StatusServiceUnavailable, "this replica is not accepting writes")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// authenticate returns the API key presented by r.
+func (s *Server) authenticate(r *http.Request) (config.APIKey, bool) {
+	presented := r.Header.Get("X-API-Key")
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		presented = token
+	}
+	if presented == "" {
+		return config.APIKey{}, false
+	}
+	for _, k := range s.cfg.Keys {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(k.Key)) == 1 {
+			return k, true
+		}
+	}
+	return config.APIKey{}, false
+}
+
+// page holds pagination parameters parsed from a request.
+type page struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// parsePage reads the limit and offset query parameters. The limit defaults
+// to the configured maximum page size and is capped at it.
+func (s *Server) parsePage(r *http.Request) (page, bool) {
+	p := page{Limit: s.cfg.MaxPageSize}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return p, false
+		}
+		p.Limit = min(n, s.cfg.MaxPageSize)
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return p, false
+		}
+		p.Offset = n
+	}
+	return p, true
+}
+
+// listResponse is the envelope for paginated collections.
+type listResponse[T any] struct {
+	Items []T `json:"items"`
+	page
+	// Total is the total number of items, when known.
+	Total *int `json:"total,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, errorResponse{Error: msg})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,552 @@
+package api_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/api"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/export"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/leader"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+const testKey = "test-key"
+
+func newTestServer(t *testing.T) *http.ServeMux {
+	t.Helper()
+	players := storetest.NewPlayers(
+		store.Player{ID: "p1", DiscordID: "d1", CharacterName: "Gandalf", DKP: 300},
+		store.Player{ID: "p2", DiscordID: "d2", CharacterName: "Frodo", DKP: 200},
+		store.Player{ID: "p3", DiscordID: "d3", CharacterName: "Sam", DKP: 100},
+	)
+	events := eventtest.NewStore(eventtest.WithEvents(
+		event.Event{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{"player_id":"p1","amount":300}`), Version: 1},
+		event.Event{AggregateID: "auction-1", Type: event.AuctionStarted, Data: json.RawMessage(`{"item_name":"Sword","min_bid":10}`), Version: 1},
+		event.Event{AggregateID: "auction-1", Type: event.AuctionBidPlaced, Data: json.RawMessage(`{"player_id":"p1","amount":50}`), Version: 2},
+	))
+
+	cfg := config.APIConfig{
+		Enabled:     true,
+		Keys:        []config.APIKey{{Name: "website", Key: testKey}},
+		MaxPageSize: 2,
+	}
+	mux := http.NewServeMux()
+	api.NewServer(cfg, players, events, nil, slog.Default(), noop.NewTracerProvider()).Register(mux)
+	return mux
+}
+
+func TestServer_Auth(t *testing.T) {
+	mux := newTestServer(t)
+
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		wantCode int
+	}{
+		{name: "no key", wantCode: http.StatusUnauthorized},
+		{name: "wrong key", header: "X-API-Key", value: "nope", wantCode: http.StatusUnauthorized},
+		{name: "x-api-key header", header: "X-API-Key", value: testKey, wantCode: http.StatusOK},
+		{name: "bearer token", header: "Authorization", value: "Bearer " + testKey, wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/players", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("got status %d, want %d", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func get(t *testing.T, mux *http.ServeMux, target string, v any) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set("X-API-Key", testKey)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if v != nil && rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+			t.Fatalf("decoding %s: %v", target, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestServer_ListPlayers_Pagination(t *testing.T) {
+	mux := newTestServer(t)
+
+	var resp struct {
+		Items []struct {
+			CharacterName string `json:"character_name"`
+		} `json:"items"`
+		Limit  int `json:"limit"`
+		Offset int `json:"offset"`
+		Total  int `json:"total"`
+	}
+
+	if code := get(t, mux, "/api/v1/players?limit=50", &resp); code != http.StatusOK {
+		t.Fatalf("got status %d", code)
+	}
+	if resp.Limit != 2 || len(resp.Items) != 2 || resp.Total != 3 {
+		t.Errorf("first page = %+v, want 2 of 3 items with limit capped at 2", resp)
+	}
+
+	if code := get(t, mux, "/api/v1/players?offset=2", &resp); code != http.StatusOK {
+		t.Fatalf("got status %d", code)
+	}
+	if len(resp.Items) != 1 || resp.Items[0].CharacterName != "Sam" {
+		t.Errorf("second page = %+v, want [Sam]", resp.Items)
+	}
+
+	if code := get(t, mux, "/api/v1/players?limit=abc", nil); code != http.StatusBadRequest {
+		t.Errorf("invalid limit status = %d, want %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestServer_PlayerHistory(t *testing.T) {
+	mux := newTestServer(t)
+
+	var resp struct {
+		Items []struct {
+			Type event.Type `json:"type"`
+		} `json:"items"`
+	}
+	if code := get(t, mux, "/api/v1/players/p1/history", &resp); code != http.StatusOK {
+		t.Fatalf("got status %d", code)
+	}
+	if len(resp.Items) != 1 || resp.Items[0].Type != event.DKPAwarded {
+		t.Errorf("history = %+v, want one dkp.awarded event", resp.Items)
+	}
+}
+
+func TestServer_GetAuction(t *testing.T) {
+	mux := newTestServer(t)
+
+	var resp struct {
+		ItemName   string `json:"item_name"`
+		Status     string `json:"status"`
+		HighestBid struct {
+			Amount int `json:"amount"`
+		} `json:"highest_bid"`
+	}
+	if code := get(t, mux, "/api/v1/auctions/auction-1", &resp); code != http.StatusOK {
+		t.Fatalf("got status %d", code)
+	}
+	if resp.ItemName != "Sword" || resp.Status != "open" || resp.HighestBid.Amount != 50 {
+		t.Errorf("auction = %+v, want open Sword with highest bid 50", resp)
+	}
+
+	if code := get(t, mux, "/api/v1/auctions/missing", nil); code != http.StatusNotFound {
+		t.Errorf("missing auction status = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+const (
+	readOnlyKey = "read-only-key"
+	raidToolKey = "raid-tool-key"
+)
+
+func newWriteServer(t *testing.T, writable bool) (*http.ServeMux, *storetest.Players, *eventtest.Store) {
+	t.Helper()
+	players := storetest.NewPlayers(store.Player{ID: "p1", DiscordID: "d1", CharacterName: "Gandalf", DKP: 100})
+	events := eventtest.NewStore()
+	tp := noop.NewTracerProvider()
+	dkpMgr := dkp.NewManager(players, events, slog.Default(), tp)
+	auctionMgr := auction.NewManager(events, players, slog.Default(), tp, clock.Mock{T: time.Now()})
+
+	cfg := config.APIConfig{
+		Enabled: true,
+		Keys: []config.APIKey{
+			{Name: "website", Key: readOnlyKey},
+			{Name: "raidtool", Key: raidToolKey, Scopes: []string{config.ScopeDKPWrite, config.ScopeAuctionWrite}},
+		},
+		MaxPageSize: 100,
+	}
+	mux := http.NewServeMux()
+	api.NewServer(cfg, players, events, nil, slog.Default(), tp,
+		api.WithManagers(dkpMgr, auctionMgr),
+		api.WithWriteGate(func() bool { return writable }),
+	).Register(mux)
+	return mux, players, events
+}
+
+func post(mux *http.ServeMux, key, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+key)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServer_WriteScopes(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		target   string
+		body     string
+		wantCode int
+	}{
+		{name: "read-only key cannot award", key: readOnlyKey, target: "/api/v1/players/p1/dkp", body: `{"amount":10}`, wantCode: http.StatusForbidden},
+		{name: "scoped key awards", key: raidToolKey, target: "/api/v1/players/p1/dkp", body: `{"amount":10,"reason":"boss kill"}`, wantCode: http.StatusNoContent},
+		{name: "zero amount rejected", key: raidToolKey, target: "/api/v1/players/p1/dkp", body: `{"amount":0}`, wantCode: http.StatusBadRequest},
+		{name: "unknown field rejected", key: raidToolKey, target: "/api/v1/players/p1/dkp", body: `{"amount":10,"bonus":5}`, wantCode: http.StatusBadRequest},
+		{name: "missing players scope", key: raidToolKey, target: "/api/v1/players", body: `{"discord_id":"d2","character_name":"Frodo"}`, wantCode: http.StatusForbidden},
+		{name: "scoped key starts auction", key: raidToolKey, target: "/api/v1/auctions", body: `{"item_name":"Sword","min_bid":10,"duration":"5m"}`, wantCode: http.StatusCreated},
+		{name: "auction without duration uses the default", key: raidToolKey, target: "/api/v1/auctions", body: `{"item_name":"Shield"}`, wantCode: http.StatusCreated},
+		{name: "invalid duration rejected", key: raidToolKey, target: "/api/v1/auctions", body: `{"item_name":"Sword","duration":"soon"}`, wantCode: http.StatusBadRequest},
+		{name: "closing unknown auction is not found", key: raidToolKey, target: "/api/v1/auctions/missing/close", wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux, _, _ := newWriteServer(t, true)
+			if rec := post(mux, tt.key, tt.target, tt.body); rec.Code != tt.wantCode {
+				t.Errorf("got status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+		})
+	}
+}
+
+func TestServer_AdjustDKP_RecordsActor(t *testing.T) {
+	mux, players, events := newWriteServer(t, true)
+
+	if rec := post(mux, raidToolKey, "/api/v1/players/p1/dkp", `{"amount":-30,"reason":"penalty"}`); rec.Code != http.StatusNoContent {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+	}
+	players.RequireDKP(t, "d1", 70)
+	events.RequireTypes(t, event.DKPDeducted)
+	if got := events.Last(t).Actor; got != "api:raidtool" {
+		t.Errorf("got actor %q, want %q", got, "api:raidtool")
+	}
+}
+
+func TestServer_WriteGateClosed(t *testing.T) {
+	mux, _, _ := newWriteServer(t, false)
+
+	if rec := post(mux, raidToolKey, "/api/v1/players/p1/dkp", `{"amount":10}`); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestServer_Stream(t *testing.T) {
+	bus := event.NewBus()
+	cfg := config.APIConfig{
+		Enabled:     true,
+		Keys:        []config.APIKey{{Name: "overlay", Key: testKey}},
+		MaxPageSize: 100,
+	}
+	mux := http.NewServeMux()
+	api.NewServer(cfg, storetest.NewPlayers(), eventtest.NewStore(), nil, slog.Default(), noop.NewTracerProvider(),
+		api.WithBus(bus),
+	).Register(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/v1/stream?types=auction.bid_placed", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-API-Key", testKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("got content type %q, want text/event-stream", ct)
+	}
+
+	// The handler subscribes before sending headers, so events appended
+	// now are delivered as stored; the filtered-out start event must not
+	// be.
+	at := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+	events := event.NewPublishingStore(eventtest.NewStore(eventtest.WithClock(clock.Mock{T: at})), bus)
+	if err := events.Append(event.WithActor(context.Background(), "officer"),
+		event.Event{AggregateID: "auction-1", Type: event.AuctionStarted},
+		event.Event{AggregateID: "auction-1", Type: event.AuctionBidPlaced, Data: json.RawMessage(`{"amount":50}`)},
+	); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	r := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 3 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream: %v", err)
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+
+	if lines[0] != "id: auction-1:2" || lines[1] != "event: auction.bid_placed" {
+		t.Errorf("got frame header %q, want bid event for auction-1 version 2", lines[:2])
+	}
+	for _, want := range []string{`"id":"evt-2"`, `"amount":50`, `"actor":"officer"`, `"created_at":"2025-06-15T20:00:00Z"`} {
+		if !strings.HasPrefix(lines[2], "data: ") || !strings.Contains(lines[2], want) {
+			t.Errorf("got data line %q, want the stored bid with %s", lines[2], want)
+		}
+	}
+}
+
+func TestServer_HidesReserve(t *testing.T) {
+	const adminKey = "admin-key"
+	bus := event.NewBus()
+	events := event.NewPublishingStore(eventtest.NewStore(), bus)
+	started := event.Event{AggregateID: "auction-1", Type: event.AuctionStarted, Data: json.RawMessage(`{"item_name":"Sword","min_bid":10,"reserve":80}`)}
+	if err := events.Append(context.Background(), started); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	cfg := config.APIConfig{
+		Enabled: true,
+		Keys: []config.APIKey{
+			{Name: "website", Key: testKey},
+			{Name: "ops", Key: adminKey, Scopes: []string{config.ScopeRead, config.ScopeAdmin}},
+		},
+		MaxPageSize: 100,
+	}
+	mux := http.NewServeMux()
+	api.NewServer(cfg, storetest.NewPlayers(), events, nil, slog.Default(), noop.NewTracerProvider(),
+		api.WithBus(bus),
+	).Register(mux)
+
+	for key, want := range map[string]bool{testKey: false, adminKey: true} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/auctions/auction-1", nil)
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+		}
+		if got := strings.Contains(rec.Body.String(), `"reserve":80`); got != want {
+			t.Errorf("auction for key %s = %s, want reserve shown %t", key, rec.Body, want)
+		}
+	}
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/v1/stream?types=auction.started", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-API-Key", testKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := events.Append(context.Background(), event.Event{AggregateID: "auction-2", Type: event.AuctionStarted, Data: started.Data}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	r := bufio.NewReader(resp.Body)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream: %v", err)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			if strings.Contains(data, "reserve") || !strings.Contains(data, `"item_name":"Sword"`) {
+				t.Errorf("streamed start = %s, want the auction without its reserve", data)
+			}
+			break
+		}
+	}
+}
+
+func TestServer_Overlay(t *testing.T) {
+	bus := event.NewBus()
+	players := storetest.NewPlayers(
+		store.Player{ID: "p1", DiscordID: "d1", CharacterName: "Gandalf", DKP: 300},
+		store.Player{ID: "p2", DiscordID: "d2", CharacterName: "Frodo", DKP: 200},
+	)
+	events := eventtest.NewStore(eventtest.WithEvents(
+		event.Event{AggregateID: "auction-1", Type: event.AuctionStarted, Data: json.RawMessage(`{"item_name":"Sword","min_bid":10}`), Version: 1},
+		event.Event{AggregateID: "auction-1", Type: event.AuctionBidPlaced, Data: json.RawMessage(`{"player_id":"p1","amount":50}`), Version: 2},
+	))
+	cfg := config.APIConfig{Enabled: true, Keys: []config.APIKey{{Name: "overlay", Key: testKey}}, MaxPageSize: 100}
+	mux := http.NewServeMux()
+	api.NewServer(cfg, players, events, nil, slog.Default(), noop.NewTracerProvider(),
+		api.WithBus(bus),
+	).Register(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/overlay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("overlay without key: got status %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+	resp, err = http.Get(srv.URL + "/overlay?key=" + testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("overlay page: got status %d and content type %q, want an HTML page", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/overlay/stream?key="+testKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+	next := func() string {
+		t.Helper()
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading stream: %v", err)
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				return data
+			}
+		}
+	}
+
+	if got := next(); !strings.Contains(got, `"item_name":"Sword"`) || !strings.Contains(got, `"top_bid":50,"bidder":"Gandalf"`) {
+		t.Errorf("initial state = %s, want Gandalf's bid on the Sword", got)
+	}
+
+	// Bids on other auctions than the current one are not shown.
+	outbid := event.Event{AggregateID: "auction-1", Type: event.AuctionBidPlaced, Data: json.RawMessage(`{"player_id":"p2","amount":60}`), Version: 3}
+	other := event.Event{AggregateID: "auction-0", Type: event.AuctionBidPlaced, Data: json.RawMessage(`{"player_id":"p1","amount":5}`), Version: 2}
+	_ = events.Append(context.Background(), other, outbid)
+	bus.Publish(context.Background(), other, outbid)
+	if got := next(); !strings.Contains(got, `"top_bid":60,"bidder":"Frodo"`) || !strings.Contains(got, `"status":"open"`) {
+		t.Errorf("state after a bid = %s, want Frodo's bid", got)
+	}
+}
+
+func TestServer_StreamInvalidTypes(t *testing.T) {
+	cfg := config.APIConfig{Enabled: true, Keys: []config.APIKey{{Name: "overlay", Key: testKey}}, MaxPageSize: 100}
+	mux := http.NewServeMux()
+	api.NewServer(cfg, storetest.NewPlayers(), eventtest.NewStore(), nil, slog.Default(), noop.NewTracerProvider(),
+		api.WithBus(event.NewBus()),
+	).Register(mux)
+
+	if code := get(t, mux, "/api/v1/stream?types=player.registered", nil); code != http.StatusBadRequest {
+		t.Errorf("got status %d, want %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestServer_Export(t *testing.T) {
+	players := storetest.NewPlayers(store.Player{ID: "p1", DiscordID: "d1", CharacterName: "Gandalf", DKP: 300})
+	events := eventtest.NewStore()
+	tp := noop.NewTracerProvider()
+	cfg := config.APIConfig{Enabled: true, Keys: []config.APIKey{{Name: "sheet", Key: testKey}}, MaxPageSize: 100}
+	mux := http.NewServeMux()
+	api.NewServer(cfg, players, events, nil, slog.Default(), tp,
+		api.WithExporter(export.NewExporter(players, events, tp)),
+	).Register(mux)
+
+	tests := []struct {
+		name     string
+		target   string
+		wantCode int
+		wantBody string
+	}{
+		{name: "standings", target: "/api/v1/export/standings", wantCode: http.StatusOK, wantBody: "1,Gandalf,d1,p1,300\n"},
+		{name: "unknown kind", target: "/api/v1/export/loot", wantCode: http.StatusNotFound},
+		{name: "addon standings", target: "/api/v1/export/standings?format=monolithdkp", wantCode: http.StatusOK, wantBody: "}\n"},
+		{name: "addon transactions rejected", target: "/api/v1/export/transactions?format=monolithdkp", wantCode: http.StatusBadRequest},
+		{name: "bad range", target: "/api/v1/export/transactions?from=yesterday", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Header.Set("X-API-Key", testKey)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" {
+				if rec.Header().Get("Content-Disposition") == "" {
+					t.Error("missing Content-Disposition header")
+				}
+				if !strings.HasSuffix(rec.Body.String(), tt.wantBody) {
+					t.Errorf("got body %q, want suffix %q", rec.Body.String(), tt.wantBody)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_Stepdown(t *testing.T) {
+	const adminKey = "admin-key"
+	tests := []struct {
+		name      string
+		key       string
+		err       error
+		wantCode  int
+		wantCalls int
+	}{
+		{name: "admin steps down", key: adminKey, wantCode: http.StatusOK, wantCalls: 1},
+		{name: "not the leader", key: adminKey, err: leader.ErrNotLeader, wantCode: http.StatusConflict, wantCalls: 1},
+		{name: "missing admin scope", key: testKey, wantCode: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			cfg := config.APIConfig{
+				Enabled: true,
+				Keys: []config.APIKey{
+					{Name: "website", Key: testKey},
+					{Name: "ops", Key: adminKey, Scopes: []string{config.ScopeAdmin}},
+				},
+				MaxPageSize: 10,
+			}
+			mux := http.NewServeMux()
+			api.NewServer(cfg, storetest.NewPlayers(), eventtest.NewStore(), nil, slog.Default(), noop.NewTracerProvider(),
+				api.WithStepdown(func(context.Context) error {
+					calls++
+					return tt.err
+				}),
+			).Register(mux)
+
+			if rec := post(mux, tt.key, "/admin/stepdown", ""); rec.Code != tt.wantCode {
+				t.Errorf("got status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("stepdown called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
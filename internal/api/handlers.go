@@ -0,0 +1,216 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/export"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+type playerResponse struct {
+	ID            string    `json:"id"`
+	DiscordID     string    `json:"discord_id"`
+	CharacterName string    `json:"character_name"`
+	DKP           int       `json:"dkp"`
+	Class         string    `json:"class,omitempty"`
+	Role          string    `json:"role,omitempty"`
+	Spec          string    `json:"spec,omitempty"`
+	Timezone      string    `json:"timezone,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// ArchivedAt is set for archived players, whose DKP is frozen.
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+func newPlayerResponse(p store.Player) playerResponse {
+	return playerResponse{
+		ID:            p.ID,
+		DiscordID:     p.DiscordID,
+		CharacterName: p.CharacterName,
+		DKP:           p.DKP,
+		Class:         p.Class,
+		Role:          p.Role,
+		Spec:          p.Spec,
+		Timezone:      p.Timezone,
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.UpdatedAt,
+		ArchivedAt:    p.ArchivedAt,
+	}
+}
+
+type eventResponse struct {
+	ID          string          `json:"id"`
+	AggregateID string          `json:"aggregate_id"`
+	Type        event.Type      `json:"type"`
+	Data        json.RawMessage `json:"data"`
+	Version     int             `json:"version"`
+	Actor       string          `json:"actor,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func newEventResponse(e event.Event) eventResponse {
+	return eventResponse{
+		ID:          e.ID,
+		AggregateID: e.AggregateID,
+		Type:        e.Type,
+		Data:        e.Data,
+		Version:     e.Version,
+		Actor:       e.Actor,
+		CreatedAt:   e.CreatedAt,
+	}
+}
+
+type auctionResponse struct {
+	auction.State
+	HighestBid *auction.Bid `json:"highest_bid"`
+	Archived   bool         `json:"archived"`
+}
+
+// newAuctionResponse returns the response for the auction in state st,
+// without its reserve unless the request of ctx may see it.
+func newAuctionResponse(ctx context.Context, st auction.State, archived bool) auctionResponse {
+	if !seesReserve(ctx) {
+		st.Reserve = 0
+	}
+	return auctionResponse{State: st, HighestBid: st.HighestBid(), Archived: archived}
+}
+
+// listPlayers serves GET /api/v1/players, ordered by DKP descending.
+func (s *Server) listPlayers(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.parsePage(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid limit or offset")
+		return
+	}
+
+	players, err := s.players.List(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "api: listing players", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "listing players failed")
+		return
+	}
+
+	total := len(players)
+	start := min(p.Offset, total)
+	end := min(start+p.Limit, total)
+
+	items := make([]playerResponse, 0, end-start)
+	for _, pl := range players[start:end] {
+		items = append(items, newPlayerResponse(pl))
+	}
+	writeJSON(w, http.StatusOK, listResponse[playerResponse]{Items: items, page: p, Total: &total})
+}
+
+// playerHistory serves GET /api/v1/players/{id}/history, newest first.
+func (s *Server) playerHistory(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.parsePage(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid limit or offset")
+		return
+	}
+
+	events, err := s.events.Query(r.Context(), event.Query{
+		AggregateID: r.PathValue("id"),
+		Limit:       p.Limit,
+		Offset:      p.Offset,
+	})
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "api: querying player history", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "loading history failed")
+		return
+	}
+
+	items := make([]eventResponse, 0, len(events))
+	for _, e := range events {
+		items = append(items, newEventResponse(e))
+	}
+	writeJSON(w, http.StatusOK, listResponse[eventResponse]{Items: items, page: p})
+}
+
+// getAuction serves GET /api/v1/auctions/{id}. The auction is replayed from
+// the event log, falling back to its snapshot if it has been archived.
+func (s *Server) getAuction(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	events, err := s.events.Load(r.Context(), id)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "api: loading auction", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "loading auction failed")
+		return
+	}
+
+	if len(events) > 0 {
+		a, err := auction.Replay(events)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "api: replaying auction", slog.Any("error", err))
+			writeError(w, http.StatusInternalServerError, "replaying auction failed")
+			return
+		}
+		writeJSON(w, http.StatusOK, newAuctionResponse(r.Context(), a.State(), false))
+		return
+	}
+
+	if s.archive != nil {
+		if snap, err := s.archive.LoadSnapshot(r.Context(), id); err == nil {
+			var state auction.State
+			if err := json.Unmarshal(snap.State, &state); err == nil {
+				writeJSON(w, http.StatusOK, newAuctionResponse(r.Context(), state, true))
+				return
+			}
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "auction not found")
+}
+
+// exportFile serves GET /api/v1/export/{kind} as a file attachment. The optional
+// from and to query parameters bound CSV exports by day, as YYYY-MM-DD, and
+// format selects an in-game addon file instead of CSV for standings.
+func (s *Server) exportFile(w http.ResponseWriter, r *http.Request) {
+	kind := export.Kind(r.PathValue("kind"))
+	if !slices.Contains(export.Kinds, kind) {
+		writeError(w, http.StatusNotFound, "unknown export kind")
+		return
+	}
+	rng, err := export.ParseRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format := export.CSV
+	if v := r.URL.Query().Get("format"); v != "" {
+		format = export.Format(v)
+	}
+	if format != export.CSV && (!format.IsAddon() || kind != export.Standings) {
+		writeError(w, http.StatusBadRequest, "unsupported format for "+string(kind))
+		return
+	}
+
+	// Render to a buffer first so that a failure can still be reported
+	// with an error status.
+	var buf bytes.Buffer
+	contentType := "text/csv"
+	if format.IsAddon() {
+		contentType = "text/plain; charset=utf-8"
+		err = s.exporter.WriteAddon(r.Context(), &buf, format)
+	} else {
+		err = s.exporter.Write(r.Context(), &buf, kind, rng)
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "api: exporting", slog.String("kind", string(kind)), slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "export failed")
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+export.Filename(kind, format)+`"`)
+	_, _ = buf.WriteTo(w)
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// overlayTypes are the event types that change what the overlay shows.
+var overlayTypes = []event.Type{
+	event.AuctionStarted,
+	event.AuctionBidPlaced,
+	event.AuctionClosed,
+	event.AuctionCanceled,
+	event.AuctionBoughtOut,
+	event.AuctionRollStarted,
+	event.AuctionRolled,
+	event.AuctionWinnerSkipped,
+	event.AuctionPaused,
+	event.AuctionResumed,
+}
+
+// overlayState is what the overlay shows of the current auction: the
+// auction started last.
+type overlayState struct {
+	AuctionID string `json:"auction_id,omitempty"`
+	ItemName  string `json:"item_name,omitempty"`
+	// Status is the auction's status, or empty before the first auction.
+	Status   string `json:"status,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	MinBid   int    `json:"min_bid,omitempty"`
+	// TopBid is the highest bid and Bidder the character who placed it, or
+	// the winner once the auction closed. Bidder is empty without bids or
+	// if the reserve was not met.
+	TopBid int    `json:"top_bid,omitempty"`
+	Bidder string `json:"bidder,omitempty"`
+	// EndsAt is when the auction ends while it is open, or when its roll
+	// ends while it is rolling.
+	EndsAt time.Time `json:"ends_at,omitzero"`
+}
+
+// withQueryKey wraps h so that it also accepts the API key as the key query
+// parameter, since browser sources of streaming software cannot send
+// headers. It is only used for the overlay, whose URLs are pasted into
+// such software.
+func withQueryKey(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if k := r.URL.Query().Get("key"); k != "" && r.Header.Get("X-API-Key") == "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("X-API-Key", k)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// overlay serves GET /overlay, a page for the browser source of streaming
+// software such as OBS that shows the current auction, its top bid, and a
+// countdown in large text on a transparent background. It follows
+// /overlay/stream, passing on its own query parameters.
+func (s *Server) overlay(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = io.WriteString(w, overlayPage)
+}
+
+// overlayStream serves GET /overlay/stream as Server-Sent Events. Each
+// event is an overlayState named "state": one when the client connects,
+// and another whenever the current auction changes.
+func (s *Server) overlayStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	ctx := r.Context()
+
+	// As for /api/v1/stream, the subscriber must never block; a client
+	// that falls behind is disconnected and reconnects on its own.
+	ch := make(chan event.Event, streamBuffer)
+	overflow := make(chan struct{})
+	var once sync.Once
+	unsubscribe := s.bus.Subscribe(func(_ context.Context, e event.Event) {
+		select {
+		case ch <- e:
+		default:
+			once.Do(func() { close(overflow) })
+		}
+	}, overlayTypes...)
+	defer unsubscribe()
+
+	var current string
+	started, err := s.events.Query(ctx, event.Query{Types: []event.Type{event.AuctionStarted}, Limit: 1})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "api: finding current auction", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "finding current auction failed")
+		return
+	}
+	if len(started) > 0 {
+		current = started[0].AggregateID
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	s.sendOverlayState(ctx, w, current)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-overflow:
+			return
+		case e := <-ch:
+			if e.Type == event.AuctionStarted {
+				current = e.AggregateID
+			}
+			if e.AggregateID != current {
+				continue
+			}
+			s.sendOverlayState(ctx, w, current)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// sendOverlayState writes the state of the auction auctionID, replayed
+// from its events, as an SSE event. An empty auctionID sends an empty
+// state. Auctions that cannot be replayed are logged and skipped.
+func (s *Server) sendOverlayState(ctx context.Context, w io.Writer, auctionID string) {
+	var st overlayState
+	if auctionID != "" {
+		var err error
+		if st, err = s.overlayState(ctx, auctionID); err != nil {
+			s.logger.WarnContext(ctx, "api: replaying auction for overlay",
+				slog.String("auction_id", auctionID),
+				slog.Any("error", err),
+			)
+			return
+		}
+	}
+	data, _ := json.Marshal(st)
+	fmt.Fprintf(w, "event: state\ndata: %s\n\n", data)
+}
+
+// overlayState replays the auction auctionID for the overlay.
+func (s *Server) overlayState(ctx context.Context, auctionID string) (overlayState, error) {
+	events, err := s.events.Load(ctx, auctionID)
+	if err != nil {
+		return overlayState{}, fmt.Errorf("loading auction: %w", err)
+	}
+	if len(events) == 0 {
+		return overlayState{}, fmt.Errorf("auction %s has no events", auctionID)
+	}
+	a, err := auction.Replay(events)
+	if err != nil {
+		return overlayState{}, err
+	}
+	st := a.State()
+	o := overlayState{
+		AuctionID: st.ID,
+		ItemName:  st.ItemName,
+		Status:    st.Status,
+		Currency:  st.Currency(),
+		MinBid:    st.MinBid,
+	}
+	switch st.Status {
+	case "open":
+		o.EndsAt = a.EndsAt()
+	case "rolling":
+		o.EndsAt = st.RollUntil
+	}
+
+	var bidderID string
+	if b := a.HighestBid(); b != nil {
+		o.TopBid, bidderID = b.Amount, b.PlayerID
+	}
+	if r := a.HighestRoll(); r != nil && st.Status == "closed" {
+		// An auction that ended without bids was rolled for, and the
+		// highest roll won it.
+		bidderID = r.PlayerID
+	}
+	if bidderID == "" || st.ReserveNotMet {
+		return o, nil
+	}
+	o.Bidder = bidderID
+	players, err := s.players.List(ctx)
+	if err != nil {
+		return overlayState{}, fmt.Errorf("listing players: %w", err)
+	}
+	for _, p := range players {
+		if p.ID == bidderID {
+			o.Bidder = p.CharacterName
+		}
+	}
+	return o, nil
+}
+
+// overlayPage is the page GET /overlay serves. Its background is
+// transparent, so that streaming software shows it over the game.
+const overlayPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Auction overlay</title>
+<style>
+  html, body { margin: 0; background: transparent; }
+  body {
+    font-family: "Segoe UI", Helvetica, Arial, sans-serif;
+    color: #fff;
+    text-shadow: 0 0 6px #000, 0 0 2px #000;
+    padding: 24px;
+  }
+  #overlay[hidden] { display: none; }
+  #item { font-size: 64px; font-weight: 700; color: #a335ee; }
+  #bid { font-size: 48px; }
+  #time { font-size: 40px; color: #ffd100; }
+</style>
+</head>
+<body>
+<div id="overlay" hidden>
+  <div id="item"></div>
+  <div id="bid"></div>
+  <div id="time"></div>
+</div>
+<script>
+  const overlay = document.getElementById("overlay");
+  const item = document.getElementById("item");
+  const bid = document.getElementById("bid");
+  const time = document.getElementById("time");
+  let state = {};
+
+  function countdown() {
+    if (!state.ends_at) {
+      return "";
+    }
+    const left = Math.max(0, Math.round((Date.parse(state.ends_at) - Date.now()) / 1000));
+    const clock = Math.floor(left / 60) + ":" + String(left % 60).padStart(2, "0");
+    return state.status === "rolling" ? "Roll ends in " + clock : clock;
+  }
+
+  function render() {
+    overlay.hidden = !state.status;
+    item.textContent = state.item_name || "";
+    const price = (state.top_bid || 0) + " " + (state.currency || "DKP");
+    switch (state.status) {
+    case "closed":
+      bid.textContent = state.bidder ? "Won by " + state.bidder + " for " + price : "No winner";
+      time.textContent = "";
+      break;
+    case "canceled":
+      bid.textContent = "Canceled";
+      time.textContent = "";
+      break;
+    case "paused":
+      bid.textContent = state.bidder ? state.bidder + ": " + price : "No bids yet";
+      time.textContent = "Paused";
+      break;
+    default:
+      bid.textContent = state.bidder ? state.bidder + ": " + price : "Bids from " + state.min_bid + " " + (state.currency || "DKP");
+      time.textContent = countdown();
+    }
+  }
+
+  const stream = new EventSource("overlay/stream" + location.search);
+  stream.addEventListener("state", (e) => {
+    state = JSON.parse(e.data);
+    render();
+  });
+  setInterval(render, 250);
+</script>
+</body>
+</html>
+`
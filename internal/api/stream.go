@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// streamTypes are the event types a stream client may subscribe to.
+var streamTypes = []event.Type{
+	event.AuctionScheduled,
+	event.AuctionQueued,
+	event.AuctionStarted,
+	event.AuctionBidPlaced,
+	event.AuctionClosed,
+	event.AuctionCanceled,
+	event.AuctionBoughtOut,
+	event.AuctionRollStarted,
+	event.AuctionRolled,
+	event.AuctionWinnerSkipped,
+	event.AuctionPaused,
+	event.AuctionResumed,
+	event.AuctionTaxed,
+	event.DKPAwarded,
+	event.DKPDeducted,
+	event.DKPAdjusted,
+}
+
+// reserveTypes are the stream types whose data, an AuctionStartedData,
+// holds the auction's hidden reserve price.
+var reserveTypes = []event.Type{event.AuctionScheduled, event.AuctionQueued, event.AuctionStarted}
+
+const (
+	// streamBuffer is how many events may be queued for a stream client
+	// before it is considered too slow and disconnected.
+	streamBuffer = 64
+	// keepAliveInterval is how often an idle stream sends a comment so that
+	// proxies do not time the connection out.
+	keepAliveInterval = 15 * time.Second
+)
+
+// stream serves GET /api/v1/stream as Server-Sent Events. Each event is
+// sent with its type as the SSE event name and its JSON encoding as data.
+// The optional types query parameter is a comma-separated subset of
+// streamTypes; by default all of them are sent.
+//
+// Events are delivered from this replica's bus only, so clients should
+// connect to the replica running the bot.
+func (s *Server) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	types, ok := parseStreamTypes(r.URL.Query().Get("types"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid types")
+		return
+	}
+
+	// Publish is synchronous, so the subscriber must never block. A client
+	// that falls behind is disconnected and may reconnect.
+	ch := make(chan event.Event, streamBuffer)
+	overflow := make(chan struct{})
+	var once sync.Once
+	unsubscribe := s.bus.Subscribe(func(_ context.Context, e event.Event) {
+		select {
+		case ch <- e:
+		default:
+			once.Do(func() { close(overflow) })
+		}
+	}, types...)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	hidden := !seesReserve(r.Context())
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-overflow:
+			return
+		case e := <-ch:
+			if hidden {
+				e = hideReserve(e)
+			}
+			data, err := json.Marshal(newEventResponse(e))
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %s:%d\nevent: %s\ndata: %s\n\n", e.AggregateID, e.Version, e.Type, data)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// parseStreamTypes parses a comma-separated list of event types, which must
+// all be in streamTypes. An empty list selects all of streamTypes.
+func parseStreamTypes(v string) ([]event.Type, bool) {
+	if v == "" {
+		return streamTypes, true
+	}
+	var types []event.Type
+	for _, name := range strings.Split(v, ",") {
+		t := event.Type(strings.TrimSpace(name))
+		if !slices.Contains(streamTypes, t) {
+			return nil, false
+		}
+		types = append(types, t)
+	}
+	return types, true
+}
+
+// hideReserve returns e without the reserve price in its data. Data that
+// cannot be decoded is dropped rather than sent with the reserve.
+func hideReserve(e event.Event) event.Event {
+	if !slices.Contains(reserveTypes, e.Type) {
+		return e
+	}
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		e.Data = nil
+		return e
+	}
+	if _, ok := data["reserve"]; !ok {
+		return e
+	}
+	delete(data, "reserve")
+	e.Data, _ = json.Marshal(data)
+	return e
+}
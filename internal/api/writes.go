@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+type registerPlayerRequest struct {
+	DiscordID     string `json:"discord_id"`
+	CharacterName string `json:"character_name"`
+	Class         string `json:"class"`
+	Role          string `json:"role"`
+	Spec          string `json:"spec"`
+	Timezone      string `json:"timezone"`
+}
+
+type adjustDKPRequest struct {
+	// Amount is awarded when positive and deducted when negative.
+	Amount int    `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+type startAuctionRequest struct {
+	ItemName string `json:"item_name"`
+	MinBid   int    `json:"min_bid"`
+	// Duration is a Go duration string such as "5m". If empty, the guild's
+	// default auction duration is used.
+	Duration string `json:"duration"`
+	// Buyout, if positive, is the price at which a player may win the
+	// item at once.
+	Buyout int `json:"buyout"`
+	// Reserve, if positive, is the hidden lowest price the item is sold at.
+	Reserve int `json:"reserve"`
+}
+
+type closeAuctionResponse struct {
+	Result string `json:"result"`
+	// RollUntil is set if the auction had no bids and players may roll for
+	// the item until then. Closing the auction again ends the roll.
+	RollUntil time.Time `json:"roll_until,omitzero"`
+	// Started lists the IDs of the queued auctions started in the slot the
+	// auction freed.
+	Started []string `json:"started,omitempty"`
+}
+
+// registerPlayer serves POST /api/v1/players.
+func (s *Server) registerPlayer(w http.ResponseWriter, r *http.Request) {
+	var req registerPlayerRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.DiscordID == "" || req.CharacterName == "" {
+		writeError(w, http.StatusBadRequest, "discord_id and character_name are required")
+		return
+	}
+
+	p, err := s.dkp.RegisterPlayer(r.Context(), req.DiscordID, req.CharacterName, store.Profile{
+		Class:    req.Class,
+		Role:     req.Role,
+		Spec:     req.Spec,
+		Timezone: req.Timezone,
+	})
+	if err != nil {
+		s.writeFailure(w, r, "registering player", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newPlayerResponse(*p))
+}
+
+// adjustDKP serves POST /api/v1/players/{id}/dkp.
+func (s *Server) adjustDKP(w http.ResponseWriter, r *http.Request) {
+	var req adjustDKPRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Amount == 0 {
+		writeError(w, http.StatusBadRequest, "amount must not be zero")
+		return
+	}
+
+	id := r.PathValue("id")
+	var err error
+	if req.Amount > 0 {
+		err = s.dkp.AwardDKP(r.Context(), id, req.Amount, req.Reason)
+	} else {
+		err = s.dkp.DeductDKP(r.Context(), id, -req.Amount, req.Reason)
+	}
+	if err != nil {
+		s.writeFailure(w, r, "adjusting DKP", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// startAuction serves POST /api/v1/auctions.
+func (s *Server) startAuction(w http.ResponseWriter, r *http.Request) {
+	var req startAuctionRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	var duration time.Duration
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "duration must be a positive duration such as 5m")
+			return
+		}
+		duration = d
+	}
+	if req.ItemName == "" || req.MinBid < 0 {
+		writeError(w, http.StatusBadRequest, "item_name and a non-negative min_bid are required")
+		return
+	}
+
+	a, err := s.auctions.StartAuction(r.Context(), req.ItemName, event.ActorFromContext(r.Context()), req.MinBid, req.Buyout, req.Reserve, duration)
+	if err != nil {
+		s.writeFailure(w, r, "starting auction", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, auctionResponse{State: a.State(), HighestBid: a.HighestBid()})
+}
+
+// closeAuction serves POST /api/v1/auctions/{id}/close.
+func (s *Server) closeAuction(w http.ResponseWriter, r *http.Request) {
+	result, err := s.auctions.CloseAuction(r.Context(), r.PathValue("id"))
+	if err != nil {
+		s.writeFailure(w, r, "closing auction", err)
+		return
+	}
+	resp := closeAuctionResponse{Result: result.Message, RollUntil: result.RollUntil}
+	for _, a := range result.Started {
+		resp.Started = append(resp.Started, a.ID)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// decode reads the JSON request body into v, writing a 400 response and
+// returning false if it is malformed.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// writeFailure reports a failed write with a status derived from the
+// error's kind, plus the error code users can quote. Internal errors are
+// logged and answered without their details.
+func (s *Server) writeFailure(w http.ResponseWriter, r *http.Request, op string, err error) {
+	kind := derrors.KindOf(err)
+	if kind == derrors.Internal {
+		s.logger.ErrorContext(r.Context(), "api: "+op+" failed", slog.Any("error", err))
+	}
+	writeJSON(w, failureStatus[kind], errorResponse{
+		Error: op + " failed: " + derrors.MessageOf(err),
+		Code:  derrors.CodeOf(err),
+	})
+}
+
+// failureStatus maps error kinds to HTTP status codes.
+var failureStatus = map[derrors.Kind]int{
+	derrors.Internal:   http.StatusInternalServerError,
+	derrors.Validation: http.StatusUnprocessableEntity,
+	derrors.NotFound:   http.StatusNotFound,
+	derrors.Permission: http.StatusForbidden,
+	derrors.Conflict:   http.StatusConflict,
+}
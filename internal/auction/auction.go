@@ -3,9 +3,9 @@ package auction
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
@@ -14,22 +14,49 @@ import (
 	"go.opentelemetry.io/otel/trace/noop"
 
 	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/gdkp"
 )
 
 // Errors returned by auction operations.
 var (
-	ErrAuctionClosed   = errors.New("auction is closed")
-	ErrBidTooLow       = errors.New("bid is below minimum")
-	ErrSelfOutbid      = errors.New("you are already the highest bidder")
-	ErrInsufficientDKP = errors.New("insufficient DKP")
+	ErrAuctionClosed    = derrors.New(derrors.Conflict, "AUCTION_CLOSED", "auction is closed")
+	ErrBidTooLow        = derrors.New(derrors.Validation, "BID_TOO_LOW", "bid is below minimum")
+	ErrSelfOutbid       = derrors.New(derrors.Conflict, "SELF_OUTBID", "you are already the highest bidder")
+	ErrInsufficientDKP  = derrors.New(derrors.Validation, "INSUFFICIENT_DKP", "insufficient DKP")
+	ErrNoBuyout         = derrors.New(derrors.Validation, "NO_BUYOUT", "auction has no buyout price")
+	ErrBidAtBuyout      = derrors.New(derrors.Validation, "BID_AT_BUYOUT", "bid reaches the buyout price, use Buy now instead")
+	ErrInvalidBuyout    = derrors.New(derrors.Validation, "INVALID_BUYOUT", "buyout price must exceed the minimum bid")
+	ErrInvalidReserve   = derrors.New(derrors.Validation, "INVALID_RESERVE", "reserve must not be negative or above the buyout price")
+	ErrNotRolling       = derrors.New(derrors.Conflict, "NOT_ROLLING", "auction is not rolling")
+	ErrRollEnded        = derrors.New(derrors.Conflict, "ROLL_ENDED", "the roll has ended")
+	ErrAlreadyRolled    = derrors.New(derrors.Conflict, "ALREADY_ROLLED", "you have already rolled")
+	ErrAuctionPaused    = derrors.New(derrors.Conflict, "AUCTION_PAUSED", "auction is paused")
+	ErrNotPaused        = derrors.New(derrors.Conflict, "NOT_PAUSED", "auction is not paused")
+	ErrAuctionQueued    = derrors.New(derrors.Conflict, "AUCTION_QUEUED", "auction is queued and has not started yet")
+	ErrAuctionScheduled = derrors.New(derrors.Conflict, "AUCTION_SCHEDULED", "auction is scheduled and has not started yet")
 )
 
 // Bid represents a single bid in an auction.
 type Bid struct {
-	PlayerID string
-	Amount   int
-	Time     time.Time
+	PlayerID string    `json:"player_id"`
+	Amount   int       `json:"amount"`
+	Time     time.Time `json:"time"`
+}
+
+// Roll is a player's roll for the item of an auction closed without bids.
+type Roll struct {
+	PlayerID string    `json:"player_id"`
+	Value    int       `json:"value"`
+	Time     time.Time `json:"time"`
+}
+
+// Announcement is a Discord message announcing an auction.
+type Announcement struct {
+	ChannelID string `json:"channel_id"`
+	MessageID string `json:"message_id"`
 }
 
 // Auction is the aggregate root for a single item auction.
@@ -41,37 +68,255 @@ type Auction struct {
 	ItemName  string
 	StartedBy string
 	MinBid    int
-	Status    string // "open", "closed", "canceled"
-	Bids      []Bid
-	Version   int
+	// MinIncrement is how much a bid must exceed the highest bid by.
+	MinIncrement int
+	// Buyout is the price at which a player may win the item at once, or
+	// zero if the auction has none.
+	Buyout int
+	// Reserve is the hidden lowest price the item is sold at, or zero if
+	// the auction has none. ReserveNotMet is set once the auction closed
+	// without a winner because its highest bid was below it.
+	Reserve       int
+	ReserveNotMet bool
+	// RaidID is the raid the auction is held in, or empty. Unless RaidMode
+	// is gdkp.ModeDKP the raid is a GDKP raid, whose bids are in gold
+	// rather than DKP.
+	RaidID   string
+	RaidMode string
+	// Points is the currency the auction is bid in besides DKP, such as
+	// "EP", or empty for DKP. An auction in a GDKP raid has none.
+	Points   string
+	Duration time.Duration
+	Status   string // "scheduled", "queued", "open", "paused", "rolling", "closed", "canceled"
+	// StartsAt is when a scheduled auction opens, or zero if it was not
+	// scheduled.
+	StartsAt time.Time
+	Bids     []Bid
+	// Paused is how long the auction was paused in total before its
+	// current pause, if any, which began at PausedAt.
+	Paused   time.Duration
+	PausedAt time.Time
+	// Skipped lists the players whose bids were passed over at close
+	// because they could no longer afford them.
+	Skipped []string
+	// RollUntil is when the roll of a rolling auction ends, and Rolls are
+	// the rolls made so far.
+	RollUntil time.Time
+	Rolls     []Roll
+	// Announcements are the messages announcing the auction.
+	Announcements []Announcement
+	Version       int
+	StartedAt     time.Time
 
 	tracer trace.Tracer
 	clock  clock.Clock
 	events []event.Event
+	// queue orders the bids the Manager places on the auction.
+	queue bidQueue
+}
+
+// New creates a new open auction and records a started event. Bids must
+// exceed the highest bid by at least minIncrement, or by 1 if it is not
+// positive. A positive buyout lets a player win the item at that price
+// before the auction ends. The TracerProvider is used to create a scoped
+// tracer for this auction.
+func New(id, itemName, startedBy string, minBid, minIncrement, buyout int, duration time.Duration, tp trace.TracerProvider, clk clock.Clock) *Auction {
+	return newAuction(id, itemName, startedBy, "", "", "", minBid, minIncrement, buyout, 0, duration, tp, clk)
+}
+
+// newAuction is New for an auction held in the raid raidID of raidMode,
+// if set, bid in points, if set, with a reserve, if not zero.
+func newAuction(id, itemName, startedBy, raidID, raidMode, points string, minBid, minIncrement, buyout, reserve int, duration time.Duration, tp trace.TracerProvider, clk clock.Clock) *Auction {
+	a := build(id, itemName, startedBy, raidID, raidMode, points, minBid, minIncrement, buyout, reserve, duration, tp, clk)
+	a.Status = "open"
+	a.StartedAt = clk.Now()
+	a.recordEvent(event.AuctionStarted, a.startedData())
+	return a
+}
+
+// queueAuction is newAuction for an auction that waits for a free slot
+// before it opens, recording a queued event.
+func queueAuction(id, itemName, startedBy, raidID, raidMode, points string, minBid, minIncrement, buyout, reserve int, duration time.Duration, tp trace.TracerProvider, clk clock.Clock) *Auction {
+	a := build(id, itemName, startedBy, raidID, raidMode, points, minBid, minIncrement, buyout, reserve, duration, tp, clk)
+	a.Status = "queued"
+	a.recordEvent(event.AuctionQueued, a.startedData())
+	return a
+}
+
+// scheduleAuction is newAuction for an auction that opens at startsAt,
+// recording a scheduled event.
+func scheduleAuction(id, itemName, startedBy, raidID, raidMode, points string, minBid, minIncrement, buyout, reserve int, duration time.Duration, startsAt time.Time, tp trace.TracerProvider, clk clock.Clock) *Auction {
+	a := build(id, itemName, startedBy, raidID, raidMode, points, minBid, minIncrement, buyout, reserve, duration, tp, clk)
+	a.Status = "scheduled"
+	a.StartsAt = startsAt.UTC()
+	a.recordEvent(event.AuctionScheduled, a.startedData())
+	return a
 }
 
-// New creates a new open auction and records a started event.
-// The TracerProvider is used to create a scoped tracer for this auction.
-func New(id, itemName, startedBy string, minBid int, duration time.Duration, tp trace.TracerProvider, clk clock.Clock) *Auction {
-	a := &Auction{
-		ID:        id,
-		ItemName:  itemName,
-		StartedBy: startedBy,
-		MinBid:    minBid,
-		Status:    "open",
-		Version:   0,
-		tracer:    tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/auction"),
-		clock:     clk,
+// build returns an auction without a status or events.
+func build(id, itemName, startedBy, raidID, raidMode, points string, minBid, minIncrement, buyout, reserve int, duration time.Duration, tp trace.TracerProvider, clk clock.Clock) *Auction {
+	return &Auction{
+		ID:           id,
+		ItemName:     itemName,
+		StartedBy:    startedBy,
+		MinBid:       minBid,
+		MinIncrement: max(minIncrement, 1),
+		Buyout:       max(buyout, 0),
+		Reserve:      max(reserve, 0),
+		RaidID:       raidID,
+		RaidMode:     raidMode,
+		Points:       points,
+		Duration:     duration,
+		tracer:       tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/auction"),
+		clock:        clk,
 	}
+}
+
+// start opens a queued or scheduled auction, recording a started event.
+// Its countdown begins now.
+func (a *Auction) start(ctx context.Context) error {
+	_, span := a.tracer.Start(ctx, "Auction.start",
+		trace.WithAttributes(attribute.String("auction.id", a.ID)),
+	)
+	defer span.End()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
+	if a.Status != "queued" && a.Status != "scheduled" {
+		return ErrAuctionClosed
+	}
+	a.Status = "open"
+	a.StartedAt = a.clock.Now()
+	a.recordEvent(event.AuctionStarted, a.startedData())
+	return nil
+}
+
+// enqueue queues a scheduled auction that is due while the guild's limit
+// of open auctions is reached, recording a queued event.
+func (a *Auction) enqueue() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.Status != "scheduled" {
+		return ErrAuctionClosed
+	}
+	a.Status = "queued"
+	a.recordEvent(event.AuctionQueued, a.startedData())
+	return nil
+}
+
+// startedData returns the payload of the auction's started, queued, or
+// scheduled event.
+func (a *Auction) startedData() json.RawMessage {
 	data, _ := json.Marshal(event.AuctionStartedData{
-		ItemName:  itemName,
-		StartedBy: startedBy,
-		MinBid:    minBid,
-		Duration:  duration,
+		ItemName:     a.ItemName,
+		StartedBy:    a.StartedBy,
+		MinBid:       a.MinBid,
+		MinIncrement: a.MinIncrement,
+		Duration:     a.Duration,
+		Buyout:       a.Buyout,
+		RaidID:       a.RaidID,
+		RaidMode:     a.RaidMode,
+		Points:       a.Points,
+		Reserve:      a.Reserve,
+		StartsAt:     a.StartsAt,
 	})
-	a.recordEvent(event.AuctionStarted, data)
-	return a
+	return data
+}
+
+// Currency names what the auction is bid in: "gold" in a GDKP raid, its
+// points if set, and "DKP" otherwise.
+func (a *Auction) Currency() string {
+	return currency(a.RaidID, a.RaidMode, a.Points)
+}
+
+func currency(raidID, raidMode, points string) string {
+	switch {
+	case gold(raidID, raidMode):
+		return "gold"
+	case points != "":
+		return points
+	}
+	return dkp.DKP
+}
+
+// gold reports whether an auction held in raidID of raidMode is bid on in
+// gold.
+func gold(raidID, raidMode string) bool {
+	return raidID != "" && raidMode != gdkp.ModeDKP
+}
+
+// affords reports whether a player with playerDKP can pay amount. Gold
+// is paid in game, so anyone can afford a GDKP bid. In an auction bid in
+// points, playerDKP is the player's balance of the points.
+func (a *Auction) affords(playerDKP, amount int) bool {
+	return gold(a.RaidID, a.RaidMode) || amount <= playerDKP
+}
+
+// EndsAt returns when the auction's countdown runs out: its duration after
+// it started, pushed back by the time it spent paused. While the auction
+// is paused the end keeps moving.
+func (a *Auction) EndsAt() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	paused := a.Paused
+	if a.Status == "paused" {
+		paused += a.clock.Now().Sub(a.PausedAt)
+	}
+	return a.StartedAt.Add(a.Duration + paused)
+}
+
+// Pause pauses an open auction, for example when the raid wipes. A paused
+// auction rejects bids and buyouts, and its countdown stands still until
+// it is resumed.
+func (a *Auction) Pause(ctx context.Context) error {
+	_, span := a.tracer.Start(ctx, "Auction.Pause",
+		trace.WithAttributes(attribute.String("auction.id", a.ID)),
+	)
+	defer span.End()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch a.Status {
+	case "open":
+	case "paused":
+		return ErrAuctionPaused
+	case "queued":
+		return ErrAuctionQueued
+	case "scheduled":
+		return ErrAuctionScheduled
+	default:
+		return ErrAuctionClosed
+	}
+	a.Status = "paused"
+	a.PausedAt = a.clock.Now().UTC()
+	a.recordEvent(event.AuctionPaused, json.RawMessage(`{}`))
+	return nil
+}
+
+// Resume reopens a paused auction, pushing its end back by the length of
+// the pause.
+func (a *Auction) Resume(ctx context.Context) error {
+	_, span := a.tracer.Start(ctx, "Auction.Resume",
+		trace.WithAttributes(attribute.String("auction.id", a.ID)),
+	)
+	defer span.End()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.Status != "paused" {
+		return ErrNotPaused
+	}
+	paused := max(a.clock.Now().Sub(a.PausedAt), 0)
+	a.Status = "open"
+	a.Paused += paused
+	a.PausedAt = time.Time{}
+	data, _ := json.Marshal(event.AuctionResumedData{Paused: paused})
+	a.recordEvent(event.AuctionResumed, data)
+	return nil
 }
 
 // PlaceBid places a bid on the auction. Thread-safe.
@@ -87,25 +332,66 @@ func (a *Auction) PlaceBid(ctx context.Context, playerID string, amount int, pla
 
 	a.mu.Lock()
 	defer a.mu.Unlock()
+	return a.placeBid(ctx, playerID, amount, playerDKP)
+}
+
+// Raise bids by more than the highest bid, or the minimum bid if there is
+// none, and returns the amount bid. The amount is worked out as the bid is
+// placed, so that it is not undercut by a concurrent bid.
+func (a *Auction) Raise(ctx context.Context, playerID string, by int, playerDKP int) (int, error) {
+	ctx, span := a.tracer.Start(ctx, "Auction.Raise",
+		trace.WithAttributes(
+			attribute.String("auction.id", a.ID),
+			attribute.String("player.id", playerID),
+			attribute.Int("bid.raise", by),
+		),
+	)
+	defer span.End()
 
-	if a.Status != "open" {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	amount := a.MinBid
+	if highest := a.highestBid(); highest != nil {
+		amount = highest.Amount + by
+	}
+	return amount, a.placeBid(ctx, playerID, amount, playerDKP)
+}
+
+// placeBid places a bid of amount. The caller must hold a.mu.
+func (a *Auction) placeBid(ctx context.Context, playerID string, amount int, playerDKP int) error {
+	switch a.Status {
+	case "open":
+	case "paused":
+		return ErrAuctionPaused
+	case "queued":
+		return ErrAuctionQueued
+	case "scheduled":
+		return ErrAuctionScheduled
+	default:
 		return ErrAuctionClosed
 	}
 	if amount < a.MinBid {
 		return ErrBidTooLow
 	}
-	if amount > playerDKP {
+	if !a.affords(playerDKP, amount) {
 		return ErrInsufficientDKP
 	}
+	if a.Buyout > 0 && amount >= a.Buyout {
+		return ErrBidAtBuyout
+	}
 
 	// Check if already highest bidder.
 	if highest := a.highestBid(); highest != nil && highest.PlayerID == playerID {
 		return ErrSelfOutbid
 	}
 
-	// Must outbid current highest.
-	if highest := a.highestBid(); highest != nil && amount <= highest.Amount {
-		return ErrBidTooLow
+	// Must outbid current highest by the increment.
+	var outbid string
+	if highest := a.highestBid(); highest != nil {
+		if amount < highest.Amount+a.MinIncrement {
+			return ErrBidTooLow
+		}
+		outbid = highest.PlayerID
 	}
 
 	a.Bids = append(a.Bids, Bid{
@@ -117,6 +403,7 @@ func (a *Auction) PlaceBid(ctx context.Context, playerID string, amount int, pla
 	data, _ := json.Marshal(event.BidPlacedData{
 		PlayerID: playerID,
 		Amount:   amount,
+		Outbid:   outbid,
 	})
 	a.recordEvent(event.AuctionBidPlaced, data)
 
@@ -128,9 +415,23 @@ func (a *Auction) PlaceBid(ctx context.Context, playerID string, amount int, pla
 	return nil
 }
 
-// Close closes the auction, awarding the item to the highest bidder.
-func (a *Auction) Close(ctx context.Context) (winner *Bid, err error) {
-	_, span := a.tracer.Start(ctx, "Auction.Close",
+// Announce records a message announcing the auction, so that it can be
+// kept up to date.
+func (a *Auction) Announce(channelID, messageID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.Announcements = append(a.Announcements, Announcement{ChannelID: channelID, MessageID: messageID})
+	data, _ := json.Marshal(event.AuctionAnnouncedData{ChannelID: channelID, MessageID: messageID})
+	a.recordEvent(event.AuctionAnnounced, data)
+}
+
+// StartRoll turns an open or paused auction without bids into a rolling
+// one, in which players may roll for the item until the given time instead
+// of bidding. It reports whether it did; auctions that have bids or are no
+// longer open are left unchanged.
+func (a *Auction) StartRoll(ctx context.Context, until time.Time) bool {
+	_, span := a.tracer.Start(ctx, "Auction.StartRoll",
 		trace.WithAttributes(attribute.String("auction.id", a.ID)),
 	)
 	defer span.End()
@@ -138,13 +439,156 @@ func (a *Auction) Close(ctx context.Context) (winner *Bid, err error) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	if a.Status != "open" {
+	// Rolls win the item at the minimum bid, which a reserve above it
+	// rules out.
+	if (a.Status != "open" && a.Status != "paused") || len(a.Bids) > 0 || a.Reserve > a.MinBid {
+		return false
+	}
+	a.Status = "rolling"
+	a.RollUntil = until.UTC()
+	data, _ := json.Marshal(event.AuctionRollStartedData{Until: a.RollUntil})
+	a.recordEvent(event.AuctionRollStarted, data)
+	return true
+}
+
+// Roll records value as playerID's roll. Players may roll once, before the
+// roll ends, and only if they could afford the minimum bid.
+func (a *Auction) Roll(ctx context.Context, playerID string, playerDKP, value int) error {
+	ctx, span := a.tracer.Start(ctx, "Auction.Roll",
+		trace.WithAttributes(
+			attribute.String("auction.id", a.ID),
+			attribute.String("player.id", playerID),
+			attribute.Int("roll", value),
+		),
+	)
+	defer span.End()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.Status != "rolling" {
+		return ErrNotRolling
+	}
+	now := a.clock.Now().UTC()
+	if !now.Before(a.RollUntil) {
+		return ErrRollEnded
+	}
+	if !a.affords(playerDKP, a.MinBid) {
+		return ErrInsufficientDKP
+	}
+	for _, r := range a.Rolls {
+		if r.PlayerID == playerID {
+			return ErrAlreadyRolled
+		}
+	}
+
+	a.Rolls = append(a.Rolls, Roll{PlayerID: playerID, Value: value, Time: now})
+	data, _ := json.Marshal(event.AuctionRolledData{
+		PlayerID: playerID,
+		Roll:     value,
+	})
+	a.recordEvent(event.AuctionRolled, data)
+
+	slog.InfoContext(ctx, "roll made",
+		slog.String("auction_id", a.ID),
+		slog.String("player_id", playerID),
+		slog.Int("roll", value),
+	)
+	return nil
+}
+
+// HighestRoll returns the winning roll so far: the highest, or the first
+// of those tied for highest. It returns nil if nobody has rolled.
+func (a *Auction) HighestRoll() *Roll {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.highestRoll()
+}
+
+func (a *Auction) highestRoll() *Roll {
+	var best *Roll
+	for i := range a.Rolls {
+		if best == nil || a.Rolls[i].Value > best.Value {
+			best = &a.Rolls[i]
+		}
+	}
+	return best
+}
+
+// Close closes the auction, awarding the item to the highest bidder who
+// can still pay. balances maps player IDs to their current DKP: a bidder
+// whose balance no longer covers their bid, for example after decay or
+// winning another auction, is skipped in favor of the next highest bidder,
+// recording a winner skipped event. Bidders missing from balances are not
+// checked. A rolling auction is awarded at the minimum bid to the player
+// with the highest roll.
+func (a *Auction) Close(ctx context.Context, balances map[string]int) (winner *Bid, err error) {
+	ctx, span := a.tracer.Start(ctx, "Auction.Close",
+		trace.WithAttributes(attribute.String("auction.id", a.ID)),
+	)
+	defer span.End()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.Status == "rolling" {
+		a.Status = "closed"
+		if r := a.highestRoll(); r != nil {
+			data, _ := json.Marshal(event.AuctionClosedData{
+				WinnerID: r.PlayerID,
+				Amount:   a.MinBid,
+				Roll:     r.Value,
+			})
+			a.recordEvent(event.AuctionClosed, data)
+			return &Bid{PlayerID: r.PlayerID, Amount: a.MinBid, Time: a.clock.Now().UTC()}, nil
+		}
+		data, _ := json.Marshal(event.AuctionClosedData{})
+		a.recordEvent(event.AuctionClosed, data)
+		return nil, nil
+	}
+	switch a.Status {
+	case "queued":
+		return nil, ErrAuctionQueued
+	case "scheduled":
+		return nil, ErrAuctionScheduled
+	}
+	if a.Status != "open" && a.Status != "paused" {
 		return nil, ErrAuctionClosed
 	}
 
+	for {
+		highest := a.highestBid()
+		if highest == nil {
+			break
+		}
+		dkp, ok := balances[highest.PlayerID]
+		if !ok || a.affords(dkp, highest.Amount) {
+			break
+		}
+		a.Skipped = append(a.Skipped, highest.PlayerID)
+		data, _ := json.Marshal(event.AuctionWinnerSkippedData{
+			PlayerID: highest.PlayerID,
+			Amount:   highest.Amount,
+			DKP:      dkp,
+		})
+		a.recordEvent(event.AuctionWinnerSkipped, data)
+		slog.WarnContext(ctx, "skipped winner who can no longer afford their bid",
+			slog.String("auction_id", a.ID),
+			slog.String("player_id", highest.PlayerID),
+			slog.Int("amount", highest.Amount),
+			slog.Int("dkp", dkp),
+		)
+	}
+
 	a.Status = "closed"
 	highest := a.highestBid()
 
+	if highest != nil && highest.Amount < a.Reserve {
+		a.ReserveNotMet = true
+		data, _ := json.Marshal(event.AuctionClosedData{ReserveNotMet: true})
+		a.recordEvent(event.AuctionClosed, data)
+		return nil, nil
+	}
 	if highest != nil {
 		data, _ := json.Marshal(event.AuctionClosedData{
 			WinnerID: highest.PlayerID,
@@ -160,7 +604,61 @@ func (a *Auction) Close(ctx context.Context) (winner *Bid, err error) {
 	return nil, nil
 }
 
-// Cancel cancels the auction.
+// BuyOut closes the auction, awarding the item to playerID at the buyout
+// price.
+func (a *Auction) BuyOut(ctx context.Context, playerID string, playerDKP int) (*Bid, error) {
+	ctx, span := a.tracer.Start(ctx, "Auction.BuyOut",
+		trace.WithAttributes(
+			attribute.String("auction.id", a.ID),
+			attribute.String("player.id", playerID),
+		),
+	)
+	defer span.End()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch a.Status {
+	case "open":
+	case "paused":
+		return nil, ErrAuctionPaused
+	case "queued":
+		return nil, ErrAuctionQueued
+	case "scheduled":
+		return nil, ErrAuctionScheduled
+	default:
+		return nil, ErrAuctionClosed
+	}
+	if a.Buyout <= 0 {
+		return nil, ErrNoBuyout
+	}
+	if !a.affords(playerDKP, a.Buyout) {
+		return nil, ErrInsufficientDKP
+	}
+
+	// The buyout is recorded as the winning bid.
+	a.Status = "closed"
+	a.Bids = append(a.Bids, Bid{
+		PlayerID: playerID,
+		Amount:   a.Buyout,
+		Time:     a.clock.Now().UTC(),
+	})
+	data, _ := json.Marshal(event.AuctionBoughtOutData{
+		BuyerID: playerID,
+		Amount:  a.Buyout,
+	})
+	a.recordEvent(event.AuctionBoughtOut, data)
+
+	slog.InfoContext(ctx, "auction bought out",
+		slog.String("auction_id", a.ID),
+		slog.String("player_id", playerID),
+		slog.Int("amount", a.Buyout),
+	)
+	return a.highestBid(), nil
+}
+
+// Cancel cancels the auction, which may be scheduled, queued, open,
+// paused, or rolling.
 func (a *Auction) Cancel(ctx context.Context) error {
 	_, span := a.tracer.Start(ctx, "Auction.Cancel",
 		trace.WithAttributes(attribute.String("auction.id", a.ID)),
@@ -170,7 +668,9 @@ func (a *Auction) Cancel(ctx context.Context) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	if a.Status != "open" {
+	switch a.Status {
+	case "scheduled", "queued", "open", "paused", "rolling":
+	default:
 		return ErrAuctionClosed
 	}
 	a.Status = "canceled"
@@ -185,11 +685,122 @@ func (a *Auction) HighestBid() *Bid {
 	return a.highestBid()
 }
 
+// highestBid returns the highest bid of a player who was not skipped.
 func (a *Auction) highestBid() *Bid {
-	if len(a.Bids) == 0 {
+	return highest(a.Bids, a.Skipped, "")
+}
+
+// SecondHighestBid returns the highest bid of a player other than the
+// highest bidder who was not skipped, or nil if there is none. It is the
+// price the winner pays in a second-price (Vickrey) auction.
+func (a *Auction) SecondHighestBid() *Bid {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	h := a.highestBid()
+	if h == nil {
 		return nil
 	}
-	return &a.Bids[len(a.Bids)-1]
+	return highest(a.Bids, a.Skipped, h.PlayerID)
+}
+
+// highest returns the highest of bids whose player is neither skipped nor
+// except: that of the greatest amount, and the earliest of those if several
+// tie. It does not rely on the order of bids, which is not the order they
+// were placed in once bids are retracted or recovered out of order.
+func highest(bids []Bid, skipped []string, except string) *Bid {
+	var best *Bid
+	for i := range bids {
+		b := &bids[i]
+		if b.PlayerID == except || slices.Contains(skipped, b.PlayerID) {
+			continue
+		}
+		if best == nil || b.Amount > best.Amount || (b.Amount == best.Amount && b.Time.Before(best.Time)) {
+			best = b
+		}
+	}
+	return best
+}
+
+// State is a serializable view of an auction, used for snapshots.
+type State struct {
+	ID        string `json:"id"`
+	ItemName  string `json:"item_name"`
+	StartedBy string `json:"started_by"`
+	MinBid    int    `json:"min_bid"`
+	// MinIncrement is zero in snapshots taken before increments were
+	// recorded, which accepted any higher bid.
+	MinIncrement int `json:"min_increment,omitempty"`
+	Buyout       int `json:"buyout,omitempty"`
+	// Reserve is hidden from players; it is not shown in announcements.
+	Reserve       int    `json:"reserve,omitempty"`
+	ReserveNotMet bool   `json:"reserve_not_met,omitempty"`
+	RaidID        string `json:"raid_id,omitempty"`
+	RaidMode      string `json:"raid_mode,omitempty"`
+	Points        string `json:"points,omitempty"`
+	// Duration is zero in snapshots taken before it was recorded.
+	Duration time.Duration `json:"duration,omitempty"`
+	Status   string        `json:"status"`
+	// StartsAt is set for scheduled auctions.
+	StartsAt time.Time `json:"starts_at,omitzero"`
+	Bids     []Bid     `json:"bids"`
+	Skipped  []string  `json:"skipped,omitempty"`
+	// Paused and PausedAt are set for auctions that were paused.
+	Paused   time.Duration `json:"paused,omitempty"`
+	PausedAt time.Time     `json:"paused_at,omitzero"`
+	// RollUntil and Rolls are set for rolling auctions.
+	RollUntil     time.Time      `json:"roll_until,omitzero"`
+	Rolls         []Roll         `json:"rolls,omitempty"`
+	Announcements []Announcement `json:"announcements,omitempty"`
+	Version       int            `json:"version"`
+}
+
+// State returns a copy of the auction's current state (thread-safe).
+func (a *Auction) State() State {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return State{
+		ID:            a.ID,
+		ItemName:      a.ItemName,
+		StartedBy:     a.StartedBy,
+		MinBid:        a.MinBid,
+		MinIncrement:  a.MinIncrement,
+		Buyout:        a.Buyout,
+		Reserve:       a.Reserve,
+		ReserveNotMet: a.ReserveNotMet,
+		RaidID:        a.RaidID,
+		RaidMode:      a.RaidMode,
+		Points:        a.Points,
+		Duration:      a.Duration,
+		Status:        a.Status,
+		StartsAt:      a.StartsAt,
+		Bids:          append([]Bid(nil), a.Bids...),
+		Skipped:       slices.Clone(a.Skipped),
+		Paused:        a.Paused,
+		PausedAt:      a.PausedAt,
+		RollUntil:     a.RollUntil,
+		Rolls:         append([]Roll(nil), a.Rolls...),
+		Announcements: slices.Clone(a.Announcements),
+		Version:       a.Version,
+	}
+}
+
+// Currency names what the auction is bid in, as Auction.Currency does.
+func (s State) Currency() string {
+	return currency(s.RaidID, s.RaidMode, s.Points)
+}
+
+// HighestBid returns the highest bid of s, as Auction.HighestBid does, or
+// nil.
+func (s State) HighestBid() *Bid {
+	return highest(s.Bids, s.Skipped, "")
+}
+
+// recordTax records the tax charged to the winner of the auction.
+func (a *Auction) recordTax(d event.AuctionTaxedData) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	data, _ := json.Marshal(d)
+	a.recordEvent(event.AuctionTaxed, data)
 }
 
 // PendingEvents returns uncommitted events and clears the buffer.
@@ -217,41 +828,136 @@ func Replay(events []event.Event) (*Auction, error) {
 		return nil, fmt.Errorf("no events to replay")
 	}
 
-	a := &Auction{
+	a := replayed()
+	for _, e := range events {
+		if err := a.apply(e); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// replayed returns an empty auction to apply events to.
+func replayed() *Auction {
+	return &Auction{
 		tracer: noop.NewTracerProvider().Tracer("auction"),
 		clock:  clock.Real{},
 	}
-	for _, e := range events {
+}
+
+// apply applies the event e of the auction's history. It leaves the
+// auction unchanged if e cannot be decoded.
+func (a *Auction) apply(e event.Event) error {
+	switch e.Type {
+	case event.AuctionScheduled, event.AuctionQueued, event.AuctionStarted:
+		var d event.AuctionStartedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return fmt.Errorf("unmarshaling %s event: %w", e.Type, err)
+		}
+		a.ID = e.AggregateID
+		a.ItemName = d.ItemName
+		a.StartedBy = d.StartedBy
+		a.MinBid = d.MinBid
+		// Auctions started before increments were recorded accepted
+		// any higher bid.
+		a.MinIncrement = max(d.MinIncrement, 1)
+		a.Buyout = d.Buyout
+		a.Reserve = d.Reserve
+		a.RaidID = d.RaidID
+		a.RaidMode = d.RaidMode
+		a.Points = d.Points
+		a.Duration = d.Duration
+		a.StartsAt = d.StartsAt
 		switch e.Type {
-		case event.AuctionStarted:
-			var d event.AuctionStartedData
-			if err := json.Unmarshal(e.Data, &d); err != nil {
-				return nil, fmt.Errorf("unmarshaling started event: %w", err)
-			}
-			a.ID = e.AggregateID
-			a.ItemName = d.ItemName
-			a.StartedBy = d.StartedBy
-			a.MinBid = d.MinBid
+		case event.AuctionScheduled:
+			a.Status = "scheduled"
+		case event.AuctionQueued:
+			a.Status = "queued"
+		default:
 			a.Status = "open"
+			a.StartedAt = e.CreatedAt
+		}
 
-		case event.AuctionBidPlaced:
-			var d event.BidPlacedData
-			if err := json.Unmarshal(e.Data, &d); err != nil {
-				return nil, fmt.Errorf("unmarshaling bid event: %w", err)
-			}
-			a.Bids = append(a.Bids, Bid{
-				PlayerID: d.PlayerID,
-				Amount:   d.Amount,
-				Time:     e.CreatedAt,
-			})
+	case event.AuctionBidPlaced:
+		var d event.BidPlacedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return fmt.Errorf("unmarshaling bid event: %w", err)
+		}
+		a.Bids = append(a.Bids, Bid{
+			PlayerID: d.PlayerID,
+			Amount:   d.Amount,
+			Time:     e.CreatedAt,
+		})
 
-		case event.AuctionClosed:
-			a.Status = "closed"
+	case event.AuctionClosed:
+		var d event.AuctionClosedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return fmt.Errorf("unmarshaling closed event: %w", err)
+		}
+		a.Status = "closed"
+		a.ReserveNotMet = d.ReserveNotMet
 
-		case event.AuctionCanceled:
-			a.Status = "canceled"
+	case event.AuctionBoughtOut:
+		var d event.AuctionBoughtOutData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return fmt.Errorf("unmarshaling buyout event: %w", err)
 		}
-		a.Version = e.Version
+		a.Bids = append(a.Bids, Bid{
+			PlayerID: d.BuyerID,
+			Amount:   d.Amount,
+			Time:     e.CreatedAt,
+		})
+		a.Status = "closed"
+
+	case event.AuctionWinnerSkipped:
+		var d event.AuctionWinnerSkippedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return fmt.Errorf("unmarshaling winner skipped event: %w", err)
+		}
+		a.Skipped = append(a.Skipped, d.PlayerID)
+
+	case event.AuctionPaused:
+		a.Status = "paused"
+		a.PausedAt = e.CreatedAt
+
+	case event.AuctionResumed:
+		var d event.AuctionResumedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return fmt.Errorf("unmarshaling resumed event: %w", err)
+		}
+		a.Status = "open"
+		a.Paused += d.Paused
+		a.PausedAt = time.Time{}
+
+	case event.AuctionRollStarted:
+		var d event.AuctionRollStartedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return fmt.Errorf("unmarshaling roll started event: %w", err)
+		}
+		a.Status = "rolling"
+		a.RollUntil = d.Until
+
+	case event.AuctionRolled:
+		var d event.AuctionRolledData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return fmt.Errorf("unmarshaling roll event: %w", err)
+		}
+		a.Rolls = append(a.Rolls, Roll{
+			PlayerID: d.PlayerID,
+			Value:    d.Roll,
+			Time:     e.CreatedAt,
+		})
+
+	case event.AuctionAnnounced:
+		var d event.AuctionAnnouncedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return fmt.Errorf("unmarshaling announced event: %w", err)
+		}
+		a.Announcements = append(a.Announcements, Announcement{ChannelID: d.ChannelID, MessageID: d.MessageID})
+
+	case event.AuctionCanceled:
+		a.Status = "canceled"
 	}
-	return a, nil
+	a.Version = e.Version
+	return nil
 }
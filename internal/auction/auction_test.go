@@ -2,7 +2,10 @@ package auction_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -11,6 +14,7 @@ import (
 
 	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
 )
 
 var (
@@ -30,7 +34,7 @@ func TestPlaceBid(t *testing.T) {
 		{
 			name: "valid first bid",
 			setup: func() *auction.Auction {
-				return auction.New("a1", "Sword of Truth", "admin", 10, 5*time.Minute, testTP, testClk)
+				return auction.New("a1", "Sword of Truth", "admin", 10, 1, 0, 5*time.Minute, testTP, testClk)
 			},
 			playerID:  "p1",
 			amount:    50,
@@ -40,7 +44,7 @@ func TestPlaceBid(t *testing.T) {
 		{
 			name: "bid below minimum",
 			setup: func() *auction.Auction {
-				return auction.New("a2", "Shield", "admin", 100, 5*time.Minute, testTP, testClk)
+				return auction.New("a2", "Shield", "admin", 100, 1, 0, 5*time.Minute, testTP, testClk)
 			},
 			playerID:  "p1",
 			amount:    50,
@@ -50,7 +54,7 @@ func TestPlaceBid(t *testing.T) {
 		{
 			name: "insufficient DKP",
 			setup: func() *auction.Auction {
-				return auction.New("a3", "Helm", "admin", 10, 5*time.Minute, testTP, testClk)
+				return auction.New("a3", "Helm", "admin", 10, 1, 0, 5*time.Minute, testTP, testClk)
 			},
 			playerID:  "p1",
 			amount:    150,
@@ -60,7 +64,7 @@ func TestPlaceBid(t *testing.T) {
 		{
 			name: "self outbid",
 			setup: func() *auction.Auction {
-				a := auction.New("a4", "Boots", "admin", 10, 5*time.Minute, testTP, testClk)
+				a := auction.New("a4", "Boots", "admin", 10, 1, 0, 5*time.Minute, testTP, testClk)
 				_ = a.PlaceBid(context.Background(), "p1", 50, 100)
 				return a
 			},
@@ -72,8 +76,8 @@ func TestPlaceBid(t *testing.T) {
 		{
 			name: "bid on closed auction",
 			setup: func() *auction.Auction {
-				a := auction.New("a5", "Ring", "admin", 10, 5*time.Minute, testTP, testClk)
-				_, _ = a.Close(context.Background())
+				a := auction.New("a5", "Ring", "admin", 10, 1, 0, 5*time.Minute, testTP, testClk)
+				_, _ = a.Close(context.Background(), nil)
 				return a
 			},
 			playerID:  "p1",
@@ -84,7 +88,7 @@ func TestPlaceBid(t *testing.T) {
 		{
 			name: "must outbid current highest",
 			setup: func() *auction.Auction {
-				a := auction.New("a6", "Cloak", "admin", 10, 5*time.Minute, testTP, testClk)
+				a := auction.New("a6", "Cloak", "admin", 10, 1, 0, 5*time.Minute, testTP, testClk)
 				_ = a.PlaceBid(context.Background(), "p1", 50, 100)
 				return a
 			},
@@ -93,6 +97,30 @@ func TestPlaceBid(t *testing.T) {
 			playerDKP: 100,
 			wantErr:   auction.ErrBidTooLow,
 		},
+		{
+			name: "below the minimum increment",
+			setup: func() *auction.Auction {
+				a := auction.New("a7", "Gloves", "admin", 10, 5, 0, 5*time.Minute, testTP, testClk)
+				_ = a.PlaceBid(context.Background(), "p1", 50, 100)
+				return a
+			},
+			playerID:  "p2",
+			amount:    54,
+			playerDKP: 100,
+			wantErr:   auction.ErrBidTooLow,
+		},
+		{
+			name: "at the minimum increment",
+			setup: func() *auction.Auction {
+				a := auction.New("a8", "Belt", "admin", 10, 5, 0, 5*time.Minute, testTP, testClk)
+				_ = a.PlaceBid(context.Background(), "p1", 50, 100)
+				return a
+			},
+			playerID:  "p2",
+			amount:    55,
+			playerDKP: 100,
+			wantErr:   nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -116,7 +144,7 @@ func TestAuction_Close(t *testing.T) {
 		{
 			name: "close with winner",
 			setup: func() *auction.Auction {
-				a := auction.New("a1", "Sword", "admin", 10, 5*time.Minute, testTP, testClk)
+				a := auction.New("a1", "Sword", "admin", 10, 1, 0, 5*time.Minute, testTP, testClk)
 				_ = a.PlaceBid(context.Background(), "p1", 50, 100)
 				_ = a.PlaceBid(context.Background(), "p2", 75, 200)
 				return a
@@ -126,15 +154,15 @@ func TestAuction_Close(t *testing.T) {
 		{
 			name: "close with no bids",
 			setup: func() *auction.Auction {
-				return auction.New("a2", "Shield", "admin", 10, 5*time.Minute, testTP, testClk)
+				return auction.New("a2", "Shield", "admin", 10, 1, 0, 5*time.Minute, testTP, testClk)
 			},
 			wantWinner: false,
 		},
 		{
 			name: "close already closed",
 			setup: func() *auction.Auction {
-				a := auction.New("a3", "Helm", "admin", 10, 5*time.Minute, testTP, testClk)
-				_, _ = a.Close(context.Background())
+				a := auction.New("a3", "Helm", "admin", 10, 1, 0, 5*time.Minute, testTP, testClk)
+				_, _ = a.Close(context.Background(), nil)
 				return a
 			},
 			wantErr: auction.ErrAuctionClosed,
@@ -144,7 +172,7 @@ func TestAuction_Close(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			a := tt.setup()
-			winner, err := a.Close(context.Background())
+			winner, err := a.Close(context.Background(), nil)
 			if err != tt.wantErr {
 				t.Fatalf("Close() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -159,7 +187,7 @@ func TestAuction_Close(t *testing.T) {
 }
 
 func TestAuction_ConcurrentBids(t *testing.T) {
-	a := auction.New("concurrent-test", "Epic Item", "admin", 1, 5*time.Minute, testTP, testClk)
+	a := auction.New("concurrent-test", "Epic Item", "admin", 1, 1, 0, 5*time.Minute, testTP, testClk)
 
 	var wg sync.WaitGroup
 	errs := make([]error, 100)
@@ -194,7 +222,7 @@ func TestAuction_ConcurrentBids(t *testing.T) {
 
 func TestAuction_Replay(t *testing.T) {
 	// Create auction and place bids.
-	original := auction.New("replay-test", "Legendary Sword", "admin", 10, 5*time.Minute, testTP, testClk)
+	original := auction.New("replay-test", "Legendary Sword", "admin", 10, 5, 0, 5*time.Minute, testTP, testClk)
 	_ = original.PlaceBid(context.Background(), "p1", 50, 100)
 	_ = original.PlaceBid(context.Background(), "p2", 75, 200)
 
@@ -212,6 +240,9 @@ func TestAuction_Replay(t *testing.T) {
 	if replayed.Status != "open" {
 		t.Errorf("status = %q, want %q", replayed.Status, "open")
 	}
+	if replayed.MinIncrement != 5 || replayed.Duration != 5*time.Minute {
+		t.Errorf("increment, duration = %d, %s, want 5, 5m", replayed.MinIncrement, replayed.Duration)
+	}
 	if len(replayed.Bids) != 2 {
 		t.Errorf("bids count = %d, want 2", len(replayed.Bids))
 	}
@@ -222,8 +253,36 @@ func TestAuction_Replay(t *testing.T) {
 	}
 }
 
+func TestAuction_OutbidAndAnnounce(t *testing.T) {
+	a := auction.New("announce-test", "Item", "admin", 10, 1, 0, 5*time.Minute, testTP, testClk)
+	_ = a.PlaceBid(context.Background(), "p1", 50, 100)
+	_ = a.PlaceBid(context.Background(), "p2", 60, 100)
+	a.Announce("c1", "m1")
+
+	events := a.PendingEvents()
+	var outbid []string
+	for _, e := range events {
+		if e.Type == event.AuctionBidPlaced {
+			var d event.BidPlacedData
+			_ = json.Unmarshal(e.Data, &d)
+			outbid = append(outbid, d.Outbid)
+		}
+	}
+	if !slices.Equal(outbid, []string{"", "p1"}) {
+		t.Errorf("outbid players = %q, want none, then p1", outbid)
+	}
+
+	replayed, err := auction.Replay(events)
+	if err != nil {
+		t.Fatalf("Replay() error: %v", err)
+	}
+	if want := []auction.Announcement{{ChannelID: "c1", MessageID: "m1"}}; !slices.Equal(replayed.Announcements, want) {
+		t.Errorf("announcements = %+v, want %+v", replayed.Announcements, want)
+	}
+}
+
 func TestAuction_PendingEvents(t *testing.T) {
-	a := auction.New("events-test", "Item", "admin", 10, 5*time.Minute, testTP, testClk)
+	a := auction.New("events-test", "Item", "admin", 10, 1, 0, 5*time.Minute, testTP, testClk)
 	_ = a.PlaceBid(context.Background(), "p1", 50, 100)
 
 	events := a.PendingEvents()
@@ -237,3 +296,366 @@ func TestAuction_PendingEvents(t *testing.T) {
 		t.Errorf("pending events after drain = %d, want 0", len(events))
 	}
 }
+
+func TestAuction_BuyOut(t *testing.T) {
+	tests := []struct {
+		name      string
+		buyout    int
+		closed    bool
+		playerDKP int
+		wantErr   error
+	}{
+		{name: "bought out", buyout: 100, playerDKP: 150},
+		{name: "no buyout price", buyout: 0, playerDKP: 150, wantErr: auction.ErrNoBuyout},
+		{name: "insufficient DKP", buyout: 100, playerDKP: 99, wantErr: auction.ErrInsufficientDKP},
+		{name: "closed", buyout: 100, closed: true, playerDKP: 150, wantErr: auction.ErrAuctionClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := auction.New("a1", "Sword", "admin", 10, 1, tt.buyout, 5*time.Minute, testTP, testClk)
+			if tt.closed {
+				_, _ = a.Close(context.Background(), nil)
+			}
+
+			winner, err := a.BuyOut(context.Background(), "p1", tt.playerDKP)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("BuyOut() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if winner == nil || winner.PlayerID != "p1" || winner.Amount != tt.buyout {
+				t.Errorf("winner = %+v, want p1 @ %d", winner, tt.buyout)
+			}
+			if a.Status != "closed" {
+				t.Errorf("status = %q, want closed", a.Status)
+			}
+			events := a.PendingEvents()
+			if last := events[len(events)-1]; last.Type != event.AuctionBoughtOut {
+				t.Errorf("last event = %s, want %s", last.Type, event.AuctionBoughtOut)
+			}
+		})
+	}
+}
+
+func TestAuction_BidAtBuyout(t *testing.T) {
+	a := auction.New("a1", "Sword", "admin", 10, 1, 100, 5*time.Minute, testTP, testClk)
+	if err := a.PlaceBid(context.Background(), "p1", 99, 200); err != nil {
+		t.Fatalf("PlaceBid(99) error = %v", err)
+	}
+	if err := a.PlaceBid(context.Background(), "p2", 100, 200); !errors.Is(err, auction.ErrBidAtBuyout) {
+		t.Errorf("PlaceBid(100) error = %v, want ErrBidAtBuyout", err)
+	}
+}
+
+func TestAuction_ReplayBuyOut(t *testing.T) {
+	original := auction.New("replay-buyout", "Sword", "admin", 10, 1, 100, 5*time.Minute, testTP, testClk)
+	_ = original.PlaceBid(context.Background(), "p1", 50, 200)
+	if _, err := original.BuyOut(context.Background(), "p2", 200); err != nil {
+		t.Fatalf("BuyOut() error = %v", err)
+	}
+
+	replayed, err := auction.Replay(original.PendingEvents())
+	if err != nil {
+		t.Fatalf("Replay() error: %v", err)
+	}
+	if replayed.Status != "closed" || replayed.Buyout != 100 {
+		t.Errorf("status, buyout = %q, %d, want closed, 100", replayed.Status, replayed.Buyout)
+	}
+	if winner := replayed.HighestBid(); winner == nil || winner.PlayerID != "p2" || winner.Amount != 100 {
+		t.Errorf("winner = %+v, want p2 @ 100", winner)
+	}
+}
+
+func TestAuction_Roll(t *testing.T) {
+	clk := &clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	a := auction.New("a1", "Sword", "admin", 10, 1, 0, 5*time.Minute, testTP, clk)
+	ctx := context.Background()
+
+	if err := a.Roll(ctx, "p1", 100, 50); !errors.Is(err, auction.ErrNotRolling) {
+		t.Errorf("Roll() before the roll error = %v, want ErrNotRolling", err)
+	}
+	if !a.StartRoll(ctx, clk.T.Add(time.Minute)) {
+		t.Fatal("StartRoll() = false, want true")
+	}
+	if err := a.PlaceBid(ctx, "p1", 20, 100); !errors.Is(err, auction.ErrAuctionClosed) {
+		t.Errorf("PlaceBid() while rolling error = %v, want ErrAuctionClosed", err)
+	}
+
+	rolls := []struct {
+		playerID  string
+		playerDKP int
+		value     int
+		wantErr   error
+	}{
+		{"p1", 100, 40, nil},
+		{"p2", 100, 90, nil},
+		{"p3", 100, 90, nil},
+		{"p1", 100, 99, auction.ErrAlreadyRolled},
+		{"p4", 9, 100, auction.ErrInsufficientDKP},
+	}
+	for _, r := range rolls {
+		if err := a.Roll(ctx, r.playerID, r.playerDKP, r.value); !errors.Is(err, r.wantErr) {
+			t.Errorf("Roll(%s, %d) error = %v, want %v", r.playerID, r.value, err, r.wantErr)
+		}
+	}
+	clk.T = clk.T.Add(time.Minute)
+	if err := a.Roll(ctx, "p5", 100, 100); !errors.Is(err, auction.ErrRollEnded) {
+		t.Errorf("Roll() after the roll error = %v, want ErrRollEnded", err)
+	}
+
+	// Ties go to the first to roll.
+	winner, err := a.Close(ctx, nil)
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if winner == nil || winner.PlayerID != "p2" || winner.Amount != 10 {
+		t.Errorf("winner = %+v, want p2 @ 10", winner)
+	}
+
+	replayed, err := auction.Replay(a.PendingEvents())
+	if err != nil {
+		t.Fatalf("Replay() error: %v", err)
+	}
+	if replayed.Status != "closed" || len(replayed.Rolls) != 3 {
+		t.Errorf("replayed status, rolls = %q, %+v, want closed with 3 rolls", replayed.Status, replayed.Rolls)
+	}
+	if r := replayed.HighestRoll(); r == nil || r.PlayerID != "p2" || r.Value != 90 {
+		t.Errorf("replayed HighestRoll() = %+v, want p2 with 90", r)
+	}
+}
+
+func TestAuction_StartRollWithBids(t *testing.T) {
+	a := auction.New("a1", "Sword", "admin", 10, 1, 0, 5*time.Minute, testTP, testClk)
+	_ = a.PlaceBid(context.Background(), "p1", 20, 100)
+
+	if a.StartRoll(context.Background(), time.Now().Add(time.Minute)) {
+		t.Error("StartRoll() = true for an auction with bids, want false")
+	}
+	if a.Status != "open" {
+		t.Errorf("status = %q, want open", a.Status)
+	}
+}
+
+func TestAuction_CloseSkipsUnaffordableWinners(t *testing.T) {
+	a := auction.New("a1", "Sword", "admin", 10, 1, 0, 5*time.Minute, testTP, testClk)
+	ctx := context.Background()
+	_ = a.PlaceBid(ctx, "p1", 20, 100)
+	_ = a.PlaceBid(ctx, "p2", 30, 100)
+	_ = a.PlaceBid(ctx, "p3", 40, 100)
+
+	// p3 spent their DKP elsewhere; p1 is not checked.
+	winner, err := a.Close(ctx, map[string]int{"p3": 35, "p2": 30})
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if winner == nil || winner.PlayerID != "p2" || winner.Amount != 30 {
+		t.Errorf("winner = %+v, want p2 @ 30", winner)
+	}
+
+	events := a.PendingEvents()
+	var skipped []event.AuctionWinnerSkippedData
+	for _, e := range events {
+		if e.Type == event.AuctionWinnerSkipped {
+			var d event.AuctionWinnerSkippedData
+			_ = json.Unmarshal(e.Data, &d)
+			skipped = append(skipped, d)
+		}
+	}
+	if want := []event.AuctionWinnerSkippedData{{PlayerID: "p3", Amount: 40, DKP: 35}}; !slices.Equal(skipped, want) {
+		t.Errorf("winner skipped events = %+v, want %+v", skipped, want)
+	}
+
+	replayed, err := auction.Replay(events)
+	if err != nil {
+		t.Fatalf("Replay() error: %v", err)
+	}
+	if h := replayed.HighestBid(); h == nil || h.PlayerID != "p2" {
+		t.Errorf("replayed HighestBid() = %+v, want p2", h)
+	}
+}
+
+func TestAuction_CloseSkipsAllWinners(t *testing.T) {
+	a := auction.New("a1", "Sword", "admin", 10, 1, 0, 5*time.Minute, testTP, testClk)
+	ctx := context.Background()
+	_ = a.PlaceBid(ctx, "p1", 20, 100)
+	_ = a.PlaceBid(ctx, "p2", 30, 100)
+
+	winner, err := a.Close(ctx, map[string]int{"p1": 0, "p2": 0})
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if winner != nil || a.Status != "closed" || len(a.Skipped) != 2 {
+		t.Errorf("Close() = %+v, status %q, skipped %v, want no winner, closed, 2 skipped", winner, a.Status, a.Skipped)
+	}
+}
+
+func TestAuction_HighestBid(t *testing.T) {
+	t0 := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+	bid := func(player string, amount int, after time.Duration) auction.Bid {
+		return auction.Bid{PlayerID: player, Amount: amount, Time: t0.Add(after)}
+	}
+	tests := []struct {
+		name       string
+		bids       []auction.Bid
+		skipped    []string
+		want       *auction.Bid
+		wantSecond *auction.Bid
+	}{
+		{name: "no bids"},
+		{
+			name:       "in order",
+			bids:       []auction.Bid{bid("p1", 20, 0), bid("p2", 30, time.Second)},
+			want:       &auction.Bid{PlayerID: "p2", Amount: 30, Time: t0.Add(time.Second)},
+			wantSecond: &auction.Bid{PlayerID: "p1", Amount: 20, Time: t0},
+		},
+		{
+			// A lower bid recovered after the highest is not the highest,
+			// as the last bid was taken to be.
+			name:       "out of order",
+			bids:       []auction.Bid{bid("p1", 20, 0), bid("p2", 50, 2*time.Second), bid("p3", 40, time.Second)},
+			want:       &auction.Bid{PlayerID: "p2", Amount: 50, Time: t0.Add(2 * time.Second)},
+			wantSecond: &auction.Bid{PlayerID: "p3", Amount: 40, Time: t0.Add(time.Second)},
+		},
+		{
+			name:       "tie goes to the earliest",
+			bids:       []auction.Bid{bid("p1", 40, time.Second), bid("p2", 40, 0)},
+			want:       &auction.Bid{PlayerID: "p2", Amount: 40, Time: t0},
+			wantSecond: &auction.Bid{PlayerID: "p1", Amount: 40, Time: t0.Add(time.Second)},
+		},
+		{
+			name:       "skipped players",
+			bids:       []auction.Bid{bid("p1", 20, 0), bid("p2", 30, time.Second), bid("p3", 40, 2*time.Second)},
+			skipped:    []string{"p3"},
+			want:       &auction.Bid{PlayerID: "p2", Amount: 30, Time: t0.Add(time.Second)},
+			wantSecond: &auction.Bid{PlayerID: "p1", Amount: 20, Time: t0},
+		},
+		{
+			// The second highest bid is another player's, not the
+			// winner's own earlier bid.
+			name:       "second highest of another player",
+			bids:       []auction.Bid{bid("p1", 20, 0), bid("p2", 30, time.Second), bid("p1", 40, 2*time.Second), bid("p2", 25, 3*time.Second)},
+			want:       &auction.Bid{PlayerID: "p1", Amount: 40, Time: t0.Add(2 * time.Second)},
+			wantSecond: &auction.Bid{PlayerID: "p2", Amount: 30, Time: t0.Add(time.Second)},
+		},
+		{
+			name: "single bidder",
+			bids: []auction.Bid{bid("p1", 20, 0), bid("p1", 30, time.Second)},
+			want: &auction.Bid{PlayerID: "p1", Amount: 30, Time: t0.Add(time.Second)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := auction.New("a1", "Sword", "admin", 10, 1, 0, 5*time.Minute, testTP, testClk)
+			a.Bids = tt.bids
+			a.Skipped = tt.skipped
+
+			for _, got := range []struct {
+				name string
+				bid  *auction.Bid
+				want *auction.Bid
+			}{
+				{"HighestBid()", a.HighestBid(), tt.want},
+				{"State().HighestBid()", a.State().HighestBid(), tt.want},
+				{"SecondHighestBid()", a.SecondHighestBid(), tt.wantSecond},
+			} {
+				if (got.bid == nil) != (got.want == nil) || (got.bid != nil && *got.bid != *got.want) {
+					t.Errorf("%s = %+v, want %+v", got.name, got.bid, got.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAuction_PauseResume(t *testing.T) {
+	clk := &clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	a := auction.New("a1", "Sword", "admin", 10, 1, 30, 5*time.Minute, testTP, clk)
+	ctx := context.Background()
+	start := clk.T
+
+	if err := a.Resume(ctx); !errors.Is(err, auction.ErrNotPaused) {
+		t.Errorf("Resume() of an open auction error = %v, want ErrNotPaused", err)
+	}
+	clk.T = clk.T.Add(time.Minute)
+	if err := a.Pause(ctx); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if err := a.Pause(ctx); !errors.Is(err, auction.ErrAuctionPaused) {
+		t.Errorf("second Pause() error = %v, want ErrAuctionPaused", err)
+	}
+	if err := a.PlaceBid(ctx, "p1", 20, 100); !errors.Is(err, auction.ErrAuctionPaused) {
+		t.Errorf("PlaceBid() while paused error = %v, want ErrAuctionPaused", err)
+	}
+	if _, err := a.BuyOut(ctx, "p1", 100); !errors.Is(err, auction.ErrAuctionPaused) {
+		t.Errorf("BuyOut() while paused error = %v, want ErrAuctionPaused", err)
+	}
+
+	// The end moves with the pause.
+	clk.T = clk.T.Add(10 * time.Minute)
+	if got, want := a.EndsAt(), start.Add(15*time.Minute); !got.Equal(want) {
+		t.Errorf("EndsAt() while paused = %v, want %v", got, want)
+	}
+	if err := a.Resume(ctx); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	clk.T = clk.T.Add(time.Minute)
+	if got, want := a.EndsAt(), start.Add(15*time.Minute); !got.Equal(want) {
+		t.Errorf("EndsAt() after resuming = %v, want %v", got, want)
+	}
+	if err := a.PlaceBid(ctx, "p1", 20, 100); err != nil {
+		t.Errorf("PlaceBid() after resuming error = %v", err)
+	}
+
+	replayed, err := auction.Replay(a.PendingEvents())
+	if err != nil {
+		t.Fatalf("Replay() error: %v", err)
+	}
+	if replayed.Status != "open" || replayed.Paused != 10*time.Minute {
+		t.Errorf("replayed status, paused = %q, %v, want open, 10m0s", replayed.Status, replayed.Paused)
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	original := auction.New("timeline-test", "Sword", "admin", 10, 5, 0, 5*time.Minute, testTP, testClk)
+	_ = original.PlaceBid(context.Background(), "p1", 50, 100)
+	_ = original.PlaceBid(context.Background(), "p2", 60, 100)
+	if _, err := original.Close(context.Background(), nil); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	events := original.PendingEvents()
+	for i, s := range auction.Timeline(events) {
+		if s.Err != nil {
+			t.Errorf("step %d error = %v, want none", i, s.Err)
+		}
+	}
+
+	bid := func(player string, amount int) json.RawMessage {
+		data, _ := json.Marshal(event.BidPlacedData{PlayerID: player, Amount: amount})
+		return data
+	}
+	closed, _ := json.Marshal(event.AuctionClosedData{WinnerID: "p1", Amount: 50})
+	tampered := []event.Event{
+		events[0],
+		{AggregateID: "timeline-test", Type: event.AuctionBidPlaced, Data: bid("p1", 50), Version: 2},
+		{AggregateID: "timeline-test", Type: event.AuctionBidPlaced, Data: bid("p2", 52), Version: 3},
+		{AggregateID: "timeline-test", Type: event.AuctionBidPlaced, Data: json.RawMessage(`{`), Version: 4},
+		{AggregateID: "timeline-test", Type: event.AuctionClosed, Data: closed, Version: 6},
+		{AggregateID: "timeline-test", Type: event.AuctionBidPlaced, Data: bid("p3", 90), Version: 7},
+	}
+	steps := auction.Timeline(tampered)
+	wantErr := []bool{false, false, true, true, true, true}
+	for i, s := range steps {
+		if (s.Err != nil) != wantErr[i] {
+			t.Errorf("step %d error = %v, want error %t", i, s.Err, wantErr[i])
+		}
+	}
+	if h := steps[2].HighestBid; h == nil || h.PlayerID != "p2" || h.Amount != 52 {
+		t.Errorf("highest bid after the low bid = %+v, want p2 @ 52", h)
+	}
+	if got := steps[3].State.Version; got != 3 {
+		t.Errorf("version after an undecodable event = %d, want 3", got)
+	}
+	if got := steps[5].State.Status; got != "closed" || len(steps[5].State.Bids) != 3 {
+		t.Errorf("after a bid on the closed auction: status %q with %d bids, want closed with 3", got, len(steps[5].State.Bids))
+	}
+}
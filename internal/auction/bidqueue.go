@@ -0,0 +1,125 @@
+package auction
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+type arrivalCtx struct{}
+
+// WithArrival returns a context carrying the time the request it serves
+// arrived, such as the time of a Discord interaction. Bids waiting on the
+// same auction are placed in the order they arrived.
+func WithArrival(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, arrivalCtx{}, t)
+}
+
+// arrivalFromContext returns the time stored by WithArrival, if any.
+func arrivalFromContext(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(arrivalCtx{}).(time.Time)
+	return t, ok
+}
+
+// bidQueue serializes the bids on an auction. A bid waits its turn while
+// another is placed, and of the bids waiting the one that arrived first
+// goes next, rather than whichever gets hold of the auction first. The
+// zero value is an empty queue.
+type bidQueue struct {
+	mu      sync.Mutex
+	busy    bool
+	waiting tickets
+	seq     uint64
+}
+
+// ticket is a bid's place in a bidQueue. Bids that arrived at the same
+// time go in the order they joined the queue.
+type ticket struct {
+	arrived time.Time
+	seq     uint64
+	ready   chan struct{}
+	index   int
+}
+
+// join queues the bid that arrived at arrived, returning its ticket and
+// the number of bids in the queue as it joined, itself and any being
+// placed included. The bid must then wait for its turn.
+func (q *bidQueue) join(arrived time.Time) (t *ticket, depth int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t = &ticket{arrived: arrived, ready: make(chan struct{})}
+	if !q.busy {
+		q.busy = true
+		close(t.ready)
+		return t, 1
+	}
+	q.seq++
+	t.seq = q.seq
+	heap.Push(&q.waiting, t)
+	return t, len(q.waiting) + 1
+}
+
+// wait returns once it is the turn of t, which must then call done, or
+// once ctx is done.
+func (q *bidQueue) wait(ctx context.Context, t *ticket) error {
+	select {
+	case <-t.ready:
+		return nil
+	case <-ctx.Done():
+	}
+	q.mu.Lock()
+	select {
+	case <-t.ready:
+		// The turn came as ctx was done; hand it on.
+		q.mu.Unlock()
+		q.done()
+	default:
+		heap.Remove(&q.waiting, t.index)
+		q.mu.Unlock()
+	}
+	return ctx.Err()
+}
+
+// done ends the turn of the bid being placed, handing it to the bid that
+// arrived first among those waiting.
+func (q *bidQueue) done() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.waiting) == 0 {
+		q.busy = false
+		return
+	}
+	close(heap.Pop(&q.waiting).(*ticket).ready)
+}
+
+// tickets is a heap of tickets, earliest arrival first.
+type tickets []*ticket
+
+func (h tickets) Len() int { return len(h) }
+
+func (h tickets) Less(i, j int) bool {
+	if c := h[i].arrived.Compare(h[j].arrived); c != 0 {
+		return c < 0
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h tickets) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *tickets) Push(x any) {
+	t := x.(*ticket)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *tickets) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return t
+}
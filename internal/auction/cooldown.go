@@ -0,0 +1,82 @@
+package auction
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+)
+
+// ErrBidCooldown is returned for a bid placed before the bidder's cooldown
+// since their last bid on the auction ended. Errors returned by the Manager
+// say how long to wait and match it under errors.Is.
+var ErrBidCooldown = derrors.New(derrors.Conflict, "BID_COOLDOWN", "you are bidding too fast")
+
+// Bid wars: when the last bidWarBids bids on an auction alternate between
+// the same two players within bidWarWindow, each of them waits the longer
+// bid war cooldown before bidding again.
+const (
+	bidWarBids   = 4
+	bidWarWindow = time.Minute
+)
+
+// cooldownError returns ErrBidCooldown saying to wait for wait, rounded up
+// to a whole second.
+func cooldownError(wait time.Duration) error {
+	secs := int((wait + time.Second - 1) / time.Second)
+	unit := "seconds"
+	if secs == 1 {
+		unit = "second"
+	}
+	return derrors.New(ErrBidCooldown.Kind, ErrBidCooldown.Code, fmt.Sprintf("wait %d %s before bidding again", secs, unit))
+}
+
+// cooldown returns how long playerID must still wait at now before bidding
+// again on the auction: cooldown since their last bid, or warCooldown if
+// they are in a bid war. Zero durations disable either.
+func (a *Auction) cooldown(playerID string, now time.Time, cooldown, warCooldown time.Duration) time.Duration {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	last := -1
+	for i := len(a.Bids) - 1; i >= 0; i-- {
+		if a.Bids[i].PlayerID == playerID {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		return 0
+	}
+	if warCooldown > cooldown && a.inBidWar(playerID) {
+		cooldown = warCooldown
+	}
+	return max(a.Bids[last].Time.Add(cooldown).Sub(now), 0)
+}
+
+// inBidWar reports whether the last bidWarBids bids alternate between
+// playerID and one other player within bidWarWindow. The caller must hold
+// a.mu.
+func (a *Auction) inBidWar(playerID string) bool {
+	if len(a.Bids) < bidWarBids {
+		return false
+	}
+	war := a.Bids[len(a.Bids)-bidWarBids:]
+	if war[len(war)-1].Time.Sub(war[0].Time) > bidWarWindow {
+		return false
+	}
+	rival := ""
+	for i, b := range war {
+		if i > 0 && b.PlayerID == war[i-1].PlayerID {
+			return false
+		}
+		switch {
+		case b.PlayerID == playerID:
+		case rival == "":
+			rival = b.PlayerID
+		case b.PlayerID != rival:
+			return false
+		}
+	}
+	return rival != ""
+}
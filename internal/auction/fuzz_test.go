@@ -0,0 +1,141 @@
+package auction_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+)
+
+// tickClock is a clock that a fuzzed history advances by a second before
+// each operation, so that pauses and bids happen at different times.
+type tickClock struct{ t time.Time }
+
+func (c *tickClock) Now() time.Time { return c.t }
+
+func (c *tickClock) tick() { c.t = c.t.Add(time.Second) }
+
+// fuzzPlayers are the players of fuzzed auctions.
+var fuzzPlayers = []string{"p1", "p2", "p3", "p4"}
+
+// FuzzAuction runs fuzzed sequences of operations on an auction and checks
+// its invariants after each: every accepted bid is at least the minimum
+// bid and exceeds the highest bid by the increment, the highest bid never
+// goes down before the auction closes, nothing changes a closed or
+// canceled auction, and replaying the events the auction recorded rebuilds
+// its state with no event flagged by Timeline.
+func FuzzAuction(f *testing.F) {
+	f.Add(uint8(10), uint8(5), uint8(0), []byte{0, 0, 50, 0, 1, 55, 1, 2, 10, 5, 0, 30, 0, 3, 90})
+	f.Add(uint8(10), uint8(1), uint8(100), []byte{0, 0, 20, 2, 0, 0, 3, 0, 0, 4, 1, 0, 0, 1, 30})
+	f.Add(uint8(0), uint8(0), uint8(0), []byte{0, 0, 20, 0, 1, 25, 5, 0, 0})
+	f.Add(uint8(5), uint8(1), uint8(0), []byte{6, 0, 0, 7, 0, 90, 7, 1, 40, 7, 1, 50, 5, 0, 0})
+	f.Add(uint8(5), uint8(2), uint8(0), []byte{0, 0, 5, 0, 1, 9, 5, 1, 0, 8, 0, 0, 0, 2, 200})
+
+	f.Fuzz(func(t *testing.T, minBid, increment, buyout uint8, ops []byte) {
+		ctx := context.Background()
+		clk := &tickClock{t: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+		price := int(buyout)
+		if price > 0 {
+			price += int(minBid)
+		}
+		a := auction.New("fuzz-auction", "Sword", "admin", int(minBid), int(increment), price, 5*time.Minute, testTP, clk)
+		events := a.PendingEvents()
+		for j := range events {
+			events[j].CreatedAt = clk.Now()
+		}
+
+		for i := 0; i+2 < len(ops); i += 3 {
+			op, player, value := ops[i]%9, fuzzPlayers[int(ops[i+1])%len(fuzzPlayers)], int(ops[i+2])
+			// Balances are large enough for most bids, but not all.
+			dkp := 4 * value
+			clk.tick()
+			before := a.State()
+			prev := a.HighestBid()
+
+			var err error
+			switch op {
+			case 0:
+				err = a.PlaceBid(ctx, player, value, dkp)
+			case 1:
+				_, err = a.Raise(ctx, player, value%20+1, dkp)
+			case 2:
+				err = a.Pause(ctx)
+			case 3:
+				err = a.Resume(ctx)
+			case 4:
+				_, err = a.BuyOut(ctx, player, dkp)
+			case 5:
+				balances := map[string]int{}
+				for j, p := range fuzzPlayers {
+					balances[p] = value * (j + 1)
+				}
+				_, err = a.Close(ctx, balances)
+			case 6:
+				if !a.StartRoll(ctx, clk.t.Add(time.Minute)) {
+					err = auction.ErrNotRolling
+				}
+			case 7:
+				err = a.Roll(ctx, player, dkp, value)
+			case 8:
+				err = a.Cancel(ctx)
+			}
+			recorded := a.PendingEvents()
+			for j := range recorded {
+				// The store stamps events as they are appended.
+				recorded[j].CreatedAt = clk.Now()
+			}
+			events = append(events, recorded...)
+			after := a.State()
+
+			if before.Status == "closed" || before.Status == "canceled" {
+				if err == nil || len(recorded) > 0 || stateJSON(t, after) != stateJSON(t, before) {
+					t.Fatalf("op %d changed the %s auction: error %v, events %v", op, before.Status, err, recorded)
+				}
+				continue
+			}
+			if err != nil && len(recorded) > 0 {
+				t.Fatalf("op %d failed with %v but recorded %v", op, err, recorded)
+			}
+			if err == nil && (op == 0 || op == 1) {
+				bid := after.Bids[len(after.Bids)-1]
+				if bid.Amount < after.MinBid {
+					t.Fatalf("accepted bid of %d below the minimum bid of %d", bid.Amount, after.MinBid)
+				}
+				if prev != nil && bid.Amount < prev.Amount+after.MinIncrement {
+					t.Fatalf("accepted bid of %d that does not exceed %d by %d", bid.Amount, prev.Amount, after.MinIncrement)
+				}
+			}
+			// Closing skips winners who can no longer pay, so only
+			// closing may lower the highest bid.
+			if h := a.HighestBid(); op != 5 && prev != nil && (h == nil || h.Amount < prev.Amount) {
+				t.Fatalf("op %d lowered the highest bid from %+v to %+v", op, prev, h)
+			}
+		}
+
+		replayed, err := auction.Replay(events)
+		if err != nil {
+			t.Fatalf("Replay() error = %v", err)
+		}
+		if got, want := stateJSON(t, replayed.State()), stateJSON(t, a.State()); got != want {
+			t.Fatalf("replayed state = %s, want %s", got, want)
+		}
+		for _, s := range auction.Timeline(events) {
+			if s.Err != nil {
+				t.Fatalf("Timeline() flags version %d %s: %v", s.Event.Version, s.Event.Type, s.Err)
+			}
+		}
+	})
+}
+
+// stateJSON returns s as JSON, to compare states whose times went through
+// JSON.
+func stateJSON(t *testing.T, s auction.State) string {
+	t.Helper()
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
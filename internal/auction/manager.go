@@ -2,16 +2,30 @@ package auction
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/jensholdgaard/discord-dkp-bot/internal/announce"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/gdkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/ids"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/settings"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
 )
 
@@ -19,6 +33,11 @@ import (
 type Manager struct {
 	mu       sync.RWMutex
 	auctions map[string]*Auction
+	// queue holds the auctions waiting for a slot under the guild's limit
+	// of open auctions, oldest first.
+	queue []*Auction
+	// scheduled holds the auctions waiting for their start time.
+	scheduled []*Auction
 
 	events  event.Store
 	players store.PlayerRepository
@@ -26,11 +45,126 @@ type Manager struct {
 	tracer  trace.Tracer
 	tp      trace.TracerProvider
 	clock   clock.Clock
+	ids     ids.Generator
+	dedup   *idempotency.Guard
+	metrics *metrics.Recorder
+	// roll returns a roll from 1 to 100.
+	roll func() int
+
+	settings *settings.Service
+	guildID  string
+	raids    *gdkp.Service
+	ledger   *dkp.Manager
+	tax      config.TaxConfig
+	lootBans []LootBans
+}
+
+// ErrPointsInGDKP is returned for an auction priced in points during a
+// GDKP raid, whose auctions are bid on in gold.
+var ErrPointsInGDKP = derrors.New(derrors.Conflict, "POINTS_IN_GDKP", "auctions in a GDKP raid are bid on in gold")
+
+// ErrPastStart is returned for an auction scheduled to start at a time
+// that has passed.
+var ErrPastStart = derrors.New(derrors.Validation, "PAST_AUCTION_START", "the auction must start in the future")
+
+// ErrLootBanned is returned for bids by players under a loot ban. Errors
+// returned by the Manager say when the ban ends and match it under
+// errors.Is.
+var ErrLootBanned = derrors.New(derrors.Permission, "LOOT_BANNED", "you are banned from loot")
+
+// LootBans reports the loot bans officers put players under.
+type LootBans interface {
+	// LootBan returns when the loot ban of the player playerID ends and
+	// the reason to tell them, which may be empty, or the zero time if
+	// they are not banned.
+	LootBan(ctx context.Context, playerID string) (until time.Time, reason string, err error)
+}
+
+// DefaultDuration is the duration of auctions started without one, unless
+// the guild settings say otherwise.
+const DefaultDuration = 5 * time.Minute
+
+// Option configures optional Manager collaborators.
+type Option func(*Manager)
+
+// WithIdempotency makes state-changing operations deduplicate on the
+// idempotency key carried in their context.
+func WithIdempotency(g *idempotency.Guard) Option {
+	return func(m *Manager) { m.dedup = g }
+}
+
+// WithMetrics records bids and the auction lifecycle on r.
+func WithMetrics(r *metrics.Recorder) Option {
+	return func(m *Manager) { m.metrics = r }
+}
+
+// WithRolls makes roll generate the rolls of rolling auctions instead of
+// the random number generator, for tests.
+func WithRolls(roll func() int) Option {
+	return func(m *Manager) { m.roll = roll }
+}
+
+// WithSettings applies the default duration and minimum increment from the
+// settings of guildID to new auctions, holds a roll for the item of
+// auctions closed without bids if the guild's roll window is set, and
+// queues auctions beyond the guild's limit of open auctions.
+func WithSettings(svc *settings.Service, guildID string) Option {
+	return func(m *Manager) { m.settings, m.guildID = svc, guildID }
+}
+
+// WithGDKP holds auctions started during a raid of raids in that raid. The
+// auctions of a GDKP raid are bid on in gold rather than DKP.
+func WithGDKP(raids *gdkp.Service) Option {
+	return func(m *Manager) { m.raids = raids }
+}
+
+// WithLedger lets auctions be bid on in the currencies of ledger besides
+// DKP, checking bidders' balances in them.
+func WithLedger(ledger *dkp.Manager) Option {
+	return func(m *Manager) { m.ledger = ledger }
+}
+
+// WithTax charges the winners of DKP auctions the tax of cfg through the
+// ledger of WithLedger, which it needs.
+func WithTax(cfg config.TaxConfig) Option {
+	return func(m *Manager) { m.tax = cfg }
+}
+
+// WithIDs gives new auctions IDs from gen rather than ULIDs.
+func WithIDs(gen ids.Generator) Option {
+	return func(m *Manager) { m.ids = gen }
+}
+
+// WithLootBans rejects the bids, buyouts, and rolls of players under a loot
+// ban recorded in bans. It may be given more than once; a player is banned
+// while any of them says so.
+func WithLootBans(bans LootBans) Option {
+	return func(m *Manager) { m.lootBans = append(m.lootBans, bans) }
+}
+
+// StartOption configures an auction started by StartAuction.
+type StartOption func(*startOptions)
+
+type startOptions struct {
+	currency string
+	startsAt time.Time
+}
+
+// InCurrency prices the auction in the currency named name rather than
+// DKP. Currencies besides DKP need WithLedger.
+func InCurrency(name string) StartOption {
+	return func(o *startOptions) { o.currency = name }
+}
+
+// StartingAt schedules the auction to open at t, which must be in the
+// future, rather than at once. Until then it has status "scheduled".
+func StartingAt(t time.Time) StartOption {
+	return func(o *startOptions) { o.startsAt = t }
 }
 
 // NewManager creates a new auction Manager.
-func NewManager(events event.Store, players store.PlayerRepository, logger *slog.Logger, tp trace.TracerProvider, clk clock.Clock) *Manager {
-	return &Manager{
+func NewManager(events event.Store, players store.PlayerRepository, logger *slog.Logger, tp trace.TracerProvider, clk clock.Clock, opts ...Option) *Manager {
+	m := &Manager{
 		auctions: make(map[string]*Auction),
 		events:   events,
 		players:  players,
@@ -38,11 +172,26 @@ func NewManager(events event.Store, players store.PlayerRepository, logger *slog
 		tracer:   tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/auction"),
 		tp:       tp,
 		clock:    clk,
+		ids:      ids.NewULID(clk),
+		metrics:  metrics.Nop(),
+		roll:     func() int { return rand.IntN(100) + 1 },
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
-// StartAuction creates and tracks a new auction.
-func (m *Manager) StartAuction(ctx context.Context, itemName, startedBy string, minBid int, duration time.Duration) (*Auction, error) {
+// StartAuction creates and tracks a new auction. If duration is not
+// positive, the guild's default duration is used. A positive buyout, which
+// must exceed minBid, lets a player win the item at that price at once. A
+// positive reserve, which must not exceed the buyout, is the hidden lowest
+// price the item is sold at: if the highest bid is below it at close, the
+// auction closes without a winner. If the guild's limit of open auctions
+// is reached, or other auctions are already queued, the auction is queued
+// with status "queued" and starts when an open auction ends. A scheduled
+// auction waits for OpenScheduled to open it.
+func (m *Manager) StartAuction(ctx context.Context, itemName, startedBy string, minBid, buyout, reserve int, duration time.Duration, opts ...StartOption) (*Auction, error) {
 	ctx, span := m.tracer.Start(ctx, "Manager.StartAuction",
 		trace.WithAttributes(
 			attribute.String("item", itemName),
@@ -51,18 +200,130 @@ func (m *Manager) StartAuction(ctx context.Context, itemName, startedBy string,
 	)
 	defer span.End()
 
-	id := fmt.Sprintf("auction-%d", m.clock.Now().UnixNano())
-	a := New(id, itemName, startedBy, minBid, duration, m.tp, m.clock)
+	id, err := idempotency.Do(ctx, m.dedup, "auction.start", func(ctx context.Context) (string, error) {
+		var o startOptions
+		for _, opt := range opts {
+			opt(&o)
+		}
+		a, err := m.startAuction(ctx, itemName, startedBy, minBid, buyout, reserve, duration, o)
+		if err != nil {
+			return "", err
+		}
+		return a.ID, nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	// Persist initial events.
-	if err := m.events.Append(ctx, a.PendingEvents()...); err != nil {
-		return nil, fmt.Errorf("persisting auction started events: %w", err)
+	// A redelivered interaction yields the ID recorded by the first run.
+	m.mu.RLock()
+	a, ok := m.auctions[id]
+	if !ok {
+		a, ok = m.pending(id)
+	}
+	m.mu.RUnlock()
+	if ok {
+		return a, nil
+	}
+	return m.ReplayAuction(ctx, id)
+}
+
+func (m *Manager) startAuction(ctx context.Context, itemName, startedBy string, minBid, buyout, reserve int, duration time.Duration, o startOptions) (*Auction, error) {
+	if buyout < 0 || buyout > 0 && buyout <= minBid {
+		return nil, ErrInvalidBuyout
+	}
+	if reserve < 0 || buyout > 0 && reserve > buyout {
+		return nil, ErrInvalidReserve
+	}
+	if !o.startsAt.IsZero() && !o.startsAt.After(m.clock.Now()) {
+		return nil, ErrPastStart
+	}
+	increment, limit := 1, 0
+	if m.settings != nil {
+		gs, err := m.settings.Get(ctx, m.guildID)
+		if err != nil {
+			return nil, err
+		}
+		increment, limit = gs.MinIncrement, gs.MaxOpenAuctions
+		if duration <= 0 {
+			duration = gs.AuctionDuration
+		}
+	}
+	if duration <= 0 {
+		duration = DefaultDuration
+	}
+	var raidID, raidMode string
+	if m.raids != nil {
+		r, err := m.raids.Active(ctx)
+		switch {
+		case err == nil:
+			raidID = r.ID
+			if !r.Gold() {
+				raidMode = r.Mode
+			}
+		case !errors.Is(err, gdkp.ErrNoRaid):
+			return nil, err
+		}
+	}
+	points, err := m.points(o.currency)
+	if err != nil {
+		return nil, err
+	}
+	if points != "" && gold(raidID, raidMode) {
+		return nil, ErrPointsInGDKP
 	}
 
+	id := "auction-" + m.ids.NewID()
+	if !o.startsAt.IsZero() {
+		a := scheduleAuction(id, itemName, startedBy, raidID, raidMode, points, minBid, increment, buyout, reserve, duration, o.startsAt, m.tp, m.clock)
+		if err := m.events.Append(ctx, a.PendingEvents()...); err != nil {
+			return nil, fmt.Errorf("persisting auction scheduled events: %w", err)
+		}
+		m.mu.Lock()
+		m.scheduled = append(m.scheduled, a)
+		m.mu.Unlock()
+
+		m.logger.InfoContext(ctx, "auction scheduled",
+			slog.String("auction_id", id),
+			slog.String("item", itemName),
+			slog.Time("starts_at", a.StartsAt),
+		)
+		return a, nil
+	}
+	// The slot is taken under the same lock the limit is checked under, so
+	// concurrent starts cannot open more auctions than the limit allows. It
+	// is given back if the auction's events cannot be persisted.
 	m.mu.Lock()
-	m.auctions[id] = a
+	full := len(m.queue) > 0 || limit > 0 && len(m.auctions) >= limit
+	var a *Auction
+	if full {
+		a = queueAuction(id, itemName, startedBy, raidID, raidMode, points, minBid, increment, buyout, reserve, duration, m.tp, m.clock)
+		m.queue = append(m.queue, a)
+	} else {
+		a = newAuction(id, itemName, startedBy, raidID, raidMode, points, minBid, increment, buyout, reserve, duration, m.tp, m.clock)
+		m.auctions[id] = a
+	}
 	m.mu.Unlock()
 
+	if err := m.events.Append(ctx, a.PendingEvents()...); err != nil {
+		m.mu.Lock()
+		m.queue = slices.DeleteFunc(m.queue, func(q *Auction) bool { return q == a })
+		delete(m.auctions, id)
+		m.mu.Unlock()
+		if full {
+			return nil, fmt.Errorf("persisting auction queued events: %w", err)
+		}
+		return nil, fmt.Errorf("persisting auction started events: %w", err)
+	}
+	if full {
+		m.logger.InfoContext(ctx, "auction queued",
+			slog.String("auction_id", id),
+			slog.String("item", itemName),
+		)
+		return a, nil
+	}
+	m.metrics.AuctionOpened(ctx)
+
 	m.logger.InfoContext(ctx, "auction started",
 		slog.String("auction_id", id),
 		slog.String("item", itemName),
@@ -70,6 +331,159 @@ func (m *Manager) StartAuction(ctx context.Context, itemName, startedBy string,
 	return a, nil
 }
 
+// points returns the name of the currency besides DKP named name, or
+// empty for DKP.
+func (m *Manager) points(name string) (string, error) {
+	if name == "" || strings.EqualFold(name, dkp.DKP) {
+		return "", nil
+	}
+	if m.ledger == nil {
+		return "", dkp.ErrUnknownCurrency.Wrap(fmt.Errorf("currency %q", name))
+	}
+	name, err := m.ledger.Currency(name)
+	if err != nil || name == dkp.DKP {
+		return "", err
+	}
+	return name, nil
+}
+
+// pending returns the queued or scheduled auction id. m.mu must be held.
+func (m *Manager) pending(id string) (*Auction, bool) {
+	for _, a := range slices.Concat(m.queue, m.scheduled) {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// openLimit returns the guild's limit of open auctions, or zero if there
+// is none.
+func (m *Manager) openLimit(ctx context.Context) (int, error) {
+	if m.settings == nil {
+		return 0, nil
+	}
+	gs, err := m.settings.Get(ctx, m.guildID)
+	if err != nil {
+		return 0, err
+	}
+	return gs.MaxOpenAuctions, nil
+}
+
+// startQueued starts queued auctions, oldest first, while the guild's limit
+// of open auctions allows, and returns the states of those it started.
+func (m *Manager) startQueued(ctx context.Context) []State {
+	m.mu.RLock()
+	empty := len(m.queue) == 0
+	m.mu.RUnlock()
+	if empty {
+		return nil
+	}
+	limit, err := m.openLimit(ctx)
+	if err != nil {
+		m.logger.ErrorContext(ctx, "loading settings to start queued auctions", slog.Any("error", err))
+		return nil
+	}
+
+	var started []State
+	for {
+		m.mu.Lock()
+		if len(m.queue) == 0 || limit > 0 && len(m.auctions) >= limit {
+			m.mu.Unlock()
+			return started
+		}
+		a := m.queue[0]
+		m.queue = m.queue[1:]
+		m.auctions[a.ID] = a
+		m.mu.Unlock()
+
+		if err := a.start(ctx); err != nil {
+			m.logger.WarnContext(ctx, "starting queued auction", slog.String("auction_id", a.ID), slog.Any("error", err))
+			continue
+		}
+		if err := m.events.Append(ctx, a.PendingEvents()...); err != nil {
+			m.logger.ErrorContext(ctx, "failed to persist auction started event", slog.Any("error", err))
+		}
+		m.metrics.AuctionOpened(ctx)
+		m.logger.InfoContext(ctx, "queued auction started",
+			slog.String("auction_id", a.ID),
+			slog.String("item", a.ItemName),
+		)
+		started = append(started, a.State())
+	}
+}
+
+// OpenScheduled opens the scheduled auctions whose start time has come,
+// earliest first, and returns the states of those it opened. A due auction
+// is queued instead while the guild's limit of open auctions is reached or
+// other auctions are queued, and starts when a slot frees up.
+func (m *Manager) OpenScheduled(ctx context.Context) []State {
+	ctx, span := m.tracer.Start(ctx, "Manager.OpenScheduled")
+	defer span.End()
+
+	now := m.clock.Now()
+	var due []*Auction
+	m.mu.Lock()
+	m.scheduled = slices.DeleteFunc(m.scheduled, func(a *Auction) bool {
+		if a.StartsAt.After(now) {
+			return false
+		}
+		due = append(due, a)
+		return true
+	})
+	m.mu.Unlock()
+	if len(due) == 0 {
+		return nil
+	}
+	slices.SortFunc(due, func(a, b *Auction) int { return a.StartsAt.Compare(b.StartsAt) })
+	limit, err := m.openLimit(ctx)
+	if err != nil {
+		// They are opened on the next call.
+		m.logger.ErrorContext(ctx, "loading settings to open scheduled auctions", slog.Any("error", err))
+		m.mu.Lock()
+		m.scheduled = append(m.scheduled, due...)
+		m.mu.Unlock()
+		return nil
+	}
+
+	var started []State
+	for _, a := range due {
+		m.mu.Lock()
+		full := len(m.queue) > 0 || limit > 0 && len(m.auctions) >= limit
+		if full {
+			m.queue = append(m.queue, a)
+		} else {
+			m.auctions[a.ID] = a
+		}
+		m.mu.Unlock()
+
+		if full {
+			if err := a.enqueue(); err != nil {
+				m.logger.WarnContext(ctx, "queueing scheduled auction", slog.String("auction_id", a.ID), slog.Any("error", err))
+				continue
+			}
+		} else if err := a.start(ctx); err != nil {
+			m.logger.WarnContext(ctx, "opening scheduled auction", slog.String("auction_id", a.ID), slog.Any("error", err))
+			continue
+		}
+		if err := m.events.Append(ctx, a.PendingEvents()...); err != nil {
+			m.logger.ErrorContext(ctx, "failed to persist scheduled auction event", slog.Any("error", err))
+		}
+		if full {
+			m.logger.InfoContext(ctx, "scheduled auction queued", slog.String("auction_id", a.ID))
+			continue
+		}
+		m.metrics.AuctionOpened(ctx)
+		m.logger.InfoContext(ctx, "scheduled auction opened",
+			slog.String("auction_id", a.ID),
+			slog.String("item", a.ItemName),
+		)
+		started = append(started, a.State())
+	}
+	span.SetAttributes(attribute.Int("opened", len(started)))
+	return started
+}
+
 // PlaceBid places a bid on an active auction.
 func (m *Manager) PlaceBid(ctx context.Context, auctionID, discordID string, amount int) error {
 	ctx, span := m.tracer.Start(ctx, "Manager.PlaceBid",
@@ -81,90 +495,788 @@ func (m *Manager) PlaceBid(ctx context.Context, auctionID, discordID string, amo
 	)
 	defer span.End()
 
+	_, err := idempotency.Do(ctx, m.dedup, "auction.bid", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, m.placeBid(ctx, auctionID, discordID, amount)
+	})
+	return err
+}
+
+func (m *Manager) placeBid(ctx context.Context, auctionID, discordID string, amount int) error {
+	_, err := m.bid(ctx, auctionID, discordID, func(a *Auction, player *store.Player) (int, error) {
+		balance, err := m.balance(ctx, a, player)
+		if err != nil {
+			return 0, err
+		}
+		return amount, a.PlaceBid(ctx, player.ID, amount, balance)
+	})
+	return err
+}
+
+// ConfirmBid reports whether the player registered as discordID should
+// confirm a bid of amount on the auction auctionID before it is placed,
+// because it is more than the guild's confirm_bid_percent of what they hold
+// of the auction's currency, and returns that balance. Bids in gold, which
+// is paid in game, and bids that will be rejected anyway need no
+// confirmation.
+func (m *Manager) ConfirmBid(ctx context.Context, auctionID, discordID string, amount int) (confirm bool, balance int, err error) {
+	if m.settings == nil {
+		return false, 0, nil
+	}
+	m.mu.RLock()
+	a, ok := m.auctions[auctionID]
+	m.mu.RUnlock()
+	if !ok || gold(a.RaidID, a.RaidMode) {
+		return false, 0, nil
+	}
+	gs, err := m.settings.Get(ctx, m.guildID)
+	if err != nil || gs.ConfirmBidPercent == 0 {
+		return false, 0, err
+	}
+	player, err := m.bidder(ctx, discordID)
+	if err != nil {
+		return false, 0, nil
+	}
+	if balance, err = m.balance(ctx, a, player); err != nil {
+		return false, 0, err
+	}
+	return amount > 0 && amount <= balance && amount*100 > balance*gs.ConfirmBidPercent, balance, nil
+}
+
+// RaiseBid bids by more than the highest bid on an active auction, or its
+// minimum bid if there is none, and returns the amount bid.
+func (m *Manager) RaiseBid(ctx context.Context, auctionID, discordID string, by int) (int, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.RaiseBid",
+		trace.WithAttributes(
+			attribute.String("auction_id", auctionID),
+			attribute.String("discord_id", discordID),
+			attribute.Int("raise", by),
+		),
+	)
+	defer span.End()
+
+	return idempotency.Do(ctx, m.dedup, "auction.raise", func(ctx context.Context) (int, error) {
+		return m.bid(ctx, auctionID, discordID, func(a *Auction, player *store.Player) (int, error) {
+			balance, err := m.balance(ctx, a, player)
+			if err != nil {
+				return 0, err
+			}
+			return a.Raise(ctx, player.ID, by, balance)
+		})
+	})
+}
+
+// bid places the bid made by place on the auction auctionID for the player
+// registered as discordID, and returns its amount.
+func (m *Manager) bid(ctx context.Context, auctionID, discordID string, place func(*Auction, *store.Player) (int, error)) (int, error) {
 	m.mu.RLock()
 	a, ok := m.auctions[auctionID]
 	m.mu.RUnlock()
 
 	if !ok {
-		return fmt.Errorf("auction %s not found", auctionID)
+		return 0, store.ErrAuctionNotFound.Wrap(fmt.Errorf("auction %s", auctionID))
 	}
 
 	// Look up the player to verify DKP.
-	player, err := m.players.GetByDiscordID(ctx, discordID)
+	player, err := m.bidder(ctx, discordID)
 	if err != nil {
-		return fmt.Errorf("player not registered: %w", err)
+		return 0, err
 	}
 
-	if err := a.PlaceBid(ctx, player.ID, amount, player.DKP); err != nil {
-		return err
+	// Bids wait their turn in the order they arrived, and are placed and
+	// persisted one at a time.
+	arrived, ok := arrivalFromContext(ctx)
+	if !ok {
+		arrived = m.clock.Now()
+	}
+	t, depth := a.queue.join(arrived)
+	m.metrics.BidQueued(ctx, depth)
+	queued := m.clock.Now()
+	err = a.queue.wait(ctx, t)
+	m.metrics.BidDequeued(ctx, m.clock.Now().Sub(queued))
+	if err != nil {
+		return 0, err
+	}
+	defer a.queue.done()
+
+	if err := m.checkCooldown(ctx, a, player.ID); err != nil {
+		return 0, err
+	}
+	amount, err := place(a, player)
+	if err != nil {
+		return 0, err
 	}
+	m.metrics.BidPlaced(ctx)
 
 	// Persist bid event.
 	if err := m.events.Append(ctx, a.PendingEvents()...); err != nil {
 		m.logger.ErrorContext(ctx, "failed to persist bid event", slog.Any("error", err))
 	}
 
+	return amount, nil
+}
+
+// checkCooldown returns an error saying how long to wait if playerID bid
+// on a within the guild's bid cooldown, or its bid war cooldown if they are
+// in a bid war.
+func (m *Manager) checkCooldown(ctx context.Context, a *Auction, playerID string) error {
+	if m.settings == nil {
+		return nil
+	}
+	gs, err := m.settings.Get(ctx, m.guildID)
+	if err != nil {
+		return err
+	}
+	if wait := a.cooldown(playerID, m.clock.Now(), gs.BidCooldown, gs.BidWarCooldown); wait > 0 {
+		m.metrics.BidThrottled(ctx)
+		return cooldownError(wait)
+	}
 	return nil
 }
 
-// CloseAuction closes an auction and returns a result message.
-func (m *Manager) CloseAuction(ctx context.Context, auctionID string) (string, error) {
+// bidder returns the player registered as discordID, who may bid on
+// auctions unless they are archived or banned from loot.
+func (m *Manager) bidder(ctx context.Context, discordID string) (*store.Player, error) {
+	player, err := m.players.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return nil, fmt.Errorf("player not registered: %w", err)
+	}
+	if player.Archived() {
+		return nil, store.ErrPlayerArchived
+	}
+	for _, bans := range m.lootBans {
+		until, reason, err := bans.LootBan(ctx, player.ID)
+		if err != nil {
+			return nil, fmt.Errorf("checking loot ban: %w", err)
+		}
+		if until.IsZero() {
+			continue
+		}
+		msg := fmt.Sprintf("you are banned from loot until <t:%d:F>", until.Unix())
+		if reason != "" {
+			msg += ": " + reason
+		}
+		return nil, derrors.New(ErrLootBanned.Kind, ErrLootBanned.Code, msg)
+	}
+	return player, nil
+}
+
+// CloseResult is the outcome of closing an auction.
+type CloseResult struct {
+	// ItemName is the item of the closed auction.
+	ItemName string `json:"item_name,omitempty"`
+	// Message announces the winner. It is empty if the auction closed
+	// without one.
+	Message string `json:"message"`
+	// RollUntil is set if the auction had no bids and a roll was started
+	// instead of closing it. Players may roll until then; closing the
+	// auction again ends the roll.
+	RollUntil time.Time `json:"roll_until,omitzero"`
+	// Started lists the queued auctions started in the slot the auction
+	// freed.
+	Started []State `json:"started,omitempty"`
+}
+
+// CloseAuction closes an auction. An open auction without bids starts a
+// roll instead if the guild's roll window is set, and a rolling auction is
+// awarded to the highest roll.
+func (m *Manager) CloseAuction(ctx context.Context, auctionID string) (CloseResult, error) {
 	ctx, span := m.tracer.Start(ctx, "Manager.CloseAuction",
 		trace.WithAttributes(attribute.String("auction_id", auctionID)),
 	)
 	defer span.End()
 
+	return idempotency.Do(ctx, m.dedup, "auction.close", func(ctx context.Context) (CloseResult, error) {
+		return m.closeAuction(ctx, auctionID)
+	})
+}
+
+func (m *Manager) closeAuction(ctx context.Context, auctionID string) (CloseResult, error) {
 	m.mu.RLock()
 	a, ok := m.auctions[auctionID]
 	m.mu.RUnlock()
 
 	if !ok {
-		return "", fmt.Errorf("auction %s not found", auctionID)
+		return CloseResult{}, store.ErrAuctionNotFound.Wrap(fmt.Errorf("auction %s", auctionID))
 	}
 
-	winner, err := a.Close(ctx)
+	window, err := m.rollWindow(ctx)
 	if err != nil {
-		return "", err
+		return CloseResult{}, err
+	}
+	if window > 0 && a.StartRoll(ctx, m.clock.Now().Add(window)) {
+		if err := m.events.Append(ctx, a.PendingEvents()...); err != nil {
+			m.logger.ErrorContext(ctx, "failed to persist roll started event", slog.Any("error", err))
+		}
+		m.logger.InfoContext(ctx, "auction roll started",
+			slog.String("auction_id", auctionID),
+			slog.Duration("window", window),
+		)
+		return CloseResult{RollUntil: a.State().RollUntil}, nil
 	}
 
+	// Balances may have changed since the bids were placed.
+	var balances map[string]int
+	if a.HighestBid() != nil {
+		if balances, err = m.balances(ctx, a); err != nil {
+			return CloseResult{}, err
+		}
+	}
+	winner, err := a.Close(ctx, balances)
+	if err != nil {
+		return CloseResult{}, err
+	}
+	m.metrics.AuctionClosed(ctx, m.clock.Now().Sub(a.StartedAt))
+
 	// Persist close event.
 	if err := m.events.Append(ctx, a.PendingEvents()...); err != nil {
 		m.logger.ErrorContext(ctx, "failed to persist close event", slog.Any("error", err))
 	}
+	var taxed string
+	if winner != nil {
+		taxed = m.chargeTax(ctx, a, winner)
+	}
 
 	// Clean up.
 	m.mu.Lock()
 	delete(m.auctions, auctionID)
 	m.mu.Unlock()
 
-	if winner == nil {
-		return "", nil
+	result := CloseResult{ItemName: a.ItemName, Started: m.startQueued(ctx)}
+	skipped := len(a.State().Skipped)
+	switch r := a.HighestRoll(); {
+	case winner == nil && a.State().ReserveNotMet:
+		result.Message = fmt.Sprintf("Auction `%s` closed without a winner: the reserve price was not met.", auctionID)
+	case winner == nil && skipped > 0:
+		result.Message = fmt.Sprintf("Auction `%s` closed without a winner: no bidder can still afford their bid.", auctionID)
+	case winner == nil:
+	case r != nil:
+		result.Message = fmt.Sprintf("Auction `%s` closed! Winner: **%s** with a roll of **%d**, for **%d %s**", auctionID, winner.PlayerID, r.Value, winner.Amount, a.Currency())
+	default:
+		result.Message = m.winnerMessage(ctx, a, winner)
+		if skipped > 0 {
+			result.Message += fmt.Sprintf(" (skipped %d higher bidders who can no longer afford their bids)", skipped)
+		}
 	}
+	result.Message += taxed
+	return result, nil
+}
 
-	return fmt.Sprintf("Auction `%s` closed! Winner: **%s** with **%d DKP**", auctionID, winner.PlayerID, winner.Amount), nil
+// winnerMessage announces winner as the winner of the auction a, worded
+// by the guild's winner_message setting.
+func (m *Manager) winnerMessage(ctx context.Context, a *Auction, winner *Bid) string {
+	var text string
+	if m.settings != nil {
+		gs, err := m.settings.Get(ctx, m.guildID)
+		if err != nil {
+			m.logger.WarnContext(ctx, "loading guild settings for winner announcement failed", slog.Any("error", err))
+		}
+		text = gs.WinnerMessage
+	}
+	return announce.Winner.Render(text, announce.Vars{
+		"item":     a.ItemName,
+		"id":       a.ID,
+		"winner":   winner.PlayerID,
+		"amount":   strconv.Itoa(winner.Amount),
+		"currency": a.Currency(),
+	})
 }
 
-// ReplayAuction reconstructs an auction from stored events.
+// chargeTax charges winner of the DKP auction a the tax on their
+// winning bid, sharing it among the other participants of the auction's
+// raid if configured, and returns a note on it for the close message. A
+// tax that cannot be charged is logged.
+func (m *Manager) chargeTax(ctx context.Context, a *Auction, winner *Bid) string {
+	if m.tax.Rate <= 0 || m.ledger == nil || a.Currency() != dkp.DKP {
+		return ""
+	}
+	amount := int(float64(winner.Amount) * m.tax.Rate / 100)
+	if amount <= 0 {
+		return ""
+	}
+	var recipients []string
+	if m.tax.Redistribute && a.RaidID != "" && m.raids != nil {
+		r, err := m.raids.Get(ctx, a.RaidID)
+		if err != nil {
+			// The tax is burned instead.
+			m.logger.WarnContext(ctx, "loading raid to share auction tax", slog.String("auction_id", a.ID), slog.Any("error", err))
+		} else {
+			for _, discordID := range r.Participants {
+				p, err := m.players.GetByDiscordID(ctx, discordID)
+				if err != nil || p.Archived() || p.ID == winner.PlayerID {
+					continue
+				}
+				recipients = append(recipients, p.ID)
+			}
+		}
+	}
+	recipients, share, err := m.ledger.Tax(ctx, a.ID, winner.PlayerID, amount, recipients)
+	if err != nil {
+		m.logger.ErrorContext(ctx, "charging auction tax failed",
+			slog.String("auction_id", a.ID),
+			slog.String("player_id", winner.PlayerID),
+			slog.Any("error", err),
+		)
+		return ""
+	}
+	a.recordTax(event.AuctionTaxedData{
+		WinnerID:   winner.PlayerID,
+		Rate:       m.tax.Rate,
+		Amount:     amount,
+		Recipients: recipients,
+		Share:      share,
+	})
+	if err := m.events.Append(ctx, a.PendingEvents()...); err != nil {
+		m.logger.ErrorContext(ctx, "failed to persist tax event", slog.Any("error", err))
+	}
+	if len(recipients) > 0 {
+		return fmt.Sprintf(" A tax of **%d DKP** was charged and shared among %d raiders, %d DKP each.", amount, len(recipients), share)
+	}
+	return fmt.Sprintf(" A tax of **%d DKP** was charged and burned.", amount)
+}
+
+// balance returns what player holds of the currency a is bid in.
+func (m *Manager) balance(ctx context.Context, a *Auction, player *store.Player) (int, error) {
+	if a.Points == "" {
+		return player.DKP, nil
+	}
+	if m.ledger == nil {
+		return 0, dkp.ErrUnknownCurrency.Wrap(fmt.Errorf("currency %q", a.Points))
+	}
+	balance, err := m.ledger.Balance(ctx, a.Points, player.ID)
+	if err != nil {
+		return 0, fmt.Errorf("loading %s balance: %w", a.Points, err)
+	}
+	return balance, nil
+}
+
+// balances returns what every player holds of the currency a is bid in,
+// by player ID.
+func (m *Manager) balances(ctx context.Context, a *Auction) (map[string]int, error) {
+	if a.Points != "" {
+		if m.ledger == nil {
+			return nil, dkp.ErrUnknownCurrency.Wrap(fmt.Errorf("currency %q", a.Points))
+		}
+		balances, err := m.ledger.Balances(ctx, a.Points)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s balances: %w", a.Points, err)
+		}
+		return balances, nil
+	}
+	players, err := m.players.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading player balances: %w", err)
+	}
+	balances := make(map[string]int, len(players))
+	for _, p := range players {
+		balances[p.ID] = p.DKP
+	}
+	return balances, nil
+}
+
+// rollWindow returns the guild's roll window, or zero without settings.
+func (m *Manager) rollWindow(ctx context.Context) (time.Duration, error) {
+	if m.settings == nil {
+		return 0, nil
+	}
+	gs, err := m.settings.Get(ctx, m.guildID)
+	if err != nil {
+		return 0, err
+	}
+	return gs.RollWindow, nil
+}
+
+// Roll rolls from 1 to 100 for the player registered as discordID in a
+// rolling auction and returns the roll.
+func (m *Manager) Roll(ctx context.Context, auctionID, discordID string) (int, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.Roll",
+		trace.WithAttributes(
+			attribute.String("auction_id", auctionID),
+			attribute.String("discord_id", discordID),
+		),
+	)
+	defer span.End()
+
+	return idempotency.Do(ctx, m.dedup, "auction.roll", func(ctx context.Context) (int, error) {
+		return m.rollFor(ctx, auctionID, discordID)
+	})
+}
+
+func (m *Manager) rollFor(ctx context.Context, auctionID, discordID string) (int, error) {
+	m.mu.RLock()
+	a, ok := m.auctions[auctionID]
+	m.mu.RUnlock()
+
+	if !ok {
+		return 0, store.ErrAuctionNotFound.Wrap(fmt.Errorf("auction %s", auctionID))
+	}
+
+	player, err := m.bidder(ctx, discordID)
+	if err != nil {
+		return 0, err
+	}
+
+	balance, err := m.balance(ctx, a, player)
+	if err != nil {
+		return 0, err
+	}
+	roll := m.roll()
+	if err := a.Roll(ctx, player.ID, balance, roll); err != nil {
+		return 0, err
+	}
+
+	if err := m.events.Append(ctx, a.PendingEvents()...); err != nil {
+		m.logger.ErrorContext(ctx, "failed to persist roll event", slog.Any("error", err))
+	}
+	return roll, nil
+}
+
+// BuyOut awards an auction to the player registered as discordID at its
+// buyout price, closing it at once.
+func (m *Manager) BuyOut(ctx context.Context, auctionID, discordID string) (CloseResult, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.BuyOut",
+		trace.WithAttributes(
+			attribute.String("auction_id", auctionID),
+			attribute.String("discord_id", discordID),
+		),
+	)
+	defer span.End()
+
+	return idempotency.Do(ctx, m.dedup, "auction.buyout", func(ctx context.Context) (CloseResult, error) {
+		return m.buyOut(ctx, auctionID, discordID)
+	})
+}
+
+func (m *Manager) buyOut(ctx context.Context, auctionID, discordID string) (CloseResult, error) {
+	m.mu.RLock()
+	a, ok := m.auctions[auctionID]
+	m.mu.RUnlock()
+
+	if !ok {
+		return CloseResult{}, store.ErrAuctionNotFound.Wrap(fmt.Errorf("auction %s", auctionID))
+	}
+
+	player, err := m.bidder(ctx, discordID)
+	if err != nil {
+		return CloseResult{}, err
+	}
+
+	balance, err := m.balance(ctx, a, player)
+	if err != nil {
+		return CloseResult{}, err
+	}
+	winner, err := a.BuyOut(ctx, player.ID, balance)
+	if err != nil {
+		return CloseResult{}, err
+	}
+	m.metrics.AuctionBoughtOut(ctx, m.clock.Now().Sub(a.StartedAt))
+
+	if err := m.events.Append(ctx, a.PendingEvents()...); err != nil {
+		m.logger.ErrorContext(ctx, "failed to persist buyout event", slog.Any("error", err))
+	}
+	taxed := m.chargeTax(ctx, a, winner)
+
+	m.mu.Lock()
+	delete(m.auctions, auctionID)
+	m.mu.Unlock()
+
+	return CloseResult{
+		Message: fmt.Sprintf("Auction `%s` bought out! Winner: **%s** for **%d %s**", auctionID, player.CharacterName, winner.Amount, a.Currency()) + taxed,
+		Started: m.startQueued(ctx),
+	}, nil
+}
+
+// CancelAuction cancels an open, queued, or scheduled auction without a
+// winner.
+func (m *Manager) CancelAuction(ctx context.Context, auctionID string) error {
+	ctx, span := m.tracer.Start(ctx, "Manager.CancelAuction",
+		trace.WithAttributes(attribute.String("auction_id", auctionID)),
+	)
+	defer span.End()
+
+	_, err := idempotency.Do(ctx, m.dedup, "auction.cancel", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, m.cancelAuction(ctx, auctionID)
+	})
+	return err
+}
+
+func (m *Manager) cancelAuction(ctx context.Context, auctionID string) error {
+	m.mu.RLock()
+	a, ok := m.auctions[auctionID]
+	pending := false
+	if !ok {
+		a, pending = m.pending(auctionID)
+	}
+	m.mu.RUnlock()
+
+	if !ok && !pending {
+		return store.ErrAuctionNotFound.Wrap(fmt.Errorf("auction %s", auctionID))
+	}
+
+	if err := a.Cancel(ctx); err != nil {
+		return err
+	}
+	if !pending {
+		m.metrics.AuctionCanceled(ctx, m.clock.Now().Sub(a.StartedAt))
+	}
+
+	if err := m.events.Append(ctx, a.PendingEvents()...); err != nil {
+		m.logger.ErrorContext(ctx, "failed to persist cancel event", slog.Any("error", err))
+	}
+
+	m.mu.Lock()
+	delete(m.auctions, auctionID)
+	m.queue = slices.DeleteFunc(m.queue, func(q *Auction) bool { return q == a })
+	m.scheduled = slices.DeleteFunc(m.scheduled, func(q *Auction) bool { return q == a })
+	m.mu.Unlock()
+	m.startQueued(ctx)
+
+	m.logger.InfoContext(ctx, "auction canceled", slog.String("auction_id", auctionID))
+	return nil
+}
+
+// RecordAnnouncement records the message messageID in channelID as
+// announcing the open or scheduled auction auctionID, so that it is
+// updated as the auction opens and bids come in.
+func (m *Manager) RecordAnnouncement(ctx context.Context, auctionID, channelID, messageID string) error {
+	m.mu.RLock()
+	a, ok := m.auctions[auctionID]
+	if !ok {
+		a, ok = m.pending(auctionID)
+	}
+	m.mu.RUnlock()
+
+	if !ok {
+		return store.ErrAuctionNotFound.Wrap(fmt.Errorf("auction %s", auctionID))
+	}
+	a.Announce(channelID, messageID)
+	if err := m.events.Append(ctx, a.PendingEvents()...); err != nil {
+		return fmt.Errorf("persisting auction announced event: %w", err)
+	}
+	return nil
+}
+
+// PauseAuction pauses an open auction, rejecting bids and stopping its
+// countdown until it is resumed.
+func (m *Manager) PauseAuction(ctx context.Context, auctionID string) error {
+	ctx, span := m.tracer.Start(ctx, "Manager.PauseAuction",
+		trace.WithAttributes(attribute.String("auction_id", auctionID)),
+	)
+	defer span.End()
+
+	_, err := idempotency.Do(ctx, m.dedup, "auction.pause", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, m.pauseAuction(ctx, auctionID)
+	})
+	return err
+}
+
+func (m *Manager) pauseAuction(ctx context.Context, auctionID string) error {
+	m.mu.RLock()
+	a, ok := m.auctions[auctionID]
+	m.mu.RUnlock()
+
+	if !ok {
+		return store.ErrAuctionNotFound.Wrap(fmt.Errorf("auction %s", auctionID))
+	}
+	if err := a.Pause(ctx); err != nil {
+		return err
+	}
+
+	if err := m.events.Append(ctx, a.PendingEvents()...); err != nil {
+		m.logger.ErrorContext(ctx, "failed to persist pause event", slog.Any("error", err))
+	}
+	m.logger.InfoContext(ctx, "auction paused", slog.String("auction_id", auctionID))
+	return nil
+}
+
+// ResumeAuction reopens a paused auction and returns when it now ends,
+// pushed back by the length of the pause.
+func (m *Manager) ResumeAuction(ctx context.Context, auctionID string) (time.Time, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.ResumeAuction",
+		trace.WithAttributes(attribute.String("auction_id", auctionID)),
+	)
+	defer span.End()
+
+	return idempotency.Do(ctx, m.dedup, "auction.resume", func(ctx context.Context) (time.Time, error) {
+		return m.resumeAuction(ctx, auctionID)
+	})
+}
+
+func (m *Manager) resumeAuction(ctx context.Context, auctionID string) (time.Time, error) {
+	m.mu.RLock()
+	a, ok := m.auctions[auctionID]
+	m.mu.RUnlock()
+
+	if !ok {
+		return time.Time{}, store.ErrAuctionNotFound.Wrap(fmt.Errorf("auction %s", auctionID))
+	}
+	if err := a.Resume(ctx); err != nil {
+		return time.Time{}, err
+	}
+
+	if err := m.events.Append(ctx, a.PendingEvents()...); err != nil {
+		m.logger.ErrorContext(ctx, "failed to persist resume event", slog.Any("error", err))
+	}
+	endsAt := a.EndsAt()
+	m.logger.InfoContext(ctx, "auction resumed",
+		slog.String("auction_id", auctionID),
+		slog.Time("ends_at", endsAt),
+	)
+	return endsAt, nil
+}
+
+// Currency returns what the open auction auctionID is bid in, "gold",
+// DKP, or another currency, or DKP if it is not open.
+func (m *Manager) Currency(auctionID string) string {
+	m.mu.RLock()
+	a, ok := m.auctions[auctionID]
+	m.mu.RUnlock()
+	if !ok {
+		return dkp.DKP
+	}
+	return a.Currency()
+}
+
+// OpenAuctions returns the IDs of the auctions currently open, sorted.
+func (m *Manager) OpenAuctions() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := make([]string, 0, len(m.auctions))
+	for id := range m.auctions {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Countdown is the time left to run of an open auction.
+type Countdown struct {
+	AuctionID string
+	// Duration is how long the auction runs, not counting pauses, and
+	// Remaining how much of it is left, which is negative once it ran out.
+	Duration      time.Duration
+	Remaining     time.Duration
+	Announcements []Announcement
+}
+
+// Countdowns returns the countdowns of the open auctions, sorted by ID.
+// Paused auctions, whose countdowns stand still, are left out.
+func (m *Manager) Countdowns() []Countdown {
+	m.mu.RLock()
+	auctions := make([]*Auction, 0, len(m.auctions))
+	for _, a := range m.auctions {
+		auctions = append(auctions, a)
+	}
+	m.mu.RUnlock()
+
+	now := m.clock.Now()
+	var countdowns []Countdown
+	for _, a := range auctions {
+		st := a.State()
+		if st.Status != "open" {
+			continue
+		}
+		countdowns = append(countdowns, Countdown{
+			AuctionID:     st.ID,
+			Duration:      st.Duration,
+			Remaining:     a.EndsAt().Sub(now),
+			Announcements: st.Announcements,
+		})
+	}
+	slices.SortFunc(countdowns, func(a, b Countdown) int { return strings.Compare(a.AuctionID, b.AuctionID) })
+	return countdowns
+}
+
+// ReplayAuction reconstructs an auction from stored events. Auctions whose
+// events were archived are not found.
 func (m *Manager) ReplayAuction(ctx context.Context, auctionID string) (*Auction, error) {
 	events, err := m.events.Load(ctx, auctionID)
 	if err != nil {
 		return nil, fmt.Errorf("loading events: %w", err)
 	}
+	if len(events) == 0 {
+		return nil, store.ErrAuctionNotFound.Wrap(fmt.Errorf("auction %s", auctionID))
+	}
 	return Replay(events)
 }
 
+// ListOpenAuctions returns the state of every scheduled, queued, open,
+// paused, or rolling auction as recorded in the event store, oldest first. Unlike OpenAuctions it does
+// not depend on this replica holding the auctions in memory, so it serves
+// read-only replicas too.
+func (m *Manager) ListOpenAuctions(ctx context.Context) ([]State, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.ListOpenAuctions")
+	defer span.End()
+
+	ids, err := openAuctionIDs(ctx, m.events)
+	if err != nil {
+		return nil, err
+	}
+
+	var states []State
+	for _, id := range ids {
+		a, err := m.ReplayAuction(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("replaying auction %s: %w", id, err)
+		}
+		if a.Status != "closed" && a.Status != "canceled" {
+			states = append(states, a.State())
+		}
+	}
+	// IDs embed the start time.
+	slices.SortFunc(states, func(a, b State) int { return strings.Compare(a.ID, b.ID) })
+	span.SetAttributes(attribute.Int("auctions", len(states)))
+	return states, nil
+}
+
+// openAuctionIDs returns the IDs of the auctions events records as
+// scheduled, queued, or started and not as closed, canceled, or bought
+// out, in order.
+func openAuctionIDs(ctx context.Context, events event.Store) ([]string, error) {
+	open := make(map[string]bool)
+	for _, t := range []event.Type{event.AuctionScheduled, event.AuctionQueued, event.AuctionStarted, event.AuctionClosed, event.AuctionCanceled, event.AuctionBoughtOut} {
+		loaded, err := events.LoadByType(ctx, t)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s events: %w", t, err)
+		}
+		for _, e := range loaded {
+			open[e.AggregateID] = t == event.AuctionScheduled || t == event.AuctionQueued || t == event.AuctionStarted
+		}
+	}
+
+	var ids []string
+	for id, isOpen := range open {
+		if isOpen {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
 // RecoverOpenAuctions replays all auctions from the event store and loads
-// any that are still open into the in-memory map. This is used on leader
+// any that are still open into the in-memory map, those still queued into
+// the queue, starting them if there is room, and those still scheduled
+// into the schedule, for OpenScheduled to open. This is used on leader
 // startup to restore state after a failover.
 func (m *Manager) RecoverOpenAuctions(ctx context.Context) (int, error) {
 	ctx, span := m.tracer.Start(ctx, "Manager.RecoverOpenAuctions")
 	defer span.End()
 
-	// Find all auction IDs by loading all "auction.started" events.
+	// Find all auction IDs by loading all "auction.started",
+	// "auction.queued", and "auction.scheduled" events.
 	started, err := m.events.LoadByType(ctx, event.AuctionStarted)
 	if err != nil {
 		return 0, fmt.Errorf("loading auction started events: %w", err)
 	}
+	queued, err := m.events.LoadByType(ctx, event.AuctionQueued)
+	if err != nil {
+		return 0, fmt.Errorf("loading auction queued events: %w", err)
+	}
+	scheduled, err := m.events.LoadByType(ctx, event.AuctionScheduled)
+	if err != nil {
+		return 0, fmt.Errorf("loading auction scheduled events: %w", err)
+	}
+	started = slices.Concat(started, queued, scheduled)
 
 	// Deduplicate aggregate IDs.
 	seen := make(map[string]struct{}, len(started))
@@ -186,7 +1298,19 @@ func (m *Manager) RecoverOpenAuctions(ctx context.Context) (int, error) {
 			)
 			continue
 		}
-		if a.Status != "open" {
+		switch a.Status {
+		case "queued":
+			m.mu.Lock()
+			m.queue = append(m.queue, a)
+			m.mu.Unlock()
+			continue
+		case "scheduled":
+			m.mu.Lock()
+			m.scheduled = append(m.scheduled, a)
+			m.mu.Unlock()
+			continue
+		}
+		if a.Status != "open" && a.Status != "paused" && a.Status != "rolling" {
 			continue
 		}
 
@@ -202,9 +1326,19 @@ func (m *Manager) RecoverOpenAuctions(ctx context.Context) (int, error) {
 		)
 	}
 
+	// IDs embed the time the auctions were queued.
+	m.mu.Lock()
+	slices.SortFunc(m.queue, func(a, b *Auction) int { return strings.Compare(a.ID, b.ID) })
+	n, scheduledN := len(m.queue), len(m.scheduled)
+	m.mu.Unlock()
+	// Slots may have freed before the queued auctions could start.
+	m.startQueued(ctx)
+
 	m.logger.InfoContext(ctx, "auction recovery complete",
 		slog.Int("total_started", len(ids)),
 		slog.Int("recovered_open", recovered),
+		slog.Int("recovered_queued", n),
+		slog.Int("recovered_scheduled", scheduledN),
 	)
 	return recovered, nil
 }
@@ -3,114 +3,33 @@ package auction_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 	"go.opentelemetry.io/otel/trace/noop"
 
 	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/gdkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/settings"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
 )
 
-// --- mock helpers ---
-
-type mockEventStore struct {
-	events   []event.Event
-	appendFn func(events ...event.Event) error
-}
-
-func (m *mockEventStore) Append(_ context.Context, events ...event.Event) error {
-	if m.appendFn != nil {
-		return m.appendFn(events...)
-	}
-	m.events = append(m.events, events...)
-	return nil
-}
-
-func (m *mockEventStore) Load(_ context.Context, aggregateID string) ([]event.Event, error) {
-	var result []event.Event
-	for _, e := range m.events {
-		if e.AggregateID == aggregateID {
-			result = append(result, e)
-		}
-	}
-	return result, nil
-}
-
-func (m *mockEventStore) LoadByType(_ context.Context, eventType event.Type) ([]event.Event, error) {
-	var result []event.Event
-	for _, e := range m.events {
-		if e.Type == eventType {
-			result = append(result, e)
-		}
-	}
-	return result, nil
-}
-
-type mockPlayerRepo struct {
-	players map[string]*store.Player
-	err     error
-}
-
-func newMockPlayerRepo() *mockPlayerRepo {
-	return &mockPlayerRepo{players: make(map[string]*store.Player)}
-}
-
-func (m *mockPlayerRepo) Create(_ context.Context, p *store.Player) error {
-	if m.err != nil {
-		return m.err
-	}
-	p.ID = "test-id-" + p.DiscordID
-	m.players[p.DiscordID] = p
-	return nil
-}
-
-func (m *mockPlayerRepo) GetByDiscordID(_ context.Context, discordID string) (*store.Player, error) {
-	if m.err != nil {
-		return nil, m.err
-	}
-	p, ok := m.players[discordID]
-	if !ok {
-		return nil, fmt.Errorf("player not found")
-	}
-	return p, nil
-}
-
-func (m *mockPlayerRepo) GetByCharacterName(_ context.Context, name string) (*store.Player, error) {
-	for _, p := range m.players {
-		if p.CharacterName == name {
-			return p, nil
-		}
-	}
-	return nil, fmt.Errorf("player not found")
-}
-
-func (m *mockPlayerRepo) List(_ context.Context) ([]store.Player, error) {
-	result := make([]store.Player, 0, len(m.players))
-	for _, p := range m.players {
-		result = append(result, *p)
-	}
-	return result, nil
-}
-
-func (m *mockPlayerRepo) UpdateDKP(_ context.Context, id string, delta int) error {
-	if m.err != nil {
-		return m.err
-	}
-	for _, p := range m.players {
-		if p.ID == id {
-			p.DKP += delta
-			return nil
-		}
-	}
-	return fmt.Errorf("player %s not found", id)
-}
-
 // --- tests ---
 
 // tickingClock is a mock clock that advances by 1 second on each call.
@@ -128,15 +47,15 @@ func (c *tickingClock) Now() time.Time {
 }
 
 func TestManager_StartAuction(t *testing.T) {
-	es := &mockEventStore{}
-	repo := newMockPlayerRepo()
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
 	tp := noop.NewTracerProvider()
 	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 	logger := slog.Default()
 
 	mgr := auction.NewManager(es, repo, logger, tp, clk)
 
-	a, err := mgr.StartAuction(context.Background(), "Legendary Sword", "admin", 10, 5*time.Minute)
+	a, err := mgr.StartAuction(context.Background(), "Legendary Sword", "admin", 10, 0, 0, 5*time.Minute)
 	if err != nil {
 		t.Fatalf("StartAuction() error = %v", err)
 	}
@@ -149,47 +68,92 @@ func TestManager_StartAuction(t *testing.T) {
 	if a.Status != "open" {
 		t.Errorf("Status = %q, want %q", a.Status, "open")
 	}
-	if len(es.events) == 0 {
+	if len(es.Events()) == 0 {
 		t.Error("expected events to be persisted")
 	}
 }
 
-func TestManager_StartAuction_PersistError(t *testing.T) {
-	es := &mockEventStore{
-		appendFn: func(events ...event.Event) error {
-			return fmt.Errorf("db write error")
-		},
+// mockSettingsRepo serves fixed guild settings.
+type mockSettingsRepo struct {
+	settings []store.GuildSetting
+}
+
+func (m *mockSettingsRepo) List(_ context.Context, guildID string) ([]store.GuildSetting, error) {
+	var result []store.GuildSetting
+	for _, s := range m.settings {
+		if s.GuildID == guildID {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockSettingsRepo) Set(context.Context, *store.GuildSetting) error { return nil }
+
+func (m *mockSettingsRepo) Delete(context.Context, string, string) error { return nil }
+
+func TestManager_StartAuction_GuildSettings(t *testing.T) {
+	repo := &mockSettingsRepo{settings: []store.GuildSetting{
+		{GuildID: "g1", Key: settings.AuctionDuration, Value: "10m"},
+		{GuildID: "g1", Key: settings.MinIncrement, Value: "5"},
+	}}
+	defaults := settings.Defaults(config.GuildDefaultsConfig{AuctionDuration: 5 * time.Minute, MinIncrement: 1})
+	svc := settings.NewService(repo, defaults, slog.Default())
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+
+	mgr := auction.NewManager(eventtest.NewStore(), storetest.NewPlayers(), slog.Default(), noop.NewTracerProvider(), clk,
+		auction.WithSettings(svc, "g1"))
+
+	a, err := mgr.StartAuction(context.Background(), "Legendary Sword", "admin", 10, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("StartAuction() error = %v", err)
+	}
+	if a.Duration != 10*time.Minute || a.MinIncrement != 5 {
+		t.Errorf("duration, increment = %s, %d, want the guild's 10m, 5", a.Duration, a.MinIncrement)
+	}
+
+	a, err = mgr.StartAuction(context.Background(), "Shield", "admin", 10, 0, 0, time.Minute)
+	if err != nil {
+		t.Fatalf("StartAuction() error = %v", err)
+	}
+	if a.Duration != time.Minute {
+		t.Errorf("duration = %s, want the requested 1m", a.Duration)
 	}
-	repo := newMockPlayerRepo()
+}
+
+func TestManager_StartAuction_PersistError(t *testing.T) {
+	es := eventtest.NewStore()
+	es.Fail("Append", fmt.Errorf("db write error"))
+	repo := storetest.NewPlayers()
 	tp := noop.NewTracerProvider()
 	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 	logger := slog.Default()
 
 	mgr := auction.NewManager(es, repo, logger, tp, clk)
 
-	_, err := mgr.StartAuction(context.Background(), "Sword", "admin", 10, 5*time.Minute)
+	_, err := mgr.StartAuction(context.Background(), "Sword", "admin", 10, 0, 0, 5*time.Minute)
 	if err == nil {
 		t.Fatal("expected error when event store fails")
 	}
 }
 
 func TestManager_PlaceBid(t *testing.T) {
-	es := &mockEventStore{}
-	repo := newMockPlayerRepo()
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
 	tp := noop.NewTracerProvider()
 	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 	logger := slog.Default()
 
 	// Register a player.
-	repo.players["discord-1"] = &store.Player{
+	repo.Put(store.Player{
 		ID:        "player-1",
 		DiscordID: "discord-1",
 		DKP:       200,
-	}
+	})
 
 	mgr := auction.NewManager(es, repo, logger, tp, clk)
 
-	a, _ := mgr.StartAuction(context.Background(), "Shield", "admin", 10, 5*time.Minute)
+	a, _ := mgr.StartAuction(context.Background(), "Shield", "admin", 10, 0, 0, 5*time.Minute)
 
 	err := mgr.PlaceBid(context.Background(), a.ID, "discord-1", 50)
 	if err != nil {
@@ -202,9 +166,39 @@ func TestManager_PlaceBid(t *testing.T) {
 	}
 }
 
+func TestManager_RaiseBid(t *testing.T) {
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers(
+		store.Player{ID: "player-1", DiscordID: "discord-1", DKP: 200},
+		store.Player{ID: "player-2", DiscordID: "discord-2", DKP: 200},
+	)
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	mgr := auction.NewManager(es, repo, slog.Default(), noop.NewTracerProvider(), clk)
+	ctx := context.Background()
+
+	a, _ := mgr.StartAuction(ctx, "Shield", "admin", 10, 0, 0, 5*time.Minute)
+
+	// The first bid is the minimum bid, whatever the raise.
+	if got, err := mgr.RaiseBid(ctx, a.ID, "discord-1", 5); err != nil || got != 10 {
+		t.Fatalf("RaiseBid() = %d, %v, want 10", got, err)
+	}
+	if got, err := mgr.RaiseBid(ctx, a.ID, "discord-2", 5); err != nil || got != 15 {
+		t.Fatalf("RaiseBid() = %d, %v, want 15", got, err)
+	}
+	if _, err := mgr.RaiseBid(ctx, a.ID, "discord-2", 5); !errors.Is(err, auction.ErrSelfOutbid) {
+		t.Errorf("RaiseBid() by the high bidder error = %v, want ErrSelfOutbid", err)
+	}
+	if highest := a.HighestBid(); highest == nil || highest.Amount != 15 || highest.PlayerID != "player-2" {
+		t.Errorf("highest bid = %+v, want 15 by player-2", highest)
+	}
+	if n := len(es.Events()); n != 3 {
+		t.Errorf("events = %d, want the start and 2 bids", n)
+	}
+}
+
 func TestManager_PlaceBid_AuctionNotFound(t *testing.T) {
-	es := &mockEventStore{}
-	repo := newMockPlayerRepo()
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
 	tp := noop.NewTracerProvider()
 	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 	logger := slog.Default()
@@ -218,15 +212,15 @@ func TestManager_PlaceBid_AuctionNotFound(t *testing.T) {
 }
 
 func TestManager_PlaceBid_PlayerNotRegistered(t *testing.T) {
-	es := &mockEventStore{}
-	repo := newMockPlayerRepo()
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
 	tp := noop.NewTracerProvider()
 	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 	logger := slog.Default()
 
 	mgr := auction.NewManager(es, repo, logger, tp, clk)
 
-	a, _ := mgr.StartAuction(context.Background(), "Shield", "admin", 10, 5*time.Minute)
+	a, _ := mgr.StartAuction(context.Background(), "Shield", "admin", 10, 0, 0, 5*time.Minute)
 
 	err := mgr.PlaceBid(context.Background(), a.ID, "unknown-discord", 50)
 	if err == nil {
@@ -234,56 +228,254 @@ func TestManager_PlaceBid_PlayerNotRegistered(t *testing.T) {
 	}
 }
 
+func TestManager_PlaceBid_PlayerArchived(t *testing.T) {
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
+	tp := noop.NewTracerProvider()
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	logger := slog.Default()
+
+	repo.Put(store.Player{
+		ID:         "player-1",
+		DiscordID:  "discord-1",
+		DKP:        200,
+		ArchivedAt: &clk.T,
+	})
+
+	mgr := auction.NewManager(es, repo, logger, tp, clk)
+
+	a, _ := mgr.StartAuction(context.Background(), "Shield", "admin", 10, 0, 0, 5*time.Minute)
+
+	err := mgr.PlaceBid(context.Background(), a.ID, "discord-1", 50)
+	if !errors.Is(err, store.ErrPlayerArchived) {
+		t.Fatalf("PlaceBid() error = %v, want ErrPlayerArchived", err)
+	}
+	if a.HighestBid() != nil {
+		t.Errorf("highest bid = %+v, want none", a.HighestBid())
+	}
+}
+
+// lootBans implements auction.LootBans with the ends of the players' bans
+// by player ID.
+type lootBans map[string]time.Time
+
+func (b lootBans) LootBan(_ context.Context, playerID string) (time.Time, string, error) {
+	if b[playerID].IsZero() {
+		return time.Time{}, "", nil
+	}
+	return b[playerID], "ninja looting", nil
+}
+
+func TestManager_PlaceBid_LootBanned(t *testing.T) {
+	repo := storetest.NewPlayers(
+		store.Player{ID: "player-1", DiscordID: "discord-1", DKP: 200},
+		store.Player{ID: "player-2", DiscordID: "discord-2", DKP: 200},
+	)
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	bans := lootBans{"player-1": time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)}
+	mgr := auction.NewManager(eventtest.NewStore(), repo, slog.Default(), noop.NewTracerProvider(), clk,
+		auction.WithLootBans(lootBans{}), auction.WithLootBans(bans))
+	ctx := context.Background()
+	a, _ := mgr.StartAuction(ctx, "Shield", "admin", 10, 100, 0, 5*time.Minute)
+
+	err := mgr.PlaceBid(ctx, a.ID, "discord-1", 50)
+	if !errors.Is(err, auction.ErrLootBanned) || derrors.MessageOf(err) != "you are banned from loot until <t:1751328000:F>: ninja looting" {
+		t.Errorf("PlaceBid() error = %v, want ErrLootBanned saying when it ends", err)
+	}
+	if _, err := mgr.BuyOut(ctx, a.ID, "discord-1"); !errors.Is(err, auction.ErrLootBanned) {
+		t.Errorf("BuyOut() error = %v, want ErrLootBanned", err)
+	}
+	if err := mgr.PlaceBid(ctx, a.ID, "discord-2", 50); err != nil {
+		t.Errorf("PlaceBid() by a player without a ban error = %v", err)
+	}
+}
+
 func TestManager_CloseAuction(t *testing.T) {
-	es := &mockEventStore{}
-	repo := newMockPlayerRepo()
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
 	tp := noop.NewTracerProvider()
 	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 	logger := slog.Default()
 
-	repo.players["discord-1"] = &store.Player{
+	repo.Put(store.Player{
 		ID:        "player-1",
 		DiscordID: "discord-1",
 		DKP:       200,
-	}
+	})
 
 	mgr := auction.NewManager(es, repo, logger, tp, clk)
 
-	a, _ := mgr.StartAuction(context.Background(), "Helm", "admin", 10, 5*time.Minute)
+	a, _ := mgr.StartAuction(context.Background(), "Helm", "admin", 10, 0, 0, 5*time.Minute)
 	_ = mgr.PlaceBid(context.Background(), a.ID, "discord-1", 75)
 
-	msg, err := mgr.CloseAuction(context.Background(), a.ID)
+	result, err := mgr.CloseAuction(context.Background(), a.ID)
 	if err != nil {
 		t.Fatalf("CloseAuction() error = %v", err)
 	}
-	if msg == "" {
+	if result.Message == "" {
 		t.Error("expected a winner message, got empty string")
 	}
 }
 
+func TestManager_CloseAuction_SkipsUnaffordableWinner(t *testing.T) {
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
+	repo.Put(store.Player{ID: "player-1", DiscordID: "discord-1", DKP: 200})
+	repo.Put(store.Player{ID: "player-2", DiscordID: "discord-2", DKP: 200})
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	mgr := auction.NewManager(es, repo, slog.Default(), noop.NewTracerProvider(), clk)
+	ctx := context.Background()
+
+	a, _ := mgr.StartAuction(ctx, "Helm", "admin", 10, 0, 0, 5*time.Minute)
+	_ = mgr.PlaceBid(ctx, a.ID, "discord-1", 75)
+	_ = mgr.PlaceBid(ctx, a.ID, "discord-2", 150)
+	// discord-2 won another item after bidding.
+	loser := repo.Player(t, "discord-2")
+	loser.DKP = 100
+	repo.Put(loser)
+
+	result, err := mgr.CloseAuction(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("CloseAuction() error = %v", err)
+	}
+	if want := "Winner: **player-1** with **75 DKP**"; !strings.Contains(result.Message, want) {
+		t.Errorf("CloseAuction() = %q, want it to contain %q", result.Message, want)
+	}
+	if skipped, _ := es.LoadByType(ctx, event.AuctionWinnerSkipped); len(skipped) != 1 {
+		t.Errorf("winner skipped events = %d, want 1", len(skipped))
+	}
+}
+
+func TestManager_CloseAuction_WinnerMessage(t *testing.T) {
+	repo := storetest.NewPlayers(store.Player{ID: "player-1", DiscordID: "discord-1", DKP: 200})
+	svc := settings.NewService(&mockSettingsRepo{settings: []store.GuildSetting{
+		{GuildID: "g1", Key: settings.WinnerMessage, Value: "**{winner}** gewinnt {item} für {amount} {currency}!"},
+	}}, settings.Defaults(config.GuildDefaultsConfig{AuctionDuration: 5 * time.Minute, MinIncrement: 1}), slog.Default())
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	mgr := auction.NewManager(eventtest.NewStore(), repo, slog.Default(), noop.NewTracerProvider(), clk, auction.WithSettings(svc, "g1"))
+	ctx := context.Background()
+
+	a, _ := mgr.StartAuction(ctx, "Helm", "admin", 10, 0, 0, 5*time.Minute)
+	_ = mgr.PlaceBid(ctx, a.ID, "discord-1", 75)
+	result, err := mgr.CloseAuction(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("CloseAuction() error = %v", err)
+	}
+	if want := "**player-1** gewinnt Helm für 75 DKP!"; result.Message != want || result.ItemName != "Helm" {
+		t.Errorf("CloseAuction() = %+v, want message %q", result, want)
+	}
+}
+
+func TestManager_Reserve(t *testing.T) {
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
+	repo.Put(store.Player{ID: "player-1", DiscordID: "discord-1", DKP: 200})
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	mgr := auction.NewManager(es, repo, slog.Default(), noop.NewTracerProvider(), &clk)
+	ctx := context.Background()
+
+	if _, err := mgr.StartAuction(ctx, "Helm", "admin", 10, 50, 60, 5*time.Minute); !errors.Is(err, auction.ErrInvalidReserve) {
+		t.Errorf("StartAuction(reserve above buyout) error = %v, want ErrInvalidReserve", err)
+	}
+
+	tests := []struct {
+		name string
+		bid  int
+		want string
+	}{
+		{"below reserve", 90, "the reserve price was not met"},
+		{"at reserve", 100, "Winner: **player-1** with **100 DKP**"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := mgr.StartAuction(ctx, "Helm "+tt.name, "admin", 10, 0, 100, 5*time.Minute)
+			if err != nil {
+				t.Fatalf("StartAuction() error = %v", err)
+			}
+			clk.T = clk.T.Add(time.Second)
+			if err := mgr.PlaceBid(ctx, a.ID, "discord-1", tt.bid); err != nil {
+				t.Fatalf("PlaceBid() error = %v", err)
+			}
+			result, err := mgr.CloseAuction(ctx, a.ID)
+			if err != nil {
+				t.Fatalf("CloseAuction() error = %v", err)
+			}
+			if !strings.Contains(result.Message, tt.want) {
+				t.Errorf("CloseAuction() = %q, want it to contain %q", result.Message, tt.want)
+			}
+		})
+	}
+
+	// The reserve is kept in the started event, so it survives a replay.
+	started, _ := es.LoadByType(ctx, event.AuctionStarted)
+	a, err := auction.Replay(started[:1])
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if a.Reserve != 100 {
+		t.Errorf("replayed reserve = %d, want 100", a.Reserve)
+	}
+}
+
+func TestManager_PauseResume(t *testing.T) {
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
+	repo.Put(store.Player{ID: "player-1", DiscordID: "discord-1", DKP: 200})
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	mgr := auction.NewManager(es, repo, slog.Default(), noop.NewTracerProvider(), &clk)
+	ctx := context.Background()
+
+	a, _ := mgr.StartAuction(ctx, "Helm", "admin", 10, 0, 0, 5*time.Minute)
+	if err := mgr.PauseAuction(ctx, a.ID); err != nil {
+		t.Fatalf("PauseAuction() error = %v", err)
+	}
+	if err := mgr.PlaceBid(ctx, a.ID, "discord-1", 50); !errors.Is(err, auction.ErrAuctionPaused) {
+		t.Errorf("PlaceBid() while paused error = %v, want ErrAuctionPaused", err)
+	}
+	states, err := mgr.ListOpenAuctions(ctx)
+	if err != nil || len(states) != 1 || states[0].Status != "paused" {
+		t.Errorf("ListOpenAuctions() = %+v, %v, want the paused auction", states, err)
+	}
+
+	clk.T = clk.T.Add(3 * time.Minute)
+	endsAt, err := mgr.ResumeAuction(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("ResumeAuction() error = %v", err)
+	}
+	if want := a.StartedAt.Add(8 * time.Minute); !endsAt.Equal(want) {
+		t.Errorf("ResumeAuction() = %v, want %v", endsAt, want)
+	}
+	if err := mgr.PlaceBid(ctx, a.ID, "discord-1", 50); err != nil {
+		t.Errorf("PlaceBid() after resuming error = %v", err)
+	}
+	if _, err := mgr.ResumeAuction(ctx, "nonexistent"); !errors.Is(err, store.ErrAuctionNotFound) {
+		t.Errorf("ResumeAuction(nonexistent) error = %v, want ErrAuctionNotFound", err)
+	}
+}
+
 func TestManager_CloseAuction_NoBids(t *testing.T) {
-	es := &mockEventStore{}
-	repo := newMockPlayerRepo()
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
 	tp := noop.NewTracerProvider()
 	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 	logger := slog.Default()
 
 	mgr := auction.NewManager(es, repo, logger, tp, clk)
 
-	a, _ := mgr.StartAuction(context.Background(), "Empty Auction", "admin", 10, 5*time.Minute)
+	a, _ := mgr.StartAuction(context.Background(), "Empty Auction", "admin", 10, 0, 0, 5*time.Minute)
 
-	msg, err := mgr.CloseAuction(context.Background(), a.ID)
+	result, err := mgr.CloseAuction(context.Background(), a.ID)
 	if err != nil {
 		t.Fatalf("CloseAuction() error = %v", err)
 	}
-	if msg != "" {
-		t.Errorf("expected empty message for no-bid close, got %q", msg)
+	if result.Message != "" || !result.RollUntil.IsZero() {
+		t.Errorf("expected an empty result for no-bid close, got %+v", result)
 	}
 }
 
 func TestManager_CloseAuction_NotFound(t *testing.T) {
-	es := &mockEventStore{}
-	repo := newMockPlayerRepo()
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
 	tp := noop.NewTracerProvider()
 	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 	logger := slog.Default()
@@ -296,22 +488,83 @@ func TestManager_CloseAuction_NotFound(t *testing.T) {
 	}
 }
 
+func TestManager_CancelAuction(t *testing.T) {
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
+	tp := noop.NewTracerProvider()
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	logger := slog.Default()
+
+	mgr := auction.NewManager(es, repo, logger, tp, clk)
+
+	a, _ := mgr.StartAuction(context.Background(), "Cloak", "admin", 10, 0, 0, 5*time.Minute)
+	if open := mgr.OpenAuctions(); len(open) != 1 || open[0] != a.ID {
+		t.Errorf("OpenAuctions() = %v, want [%s]", open, a.ID)
+	}
+	if err := mgr.CancelAuction(context.Background(), a.ID); err != nil {
+		t.Fatalf("CancelAuction() error = %v", err)
+	}
+	if open := mgr.OpenAuctions(); len(open) != 0 {
+		t.Errorf("OpenAuctions() after cancel = %v, want none", open)
+	}
+	if last := es.Last(t); last.Type != event.AuctionCanceled {
+		t.Errorf("last event = %s, want %s", last.Type, event.AuctionCanceled)
+	}
+	if err := mgr.CancelAuction(context.Background(), a.ID); err == nil {
+		t.Error("expected error canceling an auction twice")
+	}
+}
+
+func TestManager_ListOpenAuctions(t *testing.T) {
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
+	tp := noop.NewTracerProvider()
+	logger := slog.Default()
+	start := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	// Each auction gets its own clock so that their IDs differ.
+	var ids []string
+	for n, item := range []string{"Cloak", "Helm", "Ring"} {
+		mgr := auction.NewManager(es, repo, logger, tp, clock.Mock{T: start.Add(time.Duration(n) * time.Minute)})
+		a, err := mgr.StartAuction(context.Background(), item, "admin", 10, 0, 0, 5*time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, a.ID)
+		if item == "Helm" {
+			if err := mgr.CancelAuction(context.Background(), a.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// A replica that never ran the auctions still sees them.
+	standby := auction.NewManager(es, repo, logger, tp, clock.Mock{T: start})
+	got, err := standby.ListOpenAuctions(context.Background())
+	if err != nil {
+		t.Fatalf("ListOpenAuctions() error = %v", err)
+	}
+	if len(got) != 2 || got[0].ID != ids[0] || got[1].ID != ids[2] {
+		t.Errorf("ListOpenAuctions() = %+v, want auctions %s and %s", got, ids[0], ids[2])
+	}
+}
+
 func TestManager_ReplayAuction(t *testing.T) {
-	es := &mockEventStore{}
-	repo := newMockPlayerRepo()
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
 	tp := noop.NewTracerProvider()
 	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 	logger := slog.Default()
 
-	repo.players["discord-1"] = &store.Player{
+	repo.Put(store.Player{
 		ID:        "player-1",
 		DiscordID: "discord-1",
 		DKP:       500,
-	}
+	})
 
 	mgr := auction.NewManager(es, repo, logger, tp, clk)
 
-	a, _ := mgr.StartAuction(context.Background(), "Replay Item", "admin", 10, 5*time.Minute)
+	a, _ := mgr.StartAuction(context.Background(), "Replay Item", "admin", 10, 0, 0, 5*time.Minute)
 	_ = mgr.PlaceBid(context.Background(), a.ID, "discord-1", 100)
 
 	replayed, err := mgr.ReplayAuction(context.Background(), a.ID)
@@ -330,7 +583,7 @@ func TestAuction_Cancel(t *testing.T) {
 	tp := noop.NewTracerProvider()
 	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 
-	a := auction.New("cancel-test", "Ring", "admin", 10, 5*time.Minute, tp, clk)
+	a := auction.New("cancel-test", "Ring", "admin", 10, 1, 0, 5*time.Minute, tp, clk)
 
 	if err := a.Cancel(context.Background()); err != nil {
 		t.Fatalf("Cancel() error = %v", err)
@@ -349,8 +602,8 @@ func TestAuction_Cancel_AlreadyClosed(t *testing.T) {
 	tp := noop.NewTracerProvider()
 	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 
-	a := auction.New("cancel-closed-test", "Gem", "admin", 10, 5*time.Minute, tp, clk)
-	_, _ = a.Close(context.Background())
+	a := auction.New("cancel-closed-test", "Gem", "admin", 10, 1, 0, 5*time.Minute, tp, clk)
+	_, _ = a.Close(context.Background(), nil)
 
 	err := a.Cancel(context.Background())
 	if err != auction.ErrAuctionClosed {
@@ -369,7 +622,7 @@ func TestReplay_CanceledStatus(t *testing.T) {
 	tp := noop.NewTracerProvider()
 	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 
-	a := auction.New("replay-cancel", "Wand", "admin", 10, 5*time.Minute, tp, clk)
+	a := auction.New("replay-cancel", "Wand", "admin", 10, 1, 0, 5*time.Minute, tp, clk)
 	_ = a.Cancel(context.Background())
 
 	events := a.PendingEvents()
@@ -387,9 +640,9 @@ func TestReplay_ClosedStatus(t *testing.T) {
 	tp := noop.NewTracerProvider()
 	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 
-	a := auction.New("replay-close", "Staff", "admin", 10, 5*time.Minute, tp, clk)
+	a := auction.New("replay-close", "Staff", "admin", 10, 1, 0, 5*time.Minute, tp, clk)
 	_ = a.PlaceBid(context.Background(), "p1", 50, 100)
-	_, _ = a.Close(context.Background())
+	_, _ = a.Close(context.Background(), nil)
 
 	events := a.PendingEvents()
 
@@ -445,25 +698,25 @@ func TestReplay_InvalidBidData(t *testing.T) {
 }
 
 func TestManager_RecoverOpenAuctions(t *testing.T) {
-	es := &mockEventStore{}
-	repo := newMockPlayerRepo()
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
 	tp := noop.NewTracerProvider()
 	clk := &tickingClock{t: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 	logger := slog.Default()
 
-	repo.players["discord-1"] = &store.Player{
+	repo.Put(store.Player{
 		ID:        "player-1",
 		DiscordID: "discord-1",
 		DKP:       500,
-	}
+	})
 
 	mgr := auction.NewManager(es, repo, logger, tp, clk)
 
 	// Create two auctions: one open, one closed.
-	open, _ := mgr.StartAuction(context.Background(), "Open Sword", "admin", 10, 5*time.Minute)
+	open, _ := mgr.StartAuction(context.Background(), "Open Sword", "admin", 10, 0, 0, 5*time.Minute)
 	_ = mgr.PlaceBid(context.Background(), open.ID, "discord-1", 50)
 
-	closed, _ := mgr.StartAuction(context.Background(), "Closed Shield", "admin", 10, 5*time.Minute)
+	closed, _ := mgr.StartAuction(context.Background(), "Closed Shield", "admin", 10, 0, 0, 5*time.Minute)
 	_ = mgr.PlaceBid(context.Background(), closed.ID, "discord-1", 100)
 	_, _ = mgr.CloseAuction(context.Background(), closed.ID)
 
@@ -479,11 +732,11 @@ func TestManager_RecoverOpenAuctions(t *testing.T) {
 	}
 
 	// The recovered auction should be found (place a higher bid from a different player).
-	repo.players["discord-2"] = &store.Player{
+	repo.Put(store.Player{
 		ID:        "player-2",
 		DiscordID: "discord-2",
 		DKP:       500,
-	}
+	})
 	err = newMgr.PlaceBid(context.Background(), open.ID, "discord-2", 75)
 	if err != nil {
 		t.Errorf("PlaceBid on recovered auction error = %v", err)
@@ -491,8 +744,8 @@ func TestManager_RecoverOpenAuctions(t *testing.T) {
 }
 
 func TestManager_RecoverOpenAuctions_NoneOpen(t *testing.T) {
-	es := &mockEventStore{}
-	repo := newMockPlayerRepo()
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
 	tp := noop.NewTracerProvider()
 	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 	logger := slog.Default()
@@ -510,8 +763,8 @@ func TestManager_RecoverOpenAuctions_NoneOpen(t *testing.T) {
 }
 
 func TestManager_RecoverOpenAuctions_AllClosed(t *testing.T) {
-	es := &mockEventStore{}
-	repo := newMockPlayerRepo()
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
 	tp := noop.NewTracerProvider()
 	clk := &tickingClock{t: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
 	logger := slog.Default()
@@ -519,7 +772,7 @@ func TestManager_RecoverOpenAuctions_AllClosed(t *testing.T) {
 	mgr := auction.NewManager(es, repo, logger, tp, clk)
 
 	// Create and close an auction.
-	a, _ := mgr.StartAuction(context.Background(), "All Done", "admin", 10, 5*time.Minute)
+	a, _ := mgr.StartAuction(context.Background(), "All Done", "admin", 10, 0, 0, 5*time.Minute)
 	_, _ = mgr.CloseAuction(context.Background(), a.ID)
 
 	// Simulate failover.
@@ -532,3 +785,626 @@ func TestManager_RecoverOpenAuctions_AllClosed(t *testing.T) {
 		t.Errorf("RecoverOpenAuctions() recovered %d, want 0", n)
 	}
 }
+
+func TestManager_BuyOut(t *testing.T) {
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
+	repo.Put(store.Player{ID: "player-1", DiscordID: "discord-1", CharacterName: "Alice", DKP: 500})
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	mgr := auction.NewManager(es, repo, slog.Default(), noop.NewTracerProvider(), clk)
+
+	if _, err := mgr.StartAuction(context.Background(), "Sword", "admin", 10, 10, 0, 5*time.Minute); !errors.Is(err, auction.ErrInvalidBuyout) {
+		t.Errorf("StartAuction(buyout = min bid) error = %v, want ErrInvalidBuyout", err)
+	}
+
+	a, err := mgr.StartAuction(context.Background(), "Sword", "admin", 10, 100, 0, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("StartAuction() error = %v", err)
+	}
+	result, err := mgr.BuyOut(context.Background(), a.ID, "discord-1")
+	if err != nil {
+		t.Fatalf("BuyOut() error = %v", err)
+	}
+	if want := "Winner: **Alice** for **100 DKP**"; !strings.Contains(result.Message, want) {
+		t.Errorf("BuyOut() = %q, want it to contain %q", result.Message, want)
+	}
+	if last := es.Last(t); last.Type != event.AuctionBoughtOut {
+		t.Errorf("last persisted event = %s, want %s", last.Type, event.AuctionBoughtOut)
+	}
+	if open := mgr.OpenAuctions(); len(open) != 0 {
+		t.Errorf("OpenAuctions() = %v, want none after the buyout", open)
+	}
+	states, err := mgr.ListOpenAuctions(context.Background())
+	if err != nil {
+		t.Fatalf("ListOpenAuctions() error = %v", err)
+	}
+	if len(states) != 0 {
+		t.Errorf("ListOpenAuctions() = %+v, want none after the buyout", states)
+	}
+
+	if _, err := mgr.BuyOut(context.Background(), a.ID, "discord-1"); !errors.Is(err, store.ErrAuctionNotFound) {
+		t.Errorf("second BuyOut() error = %v, want ErrAuctionNotFound", err)
+	}
+}
+
+func TestManager_RollFallback(t *testing.T) {
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
+	repo.Put(store.Player{ID: "player-1", DiscordID: "discord-1", DKP: 50})
+	repo.Put(store.Player{ID: "player-2", DiscordID: "discord-2", DKP: 50})
+	svc := settings.NewService(&mockSettingsRepo{settings: []store.GuildSetting{
+		{GuildID: "g1", Key: settings.RollWindow, Value: "1m"},
+	}}, settings.Defaults(config.GuildDefaultsConfig{AuctionDuration: 5 * time.Minute, MinIncrement: 1}), slog.Default())
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	rolls := []int{30, 70}
+	mgr := auction.NewManager(es, repo, slog.Default(), noop.NewTracerProvider(), clk,
+		auction.WithSettings(svc, "g1"),
+		auction.WithRolls(func() int {
+			r := rolls[0]
+			rolls = rolls[1:]
+			return r
+		}))
+	ctx := context.Background()
+
+	a, err := mgr.StartAuction(ctx, "Sword", "admin", 5, 0, 0, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("StartAuction() error = %v", err)
+	}
+	result, err := mgr.CloseAuction(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("CloseAuction() error = %v", err)
+	}
+	if want := clk.T.Add(time.Minute); !result.RollUntil.Equal(want) {
+		t.Fatalf("CloseAuction() RollUntil = %v, want %v", result.RollUntil, want)
+	}
+	states, err := mgr.ListOpenAuctions(ctx)
+	if err != nil {
+		t.Fatalf("ListOpenAuctions() error = %v", err)
+	}
+	if len(states) != 1 || states[0].Status != "rolling" {
+		t.Errorf("ListOpenAuctions() = %+v, want the rolling auction", states)
+	}
+
+	for i, discordID := range []string{"discord-1", "discord-2"} {
+		roll, err := mgr.Roll(ctx, a.ID, discordID)
+		if err != nil {
+			t.Fatalf("Roll(%s) error = %v", discordID, err)
+		}
+		if want := []int{30, 70}[i]; roll != want {
+			t.Errorf("Roll(%s) = %d, want %d", discordID, roll, want)
+		}
+	}
+
+	result, err = mgr.CloseAuction(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("CloseAuction() ending the roll error = %v", err)
+	}
+	if want := "Winner: **player-2** with a roll of **70**, for **5 DKP**"; !strings.Contains(result.Message, want) {
+		t.Errorf("CloseAuction() = %q, want it to contain %q", result.Message, want)
+	}
+	last := es.Last(t)
+	var closed event.AuctionClosedData
+	if err := json.Unmarshal(last.Data, &closed); err != nil || last.Type != event.AuctionClosed || closed.Roll != 70 {
+		t.Errorf("last persisted event = %s %s, want a close with the winning roll", last.Type, last.Data)
+	}
+	if open := mgr.OpenAuctions(); len(open) != 0 {
+		t.Errorf("OpenAuctions() = %v, want none after the roll", open)
+	}
+}
+
+func TestManager_GDKPAuction(t *testing.T) {
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
+	repo.Put(store.Player{ID: "player-1", DiscordID: "discord-1", DKP: 0})
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	raids := gdkp.NewService(es, config.GDKPConfig{}, slog.Default(), noop.NewTracerProvider(), &clk)
+	mgr := auction.NewManager(es, repo, slog.Default(), noop.NewTracerProvider(), &clk, auction.WithGDKP(raids))
+	ctx := context.Background()
+
+	dkpAuction, err := mgr.StartAuction(ctx, "Helm", "admin", 10, 0, 0, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("StartAuction() without a raid error = %v", err)
+	}
+	if dkpAuction.RaidID != "" || mgr.Currency(dkpAuction.ID) != "DKP" {
+		t.Errorf("auction without a raid has raid %q, currency %s, want none and DKP", dkpAuction.RaidID, mgr.Currency(dkpAuction.ID))
+	}
+
+	r, err := raids.Start(ctx, "Naxx", "admin", "", 0)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	clk.T = clk.T.Add(time.Second)
+	a, err := mgr.StartAuction(ctx, "Sword", "admin", 100, 0, 0, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("StartAuction() during a raid error = %v", err)
+	}
+	if a.RaidID != r.ID || mgr.Currency(a.ID) != "gold" {
+		t.Errorf("auction during a raid has raid %q, currency %s, want %q and gold", a.RaidID, mgr.Currency(a.ID), r.ID)
+	}
+
+	// Gold is paid in game, so a player without DKP may bid.
+	if err := mgr.PlaceBid(ctx, a.ID, "discord-1", 2500); err != nil {
+		t.Fatalf("PlaceBid() error = %v", err)
+	}
+	result, err := mgr.CloseAuction(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("CloseAuction() error = %v", err)
+	}
+	if want := "**2500 gold**"; !strings.Contains(result.Message, want) {
+		t.Errorf("CloseAuction() = %q, want it to contain %q", result.Message, want)
+	}
+	pot, err := raids.Pot(ctx, r.ID)
+	if err != nil {
+		t.Fatalf("Pot() error = %v", err)
+	}
+	if pot.Total != 2500 {
+		t.Errorf("Pot().Total = %d, want 2500", pot.Total)
+	}
+}
+
+func TestManager_DKPRaidAuction(t *testing.T) {
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
+	repo.Put(store.Player{ID: "player-1", DiscordID: "discord-1", DKP: 50})
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	raids := gdkp.NewService(es, config.GDKPConfig{}, slog.Default(), noop.NewTracerProvider(), &clk)
+	mgr := auction.NewManager(es, repo, slog.Default(), noop.NewTracerProvider(), &clk, auction.WithGDKP(raids))
+	ctx := context.Background()
+
+	r, err := raids.Start(ctx, "Naxx", "admin", gdkp.ModeDKP, 0)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	clk.T = clk.T.Add(time.Second)
+	a, err := mgr.StartAuction(ctx, "Sword", "admin", 10, 0, 0, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("StartAuction() error = %v", err)
+	}
+	if a.RaidID != r.ID || mgr.Currency(a.ID) != "DKP" {
+		t.Errorf("auction during a DKP raid has raid %q, currency %s, want %q and DKP", a.RaidID, mgr.Currency(a.ID), r.ID)
+	}
+
+	// Bids are still bounded by the bidder's DKP.
+	if err := mgr.PlaceBid(ctx, a.ID, "discord-1", 60); err == nil {
+		t.Error("PlaceBid() above the bidder's DKP succeeded")
+	}
+	if err := mgr.PlaceBid(ctx, a.ID, "discord-1", 40); err != nil {
+		t.Fatalf("PlaceBid() error = %v", err)
+	}
+	if _, err := mgr.CloseAuction(ctx, a.ID); err != nil {
+		t.Fatalf("CloseAuction() error = %v", err)
+	}
+	pot, err := raids.Pot(ctx, r.ID)
+	if err != nil {
+		t.Fatalf("Pot() error = %v", err)
+	}
+	if pot.Total != 40 || len(pot.Sales) != 1 || pot.Sales[0].WinnerID != "player-1" {
+		t.Errorf("Pot() = %+v, want one 40 DKP sale to player-1", pot)
+	}
+}
+
+func TestManager_CurrencyAuction(t *testing.T) {
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers()
+	repo.Put(store.Player{ID: "player-1", DiscordID: "discord-1", DKP: 500})
+	repo.Put(store.Player{ID: "player-2", DiscordID: "discord-2", DKP: 0})
+	balances := storetest.NewBalances(repo)
+	ledger := dkp.NewManager(repo, es, slog.Default(), noop.NewTracerProvider(), dkp.WithCurrencies(balances, []string{"EP"}))
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	raids := gdkp.NewService(es, config.GDKPConfig{}, slog.Default(), noop.NewTracerProvider(), &clk)
+	mgr := auction.NewManager(es, repo, slog.Default(), noop.NewTracerProvider(), &clk,
+		auction.WithGDKP(raids), auction.WithLedger(ledger))
+	ctx := context.Background()
+
+	if _, err := mgr.StartAuction(ctx, "Helm", "admin", 10, 0, 0, 0, auction.InCurrency("GP")); !errors.Is(err, dkp.ErrUnknownCurrency) {
+		t.Errorf("StartAuction() in an unknown currency error = %v, want ErrUnknownCurrency", err)
+	}
+	a, err := mgr.StartAuction(ctx, "Sword", "admin", 10, 0, 0, 0, auction.InCurrency("ep"))
+	if err != nil {
+		t.Fatalf("StartAuction() error = %v", err)
+	}
+	if a.Points != "EP" || mgr.Currency(a.ID) != "EP" {
+		t.Errorf("auction has points %q, currency %s, want EP", a.Points, mgr.Currency(a.ID))
+	}
+
+	// Bids are bounded by the bidder's EP, not their DKP.
+	if err := balances.Update(ctx, "player-2", "EP", 40); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := mgr.PlaceBid(ctx, a.ID, "discord-1", 20); !errors.Is(err, auction.ErrInsufficientDKP) {
+		t.Errorf("PlaceBid() without EP error = %v, want ErrInsufficientDKP", err)
+	}
+	if err := mgr.PlaceBid(ctx, a.ID, "discord-2", 30); err != nil {
+		t.Fatalf("PlaceBid() error = %v", err)
+	}
+	result, err := mgr.CloseAuction(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("CloseAuction() error = %v", err)
+	}
+	if want := "**30 EP**"; !strings.Contains(result.Message, want) {
+		t.Errorf("CloseAuction() = %q, want it to contain %q", result.Message, want)
+	}
+	replayed, err := mgr.ReplayAuction(ctx, a.ID)
+	if err != nil || replayed.Points != "EP" {
+		t.Errorf("ReplayAuction() points = %q, %v, want EP", replayed.Points, err)
+	}
+
+	// Auctions in a GDKP raid are bid on in gold.
+	if _, err := raids.Start(ctx, "Naxx", "admin", "", 0); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	clk.T = clk.T.Add(time.Second)
+	if _, err := mgr.StartAuction(ctx, "Shield", "admin", 10, 0, 0, 0, auction.InCurrency("EP")); !errors.Is(err, auction.ErrPointsInGDKP) {
+		t.Errorf("StartAuction() in EP during a GDKP raid error = %v, want ErrPointsInGDKP", err)
+	}
+}
+
+func TestManager_Tax(t *testing.T) {
+	es := eventtest.NewStore()
+	repo := storetest.NewPlayers(
+		store.Player{ID: "player-1", DiscordID: "discord-1", DKP: 200},
+		store.Player{ID: "player-2", DiscordID: "discord-2", DKP: 0},
+		store.Player{ID: "player-3", DiscordID: "discord-3", DKP: 0},
+	)
+	ledger := dkp.NewManager(repo, es, slog.Default(), noop.NewTracerProvider())
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	raids := gdkp.NewService(es, config.GDKPConfig{}, slog.Default(), noop.NewTracerProvider(), &clk)
+	mgr := auction.NewManager(es, repo, slog.Default(), noop.NewTracerProvider(), &clk,
+		auction.WithGDKP(raids), auction.WithLedger(ledger), auction.WithTax(config.TaxConfig{Rate: 10, Redistribute: true}))
+	ctx := context.Background()
+
+	// Outside a raid the tax is burned.
+	a, _ := mgr.StartAuction(ctx, "Helm", "admin", 10, 0, 0, 0)
+	if err := mgr.PlaceBid(ctx, a.ID, "discord-1", 50); err != nil {
+		t.Fatalf("PlaceBid() error = %v", err)
+	}
+	result, err := mgr.CloseAuction(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("CloseAuction() error = %v", err)
+	}
+	if want := "A tax of **5 DKP** was charged and burned."; !strings.Contains(result.Message, want) {
+		t.Errorf("CloseAuction() = %q, want it to contain %q", result.Message, want)
+	}
+	repo.RequireDKP(t, "discord-1", 195)
+	if last := es.Last(t); last.Type != event.AuctionTaxed || last.AggregateID != a.ID {
+		t.Errorf("last event = %s on %s, want the tax on %s", last.Type, last.AggregateID, a.ID)
+	}
+
+	// In a raid it is shared among the other participants.
+	if _, err := raids.Start(ctx, "Naxx", "admin", gdkp.ModeDKP, 0); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	for _, id := range []string{"discord-1", "discord-2", "discord-3"} {
+		if _, err := raids.Join(ctx, id); err != nil {
+			t.Fatalf("Join(%s) error = %v", id, err)
+		}
+	}
+	clk.T = clk.T.Add(time.Second)
+	a, _ = mgr.StartAuction(ctx, "Sword", "admin", 10, 0, 0, 0)
+	if err := mgr.PlaceBid(ctx, a.ID, "discord-1", 65); err != nil {
+		t.Fatalf("PlaceBid() error = %v", err)
+	}
+	if result, err = mgr.CloseAuction(ctx, a.ID); err != nil {
+		t.Fatalf("CloseAuction() error = %v", err)
+	}
+	if want := "A tax of **6 DKP** was charged and shared among 2 raiders, 3 DKP each."; !strings.Contains(result.Message, want) {
+		t.Errorf("CloseAuction() = %q, want it to contain %q", result.Message, want)
+	}
+	repo.RequireDKP(t, "discord-1", 189)
+	repo.RequireDKP(t, "discord-2", 3)
+	repo.RequireDKP(t, "discord-3", 3)
+	d := eventtest.Data[event.AuctionTaxedData](t, es.Last(t))
+	if d.WinnerID != "player-1" || d.Amount != 6 || d.Share != 3 || !slices.Equal(d.Recipients, []string{"player-2", "player-3"}) {
+		t.Errorf("tax event = %+v", d)
+	}
+}
+
+func TestManager_Queue(t *testing.T) {
+	repo := &mockSettingsRepo{settings: []store.GuildSetting{
+		{GuildID: "g1", Key: settings.MaxOpenAuctions, Value: "1"},
+	}}
+	svc := settings.NewService(repo, settings.Defaults(config.GuildDefaultsConfig{AuctionDuration: 5 * time.Minute, MinIncrement: 1}), slog.Default())
+	es := eventtest.NewStore()
+	players := storetest.NewPlayers()
+	players.Put(store.Player{ID: "player-1", DiscordID: "discord-1", DKP: 100})
+	clk := &tickingClock{t: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	mgr := auction.NewManager(es, players, slog.Default(), noop.NewTracerProvider(), clk, auction.WithSettings(svc, "g1"))
+	ctx := context.Background()
+
+	first, _ := mgr.StartAuction(ctx, "Helm", "admin", 10, 0, 0, 0)
+	second, _ := mgr.StartAuction(ctx, "Sword", "admin", 10, 0, 0, 0)
+	third, _ := mgr.StartAuction(ctx, "Shield", "admin", 10, 0, 0, 0)
+	if first.Status != "open" || second.Status != "queued" || third.Status != "queued" {
+		t.Fatalf("statuses = %s, %s, %s, want open, queued, queued", first.Status, second.Status, third.Status)
+	}
+	if err := mgr.PlaceBid(ctx, second.ID, "discord-1", 20); !errors.Is(err, store.ErrAuctionNotFound) {
+		t.Errorf("PlaceBid() on a queued auction error = %v, want ErrAuctionNotFound", err)
+	}
+	states, err := mgr.ListOpenAuctions(ctx)
+	if err != nil || len(states) != 3 || states[1].Status != "queued" {
+		t.Errorf("ListOpenAuctions() = %+v, %v, want the open and both queued auctions", states, err)
+	}
+
+	// The queue survives a failover.
+	recovered := auction.NewManager(es, players, slog.Default(), noop.NewTracerProvider(), clk, auction.WithSettings(svc, "g1"))
+	if _, err := recovered.RecoverOpenAuctions(ctx); err != nil {
+		t.Fatalf("RecoverOpenAuctions() error = %v", err)
+	}
+	if err := recovered.CancelAuction(ctx, third.ID); err != nil {
+		t.Fatalf("CancelAuction() of a queued auction error = %v", err)
+	}
+	result, err := recovered.CloseAuction(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("CloseAuction() error = %v", err)
+	}
+	if len(result.Started) != 1 || result.Started[0].ID != second.ID || result.Started[0].Status != "open" {
+		t.Fatalf("CloseAuction() started %+v, want %s", result.Started, second.ID)
+	}
+	if err := recovered.PlaceBid(ctx, second.ID, "discord-1", 20); err != nil {
+		t.Errorf("PlaceBid() on the started auction error = %v", err)
+	}
+	if open := recovered.OpenAuctions(); !slices.Equal(open, []string{second.ID}) {
+		t.Errorf("OpenAuctions() = %v, want [%s]", open, second.ID)
+	}
+}
+
+// slowStore takes a millisecond to append events, so concurrent callers
+// overlap while they persist.
+type slowStore struct {
+	event.Store
+}
+
+func (s slowStore) Append(ctx context.Context, events ...event.Event) error {
+	time.Sleep(time.Millisecond)
+	return s.Store.Append(ctx, events...)
+}
+
+func TestManager_QueueConcurrentStarts(t *testing.T) {
+	repo := &mockSettingsRepo{settings: []store.GuildSetting{
+		{GuildID: "g1", Key: settings.MaxOpenAuctions, Value: "2"},
+	}}
+	svc := settings.NewService(repo, settings.Defaults(config.GuildDefaultsConfig{AuctionDuration: 5 * time.Minute, MinIncrement: 1}), slog.Default())
+	clk := &tickingClock{t: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	mgr := auction.NewManager(slowStore{eventtest.NewStore()}, storetest.NewPlayers(), slog.Default(), noop.NewTracerProvider(), clk, auction.WithSettings(svc, "g1"))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Go(func() {
+			if _, err := mgr.StartAuction(ctx, fmt.Sprintf("Item %d", i), "admin", 10, 0, 0, 0); err != nil {
+				t.Errorf("StartAuction() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if open := mgr.OpenAuctions(); len(open) != 2 {
+		t.Errorf("OpenAuctions() = %v, want the limit of 2", open)
+	}
+	states, err := mgr.ListOpenAuctions(ctx)
+	if err != nil || len(states) != 10 {
+		t.Fatalf("ListOpenAuctions() = %+v, %v, want all 10 auctions", states, err)
+	}
+	queued := 0
+	for _, st := range states {
+		if st.Status == "queued" {
+			queued++
+		}
+	}
+	if queued != 8 {
+		t.Errorf("queued auctions = %d, want 8", queued)
+	}
+}
+
+func TestManager_ScheduledAuction(t *testing.T) {
+	repo := &mockSettingsRepo{settings: []store.GuildSetting{
+		{GuildID: "g1", Key: settings.MaxOpenAuctions, Value: "1"},
+	}}
+	svc := settings.NewService(repo, settings.Defaults(config.GuildDefaultsConfig{AuctionDuration: 5 * time.Minute, MinIncrement: 1}), slog.Default())
+	es := eventtest.NewStore()
+	players := storetest.NewPlayers()
+	players.Put(store.Player{ID: "player-1", DiscordID: "discord-1", DKP: 100})
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	clk := &tickingClock{t: now}
+	mgr := auction.NewManager(es, players, slog.Default(), noop.NewTracerProvider(), clk, auction.WithSettings(svc, "g1"))
+	ctx := context.Background()
+
+	if _, err := mgr.StartAuction(ctx, "Helm", "admin", 10, 0, 0, 0, auction.StartingAt(now.Add(-time.Minute))); !errors.Is(err, auction.ErrPastStart) {
+		t.Errorf("StartAuction() in the past error = %v, want ErrPastStart", err)
+	}
+	helm, err := mgr.StartAuction(ctx, "Helm", "admin", 10, 0, 0, 0, auction.StartingAt(now.Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("StartAuction() error = %v", err)
+	}
+	sword, _ := mgr.StartAuction(ctx, "Sword", "admin", 10, 0, 0, 0, auction.StartingAt(now.Add(2*time.Hour)))
+	if helm.Status != "scheduled" || !helm.StartsAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("StartAuction() = %s starting %v, want scheduled at %v", helm.Status, helm.StartsAt, now.Add(time.Hour))
+	}
+	if err := mgr.PlaceBid(ctx, helm.ID, "discord-1", 20); !errors.Is(err, store.ErrAuctionNotFound) {
+		t.Errorf("PlaceBid() on a scheduled auction error = %v, want ErrAuctionNotFound", err)
+	}
+	states, err := mgr.ListOpenAuctions(ctx)
+	if err != nil || len(states) != 2 || states[0].Status != "scheduled" || states[0].StartsAt.IsZero() {
+		t.Errorf("ListOpenAuctions() = %+v, %v, want both scheduled auctions", states, err)
+	}
+	if opened := mgr.OpenScheduled(ctx); len(opened) != 0 {
+		t.Errorf("OpenScheduled() before the start = %+v, want none", opened)
+	}
+
+	// The schedule survives a failover.
+	recovered := auction.NewManager(es, players, slog.Default(), noop.NewTracerProvider(), clk, auction.WithSettings(svc, "g1"))
+	if _, err := recovered.RecoverOpenAuctions(ctx); err != nil {
+		t.Fatalf("RecoverOpenAuctions() error = %v", err)
+	}
+	clk.t = now.Add(time.Hour)
+	opened := recovered.OpenScheduled(ctx)
+	if len(opened) != 1 || opened[0].ID != helm.ID || opened[0].Status != "open" {
+		t.Fatalf("OpenScheduled() = %+v, want %s opened", opened, helm.ID)
+	}
+	if err := recovered.PlaceBid(ctx, helm.ID, "discord-1", 20); err != nil {
+		t.Errorf("PlaceBid() on the opened auction error = %v", err)
+	}
+
+	// With the limit of open auctions reached, a due auction is queued.
+	clk.t = now.Add(2 * time.Hour)
+	if opened := recovered.OpenScheduled(ctx); len(opened) != 0 {
+		t.Errorf("OpenScheduled() at the limit = %+v, want none", opened)
+	}
+	if a, err := recovered.ReplayAuction(ctx, sword.ID); err != nil || a.Status != "queued" {
+		t.Fatalf("ReplayAuction() = %v, want the auction queued", err)
+	}
+	result, err := recovered.CloseAuction(ctx, helm.ID)
+	if err != nil {
+		t.Fatalf("CloseAuction() error = %v", err)
+	}
+	if len(result.Started) != 1 || result.Started[0].ID != sword.ID {
+		t.Errorf("CloseAuction() started %+v, want %s", result.Started, sword.ID)
+	}
+}
+
+// gatedStore holds up the first bid event appended until gate is closed,
+// reporting on held that it is held up.
+type gatedStore struct {
+	event.Store
+	once sync.Once
+	held chan struct{}
+	gate chan struct{}
+}
+
+func (s *gatedStore) Append(ctx context.Context, events ...event.Event) error {
+	if len(events) > 0 && events[0].Type == event.AuctionBidPlaced {
+		s.once.Do(func() {
+			close(s.held)
+			<-s.gate
+		})
+	}
+	return s.Store.Append(ctx, events...)
+}
+
+func TestManager_BidQueue(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	recorder, err := metrics.New(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)), "g1")
+	if err != nil {
+		t.Fatalf("metrics.New() error = %v", err)
+	}
+	es := &gatedStore{Store: eventtest.NewStore(), held: make(chan struct{}), gate: make(chan struct{})}
+	players := storetest.NewPlayers()
+	for n := 1; n <= 3; n++ {
+		players.Put(store.Player{ID: fmt.Sprintf("player-%d", n), DiscordID: fmt.Sprintf("discord-%d", n), DKP: 100})
+	}
+	mgr := auction.NewManager(es, players, slog.Default(), noop.NewTracerProvider(), clock.Real{}, auction.WithMetrics(recorder))
+	ctx := context.Background()
+	a, _ := mgr.StartAuction(ctx, "Helm", "admin", 10, 0, 0, 0)
+
+	// The first bid holds the queue while two more join it, the later
+	// arrival first.
+	t0 := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+	errs := make([]error, 4)
+	var wg sync.WaitGroup
+	bid := func(n, amount int, arrived time.Time) {
+		wg.Go(func() {
+			errs[n] = mgr.PlaceBid(auction.WithArrival(ctx, arrived), a.ID, fmt.Sprintf("discord-%d", n), amount)
+		})
+	}
+	bid(1, 10, t0)
+	<-es.held
+	bid(2, 30, t0.Add(2*time.Second))
+	bid(3, 20, t0.Add(time.Second))
+	for queued := int64(0); queued < 3; {
+		var rm metricdata.ResourceMetrics
+		if err := reader.Collect(ctx, &rm); err != nil {
+			t.Fatalf("Collect() error = %v", err)
+		}
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				if h, ok := m.Data.(metricdata.Histogram[int64]); ok && m.Name == "dkpbot.bid_queue.depth" {
+					queued = int64(h.DataPoints[0].Count)
+				}
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(es.gate)
+	wg.Wait()
+
+	// Placed in the order they arrived, the bid of 20 precedes that of 30
+	// and is not rejected as too low.
+	for n, err := range errs[1:] {
+		if err != nil {
+			t.Errorf("bid %d error = %v", n+1, err)
+		}
+	}
+	var amounts []int
+	for _, b := range a.State().Bids {
+		amounts = append(amounts, b.Amount)
+	}
+	if !slices.Equal(amounts, []int{10, 20, 30}) {
+		t.Errorf("bids = %v, want 10, 20, 30", amounts)
+	}
+}
+
+// stepClock is a mock clock whose time the test moves on.
+type stepClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *stepClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *stepClock) set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = t
+}
+
+func TestManager_BidCooldown(t *testing.T) {
+	repo := &mockSettingsRepo{settings: []store.GuildSetting{
+		{GuildID: "g1", Key: settings.BidCooldown, Value: "5s"},
+		{GuildID: "g1", Key: settings.BidWarCooldown, Value: "20s"},
+	}}
+	svc := settings.NewService(repo, settings.Defaults(config.GuildDefaultsConfig{AuctionDuration: 5 * time.Minute, MinIncrement: 1}), slog.Default())
+	players := storetest.NewPlayers()
+	for n := 1; n <= 3; n++ {
+		players.Put(store.Player{ID: fmt.Sprintf("player-%d", n), DiscordID: fmt.Sprintf("discord-%d", n), DKP: 100})
+	}
+	t0 := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+	clk := &stepClock{t: t0}
+	mgr := auction.NewManager(eventtest.NewStore(), players, slog.Default(), noop.NewTracerProvider(), clk, auction.WithSettings(svc, "g1"))
+	ctx := context.Background()
+	a, _ := mgr.StartAuction(ctx, "Helm", "admin", 10, 0, 0, 0)
+
+	for _, step := range []struct {
+		at      time.Duration
+		player  int
+		amount  int
+		wantMsg string
+	}{
+		{at: 0, player: 1, amount: 10},
+		// Others may bid at once.
+		{at: 2 * time.Second, player: 2, amount: 11},
+		{at: 3 * time.Second, player: 1, amount: 12, wantMsg: "wait 2 seconds before bidding again"},
+		{at: 5 * time.Second, player: 1, amount: 12},
+		{at: 7 * time.Second, player: 2, amount: 13},
+		// Players 1 and 2 have traded four bids within a minute.
+		{at: 12 * time.Second, player: 1, amount: 14, wantMsg: "wait 13 seconds before bidding again"},
+		{at: 12 * time.Second, player: 3, amount: 14},
+		// A third bidder ends the bid war.
+		{at: 13 * time.Second, player: 1, amount: 15},
+	} {
+		clk.set(t0.Add(step.at))
+		err := mgr.PlaceBid(ctx, a.ID, fmt.Sprintf("discord-%d", step.player), step.amount)
+		switch {
+		case step.wantMsg == "" && err != nil:
+			t.Errorf("at %v: player %d bid error = %v", step.at, step.player, err)
+		case step.wantMsg != "" && (!errors.Is(err, auction.ErrBidCooldown) || derrors.MessageOf(err) != step.wantMsg):
+			t.Errorf("at %v: player %d bid error = %v, want %q", step.at, step.player, err, step.wantMsg)
+		}
+	}
+	if got := len(a.State().Bids); got != 6 {
+		t.Errorf("placed %d bids, want 6", got)
+	}
+}
@@ -0,0 +1,306 @@
+package auction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// lifecycle are the event types that change an auction's row in the
+// auctions table.
+var lifecycle = []event.Type{
+	event.AuctionScheduled, event.AuctionQueued, event.AuctionStarted,
+	event.AuctionPaused, event.AuctionResumed, event.AuctionRollStarted,
+	event.AuctionClosed, event.AuctionBoughtOut, event.AuctionCanceled,
+}
+
+// Discrepancy kinds, recorded on the dkpbot.auctions.discrepancies metric.
+const (
+	// DiscrepancyMissing marks an auction open in the event log that has
+	// no row.
+	DiscrepancyMissing = "missing"
+	// DiscrepancyStatus marks a row whose status differs from the one the
+	// event log gives.
+	DiscrepancyStatus = "status"
+	// DiscrepancyOrphaned marks an open row without events, as after its
+	// events were archived. It is not repaired, as the event log has
+	// nothing to repair it from.
+	DiscrepancyOrphaned = "orphaned"
+)
+
+// Discrepancy is an auction whose row in the auctions table disagreed with
+// its events.
+type Discrepancy struct {
+	AuctionID string
+	ItemName  string
+	Kind      string
+	// Stored is the status of the row, or empty if there was none.
+	Stored string
+	// Logged is the status the events give, or empty if there were none.
+	Logged string
+	// Repaired reports whether the row was saved as the events give it.
+	Repaired bool
+}
+
+// Reconciliation is the result of Projection.Reconcile.
+type Reconciliation struct {
+	// Checked is how many auctions are open in the event log, the auctions
+	// table, or both.
+	Checked       int
+	Discrepancies []Discrepancy
+}
+
+// Summary describes the discrepancies for the officer channel.
+func (r Reconciliation) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Auction records checked at startup**\nThe auctions table disagreed with the event log for %d of %d open auctions:\n",
+		len(r.Discrepancies), r.Checked)
+	for _, d := range r.Discrepancies {
+		var what string
+		switch d.Kind {
+		case DiscrepancyMissing:
+			what = fmt.Sprintf("missing from the table, %s in the event log", d.Logged)
+		case DiscrepancyStatus:
+			what = fmt.Sprintf("stored as %s, %s in the event log", d.Stored, d.Logged)
+		case DiscrepancyOrphaned:
+			what = fmt.Sprintf("stored as %s, with no events", d.Stored)
+		}
+		outcome := "repaired"
+		if !d.Repaired {
+			outcome = "not repaired"
+		}
+		fmt.Fprintf(&b, "- %s (`%s`): %s; %s\n", d.ItemName, d.AuctionID, what, outcome)
+	}
+	return b.String()
+}
+
+// Projection keeps the auctions table in step with the auction events. It
+// saves an auction's row after each change to its lifecycle, and Reconcile
+// repairs the rows left behind when the bot stopped between appending the
+// events and saving the row. It is safe for concurrent use.
+type Projection struct {
+	events   event.Store
+	auctions store.AuctionRepository
+	metrics  *metrics.Recorder
+	logger   *slog.Logger
+	tracer   trace.Tracer
+
+	// mu guards pending, the auctions whose rows are to be saved.
+	mu      sync.Mutex
+	pending map[string]struct{}
+}
+
+// NewProjection returns a Projection of events into auctions.
+func NewProjection(events event.Store, auctions store.AuctionRepository, recorder *metrics.Recorder, logger *slog.Logger, tp trace.TracerProvider) *Projection {
+	return &Projection{
+		events:   events,
+		auctions: auctions,
+		metrics:  recorder,
+		logger:   logger,
+		tracer:   tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/auction"),
+		pending:  make(map[string]struct{}),
+	}
+}
+
+// Run saves the rows of the auctions whose lifecycle events are published
+// on bus, until ctx is done. The rows are saved in the background, so that
+// bids and commands do not wait for them.
+func (p *Projection) Run(ctx context.Context, bus *event.Bus) {
+	changed := make(chan struct{}, 1)
+	unsubscribe := bus.Subscribe(func(_ context.Context, e event.Event) {
+		p.mu.Lock()
+		p.pending[e.AggregateID] = struct{}{}
+		p.mu.Unlock()
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}, lifecycle...)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		}
+		p.mu.Lock()
+		ids := slices.Sorted(maps.Keys(p.pending))
+		clear(p.pending)
+		p.mu.Unlock()
+		for _, id := range ids {
+			if err := p.Sync(ctx, id); err != nil {
+				p.logger.ErrorContext(ctx, "saving auction row failed",
+					slog.String("auction_id", id),
+					slog.Any("error", err),
+				)
+			}
+		}
+	}
+}
+
+// Sync saves the row of the auction id as its events give it.
+func (p *Projection) Sync(ctx context.Context, id string) error {
+	events, err := p.events.Load(ctx, id)
+	if err != nil {
+		return fmt.Errorf("loading events of auction %s: %w", id, err)
+	}
+	if len(events) == 0 {
+		return store.ErrAuctionNotFound.Wrap(fmt.Errorf("auction %s", id))
+	}
+	row, err := project(events)
+	if err != nil {
+		return err
+	}
+	return p.auctions.Save(ctx, &row)
+}
+
+// Reconcile cross-checks the auctions open in the event log with those
+// open in the auctions table, saves the row of every auction that
+// disagrees as its events give it, and reports the discrepancies in the
+// log and on the dkpbot.auctions.discrepancies metric. The leader runs it
+// at startup.
+func (p *Projection) Reconcile(ctx context.Context) (Reconciliation, error) {
+	ctx, span := p.tracer.Start(ctx, "Projection.Reconcile")
+	defer span.End()
+
+	logged, err := openAuctionIDs(ctx, p.events)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	rows, err := p.auctions.ListOpen(ctx)
+	if err != nil {
+		return Reconciliation{}, fmt.Errorf("listing open auction rows: %w", err)
+	}
+	stored := make(map[string]store.Auction, len(rows))
+	for _, row := range rows {
+		stored[row.ID] = row
+	}
+	ids := slices.Sorted(maps.Keys(stored))
+	for _, id := range logged {
+		if _, ok := stored[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	report := Reconciliation{Checked: len(ids)}
+	for _, id := range ids {
+		row, ok := stored[id]
+		if !ok {
+			got, err := p.auctions.GetByID(ctx, id)
+			switch {
+			case errors.Is(err, store.ErrAuctionNotFound):
+			case err != nil:
+				return report, fmt.Errorf("getting auction row %s: %w", id, err)
+			default:
+				row, ok = *got, true
+			}
+		}
+		events, err := p.events.Load(ctx, id)
+		if err != nil {
+			return report, fmt.Errorf("loading events of auction %s: %w", id, err)
+		}
+
+		if len(events) == 0 {
+			p.report(ctx, &report, Discrepancy{AuctionID: id, ItemName: row.ItemName, Kind: DiscrepancyOrphaned, Stored: row.Status})
+			continue
+		}
+		want, err := project(events)
+		if err != nil {
+			return report, err
+		}
+		d := Discrepancy{AuctionID: id, ItemName: want.ItemName, Logged: want.Status}
+		switch {
+		case !ok:
+			d.Kind = DiscrepancyMissing
+		case row.Status != want.Status:
+			d.Kind, d.Stored = DiscrepancyStatus, row.Status
+		default:
+			continue
+		}
+		if err := p.auctions.Save(ctx, &want); err != nil {
+			p.logger.ErrorContext(ctx, "repairing auction row failed",
+				slog.String("auction_id", id),
+				slog.Any("error", err),
+			)
+		} else {
+			d.Repaired = true
+		}
+		p.report(ctx, &report, d)
+	}
+	span.SetAttributes(
+		attribute.Int("auctions", report.Checked),
+		attribute.Int("discrepancies", len(report.Discrepancies)),
+	)
+	return report, nil
+}
+
+// report records d in the log, on the metric, and in r.
+func (p *Projection) report(ctx context.Context, r *Reconciliation, d Discrepancy) {
+	p.logger.WarnContext(ctx, "auction row disagreed with its events",
+		slog.String("auction_id", d.AuctionID),
+		slog.String("discrepancy", d.Kind),
+		slog.String("stored", d.Stored),
+		slog.String("logged", d.Logged),
+		slog.Bool("repaired", d.Repaired),
+	)
+	p.metrics.AuctionDiscrepancy(ctx, d.Kind)
+	r.Discrepancies = append(r.Discrepancies, d)
+}
+
+// project returns the row of the auction whose events are given.
+func project(events []event.Event) (store.Auction, error) {
+	a, err := Replay(events)
+	if err != nil {
+		return store.Auction{}, fmt.Errorf("replaying auction %s: %w", events[0].AggregateID, err)
+	}
+	row := store.Auction{
+		ID:        a.ID,
+		ItemName:  a.ItemName,
+		StartedBy: a.StartedBy,
+		MinBid:    a.MinBid,
+		Status:    a.Status,
+		CreatedAt: events[0].CreatedAt,
+	}
+	for _, e := range events {
+		var winner string
+		var amount int
+		switch e.Type {
+		case event.AuctionClosed:
+			var d event.AuctionClosedData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return store.Auction{}, fmt.Errorf("unmarshaling closed event: %w", err)
+			}
+			winner, amount = d.WinnerID, d.Amount
+		case event.AuctionBoughtOut:
+			var d event.AuctionBoughtOutData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return store.Auction{}, fmt.Errorf("unmarshaling buyout event: %w", err)
+			}
+			winner, amount = d.BuyerID, d.Amount
+		case event.AuctionCanceled:
+		default:
+			continue
+		}
+		closedAt := e.CreatedAt
+		row.ClosedAt = &closedAt
+		if winner != "" {
+			row.WinnerID, row.WinAmount = &winner, &amount
+		}
+	}
+	return row, nil
+}
@@ -0,0 +1,174 @@
+package auction_test
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+func TestProjection_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	es := eventtest.NewStore()
+	bus := event.NewBus()
+	auctions := storetest.NewAuctions()
+	players := storetest.NewPlayers()
+	players.Put(store.Player{ID: "p1", DiscordID: "d1", DKP: 100})
+	mgr := auction.NewManager(event.NewPublishingStore(es, bus), players, slog.Default(), noop.NewTracerProvider(), clock.Real{})
+	p := auction.NewProjection(es, auctions, nil, slog.Default(), noop.NewTracerProvider())
+	go p.Run(ctx, bus)
+
+	a, err := mgr.StartAuction(ctx, "Helm", "admin", 10, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("StartAuction() error = %v", err)
+	}
+	// The start may be published before Run subscribes, so it is
+	// published again until the row is saved.
+	waitFor(t, func() bool {
+		bus.Publish(ctx, event.Event{Type: event.AuctionStarted, AggregateID: a.ID})
+		_, err := auctions.GetByID(ctx, a.ID)
+		return err == nil
+	})
+	if got := auctions.Auction(t, a.ID); got.Status != "open" || got.ItemName != "Helm" {
+		t.Errorf("row = %+v, want open Helm", got)
+	}
+
+	if err := mgr.PlaceBid(ctx, a.ID, "d1", 40); err != nil {
+		t.Fatalf("PlaceBid() error = %v", err)
+	}
+	if _, err := mgr.CloseAuction(ctx, a.ID); err != nil {
+		t.Fatalf("CloseAuction() error = %v", err)
+	}
+	waitFor(t, func() bool { return auctions.Auction(t, a.ID).Status == "closed" })
+	got := auctions.Auction(t, a.ID)
+	if got.WinnerID == nil || *got.WinnerID != "p1" || got.WinAmount == nil || *got.WinAmount != 40 || got.ClosedAt == nil {
+		t.Errorf("closed row = %+v, want won by p1 for 40", got)
+	}
+}
+
+func TestProjection_Reconcile(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	recorder, err := metrics.New(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)), "g1")
+	if err != nil {
+		t.Fatalf("metrics.New() error = %v", err)
+	}
+	es := eventtest.NewStore()
+	mgr := auction.NewManager(es, storetest.NewPlayers(), slog.Default(), noop.NewTracerProvider(), clock.Real{})
+	start := func(item string) string {
+		a, err := mgr.StartAuction(ctx, item, "admin", 10, 0, 0, 0)
+		if err != nil {
+			t.Fatalf("StartAuction(%q) error = %v", item, err)
+		}
+		return a.ID
+	}
+	// The bot stopped before saving the rows of a started auction and of
+	// a canceled one, and an archived auction left an open row behind.
+	missing := start("Helm")
+	canceled := start("Boots")
+	inStep := start("Ring")
+	if err := mgr.CancelAuction(ctx, canceled); err != nil {
+		t.Fatalf("CancelAuction() error = %v", err)
+	}
+	auctions := storetest.NewAuctions(
+		store.Auction{ID: canceled, ItemName: "Boots", Status: "open"},
+		store.Auction{ID: inStep, ItemName: "Ring", Status: "open"},
+		store.Auction{ID: "archived", ItemName: "Cloak", Status: "open"},
+	)
+	p := auction.NewProjection(es, auctions, recorder, slog.Default(), noop.NewTracerProvider())
+
+	report, err := p.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if report.Checked != 4 {
+		t.Errorf("Checked = %d, want 4", report.Checked)
+	}
+	kinds := make(map[string]auction.Discrepancy)
+	for _, d := range report.Discrepancies {
+		kinds[d.AuctionID] = d
+	}
+	want := map[string]auction.Discrepancy{
+		missing:    {AuctionID: missing, ItemName: "Helm", Kind: auction.DiscrepancyMissing, Logged: "open", Repaired: true},
+		canceled:   {AuctionID: canceled, ItemName: "Boots", Kind: auction.DiscrepancyStatus, Stored: "open", Logged: "canceled", Repaired: true},
+		"archived": {AuctionID: "archived", ItemName: "Cloak", Kind: auction.DiscrepancyOrphaned, Stored: "open"},
+	}
+	if len(kinds) != len(want) {
+		t.Errorf("discrepancies = %+v, want %d", report.Discrepancies, len(want))
+	}
+	for id, w := range want {
+		if kinds[id] != w {
+			t.Errorf("discrepancy of %s = %+v, want %+v", id, kinds[id], w)
+		}
+	}
+
+	if got := auctions.Auction(t, missing); got.Status != "open" || got.ItemName != "Helm" {
+		t.Errorf("repaired row = %+v, want open Helm", got)
+	}
+	if got := auctions.Auction(t, canceled); got.Status != "canceled" || got.ClosedAt == nil {
+		t.Errorf("repaired row = %+v, want canceled", got)
+	}
+	if got := auctions.Auction(t, "archived"); got.Status != "open" {
+		t.Errorf("orphaned row status = %q, want it left open", got.Status)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	var total int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if s, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == "dkpbot.auctions.discrepancies" {
+				for _, dp := range s.DataPoints {
+					total += dp.Value
+				}
+			}
+		}
+	}
+	if total != 3 {
+		t.Errorf("dkpbot.auctions.discrepancies = %d, want 3", total)
+	}
+
+	summary := report.Summary()
+	for _, s := range []string{"3 of 4", "Helm", "stored as open, canceled in the event log", "with no events; not repaired"} {
+		if !strings.Contains(summary, s) {
+			t.Errorf("Summary() = %q, want it to contain %q", summary, s)
+		}
+	}
+
+	// Once repaired, the rows agree with the events.
+	report, err = p.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if len(report.Discrepancies) != 1 || report.Discrepancies[0].Kind != auction.DiscrepancyOrphaned {
+		t.Errorf("second Reconcile() discrepancies = %+v, want only the orphaned row", report.Discrepancies)
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after a second.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
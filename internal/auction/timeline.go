@@ -0,0 +1,113 @@
+package auction
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// Step is an event of an auction's history and the state of the auction
+// after it, as Timeline replays them.
+type Step struct {
+	Event event.Event
+	State State
+	// HighestBid is the highest bid after the event, as HighestBid
+	// returns it, or nil.
+	HighestBid *Bid
+	// Err tells why the event could not be applied, in which case State is
+	// that before it, or how it contradicts the history before it.
+	Err error
+}
+
+// Timeline replays events, the history of one auction, one at a time as
+// Replay does, for debugging auctions with an unexpected outcome. Unlike
+// Replay it does not stop at an event it cannot apply, and it checks each
+// event against the state before it: that it belongs to the auction and
+// follows the previous version, that bids were placed on an open auction
+// at no less than it accepted, that nothing but announcements and tax
+// follows the close, and that the close names the winner the replayed
+// bids and rolls give.
+func Timeline(events []event.Event) []Step {
+	steps := make([]Step, 0, len(events))
+	a := replayed()
+	for i, e := range events {
+		var errs []error
+		if e.AggregateID != events[0].AggregateID {
+			errs = append(errs, fmt.Errorf("belongs to %s, not %s", e.AggregateID, events[0].AggregateID))
+		}
+		if i > 0 && e.Version != a.Version+1 {
+			errs = append(errs, fmt.Errorf("version %d follows version %d", e.Version, a.Version))
+		}
+		errs = append(errs, a.check(e)...)
+		if err := a.apply(e); err != nil {
+			errs = append(errs, err)
+		}
+		steps = append(steps, Step{Event: e, State: a.State(), HighestBid: a.HighestBid(), Err: errors.Join(errs...)})
+	}
+	return steps
+}
+
+// check returns how e contradicts the auction before it.
+func (a *Auction) check(e event.Event) []error {
+	if a.Status == "closed" || a.Status == "canceled" {
+		if e.Type != event.AuctionAnnounced && e.Type != event.AuctionTaxed {
+			return []error{fmt.Errorf("follows the auction being %s", a.Status)}
+		}
+		return nil
+	}
+	switch e.Type {
+	case event.AuctionScheduled, event.AuctionQueued, event.AuctionStarted:
+		if a.Status == "open" || a.Status == "paused" || a.Status == "rolling" {
+			return []error{fmt.Errorf("starts the auction while it is %s", a.Status)}
+		}
+	case event.AuctionBidPlaced:
+		var d event.BidPlacedData
+		if json.Unmarshal(e.Data, &d) != nil {
+			return nil
+		}
+		var errs []error
+		if a.Status != "open" {
+			errs = append(errs, fmt.Errorf("bid placed while the auction is %s", a.Status))
+		}
+		if d.Amount < a.MinBid {
+			errs = append(errs, fmt.Errorf("bid of %d is below the minimum bid of %d", d.Amount, a.MinBid))
+		}
+		if h := a.highestBid(); h != nil && d.Amount < h.Amount+a.MinIncrement {
+			errs = append(errs, fmt.Errorf("bid of %d does not exceed the highest bid of %d by %d", d.Amount, h.Amount, a.MinIncrement))
+		}
+		return errs
+	case event.AuctionClosed:
+		var d event.AuctionClosedData
+		if json.Unmarshal(e.Data, &d) != nil {
+			return nil
+		}
+		winner, amount := a.expectedWinner()
+		if d.WinnerID != winner || (winner != "" && d.Amount != amount) {
+			return []error{fmt.Errorf("closed with winner %q for %d, but the replayed bids and rolls give %q for %d", d.WinnerID, d.Amount, winner, amount)}
+		}
+	case event.AuctionRolled:
+		if a.Status != "rolling" {
+			return []error{fmt.Errorf("roll while the auction is %s", a.Status)}
+		}
+	}
+	return nil
+}
+
+// expectedWinner returns who should win the auction when it closes, and for
+// how much, as Close decides it after skipping the winners it skips. It
+// returns "" if nobody should.
+func (a *Auction) expectedWinner() (string, int) {
+	if a.Status == "rolling" {
+		if r := a.highestRoll(); r != nil {
+			return r.PlayerID, a.MinBid
+		}
+		return "", 0
+	}
+	h := a.highestBid()
+	if h == nil || h.Amount < a.Reserve {
+		return "", 0
+	}
+	return h.PlayerID, h.Amount
+}
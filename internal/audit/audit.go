@@ -0,0 +1,455 @@
+// Package audit renders the event log as a human-readable timeline for
+// officers reviewing DKP and auction activity.
+package audit
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// Entry is a single rendered line of the audit timeline.
+type Entry struct {
+	// ID identifies the event, for example to /dkp-undo it.
+	ID          string
+	Time        time.Time
+	Type        event.Type
+	AggregateID string
+	Actor       string
+	Summary     string
+}
+
+// Log queries the event store and renders entries.
+type Log struct {
+	events  event.Store
+	players store.PlayerRepository
+	tracer  trace.Tracer
+}
+
+// NewLog returns a new audit Log.
+func NewLog(events event.Store, players store.PlayerRepository, tp trace.TracerProvider) *Log {
+	return &Log{
+		events:  events,
+		players: players,
+		tracer:  tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/audit"),
+	}
+}
+
+// Query returns rendered entries for events matching q, newest first.
+func (l *Log) Query(ctx context.Context, q event.Query) ([]Entry, error) {
+	ctx, span := l.tracer.Start(ctx, "Log.Query",
+		trace.WithAttributes(
+			attribute.String("aggregate_id", q.AggregateID),
+			attribute.String("player_id", q.Player),
+			attribute.String("actor", q.Actor),
+			attribute.Int("limit", q.Limit),
+		),
+	)
+	defer span.End()
+
+	events, err := l.events.Query(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("querying events: %w", err)
+	}
+
+	players, err := l.players.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing players: %w", err)
+	}
+	names := make(map[string]string, len(players))
+	for _, p := range players {
+		names[p.ID] = p.CharacterName
+	}
+
+	entries := make([]Entry, 0, len(events))
+	for _, e := range events {
+		entries = append(entries, Entry{
+			ID:          e.ID,
+			Time:        e.CreatedAt,
+			Type:        e.Type,
+			AggregateID: e.AggregateID,
+			Actor:       e.Actor,
+			Summary:     Describe(e, names),
+		})
+	}
+	return entries, nil
+}
+
+// Describe renders e as a sentence. names maps player IDs to character names;
+// unknown IDs are shown verbatim.
+func Describe(e event.Event, names map[string]string) string {
+	actor := "System"
+	if e.Actor != "" {
+		actor = "<@" + e.Actor + ">"
+	}
+	name := func(id string) string {
+		if n, ok := names[id]; ok {
+			return n
+		}
+		return id
+	}
+
+	switch e.Type {
+	case event.DKPAwarded, event.DKPDeducted, event.DKPAdjusted:
+		var d event.DKPChangeData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		switch {
+		case e.Type == event.DKPAwarded:
+			return fmt.Sprintf("%s awarded %d DKP to %s for %s", actor, d.Amount, name(d.PlayerID), d.Reason)
+		case e.Type == event.DKPDeducted:
+			return fmt.Sprintf("%s deducted %d DKP from %s for %s", actor, abs(d.Amount), name(d.PlayerID), d.Reason)
+		case d.Merge != nil:
+			return fmt.Sprintf("%s imported %+d DKP for %s from %s's %d DKP in guild merge %s", actor, d.Amount, name(d.PlayerID), d.Merge.Character, d.Merge.Balance, d.Merge.ID)
+		case d.Correction != nil:
+			return fmt.Sprintf("%s corrected the balance of %s from %d to %d DKP for %s", actor, name(d.PlayerID), d.Correction.From, d.Correction.To, d.Reason)
+		case d.Counterparty != "" && d.Amount < 0:
+			return fmt.Sprintf("%s transferred %d DKP from %s to %s for %s", actor, -d.Amount, name(d.PlayerID), name(d.Counterparty), d.Reason)
+		case d.Counterparty != "":
+			return fmt.Sprintf("%s transferred %d DKP to %s from %s for %s", actor, d.Amount, name(d.PlayerID), name(d.Counterparty), d.Reason)
+		default:
+			return fmt.Sprintf("%s adjusted %s by %+d DKP for %s", actor, name(d.PlayerID), d.Amount, d.Reason)
+		}
+
+	case event.CurrencyAwarded, event.CurrencyDeducted, event.CurrencyTransferred:
+		var d event.CurrencyChangeData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		switch {
+		case e.Type == event.CurrencyAwarded:
+			return fmt.Sprintf("%s awarded %d %s to %s for %s", actor, d.Amount, d.Currency, name(d.PlayerID), d.Reason)
+		case e.Type == event.CurrencyDeducted:
+			return fmt.Sprintf("%s deducted %d %s from %s for %s", actor, abs(d.Amount), d.Currency, name(d.PlayerID), d.Reason)
+		case d.Amount < 0:
+			return fmt.Sprintf("%s transferred %d %s from %s to %s for %s", actor, -d.Amount, d.Currency, name(d.PlayerID), name(d.Counterparty), d.Reason)
+		default:
+			return fmt.Sprintf("%s transferred %d %s to %s from %s for %s", actor, d.Amount, d.Currency, name(d.PlayerID), name(d.Counterparty), d.Reason)
+		}
+
+	case event.PlayerRegistered:
+		var d event.PlayerRegisteredData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("<@%s> registered character %s", d.DiscordID, d.CharacterName)
+
+	case event.PlayerProfileUpdated:
+		var d event.PlayerProfileUpdatedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		msg := fmt.Sprintf("%s set the profile of %s to class %q, role %q, spec %q", actor, name(e.AggregateID), d.Class, d.Role, d.Spec)
+		if d.Timezone != "" {
+			msg += fmt.Sprintf(", timezone %s", d.Timezone)
+		}
+		return msg
+
+	case event.PlayerArchived, event.PlayerRestored:
+		var d event.PlayerArchivedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		if e.Type == event.PlayerRestored {
+			return fmt.Sprintf("%s restored %s with %d DKP", actor, name(e.AggregateID), d.DKP)
+		}
+		desc := fmt.Sprintf("%s archived %s, freezing %d DKP", actor, name(e.AggregateID), d.DKP)
+		if d.Reason != "" {
+			desc += " (" + d.Reason + ")"
+		}
+		return desc
+
+	case event.PlayerLootBanned, event.PlayerLootBanLifted:
+		if e.Type == event.PlayerLootBanLifted {
+			return fmt.Sprintf("%s lifted the loot ban of %s", actor, name(e.AggregateID))
+		}
+		var d event.PlayerLootBanData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		desc := fmt.Sprintf("%s banned %s from loot until %s", actor, name(e.AggregateID), d.Until.UTC().Format("2006-01-02 15:04 UTC"))
+		if d.Reason != "" {
+			desc += " (" + d.Reason + ")"
+		}
+		return desc
+
+	case event.AuctionQueued:
+		var d event.AuctionStartedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s queued auction `%s` for %s until an open auction ends", actor, e.AggregateID, d.ItemName)
+
+	case event.AuctionScheduled:
+		var d event.AuctionStartedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s scheduled auction `%s` for %s to open at %s", actor, e.AggregateID, d.ItemName, d.StartsAt.UTC().Format("2006-01-02 15:04 UTC"))
+
+	case event.AuctionStarted:
+		var d event.AuctionStartedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		if d.Buyout > 0 {
+			return fmt.Sprintf("%s started auction `%s` for %s (min bid %d, buyout %d)", actor, e.AggregateID, d.ItemName, d.MinBid, d.Buyout)
+		}
+		return fmt.Sprintf("%s started auction `%s` for %s (min bid %d)", actor, e.AggregateID, d.ItemName, d.MinBid)
+
+	case event.AuctionBidPlaced:
+		var d event.BidPlacedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		if d.Outbid != "" && d.Outbid != d.PlayerID {
+			return fmt.Sprintf("%s bid %d DKP on auction `%s`, outbidding %s", name(d.PlayerID), d.Amount, e.AggregateID, name(d.Outbid))
+		}
+		return fmt.Sprintf("%s bid %d DKP on auction `%s`", name(d.PlayerID), d.Amount, e.AggregateID)
+
+	case event.AuctionClosed:
+		var d event.AuctionClosedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		if d.ReserveNotMet {
+			return fmt.Sprintf("%s closed auction `%s` without a winner as the reserve was not met", actor, e.AggregateID)
+		}
+		if d.WinnerID == "" {
+			return fmt.Sprintf("%s closed auction `%s` with no bids", actor, e.AggregateID)
+		}
+		if d.Roll > 0 {
+			return fmt.Sprintf("%s closed auction `%s`, won by %s with a roll of %d for %d DKP", actor, e.AggregateID, name(d.WinnerID), d.Roll, d.Amount)
+		}
+		return fmt.Sprintf("%s closed auction `%s`, won by %s for %d DKP", actor, e.AggregateID, name(d.WinnerID), d.Amount)
+
+	case event.AuctionRollStarted:
+		return fmt.Sprintf("%s started a roll for auction `%s`, which had no bids", actor, e.AggregateID)
+
+	case event.AuctionRolled:
+		var d event.AuctionRolledData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s rolled %d on auction `%s`", name(d.PlayerID), d.Roll, e.AggregateID)
+
+	case event.AuctionWinnerSkipped:
+		var d event.AuctionWinnerSkippedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s was skipped as winner of auction `%s`: bid %d DKP but has %d", name(d.PlayerID), e.AggregateID, d.Amount, d.DKP)
+
+	case event.AuctionBoughtOut:
+		var d event.AuctionBoughtOutData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s bought out auction `%s` for %d DKP", name(d.BuyerID), e.AggregateID, d.Amount)
+
+	case event.AuctionTaxed:
+		var d event.AuctionTaxedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		if len(d.Recipients) == 0 {
+			return fmt.Sprintf("%s was taxed %d DKP on auction `%s`, which was burned", name(d.WinnerID), d.Amount, e.AggregateID)
+		}
+		return fmt.Sprintf("%s was taxed %d DKP on auction `%s`, shared among %d raiders", name(d.WinnerID), d.Amount, e.AggregateID, len(d.Recipients))
+
+	case event.AuctionPaused:
+		return fmt.Sprintf("%s paused auction `%s`", actor, e.AggregateID)
+
+	case event.AuctionResumed:
+		var d event.AuctionResumedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s resumed auction `%s` after %s", actor, e.AggregateID, d.Paused.Round(time.Second))
+
+	case event.AuctionCanceled:
+		return fmt.Sprintf("%s canceled auction `%s`", actor, e.AggregateID)
+
+	case event.GDKPRaidStarted:
+		var d event.GDKPRaidStartedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s started GDKP raid %s (organizer cut %g%%)", actor, d.Name, d.OrganizerCut)
+
+	case event.GDKPRaidJoined:
+		var d event.GDKPRaidJoinedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("<@%s> joined GDKP raid `%s`", d.DiscordID, e.AggregateID)
+
+	case event.GDKPRaidEnded:
+		var d event.GDKPRaidEndedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s ended GDKP raid `%s`: %d gold pot, %d gold to each of %d participants", actor, e.AggregateID, d.Pot, d.Share, len(d.Participants))
+
+	case event.RaidScheduled:
+		var d event.RaidScheduledData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s scheduled raid %s for %s", actor, d.Name, d.StartsAt.UTC().Format("2006-01-02 15:04 UTC"))
+
+	case event.RaidSignedUp:
+		var d event.RaidSignedUpData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("<@%s> signed up for raid `%s` as %s", d.DiscordID, e.AggregateID, d.Status)
+
+	case event.RaidReminded:
+		var d event.RaidRemindedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%d members were reminded of raid `%s`", len(d.Recipients), e.AggregateID)
+
+	case event.RaidBonusAwarded:
+		var d event.RaidBonusAwardedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s awarded an on-time bonus of %d DKP to %d players for raid `%s`", actor, d.Amount, len(d.PlayerIDs), e.AggregateID)
+
+	case event.RaidComposed:
+		var d event.RaidComposedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s composed raid `%s`: %d in the roster, %d on standby", actor, e.AggregateID, len(d.Roster), len(d.Standby))
+
+	case event.BankItemDeposited:
+		var d event.BankItemDepositedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s banked %s as `%s`", actor, d.ItemName, e.AggregateID)
+
+	case event.BankItemAuctioned:
+		var d event.BankItemAuctionData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s put banked item `%s` up for auction `%s`", actor, e.AggregateID, d.AuctionID)
+
+	case event.BankItemReturned:
+		var d event.BankItemAuctionData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("banked item `%s` returned to the bank after auction `%s` ended without a winner", e.AggregateID, d.AuctionID)
+
+	case event.RaffleStarted:
+		var d event.RaffleStartedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s started raffle `%s` for %s at %d DKP a ticket", actor, e.AggregateID, d.ItemName, d.TicketCost)
+
+	case event.RaffleEntered:
+		var d event.RaffleEnteredData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s bought %d tickets in raffle `%s` for %d DKP", name(d.PlayerID), d.Tickets, e.AggregateID, d.Cost)
+
+	case event.RaffleDrawn:
+		var d event.RaffleDrawnData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		if d.WinnerID == "" {
+			return fmt.Sprintf("%s drew raffle `%s`, which sold no tickets", actor, e.AggregateID)
+		}
+		return fmt.Sprintf("%s drew raffle `%s`: ticket %d of %d, won by %s", actor, e.AggregateID, d.Ticket, d.Tickets, name(d.WinnerID))
+
+	case event.ItemCostSet, event.ItemCostRemoved, event.ItemCostAwarded:
+		var d event.ItemCostData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		switch e.Type {
+		case event.ItemCostRemoved:
+			return fmt.Sprintf("%s removed %s from the item cost table", actor, d.ItemName)
+		case event.ItemCostAwarded:
+			return fmt.Sprintf("%s awarded %s to %s for %d DKP", actor, d.ItemName, name(d.PlayerID), d.Cost)
+		}
+		return fmt.Sprintf("%s set the cost of %s to %d DKP", actor, d.ItemName, d.Cost)
+
+	case event.DisputeOpened:
+		var d event.DisputeOpenedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		if d.Anonymous {
+			actor = "An anonymous member"
+		}
+		if d.AuctionID != "" {
+			return fmt.Sprintf("%s disputed auction `%s` in `%s`: %s", actor, d.AuctionID, e.AggregateID, d.Reason)
+		}
+		return fmt.Sprintf("%s opened dispute `%s`: %s", actor, e.AggregateID, d.Reason)
+
+	case event.DisputeResolved:
+		var d event.DisputeResolvedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s resolved dispute `%s`: %s", actor, e.AggregateID, d.Resolution)
+
+	case event.AdminCommandRun:
+		var d event.AdminCommandData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			break
+		}
+		return fmt.Sprintf("%s ran `%s` from the command line as %s on %s", actor, d.Command, d.User, d.Host)
+	}
+
+	return fmt.Sprintf("%s recorded %s on %s", actor, e.Type, e.AggregateID)
+}
+
+// WriteCSV writes entries as CSV with a header row.
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "type", "aggregate_id", "actor", "summary"}); err != nil {
+		return fmt.Errorf("writing csv header: %w", err)
+	}
+	for _, e := range entries {
+		record := []string{
+			e.Time.UTC().Format(time.RFC3339),
+			string(e.Type),
+			e.AggregateID,
+			e.Actor,
+			e.Summary,
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("writing csv record: %w", err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,347 @@
+package audit_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/audit"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+func TestDescribe(t *testing.T) {
+	names := map[string]string{"p1": "Gandalf", "p2": "Frodo"}
+
+	tests := []struct {
+		name string
+		e    event.Event
+		want string
+	}{
+		{
+			name: "dkp awarded",
+			e: event.Event{
+				Type:  event.DKPAwarded,
+				Actor: "officer",
+				Data:  json.RawMessage(`{"player_id":"p1","amount":50,"reason":"boss kill"}`),
+			},
+			want: "<@officer> awarded 50 DKP to Gandalf for boss kill",
+		},
+		{
+			name: "dkp deducted without actor",
+			e: event.Event{
+				Type: event.DKPDeducted,
+				Data: json.RawMessage(`{"player_id":"p2","amount":-20,"reason":"late"}`),
+			},
+			want: "System deducted 20 DKP from Frodo for late",
+		},
+		{
+			name: "dkp corrected",
+			e: event.Event{
+				Type:  event.DKPAdjusted,
+				Actor: "officer",
+				Data:  json.RawMessage(`{"player_id":"p2","amount":-45,"reason":"double-counted raid","correction":{"from":140,"to":95,"officer":"officer"}}`),
+			},
+			want: "<@officer> corrected the balance of Frodo from 140 to 95 DKP for double-counted raid",
+		},
+		{
+			name: "dkp merged",
+			e: event.Event{
+				Type:  event.DKPAdjusted,
+				Actor: "officer",
+				Data:  json.RawMessage(`{"player_id":"p2","amount":25,"reason":"guild merge","merge":{"id":"merge-1","character":"Frodo","balance":50,"ratio":0.5}}`),
+			},
+			want: "<@officer> imported +25 DKP for Frodo from Frodo's 50 DKP in guild merge merge-1",
+		},
+		{
+			name: "dkp transferred",
+			e: event.Event{
+				Type:  event.DKPAdjusted,
+				Actor: "officer",
+				Data:  json.RawMessage(`{"player_id":"p1","amount":-10,"reason":"gift","counterparty":"p2"}`),
+			},
+			want: "<@officer> transferred 10 DKP from Gandalf to Frodo for gift",
+		},
+		{
+			name: "currency deducted",
+			e: event.Event{
+				Type:  event.CurrencyDeducted,
+				Actor: "officer",
+				Data:  json.RawMessage(`{"player_id":"p2","currency":"EP","amount":-5,"reason":"late"}`),
+			},
+			want: "<@officer> deducted 5 EP from Frodo for late",
+		},
+		{
+			name: "currency transferred",
+			e: event.Event{
+				Type:  event.CurrencyTransferred,
+				Actor: "officer",
+				Data:  json.RawMessage(`{"player_id":"p2","currency":"Raid tokens","amount":2,"reason":"trade","counterparty":"p1"}`),
+			},
+			want: "<@officer> transferred 2 Raid tokens to Frodo from Gandalf for trade",
+		},
+		{
+			name: "player archived",
+			e: event.Event{
+				Type:        event.PlayerArchived,
+				AggregateID: "p2",
+				Actor:       "officer",
+				Data:        json.RawMessage(`{"discord_id":"d2","dkp":120,"reason":"inactive"}`),
+			},
+			want: "<@officer> archived Frodo, freezing 120 DKP (inactive)",
+		},
+		{
+			name: "player restored",
+			e: event.Event{
+				Type:        event.PlayerRestored,
+				AggregateID: "p2",
+				Actor:       "officer",
+				Data:        json.RawMessage(`{"discord_id":"d2","dkp":120}`),
+			},
+			want: "<@officer> restored Frodo with 120 DKP",
+		},
+		{
+			name: "player loot banned",
+			e: event.Event{
+				Type:        event.PlayerLootBanned,
+				AggregateID: "p2",
+				Actor:       "officer",
+				Data:        json.RawMessage(`{"discord_id":"d2","until":"2025-07-01T00:00:00Z","reason":"ninja looting"}`),
+			},
+			want: "<@officer> banned Frodo from loot until 2025-07-01 00:00 UTC (ninja looting)",
+		},
+		{
+			name: "player loot ban lifted",
+			e: event.Event{
+				Type:        event.PlayerLootBanLifted,
+				AggregateID: "p2",
+				Actor:       "officer",
+				Data:        json.RawMessage(`{"discord_id":"d2"}`),
+			},
+			want: "<@officer> lifted the loot ban of Frodo",
+		},
+		{
+			name: "auction closed with winner",
+			e: event.Event{
+				Type:        event.AuctionClosed,
+				AggregateID: "auction-1",
+				Actor:       "officer",
+				Data:        json.RawMessage(`{"winner_id":"p1","amount":75}`),
+			},
+			want: "<@officer> closed auction `auction-1`, won by Gandalf for 75 DKP",
+		},
+		{
+			name: "auction bought out",
+			e: event.Event{
+				Type:        event.AuctionBoughtOut,
+				AggregateID: "auction-1",
+				Actor:       "d2",
+				Data:        json.RawMessage(`{"buyer_id":"p2","amount":40}`),
+			},
+			want: "Frodo bought out auction `auction-1` for 40 DKP",
+		},
+		{
+			name: "auction tax shared",
+			e: event.Event{
+				Type:        event.AuctionTaxed,
+				AggregateID: "auction-1",
+				Data:        json.RawMessage(`{"winner_id":"p2","rate":10,"amount":4,"recipients":["p1","p3"],"share":2}`),
+			},
+			want: "Frodo was taxed 4 DKP on auction `auction-1`, shared among 2 raiders",
+		},
+		{
+			name: "auction won by rolling",
+			e: event.Event{
+				Type:        event.AuctionClosed,
+				AggregateID: "auction-1",
+				Data:        json.RawMessage(`{"winner_id":"p2","amount":0,"roll":87}`),
+			},
+			want: "System closed auction `auction-1`, won by Frodo with a roll of 87 for 0 DKP",
+		},
+		{
+			name: "queued",
+			e: event.Event{
+				Type:        event.AuctionQueued,
+				AggregateID: "auction-2",
+				Actor:       "d2",
+				Data:        json.RawMessage(`{"item_name":"Sword","min_bid":10}`),
+			},
+			want: "<@d2> queued auction `auction-2` for Sword until an open auction ends",
+		},
+		{
+			name: "scheduled",
+			e: event.Event{
+				Type:        event.AuctionScheduled,
+				AggregateID: "auction-3",
+				Actor:       "d2",
+				Data:        json.RawMessage(`{"item_name":"Shield","min_bid":10,"starts_at":"2026-01-31T19:30:00Z"}`),
+			},
+			want: "<@d2> scheduled auction `auction-3` for Shield to open at 2026-01-31 19:30 UTC",
+		},
+		{
+			name: "resumed",
+			e: event.Event{
+				Type:        event.AuctionResumed,
+				AggregateID: "auction-1",
+				Actor:       "d2",
+				Data:        json.RawMessage(`{"paused":90000000000}`),
+			},
+			want: "<@d2> resumed auction `auction-1` after 1m30s",
+		},
+		{
+			name: "roll",
+			e: event.Event{
+				Type:        event.AuctionRolled,
+				AggregateID: "auction-1",
+				Data:        json.RawMessage(`{"player_id":"p2","roll":87}`),
+			},
+			want: "Frodo rolled 87 on auction `auction-1`",
+		},
+		{
+			name: "winner skipped",
+			e: event.Event{
+				Type:        event.AuctionWinnerSkipped,
+				AggregateID: "auction-1",
+				Data:        json.RawMessage(`{"player_id":"p2","amount":150,"dkp":100}`),
+			},
+			want: "Frodo was skipped as winner of auction `auction-1`: bid 150 DKP but has 100",
+		},
+		{
+			name: "GDKP raid ended",
+			e: event.Event{
+				Type:        event.GDKPRaidEnded,
+				AggregateID: "raid-1",
+				Actor:       "d2",
+				Data:        json.RawMessage(`{"pot":1000,"organizer_cut":100,"share":300,"participants":["d1","d2","d3"]}`),
+			},
+			want: "<@d2> ended GDKP raid `raid-1`: 1000 gold pot, 300 gold to each of 3 participants",
+		},
+		{
+			name: "banked item returned",
+			e: event.Event{
+				Type:        event.BankItemReturned,
+				AggregateID: "bank-1",
+				Data:        json.RawMessage(`{"auction_id":"auction-1"}`),
+			},
+			want: "banked item `bank-1` returned to the bank after auction `auction-1` ended without a winner",
+		},
+		{
+			name: "raffle drawn",
+			e: event.Event{
+				Type:        event.RaffleDrawn,
+				AggregateID: "raffle-1",
+				Actor:       "d1",
+				Data:        json.RawMessage(`{"ticket":4,"tickets":5,"winner_id":"p2"}`),
+			},
+			want: "<@d1> drew raffle `raffle-1`: ticket 4 of 5, won by Frodo",
+		},
+		{
+			name: "item cost set",
+			e: event.Event{
+				Type:        event.ItemCostSet,
+				AggregateID: event.ItemCostsAggregateID,
+				Actor:       "d1",
+				Data:        json.RawMessage(`{"item_name":"Ashkandi","cost":80}`),
+			},
+			want: "<@d1> set the cost of Ashkandi to 80 DKP",
+		},
+		{
+			name: "item awarded at its cost",
+			e: event.Event{
+				Type:        event.ItemCostAwarded,
+				AggregateID: event.ItemCostsAggregateID,
+				Actor:       "d1",
+				Data:        json.RawMessage(`{"item_name":"Ashkandi","cost":80,"player_id":"p2"}`),
+			},
+			want: "<@d1> awarded Ashkandi to Frodo for 80 DKP",
+		},
+		{
+			name: "auction disputed",
+			e: event.Event{
+				Type:        event.DisputeOpened,
+				AggregateID: "dispute-1",
+				Actor:       "d1",
+				Data:        json.RawMessage(`{"reason":"My bid was ignored","auction_id":"auction-1","opened_by":"d1","thread_id":"t1"}`),
+			},
+			want: "<@d1> disputed auction `auction-1` in `dispute-1`: My bid was ignored",
+		},
+		{
+			name: "anonymous dispute",
+			e: event.Event{
+				Type:        event.DisputeOpened,
+				AggregateID: "dispute-2",
+				Data:        json.RawMessage(`{"reason":"Bench rotation","anonymous":true,"thread_id":"t2"}`),
+			},
+			want: "An anonymous member opened dispute `dispute-2`: Bench rotation",
+		},
+		{
+			name: "dispute resolved",
+			e: event.Event{
+				Type:        event.DisputeResolved,
+				AggregateID: "dispute-1",
+				Actor:       "d2",
+				Data:        json.RawMessage(`{"resolution":"Bid refunded","resolved_by":"d2"}`),
+			},
+			want: "<@d2> resolved dispute `dispute-1`: Bid refunded",
+		},
+		{
+			name: "admin command",
+			e: event.Event{
+				Type:        event.AdminCommandRun,
+				AggregateID: event.AdminAggregateID,
+				Actor:       "d1",
+				Data:        json.RawMessage(`{"command":"close auction-1","user":"ops","host":"bastion"}`),
+			},
+			want: "<@d1> ran `close auction-1` from the command line as ops on bastion",
+		},
+		{
+			name: "bid by unknown player",
+			e: event.Event{
+				Type:        event.AuctionBidPlaced,
+				AggregateID: "auction-1",
+				Data:        json.RawMessage(`{"player_id":"p9","amount":10}`),
+			},
+			want: "p9 bid 10 DKP on auction `auction-1`",
+		},
+		{
+			name: "unknown type falls back",
+			e:    event.Event{Type: "custom.thing", AggregateID: "x"},
+			want: "System recorded custom.thing on x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := audit.Describe(tt.e, names); got != tt.want {
+				t.Errorf("Describe() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	entries := []audit.Entry{
+		{
+			Time:        time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC),
+			Type:        event.DKPAwarded,
+			AggregateID: "p1",
+			Actor:       "officer",
+			Summary:     "awarded, with comma",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := audit.WriteCSV(&buf, entries); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	want := `2025-06-15T12:00:00Z,dkp.awarded,p1,officer,"awarded, with comma"`
+	if lines[1] != want {
+		t.Errorf("record = %q, want %q", lines[1], want)
+	}
+}
@@ -0,0 +1,276 @@
+// Package bank tracks the items held by the guild bank, such as drops that
+// were not auctioned when they dropped. Officers deposit items and later
+// put them up for auction; an item whose auction is canceled or ends
+// without a winner returns to the bank. Each item is kept as events in the
+// event store, which record its custody.
+package bank
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// Errors returned by bank operations.
+var (
+	ErrUnknownItem = derrors.New(derrors.NotFound, "UNKNOWN_BANK_ITEM", "no such item in the guild bank")
+	ErrNotInBank   = derrors.New(derrors.Conflict, "BANK_ITEM_AUCTIONED", "the item is up for auction or was sold")
+	ErrNoItemName  = derrors.New(derrors.Validation, "NO_ITEM_NAME", "name the item to bank")
+)
+
+// Item is an item deposited in the bank, as recorded in its events.
+type Item struct {
+	ID   string
+	Name string
+	Note string
+	// DepositedBy is the Discord ID of the member who banked the item.
+	DepositedBy string
+	DepositedAt time.Time
+	// AuctionID is the auction the item was handed to, or empty while it
+	// is in the bank.
+	AuctionID string
+	Version   int
+}
+
+// InBank reports whether the item is in the bank.
+func (it *Item) InBank() bool {
+	return it.AuctionID == ""
+}
+
+// Service deposits items and hands them to auctions.
+type Service struct {
+	events event.Store
+	logger *slog.Logger
+	tracer trace.Tracer
+	clock  clock.Clock
+
+	// mu serializes changes, which version the item's events.
+	mu sync.Mutex
+}
+
+// NewService returns a Service that records the bank's items in events.
+func NewService(events event.Store, logger *slog.Logger, tp trace.TracerProvider, clk clock.Clock) *Service {
+	return &Service{
+		events: events,
+		logger: logger,
+		tracer: tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/bank"),
+		clock:  clk,
+	}
+}
+
+// Deposit banks an item named itemName on behalf of the member
+// depositedBy.
+func (s *Service) Deposit(ctx context.Context, itemName, depositedBy, note string) (*Item, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Deposit",
+		trace.WithAttributes(attribute.String("item", itemName)),
+	)
+	defer span.End()
+
+	itemName = strings.TrimSpace(itemName)
+	if itemName == "" {
+		return nil, ErrNoItemName
+	}
+	now := s.clock.Now()
+	it := &Item{
+		ID:          fmt.Sprintf("bank-%d", now.UnixNano()),
+		Name:        itemName,
+		Note:        strings.TrimSpace(note),
+		DepositedBy: depositedBy,
+		DepositedAt: now,
+	}
+	data, _ := json.Marshal(event.BankItemDepositedData{ItemName: it.Name, DepositedBy: depositedBy, Note: it.Note})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.append(ctx, it, event.BankItemDeposited, data); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "item banked",
+		slog.String("bank_item_id", it.ID),
+		slog.String("item", it.Name),
+	)
+	return it, nil
+}
+
+// Get returns the item id.
+func (s *Service) Get(ctx context.Context, id string) (*Item, error) {
+	events, err := s.events.Load(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("loading bank item events: %w", err)
+	}
+	if len(events) == 0 || events[0].Type != event.BankItemDeposited {
+		return nil, ErrUnknownItem.Wrap(fmt.Errorf("item %s", id))
+	}
+	return replay(events)
+}
+
+// List returns the items in the bank, longest banked first.
+func (s *Service) List(ctx context.Context) ([]*Item, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.List")
+	defer span.End()
+
+	deposits, err := s.events.LoadByType(ctx, event.BankItemDeposited)
+	if err != nil {
+		return nil, fmt.Errorf("loading bank deposits: %w", err)
+	}
+	var items []*Item
+	for _, e := range deposits {
+		it, err := s.Get(ctx, e.AggregateID)
+		if err != nil {
+			return nil, err
+		}
+		if it.InBank() {
+			items = append(items, it)
+		}
+	}
+	slices.SortFunc(items, func(a, b *Item) int { return a.DepositedAt.Compare(b.DepositedAt) })
+	return items, nil
+}
+
+// Auction hands the item id to the auction start starts for its name, and
+// returns the item. The item must be in the bank.
+func (s *Service) Auction(ctx context.Context, id string, start func(ctx context.Context, itemName string) (auctionID string, err error)) (*Item, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Auction",
+		trace.WithAttributes(attribute.String("bank_item.id", id)),
+	)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	it, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !it.InBank() {
+		return nil, ErrNotInBank
+	}
+	auctionID, err := start(ctx, it.Name)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := json.Marshal(event.BankItemAuctionData{AuctionID: auctionID})
+	if err := s.append(ctx, it, event.BankItemAuctioned, data); err != nil {
+		return nil, err
+	}
+	it.AuctionID = auctionID
+	s.logger.InfoContext(ctx, "banked item auctioned",
+		slog.String("bank_item_id", it.ID),
+		slog.String("auction_id", auctionID),
+	)
+	return it, nil
+}
+
+// Return returns the item handed to auctionID to the bank, and reports
+// whether there was one.
+func (s *Service) Return(ctx context.Context, auctionID string) (bool, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Return",
+		trace.WithAttributes(attribute.String("auction.id", auctionID)),
+	)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	handed, err := s.events.LoadByType(ctx, event.BankItemAuctioned)
+	if err != nil {
+		return false, fmt.Errorf("loading bank auctions: %w", err)
+	}
+	for _, e := range handed {
+		var d event.BankItemAuctionData
+		if err := json.Unmarshal(e.Data, &d); err != nil || d.AuctionID != auctionID {
+			continue
+		}
+		it, err := s.Get(ctx, e.AggregateID)
+		if err != nil {
+			return false, err
+		}
+		if it.AuctionID != auctionID {
+			continue
+		}
+		if err := s.append(ctx, it, event.BankItemReturned, e.Data); err != nil {
+			return false, err
+		}
+		s.logger.InfoContext(ctx, "banked item returned from auction",
+			slog.String("bank_item_id", it.ID),
+			slog.String("auction_id", auctionID),
+		)
+		return true, nil
+	}
+	return false, nil
+}
+
+// Run returns banked items to the bank when the auctions published on bus
+// are canceled or end without a winner, until ctx is done. Only the leader
+// should run it. The results are queued by the bus as its "bank"
+// subscriber.
+func (s *Service) Run(ctx context.Context, bus *event.Bus) {
+	unsubscribe := bus.SubscribeQueue(ctx, "bank", func(ctx context.Context, e event.Event) {
+		if e.Type == event.AuctionClosed {
+			var d event.AuctionClosedData
+			if err := json.Unmarshal(e.Data, &d); err != nil || d.WinnerID != "" {
+				return
+			}
+		}
+		if _, err := s.Return(ctx, e.AggregateID); err != nil {
+			s.logger.ErrorContext(ctx, "returning banked item failed",
+				slog.String("auction_id", e.AggregateID),
+				slog.Any("error", err),
+			)
+		}
+	}, event.AuctionClosed, event.AuctionCanceled)
+	defer unsubscribe()
+	<-ctx.Done()
+}
+
+// append records an event of type t on it.
+func (s *Service) append(ctx context.Context, it *Item, t event.Type, data json.RawMessage) error {
+	e := event.Event{
+		AggregateID: it.ID,
+		Type:        t,
+		Data:        data,
+		Version:     it.Version + 1,
+	}
+	if err := s.events.Append(ctx, e); err != nil {
+		return fmt.Errorf("recording %s event: %w", t, err)
+	}
+	it.Version = e.Version
+	return nil
+}
+
+// replay rebuilds an item from its events, oldest first.
+func replay(events []event.Event) (*Item, error) {
+	it := &Item{ID: events[0].AggregateID}
+	for _, e := range events {
+		switch e.Type {
+		case event.BankItemDeposited:
+			var d event.BankItemDepositedData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+			}
+			it.Name, it.Note, it.DepositedBy, it.DepositedAt = d.ItemName, d.Note, d.DepositedBy, e.CreatedAt
+		case event.BankItemAuctioned:
+			var d event.BankItemAuctionData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+			}
+			it.AuctionID = d.AuctionID
+		case event.BankItemReturned:
+			it.AuctionID = ""
+		}
+		it.Version = e.Version
+	}
+	return it, nil
+}
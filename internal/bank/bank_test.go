@@ -0,0 +1,131 @@
+package bank_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/bank"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+)
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	es := eventtest.NewStore(eventtest.WithClock(clock.Real{}))
+	s := bank.NewService(es, slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clock.Real{})
+
+	if _, err := s.Deposit(ctx, " ", "officer", ""); !errors.Is(err, bank.ErrNoItemName) {
+		t.Errorf("Deposit() with no name error = %v, want ErrNoItemName", err)
+	}
+	sword, err := s.Deposit(ctx, "Sword of Truth", "officer", "from Nagafen")
+	if err != nil {
+		t.Fatalf("Deposit() error = %v", err)
+	}
+	time.Sleep(time.Millisecond) // IDs are stamped with the time
+	shield, err := s.Deposit(ctx, "Shield", "officer", "")
+	if err != nil {
+		t.Fatalf("Deposit() error = %v", err)
+	}
+
+	starts := 0
+	start := func(_ context.Context, itemName string) (string, error) {
+		starts++
+		if itemName != "Sword of Truth" {
+			t.Errorf("auction started for %q, want the sword", itemName)
+		}
+		return "auction-1", nil
+	}
+	it, err := s.Auction(ctx, sword.ID, start)
+	if err != nil || it.AuctionID != "auction-1" {
+		t.Fatalf("Auction() = %+v, %v, want it handed to auction-1", it, err)
+	}
+	if _, err := s.Auction(ctx, sword.ID, start); !errors.Is(err, bank.ErrNotInBank) {
+		t.Errorf("second Auction() error = %v, want ErrNotInBank", err)
+	}
+	if _, err := s.Auction(ctx, "bank-0", start); !errors.Is(err, bank.ErrUnknownItem) {
+		t.Errorf("Auction() of an unknown item error = %v, want ErrUnknownItem", err)
+	}
+	if starts != 1 {
+		t.Errorf("auctions started = %d, want 1", starts)
+	}
+
+	items, err := s.List(ctx)
+	if err != nil || len(items) != 1 || items[0].ID != shield.ID {
+		t.Fatalf("List() = %+v, %v, want only the shield", items, err)
+	}
+
+	if ok, err := s.Return(ctx, "auction-2"); ok || err != nil {
+		t.Errorf("Return() of another auction = %v, %v, want false", ok, err)
+	}
+	if ok, err := s.Return(ctx, "auction-1"); !ok || err != nil {
+		t.Fatalf("Return() = %v, %v, want true", ok, err)
+	}
+	items, err = s.List(ctx)
+	if err != nil || len(items) != 2 || items[0].ID != sword.ID || items[0].Note != "from Nagafen" {
+		t.Fatalf("List() = %+v, %v, want the sword and then the shield", items, err)
+	}
+	if ok, _ := s.Return(ctx, "auction-1"); ok {
+		t.Error("second Return() = true, want false")
+	}
+	es.RequireTypes(t, event.BankItemDeposited, event.BankItemDeposited, event.BankItemAuctioned, event.BankItemReturned)
+}
+
+func TestService_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	es := eventtest.NewStore(eventtest.WithClock(clock.Real{}))
+	s := bank.NewService(es, slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clock.Real{})
+	bus := event.NewBus()
+
+	var ids []string
+	for _, auctionID := range []string{"won", "unsold", "canceled"} {
+		it, err := s.Deposit(ctx, "Sword", "officer", "")
+		if err != nil {
+			t.Fatalf("Deposit() error = %v", err)
+		}
+		if _, err := s.Auction(ctx, it.ID, func(context.Context, string) (string, error) { return auctionID, nil }); err != nil {
+			t.Fatalf("Auction() error = %v", err)
+		}
+		ids = append(ids, it.ID)
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, bus)
+		close(done)
+	}()
+	closed := func(id, winner string) event.Event {
+		data, _ := json.Marshal(event.AuctionClosedData{WinnerID: winner, Amount: 10})
+		return event.Event{AggregateID: id, Type: event.AuctionClosed, Data: data}
+	}
+	// Run subscribes once started; publish until the results are handled.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		bus.Publish(ctx, closed("won", "player-1"), closed("unsold", ""),
+			event.Event{AggregateID: "canceled", Type: event.AuctionCanceled})
+		items, err := s.List(ctx)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if len(items) == 2 {
+			if items[0].ID != ids[1] || items[1].ID != ids[2] {
+				t.Errorf("List() = %+v, want the unsold and canceled items", items)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("List() = %+v, want two items returned", items)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
@@ -4,16 +4,30 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/audit"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/bot/commands"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/eqdkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/export"
 )
 
+// scheduleInterval is how often scheduled auctions are checked for those
+// due to open.
+const scheduleInterval = 15 * time.Second
+
+// countdownInterval is how often the countdowns of open auctions are
+// checked for milestones to show on their announcements.
+const countdownInterval = time.Second
+
 // Bot wraps the Discord session and command handlers.
 type Bot struct {
 	session  *discordgo.Session
@@ -21,16 +35,32 @@ type Bot struct {
 	logger   *slog.Logger
 	handlers *commands.Handlers
 	cmds     []*discordgo.ApplicationCommand
+	gateway  *Supervisor
+	// keepCommands leaves the slash commands registered on Stop, for
+	// bots whose commands another replica serves or is about to serve.
+	keepCommands bool
 }
 
 // New creates a new Bot instance.
-func New(cfg config.DiscordConfig, dkpMgr *dkp.Manager, auctionMgr *auction.Manager, logger *slog.Logger, tp trace.TracerProvider) (*Bot, error) {
+func New(cfg config.DiscordConfig, dkpMgr *dkp.Manager, auctionMgr *auction.Manager, auditLog *audit.Log, exporter *export.Exporter, importer *eqdkp.Importer, logger *slog.Logger, tp trace.TracerProvider, opts ...commands.Option) (*Bot, error) {
 	session, err := discordgo.New("Bot " + cfg.Token)
 	if err != nil {
 		return nil, fmt.Errorf("creating discord session: %w", err)
 	}
+	// Trace REST calls so that each interaction span shows the time spent
+	// waiting on Discord, including rate limiting.
+	session.Client = &http.Client{
+		Timeout:   session.Client.Timeout,
+		Transport: NewTracingTransport(session.Client.Transport, tp),
+	}
 
-	handlers := commands.NewHandlers(dkpMgr, auctionMgr, logger, tp)
+	// The options from cfg come first, so that opts may override them.
+	cfgOpts := []commands.Option{commands.WithTimeouts(cfg.Timeouts.Default, cfg.Timeouts.Commands)}
+	if cfg.CommandPrefix != "" {
+		session.Identify.Intents |= discordgo.IntentsMessageContent
+		cfgOpts = append(cfgOpts, commands.WithPrefix(cfg.CommandPrefix))
+	}
+	handlers := commands.NewHandlers(dkpMgr, auctionMgr, auditLog, exporter, importer, logger, tp, append(cfgOpts, opts...)...)
 
 	return &Bot{
 		session:  session,
@@ -40,19 +70,105 @@ func New(cfg config.DiscordConfig, dkpMgr *dkp.Manager, auctionMgr *auction.Mana
 	}, nil
 }
 
+// Supervise hands reconnection of the gateway to s, which runs until ctx is
+// canceled. It must be called before Start.
+func (b *Bot) Supervise(ctx context.Context, s *Supervisor) {
+	b.session.ShouldReconnectOnError = false
+	b.session.AddHandler(func(*discordgo.Session, *discordgo.Connect) { s.Connected(ctx) })
+	b.session.AddHandler(func(*discordgo.Session, *discordgo.Disconnect) { s.Disconnected(ctx) })
+	b.gateway = s
+	s.attach(b.session)
+	go s.Run(ctx, rotatingGateway{b})
+}
+
+// rotatingGateway reconnects with the current token, so that a token
+// rotated by a secrets provider takes effect on the next reconnect.
+type rotatingGateway struct {
+	b *Bot
+}
+
+func (g rotatingGateway) Open() error {
+	g.b.refreshToken()
+	return g.b.session.Open()
+}
+
+func (g rotatingGateway) HeartbeatLatency() time.Duration {
+	return g.b.session.HeartbeatLatency()
+}
+
+// refreshToken points the session at the current token, if cfg.TokenFunc
+// is set.
+func (b *Bot) refreshToken() {
+	if b.cfg.TokenFunc == nil {
+		return
+	}
+	b.session.Lock()
+	b.session.Token = "Bot " + b.cfg.TokenFunc()
+	b.session.Unlock()
+}
+
 // Start opens the Discord connection and registers slash commands.
 func (b *Bot) Start(ctx context.Context) error {
+	if err := b.open(ctx); err != nil {
+		return err
+	}
+	return b.registerCommands(ctx)
+}
+
+// StartStandby opens the Discord connection without registering slash
+// commands, for a warm standby whose handlers were created with
+// commands.WithStandby. Promote it once it becomes the leader.
+func (b *Bot) StartStandby(ctx context.Context) error {
+	b.keepCommands = true
+	return b.open(ctx)
+}
+
+// Promote makes a warm standby serve every command.
+func (b *Bot) Promote(ctx context.Context) error {
+	if err := b.registerCommands(ctx); err != nil {
+		return err
+	}
+	b.handlers.Promote()
+	return nil
+}
+
+// RunScheduler opens scheduled auctions as their start time comes, and
+// shows the time left of open auctions on their announcements, until ctx
+// is done. Only the leader should run it, once Start or Promote has
+// returned. clk paces the checks.
+func (b *Bot) RunScheduler(ctx context.Context, clk clock.Clock) {
+	ticker := clock.NewTicker(clk, scheduleInterval)
+	defer ticker.Stop()
+	countdown := clock.NewTicker(clk, countdownInterval)
+	defer countdown.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			b.handlers.OpenScheduled(ctx, b.session, b.cfg.GuildID)
+		case <-countdown.C():
+			b.handlers.UpdateCountdowns(ctx, b.session)
+		}
+	}
+}
+
+func (b *Bot) open(ctx context.Context) error {
 	b.session.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
 		b.logger.InfoContext(ctx, "bot is ready", slog.String("user", s.State.User.Username))
 	})
 
 	b.session.AddHandler(b.handlers.InteractionCreate)
+	b.session.AddHandler(b.handlers.MessageCreate)
 
+	b.refreshToken()
 	if err := b.session.Open(); err != nil {
 		return fmt.Errorf("opening discord session: %w", err)
 	}
+	return nil
+}
 
-	// Register slash commands.
+func (b *Bot) registerCommands(ctx context.Context) error {
 	appCmds := commands.SlashCommands()
 	registered, err := b.session.ApplicationCommandBulkOverwrite(b.session.State.User.ID, b.cfg.GuildID, appCmds)
 	if err != nil {
@@ -64,10 +180,26 @@ func (b *Bot) Start(ctx context.Context) error {
 	return nil
 }
 
+// Drain answers new interactions with a request to retry and waits for
+// those in flight, so that leadership can be handed over without losing
+// commands. The slash commands stay registered for the next leader.
+func (b *Bot) Drain(ctx context.Context) error {
+	b.keepCommands = true
+	return b.handlers.Drain(ctx)
+}
+
 // Stop gracefully closes the Discord connection.
 func (b *Bot) Stop() error {
-	// Remove slash commands on shutdown (optional for dev).
-	for _, cmd := range b.cmds {
+	if b.gateway != nil {
+		b.gateway.Stop()
+	}
+	// Remove slash commands on shutdown (optional for dev), unless other
+	// replicas still serve them.
+	cmds := b.cmds
+	if b.keepCommands {
+		cmds = nil
+	}
+	for _, cmd := range cmds {
 		if err := b.session.ApplicationCommandDelete(b.session.State.User.ID, b.cfg.GuildID, cmd.ID); err != nil {
 			b.logger.Error("failed to delete command", slog.String("command", cmd.Name), slog.Any("error", err))
 		}
@@ -0,0 +1,48 @@
+package bot_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/bot"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+func TestBot_RunScheduler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	now := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+	clk := clock.NewFakeClock(now)
+	es := eventtest.NewStore(eventtest.WithClock(clk))
+	mgr := auction.NewManager(es, storetest.NewPlayers(), slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clk)
+	a, err := mgr.StartAuction(ctx, "Helm", "admin", 10, 0, 0, time.Hour, auction.StartingAt(now.Add(time.Minute)))
+	if err != nil {
+		t.Fatalf("StartAuction() error = %v", err)
+	}
+	b, err := bot.New(config.DiscordConfig{Token: "token"}, nil, mgr, nil, nil, nil, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	go b.RunScheduler(ctx, clk)
+
+	// The schedule and countdown tickers.
+	clk.BlockUntil(2)
+	clk.Advance(45 * time.Second)
+	if states, _ := mgr.ListOpenAuctions(ctx); len(states) != 1 || states[0].Status != "scheduled" {
+		t.Fatalf("auctions = %+v, want %s still scheduled", states, a.ID)
+	}
+	// The auction opens on the first check after its start.
+	clk.Advance(15 * time.Second)
+	if started := es.Await(t, event.AuctionStarted); started.AggregateID != a.ID || !started.CreatedAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("started %s at %v, want %s at %v", started.AggregateID, started.CreatedAt, a.ID, now.Add(time.Minute))
+	}
+}
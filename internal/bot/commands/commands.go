@@ -1,330 +1,6299 @@
 package commands
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/jensholdgaard/discord-dkp-bot/internal/announce"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/audit"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/bank"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/calendar"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/chart"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/deadletter"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dispute"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/economy"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/eqdkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/export"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/gdkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/itemcost"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/items"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/jobs"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/merge"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/notes"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/onboard"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/raffle"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/rolesync"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/roster"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/settings"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/standings"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/textfilter"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/usage"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/wcl"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/wishlist"
 )
 
-// Handlers process Discord interactions.
+// adminPermissions restricts officer commands to members with the
+// Administrator permission unless overridden in the guild settings.
+var adminPermissions int64 = discordgo.PermissionAdministrator
+
+// maxMessageLength is Discord's limit on message content length.
+const maxMessageLength = 2000
+
+// maxEmbedDescription is Discord's limit on embed description length.
+const maxEmbedDescription = 4096
+
+// maxChoices and maxChoiceLength are Discord's limits on autocomplete
+// choices.
+const (
+	maxChoices      = 25
+	maxChoiceLength = 100
+)
+
+// buyoutAction is the action of the Buy now button of auctions with a
+// buyout price. The button's custom ID is the action and the auction ID,
+// separated by a colon.
+const buyoutAction = "auction-buyout"
+
+// bidAction is the action of the quick bid buttons of auctions, which
+// raise the highest bid. The button's custom ID is the action, the auction
+// ID, and the raise, separated by colons.
+const bidAction = "auction-bid"
+
+// customBidAction is the action of the Custom button of auctions, which
+// opens a modal asking for the amount, and customBidModal that of the
+// modal. Their custom IDs are formed like that of buyoutAction.
+const (
+	customBidAction = "auction-bid-custom"
+	customBidModal  = "auction-bid-amount"
+)
+
+// confirmBidAction is the action of the button that places a bid the
+// bidder was asked to confirm. Its custom ID is the action, the auction
+// ID, and the amount, separated by colons.
+const confirmBidAction = "auction-bid-confirm"
+
+// quickRaises are the raises offered as quick bid buttons besides the
+// minimum increment.
+var quickRaises = []int{5, 10}
+
+// rollAction is the action of the Roll button of auctions closed without
+// bids, whose custom ID is formed like that of buyoutAction.
+const rollAction = "auction-roll"
+
+// signupAction is the action of the Accept, Tentative, and Decline buttons
+// of scheduled raids, whose custom ID is the action, the raid ID, and the
+// signup status, separated by colons.
+const signupAction = "raid-signup"
+
+// errRejected marks a command that was refused before doing any work, for
+// example because of invalid options. The user has already been told why.
+var errRejected = derrors.New(derrors.Validation, "REJECTED", "command rejected")
+
+// errPanic marks a command whose handler panicked.
+var errPanic = derrors.New(derrors.Internal, "PANIC", "command handler panicked")
+
+// errTimedOut answers commands whose deadline passed before they finished.
+var errTimedOut = derrors.New(derrors.Internal, "TIMEOUT", "the command timed out, please try again")
+
+// errDraining answers interactions that arrive while the leader hands over.
+var errDraining = derrors.New(derrors.Conflict, "HANDOVER", "the bot is handing over to another replica, please try again in a few seconds")
+
+// errNotOfficer answers officer commands used by other members.
+var errNotOfficer = derrors.New(derrors.Permission, "NOT_OFFICER", "only officers may use this command")
+
+// errAuctionsOff and errFixedPricesOff answer loot commands of a way of
+// handing out loot that the guild's loot_mode setting does not allow.
+var (
+	errAuctionsOff    = derrors.New(derrors.Validation, "AUCTIONS_OFF", "this server hands out loot at fixed prices with /loot-award; set loot_mode to auction or both to auction it")
+	errFixedPricesOff = derrors.New(derrors.Validation, "FIXED_PRICES_OFF", "this server auctions loot; set loot_mode to fixed_price or both to award it at fixed prices")
+)
+
+// command declares a slash command or a button action: its definition,
+// who may use it, and its handler.
+type command struct {
+	// ApplicationCommand is the definition registered with Discord. Button
+	// actions only have a Name, the action of their custom IDs.
+	discordgo.ApplicationCommand
+	button bool
+	// officer commands may only be used by members with the Administrator
+	// permission or one of the guild's admin roles. Discord hides them from
+	// other members unless the server's integration settings grant them
+	// access.
+	officer bool
+	// readOnly commands are served by every replica of a warm-standby
+	// deployment, not only the leader. They must not change state.
+	readOnly bool
+	handle   func(*Handlers, context.Context, *discordgo.Session, *discordgo.InteractionCreate) error
+	// text runs the command sent as a prefix command, such as "!bid 50",
+	// with the words that follow its name, and returns the reply. Commands
+	// without it are only available as slash commands.
+	text func(*Handlers, context.Context, *discordgo.MessageCreate, []string) (string, error)
+	// freeText returns the free text among the words of the prefix
+	// command, keyed by the name of the slash command option it stands
+	// for, for the text filter. Commands without it take no free text.
+	freeText func([]string) map[string]string
+}
+
+// registry indexes commandList by name.
+var registry = func() map[string]command {
+	byName := make(map[string]command)
+	for _, c := range commandList() {
+		if _, ok := byName[c.Name]; ok {
+			panic("commands: " + c.Name + " is declared twice")
+		}
+		byName[c.Name] = c
+	}
+	return byName
+}()
+
+// auditTypeGroups maps the /audit "type" choices to event types.
+var auditTypeGroups = map[string][]event.Type{
+	"dkp":      {event.DKPAwarded, event.DKPDeducted, event.DKPAdjusted},
+	"auction":  {event.AuctionScheduled, event.AuctionQueued, event.AuctionStarted, event.AuctionBidPlaced, event.AuctionClosed, event.AuctionCanceled, event.AuctionBoughtOut, event.AuctionRollStarted, event.AuctionRolled, event.AuctionWinnerSkipped, event.AuctionPaused, event.AuctionResumed, event.AuctionTaxed},
+	"player":   {event.PlayerRegistered, event.PlayerProfileUpdated, event.PlayerArchived, event.PlayerRestored, event.PlayerLootBanned, event.PlayerLootBanLifted},
+	"gdkp":     {event.GDKPRaidStarted, event.GDKPRaidJoined, event.GDKPRaidEnded},
+	"calendar": {event.RaidScheduled, event.RaidSignedUp, event.RaidReminded, event.RaidBonusAwarded, event.RaidComposed},
+	"bank":     {event.BankItemDeposited, event.BankItemAuctioned, event.BankItemReturned},
+	"raffle":   {event.RaffleStarted, event.RaffleEntered, event.RaffleDrawn},
+	"itemcost": {event.ItemCostSet, event.ItemCostRemoved, event.ItemCostAwarded},
+	"dispute":  {event.DisputeOpened, event.DisputeResolved},
+	"currency": {event.CurrencyAwarded, event.CurrencyDeducted, event.CurrencyTransferred},
+	"admin":    {event.AdminCommandRun},
+}
+
+// Handlers process Discord interactions and prefix commands.
 type Handlers struct {
 	dkpMgr     *dkp.Manager
 	auctionMgr *auction.Manager
-	logger     *slog.Logger
-	tracer     trace.Tracer
+	auditLog   *audit.Log
+	exporter   *export.Exporter
+	importer   *eqdkp.Importer
+	attendance *wcl.Attendance
+	deadLetter *deadletter.Store
+	settings   *settings.Service
+	items      *items.Catalog
+	resolver   items.ItemResolver
+	wishlist   *wishlist.Service
+	notes      *notes.Service
+	raids      *gdkp.Service
+	calendar   *calendar.Service
+	roster     *roster.Reviewer
+	merger     *merge.Merger
+	onboarder  *onboard.Importer
+	roles      *rolesync.Syncer
+	bank       *bank.Service
+	raffles    *raffle.Service
+	itemCosts  *itemcost.Service
+	economy    *economy.Projection
+	disputes   *dispute.Service
+	textFilter *textfilter.Filter
+	projection *standings.Projection
+	usage      *usage.Tracker
+	jobs       *jobs.Runner
+	// jobsDM reports jobs in direct messages rather than in the channel.
+	jobsDM  bool
+	metrics *metrics.Recorder
+	logger  *slog.Logger
+	tracer  trace.Tracer
+	clock   clock.Clock
+	// prefix starts prefix commands, if they are enabled.
+	prefix string
+	// timeout is the deadline of commands not in timeouts.
+	timeout  time.Duration
+	timeouts map[string]time.Duration
+
+	// claims is set on warm-standby deployments, where every replica
+	// receives every interaction.
+	claims *idempotency.Guard
+	// leader reports whether state-changing commands are served.
+	leader atomic.Bool
+
+	// mu guards draining, which is set once Drain is called, after which
+	// no interactions are added to inflight.
+	mu       sync.Mutex
+	draining bool
+	inflight sync.WaitGroup
+
+	// countdownMu guards countdowns, the countdown milestone last shown on
+	// the announcements of each open auction.
+	countdownMu sync.Mutex
+	countdowns  map[string]time.Duration
+}
+
+// Option configures optional Handlers collaborators.
+type Option func(*Handlers)
+
+// WithAttendance enables /wcl-import.
+func WithAttendance(a *wcl.Attendance) Option {
+	return func(h *Handlers) { h.attendance = a }
+}
+
+// WithDeadLetters enables /deadletter.
+func WithDeadLetters(d *deadletter.Store) Option {
+	return func(h *Handlers) { h.deadLetter = d }
+}
+
+// WithSettings enables /settings and applies the guild's admin roles and
+// loot channel. Without it only administrators may use officer commands.
+func WithSettings(svc *settings.Service) Option {
+	return func(h *Handlers) { h.settings = svc }
+}
+
+// WithItems completes /auction-start item names from c and shows item
+// icons and quality colors in auction announcements.
+func WithItems(c *items.Catalog) Option {
+	return func(h *Handlers) { h.items = c }
+}
+
+// WithItemResolver links the items of auction announcements to a game
+// database and shows their tooltips, as resolved by r. It needs WithItems.
+func WithItemResolver(r items.ItemResolver) Option {
+	return func(h *Handlers) { h.resolver = r }
+}
+
+// WithNotes enables /player-note and the officers' view of /profile.
+func WithNotes(svc *notes.Service) Option {
+	return func(h *Handlers) { h.notes = svc }
+}
+
+// WithWishlist enables /wishlist and /wishlist-report.
+func WithWishlist(svc *wishlist.Service) Option {
+	return func(h *Handlers) { h.wishlist = svc }
+}
+
+// WithGDKP enables /raid-start, /raid-join, /raid-pot, /raid-loot, and
+// /raid-end.
+func WithGDKP(svc *gdkp.Service) Option {
+	return func(h *Handlers) { h.raids = svc }
+}
+
+// WithCalendar enables /raid-schedule, /raid-calendar, signing up for
+// scheduled raids, and the on-time bonus of /raid-end.
+func WithCalendar(svc *calendar.Service) Option {
+	return func(h *Handlers) { h.calendar = svc }
+}
+
+// WithRoster enables /roster-inactive.
+func WithRoster(r *roster.Reviewer) Option {
+	return func(h *Handlers) { h.roster = r }
+}
+
+// WithMerger enables /guild-merge.
+func WithMerger(m *merge.Merger) Option {
+	return func(h *Handlers) { h.merger = m }
+}
+
+// WithPlayerImport enables /import players.
+func WithPlayerImport(im *onboard.Importer) Option {
+	return func(h *Handlers) { h.onboarder = im }
+}
+
+// WithRoleSync enables /role-sync.
+func WithRoleSync(s *rolesync.Syncer) Option {
+	return func(h *Handlers) { h.roles = s }
+}
+
+// WithBank enables /bank.
+func WithBank(b *bank.Service) Option {
+	return func(h *Handlers) { h.bank = b }
+}
+
+// WithRaffles enables /raffle.
+func WithRaffles(svc *raffle.Service) Option {
+	return func(h *Handlers) { h.raffles = svc }
+}
+
+// WithEconomy enables /dkp-economy.
+func WithEconomy(p *economy.Projection) Option {
+	return func(h *Handlers) { h.economy = p }
+}
+
+// WithItemCosts enables /itemcost and /loot-award.
+func WithItemCosts(svc *itemcost.Service) Option {
+	return func(h *Handlers) { h.itemCosts = svc }
+}
+
+// WithDisputes enables /dispute.
+func WithDisputes(svc *dispute.Service) Option {
+	return func(h *Handlers) { h.disputes = svc }
+}
+
+// WithTextFilter refuses commands whose free text f refuses.
+func WithTextFilter(f *textfilter.Filter) Option {
+	return func(h *Handlers) { h.textFilter = f }
+}
+
+// WithStandings serves /dkp-list from p instead of listing the players
+// from the database each time, and lets officers rebuild p with its
+// refresh option.
+func WithStandings(p *standings.Projection) Option {
+	return func(h *Handlers) { h.projection = p }
+}
+
+// WithUsage records the use of every command on t and enables /bot-stats.
+func WithUsage(t *usage.Tracker) Option {
+	return func(h *Handlers) { h.usage = t }
+}
+
+// WithJobs runs imports, exports, and other commands that can outlast an
+// interaction as jobs of r. They are answered at once, and report their
+// progress and outcome in a message of their own, in the channel the
+// command was used in or, if dm, in a direct message to the member who used
+// it. Without it they run while the interaction waits, and fail if they
+// outlast it.
+func WithJobs(r *jobs.Runner, dm bool) Option {
+	return func(h *Handlers) { h.jobs, h.jobsDM = r, dm }
+}
+
+// WithMetrics records command counts and latency on r.
+func WithMetrics(r *metrics.Recorder) Option {
+	return func(h *Handlers) { h.metrics = r }
+}
+
+// WithClock times roll windows and audit periods on clk rather than the
+// system clock.
+func WithClock(clk clock.Clock) Option {
+	return func(h *Handlers) { h.clock = clk }
+}
+
+// interactionTTL is how long Discord accepts responses to an interaction,
+// and so the longest a command may run.
+const interactionTTL = 15 * time.Minute
+
+// WithTimeouts gives each command a deadline: the named commands in
+// commands, the others def. A command still running at its deadline has
+// its calls canceled and the member is told it timed out. Without it
+// commands run until the interaction expires.
+func WithTimeouts(def time.Duration, commands map[string]time.Duration) Option {
+	return func(h *Handlers) {
+		h.timeout = def
+		h.timeouts = commands
+	}
+}
+
+// WithStandby prepares the handlers for a warm-standby deployment, in which
+// every replica keeps a Discord session open. Until Promote is called only
+// read-only commands are served. Each read-only interaction is claimed
+// through g, so that exactly one replica answers it.
+func WithStandby(g *idempotency.Guard) Option {
+	return func(h *Handlers) {
+		h.claims = g
+		h.leader.Store(false)
+	}
+}
+
+// NewHandlers creates new command handlers.
+func NewHandlers(dkpMgr *dkp.Manager, auctionMgr *auction.Manager, auditLog *audit.Log, exporter *export.Exporter, importer *eqdkp.Importer, logger *slog.Logger, tp trace.TracerProvider, opts ...Option) *Handlers {
+	h := &Handlers{
+		dkpMgr:     dkpMgr,
+		auctionMgr: auctionMgr,
+		auditLog:   auditLog,
+		exporter:   exporter,
+		importer:   importer,
+		metrics:    metrics.Nop(),
+		clock:      clock.Real{},
+		timeout:    interactionTTL,
+		countdowns: make(map[string]time.Duration),
+		logger:     logger,
+		tracer:     tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/bot/commands"),
+	}
+	h.leader.Store(true)
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Promote makes the handlers serve every command, once this replica has
+// become the leader.
+func (h *Handlers) Promote() {
+	h.leader.Store(true)
+}
+
+// Drain stops the handlers from taking on new interactions, which are
+// answered with a request to retry, and waits until those in flight have
+// been handled or ctx is done.
+func (h *Handlers) Drain(ctx context.Context) error {
+	h.mu.Lock()
+	h.draining = true
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// track adds an interaction to inflight, unless the handlers are draining.
+func (h *Handlers) track() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.draining {
+		return false
+	}
+	h.inflight.Add(1)
+	return true
+}
+
+// serves reports whether this replica should handle the named command,
+// sent as the interaction or message id.
+func (h *Handlers) serves(id, name string) bool {
+	if h.claims == nil {
+		return true
+	}
+	if !registry[name].readOnly {
+		return h.leader.Load()
+	}
+	ctx, cancel := context.WithTimeout(idempotency.WithKey(context.Background(), id), h.deadline(name))
+	defer cancel()
+	claimed, err := h.claims.Claim(ctx, "command.read")
+	if err != nil {
+		// Without the store no replica can claim it; let the leader try.
+		h.logger.WarnContext(ctx, "claiming interaction failed", slog.String("command", name), slog.Any("error", err))
+		return h.leader.Load()
+	}
+	return claimed
+}
+
+// deadline returns how long the named command may run.
+func (h *Handlers) deadline(name string) time.Duration {
+	d, ok := h.timeouts[name]
+	if !ok {
+		d = h.timeout
+	}
+	return min(d, interactionTTL)
+}
+
+// timedOut returns err marked with errTimedOut if the handler of the named
+// command failed because the deadline of ctx passed, and records the
+// timeout. Other errors are returned as is.
+func (h *Handlers) timedOut(ctx context.Context, name string, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	h.metrics.CommandTimedOut(ctx, name)
+	return errTimedOut.Wrap(err)
+}
+
+// authorize reports whether member may use officer commands in the guild.
+func (h *Handlers) authorize(ctx context.Context, guildID string, member *discordgo.Member) error {
+	if member.Permissions&discordgo.PermissionAdministrator != 0 {
+		return nil
+	}
+	if h.settings != nil {
+		gs, err := h.settings.Get(ctx, guildID)
+		if err != nil {
+			return err
+		}
+		for _, role := range member.Roles {
+			if gs.IsAdminRole(role) {
+				return nil
+			}
+		}
+	}
+	return errNotOfficer
+}
+
+// announce posts msg to the guild's loot channel, unless none is set or
+// the interaction was sent there and its response already shows msg. It
+// returns the posted message, or nil if none was posted.
+func (h *Handlers) announce(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, msg *discordgo.MessageSend) *discordgo.Message {
+	if h.settings == nil {
+		return nil
+	}
+	gs, err := h.settings.Get(ctx, i.GuildID)
+	if err != nil {
+		h.logger.WarnContext(ctx, "loading guild settings for announcement failed", slog.Any("error", err))
+		return nil
+	}
+	if gs.LootChannel == "" || gs.LootChannel == i.ChannelID {
+		return nil
+	}
+	m, err := s.ChannelMessageSendComplex(gs.LootChannel, msg, discordgo.WithContext(ctx))
+	if err != nil {
+		h.logger.WarnContext(ctx, "announcing in loot channel failed",
+			slog.String("channel_id", gs.LootChannel),
+			slog.Any("error", err),
+		)
+		return nil
+	}
+	return m
+}
+
+// template returns the announcement template that text picks from the
+// settings of guildID, or "" for the default if there are none.
+func (h *Handlers) template(ctx context.Context, guildID string, text func(settings.Settings) string) string {
+	if h.settings == nil {
+		return ""
+	}
+	gs, err := h.settings.Get(ctx, guildID)
+	if err != nil {
+		h.logger.WarnContext(ctx, "loading guild settings for announcement failed", slog.Any("error", err))
+		return ""
+	}
+	return text(gs)
+}
+
+// location returns the timezone dates and times the member who sent i
+// gives are read in: the one in their profile, or else the guild's.
+func (h *Handlers) location(ctx context.Context, i *discordgo.InteractionCreate) *time.Location {
+	if h.dkpMgr != nil {
+		if p, err := h.dkpMgr.GetPlayer(ctx, memberID(i)); err == nil && p.Timezone != "" {
+			if loc, err := clock.LoadLocation(p.Timezone); err == nil {
+				return loc
+			}
+		}
+	}
+	if h.settings == nil {
+		return time.UTC
+	}
+	return h.settings.Location(ctx, i.GuildID)
+}
+
+// recordAnnouncement records m, if not nil, as announcing the auction
+// auctionID, so that the highest bid shown in it is kept up to date.
+func (h *Handlers) recordAnnouncement(ctx context.Context, auctionID string, m *discordgo.Message) {
+	if m == nil {
+		return
+	}
+	if err := h.auctionMgr.RecordAnnouncement(ctx, auctionID, m.ChannelID, m.ID); err != nil {
+		h.logger.WarnContext(ctx, "recording auction announcement failed",
+			slog.String("auction_id", auctionID),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// roleChoices offers the raid roles of player profiles.
+func roleChoices() []*discordgo.ApplicationCommandOptionChoice {
+	return []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "Tank", Value: dkp.RoleTank},
+		{Name: "Healer", Value: dkp.RoleHealer},
+		{Name: "DPS", Value: dkp.RoleDPS},
+	}
 }
 
-// NewHandlers creates new command handlers.
-func NewHandlers(dkpMgr *dkp.Manager, auctionMgr *auction.Manager, logger *slog.Logger, tp trace.TracerProvider) *Handlers {
-	return &Handlers{
-		dkpMgr:     dkpMgr,
-		auctionMgr: auctionMgr,
-		logger:     logger,
-		tracer:     tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/bot/commands"),
+// settingChoices offers every setting key as a choice.
+func settingChoices() []*discordgo.ApplicationCommandOptionChoice {
+	keys := settings.Keys()
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, len(keys))
+	for i, k := range keys {
+		choices[i] = &discordgo.ApplicationCommandOptionChoice{Name: k.Name, Value: k.Name}
+	}
+	return choices
+}
+
+// commandList declares the slash commands, in the order Discord lists
+// them, followed by the actions of the buttons on the bot's messages.
+func commandList() []command {
+	return []command{
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "register",
+				Description: "Register your character for DKP tracking",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "character",
+						Description: "Your in-game character name",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "class",
+						Description: "Your character's class",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "role",
+						Description: "Your raid role",
+						Required:    false,
+						Choices:     roleChoices(),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "spec",
+						Description: "Your character's specialization",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "timezone",
+						Description: "Your timezone, such as Europe/Copenhagen, if not the server's",
+						Required:    false,
+					},
+				},
+			},
+			handle: (*Handlers).handleRegister,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "profile",
+				Description: "Show a player's class, role, and spec, or change your own",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "The player to show (default: you)",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "class",
+						Description: "Change your character's class",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "role",
+						Description: "Change your raid role",
+						Required:    false,
+						Choices:     roleChoices(),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "spec",
+						Description: "Change your character's specialization",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "timezone",
+						Description: "Change your timezone, such as Europe/Copenhagen, or none for the server's",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "notes",
+						Description: "Also show the officers' notes on the player, only to you (admin only)",
+						Required:    false,
+					},
+				},
+			},
+			handle: (*Handlers).handleProfile,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "player-note",
+				Description: "Keep notes on players that only officers see (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "add",
+						Description: "Add a note on a player",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionUser,
+								Name:        "player",
+								Description: "The player",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "text",
+								Description: "The note",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "loot-ban-until",
+								Description: "Ban the player from bidding until this date and time in your timezone, such as 2026-03-01 19:30",
+								Required:    false,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "list",
+						Description: "List the notes on a player",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionUser,
+								Name:        "player",
+								Description: "The player",
+								Required:    true,
+							},
+						},
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handlePlayerNote,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "dkp",
+				Description: "Check your DKP balance",
+			},
+			readOnly: true,
+			handle:   (*Handlers).handleDKP,
+			text:     (*Handlers).textDKP,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "dkp-list",
+				Description: "List all players and their DKP",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "refresh",
+						Description: "Rebuild the standings from the database first (admin only)",
+						Required:    false,
+					},
+				},
+			},
+			readOnly: true,
+			handle:   (*Handlers).handleDKPList,
+			text:     (*Handlers).textDKPList,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "dkp-history",
+				Description: "Show a player's recent DKP changes",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "The player to show (default: you)",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "chart",
+						Description: "Attach a chart of the player's DKP over time",
+						Required:    false,
+					},
+				},
+			},
+			readOnly: true,
+			handle:   (*Handlers).handleDKPHistory,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "my-history",
+				Description: "Show your recent DKP changes, or download your complete DKP and loot history",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "export",
+						Description: "Download your complete DKP and loot history as a CSV file only you can see",
+						Required:    false,
+					},
+				},
+			},
+			readOnly: true,
+			handle:   (*Handlers).handleMyHistory,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "dkp-stats",
+				Description: "Show how much DKP the guild holds and how it grew each week",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "weeks",
+						Description: fmt.Sprintf("Weeks to show, up to %d (default: %d)", maxStatsWeeks, defaultStatsWeeks),
+						Required:    false,
+					},
+				},
+			},
+			readOnly: true,
+			handle:   (*Handlers).handleDKPStats,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "dkp-economy",
+				Description: "Show the DKP supply, its inflow and loot outflow, auction prices, and how concentrated DKP is",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "weeks",
+						Description: fmt.Sprintf("Weeks to show, up to %d (default: %d)", maxStatsWeeks, defaultStatsWeeks),
+						Required:    false,
+					},
+				},
+			},
+			readOnly: true,
+			handle:   (*Handlers).handleDKPEconomy,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "dkp-add",
+				Description: "Add DKP to a player (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "The player to award DKP to",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "amount",
+						Description: "Amount of DKP to award",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "reason",
+						Description: "Reason for the DKP award",
+						Required:    true,
+					},
+				},
+			},
+			officer:  true,
+			handle:   (*Handlers).handleDKPAdd,
+			text:     (*Handlers).textDKPAdd,
+			freeText: dkpChangeText,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "dkp-remove",
+				Description: "Remove DKP from a player (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "The player to deduct DKP from",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "amount",
+						Description: "Amount of DKP to deduct",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "reason",
+						Description: "Reason for the DKP deduction",
+						Required:    true,
+					},
+				},
+			},
+			officer:  true,
+			handle:   (*Handlers).handleDKPRemove,
+			text:     (*Handlers).textDKPRemove,
+			freeText: dkpChangeText,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "dkp-undo",
+				Description: "Reverse a recent DKP change of a player (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "The player whose DKP change to reverse",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "event-id",
+						Description: "The change to reverse, as shown by /audit (default: the most recent)",
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleDKPUndo,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "dkp-correct",
+				Description: "Set a player's DKP balance to fix a bookkeeping error (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "The player whose balance to correct",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "set",
+						Description: "The correct balance",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "reason",
+						Description: "What the correction fixes",
+						Required:    true,
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleDKPCorrect,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "currency",
+				Description: "Change players' balances of a currency such as EP or raid tokens (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "award",
+						Description: "Add to a player's balance",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "currency",
+								Description: "The currency, such as DKP or EP",
+								Required:    true,
+								// Completed from the configured currencies.
+								Autocomplete: true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionUser,
+								Name:        "player",
+								Description: "The player to award to",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionInteger,
+								Name:        "amount",
+								Description: "Amount to award",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "reason",
+								Description: "Reason for the award",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "deduct",
+						Description: "Remove from a player's balance",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "currency",
+								Description: "The currency, such as DKP or EP",
+								Required:    true,
+								// Completed from the configured currencies.
+								Autocomplete: true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionUser,
+								Name:        "player",
+								Description: "The player to deduct from",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionInteger,
+								Name:        "amount",
+								Description: "Amount to deduct",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "reason",
+								Description: "Reason for the deduction",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "transfer",
+						Description: "Move an amount from one player to another",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "currency",
+								Description: "The currency, such as DKP or EP",
+								Required:    true,
+								// Completed from the configured currencies.
+								Autocomplete: true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionUser,
+								Name:        "from",
+								Description: "The player to take from",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionUser,
+								Name:        "to",
+								Description: "The player to give to",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionInteger,
+								Name:        "amount",
+								Description: "Amount to move",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "reason",
+								Description: "Reason for the transfer",
+								Required:    true,
+							},
+						},
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleCurrency,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "balance",
+				Description: "Show a player's balance of every currency",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "The player to show (default: you)",
+						Required:    false,
+					},
+				},
+			},
+			readOnly: true,
+			handle:   (*Handlers).handleBalance,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "currency-list",
+				Description: "List all players and their balance of a currency",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "currency",
+						Description: "The currency, such as DKP or EP",
+						Required:    true,
+						// Completed from the configured currencies.
+						Autocomplete: true,
+					},
+				},
+			},
+			readOnly: true,
+			handle:   (*Handlers).handleCurrencyList,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "auction-start",
+				Description: "Start an item auction",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "item",
+						Description: "Item name to auction",
+						Required:    true,
+						// Completed from the item catalog, if one is configured.
+						Autocomplete: true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "min-bid",
+						Description: "Minimum bid amount",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "duration",
+						Description: "Auction duration in minutes (default: the auction_duration setting)",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "buyout",
+						Description: "Price at which a player may win the item at once with Buy now",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "reserve",
+						Description: "Hidden lowest price; below it the auction closes without a winner",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "currency",
+						Description: "The currency bids are in (default: DKP)",
+						Required:    false,
+						// Completed from the configured currencies.
+						Autocomplete: true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "starts-at",
+						Description: "Open the auction later, at a time in your timezone such as 2026-01-31 19:30; it is announced now",
+						Required:    false,
+					},
+				},
+			},
+			handle: (*Handlers).handleAuctionStart,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "bid",
+				Description: "Place a bid on the current auction",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "auction-id",
+						Description: "Auction ID to bid on",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "amount",
+						Description: "Bid amount",
+						Required:    true,
+					},
+				},
+			},
+			handle: (*Handlers).handleBid,
+			text:   (*Handlers).textBid,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "auction-close",
+				Description: "Close an auction (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "auction-id",
+						Description: "Auction ID to close",
+						Required:    true,
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleAuctionClose,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "auction-pause",
+				Description: "Pause an auction, rejecting bids and stopping its countdown (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "auction-id",
+						Description: "Auction ID to pause",
+						Required:    true,
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleAuctionPause,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "auction-resume",
+				Description: "Resume a paused auction (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "auction-id",
+						Description: "Auction ID to resume",
+						Required:    true,
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleAuctionResume,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "auction-list",
+				Description: "List open auctions",
+			},
+			readOnly: true,
+			handle:   (*Handlers).handleAuctionList,
+			text:     (*Handlers).textAuctionList,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "auction-info",
+				Description: "Show the status and bid history of an auction",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "auction-id",
+						Description: "Auction ID to show",
+						Required:    true,
+					},
+				},
+			},
+			readOnly: true,
+			handle:   (*Handlers).handleAuctionInfo,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "audit",
+				Description: "Show recent DKP and auction activity (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "type",
+						Description: "Only show events of this kind",
+						Required:    false,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "DKP changes", Value: "dkp"},
+							{Name: "Auctions", Value: "auction"},
+							{Name: "Registrations", Value: "player"},
+							{Name: "GDKP raids", Value: "gdkp"},
+							{Name: "Raid calendar", Value: "calendar"},
+							{Name: "Guild bank", Value: "bank"},
+							{Name: "Raffles", Value: "raffle"},
+							{Name: "Item costs", Value: "itemcost"},
+							{Name: "Disputes", Value: "dispute"},
+							{Name: "Currencies", Value: "currency"},
+							{Name: "Command-line changes", Value: "admin"},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "Only show events for this player",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "actor",
+						Description: "Only show events issued by this user",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "hours",
+						Description: "How far back to look in hours (default: 24)",
+						Required:    false,
+						MinValue:    &positive,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "csv",
+						Description: "Attach the results as a CSV file",
+						Required:    false,
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleAudit,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "dkp-export",
+				Description: "Export standings, DKP history, or auction results as CSV (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "kind",
+						Description: "What to export",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Standings", Value: string(export.Standings)},
+							{Name: "DKP transactions", Value: string(export.Transactions)},
+							{Name: "Auction results", Value: string(export.Auctions)},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "from",
+						Description: "First day to include, as YYYY-MM-DD (default: all history)",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "to",
+						Description: "Last day to include, as YYYY-MM-DD (default: today)",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "format",
+						Description: "File format (default: CSV); addon formats export standings",
+						Required:    false,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "CSV", Value: string(export.CSV)},
+							{Name: "MonolithDKP addon", Value: string(export.MonolithDKP)},
+							{Name: "CommunityDKP addon", Value: string(export.CommunityDKP)},
+						},
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleDKPExport,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "import-eqdkp",
+				Description: "Migrate players, raids, and items from an EQDKP Plus export (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionAttachment,
+						Name:        "file",
+						Description: "EQDKP Plus XML export",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "confirm",
+						Description: "Write the import; without this only a preview is shown",
+						Required:    false,
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleImportEQDKP,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "import",
+				Description: "Import data from files (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "players",
+						Description: "Register players with starting DKP from a CSV of character_name, discord_id, and dkp",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionAttachment,
+								Name:        "file",
+								Description: "CSV file with a header row",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionBoolean,
+								Name:        "confirm",
+								Description: "Register the players; without this only a preview is shown",
+								Required:    false,
+							},
+						},
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleImportPlayers,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "guild-merge",
+				Description: "Merge another guild's members and DKP into this one (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "import",
+						Description: "Preview the import of the other guild's standings or event log",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionAttachment,
+								Name:        "file",
+								Description: "Standings CSV, or an event log from dkpbot export events",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionNumber,
+								Name:        "ratio",
+								Description: "Multiply imported balances by this (default: 1)",
+								Required:    false,
+							},
+						},
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleGuildMerge,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "wcl-import",
+				Description: "Award attendance and boss kill DKP from a Warcraft Logs report (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "url",
+						Description: "Report URL, e.g. https://www.warcraftlogs.com/reports/...",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "confirm",
+						Description: "Award the DKP; without this only a preview is shown",
+						Required:    false,
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleWCLImport,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "deadletter",
+				Description: "Inspect events waiting to be persisted (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "status",
+						Description: "Show how many events are queued for retry",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "discard",
+						Description: "Drop a batch of queued events that is no longer retried",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "id",
+								Description: "The batch ID shown by /deadletter status",
+								Required:    true,
+							},
+						},
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleDeadLetter,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "settings",
+				Description: "Show or change this server's DKP settings (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "show",
+						Description: "Show every setting and whether it was changed",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "set",
+						Description: "Change a setting",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "key",
+								Description: "Setting to change",
+								Required:    true,
+								Choices:     settingChoices(),
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "value",
+								Description: "New value, e.g. 10m, 5, @Officers, #loot, or none",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "reset",
+						Description: "Restore a setting to its configured default",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "key",
+								Description: "Setting to reset",
+								Required:    true,
+								Choices:     settingChoices(),
+							},
+						},
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleSettings,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "wishlist",
+				Description: "Manage the items you want; you get a DM when an auction for one starts",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "add",
+						Description: "Add an item to your wishlist",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:         discordgo.ApplicationCommandOptionString,
+								Name:         "item",
+								Description:  "Item name",
+								Required:     true,
+								Autocomplete: true,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "remove",
+						Description: "Remove an item from your wishlist",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:         discordgo.ApplicationCommandOptionString,
+								Name:         "item",
+								Description:  "Item name",
+								Required:     true,
+								Autocomplete: true,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "show",
+						Description: "Show your wishlist",
+					},
+				},
+			},
+			handle: (*Handlers).handleWishlist,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "wishlist-report",
+				Description: "Show which items the most players want (admin only)",
+			},
+			officer:  true,
+			readOnly: true,
+			handle:   (*Handlers).handleWishlistReport,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "raid-start",
+				Description: "Start a raid, which the auctions started until it ends are held in (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "Raid name",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "mode",
+						Description: "What the raid's auctions are bid on in",
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "GDKP: gold, for the raid's pot", Value: gdkp.ModeGDKP},
+							{Name: "DKP", Value: gdkp.ModeDKP},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "organizer-cut",
+						Description: "Percentage of the pot paid to you as organizer",
+						MinValue:    new(float64),
+						MaxValue:    100,
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleRaidStart,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "raid-join",
+				Description: "Join the raid in progress, for a share of its pot in a GDKP raid",
+			},
+			handle: (*Handlers).handleRaidJoin,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "raid-pot",
+				Description: "Show what the auctions of the raid in progress have raised",
+			},
+			readOnly: true,
+			handle:   (*Handlers).handleRaidPot,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "raid-loot",
+				Description: "Show the items won in a raid's auctions",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "raid",
+						Description: "Raid ID, if not the raid in progress",
+					},
+				},
+			},
+			readOnly: true,
+			handle:   (*Handlers).handleRaidLoot,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "raid-end",
+				Description: "End the raid and post its loot, or the payout of a GDKP raid's pot (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "on-time-bonus",
+						Description: "DKP for members who accepted the scheduled raid and joined on time",
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleRaidEnd,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "raid-schedule",
+				Description: "Schedule a raid for members to sign up for (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "Raid name",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "start",
+						Description: "Start in your timezone, such as 2026-01-31 19:30",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "tanks",
+						Description: "Tanks needed",
+						MinValue:    new(float64),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "healers",
+						Description: "Healers needed",
+						MinValue:    new(float64),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "dps",
+						Description: "DPS needed",
+						MinValue:    new(float64),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "size",
+						Description: "Members the raid takes; /raid-compose benches the rest",
+						MinValue:    new(float64),
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleRaidSchedule,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "raid-compose",
+				Description: "Propose a roster for a scheduled raid from its signups, benching the rest (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "raid",
+						Description: "ID of the scheduled raid, as shown on its post",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "confirm",
+						Description: "Set the roster and standby list; without this only a preview is shown",
+						Required:    false,
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleRaidCompose,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "raid-calendar",
+				Description: "Show the upcoming scheduled raids and their signups",
+			},
+			readOnly: true,
+			handle:   (*Handlers).handleRaidCalendar,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "bank",
+				Description: "Keep items in the guild bank and auction them later (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "add",
+						Description: "Deposit an item that was not auctioned in the bank",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:         discordgo.ApplicationCommandOptionString,
+								Name:         "item",
+								Description:  "Item name",
+								Required:     true,
+								Autocomplete: true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "note",
+								Description: "Where it dropped, or who holds it",
+								Required:    false,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "list",
+						Description: "List the items in the bank",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "auction",
+						Description: "Put a banked item up for auction",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "item",
+								Description: "The banked item",
+								Required:    true,
+								// Completed from the items in the bank.
+								Autocomplete: true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionInteger,
+								Name:        "min-bid",
+								Description: "Minimum bid amount",
+								Required:    false,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionInteger,
+								Name:        "duration",
+								Description: "Auction duration in minutes (default: the auction_duration setting)",
+								Required:    false,
+							},
+						},
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleBank,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "raffle",
+				Description: "Raffle items for DKP: buy tickets, and the winning ticket is drawn at random",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "start",
+						Description: "Start a raffle of a cosmetic or surplus item (admin only)",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:         discordgo.ApplicationCommandOptionString,
+								Name:         "item",
+								Description:  "Item name",
+								Required:     true,
+								Autocomplete: true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionInteger,
+								Name:        "cost",
+								Description: "DKP per ticket",
+								Required:    true,
+								MinValue:    &positive,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionInteger,
+								Name:        "max-tickets",
+								Description: "Tickets each player may buy (default: no limit)",
+								Required:    false,
+								MinValue:    &positive,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "enter",
+						Description: "Buy tickets in a raffle; their DKP is deducted at once",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "raffle",
+								Description: "The raffle",
+								Required:    true,
+								// Completed from the open raffles.
+								Autocomplete: true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionInteger,
+								Name:        "tickets",
+								Description: "Tickets to buy (default: 1)",
+								Required:    false,
+								MinValue:    &positive,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "draw",
+						Description: "Draw the winning ticket of a raffle (admin only)",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:         discordgo.ApplicationCommandOptionString,
+								Name:         "raffle",
+								Description:  "The raffle",
+								Required:     true,
+								Autocomplete: true,
+							},
+						},
+					},
+				},
+			},
+			handle: (*Handlers).handleRaffle,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "itemcost",
+				Description: "Maintain the item cost table of fixed-price loot (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "set",
+						Description: "Set or change the DKP an item costs",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:         discordgo.ApplicationCommandOptionString,
+								Name:         "item",
+								Description:  "Item name",
+								Required:     true,
+								Autocomplete: true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionInteger,
+								Name:        "cost",
+								Description: "DKP the item costs",
+								Required:    true,
+								MinValue:    &positive,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "remove",
+						Description: "Remove an item from the table",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "item",
+								Description: "Item name",
+								Required:    true,
+								// Completed from the item cost table.
+								Autocomplete: true,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "list",
+						Description: "List the items of the table and their costs",
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleItemCost,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "loot-award",
+				Description: "Award an item to a player, deducting its cost from the item cost table (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "The player receiving the item",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "item",
+						Description:  "The item",
+						Required:     true,
+						Autocomplete: true,
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleLootAward,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "dispute",
+				Description: "Dispute loot or give feedback to the officers in a private thread",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "open",
+						Description: "Open a dispute with the officers",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "reason",
+								Description: "What the dispute is about",
+								Required:    true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "auction",
+								Description: "ID of the auction disputed; its history is attached",
+								Required:    false,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionBoolean,
+								Name:        "anonymous",
+								Description: "Don't tell the officers who opened it; you won't be added to its thread",
+								Required:    false,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "resolve",
+						Description: "Resolve a dispute and close its thread (admin only)",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "dispute",
+								Description: "The dispute",
+								Required:    true,
+								// Completed from the unresolved disputes.
+								Autocomplete: true,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "resolution",
+								Description: "How the dispute was resolved",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "list",
+						Description: "List the unresolved disputes (admin only)",
+					},
+				},
+			},
+			handle: (*Handlers).handleDispute,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "roster-inactive",
+				Description: "Propose archiving players without attendance or DKP activity (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "weeks",
+						Description: fmt.Sprintf("Weeks without activity (default: as configured, or %d)", defaultInactiveWeeks),
+						Required:    false,
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleRosterInactive,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "roster-restore",
+				Description: "Restore an archived player, unfreezing their DKP (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "The player to restore",
+						Required:    true,
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleRosterRestore,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "loot-ban",
+				Description: "Ban a player from bidding on loot for a number of days (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "The player to ban",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "duration",
+						Description: "How many days the ban lasts",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "reason",
+						Description: "Why, as the player is told when they try to bid",
+						Required:    true,
+						MaxLength:   200,
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleLootBan,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "loot-ban-lift",
+				Description: "End a player's loot ban early (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "The banned player",
+						Required:    true,
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleLootBanLift,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "role-sync",
+				Description: "Give and take the Discord roles players' DKP earns them now (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "dry-run",
+						Description: "Only list the changes",
+						Required:    false,
+					},
+				},
+			},
+			officer: true,
+			handle:  (*Handlers).handleRoleSync,
+		},
+		{
+			ApplicationCommand: discordgo.ApplicationCommand{
+				Name:        "bot-stats",
+				Description: "Show how often each command was used and how often it failed (admin only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "days",
+						Description: fmt.Sprintf("Days to cover, up to %d (default: %d)", maxUsageDays, defaultUsageDays),
+						Required:    false,
+					},
+				},
+			},
+			officer:  true,
+			readOnly: true,
+			handle:   (*Handlers).handleBotStats,
+		},
+		{ApplicationCommand: discordgo.ApplicationCommand{Name: bidAction}, button: true, handle: (*Handlers).handleAuctionQuickBid},
+		{ApplicationCommand: discordgo.ApplicationCommand{Name: customBidAction}, button: true, handle: (*Handlers).handleAuctionCustomBid},
+		{ApplicationCommand: discordgo.ApplicationCommand{Name: customBidModal}, button: true, handle: (*Handlers).handleAuctionBidAmount},
+		{ApplicationCommand: discordgo.ApplicationCommand{Name: confirmBidAction}, button: true, handle: (*Handlers).handleAuctionBidConfirm},
+		{ApplicationCommand: discordgo.ApplicationCommand{Name: buyoutAction}, button: true, handle: (*Handlers).handleAuctionBuyout},
+		{ApplicationCommand: discordgo.ApplicationCommand{Name: rollAction}, button: true, handle: (*Handlers).handleAuctionRoll},
+		{ApplicationCommand: discordgo.ApplicationCommand{Name: signupAction}, button: true, handle: (*Handlers).handleRaidSignup},
+		{ApplicationCommand: discordgo.ApplicationCommand{Name: roster.ArchiveAction}, button: true, officer: true, handle: (*Handlers).handleRosterArchive},
+		{ApplicationCommand: discordgo.ApplicationCommand{Name: merge.SeparateAction}, button: true, officer: true, handle: (*Handlers).handleGuildMergeSeparate},
+		{ApplicationCommand: discordgo.ApplicationCommand{Name: merge.ApplyAction}, button: true, officer: true, handle: (*Handlers).handleGuildMergeApply},
+		{ApplicationCommand: discordgo.ApplicationCommand{Name: merge.CancelAction}, button: true, officer: true, handle: (*Handlers).handleGuildMergeCancel},
+	}
+}
+
+// SlashCommands returns the slash command definitions. Officer commands
+// require the Administrator permission by default.
+func SlashCommands() []*discordgo.ApplicationCommand {
+	var defs []*discordgo.ApplicationCommand
+	for _, c := range commandList() {
+		if c.button {
+			continue
+		}
+		def := c.ApplicationCommand
+		if c.officer {
+			def.DefaultMemberPermissions = &adminPermissions
+		}
+		defs = append(defs, &def)
+	}
+	return defs
+}
+
+// InteractionCreate handles incoming slash command interactions and clicks
+// on the buttons of the bot's messages.
+func (h *Handlers) InteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	start := time.Now()
+	name := interactionName(i)
+	if !h.serves(i.ID, name) {
+		return
+	}
+	ctx, span := h.tracer.Start(context.Background(), "InteractionCreate",
+		trace.WithAttributes(attribute.String("command", name)),
+	)
+	defer span.End()
+	ctx = metrics.WithGuild(ctx, i.GuildID)
+
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		h.autocomplete(ctx, s, i, name)
+		return
+	}
+
+	if !h.track() {
+		respondFailure(ctx, s, i, userMessage(ctx, errDraining))
+		h.handled(ctx, name, memberID(i), errDraining, start)
+		return
+	}
+	defer h.inflight.Done()
+
+	err := h.dispatch(ctx, s, i, name)
+	h.handled(ctx, name, memberID(i), err, start)
+	h.failed(ctx, span, name, err)
+}
+
+// failed records err, returned by the handler of the named command, on
+// span.
+func (h *Handlers) failed(ctx context.Context, span trace.Span, name string, err error) {
+	if err == nil {
+		return
+	}
+	span.SetAttributes(
+		attribute.String("error.kind", derrors.KindOf(err).String()),
+		attribute.String("error.code", derrors.CodeOf(err)),
+	)
+	// Expected failures were explained to the user; only internal ones
+	// need an operator's attention. Panics were reported when recovered.
+	if derrors.KindOf(err) == derrors.Internal && !errors.Is(err, errPanic) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		h.logger.ErrorContext(ctx, "command failed",
+			slog.String("command", name),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// handled records the outcome of the named command, sent by userID at
+// start. An empty userID is not counted as a use.
+func (h *Handlers) handled(ctx context.Context, name, userID string, err error, start time.Time) {
+	h.metrics.CommandHandled(ctx, name, err, time.Since(start))
+	if h.usage != nil && userID != "" {
+		h.usage.Record(ctx, name, userID, err)
+	}
+}
+
+// withArrival returns a copy of ctx recording that the interaction or
+// message id arrived when Discord created it, the time its snowflake
+// encodes, so that concurrent bids are placed in the order they were made.
+func withArrival(ctx context.Context, id string) context.Context {
+	t, err := discordgo.SnowflakeTimestamp(id)
+	if err != nil {
+		return ctx
+	}
+	return auction.WithArrival(ctx, t)
+}
+
+// memberID returns the ID of the member who sent i, or "" outside a guild.
+func memberID(i *discordgo.InteractionCreate) string {
+	if i.Member == nil {
+		return ""
+	}
+	return i.Member.User.ID
+}
+
+// interactionName returns the command i invokes: the name of a slash
+// command, or the action of a button or modal, the part of its custom ID
+// before the first colon.
+func interactionName(i *discordgo.InteractionCreate) string {
+	switch i.Type {
+	case discordgo.InteractionMessageComponent:
+		action, _, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+		return action
+	case discordgo.InteractionModalSubmit:
+		action, _, _ := strings.Cut(i.ModalSubmitData().CustomID, ":")
+		return action
+	}
+	return i.ApplicationCommandData().Name
+}
+
+// autocomplete suggests values for the focused option of the named
+// command: item options are completed from the item catalog. Failures
+// leave the user without suggestions.
+func (h *Handlers) autocomplete(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, name string) {
+	ctx, cancel := context.WithTimeout(ctx, h.deadline(name))
+	defer cancel()
+	opts := i.ApplicationCommandData().Options
+	var sub string
+	if len(opts) == 1 && opts[0].Type == discordgo.ApplicationCommandOptionSubCommand {
+		sub, opts = opts[0].Name, opts[0].Options
+	}
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, opt := range opts {
+		if !opt.Focused {
+			continue
+		}
+		switch {
+		case name == "bank" && sub == "auction":
+			if h.bank == nil {
+				break
+			}
+			banked, err := h.bank.List(ctx)
+			if err != nil {
+				h.logger.WarnContext(ctx, "listing banked items failed", slog.Any("error", err))
+			}
+			choices = bankChoices(banked, opt.StringValue(), h.location(ctx, i))
+		case (name == "loot-award" || name == "itemcost" && sub == "remove") && opt.Name == "item":
+			if h.itemCosts == nil {
+				break
+			}
+			table, err := h.itemCosts.List(ctx)
+			if err != nil {
+				h.logger.WarnContext(ctx, "listing item costs failed", slog.Any("error", err))
+			}
+			choices = itemCostChoices(table, opt.StringValue())
+		case name == "raffle" && opt.Name == "raffle":
+			if h.raffles == nil {
+				break
+			}
+			open, err := h.raffles.Open(ctx)
+			if err != nil {
+				h.logger.WarnContext(ctx, "listing raffles failed", slog.Any("error", err))
+			}
+			choices = raffleChoices(open, opt.StringValue())
+		case name == "dispute" && opt.Name == "dispute":
+			if h.disputes == nil {
+				break
+			}
+			open, err := h.disputes.Unresolved(ctx)
+			if err != nil {
+				h.logger.WarnContext(ctx, "listing disputes failed", slog.Any("error", err))
+			}
+			choices = disputeChoices(open, opt.StringValue())
+		case opt.Name == "currency":
+			choices = currencyChoices(h.dkpMgr.Currencies(), opt.StringValue())
+		case opt.Name == "item" && h.items != nil:
+			found, err := h.items.Search(ctx, opt.StringValue(), maxChoices)
+			if err != nil {
+				h.logger.WarnContext(ctx, "searching items failed", slog.String("command", name), slog.Any("error", err))
+			}
+			choices = itemChoices(found)
+		}
+	}
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	}, discordgo.WithContext(ctx))
+}
+
+// itemChoices offers found as choices, labeled with their quality. Names
+// too long for a choice value are left out.
+func itemChoices(found []store.Item) []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(found))
+	for _, item := range found {
+		if len(item.Name) > maxChoiceLength {
+			continue
+		}
+		label := item.Name
+		if item.Quality != "" && len(label)+len(item.Quality)+3 <= maxChoiceLength {
+			label += " (" + item.Quality + ")"
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: label, Value: item.Name})
+	}
+	return choices
+}
+
+// currencyChoices offers the currencies whose names contain query.
+func currencyChoices(currencies []string, query string) []*discordgo.ApplicationCommandOptionChoice {
+	query = strings.ToLower(query)
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, c := range currencies {
+		if len(choices) < maxChoices && strings.Contains(strings.ToLower(c), query) {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: c, Value: c})
+		}
+	}
+	return choices
+}
+
+// bankChoices offers the banked items whose names contain query, by ID,
+// labeled with the day they were banked in loc.
+func bankChoices(banked []*bank.Item, query string, loc *time.Location) []*discordgo.ApplicationCommandOptionChoice {
+	query = strings.ToLower(query)
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, it := range banked {
+		if len(choices) == maxChoices {
+			break
+		}
+		if !strings.Contains(strings.ToLower(it.Name), query) {
+			continue
+		}
+		label := fmt.Sprintf("%s (banked %s)", it.Name, it.DepositedAt.In(loc).Format("2006-01-02"))
+		if len(label) > maxChoiceLength {
+			label = it.ID
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: label, Value: it.ID})
+	}
+	return choices
+}
+
+// raffleChoices offers the open raffles whose item matches query, labeled
+// with their ticket cost.
+func raffleChoices(open []*raffle.Raffle, query string) []*discordgo.ApplicationCommandOptionChoice {
+	query = strings.ToLower(query)
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, r := range open {
+		if len(choices) == maxChoices {
+			break
+		}
+		if !strings.Contains(strings.ToLower(r.ItemName), query) {
+			continue
+		}
+		label := fmt.Sprintf("%s (%d DKP a ticket, %d sold)", r.ItemName, r.TicketCost, r.Sold)
+		if len(label) > maxChoiceLength {
+			label = r.ID
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: label, Value: r.ID})
+	}
+	return choices
+}
+
+// disputeChoices offers the unresolved disputes whose reason matches
+// query, labeled with their reason.
+func disputeChoices(open []*dispute.Dispute, query string) []*discordgo.ApplicationCommandOptionChoice {
+	query = strings.ToLower(query)
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, d := range open {
+		if len(choices) == maxChoices {
+			break
+		}
+		if !strings.Contains(strings.ToLower(d.Reason), query) && !strings.Contains(d.ID, query) {
+			continue
+		}
+		label := d.Reason
+		if d.AuctionID != "" {
+			label = fmt.Sprintf("%s (auction %s)", d.Reason, d.AuctionID)
+		}
+		if len(label) > maxChoiceLength {
+			label = d.ID
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: label, Value: d.ID})
+	}
+	return choices
+}
+
+// itemCostChoices offers the items of the cost table whose name matches
+// query, labeled with their cost.
+func itemCostChoices(table []itemcost.Item, query string) []*discordgo.ApplicationCommandOptionChoice {
+	query = strings.ToLower(query)
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, it := range table {
+		if len(choices) == maxChoices {
+			break
+		}
+		if !strings.Contains(strings.ToLower(it.Name), query) || len(it.Name) > maxChoiceLength {
+			continue
+		}
+		label := fmt.Sprintf("%s (%d DKP)", it.Name, it.Cost)
+		if len(label) > maxChoiceLength {
+			label = it.Name
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: label, Value: it.Name})
+	}
+	return choices
+}
+
+// dispatch runs the handler for the named command. A panicking handler is
+// recovered and reported as an errPanic error.
+func (h *Handlers) dispatch(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, name string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = h.recovered(ctx, name, r)
+			respondFailure(ctx, s, i, fmt.Sprintf("Command failed: %s", userMessage(ctx, err)))
+		}
+	}()
+
+	// The interaction ID doubles as the idempotency key so that redelivered
+	// interactions are not applied twice.
+	ctx = event.WithActor(ctx, i.Member.User.ID)
+	ctx = idempotency.WithKey(ctx, i.ID)
+	ctx = withArrival(ctx, i.ID)
+	ctx, cancel := context.WithTimeout(ctx, h.deadline(name))
+	defer cancel()
+
+	c, ok := registry[name]
+	if !ok {
+		respond(ctx, s, i, "Unknown command")
+		return errRejected
+	}
+	if c.officer {
+		if err := h.authorize(ctx, i.GuildID, i.Member); err != nil {
+			respond(ctx, s, i, userMessage(ctx, err))
+			return err
+		}
+	}
+	if err := h.filterText(ctx, s, i, name); err != nil {
+		respondPrivate(ctx, s, i, fmt.Sprintf("Refused: %s", userMessage(ctx, err)))
+		return err
+	}
+	err = h.timedOut(ctx, name, c.handle(h, ctx, s, i))
+	if errors.Is(err, errTimedOut) {
+		// The handler's own response was canceled with ctx.
+		respondFailure(context.WithoutCancel(ctx), s, i, userMessage(ctx, err))
+	}
+	return err
+}
+
+// freeTextOptions are the options of commands that take free text, which
+// is recorded and often posted publicly.
+var freeTextOptions = map[string]bool{
+	"character":  true,
+	"spec":       true,
+	"text":       true,
+	"reason":     true,
+	"item":       true,
+	"name":       true,
+	"note":       true,
+	"resolution": true,
+}
+
+// filterText checks the free text options of the slash command i, named
+// name, with checkText.
+func (h *Handlers) filterText(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, name string) error {
+	if h.textFilter == nil || i.Type != discordgo.InteractionApplicationCommand {
+		return nil
+	}
+	opts := i.ApplicationCommandData().Options
+	for len(opts) == 1 && (opts[0].Type == discordgo.ApplicationCommandOptionSubCommand || opts[0].Type == discordgo.ApplicationCommandOptionSubCommandGroup) {
+		opts = opts[0].Options
+	}
+	texts := make(map[string]string)
+	for _, opt := range opts {
+		if opt.Type == discordgo.ApplicationCommandOptionString && freeTextOptions[opt.Name] {
+			texts[opt.Name] = opt.StringValue()
+		}
+	}
+	return h.checkText(ctx, s, i.GuildID, i.Member.User.ID, "/"+name, texts)
+}
+
+// checkText checks texts, the free text the member userID gave command,
+// as typed with its prefix, keyed by option name, with the text filter,
+// if there is one. A refusal is logged and reported in the officer
+// channel of guildID, and returned.
+func (h *Handlers) checkText(ctx context.Context, s *discordgo.Session, guildID, userID, command string, texts map[string]string) error {
+	if h.textFilter == nil {
+		return nil
+	}
+	for _, opt := range slices.Sorted(maps.Keys(texts)) {
+		err := h.textFilter.Check(texts[opt])
+		if err == nil {
+			continue
+		}
+		h.logger.WarnContext(ctx, "free text refused",
+			slog.String("command", command),
+			slog.String("option", opt),
+			slog.String("user_id", userID),
+			slog.Any("error", err),
+		)
+		h.reportRefusal(ctx, s, guildID, userID, command, opt, texts[opt], err)
+		return err
+	}
+	return nil
+}
+
+// reportRefusal tells the officers in the officer channel of guildID, if it
+// has one, that the text of the option opt of command, sent by userID, was
+// refused with err.
+func (h *Handlers) reportRefusal(ctx context.Context, s *discordgo.Session, guildID, userID, command, opt, text string, err error) {
+	if h.settings == nil {
+		return
+	}
+	gs, gerr := h.settings.Get(ctx, guildID)
+	if gerr != nil {
+		h.logger.WarnContext(ctx, "loading guild settings for refused text failed", slog.Any("error", gerr))
+		return
+	}
+	if gs.OfficerChannel == "" {
+		return
+	}
+	if r := []rune(text); len(r) > 200 {
+		text = string(r[:200]) + "…"
+	}
+	// The text is hidden behind a spoiler, as it may be abusive.
+	msg := fmt.Sprintf("🚫 <@%s> was refused `%s` for its `%s`: %s (code `%s`).\n||%s||",
+		userID, command, opt, derrors.MessageOf(err), derrors.CodeOf(err), strings.ReplaceAll(text, "|", "\\|"))
+	if _, serr := s.ChannelMessageSendComplex(gs.OfficerChannel, &discordgo.MessageSend{
+		Content:         msg,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}, discordgo.WithContext(ctx)); serr != nil {
+		h.logger.WarnContext(ctx, "reporting refused text failed",
+			slog.String("channel_id", gs.OfficerChannel),
+			slog.Any("error", serr),
+		)
+	}
+}
+
+// recovered reports a panic recovered from the handler of the named command
+// and returns the error to tell the user about, so that the interaction
+// does not time out.
+func (h *Handlers) recovered(ctx context.Context, name string, r any) error {
+	err := errPanic.Wrap(fmt.Errorf("%v", r))
+	stack := string(debug.Stack())
+
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err, trace.WithAttributes(attribute.String("exception.stacktrace", stack)))
+	span.SetStatus(codes.Error, err.Error())
+	h.metrics.CommandPanicked(ctx, name)
+	h.logger.ErrorContext(ctx, "command handler panicked",
+		slog.String("command", name),
+		slog.Any("panic", r),
+		slog.String("stack", stack),
+	)
+	return err
+}
+
+func (h *Handlers) handleRegister(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	opts := i.ApplicationCommandData().Options
+	charName := opts[0].StringValue()
+	discordID := i.Member.User.ID
+	var profile store.Profile
+	setProfileOptions(&profile, opts[1:])
+
+	p, err := h.dkpMgr.RegisterPlayer(ctx, discordID, charName, profile)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to register: %s", userMessage(ctx, err)))
+		return err
+	}
+	respond(ctx, s, i, fmt.Sprintf("Registered **%s** (DKP: %d)", p.CharacterName, p.DKP))
+	return nil
+}
+
+// setProfileOptions sets the fields of profile given by the class, role,
+// spec, and timezone options among opts, and reports whether any were
+// given. A timezone of "none" clears it.
+func setProfileOptions(profile *store.Profile, opts []*discordgo.ApplicationCommandInteractionDataOption) bool {
+	set := false
+	for _, opt := range opts {
+		switch opt.Name {
+		case "class":
+			profile.Class = opt.StringValue()
+		case "role":
+			profile.Role = opt.StringValue()
+		case "spec":
+			profile.Spec = opt.StringValue()
+		case "timezone":
+			profile.Timezone = opt.StringValue()
+			if strings.EqualFold(strings.TrimSpace(profile.Timezone), "none") {
+				profile.Timezone = ""
+			}
+		default:
+			continue
+		}
+		set = true
+	}
+	return set
+}
+
+func (h *Handlers) handleProfile(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	opts := i.ApplicationCommandData().Options
+	discordID := i.Member.User.ID
+	var withNotes bool
+	for _, opt := range opts {
+		switch opt.Name {
+		case "player":
+			// Only the ID is needed, so the user is not fetched.
+			discordID = opt.UserValue(nil).ID
+		case "notes":
+			withNotes = opt.BoolValue()
+		}
+	}
+	if withNotes {
+		if err := h.authorize(ctx, i.GuildID, i.Member); err != nil {
+			respondPrivate(ctx, s, i, userMessage(ctx, err))
+			return err
+		}
+		return h.respondNotes(ctx, s, i, discordID, true)
+	}
+
+	p, err := h.dkpMgr.GetPlayer(ctx, discordID)
+	switch {
+	case errors.Is(err, store.ErrPlayerNotFound) && discordID == i.Member.User.ID:
+		respond(ctx, s, i, "You are not registered. Use `/register` first.")
+		return nil
+	case errors.Is(err, store.ErrPlayerNotFound):
+		respond(ctx, s, i, "Player is not registered.")
+		return nil
+	case err != nil:
+		respond(ctx, s, i, fmt.Sprintf("Failed to load profile: %s", userMessage(ctx, err)))
+		return err
+	}
+
+	profile := p.Profile
+	if !setProfileOptions(&profile, opts) {
+		respond(ctx, s, i, fmt.Sprintf("**%s**: %s", p.CharacterName, describeProfile(p.Profile)))
+		return nil
+	}
+	if discordID != i.Member.User.ID {
+		respond(ctx, s, i, "You can only change your own profile.")
+		return nil
+	}
+	if profile, err = h.dkpMgr.UpdateProfile(ctx, p.ID, profile); err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to update profile: %s", userMessage(ctx, err)))
+		return err
+	}
+	respond(ctx, s, i, fmt.Sprintf("Updated **%s**: %s", p.CharacterName, describeProfile(profile)))
+	return nil
+}
+
+func (h *Handlers) handlePlayerNote(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.notes == nil {
+		respond(ctx, s, i, "Player notes are not configured.")
+		return errRejected
+	}
+	sub := i.ApplicationCommandData().Options[0]
+	var discordID, text string
+	var banUntil time.Time
+	for _, opt := range sub.Options {
+		switch opt.Name {
+		case "player":
+			discordID = opt.UserValue(nil).ID
+		case "text":
+			text = opt.StringValue()
+		case "loot-ban-until":
+			var err error
+			loc := h.location(ctx, i)
+			if banUntil, err = parseBanEnd(opt.StringValue(), loc); err != nil {
+				respondPrivate(ctx, s, i, fmt.Sprintf("Invalid loot ban end %q: give a date, or a date and time, in %s, such as 2026-03-01 or 2026-03-01 19:30.", opt.StringValue(), loc))
+				return errRejected
+			}
+		}
+	}
+
+	if sub.Name == "list" {
+		return h.respondNotes(ctx, s, i, discordID, false)
+	}
+	p, n, err := h.notes.Add(ctx, discordID, i.Member.User.ID, text, banUntil)
+	if err != nil {
+		respondPrivate(ctx, s, i, fmt.Sprintf("Failed to add note: %s", userMessage(ctx, err)))
+		return err
+	}
+	msg := fmt.Sprintf("Added a note on **%s**.", p.CharacterName)
+	if n.LootBanUntil != nil {
+		msg += fmt.Sprintf(" They may not bid on loot until <t:%d:F>.", n.LootBanUntil.Unix())
+	}
+	respondPrivate(ctx, s, i, msg)
+	return nil
+}
+
+// parseBanEnd parses the loot-ban-until option of /player-note add: a date
+// and time in loc, or a date, meaning its start.
+func parseBanEnd(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.ParseInLocation(scheduleLayout, value, loc); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(time.DateOnly, value, loc)
+}
+
+// respondNotes answers i, only to the member who sent it, with the notes on
+// the player registered as discordID, after their profile if withProfile
+// is set. Callers check that the member is an officer.
+func (h *Handlers) respondNotes(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, discordID string, withProfile bool) error {
+	if h.notes == nil {
+		respondPrivate(ctx, s, i, "Player notes are not configured.")
+		return errRejected
+	}
+	p, list, err := h.notes.List(ctx, discordID)
+	switch {
+	case errors.Is(err, store.ErrPlayerNotFound):
+		respondPrivate(ctx, s, i, "Player is not registered.")
+		return nil
+	case err != nil:
+		respondPrivate(ctx, s, i, fmt.Sprintf("Failed to load notes: %s", userMessage(ctx, err)))
+		return err
+	}
+
+	var b strings.Builder
+	if withProfile {
+		fmt.Fprintf(&b, "**%s**: %s\n", p.CharacterName, describeProfile(p.Profile))
+	}
+	if len(list) == 0 {
+		fmt.Fprintf(&b, "No notes on **%s**.", p.CharacterName)
+		respondPrivate(ctx, s, i, b.String())
+		return nil
+	}
+	fmt.Fprintf(&b, "Notes on **%s**:\n", p.CharacterName)
+	for _, n := range list {
+		fmt.Fprintf(&b, "<t:%d:d> <@%s>: %s", n.CreatedAt.Unix(), n.Author, n.Text)
+		if n.LootBanUntil != nil {
+			fmt.Fprintf(&b, " (loot ban until <t:%d:F>)", n.LootBanUntil.Unix())
+		}
+		b.WriteString("\n")
+	}
+	respondPrivate(ctx, s, i, b.String())
+	return nil
+}
+
+// roleCounts renders how many of awards went to players of each raid role.
+func roleCounts(awards []wcl.Award) string {
+	counts := make(map[string]int)
+	for _, a := range awards {
+		counts[a.Role]++
+	}
+	var parts []string
+	for _, role := range []string{dkp.RoleTank, dkp.RoleHealer, dkp.RoleDPS, ""} {
+		if n := counts[role]; n > 0 {
+			if role == "" {
+				role = "no role set"
+			}
+			parts = append(parts, fmt.Sprintf("%d %s", n, role))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeProfile renders the set fields of p.
+func describeProfile(p store.Profile) string {
+	var parts []string
+	for _, f := range []struct{ name, value string }{{"class", p.Class}, {"role", p.Role}, {"spec", p.Spec}, {"timezone", p.Timezone}} {
+		if f.value != "" {
+			parts = append(parts, fmt.Sprintf("%s %s", f.name, f.value))
+		}
+	}
+	if len(parts) == 0 {
+		return "no class, role, or spec set"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (h *Handlers) handleDKP(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	msg, err := h.balance(ctx, i.Member.User.ID)
+	respond(ctx, s, i, msg)
+	return err
+}
+
+// balance describes the DKP of the player registered as discordID.
+func (h *Handlers) balance(ctx context.Context, discordID string) (string, error) {
+	p, err := h.dkpMgr.GetPlayer(ctx, discordID)
+	if err != nil {
+		return "You are not registered. Use `/register` first.", err
+	}
+	return fmt.Sprintf("**%s** — DKP: **%d**", p.CharacterName, p.DKP), nil
+}
+
+func (h *Handlers) handleDKPList(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	var refresh bool
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "refresh" {
+			refresh = opt.BoolValue()
+		}
+	}
+	if refresh {
+		if err := h.authorize(ctx, i.GuildID, i.Member); err != nil {
+			respond(ctx, s, i, userMessage(ctx, err))
+			return err
+		}
+	}
+	msg, err := h.standings(ctx, refresh)
+	respond(ctx, s, i, msg)
+	return err
+}
+
+// standings lists the DKP of the active players, from the standings
+// projection if there is one, rebuilt first with refresh.
+func (h *Handlers) standings(ctx context.Context, refresh bool) (string, error) {
+	var (
+		players []store.Player
+		view    standings.View
+		err     error
+	)
+	switch {
+	case h.projection == nil:
+		players, err = h.dkpMgr.ListPlayers(ctx)
+	case refresh:
+		view, err = h.projection.Refresh(ctx)
+		players = view.Players
+	default:
+		view, err = h.projection.Standings(ctx)
+		players = view.Players
+	}
+	if err != nil {
+		return fmt.Sprintf("Error listing players: %s", userMessage(ctx, err)), err
+	}
+	// Archived players are left out.
+	active := slices.DeleteFunc(players, store.Player.Archived)
+	archived := len(players) - len(active)
+	if len(active) == 0 && archived == 0 {
+		return "No players registered yet.", nil
+	}
+	msg := "**DKP Standings:**\n"
+	for idx, p := range active {
+		msg += fmt.Sprintf("%d. %s — %d DKP\n", idx+1, p.CharacterName, p.DKP)
+	}
+	if archived > 0 {
+		msg += fmt.Sprintf("_%d archived players are not listed._\n", archived)
+	}
+	switch {
+	case view.BuiltAt.IsZero():
+	case view.Stale:
+		msg += fmt.Sprintf("_As of <t:%d:R>; recent DKP changes will show shortly._\n", view.BuiltAt.Unix())
+	default:
+		msg += fmt.Sprintf("_As of <t:%d:R>._\n", view.BuiltAt.Unix())
+	}
+	return msg, nil
+}
+
+// historyChanges is how many of a player's latest DKP changes /dkp-history
+// lists.
+const historyChanges = 10
+
+func (h *Handlers) handleDKPHistory(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	discordID := i.Member.User.ID
+	var withChart bool
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "player":
+			// Only the ID is needed, so the user is not fetched.
+			discordID = opt.UserValue(nil).ID
+		case "chart":
+			withChart = opt.BoolValue()
+		}
+	}
+
+	return h.dkpHistory(ctx, s, i, discordID, withChart)
+}
+
+// dkpHistory answers i with the latest DKP changes of the player
+// registered as discordID, and with withChart a chart of their DKP over
+// time.
+func (h *Handlers) dkpHistory(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, discordID string, withChart bool) error {
+	p, err := h.dkpMgr.GetPlayer(ctx, discordID)
+	switch {
+	case errors.Is(err, store.ErrPlayerNotFound) && discordID == i.Member.User.ID:
+		respond(ctx, s, i, "You are not registered. Use `/register` first.")
+		return nil
+	case errors.Is(err, store.ErrPlayerNotFound):
+		respond(ctx, s, i, "Player is not registered.")
+		return nil
+	case err != nil:
+		respond(ctx, s, i, fmt.Sprintf("Error loading player: %s", userMessage(ctx, err)))
+		return err
+	}
+	changes, err := h.dkpMgr.History(ctx, p)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error loading DKP history: %s", userMessage(ctx, err)))
+		return err
+	}
+	if len(changes) == 0 {
+		respond(ctx, s, i, fmt.Sprintf("**%s** has no DKP changes. DKP: **%d**", p.CharacterName, p.DKP))
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**DKP history of %s** — DKP: **%d**\n", p.CharacterName, p.DKP)
+	for _, c := range slices.Backward(changes[max(len(changes)-historyChanges, 0):]) {
+		fmt.Fprintf(&b, "<t:%d:d> %+d → %d: %s\n", c.CreatedAt.Unix(), c.Amount, c.Balance, c.Reason)
+	}
+	if n := len(changes) - historyChanges; n > 0 {
+		fmt.Fprintf(&b, "…and %d earlier changes\n", n)
+	}
+	msg := &discordgo.MessageSend{Content: b.String()}
+	if withChart {
+		// The line starts from the balance before the first change.
+		points := []chart.Point{{At: changes[0].CreatedAt, Value: changes[0].Balance - changes[0].Amount}}
+		for _, c := range changes {
+			points = append(points, chart.Point{At: c.CreatedAt, Value: c.Balance})
+		}
+		if err := attachChart(msg, "dkp-history.png", chart.Line, points); err != nil {
+			respond(ctx, s, i, fmt.Sprintf("Error rendering chart: %s", userMessage(ctx, err)))
+			return err
+		}
+	}
+	respondMessage(ctx, s, i, msg)
+	return nil
+}
+
+// handleMyHistory shows the member's DKP history, or with export sends
+// them their complete DKP and loot history as a file only they can see.
+func (h *Handlers) handleMyHistory(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	var asCSV bool
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "export" {
+			asCSV = opt.BoolValue()
+		}
+	}
+	if !asCSV {
+		return h.dkpHistory(ctx, s, i, i.Member.User.ID, false)
+	}
+
+	p, err := h.dkpMgr.GetPlayer(ctx, i.Member.User.ID)
+	switch {
+	case errors.Is(err, store.ErrPlayerNotFound):
+		respond(ctx, s, i, "You are not registered. Use `/register` first.")
+		return nil
+	case err != nil:
+		respond(ctx, s, i, fmt.Sprintf("Error loading player: %s", userMessage(ctx, err)))
+		return err
+	}
+	var buf bytes.Buffer
+	if err := h.exporter.WriteLedger(ctx, &buf, p); err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error exporting your history: %s", userMessage(ctx, err)))
+		return err
+	}
+	respondPrivateFile(ctx, s, i, fmt.Sprintf("DKP and loot history of **%s** — DKP: **%d**", p.CharacterName, p.DKP),
+		export.LedgerFilename, "text/csv", &buf)
+	return nil
+}
+
+// Weeks /dkp-stats shows by default and at most.
+const (
+	defaultStatsWeeks = 8
+	maxStatsWeeks     = 52
+)
+
+func (h *Handlers) handleDKPStats(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	n := defaultStatsWeeks
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "weeks" {
+			n = min(max(int(opt.IntValue()), 1), maxStatsWeeks)
+		}
+	}
+
+	players, err := h.dkpMgr.ListPlayers(ctx)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error listing players: %s", userMessage(ctx, err)))
+		return err
+	}
+	weeks, err := h.dkpMgr.Weekly(ctx, n)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error loading DKP changes: %s", userMessage(ctx, err)))
+		return err
+	}
+
+	var total int
+	for _, p := range players {
+		total += p.DKP
+	}
+	var awarded, spent int
+	rows := make([]string, len(weeks))
+	points := make([]chart.Point, len(weeks))
+	for k, w := range weeks {
+		awarded += w.Awarded
+		spent += w.Spent
+		rows[k] = fmt.Sprintf("`%s` +%d / -%d = %+d\n", w.Start.Format("2006-01-02"), w.Awarded, w.Spent, w.Net())
+		points[k] = chart.Point{At: w.Start, Value: w.Net()}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**DKP economy**: %d players hold **%d DKP**.\n", len(players), total)
+	fmt.Fprintf(&b, "Last %d weeks: %d awarded, %d spent, net **%+d DKP**.\n", n, awarded, spent, awarded-spent)
+	b.WriteString("Week starting: awarded / spent = net\n")
+	// List the latest weeks that fit; the chart shows them all.
+	size := b.Len() + len("…and 1000 earlier weeks\n")
+	first := len(rows)
+	for first > 0 && size+len(rows[first-1]) <= maxMessageLength {
+		first--
+		size += len(rows[first])
+	}
+	if first > 0 {
+		fmt.Fprintf(&b, "…and %d earlier weeks\n", first)
+	}
+	for _, row := range rows[first:] {
+		b.WriteString(row)
+	}
+	msg := &discordgo.MessageSend{Content: b.String()}
+	if err := attachChart(msg, "dkp-stats.png", chart.Bars, points); err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error rendering chart: %s", userMessage(ctx, err)))
+		return err
+	}
+	respondMessage(ctx, s, i, msg)
+	return nil
+}
+
+func (h *Handlers) handleDKPEconomy(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.economy == nil {
+		respond(ctx, s, i, "The DKP economy is not configured.")
+		return errRejected
+	}
+	n := defaultStatsWeeks
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "weeks" {
+			n = min(max(int(opt.IntValue()), 1), maxStatsWeeks)
+		}
+	}
+
+	report, err := h.economy.Report(ctx, n)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error loading the DKP economy: %s", userMessage(ctx, err)))
+		return err
+	}
+	weeks := report.Weeks
+	var awarded, loot, other, taxed, burned, sold, soldFor int
+	rows := make([]string, len(weeks))
+	points := make([]chart.Point, len(weeks))
+	for k, w := range weeks {
+		awarded += w.Awarded
+		loot += w.Loot
+		other += w.Other()
+		taxed += w.Taxed
+		burned += w.Burned()
+		sold += w.Sold
+		soldFor += w.SoldFor
+		sales := "no items sold"
+		if w.Sold > 0 {
+			sales = fmt.Sprintf("%d sold at %.1f on average", w.Sold, w.AveragePrice())
+		}
+		rows[k] = fmt.Sprintf("`%s` %d DKP (%+.1f%%): +%d in, -%d loot, -%d other; %s; taxed %d, burned %d\n",
+			w.Start.Format("2006-01-02"), w.Supply, w.Inflation(), w.Awarded, w.Loot, w.Other(), sales, w.Taxed, w.Burned())
+		points[k] = chart.Point{At: w.Start, Value: w.Supply}
+	}
+	first := weeks[0]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**DKP economy**: %d players hold **%d DKP**.\n", report.Players, report.Supply())
+	if start := first.Supply - first.Net(); start > 0 {
+		fmt.Fprintf(&b, "Last %d weeks: the supply grew **%+.1f%%** from %d DKP.\n", n, float64(report.Supply()-start)/float64(start)*100, start)
+	}
+	fmt.Fprintf(&b, "Inflow: %d DKP awarded. Outflow: %d DKP spent on loot, %d by decay, tax, and deductions.\n", awarded, loot, other)
+	if sold > 0 {
+		fmt.Fprintf(&b, "Items sold in DKP auctions: %d, at %.1f DKP on average.\n", sold, float64(soldFor)/float64(sold))
+	}
+	fmt.Fprintf(&b, "Concentration: a Gini coefficient of **%.2f**, from 0 if every player holds the same DKP to 1 if one holds it all.\n", report.Gini)
+	fmt.Fprintf(&b, "Auction tax: %d DKP charged, %d burned, %d shared with raiders.\n", taxed, burned, taxed-burned)
+	b.WriteString("Week starting: supply at its end (growth): inflow, outflow, auction prices, tax\n")
+	// List the latest weeks that fit; the chart shows them all.
+	size := b.Len() + len("…and 1000 earlier weeks\n")
+	k := len(rows)
+	for k > 0 && size+len(rows[k-1]) <= maxMessageLength {
+		k--
+		size += len(rows[k])
+	}
+	if k > 0 {
+		fmt.Fprintf(&b, "…and %d earlier weeks\n", k)
+	}
+	for _, row := range rows[k:] {
+		b.WriteString(row)
+	}
+	msg := &discordgo.MessageSend{Content: b.String()}
+	if err := attachChart(msg, "dkp-economy.png", chart.Line, points); err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error rendering chart: %s", userMessage(ctx, err)))
+		return err
+	}
+	respondMessage(ctx, s, i, msg)
+	return nil
+}
+
+// attachChart renders points with render and attaches the image to msg as
+// name.
+func attachChart(msg *discordgo.MessageSend, name string, render func([]chart.Point) ([]byte, error), points []chart.Point) error {
+	img, err := render(points)
+	if err != nil {
+		return err
+	}
+	msg.Files = append(msg.Files, &discordgo.File{Name: name, ContentType: "image/png", Reader: bytes.NewReader(img)})
+	return nil
+}
+
+func (h *Handlers) handleDKPAdd(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	opts := i.ApplicationCommandData().Options
+	msg, err := h.awardDKP(ctx, opts[0].UserValue(s).ID, int(opts[1].IntValue()), opts[2].StringValue())
+	respond(ctx, s, i, msg)
+	return err
+}
+
+// awardDKP awards amount DKP to the player registered as discordID.
+func (h *Handlers) awardDKP(ctx context.Context, discordID string, amount int, reason string) (string, error) {
+	target, err := h.dkpMgr.GetPlayer(ctx, discordID)
+	if err != nil {
+		return "Target player is not registered.", err
+	}
+	if err := h.dkpMgr.AwardDKP(ctx, target.ID, amount, reason); err != nil {
+		return fmt.Sprintf("Failed to award DKP: %s", userMessage(ctx, err)), err
+	}
+	return fmt.Sprintf("Awarded **%d DKP** to **%s** for: %s", amount, target.CharacterName, reason), nil
+}
+
+func (h *Handlers) handleDKPRemove(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	opts := i.ApplicationCommandData().Options
+	msg, err := h.deductDKP(ctx, opts[0].UserValue(s).ID, int(opts[1].IntValue()), opts[2].StringValue())
+	respond(ctx, s, i, msg)
+	return err
+}
+
+// deductDKP deducts amount DKP from the player registered as discordID.
+func (h *Handlers) deductDKP(ctx context.Context, discordID string, amount int, reason string) (string, error) {
+	target, err := h.dkpMgr.GetPlayer(ctx, discordID)
+	if err != nil {
+		return "Target player is not registered.", err
+	}
+	if err := h.dkpMgr.DeductDKP(ctx, target.ID, amount, reason); err != nil {
+		return fmt.Sprintf("Failed to deduct DKP: %s", userMessage(ctx, err)), err
+	}
+	return fmt.Sprintf("Deducted **%d DKP** from **%s** for: %s", amount, target.CharacterName, reason), nil
+}
+
+func (h *Handlers) handleDKPUndo(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	var targetUser *discordgo.User
+	var eventID string
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "player":
+			targetUser = opt.UserValue(s)
+		case "event-id":
+			eventID = strings.TrimSpace(opt.StringValue())
+		}
+	}
+
+	target, err := h.dkpMgr.GetPlayer(ctx, targetUser.ID)
+	if err != nil {
+		respond(ctx, s, i, "Target player is not registered.")
+		return err
+	}
+
+	// A zero window selects the default.
+	var window time.Duration
+	if h.settings != nil {
+		gs, err := h.settings.Get(ctx, i.GuildID)
+		if err != nil {
+			respond(ctx, s, i, fmt.Sprintf("Error loading settings: %s", userMessage(ctx, err)))
+			return err
+		}
+		window = gs.UndoWindow
+	}
+
+	r, err := h.dkpMgr.Undo(ctx, target.ID, eventID, window)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to undo DKP change: %s", userMessage(ctx, err)))
+		return err
+	}
+	respond(ctx, s, i, fmt.Sprintf("Reversed **%+d DKP** for **%s** from <t:%d:f> (%s)", r.Amount, target.CharacterName, r.CreatedAt.Unix(), r.Reason))
+	return nil
+}
+
+// handleDKPCorrect sets a player's balance. The officer recorded is always
+// the member who sent the interaction.
+func (h *Handlers) handleDKPCorrect(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	var (
+		targetUser *discordgo.User
+		balance    int
+		reason     string
+	)
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "player":
+			targetUser = opt.UserValue(s)
+		case "set":
+			balance = int(opt.IntValue())
+		case "reason":
+			reason = strings.TrimSpace(opt.StringValue())
+		}
+	}
+	if targetUser == nil || reason == "" {
+		respond(ctx, s, i, "A player and a reason are required.")
+		return errRejected
+	}
+
+	c, err := h.dkpMgr.Correct(ctx, targetUser.ID, balance, reason)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to correct DKP: %s", userMessage(ctx, err)))
+		return err
+	}
+	respond(ctx, s, i, fmt.Sprintf("Corrected **%s** from **%d** to **%d DKP** (%+d) for: %s", c.CharacterName, c.From, c.To, c.To-c.From, reason))
+	return nil
+}
+
+func (h *Handlers) handleCurrency(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	sub := i.ApplicationCommandData().Options[0]
+	var (
+		currency, reason string
+		player, from, to *discordgo.User
+		amount           int
+	)
+	for _, opt := range sub.Options {
+		switch opt.Name {
+		case "currency":
+			currency = opt.StringValue()
+		case "player":
+			player = opt.UserValue(s)
+		case "from":
+			from = opt.UserValue(s)
+		case "to":
+			to = opt.UserValue(s)
+		case "amount":
+			amount = int(opt.IntValue())
+		case "reason":
+			reason = strings.TrimSpace(opt.StringValue())
+		}
+	}
+	currency, err := h.dkpMgr.Currency(currency)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Unknown currency. Choose one of: %s.", strings.Join(h.dkpMgr.Currencies(), ", ")))
+		return errRejected
+	}
+
+	if sub.Name == "transfer" {
+		sender, err := h.dkpMgr.GetPlayer(ctx, from.ID)
+		if err != nil {
+			respond(ctx, s, i, "The sending player is not registered.")
+			return err
+		}
+		recipient, err := h.dkpMgr.GetPlayer(ctx, to.ID)
+		if err != nil {
+			respond(ctx, s, i, "The receiving player is not registered.")
+			return err
+		}
+		if err := h.dkpMgr.Transfer(ctx, currency, sender.ID, recipient.ID, amount, reason); err != nil {
+			respond(ctx, s, i, fmt.Sprintf("Failed to transfer %s: %s", currency, userMessage(ctx, err)))
+			return err
+		}
+		respond(ctx, s, i, fmt.Sprintf("Transferred **%d %s** from **%s** to **%s** for: %s", amount, currency, sender.CharacterName, recipient.CharacterName, reason))
+		return nil
+	}
+
+	target, err := h.dkpMgr.GetPlayer(ctx, player.ID)
+	if err != nil {
+		respond(ctx, s, i, "Target player is not registered.")
+		return err
+	}
+	switch sub.Name {
+	case "award":
+		if err := h.dkpMgr.Award(ctx, currency, target.ID, amount, reason); err != nil {
+			respond(ctx, s, i, fmt.Sprintf("Failed to award %s: %s", currency, userMessage(ctx, err)))
+			return err
+		}
+		respond(ctx, s, i, fmt.Sprintf("Awarded **%d %s** to **%s** for: %s", amount, currency, target.CharacterName, reason))
+	case "deduct":
+		if err := h.dkpMgr.Deduct(ctx, currency, target.ID, amount, reason); err != nil {
+			respond(ctx, s, i, fmt.Sprintf("Failed to deduct %s: %s", currency, userMessage(ctx, err)))
+			return err
+		}
+		respond(ctx, s, i, fmt.Sprintf("Deducted **%d %s** from **%s** for: %s", amount, currency, target.CharacterName, reason))
+	}
+	return nil
+}
+
+func (h *Handlers) handleBalance(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	discordID := i.Member.User.ID
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "player" {
+			// Only the ID is needed, so the user is not fetched.
+			discordID = opt.UserValue(nil).ID
+		}
+	}
+
+	p, err := h.dkpMgr.GetPlayer(ctx, discordID)
+	switch {
+	case errors.Is(err, store.ErrPlayerNotFound) && discordID == i.Member.User.ID:
+		respond(ctx, s, i, "You are not registered. Use `/register` first.")
+		return nil
+	case errors.Is(err, store.ErrPlayerNotFound):
+		respond(ctx, s, i, "Player is not registered.")
+		return nil
+	case err != nil:
+		respond(ctx, s, i, fmt.Sprintf("Error loading player: %s", userMessage(ctx, err)))
+		return err
+	}
+	wallet, err := h.dkpMgr.Wallet(ctx, p)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error loading balances: %s", userMessage(ctx, err)))
+		return err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Balances of %s:**\n", p.CharacterName)
+	for _, c := range wallet {
+		fmt.Fprintf(&b, "%s: **%d**\n", c.Currency, c.Balance)
+	}
+	respond(ctx, s, i, b.String())
+	return nil
+}
+
+func (h *Handlers) handleCurrencyList(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	currency, err := h.dkpMgr.Currency(i.ApplicationCommandData().Options[0].StringValue())
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Unknown currency. Choose one of: %s.", strings.Join(h.dkpMgr.Currencies(), ", ")))
+		return errRejected
+	}
+	if currency == dkp.DKP {
+		return h.handleDKPList(ctx, s, i)
+	}
+
+	players, err := h.dkpMgr.ListPlayers(ctx)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error listing players: %s", userMessage(ctx, err)))
+		return err
+	}
+	balances, err := h.dkpMgr.Balances(ctx, currency)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error loading balances: %s", userMessage(ctx, err)))
+		return err
+	}
+	// Archived players are left out, as on /dkp-list.
+	active := slices.DeleteFunc(players, store.Player.Archived)
+	if len(active) == 0 {
+		respond(ctx, s, i, "No players registered yet.")
+		return nil
+	}
+	slices.SortStableFunc(active, func(a, b store.Player) int { return balances[b.ID] - balances[a.ID] })
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s Standings:**\n", currency)
+	for idx, p := range active {
+		line := fmt.Sprintf("%d. %s — %d %s\n", idx+1, p.CharacterName, balances[p.ID], currency)
+		if b.Len()+len(line) > maxMessageLength {
+			break
+		}
+		b.WriteString(line)
+	}
+	respond(ctx, s, i, b.String())
+	return nil
+}
+
+func (h *Handlers) handleAuctionStart(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if err := h.lootMode(ctx, i.GuildID, settings.Settings.Auctions, errAuctionsOff); err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to start auction: %s", userMessage(ctx, err)))
+		return err
+	}
+	data := i.ApplicationCommandData().Options
+	itemName := data[0].StringValue()
+
+	// A zero duration selects the guild's default.
+	minBid, buyout, reserve := 0, 0, 0
+	var duration time.Duration
+	var opts []auction.StartOption
+
+	for _, opt := range data[1:] {
+		switch opt.Name {
+		case "min-bid":
+			minBid = int(opt.IntValue())
+		case "duration":
+			duration = time.Duration(opt.IntValue()) * time.Minute
+		case "buyout":
+			buyout = int(opt.IntValue())
+		case "reserve":
+			reserve = int(opt.IntValue())
+		case "currency":
+			opts = append(opts, auction.InCurrency(opt.StringValue()))
+		case "starts-at":
+			loc := h.location(ctx, i)
+			startsAt, err := time.ParseInLocation(scheduleLayout, strings.TrimSpace(opt.StringValue()), loc)
+			if err != nil {
+				respond(ctx, s, i, fmt.Sprintf("Invalid start %q: give a date and time in %s, such as 2026-01-31 19:30.", opt.StringValue(), loc))
+				return errRejected
+			}
+			opts = append(opts, auction.StartingAt(startsAt))
+		}
+	}
+
+	a, err := h.auctionMgr.StartAuction(ctx, itemName, i.Member.User.ID, minBid, buyout, reserve, duration, opts...)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to start auction: %s", userMessage(ctx, err)))
+		return err
+	}
+	h.respondStarted(ctx, s, i, a.State())
+	// The reserve is confirmed only to the officer who set it.
+	if a.Reserve > 0 {
+		_, _ = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: fmt.Sprintf("Auction `%s` has a hidden reserve of %d %s.", a.ID, a.Reserve, a.Currency()),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		}, discordgo.WithContext(ctx))
+	}
+	return nil
+}
+
+// respondStarted answers i, which started the auction a, with its
+// announcement, or that of its schedule, which is also posted to the
+// announcement channel, or says that it is queued.
+func (h *Handlers) respondStarted(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, a auction.State) {
+	if a.Status == "queued" {
+		respond(ctx, s, i, fmt.Sprintf("The limit of open auctions is reached, so auction `%s` for **%s** is queued. It starts when another auction ends.", a.ID, a.ItemName))
+		return
+	}
+	msg := h.startedMessage(ctx, i.GuildID, a)
+	if a.Status == "scheduled" {
+		msg = h.scheduledMessage(ctx, a)
+	}
+	respondMessage(ctx, s, i, msg)
+	if m, err := s.InteractionResponse(i.Interaction, discordgo.WithContext(ctx)); err == nil {
+		h.recordAnnouncement(ctx, a.ID, m)
+	}
+	h.recordAnnouncement(ctx, a.ID, h.announce(ctx, s, i, msg))
+}
+
+// startedMessage announces the start of the auction a, worded by the
+// started_message setting of guildID, with quick bid buttons and a Buy now
+// button if it has a buyout price.
+func (h *Handlers) startedMessage(ctx context.Context, guildID string, a auction.State) *discordgo.MessageSend {
+	embed := h.auctionEmbed(ctx, a.ItemName)
+	embed.Description = fmt.Sprintf("ID: `%s`\nMin bid: %d, Min increment: %d, Duration: %s", a.ID, a.MinBid, a.MinIncrement, a.Duration)
+	switch {
+	case a.Currency() == "gold":
+		embed.Description += "\nBids are in gold for the GDKP raid's pot."
+	case a.Points != "":
+		embed.Description += fmt.Sprintf("\nBids are in %s.", a.Points)
+	}
+	msg := &discordgo.MessageSend{
+		Content: announce.Started.Render(h.template(ctx, guildID, func(gs settings.Settings) string { return gs.StartedMessage }), announce.Vars{
+			"item":     a.ItemName,
+			"id":       a.ID,
+			"min_bid":  strconv.Itoa(a.MinBid),
+			"duration": a.Duration.String(),
+			"currency": a.Currency(),
+		}),
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: []discordgo.MessageComponent{bidButtons(a)},
+	}
+	if a.Buyout > 0 {
+		embed.Description += fmt.Sprintf("\nBuyout: %d", a.Buyout)
+		msg.Components = append(msg.Components,
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    fmt.Sprintf("Buy now for %d %s", a.Buyout, a.Currency()),
+					Style:    discordgo.SuccessButton,
+					CustomID: buyoutAction + ":" + a.ID,
+				},
+			}},
+		)
+	}
+	return msg
+}
+
+// scheduledMessage announces the auction a, which opens at a later time.
+// OpenScheduled turns it into the announcement of its start.
+func (h *Handlers) scheduledMessage(ctx context.Context, a auction.State) *discordgo.MessageSend {
+	embed := h.auctionEmbed(ctx, a.ItemName)
+	embed.Description = fmt.Sprintf("ID: `%s`\nOpens <t:%d:F> (<t:%[2]d:R>)\nMin bid: %d, Min increment: %d, Duration: %s", a.ID, a.StartsAt.Unix(), a.MinBid, a.MinIncrement, a.Duration)
+	switch {
+	case a.Currency() == "gold":
+		embed.Description += "\nBids are in gold for the GDKP raid's pot."
+	case a.Points != "":
+		embed.Description += fmt.Sprintf("\nBids are in %s.", a.Points)
+	}
+	if a.Buyout > 0 {
+		embed.Description += fmt.Sprintf("\nBuyout: %d", a.Buyout)
+	}
+	return &discordgo.MessageSend{
+		Content: "Auction scheduled!",
+		Embeds:  []*discordgo.MessageEmbed{embed},
+	}
+}
+
+// OpenScheduled opens the scheduled auctions whose start time has come
+// and turns their announcements into those of their start in guildID, with
+// bid buttons. Only the leader should call it.
+func (h *Handlers) OpenScheduled(ctx context.Context, s *discordgo.Session, guildID string) {
+	for _, a := range h.auctionMgr.OpenScheduled(ctx) {
+		msg := h.startedMessage(ctx, guildID, a)
+		for _, an := range a.Announcements {
+			if _, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+				Channel:    an.ChannelID,
+				ID:         an.MessageID,
+				Content:    &msg.Content,
+				Embeds:     &msg.Embeds,
+				Components: &msg.Components,
+			}, discordgo.WithContext(ctx)); err != nil {
+				h.logger.WarnContext(ctx, "announcing scheduled auction start failed",
+					slog.String("auction_id", a.ID),
+					slog.String("message_id", an.MessageID),
+					slog.Any("error", err),
+				)
+			}
+		}
+	}
+}
+
+// countdownField names the embed field showing an auction's time left.
+const countdownField = "Time left"
+
+// countdownCells is the length of the progress bar of countdownField.
+const countdownCells = 10
+
+// countdownMilestone returns the latest countdown milestone an auction of
+// duration with remaining left has reached: half and a quarter of its
+// duration, a minute, ten seconds, and the end, at zero. Only the
+// milestones are shown, so that announcements are edited a few times per
+// auction rather than running into Discord's rate limits.
+func countdownMilestone(duration, remaining time.Duration) (time.Duration, bool) {
+	if remaining <= 0 {
+		return 0, true
+	}
+	reached, ok := duration, false
+	for _, m := range []time.Duration{duration / 2, duration / 4, time.Minute, 10 * time.Second} {
+		if m < duration && remaining <= m && m < reached {
+			reached, ok = m, true
+		}
+	}
+	return reached, ok
+}
+
+// countdownValue describes an auction of duration with remaining left,
+// such as "Closes in 2:30" under a progress bar.
+func countdownValue(duration, remaining time.Duration) string {
+	if remaining <= 0 {
+		return "`" + strings.Repeat("▰", countdownCells) + "`\nTime is up; the auction closes soon."
+	}
+	filled := 0
+	if duration > 0 {
+		filled = min(int((duration-remaining)*countdownCells/duration), countdownCells)
+	}
+	secs := int(remaining.Round(time.Second).Seconds())
+	return fmt.Sprintf("`%s%s`\nCloses in %d:%02d",
+		strings.Repeat("▰", filled), strings.Repeat("▱", countdownCells-filled), secs/60, secs%60)
+}
+
+// UpdateCountdowns edits the announcements of the open auctions to show
+// their time left as they reach each countdown milestone. Only the leader
+// should call it, frequently enough not to miss the ten-second one.
+func (h *Handlers) UpdateCountdowns(ctx context.Context, s *discordgo.Session) {
+	countdowns := h.auctionMgr.Countdowns()
+	var due []auction.Countdown
+	h.countdownMu.Lock()
+	open := make(map[string]bool, len(countdowns))
+	for _, c := range countdowns {
+		open[c.AuctionID] = true
+		m, ok := countdownMilestone(c.Duration, c.Remaining)
+		if shown, seen := h.countdowns[c.AuctionID]; !ok || seen && shown == m {
+			continue
+		}
+		h.countdowns[c.AuctionID] = m
+		due = append(due, c)
+	}
+	for id := range h.countdowns {
+		if !open[id] {
+			delete(h.countdowns, id)
+		}
+	}
+	h.countdownMu.Unlock()
+
+	for _, c := range due {
+		value := countdownValue(c.Duration, c.Remaining)
+		for _, an := range c.Announcements {
+			if err := setEmbedField(ctx, s, an, countdownField, value); err != nil {
+				h.logger.WarnContext(ctx, "updating auction countdown failed",
+					slog.String("auction_id", c.AuctionID),
+					slog.String("message_id", an.MessageID),
+					slog.Any("error", err),
+				)
+			}
+		}
+	}
+}
+
+// setEmbedField edits the message an to set the field name of its first
+// embed to value, adding the field if it has none, and keeps the rest of
+// the message as it is.
+func setEmbedField(ctx context.Context, s *discordgo.Session, an auction.Announcement, name, value string) error {
+	m, err := s.ChannelMessage(an.ChannelID, an.MessageID, discordgo.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("fetching message: %w", err)
+	}
+	if len(m.Embeds) == 0 {
+		return nil
+	}
+	embeds := m.Embeds
+	embed := embeds[0]
+	i := slices.IndexFunc(embed.Fields, func(f *discordgo.MessageEmbedField) bool { return f.Name == name })
+	if i < 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: name})
+		i = len(embed.Fields) - 1
+	}
+	embed.Fields[i].Value = value
+	if _, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel: an.ChannelID,
+		ID:      an.MessageID,
+		Embeds:  &embeds,
+	}, discordgo.WithContext(ctx)); err != nil {
+		return fmt.Errorf("editing message: %w", err)
+	}
+	return nil
+}
+
+// bidButtons returns the quick bid buttons of the auction a, which raise
+// the highest bid by its minimum increment and by each of quickRaises
+// above it, and the Custom button.
+func bidButtons(a auction.State) discordgo.ActionsRow {
+	raises := []int{max(a.MinIncrement, 1)}
+	for _, r := range quickRaises {
+		if r > raises[0] {
+			raises = append(raises, r)
+		}
+	}
+	row := discordgo.ActionsRow{}
+	for _, r := range raises {
+		row.Components = append(row.Components, discordgo.Button{
+			Label:    fmt.Sprintf("+%d", r),
+			Style:    discordgo.PrimaryButton,
+			CustomID: fmt.Sprintf("%s:%s:%d", bidAction, a.ID, r),
+		})
+	}
+	row.Components = append(row.Components, discordgo.Button{
+		Label:    "Custom…",
+		Style:    discordgo.SecondaryButton,
+		CustomID: customBidAction + ":" + a.ID,
+	})
+	return row
+}
+
+// announceStarted posts the start of queued auctions that started when an
+// auction ended in the channel of i, the interaction that ended it.
+func (h *Handlers) announceStarted(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, started []auction.State) {
+	for _, a := range started {
+		msg := h.startedMessage(ctx, i.GuildID, a)
+		if m, err := s.ChannelMessageSendComplex(i.ChannelID, msg, discordgo.WithContext(ctx)); err != nil {
+			h.logger.WarnContext(ctx, "posting queued auction start failed",
+				slog.String("auction_id", a.ID),
+				slog.Any("error", err),
+			)
+		} else {
+			h.recordAnnouncement(ctx, a.ID, m)
+		}
+		h.recordAnnouncement(ctx, a.ID, h.announce(ctx, s, i, msg))
+	}
+}
+
+// handleAuctionBuyout handles a click on the Buy now button of an auction.
+func (h *Handlers) handleAuctionBuyout(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	_, auctionID, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+
+	result, err := h.auctionMgr.BuyOut(ctx, auctionID, i.Member.User.ID)
+	if err != nil {
+		respondBid(ctx, s, i, fmt.Sprintf("Buy now failed: %s", userMessage(ctx, err)), err)
+		return err
+	}
+	respond(ctx, s, i, result.Message)
+	h.announce(ctx, s, i, &discordgo.MessageSend{Content: result.Message})
+	h.announceStarted(ctx, s, i, result.Started)
+	return nil
+}
+
+// handleAuctionQuickBid handles a click on a quick bid button of an
+// auction, which raises the highest bid by the amount in its custom ID.
+func (h *Handlers) handleAuctionQuickBid(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	_, rest, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	auctionID, raise, _ := strings.Cut(rest, ":")
+	by, err := strconv.Atoi(raise)
+	if err != nil || by < 1 {
+		respond(ctx, s, i, "This bid button is invalid.")
+		return errRejected
+	}
+
+	amount, err := h.auctionMgr.RaiseBid(ctx, auctionID, i.Member.User.ID, by)
+	if err != nil {
+		respondBid(ctx, s, i, fmt.Sprintf("Bid failed: %s", userMessage(ctx, err)), err)
+		return err
+	}
+	respond(ctx, s, i, fmt.Sprintf("Bid of **%d %s** placed on auction `%s`", amount, h.auctionMgr.Currency(auctionID), auctionID))
+	return nil
+}
+
+// handleAuctionCustomBid handles a click on the Custom button of an
+// auction by asking for the amount to bid.
+func (h *Handlers) handleAuctionCustomBid(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	_, auctionID, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: customBidModal + ":" + auctionID,
+			Title:    "Place a bid",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  "amount",
+						Label:     fmt.Sprintf("Amount (%s)", h.auctionMgr.Currency(auctionID)),
+						Style:     discordgo.TextInputShort,
+						Required:  true,
+						MaxLength: 9,
+					},
+				}},
+			},
+		},
+	}, discordgo.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("opening bid modal: %w", err)
+	}
+	return nil
+}
+
+// handleAuctionBidAmount handles the amount submitted in the modal opened
+// by the Custom button of an auction.
+func (h *Handlers) handleAuctionBidAmount(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	data := i.ModalSubmitData()
+	_, auctionID, _ := strings.Cut(data.CustomID, ":")
+	amount, err := strconv.Atoi(strings.TrimSpace(modalValue(data, "amount")))
+	if err != nil {
+		respond(ctx, s, i, "The amount must be a whole number.")
+		return errRejected
+	}
+	return h.bidOrConfirm(ctx, s, i, auctionID, amount)
+}
+
+// handleAuctionBidConfirm handles a click on the button confirming a bid,
+// placing the bid in its custom ID.
+func (h *Handlers) handleAuctionBidConfirm(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	_, rest, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	auctionID, value, _ := strings.Cut(rest, ":")
+	amount, err := strconv.Atoi(value)
+	if err != nil {
+		respond(ctx, s, i, "This confirm button is invalid.")
+		return errRejected
+	}
+	msg, err := h.placeBid(ctx, auctionID, i.Member.User.ID, amount)
+	updateMessage(ctx, s, i, &discordgo.MessageSend{Content: msg})
+	return err
+}
+
+// modalValue returns the value of the text input customID of a submitted
+// modal, or "" if it has none.
+func modalValue(data discordgo.ModalSubmitInteractionData, customID string) string {
+	for _, c := range data.Components {
+		row, ok := c.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, rc := range row.Components {
+			if input, ok := rc.(*discordgo.TextInput); ok && input.CustomID == customID {
+				return input.Value
+			}
+		}
+	}
+	return ""
+}
+
+// auctionEmbed returns an embed titled itemName, with the item's icon,
+// quality color, database link, and tooltip if it is in the catalog.
+func (h *Handlers) auctionEmbed(ctx context.Context, itemName string) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{Title: itemName}
+	if h.items == nil {
+		return embed
+	}
+	item, err := h.items.Lookup(ctx, itemName)
+	if err != nil {
+		h.logger.WarnContext(ctx, "looking up auction item failed", slog.String("item", itemName), slog.Any("error", err))
+		return embed
+	}
+	if item != nil {
+		embed.Title = item.Name
+		embed.Color = items.Color(item.Quality)
+		if item.IconURL != "" {
+			embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: item.IconURL}
+		}
+		h.addTooltip(ctx, embed, *item)
+	}
+	return embed
+}
+
+// addTooltip links the embed of an auction for item to the item's page in
+// a game database and adds its slot and stats.
+func (h *Handlers) addTooltip(ctx context.Context, embed *discordgo.MessageEmbed, item store.Item) {
+	if h.resolver == nil {
+		return
+	}
+	tip, err := h.resolver.Resolve(ctx, item)
+	if err != nil {
+		h.logger.WarnContext(ctx, "resolving auction item failed", slog.String("item", item.Name), slog.Any("error", err))
+		return
+	}
+	embed.URL = tip.URL
+	if tip.Slot != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Slot", Value: tip.Slot, Inline: true})
+	}
+	if len(tip.Stats) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Stats", Value: strings.Join(tip.Stats, "\n"), Inline: true})
+	}
+}
+
+func (h *Handlers) handleBid(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	opts := i.ApplicationCommandData().Options
+	return h.bidOrConfirm(ctx, s, i, opts[0].StringValue(), int(opts[1].IntValue()))
+}
+
+// bidOrConfirm bids amount on the auction for the member who sent i, or,
+// if the bid is more of their balance than the guild's confirm_bid_percent
+// setting allows, asks them privately to confirm it first, so that a typo
+// does not cost them their savings.
+func (h *Handlers) bidOrConfirm(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, auctionID string, amount int) error {
+	confirm, balance, err := h.auctionMgr.ConfirmBid(ctx, auctionID, i.Member.User.ID, amount)
+	if err != nil {
+		respondBid(ctx, s, i, fmt.Sprintf("Bid failed: %s", userMessage(ctx, err)), err)
+		return err
+	}
+	if !confirm {
+		msg, err := h.placeBid(ctx, auctionID, i.Member.User.ID, amount)
+		respondBid(ctx, s, i, msg, err)
+		return err
+	}
+	currency := h.auctionMgr.Currency(auctionID)
+	respondMessage(ctx, s, i, &discordgo.MessageSend{
+		Content: fmt.Sprintf("Confirm bid of **%d %s** on auction `%s`? That is %d%% of your %d %s.",
+			amount, currency, auctionID, amount*100/balance, balance, currency),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    fmt.Sprintf("Confirm bid of %d %s", amount, currency),
+					Style:    discordgo.DangerButton,
+					CustomID: fmt.Sprintf("%s:%s:%d", confirmBidAction, auctionID, amount),
+				},
+			}},
+		},
+		Flags: discordgo.MessageFlagsEphemeral,
+	})
+	return nil
+}
+
+// respondBid replies to i with msg, the outcome of a bid that failed with
+// err or succeeded if err is nil. A loot ban is the bidder's business, so
+// it is told only to them.
+func respondBid(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, msg string, err error) {
+	if errors.Is(err, auction.ErrLootBanned) {
+		respondPrivate(ctx, s, i, msg)
+		return
+	}
+	respond(ctx, s, i, msg)
+}
+
+// placeBid bids amount on the auction for the player registered as
+// discordID.
+func (h *Handlers) placeBid(ctx context.Context, auctionID, discordID string, amount int) (string, error) {
+	if err := h.auctionMgr.PlaceBid(ctx, auctionID, discordID, amount); err != nil {
+		return fmt.Sprintf("Bid failed: %s", userMessage(ctx, err)), err
+	}
+	return fmt.Sprintf("Bid of **%d %s** placed on auction `%s`", amount, h.auctionMgr.Currency(auctionID), auctionID), nil
+}
+
+func (h *Handlers) handleAuctionClose(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	opts := i.ApplicationCommandData().Options
+	auctionID := opts[0].StringValue()
+
+	result, err := h.auctionMgr.CloseAuction(ctx, auctionID)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to close auction: %s", userMessage(ctx, err)))
+		return err
+	}
+	if !result.RollUntil.IsZero() {
+		msg := &discordgo.MessageSend{
+			Content: fmt.Sprintf("Auction `%s` had no bids. Click **Roll** to roll for the item; the roll ends <t:%d:R>.", auctionID, result.RollUntil.Unix()),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Roll",
+						Style:    discordgo.PrimaryButton,
+						CustomID: rollAction + ":" + auctionID,
+					},
+				}},
+			},
+		}
+		respondMessage(ctx, s, i, msg)
+		h.announce(ctx, s, i, msg)
+		timer := clock.NewTimer(h.clock, result.RollUntil.Sub(h.clock.Now()))
+		go func() {
+			<-timer.C()
+			h.endRoll(s, i, auctionID)
+		}()
+		return nil
+	}
+	msg := result.Message
+	if msg == "" {
+		msg = announce.NoBids.Render(h.template(ctx, i.GuildID, func(gs settings.Settings) string { return gs.NoBidsMessage }), announce.Vars{
+			"item": result.ItemName,
+			"id":   auctionID,
+		})
+	}
+	respond(ctx, s, i, msg)
+	h.announce(ctx, s, i, &discordgo.MessageSend{Content: msg})
+	h.announceStarted(ctx, s, i, result.Started)
+	return nil
+}
+
+func (h *Handlers) handleAuctionPause(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	auctionID := i.ApplicationCommandData().Options[0].StringValue()
+
+	if err := h.auctionMgr.PauseAuction(ctx, auctionID); err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to pause auction: %s", userMessage(ctx, err)))
+		return err
+	}
+	msg := fmt.Sprintf("Auction `%s` paused. Bids are rejected until it is resumed.", auctionID)
+	respond(ctx, s, i, msg)
+	h.announce(ctx, s, i, &discordgo.MessageSend{Content: msg})
+	return nil
+}
+
+func (h *Handlers) handleAuctionResume(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	auctionID := i.ApplicationCommandData().Options[0].StringValue()
+
+	endsAt, err := h.auctionMgr.ResumeAuction(ctx, auctionID)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to resume auction: %s", userMessage(ctx, err)))
+		return err
+	}
+	msg := fmt.Sprintf("Auction `%s` resumed; it now ends <t:%d:R>.", auctionID, endsAt.Unix())
+	respond(ctx, s, i, msg)
+	h.announce(ctx, s, i, &discordgo.MessageSend{Content: msg})
+	return nil
+}
+
+// handleAuctionRoll handles a click on the Roll button of an auction.
+func (h *Handlers) handleAuctionRoll(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	_, auctionID, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+
+	roll, err := h.auctionMgr.Roll(ctx, auctionID, i.Member.User.ID)
+	if err != nil {
+		respondBid(ctx, s, i, fmt.Sprintf("Roll failed: %s", userMessage(ctx, err)), err)
+		return err
+	}
+	respond(ctx, s, i, fmt.Sprintf("<@%s> rolls **%d** (1-100) for auction `%s`.", i.Member.User.ID, roll, auctionID))
+	return nil
+}
+
+// endRoll closes a rolling auction when its roll ends and posts the winner
+// in the channel of i, the interaction that started the roll. Auctions an
+// officer closed in the meantime are left alone, as is everything while the
+// bot hands over; the next leader keeps the auction rolling until an
+// officer closes it.
+func (h *Handlers) endRoll(s *discordgo.Session, i *discordgo.InteractionCreate, auctionID string) {
+	if !h.track() {
+		return
+	}
+	defer h.inflight.Done()
+
+	ctx, span := h.tracer.Start(context.Background(), "endRoll",
+		trace.WithAttributes(attribute.String("auction_id", auctionID)),
+	)
+	defer span.End()
+	ctx = metrics.WithGuild(ctx, i.GuildID)
+	ctx, cancel := context.WithTimeout(ctx, h.deadline("auction-close"))
+	defer cancel()
+
+	result, err := h.auctionMgr.CloseAuction(ctx, auctionID)
+	if err != nil {
+		if !errors.Is(err, store.ErrAuctionNotFound) {
+			h.logger.WarnContext(ctx, "ending auction roll failed",
+				slog.String("auction_id", auctionID),
+				slog.Any("error", err),
+			)
+		}
+		return
+	}
+	msg := &discordgo.MessageSend{Content: result.Message}
+	if msg.Content == "" {
+		msg.Content = fmt.Sprintf("Auction `%s` closed: nobody rolled.", auctionID)
+	}
+	if _, err := s.ChannelMessageSendComplex(i.ChannelID, msg, discordgo.WithContext(ctx)); err != nil {
+		h.logger.WarnContext(ctx, "posting auction roll result failed",
+			slog.String("auction_id", auctionID),
+			slog.Any("error", err),
+		)
+	}
+	h.announce(ctx, s, i, msg)
+	h.announceStarted(ctx, s, i, result.Started)
+}
+
+func (h *Handlers) handleAuctionList(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	msg, err := h.openAuctions(ctx)
+	respond(ctx, s, i, msg)
+	return err
+}
+
+// openAuctions lists the open auctions, then the queued ones.
+func (h *Handlers) openAuctions(ctx context.Context) (string, error) {
+	auctions, err := h.auctionMgr.ListOpenAuctions(ctx)
+	if err != nil {
+		return fmt.Sprintf("Error listing auctions: %s", userMessage(ctx, err)), err
+	}
+	if len(auctions) == 0 {
+		return "No open auctions.", nil
+	}
+	// Queued auctions go after the open ones, in the order they will
+	// start, and scheduled auctions last, by their start time.
+	rank := func(a auction.State) int {
+		switch a.Status {
+		case "queued":
+			return 1
+		case "scheduled":
+			return 2
+		}
+		return 0
+	}
+	slices.SortStableFunc(auctions, func(a, b auction.State) int {
+		if r := rank(a) - rank(b); r != 0 || a.Status != "scheduled" {
+			return r
+		}
+		return a.StartsAt.Compare(b.StartsAt)
+	})
+	var b strings.Builder
+	b.WriteString("**Open auctions:**\n")
+	queued := 0
+	for _, a := range auctions {
+		line := fmt.Sprintf("`%s` **%s** — min bid %d, no bids\n", a.ID, a.ItemName, a.MinBid)
+		if a.Status == "queued" {
+			queued++
+			line = fmt.Sprintf("`%s` **%s** — queued, #%d in line\n", a.ID, a.ItemName, queued)
+		}
+		if a.Status == "scheduled" {
+			line = fmt.Sprintf("`%s` **%s** — scheduled, opens <t:%d:R>\n", a.ID, a.ItemName, a.StartsAt.Unix())
+		}
+		if h := a.HighestBid(); h != nil {
+			line = fmt.Sprintf("`%s` **%s** — %d bids, highest %d\n", a.ID, a.ItemName, len(a.Bids), h.Amount)
+		}
+		if a.Status == "paused" {
+			line = strings.TrimSuffix(line, "\n") + " (paused)\n"
+		}
+		if b.Len()+len(line) > maxMessageLength {
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String(), nil
+}
+
+// handleAuctionInfo shows an auction's status, time remaining, and bid
+// history, replayed from its events so that ended auctions can be shown
+// too.
+func (h *Handlers) handleAuctionInfo(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	auctionID := i.ApplicationCommandData().Options[0].StringValue()
+
+	embed, err := h.auctionInfo(ctx, auctionID)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error loading auction: %s", userMessage(ctx, err)))
+		return err
+	}
+	respondMessage(ctx, s, i, &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}})
+	return nil
+}
+
+// auctionInfo returns an embed of the auction auctionID's status and bid
+// history, replayed from its events.
+func (h *Handlers) auctionInfo(ctx context.Context, auctionID string) (*discordgo.MessageEmbed, error) {
+	a, err := h.auctionMgr.ReplayAuction(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string)
+	if h.dkpMgr != nil {
+		players, err := h.dkpMgr.ListPlayers(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing players: %w", err)
+		}
+		for _, p := range players {
+			names[p.ID] = p.CharacterName
+		}
+	}
+	name := func(id string) string {
+		if n, ok := names[id]; ok {
+			return n
+		}
+		return id
+	}
+
+	st := a.State()
+	embed := h.auctionEmbed(ctx, st.ItemName)
+	status := st.Status
+	switch st.Status {
+	case "open":
+		status = fmt.Sprintf("open, ends <t:%d:R>", a.EndsAt().Unix())
+	case "paused":
+		status = "paused"
+	case "scheduled":
+		status = fmt.Sprintf("scheduled, opens <t:%d:F>", st.StartsAt.Unix())
+	case "rolling":
+		status = fmt.Sprintf("rolling, the roll ends <t:%d:R>", st.RollUntil.Unix())
+	case "closed":
+		if st.ReserveNotMet {
+			status = "closed without a winner: the reserve was not met"
+		} else if r := a.HighestRoll(); r != nil {
+			status = fmt.Sprintf("won by **%s** with a roll of %d", name(r.PlayerID), r.Value)
+		} else if w := a.HighestBid(); w != nil {
+			status = fmt.Sprintf("won by **%s** for %d %s", name(w.PlayerID), w.Amount, st.Currency())
+		} else {
+			status = "closed without a winner"
+		}
+	}
+	embed.Fields = []*discordgo.MessageEmbedField{
+		{Name: "Status", Value: status},
+		{Name: "Min bid", Value: strconv.Itoa(st.MinBid), Inline: true},
+		{Name: "Min increment", Value: strconv.Itoa(st.MinIncrement), Inline: true},
+	}
+	if st.Buyout > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Buyout", Value: strconv.Itoa(st.Buyout), Inline: true})
+	}
+	if st.RaidID != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Raid", Value: "`" + st.RaidID + "`", Inline: true})
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "ID: `%s`\n", st.ID)
+	if len(st.Bids) == 0 && len(st.Rolls) == 0 {
+		b.WriteString("No bids.")
+	}
+	for n, bid := range st.Bids {
+		line := fmt.Sprintf("<t:%d:T> **%s** — %d %s", bid.Time.Unix(), name(bid.PlayerID), bid.Amount, st.Currency())
+		if slices.Contains(st.Skipped, bid.PlayerID) {
+			line += " (skipped: could no longer afford it)"
+		}
+		line += "\n"
+		if b.Len()+len(line) > maxEmbedDescription-len("…and 1000 later bids\n") {
+			fmt.Fprintf(&b, "…and %d later bids\n", len(st.Bids)-n)
+			break
+		}
+		b.WriteString(line)
+	}
+	for _, r := range st.Rolls {
+		line := fmt.Sprintf("<t:%d:T> **%s** rolled %d\n", r.Time.Unix(), name(r.PlayerID), r.Value)
+		if b.Len()+len(line) > maxEmbedDescription {
+			break
+		}
+		b.WriteString(line)
+	}
+	embed.Description = b.String()
+	return embed, nil
+}
+
+func (h *Handlers) handleAudit(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	q := event.Query{Limit: 25}
+	hours := 24
+	asCSV := false
+
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "type":
+			q.Types = auditTypeGroups[opt.StringValue()]
+		case "player":
+			p, err := h.dkpMgr.GetPlayer(ctx, opt.UserValue(s).ID)
+			if err != nil {
+				respondPrivate(ctx, s, i, "Player is not registered.")
+				return err
+			}
+			q.Player = p.ID
+		case "actor":
+			q.Actor = opt.UserValue(s).ID
+		case "hours":
+			hours = int(opt.IntValue())
+		case "csv":
+			asCSV = opt.BoolValue()
+		}
+	}
+	if hours < 1 {
+		respondPrivate(ctx, s, i, "Hours must be at least 1.")
+		return errRejected
+	}
+	q.Since = h.clock.Now().Add(-time.Duration(hours) * time.Hour)
+	if asCSV {
+		q.Limit = 0
+	}
+
+	entries, err := h.auditLog.Query(ctx, q)
+	if err != nil {
+		respondPrivate(ctx, s, i, fmt.Sprintf("Error querying audit log: %s", userMessage(ctx, err)))
+		return err
+	}
+	if len(entries) == 0 {
+		respondPrivate(ctx, s, i, fmt.Sprintf("No matching events in the last %d hours.", hours))
+		return nil
+	}
+
+	if asCSV {
+		var buf bytes.Buffer
+		if err := audit.WriteCSV(&buf, entries); err != nil {
+			respondPrivate(ctx, s, i, fmt.Sprintf("Error exporting audit log: %s", userMessage(ctx, err)))
+			return err
+		}
+		respondPrivateFile(ctx, s, i, fmt.Sprintf("Exported %d events.", len(entries)), "audit.csv", "text/csv", &buf)
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("**Audit log:**\n")
+	for _, e := range entries {
+		summary := e.Summary
+		if slices.Contains(auditTypeGroups["dkp"], e.Type) {
+			// Show the IDs of DKP changes so that they can be undone.
+			summary += fmt.Sprintf(" (`%s`)", e.ID)
+		}
+		line := fmt.Sprintf("<t:%d:f> %s\n", e.Time.Unix(), summary)
+		if b.Len()+len(line) > maxMessageLength {
+			break
+		}
+		b.WriteString(line)
+	}
+	respondPrivate(ctx, s, i, b.String())
+	return nil
+}
+
+func (h *Handlers) handleDKPExport(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	var kind export.Kind
+	var from, to string
+	format := export.CSV
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "kind":
+			kind = export.Kind(opt.StringValue())
+		case "from":
+			from = opt.StringValue()
+		case "to":
+			to = opt.StringValue()
+		case "format":
+			format = export.Format(opt.StringValue())
+		}
+	}
+
+	if format.IsAddon() {
+		if kind != export.Standings {
+			respond(ctx, s, i, "Addon formats only support standings.")
+			return errRejected
+		}
+		return h.runJob(ctx, s, i, "Export of "+string(kind), func(ctx context.Context, _ func(string)) (jobs.Result, error) {
+			var buf bytes.Buffer
+			if err := h.exporter.WriteAddon(ctx, &buf, format); err != nil {
+				return jobs.Result{Message: fmt.Sprintf("Error exporting %s: %s", kind, userMessage(ctx, err))}, err
+			}
+			return jobs.Result{
+				Message: "Copy this file into your WTF/Account/<name>/SavedVariables folder.",
+				File:    &jobs.File{Name: export.Filename(kind, format), ContentType: "text/plain", Data: buf.Bytes()},
+			}, nil
+		})
+	}
+
+	r, err := export.ParseRange(from, to)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Invalid date range: %s", userMessage(ctx, err)))
+		return err
+	}
+	return h.runJob(ctx, s, i, "Export of "+string(kind), func(ctx context.Context, _ func(string)) (jobs.Result, error) {
+		var buf bytes.Buffer
+		if err := h.exporter.Write(ctx, &buf, kind, r); err != nil {
+			return jobs.Result{Message: fmt.Sprintf("Error exporting %s: %s", kind, userMessage(ctx, err))}, err
+		}
+		return jobs.Result{
+			Message: fmt.Sprintf("Exported %s.", kind),
+			File:    &jobs.File{Name: export.Filename(kind, format), ContentType: "text/csv", Data: buf.Bytes()},
+		}, nil
+	})
+}
+
+// maxImportSize bounds the size of an uploaded EQDKP export.
+const maxImportSize = 10 << 20
+
+// handleImportEQDKP runs the EQDKP import as a two-step flow: without
+// confirm it previews the import, listing characters that will not be linked
+// to a Discord user so that those members can /register first.
+func (h *Handlers) handleImportEQDKP(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	data := i.ApplicationCommandData()
+	var attachmentID string
+	confirm := false
+	for _, opt := range data.Options {
+		switch opt.Name {
+		case "file":
+			attachmentID, _ = opt.Value.(string)
+		case "confirm":
+			confirm = opt.BoolValue()
+		}
+	}
+	attachment, ok := data.Resolved.Attachments[attachmentID]
+	if !ok {
+		respond(ctx, s, i, "No export file attached.")
+		return errRejected
+	}
+	if attachment.Size > maxImportSize {
+		respond(ctx, s, i, fmt.Sprintf("Export is too large (max %d MB).", maxImportSize>>20))
+		return errRejected
+	}
+
+	// Downloading and importing can exceed the interaction response
+	// deadline.
+	name := "EQDKP import preview"
+	if confirm {
+		name = "EQDKP import"
+	}
+	return h.runJob(ctx, s, i, name, func(ctx context.Context, progress func(string)) (jobs.Result, error) {
+		return h.importEQDKP(ctx, attachment, confirm, progress)
+	})
+}
+
+// importEQDKP imports, or previews the import of, the uploaded EQDKP
+// export.
+func (h *Handlers) importEQDKP(ctx context.Context, attachment *discordgo.MessageAttachment, confirm bool, progress func(string)) (jobs.Result, error) {
+	progress("Downloading the export…")
+	body, err := download(ctx, attachment)
+	if err != nil {
+		return jobs.Result{Message: fmt.Sprintf("Failed to download export: %s", userMessage(ctx, err))}, err
+	}
+	defer body.Close()
+
+	dump, err := eqdkp.Parse(body)
+	if err != nil {
+		return jobs.Result{Message: fmt.Sprintf("Could not read export: %s", userMessage(ctx, err))}, err
+	}
+
+	progress(fmt.Sprintf("Importing %d characters…", len(dump.Players)))
+	report, err := h.importer.Import(ctx, dump, nil, !confirm)
+	if err != nil {
+		return jobs.Result{Message: fmt.Sprintf("Import failed: %s", userMessage(ctx, err))}, err
+	}
+
+	var b strings.Builder
+	if confirm {
+		b.WriteString("**EQDKP import complete:**\n")
+	} else {
+		b.WriteString("**EQDKP import preview:**\n")
+	}
+	fmt.Fprintf(&b, "%d characters (%d linked to registered players, %d new)\n", report.Players, report.Linked, report.Created)
+	fmt.Fprintf(&b, "%d raids, %d raid awards, %d items, %d adjustments\n", report.Raids, report.Awards, report.Items, report.Adjustments)
+	if len(report.Unlinked) > 0 {
+		line := fmt.Sprintf("Not linked to a Discord user: %s\n", strings.Join(report.Unlinked, ", "))
+		if b.Len()+len(line) > maxMessageLength-200 {
+			line = fmt.Sprintf("%d characters are not linked to a Discord user.\n", len(report.Unlinked))
+		}
+		b.WriteString(line)
+	}
+	if !confirm {
+		b.WriteString("Members who /register with the same character name before the import keep their Discord link. Run again with `confirm: True` to import.")
+	}
+	return jobs.Result{Message: b.String()}, nil
+}
+
+// handleImportPlayers registers players with starting balances from an
+// uploaded CSV file. As for /import-eqdkp, without confirm it only previews
+// the import, so that officers can fix the rows it reports first.
+func (h *Handlers) handleImportPlayers(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.onboarder == nil {
+		respond(ctx, s, i, "Player imports are not configured.")
+		return errRejected
+	}
+	data := i.ApplicationCommandData()
+	var attachmentID string
+	confirm := false
+	for _, opt := range data.Options[0].Options {
+		switch opt.Name {
+		case "file":
+			attachmentID, _ = opt.Value.(string)
+		case "confirm":
+			confirm = opt.BoolValue()
+		}
+	}
+	attachment, ok := data.Resolved.Attachments[attachmentID]
+	if !ok {
+		respond(ctx, s, i, "No file attached.")
+		return errRejected
+	}
+	if attachment.Size > maxImportSize {
+		respond(ctx, s, i, fmt.Sprintf("File is too large (max %d MB).", maxImportSize>>20))
+		return errRejected
+	}
+
+	name := "Player import preview"
+	if confirm {
+		name = "Player import"
+	}
+	return h.runJob(ctx, s, i, name, func(ctx context.Context, progress func(string)) (jobs.Result, error) {
+		return h.importPlayers(ctx, attachment, confirm, progress)
+	})
+}
+
+// importPlayers imports, or previews the import of, the uploaded CSV file
+// of players.
+func (h *Handlers) importPlayers(ctx context.Context, attachment *discordgo.MessageAttachment, confirm bool, progress func(string)) (jobs.Result, error) {
+	progress("Downloading the file…")
+	body, err := download(ctx, attachment)
+	if err != nil {
+		return jobs.Result{Message: fmt.Sprintf("Failed to download file: %s", userMessage(ctx, err))}, err
+	}
+	defer body.Close()
+
+	f, err := onboard.Parse(body)
+	if err != nil {
+		return jobs.Result{Message: fmt.Sprintf("Could not read file: %s", userMessage(ctx, err))}, err
+	}
+	progress(fmt.Sprintf("Importing %d players…", len(f.Rows)))
+	report, err := h.onboarder.Import(ctx, f, !confirm)
+	if err != nil {
+		return jobs.Result{Message: fmt.Sprintf("Import failed: %s", userMessage(ctx, err))}, err
+	}
+
+	var b strings.Builder
+	if confirm {
+		fmt.Fprintf(&b, "**Player import complete:** registered %d players with %d DKP in all.\n", report.Created, report.DKP)
+	} else {
+		fmt.Fprintf(&b, "**Player import preview:** %d players with %d DKP in all would be registered.\n", report.Created, report.DKP)
+	}
+	if len(report.Errors) > 0 {
+		fmt.Fprintf(&b, "%d rows are skipped:\n", len(report.Errors))
+		for n, e := range report.Errors {
+			line := fmt.Sprintf("- %s\n", e)
+			if b.Len()+len(line) > maxMessageLength-200 {
+				fmt.Fprintf(&b, "… and %d more\n", len(report.Errors)-n)
+				break
+			}
+			b.WriteString(line)
+		}
+	}
+	if !confirm {
+		b.WriteString("Run again with `confirm: True` to import.")
+	}
+	return jobs.Result{Message: b.String()}, nil
+}
+
+// download fetches an uploaded attachment, reading at most maxImportSize
+// bytes of it.
+func download(ctx context.Context, attachment *discordgo.MessageAttachment) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, attachment.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(resp.Body, maxImportSize), resp.Body}, nil
+}
+
+// handleGuildMerge prepares the import of another guild's members and
+// balances and shows its preview, whose components decide which characters
+// named like a registered player are them and apply or cancel the merge.
+func (h *Handlers) handleGuildMerge(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.merger == nil {
+		respond(ctx, s, i, "Guild merges are not configured.")
+		return errRejected
+	}
+	data := i.ApplicationCommandData()
+	var attachmentID string
+	ratio := 1.0
+	for _, opt := range data.Options[0].Options {
+		switch opt.Name {
+		case "file":
+			attachmentID, _ = opt.Value.(string)
+		case "ratio":
+			ratio = opt.FloatValue()
+		}
+	}
+	attachment, ok := data.Resolved.Attachments[attachmentID]
+	if !ok {
+		respond(ctx, s, i, "No file attached.")
+		return errRejected
+	}
+	if attachment.Size > maxImportSize {
+		respond(ctx, s, i, fmt.Sprintf("File is too large (max %d MB).", maxImportSize>>20))
+		return errRejected
+	}
+
+	edit := respondLater(ctx, s, i)
+	body, err := download(ctx, attachment)
+	if err != nil {
+		edit(fmt.Sprintf("Failed to download file: %s", userMessage(ctx, err)))
+		return err
+	}
+	defer body.Close()
+
+	members, err := merge.Parse(body)
+	if err != nil {
+		edit(fmt.Sprintf("Could not read file: %s", userMessage(ctx, err)))
+		return err
+	}
+	plan, err := h.merger.Prepare(ctx, members, ratio)
+	if err != nil {
+		edit(fmt.Sprintf("Guild merge failed: %s", userMessage(ctx, err)))
+		return err
+	}
+	msg := merge.Preview(plan)
+	_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Embeds:     &msg.Embeds,
+		Components: &msg.Components,
+	}, discordgo.WithContext(ctx))
+	return nil
+}
+
+// handleGuildMergeSeparate handles the selection of the duplicates of a
+// merge preview to import as new players, and updates the preview.
+func (h *Handlers) handleGuildMergeSeparate(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	data := i.MessageComponentData()
+	_, rest, _ := strings.Cut(data.CustomID, ":")
+	mergeID, pageArg, _ := strings.Cut(rest, ":")
+	if h.merger == nil {
+		respond(ctx, s, i, "Guild merges are not configured.")
+		return errRejected
+	}
+	page, err := strconv.Atoi(pageArg)
+	if err != nil {
+		respond(ctx, s, i, "This menu is invalid.")
+		return errRejected
+	}
+	plan, err := h.merger.Plan(mergeID)
+	if err != nil {
+		respond(ctx, s, i, userMessage(ctx, err))
+		return err
+	}
+	separate := make([]int, 0, len(data.Values))
+	for _, v := range data.Values {
+		if n, err := strconv.Atoi(v); err == nil {
+			separate = append(separate, n)
+		}
+	}
+	if plan, err = h.merger.Separate(mergeID, merge.Page(plan, page), separate); err != nil {
+		respond(ctx, s, i, userMessage(ctx, err))
+		return err
+	}
+	updateMessage(ctx, s, i, merge.Preview(plan))
+	return nil
+}
+
+// handleGuildMergeApply handles a click on the Apply button of a merge
+// preview.
+func (h *Handlers) handleGuildMergeApply(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	_, mergeID, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	if h.merger == nil {
+		respond(ctx, s, i, "Guild merges are not configured.")
+		return errRejected
+	}
+	if _, err := h.merger.Plan(mergeID); err != nil {
+		respond(ctx, s, i, userMessage(ctx, err))
+		return err
+	}
+
+	// Applying a large merge can exceed the interaction response deadline.
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	}, discordgo.WithContext(ctx))
+	report, err := h.merger.Apply(ctx, mergeID)
+	if errors.Is(err, merge.ErrPlanNotFound) {
+		// Another officer applied or canceled it meanwhile.
+		respondFailure(ctx, s, i, userMessage(ctx, err))
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Guild merge `%s` applied by <@%s>: %d characters merged into registered players, %d imported as new players, **%+d DKP** in all.",
+		mergeID, i.Member.User.ID, report.Merged, report.Created, report.DKP)
+	if len(report.Skipped) > 0 {
+		fmt.Fprintf(&b, "\nSkipped, as their player is archived: %s", strings.Join(report.Skipped, ", "))
+	}
+	if err != nil {
+		fmt.Fprintf(&b, "\nThe merge stopped part way: %s", userMessage(ctx, err))
+	}
+	content, components := b.String(), []discordgo.MessageComponent{}
+	embeds := []*discordgo.MessageEmbed{}
+	_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:    &content,
+		Embeds:     &embeds,
+		Components: &components,
+	}, discordgo.WithContext(ctx))
+	return err
+}
+
+// handleGuildMergeCancel handles a click on the Cancel button of a merge
+// preview.
+func (h *Handlers) handleGuildMergeCancel(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	_, mergeID, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	if h.merger != nil {
+		h.merger.Cancel(mergeID)
+	}
+	updateMessage(ctx, s, i, &discordgo.MessageSend{Content: fmt.Sprintf("Guild merge `%s` canceled by <@%s>.", mergeID, i.Member.User.ID)})
+	return nil
+}
+
+// handleWCLImport previews, or with confirm applies, the DKP awards for the
+// participants and boss kills of a log report.
+func (h *Handlers) handleWCLImport(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.attendance == nil {
+		respond(ctx, s, i, "The Warcraft Logs integration is not configured.")
+		return errRejected
+	}
+	var reportURL string
+	confirm := false
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "url":
+			reportURL = opt.StringValue()
+		case "confirm":
+			confirm = opt.BoolValue()
+		}
+	}
+
+	name := "Warcraft Logs import preview"
+	if confirm {
+		name = "Warcraft Logs import"
+	}
+	return h.runJob(ctx, s, i, name, func(ctx context.Context, progress func(string)) (jobs.Result, error) {
+		return h.importWCL(ctx, reportURL, confirm, progress)
+	})
+}
+
+// importWCL awards, or previews awarding, attendance DKP for the Warcraft
+// Logs report at reportURL.
+func (h *Handlers) importWCL(ctx context.Context, reportURL string, confirm bool, progress func(string)) (jobs.Result, error) {
+	progress("Loading the report…")
+	plan, err := h.attendance.Preview(ctx, reportURL)
+	if err != nil {
+		return jobs.Result{Message: fmt.Sprintf("Failed to load report: %s", userMessage(ctx, err))}, err
+	}
+	if len(plan.Awards) == 0 {
+		return jobs.Result{Message: fmt.Sprintf("No registered characters found in **%s**.", plan.Report.Title)}, nil
+	}
+
+	var b strings.Builder
+	if confirm {
+		progress(fmt.Sprintf("Awarding DKP to %d players…", len(plan.Awards)))
+		n, err := h.attendance.Apply(ctx, plan)
+		if err != nil {
+			return jobs.Result{Message: fmt.Sprintf("Awarding DKP failed after %d players: %s", n, userMessage(ctx, err))}, err
+		}
+		fmt.Fprintf(&b, "**Awarded %d DKP to %d players** for %s\n", plan.Awards[0].Amount, n, plan.Reason)
+	} else {
+		fmt.Fprintf(&b, "**Preview:** %d DKP each to %d players for %s\n", plan.Awards[0].Amount, len(plan.Awards), plan.Reason)
+	}
+	if len(plan.Report.Kills) > 0 {
+		fmt.Fprintf(&b, "Kills: %s\n", strings.Join(plan.Report.Kills, ", "))
+	}
+	fmt.Fprintf(&b, "Roles: %s\n", roleCounts(plan.Awards))
+	if len(plan.Unmatched) > 0 {
+		line := fmt.Sprintf("Not registered: %s\n", strings.Join(plan.Unmatched, ", "))
+		if b.Len()+len(line) > maxMessageLength-200 {
+			line = fmt.Sprintf("%d participants are not registered.\n", len(plan.Unmatched))
+		}
+		b.WriteString(line)
+	}
+	if !confirm {
+		b.WriteString("Run again with `confirm: True` to award.")
+	}
+	return jobs.Result{Message: b.String()}, nil
+}
+
+// handleDeadLetter reports on events that failed to persist and are
+// waiting to be retried, and discards batches that are no longer retried.
+func (h *Handlers) handleDeadLetter(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.deadLetter == nil {
+		respond(ctx, s, i, "The dead-letter queue is not configured.")
+		return errRejected
+	}
+	if sub := i.ApplicationCommandData().Options[0]; sub.Name == "discard" {
+		l, err := h.deadLetter.Discard(ctx, sub.Options[0].StringValue())
+		if err != nil {
+			respond(ctx, s, i, fmt.Sprintf("Failed to discard: %s", userMessage(ctx, err)))
+			return err
+		}
+		respond(ctx, s, i, fmt.Sprintf("Discarded %d events of batch `%s`. They will not be persisted.", len(l.Events), l.ID))
+		return nil
+	}
+
+	st := h.deadLetter.Status()
+	if st.Letters == 0 {
+		respond(ctx, s, i, "**Dead-letter queue:** empty. All events are persisted.")
+		return nil
+	}
+	msg := fmt.Sprintf("**Dead-letter queue:** %d events in %d batches, waiting since <t:%d:R>, retried up to %d times.\nLast error: %s",
+		st.Events, st.Letters, st.Oldest.Unix(), st.MaxAttempts, st.LastError)
+	if st.GivenUp > 0 {
+		msg += fmt.Sprintf("\n%d batches are no longer retried and hold back later events of their aggregates. Discard them with /deadletter discard:", st.GivenUp)
+		for _, l := range h.deadLetter.GivenUp() {
+			msg += fmt.Sprintf("\n`%s`: %d events, %s", l.ID, len(l.Events), l.LastError)
+		}
+	}
+	if len(msg) > maxMessageLength {
+		msg = msg[:maxMessageLength]
+	}
+	respond(ctx, s, i, msg)
+	return nil
+}
+
+// handleSettings shows and changes the guild settings.
+func (h *Handlers) handleSettings(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.settings == nil {
+		respond(ctx, s, i, "Guild settings are not configured.")
+		return errRejected
+	}
+	sub := i.ApplicationCommandData().Options[0]
+	var key, value string
+	for _, opt := range sub.Options {
+		switch opt.Name {
+		case "key":
+			key = opt.StringValue()
+		case "value":
+			value = opt.StringValue()
+		}
+	}
+
+	switch sub.Name {
+	case "set":
+		if _, err := h.settings.Set(ctx, i.GuildID, key, value, i.Member.User.ID); err != nil {
+			respond(ctx, s, i, fmt.Sprintf("Failed to change setting: %s", userMessage(ctx, err)))
+			return err
+		}
+	case "reset":
+		if _, err := h.settings.Reset(ctx, i.GuildID, key); err != nil {
+			respond(ctx, s, i, fmt.Sprintf("Failed to reset setting: %s", userMessage(ctx, err)))
+			return err
+		}
+	}
+
+	entries, err := h.settings.List(ctx, i.GuildID)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error loading settings: %s", userMessage(ctx, err)))
+		return err
+	}
+	var b strings.Builder
+	switch sub.Name {
+	case "set":
+		fmt.Fprintf(&b, "Changed `%s`.\n", key)
+	case "reset":
+		fmt.Fprintf(&b, "Reset `%s` to its default.\n", key)
+	}
+	b.WriteString("**Settings:**\n")
+	for _, e := range entries {
+		fmt.Fprintf(&b, "`%s` = %s", e.Key, formatSetting(e))
+		if e.Default {
+			b.WriteString(" (default)\n")
+		} else {
+			fmt.Fprintf(&b, " (set by <@%s> <t:%d:R>)\n", e.UpdatedBy, e.UpdatedAt.Unix())
+		}
+	}
+	respond(ctx, s, i, b.String())
+	return nil
+}
+
+// formatSetting renders a setting value, with roles and channels as
+// mentions.
+func formatSetting(e settings.Entry) string {
+	if e.Value == "" {
+		return "none"
+	}
+	switch e.Key {
+	case settings.AdminRoles:
+		roles := strings.Split(e.Value, ",")
+		for n, r := range roles {
+			roles[n] = "<@&" + r + ">"
+		}
+		return strings.Join(roles, ", ")
+	case settings.LootChannel, settings.LeaderboardChannel, settings.OfficerChannel:
+		return "<#" + e.Value + ">"
+	}
+	return e.Value
+}
+
+// wishlistReportSize is how many items /wishlist-report lists.
+const wishlistReportSize = 25
+
+func (h *Handlers) handleWishlist(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.wishlist == nil {
+		respond(ctx, s, i, "Wishlists are not configured.")
+		return errRejected
+	}
+	sub := i.ApplicationCommandData().Options[0]
+	var item string
+	for _, opt := range sub.Options {
+		if opt.Name == "item" {
+			item = opt.StringValue()
+		}
+	}
+	discordID := i.Member.User.ID
+
+	var err error
+	switch sub.Name {
+	case "add":
+		var name string
+		if name, err = h.wishlist.Add(ctx, discordID, item); err == nil {
+			respond(ctx, s, i, fmt.Sprintf("Added **%s** to your wishlist. You will get a direct message when an auction for it starts.", name))
+			return nil
+		}
+	case "remove":
+		if err = h.wishlist.Remove(ctx, discordID, item); err == nil {
+			respond(ctx, s, i, fmt.Sprintf("Removed **%s** from your wishlist.", item))
+			return nil
+		}
+	default:
+		var entries []store.WishlistEntry
+		if entries, err = h.wishlist.List(ctx, discordID); err == nil {
+			if len(entries) == 0 {
+				respond(ctx, s, i, "Your wishlist is empty. Add items with `/wishlist add`.")
+				return nil
+			}
+			var b strings.Builder
+			b.WriteString("**Your wishlist:**\n")
+			for _, e := range entries {
+				fmt.Fprintf(&b, "- %s\n", e.ItemName)
+			}
+			respond(ctx, s, i, b.String())
+			return nil
+		}
+	}
+	if errors.Is(err, store.ErrPlayerNotFound) {
+		respond(ctx, s, i, "You are not registered. Use `/register` first.")
+	} else {
+		respond(ctx, s, i, fmt.Sprintf("Failed to update your wishlist: %s", userMessage(ctx, err)))
+	}
+	return err
+}
+
+func (h *Handlers) handleWishlistReport(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.wishlist == nil {
+		respond(ctx, s, i, "Wishlists are not configured.")
+		return errRejected
+	}
+	demand, err := h.wishlist.Demand(ctx, wishlistReportSize)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error loading wishlists: %s", userMessage(ctx, err)))
+		return err
+	}
+	if len(demand) == 0 {
+		respond(ctx, s, i, "No player has wishlisted an item yet.")
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("**Most wanted items:**\n")
+	for n, d := range demand {
+		fmt.Fprintf(&b, "%d. %s — wanted by %d\n", n+1, d.ItemName, d.Players)
+	}
+	respond(ctx, s, i, b.String())
+	return nil
+}
+
+func (h *Handlers) handleRaidStart(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.raids == nil {
+		respond(ctx, s, i, "Raids are not configured.")
+		return errRejected
+	}
+	var name, mode string
+	// A negative cut selects the configured one.
+	cut := -1.0
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "name":
+			name = opt.StringValue()
+		case "mode":
+			mode = opt.StringValue()
+		case "organizer-cut":
+			cut = opt.FloatValue()
+		}
+	}
+
+	r, err := h.raids.Start(ctx, name, i.Member.User.ID, mode, cut)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to start raid: %s", userMessage(ctx, err)))
+		return err
+	}
+	msg := fmt.Sprintf("GDKP raid **%s** started! Auctions are bid on in gold until the raid ends; the organizer takes %g%% of the pot and the rest is split among those who use `/raid-join`.", r.Name, r.OrganizerCut)
+	if !r.Gold() {
+		msg = fmt.Sprintf("DKP raid **%s** started! Auctions are held in the raid until it ends; `/raid-loot` shows what they awarded.", r.Name)
+	}
+	respond(ctx, s, i, msg)
+	h.announce(ctx, s, i, &discordgo.MessageSend{Content: msg})
+	return nil
+}
+
+func (h *Handlers) handleRaidJoin(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.raids == nil {
+		respond(ctx, s, i, "Raids are not configured.")
+		return errRejected
+	}
+	r, err := h.raids.Join(ctx, i.Member.User.ID)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to join raid: %s", userMessage(ctx, err)))
+		return err
+	}
+	respond(ctx, s, i, fmt.Sprintf("<@%s> joined %s **%s**. Participants: %d.", i.Member.User.ID, raidKind(r), r.Name, len(r.Participants)))
+	return nil
+}
+
+func (h *Handlers) handleRaidPot(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.raids == nil {
+		respond(ctx, s, i, "Raids are not configured.")
+		return errRejected
+	}
+	r, err := h.raids.Active(ctx)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error loading raid: %s", userMessage(ctx, err)))
+		return err
+	}
+	pot, err := h.raids.Pot(ctx, r.ID)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error loading raid pot: %s", userMessage(ctx, err)))
+		return err
+	}
+	if !r.Gold() {
+		respond(ctx, s, i, fmt.Sprintf("DKP raid **%s**: %d items awarded for **%d DKP**, %d auctions open.", r.Name, len(pot.Sales), pot.Total, pot.Open))
+		return nil
+	}
+	cut, share := gdkp.Split(pot.Total, r.OrganizerCut, len(r.Participants))
+	respond(ctx, s, i, fmt.Sprintf("GDKP raid **%s**: **%d gold** from %d items sold, %d auctions open. Split now, the organizer would get %d gold and each of %d participants %d gold.",
+		r.Name, pot.Total, len(pot.Sales), pot.Open, cut, len(r.Participants), share))
+	return nil
+}
+
+// raidKind names the mode of r for messages.
+func raidKind(r *gdkp.Raid) string {
+	if r.Gold() {
+		return "GDKP raid"
+	}
+	return "DKP raid"
+}
+
+// handleRaidLoot lists the items won in the auctions of a raid, the raid
+// in progress unless the raid option names another.
+func (h *Handlers) handleRaidLoot(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.raids == nil {
+		respond(ctx, s, i, "Raids are not configured.")
+		return errRejected
+	}
+	var r *gdkp.Raid
+	var err error
+	if opts := i.ApplicationCommandData().Options; len(opts) > 0 {
+		r, err = h.raids.Get(ctx, opts[0].StringValue())
+	} else {
+		r, err = h.raids.Active(ctx)
+	}
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error loading raid: %s", userMessage(ctx, err)))
+		return err
+	}
+	pot, err := h.raids.Pot(ctx, r.ID)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error loading raid loot: %s", userMessage(ctx, err)))
+		return err
+	}
+	names, err := h.playerNames(ctx)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error listing players: %s", userMessage(ctx, err)))
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Loot of %s %s** (`%s`): %d items for **%d %s**", raidKind(r), r.Name, r.ID, len(pot.Sales), pot.Total, raidCurrency(r))
+	if pot.Open > 0 {
+		fmt.Fprintf(&b, ", %d auctions open", pot.Open)
+	}
+	b.WriteString(".\n")
+	writeLoot(&b, pot, raidCurrency(r), names)
+	respond(ctx, s, i, b.String())
+	return nil
+}
+
+// raidCurrency names what the auctions of r are bid on in.
+func raidCurrency(r *gdkp.Raid) string {
+	if r.Gold() {
+		return "gold"
+	}
+	return "DKP"
+}
+
+// writeLoot lists the sales of pot, by winner name where known, within a
+// message's length.
+func writeLoot(b *strings.Builder, pot *gdkp.Pot, currency string, names map[string]string) {
+	for n, sale := range pot.Sales {
+		winner := sale.WinnerID
+		if name, ok := names[winner]; ok {
+			winner = name
+		}
+		line := fmt.Sprintf("%s — **%s** for %d %s\n", sale.ItemName, winner, sale.Amount, currency)
+		if b.Len()+len(line) > maxMessageLength-len("…and 1000 more\n") {
+			fmt.Fprintf(b, "…and %d more\n", len(pot.Sales)-n)
+			return
+		}
+		b.WriteString(line)
+	}
+}
+
+// playerNames maps player IDs to character names.
+func (h *Handlers) playerNames(ctx context.Context) (map[string]string, error) {
+	names := make(map[string]string)
+	if h.dkpMgr == nil {
+		return names, nil
+	}
+	players, err := h.dkpMgr.ListPlayers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range players {
+		names[p.ID] = p.CharacterName
+	}
+	return names, nil
+}
+
+// handleRaidEnd ends the raid and posts its loot or, for a GDKP raid, what
+// each participant is owed.
+func (h *Handlers) handleRaidEnd(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.raids == nil {
+		respond(ctx, s, i, "Raids are not configured.")
+		return errRejected
+	}
+	var bonus int
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "on-time-bonus" {
+			bonus = int(opt.IntValue())
+		}
+	}
+	if bonus != 0 && h.calendar == nil {
+		respond(ctx, s, i, "The raid calendar is not configured, so no on-time bonus can be awarded.")
+		return errRejected
+	}
+	p, err := h.raids.End(ctx)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to end raid: %s", userMessage(ctx, err)))
+		return err
+	}
+
+	var b strings.Builder
+	if !p.Raid.Gold() {
+		fmt.Fprintf(&b, "**DKP raid %s ended.** %d items awarded for **%d DKP**.\n", p.Raid.Name, len(p.Pot.Sales), p.Pot.Total)
+		names, err := h.playerNames(ctx)
+		if err != nil {
+			h.logger.WarnContext(ctx, "listing players for raid loot failed", slog.Any("error", err))
+		}
+		writeLoot(&b, p.Pot, "DKP", names)
+	} else {
+		writePayout(&b, p)
+	}
+	if bonus != 0 {
+		b.WriteString(h.awardOnTime(ctx, p.Raid, bonus))
+	}
+	msg := b.String()
+	respond(ctx, s, i, msg)
+	h.announce(ctx, s, i, &discordgo.MessageSend{Content: msg})
+	return nil
+}
+
+// writePayout lists what the organizer and each participant of the ended
+// GDKP raid of p are owed.
+func writePayout(b *strings.Builder, p *gdkp.Payout) {
+	fmt.Fprintf(b, "**GDKP raid %s ended.** Pot: **%d gold** from %d items sold.\n", p.Raid.Name, p.Pot.Total, len(p.Pot.Sales))
+	fmt.Fprintf(b, "Organizer cut (%g%%): <@%s> — %d gold\n", p.Raid.OrganizerCut, p.Raid.Organizer, p.OrganizerCut)
+	for n, id := range p.Raid.Participants {
+		line := fmt.Sprintf("<@%s> — %d gold\n", id, p.Share)
+		if b.Len()+len(line) > maxMessageLength-len("…and 1000 more\n") {
+			fmt.Fprintf(b, "…and %d more\n", len(p.Raid.Participants)-n)
+			return
+		}
+		b.WriteString(line)
+	}
+}
+
+// awardOnTime awards the on-time bonus of the scheduled raid that just
+// started to the participants of r who accepted it, and describes the
+// outcome. The raid has ended either way.
+func (h *Handlers) awardOnTime(ctx context.Context, r *gdkp.Raid, amount int) string {
+	bonus, err := h.calendar.AwardOnTime(ctx, r.JoinedAt, amount)
+	if err != nil {
+		return fmt.Sprintf("On-time bonus not awarded: %s\n", userMessage(ctx, err))
+	}
+	names := make([]string, len(bonus.Players))
+	for n, p := range bonus.Players {
+		names[n] = p.CharacterName
+	}
+	line := fmt.Sprintf("On-time bonus for **%s**: %d DKP to %d players", bonus.Raid.Name, amount, len(names))
+	if len(names) > 0 {
+		line += ": " + strings.Join(names, ", ")
+	}
+	if len(bonus.Late) > 0 {
+		line += fmt.Sprintf("; %d accepted but were late or absent", len(bonus.Late))
+	}
+	line += ".\n"
+	if len(line) > maxMessageLength/2 {
+		line = fmt.Sprintf("On-time bonus for **%s**: %d DKP to %d players, %d late or absent.\n", bonus.Raid.Name, amount, len(names), len(bonus.Late))
+	}
+	return line
+}
+
+func (h *Handlers) handleRaidSchedule(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.calendar == nil {
+		respond(ctx, s, i, "The raid calendar is not configured.")
+		return errRejected
+	}
+	var name, start string
+	var quotas calendar.Quotas
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "name":
+			name = opt.StringValue()
+		case "start":
+			start = opt.StringValue()
+		case "tanks":
+			quotas.Tanks = int(opt.IntValue())
+		case "healers":
+			quotas.Healers = int(opt.IntValue())
+		case "dps":
+			quotas.DPS = int(opt.IntValue())
+		case "size":
+			quotas.Size = int(opt.IntValue())
+		}
+	}
+	loc := h.location(ctx, i)
+	startsAt, err := time.ParseInLocation(scheduleLayout, strings.TrimSpace(start), loc)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Invalid start %q: give a date and time in %s, such as 2026-01-31 19:30.", start, loc))
+		return errRejected
+	}
+
+	r, err := h.calendar.Schedule(ctx, name, i.Member.User.ID, startsAt, quotas)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to schedule raid: %s", userMessage(ctx, err)))
+		return err
+	}
+	msg := scheduleMessage(r)
+	respondMessage(ctx, s, i, msg)
+	h.announce(ctx, s, i, msg)
+	return nil
+}
+
+// scheduleLayout is the layout of the start option of /raid-schedule and
+// the starts-at option of /auction-start, read in the timezone returned by
+// location.
+const scheduleLayout = "2006-01-02 15:04"
+
+// scheduleMessage shows the scheduled raid r with its signups and the
+// buttons to sign up.
+func scheduleMessage(r *calendar.Raid) *discordgo.MessageSend {
+	embed := &discordgo.MessageEmbed{
+		Title:       r.Name,
+		Description: fmt.Sprintf("Starts <t:%d:F> (<t:%[1]d:R>)\nID: `%s`", r.StartsAt.Unix(), r.ID),
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:  "Expected",
+				Value: fmt.Sprintf("%d accepted, %d tentative, %d declined", r.Expected(), r.Count(calendar.Tentative, ""), r.Count(calendar.Declined, "")),
+			},
+			{
+				Name: "Roles",
+				Value: strings.Join([]string{
+					quota("Tanks", r.Count(calendar.Accepted, dkp.RoleTank), r.Quotas.Tanks),
+					quota("Healers", r.Count(calendar.Accepted, dkp.RoleHealer), r.Quotas.Healers),
+					quota("DPS", r.Count(calendar.Accepted, dkp.RoleDPS), r.Quotas.DPS),
+				}, " · "),
+			},
+			{Name: "Accepted", Value: signups(r, calendar.Accepted)},
+			{Name: "Tentative", Value: signups(r, calendar.Tentative)},
+		},
+	}
+	if r.Quotas.Size > 0 {
+		embed.Fields[1].Value += fmt.Sprintf(" · Size %d", r.Quotas.Size)
+	}
+	if len(r.Roster) > 0 || len(r.Standby) > 0 {
+		embed.Fields = append(embed.Fields,
+			&discordgo.MessageEmbedField{Name: "Roster", Value: mentions(r.Roster)},
+			&discordgo.MessageEmbedField{Name: "Standby", Value: mentions(r.Standby)},
+		)
+	}
+	button := func(label, status string, style discordgo.ButtonStyle) discordgo.Button {
+		return discordgo.Button{Label: label, Style: style, CustomID: signupAction + ":" + r.ID + ":" + status}
+	}
+	return &discordgo.MessageSend{
+		Content: "Raid scheduled! Sign up below; set your raid role with `/profile`.",
+		Embeds:  []*discordgo.MessageEmbed{embed},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				button("Accept", calendar.Accepted, discordgo.SuccessButton),
+				button("Tentative", calendar.Tentative, discordgo.SecondaryButton),
+				button("Decline", calendar.Declined, discordgo.DangerButton),
+			}},
+		},
+	}
+}
+
+// quota renders the accepted members of a role against its quota.
+func quota(label string, accepted, want int) string {
+	if want == 0 {
+		return fmt.Sprintf("%s %d", label, accepted)
+	}
+	return fmt.Sprintf("%s %d/%d", label, accepted, want)
+}
+
+// signups mentions the members of r who signed up with status, within the
+// length of an embed field.
+func signups(r *calendar.Raid, status string) string {
+	var b strings.Builder
+	total := r.Count(status, "")
+	n := 0
+	for _, su := range r.Signups {
+		if su.Status != status {
+			continue
+		}
+		mention := fmt.Sprintf("<@%s> ", su.DiscordID)
+		if b.Len()+len(mention) > 1000 {
+			fmt.Fprintf(&b, "…and %d more", total-n)
+			break
+		}
+		b.WriteString(mention)
+		n++
+	}
+	if b.Len() == 0 {
+		return "Nobody yet."
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// mentions mentions the members ids within the length of an embed field.
+func mentions(ids []string) string {
+	var b strings.Builder
+	for n, id := range ids {
+		mention := fmt.Sprintf("<@%s> ", id)
+		if b.Len()+len(mention) > 1000 {
+			fmt.Fprintf(&b, "…and %d more", len(ids)-n)
+			break
+		}
+		b.WriteString(mention)
+	}
+	if b.Len() == 0 {
+		return "Nobody."
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// handleRaidCompose previews, or with confirm sets, the roster of a
+// scheduled raid proposed from its signups. Confirming announces the raid
+// with its roster and standby list.
+func (h *Handlers) handleRaidCompose(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.calendar == nil {
+		respond(ctx, s, i, "The raid calendar is not configured.")
+		return errRejected
+	}
+	var raidID string
+	confirm := false
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "raid":
+			raidID = strings.TrimSpace(opt.StringValue())
+		case "confirm":
+			confirm = opt.BoolValue()
+		}
+	}
+
+	compose := h.calendar.Propose
+	if confirm {
+		compose = h.calendar.Compose
+	}
+	c, err := compose(ctx, raidID)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to compose raid: %s", userMessage(ctx, err)))
+		return err
+	}
+	content := compositionMessage(c, confirm)
+	if !confirm {
+		respond(ctx, s, i, content)
+		return nil
+	}
+	msg := scheduleMessage(c.Raid)
+	msg.Content = content
+	respondMessage(ctx, s, i, msg)
+	h.announce(ctx, s, i, msg)
+	return nil
+}
+
+// compositionMessage lists the roster of c by role, its bench, and the
+// roles it lacks.
+func compositionMessage(c *calendar.Composition, confirmed bool) string {
+	var b strings.Builder
+	if confirmed {
+		fmt.Fprintf(&b, "**Roster set for %s**: %d picked, %d on standby\n", c.Raid.Name, len(c.Roster), len(c.Bench))
+	} else {
+		fmt.Fprintf(&b, "**Proposed roster for %s**: %d picked, %d benched\n", c.Raid.Name, len(c.Roster), len(c.Bench))
+	}
+	for _, role := range []struct{ label, role string }{
+		{"Tanks", dkp.RoleTank},
+		{"Healers", dkp.RoleHealer},
+		{"DPS", dkp.RoleDPS},
+		{"No role", ""},
+	} {
+		var ids []string
+		for _, p := range c.Roster {
+			if p.Role == role.role {
+				ids = append(ids, pickMention(p))
+			}
+		}
+		if len(ids) > 0 {
+			fmt.Fprintf(&b, "%s: %s\n", role.label, strings.Join(ids, " "))
+		}
+	}
+	if len(c.Bench) > 0 {
+		ids := make([]string, len(c.Bench))
+		for n, p := range c.Bench {
+			ids[n] = pickMention(p)
+		}
+		fmt.Fprintf(&b, "Benched: %s\n", strings.Join(ids, " "))
+	}
+	var missing []string
+	for _, m := range []struct {
+		label string
+		n     int
+	}{{"tanks", c.Missing.Tanks}, {"healers", c.Missing.Healers}, {"DPS", c.Missing.DPS}} {
+		if m.n > 0 {
+			missing = append(missing, fmt.Sprintf("%d %s", m.n, m.label))
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(&b, "⚠️ Short of %s.\n", strings.Join(missing, ", "))
+	}
+	if !confirmed {
+		b.WriteString("Benched members had priority if benched from the raids before; then attendance over the last four weeks and DKP decide. Run again with `confirm: True` to set the roster and put the benched members on standby.")
+	}
+	out := b.String()
+	if len(out) > maxMessageLength {
+		out = fmt.Sprintf("**Roster for %s**: %d picked, %d benched. Too many to list here; the raid's post shows them once confirmed.", c.Raid.Name, len(c.Roster), len(c.Bench))
+	}
+	return out
+}
+
+// pickMention mentions the member of p, marked if they are tentative.
+func pickMention(p calendar.Pick) string {
+	if p.Status == calendar.Tentative {
+		return fmt.Sprintf("<@%s> (tentative)", p.DiscordID)
+	}
+	return fmt.Sprintf("<@%s>", p.DiscordID)
+}
+
+// handleRaidSignup handles a click on a signup button of a scheduled raid
+// and updates the raid's message with the new signups.
+func (h *Handlers) handleRaidSignup(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.calendar == nil {
+		respond(ctx, s, i, "The raid calendar is not configured.")
+		return errRejected
+	}
+	_, rest, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	raidID, status, _ := strings.Cut(rest, ":")
+
+	// The role comes from the member's profile, if they are registered.
+	var role string
+	if p, err := h.dkpMgr.GetPlayer(ctx, i.Member.User.ID); err == nil {
+		role = p.Role
+	}
+	r, err := h.calendar.SignUp(ctx, raidID, i.Member.User.ID, status, role)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Signup failed: %s", userMessage(ctx, err)))
+		return err
+	}
+	msg := scheduleMessage(r)
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg.Content,
+			Embeds:     msg.Embeds,
+			Components: msg.Components,
+		},
+	}, discordgo.WithContext(ctx))
+	return nil
+}
+
+func (h *Handlers) handleRaidCalendar(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.calendar == nil {
+		respond(ctx, s, i, "The raid calendar is not configured.")
+		return errRejected
+	}
+	raids, err := h.calendar.Upcoming(ctx)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error loading raid calendar: %s", userMessage(ctx, err)))
+		return err
+	}
+	if len(raids) == 0 {
+		respond(ctx, s, i, "No raids are scheduled.")
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("**Upcoming raids**\n")
+	for n, r := range raids {
+		line := fmt.Sprintf("<t:%d:F> **%s**: %d accepted, %d tentative (`%s`)\n",
+			r.StartsAt.Unix(), r.Name, r.Expected(), r.Count(calendar.Tentative, ""), r.ID)
+		if b.Len()+len(line) > maxMessageLength-len("…and 1000 more\n") {
+			fmt.Fprintf(&b, "…and %d more\n", len(raids)-n)
+			break
+		}
+		b.WriteString(line)
+	}
+	respond(ctx, s, i, b.String())
+	return nil
+}
+
+func (h *Handlers) handleBank(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.bank == nil {
+		respond(ctx, s, i, "The guild bank is not configured.")
+		return errRejected
+	}
+	sub := i.ApplicationCommandData().Options[0]
+	var itemName, note string
+	// A zero duration selects the guild's default.
+	var minBid int
+	var duration time.Duration
+	for _, opt := range sub.Options {
+		switch opt.Name {
+		case "item":
+			itemName = opt.StringValue()
+		case "note":
+			note = opt.StringValue()
+		case "min-bid":
+			minBid = int(opt.IntValue())
+		case "duration":
+			duration = time.Duration(opt.IntValue()) * time.Minute
+		}
+	}
+
+	switch sub.Name {
+	case "add":
+		it, err := h.bank.Deposit(ctx, itemName, i.Member.User.ID, note)
+		if err != nil {
+			respond(ctx, s, i, fmt.Sprintf("Failed to bank item: %s", userMessage(ctx, err)))
+			return err
+		}
+		respond(ctx, s, i, fmt.Sprintf("Banked **%s** as `%s`. Auction it later with `/bank auction`.", it.Name, it.ID))
+		return nil
+	case "auction":
+		if err := h.lootMode(ctx, i.GuildID, settings.Settings.Auctions, errAuctionsOff); err != nil {
+			respond(ctx, s, i, fmt.Sprintf("Failed to auction banked item: %s", userMessage(ctx, err)))
+			return err
+		}
+		// The item option holds the banked item's ID.
+		var a *auction.Auction
+		_, err := h.bank.Auction(ctx, itemName, func(ctx context.Context, name string) (string, error) {
+			var err error
+			if a, err = h.auctionMgr.StartAuction(ctx, name, i.Member.User.ID, minBid, 0, 0, duration); err != nil {
+				return "", err
+			}
+			return a.ID, nil
+		})
+		if err != nil {
+			respond(ctx, s, i, fmt.Sprintf("Failed to auction banked item: %s", userMessage(ctx, err)))
+			return err
+		}
+		h.respondStarted(ctx, s, i, a.State())
+		return nil
+	}
+
+	banked, err := h.bank.List(ctx)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Error loading the guild bank: %s", userMessage(ctx, err)))
+		return err
+	}
+	if len(banked) == 0 {
+		respond(ctx, s, i, "The guild bank is empty. Deposit items with `/bank add`.")
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("**Guild bank**\n")
+	for n, it := range banked {
+		line := fmt.Sprintf("**%s** (`%s`), banked <t:%d:R> by <@%s>", it.Name, it.ID, it.DepositedAt.Unix(), it.DepositedBy)
+		if it.Note != "" {
+			line += ": " + it.Note
+		}
+		line += "\n"
+		if b.Len()+len(line) > maxMessageLength-len("…and 1000 more\n") {
+			fmt.Fprintf(&b, "…and %d more\n", len(banked)-n)
+			break
+		}
+		b.WriteString(line)
+	}
+	respond(ctx, s, i, b.String())
+	return nil
+}
+
+// positive is the MinValue of integer options that must be positive.
+var positive = 1.0
+
+func (h *Handlers) handleRaffle(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.raffles == nil {
+		respond(ctx, s, i, "Raffles are not configured.")
+		return errRejected
+	}
+	sub := i.ApplicationCommandData().Options[0]
+	var itemName, raffleID string
+	cost, maxTickets, tickets := 0, 0, 1
+	for _, opt := range sub.Options {
+		switch opt.Name {
+		case "item":
+			itemName = opt.StringValue()
+		case "raffle":
+			raffleID = strings.TrimSpace(opt.StringValue())
+		case "cost":
+			cost = int(opt.IntValue())
+		case "max-tickets":
+			maxTickets = int(opt.IntValue())
+		case "tickets":
+			tickets = int(opt.IntValue())
+		}
+	}
+	if sub.Name != "enter" {
+		if err := h.authorize(ctx, i.GuildID, i.Member); err != nil {
+			respond(ctx, s, i, userMessage(ctx, err))
+			return err
+		}
+	}
+
+	switch sub.Name {
+	case "start":
+		r, err := h.raffles.Start(ctx, itemName, i.Member.User.ID, cost, maxTickets)
+		if err != nil {
+			respond(ctx, s, i, fmt.Sprintf("Failed to start raffle: %s", userMessage(ctx, err)))
+			return err
+		}
+		limit := ""
+		if r.MaxTickets > 0 {
+			limit = fmt.Sprintf(", up to %d per player", r.MaxTickets)
+		}
+		msg := &discordgo.MessageSend{Content: fmt.Sprintf("🎟️ Raffle for **%s** started: %d DKP a ticket%s. Buy tickets with `/raffle enter`. (ID: `%s`)", r.ItemName, r.TicketCost, limit, r.ID)}
+		respondMessage(ctx, s, i, msg)
+		h.announce(ctx, s, i, msg)
+		return nil
+	case "enter":
+		r, err := h.raffles.Enter(ctx, raffleID, i.Member.User.ID, tickets)
+		if err != nil {
+			respond(ctx, s, i, fmt.Sprintf("Failed to buy tickets: %s", userMessage(ctx, err)))
+			return err
+		}
+		var held int
+		for _, e := range r.Entries {
+			if e.DiscordID == i.Member.User.ID {
+				held = e.Tickets
+			}
+		}
+		respond(ctx, s, i, fmt.Sprintf("<@%s> bought %d tickets for **%s** for %d DKP, and holds %d of the %d sold.",
+			i.Member.User.ID, tickets, r.ItemName, tickets*r.TicketCost, held, r.Sold))
+		return nil
+	}
+
+	r, err := h.raffles.Draw(ctx, raffleID)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to draw raffle: %s", userMessage(ctx, err)))
+		return err
+	}
+	content := fmt.Sprintf("The raffle for **%s** sold no tickets, so nobody won.", r.ItemName)
+	if r.Winner != "" {
+		content = fmt.Sprintf("🎉 <@%s> won **%s** with ticket %d of %d!", r.Entry(r.Winner).DiscordID, r.ItemName, r.Ticket, r.Sold)
 	}
+	msg := &discordgo.MessageSend{Content: content}
+	respondMessage(ctx, s, i, msg)
+	h.announce(ctx, s, i, msg)
+	return nil
 }
 
-// SlashCommands returns the slash command definitions.
-func SlashCommands() []*discordgo.ApplicationCommand {
-	return []*discordgo.ApplicationCommand{
-		{
-			Name:        "register",
-			Description: "Register your character for DKP tracking",
-			Options: []*discordgo.ApplicationCommandOption{
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "character",
-					Description: "Your in-game character name",
-					Required:    true,
-				},
-			},
-		},
-		{
-			Name:        "dkp",
-			Description: "Check your DKP balance",
-		},
-		{
-			Name:        "dkp-list",
-			Description: "List all players and their DKP",
-		},
-		{
-			Name:        "dkp-add",
-			Description: "Add DKP to a player (admin only)",
-			Options: []*discordgo.ApplicationCommandOption{
-				{
-					Type:        discordgo.ApplicationCommandOptionUser,
-					Name:        "player",
-					Description: "The player to award DKP to",
-					Required:    true,
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "amount",
-					Description: "Amount of DKP to award",
-					Required:    true,
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "reason",
-					Description: "Reason for the DKP award",
-					Required:    true,
-				},
-			},
-		},
-		{
-			Name:        "dkp-remove",
-			Description: "Remove DKP from a player (admin only)",
-			Options: []*discordgo.ApplicationCommandOption{
-				{
-					Type:        discordgo.ApplicationCommandOptionUser,
-					Name:        "player",
-					Description: "The player to deduct DKP from",
-					Required:    true,
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "amount",
-					Description: "Amount of DKP to deduct",
-					Required:    true,
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "reason",
-					Description: "Reason for the DKP deduction",
-					Required:    true,
-				},
-			},
-		},
-		{
-			Name:        "auction-start",
-			Description: "Start an item auction",
-			Options: []*discordgo.ApplicationCommandOption{
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "item",
-					Description: "Item name to auction",
-					Required:    true,
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "min-bid",
-					Description: "Minimum bid amount",
-					Required:    false,
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "duration",
-					Description: "Auction duration in minutes (default: 5)",
-					Required:    false,
-				},
-			},
-		},
-		{
-			Name:        "bid",
-			Description: "Place a bid on the current auction",
-			Options: []*discordgo.ApplicationCommandOption{
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "auction-id",
-					Description: "Auction ID to bid on",
-					Required:    true,
-				},
-				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "amount",
-					Description: "Bid amount",
-					Required:    true,
-				},
-			},
-		},
-		{
-			Name:        "auction-close",
-			Description: "Close an auction (admin only)",
-			Options: []*discordgo.ApplicationCommandOption{
-				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "auction-id",
-					Description: "Auction ID to close",
-					Required:    true,
-				},
-			},
-		},
+func (h *Handlers) handleItemCost(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.itemCosts == nil {
+		respond(ctx, s, i, "Item costs are not configured.")
+		return errRejected
+	}
+	sub := i.ApplicationCommandData().Options[0]
+	var itemName string
+	var cost int
+	for _, opt := range sub.Options {
+		switch opt.Name {
+		case "item":
+			itemName = opt.StringValue()
+		case "cost":
+			cost = int(opt.IntValue())
+		}
 	}
-}
 
-// InteractionCreate handles incoming slash command interactions.
-func (h *Handlers) InteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	ctx, span := h.tracer.Start(context.Background(), "InteractionCreate",
-		trace.WithAttributes(attribute.String("command", i.ApplicationCommandData().Name)),
-	)
-	defer span.End()
+	switch sub.Name {
+	case "set":
+		it, err := h.itemCosts.Set(ctx, itemName, cost)
+		if err != nil {
+			respond(ctx, s, i, fmt.Sprintf("Failed to set item cost: %s", userMessage(ctx, err)))
+			return err
+		}
+		respond(ctx, s, i, fmt.Sprintf("**%s** now costs **%d DKP**.", it.Name, it.Cost))
+		return nil
+	case "remove":
+		it, err := h.itemCosts.Remove(ctx, itemName)
+		if err != nil {
+			respond(ctx, s, i, fmt.Sprintf("Failed to remove item cost: %s", userMessage(ctx, err)))
+			return err
+		}
+		respond(ctx, s, i, fmt.Sprintf("Removed **%s** from the item cost table.", it.Name))
+		return nil
+	}
 
-	switch i.ApplicationCommandData().Name {
-	case "register":
-		h.handleRegister(ctx, s, i)
-	case "dkp":
-		h.handleDKP(ctx, s, i)
-	case "dkp-list":
-		h.handleDKPList(ctx, s, i)
-	case "dkp-add":
-		h.handleDKPAdd(ctx, s, i)
-	case "dkp-remove":
-		h.handleDKPRemove(ctx, s, i)
-	case "auction-start":
-		h.handleAuctionStart(ctx, s, i)
-	case "bid":
-		h.handleBid(ctx, s, i)
-	case "auction-close":
-		h.handleAuctionClose(ctx, s, i)
-	default:
-		respond(s, i, "Unknown command")
+	table, err := h.itemCosts.List(ctx)
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to list item costs: %s", userMessage(ctx, err)))
+		return err
+	}
+	if len(table) == 0 {
+		respond(ctx, s, i, "The item cost table is empty. Add items with `/itemcost set`.")
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("**Item costs**\n")
+	for n, it := range table {
+		line := fmt.Sprintf("%s: %d DKP\n", it.Name, it.Cost)
+		if b.Len()+len(line) > maxMessageLength-len("…and 1000 more\n") {
+			fmt.Fprintf(&b, "…and %d more\n", len(table)-n)
+			break
+		}
+		b.WriteString(line)
 	}
+	respond(ctx, s, i, b.String())
+	return nil
 }
 
-func (h *Handlers) handleRegister(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+func (h *Handlers) handleLootAward(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.itemCosts == nil {
+		respond(ctx, s, i, "Item costs are not configured.")
+		return errRejected
+	}
+	if err := h.lootMode(ctx, i.GuildID, settings.Settings.FixedPrices, errFixedPricesOff); err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to award loot: %s", userMessage(ctx, err)))
+		return err
+	}
 	opts := i.ApplicationCommandData().Options
-	charName := opts[0].StringValue()
-	discordID := i.Member.User.ID
+	var discordID, itemName string
+	for _, opt := range opts {
+		switch opt.Name {
+		case "player":
+			discordID = opt.UserValue(nil).ID
+		case "item":
+			itemName = opt.StringValue()
+		}
+	}
+	p, it, err := h.itemCosts.Award(ctx, discordID, itemName)
+	if errors.Is(err, store.ErrPlayerNotFound) {
+		respond(ctx, s, i, "Target player is not registered.")
+		return err
+	}
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to award loot: %s", userMessage(ctx, err)))
+		return err
+	}
+	msg := &discordgo.MessageSend{Content: fmt.Sprintf("💰 <@%s> received **%s** for **%d DKP** and has %d DKP left.", discordID, it.Name, it.Cost, p.DKP-it.Cost)}
+	respondMessage(ctx, s, i, msg)
+	h.announce(ctx, s, i, msg)
+	return nil
+}
+
+// disputeThreadArchive is how many minutes a dispute's thread stays open
+// without messages: a week, so that officers have time to look into it.
+const disputeThreadArchive = 7 * 24 * 60
+
+func (h *Handlers) handleDispute(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.disputes == nil {
+		respondPrivate(ctx, s, i, "Disputes are not configured.")
+		return errRejected
+	}
+	sub := i.ApplicationCommandData().Options[0]
+	var reason, auctionID, disputeID, resolution string
+	var anonymous bool
+	for _, opt := range sub.Options {
+		switch opt.Name {
+		case "reason":
+			reason = strings.TrimSpace(opt.StringValue())
+		case "auction":
+			auctionID = strings.TrimSpace(opt.StringValue())
+		case "anonymous":
+			anonymous = opt.BoolValue()
+		case "dispute":
+			disputeID = strings.TrimSpace(opt.StringValue())
+		case "resolution":
+			resolution = opt.StringValue()
+		}
+	}
+	if sub.Name == "open" {
+		return h.openDispute(ctx, s, i, reason, auctionID, anonymous)
+	}
+	if err := h.authorize(ctx, i.GuildID, i.Member); err != nil {
+		respondPrivate(ctx, s, i, userMessage(ctx, err))
+		return err
+	}
+
+	if sub.Name == "list" {
+		open, err := h.disputes.Unresolved(ctx)
+		if err != nil {
+			respondPrivate(ctx, s, i, fmt.Sprintf("Error listing disputes: %s", userMessage(ctx, err)))
+			return err
+		}
+		if len(open) == 0 {
+			respondPrivate(ctx, s, i, "No disputes are open.")
+			return nil
+		}
+		var b strings.Builder
+		b.WriteString("**Open disputes:**\n")
+		for n, d := range open {
+			line := fmt.Sprintf("<t:%d:d> <#%s> `%s`: %s", d.OpenedAt.Unix(), d.ThreadID, d.ID, d.Reason)
+			if d.AuctionID != "" {
+				line += fmt.Sprintf(" (auction `%s`)", d.AuctionID)
+			}
+			line += "\n"
+			if b.Len()+len(line) > maxMessageLength-len("…and 1000 more\n") {
+				fmt.Fprintf(&b, "…and %d more\n", len(open)-n)
+				break
+			}
+			b.WriteString(line)
+		}
+		respondPrivate(ctx, s, i, b.String())
+		return nil
+	}
 
-	p, err := h.dkpMgr.RegisterPlayer(ctx, discordID, charName)
+	d, err := h.disputes.Resolve(ctx, disputeID, i.Member.User.ID, resolution)
 	if err != nil {
-		respond(s, i, fmt.Sprintf("Failed to register: %s", err))
-		return
+		respondPrivate(ctx, s, i, fmt.Sprintf("Failed to resolve dispute: %s", userMessage(ctx, err)))
+		return err
+	}
+	msg := fmt.Sprintf("✅ Resolved by <@%s>: %s", d.ResolvedBy, d.Resolution)
+	if _, err := s.ChannelMessageSend(d.ThreadID, msg, discordgo.WithContext(ctx)); err != nil {
+		h.logger.WarnContext(ctx, "posting dispute resolution failed",
+			slog.String("dispute_id", d.ID),
+			slog.Any("error", err),
+		)
 	}
-	respond(s, i, fmt.Sprintf("Registered **%s** (DKP: %d)", p.CharacterName, p.DKP))
+	closed := true
+	if _, err := s.ChannelEditComplex(d.ThreadID, &discordgo.ChannelEdit{Archived: &closed, Locked: &closed}, discordgo.WithContext(ctx)); err != nil {
+		h.logger.WarnContext(ctx, "closing dispute thread failed",
+			slog.String("dispute_id", d.ID),
+			slog.Any("error", err),
+		)
+	}
+	respondPrivate(ctx, s, i, fmt.Sprintf("Resolved dispute `%s`: %s", d.ID, d.Resolution))
+	return nil
 }
 
-func (h *Handlers) handleDKP(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	discordID := i.Member.User.ID
-	p, err := h.dkpMgr.GetPlayer(ctx, discordID)
+// openDispute opens a dispute about reason in a private thread of the
+// channel i was sent in, adding the guild's officers and, unless it is
+// anonymous, the member who opened it. If auctionID is set, the auction's
+// status and bid history, and its events as CSV, are posted in the thread
+// so that the officers have them at hand.
+func (h *Handlers) openDispute(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, reason, auctionID string, anonymous bool) error {
+	if reason == "" {
+		respondPrivate(ctx, s, i, fmt.Sprintf("Failed to open dispute: %s", userMessage(ctx, dispute.ErrNoReason)))
+		return dispute.ErrNoReason
+	}
+	msg := &discordgo.MessageSend{}
+	if auctionID != "" {
+		embed, err := h.auctionInfo(ctx, auctionID)
+		if err != nil {
+			respondPrivate(ctx, s, i, fmt.Sprintf("Error loading auction: %s", userMessage(ctx, err)))
+			return err
+		}
+		msg.Embeds = []*discordgo.MessageEmbed{embed}
+	}
+	if auctionID != "" && h.auditLog != nil {
+		entries, err := h.auditLog.Query(ctx, event.Query{AggregateID: auctionID})
+		if err != nil {
+			respondPrivate(ctx, s, i, fmt.Sprintf("Error querying audit log: %s", userMessage(ctx, err)))
+			return err
+		}
+		// The audit log lists the newest events first; the history reads
+		// better oldest first.
+		slices.Reverse(entries)
+		var buf bytes.Buffer
+		if err := audit.WriteCSV(&buf, entries); err != nil {
+			respondPrivate(ctx, s, i, fmt.Sprintf("Error exporting auction history: %s", userMessage(ctx, err)))
+			return err
+		}
+		msg.Files = []*discordgo.File{{Name: auctionID + ".csv", ContentType: "text/csv", Reader: &buf}}
+	}
+
+	name := "Dispute: " + reason
+	if r := []rune(name); len(r) > 100 {
+		name = string(r[:99]) + "…"
+	}
+	thread, err := s.ThreadStartComplex(i.ChannelID, &discordgo.ThreadStart{
+		Name:                name,
+		Type:                discordgo.ChannelTypeGuildPrivateThread,
+		AutoArchiveDuration: disputeThreadArchive,
+	}, discordgo.WithContext(ctx))
 	if err != nil {
-		respond(s, i, "You are not registered. Use `/register` first.")
-		return
+		respondPrivate(ctx, s, i, "Failed to open dispute: the dispute thread could not be created.")
+		return err
+	}
+	t := dispute.Ticket{Reason: reason, AuctionID: auctionID, OpenedBy: i.Member.User.ID, Anonymous: anonymous, ThreadID: thread.ID}
+	d, err := h.disputes.Open(ctx, t)
+	if err != nil {
+		if _, derr := s.ChannelDelete(thread.ID, discordgo.WithContext(ctx)); derr != nil {
+			h.logger.WarnContext(ctx, "deleting dispute thread failed", slog.String("thread_id", thread.ID), slog.Any("error", derr))
+		}
+		respondPrivate(ctx, s, i, fmt.Sprintf("Failed to open dispute: %s", userMessage(ctx, err)))
+		return err
+	}
+
+	// Mentions add the officers, and the member who opened the dispute,
+	// to the private thread.
+	var b strings.Builder
+	if h.settings != nil {
+		gs, err := h.settings.Get(ctx, i.GuildID)
+		if err != nil {
+			h.logger.WarnContext(ctx, "loading guild settings for dispute failed", slog.Any("error", err))
+		}
+		for _, role := range gs.AdminRoles {
+			fmt.Fprintf(&b, "<@&%s> ", role)
+		}
+	}
+	if d.Anonymous {
+		b.WriteString("An anonymous member opened a dispute")
+	} else {
+		fmt.Fprintf(&b, "<@%s> opened a dispute", d.OpenedBy)
+	}
+	if d.AuctionID != "" {
+		fmt.Fprintf(&b, " about auction `%s`", d.AuctionID)
 	}
-	respond(s, i, fmt.Sprintf("**%s** — DKP: **%d**", p.CharacterName, p.DKP))
+	fmt.Fprintf(&b, ":\n> %s\nResolve it with `/dispute resolve`. (ID: `%s`)", d.Reason, d.ID)
+	msg.Content = b.String()
+	if _, err := s.ChannelMessageSendComplex(thread.ID, msg, discordgo.WithContext(ctx)); err != nil {
+		h.logger.WarnContext(ctx, "posting dispute failed",
+			slog.String("dispute_id", d.ID),
+			slog.Any("error", err),
+		)
+	}
+
+	if d.Anonymous {
+		respondPrivate(ctx, s, i, "Your dispute was passed on to the officers without your name. You were not added to its thread, so you will not see their answer there.")
+		return nil
+	}
+	respondPrivate(ctx, s, i, fmt.Sprintf("Your dispute is open in <#%s> with the officers.", thread.ID))
+	return nil
 }
 
-func (h *Handlers) handleDKPList(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	players, err := h.dkpMgr.ListPlayers(ctx)
+// lootMode returns errOff if allowed reports that the loot_mode setting of
+// guildID does not allow a way of handing out loot. Without settings,
+// every way is allowed.
+func (h *Handlers) lootMode(ctx context.Context, guildID string, allowed func(settings.Settings) bool, errOff error) error {
+	if h.settings == nil {
+		return nil
+	}
+	gs, err := h.settings.Get(ctx, guildID)
 	if err != nil {
-		respond(s, i, fmt.Sprintf("Error listing players: %s", err))
-		return
+		return err
 	}
-	if len(players) == 0 {
-		respond(s, i, "No players registered yet.")
-		return
+	if !allowed(gs) {
+		return errOff
 	}
-	msg := "**DKP Standings:**\n"
-	for idx, p := range players {
-		msg += fmt.Sprintf("%d. %s — %d DKP\n", idx+1, p.CharacterName, p.DKP)
+	return nil
+}
+
+// userMessage describes err for a Discord reply. Classified errors show
+// their message and code; internal errors show only a reference to the
+// trace, which holds the details.
+func userMessage(ctx context.Context, err error) string {
+	if derrors.KindOf(err) != derrors.Internal {
+		return fmt.Sprintf("%s (code `%s`)", derrors.MessageOf(err), derrors.CodeOf(err))
+	}
+	msg := fmt.Sprintf("%s (code `%s`", derrors.MessageOf(err), derrors.CodeOf(err))
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		msg += fmt.Sprintf(", ref `%s`", sc.TraceID())
 	}
-	respond(s, i, msg)
+	return msg + ")"
 }
 
-func (h *Handlers) handleDKPAdd(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	opts := i.ApplicationCommandData().Options
-	targetUser := opts[0].UserValue(s)
-	amount := int(opts[1].IntValue())
-	reason := opts[2].StringValue()
+// defaultInactiveWeeks is how many weeks without activity /roster-inactive
+// looks for if neither the command nor the config says.
+const defaultInactiveWeeks = 4
 
-	target, err := h.dkpMgr.GetPlayer(ctx, targetUser.ID)
+func (h *Handlers) handleRosterInactive(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.roster == nil {
+		respond(ctx, s, i, "The roster review is not configured.")
+		return errRejected
+	}
+	weeks := h.roster.Weeks()
+	if weeks <= 0 {
+		weeks = defaultInactiveWeeks
+	}
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "weeks" {
+			weeks = max(int(opt.IntValue()), 1)
+		}
+	}
+
+	inactive, err := h.roster.Inactive(ctx, weeks)
 	if err != nil {
-		respond(s, i, "Target player is not registered.")
-		return
+		respond(ctx, s, i, fmt.Sprintf("Error finding inactive players: %s", userMessage(ctx, err)))
+		return err
 	}
+	if len(inactive) == 0 {
+		respond(ctx, s, i, fmt.Sprintf("Every player has been active in the last %d weeks.", weeks))
+		return nil
+	}
+	respondMessage(ctx, s, i, roster.Proposal(inactive, weeks))
+	return nil
+}
 
-	if err := h.dkpMgr.AwardDKP(ctx, target.ID, amount, reason); err != nil {
-		respond(s, i, fmt.Sprintf("Failed to award DKP: %s", err))
-		return
+func (h *Handlers) handleRosterArchive(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	_, discordID, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	p, err := h.dkpMgr.Archive(ctx, discordID, "inactive")
+	if err != nil {
+		respond(ctx, s, i, fmt.Sprintf("Failed to archive player: %s", userMessage(ctx, err)))
+		return err
+	}
+	respond(ctx, s, i, fmt.Sprintf("**%s** archived by <@%s>; their **%d DKP** are frozen until `/roster-restore`.", p.CharacterName, i.Member.User.ID, p.DKP))
+	return nil
+}
+
+func (h *Handlers) handleRosterRestore(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	// Only the ID is needed, so the user is not fetched.
+	discordID := i.ApplicationCommandData().Options[0].UserValue(nil).ID
+	p, err := h.dkpMgr.Restore(ctx, discordID)
+	switch {
+	case errors.Is(err, store.ErrPlayerNotFound):
+		respond(ctx, s, i, "That player is not registered.")
+		return nil
+	case err != nil:
+		respond(ctx, s, i, fmt.Sprintf("Failed to restore player: %s", userMessage(ctx, err)))
+		return err
 	}
-	respond(s, i, fmt.Sprintf("Awarded **%d DKP** to **%s** for: %s", amount, target.CharacterName, reason))
+	respond(ctx, s, i, fmt.Sprintf("**%s** restored with **%d DKP**.", p.CharacterName, p.DKP))
+	return nil
 }
 
-func (h *Handlers) handleDKPRemove(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+func (h *Handlers) handleLootBan(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
 	opts := i.ApplicationCommandData().Options
-	targetUser := opts[0].UserValue(s)
-	amount := int(opts[1].IntValue())
-	reason := opts[2].StringValue()
+	// Only the ID is needed, so the user is not fetched.
+	discordID := opts[0].UserValue(nil).ID
+	days := int(opts[1].IntValue())
+	ban, err := h.dkpMgr.BanLoot(ctx, discordID, time.Duration(days)*24*time.Hour, opts[2].StringValue())
+	switch {
+	case errors.Is(err, store.ErrPlayerNotFound):
+		respond(ctx, s, i, "That player is not registered.")
+		return nil
+	case err != nil:
+		respond(ctx, s, i, fmt.Sprintf("Failed to ban player: %s", userMessage(ctx, err)))
+		return err
+	}
+	respond(ctx, s, i, fmt.Sprintf("**%s** is banned from loot by <@%s> until <t:%d:F>: %s", ban.CharacterName, i.Member.User.ID, ban.Until.Unix(), ban.Reason))
+	return nil
+}
 
-	target, err := h.dkpMgr.GetPlayer(ctx, targetUser.ID)
-	if err != nil {
-		respond(s, i, "Target player is not registered.")
-		return
+func (h *Handlers) handleLootBanLift(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	// Only the ID is needed, so the user is not fetched.
+	discordID := i.ApplicationCommandData().Options[0].UserValue(nil).ID
+	ban, err := h.dkpMgr.LiftLootBan(ctx, discordID)
+	switch {
+	case errors.Is(err, store.ErrPlayerNotFound):
+		respond(ctx, s, i, "That player is not registered.")
+		return nil
+	case err != nil:
+		respond(ctx, s, i, fmt.Sprintf("Failed to lift loot ban: %s", userMessage(ctx, err)))
+		return err
 	}
+	respond(ctx, s, i, fmt.Sprintf("The loot ban of **%s** is lifted; they may bid again.", ban.CharacterName))
+	return nil
+}
 
-	if err := h.dkpMgr.DeductDKP(ctx, target.ID, amount, reason); err != nil {
-		respond(s, i, fmt.Sprintf("Failed to deduct DKP: %s", err))
-		return
+func (h *Handlers) handleRoleSync(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.roles == nil {
+		respond(ctx, s, i, "Role sync is not configured.")
+		return errRejected
+	}
+	dryRun := h.roles.DryRun()
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "dry-run" {
+			dryRun = dryRun || opt.BoolValue()
+		}
+	}
+
+	// Each player's member is fetched, and changes are paced.
+	name := "Role sync"
+	if dryRun {
+		name = "Role sync dry run"
 	}
-	respond(s, i, fmt.Sprintf("Deducted **%d DKP** from **%s** for: %s", amount, target.CharacterName, reason))
+	return h.runJob(ctx, s, i, name, func(ctx context.Context, _ func(string)) (jobs.Result, error) {
+		changes, err := h.roles.Sync(ctx, dryRun)
+		if err != nil && len(changes) == 0 {
+			return jobs.Result{Message: fmt.Sprintf("Role sync failed: %s", userMessage(ctx, err))}, err
+		}
+		msg := roleChanges(s.State, i.GuildID, changes, dryRun)
+		if err != nil {
+			msg += fmt.Sprintf("Role sync stopped: %s", userMessage(ctx, err))
+		}
+		return jobs.Result{Message: msg}, err
+	})
 }
 
-func (h *Handlers) handleAuctionStart(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	opts := i.ApplicationCommandData().Options
-	itemName := opts[0].StringValue()
+// roleChanges describes changes within a message's length, naming the
+// roles found in st. dryRun says they were not made.
+func roleChanges(st *discordgo.State, guildID string, changes []rolesync.Change, dryRun bool) string {
+	if len(changes) == 0 {
+		return "Every player has the roles their DKP earns them."
+	}
+	var b strings.Builder
+	if dryRun {
+		fmt.Fprintf(&b, "**Role sync dry run**: %d changes would be made.\n", len(changes))
+	} else {
+		fmt.Fprintf(&b, "**Role sync**: %d changes made.\n", len(changes))
+	}
+	for n, c := range changes {
+		// Role names rather than mentions, which would notify the role.
+		role := c.RoleID
+		if r, err := st.Role(guildID, c.RoleID); err == nil {
+			role = r.Name
+		}
+		line := fmt.Sprintf("**%s** loses %s\n", c.CharacterName, role)
+		if c.Add {
+			line = fmt.Sprintf("**%s** gets %s\n", c.CharacterName, role)
+		}
+		if b.Len()+len(line) > maxMessageLength-len("…and 1000 more\n") {
+			fmt.Fprintf(&b, "…and %d more\n", len(changes)-n)
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
 
-	minBid := 0
-	duration := 5 * time.Minute
+// Days /bot-stats covers by default and at most.
+const (
+	defaultUsageDays = 30
+	maxUsageDays     = 365
+)
 
-	for _, opt := range opts[1:] {
-		switch opt.Name {
-		case "min-bid":
-			minBid = int(opt.IntValue())
-		case "duration":
-			duration = time.Duration(opt.IntValue()) * time.Minute
+func (h *Handlers) handleBotStats(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if h.usage == nil {
+		respond(ctx, s, i, "Command usage is not tracked.")
+		return errRejected
+	}
+	days := defaultUsageDays
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "days" {
+			days = min(max(int(opt.IntValue()), 1), maxUsageDays)
 		}
 	}
 
-	a, err := h.auctionMgr.StartAuction(ctx, itemName, i.Member.User.ID, minBid, duration)
+	report, err := h.usage.Report(ctx, days)
 	if err != nil {
-		respond(s, i, fmt.Sprintf("Failed to start auction: %s", err))
-		return
+		respond(ctx, s, i, fmt.Sprintf("Error loading command usage: %s", userMessage(ctx, err)))
+		return err
 	}
-	respond(s, i, fmt.Sprintf("Auction started for **%s** (ID: `%s`, Min bid: %d, Duration: %s)", itemName, a.ID, minBid, duration))
+	respond(ctx, s, i, usageReport(report))
+	return nil
 }
 
-func (h *Handlers) handleBid(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	opts := i.ApplicationCommandData().Options
-	auctionID := opts[0].StringValue()
-	amount := int(opts[1].IntValue())
-	discordID := i.Member.User.ID
+// usageReport describes report within a message's length: the commands
+// used, most used first, then the slash commands nobody used.
+func usageReport(report *usage.Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Bot usage, last %d days**: %d members used commands %d times, %s failed.\n",
+		report.Days, report.Users, report.Uses(), percent(report.Failures(), report.Uses()))
 
-	if err := h.auctionMgr.PlaceBid(ctx, auctionID, discordID, amount); err != nil {
-		respond(s, i, fmt.Sprintf("Bid failed: %s", err))
-		return
+	slash := SlashCommands()
+	unused := make(map[string]bool, len(slash))
+	for _, cmd := range slash {
+		unused[cmd.Name] = true
+	}
+	lines := make([]string, 0, len(report.Commands)+1)
+	for _, c := range report.Commands {
+		// Buttons are recorded by their action, which is not typed.
+		name := c.Command
+		if _, ok := unused[name]; ok {
+			name = "/" + name
+			unused[c.Command] = false
+		}
+		lines = append(lines, fmt.Sprintf("`%s` — %d uses by %d members, %s failed\n", name, c.Uses, c.Users, percent(c.Failures, c.Uses)))
+	}
+	var names []string
+	for _, cmd := range slash {
+		if unused[cmd.Name] {
+			names = append(names, "`/"+cmd.Name+"`")
+		}
+	}
+	if len(names) > 0 {
+		lines = append(lines, "Unused: "+strings.Join(names, ", ")+"\n")
+	}
+
+	for n, line := range lines {
+		if b.Len()+len(line) > maxMessageLength-len("…and 1000 more\n") {
+			fmt.Fprintf(&b, "…and %d more\n", len(lines)-n)
+			break
+		}
+		b.WriteString(line)
 	}
-	respond(s, i, fmt.Sprintf("Bid of **%d DKP** placed on auction `%s`", amount, auctionID))
+	return b.String()
 }
 
-func (h *Handlers) handleAuctionClose(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	opts := i.ApplicationCommandData().Options
-	auctionID := opts[0].StringValue()
+// percent formats n of total as a whole percentage.
+func percent(n, total int) string {
+	if total == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%d%%", (100*n+total/2)/total)
+}
 
-	result, err := h.auctionMgr.CloseAuction(ctx, auctionID)
+// respondLater acknowledges an interaction whose work may exceed Discord's
+// response deadline and returns a function that sets the final message.
+func respondLater(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) (edit func(msg string)) {
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}, discordgo.WithContext(ctx))
+	return func(msg string) {
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &msg}, discordgo.WithContext(ctx))
+	}
+}
+
+// runJob answers i and runs task, the command named name, as a job that
+// reports in a message of its own, linked from the answer, which only the
+// member who used the command sees. Without jobs, task runs while the
+// interaction waits and its result is the answer.
+func (h *Handlers) runJob(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, name string, task jobs.Task) error {
+	if h.jobs == nil {
+		_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		}, discordgo.WithContext(ctx))
+		res, err := task(ctx, func(string) {})
+		edit := &discordgo.WebhookEdit{Content: &res.Message}
+		if res.File != nil {
+			edit.Files = []*discordgo.File{
+				{Name: res.File.Name, ContentType: res.File.ContentType, Reader: bytes.NewReader(res.File.Data)},
+			}
+		}
+		_, _ = s.InteractionResponseEdit(i.Interaction, edit, discordgo.WithContext(ctx))
+		return err
+	}
+
+	rep := jobReporter{session: s, guildID: i.GuildID, channelID: i.ChannelID}
+	if h.jobsDM {
+		ch, err := s.UserChannelCreate(memberID(i), discordgo.WithContext(ctx))
+		if err != nil {
+			respondPrivate(ctx, s, i, "I could not send you a direct message. Allow direct messages from server members, or ask an admin to report jobs in channels.")
+			return err
+		}
+		rep.guildID, rep.channelID = "", ch.ID
+	}
+	job, err := h.jobs.Start(ctx, name, memberID(i), rep, task)
 	if err != nil {
-		respond(s, i, fmt.Sprintf("Failed to close auction: %s", err))
-		return
+		respondPrivate(ctx, s, i, fmt.Sprintf("Could not start %s: %s", name, userMessage(ctx, err)))
+		return err
 	}
-	if result == "" {
-		respond(s, i, fmt.Sprintf("Auction `%s` closed with no bids.", auctionID))
-	} else {
-		respond(s, i, result)
+	respondPrivate(ctx, s, i, fmt.Sprintf("%s is running in the background as job `%s`. Its progress and outcome are posted in %s.", name, job.ID, job.Link))
+	return nil
+}
+
+// jobReporter posts the message of a job in a channel, or a direct message
+// channel if guildID is empty.
+type jobReporter struct {
+	session   *discordgo.Session
+	guildID   string
+	channelID string
+}
+
+func (r jobReporter) Post(ctx context.Context, content string) (string, string, error) {
+	m, err := r.session.ChannelMessageSend(r.channelID, content, discordgo.WithContext(ctx))
+	if err != nil {
+		return "", "", err
+	}
+	guild := r.guildID
+	if guild == "" {
+		guild = "@me"
+	}
+	return m.ID, fmt.Sprintf("https://discord.com/channels/%s/%s/%s", guild, r.channelID, m.ID), nil
+}
+
+func (r jobReporter) Edit(ctx context.Context, messageID, content string, f *jobs.File) error {
+	edit := discordgo.NewMessageEdit(r.channelID, messageID).SetContent(content)
+	if f != nil {
+		edit.Files = []*discordgo.File{
+			{Name: f.Name, ContentType: f.ContentType, Reader: bytes.NewReader(f.Data)},
+		}
 	}
+	_, err := r.session.ChannelMessageEditComplex(edit, discordgo.WithContext(ctx))
+	return err
 }
 
-func respond(s *discordgo.Session, i *discordgo.InteractionCreate, msg string) {
+// respond replies to an interaction. The REST call is traced as a child of
+// ctx.
+func respond(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, msg string) {
 	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
 			Content: msg,
 		},
-	})
+	}, discordgo.WithContext(ctx))
+}
+
+// respondPrivate replies to an interaction with msg, which only the member
+// who sent it sees.
+func respondPrivate(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, msg string) {
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}, discordgo.WithContext(ctx))
+}
+
+// respondMessage replies to an interaction with the content, embeds,
+// components, and files of msg.
+func respondMessage(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, msg *discordgo.MessageSend) {
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg.Content,
+			Embeds:     msg.Embeds,
+			Components: msg.Components,
+			Files:      msg.Files,
+			Flags:      msg.Flags,
+		},
+	}, discordgo.WithContext(ctx))
+}
+
+// updateMessage replaces the message whose component was used with msg.
+func updateMessage(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, msg *discordgo.MessageSend) {
+	embeds, components := msg.Embeds, msg.Components
+	// Empty, rather than nil, slices remove those of the message.
+	if embeds == nil {
+		embeds = []*discordgo.MessageEmbed{}
+	}
+	if components == nil {
+		components = []discordgo.MessageComponent{}
+	}
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg.Content,
+			Embeds:     embeds,
+			Components: components,
+		},
+	}, discordgo.WithContext(ctx))
+}
+
+// respondFailure tells the user that an interaction failed. The handler may
+// already have acknowledged it, in which case a follow-up message is sent.
+func respondFailure(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, msg string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+		},
+	}, discordgo.WithContext(ctx))
+	if err == nil {
+		return
+	}
+	_, _ = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{Content: msg}, discordgo.WithContext(ctx))
+}
+
+// respondPrivateFile answers i with msg and a file that only the member
+// who sent i sees.
+func respondPrivateFile(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, msg, name, contentType string, r *bytes.Buffer) {
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Files: []*discordgo.File{
+				{Name: name, ContentType: contentType, Reader: r},
+			},
+		},
+	}, discordgo.WithContext(ctx))
+}
+
+func respondFile(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, msg, name, contentType string, r *bytes.Buffer) {
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Files: []*discordgo.File{
+				{Name: name, ContentType: contentType, Reader: r},
+			},
+		},
+	}, discordgo.WithContext(ctx))
 }
@@ -0,0 +1,2423 @@
+package commands_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/audit"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/bank"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/bot/commands"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/calendar"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/discordtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dispute"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/economy"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/export"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/gdkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/ids"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/itemcost"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/items"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/jobs"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/merge"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/notes"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/raffle"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/roster"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/settings"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/standings"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/textfilter"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/usage"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/wishlist"
+)
+
+// recordingTransport answers every Discord REST call with 204 and keeps
+// the request bodies.
+type recordingTransport struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	rt.mu.Lock()
+	rt.bodies = append(rt.bodies, string(body))
+	rt.mu.Unlock()
+	return &http.Response{
+		StatusCode: http.StatusNoContent,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+// memIdempotency implements store.IdempotencyRepository in memory.
+type memIdempotency struct {
+	mu   sync.Mutex
+	keys map[string]bool
+}
+
+func (r *memIdempotency) Reserve(_ context.Context, key string, _ time.Duration) (*store.IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.keys[key] {
+		return &store.IdempotencyRecord{Key: key}, false, nil
+	}
+	r.keys[key] = true
+	return nil, true, nil
+}
+
+func (r *memIdempotency) Complete(context.Context, string, []byte) error { return nil }
+
+func (r *memIdempotency) Release(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, key)
+	return nil
+}
+
+func (r *memIdempotency) Prune(context.Context, time.Duration) (int64, error) { return 0, nil }
+
+func interaction(id, command string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:      id,
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild-1",
+		Token:   "token",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "user-1"}},
+		Data:    discordgo.ApplicationCommandInteractionData{Name: command},
+	}}
+}
+
+func TestInteractionCreate_Standby(t *testing.T) {
+	// Both replicas see every interaction. Their nil managers make every
+	// served command fail, which still produces exactly one response.
+	guard := idempotency.NewGuard(&memIdempotency{keys: make(map[string]bool)})
+	leader := commands.NewHandlers(nil, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithStandby(guard))
+	leader.Promote()
+	standby := commands.NewHandlers(nil, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithStandby(guard))
+
+	tests := []struct {
+		name     string
+		command  string
+		replicas []*commands.Handlers
+		want     int
+	}{
+		{name: "read served once", command: "dkp-list", replicas: []*commands.Handlers{standby, leader}, want: 1},
+		{name: "write ignored by standby", command: "auction-start", replicas: []*commands.Handlers{standby}, want: 0},
+		{name: "write served by leader", command: "auction-start", replicas: []*commands.Handlers{standby, leader}, want: 1},
+	}
+	for n, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &recordingTransport{}
+			s, _ := discordgo.New("Bot token")
+			s.Client = &http.Client{Transport: rt}
+
+			i := interaction(fmt.Sprintf("interaction-%d", n), tt.command)
+			for _, h := range tt.replicas {
+				h.InteractionCreate(s, i)
+			}
+			if len(rt.bodies) != tt.want {
+				t.Errorf("got %d responses, want %d", len(rt.bodies), tt.want)
+			}
+		})
+	}
+}
+
+// memSettings implements store.GuildSettingsRepository in memory for one
+// guild.
+type memSettings struct {
+	mu       sync.Mutex
+	settings map[string]store.GuildSetting
+}
+
+func (r *memSettings) List(context.Context, string) ([]store.GuildSetting, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []store.GuildSetting
+	for _, s := range r.settings {
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func (r *memSettings) Set(_ context.Context, s *store.GuildSetting) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.settings[s.Key] = *s
+	return nil
+}
+
+func (r *memSettings) Delete(_ context.Context, _, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.settings, key)
+	return nil
+}
+
+func TestInteractionCreate_OfficerCommands(t *testing.T) {
+	repo := &memSettings{settings: map[string]store.GuildSetting{
+		settings.AdminRoles: {Key: settings.AdminRoles, Value: "42"},
+	}}
+	svc := settings.NewService(repo, settings.Defaults(config.GuildDefaultsConfig{}), slog.Default())
+	h := commands.NewHandlers(nil, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithSettings(svc))
+
+	tests := []struct {
+		name        string
+		roles       []string
+		permissions int64
+		want        string
+	}{
+		{name: "member", roles: []string{"7"}, want: "`NOT_OFFICER`"},
+		{name: "admin role", roles: []string{"7", "42"}, want: "not configured"},
+		{name: "administrator", permissions: discordgo.PermissionAdministrator, want: "not configured"},
+	}
+	for n, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &recordingTransport{}
+			s, _ := discordgo.New("Bot token")
+			s.Client = &http.Client{Transport: rt}
+
+			i := interaction(fmt.Sprintf("interaction-%d", n), "deadletter")
+			i.Member.Roles = tt.roles
+			i.Member.Permissions = tt.permissions
+			h.InteractionCreate(s, i)
+
+			if len(rt.bodies) != 1 || !strings.Contains(rt.bodies[0], tt.want) {
+				t.Errorf("responses = %q, want one containing %q", rt.bodies, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlashCommands(t *testing.T) {
+	repo := &memSettings{settings: map[string]store.GuildSetting{
+		settings.AdminRoles: {Key: settings.AdminRoles, Value: "42"},
+	}}
+	svc := settings.NewService(repo, settings.Defaults(config.GuildDefaultsConfig{}), slog.New(slog.DiscardHandler))
+	h := commands.NewHandlers(nil, nil, nil, nil, nil, slog.New(slog.DiscardHandler), noop.NewTracerProvider(), commands.WithSettings(svc))
+
+	// Every definition is routed to a handler, and only those hidden from
+	// members by default are refused to them.
+	for n, def := range commands.SlashCommands() {
+		t.Run(def.Name, func(t *testing.T) {
+			rt := &recordingTransport{}
+			s, _ := discordgo.New("Bot token")
+			s.Client = &http.Client{Transport: rt}
+
+			i := interaction(fmt.Sprintf("interaction-%d", n), def.Name)
+			i.Member.Roles = []string{"7"}
+			h.InteractionCreate(s, i)
+
+			if len(rt.bodies) == 0 {
+				t.Fatal("got no response")
+			}
+			if strings.Contains(rt.bodies[0], "Unknown command") {
+				t.Errorf("response = %q, want the command handled", rt.bodies[0])
+			}
+			if officer := strings.Contains(rt.bodies[0], "`NOT_OFFICER`"); officer != (def.DefaultMemberPermissions != nil) {
+				t.Errorf("refused to members = %t, want %t as DefaultMemberPermissions = %v", officer, !officer, def.DefaultMemberPermissions)
+			}
+		})
+	}
+}
+
+func TestInteractionCreate_Draining(t *testing.T) {
+	h := commands.NewHandlers(nil, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider())
+	if err := h.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain() = %v", err)
+	}
+
+	rt := &recordingTransport{}
+	s, _ := discordgo.New("Bot token")
+	s.Client = &http.Client{Transport: rt}
+
+	h.InteractionCreate(s, interaction("interaction-1", "bid"))
+
+	if len(rt.bodies) != 1 || !strings.Contains(rt.bodies[0], "`HANDOVER`") {
+		t.Errorf("responses = %q, want one handover message", rt.bodies)
+	}
+}
+
+func TestInteractionCreate_RecoversPanic(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	rec, err := metrics.New(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)), "guild-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The handler dereferences its nil managers and panics.
+	h := commands.NewHandlers(nil, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithMetrics(rec))
+
+	rt := &recordingTransport{}
+	s, _ := discordgo.New("Bot token")
+	s.Client = &http.Client{Transport: rt}
+
+	h.InteractionCreate(s, interaction("interaction-1", "dkp"))
+
+	if len(rt.bodies) != 1 {
+		t.Fatalf("got %d responses, want 1", len(rt.bodies))
+	}
+	var resp discordgo.InteractionResponse
+	if err := json.Unmarshal([]byte(rt.bodies[0]), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Data == nil || !strings.Contains(resp.Data.Content, "`PANIC`") {
+		t.Errorf("response = %+v, want failure message with code PANIC", resp.Data)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	var panics int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "dkpbot.command.panics" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				panics += dp.Value
+			}
+		}
+	}
+	if panics != 1 {
+		t.Errorf("got %d recorded panics, want 1", panics)
+	}
+}
+
+// hangingPlayers is a store.PlayerRepository whose lookups hang until
+// their context is done, like a stuck database.
+type hangingPlayers struct {
+	store.PlayerRepository
+}
+
+func (hangingPlayers) GetByDiscordID(ctx context.Context, _ string) (*store.Player, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestInteractionCreate_TimesOut(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	rec, err := metrics.New(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)), "guild-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dkpMgr := dkp.NewManager(hangingPlayers{}, nil, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+	h := commands.NewHandlers(dkpMgr, nil, nil, nil, nil, slog.New(slog.DiscardHandler), noop.NewTracerProvider(),
+		commands.WithMetrics(rec),
+		commands.WithTimeouts(time.Hour, map[string]time.Duration{"dkp": 10 * time.Millisecond}),
+		commands.WithPrefix("!"),
+	)
+
+	rt := &recordingTransport{}
+	s, _ := discordgo.New("Bot token")
+	s.Client = &http.Client{Transport: rt}
+
+	h.InteractionCreate(s, interaction("interaction-1", "dkp"))
+	h.MessageCreate(s, &discordgo.MessageCreate{Message: &discordgo.Message{
+		ID: "message-1", ChannelID: "channel-1", GuildID: "guild-1", Content: "!dkp", Author: &discordgo.User{ID: "user-1"},
+	}})
+
+	// The handler's own response may or may not have been sent before it
+	// was canceled; the last tells the member the command timed out.
+	for _, got := range []string{"interaction", "message"} {
+		if !slices.ContainsFunc(rt.bodies, func(body string) bool {
+			return strings.Contains(body, "`TIMEOUT`") && strings.Contains(body, "message_reference") == (got == "message")
+		}) {
+			t.Errorf("responses = %q, want a timeout reply to the %s", rt.bodies, got)
+		}
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	var timeouts int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "dkpbot.command.timeouts" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				timeouts += dp.Value
+			}
+		}
+	}
+	if timeouts != 2 {
+		t.Errorf("got %d recorded timeouts, want 2", timeouts)
+	}
+}
+
+// memItems implements store.ItemRepository over a fixed catalog.
+type memItems []store.Item
+
+func (memItems) Upsert(context.Context, []store.Item) error { return nil }
+
+func (r memItems) Search(_ context.Context, query string, limit int) ([]store.Item, error) {
+	var out []store.Item
+	for _, it := range r {
+		if strings.Contains(strings.ToLower(it.Name), strings.ToLower(query)) && len(out) < limit {
+			out = append(out, it)
+		}
+	}
+	return out, nil
+}
+
+func (r memItems) GetByName(_ context.Context, name string) (*store.Item, error) {
+	for _, it := range r {
+		if strings.EqualFold(it.Name, name) {
+			return &it, nil
+		}
+	}
+	return nil, store.ErrItemNotFound
+}
+
+func TestInteractionCreate_AutocompletesItems(t *testing.T) {
+	catalog := items.NewCatalog(memItems{
+		{ID: "19019", Name: "Thunderfury, Blessed Blade of the Windseeker", Quality: "legendary"},
+		{ID: "18814", Name: "Choker of the Fire Lord", Quality: "epic"},
+		{ID: "1", Name: strings.Repeat("Very ", 20) + "Long Name"},
+	}, slog.Default(), noop.NewTracerProvider())
+	h := commands.NewHandlers(nil, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithItems(catalog))
+
+	rt := &recordingTransport{}
+	s, _ := discordgo.New("Bot token")
+	s.Client = &http.Client{Transport: rt}
+
+	i := interaction("interaction-1", "auction-start")
+	i.Type = discordgo.InteractionApplicationCommandAutocomplete
+	i.Data = discordgo.ApplicationCommandInteractionData{
+		Name: "auction-start",
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "item", Type: discordgo.ApplicationCommandOptionString, Value: "thunder", Focused: true},
+		},
+	}
+	h.InteractionCreate(s, i)
+
+	if len(rt.bodies) != 1 {
+		t.Fatalf("got %d responses, want 1", len(rt.bodies))
+	}
+	var resp discordgo.InteractionResponse
+	if err := json.Unmarshal([]byte(rt.bodies[0]), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Type != discordgo.InteractionApplicationCommandAutocompleteResult || resp.Data == nil || len(resp.Data.Choices) != 1 {
+		t.Fatalf("response = %s, want one autocomplete choice", rt.bodies[0])
+	}
+	c := resp.Data.Choices[0]
+	if c.Name != "Thunderfury, Blessed Blade of the Windseeker (legendary)" || c.Value != "Thunderfury, Blessed Blade of the Windseeker" {
+		t.Errorf("choice = %+v, want Thunderfury labeled with its quality", c)
+	}
+}
+
+func TestInteractionCreate_AuctionItemTooltip(t *testing.T) {
+	catalog := items.NewCatalog(memItems{
+		{ID: "19019", Name: "Thunderfury, Blessed Blade of the Windseeker", Quality: "legendary", Slot: "One-Hand", Stats: "+5 Agility\n+8 Stamina"},
+	}, slog.Default(), noop.NewTracerProvider())
+	mgr := auction.NewManager(eventtest.NewStore(), storetest.NewPlayers(), slog.Default(), noop.NewTracerProvider(),
+		clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)})
+	h := commands.NewHandlers(nil, mgr, nil, nil, nil, slog.Default(), noop.NewTracerProvider(),
+		commands.WithItems(catalog), commands.WithItemResolver(items.NewLinkResolver("https://www.wowhead.com/classic/item={id}")))
+
+	rt := &recordingTransport{}
+	s, _ := discordgo.New("Bot token")
+	s.Client = &http.Client{Transport: rt}
+	i := interaction("interaction-1", "auction-start")
+	i.Member.Permissions = discordgo.PermissionAdministrator
+	i.Data = discordgo.ApplicationCommandInteractionData{
+		Name: "auction-start",
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "item", Type: discordgo.ApplicationCommandOptionString, Value: "thunderfury, blessed blade of the windseeker"},
+		},
+	}
+	h.InteractionCreate(s, i)
+
+	if len(rt.bodies) == 0 {
+		t.Fatal("no response")
+	}
+	for _, want := range []string{
+		`"title":"Thunderfury, Blessed Blade of the Windseeker"`,
+		`"url":"https://www.wowhead.com/classic/item=19019"`,
+		`{"name":"Slot","value":"One-Hand","inline":true}`,
+		`{"name":"Stats","value":"+5 Agility\n+8 Stamina","inline":true}`,
+	} {
+		if !strings.Contains(rt.bodies[0], want) {
+			t.Errorf("auction start = %s, want it to contain %s", rt.bodies[0], want)
+		}
+	}
+}
+
+func TestInteractionCreate_AnnouncementTemplates(t *testing.T) {
+	repo := &memSettings{settings: map[string]store.GuildSetting{
+		settings.StartedMessage: {Key: settings.StartedMessage, Value: "{item} is up for bids from {min_bid} {currency}!"},
+		settings.NoBidsMessage:  {Key: settings.NoBidsMessage, Value: "Nobody wanted {item}."},
+	}}
+	svc := settings.NewService(repo, settings.Defaults(config.GuildDefaultsConfig{AuctionDuration: 5 * time.Minute, MinIncrement: 1}), slog.Default())
+	mgr := auction.NewManager(eventtest.NewStore(), storetest.NewPlayers(), slog.Default(), noop.NewTracerProvider(),
+		clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}, auction.WithSettings(svc, "guild-1"))
+	h := commands.NewHandlers(nil, mgr, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithSettings(svc))
+
+	run := func(t *testing.T, i *discordgo.InteractionCreate) string {
+		t.Helper()
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		i.Member.Permissions = discordgo.PermissionAdministrator
+		h.InteractionCreate(s, i)
+		if len(rt.bodies) == 0 {
+			t.Fatal("no response")
+		}
+		return rt.bodies[0]
+	}
+
+	start := interaction("i1", "auction-start")
+	start.Data = discordgo.ApplicationCommandInteractionData{Name: "auction-start", Options: []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "item", Type: discordgo.ApplicationCommandOptionString, Value: "Sword"},
+		{Name: "min-bid", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(10)},
+	}}
+	if got := run(t, start); !strings.Contains(got, `"content":"Sword is up for bids from 10 DKP!"`) {
+		t.Errorf("auction start = %s, want the guild's started message", got)
+	}
+
+	a, err := mgr.StartAuction(context.Background(), "Shield", "officer", 5, 0, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("StartAuction: %v", err)
+	}
+	closing := interaction("i2", "auction-close")
+	closing.Data = discordgo.ApplicationCommandInteractionData{Name: "auction-close", Options: []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "auction-id", Type: discordgo.ApplicationCommandOptionString, Value: a.ID},
+	}}
+	if got := run(t, closing); !strings.Contains(got, `"content":"Nobody wanted Shield."`) {
+		t.Errorf("auction close = %s, want the guild's no bids message", got)
+	}
+}
+
+func TestInteractionCreate_RollEndsOnClock(t *testing.T) {
+	svc := settings.NewService(storetest.NewGuildSettings(),
+		settings.Defaults(config.GuildDefaultsConfig{AuctionDuration: 5 * time.Minute, MinIncrement: 1, RollWindow: time.Minute}), slog.Default())
+	clk := clock.NewFakeClock(time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC))
+	es := eventtest.NewStore(eventtest.WithClock(clk))
+	mgr := auction.NewManager(es, storetest.NewPlayers(), slog.Default(), noop.NewTracerProvider(), clk, auction.WithSettings(svc, "guild-1"))
+	h := commands.NewHandlers(nil, mgr, nil, nil, nil, slog.Default(), noop.NewTracerProvider(),
+		commands.WithSettings(svc), commands.WithClock(clk))
+
+	a, err := mgr.StartAuction(context.Background(), "Shield", "officer", 5, 0, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("StartAuction: %v", err)
+	}
+	closing := interaction("i1", "auction-close")
+	closing.Member.Permissions = discordgo.PermissionAdministrator
+	closing.Data = discordgo.ApplicationCommandInteractionData{Name: "auction-close", Options: []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "auction-id", Type: discordgo.ApplicationCommandOptionString, Value: a.ID},
+	}}
+	s, _ := discordtest.NewSession()
+	h.InteractionCreate(s, closing)
+	es.RequireTypes(t, event.AuctionStarted, event.AuctionRollStarted)
+
+	// Nobody rolls, so the auction closes once the roll window ends.
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	if closed := es.Await(t, event.AuctionClosed); !closed.CreatedAt.Equal(clk.Now()) {
+		t.Errorf("closed at %v, want at the end of the roll window %v", closed.CreatedAt, clk.Now())
+	}
+}
+
+// memPlayers implements the lookups of store.PlayerRepository over a fixed
+// set of players.
+type memPlayers struct {
+	store.PlayerRepository
+	players []store.Player
+}
+
+func (r memPlayers) GetByDiscordID(_ context.Context, discordID string) (*store.Player, error) {
+	for _, p := range r.players {
+		if p.DiscordID == discordID {
+			return &p, nil
+		}
+	}
+	return nil, store.ErrPlayerNotFound
+}
+
+// memWishlists implements the player lists of store.WishlistRepository.
+type memWishlists struct {
+	store.WishlistRepository
+	entries []store.WishlistEntry
+}
+
+func (r *memWishlists) Add(_ context.Context, e *store.WishlistEntry) error {
+	r.entries = append(r.entries, *e)
+	return nil
+}
+
+func (r *memWishlists) ListByPlayer(_ context.Context, playerID string) ([]store.WishlistEntry, error) {
+	var out []store.WishlistEntry
+	for _, e := range r.entries {
+		if e.PlayerID == playerID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func TestInteractionCreate_Wishlist(t *testing.T) {
+	catalog := items.NewCatalog(memItems{
+		{ID: "19019", Name: "Thunderfury, Blessed Blade of the Windseeker", Quality: "legendary"},
+	}, slog.Default(), noop.NewTracerProvider())
+	svc := wishlist.NewService(&memWishlists{}, memPlayers{players: []store.Player{{ID: "p1", DiscordID: "user-1"}}}, catalog, slog.Default())
+	h := commands.NewHandlers(nil, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithWishlist(svc))
+
+	tests := []struct {
+		name string
+		user string
+		sub  *discordgo.ApplicationCommandInteractionDataOption
+		want string
+	}{
+		{
+			name: "show empty",
+			user: "user-1",
+			sub:  &discordgo.ApplicationCommandInteractionDataOption{Name: "show", Type: discordgo.ApplicationCommandOptionSubCommand},
+			want: "Your wishlist is empty",
+		},
+		{
+			name: "add",
+			user: "user-1",
+			sub: &discordgo.ApplicationCommandInteractionDataOption{Name: "add", Type: discordgo.ApplicationCommandOptionSubCommand, Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "item", Type: discordgo.ApplicationCommandOptionString, Value: "thunderfury, blessed blade of the windseeker"},
+			}},
+			want: "Added **Thunderfury, Blessed Blade of the Windseeker** to your wishlist",
+		},
+		{
+			name: "show",
+			user: "user-1",
+			sub:  &discordgo.ApplicationCommandInteractionDataOption{Name: "show", Type: discordgo.ApplicationCommandOptionSubCommand},
+			want: `- Thunderfury, Blessed Blade of the Windseeker\n`,
+		},
+		{
+			name: "unregistered",
+			user: "user-2",
+			sub:  &discordgo.ApplicationCommandInteractionDataOption{Name: "show", Type: discordgo.ApplicationCommandOptionSubCommand},
+			want: "You are not registered",
+		},
+	}
+	for n, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &recordingTransport{}
+			s, _ := discordgo.New("Bot token")
+			s.Client = &http.Client{Transport: rt}
+
+			i := interaction(fmt.Sprintf("interaction-%d", n), "wishlist")
+			i.Member.User.ID = tt.user
+			i.Data = discordgo.ApplicationCommandInteractionData{
+				Name:    "wishlist",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{tt.sub},
+			}
+			h.InteractionCreate(s, i)
+
+			if len(rt.bodies) != 1 || !strings.Contains(rt.bodies[0], tt.want) {
+				t.Errorf("responses = %q, want one containing %q", rt.bodies, tt.want)
+			}
+		})
+	}
+}
+
+func TestInteractionCreate_Button(t *testing.T) {
+	// Buttons are routed by the action in their custom ID. The nil auction
+	// manager makes the action fail, which still produces one response.
+	h := commands.NewHandlers(nil, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider())
+
+	for _, customID := range []string{"auction-buyout:auction-1", "auction-roll:auction-1"} {
+		t.Run(customID, func(t *testing.T) {
+			rt := &recordingTransport{}
+			s, _ := discordgo.New("Bot token")
+			s.Client = &http.Client{Transport: rt}
+
+			i := interaction("interaction-1", "")
+			i.Type = discordgo.InteractionMessageComponent
+			i.Data = discordgo.MessageComponentInteractionData{CustomID: customID, ComponentType: discordgo.ButtonComponent}
+			h.InteractionCreate(s, i)
+
+			if len(rt.bodies) != 1 || strings.Contains(rt.bodies[0], "Unknown command") {
+				t.Errorf("responses = %q, want one from the button's handler", rt.bodies)
+			}
+		})
+	}
+}
+
+// memEvents is an event.Store in memory.
+type memEvents struct {
+	events []event.Event
+}
+
+func (m *memEvents) Append(_ context.Context, events ...event.Event) error {
+	m.events = append(m.events, events...)
+	return nil
+}
+
+func (m *memEvents) Load(_ context.Context, aggregateID string) ([]event.Event, error) {
+	var out []event.Event
+	for _, e := range m.events {
+		if e.AggregateID == aggregateID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (m *memEvents) LoadByType(_ context.Context, t event.Type) ([]event.Event, error) {
+	var out []event.Event
+	for _, e := range m.events {
+		if e.Type == t {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (m *memEvents) Query(_ context.Context, q event.Query) ([]event.Event, error) {
+	return q.Filter(m.events), nil
+}
+
+func TestInteractionCreate_GDKPRaid(t *testing.T) {
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	raids := gdkp.NewService(&memEvents{}, config.GDKPConfig{OrganizerCut: 10}, slog.Default(), noop.NewTracerProvider(), clk)
+	h := commands.NewHandlers(nil, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithGDKP(raids))
+
+	tests := []struct {
+		command string
+		user    string
+		options []*discordgo.ApplicationCommandInteractionDataOption
+		want    string
+	}{
+		{command: "raid-pot", user: "user-1", want: "`NO_RAID`"},
+		{
+			command: "raid-start",
+			user:    "officer",
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "name", Type: discordgo.ApplicationCommandOptionString, Value: "Naxx"},
+			},
+			want: "GDKP raid **Naxx** started!",
+		},
+		{command: "raid-join", user: "user-1", want: "joined GDKP raid **Naxx**. Participants: 1"},
+		{command: "raid-join", user: "user-1", want: "`ALREADY_JOINED`"},
+		{command: "raid-pot", user: "user-2", want: "**0 gold** from 0 items sold"},
+		{command: "raid-loot", user: "user-2", want: "**Loot of GDKP raid Naxx**"},
+		{command: "raid-end", user: "officer", want: `Organizer cut (10%): \u003c@officer\u003e — 0 gold`},
+		{command: "raid-end", user: "officer", want: "`NO_RAID`"},
+		{command: "raid-loot", user: "user-2", want: "`NO_RAID`"},
+	}
+	for n, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			rt := &recordingTransport{}
+			s, _ := discordgo.New("Bot token")
+			s.Client = &http.Client{Transport: rt}
+
+			i := interaction(fmt.Sprintf("interaction-%d", n), tt.command)
+			i.Member.User.ID = tt.user
+			i.Member.Permissions = discordgo.PermissionAdministrator
+			i.Data = discordgo.ApplicationCommandInteractionData{Name: tt.command, Options: tt.options}
+			h.InteractionCreate(s, i)
+
+			if len(rt.bodies) != 1 || !strings.Contains(rt.bodies[0], tt.want) {
+				t.Errorf("responses = %q, want one containing %q", rt.bodies, tt.want)
+			}
+		})
+	}
+}
+
+func TestInteractionCreate_DKPRaid(t *testing.T) {
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	raids := gdkp.NewService(&memEvents{}, config.GDKPConfig{OrganizerCut: 10}, slog.Default(), noop.NewTracerProvider(), clk)
+	h := commands.NewHandlers(nil, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithGDKP(raids))
+
+	tests := []struct {
+		command string
+		user    string
+		options []*discordgo.ApplicationCommandInteractionDataOption
+		want    string
+	}{
+		{
+			command: "raid-start",
+			user:    "officer",
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "name", Type: discordgo.ApplicationCommandOptionString, Value: "Molten Core"},
+				{Name: "mode", Type: discordgo.ApplicationCommandOptionString, Value: "dkp"},
+			},
+			want: "DKP raid **Molten Core** started!",
+		},
+		{command: "raid-join", user: "user-1", want: "joined DKP raid **Molten Core**"},
+		{command: "raid-pot", user: "user-2", want: "0 items awarded for **0 DKP**"},
+		{command: "raid-loot", user: "user-2", want: "**Loot of DKP raid Molten Core**"},
+		{command: "raid-end", user: "officer", want: "**DKP raid Molten Core ended.** 0 items awarded"},
+		{
+			command: "raid-loot",
+			user:    "user-2",
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "raid", Type: discordgo.ApplicationCommandOptionString, Value: "raid-1750017600000000000"},
+			},
+			want: "**Loot of DKP raid Molten Core** (`raid-1750017600000000000`): 0 items for **0 DKP**.",
+		},
+	}
+	for n, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			rt := &recordingTransport{}
+			s, _ := discordgo.New("Bot token")
+			s.Client = &http.Client{Transport: rt}
+
+			i := interaction(fmt.Sprintf("interaction-%d", n), tt.command)
+			i.Member.User.ID = tt.user
+			i.Member.Permissions = discordgo.PermissionAdministrator
+			i.Data = discordgo.ApplicationCommandInteractionData{Name: tt.command, Options: tt.options}
+			h.InteractionCreate(s, i)
+
+			if len(rt.bodies) != 1 || !strings.Contains(rt.bodies[0], tt.want) {
+				t.Errorf("responses = %q, want one containing %q", rt.bodies, tt.want)
+			}
+		})
+	}
+}
+
+func TestInteractionCreate_AuctionPause(t *testing.T) {
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	mgr := auction.NewManager(&memEvents{}, nil, slog.Default(), noop.NewTracerProvider(), clk)
+	a, err := mgr.StartAuction(context.Background(), "Sword", "officer", 10, 0, 0, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("StartAuction() error = %v", err)
+	}
+	h := commands.NewHandlers(nil, mgr, nil, nil, nil, slog.Default(), noop.NewTracerProvider())
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{command: "auction-resume", want: "`NOT_PAUSED`"},
+		{command: "auction-pause", want: "Bids are rejected until it is resumed."},
+		{command: "auction-pause", want: "`AUCTION_PAUSED`"},
+		{command: "auction-resume", want: fmt.Sprintf(`it now ends \u003ct:%d:R\u003e`, a.StartedAt.Add(5*time.Minute).Unix())},
+	}
+	for n, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			rt := &recordingTransport{}
+			s, _ := discordgo.New("Bot token")
+			s.Client = &http.Client{Transport: rt}
+
+			i := interaction(fmt.Sprintf("interaction-%d", n), tt.command)
+			i.Member.Permissions = discordgo.PermissionAdministrator
+			i.Data = discordgo.ApplicationCommandInteractionData{
+				Name: tt.command,
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{Name: "auction-id", Type: discordgo.ApplicationCommandOptionString, Value: a.ID},
+				},
+			}
+			h.InteractionCreate(s, i)
+
+			if len(rt.bodies) != 1 || !strings.Contains(rt.bodies[0], tt.want) {
+				t.Errorf("responses = %q, want one containing %q", rt.bodies, tt.want)
+			}
+		})
+	}
+}
+
+func TestInteractionCreate_AuctionInfo(t *testing.T) {
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	mgr := auction.NewManager(&memEvents{}, nil, slog.Default(), noop.NewTracerProvider(), clk)
+	a, err := mgr.StartAuction(context.Background(), "Sword", "officer", 10, 50, 0, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("StartAuction() error = %v", err)
+	}
+	h := commands.NewHandlers(nil, mgr, nil, nil, nil, slog.Default(), noop.NewTracerProvider())
+
+	tests := []struct {
+		auctionID string
+		want      []string
+	}{
+		{auctionID: a.ID, want: []string{`"title":"Sword"`, "open, ends", `"name":"Buyout","value":"50"`, "No bids."}},
+		{auctionID: "auction-0", want: []string{"`AUCTION_NOT_FOUND`"}},
+	}
+	for n, tt := range tests {
+		t.Run(tt.auctionID, func(t *testing.T) {
+			rt := &recordingTransport{}
+			s, _ := discordgo.New("Bot token")
+			s.Client = &http.Client{Transport: rt}
+
+			i := interaction(fmt.Sprintf("interaction-%d", n), "auction-info")
+			i.Data = discordgo.ApplicationCommandInteractionData{
+				Name: "auction-info",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{Name: "auction-id", Type: discordgo.ApplicationCommandOptionString, Value: tt.auctionID},
+				},
+			}
+			h.InteractionCreate(s, i)
+
+			if len(rt.bodies) != 1 {
+				t.Fatalf("responses = %q, want one", rt.bodies)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(rt.bodies[0], want) {
+					t.Errorf("response = %q, want it to contain %q", rt.bodies[0], want)
+				}
+			}
+		})
+	}
+}
+
+func TestInteractionCreate_BidButtons(t *testing.T) {
+	players := storetest.NewPlayers(
+		store.Player{DiscordID: "user-1", CharacterName: "Frodo", DKP: 100},
+		store.Player{DiscordID: "user-2", CharacterName: "Sam", DKP: 100},
+	)
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	mgr := auction.NewManager(eventtest.NewStore(), players, slog.Default(), noop.NewTracerProvider(), clk)
+	h := commands.NewHandlers(nil, mgr, nil, nil, nil, slog.Default(), noop.NewTracerProvider())
+
+	n := 0
+	run := func(t *testing.T, i *discordgo.InteractionCreate, user string) string {
+		t.Helper()
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		n++
+		i.ID = fmt.Sprintf("interaction-%d", n)
+		i.Member.User.ID = user
+		i.Member.Permissions = discordgo.PermissionAdministrator
+		h.InteractionCreate(s, i)
+		// Starting an auction also fetches its response to record it.
+		if len(rt.bodies) == 0 {
+			t.Fatal("no response")
+		}
+		return rt.bodies[0]
+	}
+	click := func(customID string) *discordgo.InteractionCreate {
+		i := interaction("", "")
+		i.Type = discordgo.InteractionMessageComponent
+		i.Data = discordgo.MessageComponentInteractionData{CustomID: customID, ComponentType: discordgo.ButtonComponent}
+		return i
+	}
+
+	start := interaction("", "auction-start")
+	start.Data = discordgo.ApplicationCommandInteractionData{
+		Name: "auction-start",
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "item", Type: discordgo.ApplicationCommandOptionString, Value: "Sword"},
+			{Name: "min-bid", Type: discordgo.ApplicationCommandOptionInteger, Value: 10.0},
+		},
+	}
+	got := run(t, start, "officer")
+	open := mgr.OpenAuctions()
+	if len(open) != 1 {
+		t.Fatalf("open auctions = %v, want one", open)
+	}
+	id := open[0]
+	for _, want := range []string{`"label":"+1"`, `"custom_id":"auction-bid:` + id + `:5"`, `"custom_id":"auction-bid:` + id + `:10"`, `"custom_id":"auction-bid-custom:` + id + `"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("auction start = %q, want it to contain %q", got, want)
+		}
+	}
+
+	// The first quick bid is the minimum bid; later ones raise the highest.
+	if got := run(t, click("auction-bid:"+id+":5"), "user-1"); !strings.Contains(got, "Bid of **10 DKP** placed") {
+		t.Errorf("first quick bid = %q, want the minimum bid", got)
+	}
+	if got := run(t, click("auction-bid:"+id+":10"), "user-2"); !strings.Contains(got, "Bid of **20 DKP** placed") {
+		t.Errorf("quick bid = %q, want 10 above the highest bid", got)
+	}
+
+	if got := run(t, click("auction-bid-custom:"+id), "user-1"); !strings.Contains(got, `"type":9`) || !strings.Contains(got, `"custom_id":"auction-bid-amount:`+id+`"`) {
+		t.Errorf("custom bid = %q, want a modal for the amount", got)
+	}
+	submit := func(value string) *discordgo.InteractionCreate {
+		i := interaction("", "")
+		i.Type = discordgo.InteractionModalSubmit
+		i.Data = discordgo.ModalSubmitInteractionData{
+			CustomID: "auction-bid-amount:" + id,
+			Components: []discordgo.MessageComponent{&discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				&discordgo.TextInput{CustomID: "amount", Value: value},
+			}}},
+		}
+		return i
+	}
+	if got := run(t, submit("lots"), "user-1"); !strings.Contains(got, "whole number") {
+		t.Errorf("custom bid of %q = %q, want it rejected", "lots", got)
+	}
+	if got := run(t, submit(" 42 "), "user-1"); !strings.Contains(got, "Bid of **42 DKP** placed") {
+		t.Errorf("custom bid = %q, want 42 placed", got)
+	}
+}
+
+func TestInteractionCreate_BidConfirmation(t *testing.T) {
+	players := storetest.NewPlayers(
+		store.Player{DiscordID: "user-1", CharacterName: "Frodo", DKP: 100},
+		store.Player{DiscordID: "user-2", CharacterName: "Sam", DKP: 100},
+	)
+	svc := settings.NewService(&memSettings{settings: map[string]store.GuildSetting{}},
+		settings.Defaults(config.GuildDefaultsConfig{ConfirmBidPercent: 50}), slog.Default())
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	mgr := auction.NewManager(eventtest.NewStore(), players, slog.Default(), noop.NewTracerProvider(), clk,
+		auction.WithSettings(svc, "guild-1"))
+	a, err := mgr.StartAuction(context.Background(), "Sword", "officer", 10, 0, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("StartAuction: %v", err)
+	}
+	h := commands.NewHandlers(nil, mgr, nil, nil, nil, slog.Default(), noop.NewTracerProvider())
+
+	n := 0
+	run := func(t *testing.T, i *discordgo.InteractionCreate) string {
+		t.Helper()
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		n++
+		i.ID = fmt.Sprintf("interaction-%d", n)
+		h.InteractionCreate(s, i)
+		if len(rt.bodies) != 1 {
+			t.Fatalf("responses = %q, want one", rt.bodies)
+		}
+		return rt.bodies[0]
+	}
+	bid := func(amount int) *discordgo.InteractionCreate {
+		i := interaction("", "bid")
+		i.Data = discordgo.ApplicationCommandInteractionData{Name: "bid", Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "auction-id", Type: discordgo.ApplicationCommandOptionString, Value: a.ID},
+			{Name: "amount", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(amount)},
+		}}
+		return i
+	}
+
+	first := bid(50)
+	first.Member.User.ID = "user-2"
+	if got := run(t, first); !strings.Contains(got, "Bid of **50 DKP** placed") {
+		t.Errorf("bid of half the balance = %q, want it placed at once", got)
+	}
+	got := run(t, bid(90))
+	for _, want := range []string{"Confirm bid of **90 DKP** on auction `" + a.ID + "`? That is 90% of your 100 DKP.", `"flags":64`, `"custom_id":"auction-bid-confirm:` + a.ID + `:90"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("large bid = %q, want it to contain %q", got, want)
+		}
+	}
+	if bids := a.State().Bids; len(bids) != 1 {
+		t.Errorf("bids before confirming = %+v, want only the first", bids)
+	}
+
+	confirm := interaction("", "")
+	confirm.Type = discordgo.InteractionMessageComponent
+	confirm.Data = discordgo.MessageComponentInteractionData{CustomID: "auction-bid-confirm:" + a.ID + ":90", ComponentType: discordgo.ButtonComponent}
+	if got := run(t, confirm); !strings.Contains(got, `"type":7`) || !strings.Contains(got, "Bid of **90 DKP** placed") {
+		t.Errorf("confirm = %q, want the prompt replaced by the placed bid", got)
+	}
+}
+
+// rolePlayers is a store.PlayerRepository whose only player is the tank
+// of member user-1.
+type rolePlayers struct{ store.PlayerRepository }
+
+func (rolePlayers) GetByDiscordID(_ context.Context, discordID string) (*store.Player, error) {
+	if discordID != "user-1" {
+		return nil, fmt.Errorf("player %s not found", discordID)
+	}
+	return &store.Player{ID: "p1", DiscordID: discordID, Profile: store.Profile{Role: dkp.RoleTank}}, nil
+}
+
+func TestInteractionCreate_ScheduledAuction(t *testing.T) {
+	es := eventtest.NewStore()
+	players := storetest.NewPlayers(store.Player{DiscordID: "user-1", CharacterName: "Frodo", DKP: 100})
+	now := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+	mgr := auction.NewManager(es, players, slog.Default(), noop.NewTracerProvider(), clock.Mock{T: now})
+	h := commands.NewHandlers(nil, mgr, nil, nil, nil, slog.Default(), noop.NewTracerProvider())
+	ctx := context.Background()
+
+	start := func(startsAt string) string {
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		i := interaction("interaction-"+startsAt, "auction-start")
+		i.Member.Permissions = discordgo.PermissionAdministrator
+		i.Data = discordgo.ApplicationCommandInteractionData{
+			Name: "auction-start",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "item", Type: discordgo.ApplicationCommandOptionString, Value: "Sword"},
+				{Name: "starts-at", Type: discordgo.ApplicationCommandOptionString, Value: startsAt},
+			},
+		}
+		h.InteractionCreate(s, i)
+		if len(rt.bodies) == 0 {
+			t.Fatal("no response")
+		}
+		return rt.bodies[0]
+	}
+
+	if got := start("tonight"); !strings.Contains(got, "Invalid start") {
+		t.Errorf("auction start = %q, want the start rejected", got)
+	}
+	if got := start("2025-06-15 19:00"); !strings.Contains(got, "must start in the future") {
+		t.Errorf("auction start = %q, want a past start rejected", got)
+	}
+	opens := now.Add(time.Hour)
+	got := start("2025-06-15 21:00")
+	for _, want := range []string{"Auction scheduled!", fmt.Sprintf(`Opens \u003ct:%d:F\u003e`, opens.Unix())} {
+		if !strings.Contains(got, want) {
+			t.Errorf("auction start = %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "auction-bid:") {
+		t.Errorf("auction start = %q, want no bid buttons before the auction opens", got)
+	}
+	states, err := mgr.ListOpenAuctions(ctx)
+	if err != nil || len(states) != 1 || states[0].Status != "scheduled" {
+		t.Fatalf("ListOpenAuctions() = %+v, %v, want the scheduled auction", states, err)
+	}
+	id := states[0].ID
+	if err := mgr.RecordAnnouncement(ctx, id, "channel-1", "message-1"); err != nil {
+		t.Fatalf("RecordAnnouncement() error = %v", err)
+	}
+
+	// The leader opens the auction once its start time comes, turning its
+	// announcement into that of its start.
+	later := auction.NewManager(es, players, slog.Default(), noop.NewTracerProvider(), clock.Mock{T: opens})
+	if _, err := later.RecoverOpenAuctions(ctx); err != nil {
+		t.Fatalf("RecoverOpenAuctions() error = %v", err)
+	}
+	rt := &recordingTransport{}
+	s, _ := discordgo.New("Bot token")
+	s.Client = &http.Client{Transport: rt}
+	commands.NewHandlers(nil, later, nil, nil, nil, slog.Default(), noop.NewTracerProvider()).OpenScheduled(ctx, s, "guild-1")
+	if len(rt.bodies) != 1 || !strings.Contains(rt.bodies[0], "Auction started!") || !strings.Contains(rt.bodies[0], `"custom_id":"auction-bid:`+id+`:1"`) {
+		t.Errorf("announcement edits = %q, want the start with bid buttons", rt.bodies)
+	}
+	if open := later.OpenAuctions(); !slices.Equal(open, []string{id}) {
+		t.Errorf("OpenAuctions() = %v, want [%s]", open, id)
+	}
+}
+
+func TestInteractionCreate_Timezones(t *testing.T) {
+	players := storetest.NewPlayers(
+		store.Player{DiscordID: "user-1", CharacterName: "Frodo", Profile: store.Profile{Timezone: "America/New_York"}},
+		store.Player{DiscordID: "user-2", CharacterName: "Sam"},
+	)
+	now := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+	svc := settings.NewService(&memSettings{settings: map[string]store.GuildSetting{
+		settings.Timezone: {Key: settings.Timezone, Value: "Europe/Copenhagen"},
+	}}, settings.Defaults(config.GuildDefaultsConfig{}), slog.Default())
+	dkpMgr := dkp.NewManager(players, eventtest.NewStore(), slog.Default(), noop.NewTracerProvider())
+
+	start := func(userID, startsAt string) string {
+		mgr := auction.NewManager(eventtest.NewStore(), players, slog.Default(), noop.NewTracerProvider(), clock.Mock{T: now})
+		h := commands.NewHandlers(dkpMgr, mgr, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithSettings(svc))
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		i := interaction("interaction-"+userID+startsAt, "auction-start")
+		i.Member.User.ID = userID
+		i.Member.Permissions = discordgo.PermissionAdministrator
+		i.Data = discordgo.ApplicationCommandInteractionData{
+			Name: "auction-start",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "item", Type: discordgo.ApplicationCommandOptionString, Value: "Sword"},
+				{Name: "starts-at", Type: discordgo.ApplicationCommandOptionString, Value: startsAt},
+			},
+		}
+		h.InteractionCreate(s, i)
+		if len(rt.bodies) == 0 {
+			t.Fatal("no response")
+		}
+		return rt.bodies[0]
+	}
+
+	tests := []struct {
+		name, userID, startsAt string
+		want                   string
+	}{
+		{"in the guild's timezone", "user-2", "2025-06-15 23:00", fmt.Sprintf(`Opens \u003ct:%d:F\u003e`, now.Add(time.Hour).Unix())},
+		{"in the player's timezone", "user-1", "2025-06-15 17:30", fmt.Sprintf(`Opens \u003ct:%d:F\u003e`, now.Add(90*time.Minute).Unix())},
+		{"named when rejected", "user-2", "tonight", "give a date and time in Europe/Copenhagen"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := start(tt.userID, tt.startsAt); !strings.Contains(got, tt.want) {
+				t.Errorf("auction start = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// settableClock is a mock clock whose time the test moves on.
+type settableClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *settableClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *settableClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = t
+}
+
+// editTransport answers Discord REST calls as if they succeeded, serving
+// fetched messages with one embed, and keeps the bodies of message edits.
+type editTransport struct {
+	mu    sync.Mutex
+	edits []string
+}
+
+func (rt *editTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	resp := `{"id":"message-1","channel_id":"channel-1","embeds":[{"title":"Sword"}]}`
+	if req.Method == http.MethodPatch {
+		rt.edits = append(rt.edits, string(body))
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(resp)),
+		Request:    req,
+	}, nil
+}
+
+func TestUpdateCountdowns(t *testing.T) {
+	es := eventtest.NewStore()
+	players := storetest.NewPlayers()
+	start := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+	clk := &settableClock{t: start}
+	mgr := auction.NewManager(es, players, slog.Default(), noop.NewTracerProvider(), clk)
+	h := commands.NewHandlers(nil, mgr, nil, nil, nil, slog.Default(), noop.NewTracerProvider())
+	ctx := context.Background()
+
+	a, err := mgr.StartAuction(ctx, "Sword", "officer-1", 10, 0, 0, 4*time.Minute)
+	if err != nil {
+		t.Fatalf("StartAuction() error = %v", err)
+	}
+	if err := mgr.RecordAnnouncement(ctx, a.ID, "channel-1", "message-1"); err != nil {
+		t.Fatalf("RecordAnnouncement() error = %v", err)
+	}
+
+	rt := &editTransport{}
+	s, _ := discordgo.New("Bot token")
+	s.Client = &http.Client{Transport: rt}
+
+	// Each step moves the clock on and updates the countdowns twice: a
+	// milestone is shown once.
+	for _, step := range []struct {
+		elapsed time.Duration
+		want    string
+	}{
+		{elapsed: time.Minute},
+		{elapsed: 2 * time.Minute, want: `Closes in 2:00`},
+		{elapsed: 2*time.Minute + 30*time.Second},
+		{elapsed: 3*time.Minute + 5*time.Second, want: `Closes in 0:55`},
+		{elapsed: 3*time.Minute + 50*time.Second, want: `Closes in 0:10`},
+		{elapsed: 4*time.Minute + time.Second, want: `Time is up`},
+	} {
+		clk.Set(start.Add(step.elapsed))
+		rt.edits = nil
+		h.UpdateCountdowns(ctx, s)
+		h.UpdateCountdowns(ctx, s)
+		if step.want == "" {
+			if len(rt.edits) != 0 {
+				t.Errorf("after %v: edits = %q, want none", step.elapsed, rt.edits)
+			}
+			continue
+		}
+		if len(rt.edits) != 1 {
+			t.Fatalf("after %v: edits = %q, want one", step.elapsed, rt.edits)
+		}
+		for _, want := range []string{`"title":"Sword"`, `"name":"Time left"`, step.want} {
+			if !strings.Contains(rt.edits[0], want) {
+				t.Errorf("after %v: edit = %q, want it to contain %q", step.elapsed, rt.edits[0], want)
+			}
+		}
+	}
+}
+
+func TestInteractionCreate_RaidCalendar(t *testing.T) {
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	events := &memEvents{}
+	dkpMgr := dkp.NewManager(rolePlayers{}, events, slog.Default(), noop.NewTracerProvider())
+	raids := calendar.NewService(events, dkpMgr, config.CalendarConfig{}, slog.Default(), noop.NewTracerProvider(), clk)
+	h := commands.NewHandlers(dkpMgr, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithCalendar(raids))
+
+	run := func(t *testing.T, i *discordgo.InteractionCreate) string {
+		t.Helper()
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		i.Member.Permissions = discordgo.PermissionAdministrator
+		h.InteractionCreate(s, i)
+		if len(rt.bodies) != 1 {
+			t.Fatalf("responses = %q, want one", rt.bodies)
+		}
+		return rt.bodies[0]
+	}
+	command := func(id, name string, opts ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+		i := interaction(id, name)
+		i.Data = discordgo.ApplicationCommandInteractionData{Name: name, Options: opts}
+		return i
+	}
+	option := func(name string, value any) *discordgo.ApplicationCommandInteractionDataOption {
+		typ := discordgo.ApplicationCommandOptionString
+		switch value.(type) {
+		case float64:
+			typ = discordgo.ApplicationCommandOptionInteger
+		case bool:
+			typ = discordgo.ApplicationCommandOptionBoolean
+		}
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: typ, Value: value}
+	}
+
+	if got := run(t, command("i1", "raid-schedule", option("name", "Naxx"), option("start", "tomorrow"))); !strings.Contains(got, "Invalid start") {
+		t.Errorf("schedule with an invalid start = %q", got)
+	}
+	got := run(t, command("i2", "raid-schedule", option("name", "Naxx"), option("start", "2025-06-16 19:30"), option("tanks", 2.0)))
+	if !strings.Contains(got, "Tanks 0/2") || !strings.Contains(got, `"custom_id":"raid-signup:scheduled-`) {
+		t.Fatalf("schedule = %q, want the raid with its quotas and signup buttons", got)
+	}
+
+	upcoming, _ := raids.Upcoming(context.Background())
+	if len(upcoming) != 1 {
+		t.Fatalf("upcoming raids = %d, want 1", len(upcoming))
+	}
+	click := interaction("i3", "")
+	click.Type = discordgo.InteractionMessageComponent
+	click.Data = discordgo.MessageComponentInteractionData{CustomID: "raid-signup:" + upcoming[0].ID + ":accepted", ComponentType: discordgo.ButtonComponent}
+	if got := run(t, click); !strings.Contains(got, `"type":7`) || !strings.Contains(got, "Tanks 1/2") || !strings.Contains(got, "1 accepted") {
+		t.Errorf("signup = %q, want the message updated with one accepted tank", got)
+	}
+
+	if got := run(t, command("i4", "raid-calendar")); !strings.Contains(got, "**Naxx**: 1 accepted, 0 tentative") {
+		t.Errorf("calendar = %q", got)
+	}
+
+	if got := run(t, command("i5", "raid-compose", option("raid", upcoming[0].ID))); !strings.Contains(got, "Proposed roster for Naxx**: 1 picked, 0 benched") || !strings.Contains(got, "Short of 1 tanks") {
+		t.Errorf("compose preview = %q, want the roster a tank short", got)
+	}
+	if got := run(t, command("i6", "raid-compose", option("raid", upcoming[0].ID), option("confirm", true))); !strings.Contains(got, "Roster set for Naxx") || !strings.Contains(got, `"name":"Standby"`) {
+		t.Errorf("compose = %q, want the raid posted with its roster", got)
+	}
+}
+
+// listedPlayers serves a fixed roster.
+type listedPlayers struct {
+	store.PlayerRepository
+	players []store.Player
+}
+
+func (l listedPlayers) GetByDiscordID(_ context.Context, discordID string) (*store.Player, error) {
+	for _, p := range l.players {
+		if p.DiscordID == discordID {
+			return &p, nil
+		}
+	}
+	return nil, store.ErrPlayerNotFound
+}
+
+func (l listedPlayers) List(context.Context) ([]store.Player, error) { return l.players, nil }
+
+func TestInteractionCreate_DKPCharts(t *testing.T) {
+	now := time.Date(2025, 6, 18, 12, 0, 0, 0, time.UTC)
+	events := &memEvents{}
+	for n, c := range []struct {
+		amount int
+		reason string
+		at     time.Time
+	}{
+		{100, "Molten Core", now.AddDate(0, 0, -9)},
+		{-30, "Item: Sword", now.AddDate(0, 0, -1)},
+	} {
+		typ := event.DKPAwarded
+		if c.amount < 0 {
+			typ = event.DKPDeducted
+		}
+		data, _ := json.Marshal(event.DKPChangeData{PlayerID: "p1", Amount: c.amount, Reason: c.reason})
+		events.events = append(events.events, event.Event{ID: fmt.Sprintf("evt-%d", n), AggregateID: "p1", Type: typ, Data: data, CreatedAt: c.at})
+	}
+	taxed, _ := json.Marshal(event.AuctionTaxedData{WinnerID: "p1", Rate: 10, Amount: 3})
+	events.events = append(events.events, event.Event{ID: "evt-tax", AggregateID: "auction-1", Type: event.AuctionTaxed, Data: taxed, CreatedAt: now.AddDate(0, 0, -1)})
+	started, _ := json.Marshal(event.AuctionStartedData{ItemName: "Sword"})
+	closed, _ := json.Marshal(event.AuctionClosedData{WinnerID: "p1", Amount: 30})
+	events.events = append(events.events,
+		event.Event{ID: "evt-start", AggregateID: "auction-1", Type: event.AuctionStarted, Data: started, CreatedAt: now.AddDate(0, 0, -1)},
+		event.Event{ID: "evt-close", AggregateID: "auction-1", Type: event.AuctionClosed, Data: closed, CreatedAt: now.AddDate(0, 0, -1)},
+	)
+	players := listedPlayers{players: []store.Player{
+		{ID: "p1", DiscordID: "user-1", CharacterName: "Gandalf", DKP: 70},
+		{ID: "p2", DiscordID: "user-2", CharacterName: "Frodo", DKP: 5},
+	}}
+	dkpMgr := dkp.NewManager(players, events, slog.Default(), noop.NewTracerProvider(), dkp.WithClock(clock.Mock{T: now}))
+	exporter := export.NewExporter(players, events, noop.NewTracerProvider())
+	h := commands.NewHandlers(dkpMgr, nil, nil, exporter, nil, slog.Default(), noop.NewTracerProvider(),
+		commands.WithEconomy(economy.NewProjection(events, dkpMgr, players, noop.NewTracerProvider())))
+
+	tests := []struct {
+		name    string
+		command string
+		user    string
+		options []*discordgo.ApplicationCommandInteractionDataOption
+		want    []string
+		chart   string
+	}{
+		{
+			name:    "history",
+			command: "dkp-history",
+			user:    "user-1",
+			want:    []string{"**DKP history of Gandalf** — DKP: **70**", "\\u003ct:1750161600:d\\u003e -30 → 70: Item: Sword", "\\u003ct:1749470400:d\\u003e +100 → 100: Molten Core"},
+		},
+		{
+			name:    "history chart",
+			command: "dkp-history",
+			user:    "user-1",
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "chart", Type: discordgo.ApplicationCommandOptionBoolean, Value: true},
+			},
+			want:  []string{"**DKP history of Gandalf**"},
+			chart: "dkp-history.png",
+		},
+		{
+			name:    "own history",
+			command: "my-history",
+			user:    "user-1",
+			want:    []string{"**DKP history of Gandalf** — DKP: **70**", "\\u003ct:1750161600:d\\u003e -30 → 70: Item: Sword"},
+		},
+		{
+			name:    "own history export",
+			command: "my-history",
+			user:    "user-1",
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "export", Type: discordgo.ApplicationCommandOptionBoolean, Value: true},
+			},
+			want: []string{
+				"DKP and loot history of **Gandalf** — DKP: **70**", `"flags":64`, `filename="dkp-ledger.csv"`,
+				"2025-06-09T12:00:00Z,dkp.awarded,100,100,DKP,Molten Core,,,\n" +
+					"2025-06-17T12:00:00Z,dkp.deducted,-30,70,DKP,Item: Sword,,,",
+			},
+		},
+		{
+			name:    "no history",
+			command: "dkp-history",
+			user:    "user-2",
+			want:    []string{"**Frodo** has no DKP changes."},
+		},
+		{
+			name:    "stats",
+			command: "dkp-stats",
+			user:    "user-2",
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "weeks", Type: discordgo.ApplicationCommandOptionInteger, Value: 2.0},
+			},
+			want:  []string{"2 players hold **75 DKP**", "Last 2 weeks: 100 awarded, 30 spent, net **+70 DKP**", "`2025-06-09` +100 / -0 = +100", "`2025-06-16` +0 / -30 = -30"},
+			chart: "dkp-stats.png",
+		},
+		{
+			name:    "economy",
+			command: "dkp-economy",
+			user:    "user-2",
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "weeks", Type: discordgo.ApplicationCommandOptionInteger, Value: 2.0},
+			},
+			want: []string{
+				"2 players hold **75 DKP**", "the supply grew **+1400.0%** from 5 DKP",
+				"Inflow: 100 DKP awarded. Outflow: 30 DKP spent on loot, 0 by decay", "Items sold in DKP auctions: 1, at 30.0 DKP on average",
+				"a Gini coefficient of **0.43**", "Auction tax: 3 DKP charged, 3 burned, 0 shared",
+				"`2025-06-09` 105 DKP (+2000.0%): +100 in, -0 loot, -0 other; no items sold; taxed 0, burned 0",
+				"`2025-06-16` 75 DKP (-28.6%): +0 in, -30 loot, -0 other; 1 sold at 30.0 on average; taxed 3, burned 3",
+			},
+			chart: "dkp-economy.png",
+		},
+	}
+	for n, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &recordingTransport{}
+			s, _ := discordgo.New("Bot token")
+			s.Client = &http.Client{Transport: rt}
+
+			i := interaction(fmt.Sprintf("interaction-%d", n), tt.command)
+			i.Member.User.ID = tt.user
+			i.Data = discordgo.ApplicationCommandInteractionData{Name: tt.command, Options: tt.options}
+			h.InteractionCreate(s, i)
+
+			if len(rt.bodies) != 1 {
+				t.Fatalf("responses = %q, want one", rt.bodies)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(rt.bodies[0], want) {
+					t.Errorf("response %q does not contain %q", rt.bodies[0], want)
+				}
+			}
+			if tt.chart != "" && !strings.Contains(rt.bodies[0], `filename="`+tt.chart+`"`) || tt.chart == "" && strings.Contains(rt.bodies[0], "image/png") {
+				t.Errorf("response attaches a chart = %t, want %q", strings.Contains(rt.bodies[0], "image/png"), tt.chart)
+			}
+		})
+	}
+}
+
+// archivablePlayers is a store.PlayerRepository whose players may be
+// archived.
+type archivablePlayers struct {
+	store.PlayerRepository
+	players []*store.Player
+}
+
+func (a *archivablePlayers) GetByDiscordID(_ context.Context, discordID string) (*store.Player, error) {
+	for _, p := range a.players {
+		if p.DiscordID == discordID {
+			cp := *p
+			return &cp, nil
+		}
+	}
+	return nil, store.ErrPlayerNotFound
+}
+
+func (a *archivablePlayers) List(context.Context) ([]store.Player, error) {
+	list := make([]store.Player, len(a.players))
+	for n, p := range a.players {
+		list[n] = *p
+	}
+	return list, nil
+}
+
+func (a *archivablePlayers) SetArchived(_ context.Context, id string, archived bool) error {
+	for _, p := range a.players {
+		if p.ID == id {
+			p.ArchivedAt = nil
+			if archived {
+				p.ArchivedAt = &time.Time{}
+			}
+			return nil
+		}
+	}
+	return store.ErrPlayerNotFound
+}
+
+func TestInteractionCreate_Roster(t *testing.T) {
+	now := time.Date(2025, 6, 16, 17, 0, 0, 0, time.UTC)
+	old := now.AddDate(0, -6, 0)
+	events := &memEvents{}
+	data, _ := json.Marshal(event.DKPChangeData{PlayerID: "p1", Amount: 10})
+	events.events = append(events.events, event.Event{AggregateID: "p1", Type: event.DKPAwarded, Data: data, CreatedAt: now.AddDate(0, 0, -3)})
+	players := &archivablePlayers{players: []*store.Player{
+		{ID: "p1", DiscordID: "user-1", CharacterName: "Gandalf", DKP: 70, CreatedAt: old},
+		{ID: "p2", DiscordID: "user-2", CharacterName: "Frodo", DKP: 25, CreatedAt: old},
+	}}
+	dkpMgr := dkp.NewManager(players, events, slog.Default(), noop.NewTracerProvider())
+	reviewer := roster.NewReviewer(config.RosterConfig{InactiveWeeks: 4}, players, events, nil, "guild-1", nil, nil,
+		slog.Default(), noop.NewTracerProvider(), clock.Mock{T: now})
+	h := commands.NewHandlers(dkpMgr, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithRoster(reviewer))
+
+	run := func(t *testing.T, i *discordgo.InteractionCreate, officer bool) string {
+		t.Helper()
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		if officer {
+			i.Member.Permissions = discordgo.PermissionAdministrator
+		}
+		h.InteractionCreate(s, i)
+		if len(rt.bodies) != 1 {
+			t.Fatalf("responses = %q, want one", rt.bodies)
+		}
+		return rt.bodies[0]
+	}
+	click := func(id, customID string) *discordgo.InteractionCreate {
+		i := interaction(id, "")
+		i.Type = discordgo.InteractionMessageComponent
+		i.Data = discordgo.MessageComponentInteractionData{CustomID: customID, ComponentType: discordgo.ButtonComponent}
+		return i
+	}
+
+	got := run(t, interaction("i1", "roster-inactive"), true)
+	if !strings.Contains(got, "**Frodo** — 25 DKP, never active") || strings.Contains(got, "Gandalf") || !strings.Contains(got, `"custom_id":"roster-archive:user-2"`) {
+		t.Errorf("inactive = %q, want a proposal to archive Frodo only", got)
+	}
+
+	if got := run(t, click("i2", "roster-archive:user-2"), false); !strings.Contains(got, "only officers") {
+		t.Errorf("archive by member = %q, want it refused", got)
+	}
+	if got := run(t, click("i3", "roster-archive:user-2"), true); !strings.Contains(got, "**Frodo** archived by \\u003c@user-1\\u003e; their **25 DKP** are frozen") {
+		t.Errorf("archive = %q", got)
+	}
+	if got := run(t, interaction("i4", "dkp-list"), false); strings.Contains(got, "Frodo") || !strings.Contains(got, "1 archived players are not listed") {
+		t.Errorf("list = %q, want Frodo hidden", got)
+	}
+	if got := run(t, interaction("i5", "roster-inactive"), true); !strings.Contains(got, "Every player has been active in the last 4 weeks.") {
+		t.Errorf("inactive after archiving = %q", got)
+	}
+
+	restore := interaction("i6", "roster-restore")
+	restore.Data = discordgo.ApplicationCommandInteractionData{Name: "roster-restore", Options: []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "player", Type: discordgo.ApplicationCommandOptionUser, Value: "user-2"},
+	}}
+	if got := run(t, restore, true); !strings.Contains(got, "**Frodo** restored with **25 DKP**.") {
+		t.Errorf("restore = %q", got)
+	}
+}
+
+// memUsage is a store.UsageRepository in memory, over a single day.
+type memUsage struct {
+	mu    sync.Mutex
+	usage map[string]*store.CommandUsage
+	users map[string]map[string]bool
+}
+
+func (m *memUsage) Record(_ context.Context, command, userID string, failed bool, _ time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.usage[command]
+	if !ok {
+		c = &store.CommandUsage{Command: command}
+		m.usage[command] = c
+		m.users[command] = make(map[string]bool)
+	}
+	first := !m.users[command][userID]
+	if first {
+		m.users[command][userID] = true
+		c.Users++
+	}
+	c.Uses++
+	if failed {
+		c.Failures++
+	}
+	return first, nil
+}
+
+func (m *memUsage) Summary(context.Context, time.Time) ([]store.CommandUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var summary []store.CommandUsage
+	for _, c := range m.usage {
+		summary = append(summary, *c)
+	}
+	slices.SortFunc(summary, func(a, b store.CommandUsage) int { return b.Uses - a.Uses })
+	return summary, nil
+}
+
+func (m *memUsage) Users(context.Context, time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	users := make(map[string]bool)
+	for _, byUser := range m.users {
+		maps.Copy(users, byUser)
+	}
+	return len(users), nil
+}
+
+func TestInteractionCreate_DKPListStandings(t *testing.T) {
+	now := time.Date(2025, 6, 16, 17, 0, 0, 0, time.UTC)
+	players := storetest.NewPlayers(
+		store.Player{DiscordID: "user-1", CharacterName: "Gandalf", DKP: 70},
+		store.Player{DiscordID: "user-2", CharacterName: "Frodo", DKP: 25},
+	)
+	view := standings.NewProjection(players, slog.Default(), noop.NewTracerProvider(), clock.Mock{T: now})
+	h := commands.NewHandlers(nil, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithStandings(view))
+
+	run := func(t *testing.T, i *discordgo.InteractionCreate) string {
+		t.Helper()
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		h.InteractionCreate(s, i)
+		if len(rt.bodies) != 1 {
+			t.Fatalf("responses = %q, want one", rt.bodies)
+		}
+		return rt.bodies[0]
+	}
+	list := func(id string, refresh, officer bool) *discordgo.InteractionCreate {
+		i := interaction(id, "dkp-list")
+		if refresh {
+			i.Data = discordgo.ApplicationCommandInteractionData{Name: "dkp-list", Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "refresh", Type: discordgo.ApplicationCommandOptionBoolean, Value: true},
+			}}
+		}
+		if officer {
+			i.Member.Permissions = discordgo.PermissionAdministrator
+		}
+		return i
+	}
+
+	asOf := fmt.Sprintf("_As of \\u003ct:%d:R\\u003e._", now.Unix())
+	if got := run(t, list("i1", false, false)); !strings.Contains(got, "1. Gandalf — 70 DKP") || !strings.Contains(got, asOf) {
+		t.Errorf("list = %q, want the standings as of now", got)
+	}
+
+	// Changes made behind the projection's back show once it is rebuilt.
+	players.Put(store.Player{DiscordID: "user-2", CharacterName: "Frodo", DKP: 90})
+	if got := run(t, list("i2", false, false)); !strings.Contains(got, "2. Frodo — 25 DKP") {
+		t.Errorf("cached list = %q, want Frodo's old DKP", got)
+	}
+	if got := run(t, list("i3", true, false)); !strings.Contains(got, "only officers") {
+		t.Errorf("refresh by member = %q, want it refused", got)
+	}
+	if got := run(t, list("i4", true, true)); !strings.Contains(got, "2. Frodo — 90 DKP") {
+		t.Errorf("refreshed list = %q, want Frodo's new DKP", got)
+	}
+}
+
+func TestInteractionCreate_BotStats(t *testing.T) {
+	repo := &memUsage{usage: make(map[string]*store.CommandUsage), users: make(map[string]map[string]bool)}
+	tracker := usage.NewTracker(repo, metrics.Nop(), slog.Default(), noop.NewTracerProvider(), clock.Real{})
+	h := commands.NewHandlers(nil, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithUsage(tracker))
+
+	run := func(i *discordgo.InteractionCreate) string {
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		h.InteractionCreate(s, i)
+		if len(rt.bodies) != 1 {
+			t.Fatalf("responses = %q, want one", rt.bodies)
+		}
+		return rt.bodies[0]
+	}
+
+	// The nil auction manager makes the button fail.
+	click := interaction("i1", "")
+	click.Type = discordgo.InteractionMessageComponent
+	click.Data = discordgo.MessageComponentInteractionData{CustomID: "auction-buyout:auction-1", ComponentType: discordgo.ButtonComponent}
+	run(click)
+	// Members may not use /bot-stats, which fails too.
+	other := interaction("i2", "bot-stats")
+	other.Member.User.ID = "user-2"
+	if got := run(other); !strings.Contains(got, "only officers") {
+		t.Fatalf("bot-stats by member = %q, want it refused", got)
+	}
+
+	stats := interaction("i3", "bot-stats")
+	stats.Member.Permissions = discordgo.PermissionAdministrator
+	got := run(stats)
+	for _, want := range []string{
+		"**Bot usage, last 30 days**: 2 members used commands 2 times, 100% failed.",
+		"`auction-buyout` — 1 uses by 1 members, 100% failed",
+		"`/bot-stats` — 1 uses by 1 members, 100% failed",
+		"Unused: `/register`",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("bot-stats = %q, want %q", got, want)
+		}
+	}
+	if strings.Contains(got, "`/bot-stats`,") {
+		t.Errorf("bot-stats = %q, lists /bot-stats as unused", got)
+	}
+}
+
+func TestInteractionCreate_GuildMerge(t *testing.T) {
+	players := storetest.NewPlayers(
+		store.Player{ID: "p1", DiscordID: "user-2", CharacterName: "Frodo", DKP: 100},
+		store.Player{ID: "p2", DiscordID: "user-3", CharacterName: "Sam", DKP: 50},
+	)
+	events := eventtest.NewStore()
+	merger := merge.NewMerger(players, events, slog.Default(), noop.NewTracerProvider(), clock.Real{})
+	h := commands.NewHandlers(nil, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithMerger(merger))
+
+	ctx := context.Background()
+	plan, err := merger.Prepare(ctx, []merge.Member{{Name: "Frodo", DKP: 40}, {Name: "Sam", DKP: 20}}, 0.5)
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	run := func(t *testing.T, id, customID string, values ...string) string {
+		t.Helper()
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		i := interaction(id, "")
+		i.Type = discordgo.InteractionMessageComponent
+		i.Member.Permissions = discordgo.PermissionAdministrator
+		i.Data = discordgo.MessageComponentInteractionData{CustomID: customID, ComponentType: discordgo.SelectMenuComponent, Values: values}
+		h.InteractionCreate(s, i)
+		// Apply acknowledges the click before editing the message.
+		return strings.Join(rt.bodies, "\n")
+	}
+
+	got := run(t, "i1", merge.SeparateAction+":"+plan.ID+":0", "1")
+	if !strings.Contains(got, `"type":7`) || !strings.Contains(got, "**Sam** (+10 DKP), separate from") || !strings.Contains(got, "**Frodo** (+20 DKP), merged into") {
+		t.Errorf("separate = %q, want the preview updated with Sam separate", got)
+	}
+	got = run(t, "i2", merge.ApplyAction+":"+plan.ID)
+	if !strings.Contains(got, "1 characters merged into registered players, 1 imported as new players, **+30 DKP** in all.") || !strings.Contains(got, `"components":[]`) {
+		t.Errorf("apply = %q", got)
+	}
+	players.RequireDKP(t, "user-2", 120)
+	players.RequireDKP(t, "user-3", 50)
+
+	if got := run(t, "i3", merge.ApplyAction+":"+plan.ID); !strings.Contains(got, "already applied") {
+		t.Errorf("second apply = %q, want it refused", got)
+	}
+}
+
+func TestInteractionCreate_Bank(t *testing.T) {
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	events := eventtest.NewStore(eventtest.WithClock(clk))
+	guildBank := bank.NewService(events, slog.Default(), noop.NewTracerProvider(), clk)
+	mgr := auction.NewManager(&memEvents{}, nil, slog.Default(), noop.NewTracerProvider(), clk)
+	h := commands.NewHandlers(nil, mgr, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithBank(guildBank))
+
+	run := func(t *testing.T, id, sub string, options ...*discordgo.ApplicationCommandInteractionDataOption) string {
+		t.Helper()
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		i := interaction(id, "bank")
+		i.Member.Permissions = discordgo.PermissionAdministrator
+		i.Data = discordgo.ApplicationCommandInteractionData{
+			Name: "bank",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: sub, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options},
+			},
+		}
+		h.InteractionCreate(s, i)
+		return strings.Join(rt.bodies, "\n")
+	}
+
+	if got := run(t, "i1", "list"); !strings.Contains(got, "The guild bank is empty.") {
+		t.Errorf("list = %q, want the bank empty", got)
+	}
+	got := run(t, "i2", "add",
+		&discordgo.ApplicationCommandInteractionDataOption{Name: "item", Type: discordgo.ApplicationCommandOptionString, Value: "Sword"},
+		&discordgo.ApplicationCommandInteractionDataOption{Name: "note", Type: discordgo.ApplicationCommandOptionString, Value: "from Nagafen"},
+	)
+	if !strings.Contains(got, "Banked **Sword**") {
+		t.Errorf("add = %q", got)
+	}
+	banked, err := guildBank.List(context.Background())
+	if err != nil || len(banked) != 1 {
+		t.Fatalf("List() = %+v, %v, want the sword", banked, err)
+	}
+	if got := run(t, "i3", "list"); !strings.Contains(got, "**Sword** (`"+banked[0].ID+"`)") || !strings.Contains(got, "from Nagafen") {
+		t.Errorf("list = %q, want the sword", got)
+	}
+
+	item := &discordgo.ApplicationCommandInteractionDataOption{Name: "item", Type: discordgo.ApplicationCommandOptionString, Value: banked[0].ID}
+	if got := run(t, "i4", "auction", item); !strings.Contains(got, "Auction started!") {
+		t.Errorf("auction = %q, want it started", got)
+	}
+	if open := mgr.OpenAuctions(); len(open) != 1 {
+		t.Errorf("open auctions = %v, want 1", open)
+	}
+	if got := run(t, "i5", "auction", item); !strings.Contains(got, "`BANK_ITEM_AUCTIONED`") {
+		t.Errorf("second auction = %q, want it refused", got)
+	}
+}
+
+func TestInteractionCreate_Raffle(t *testing.T) {
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	players := storetest.NewPlayers(store.Player{ID: "p1", DiscordID: "user-1", CharacterName: "Gandalf", DKP: 10})
+	events := eventtest.NewStore(eventtest.WithClock(clk))
+	dkpMgr := dkp.NewManager(players, events, slog.Default(), noop.NewTracerProvider())
+	raffles := raffle.NewService(events, dkpMgr, ids.NewULID(clk), slog.Default(), noop.NewTracerProvider(), clk)
+	h := commands.NewHandlers(dkpMgr, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithRaffles(raffles))
+
+	run := func(t *testing.T, id, sub string, admin bool, options ...*discordgo.ApplicationCommandInteractionDataOption) string {
+		t.Helper()
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		i := interaction(id, "raffle")
+		if admin {
+			i.Member.Permissions = discordgo.PermissionAdministrator
+		}
+		i.Data = discordgo.ApplicationCommandInteractionData{
+			Name: "raffle",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: sub, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options},
+			},
+		}
+		h.InteractionCreate(s, i)
+		return strings.Join(rt.bodies, "\n")
+	}
+	str := func(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionString, Value: value}
+	}
+	num := func(name string, value float64) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionInteger, Value: value}
+	}
+
+	if got := run(t, "i1", "start", false, str("item", "Tabard"), num("cost", 3)); !strings.Contains(got, "`NOT_OFFICER`") {
+		t.Errorf("start by a member = %q, want it refused", got)
+	}
+	if got := run(t, "i2", "start", true, str("item", "Tabard"), num("cost", 3)); !strings.Contains(got, "Raffle for **Tabard** started: 3 DKP a ticket") {
+		t.Errorf("start = %q", got)
+	}
+	open, err := raffles.Open(context.Background())
+	if err != nil || len(open) != 1 {
+		t.Fatalf("Open() = %v, %v; want the raffle", open, err)
+	}
+	id := str("raffle", open[0].ID)
+	if got := run(t, "i3", "enter", false, id, num("tickets", 2)); !strings.Contains(got, "bought 2 tickets for **Tabard** for 6 DKP") {
+		t.Errorf("enter = %q", got)
+	}
+	if got := run(t, "i4", "enter", false, id, num("tickets", 2)); !strings.Contains(got, "`INSUFFICIENT_DKP`") {
+		t.Errorf("enter beyond the player's DKP = %q, want it refused", got)
+	}
+	if p, _ := dkpMgr.GetPlayer(context.Background(), "user-1"); p.DKP != 4 {
+		t.Errorf("DKP = %d, want 4 after buying 2 tickets at 3", p.DKP)
+	}
+	if got := run(t, "i5", "draw", true, id); !strings.Contains(got, `user-1\u003e won **Tabard** with ticket`) {
+		t.Errorf("draw = %q, want the only player to win", got)
+	}
+}
+
+func TestInteractionCreate_ItemCost(t *testing.T) {
+	players := storetest.NewPlayers(store.Player{ID: "p1", DiscordID: "user-2", CharacterName: "Frodo", DKP: 50})
+	events := eventtest.NewStore()
+	dkpMgr := dkp.NewManager(players, events, slog.Default(), noop.NewTracerProvider())
+	costs := itemcost.NewService(events, dkpMgr, slog.Default(), noop.NewTracerProvider())
+	svc := settings.NewService(&memSettings{settings: map[string]store.GuildSetting{}},
+		settings.Defaults(config.GuildDefaultsConfig{LootMode: config.LootModeAuction}), slog.Default())
+	h := commands.NewHandlers(dkpMgr, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(),
+		commands.WithItemCosts(costs), commands.WithSettings(svc))
+
+	run := func(t *testing.T, id, name string, options ...*discordgo.ApplicationCommandInteractionDataOption) string {
+		t.Helper()
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		i := interaction(id, name)
+		i.Member.Permissions = discordgo.PermissionAdministrator
+		i.Data = discordgo.ApplicationCommandInteractionData{Name: name, Options: options}
+		h.InteractionCreate(s, i)
+		return strings.Join(rt.bodies, "\n")
+	}
+	sub := func(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options}
+	}
+	str := func(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionString, Value: value}
+	}
+	num := func(name string, value float64) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionInteger, Value: value}
+	}
+	player := &discordgo.ApplicationCommandInteractionDataOption{Name: "player", Type: discordgo.ApplicationCommandOptionUser, Value: "user-2"}
+
+	if got := run(t, "i1", "itemcost", sub("set", str("item", "Ashkandi"), num("cost", 30))); !strings.Contains(got, "**Ashkandi** now costs **30 DKP**") {
+		t.Errorf("itemcost set = %q", got)
+	}
+	if got := run(t, "i2", "itemcost", sub("list")); !strings.Contains(got, "Ashkandi: 30 DKP") {
+		t.Errorf("itemcost list = %q", got)
+	}
+	if got := run(t, "i3", "loot-award", player, str("item", "ashkandi")); !strings.Contains(got, "`FIXED_PRICES_OFF`") {
+		t.Errorf("loot-award in auction mode = %q, want it refused", got)
+	}
+
+	if _, err := svc.Set(context.Background(), "guild-1", settings.LootMode, config.LootModeFixedPrice, "user-1"); err != nil {
+		t.Fatalf("setting loot_mode: %v", err)
+	}
+	if got := run(t, "i4", "loot-award", player, str("item", "ashkandi")); !strings.Contains(got, "received **Ashkandi** for **30 DKP** and has 20 DKP left") {
+		t.Errorf("loot-award = %q", got)
+	}
+	if p, _ := dkpMgr.GetPlayer(context.Background(), "user-2"); p.DKP != 20 {
+		t.Errorf("DKP = %d, want 20 after the award", p.DKP)
+	}
+	if got := run(t, "i5", "loot-award", player, str("item", "Ashkandi")); !strings.Contains(got, "`INSUFFICIENT_DKP`") {
+		t.Errorf("loot-award beyond the player's DKP = %q, want it refused", got)
+	}
+	if got := run(t, "i6", "auction-start", str("item", "Ashkandi")); !strings.Contains(got, "`AUCTIONS_OFF`") {
+		t.Errorf("auction-start in fixed-price mode = %q, want it refused", got)
+	}
+}
+
+func TestInteractionCreate_Dispute(t *testing.T) {
+	players := storetest.NewPlayers(store.Player{ID: "p1", DiscordID: "user-1", CharacterName: "Gandalf", DKP: 50})
+	events := eventtest.NewStore()
+	clk := clock.Real{}
+	mgr := auction.NewManager(events, players, slog.Default(), noop.NewTracerProvider(), clk)
+	disputes := dispute.NewService(events, ids.NewULID(clk), slog.Default(), noop.NewTracerProvider(), clk)
+	svc := settings.NewService(&memSettings{settings: map[string]store.GuildSetting{}},
+		settings.Defaults(config.GuildDefaultsConfig{AdminRoles: []string{"role-officer"}}), slog.Default())
+	h := commands.NewHandlers(nil, mgr, audit.NewLog(events, players, noop.NewTracerProvider()), nil, nil, slog.Default(), noop.NewTracerProvider(),
+		commands.WithDisputes(disputes), commands.WithSettings(svc))
+
+	a, err := mgr.StartAuction(context.Background(), "Ashkandi", "officer", 10, 0, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("StartAuction: %v", err)
+	}
+
+	run := func(t *testing.T, id string, admin bool, options ...*discordgo.ApplicationCommandInteractionDataOption) string {
+		t.Helper()
+		rt := &jobTransport{edited: make(chan struct{}, 1)}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		i := interaction(id, "dispute")
+		i.ChannelID = "channel-1"
+		if admin {
+			i.Member.Permissions = discordgo.PermissionAdministrator
+		}
+		i.Data = discordgo.ApplicationCommandInteractionData{Name: "dispute", Options: options}
+		h.InteractionCreate(s, i)
+		return strings.Join(rt.requests, "\n")
+	}
+	sub := func(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options}
+	}
+	str := func(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionString, Value: value}
+	}
+
+	got := run(t, "i1", false, sub("open", str("reason", "My bid was ignored"), str("auction", a.ID)))
+	for _, want := range []string{
+		"POST /api/v9/channels/channel-1/threads",
+		`"type":12`,
+		`\u003c@\u0026role-officer\u003e \u003c@user-1\u003e opened a dispute about auction ` + "`" + a.ID + "`",
+		`"title":"Ashkandi"`,
+		"auction.started",
+		`Your dispute is open in \u003c#m1\u003e`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("dispute open = %s, want %q", got, want)
+		}
+	}
+
+	got = run(t, "i2", false, sub("open", str("reason", "Bench rotation"), &discordgo.ApplicationCommandInteractionDataOption{
+		Name: "anonymous", Type: discordgo.ApplicationCommandOptionBoolean, Value: true,
+	}))
+	if !strings.Contains(got, "An anonymous member opened a dispute") || strings.Contains(got, `\u003c@user-1\u003e`) {
+		t.Errorf("anonymous dispute open = %s, want it not to name the member", got)
+	}
+
+	if got := run(t, "i3", false, sub("list")); !strings.Contains(got, "`NOT_OFFICER`") {
+		t.Errorf("dispute list by a member = %s, want it refused", got)
+	}
+	open, err := disputes.Unresolved(context.Background())
+	if err != nil || len(open) != 2 {
+		t.Fatalf("Unresolved() = %v, %v, want the two disputes", open, err)
+	}
+	if got := run(t, "i4", true, sub("list")); !strings.Contains(got, "My bid was ignored") || !strings.Contains(got, "Bench rotation") {
+		t.Errorf("dispute list = %s, want both disputes", got)
+	}
+
+	got = run(t, "i5", true, sub("resolve", str("dispute", open[0].ID), str("resolution", "Bid refunded")))
+	for _, want := range []string{
+		"POST /api/v9/channels/m1/messages",
+		`Resolved by \u003c@user-1\u003e: Bid refunded`,
+		`PATCH /api/v9/channels/m1 {"archived":true,"locked":true}`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("dispute resolve = %s, want %q", got, want)
+		}
+	}
+	if open, _ := disputes.Unresolved(context.Background()); len(open) != 1 {
+		t.Errorf("Unresolved() after resolving = %d disputes, want 1", len(open))
+	}
+}
+
+func TestInteractionCreate_TextFilter(t *testing.T) {
+	players := storetest.NewPlayers()
+	dkpMgr := dkp.NewManager(players, eventtest.NewStore(), slog.Default(), noop.NewTracerProvider())
+	svc := settings.NewService(&memSettings{settings: map[string]store.GuildSetting{}},
+		settings.Defaults(config.GuildDefaultsConfig{OfficerChannel: "officers"}), slog.Default())
+	filter := textfilter.New(config.TextFilterConfig{Words: []string{"noob"}, MaxLength: 12})
+	h := commands.NewHandlers(dkpMgr, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(),
+		commands.WithSettings(svc), commands.WithTextFilter(filter))
+
+	run := func(t *testing.T, id, character string) string {
+		t.Helper()
+		rt := &jobTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		i := interaction(id, "register")
+		i.Data = discordgo.ApplicationCommandInteractionData{Name: "register", Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "character", Type: discordgo.ApplicationCommandOptionString, Value: character},
+		}}
+		h.InteractionCreate(s, i)
+		return strings.Join(rt.requests, "\n")
+	}
+
+	got := run(t, "i1", "N00b Slayer")
+	for _, want := range []string{
+		"POST /api/v9/channels/officers/messages",
+		"was refused `/register` for its `character`",
+		"`TEXT_BLOCKED`",
+		"||N00b Slayer||",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("register with a blocked word = %s, want %q", got, want)
+		}
+	}
+	if _, err := players.GetByDiscordID(context.Background(), "user-1"); !errors.Is(err, store.ErrPlayerNotFound) {
+		t.Errorf("player registered despite the refusal: %v", err)
+	}
+	if got := run(t, "i2", "Gandalf the Grey"); !strings.Contains(got, "`TEXT_TOO_LONG`") {
+		t.Errorf("register with a long name = %s, want it refused", got)
+	}
+	if got := run(t, "i3", "Gandalf"); !strings.Contains(got, "Registered **Gandalf**") || strings.Contains(got, "officers") {
+		t.Errorf("register = %s, want it to pass the filter", got)
+	}
+}
+
+func TestInteractionCreate_Currency(t *testing.T) {
+	players := storetest.NewPlayers(
+		store.Player{ID: "p1", DiscordID: "user-1", CharacterName: "Gandalf", DKP: 10},
+		store.Player{ID: "p2", DiscordID: "user-2", CharacterName: "Frodo"},
+	)
+	balances := storetest.NewBalances(players)
+	dkpMgr := dkp.NewManager(players, eventtest.NewStore(), slog.Default(), noop.NewTracerProvider(),
+		dkp.WithCurrencies(balances, []string{"EP"}))
+	h := commands.NewHandlers(dkpMgr, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider())
+
+	run := func(t *testing.T, id, name string, options ...*discordgo.ApplicationCommandInteractionDataOption) string {
+		t.Helper()
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		i := interaction(id, name)
+		i.Member.Permissions = discordgo.PermissionAdministrator
+		i.Data = discordgo.ApplicationCommandInteractionData{Name: name, Options: options}
+		h.InteractionCreate(s, i)
+		return strings.Join(rt.bodies, "\n")
+	}
+	str := func(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionString, Value: value}
+	}
+	user := func(name, id string) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionUser, Value: id}
+	}
+	amount := func(n int) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: "amount", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(n)}
+	}
+	sub := func(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options}
+	}
+
+	got := run(t, "i1", "currency", sub("award", str("currency", "ep"), user("player", "user-1"), amount(30), str("reason", "raid")))
+	if !strings.Contains(got, "Awarded **30 EP** to **Gandalf** for: raid") {
+		t.Errorf("award = %q", got)
+	}
+	got = run(t, "i2", "currency", sub("transfer", str("currency", "EP"), user("from", "user-1"), user("to", "user-2"), amount(12), str("reason", "gift")))
+	if !strings.Contains(got, "Transferred **12 EP** from **Gandalf** to **Frodo**") {
+		t.Errorf("transfer = %q", got)
+	}
+	balances.RequireBalance(t, "user-1", "EP", 18)
+	balances.RequireBalance(t, "user-2", "EP", 12)
+	if got := run(t, "i3", "currency", sub("deduct", str("currency", "GP"), user("player", "user-1"), amount(1), str("reason", "x"))); !strings.Contains(got, "Unknown currency. Choose one of: DKP, EP.") {
+		t.Errorf("deduct in an unknown currency = %q", got)
+	}
+
+	if got := run(t, "i4", "balance"); !strings.Contains(got, "DKP: **10**") || !strings.Contains(got, "EP: **18**") {
+		t.Errorf("balance = %q", got)
+	}
+	if got := run(t, "i5", "currency-list", str("currency", "EP")); !strings.Contains(got, "1. Gandalf — 18 EP") || !strings.Contains(got, "2. Frodo — 12 EP") {
+		t.Errorf("currency-list = %q", got)
+	}
+}
+
+// memNotes implements store.PlayerNoteRepository in memory.
+type memNotes struct {
+	notes []store.PlayerNote
+	clock clock.Clock
+}
+
+func (r *memNotes) Add(_ context.Context, n *store.PlayerNote) error {
+	n.CreatedAt = r.clock.Now()
+	r.notes = append([]store.PlayerNote{*n}, r.notes...)
+	return nil
+}
+
+func (r *memNotes) ListByPlayer(_ context.Context, playerID string) ([]store.PlayerNote, error) {
+	var out []store.PlayerNote
+	for _, n := range r.notes {
+		if n.PlayerID == playerID {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+func TestInteractionCreate_PlayerNotes(t *testing.T) {
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	players := storetest.NewPlayers(store.Player{ID: "p2", DiscordID: "user-2", CharacterName: "Frodo", DKP: 25})
+	svc := notes.NewService(&memNotes{clock: clk}, players, slog.Default(), noop.NewTracerProvider(), clk)
+	h := commands.NewHandlers(nil, nil, nil, nil, nil, slog.Default(), noop.NewTracerProvider(), commands.WithNotes(svc))
+
+	run := func(t *testing.T, i *discordgo.InteractionCreate, officer bool) string {
+		t.Helper()
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		if officer {
+			i.Member.Permissions = discordgo.PermissionAdministrator
+		}
+		h.InteractionCreate(s, i)
+		if len(rt.bodies) != 1 {
+			t.Fatalf("responses = %q, want one", rt.bodies)
+		}
+		return rt.bodies[0]
+	}
+	player := &discordgo.ApplicationCommandInteractionDataOption{Name: "player", Type: discordgo.ApplicationCommandOptionUser, Value: "user-2"}
+	note := func(id, sub string, opts ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+		i := interaction(id, "player-note")
+		i.Data = discordgo.ApplicationCommandInteractionData{Name: "player-note", Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: sub, Type: discordgo.ApplicationCommandOptionSubCommand, Options: append([]*discordgo.ApplicationCommandInteractionDataOption{player}, opts...)},
+		}}
+		return i
+	}
+	profile := func(id string) *discordgo.InteractionCreate {
+		i := interaction(id, "profile")
+		i.Data = discordgo.ApplicationCommandInteractionData{Name: "profile", Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			player, {Name: "notes", Type: discordgo.ApplicationCommandOptionBoolean, Value: true},
+		}}
+		return i
+	}
+
+	if got := run(t, note("i1", "list"), true); !strings.Contains(got, "No notes on **Frodo**.") {
+		t.Errorf("list before = %q", got)
+	}
+	got := run(t, note("i2", "add",
+		&discordgo.ApplicationCommandInteractionDataOption{Name: "text", Type: discordgo.ApplicationCommandOptionString, Value: "Ninja-looted the Onyxia bag"},
+		&discordgo.ApplicationCommandInteractionDataOption{Name: "loot-ban-until", Type: discordgo.ApplicationCommandOptionString, Value: "2025-07-01"},
+	), true)
+	if !strings.Contains(got, "Added a note on **Frodo**. They may not bid on loot until \\u003ct:1751328000:F\\u003e.") || !strings.Contains(got, `"flags":64`) {
+		t.Errorf("add = %q, want a private confirmation with the ban", got)
+	}
+	if got := run(t, note("i3", "add",
+		&discordgo.ApplicationCommandInteractionDataOption{Name: "text", Type: discordgo.ApplicationCommandOptionString, Value: "late"},
+		&discordgo.ApplicationCommandInteractionDataOption{Name: "loot-ban-until", Type: discordgo.ApplicationCommandOptionString, Value: "next week"},
+	), true); !strings.Contains(got, "Invalid loot ban end") {
+		t.Errorf("add with a bad ban end = %q", got)
+	}
+
+	want := "\\u003ct:1750017600:d\\u003e \\u003c@user-1\\u003e: Ninja-looted the Onyxia bag (loot ban until \\u003ct:1751328000:F\\u003e)"
+	if got := run(t, note("i4", "list"), true); !strings.Contains(got, "Notes on **Frodo**:") || !strings.Contains(got, want) {
+		t.Errorf("list = %q, want %q", got, want)
+	}
+	if got := run(t, profile("i5"), true); !strings.Contains(got, "**Frodo**: ") || !strings.Contains(got, want) {
+		t.Errorf("profile with notes = %q, want the profile and %q", got, want)
+	}
+	if got := run(t, profile("i6"), false); !strings.Contains(got, "only officers") || strings.Contains(got, "Onyxia") {
+		t.Errorf("profile with notes by member = %q, want it refused", got)
+	}
+	if got := run(t, note("i7", "list"), false); !strings.Contains(got, "only officers") {
+		t.Errorf("list by member = %q, want it refused", got)
+	}
+}
+
+func TestInteractionCreate_LootBan(t *testing.T) {
+	players := storetest.NewPlayers(
+		store.Player{ID: "p1", DiscordID: "user-1", CharacterName: "Gandalf", DKP: 100},
+		store.Player{ID: "p2", DiscordID: "user-2", CharacterName: "Frodo", DKP: 100},
+	)
+	events := eventtest.NewStore()
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	dkpMgr := dkp.NewManager(players, events, slog.Default(), noop.NewTracerProvider(), dkp.WithClock(clk))
+	mgr := auction.NewManager(events, players, slog.Default(), noop.NewTracerProvider(), clk, auction.WithLootBans(dkpMgr))
+	a, err := mgr.StartAuction(context.Background(), "Sword", "officer", 10, 0, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("StartAuction: %v", err)
+	}
+	h := commands.NewHandlers(dkpMgr, mgr, nil, nil, nil, slog.Default(), noop.NewTracerProvider())
+
+	n := 0
+	run := func(t *testing.T, i *discordgo.InteractionCreate, officer bool) string {
+		t.Helper()
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		n++
+		i.ID = fmt.Sprintf("interaction-%d", n)
+		if officer {
+			i.Member.Permissions = discordgo.PermissionAdministrator
+		}
+		h.InteractionCreate(s, i)
+		if len(rt.bodies) != 1 {
+			t.Fatalf("responses = %q, want one", rt.bodies)
+		}
+		return rt.bodies[0]
+	}
+	player := &discordgo.ApplicationCommandInteractionDataOption{Name: "player", Type: discordgo.ApplicationCommandOptionUser, Value: "user-2"}
+	bid := func(amount int) *discordgo.InteractionCreate {
+		i := interaction("", "bid")
+		i.Member.User.ID = "user-2"
+		i.Data = discordgo.ApplicationCommandInteractionData{Name: "bid", Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "auction-id", Type: discordgo.ApplicationCommandOptionString, Value: a.ID},
+			{Name: "amount", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(amount)},
+		}}
+		return i
+	}
+	lift := interaction("", "loot-ban-lift")
+	lift.Data = discordgo.ApplicationCommandInteractionData{Name: "loot-ban-lift", Options: []*discordgo.ApplicationCommandInteractionDataOption{player}}
+
+	ban := interaction("", "loot-ban")
+	ban.Data = discordgo.ApplicationCommandInteractionData{Name: "loot-ban", Options: []*discordgo.ApplicationCommandInteractionDataOption{
+		player,
+		{Name: "duration", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(3)},
+		{Name: "reason", Type: discordgo.ApplicationCommandOptionString, Value: "ninja looting"},
+	}}
+	if got := run(t, ban, false); !strings.Contains(got, "only officers") {
+		t.Errorf("ban by member = %q, want it refused", got)
+	}
+	if got := run(t, ban, true); !strings.Contains(got, "**Frodo** is banned from loot by \\u003c@user-1\\u003e until \\u003ct:1750276800:F\\u003e: ninja looting") {
+		t.Errorf("ban = %q", got)
+	}
+
+	got := run(t, bid(20), false)
+	if !strings.Contains(got, "Bid failed: you are banned from loot until \\u003ct:1750276800:F\\u003e: ninja looting") || !strings.Contains(got, `"flags":64`) {
+		t.Errorf("bid while banned = %q, want a private explanation", got)
+	}
+	if got := run(t, lift, true); !strings.Contains(got, "The loot ban of **Frodo** is lifted") {
+		t.Errorf("lift = %q", got)
+	}
+	if got := run(t, lift, true); !strings.Contains(got, "this player is not banned from loot") {
+		t.Errorf("lift again = %q", got)
+	}
+	if got := run(t, bid(20), false); !strings.Contains(got, "Bid of **20 DKP** placed") {
+		t.Errorf("bid after lifting = %q, want it placed", got)
+	}
+}
+
+// jobTransport answers the Discord REST calls of a job: messages sent to a
+// channel get the ID m1, and the rest 204. It keeps the request paths and
+// bodies.
+func TestInteractionCreate_Audit(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	players := storetest.NewPlayers(
+		store.Player{ID: "p1", DiscordID: "user-1", CharacterName: "Gandalf", DKP: 50},
+		store.Player{ID: "p2", DiscordID: "user-2", CharacterName: "Frodo", DKP: 50},
+	)
+	events := eventtest.NewStore()
+	if err := events.Append(context.Background(),
+		event.Event{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{"player_id":"p1","amount":10,"reason":"raid"}`), Actor: "officer", CreatedAt: now.Add(-time.Hour)},
+		event.Event{AggregateID: "a1", Type: event.AuctionBidPlaced, Data: json.RawMessage(`{"player_id":"p1","amount":15}`), Actor: "user-1", CreatedAt: now.Add(-time.Hour)},
+		event.Event{AggregateID: "a1", Type: event.AuctionBidPlaced, Data: json.RawMessage(`{"player_id":"p2","amount":20}`), Actor: "user-2", CreatedAt: now.Add(-time.Hour)},
+		event.Event{AggregateID: "p1", Type: event.DKPDeducted, Data: json.RawMessage(`{"player_id":"p1","amount":5,"reason":"late"}`), Actor: "officer", CreatedAt: now.Add(-48 * time.Hour)},
+	); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	dkpMgr := dkp.NewManager(players, events, slog.Default(), noop.NewTracerProvider())
+	h := commands.NewHandlers(dkpMgr, nil, audit.NewLog(events, players, noop.NewTracerProvider()), nil, nil, slog.Default(), noop.NewTracerProvider(),
+		commands.WithClock(clock.NewFakeClock(now)))
+
+	run := func(t *testing.T, id string, options ...*discordgo.ApplicationCommandInteractionDataOption) string {
+		t.Helper()
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		i := interaction(id, "audit")
+		i.Member.Permissions = discordgo.PermissionAdministrator
+		i.Data = discordgo.ApplicationCommandInteractionData{Name: "audit", Options: options}
+		h.InteractionCreate(s, i)
+		return strings.Join(rt.bodies, "\n")
+	}
+	player := &discordgo.ApplicationCommandInteractionDataOption{Name: "player", Type: discordgo.ApplicationCommandOptionUser, Value: "user-1"}
+	hours := func(n float64) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: "hours", Type: discordgo.ApplicationCommandOptionInteger, Value: n}
+	}
+
+	got := run(t, "i1", player)
+	for _, want := range []string{"awarded 10 DKP to Gandalf", "Gandalf bid 15 DKP on auction `a1`", `"flags":64`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("audit of a player = %s, want %q", got, want)
+		}
+	}
+	for _, unwanted := range []string{"Frodo bid 20 DKP", "late"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("audit of a player = %s, want no %q", got, unwanted)
+		}
+	}
+	if got := run(t, "i2", player, hours(72)); !strings.Contains(got, "late") {
+		t.Errorf("audit of the last 72 hours = %s, want the older deduction", got)
+	}
+	if got := run(t, "i3", hours(0)); !strings.Contains(got, "Hours must be at least 1.") || !strings.Contains(got, `"flags":64`) {
+		t.Errorf("audit of 0 hours = %s, want it refused privately", got)
+	}
+}
+
+type jobTransport struct {
+	mu       sync.Mutex
+	requests []string
+	edited   chan struct{}
+}
+
+func (rt *jobTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	rt.mu.Lock()
+	rt.requests = append(rt.requests, req.Method+" "+req.URL.Path+" "+string(body))
+	rt.mu.Unlock()
+	resp := &http.Response{StatusCode: http.StatusNoContent, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}
+	if strings.HasPrefix(req.URL.Path, "/api/v9/channels/") {
+		resp.StatusCode = http.StatusOK
+		resp.Body = io.NopCloser(strings.NewReader(`{"id":"m1","channel_id":"channel-1"}`))
+		if req.Method == http.MethodPatch {
+			rt.edited <- struct{}{}
+		}
+	}
+	return resp, nil
+}
+
+func TestInteractionCreate_Jobs(t *testing.T) {
+	players := storetest.NewPlayers(store.Player{ID: "p1", DiscordID: "user-1", CharacterName: "Gandalf", DKP: 70})
+	exporter := export.NewExporter(players, eventtest.NewStore(), noop.NewTracerProvider())
+	clk := clock.Real{}
+	runner := jobs.NewRunner(config.JobsConfig{Timeout: time.Minute, ProgressInterval: time.Second, ReportTo: config.JobsReportChannel},
+		ids.NewULID(clk), clk, slog.Default(), noop.NewTracerProvider())
+	h := commands.NewHandlers(nil, nil, nil, exporter, nil, slog.Default(), noop.NewTracerProvider(), commands.WithJobs(runner, false))
+
+	rt := &jobTransport{edited: make(chan struct{}, 1)}
+	s, _ := discordgo.New("Bot token")
+	s.Client = &http.Client{Transport: rt}
+	i := interaction("i1", "dkp-export")
+	i.ChannelID = "channel-1"
+	i.Member.Permissions = discordgo.PermissionAdministrator
+	i.Data = discordgo.ApplicationCommandInteractionData{Name: "dkp-export", Options: []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "kind", Type: discordgo.ApplicationCommandOptionString, Value: string(export.Standings)},
+	}}
+	h.InteractionCreate(s, i)
+	<-rt.edited
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if len(rt.requests) != 3 {
+		t.Fatalf("requests = %q, want the job message, the answer, and the outcome", rt.requests)
+	}
+	// The job may finish before the interaction is answered.
+	var posted, answer, outcome string
+	for _, r := range rt.requests {
+		switch {
+		case strings.HasPrefix(r, "POST /api/v9/channels/channel-1/messages "):
+			posted = r
+		case strings.HasPrefix(r, "PATCH /api/v9/channels/channel-1/messages/m1 "):
+			outcome = r
+		default:
+			answer = r
+		}
+	}
+	if !strings.Contains(posted, "Export of standings** started by \\u003c@user-1\\u003e") {
+		t.Errorf("job message = %q", posted)
+	}
+	if !strings.Contains(answer, "running in the background as job `job-") || !strings.Contains(answer, "https://discord.com/channels/guild-1/channel-1/m1") || !strings.Contains(answer, `"flags":64`) {
+		t.Errorf("answer = %q, want a private link to the job message", answer)
+	}
+	if !strings.Contains(outcome, "finished in") || !strings.Contains(outcome, "Exported standings.") || !strings.Contains(outcome, "Gandalf") {
+		t.Errorf("outcome = %q, want the export attached", outcome)
+	}
+}
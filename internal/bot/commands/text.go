@@ -0,0 +1,241 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+)
+
+// Prefix commands are classic text commands, such as "!dkp" or "!bid 50",
+// for servers that restrict slash commands. They are routed through the
+// same registry as slash commands, so the same officer checks, standby
+// claims, and metrics apply, and they reply with the same messages.
+
+// WithPrefix enables prefix commands: messages that start with prefix,
+// followed by a command name and its arguments. Reading them requires the
+// privileged message content intent.
+func WithPrefix(prefix string) Option {
+	return func(h *Handlers) { h.prefix = prefix }
+}
+
+// MessageCreate handles prefix commands. Other messages, and names that are
+// not commands, are ignored, as other bots may share the prefix.
+func (h *Handlers) MessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if h.prefix == "" || m.Author == nil || m.Author.Bot || m.GuildID == "" {
+		return
+	}
+	name, args, ok := parsePrefix(h.prefix, m.Content)
+	if !ok {
+		return
+	}
+	c, ok := registry[name]
+	if !ok || c.button {
+		return
+	}
+	start := time.Now()
+	if !h.serves(m.ID, name) {
+		return
+	}
+	ctx, span := h.tracer.Start(context.Background(), "MessageCreate",
+		trace.WithAttributes(attribute.String("command", name)),
+	)
+	defer span.End()
+	ctx = metrics.WithGuild(ctx, m.GuildID)
+
+	if !h.track() {
+		reply(ctx, s, m, userMessage(ctx, errDraining))
+		h.handled(ctx, name, m.Author.ID, errDraining, start)
+		return
+	}
+	defer h.inflight.Done()
+
+	msg, err := h.dispatchText(ctx, s, m, c, args)
+	reply(ctx, s, m, msg)
+	h.handled(ctx, name, m.Author.ID, err, start)
+	h.failed(ctx, span, name, err)
+}
+
+// parsePrefix splits content into a command name, lowercased, and its
+// arguments, if it starts with prefix.
+func parsePrefix(prefix, content string) (name string, args []string, ok bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(content), prefix)
+	if !ok {
+		return "", nil, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || !strings.HasPrefix(rest, fields[0]) {
+		return "", nil, false
+	}
+	return strings.ToLower(fields[0]), fields[1:], true
+}
+
+// dispatchText runs command c sent as message m and returns the reply. A
+// panicking handler is recovered and reported as an errPanic error.
+func (h *Handlers) dispatchText(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, c command, args []string) (msg string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = h.recovered(ctx, c.Name, r)
+			msg = fmt.Sprintf("Command failed: %s", userMessage(ctx, err))
+		}
+	}()
+
+	// The message ID doubles as the idempotency key, as the interaction ID
+	// does for slash commands.
+	ctx = event.WithActor(ctx, m.Author.ID)
+	ctx = idempotency.WithKey(ctx, m.ID)
+	ctx = withArrival(ctx, m.ID)
+	ctx, cancel := context.WithTimeout(ctx, h.deadline(c.Name))
+	defer cancel()
+
+	if c.text == nil {
+		return fmt.Sprintf("`%s%s` is only available as the slash command `/%s`.", h.prefix, c.Name, c.Name), errRejected
+	}
+	if c.officer {
+		if err := h.authorize(ctx, m.GuildID, h.author(ctx, s, m)); err != nil {
+			return userMessage(ctx, err), err
+		}
+	}
+	if c.freeText != nil {
+		if err := h.checkText(ctx, s, m.GuildID, m.Author.ID, h.prefix+c.Name, c.freeText(args)); err != nil {
+			return fmt.Sprintf("Refused: %s", userMessage(ctx, err)), err
+		}
+	}
+	msg, err = c.text(h, ctx, m, args)
+	if err = h.timedOut(ctx, c.Name, err); errors.Is(err, errTimedOut) {
+		msg = userMessage(ctx, err)
+	}
+	return msg, err
+}
+
+// author returns the member who sent m with their permissions in its
+// channel, which messages, unlike interactions, do not carry. Permissions
+// that cannot be computed from the session state are left empty, so that
+// only the guild's admin roles apply.
+func (h *Handlers) author(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate) *discordgo.Member {
+	member := &discordgo.Member{User: m.Author}
+	if m.Member != nil {
+		member.Roles = m.Member.Roles
+	}
+	perms, err := s.State.MessagePermissions(m.Message)
+	if err != nil {
+		h.logger.DebugContext(ctx, "computing message author permissions failed", slog.Any("error", err))
+		return member
+	}
+	member.Permissions = perms
+	return member
+}
+
+// reply answers message m in its channel. The REST call is traced as a
+// child of ctx.
+func reply(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, msg string) {
+	_, _ = s.ChannelMessageSendReply(m.ChannelID, msg, m.Reference(), discordgo.WithContext(ctx))
+}
+
+// syntax returns a reply explaining how to use the named prefix command.
+func (h *Handlers) syntax(name, args string) string {
+	return fmt.Sprintf("Usage: `%s%s %s`", h.prefix, name, args)
+}
+
+// mention matches a user mention, such as "<@123>" or "<@!123>".
+var mention = regexp.MustCompile(`^<@!?(\d+)>$`)
+
+func (h *Handlers) textDKP(ctx context.Context, m *discordgo.MessageCreate, _ []string) (string, error) {
+	return h.balance(ctx, m.Author.ID)
+}
+
+func (h *Handlers) textDKPList(ctx context.Context, _ *discordgo.MessageCreate, _ []string) (string, error) {
+	return h.standings(ctx, false)
+}
+
+func (h *Handlers) textAuctionList(ctx context.Context, _ *discordgo.MessageCreate, _ []string) (string, error) {
+	return h.openAuctions(ctx)
+}
+
+// textBid bids on the auction given, or on the only one open if none is.
+func (h *Handlers) textBid(ctx context.Context, m *discordgo.MessageCreate, args []string) (string, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return h.syntax("bid", "[auction] <amount>"), errRejected
+	}
+	amount, err := strconv.Atoi(args[len(args)-1])
+	if err != nil {
+		return h.syntax("bid", "[auction] <amount>"), errRejected
+	}
+	if len(args) == 2 {
+		return h.placeBid(ctx, args[0], m.Author.ID, amount)
+	}
+
+	auctions, err := h.auctionMgr.ListOpenAuctions(ctx)
+	if err != nil {
+		return fmt.Sprintf("Error listing auctions: %s", userMessage(ctx, err)), err
+	}
+	var open []string
+	for _, a := range auctions {
+		if a.Status != "queued" && a.Status != "scheduled" {
+			open = append(open, a.ID)
+		}
+	}
+	switch len(open) {
+	case 0:
+		return "No open auctions.", errRejected
+	case 1:
+		return h.placeBid(ctx, open[0], m.Author.ID, amount)
+	default:
+		return fmt.Sprintf("%d auctions are open; name one with `%sbid <auction> <amount>`, as listed by `%sauction-list`.", len(open), h.prefix, h.prefix), errRejected
+	}
+}
+
+func (h *Handlers) textDKPAdd(ctx context.Context, _ *discordgo.MessageCreate, args []string) (string, error) {
+	discordID, amount, reason, ok := dkpChange(args)
+	if !ok {
+		return h.syntax("dkp-add", "@player <amount> <reason>"), errRejected
+	}
+	return h.awardDKP(ctx, discordID, amount, reason)
+}
+
+func (h *Handlers) textDKPRemove(ctx context.Context, _ *discordgo.MessageCreate, args []string) (string, error) {
+	discordID, amount, reason, ok := dkpChange(args)
+	if !ok {
+		return h.syntax("dkp-remove", "@player <amount> <reason>"), errRejected
+	}
+	return h.deductDKP(ctx, discordID, amount, reason)
+}
+
+// dkpChangeText returns the reason among the arguments of !dkp-add and
+// !dkp-remove, if they parse.
+func dkpChangeText(args []string) map[string]string {
+	_, _, reason, ok := dkpChange(args)
+	if !ok {
+		return nil
+	}
+	return map[string]string{"reason": reason}
+}
+
+// dkpChange parses the "@player <amount> <reason>" arguments of
+// !dkp-add and !dkp-remove.
+func dkpChange(args []string) (discordID string, amount int, reason string, ok bool) {
+	if len(args) < 3 {
+		return "", 0, "", false
+	}
+	match := mention.FindStringSubmatch(args[0])
+	if match == nil {
+		return "", 0, "", false
+	}
+	amount, err := strconv.Atoi(args[1])
+	if err != nil {
+		return "", 0, "", false
+	}
+	return match[1], amount, strings.Join(args[2:], " "), true
+}
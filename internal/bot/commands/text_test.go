@@ -0,0 +1,168 @@
+package commands_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/bot/commands"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/settings"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/textfilter"
+)
+
+func message(content string, roles ...string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{Message: &discordgo.Message{
+		ChannelID: "channel-1",
+		GuildID:   "guild-1",
+		Content:   content,
+		Author:    &discordgo.User{ID: "111"},
+		Member:    &discordgo.Member{Roles: roles},
+	}}
+}
+
+func TestMessageCreate(t *testing.T) {
+	players := storetest.NewPlayers(
+		store.Player{DiscordID: "111", CharacterName: "Gandalf", DKP: 70},
+		store.Player{DiscordID: "222", CharacterName: "Frodo", DKP: 25},
+	)
+	events := eventtest.NewStore()
+	logger := slog.New(slog.DiscardHandler)
+	tp := noop.NewTracerProvider()
+	dkpMgr := dkp.NewManager(players, events, logger, tp)
+	auctionMgr := auction.NewManager(events, players, logger, tp, clock.Real{})
+	svc := settings.NewService(&memSettings{settings: map[string]store.GuildSetting{
+		settings.AdminRoles: {Key: settings.AdminRoles, Value: "42"},
+	}}, settings.Defaults(config.GuildDefaultsConfig{}), logger)
+	h := commands.NewHandlers(dkpMgr, auctionMgr, nil, nil, nil, logger, tp, commands.WithSettings(svc), commands.WithPrefix("!"))
+
+	ctx := context.Background()
+	first, err := auctionMgr.StartAuction(ctx, "Thunderfury", "officer", 10, 0, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("StartAuction() error = %v", err)
+	}
+	defer func() { _ = auctionMgr.CancelAuction(ctx, first.ID) }()
+
+	n := 0
+	run := func(t *testing.T, h *commands.Handlers, m *discordgo.MessageCreate) []string {
+		t.Helper()
+		rt := &recordingTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		n++
+		m.ID = fmt.Sprintf("message-%d", n)
+		h.MessageCreate(s, m)
+		return rt.bodies
+	}
+	want := func(t *testing.T, m *discordgo.MessageCreate, want string) {
+		t.Helper()
+		bodies := run(t, h, m)
+		var got discordgo.MessageSend
+		if len(bodies) == 1 {
+			_ = json.Unmarshal([]byte(bodies[0]), &got)
+		}
+		if len(bodies) != 1 || !strings.Contains(got.Content, want) {
+			t.Errorf("replies to %q = %q, want one containing %q", m.Content, bodies, want)
+		}
+	}
+
+	t.Run("ignored", func(t *testing.T) {
+		bot := message("!dkp")
+		bot.Author.Bot = true
+		for _, m := range []*discordgo.MessageCreate{message("hello"), message("!hello"), message("! dkp"), bot} {
+			if got := run(t, h, m); len(got) != 0 {
+				t.Errorf("replies to %q = %q, want none", m.Content, got)
+			}
+		}
+		disabled := commands.NewHandlers(dkpMgr, auctionMgr, nil, nil, nil, logger, tp)
+		if got := run(t, disabled, message("!dkp")); len(got) != 0 {
+			t.Errorf("replies without a prefix = %q, want none", got)
+		}
+	})
+	t.Run("read", func(t *testing.T) {
+		want(t, message("!DKP"), "**Gandalf** — DKP: **70**")
+		want(t, message("!dkp-list"), "2. Frodo — 25 DKP")
+		want(t, message("!auction-list"), "**Thunderfury**")
+		want(t, message("!audit"), "only available as the slash command `/audit`")
+	})
+	t.Run("officer", func(t *testing.T) {
+		want(t, message("!dkp-add <@222> 10 boss kill"), "`NOT_OFFICER`")
+		players.RequireDKP(t, "222", 25)
+		want(t, message("!dkp-add <@!222> 10 boss kill", "42"), "Awarded **10 DKP** to **Frodo** for: boss kill")
+		want(t, message("!dkp-remove <@222> 5 late", "42"), "Deducted **5 DKP** from **Frodo** for: late")
+		players.RequireDKP(t, "222", 30)
+		want(t, message("!dkp-add Frodo 10", "42"), "Usage: `!dkp-add @player <amount> <reason>`")
+	})
+	t.Run("bid", func(t *testing.T) {
+		want(t, message("!bid lots"), "Usage: `!bid [auction] <amount>`")
+		want(t, message("!bid 20"), fmt.Sprintf("Bid of **20 DKP** placed on auction `%s`", first.ID))
+
+		second, err := auctionMgr.StartAuction(ctx, "Sulfuras", "officer", 10, 0, 0, time.Hour)
+		if err != nil {
+			t.Fatalf("StartAuction() error = %v", err)
+		}
+		defer func() { _ = auctionMgr.CancelAuction(ctx, second.ID) }()
+		want(t, message("!bid 30"), "2 auctions are open")
+		want(t, message("!bid "+second.ID+" 30"), fmt.Sprintf("Bid of **30 DKP** placed on auction `%s`", second.ID))
+	})
+}
+
+func TestMessageCreate_TextFilter(t *testing.T) {
+	players := storetest.NewPlayers(store.Player{DiscordID: "222", CharacterName: "Frodo", DKP: 25})
+	events := eventtest.NewStore()
+	logger := slog.New(slog.DiscardHandler)
+	tp := noop.NewTracerProvider()
+	svc := settings.NewService(&memSettings{settings: map[string]store.GuildSetting{
+		settings.AdminRoles: {Key: settings.AdminRoles, Value: "42"},
+	}}, settings.Defaults(config.GuildDefaultsConfig{OfficerChannel: "officers"}), logger)
+	filter := textfilter.New(config.TextFilterConfig{Words: []string{"noob"}, MaxLength: 20})
+	h := commands.NewHandlers(dkp.NewManager(players, events, logger, tp), nil, nil, nil, nil, logger, tp,
+		commands.WithSettings(svc), commands.WithTextFilter(filter), commands.WithPrefix("!"))
+
+	run := func(t *testing.T, id, content string) string {
+		t.Helper()
+		rt := &jobTransport{}
+		s, _ := discordgo.New("Bot token")
+		s.Client = &http.Client{Transport: rt}
+		m := message(content, "42")
+		m.ID = id
+		h.MessageCreate(s, m)
+		return strings.Join(rt.requests, "\n")
+	}
+
+	got := run(t, "message-1", "!dkp-remove <@222> 5 what a N00b")
+	for _, want := range []string{
+		"POST /api/v9/channels/officers/messages",
+		"was refused `!dkp-remove` for its `reason`",
+		"`TEXT_BLOCKED`",
+		"||what a N00b||",
+		"Refused: ",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("!dkp-remove with a blocked word = %s, want %q", got, want)
+		}
+	}
+	if got := run(t, "message-2", "!dkp-add <@222> 5 for carrying the whole raid"); !strings.Contains(got, "`TEXT_TOO_LONG`") {
+		t.Errorf("!dkp-add with a long reason = %s, want it refused", got)
+	}
+	players.RequireDKP(t, "222", 25)
+	events.RequireTypes(t)
+
+	if got := run(t, "message-3", "!dkp-add <@222> 5 boss kill"); !strings.Contains(got, "Awarded **5 DKP** to **Frodo**") || strings.Contains(got, "officers") {
+		t.Errorf("!dkp-add = %s, want it to pass the filter", got)
+	}
+}
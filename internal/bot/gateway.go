@@ -0,0 +1,205 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+)
+
+// Gateway is the part of a Discord session that a Supervisor drives.
+// *discordgo.Session implements it.
+type Gateway interface {
+	Open() error
+	HeartbeatLatency() time.Duration
+}
+
+// Supervisor watches the Discord gateway connection. It samples heartbeat
+// latency, reconnects with exponential backoff after the connection drops,
+// and reports the connection state as a health check.
+//
+// discordgo's own reconnect loop is disabled while a session is supervised,
+// so that reconnects are bounded by config and visible in metrics.
+type Supervisor struct {
+	cfg          config.GatewayConfig
+	clock        clock.Clock
+	logger       *slog.Logger
+	metrics      *metrics.Recorder
+	openAuctions func() []string
+
+	// down wakes Run when the connection drops.
+	down chan struct{}
+
+	mu        sync.Mutex
+	session   *discordgo.Session
+	connected bool
+	stopped   bool
+	downSince time.Time
+	latency   time.Duration
+}
+
+// NewSupervisor creates a Supervisor. openAuctions reports the auctions open
+// at the time of a disconnect, so that drops mid-auction stand out.
+func NewSupervisor(cfg config.GatewayConfig, clk clock.Clock, logger *slog.Logger, rec *metrics.Recorder, openAuctions func() []string) *Supervisor {
+	return &Supervisor{
+		cfg:          cfg,
+		clock:        clk,
+		logger:       logger,
+		metrics:      rec,
+		openAuctions: openAuctions,
+		down:         make(chan struct{}, 1),
+	}
+}
+
+// Connected records that the gateway connection is established.
+func (s *Supervisor) Connected(ctx context.Context) {
+	s.mu.Lock()
+	since := s.downSince
+	s.connected = true
+	s.downSince = time.Time{}
+	s.mu.Unlock()
+
+	if since.IsZero() {
+		return
+	}
+	downtime := s.clock.Now().Sub(since)
+	s.metrics.GatewayReconnected(ctx, downtime)
+	s.logger.InfoContext(ctx, "discord gateway reconnected", slog.Duration("downtime", downtime))
+}
+
+// Disconnected records that the gateway connection dropped and wakes Run to
+// reconnect. It is a no-op once the Supervisor is stopped, so that a
+// deliberate shutdown is not reported as an outage.
+func (s *Supervisor) Disconnected(ctx context.Context) {
+	s.mu.Lock()
+	if !s.connected || s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.connected = false
+	s.downSince = s.clock.Now()
+	s.mu.Unlock()
+
+	open := s.openAuctions()
+	s.metrics.GatewayDisconnected(ctx, len(open) > 0)
+	if len(open) > 0 {
+		s.logger.WarnContext(ctx, "discord gateway disconnected with auctions open",
+			slog.Any("auctions", open),
+		)
+	} else {
+		s.logger.WarnContext(ctx, "discord gateway disconnected")
+	}
+
+	select {
+	case s.down <- struct{}{}:
+	default:
+	}
+}
+
+// attach records the session whose gateway is supervised.
+func (s *Supervisor) attach(session *discordgo.Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.session = session
+}
+
+// Session returns the supervised Discord session, or nil if none is
+// attached yet. It is meant for health.DiscordChecker.
+func (s *Supervisor) Session() *discordgo.Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.session
+}
+
+// Stop marks the Supervisor as stopped. Later disconnects are expected and
+// not reconnected.
+func (s *Supervisor) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+}
+
+// Run samples heartbeat latency every check interval and reconnects gw after
+// each disconnect, until ctx is canceled.
+func (s *Supervisor) Run(ctx context.Context, gw Gateway) {
+	ticker := clock.NewTicker(s.clock, s.cfg.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			s.sample(ctx, gw)
+		case <-s.down:
+			s.reconnect(ctx, gw)
+		}
+	}
+}
+
+func (s *Supervisor) sample(ctx context.Context, gw Gateway) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.connected {
+		return
+	}
+	// The latency is negative while a heartbeat awaits its ack.
+	if l := gw.HeartbeatLatency(); l >= 0 {
+		s.latency = l
+		s.metrics.HeartbeatLatency(ctx, l)
+	}
+}
+
+func (s *Supervisor) reconnect(ctx context.Context, gw Gateway) {
+	wait := s.cfg.ReconnectMin
+	for attempt := 1; ; attempt++ {
+		s.mu.Lock()
+		done := s.connected || s.stopped
+		s.mu.Unlock()
+		if done {
+			return
+		}
+
+		err := gw.Open()
+		if err == nil || errors.Is(err, discordgo.ErrWSAlreadyOpen) {
+			return
+		}
+		s.logger.WarnContext(ctx, "discord gateway reconnect failed",
+			slog.Int("attempt", attempt),
+			slog.Duration("retry_in", wait),
+			slog.Any("error", err),
+		)
+
+		timer := clock.NewTimer(s.clock, wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		wait = min(wait*2, s.cfg.ReconnectMax)
+	}
+}
+
+// Check reports an error while the gateway is disconnected or its heartbeat
+// latency exceeds the configured maximum. It is meant for a health.Checker.
+func (s *Supervisor) Check(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case !s.connected && s.downSince.IsZero():
+		return errors.New("discord gateway not connected")
+	case !s.connected:
+		return fmt.Errorf("discord gateway disconnected for %s", s.clock.Now().Sub(s.downSince).Round(time.Second))
+	case s.latency > s.cfg.MaxLatency:
+		return fmt.Errorf("discord gateway heartbeat latency %s exceeds %s", s.latency, s.cfg.MaxLatency)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package bot_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/bot"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+)
+
+// fakeGateway fails the first failures calls to Open, then reconnects. It
+// signals each reconnect on opened and each latency sample on sampled.
+type fakeGateway struct {
+	mu       sync.Mutex
+	sup      *bot.Supervisor
+	failures int
+	opens    int
+	latency  time.Duration
+	opened   chan struct{}
+	sampled  chan struct{}
+}
+
+func newFakeGateway(sup *bot.Supervisor, failures int, latency time.Duration) *fakeGateway {
+	return &fakeGateway{
+		sup:      sup,
+		failures: failures,
+		latency:  latency,
+		opened:   make(chan struct{}, 1),
+		sampled:  make(chan struct{}, 1),
+	}
+}
+
+func (g *fakeGateway) Open() error {
+	g.mu.Lock()
+	g.opens++
+	fail := g.opens <= g.failures
+	g.mu.Unlock()
+	if fail {
+		return errors.New("dial tcp: connection refused")
+	}
+	g.sup.Connected(context.Background())
+	signal(g.opened)
+	return nil
+}
+
+func (g *fakeGateway) HeartbeatLatency() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	signal(g.sampled)
+	return g.latency
+}
+
+// signal sends on c unless a send is already pending.
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+// await waits for a signal on c, failing t if none comes in time.
+func await(t *testing.T, c chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-c:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+var testGatewayConfig = config.GatewayConfig{
+	CheckInterval: time.Millisecond,
+	MaxLatency:    time.Second,
+	ReconnectMin:  time.Millisecond,
+	ReconnectMax:  4 * time.Millisecond,
+}
+
+func TestSupervisor_Reconnect(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	rec, err := metrics.New(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)), "guild-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := clock.NewFakeClock(time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC))
+	sup := bot.NewSupervisor(testGatewayConfig, clk, slog.Default(), rec,
+		func() []string { return []string{"auction-1"} })
+	gw := newFakeGateway(sup, 3, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sup.Run(ctx, gw)
+
+	if err := sup.Check(ctx); err == nil {
+		t.Fatal("Check() before connecting = nil, want error")
+	}
+	sup.Connected(ctx)
+	if err := sup.Check(ctx); err != nil {
+		t.Fatalf("Check() after connecting = %v", err)
+	}
+
+	sup.Disconnected(ctx)
+	if err := sup.Check(ctx); err == nil {
+		t.Fatal("Check() after disconnect = nil, want error")
+	}
+
+	// Each failed Open waits for a backoff timer, next to the check
+	// ticker.
+	for range gw.failures {
+		clk.BlockUntil(2)
+		clk.Advance(testGatewayConfig.ReconnectMax)
+	}
+	await(t, gw.opened, "the reconnect")
+	if err := sup.Check(ctx); err != nil {
+		t.Fatalf("Check() after reconnecting = %v", err)
+	}
+	gw.mu.Lock()
+	opens := gw.opens
+	gw.mu.Unlock()
+	if opens != 4 {
+		t.Errorf("got %d Open calls, want 4", opens)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	var disconnects, reconnects int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				continue
+			}
+			for _, dp := range sum.DataPoints {
+				switch m.Name {
+				case "dkpbot.gateway.disconnects":
+					if v, _ := dp.Attributes.Value(metrics.MidAuctionKey); v != attribute.BoolValue(true) {
+						t.Errorf("disconnect not marked mid-auction: %v", dp.Attributes)
+					}
+					disconnects += dp.Value
+				case "dkpbot.gateway.reconnects":
+					reconnects += dp.Value
+				}
+			}
+		}
+	}
+	if disconnects != 1 || reconnects != 1 {
+		t.Errorf("got %d disconnects and %d reconnects, want 1 each", disconnects, reconnects)
+	}
+}
+
+func TestSupervisor_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		latency time.Duration
+		wantErr bool
+	}{
+		{name: "healthy latency", latency: 50 * time.Millisecond},
+		{name: "latency too high", latency: 2 * time.Second, wantErr: true},
+		{name: "awaiting heartbeat ack", latency: -time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFakeClock(time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC))
+			sup := bot.NewSupervisor(testGatewayConfig, clk, slog.Default(), metrics.Nop(),
+				func() []string { return nil })
+			gw := newFakeGateway(sup, 0, tt.latency)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			sup.Connected(ctx)
+			go sup.Run(ctx, gw)
+
+			// The latency is sampled under the lock Check takes, so once
+			// the gateway was asked for it, Check sees it.
+			clk.BlockUntil(1)
+			clk.Advance(testGatewayConfig.CheckInterval)
+			await(t, gw.sampled, "a latency sample")
+			if err := sup.Check(ctx); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSupervisor_StopIgnoresDisconnect(t *testing.T) {
+	sup := bot.NewSupervisor(testGatewayConfig, clock.Real{}, slog.Default(), metrics.Nop(),
+		func() []string { return nil })
+	ctx := context.Background()
+	sup.Connected(ctx)
+	sup.Stop()
+	sup.Disconnected(ctx)
+	if err := sup.Check(ctx); err != nil {
+		t.Errorf("Check() after stopped disconnect = %v, want nil", err)
+	}
+}
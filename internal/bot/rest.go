@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Discord REST span attributes not covered by semantic conventions.
+const (
+	attrRoute           = attribute.Key("discord.route")
+	attrRateLimitBucket = attribute.Key("discord.ratelimit.bucket")
+	attrRateLimitRemain = attribute.Key("discord.ratelimit.remaining")
+	attrRateLimitRetry  = attribute.Key("discord.ratelimit.retry_after")
+	attrRateLimitGlobal = attribute.Key("discord.ratelimit.global")
+	attrRateLimitScope  = attribute.Key("discord.ratelimit.scope")
+	attrResponseStatus  = attribute.Key("http.response.status_code")
+	attrRequestMethod   = attribute.Key("http.request.method")
+)
+
+// tracingTransport records a client span for every Discord REST call. The
+// span is a child of the request context, so calls made with
+// discordgo.WithContext nest under the interaction that made them.
+type tracingTransport struct {
+	base   http.RoundTripper
+	tracer trace.Tracer
+}
+
+// NewTracingTransport wraps base so that each request is traced, including
+// the rate-limit bucket and, when throttled, the retry-after delay. A nil
+// base uses http.DefaultTransport.
+func NewTracingTransport(base http.RoundTripper, tp trace.TracerProvider) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &tracingTransport{
+		base:   base,
+		tracer: tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/bot"),
+	}
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	route := Route(req.URL.Path)
+	ctx, span := t.tracer.Start(req.Context(), "discord "+req.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attrRequestMethod.String(req.Method),
+			attrRoute.String(route),
+		),
+	)
+	defer span.End()
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attrResponseStatus.Int(resp.StatusCode))
+	if bucket := resp.Header.Get("X-RateLimit-Bucket"); bucket != "" {
+		span.SetAttributes(attrRateLimitBucket.String(bucket))
+	}
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		span.SetAttributes(attrRateLimitRemain.Int(remaining))
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		// discordgo sleeps for retry-after and reissues the request, which
+		// shows up as a sibling span.
+		retry, _ := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64)
+		span.SetAttributes(
+			attrRateLimitRetry.Float64(retry),
+			attrRateLimitGlobal.Bool(resp.Header.Get("X-RateLimit-Global") == "true"),
+			attrRateLimitScope.String(resp.Header.Get("X-RateLimit-Scope")),
+		)
+		span.AddEvent("rate limited")
+	}
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
+
+// Route returns path with snowflake IDs and interaction or webhook tokens
+// replaced by placeholders, so that span names have low cardinality and
+// never contain credentials.
+func Route(path string) string {
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		switch {
+		case isSnowflake(seg):
+			segs[i] = ":id"
+		case i >= 2 && (segs[i-2] == "interactions" || segs[i-2] == "webhooks"):
+			segs[i] = ":token"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+func isSnowflake(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
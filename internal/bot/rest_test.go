@@ -0,0 +1,85 @@
+package bot_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/bot"
+)
+
+func TestRoute(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/v9/channels/123456789012345678/messages", "/api/v9/channels/:id/messages"},
+		{"/api/v9/interactions/123456789012345678/aW50ZXJhY3Rpb24.token/callback", "/api/v9/interactions/:id/:token/callback"},
+		{"/api/v9/webhooks/987654321098765432/aW50ZXJhY3Rpb24.token/messages/@original", "/api/v9/webhooks/:id/:token/messages/@original"},
+		{"/api/v9/users/@me", "/api/v9/users/@me"},
+	}
+	for _, tt := range tests {
+		if got := bot.Route(tt.path); got != tt.want {
+			t.Errorf("Route(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTracingTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Bucket", "abcd1234")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("Retry-After", "1.5")
+		w.Header().Set("X-RateLimit-Scope", "user")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "InteractionCreate")
+	client := &http.Client{Transport: bot.NewTracingTransport(nil, tp)}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/api/v9/interactions/1/secret-token/callback", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "discord POST /api/v9/interactions/:id/:token/callback" {
+		t.Errorf("span name = %q", span.Name())
+	}
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("REST span is not a child of the interaction span")
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("status = %v, want Error", span.Status().Code)
+	}
+
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if got := attrs["discord.ratelimit.bucket"].AsString(); got != "abcd1234" {
+		t.Errorf("bucket = %q, want abcd1234", got)
+	}
+	if got := attrs["discord.ratelimit.retry_after"].AsFloat64(); got != 1.5 {
+		t.Errorf("retry_after = %v, want 1.5", got)
+	}
+	if got := attrs["http.response.status_code"].AsInt64(); got != http.StatusTooManyRequests {
+		t.Errorf("status code = %d, want 429", got)
+	}
+}
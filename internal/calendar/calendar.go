@@ -0,0 +1,492 @@
+// Package calendar schedules raids ahead of time. Members sign up for a
+// scheduled raid as accepted, tentative, or declined, which tells officers
+// how many of each raid role to expect against the raid's quotas. Officers
+// can compose the raid's roster from the signups, benching the rest on
+// standby. Signed-up members are reminded before the raid starts, and those
+// who signed up and showed on time can be awarded a DKP bonus. Scheduled raids are kept as
+// events in the event store.
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// Errors returned by calendar operations.
+var (
+	ErrUnknownRaid   = derrors.New(derrors.NotFound, "UNKNOWN_SCHEDULED_RAID", "no such scheduled raid")
+	ErrNoRecentRaid  = derrors.New(derrors.NotFound, "NO_RECENT_SCHEDULED_RAID", "no scheduled raid started in the last 12 hours")
+	ErrPastStart     = derrors.New(derrors.Validation, "PAST_RAID_START", "the raid must start in the future")
+	ErrInvalidQuota  = derrors.New(derrors.Validation, "INVALID_QUOTA", "role quotas must not be negative")
+	ErrInvalidStatus = derrors.New(derrors.Validation, "INVALID_SIGNUP_STATUS", "sign up as accepted, tentative, or declined")
+	ErrRaidStarted   = derrors.New(derrors.Conflict, "SCHEDULED_RAID_STARTED", "the raid has already started")
+	ErrBonusAwarded  = derrors.New(derrors.Conflict, "BONUS_ALREADY_AWARDED", "the on-time bonus of this raid was already awarded")
+	ErrInvalidBonus  = derrors.New(derrors.Validation, "INVALID_BONUS", "the on-time bonus must be positive")
+	// ErrQuotasExceedSize is returned when the role quotas of a raid add
+	// up to more members than it takes.
+	ErrQuotasExceedSize = derrors.New(derrors.Validation, "QUOTAS_EXCEED_SIZE", "the role quotas add up to more than the raid size")
+)
+
+// Signup statuses.
+const (
+	Accepted  = "accepted"
+	Tentative = "tentative"
+	Declined  = "declined"
+)
+
+// recentRaid is how long after its start a scheduled raid may still be
+// awarded its on-time bonus.
+const recentRaid = 12 * time.Hour
+
+// Quotas are how many members of each raid role a raid needs, at least.
+// Zero means no quota.
+type Quotas struct {
+	Tanks   int
+	Healers int
+	DPS     int
+	// Size is how many members the raid takes. Zero means the sum of the
+	// role quotas.
+	Size int
+}
+
+// size returns how many members the raid takes, or 0 for no limit.
+func (q Quotas) size() int {
+	if q.Size > 0 {
+		return q.Size
+	}
+	return q.Tanks + q.Healers + q.DPS
+}
+
+// Signup is a member's answer to a raid.
+type Signup struct {
+	DiscordID string
+	Status    string
+	// Role is the member's raid role as of signing up, if known.
+	Role string
+	At   time.Time
+}
+
+// Raid is a scheduled raid as recorded in its events.
+type Raid struct {
+	ID       string
+	Name     string
+	StartsAt time.Time
+	Quotas   Quotas
+	// ScheduledBy is the Discord ID of the member who scheduled the raid.
+	ScheduledBy string
+	// Signups hold each member's latest answer, in the order they first
+	// answered.
+	Signups  []Signup
+	Reminded bool
+	// Roster and Standby are the Discord IDs of the members picked for
+	// the raid and benched by its latest composition, if any. Members who
+	// decline afterwards are taken off either.
+	Roster  []string
+	Standby []string
+	// Bonus is the on-time bonus awarded, or 0.
+	Bonus   int
+	Version int
+}
+
+// Count returns how many members signed up with status, of role if role is
+// not empty.
+func (r *Raid) Count(status, role string) int {
+	n := 0
+	for _, s := range r.Signups {
+		if s.Status == status && (role == "" || s.Role == role) {
+			n++
+		}
+	}
+	return n
+}
+
+// Expected returns how many members are expected to attend: those who
+// accepted.
+func (r *Raid) Expected() int {
+	return r.Count(Accepted, "")
+}
+
+// Attending returns the Discord IDs of the members who accepted or may
+// attend.
+func (r *Raid) Attending() []string {
+	var ids []string
+	for _, s := range r.Signups {
+		if s.Status == Accepted || s.Status == Tentative {
+			ids = append(ids, s.DiscordID)
+		}
+	}
+	return ids
+}
+
+// Bonus is an on-time bonus awarded for a scheduled raid.
+type Bonus struct {
+	Raid *Raid
+	// Players are those awarded the bonus.
+	Players []store.Player
+	// Late are the members who accepted but joined late or not at all.
+	Late []string
+}
+
+// DKP looks players up and awards them DKP.
+type DKP interface {
+	GetPlayer(ctx context.Context, discordID string) (*store.Player, error)
+	AwardDKP(ctx context.Context, playerID string, amount int, reason string) error
+}
+
+// Service schedules raids and records signups.
+type Service struct {
+	events event.Store
+	dkp    DKP
+	grace  time.Duration
+	logger *slog.Logger
+	tracer trace.Tracer
+	clock  clock.Clock
+
+	// mu serializes changes, which version the raid's events.
+	mu sync.Mutex
+}
+
+// NewService returns a Service that records scheduled raids in events and
+// awards on-time bonuses through dkp.
+func NewService(events event.Store, dkp DKP, cfg config.CalendarConfig, logger *slog.Logger, tp trace.TracerProvider, clk clock.Clock) *Service {
+	return &Service{
+		events: events,
+		dkp:    dkp,
+		grace:  cfg.OnTimeGrace,
+		logger: logger,
+		tracer: tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/calendar"),
+		clock:  clk,
+	}
+}
+
+// Schedule schedules a raid named name starting at startsAt, on behalf of
+// the member scheduledBy.
+func (s *Service) Schedule(ctx context.Context, name, scheduledBy string, startsAt time.Time, quotas Quotas) (*Raid, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Schedule",
+		trace.WithAttributes(
+			attribute.String("raid.name", name),
+			attribute.String("raid.starts_at", startsAt.UTC().Format(time.RFC3339)),
+		),
+	)
+	defer span.End()
+
+	now := s.clock.Now()
+	if !startsAt.After(now) {
+		return nil, ErrPastStart
+	}
+	if quotas.Tanks < 0 || quotas.Healers < 0 || quotas.DPS < 0 || quotas.Size < 0 {
+		return nil, ErrInvalidQuota
+	}
+	if quotas.Size > 0 && quotas.Size < quotas.Tanks+quotas.Healers+quotas.DPS {
+		return nil, ErrQuotasExceedSize
+	}
+
+	r := &Raid{
+		ID:          fmt.Sprintf("scheduled-%d", now.UnixNano()),
+		Name:        name,
+		StartsAt:    startsAt.UTC(),
+		Quotas:      quotas,
+		ScheduledBy: scheduledBy,
+	}
+	data, _ := json.Marshal(event.RaidScheduledData{
+		Name:        name,
+		StartsAt:    r.StartsAt,
+		ScheduledBy: scheduledBy,
+		Tanks:       quotas.Tanks,
+		Healers:     quotas.Healers,
+		DPS:         quotas.DPS,
+		Size:        quotas.Size,
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.append(ctx, r, event.RaidScheduled, data); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "raid scheduled",
+		slog.String("raid_id", r.ID),
+		slog.String("name", name),
+		slog.Time("starts_at", r.StartsAt),
+	)
+	return r, nil
+}
+
+// Get returns the scheduled raid raidID.
+func (s *Service) Get(ctx context.Context, raidID string) (*Raid, error) {
+	events, err := s.events.Load(ctx, raidID)
+	if err != nil {
+		return nil, fmt.Errorf("loading scheduled raid events: %w", err)
+	}
+	if len(events) == 0 || events[0].Type != event.RaidScheduled {
+		return nil, ErrUnknownRaid.Wrap(fmt.Errorf("raid %s", raidID))
+	}
+	return replay(events)
+}
+
+// Upcoming returns the raids that have yet to start, soonest first.
+func (s *Service) Upcoming(ctx context.Context) ([]*Raid, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Upcoming")
+	defer span.End()
+
+	return s.since(ctx, s.clock.Now())
+}
+
+// since returns the raids starting after t, soonest first.
+func (s *Service) since(ctx context.Context, t time.Time) ([]*Raid, error) {
+	scheduled, err := s.events.LoadByType(ctx, event.RaidScheduled)
+	if err != nil {
+		return nil, fmt.Errorf("loading raid scheduled events: %w", err)
+	}
+	var raids []*Raid
+	for _, e := range scheduled {
+		var d event.RaidScheduledData
+		if err := json.Unmarshal(e.Data, &d); err != nil || !d.StartsAt.After(t) {
+			continue
+		}
+		r, err := s.Get(ctx, e.AggregateID)
+		if err != nil {
+			return nil, err
+		}
+		raids = append(raids, r)
+	}
+	slices.SortFunc(raids, func(a, b *Raid) int { return a.StartsAt.Compare(b.StartsAt) })
+	return raids, nil
+}
+
+// SignUp records the answer of the member discordID, of raid role role, to
+// raidID. Members may change their answer until the raid starts.
+func (s *Service) SignUp(ctx context.Context, raidID, discordID, status, role string) (*Raid, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.SignUp",
+		trace.WithAttributes(
+			attribute.String("raid.id", raidID),
+			attribute.String("discord_id", discordID),
+			attribute.String("status", status),
+		),
+	)
+	defer span.End()
+
+	if status != Accepted && status != Tentative && status != Declined {
+		return nil, ErrInvalidStatus
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.Get(ctx, raidID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	if !now.Before(r.StartsAt) {
+		return nil, ErrRaidStarted
+	}
+	data, _ := json.Marshal(event.RaidSignedUpData{DiscordID: discordID, Status: status, Role: role})
+	if err := s.append(ctx, r, event.RaidSignedUp, data); err != nil {
+		return nil, err
+	}
+	r.signUp(Signup{DiscordID: discordID, Status: status, Role: role, At: now})
+	return r, nil
+}
+
+// Due returns the raids starting within lead whose members have not been
+// reminded yet.
+func (s *Service) Due(ctx context.Context, lead time.Duration) ([]*Raid, error) {
+	now := s.clock.Now()
+	upcoming, err := s.since(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	var due []*Raid
+	for _, r := range upcoming {
+		if !r.Reminded && !r.StartsAt.After(now.Add(lead)) {
+			due = append(due, r)
+		}
+	}
+	return due, nil
+}
+
+// MarkReminded records that recipients were reminded of raidID.
+func (s *Service) MarkReminded(ctx context.Context, raidID string, recipients []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.Get(ctx, raidID)
+	if err != nil {
+		return err
+	}
+	data, _ := json.Marshal(event.RaidRemindedData{Recipients: recipients})
+	return s.append(ctx, r, event.RaidReminded, data)
+}
+
+// AwardOnTime awards amount DKP to the members who accepted the scheduled
+// raid that started most recently, within the last 12 hours, and joined
+// by its start plus the configured grace, as joinedAt reports. Members
+// without a player are skipped. A raid's bonus is awarded once.
+func (s *Service) AwardOnTime(ctx context.Context, joinedAt map[string]time.Time, amount int) (*Bonus, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.AwardOnTime",
+		trace.WithAttributes(attribute.Int("amount", amount)),
+	)
+	defer span.End()
+
+	if amount <= 0 {
+		return nil, ErrInvalidBonus
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	recent, err := s.since(ctx, now.Add(-recentRaid))
+	if err != nil {
+		return nil, err
+	}
+	var r *Raid
+	for _, candidate := range recent {
+		if !candidate.StartsAt.After(now) {
+			r = candidate
+		}
+	}
+	if r == nil {
+		return nil, ErrNoRecentRaid
+	}
+	if r.Bonus > 0 {
+		return nil, ErrBonusAwarded
+	}
+
+	bonus := &Bonus{Raid: r}
+	deadline := r.StartsAt.Add(s.grace)
+	reason := r.Name + ": on-time attendance bonus"
+	for _, signup := range r.Signups {
+		if signup.Status != Accepted {
+			continue
+		}
+		joined, ok := joinedAt[signup.DiscordID]
+		if !ok || joined.After(deadline) {
+			bonus.Late = append(bonus.Late, signup.DiscordID)
+			continue
+		}
+		p, err := s.dkp.GetPlayer(ctx, signup.DiscordID)
+		if err != nil {
+			s.logger.WarnContext(ctx, "skipping on-time bonus of unregistered member",
+				slog.String("discord_id", signup.DiscordID),
+				slog.Any("error", err),
+			)
+			continue
+		}
+		if p.Archived() {
+			s.logger.WarnContext(ctx, "skipping on-time bonus of archived player",
+				slog.String("discord_id", signup.DiscordID),
+			)
+			continue
+		}
+		// Keyed per raid and player, so that a retried award is applied
+		// once.
+		awardCtx := idempotency.WithKey(ctx, "calendar:"+r.ID+":"+p.ID)
+		if err := s.dkp.AwardDKP(awardCtx, p.ID, amount, reason); err != nil {
+			return nil, fmt.Errorf("awarding %s: %w", p.CharacterName, err)
+		}
+		bonus.Players = append(bonus.Players, *p)
+	}
+
+	ids := make([]string, len(bonus.Players))
+	for n, p := range bonus.Players {
+		ids[n] = p.ID
+	}
+	data, _ := json.Marshal(event.RaidBonusAwardedData{Amount: amount, PlayerIDs: ids})
+	if err := s.append(ctx, r, event.RaidBonusAwarded, data); err != nil {
+		return nil, err
+	}
+	r.Bonus = amount
+
+	s.logger.InfoContext(ctx, "on-time bonus awarded",
+		slog.String("raid_id", r.ID),
+		slog.Int("amount", amount),
+		slog.Int("players", len(bonus.Players)),
+	)
+	return bonus, nil
+}
+
+// append records an event of type t on r.
+func (s *Service) append(ctx context.Context, r *Raid, t event.Type, data json.RawMessage) error {
+	e := event.Event{
+		AggregateID: r.ID,
+		Type:        t,
+		Data:        data,
+		Version:     r.Version + 1,
+	}
+	if err := s.events.Append(ctx, e); err != nil {
+		return fmt.Errorf("recording %s event: %w", t, err)
+	}
+	r.Version = e.Version
+	return nil
+}
+
+// signUp replaces the member's earlier answer, if any, with signup.
+func (r *Raid) signUp(signup Signup) {
+	if signup.Status == Declined {
+		drop := func(id string) bool { return id == signup.DiscordID }
+		r.Roster = slices.DeleteFunc(r.Roster, drop)
+		r.Standby = slices.DeleteFunc(r.Standby, drop)
+	}
+	for n, s := range r.Signups {
+		if s.DiscordID == signup.DiscordID {
+			r.Signups[n] = signup
+			return
+		}
+	}
+	r.Signups = append(r.Signups, signup)
+}
+
+// replay reconstructs a scheduled raid from its events.
+func replay(events []event.Event) (*Raid, error) {
+	r := &Raid{ID: events[0].AggregateID}
+	for _, e := range events {
+		switch e.Type {
+		case event.RaidScheduled:
+			var d event.RaidScheduledData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, fmt.Errorf("unmarshaling raid scheduled event: %w", err)
+			}
+			r.Name, r.StartsAt, r.ScheduledBy = d.Name, d.StartsAt, d.ScheduledBy
+			r.Quotas = Quotas{Tanks: d.Tanks, Healers: d.Healers, DPS: d.DPS, Size: d.Size}
+		case event.RaidSignedUp:
+			var d event.RaidSignedUpData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, fmt.Errorf("unmarshaling signup event: %w", err)
+			}
+			r.signUp(Signup{DiscordID: d.DiscordID, Status: d.Status, Role: d.Role, At: e.CreatedAt})
+		case event.RaidReminded:
+			r.Reminded = true
+		case event.RaidBonusAwarded:
+			var d event.RaidBonusAwardedData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, fmt.Errorf("unmarshaling bonus event: %w", err)
+			}
+			r.Bonus = d.Amount
+		case event.RaidComposed:
+			var d event.RaidComposedData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, fmt.Errorf("unmarshaling composition event: %w", err)
+			}
+			r.Roster, r.Standby = d.Roster, d.Standby
+		}
+		r.Version = e.Version
+	}
+	return r, nil
+}
@@ -0,0 +1,246 @@
+package calendar_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/calendar"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// mockDKP knows the players of members d1 to d3 and records awards.
+type mockDKP struct {
+	awards map[string]int
+}
+
+func (m *mockDKP) GetPlayer(_ context.Context, discordID string) (*store.Player, error) {
+	switch discordID {
+	case "d1", "d2", "d3":
+		return &store.Player{ID: "p" + discordID[1:], DiscordID: discordID, CharacterName: "Char" + discordID[1:]}, nil
+	}
+	return nil, errors.New("player not found")
+}
+
+func (m *mockDKP) AwardDKP(_ context.Context, playerID string, amount int, _ string) error {
+	m.awards[playerID] += amount
+	return nil
+}
+
+var start = time.Date(2025, 6, 20, 19, 30, 0, 0, time.UTC)
+
+func newService(clk *clock.Mock, dkp *mockDKP) *calendar.Service {
+	return calendar.NewService(eventtest.NewStore(), dkp, config.CalendarConfig{OnTimeGrace: 10 * time.Minute},
+		slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clk)
+}
+
+func TestService_SignUp(t *testing.T) {
+	ctx := context.Background()
+	clk := &clock.Mock{T: start.Add(-24 * time.Hour)}
+	svc := newService(clk, &mockDKP{})
+
+	if _, err := svc.Schedule(ctx, "Molten Core", "officer", clk.T, calendar.Quotas{}); !errors.Is(err, calendar.ErrPastStart) {
+		t.Fatalf("Schedule() in the past error = %v, want ErrPastStart", err)
+	}
+	if _, err := svc.Schedule(ctx, "Molten Core", "officer", start, calendar.Quotas{Tanks: -1}); !errors.Is(err, calendar.ErrInvalidQuota) {
+		t.Fatalf("Schedule() with a negative quota error = %v, want ErrInvalidQuota", err)
+	}
+	r, err := svc.Schedule(ctx, "Molten Core", "officer", start, calendar.Quotas{Tanks: 2, Healers: 4, DPS: 14})
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+
+	for _, su := range []struct{ id, status, role string }{
+		{"d1", calendar.Accepted, "tank"},
+		{"d2", calendar.Tentative, "healer"},
+		{"d3", calendar.Accepted, "dps"},
+		{"d1", calendar.Declined, "tank"},
+	} {
+		if _, err := svc.SignUp(ctx, r.ID, su.id, su.status, su.role); err != nil {
+			t.Fatalf("SignUp(%s, %s) error = %v", su.id, su.status, err)
+		}
+	}
+	if _, err := svc.SignUp(ctx, r.ID, "d4", "maybe", ""); !errors.Is(err, calendar.ErrInvalidStatus) {
+		t.Errorf("SignUp() with an unknown status error = %v, want ErrInvalidStatus", err)
+	}
+
+	got, err := svc.Get(ctx, r.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Name != "Molten Core" || got.ScheduledBy != "officer" || got.Quotas.DPS != 14 || len(got.Signups) != 3 {
+		t.Fatalf("Get() = %+v", got)
+	}
+	if got.Expected() != 1 || got.Count(calendar.Accepted, "dps") != 1 || got.Count(calendar.Accepted, "tank") != 0 || got.Count(calendar.Declined, "") != 1 {
+		t.Errorf("counts: expected %d, accepted dps %d, accepted tanks %d, declined %d; want 1, 1, 0, 1",
+			got.Expected(), got.Count(calendar.Accepted, "dps"), got.Count(calendar.Accepted, "tank"), got.Count(calendar.Declined, ""))
+	}
+	if want := []string{"d2", "d3"}; !slices.Equal(got.Attending(), want) {
+		t.Errorf("Attending() = %v, want %v", got.Attending(), want)
+	}
+
+	clk.T = start
+	if _, err := svc.SignUp(ctx, r.ID, "d4", calendar.Accepted, ""); !errors.Is(err, calendar.ErrRaidStarted) {
+		t.Errorf("SignUp() after the start error = %v, want ErrRaidStarted", err)
+	}
+	if upcoming, err := svc.Upcoming(ctx); err != nil || len(upcoming) != 0 {
+		t.Errorf("Upcoming() after the start = %d raids, %v; want none", len(upcoming), err)
+	}
+}
+
+func TestService_DueAndAwardOnTime(t *testing.T) {
+	ctx := context.Background()
+	clk := &clock.Mock{T: start.Add(-time.Hour)}
+	dkp := &mockDKP{awards: map[string]int{}}
+	svc := newService(clk, dkp)
+
+	r, err := svc.Schedule(ctx, "Molten Core", "officer", start, calendar.Quotas{})
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	for _, id := range []string{"d1", "d2", "d3", "d9"} {
+		if _, err := svc.SignUp(ctx, r.ID, id, calendar.Accepted, ""); err != nil {
+			t.Fatalf("SignUp(%s) error = %v", id, err)
+		}
+	}
+
+	if due, _ := svc.Due(ctx, 30*time.Minute); len(due) != 0 {
+		t.Errorf("Due() an hour before = %d raids, want none", len(due))
+	}
+	clk.T = start.Add(-20 * time.Minute)
+	due, err := svc.Due(ctx, 30*time.Minute)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("Due() 20 minutes before = %d raids, %v; want 1", len(due), err)
+	}
+	if err := svc.MarkReminded(ctx, r.ID, due[0].Attending()); err != nil {
+		t.Fatalf("MarkReminded() error = %v", err)
+	}
+	if due, _ := svc.Due(ctx, 30*time.Minute); len(due) != 0 {
+		t.Errorf("Due() after reminding = %d raids, want none", len(due))
+	}
+
+	if _, err := svc.AwardOnTime(ctx, nil, 10); !errors.Is(err, calendar.ErrNoRecentRaid) {
+		t.Errorf("AwardOnTime() before the start error = %v, want ErrNoRecentRaid", err)
+	}
+
+	clk.T = start.Add(3 * time.Hour)
+	joined := map[string]time.Time{
+		"d1": start.Add(5 * time.Minute),  // within the grace
+		"d2": start.Add(30 * time.Minute), // late
+		"d9": start,                       // not registered
+	}
+	bonus, err := svc.AwardOnTime(ctx, joined, 10)
+	if err != nil {
+		t.Fatalf("AwardOnTime() error = %v", err)
+	}
+	if len(bonus.Players) != 1 || bonus.Players[0].ID != "p1" || dkp.awards["p1"] != 10 || len(dkp.awards) != 1 {
+		t.Errorf("AwardOnTime() players = %v, awards = %v; want only p1 awarded 10", bonus.Players, dkp.awards)
+	}
+	if want := []string{"d2", "d3"}; !slices.Equal(bonus.Late, want) {
+		t.Errorf("Late = %v, want %v", bonus.Late, want)
+	}
+	if _, err := svc.AwardOnTime(ctx, joined, 10); !errors.Is(err, calendar.ErrBonusAwarded) {
+		t.Errorf("second AwardOnTime() error = %v, want ErrBonusAwarded", err)
+	}
+}
+
+func TestService_Compose(t *testing.T) {
+	ctx := context.Background()
+	clk := &clock.Mock{T: start.Add(-24 * time.Hour)}
+	events := eventtest.NewStore()
+	svc := calendar.NewService(events, &mockDKP{}, config.CalendarConfig{}, slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clk)
+
+	if _, err := svc.Schedule(ctx, "Molten Core", "officer", start, calendar.Quotas{Tanks: 2, Size: 1}); !errors.Is(err, calendar.ErrQuotasExceedSize) {
+		t.Fatalf("Schedule() with quotas over the size error = %v, want ErrQuotasExceedSize", err)
+	}
+	signUp := func(r *calendar.Raid, signups ...[3]string) {
+		t.Helper()
+		for _, su := range signups {
+			if _, err := svc.SignUp(ctx, r.ID, su[0], su[1], su[2]); err != nil {
+				t.Fatalf("SignUp(%s) error = %v", su[0], err)
+			}
+		}
+	}
+
+	// Last week d3 was benched.
+	mc, err := svc.Schedule(ctx, "Molten Core", "officer", start, calendar.Quotas{Tanks: 1, Size: 2})
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	signUp(mc, [3]string{"d2", calendar.Accepted, "dps"}, [3]string{"d3", calendar.Accepted, "dps"}, [3]string{"d1", calendar.Accepted, "tank"})
+	if _, err := svc.Compose(ctx, mc.ID); err != nil {
+		t.Fatalf("Compose() error = %v", err)
+	}
+	got, _ := svc.Get(ctx, mc.ID)
+	if !slices.Equal(got.Roster, []string{"d1", "d2"}) || !slices.Equal(got.Standby, []string{"d3"}) {
+		t.Fatalf("roster = %v, standby = %v; want the tank first and d3 benched", got.Roster, got.Standby)
+	}
+
+	// This week d2 has attended, and d4 has no player. Raids scheduled at
+	// the same time would share an ID.
+	clk.T = clk.T.Add(time.Minute)
+	if err := events.Append(ctx, event.Event{
+		AggregateID: "p2",
+		Type:        event.DKPAwarded,
+		Data:        []byte(`{"player_id":"p2","amount":10,"reason":"Molten Core: attendance and 10 boss kills"}`),
+		CreatedAt:   clk.T,
+	}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	bwl, err := svc.Schedule(ctx, "Blackwing Lair", "officer", start.Add(7*24*time.Hour), calendar.Quotas{Healers: 1, DPS: 1, Size: 2})
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	signUp(bwl,
+		[3]string{"d1", calendar.Accepted, "tank"},
+		[3]string{"d4", calendar.Tentative, "dps"},
+		[3]string{"d2", calendar.Accepted, "dps"},
+		[3]string{"d3", calendar.Accepted, "dps"},
+	)
+	c, err := svc.Propose(ctx, bwl.ID)
+	if err != nil {
+		t.Fatalf("Propose() error = %v", err)
+	}
+	ids := func(picks []calendar.Pick) []string {
+		var ids []string
+		for _, p := range picks {
+			ids = append(ids, p.DiscordID)
+		}
+		return ids
+	}
+	if want := []string{"d3", "d2"}; !slices.Equal(ids(c.Roster), want) {
+		t.Errorf("Propose() roster = %v, want %v: the benched member, then the one who attended", ids(c.Roster), want)
+	}
+	if want := []string{"d1", "d4"}; !slices.Equal(ids(c.Bench), want) {
+		t.Errorf("Propose() bench = %v, want %v", ids(c.Bench), want)
+	}
+	if c.Missing != (calendar.Quotas{Healers: 1}) {
+		t.Errorf("Propose() missing = %+v, want a healer", c.Missing)
+	}
+	if got, _ := svc.Get(ctx, bwl.ID); got.Roster != nil {
+		t.Errorf("roster after Propose() = %v, want none recorded", got.Roster)
+	}
+
+	if _, err := svc.Compose(ctx, bwl.ID); err != nil {
+		t.Fatalf("Compose() error = %v", err)
+	}
+	signUp(bwl, [3]string{"d1", calendar.Declined, "tank"})
+	got, _ = svc.Get(ctx, bwl.ID)
+	if !slices.Equal(got.Roster, []string{"d3", "d2"}) || !slices.Equal(got.Standby, []string{"d4"}) {
+		t.Errorf("roster = %v, standby = %v; want d1 off standby after declining", got.Roster, got.Standby)
+	}
+
+	clk.T = start
+	if _, err := svc.Compose(ctx, mc.ID); !errors.Is(err, calendar.ErrRaidStarted) {
+		t.Errorf("Compose() after the start error = %v, want ErrRaidStarted", err)
+	}
+}
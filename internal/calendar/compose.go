@@ -0,0 +1,253 @@
+package calendar
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// attendanceWindow is how far back attendance counts when composing a
+// roster.
+const attendanceWindow = 28 * 24 * time.Hour
+
+// Pick is a member considered for the roster of a raid, with what their
+// place in line depends on.
+type Pick struct {
+	Signup
+	// Benched is how many compositions of earlier raids in a row benched
+	// the member.
+	Benched int
+	// Attendance is how many attendance awards the member's player was
+	// given in the last four weeks.
+	Attendance int
+	// DKP is the player's balance, or 0 for a member without a player.
+	DKP int
+}
+
+// Composition is a roster proposed for a raid from its signups.
+type Composition struct {
+	Raid *Raid
+	// Roster are the members picked, in the order they were picked: those
+	// filling the role quotas first.
+	Roster []Pick
+	// Bench are the members who signed up but were not picked, best
+	// placed first. They stand by to fill in.
+	Bench []Pick
+	// Missing are how many members of each role the roster lacks to meet
+	// the raid's quotas.
+	Missing Quotas
+}
+
+// Propose composes a roster for raidID from the members who accepted or
+// may attend, without recording it. Quotas are filled first, then the
+// remaining places up to the raid's size; a raid without a size or quotas
+// takes everyone.
+//
+// Members are placed in line by, in order: accepted before tentative, more
+// earlier raids benched in a row, more attendance in the last four weeks,
+// more DKP, and signing up earlier. Benching thus rotates instead of
+// falling on the same members every week.
+func (s *Service) Propose(ctx context.Context, raidID string) (*Composition, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Propose",
+		trace.WithAttributes(attribute.String("raid.id", raidID)),
+	)
+	defer span.End()
+
+	r, err := s.Get(ctx, raidID)
+	if err != nil {
+		return nil, err
+	}
+	return s.propose(ctx, r)
+}
+
+// Compose proposes a roster for raidID like Propose and records it: the
+// raid's roster becomes the members picked and its standby list those
+// benched.
+func (s *Service) Compose(ctx context.Context, raidID string) (*Composition, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Compose",
+		trace.WithAttributes(attribute.String("raid.id", raidID)),
+	)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.Get(ctx, raidID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.clock.Now().Before(r.StartsAt) {
+		return nil, ErrRaidStarted
+	}
+	c, err := s.propose(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	d := event.RaidComposedData{Roster: discordIDs(c.Roster), Standby: discordIDs(c.Bench)}
+	data, _ := json.Marshal(d)
+	if err := s.append(ctx, r, event.RaidComposed, data); err != nil {
+		return nil, err
+	}
+	r.Roster, r.Standby = d.Roster, d.Standby
+
+	s.logger.InfoContext(ctx, "raid composed",
+		slog.String("raid_id", r.ID),
+		slog.Int("roster", len(d.Roster)),
+		slog.Int("standby", len(d.Standby)),
+	)
+	return c, nil
+}
+
+func (s *Service) propose(ctx context.Context, r *Raid) (*Composition, error) {
+	benched, err := s.benched(ctx, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	attendance, err := s.attendance(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var line []Pick
+	for _, su := range r.Signups {
+		if su.Status != Accepted && su.Status != Tentative {
+			continue
+		}
+		p := Pick{Signup: su, Benched: benched[su.DiscordID]}
+		if player, err := s.dkp.GetPlayer(ctx, su.DiscordID); err == nil {
+			if player.Archived() {
+				continue
+			}
+			p.Attendance, p.DKP = attendance[player.ID], player.DKP
+		}
+		line = append(line, p)
+	}
+	// Stable, so that signing up earlier breaks ties.
+	slices.SortStableFunc(line, func(a, b Pick) int {
+		return cmp.Or(
+			cmp.Compare(statusRank(a.Status), statusRank(b.Status)),
+			cmp.Compare(b.Benched, a.Benched),
+			cmp.Compare(b.Attendance, a.Attendance),
+			cmp.Compare(b.DKP, a.DKP),
+		)
+	})
+
+	size := r.Quotas.size()
+	if size == 0 {
+		size = len(line)
+	}
+	c := &Composition{Raid: r}
+	picked := make([]bool, len(line))
+	pick := func(role string, n int) int {
+		for k, p := range line {
+			if n == 0 || len(c.Roster) == size {
+				break
+			}
+			if !picked[k] && (role == "" || p.Role == role) {
+				picked[k] = true
+				c.Roster = append(c.Roster, p)
+				n--
+			}
+		}
+		return n
+	}
+	c.Missing.Tanks = pick(dkp.RoleTank, r.Quotas.Tanks)
+	c.Missing.Healers = pick(dkp.RoleHealer, r.Quotas.Healers)
+	c.Missing.DPS = pick(dkp.RoleDPS, r.Quotas.DPS)
+	pick("", size)
+	for k, p := range line {
+		if !picked[k] {
+			c.Bench = append(c.Bench, p)
+		}
+	}
+	return c, nil
+}
+
+// statusRank places accepted members before tentative ones.
+func statusRank(status string) int {
+	if status == Accepted {
+		return 0
+	}
+	return 1
+}
+
+// benched returns, for each member, how many of the latest compositions of
+// raids other than raidID in a row benched them. Compositions that did not
+// consider the member, because they did not sign up, are skipped.
+func (s *Service) benched(ctx context.Context, raidID string) (map[string]int, error) {
+	events, err := s.events.LoadByType(ctx, event.RaidComposed)
+	if err != nil {
+		return nil, fmt.Errorf("loading raid composed events: %w", err)
+	}
+	// Only the latest composition of each raid counts.
+	latest := make(map[string]event.Event)
+	for _, e := range events {
+		if e.AggregateID != raidID {
+			latest[e.AggregateID] = e
+		}
+	}
+	compositions := slices.SortedFunc(maps.Values(latest), func(a, b event.Event) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	benched := make(map[string]int)
+	for _, e := range compositions {
+		var d event.RaidComposedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+		}
+		for _, id := range d.Roster {
+			delete(benched, id)
+		}
+		for _, id := range d.Standby {
+			benched[id]++
+		}
+	}
+	return benched, nil
+}
+
+// attendance returns, for each player, how many DKP awards for attendance
+// they were given in the attendance window. An award counts as attendance
+// if its reason mentions it, as Warcraft Logs imports and on-time bonuses
+// do.
+func (s *Service) attendance(ctx context.Context) (map[string]int, error) {
+	awards, err := s.events.Query(ctx, event.Query{
+		Types: []event.Type{event.DKPAwarded},
+		Since: s.clock.Now().Add(-attendanceWindow),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading DKP awards: %w", err)
+	}
+	attendance := make(map[string]int)
+	for _, e := range awards {
+		var d event.DKPChangeData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+		}
+		if strings.Contains(strings.ToLower(d.Reason), "attendance") {
+			attendance[d.PlayerID]++
+		}
+	}
+	return attendance, nil
+}
+
+// discordIDs returns the Discord IDs of picks.
+func discordIDs(picks []Pick) []string {
+	ids := make([]string, len(picks))
+	for n, p := range picks {
+		ids[n] = p.DiscordID
+	}
+	return ids
+}
@@ -0,0 +1,107 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+)
+
+// remindInterval is how often the Reminder looks for raids to remind of.
+const remindInterval = time.Minute
+
+// Reminder sends direct messages to the members who accepted or may attend
+// a scheduled raid shortly before it starts, telling those benched that they
+// are on standby. Only the leader should run it.
+type Reminder struct {
+	svc     *Service
+	lead    time.Duration
+	session func() *discordgo.Session
+	logger  *slog.Logger
+}
+
+// NewReminder returns a Reminder that reminds members lead before their
+// raid starts. session returns the current Discord session, or nil while
+// none is open, in which case reminders wait for the next check.
+func NewReminder(svc *Service, lead time.Duration, session func() *discordgo.Session, logger *slog.Logger) *Reminder {
+	return &Reminder{svc: svc, lead: lead, session: session, logger: logger}
+}
+
+// Run sends reminders until ctx is done. With no lead time it returns at
+// once.
+func (r *Reminder) Run(ctx context.Context) {
+	if r.lead <= 0 {
+		return
+	}
+	ticker := clock.NewTicker(r.svc.clock, remindInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+		if err := r.Remind(ctx); err != nil {
+			r.logger.ErrorContext(ctx, "sending raid reminders failed", slog.Any("error", err))
+		}
+	}
+}
+
+// Remind reminds the members of each raid now due. A raid is marked as
+// reminded even if some of its members could not be sent a message.
+func (r *Reminder) Remind(ctx context.Context) error {
+	due, err := r.svc.Due(ctx, r.lead)
+	if err != nil {
+		return err
+	}
+	if len(due) == 0 {
+		return nil
+	}
+	s := r.session()
+	if s == nil {
+		return nil
+	}
+	for _, raid := range due {
+		var sent []string
+		for _, id := range raid.Attending() {
+			msg := fmt.Sprintf("Reminder: raid **%s** starts <t:%d:R>.", raid.Name, raid.StartsAt.Unix())
+			if slices.Contains(raid.Standby, id) {
+				msg += " You are on standby: be ready to fill in if someone drops out."
+			}
+			if err := sendDM(ctx, s, id, msg); err != nil {
+				r.logger.WarnContext(ctx, "sending raid reminder failed",
+					slog.String("raid_id", raid.ID),
+					slog.String("discord_id", id),
+					slog.Any("error", err),
+				)
+				continue
+			}
+			sent = append(sent, id)
+		}
+		if err := r.svc.MarkReminded(ctx, raid.ID, sent); err != nil {
+			return err
+		}
+		r.logger.InfoContext(ctx, "raid reminders sent",
+			slog.String("raid_id", raid.ID),
+			slog.Int("recipients", len(sent)),
+		)
+	}
+	return nil
+}
+
+// sendDM sends msg to the Discord user userID.
+func sendDM(ctx context.Context, s *discordgo.Session, userID, msg string) error {
+	ch, err := s.UserChannelCreate(userID, discordgo.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("opening DM channel: %w", err)
+	}
+	if _, err := s.ChannelMessageSend(ch.ID, msg, discordgo.WithContext(ctx)); err != nil {
+		return fmt.Errorf("sending DM: %w", err)
+	}
+	return nil
+}
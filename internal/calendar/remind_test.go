@@ -0,0 +1,52 @@
+package calendar_test
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/calendar"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/discordtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+)
+
+func TestReminder_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clk := clock.NewFakeClock(start.Add(-2 * time.Hour))
+	es := eventtest.NewStore(eventtest.WithClock(clk))
+	svc := calendar.NewService(es, &mockDKP{awards: map[string]int{}}, config.CalendarConfig{},
+		slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clk)
+	r, err := svc.Schedule(ctx, "Molten Core", "officer", start, calendar.Quotas{})
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	if _, err := svc.SignUp(ctx, r.ID, "d1", calendar.Accepted, "tank"); err != nil {
+		t.Fatalf("SignUp() error = %v", err)
+	}
+
+	session, rt := discordtest.NewSession()
+	reminder := calendar.NewReminder(svc, time.Hour, func() *discordgo.Session { return session }, slog.New(slog.DiscardHandler))
+	go reminder.Run(ctx)
+
+	// Reminders go out an hour before the raid, on the first check after.
+	clk.BlockUntil(1)
+	clk.Advance(59 * time.Minute)
+	if reqs := rt.Requests(); len(reqs) != 0 {
+		t.Fatalf("reminded with %+v more than an hour before the raid", reqs)
+	}
+	clk.Advance(time.Minute)
+	es.Await(t, event.RaidReminded)
+	reqs := rt.Requests()
+	if len(reqs) != 2 || !strings.Contains(reqs[1].Body, "Molten Core") {
+		t.Errorf("requests = %+v, want a direct message about Molten Core", reqs)
+	}
+}
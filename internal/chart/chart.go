@@ -0,0 +1,211 @@
+// Package chart renders simple line and bar charts as PNG images, for
+// attaching to Discord messages, whose embeds cannot draw charts.
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"time"
+)
+
+// Size of the rendered images, and the margins around the plot area that
+// hold the axis labels.
+const (
+	Width  = 800
+	Height = 400
+
+	marginLeft   = 80
+	marginRight  = 24
+	marginTop    = 24
+	marginBottom = 40
+)
+
+// Colors of the charts, chosen to read well on Discord's dark theme.
+var (
+	background = color.RGBA{0x2b, 0x2d, 0x31, 0xff}
+	grid       = color.RGBA{0x41, 0x43, 0x4a, 0xff}
+	ink        = color.RGBA{0xdb, 0xde, 0xe1, 0xff}
+	line       = color.RGBA{0x58, 0x65, 0xf2, 0xff}
+	gain       = color.RGBA{0x57, 0xf2, 0x87, 0xff}
+	loss       = color.RGBA{0xed, 0x42, 0x45, 0xff}
+)
+
+// Point is a value at a time.
+type Point struct {
+	At    time.Time
+	Value int
+}
+
+// Line renders points, ordered by time, as a line across the time they
+// span. It needs at least one point.
+func Line(points []Point) ([]byte, error) {
+	if len(points) == 0 {
+		return nil, fmt.Errorf("no points to chart")
+	}
+	c := newCanvas(points, false)
+	first, last := points[0].At, points[len(points)-1].At
+	span := last.Sub(first)
+	x := func(t time.Time) int {
+		if span <= 0 {
+			return c.plot.Min.X + c.plot.Dx()/2
+		}
+		return c.plot.Min.X + int(float64(c.plot.Dx())*float64(t.Sub(first))/float64(span))
+	}
+	prev := image.Pt(x(first), c.y(points[0].Value))
+	c.dot(prev, line)
+	for _, p := range points[1:] {
+		next := image.Pt(x(p.At), c.y(p.Value))
+		c.line(prev, next, line)
+		prev = next
+	}
+	c.xLabels(first, last)
+	return c.encode()
+}
+
+// Bars renders one bar per point, rising from zero for positive values and
+// falling for negative ones. It needs at least one point.
+func Bars(points []Point) ([]byte, error) {
+	if len(points) == 0 {
+		return nil, fmt.Errorf("no points to chart")
+	}
+	c := newCanvas(points, true)
+	slot := c.plot.Dx() / len(points)
+	gap := max(slot/5, 1)
+	zero := c.y(0)
+	for n, p := range points {
+		x0 := c.plot.Min.X + n*slot + gap/2
+		bar := image.Rect(x0, min(zero, c.y(p.Value)), x0+slot-gap, max(zero, c.y(p.Value))+1)
+		col := gain
+		if p.Value < 0 {
+			col = loss
+		}
+		c.fill(bar, col)
+	}
+	c.hline(zero, ink)
+	c.xLabels(points[0].At, points[len(points)-1].At)
+	return c.encode()
+}
+
+// canvas is an image with a plot area mapped to a range of values.
+type canvas struct {
+	img    *image.RGBA
+	plot   image.Rectangle
+	lo, hi int
+}
+
+// newCanvas returns a canvas whose value range covers points, and zero if
+// withZero, with labeled grid lines drawn.
+func newCanvas(points []Point, withZero bool) *canvas {
+	lo, hi := points[0].Value, points[0].Value
+	for _, p := range points {
+		lo, hi = min(lo, p.Value), max(hi, p.Value)
+	}
+	if withZero {
+		lo, hi = min(lo, 0), max(hi, 0)
+	}
+	if lo == hi {
+		lo, hi = lo-1, hi+1
+	}
+
+	c := &canvas{
+		img:  image.NewRGBA(image.Rect(0, 0, Width, Height)),
+		plot: image.Rect(marginLeft, marginTop, Width-marginRight, Height-marginBottom),
+		lo:   lo,
+		hi:   hi,
+	}
+	c.fill(c.img.Bounds(), background)
+	const ticks = 4
+	for k := 0; k <= ticks; k++ {
+		v := lo + (hi-lo)*k/ticks
+		y := c.y(v)
+		c.hline(y, grid)
+		label := strconv.Itoa(v)
+		c.text(c.plot.Min.X-8-textWidth(label), y-glyphHeight*scale/2, label)
+	}
+	return c
+}
+
+// y maps v to its row in the plot area.
+func (c *canvas) y(v int) int {
+	return c.plot.Max.Y - int(float64(c.plot.Dy())*float64(v-c.lo)/float64(c.hi-c.lo))
+}
+
+// xLabels labels the left and right ends of the plot area with the dates
+// of from and to.
+func (c *canvas) xLabels(from, to time.Time) {
+	y := c.plot.Max.Y + 12
+	left := from.UTC().Format("01-02")
+	c.text(c.plot.Min.X, y, left)
+	if right := to.UTC().Format("01-02"); right != left {
+		c.text(c.plot.Max.X-textWidth(right), y, right)
+	}
+}
+
+func (c *canvas) fill(r image.Rectangle, col color.RGBA) {
+	r = r.Intersect(c.img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c.img.SetRGBA(x, y, col)
+		}
+	}
+}
+
+func (c *canvas) hline(y int, col color.RGBA) {
+	c.fill(image.Rect(c.plot.Min.X, y, c.plot.Max.X, y+1), col)
+}
+
+// dot draws a two-pixel square at p, the width of chart lines.
+func (c *canvas) dot(p image.Point, col color.RGBA) {
+	c.fill(image.Rect(p.X, p.Y, p.X+2, p.Y+2), col)
+}
+
+// line draws a straight line from a to b with Bresenham's algorithm.
+func (c *canvas) line(a, b image.Point, col color.RGBA) {
+	dx, dy := abs(b.X-a.X), -abs(b.Y-a.Y)
+	sx, sy := sign(b.X-a.X), sign(b.Y-a.Y)
+	e := dx + dy
+	for {
+		c.dot(a, col)
+		if a == b {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			a.X += sx
+		}
+		if e2 <= dx {
+			e += dx
+			a.Y += sy
+		}
+	}
+}
+
+func (c *canvas) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.img); err != nil {
+		return nil, fmt.Errorf("encoding chart: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package chart_test
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/chart"
+)
+
+var day = time.Date(2025, 6, 16, 0, 0, 0, 0, time.UTC)
+
+// decode decodes a rendered chart and counts its distinct colors.
+func decode(t *testing.T, data []byte) (image.Image, int) {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+	colors := make(map[[4]uint32]bool)
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := img.At(x, y).RGBA()
+			colors[[4]uint32{r, g, bl, a}] = true
+		}
+	}
+	return img, len(colors)
+}
+
+func TestLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []chart.Point
+	}{
+		{name: "one point", points: []chart.Point{{At: day, Value: 10}}},
+		{name: "flat", points: []chart.Point{{At: day, Value: 10}, {At: day.AddDate(0, 0, 7), Value: 10}}},
+		{
+			name: "rising and falling",
+			points: []chart.Point{
+				{At: day, Value: 0},
+				{At: day.AddDate(0, 0, 3), Value: 120},
+				{At: day.AddDate(0, 0, 4), Value: -40},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := chart.Line(tt.points)
+			if err != nil {
+				t.Fatalf("Line() error = %v", err)
+			}
+			img, colors := decode(t, data)
+			if b := img.Bounds(); b.Dx() != chart.Width || b.Dy() != chart.Height {
+				t.Errorf("size = %v, want %dx%d", b.Size(), chart.Width, chart.Height)
+			}
+			// Background, grid, labels, and the line.
+			if colors < 4 {
+				t.Errorf("chart has %d colors, want at least 4", colors)
+			}
+		})
+	}
+	if _, err := chart.Line(nil); err == nil {
+		t.Error("Line(nil) succeeded")
+	}
+}
+
+func TestBars(t *testing.T) {
+	data, err := chart.Bars([]chart.Point{
+		{At: day, Value: 50},
+		{At: day.AddDate(0, 0, 7), Value: -20},
+		{At: day.AddDate(0, 0, 14), Value: 0},
+	})
+	if err != nil {
+		t.Fatalf("Bars() error = %v", err)
+	}
+	// Gains and losses are drawn in colors of their own.
+	if _, colors := decode(t, data); colors < 5 {
+		t.Errorf("chart has %d colors, want at least 5", colors)
+	}
+	if _, err := chart.Bars(nil); err == nil {
+		t.Error("Bars(nil) succeeded")
+	}
+}
@@ -0,0 +1,54 @@
+package chart
+
+import "image"
+
+// Glyphs are drawn from a 3×5 pixel font, scaled up, which covers the
+// characters of numbers and dates.
+const (
+	glyphWidth  = 3
+	glyphHeight = 5
+	scale       = 2
+)
+
+// glyphs holds a bitmap per character, one row per byte with the leftmost
+// pixel in the highest of the three bits.
+var glyphs = map[rune][glyphHeight]byte{
+	'0': {0b111, 0b101, 0b101, 0b101, 0b111},
+	'1': {0b010, 0b110, 0b010, 0b010, 0b111},
+	'2': {0b111, 0b001, 0b111, 0b100, 0b111},
+	'3': {0b111, 0b001, 0b111, 0b001, 0b111},
+	'4': {0b101, 0b101, 0b111, 0b001, 0b001},
+	'5': {0b111, 0b100, 0b111, 0b001, 0b111},
+	'6': {0b111, 0b100, 0b111, 0b101, 0b111},
+	'7': {0b111, 0b001, 0b010, 0b010, 0b010},
+	'8': {0b111, 0b101, 0b111, 0b101, 0b111},
+	'9': {0b111, 0b101, 0b111, 0b001, 0b111},
+	'-': {0b000, 0b000, 0b111, 0b000, 0b000},
+}
+
+// textWidth returns the width in pixels of s as drawn by text.
+func textWidth(s string) int {
+	n := len([]rune(s))
+	if n == 0 {
+		return 0
+	}
+	return (n*(glyphWidth+1) - 1) * scale
+}
+
+// text draws s with its top left corner at x, y. Characters without a
+// glyph are left blank.
+func (c *canvas) text(x, y int, s string) {
+	for _, r := range s {
+		g := glyphs[r]
+		for row, bits := range g {
+			for col := range glyphWidth {
+				if bits&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				px, py := x+col*scale, y+row*scale
+				c.fill(image.Rect(px, py, px+scale, py+scale), ink)
+			}
+		}
+		x += (glyphWidth + 1) * scale
+	}
+}
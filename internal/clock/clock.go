@@ -1,6 +1,9 @@
 package clock
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // Clock abstracts time operations for testability.
 type Clock interface {
@@ -20,3 +23,13 @@ type Mock struct {
 
 // Now returns the fixed time.
 func (m Mock) Now() time.Time { return m.T }
+
+// LoadLocation returns the timezone with the IANA name name, such as
+// "Europe/Copenhagen" or "UTC". Unlike time.LoadLocation, it rejects the
+// empty name and "Local", which depend on the machine the bot runs on.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return time.LoadLocation(name)
+}
@@ -33,3 +33,17 @@ func TestMock_Now(t *testing.T) {
 		t.Errorf("Mock.Now() second call = %v, want %v", got2, fixed)
 	}
 }
+
+func TestLoadLocation(t *testing.T) {
+	for _, name := range []string{"UTC", "Europe/Copenhagen", "America/New_York"} {
+		loc, err := clock.LoadLocation(name)
+		if err != nil || loc.String() != name {
+			t.Errorf("LoadLocation(%q) = %v, %v, want the zone", name, loc, err)
+		}
+	}
+	for _, name := range []string{"", "Local", "Mars/Olympus_Mons", "cet stuff"} {
+		if _, err := clock.LoadLocation(name); err == nil {
+			t.Errorf("LoadLocation(%q) error = nil, want one", name)
+		}
+	}
+}
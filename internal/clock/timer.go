@@ -0,0 +1,209 @@
+package clock
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Timer fires once, as a time.Timer does: C receives the time it fired.
+type Timer interface {
+	C() <-chan time.Time
+	// Stop stops the timer, reporting whether it had yet to fire.
+	Stop() bool
+}
+
+// Ticker fires every period, as a time.Ticker does: C receives the time of
+// each tick, dropping ticks for slow receivers.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Timers is a Clock that also makes timers and tickers, so that code
+// waiting for time to pass can be tested with a FakeClock.
+type Timers interface {
+	Clock
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+var (
+	_ Timers = Real{}
+	_ Timers = (*FakeClock)(nil)
+)
+
+// NewTimer returns a Timer that fires after d on c, if c makes timers, or
+// else on the system clock.
+func NewTimer(c Clock, d time.Duration) Timer {
+	if t, ok := c.(Timers); ok {
+		return t.NewTimer(d)
+	}
+	return Real{}.NewTimer(d)
+}
+
+// NewTicker returns a Ticker that fires every d on c, if c makes tickers,
+// or else on the system clock. d must be positive.
+func NewTicker(c Clock, d time.Duration) Ticker {
+	if t, ok := c.(Timers); ok {
+		return t.NewTicker(d)
+	}
+	return Real{}.NewTicker(d)
+}
+
+// NewTimer returns a time.Timer that fires after d.
+func (Real) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+// NewTicker returns a time.Ticker that fires every d.
+func (Real) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+type realTimer struct{ t *time.Timer }
+
+func (r realTimer) C() <-chan time.Time { return r.t.C }
+func (r realTimer) Stop() bool          { return r.t.Stop() }
+
+type realTicker struct{ t *time.Ticker }
+
+func (r realTicker) C() <-chan time.Time { return r.t.C }
+func (r realTicker) Stop()               { r.t.Stop() }
+
+// FakeClock is a Clock whose time only moves when Advance is called,
+// firing the timers and tickers it made as their times come, so that
+// schedulers can be tested without sleeping. It is safe for concurrent
+// use.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+	// changed is closed and replaced whenever a timer or ticker is added
+	// or removed, to wake BlockUntil.
+	changed chan struct{}
+}
+
+// NewFakeClock returns a FakeClock showing t.
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{now: t, changed: make(chan struct{})}
+}
+
+// Now returns the time of the clock.
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTimer returns a Timer that fires once the clock advanced by d. A
+// timer for no time fires at once.
+func (f *FakeClock) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &fakeWaiter{clock: f, at: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.c <- f.now
+		return w
+	}
+	f.add(w)
+	return w
+}
+
+// NewTicker returns a Ticker that fires each time the clock advanced by
+// d. d must be positive, as for time.NewTicker.
+func (f *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &fakeWaiter{clock: f, at: f.now.Add(d), period: d, c: make(chan time.Time, 1)}
+	f.add(w)
+	return fakeTicker{w}
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers due
+// in order. While one fires, Now returns the time it was due.
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	end := f.now.Add(d)
+	for {
+		i := -1
+		for j, w := range f.waiters {
+			if !w.at.After(end) && (i < 0 || w.at.Before(f.waiters[i].at)) {
+				i = j
+			}
+		}
+		if i < 0 {
+			break
+		}
+		w := f.waiters[i]
+		f.now = w.at
+		select {
+		case w.c <- w.at:
+		default:
+		}
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			f.remove(w)
+		}
+	}
+	f.now = end
+}
+
+// BlockUntil waits until n timers and tickers wait for the clock, such as
+// once the goroutine under test set its next timer, so that Advance
+// fires it.
+func (f *FakeClock) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		waiting, changed := len(f.waiters), f.changed
+		f.mu.Unlock()
+		if waiting >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// add adds w to the waiters. f.mu must be held.
+func (f *FakeClock) add(w *fakeWaiter) {
+	f.waiters = append(f.waiters, w)
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// remove removes w from the waiters, reporting whether it was one. f.mu
+// must be held.
+func (f *FakeClock) remove(w *fakeWaiter) bool {
+	i := slices.Index(f.waiters, w)
+	if i < 0 {
+		return false
+	}
+	f.waiters = slices.Delete(f.waiters, i, i+1)
+	close(f.changed)
+	f.changed = make(chan struct{})
+	return true
+}
+
+// fakeWaiter is a timer or, with a period, a ticker of a FakeClock.
+type fakeWaiter struct {
+	clock  *FakeClock
+	at     time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+func (w *fakeWaiter) C() <-chan time.Time { return w.c }
+
+// Stop stops w, reporting whether it was waiting to fire.
+func (w *fakeWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	return w.clock.remove(w)
+}
+
+// fakeTicker is the Ticker of a fakeWaiter with a period.
+type fakeTicker struct{ w *fakeWaiter }
+
+func (t fakeTicker) C() <-chan time.Time { return t.w.c }
+func (t fakeTicker) Stop()               { t.w.Stop() }
@@ -0,0 +1,120 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+)
+
+func TestFakeClock_Timer(t *testing.T) {
+	start := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFakeClock(start)
+	timer := clock.NewTimer(clk, time.Minute)
+
+	clk.Advance(59 * time.Second)
+	select {
+	case got := <-timer.C():
+		t.Fatalf("timer fired at %v, before its minute", got)
+	default:
+	}
+
+	clk.Advance(time.Hour)
+	select {
+	case got := <-timer.C():
+		if want := start.Add(time.Minute); !got.Equal(want) {
+			t.Errorf("timer fired at %v, want %v", got, want)
+		}
+	default:
+		t.Fatal("timer did not fire after its minute")
+	}
+	if want := start.Add(time.Hour + 59*time.Second); !clk.Now().Equal(want) {
+		t.Errorf("Now() = %v, want %v", clk.Now(), want)
+	}
+	if timer.Stop() {
+		t.Error("Stop() of a fired timer = true, want false")
+	}
+
+	stopped := clk.NewTimer(time.Minute)
+	if !stopped.Stop() {
+		t.Error("Stop() of a waiting timer = false, want true")
+	}
+	clk.Advance(time.Hour)
+	select {
+	case <-stopped.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+
+	select {
+	case <-clk.NewTimer(0).C():
+	default:
+		t.Fatal("timer for no time did not fire at once")
+	}
+}
+
+func TestFakeClock_Ticker(t *testing.T) {
+	start := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFakeClock(start)
+	ticker := clock.NewTicker(clk, time.Minute)
+	defer ticker.Stop()
+
+	for i := 1; i <= 3; i++ {
+		clk.Advance(time.Minute)
+		select {
+		case got := <-ticker.C():
+			if want := start.Add(time.Duration(i) * time.Minute); !got.Equal(want) {
+				t.Errorf("tick %d at %v, want %v", i, got, want)
+			}
+		default:
+			t.Fatalf("no tick %d", i)
+		}
+	}
+
+	// Like a time.Ticker, ticks the receiver is not ready for are dropped.
+	clk.Advance(10 * time.Minute)
+	if got, want := <-ticker.C(), start.Add(4*time.Minute); !got.Equal(want) {
+		t.Errorf("tick after missed ticks at %v, want %v", got, want)
+	}
+	select {
+	case got := <-ticker.C():
+		t.Fatalf("dropped tick at %v was kept", got)
+	default:
+	}
+}
+
+func TestFakeClock_AdvanceOrder(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC))
+	late := clk.NewTimer(2 * time.Minute)
+	early := clk.NewTimer(time.Minute)
+
+	clk.Advance(3 * time.Minute)
+	if got, want := (<-late.C()).Sub(<-early.C()), time.Minute; got != want {
+		t.Errorf("timers fired %v apart, want %v", got, want)
+	}
+}
+
+func TestFakeClock_BlockUntil(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC))
+	fired := make(chan time.Time)
+	go func() {
+		timer := clk.NewTimer(time.Hour)
+		fired <- <-timer.C()
+	}()
+
+	clk.BlockUntil(1)
+	clk.Advance(time.Hour)
+	if got := <-fired; !got.Equal(clk.Now()) {
+		t.Errorf("timer fired at %v, want %v", got, clk.Now())
+	}
+}
+
+func TestNewTimer_Real(t *testing.T) {
+	// Clocks that make no timers, such as Mock, get real ones.
+	timer := clock.NewTimer(clock.Mock{}, time.Millisecond)
+	select {
+	case <-timer.C():
+	case <-time.After(5 * time.Second):
+		t.Fatal("real timer did not fire")
+	}
+}
@@ -1,12 +1,23 @@
 package config
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/announce"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
 )
 
 // Config represents the application configuration.
@@ -16,12 +27,108 @@ type Config struct {
 	Server         ServerConfig         `yaml:"server"`
 	Telemetry      TelemetryConfig      `yaml:"telemetry"`
 	LeaderElection LeaderElectionConfig `yaml:"leader_election"`
+	Retention      RetentionConfig      `yaml:"retention"`
+	API            APIConfig            `yaml:"api"`
+	WarcraftLogs   WarcraftLogsConfig   `yaml:"warcraft_logs"`
+	DeadLetter     DeadLetterConfig     `yaml:"dead_letter"`
+	Idempotency    IdempotencyConfig    `yaml:"idempotency"`
+	EventBus       EventBusConfig       `yaml:"event_bus"`
+	GuildDefaults  GuildDefaultsConfig  `yaml:"guild_defaults"`
+	Items          ItemsConfig          `yaml:"items"`
+	GDKP           GDKPConfig           `yaml:"gdkp"`
+	Tax            TaxConfig            `yaml:"tax"`
+	Leaderboard    LeaderboardConfig    `yaml:"leaderboard"`
+	Calendar       CalendarConfig       `yaml:"calendar"`
+	Roster         RosterConfig         `yaml:"roster"`
+	Reconcile      ReconcileConfig      `yaml:"reconcile"`
+	RoleSync       RoleSyncConfig       `yaml:"role_sync"`
+	TextFilter     TextFilterConfig     `yaml:"text_filter"`
+	Secrets        SecretsConfig        `yaml:"secrets"`
+	// Currencies names the currencies players hold besides DKP, such as
+	// "EP", "GP", or "Raid tokens".
+	Currencies []string `yaml:"currencies"`
 }
 
 // DiscordConfig holds Discord bot settings.
 type DiscordConfig struct {
-	Token   string `yaml:"token"`
-	GuildID string `yaml:"guild_id"`
+	Token   string        `yaml:"token" secret:"true"`
+	GuildID string        `yaml:"guild_id"`
+	Gateway GatewayConfig `yaml:"gateway"`
+	// CommandPrefix, if set, enables prefix commands such as "!dkp" for
+	// servers that restrict slash commands. The bot then needs the
+	// privileged message content intent.
+	CommandPrefix string `yaml:"command_prefix"`
+	// Timeouts bounds how long commands may run.
+	Timeouts TimeoutConfig `yaml:"timeouts"`
+	// Jobs runs long commands in the background.
+	Jobs JobsConfig `yaml:"jobs"`
+	// TokenFunc, if set, returns the current token when the gateway
+	// connects, for tokens rotated by a secrets provider.
+	TokenFunc func() string `yaml:"-"`
+}
+
+// TimeoutConfig holds the deadlines of commands. A command still running
+// at its deadline has its database and Discord calls canceled, and the
+// member is told it timed out. Discord gives up on an interaction after 15
+// minutes, which no deadline may exceed.
+type TimeoutConfig struct {
+	// Default is the deadline of commands not named in Commands.
+	Default time.Duration `yaml:"default"`
+	// Commands overrides Default for the named commands, for example to
+	// give imports longer.
+	Commands map[string]time.Duration `yaml:"commands"`
+}
+
+// Where background jobs report.
+const (
+	// JobsReportChannel reports in the channel the command was used in.
+	JobsReportChannel = "channel"
+	// JobsReportDM reports in a direct message to the member who used it.
+	JobsReportDM = "dm"
+)
+
+// JobsConfig holds the settings of background jobs. Imports, exports, and
+// other commands that can outlast an interaction answer at once and run as
+// jobs, which report their progress and outcome in a message of their own.
+type JobsConfig struct {
+	// Timeout is the longest a job may run. Unlike command timeouts it
+	// may exceed 15 minutes.
+	Timeout time.Duration `yaml:"timeout"`
+	// ProgressInterval is the least time between two updates of a job's
+	// message, which keeps long jobs within Discord's rate limits.
+	ProgressInterval time.Duration `yaml:"progress_interval"`
+	// ReportTo is JobsReportChannel or JobsReportDM.
+	ReportTo string `yaml:"report_to"`
+}
+
+func (j JobsConfig) validate(p *problems) {
+	if j.Timeout <= 0 {
+		p.add("discord.jobs.timeout", "must be positive, got %s", j.Timeout)
+	}
+	if j.ProgressInterval < time.Second {
+		p.add("discord.jobs.progress_interval", "must be at least 1s, got %s", j.ProgressInterval)
+	}
+	if j.ReportTo != JobsReportChannel && j.ReportTo != JobsReportDM {
+		p.add("discord.jobs.report_to", "must be %q or %q, got %q", JobsReportChannel, JobsReportDM, j.ReportTo)
+	}
+}
+
+// maxCommandTimeout is how long Discord accepts responses to an
+// interaction.
+const maxCommandTimeout = 15 * time.Minute
+
+// GatewayConfig holds settings for supervising the Discord gateway
+// connection.
+type GatewayConfig struct {
+	// CheckInterval is how often heartbeat latency is sampled.
+	CheckInterval time.Duration `yaml:"check_interval"`
+	// MaxLatency is the heartbeat latency above which the gateway is
+	// reported unhealthy.
+	MaxLatency time.Duration `yaml:"max_latency"`
+	// ReconnectMin and ReconnectMax bound the exponential backoff between
+	// reconnection attempts.
+	ReconnectMin time.Duration `yaml:"reconnect_min"`
+	ReconnectMax time.Duration `yaml:"reconnect_max"`
 }
 
 // DatabaseConfig holds database connection settings.
@@ -29,10 +136,33 @@ type DatabaseConfig struct {
 	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
 	User     string `yaml:"user"`
-	Password string `yaml:"password"`
+	Password string `yaml:"password" secret:"true"`
 	DBName   string `yaml:"dbname"`
 	SSLMode  string `yaml:"sslmode"`
 	Driver   string `yaml:"driver"` // "sqlx" or "ent"
+	// AppendBatchSize is the number of events inserted per statement when
+	// appending to the event log, as during imports and batch awards.
+	AppendBatchSize int `yaml:"append_batch_size"`
+	// TraceQueries records on the database spans the statements, with
+	// their literals removed, the rows they affected or returned, and the
+	// repository method that ran them. Arguments are never recorded.
+	TraceQueries bool `yaml:"trace_queries"`
+	// EncryptionKey, a base64-encoded 32-byte key, enables encryption of
+	// event payloads and snapshots, event actors, and the Discord IDs of
+	// players, note authors, and command users at rest, for databases on
+	// shared providers. Empty stores
+	// them in the clear.
+	EncryptionKey string `yaml:"encryption_key" secret:"true"`
+	// ChainKey, a base64-encoded 32-byte key, keys the hash chain over the
+	// event log, so that someone who can write to the database but does
+	// not hold the key cannot rechain edited events. Empty chains them
+	// with a plain SHA-256.
+	ChainKey string `yaml:"chain_key" secret:"true"`
+	// IDs selects how the IDs of new auctions are generated.
+	IDs IDsConfig `yaml:"ids"`
+	// PasswordFunc, if set, returns the current password for each new
+	// connection, for passwords rotated by a secrets provider.
+	PasswordFunc func() string `yaml:"-"`
 }
 
 // DSN returns the Postgres connection string.
@@ -43,28 +173,887 @@ func (d DatabaseConfig) DSN() string {
 	)
 }
 
+// ID strategies.
+const (
+	IDStrategyULID   = "ulid"
+	IDStrategyUUIDv7 = "uuidv7"
+)
+
+// IDsConfig selects how the IDs of new records are generated.
+type IDsConfig struct {
+	// Strategy is "ulid", for IDs that sort by creation time, or "uuidv7",
+	// for version 7 UUIDs.
+	Strategy string `yaml:"strategy"`
+}
+
+func (i IDsConfig) validate(p *problems) {
+	switch i.Strategy {
+	case IDStrategyULID, IDStrategyUUIDv7:
+	default:
+		p.add("database.ids.strategy", "unsupported strategy %q: must be \"ulid\" or \"uuidv7\"", i.Strategy)
+	}
+}
+
 // ServerConfig holds HTTP server settings.
 type ServerConfig struct {
 	Port            int           `yaml:"port"`
 	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
 }
 
+// Telemetry exporters.
+const (
+	// TelemetryExporterOTLP sends traces, metrics, and logs to an OTLP
+	// collector.
+	TelemetryExporterOTLP = "otlp"
+	// TelemetryExporterStderr writes logs to stderr and exports no traces,
+	// and metrics only through Prometheus, for deployments without a
+	// collector.
+	TelemetryExporterStderr = "stderr"
+)
+
 // TelemetryConfig holds OpenTelemetry settings.
 type TelemetryConfig struct {
 	ServiceName    string `yaml:"service_name"`
 	ServiceVersion string `yaml:"service_version"`
-	OTLPEndpoint   string `yaml:"otlp_endpoint"`
-	Insecure       bool   `yaml:"insecure"`
+	// Exporter is TelemetryExporterOTLP or TelemetryExporterStderr.
+	Exporter     string `yaml:"exporter"`
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+	Insecure     bool   `yaml:"insecure"`
+	// Prometheus additionally serves metrics for scraping at /metrics on
+	// the server port, for deployments without an OTLP collector.
+	Prometheus PrometheusConfig `yaml:"prometheus"`
+	// Sampling controls which traces are recorded.
+	Sampling SamplingConfig `yaml:"sampling"`
+	// MetricInterval is how often metrics are pushed over OTLP.
+	MetricInterval time.Duration `yaml:"metric_interval"`
+	// LogLevel is the minimum level logged: debug, info, warn, or error.
+	LogLevel string `yaml:"log_level"`
+	// Debug serves runtime profiling and live span endpoints on the server
+	// port.
+	Debug DebugConfig `yaml:"debug"`
+}
+
+// DebugConfig holds settings for the /debug/pprof and /debug/tracez
+// endpoints. They expose internals, so they require basic auth.
+type DebugConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password" secret:"true"`
 }
 
-// LeaderElectionConfig holds Kubernetes leader election settings.
+// SamplingConfig holds trace sampling settings. Sampling is parent-based:
+// spans whose parent was sampled are always recorded, so the ratios only
+// decide for new traces.
+type SamplingConfig struct {
+	// Ratio is the fraction of new traces sampled, from 0 to 1.
+	Ratio float64 `yaml:"ratio"`
+	// Commands overrides Ratio for traces of the named slash commands, for
+	// example to sample only a few of the frequent /dkp checks.
+	Commands map[string]float64 `yaml:"commands"`
+}
+
+// SlogLevel returns LogLevel as a slog.Level. It is only valid after the
+// config has been validated.
+func (c TelemetryConfig) SlogLevel() slog.Level {
+	var l slog.Level
+	_ = l.UnmarshalText([]byte(c.LogLevel))
+	return l
+}
+
+// PrometheusConfig holds Prometheus exposition settings.
+type PrometheusConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// Leader election backends.
+const (
+	LeaderBackendKubernetes = "kubernetes"
+	LeaderBackendRedis      = "redis"
+)
+
+// LeaderElectionConfig holds leader election settings. The timings apply to
+// every backend.
 type LeaderElectionConfig struct {
-	Enabled        bool          `yaml:"enabled"`
-	LeaseName      string        `yaml:"lease_name"`
-	LeaseNamespace string        `yaml:"lease_namespace"`
-	LeaseDuration  time.Duration `yaml:"lease_duration"`
-	RenewDeadline  time.Duration `yaml:"renew_deadline"`
-	RetryPeriod    time.Duration `yaml:"retry_period"`
+	Enabled bool `yaml:"enabled"`
+	// Backend is where the lock lives: "kubernetes" (a Lease) or "redis".
+	Backend string `yaml:"backend"`
+	// Standby keeps a Discord session open on non-leaders, serving
+	// read-only commands, so that failover only has to promote it.
+	Standby        bool              `yaml:"standby"`
+	LeaseName      string            `yaml:"lease_name"`
+	LeaseNamespace string            `yaml:"lease_namespace"`
+	LeaseDuration  time.Duration     `yaml:"lease_duration"`
+	RenewDeadline  time.Duration     `yaml:"renew_deadline"`
+	RetryPeriod    time.Duration     `yaml:"retry_period"`
+	Redis          RedisLeaderConfig `yaml:"redis"`
+}
+
+// RedisLeaderConfig holds settings for the Redis leader election backend.
+// With several independent Redis servers the lock is held on a majority of
+// them, Redlock-style, so that losing one server does not lose the lock.
+type RedisLeaderConfig struct {
+	Addresses []string `yaml:"addresses"`
+	Password  string   `yaml:"password" secret:"true"`
+	DB        int      `yaml:"db"`
+	// Key is the Redis key of the lock. It defaults to the lease name.
+	Key string `yaml:"key"`
+}
+
+func (l LeaderElectionConfig) validate(p *problems) {
+	if !l.Enabled {
+		if l.Standby {
+			p.add("leader_election.standby", "requires leader_election.enabled")
+		}
+		return
+	}
+	switch l.Backend {
+	case LeaderBackendKubernetes:
+		if l.LeaseNamespace == "" {
+			p.add("leader_election.lease_namespace", "must not be empty for the kubernetes backend")
+		}
+	case LeaderBackendRedis:
+		if len(l.Redis.Addresses) == 0 {
+			p.add("leader_election.redis.addresses", "must not be empty for the redis backend")
+		}
+	default:
+		p.add("leader_election.backend", "unsupported backend %q: must be %q or %q", l.Backend, LeaderBackendKubernetes, LeaderBackendRedis)
+	}
+	if l.LeaseName == "" {
+		p.add("leader_election.lease_name", "must not be empty")
+	}
+	if l.RetryPeriod <= 0 || l.RenewDeadline <= l.RetryPeriod || l.LeaseDuration <= l.RenewDeadline {
+		p.add("leader_election", "timings must satisfy 0 < retry_period < renew_deadline < lease_duration, got %s, %s, and %s", l.RetryPeriod, l.RenewDeadline, l.LeaseDuration)
+	}
+}
+
+// RetentionConfig holds event archival settings.
+type RetentionConfig struct {
+	// MaxAge is how long events of finished aggregates stay in the hot
+	// events table before `dkpbot archive run` moves them to the archive.
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// DeadLetterConfig holds settings for the queue of events that could not be
+// persisted.
+type DeadLetterConfig struct {
+	// Path is the file queued events are kept in. It should be on a volume
+	// that survives restarts of the bot.
+	Path string `yaml:"path"`
+	// RetryInterval is how often queued events are retried.
+	RetryInterval time.Duration `yaml:"retry_interval"`
+	// MaxAttempts is how many times queued events are retried before they
+	// are given up on and left for an operator to discard.
+	MaxAttempts int `yaml:"max_attempts"`
+}
+
+// IdempotencyConfig holds settings for the keys that deduplicate redelivered
+// interactions.
+type IdempotencyConfig struct {
+	// TTL is how long keys are kept. It should cover the longest period
+	// scheduled posts are claimed for, a week.
+	TTL time.Duration `yaml:"ttl"`
+	// StaleAfter is how long an operation may hold its key without
+	// recording a result before a later delivery may take the key over, as
+	// after a crash halfway through. It must exceed every command timeout.
+	StaleAfter time.Duration `yaml:"stale_after"`
+	// PruneInterval is how often keys older than TTL are deleted.
+	PruneInterval time.Duration `yaml:"prune_interval"`
+}
+
+// What a subscriber's queue does with an event published while it is full.
+const (
+	// OverflowDropOldest drops the oldest queued event to make room.
+	OverflowDropOldest = "drop_oldest"
+	// OverflowBlock makes the publisher wait for room, for at most the
+	// queue's block timeout, after which the event is dropped.
+	OverflowBlock = "block"
+	// OverflowSpill writes the event to a file, from which it is delivered
+	// once the subscriber has caught up.
+	OverflowSpill = "spill"
+)
+
+// EventBusConfig holds the queues of the subscribers of the in-process
+// event bus that handle events in the background, such as notifications.
+// Each has a queue of its own, so that a slow subscriber does not hold up
+// the commands appending events or the other subscribers.
+type EventBusConfig struct {
+	// Default is the queue of subscribers not named in Subscribers.
+	Default QueueConfig `yaml:"default"`
+	// Subscribers overrides Default for the named subscribers. Fields left
+	// zero are taken from Default.
+	Subscribers map[string]QueueConfig `yaml:"subscribers"`
+	// SpillDir is the directory spilled events are kept in, one file per
+	// subscriber. It should be on a volume that survives restarts of the
+	// bot.
+	SpillDir string `yaml:"spill_dir"`
+}
+
+// QueueConfig holds the queue of an event bus subscriber.
+type QueueConfig struct {
+	// Size is how many events may wait in memory.
+	Size int `yaml:"size"`
+	// Overflow is OverflowDropOldest, OverflowBlock, or OverflowSpill.
+	Overflow string `yaml:"overflow"`
+	// BlockTimeout is how long a publisher waits for room with
+	// OverflowBlock.
+	BlockTimeout time.Duration `yaml:"block_timeout"`
+}
+
+// Queue returns the queue of the subscriber name.
+func (c EventBusConfig) Queue(name string) QueueConfig {
+	q, ok := c.Subscribers[name]
+	if !ok {
+		return c.Default
+	}
+	if q.Size == 0 {
+		q.Size = c.Default.Size
+	}
+	if q.Overflow == "" {
+		q.Overflow = c.Default.Overflow
+	}
+	if q.BlockTimeout == 0 {
+		q.BlockTimeout = c.Default.BlockTimeout
+	}
+	return q
+}
+
+func (c EventBusConfig) validate(p *problems) {
+	c.Default.validate(p, "event_bus.default")
+	for name := range c.Subscribers {
+		c.Queue(name).validate(p, "event_bus.subscribers."+name)
+	}
+	if c.SpillDir == "" {
+		p.add("event_bus.spill_dir", "must not be empty")
+	}
+}
+
+func (q QueueConfig) validate(p *problems, path string) {
+	if q.Size <= 0 {
+		p.add(path+".size", "must be positive, got %d", q.Size)
+	}
+	switch q.Overflow {
+	case OverflowDropOldest, OverflowBlock, OverflowSpill:
+	default:
+		p.add(path+".overflow", "must be %q, %q, or %q, got %q", OverflowDropOldest, OverflowBlock, OverflowSpill, q.Overflow)
+	}
+	if q.BlockTimeout <= 0 {
+		p.add(path+".block_timeout", "must be positive, got %s", q.BlockTimeout)
+	}
+}
+
+// Ways of telling players they were outbid.
+const (
+	OutbidDM      = "dm"
+	OutbidChannel = "channel"
+	OutbidOff     = "off"
+)
+
+// Ways of handing out loot: by auction, at the fixed prices of the item
+// cost table, or both.
+const (
+	LootModeAuction    = "auction"
+	LootModeFixedPrice = "fixed_price"
+	LootModeBoth       = "both"
+)
+
+// GuildDefaultsConfig holds the initial values of the settings officers
+// can change per guild with /settings. Changed settings are stored in the
+// database and take precedence over these.
+type GuildDefaultsConfig struct {
+	// AuctionDuration is used when /auction-start is given no duration.
+	AuctionDuration time.Duration `yaml:"auction_duration"`
+	// MinIncrement is how much a bid must exceed the highest bid by.
+	MinIncrement int `yaml:"min_increment"`
+	// DecayRate is the percentage of their balance players lose per decay
+	// period.
+	DecayRate float64 `yaml:"decay_rate"`
+	// UndoWindow is how long after a DKP change /dkp-undo may reverse it.
+	UndoWindow time.Duration `yaml:"undo_window"`
+	// RollWindow is how long players may roll for the item of an auction
+	// that closes without bids. Zero closes such auctions without a winner.
+	RollWindow time.Duration `yaml:"roll_window"`
+	// MaxOpenAuctions is how many auctions may be open at once. Auctions
+	// started beyond it are queued until one ends. Zero means no limit.
+	MaxOpenAuctions int `yaml:"max_open_auctions"`
+	// OutbidNotifications is how players are told they were outbid: by
+	// direct message (OutbidDM), by a mention in the auction's channel
+	// (OutbidChannel), or not at all (OutbidOff).
+	OutbidNotifications string `yaml:"outbid_notifications"`
+	// ConfirmBidPercent is the percentage of their balance above which
+	// players are asked to confirm a bid they typed before it is placed.
+	// Zero places bids at once.
+	ConfirmBidPercent int `yaml:"confirm_bid_percent"`
+	// BidCooldown is how long players wait between their bids on an
+	// auction. Zero lets them bid at once.
+	BidCooldown time.Duration `yaml:"bid_cooldown"`
+	// BidWarCooldown is how long players wait between their bids when the
+	// last bids on an auction alternate between them and one other player
+	// within a minute. Zero waits only BidCooldown.
+	BidWarCooldown time.Duration `yaml:"bid_war_cooldown"`
+	// LootMode is how loot is handed out: by /auction-start
+	// (LootModeAuction), by /loot-award at the prices of the item cost
+	// table (LootModeFixedPrice), or by either (LootModeBoth).
+	LootMode string `yaml:"loot_mode"`
+	// AdminRoles lists the IDs of roles whose members may use officer
+	// commands, in addition to members with the Administrator permission.
+	AdminRoles []string `yaml:"admin_roles"`
+	// LootChannel is the ID of the channel auctions are announced in. If
+	// empty, auctions are announced only where they were started.
+	LootChannel string `yaml:"loot_channel"`
+	// LeaderboardChannel is the ID of the channel the weekly leaderboard is
+	// posted in. If empty, it is not posted.
+	LeaderboardChannel string `yaml:"leaderboard_channel"`
+	// OfficerChannel is the ID of the channel proposals for officers, such
+	// as archiving inactive players, are posted in. If empty, they are not
+	// posted.
+	OfficerChannel string `yaml:"officer_channel"`
+	// Timezone is the IANA name of the timezone, such as
+	// "Europe/Copenhagen", the weekly schedules run in and dates and times
+	// given to commands are read in, unless players set their own with
+	// /profile.
+	Timezone string `yaml:"timezone"`
+	// StartedMessage, OutbidMessage, WinnerMessage, and NoBidsMessage word
+	// the announcements of auctions starting, players being outbid, the
+	// winner, and auctions closing without bids, with the placeholders of
+	// the announce package, such as {item}.
+	StartedMessage string `yaml:"started_message"`
+	OutbidMessage  string `yaml:"outbid_message"`
+	WinnerMessage  string `yaml:"winner_message"`
+	NoBidsMessage  string `yaml:"no_bids_message"`
+}
+
+func (g GuildDefaultsConfig) validate(p *problems) {
+	if g.AuctionDuration <= 0 {
+		p.add("guild_defaults.auction_duration", "must be positive, got %s", g.AuctionDuration)
+	}
+	if g.MinIncrement < 1 {
+		p.add("guild_defaults.min_increment", "must be at least 1, got %d", g.MinIncrement)
+	}
+	if g.DecayRate < 0 || g.DecayRate > 100 {
+		p.add("guild_defaults.decay_rate", "must be a percentage between 0 and 100, got %g", g.DecayRate)
+	}
+	if g.UndoWindow <= 0 {
+		p.add("guild_defaults.undo_window", "must be positive, got %s", g.UndoWindow)
+	}
+	if g.RollWindow < 0 {
+		p.add("guild_defaults.roll_window", "must not be negative, got %s", g.RollWindow)
+	}
+	if g.MaxOpenAuctions < 0 {
+		p.add("guild_defaults.max_open_auctions", "must not be negative, got %d", g.MaxOpenAuctions)
+	}
+	switch g.OutbidNotifications {
+	case OutbidDM, OutbidChannel, OutbidOff:
+	default:
+		p.add("guild_defaults.outbid_notifications", "must be %q, %q, or %q, got %q", OutbidDM, OutbidChannel, OutbidOff, g.OutbidNotifications)
+	}
+	if g.ConfirmBidPercent < 0 || g.ConfirmBidPercent > 100 {
+		p.add("guild_defaults.confirm_bid_percent", "must be a percentage between 0 and 100, got %d", g.ConfirmBidPercent)
+	}
+	if g.BidCooldown < 0 {
+		p.add("guild_defaults.bid_cooldown", "must not be negative, got %s", g.BidCooldown)
+	}
+	if g.BidWarCooldown < 0 {
+		p.add("guild_defaults.bid_war_cooldown", "must not be negative, got %s", g.BidWarCooldown)
+	}
+	switch g.LootMode {
+	case LootModeAuction, LootModeFixedPrice, LootModeBoth:
+	default:
+		p.add("guild_defaults.loot_mode", "must be %q, %q, or %q, got %q", LootModeAuction, LootModeFixedPrice, LootModeBoth, g.LootMode)
+	}
+	for i, role := range g.AdminRoles {
+		if !isSnowflake(role) {
+			p.add(fmt.Sprintf("guild_defaults.admin_roles[%d]", i), "must be a Discord role ID, got %q", role)
+		}
+	}
+	if g.LootChannel != "" && !isSnowflake(g.LootChannel) {
+		p.add("guild_defaults.loot_channel", "must be a Discord channel ID, got %q", g.LootChannel)
+	}
+	if g.LeaderboardChannel != "" && !isSnowflake(g.LeaderboardChannel) {
+		p.add("guild_defaults.leaderboard_channel", "must be a Discord channel ID, got %q", g.LeaderboardChannel)
+	}
+	if g.OfficerChannel != "" && !isSnowflake(g.OfficerChannel) {
+		p.add("guild_defaults.officer_channel", "must be a Discord channel ID, got %q", g.OfficerChannel)
+	}
+	if _, err := clock.LoadLocation(g.Timezone); err != nil {
+		p.add("guild_defaults.timezone", "must be an IANA timezone such as Europe/Copenhagen, got %q", g.Timezone)
+	}
+	for _, m := range []struct {
+		key, text string
+		tmpl      announce.Template
+	}{
+		{"started_message", g.StartedMessage, announce.Started},
+		{"outbid_message", g.OutbidMessage, announce.Outbid},
+		{"winner_message", g.WinnerMessage, announce.Winner},
+		{"no_bids_message", g.NoBidsMessage, announce.NoBids},
+	} {
+		if err := m.tmpl.Check(m.text); err != nil {
+			p.add("guild_defaults."+m.key, "%s", err)
+		}
+	}
+}
+
+// ItemsConfig holds settings for the item catalog.
+type ItemsConfig struct {
+	// IconURL turns the icon names of imported item dumps into URLs: its
+	// "{icon}" is replaced by the lowercased icon name. Icons given as URLs
+	// are used as is.
+	IconURL string `yaml:"icon_url"`
+	// LinkURL links auction items to their page in a game database, such
+	// as Wowhead: its "{id}" is replaced by the item's ID and "{name}" by
+	// its name. Empty leaves items unlinked.
+	LinkURL string `yaml:"link_url"`
+}
+
+func (i ItemsConfig) validate(p *problems) {
+	if i.IconURL != "" {
+		if !strings.Contains(i.IconURL, "{icon}") {
+			p.add("items.icon_url", "must contain {icon}, got %q", i.IconURL)
+		}
+		if u, err := url.Parse(i.IconURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			p.add("items.icon_url", "must be an http or https URL, got %q", i.IconURL)
+		}
+	}
+	if i.LinkURL != "" {
+		if !strings.Contains(i.LinkURL, "{id}") && !strings.Contains(i.LinkURL, "{name}") {
+			p.add("items.link_url", "must contain {id} or {name}, got %q", i.LinkURL)
+		}
+		if u, err := url.Parse(i.LinkURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			p.add("items.link_url", "must be an http or https URL, got %q", i.LinkURL)
+		}
+	}
+}
+
+// GDKPConfig holds settings for GDKP raids, whose items are auctioned for
+// gold.
+type GDKPConfig struct {
+	// OrganizerCut is the percentage of the pot paid to the organizer
+	// before it is split among the participants, unless /raid-start is
+	// given another.
+	OrganizerCut float64 `yaml:"organizer_cut"`
+}
+
+func (g GDKPConfig) validate(p *problems) {
+	if g.OrganizerCut < 0 || g.OrganizerCut > 100 {
+		p.add("gdkp.organizer_cut", "must be a percentage between 0 and 100, got %g", g.OrganizerCut)
+	}
+}
+
+// TaxConfig sets the tax charged on the winning bids of DKP auctions to
+// curb DKP inflation.
+type TaxConfig struct {
+	// Rate is the percentage of a winning bid charged to the winner on top
+	// of it, rounded down. Zero disables the tax.
+	Rate float64 `yaml:"rate"`
+	// Redistribute shares the tax evenly among the other participants of
+	// the raid the auction was held in. Otherwise, or if the auction was
+	// held outside a raid, the tax is burned.
+	Redistribute bool `yaml:"redistribute"`
+}
+
+func (t TaxConfig) validate(p *problems) {
+	if t.Rate < 0 || t.Rate > 100 {
+		p.add("tax.rate", "must be a percentage between 0 and 100, got %g", t.Rate)
+	}
+}
+
+// LeaderboardConfig schedules the weekly leaderboard post, which is made in
+// the channel of the leaderboard_channel setting.
+type LeaderboardConfig struct {
+	// Weekday, such as "monday", and Time, as "15:04" in the guild's
+	// timezone, are when the leaderboard is posted each week.
+	Weekday string `yaml:"weekday"`
+	Time    string `yaml:"time"`
+}
+
+// Next returns the first posting time after t in the timezone loc.
+func (l LeaderboardConfig) Next(t time.Time, loc *time.Location) time.Time {
+	return nextWeekly(l.Weekday, l.Time, t.In(loc))
+}
+
+func (l LeaderboardConfig) validate(p *problems) {
+	validateWeekly(p, "leaderboard", l.Weekday, l.Time)
+}
+
+// RosterConfig schedules the weekly review of inactive players, which
+// proposes archiving them in the channel of the officer_channel setting.
+type RosterConfig struct {
+	// InactiveWeeks is how many weeks a player must go without attendance
+	// or DKP activity to be proposed for archiving. Zero disables the
+	// review.
+	InactiveWeeks int `yaml:"inactive_weeks"`
+	// Weekday, such as "monday", and Time, as "15:04" in the guild's
+	// timezone, are when the review is posted each week.
+	Weekday string `yaml:"weekday"`
+	Time    string `yaml:"time"`
+}
+
+// Enabled reports whether inactive players are reviewed.
+func (r RosterConfig) Enabled() bool {
+	return r.InactiveWeeks > 0
+}
+
+// Next returns the first review time after t in the timezone loc.
+func (r RosterConfig) Next(t time.Time, loc *time.Location) time.Time {
+	return nextWeekly(r.Weekday, r.Time, t.In(loc))
+}
+
+func (r RosterConfig) validate(p *problems) {
+	if r.InactiveWeeks < 0 {
+		p.add("roster.inactive_weeks", "must not be negative, got %d", r.InactiveWeeks)
+	}
+	if r.Enabled() {
+		validateWeekly(p, "roster", r.Weekday, r.Time)
+	}
+}
+
+// ReconcileConfig schedules the weekly reconciliation of player balances
+// with their DKP history. `dkpbot reconcile` runs it on demand.
+type ReconcileConfig struct {
+	// Enabled runs the reconciliation every Weekday at Time, as "15:04"
+	// in the guild's timezone.
+	Enabled bool   `yaml:"enabled"`
+	Weekday string `yaml:"weekday"`
+	Time    string `yaml:"time"`
+	// Fix sets drifted balances to the sum of their history. Otherwise
+	// drift is only reported.
+	Fix bool `yaml:"fix"`
+}
+
+// Next returns the first reconciliation time after t in the timezone loc.
+func (r ReconcileConfig) Next(t time.Time, loc *time.Location) time.Time {
+	return nextWeekly(r.Weekday, r.Time, t.In(loc))
+}
+
+func (r ReconcileConfig) validate(p *problems) {
+	if r.Enabled {
+		validateWeekly(p, "reconcile", r.Weekday, r.Time)
+	}
+}
+
+// RoleSyncConfig gives players the Discord roles their DKP earns them.
+type RoleSyncConfig struct {
+	// Roles lists the synchronized roles. None disables the sync.
+	Roles []RoleRule `yaml:"roles"`
+	// Interval is how often every player's roles are re-evaluated. They
+	// also are shortly after each DKP change.
+	Interval time.Duration `yaml:"interval"`
+	// UpdateInterval is the pause between two role changes, which keeps a
+	// large sync under Discord's rate limits.
+	UpdateInterval time.Duration `yaml:"update_interval"`
+	// DryRun logs the role changes instead of making them.
+	DryRun bool `yaml:"dry_run"`
+}
+
+// RoleRule gives a role to the players among the TopRank highest in the
+// standings or, if TopRank is zero, to those with at least MinDKP. The
+// role is taken from players who no longer qualify.
+type RoleRule struct {
+	RoleID  string `yaml:"role_id"`
+	MinDKP  int    `yaml:"min_dkp"`
+	TopRank int    `yaml:"top_rank"`
+}
+
+// Enabled reports whether any roles are synchronized.
+func (r RoleSyncConfig) Enabled() bool {
+	return len(r.Roles) > 0
+}
+
+func (r RoleSyncConfig) validate(p *problems) {
+	if !r.Enabled() {
+		return
+	}
+	if r.Interval <= 0 {
+		p.add("role_sync.interval", "must be positive, got %s", r.Interval)
+	}
+	if r.UpdateInterval < 0 {
+		p.add("role_sync.update_interval", "must not be negative, got %s", r.UpdateInterval)
+	}
+	seen := make(map[string]bool, len(r.Roles))
+	for i, rule := range r.Roles {
+		field := fmt.Sprintf("role_sync.roles[%d]", i)
+		switch {
+		case !isSnowflake(rule.RoleID):
+			p.add(field+".role_id", "must be a Discord role ID, got %q", rule.RoleID)
+		case seen[rule.RoleID]:
+			p.add(field+".role_id", "role %s is listed twice", rule.RoleID)
+		}
+		seen[rule.RoleID] = true
+		if rule.TopRank < 0 {
+			p.add(field+".top_rank", "must not be negative, got %d", rule.TopRank)
+		}
+		if rule.TopRank > 0 && rule.MinDKP != 0 {
+			p.add(field, "must set min_dkp or top_rank, not both")
+		}
+	}
+}
+
+// TextFilterConfig filters the free text members give commands, such as
+// reasons, item names, and notes, which is recorded and often posted
+// publicly. Text that breaks it is refused, and the refusal is reported
+// in the channel of the officer_channel setting.
+type TextFilterConfig struct {
+	// Words lists the words and phrases refused, matched as whole words
+	// regardless of case.
+	Words []string `yaml:"words"`
+	// MaxLength is the most characters free text may have, or zero for no
+	// limit beyond Discord's.
+	MaxLength int `yaml:"max_length"`
+}
+
+// Enabled reports whether free text is filtered.
+func (f TextFilterConfig) Enabled() bool {
+	return len(f.Words) > 0 || f.MaxLength > 0
+}
+
+func (f TextFilterConfig) validate(p *problems) {
+	if f.MaxLength < 0 {
+		p.add("text_filter.max_length", "must not be negative, got %d", f.MaxLength)
+	}
+	for i, w := range f.Words {
+		if strings.TrimSpace(w) == "" {
+			p.add(fmt.Sprintf("text_filter.words[%d]", i), "must not be empty")
+		}
+	}
+}
+
+// maxCurrencyName is the longest currency name accepted, in characters.
+const maxCurrencyName = 24
+
+// validateCurrencies checks the currency names: each is unique ignoring
+// case, is not one of the built-in DKP and gold, and is made of letters,
+// digits, spaces, and hyphens.
+func validateCurrencies(p *problems, names []string) {
+	seen := make(map[string]bool, len(names))
+	for i, name := range names {
+		field := fmt.Sprintf("currencies[%d]", i)
+		key := strings.ToLower(name)
+		switch {
+		case strings.TrimSpace(name) != name || name == "" || utf8.RuneCountInString(name) > maxCurrencyName:
+			p.add(field, "must be 1 to %d characters without leading or trailing spaces, got %q", maxCurrencyName, name)
+		case strings.ContainsFunc(name, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' && r != '-'
+		}):
+			p.add(field, "may only contain letters, digits, spaces, and hyphens, got %q", name)
+		case key == "dkp" || key == "gold":
+			p.add(field, "%q is built in", name)
+		case seen[key]:
+			p.add(field, "%q is listed twice", name)
+		}
+		seen[key] = true
+	}
+}
+
+// parseWeekday returns the weekday named s, such as "monday", and false if
+// it is not one.
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// nextWeekly returns the first time after t that falls on weekday at the
+// time of day at, as "15:04" in the location of t.
+func nextWeekly(weekday, at string, t time.Time) time.Time {
+	day, _ := parseWeekday(weekday)
+	tod, _ := time.Parse("15:04", at)
+	next := time.Date(t.Year(), t.Month(), t.Day(), tod.Hour(), tod.Minute(), 0, 0, t.Location())
+	next = next.AddDate(0, 0, (int(day)-int(next.Weekday())+7)%7)
+	if !next.After(t) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// validateWeekly checks the weekday and time of day of the weekly schedule
+// in section.
+func validateWeekly(p *problems, section, weekday, at string) {
+	if _, ok := parseWeekday(weekday); !ok {
+		p.add(section+".weekday", "must be a day of the week such as monday, got %q", weekday)
+	}
+	if _, err := time.Parse("15:04", at); err != nil {
+		p.add(section+".time", "must be a time of day such as 18:00, got %q", at)
+	}
+}
+
+// CalendarConfig holds settings for raids scheduled with /raid-schedule.
+type CalendarConfig struct {
+	// Reminder is how long before a raid starts its signed-up members are
+	// reminded by direct message. Zero sends no reminders.
+	Reminder time.Duration `yaml:"reminder"`
+	// OnTimeGrace is how long after a raid's start members may join it and
+	// still count as on time for the on-time bonus.
+	OnTimeGrace time.Duration `yaml:"on_time_grace"`
+}
+
+func (c CalendarConfig) validate(p *problems) {
+	if c.Reminder < 0 {
+		p.add("calendar.reminder", "must not be negative, got %s", c.Reminder)
+	}
+	if c.OnTimeGrace < 0 {
+		p.add("calendar.on_time_grace", "must not be negative, got %s", c.OnTimeGrace)
+	}
+}
+
+// Secrets providers.
+const (
+	SecretsVault = "vault"
+	SecretsGCP   = "gcp"
+)
+
+// SecretsConfig selects a secrets provider that the Discord token,
+// database password, encryption key, and chain key are fetched from at
+// startup, and refetched every RefreshInterval so that rotated values take
+// effect; the encryption and chain keys are fetched only at startup, as data
+// sealed or chained with one cannot be read or verified with another. A reference names a
+// secret in the provider's terms; secrets without a reference keep their
+// value from this file.
+type SecretsConfig struct {
+	// Provider is "vault", "gcp", or empty for none.
+	Provider string `yaml:"provider"`
+	// RefreshInterval is how often secrets are refetched; zero fetches
+	// them only at startup.
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	// DiscordToken and DatabasePassword reference secrets: "path#key" in
+	// a Vault KV v2 mount, or a secret name (optionally with
+	// "/versions/N") in Google Secret Manager.
+	DiscordToken     string `yaml:"discord_token"`
+	DatabasePassword string `yaml:"database_password"`
+	// EncryptionKey references database.encryption_key.
+	EncryptionKey string `yaml:"encryption_key"`
+	// ChainKey references database.chain_key.
+	ChainKey string           `yaml:"chain_key"`
+	Vault    VaultConfig      `yaml:"vault"`
+	GCP      GCPSecretsConfig `yaml:"gcp"`
+}
+
+// Enabled reports whether a secrets provider is configured.
+func (s SecretsConfig) Enabled() bool {
+	return s.Provider != ""
+}
+
+// VaultConfig holds settings for reading secrets from HashiCorp Vault.
+type VaultConfig struct {
+	Address string `yaml:"address"`
+	// Mount is the path of the KV v2 secrets engine.
+	Mount string `yaml:"mount"`
+	// Token authenticates directly. Without it, the bot logs in with its
+	// Kubernetes service account token as Role.
+	Token string `yaml:"token" secret:"true"`
+	Role  string `yaml:"role"`
+	// AuthPath is the mount path of the Kubernetes auth method.
+	AuthPath string `yaml:"auth_path"`
+}
+
+// GCPSecretsConfig holds settings for reading secrets from Google Secret
+// Manager, authenticating as the workload's service account through the
+// metadata server.
+type GCPSecretsConfig struct {
+	Project string `yaml:"project"`
+}
+
+func (s SecretsConfig) validate(p *problems) {
+	switch s.Provider {
+	case "":
+		if s.DiscordToken != "" || s.DatabasePassword != "" || s.EncryptionKey != "" || s.ChainKey != "" {
+			p.add("secrets.provider", "is required when secret references are set")
+		}
+		return
+	case SecretsVault:
+		if s.Vault.Address == "" {
+			p.add("secrets.vault.address", "is required for the vault provider")
+		}
+		if s.Vault.Token == "" && s.Vault.Role == "" {
+			p.add("secrets.vault", "token or role is required for the vault provider")
+		}
+		for _, ref := range [][2]string{{"discord_token", s.DiscordToken}, {"database_password", s.DatabasePassword}, {"encryption_key", s.EncryptionKey}, {"chain_key", s.ChainKey}} {
+			if _, key, ok := strings.Cut(ref[1], "#"); ref[1] != "" && (!ok || key == "") {
+				p.add("secrets."+ref[0], "%q must be a Vault reference of the form path#key", ref[1])
+			}
+		}
+	case SecretsGCP:
+		if s.GCP.Project == "" {
+			p.add("secrets.gcp.project", "is required for the gcp provider")
+		}
+	default:
+		p.add("secrets.provider", "unsupported provider %q: must be \"vault\" or \"gcp\"", s.Provider)
+		return
+	}
+	if s.DiscordToken == "" && s.DatabasePassword == "" && s.EncryptionKey == "" && s.ChainKey == "" {
+		p.add("secrets", "provider %q is set without discord_token, database_password, encryption_key, or chain_key references", s.Provider)
+	}
+	if s.RefreshInterval < 0 {
+		p.add("secrets.refresh_interval", "must not be negative, got %s", s.RefreshInterval)
+	}
+}
+
+// APIConfig holds settings for the HTTP API served alongside the health
+// endpoints.
+type APIConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Keys lists the API keys accepted by the API, one per integration.
+	Keys []APIKey `yaml:"keys"`
+	// MaxPageSize caps the limit query parameter of list endpoints.
+	MaxPageSize int `yaml:"max_page_size"`
+}
+
+// APIKey is a named credential for the HTTP API.
+type APIKey struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key" secret:"true"`
+	// Scopes lists what the key may do. A key without scopes is read-only.
+	Scopes []string `yaml:"scopes"`
+}
+
+// API key scopes.
+const (
+	ScopeRead         = "read"
+	ScopeDKPWrite     = "dkp:write"
+	ScopeAuctionWrite = "auction:write"
+	ScopePlayersWrite = "players:write"
+	// ScopeAdmin grants operational endpoints such as leader stepdown, and
+	// shows auctions' hidden reserve prices.
+	ScopeAdmin = "admin"
+)
+
+var knownScopes = map[string]bool{
+	ScopeRead:         true,
+	ScopeDKPWrite:     true,
+	ScopeAuctionWrite: true,
+	ScopePlayersWrite: true,
+	ScopeAdmin:        true,
+}
+
+// HasScope reports whether the key grants scope.
+func (k APIKey) HasScope(scope string) bool {
+	if len(k.Scopes) == 0 {
+		return scope == ScopeRead
+	}
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// WarcraftLogsConfig holds credentials and award amounts for importing
+// raid attendance from Warcraft Logs or ESO Logs reports. The integration is
+// enabled when a client ID is set.
+type WarcraftLogsConfig struct {
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret" secret:"true"`
+	// AttendanceDKP is awarded to every player in a report.
+	AttendanceDKP int `yaml:"attendance_dkp"`
+	// BossKillDKP is awarded to every player in a report per boss kill.
+	BossKillDKP int `yaml:"boss_kill_dkp"`
+}
+
+// Enabled reports whether the Warcraft Logs integration is configured.
+func (w WarcraftLogsConfig) Enabled() bool {
+	return w.ClientID != ""
 }
 
 // expandEnv resolves ${VAR} and $VAR placeholders in raw config bytes
@@ -76,43 +1065,168 @@ func expandEnv(data []byte) []byte {
 
 // Load reads a YAML configuration file from the given path.
 // Environment variable references (${VAR} or $VAR) in the YAML are
-// expanded before parsing.
+// expanded before parsing, and DKPBOT_* variables then override individual
+// fields (see applyEnv).
 func Load(path string) (*Config, error) {
+	return LoadProfile(path, "")
+}
+
+// LoadProfile is Load with the defaults of the named profile (see
+// Profiles) in place of the built-in ones. With a profile the file may be
+// missing, so that a bot can run on the profile and DKPBOT_* variables
+// alone.
+func LoadProfile(path, profile string) (*Config, error) {
 	data, err := os.ReadFile(filepath.Clean(path))
-	if err != nil {
+	switch {
+	case profile != "" && errors.Is(err, fs.ErrNotExist):
+		data = nil
+	case err != nil:
 		return nil, fmt.Errorf("reading config file: %w", err)
 	}
 
 	data = expandEnv(data)
 
 	cfg := &Config{
+		Discord: DiscordConfig{
+			Gateway: GatewayConfig{
+				CheckInterval: 30 * time.Second,
+				MaxLatency:    5 * time.Second,
+				ReconnectMin:  time.Second,
+				ReconnectMax:  2 * time.Minute,
+			},
+			Timeouts: TimeoutConfig{
+				Default: 30 * time.Second,
+				Commands: map[string]time.Duration{
+					"import-eqdkp":      5 * time.Minute,
+					"wcl-import":        5 * time.Minute,
+					"guild-merge":       5 * time.Minute,
+					"guild-merge-apply": 5 * time.Minute,
+					"role-sync":         5 * time.Minute,
+				},
+			},
+			Jobs: JobsConfig{
+				Timeout:          2 * time.Hour,
+				ProgressInterval: 5 * time.Second,
+				ReportTo:         JobsReportChannel,
+			},
+		},
 		Server: ServerConfig{
 			Port:            8080,
 			ShutdownTimeout: 15 * time.Second,
 		},
 		Database: DatabaseConfig{
-			Host:    "localhost",
-			Port:    5432,
-			SSLMode: "disable",
-			Driver:  "sqlx",
+			Host:            "localhost",
+			Port:            5432,
+			SSLMode:         "disable",
+			Driver:          "sqlx",
+			AppendBatchSize: 500,
+			TraceQueries:    true,
+			IDs:             IDsConfig{Strategy: IDStrategyULID},
 		},
 		Telemetry: TelemetryConfig{
 			ServiceName:    "dkpbot",
 			ServiceVersion: "0.1.0",
+			Exporter:       TelemetryExporterOTLP,
+			Sampling:       SamplingConfig{Ratio: 1},
+			MetricInterval: time.Minute,
+			LogLevel:       "info",
 		},
 		LeaderElection: LeaderElectionConfig{
 			Enabled:        false,
+			Backend:        LeaderBackendKubernetes,
 			LeaseName:      "dkpbot-leader",
 			LeaseNamespace: "default",
 			LeaseDuration:  15 * time.Second,
 			RenewDeadline:  10 * time.Second,
 			RetryPeriod:    2 * time.Second,
 		},
+		Retention: RetentionConfig{
+			MaxAge: 90 * 24 * time.Hour,
+		},
+		API: APIConfig{
+			MaxPageSize: 100,
+		},
+		DeadLetter: DeadLetterConfig{
+			Path:          "deadletter.json",
+			RetryInterval: 30 * time.Second,
+			// An hour of retries at the default interval.
+			MaxAttempts: 120,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:           30 * 24 * time.Hour,
+			StaleAfter:    maxCommandTimeout + 5*time.Minute,
+			PruneInterval: time.Hour,
+		},
+		EventBus: EventBusConfig{
+			Default: QueueConfig{
+				Size:         256,
+				Overflow:     OverflowDropOldest,
+				BlockTimeout: time.Second,
+			},
+			// Unsold banked items are returned to the bank from auction
+			// results, which must not be lost.
+			Subscribers: map[string]QueueConfig{
+				"bank": {Overflow: OverflowSpill},
+			},
+			SpillDir: "spill",
+		},
+		GuildDefaults: GuildDefaultsConfig{
+			AuctionDuration:     5 * time.Minute,
+			MinIncrement:        1,
+			UndoWindow:          24 * time.Hour,
+			OutbidNotifications: OutbidDM,
+			LootMode:            LootModeAuction,
+			Timezone:            "UTC",
+			StartedMessage:      announce.Started.Default,
+			OutbidMessage:       announce.Outbid.Default,
+			WinnerMessage:       announce.Winner.Default,
+			NoBidsMessage:       announce.NoBids.Default,
+		},
+		Leaderboard: LeaderboardConfig{
+			Weekday: "monday",
+			Time:    "18:00",
+		},
+		Calendar: CalendarConfig{
+			Reminder:    30 * time.Minute,
+			OnTimeGrace: 10 * time.Minute,
+		},
+		Roster: RosterConfig{
+			InactiveWeeks: 4,
+			Weekday:       "monday",
+			Time:          "17:00",
+		},
+		Reconcile: ReconcileConfig{
+			Enabled: true,
+			Weekday: "sunday",
+			Time:    "05:00",
+		},
+		RoleSync: RoleSyncConfig{
+			Interval:       time.Hour,
+			UpdateInterval: 500 * time.Millisecond,
+		},
+		Secrets: SecretsConfig{
+			RefreshInterval: 15 * time.Minute,
+			Vault: VaultConfig{
+				Mount:    "secret",
+				AuthPath: "kubernetes",
+			},
+		},
+	}
+
+	if profile != "" {
+		apply, ok := profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q (available: %s)", profile, strings.Join(Profiles(), ", "))
+		}
+		apply(cfg)
 	}
 
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("parsing config file: %w", err)
 	}
+	if err := applyEnv(cfg, os.Getenv); err != nil {
+		return nil, fmt.Errorf("applying environment overrides: %w", err)
+	}
 
 	if err := cfg.validate(); err != nil {
 		return nil, fmt.Errorf("validating config: %w", err)
@@ -121,13 +1235,205 @@ func Load(path string) (*Config, error) {
 	return cfg, nil
 }
 
-// validate checks configuration invariants.
-func (c *Config) validate() error {
-	switch c.Database.Driver {
+func (t TelemetryConfig) validate(p *problems) {
+	if t.Exporter != TelemetryExporterOTLP && t.Exporter != TelemetryExporterStderr {
+		p.add("telemetry.exporter", "must be %q or %q, got %q", TelemetryExporterOTLP, TelemetryExporterStderr, t.Exporter)
+	}
+	if t.Sampling.Ratio < 0 || t.Sampling.Ratio > 1 {
+		p.add("telemetry.sampling.ratio", "must be between 0 and 1, got %g", t.Sampling.Ratio)
+	}
+	for name, ratio := range t.Sampling.Commands {
+		if ratio < 0 || ratio > 1 {
+			p.add("telemetry.sampling.commands."+name, "must be between 0 and 1, got %g", ratio)
+		}
+	}
+	if t.MetricInterval <= 0 {
+		p.add("telemetry.metric_interval", "must be positive, got %s", t.MetricInterval)
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(t.LogLevel)); err != nil {
+		p.add("telemetry.log_level", "%q must be debug, info, warn, or error", t.LogLevel)
+	}
+	if t.Debug.Enabled && (t.Debug.Username == "" || t.Debug.Password == "") {
+		p.add("telemetry.debug", "requires a username and password when enabled")
+	}
+}
+
+func (g GatewayConfig) validate(p *problems) {
+	if g.CheckInterval <= 0 {
+		p.add("discord.gateway.check_interval", "must be positive, got %s", g.CheckInterval)
+	}
+	if g.MaxLatency <= 0 {
+		p.add("discord.gateway.max_latency", "must be positive, got %s", g.MaxLatency)
+	}
+	if g.ReconnectMin <= 0 || g.ReconnectMax < g.ReconnectMin {
+		p.add("discord.gateway.reconnect_min", "must be positive and not above reconnect_max, got %s and %s", g.ReconnectMin, g.ReconnectMax)
+	}
+}
+
+func (d DiscordConfig) validate(p *problems, fromSecrets bool) {
+	if d.Token == "" && !fromSecrets {
+		p.add("discord.token", "must not be empty")
+	}
+	if d.GuildID != "" && !isSnowflake(d.GuildID) {
+		p.add("discord.guild_id", "%q is not a Discord ID", d.GuildID)
+	}
+	if strings.ContainsFunc(d.CommandPrefix, unicode.IsSpace) || strings.HasPrefix(d.CommandPrefix, "/") {
+		p.add("discord.command_prefix", "%q must not contain spaces or start with a slash", d.CommandPrefix)
+	}
+	d.Gateway.validate(p)
+	d.Timeouts.validate(p)
+	d.Jobs.validate(p)
+}
+
+func (t TimeoutConfig) validate(p *problems) {
+	if t.Default <= 0 || t.Default > maxCommandTimeout {
+		p.add("discord.timeouts.default", "must be positive and at most %s, got %s", maxCommandTimeout, t.Default)
+	}
+	for name, d := range t.Commands {
+		if d <= 0 || d > maxCommandTimeout {
+			p.add("discord.timeouts.commands."+name, "must be positive and at most %s, got %s", maxCommandTimeout, d)
+		}
+	}
+}
+
+func (c IdempotencyConfig) validate(p *problems, timeouts TimeoutConfig) {
+	if c.TTL <= 0 {
+		p.add("idempotency.ttl", "must be positive, got %s", c.TTL)
+	}
+	// Timeouts beyond the maximum are reported on their own.
+	longest := min(timeouts.Default, maxCommandTimeout)
+	for _, d := range timeouts.Commands {
+		longest = max(longest, min(d, maxCommandTimeout))
+	}
+	if c.StaleAfter <= longest {
+		p.add("idempotency.stale_after", "must exceed the longest command timeout %s, got %s", longest, c.StaleAfter)
+	}
+	if c.PruneInterval <= 0 {
+		p.add("idempotency.prune_interval", "must be positive, got %s", c.PruneInterval)
+	}
+}
+
+// sslModes are the sslmode values understood by lib/pq.
+var sslModes = map[string]bool{
+	"disable": true, "require": true, "verify-ca": true, "verify-full": true,
+}
+
+func (d DatabaseConfig) validate(p *problems) {
+	switch d.Driver {
 	case "sqlx", "ent":
-		// valid
 	default:
-		return fmt.Errorf("unsupported database driver %q: must be \"sqlx\" or \"ent\"", c.Database.Driver)
+		p.add("database.driver", "unsupported driver %q: must be \"sqlx\" or \"ent\"", d.Driver)
+	}
+	if d.Host == "" {
+		p.add("database.host", "must not be empty")
+	}
+	validatePort(p, "database.port", d.Port)
+	if !sslModes[d.SSLMode] {
+		p.add("database.sslmode", "%q must be disable, require, verify-ca, or verify-full", d.SSLMode)
+	}
+	// Postgres allows at most 65535 parameters per statement, 8 per event.
+	if d.AppendBatchSize < 1 || d.AppendBatchSize > 8191 {
+		p.add("database.append_batch_size", "%d must be between 1 and 8191", d.AppendBatchSize)
+	}
+	if d.EncryptionKey != "" {
+		// The key is not echoed, as it is a secret.
+		if key, err := base64.StdEncoding.DecodeString(d.EncryptionKey); err != nil || len(key) != 32 {
+			p.add("database.encryption_key", "must be a base64-encoded 32-byte key")
+		}
+	}
+	if d.ChainKey != "" {
+		if key, err := base64.StdEncoding.DecodeString(d.ChainKey); err != nil || len(key) != 32 {
+			p.add("database.chain_key", "must be a base64-encoded 32-byte key")
+		}
+	}
+	d.IDs.validate(p)
+}
+
+func (a APIConfig) validate(p *problems) {
+	if !a.Enabled {
+		return
+	}
+	if len(a.Keys) == 0 {
+		p.add("api.keys", "must not be empty when the API is enabled")
+	}
+	names := make(map[string]bool)
+	keys := make(map[string]bool)
+	for i, k := range a.Keys {
+		field := fmt.Sprintf("api.keys[%d]", i)
+		if k.Name == "" || k.Key == "" {
+			p.add(field, "must have both a name and a key")
+		}
+		if k.Name != "" && names[k.Name] {
+			p.add(field+".name", "%q is used by another key", k.Name)
+		}
+		if k.Key != "" && keys[k.Key] {
+			p.add(field+".key", "is the same as another key's")
+		}
+		names[k.Name], keys[k.Key] = true, true
+		for _, scope := range k.Scopes {
+			if !knownScopes[scope] {
+				p.add(field+".scopes", "unknown scope %q", scope)
+			}
+		}
+	}
+	if a.MaxPageSize <= 0 {
+		p.add("api.max_page_size", "must be positive, got %d", a.MaxPageSize)
+	}
+}
+
+func (w WarcraftLogsConfig) validate(p *problems) {
+	if !w.Enabled() {
+		if w.ClientSecret != "" {
+			p.add("warcraft_logs.client_secret", "is set without warcraft_logs.client_id")
+		}
+		return
+	}
+	if w.ClientSecret == "" {
+		p.add("warcraft_logs.client_secret", "is required when client_id is set")
+	}
+	if w.AttendanceDKP < 0 || w.BossKillDKP < 0 {
+		p.add("warcraft_logs", "award amounts must not be negative")
+	}
+}
+
+func (c *Config) validate() error {
+	var p problems
+	c.Discord.validate(&p, c.Secrets.DiscordToken != "")
+	c.Database.validate(&p)
+	validatePort(&p, "server.port", c.Server.Port)
+	if c.Server.ShutdownTimeout <= 0 {
+		p.add("server.shutdown_timeout", "must be positive, got %s", c.Server.ShutdownTimeout)
+	}
+	c.Telemetry.validate(&p)
+	c.LeaderElection.validate(&p)
+	if c.Retention.MaxAge <= 0 {
+		p.add("retention.max_age", "must be positive, got %s", c.Retention.MaxAge)
+	}
+	if c.DeadLetter.Path == "" {
+		p.add("dead_letter.path", "must not be empty")
+	}
+	if c.DeadLetter.RetryInterval <= 0 {
+		p.add("dead_letter.retry_interval", "must be positive, got %s", c.DeadLetter.RetryInterval)
+	}
+	if c.DeadLetter.MaxAttempts <= 0 {
+		p.add("dead_letter.max_attempts", "must be positive, got %d", c.DeadLetter.MaxAttempts)
 	}
-	return nil
+	c.Idempotency.validate(&p, c.Discord.Timeouts)
+	c.EventBus.validate(&p)
+	c.API.validate(&p)
+	c.WarcraftLogs.validate(&p)
+	c.GuildDefaults.validate(&p)
+	c.Items.validate(&p)
+	c.GDKP.validate(&p)
+	c.Tax.validate(&p)
+	c.Leaderboard.validate(&p)
+	c.Calendar.validate(&p)
+	c.Roster.validate(&p)
+	c.Reconcile.validate(&p)
+	c.RoleSync.validate(&p)
+	c.TextFilter.validate(&p)
+	c.Secrets.validate(&p)
+	validateCurrencies(&p, c.Currencies)
+	return p.err()
 }
@@ -1,9 +1,13 @@
 package config_test
 
 import (
+	"errors"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
 )
@@ -118,8 +122,730 @@ discord:
 				if cfg.Database.Driver != "sqlx" {
 					t.Errorf("got driver %q, want %q", cfg.Database.Driver, "sqlx")
 				}
+				if !cfg.Database.TraceQueries {
+					t.Error("TraceQueries = false, want true by default")
+				}
+			},
+		},
+		{
+			name: "retention max age parsed",
+			yaml: `
+discord:
+  token: "tok"
+retention:
+  max_age: 720h
+`,
+			wantErr: false,
+			check: func(t *testing.T, cfg *config.Config) {
+				t.Helper()
+				if cfg.Retention.MaxAge != 720*time.Hour {
+					t.Errorf("got retention max age %s, want %s", cfg.Retention.MaxAge, 720*time.Hour)
+				}
+			},
+		},
+		{
+			name: "api enabled with keys",
+			yaml: `
+discord:
+  token: "tok"
+api:
+  enabled: true
+  keys:
+    - name: "website"
+      key: "secret"
+`,
+			wantErr: false,
+			check: func(t *testing.T, cfg *config.Config) {
+				t.Helper()
+				if len(cfg.API.Keys) != 1 || cfg.API.Keys[0].Name != "website" {
+					t.Errorf("got api keys %+v, want one key named website", cfg.API.Keys)
+				}
+				if cfg.API.MaxPageSize != 100 {
+					t.Errorf("got max page size %d, want 100", cfg.API.MaxPageSize)
+				}
+			},
+		},
+		{
+			name: "api enabled without keys rejected",
+			yaml: `
+discord:
+  token: "tok"
+api:
+  enabled: true
+`,
+			wantErr: true,
+		},
+		{
+			name: "api key with unknown scope rejected",
+			yaml: `
+discord:
+  token: "tok"
+api:
+  enabled: true
+  keys:
+    - name: "raidtool"
+      key: "secret"
+      scopes: ["dkp:write", "superuser"]
+`,
+			wantErr: true,
+		},
+		{
+			name: "warcraft logs without secret rejected",
+			yaml: `
+discord:
+  token: "tok"
+warcraft_logs:
+  client_id: "abc"
+  attendance_dkp: 10
+`,
+			wantErr: true,
+		},
+		{
+			name: "query tracing can be turned off",
+			yaml: `
+discord:
+  token: "tok"
+database:
+  trace_queries: false
+`,
+			check: func(t *testing.T, cfg *config.Config) {
+				t.Helper()
+				if cfg.Database.TraceQueries {
+					t.Error("TraceQueries = true, want false")
+				}
+			},
+		},
+		{
+			name: "append batch size above the parameter limit rejected",
+			yaml: `
+discord:
+  token: "tok"
+database:
+  append_batch_size: 10000
+`,
+			wantErr: true,
+		},
+		{
+			name: "short encryption key rejected",
+			yaml: `
+discord:
+  token: "tok"
+database:
+  encryption_key: "c2hvcnQ="
+`,
+			wantErr: true,
+		},
+		{
+			name: "encryption key reference accepted",
+			yaml: `
+discord:
+  token: "tok"
+secrets:
+  provider: gcp
+  gcp:
+    project: guild
+  encryption_key: dkpbot-encryption-key
+`,
+		},
+		{
+			name: "short chain key rejected",
+			yaml: `
+discord:
+  token: "tok"
+database:
+  chain_key: "c2hvcnQ="
+`,
+			wantErr: true,
+		},
+		{
+			name: "chain key reference accepted",
+			yaml: `
+discord:
+  token: "tok"
+secrets:
+  provider: vault
+  vault:
+    address: https://vault.example.com
+    role: dkpbot
+  chain_key: dkpbot/ledger#chain_key
+`,
+		},
+		{
+			name: "uuidv7 IDs accepted",
+			yaml: `
+discord:
+  token: "tok"
+database:
+  ids:
+    strategy: uuidv7
+`,
+		},
+		{
+			name: "unknown ID strategy rejected",
+			yaml: `
+discord:
+  token: "tok"
+database:
+  ids:
+    strategy: serial
+`,
+			wantErr: true,
+		},
+		{
+			name: "job reports by DM accepted",
+			yaml: `
+discord:
+  token: "tok"
+  jobs:
+    report_to: dm
+`,
+		},
+		{
+			name: "unknown job report destination rejected",
+			yaml: `
+discord:
+  token: "tok"
+  jobs:
+    report_to: email
+`,
+			wantErr: true,
+		},
+		{
+			name: "sub-second job progress interval rejected",
+			yaml: `
+discord:
+  token: "tok"
+  jobs:
+    progress_interval: 100ms
+`,
+			wantErr: true,
+		},
+		{
+			name: "event bus subscriber overrides accepted",
+			yaml: `
+discord:
+  token: "tok"
+event_bus:
+  subscribers:
+    notify:
+      overflow: block
+`,
+		},
+		{
+			name: "unknown event bus overflow rejected",
+			yaml: `
+discord:
+  token: "tok"
+event_bus:
+  subscribers:
+    notify:
+      overflow: drop_newest
+`,
+			wantErr: true,
+		},
+		{
+			name: "non-positive event bus queue size rejected",
+			yaml: `
+discord:
+  token: "tok"
+event_bus:
+  default:
+    size: 0
+`,
+			wantErr: true,
+		},
+		{
+			name: "non-positive retention max age rejected",
+			yaml: `
+discord:
+  token: "tok"
+retention:
+  max_age: 0s
+`,
+			wantErr: true,
+		},
+		{
+			name: "non-positive dead letter retry interval rejected",
+			yaml: `
+discord:
+  token: "tok"
+dead_letter:
+  retry_interval: 0s
+`,
+			wantErr: true,
+		},
+		{
+			name: "idempotency stale_after within a command timeout rejected",
+			yaml: `
+discord:
+  token: "tok"
+  timeouts:
+    commands:
+      import-eqdkp: 5m
+idempotency:
+  stale_after: 5m
+`,
+			wantErr: true,
+		},
+		{
+			name: "non-positive dead letter max attempts rejected",
+			yaml: `
+discord:
+  token: "tok"
+dead_letter:
+  max_attempts: 0
+`,
+			wantErr: true,
+		},
+		{
+			name: "telemetry sampling parsed",
+			yaml: `
+discord:
+  token: "tok"
+telemetry:
+  sampling:
+    commands:
+      dkp: 0.1
+  metric_interval: 15s
+  log_level: warn
+`,
+			check: func(t *testing.T, cfg *config.Config) {
+				t.Helper()
+				if cfg.Telemetry.Sampling.Ratio != 1 {
+					t.Errorf("got sampling ratio %v, want default 1", cfg.Telemetry.Sampling.Ratio)
+				}
+				if got := cfg.Telemetry.Sampling.Commands["dkp"]; got != 0.1 {
+					t.Errorf("got dkp sampling ratio %v, want 0.1", got)
+				}
+				if cfg.Telemetry.MetricInterval != 15*time.Second {
+					t.Errorf("got metric interval %v, want 15s", cfg.Telemetry.MetricInterval)
+				}
+				if cfg.Telemetry.SlogLevel() != slog.LevelWarn {
+					t.Errorf("got log level %v, want %v", cfg.Telemetry.SlogLevel(), slog.LevelWarn)
+				}
+			},
+		},
+		{
+			name: "sampling ratio out of range rejected",
+			yaml: `
+discord:
+  token: "tok"
+telemetry:
+  sampling:
+    commands:
+      dkp: 1.5
+`,
+			wantErr: true,
+		},
+		{
+			name: "debug endpoints without credentials rejected",
+			yaml: `
+discord:
+  token: "tok"
+telemetry:
+  debug:
+    enabled: true
+`,
+			wantErr: true,
+		},
+		{
+			name: "gateway reconnect backoff bounds rejected",
+			yaml: `
+discord:
+  token: "tok"
+  gateway:
+    reconnect_min: 1m
+    reconnect_max: 10s
+`,
+			wantErr: true,
+		},
+		{
+			name: "redis leader election without addresses rejected",
+			yaml: `
+discord:
+  token: "tok"
+leader_election:
+  enabled: true
+  backend: redis
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown leader election backend rejected",
+			yaml: `
+discord:
+  token: "tok"
+leader_election:
+  enabled: true
+  backend: etcd
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown log level rejected",
+			yaml: `
+discord:
+  token: "tok"
+telemetry:
+  log_level: verbose
+`,
+			wantErr: true,
+		},
+		{
+			name: "token from vault",
+			yaml: `
+secrets:
+  provider: vault
+  discord_token: "dkpbot/discord#token"
+  vault:
+    address: "https://vault:8200"
+    role: dkpbot
+`,
+			wantErr: false,
+			check: func(t *testing.T, cfg *config.Config) {
+				t.Helper()
+				if cfg.Secrets.Vault.Mount != "secret" || cfg.Secrets.Vault.AuthPath != "kubernetes" {
+					t.Errorf("vault defaults = %+v", cfg.Secrets.Vault)
+				}
+				if cfg.Secrets.RefreshInterval != 15*time.Minute {
+					t.Errorf("refresh interval = %s, want 15m", cfg.Secrets.RefreshInterval)
+				}
+			},
+		},
+		{
+			name: "vault reference without key rejected",
+			yaml: `
+secrets:
+  provider: vault
+  discord_token: "dkpbot/discord"
+  vault:
+    address: "https://vault:8200"
+    token: root
+`,
+			wantErr: true,
+		},
+		{
+			name: "secret reference without provider rejected",
+			yaml: `
+discord:
+  token: "tok"
+secrets:
+  database_password: "dkpbot-db"
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown secrets provider rejected",
+			yaml: `
+secrets:
+  provider: aws
+  discord_token: "dkpbot-discord"
+`,
+			wantErr: true,
+		},
+		{
+			name: "item icon URL without placeholder rejected",
+			yaml: `
+discord:
+  token: "tok"
+items:
+  icon_url: "https://wow.zamimg.com/images/wow/icons/large/icon.jpg"
+`,
+			wantErr: true,
+		},
+		{
+			name: "item link URL without placeholder rejected",
+			yaml: `
+discord:
+  token: "tok"
+items:
+  link_url: "https://www.wowhead.com/classic/item"
+`,
+			wantErr: true,
+		},
+		{
+			name: "GDKP organizer cut above 100 percent rejected",
+			yaml: `
+discord:
+  token: "tok"
+gdkp:
+  organizer_cut: 150
+`,
+			wantErr: true,
+		},
+		{
+			name: "negative tax rate rejected",
+			yaml: `
+discord:
+  token: "tok"
+tax:
+  rate: -5
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown leaderboard weekday rejected",
+			yaml: `
+discord:
+  token: "tok"
+leaderboard:
+  weekday: caturday
+`,
+			wantErr: true,
+		},
+		{
+			name: "negative calendar reminder rejected",
+			yaml: `
+discord:
+  token: "tok"
+calendar:
+  reminder: -5m
+`,
+			wantErr: true,
+		},
+		{
+			name: "bad roster time rejected",
+			yaml: `
+discord:
+  token: "tok"
+roster:
+  time: "5pm"
+`,
+			wantErr: true,
+		},
+		{
+			name: "disabled roster review skips its schedule",
+			yaml: `
+discord:
+  token: "tok"
+roster:
+  inactive_weeks: 0
+  time: "5pm"
+`,
+			check: func(t *testing.T, cfg *config.Config) {
+				t.Helper()
+				if cfg.Roster.Enabled() {
+					t.Error("roster review enabled, want disabled")
+				}
+			},
+		},
+		{
+			name: "bad reconcile weekday rejected",
+			yaml: `
+discord:
+  token: "tok"
+reconcile:
+  weekday: "caturday"
+`,
+			wantErr: true,
+		},
+		{
+			name: "disabled reconciliation skips its schedule",
+			yaml: `
+discord:
+  token: "tok"
+reconcile:
+  enabled: false
+  weekday: "caturday"
+`,
+		},
+		{
+			name: "role sync rules",
+			yaml: `
+discord:
+  token: "tok"
+role_sync:
+  roles:
+    - role_id: "111111111111111111"
+      top_rank: 10
+    - role_id: "222222222222222222"
+      min_dkp: 500
+`,
+			check: func(t *testing.T, cfg *config.Config) {
+				t.Helper()
+				if !cfg.RoleSync.Enabled() || cfg.RoleSync.Roles[1].MinDKP != 500 || cfg.RoleSync.Interval != time.Hour {
+					t.Errorf("role sync = %+v, want two rules re-evaluated hourly", cfg.RoleSync)
+				}
+			},
+		},
+		{
+			name: "role sync rule with both thresholds rejected",
+			yaml: `
+discord:
+  token: "tok"
+role_sync:
+  roles:
+    - role_id: "111111111111111111"
+      top_rank: 10
+      min_dkp: 500
+`,
+			wantErr: true,
+		},
+		{
+			name: "role sync rule without a role ID rejected",
+			yaml: `
+discord:
+  token: "tok"
+role_sync:
+  roles:
+    - role_id: "Top 10"
+      top_rank: 10
+`,
+			wantErr: true,
+		},
+		{
+			name: "currencies",
+			yaml: `
+discord:
+  token: "tok"
+currencies: ["EP", "GP", "Raid tokens"]
+`,
+			check: func(t *testing.T, cfg *config.Config) {
+				t.Helper()
+				if len(cfg.Currencies) != 3 || cfg.Currencies[2] != "Raid tokens" {
+					t.Errorf("currencies = %q, want EP, GP, and Raid tokens", cfg.Currencies)
+				}
+			},
+		},
+		{
+			name: "currency named like DKP rejected",
+			yaml: `
+discord:
+  token: "tok"
+currencies: ["EP", "dkp"]
+`,
+			wantErr: true,
+		},
+		{
+			name: "duplicate currency rejected",
+			yaml: `
+discord:
+  token: "tok"
+currencies: ["EP", "ep"]
+`,
+			wantErr: true,
+		},
+		{
+			name: "text filter",
+			yaml: `
+discord:
+  token: "tok"
+text_filter:
+  words: ["ninja looter", "scrub"]
+  max_length: 200
+`,
+			check: func(t *testing.T, cfg *config.Config) {
+				t.Helper()
+				if !cfg.TextFilter.Enabled() || len(cfg.TextFilter.Words) != 2 || cfg.TextFilter.MaxLength != 200 {
+					t.Errorf("text filter = %+v, want two words and at most 200 characters", cfg.TextFilter)
+				}
+			},
+		},
+		{
+			name: "empty filtered word rejected",
+			yaml: `
+discord:
+  token: "tok"
+text_filter:
+  words: ["scrub", " "]
+`,
+			wantErr: true,
+		},
+		{
+			name: "negative text length rejected",
+			yaml: `
+discord:
+  token: "tok"
+text_filter:
+  max_length: -1
+`,
+			wantErr: true,
+		},
+		{
+			name: "bad officer channel rejected",
+			yaml: `
+discord:
+  token: "tok"
+guild_defaults:
+  officer_channel: "#officers"
+`,
+			wantErr: true,
+		},
+		{
+			name: "guild timezone",
+			yaml: `
+discord:
+  token: "tok"
+guild_defaults:
+  timezone: "Europe/Copenhagen"
+`,
+			check: func(t *testing.T, cfg *config.Config) {
+				t.Helper()
+				if cfg.GuildDefaults.Timezone != "Europe/Copenhagen" {
+					t.Errorf("timezone = %q, want Europe/Copenhagen", cfg.GuildDefaults.Timezone)
+				}
+			},
+		},
+		{
+			name: "unknown timezone rejected",
+			yaml: `
+discord:
+  token: "tok"
+guild_defaults:
+  timezone: "Local"
+`,
+			wantErr: true,
+		},
+		{
+			name: "loot mode",
+			yaml: `
+discord:
+  token: "tok"
+guild_defaults:
+  loot_mode: fixed_price
+`,
+			check: func(t *testing.T, cfg *config.Config) {
+				t.Helper()
+				if cfg.GuildDefaults.LootMode != config.LootModeFixedPrice {
+					t.Errorf("loot_mode = %q, want fixed_price", cfg.GuildDefaults.LootMode)
+				}
+			},
+		},
+		{
+			name: "unknown loot mode rejected",
+			yaml: `
+discord:
+  token: "tok"
+guild_defaults:
+  loot_mode: dice
+`,
+			wantErr: true,
+		},
+		{
+			name: "announcement templates",
+			yaml: `
+discord:
+  token: "tok"
+guild_defaults:
+  winner_message: "**{winner}** gewinnt {item} für {amount} {currency}!"
+`,
+			check: func(t *testing.T, cfg *config.Config) {
+				t.Helper()
+				if cfg.GuildDefaults.WinnerMessage != "**{winner}** gewinnt {item} für {amount} {currency}!" || cfg.GuildDefaults.NoBidsMessage != "Auction `{id}` closed with no bids." {
+					t.Errorf("guild defaults = %+v, want the winner message changed and the others kept", cfg.GuildDefaults)
+				}
 			},
 		},
+		{
+			name: "unknown template placeholder rejected",
+			yaml: `
+discord:
+  token: "tok"
+guild_defaults:
+  no_bids_message: "Nobody wanted {item}, {winner}"
+`,
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -148,6 +874,49 @@ func TestLoad_FileNotFound(t *testing.T) {
 	}
 }
 
+func TestLoadProfile(t *testing.T) {
+	t.Setenv("DKPBOT_DISCORD_TOKEN", "env-token")
+
+	t.Run("missing file is fine with a profile", func(t *testing.T) {
+		cfg, err := config.LoadProfile(filepath.Join(t.TempDir(), "config.yaml"), config.ProfileDockerPostgres)
+		if err != nil {
+			t.Fatalf("LoadProfile() error = %v", err)
+		}
+		if cfg.Discord.Token != "env-token" || cfg.Database.Host != "postgres" || cfg.Telemetry.Exporter != config.TelemetryExporterStderr {
+			t.Errorf("config = token %q, host %q, exporter %q, want the profile with the token from the environment",
+				cfg.Discord.Token, cfg.Database.Host, cfg.Telemetry.Exporter)
+		}
+	})
+
+	t.Run("file overrides the profile", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte("leader_election:\n  standby: false\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := config.LoadProfile(path, config.ProfileKubernetesHA)
+		if err != nil {
+			t.Fatalf("LoadProfile() error = %v", err)
+		}
+		if !cfg.LeaderElection.Enabled || cfg.LeaderElection.Standby {
+			t.Errorf("leader election = %+v, want enabled by the profile without standby", cfg.LeaderElection)
+		}
+	})
+
+	t.Run("every profile is valid", func(t *testing.T) {
+		for _, name := range config.Profiles() {
+			if _, err := config.LoadProfile(filepath.Join(t.TempDir(), "config.yaml"), name); err != nil {
+				t.Errorf("LoadProfile(%q) error = %v", name, err)
+			}
+		}
+	})
+
+	t.Run("unknown profile rejected", func(t *testing.T) {
+		if _, err := config.LoadProfile(filepath.Join(t.TempDir(), "config.yaml"), "solo"); err == nil || !strings.Contains(err.Error(), "solo-binary") {
+			t.Errorf("LoadProfile() error = %v, want one listing the profiles", err)
+		}
+	})
+}
+
 func TestLoad_EnvVarExpansion(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -187,13 +956,15 @@ database:
 			name: "unset var expands to empty",
 			yaml: `
 discord:
-  token: ${UNSET_VAR_12345}
+  token: "literal-token"
+database:
+  user: ${UNSET_VAR_12345}
 `,
 			env: map[string]string{},
 			check: func(t *testing.T, cfg *config.Config) {
 				t.Helper()
-				if cfg.Discord.Token != "" {
-					t.Errorf("token = %q, want empty", cfg.Discord.Token)
+				if cfg.Database.User != "" {
+					t.Errorf("db user = %q, want empty", cfg.Database.User)
 				}
 			},
 		},
@@ -261,6 +1032,56 @@ discord:
 	}
 }
 
+func TestLeaderboardConfig_Next(t *testing.T) {
+	l := config.LeaderboardConfig{Weekday: "Monday", Time: "18:00"}
+	copenhagen, err := time.LoadLocation("Europe/Copenhagen")
+	if err != nil {
+		t.Skipf("no timezone database: %v", err)
+	}
+	tests := []struct {
+		name string
+		t    time.Time
+		loc  *time.Location
+		want time.Time
+	}{
+		{"later that week", time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC), time.UTC, time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC)},
+		{"later that day", time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), time.UTC, time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC)},
+		{"at posting time", time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC), time.UTC, time.Date(2026, 10, 26, 18, 0, 0, 0, time.UTC)},
+		{"in another zone", time.Date(2026, 10, 19, 20, 30, 0, 0, time.FixedZone("CEST", 2*60*60)), time.UTC, time.Date(2026, 10, 26, 18, 0, 0, 0, time.UTC)},
+		{"in the guild's timezone", time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), copenhagen, time.Date(2026, 10, 19, 16, 0, 0, 0, time.UTC)},
+		{"across a change of daylight saving time", time.Date(2026, 10, 19, 17, 0, 0, 0, time.UTC), copenhagen, time.Date(2026, 10, 26, 17, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := l.Next(tt.t, tt.loc); !got.Equal(tt.want) {
+				t.Errorf("Next(%s, %s) = %s, want %s", tt.t, tt.loc, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKey_HasScope(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   config.APIKey
+		scope string
+		want  bool
+	}{
+		{name: "no scopes is read-only", key: config.APIKey{}, scope: config.ScopeRead, want: true},
+		{name: "no scopes cannot write", key: config.APIKey{}, scope: config.ScopeDKPWrite, want: false},
+		{name: "granted scope", key: config.APIKey{Scopes: []string{config.ScopeDKPWrite}}, scope: config.ScopeDKPWrite, want: true},
+		{name: "explicit scopes replace read", key: config.APIKey{Scopes: []string{config.ScopeDKPWrite}}, scope: config.ScopeRead, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.HasScope(tt.scope); got != tt.want {
+				t.Errorf("HasScope(%q) = %v, want %v", tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestDatabaseConfig_DSN(t *testing.T) {
 	cfg := config.DatabaseConfig{
 		Host:     "localhost",
@@ -275,3 +1096,192 @@ func TestDatabaseConfig_DSN(t *testing.T) {
 		t.Errorf("DSN() = %q, want %q", got, want)
 	}
 }
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg := config.Config{
+		Discord:  config.DiscordConfig{Token: "bot-token", GuildID: "123"},
+		Database: config.DatabaseConfig{User: "dkp", Password: "hunter2"},
+		API: config.APIConfig{Keys: []config.APIKey{
+			{Name: "web", Key: "k1", Scopes: []string{"read"}},
+			{Name: "empty"},
+		}},
+	}
+
+	got := cfg.Redacted()
+
+	if got.Discord.Token != "REDACTED" || got.Database.Password != "REDACTED" || got.API.Keys[0].Key != "REDACTED" {
+		t.Errorf("secrets not redacted: %+v", got)
+	}
+	if got.API.Keys[1].Key != "" {
+		t.Errorf("empty key = %q, want it left empty", got.API.Keys[1].Key)
+	}
+	if got.Discord.GuildID != "123" || got.Database.User != "dkp" || got.API.Keys[0].Name != "web" {
+		t.Errorf("non-secret fields changed: %+v", got)
+	}
+	if cfg.Discord.Token != "bot-token" || cfg.API.Keys[0].Key != "k1" {
+		t.Error("Redacted modified the original config")
+	}
+}
+
+func TestLoad_EnvOverrides(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "db-password")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	const yaml = `
+discord:
+  token: "yaml-token"
+server:
+  port: 9090
+api:
+  enabled: true
+  keys:
+    - name: "website"
+      key: "yaml-key"
+`
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+		check   func(t *testing.T, cfg *config.Config)
+	}{
+		{
+			name: "yaml values kept without overrides",
+			check: func(t *testing.T, cfg *config.Config) {
+				t.Helper()
+				if cfg.Discord.Token != "yaml-token" || cfg.Server.Port != 9090 {
+					t.Errorf("token = %q, port = %d, want YAML values", cfg.Discord.Token, cfg.Server.Port)
+				}
+			},
+		},
+		{
+			name: "scalars, lists, maps, and slice entries",
+			env: map[string]string{
+				"DKPBOT_DISCORD_TOKEN":                   "env-token",
+				"DKPBOT_SERVER_PORT":                     "8181",
+				"DKPBOT_LEADER_ELECTION_ENABLED":         "true",
+				"DKPBOT_LEADER_ELECTION_LEASE_DURATION":  "30s",
+				"DKPBOT_LEADER_ELECTION_BACKEND":         "redis",
+				"DKPBOT_LEADER_ELECTION_REDIS_ADDRESSES": "redis-0:6379, redis-1:6379",
+				"DKPBOT_TELEMETRY_SAMPLING_COMMANDS":     "dkp=0.1,bid=1",
+				"DKPBOT_API_KEYS_0_KEY":                  "env-key",
+			},
+			check: func(t *testing.T, cfg *config.Config) {
+				t.Helper()
+				if cfg.Discord.Token != "env-token" || cfg.Server.Port != 8181 {
+					t.Errorf("token = %q, port = %d, want env values", cfg.Discord.Token, cfg.Server.Port)
+				}
+				le := cfg.LeaderElection
+				if !le.Enabled || le.LeaseDuration != 30*time.Second || len(le.Redis.Addresses) != 2 || le.Redis.Addresses[1] != "redis-1:6379" {
+					t.Errorf("leader election = %+v", le)
+				}
+				if cfg.Telemetry.Sampling.Commands["dkp"] != 0.1 || cfg.Telemetry.Sampling.Commands["bid"] != 1 {
+					t.Errorf("sampling commands = %v", cfg.Telemetry.Sampling.Commands)
+				}
+				if k := cfg.API.Keys[0]; k.Name != "website" || k.Key != "env-key" {
+					t.Errorf("api key = %+v, want website with env-key", k)
+				}
+			},
+		},
+		{
+			name: "secret from file",
+			env:  map[string]string{"DKPBOT_DATABASE_PASSWORD_FILE": secretFile},
+			check: func(t *testing.T, cfg *config.Config) {
+				t.Helper()
+				if cfg.Database.Password != "from-file" {
+					t.Errorf("db password = %q, want %q", cfg.Database.Password, "from-file")
+				}
+			},
+		},
+		{
+			name:    "secret set twice",
+			env:     map[string]string{"DKPBOT_DATABASE_PASSWORD": "x", "DKPBOT_DATABASE_PASSWORD_FILE": secretFile},
+			wantErr: true,
+		},
+		{
+			name:    "missing secret file",
+			env:     map[string]string{"DKPBOT_DISCORD_TOKEN_FILE": filepath.Join(t.TempDir(), "missing")},
+			wantErr: true,
+		},
+		{
+			name:    "invalid integer",
+			env:     map[string]string{"DKPBOT_SERVER_PORT": "http"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := config.Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, cfg)
+			}
+		})
+	}
+}
+
+func TestLoad_ReportsAllProblems(t *testing.T) {
+	const yaml = `
+discord:
+  guild_id: "my-guild"
+  command_prefix: "dkp "
+  timeouts:
+    default: 1h
+server:
+  port: 70000
+database:
+  sslmode: "sometimes"
+leader_election:
+  standby: true
+api:
+  enabled: true
+  keys:
+    - name: "website"
+      key: "k1"
+    - name: "website"
+      key: "k2"
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := config.Load(path)
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Load() error = %v, want *config.ValidationError", err)
+	}
+	got := make(map[string]bool)
+	for _, p := range verr.Problems {
+		got[p.Field] = true
+	}
+	for _, field := range []string{
+		"discord.token",
+		"discord.guild_id",
+		"discord.command_prefix",
+		"discord.timeouts.default",
+		"server.port",
+		"database.sslmode",
+		"leader_election.standby",
+		"api.keys[1].name",
+	} {
+		if !got[field] {
+			t.Errorf("no problem reported for %s in:\n%v", field, err)
+		}
+	}
+	if len(verr.Problems) != 8 {
+		t.Errorf("got %d problems, want 8:\n%v", len(verr.Problems), err)
+	}
+}
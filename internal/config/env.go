@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix prefixes the environment variables that override config
+// fields.
+const EnvPrefix = "DKPBOT"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv overrides fields of cfg from environment variables named after
+// their YAML path: discord.token is DKPBOT_DISCORD_TOKEN, and the key of
+// the first API key is DKPBOT_API_KEYS_0_KEY. Lists are comma separated and
+// maps are given as k=v pairs, for example
+// DKPBOT_TELEMETRY_SAMPLING_COMMANDS=dkp=0.1,bid=1. Empty variables are
+// ignored.
+//
+// Fields tagged secret:"true" can instead be read from the file named by
+// the variable with a _FILE suffix, as with Docker and Kubernetes secrets.
+func applyEnv(cfg *Config, getenv func(string) string) error {
+	return envStruct(reflect.ValueOf(cfg).Elem(), EnvPrefix, getenv)
+}
+
+func envStruct(v reflect.Value, prefix string, getenv func(string) string) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + "_" + strings.ToUpper(name)
+		if err := envValue(v.Field(i), key, f.Tag.Get("secret") == "true", getenv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func envValue(v reflect.Value, key string, secret bool, getenv func(string) string) error {
+	switch {
+	case v.Kind() == reflect.Struct:
+		return envStruct(v, key, getenv)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		// Entries are listed in YAML; the environment can only override
+		// their fields, such as a secret key.
+		for i := range v.Len() {
+			if err := envStruct(v.Index(i), key+"_"+strconv.Itoa(i), getenv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	raw, err := lookup(key, secret, getenv)
+	if err != nil || raw == "" {
+		return err
+	}
+	if err := setValue(v, raw); err != nil {
+		return fmt.Errorf("environment variable %s: %w", key, err)
+	}
+	return nil
+}
+
+// lookup returns the value of key, or for secrets the contents of the file
+// named by key_FILE, without the trailing newline most editors add.
+func lookup(key string, secret bool, getenv func(string) string) (string, error) {
+	val := getenv(key)
+	if !secret {
+		return val, nil
+	}
+	path := getenv(key + "_FILE")
+	if path == "" {
+		return val, nil
+	}
+	if val != "" {
+		return "", fmt.Errorf("environment variables %s and %s_FILE are both set", key, key)
+	}
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("environment variable %s_FILE: %w", key, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		items := strings.Split(raw, ",")
+		s := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(s.Index(i), strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		for _, pair := range strings.Split(raw, ",") {
+			k, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("map entry %q is not key=value", pair)
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(elem, strings.TrimSpace(val)); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(k)), elem)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
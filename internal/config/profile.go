@@ -0,0 +1,61 @@
+package config
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// Profiles of common deployments, selected with dkpbot -profile.
+const (
+	// ProfileSoloBinary runs the bot from a downloaded binary against a
+	// Postgres on the same machine, logging to stderr.
+	ProfileSoloBinary = "solo-binary"
+	// ProfileDockerPostgres runs the bot in a container next to the
+	// postgres service of a Compose file, keeping its files on a volume
+	// at /var/lib/dkpbot.
+	ProfileDockerPostgres = "docker-postgres"
+	// ProfileKubernetesHA runs replicas on Kubernetes, with a Lease
+	// electing the one running the bot and warm standbys, and telemetry
+	// sent to an OTLP collector.
+	ProfileKubernetesHA = "kubernetes-ha"
+)
+
+// profiles change the built-in defaults for a deployment. The config file
+// and DKPBOT_* variables still override what they set.
+var profiles = map[string]func(*Config){
+	ProfileSoloBinary: func(c *Config) {
+		c.Database.User = "dkpbot"
+		c.Database.DBName = "dkpbot"
+		c.Telemetry.Exporter = TelemetryExporterStderr
+	},
+	ProfileDockerPostgres: func(c *Config) {
+		c.Database.Host = "postgres"
+		c.Database.User = "dkpbot"
+		c.Database.DBName = "dkpbot"
+		c.Telemetry.Exporter = TelemetryExporterStderr
+		c.Telemetry.Prometheus.Enabled = true
+		c.DeadLetter.Path = "/var/lib/dkpbot/deadletter.json"
+		c.EventBus.SpillDir = "/var/lib/dkpbot/spill"
+	},
+	ProfileKubernetesHA: func(c *Config) {
+		c.Database.User = "dkpbot"
+		c.Database.DBName = "dkpbot"
+		c.Database.SSLMode = "require"
+		c.LeaderElection.Enabled = true
+		c.LeaderElection.Backend = LeaderBackendKubernetes
+		c.LeaderElection.Standby = true
+		c.Telemetry.Exporter = TelemetryExporterOTLP
+		c.Telemetry.Prometheus.Enabled = true
+		// Replicas drain their commands and jobs before handing over the
+		// lease.
+		c.Server.ShutdownTimeout = 30 * time.Second
+		c.DeadLetter.Path = "/var/lib/dkpbot/deadletter.json"
+		c.EventBus.SpillDir = "/var/lib/dkpbot/spill"
+	},
+}
+
+// Profiles returns the names of the profiles, sorted.
+func Profiles() []string {
+	return slices.Sorted(maps.Keys(profiles))
+}
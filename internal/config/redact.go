@@ -0,0 +1,40 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// redacted replaces secret values in printed configs.
+const redacted = "REDACTED"
+
+// Redacted returns a copy of c with every non-empty field tagged
+// secret:"true" replaced by "REDACTED", for printing.
+func (c Config) Redacted() Config {
+	redactStruct(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+func redactStruct(v reflect.Value) {
+	t := v.Type()
+	for i := range t.NumField() {
+		f := v.Field(i)
+		switch {
+		case f.Kind() == reflect.Struct:
+			redactStruct(f)
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Struct:
+			// Copy the entries so that the original config keeps its
+			// secrets.
+			s := reflect.MakeSlice(f.Type(), f.Len(), f.Len())
+			reflect.Copy(s, f)
+			for j := range s.Len() {
+				redactStruct(s.Index(j))
+			}
+			f.Set(s)
+		case f.Kind() == reflect.String && t.Field(i).Tag.Get("secret") == "true":
+			if strings.TrimSpace(f.String()) != "" {
+				f.SetString(redacted)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FieldError reports an invalid config field.
+type FieldError struct {
+	// Field is the YAML path of the field, such as "server.port".
+	Field string
+	Msg   string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Msg
+}
+
+// ValidationError lists every problem found in a config, so that they can
+// all be fixed at once.
+type ValidationError struct {
+	Problems []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d problems:", len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(p.Error())
+	}
+	return b.String()
+}
+
+// problems collects FieldErrors during validation.
+type problems struct {
+	errs []*FieldError
+}
+
+func (p *problems) add(field, format string, args ...any) {
+	p.errs = append(p.errs, &FieldError{Field: field, Msg: fmt.Sprintf(format, args...)})
+}
+
+// err returns a *ValidationError holding the collected problems, or nil.
+func (p *problems) err() error {
+	if len(p.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: p.errs}
+}
+
+func validatePort(p *problems, field string, port int) {
+	if port < 1 || port > 65535 {
+		p.add(field, "must be between 1 and 65535, got %d", port)
+	}
+}
+
+// isSnowflake reports whether id looks like a Discord ID: a decimal 64-bit
+// unsigned integer.
+func isSnowflake(id string) bool {
+	_, err := strconv.ParseUint(id, 10, 64)
+	return err == nil
+}
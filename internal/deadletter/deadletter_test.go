@@ -0,0 +1,231 @@
+package deadletter_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/deadletter"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// errRefused is the error of a database that is down.
+var errRefused = &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+// flakyStore fails every Append with err, or errRefused if err is nil,
+// while down is set.
+type flakyStore struct {
+	down   bool
+	err    error
+	events []event.Event
+}
+
+func (f *flakyStore) Append(_ context.Context, events ...event.Event) error {
+	if f.down {
+		if f.err != nil {
+			return f.err
+		}
+		return errRefused
+	}
+	f.events = append(f.events, events...)
+	return nil
+}
+
+func (f *flakyStore) Load(_ context.Context, _ string) ([]event.Event, error) { return f.events, nil }
+
+func (f *flakyStore) LoadByType(_ context.Context, _ event.Type) ([]event.Event, error) {
+	return f.events, nil
+}
+
+func (f *flakyStore) Query(_ context.Context, q event.Query) ([]event.Event, error) {
+	return q.Filter(f.events), nil
+}
+
+var now = time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+
+const maxAttempts = 3
+
+func newStore(t *testing.T, inner event.Store) (*deadletter.Store, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dlq", "deadletter.json")
+	q, err := deadletter.OpenQueue(path)
+	if err != nil {
+		t.Fatalf("OpenQueue() error = %v", err)
+	}
+	return deadletter.NewStore(inner, q, maxAttempts, clock.Mock{T: now}, slog.Default(), noop.NewTracerProvider()), path
+}
+
+func TestStore_QueuesFailedAppends(t *testing.T) {
+	inner := &flakyStore{down: true}
+	s, path := newStore(t, inner)
+	ctx := event.WithActor(context.Background(), "officer-1")
+
+	if err := s.Append(ctx, event.Event{AggregateID: "a1", Type: event.AuctionStarted, Version: 1}); err != nil {
+		t.Fatalf("Append() error = %v, want nil when queued", err)
+	}
+
+	// The database is back, but the next event of the same auction must
+	// wait behind the queued one.
+	inner.down = false
+	if err := s.Append(ctx, event.Event{AggregateID: "a1", Type: event.AuctionBidPlaced, Version: 2}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if err := s.Append(ctx, event.Event{AggregateID: "p1", Type: event.DKPAwarded}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if len(inner.events) != 1 || inner.events[0].AggregateID != "p1" {
+		t.Fatalf("store has %v, want only the unrelated event", inner.events)
+	}
+
+	st := s.Status()
+	if st.Letters != 2 || st.Events != 2 || !st.Oldest.Equal(now) {
+		t.Errorf("Status() = %+v, want 2 letters queued at %s", st, now)
+	}
+
+	// The queue survives a restart.
+	q, err := deadletter.OpenQueue(path)
+	if err != nil {
+		t.Fatalf("reopening queue: %v", err)
+	}
+	if got := len(q.Letters()); got != 2 {
+		t.Fatalf("reopened queue has %d letters, want 2", got)
+	}
+
+	n, err := s.Retry(context.Background())
+	if err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Retry() persisted %d letters, want 2", n)
+	}
+	if len(inner.events) != 3 {
+		t.Fatalf("store has %d events, want 3", len(inner.events))
+	}
+	started, bid := inner.events[1], inner.events[2]
+	if started.Type != event.AuctionStarted || bid.Type != event.AuctionBidPlaced {
+		t.Errorf("retried out of order: %s then %s", started.Type, bid.Type)
+	}
+	if started.Actor != "officer-1" || !started.CreatedAt.Equal(now) {
+		t.Errorf("retried event actor=%q created=%s, want the original actor and time", started.Actor, started.CreatedAt)
+	}
+	if st := s.Status(); st.Letters != 0 {
+		t.Errorf("queue still has %d letters after retry", st.Letters)
+	}
+}
+
+func TestStore_RetryKeepsFailingLetters(t *testing.T) {
+	inner := &flakyStore{down: true}
+	s, _ := newStore(t, inner)
+
+	_ = s.Append(context.Background(), event.Event{AggregateID: "p1", Type: event.DKPAwarded})
+	n, err := s.Retry(context.Background())
+	if err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Retry() persisted %d letters, want 0", n)
+	}
+	st := s.Status()
+	if st.Letters != 1 || st.MaxAttempts != 1 || st.LastError != errRefused.Error() {
+		t.Errorf("Status() = %+v, want one letter with one failed attempt", st)
+	}
+}
+
+// fencedStore rejects every Append as coming from a superseded leader.
+type fencedStore struct{ flakyStore }
+
+func (f *fencedStore) Append(context.Context, ...event.Event) error {
+	return store.ErrFenced.Wrap(errors.New("holding fencing token 1, current is 2"))
+}
+
+func TestStore_DoesNotQueueFencedAppends(t *testing.T) {
+	s, _ := newStore(t, &fencedStore{})
+
+	err := s.Append(context.Background(), event.Event{AggregateID: "p1", Type: event.DKPAwarded})
+	if !errors.Is(err, store.ErrFenced) {
+		t.Fatalf("Append() error = %v, want ErrFenced", err)
+	}
+	if st := s.Status(); st.Letters != 0 {
+		t.Errorf("Status() = %+v, want nothing queued", st)
+	}
+}
+
+// sqlStateError mimics the driver errors that expose a SQLSTATE.
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "pq: " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestStore_DoesNotQueueRejectedAppends(t *testing.T) {
+	s, _ := newStore(t, &flakyStore{down: true, err: sqlStateError("23505")})
+
+	err := s.Append(context.Background(), event.Event{AggregateID: "p1", Type: event.DKPAwarded, Version: 1})
+	if err == nil || err.Error() != "pq: 23505" {
+		t.Fatalf("Append() error = %v, want the unique violation", err)
+	}
+	if st := s.Status(); st.Letters != 0 {
+		t.Errorf("Status() = %+v, want nothing queued", st)
+	}
+}
+
+func TestStore_RetryGivesUp(t *testing.T) {
+	inner := &flakyStore{down: true}
+	s, _ := newStore(t, inner)
+
+	_ = s.Append(context.Background(), event.Event{AggregateID: "p1", Type: event.DKPAwarded})
+	_ = s.Append(context.Background(), event.Event{AggregateID: "p1", Type: event.DKPDeducted})
+	_ = s.Append(context.Background(), event.Event{AggregateID: "p2", Type: event.DKPAwarded})
+	for range maxAttempts + 1 {
+		if _, err := s.Retry(context.Background()); err != nil {
+			t.Fatalf("Retry() error = %v", err)
+		}
+	}
+	st := s.Status()
+	if st.Letters != 3 || st.MaxAttempts != maxAttempts || st.GivenUp != 2 {
+		t.Fatalf("Status() = %+v, want the letters of p1 and p2 given up after %d attempts", st, maxAttempts)
+	}
+
+	// A letter given up on still holds back its aggregate until it is
+	// discarded.
+	inner.down = false
+	if n, err := s.Retry(context.Background()); err != nil || n != 0 {
+		t.Fatalf("Retry() = %d, %v, want nothing persisted", n, err)
+	}
+	for _, l := range s.GivenUp() {
+		if _, err := s.Discard(context.Background(), l.ID); err != nil {
+			t.Fatalf("Discard(%s) error = %v", l.ID, err)
+		}
+	}
+	if n, err := s.Retry(context.Background()); err != nil || n != 1 {
+		t.Fatalf("Retry() = %d, %v, want the deduction persisted", n, err)
+	}
+	if len(inner.events) != 1 || inner.events[0].Type != event.DKPDeducted {
+		t.Errorf("store has %v, want only the deduction", inner.events)
+	}
+	if _, err := s.Discard(context.Background(), "missing"); !errors.Is(err, deadletter.ErrLetterNotFound) {
+		t.Errorf("Discard(missing) error = %v, want ErrLetterNotFound", err)
+	}
+}
+
+func TestStore_RetryGivesUpOnRejection(t *testing.T) {
+	inner := &flakyStore{down: true}
+	s, _ := newStore(t, inner)
+
+	_ = s.Append(context.Background(), event.Event{AggregateID: "p1", Type: event.DKPAwarded, Version: 1})
+	inner.err = sqlStateError("23505")
+	if _, err := s.Retry(context.Background()); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+	if st := s.Status(); st.GivenUp != 1 || st.MaxAttempts != 1 {
+		t.Errorf("Status() = %+v, want the letter given up after one rejection", st)
+	}
+}
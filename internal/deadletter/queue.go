@@ -0,0 +1,139 @@
+// Package deadletter keeps domain events that could not be persisted and
+// retries them until the event store accepts them.
+//
+// Events are parked in a file on local disk rather than in the database,
+// since the failures it guards against are usually the database being
+// unavailable.
+package deadletter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// Letter is a batch of events from a single failed Append.
+type Letter struct {
+	ID          string        `json:"id"`
+	Events      []event.Event `json:"events"`
+	FirstFailed time.Time     `json:"first_failed"`
+	LastAttempt time.Time     `json:"last_attempt"`
+	Attempts    int           `json:"attempts"`
+	LastError   string        `json:"last_error"`
+	// Rejected is set once the store failed the letter with an error that
+	// retrying will not get past.
+	Rejected bool `json:"rejected,omitempty"`
+}
+
+// Queue is a FIFO of letters persisted to a JSON file. Every change is
+// written to a temporary file and renamed over the queue file, so a crash
+// leaves either the old or the new contents.
+// It is safe for concurrent use.
+type Queue struct {
+	mu      sync.Mutex
+	path    string
+	letters []Letter
+	nextID  int
+}
+
+// OpenQueue loads the queue stored at path, creating its directory if
+// needed. A missing file is an empty queue.
+func OpenQueue(path string) (*Queue, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("creating dead-letter directory: %w", err)
+	}
+	q := &Queue{path: path}
+	data, err := os.ReadFile(filepath.Clean(path))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return q, nil
+	case err != nil:
+		return nil, fmt.Errorf("reading dead-letter queue: %w", err)
+	}
+	if err := json.Unmarshal(data, &q.letters); err != nil {
+		return nil, fmt.Errorf("decoding dead-letter queue %s: %w", path, err)
+	}
+	q.nextID = len(q.letters)
+	return q, nil
+}
+
+// Push appends a letter and persists the queue.
+func (q *Queue) Push(l Letter) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nextID++
+	l.ID = fmt.Sprintf("%d-%d", l.FirstFailed.UnixNano(), q.nextID)
+	q.letters = append(q.letters, l)
+	if err := q.save(); err != nil {
+		q.letters = q.letters[:len(q.letters)-1]
+		return err
+	}
+	return nil
+}
+
+// Letters returns a copy of the queued letters, oldest first.
+func (q *Queue) Letters() []Letter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Letter(nil), q.letters...)
+}
+
+// Update replaces the letter with l's ID and persists the queue.
+func (q *Queue) Update(l Letter) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := range q.letters {
+		if q.letters[i].ID == l.ID {
+			q.letters[i] = l
+			return q.save()
+		}
+	}
+	return nil
+}
+
+// Remove deletes the letter with the given ID and persists the queue.
+func (q *Queue) Remove(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := range q.letters {
+		if q.letters[i].ID == id {
+			q.letters = append(q.letters[:i:i], q.letters[i+1:]...)
+			return q.save()
+		}
+	}
+	return nil
+}
+
+func (q *Queue) save() error {
+	data, err := json.Marshal(q.letters)
+	if err != nil {
+		return fmt.Errorf("encoding dead-letter queue: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), ".deadletter-*")
+	if err != nil {
+		return fmt.Errorf("writing dead-letter queue: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing dead-letter queue: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing dead-letter queue: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing dead-letter queue: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), q.path); err != nil {
+		return fmt.Errorf("replacing dead-letter queue: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// errQueuedBehind is recorded for events queued only because earlier events
+// of the same aggregate are still waiting to be persisted.
+var errQueuedBehind = errors.New("queued behind earlier events of the same aggregate")
+
+// ErrLetterNotFound is returned by Discard for an ID that is not queued.
+var ErrLetterNotFound = derrors.New(derrors.NotFound, "LETTER_NOT_FOUND", "no queued letter has this ID")
+
+// Store decorates an event.Store so that events whose Append fails are
+// parked in a Queue and retried in the background instead of being lost.
+//
+// Events of an aggregate with queued letters are queued behind them, so that
+// each aggregate's events reach the store in order.
+//
+// A letter is retried until the store rejects it with an error that is not
+// transient or it has been tried maxAttempts times. It then stays queued,
+// holding back its aggregates, until an operator discards it.
+type Store struct {
+	event.Store
+
+	// mu serializes appends so that the check for queued letters and the
+	// append or push that follows it are atomic.
+	mu          sync.Mutex
+	queue       *Queue
+	maxAttempts int
+	clock       clock.Clock
+	logger      *slog.Logger
+	tracer      trace.Tracer
+}
+
+// NewStore wraps s so that failed appends are queued in q and retried at
+// most maxAttempts times.
+func NewStore(s event.Store, q *Queue, maxAttempts int, clk clock.Clock, logger *slog.Logger, tp trace.TracerProvider) *Store {
+	return &Store{
+		Store:       s,
+		queue:       q,
+		maxAttempts: maxAttempts,
+		clock:       clk,
+		logger:      logger,
+		tracer:      tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/deadletter"),
+	}
+}
+
+// Append persists events via the underlying store. If that fails with a
+// transient error, such as a lost connection or a timeout, or if the events
+// must wait behind queued events of the same aggregate, they are queued
+// instead and Append succeeds. Other errors, such as a version the store
+// already holds or store.ErrFenced on a replica fenced off by a newer
+// leader, are returned, since retrying would fail the same way or overwrite
+// newer state. Append also fails if the queue cannot be written.
+func (s *Store) Append(ctx context.Context, events ...event.Event) error {
+	// Events the store would reject however often they are retried are
+	// not queued.
+	for _, e := range events {
+		if err := event.ValidateAggregateID(e.AggregateID); err != nil {
+			return err
+		}
+	}
+
+	// Capture what the store would otherwise take from the context, which
+	// is gone by the time a queued event is retried. The store fills in
+	// the copies, which are copied back once persisted.
+	appended := events
+	events = append([]event.Event(nil), events...)
+	for i := range events {
+		if events[i].Actor == "" {
+			events[i].Actor = event.ActorFromContext(ctx)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cause := errQueuedBehind
+	if !s.blocked(events) {
+		err := s.Store.Append(ctx, events...)
+		if err == nil {
+			copy(appended, events)
+		}
+		if err == nil || !store.Transient(err) {
+			return err
+		}
+		cause = err
+	}
+
+	now := s.clock.Now()
+	for i := range events {
+		if events[i].CreatedAt.IsZero() {
+			events[i].CreatedAt = now
+		}
+	}
+	if err := s.queue.Push(Letter{Events: events, FirstFailed: now, LastError: cause.Error()}); err != nil {
+		return errors.Join(cause, err)
+	}
+	s.logger.WarnContext(ctx, "event append failed, queued for retry",
+		slog.Int("events", len(events)),
+		slog.Any("error", cause),
+	)
+	return nil
+}
+
+// blocked reports whether any of events belongs to an aggregate with
+// queued letters.
+func (s *Store) blocked(events []event.Event) bool {
+	letters := s.queue.Letters()
+	if len(letters) == 0 {
+		return false
+	}
+	queued := make(map[string]bool)
+	for _, l := range letters {
+		for _, e := range l.Events {
+			queued[e.AggregateID] = true
+		}
+	}
+	for _, e := range events {
+		if queued[e.AggregateID] {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry tries to persist every queued letter that has not been given up on,
+// oldest first, and returns how many were persisted. Once a letter fails,
+// later letters touching the same aggregates are skipped until the next run.
+// A letter that fails with an error that is not transient is given up on
+// at once.
+func (s *Store) Retry(ctx context.Context) (int, error) {
+	ctx, span := s.tracer.Start(ctx, "Store.Retry")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	persisted := 0
+	failed := make(map[string]bool)
+	for _, l := range s.queue.Letters() {
+		if touches(l, failed) || s.givenUp(l) {
+			for _, e := range l.Events {
+				failed[e.AggregateID] = true
+			}
+			continue
+		}
+		l.Attempts++
+		l.LastAttempt = s.clock.Now()
+		if err := s.Store.Append(ctx, l.Events...); err != nil {
+			l.LastError = err.Error()
+			l.Rejected = !store.Transient(err)
+			for _, e := range l.Events {
+				failed[e.AggregateID] = true
+			}
+			if s.givenUp(l) {
+				s.logger.ErrorContext(ctx, "giving up on queued events",
+					slog.String("letter_id", l.ID),
+					slog.Int("events", len(l.Events)),
+					slog.Int("attempts", l.Attempts),
+					slog.Any("error", err),
+				)
+			}
+			if err := s.queue.Update(l); err != nil {
+				return persisted, err
+			}
+			continue
+		}
+		if err := s.queue.Remove(l.ID); err != nil {
+			return persisted, err
+		}
+		persisted++
+	}
+	span.SetAttributes(attribute.Int("persisted", persisted))
+	return persisted, nil
+}
+
+// givenUp reports whether l is no longer retried.
+func (s *Store) givenUp(l Letter) bool {
+	return l.Rejected || l.Attempts >= s.maxAttempts
+}
+
+// GivenUp returns the queued letters that are no longer retried, oldest
+// first.
+func (s *Store) GivenUp() []Letter {
+	var letters []Letter
+	for _, l := range s.queue.Letters() {
+		if s.givenUp(l) {
+			letters = append(letters, l)
+		}
+	}
+	return letters
+}
+
+// Discard removes the queued letter with the given ID and returns it. Its
+// events are never persisted; later events of its aggregates are retried
+// without it.
+func (s *Store) Discard(ctx context.Context, id string) (Letter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.queue.Letters(), func(l Letter) bool { return l.ID == id })
+	if i < 0 {
+		return Letter{}, ErrLetterNotFound
+	}
+	l := s.queue.Letters()[i]
+	if err := s.queue.Remove(id); err != nil {
+		return Letter{}, err
+	}
+	s.logger.WarnContext(ctx, "discarded queued events",
+		slog.String("letter_id", l.ID),
+		slog.Int("events", len(l.Events)),
+		slog.String("last_error", l.LastError),
+	)
+	return l, nil
+}
+
+func touches(l Letter, aggregates map[string]bool) bool {
+	for _, e := range l.Events {
+		if aggregates[e.AggregateID] {
+			return true
+		}
+	}
+	return false
+}
+
+// Run retries queued letters every interval until ctx is canceled.
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if len(s.queue.Letters()) == 0 {
+			continue
+		}
+		n, err := s.Retry(ctx)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "dead-letter retry failed", slog.Any("error", err))
+		}
+		if n > 0 {
+			s.logger.InfoContext(ctx, "persisted queued events",
+				slog.Int("letters", n),
+				slog.Int("remaining", len(s.queue.Letters())),
+			)
+		}
+	}
+}
+
+// Status summarizes the queue.
+type Status struct {
+	Letters int
+	Events  int
+	// Oldest is when the oldest queued letter first failed.
+	Oldest time.Time
+	// MaxAttempts is the highest retry count of any queued letter.
+	MaxAttempts int
+	// GivenUp is how many of the letters are no longer retried.
+	GivenUp int
+	// LastError is the most recent error of the oldest letter.
+	LastError string
+}
+
+// Status returns a summary of the queued letters.
+func (s *Store) Status() Status {
+	letters := s.queue.Letters()
+	st := Status{Letters: len(letters)}
+	for i, l := range letters {
+		if i == 0 {
+			st.Oldest = l.FirstFailed
+			st.LastError = l.LastError
+		}
+		st.Events += len(l.Events)
+		st.MaxAttempts = max(st.MaxAttempts, l.Attempts)
+		if s.givenUp(l) {
+			st.GivenUp++
+		}
+	}
+	return st
+}
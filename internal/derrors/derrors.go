@@ -0,0 +1,112 @@
+// Package derrors classifies domain errors. Managers and repositories wrap
+// failures in an *Error carrying a Kind and a short, stable code, so that
+// Discord handlers and the REST API can show users a friendly message and a
+// code they can report, while the underlying cause stays in logs and traces.
+package derrors
+
+import (
+	"errors"
+)
+
+// Kind is the category of an error.
+type Kind uint8
+
+// Error kinds. Errors that are not classified are Internal.
+const (
+	Internal Kind = iota
+	Validation
+	NotFound
+	Permission
+	Conflict
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Validation:
+		return "validation"
+	case NotFound:
+		return "not_found"
+	case Permission:
+		return "permission"
+	case Conflict:
+		return "conflict"
+	default:
+		return "internal"
+	}
+}
+
+// CodeInternal is reported for errors that carry no classification.
+const CodeInternal = "INTERNAL"
+
+// Error is a classified error.
+type Error struct {
+	Kind Kind
+	// Code is a short identifier users can quote in bug reports, for
+	// example "AUCTION_CLOSED".
+	Code string
+	// Message is safe to show to users.
+	Message string
+	// Err is the underlying cause. It is never shown to users.
+	Err error
+}
+
+// New returns a classified error without an underlying cause. It is
+// typically used for package-level sentinels.
+func New(kind Kind, code, msg string) *Error {
+	return &Error{Kind: kind, Code: code, Message: msg}
+}
+
+// Wrap returns a copy of e with err as its cause. The copy still matches e
+// under errors.Is.
+func (e *Error) Wrap(err error) error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying cause.
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is reports whether target is an *Error with the same code, so that a
+// wrapped copy matches its sentinel.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// KindOf returns the kind of the outermost *Error in err's chain, or
+// Internal if there is none.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return Internal
+}
+
+// CodeOf returns the code of the outermost *Error in err's chain, or
+// CodeInternal if there is none.
+func CodeOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeInternal
+}
+
+// MessageOf returns the user-facing message for err. Unclassified errors
+// yield a generic message so that internal details are not leaked.
+func MessageOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) && e.Kind != Internal {
+		return e.Message
+	}
+	return "something went wrong"
+}
@@ -0,0 +1,82 @@
+package derrors_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+)
+
+var errClosed = derrors.New(derrors.Conflict, "AUCTION_CLOSED", "auction is closed")
+
+func TestClassification(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantKind    derrors.Kind
+		wantCode    string
+		wantMessage string
+	}{
+		{
+			name:        "sentinel",
+			err:         errClosed,
+			wantKind:    derrors.Conflict,
+			wantCode:    "AUCTION_CLOSED",
+			wantMessage: "auction is closed",
+		},
+		{
+			name:        "wrapped with fmt",
+			err:         fmt.Errorf("placing bid: %w", errClosed),
+			wantKind:    derrors.Conflict,
+			wantCode:    "AUCTION_CLOSED",
+			wantMessage: "auction is closed",
+		},
+		{
+			name:        "sentinel wrapping a cause",
+			err:         errClosed.Wrap(errors.New("connection reset")),
+			wantKind:    derrors.Conflict,
+			wantCode:    "AUCTION_CLOSED",
+			wantMessage: "auction is closed",
+		},
+		{
+			name:        "unclassified is internal",
+			err:         errors.New("pq: connection refused"),
+			wantKind:    derrors.Internal,
+			wantCode:    derrors.CodeInternal,
+			wantMessage: "something went wrong",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := derrors.KindOf(tt.err); got != tt.wantKind {
+				t.Errorf("KindOf() = %s, want %s", got, tt.wantKind)
+			}
+			if got := derrors.CodeOf(tt.err); got != tt.wantCode {
+				t.Errorf("CodeOf() = %q, want %q", got, tt.wantCode)
+			}
+			if got := derrors.MessageOf(tt.err); got != tt.wantMessage {
+				t.Errorf("MessageOf() = %q, want %q", got, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestError_WrapKeepsIdentity(t *testing.T) {
+	cause := errors.New("no rows")
+	err := errClosed.Wrap(cause)
+
+	if !errors.Is(err, errClosed) {
+		t.Error("wrapped error does not match its sentinel")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("wrapped error does not match its cause")
+	}
+	if errClosed.Err != nil {
+		t.Error("Wrap modified the sentinel")
+	}
+	if got, want := err.Error(), "auction is closed: no rows"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+// Package discordtest provides a Discord session whose REST calls are
+// answered in memory, for testing code that posts to Discord without a
+// connection.
+package discordtest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Request is a REST call made through a Transport.
+type Request struct {
+	Method string
+	// Path is the path of the call, such as
+	// "/api/v9/channels/c1/messages".
+	Path string
+	Body string
+}
+
+// Transport answers every Discord REST call with 200 and an object with
+// the next ID, "1" for the first, which decodes as the message or channel
+// the call creates, and records the calls. It is safe for concurrent use.
+type Transport struct {
+	mu       sync.Mutex
+	requests []Request
+	// made, if set, is closed on the next call, to wake Await.
+	made chan struct{}
+}
+
+// NewSession returns a session whose REST calls go to the returned
+// Transport.
+func NewSession() (*discordgo.Session, *Transport) {
+	s, err := discordgo.New("Bot token")
+	if err != nil {
+		panic(err)
+	}
+	t := &Transport{}
+	s.Client = &http.Client{Transport: t}
+	return s, t
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	t.mu.Lock()
+	t.requests = append(t.requests, Request{Method: req.Method, Path: req.URL.Path, Body: string(body)})
+	id := len(t.requests)
+	if t.made != nil {
+		close(t.made)
+		t.made = nil
+	}
+	t.mu.Unlock()
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"id":"%d"}`, id))),
+		Request:    req,
+	}, nil
+}
+
+// Requests returns the calls made, in order.
+func (t *Transport) Requests() []Request {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Request(nil), t.requests...)
+}
+
+// Await waits until n calls have been made and returns them, for code that
+// posts from its own goroutine. It fails tb if they are not made within a
+// few seconds.
+func (t *Transport) Await(tb testing.TB, n int) []Request {
+	tb.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		t.mu.Lock()
+		if len(t.requests) >= n {
+			defer t.mu.Unlock()
+			return append([]Request(nil), t.requests...)
+		}
+		if t.made == nil {
+			t.made = make(chan struct{})
+		}
+		made := t.made
+		t.mu.Unlock()
+		select {
+		case <-made:
+		case <-timeout:
+			tb.Fatalf("%d Discord calls were made, want %d", len(t.Requests()), n)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package discordtest_test
+
+import (
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/discordtest"
+)
+
+func TestSession(t *testing.T) {
+	s, rt := discordtest.NewSession()
+	go func() {
+		ch, err := s.UserChannelCreate("d1")
+		if err != nil {
+			return
+		}
+		_, _ = s.ChannelMessageSend(ch.ID, "hello")
+	}()
+
+	reqs := rt.Await(t, 2)
+	if reqs[0].Path != "/api/v9/users/@me/channels" || reqs[1].Path != "/api/v9/channels/1/messages" {
+		t.Errorf("requests = %+v, want a DM channel and a message in it", reqs)
+	}
+}
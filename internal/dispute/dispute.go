@@ -0,0 +1,249 @@
+// Package dispute tracks the tickets members open with officers to dispute
+// loot or give feedback. Each dispute is discussed in a private thread and
+// stays open until an officer resolves it. A dispute may be anonymous, in
+// which case neither it nor its event records who opened it. Disputes are
+// kept as events in the event store.
+package dispute
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/ids"
+)
+
+// Errors returned by dispute operations.
+var (
+	ErrUnknownDispute  = derrors.New(derrors.NotFound, "UNKNOWN_DISPUTE", "no such dispute")
+	ErrNoReason        = derrors.New(derrors.Validation, "NO_DISPUTE_REASON", "say what the dispute is about")
+	ErrNoResolution    = derrors.New(derrors.Validation, "NO_RESOLUTION", "say how the dispute was resolved")
+	ErrDisputeResolved = derrors.New(derrors.Conflict, "DISPUTE_RESOLVED", "the dispute was already resolved")
+)
+
+// Dispute is a dispute as recorded in its events.
+type Dispute struct {
+	ID     string
+	Reason string
+	// AuctionID is the auction disputed, or empty for feedback.
+	AuctionID string
+	// OpenedBy is the Discord ID of the member who opened the dispute, or
+	// empty if Anonymous.
+	OpenedBy  string
+	Anonymous bool
+	OpenedAt  time.Time
+	// ThreadID is the private thread the dispute is discussed in.
+	ThreadID string
+	// Resolved is set once an officer resolved the dispute.
+	Resolved   bool
+	Resolution string
+	ResolvedBy string
+	ResolvedAt time.Time
+	Version    int
+}
+
+// Ticket describes a dispute to open.
+type Ticket struct {
+	Reason    string
+	AuctionID string
+	// OpenedBy is the Discord ID of the member opening the dispute. It is
+	// not recorded if Anonymous is set.
+	OpenedBy  string
+	Anonymous bool
+	ThreadID  string
+}
+
+// Service tracks disputes.
+type Service struct {
+	events event.Store
+	ids    ids.Generator
+	logger *slog.Logger
+	tracer trace.Tracer
+	clock  clock.Clock
+
+	// mu serializes changes, which version the dispute's events.
+	mu sync.Mutex
+}
+
+// NewService returns a Service that records disputes in events, identified
+// by IDs from gen.
+func NewService(events event.Store, gen ids.Generator, logger *slog.Logger, tp trace.TracerProvider, clk clock.Clock) *Service {
+	return &Service{
+		events: events,
+		ids:    gen,
+		logger: logger,
+		tracer: tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/dispute"),
+		clock:  clk,
+	}
+}
+
+// Open opens the dispute t.
+func (s *Service) Open(ctx context.Context, t Ticket) (*Dispute, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Open",
+		trace.WithAttributes(
+			attribute.String("auction.id", t.AuctionID),
+			attribute.Bool("anonymous", t.Anonymous),
+		),
+	)
+	defer span.End()
+
+	t.Reason = strings.TrimSpace(t.Reason)
+	if t.Reason == "" {
+		return nil, ErrNoReason
+	}
+	if t.Anonymous {
+		// The event must not tell who opened the dispute, not even as
+		// its actor.
+		t.OpenedBy = ""
+		ctx = event.WithActor(ctx, "")
+	}
+	d := &Dispute{
+		ID:        "dispute-" + s.ids.NewID(),
+		Reason:    t.Reason,
+		AuctionID: t.AuctionID,
+		OpenedBy:  t.OpenedBy,
+		Anonymous: t.Anonymous,
+		OpenedAt:  s.clock.Now(),
+		ThreadID:  t.ThreadID,
+	}
+	data, _ := json.Marshal(event.DisputeOpenedData{
+		Reason:    d.Reason,
+		AuctionID: d.AuctionID,
+		OpenedBy:  d.OpenedBy,
+		Anonymous: d.Anonymous,
+		ThreadID:  d.ThreadID,
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.append(ctx, d, event.DisputeOpened, data); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "dispute opened",
+		slog.String("dispute_id", d.ID),
+		slog.String("auction_id", d.AuctionID),
+		slog.Bool("anonymous", d.Anonymous),
+	)
+	return d, nil
+}
+
+// Get returns the dispute id.
+func (s *Service) Get(ctx context.Context, id string) (*Dispute, error) {
+	events, err := s.events.Load(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("loading dispute events: %w", err)
+	}
+	if len(events) == 0 || events[0].Type != event.DisputeOpened {
+		return nil, ErrUnknownDispute.Wrap(fmt.Errorf("dispute %s", id))
+	}
+	return replay(events)
+}
+
+// Unresolved returns the disputes not resolved yet, oldest first.
+func (s *Service) Unresolved(ctx context.Context) ([]*Dispute, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Unresolved")
+	defer span.End()
+
+	opened, err := s.events.LoadByType(ctx, event.DisputeOpened)
+	if err != nil {
+		return nil, fmt.Errorf("loading dispute opened events: %w", err)
+	}
+	var disputes []*Dispute
+	for _, e := range opened {
+		d, err := s.Get(ctx, e.AggregateID)
+		if err != nil {
+			return nil, err
+		}
+		if !d.Resolved {
+			disputes = append(disputes, d)
+		}
+	}
+	slices.SortFunc(disputes, func(a, b *Dispute) int { return a.OpenedAt.Compare(b.OpenedAt) })
+	return disputes, nil
+}
+
+// Resolve resolves the dispute id on behalf of the officer resolvedBy, with
+// resolution saying how.
+func (s *Service) Resolve(ctx context.Context, id, resolvedBy, resolution string) (*Dispute, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Resolve",
+		trace.WithAttributes(attribute.String("dispute.id", id)),
+	)
+	defer span.End()
+
+	resolution = strings.TrimSpace(resolution)
+	if resolution == "" {
+		return nil, ErrNoResolution
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Resolved {
+		return nil, ErrDisputeResolved
+	}
+	data, _ := json.Marshal(event.DisputeResolvedData{Resolution: resolution, ResolvedBy: resolvedBy})
+	if err := s.append(ctx, d, event.DisputeResolved, data); err != nil {
+		return nil, err
+	}
+	d.Resolved, d.Resolution, d.ResolvedBy, d.ResolvedAt = true, resolution, resolvedBy, s.clock.Now()
+
+	s.logger.InfoContext(ctx, "dispute resolved",
+		slog.String("dispute_id", d.ID),
+		slog.String("resolved_by", resolvedBy),
+	)
+	return d, nil
+}
+
+// append records an event of type t on d.
+func (s *Service) append(ctx context.Context, d *Dispute, t event.Type, data json.RawMessage) error {
+	e := event.Event{
+		AggregateID: d.ID,
+		Type:        t,
+		Data:        data,
+		Version:     d.Version + 1,
+	}
+	if err := s.events.Append(ctx, e); err != nil {
+		return fmt.Errorf("recording %s event: %w", t, err)
+	}
+	d.Version = e.Version
+	return nil
+}
+
+// replay rebuilds a dispute from its events, oldest first.
+func replay(events []event.Event) (*Dispute, error) {
+	d := &Dispute{ID: events[0].AggregateID}
+	for _, e := range events {
+		switch e.Type {
+		case event.DisputeOpened:
+			var o event.DisputeOpenedData
+			if err := json.Unmarshal(e.Data, &o); err != nil {
+				return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+			}
+			d.Reason, d.AuctionID, d.OpenedBy, d.Anonymous = o.Reason, o.AuctionID, o.OpenedBy, o.Anonymous
+			d.ThreadID, d.OpenedAt = o.ThreadID, e.CreatedAt
+		case event.DisputeResolved:
+			var r event.DisputeResolvedData
+			if err := json.Unmarshal(e.Data, &r); err != nil {
+				return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+			}
+			d.Resolved, d.Resolution, d.ResolvedBy, d.ResolvedAt = true, r.Resolution, r.ResolvedBy, e.CreatedAt
+		}
+		d.Version = e.Version
+	}
+	return d, nil
+}
@@ -0,0 +1,88 @@
+package dispute_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dispute"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/ids"
+)
+
+// actorStore records the actor of each event appended.
+type actorStore struct {
+	event.Store
+	actors []string
+}
+
+func (s *actorStore) Append(ctx context.Context, events ...event.Event) error {
+	for range events {
+		s.actors = append(s.actors, event.ActorFromContext(ctx))
+	}
+	return s.Store.Append(ctx, events...)
+}
+
+func TestService(t *testing.T) {
+	ctx := event.WithActor(context.Background(), "d1")
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	events := &actorStore{Store: eventtest.NewStore()}
+	s := dispute.NewService(events, ids.NewULID(clk), slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clk)
+
+	if _, err := s.Open(ctx, dispute.Ticket{Reason: " ", OpenedBy: "d1"}); !errors.Is(err, dispute.ErrNoReason) {
+		t.Errorf("Open() with no reason error = %v, want ErrNoReason", err)
+	}
+	loot, err := s.Open(ctx, dispute.Ticket{Reason: "Bid was ignored", AuctionID: "auction-1", OpenedBy: "d1", ThreadID: "thread-1"})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	feedback, err := s.Open(ctx, dispute.Ticket{Reason: "Bench rotation is unfair", OpenedBy: "d1", Anonymous: true, ThreadID: "thread-2"})
+	if err != nil {
+		t.Fatalf("Open() anonymous error = %v", err)
+	}
+	if feedback.OpenedBy != "" {
+		t.Errorf("anonymous OpenedBy = %q, want empty", feedback.OpenedBy)
+	}
+	if want := []string{"d1", ""}; len(events.actors) != 2 || events.actors[0] != want[0] || events.actors[1] != want[1] {
+		t.Errorf("event actors = %q, want %q", events.actors, want)
+	}
+
+	got, err := s.Get(ctx, loot.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Reason != "Bid was ignored" || got.AuctionID != "auction-1" || got.OpenedBy != "d1" || got.ThreadID != "thread-1" {
+		t.Errorf("Get() = %+v, want the dispute as opened", got)
+	}
+	if _, err := s.Get(ctx, "dispute-none"); !errors.Is(err, dispute.ErrUnknownDispute) {
+		t.Errorf("Get() unknown error = %v, want ErrUnknownDispute", err)
+	}
+
+	if _, err := s.Resolve(ctx, loot.ID, "officer", " "); !errors.Is(err, dispute.ErrNoResolution) {
+		t.Errorf("Resolve() with no resolution error = %v, want ErrNoResolution", err)
+	}
+	resolved, err := s.Resolve(ctx, loot.ID, "officer", "Bid refunded")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if !resolved.Resolved || resolved.Resolution != "Bid refunded" || resolved.ResolvedBy != "officer" {
+		t.Errorf("Resolve() = %+v, want it resolved by officer", resolved)
+	}
+	if _, err := s.Resolve(ctx, loot.ID, "officer", "Again"); !errors.Is(err, dispute.ErrDisputeResolved) {
+		t.Errorf("Resolve() twice error = %v, want ErrDisputeResolved", err)
+	}
+
+	open, err := s.Unresolved(ctx)
+	if err != nil {
+		t.Fatalf("Unresolved() error = %v", err)
+	}
+	if len(open) != 1 || open[0].ID != feedback.ID || !open[0].Anonymous || open[0].OpenedBy != "" {
+		t.Errorf("Unresolved() = %+v, want only the anonymous dispute", open)
+	}
+}
@@ -0,0 +1,84 @@
+package dkp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// ErrNotArchived is returned by Restore for a player who is not archived.
+var ErrNotArchived = derrors.New(derrors.Conflict, "NOT_ARCHIVED", "this player is not archived")
+
+// Archive archives the player registered as discordID, freezing their DKP
+// until they are restored, and returns them. Their history is kept. An
+// archived player cannot be archived again: that fails with
+// store.ErrPlayerArchived.
+func (m *Manager) Archive(ctx context.Context, discordID, reason string) (*store.Player, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.Archive",
+		trace.WithAttributes(attribute.String("discord_id", discordID)),
+	)
+	defer span.End()
+
+	return idempotency.Do(ctx, m.dedup, "dkp.archive", func(ctx context.Context) (*store.Player, error) {
+		return m.setArchived(ctx, discordID, true, reason)
+	})
+}
+
+// Restore restores the archived player registered as discordID, so that
+// their DKP may change again, and returns them.
+func (m *Manager) Restore(ctx context.Context, discordID string) (*store.Player, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.Restore",
+		trace.WithAttributes(attribute.String("discord_id", discordID)),
+	)
+	defer span.End()
+
+	return idempotency.Do(ctx, m.dedup, "dkp.restore", func(ctx context.Context) (*store.Player, error) {
+		return m.setArchived(ctx, discordID, false, "")
+	})
+}
+
+func (m *Manager) setArchived(ctx context.Context, discordID string, archived bool, reason string) (*store.Player, error) {
+	p, err := m.players.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case archived && p.Archived():
+		return nil, store.ErrPlayerArchived
+	case !archived && !p.Archived():
+		return nil, ErrNotArchived
+	}
+	if err := m.players.SetArchived(ctx, p.ID, archived); err != nil {
+		return nil, fmt.Errorf("archiving player: %w", err)
+	}
+
+	typ, msg := event.PlayerArchived, "player archived"
+	if !archived {
+		typ, msg = event.PlayerRestored, "player restored"
+	}
+	data, _ := json.Marshal(event.PlayerArchivedData{DiscordID: discordID, DKP: p.DKP, Reason: reason})
+	evt := event.Event{
+		AggregateID: p.ID,
+		Type:        typ,
+		Data:        data,
+		Version:     0,
+	}
+	if err := m.events.Append(ctx, evt); err != nil {
+		m.logger.ErrorContext(ctx, "failed to append player archive event", slog.Any("error", err))
+	}
+
+	m.logger.InfoContext(ctx, msg,
+		slog.String("player_id", p.ID),
+		slog.Int("dkp", p.DKP),
+	)
+	return m.players.GetByDiscordID(ctx, discordID)
+}
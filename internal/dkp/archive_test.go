@@ -0,0 +1,77 @@
+package dkp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+func TestManager_Archive(t *testing.T) {
+	repo := storetest.NewPlayers()
+	es := eventtest.NewStore()
+	mgr := dkp.NewManager(repo, es, slog.Default(), testTP)
+	ctx := context.Background()
+
+	p, _ := mgr.RegisterPlayer(ctx, "d1", "Frodo", store.Profile{})
+	_ = mgr.AwardDKP(ctx, p.ID, 40, "raid")
+
+	got, err := mgr.Archive(ctx, "d1", "inactive")
+	if err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+	if !got.Archived() {
+		t.Fatal("player not archived")
+	}
+	last := es.Last(t)
+	if last.Type != event.PlayerArchived || last.AggregateID != p.ID {
+		t.Fatalf("last event = %s on %s, want %s on %s", last.Type, last.AggregateID, event.PlayerArchived, p.ID)
+	}
+	if data := eventtest.Data[event.PlayerArchivedData](t, last); data.DKP != 40 || data.Reason != "inactive" {
+		t.Errorf("event data = %+v, want 40 DKP for inactive", data)
+	}
+
+	// The DKP is frozen.
+	if err := mgr.AwardDKP(ctx, p.ID, 10, "raid"); !errors.Is(err, store.ErrPlayerArchived) {
+		t.Errorf("AwardDKP() error = %v, want ErrPlayerArchived", err)
+	}
+	if _, err := mgr.Archive(ctx, "d1", ""); !errors.Is(err, store.ErrPlayerArchived) {
+		t.Errorf("Archive() again error = %v, want ErrPlayerArchived", err)
+	}
+
+	got, err = mgr.Restore(ctx, "d1")
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if got.Archived() {
+		t.Fatal("player still archived")
+	}
+	if last := es.Last(t); last.Type != event.PlayerRestored {
+		t.Errorf("last event = %s, want %s", last.Type, event.PlayerRestored)
+	}
+	if err := mgr.AwardDKP(ctx, p.ID, 10, "raid"); err != nil {
+		t.Errorf("AwardDKP() after restore error = %v", err)
+	}
+	repo.RequireDKP(t, "d1", 50)
+}
+
+func TestManager_Restore_NotArchived(t *testing.T) {
+	repo := storetest.NewPlayers()
+	mgr := dkp.NewManager(repo, eventtest.NewStore(), slog.Default(), testTP)
+	ctx := context.Background()
+
+	_, _ = mgr.RegisterPlayer(ctx, "d1", "Sam", store.Profile{})
+
+	if _, err := mgr.Restore(ctx, "d1"); !errors.Is(err, dkp.ErrNotArchived) {
+		t.Errorf("Restore() error = %v, want ErrNotArchived", err)
+	}
+	if _, err := mgr.Archive(ctx, "unknown", ""); err == nil {
+		t.Error("Archive() of unknown player succeeded")
+	}
+}
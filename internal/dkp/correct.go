@@ -0,0 +1,91 @@
+package dkp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+)
+
+// Errors returned by Correct.
+var (
+	ErrNothingToCorrect = derrors.New(derrors.Conflict, "NOTHING_TO_CORRECT", "the player already has this balance")
+	ErrNoOfficer        = derrors.New(derrors.Permission, "NO_OFFICER", "a correction must be made by an officer")
+)
+
+// Correction describes a balance set by Correct.
+type Correction struct {
+	PlayerID      string `json:"player_id"`
+	CharacterName string `json:"character_name"`
+	From          int    `json:"from"`
+	To            int    `json:"to"`
+}
+
+// Correct sets the balance of the player registered as discordID, to fix
+// bookkeeping errors. It records a dkp.adjusted event with the change, the
+// old and new balances, and the officer, who is the actor of ctx: the
+// officer cannot be chosen by the caller. A change made concurrently with
+// the correction is kept on top of it.
+func (m *Manager) Correct(ctx context.Context, discordID string, balance int, reason string) (Correction, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.Correct",
+		trace.WithAttributes(
+			attribute.String("discord_id", discordID),
+			attribute.Int("balance", balance),
+		),
+	)
+	defer span.End()
+
+	return idempotency.Do(ctx, m.dedup, "dkp.correct", func(ctx context.Context) (Correction, error) {
+		return m.correct(ctx, discordID, balance, reason)
+	})
+}
+
+func (m *Manager) correct(ctx context.Context, discordID string, balance int, reason string) (Correction, error) {
+	officer := event.ActorFromContext(ctx)
+	if officer == "" {
+		return Correction{}, ErrNoOfficer
+	}
+	p, err := m.players.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return Correction{}, err
+	}
+	delta := balance - p.DKP
+	if delta == 0 {
+		return Correction{}, ErrNothingToCorrect
+	}
+	if err := m.players.UpdateDKP(ctx, p.ID, delta); err != nil {
+		return Correction{}, fmt.Errorf("correcting DKP: %w", err)
+	}
+
+	data, _ := json.Marshal(event.DKPChangeData{
+		PlayerID:   p.ID,
+		Amount:     delta,
+		Reason:     reason,
+		Correction: &event.DKPCorrection{From: p.DKP, To: balance, Officer: officer},
+	})
+	evt := event.Event{
+		AggregateID: p.ID,
+		Type:        event.DKPAdjusted,
+		Data:        data,
+		Version:     0,
+	}
+	if err := m.events.Append(ctx, evt); err != nil {
+		m.logger.ErrorContext(ctx, "failed to append DKP correction event", slog.Any("error", err))
+	}
+
+	m.logger.InfoContext(ctx, "DKP corrected",
+		slog.String("player_id", p.ID),
+		slog.Int("from", p.DKP),
+		slog.Int("to", balance),
+		slog.String("officer", officer),
+		slog.String("reason", reason),
+	)
+	return Correction{PlayerID: p.ID, CharacterName: p.CharacterName, From: p.DKP, To: balance}, nil
+}
@@ -0,0 +1,64 @@
+package dkp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+func TestManager_Correct(t *testing.T) {
+	repo := storetest.NewPlayers(store.Player{ID: "p1", DiscordID: "d1", CharacterName: "Frodo", DKP: 140})
+	es := eventtest.NewStore(eventtest.WithClock(clock.Real{}))
+	mgr := dkp.NewManager(repo, es, slog.Default(), testTP)
+	ctx := event.WithActor(context.Background(), "officer-1")
+
+	got, err := mgr.Correct(ctx, "d1", 95, "double-counted raid")
+	if err != nil {
+		t.Fatalf("Correct() error = %v", err)
+	}
+	if want := (dkp.Correction{PlayerID: "p1", CharacterName: "Frodo", From: 140, To: 95}); got != want {
+		t.Errorf("Correct() = %+v, want %+v", got, want)
+	}
+	repo.RequireDKP(t, "d1", 95)
+
+	last := es.Last(t)
+	if last.Type != event.DKPAdjusted || last.AggregateID != "p1" {
+		t.Fatalf("last event = %s on %s, want %s on p1", last.Type, last.AggregateID, event.DKPAdjusted)
+	}
+	data := eventtest.Data[event.DKPChangeData](t, last)
+	if data.Amount != -45 || data.Reason != "double-counted raid" {
+		t.Errorf("event data = %+v, want -45 for double-counted raid", data)
+	}
+	if c := data.Correction; c == nil || *c != (event.DKPCorrection{From: 140, To: 95, Officer: "officer-1"}) {
+		t.Errorf("correction = %+v, want 140 to 95 by officer-1", c)
+	}
+
+	if _, err := mgr.Correct(ctx, "d1", 95, "again"); !errors.Is(err, dkp.ErrNothingToCorrect) {
+		t.Errorf("Correct() to the same balance error = %v, want ErrNothingToCorrect", err)
+	}
+	if _, err := mgr.Correct(context.Background(), "d1", 100, "anonymous"); !errors.Is(err, dkp.ErrNoOfficer) {
+		t.Errorf("Correct() without an actor error = %v, want ErrNoOfficer", err)
+	}
+	if _, err := mgr.Correct(ctx, "d2", 100, "unknown"); !errors.Is(err, store.ErrPlayerNotFound) {
+		t.Errorf("Correct() of an unknown player error = %v, want ErrPlayerNotFound", err)
+	}
+	repo.RequireDKP(t, "d1", 95)
+
+	// A correction is undone like any other change.
+	rev, err := mgr.Undo(ctx, "p1", "", 0)
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if rev.EventID != last.ID {
+		t.Errorf("Undo() reversed %s, want %s", rev.EventID, last.ID)
+	}
+	repo.RequireDKP(t, "d1", 140)
+}
@@ -0,0 +1,377 @@
+package dkp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// DKP is the name of the built-in currency, which players.dkp holds.
+const DKP = "DKP"
+
+// Errors returned by currency operations.
+var (
+	ErrUnknownCurrency = derrors.New(derrors.Validation, "UNKNOWN_CURRENCY", "no such currency")
+	ErrBadTransfer     = derrors.New(derrors.Validation, "BAD_TRANSFER", "a transfer must move a positive amount between two players")
+)
+
+// WithCurrencies makes the currencies names available besides DKP, with
+// their balances kept in balances.
+func WithCurrencies(balances store.BalanceRepository, names []string) Option {
+	return func(m *Manager) {
+		m.balances = balances
+		m.currencies = names
+	}
+}
+
+// change is the kind of a change to a ledger.
+type change int
+
+const (
+	awarded change = iota
+	deducted
+	transferred
+)
+
+// A ledger holds the balances of one currency.
+type ledger interface {
+	// currency returns the name of the currency.
+	currency() string
+	update(ctx context.Context, playerID string, delta int) error
+	// transfer moves amount from the player fromID to the player toID, or
+	// changes neither.
+	transfer(ctx context.Context, fromID, toID string, amount int) error
+	// balances returns the balance of every player who holds the currency,
+	// by player ID.
+	balances(ctx context.Context) (map[string]int, error)
+	// event returns the event recording a change of delta to the player
+	// playerID.
+	event(c change, playerID, counterparty string, delta int, reason string) event.Event
+}
+
+// dkpLedger is the ledger of DKP, kept on the players.
+type dkpLedger struct {
+	players store.PlayerRepository
+}
+
+func (dkpLedger) currency() string { return DKP }
+
+func (l dkpLedger) update(ctx context.Context, playerID string, delta int) error {
+	return l.players.UpdateDKP(ctx, playerID, delta)
+}
+
+func (l dkpLedger) transfer(ctx context.Context, fromID, toID string, amount int) error {
+	if err := l.players.UpdateDKP(ctx, fromID, -amount); err != nil {
+		return err
+	}
+	if err := l.players.UpdateDKP(ctx, toID, amount); err != nil {
+		// Give the sender their DKP back.
+		if rerr := l.players.UpdateDKP(ctx, fromID, amount); rerr != nil {
+			return errors.Join(err, fmt.Errorf("returning DKP to %s: %w", fromID, rerr))
+		}
+		return err
+	}
+	return nil
+}
+
+func (l dkpLedger) balances(ctx context.Context) (map[string]int, error) {
+	players, err := l.players.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[string]int, len(players))
+	for _, p := range players {
+		balances[p.ID] = p.DKP
+	}
+	return balances, nil
+}
+
+func (dkpLedger) event(c change, playerID, counterparty string, delta int, reason string) event.Event {
+	typ := map[change]event.Type{awarded: event.DKPAwarded, deducted: event.DKPDeducted, transferred: event.DKPAdjusted}[c]
+	data, _ := json.Marshal(event.DKPChangeData{
+		PlayerID:     playerID,
+		Amount:       delta,
+		Reason:       reason,
+		Counterparty: counterparty,
+	})
+	return event.Event{AggregateID: playerID, Type: typ, Data: data, Version: 0}
+}
+
+// currencyLedger is the ledger of a configured currency.
+type currencyLedger struct {
+	name  string
+	store store.BalanceRepository
+}
+
+func (l currencyLedger) currency() string { return l.name }
+
+func (l currencyLedger) update(ctx context.Context, playerID string, delta int) error {
+	return l.store.Update(ctx, playerID, l.name, delta)
+}
+
+func (l currencyLedger) transfer(ctx context.Context, fromID, toID string, amount int) error {
+	return l.store.Transfer(ctx, fromID, toID, l.name, amount)
+}
+
+func (l currencyLedger) balances(ctx context.Context) (map[string]int, error) {
+	held, err := l.store.List(ctx, l.name)
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[string]int, len(held))
+	for _, b := range held {
+		balances[b.PlayerID] = b.Balance
+	}
+	return balances, nil
+}
+
+func (l currencyLedger) event(c change, playerID, counterparty string, delta int, reason string) event.Event {
+	typ := map[change]event.Type{awarded: event.CurrencyAwarded, deducted: event.CurrencyDeducted, transferred: event.CurrencyTransferred}[c]
+	data, _ := json.Marshal(event.CurrencyChangeData{
+		PlayerID:     playerID,
+		Currency:     l.name,
+		Amount:       delta,
+		Reason:       reason,
+		Counterparty: counterparty,
+	})
+	return event.Event{AggregateID: playerID, Type: typ, Data: data, Version: 0}
+}
+
+// Currencies returns the names of the currencies players may hold, DKP
+// first.
+func (m *Manager) Currencies() []string {
+	return append([]string{DKP}, m.currencies...)
+}
+
+// Currency returns the name of the currency named name, ignoring case.
+func (m *Manager) Currency(name string) (string, error) {
+	l, err := m.ledger(name)
+	if err != nil {
+		return "", err
+	}
+	return l.currency(), nil
+}
+
+// ledger returns the ledger of the currency named name, ignoring case.
+func (m *Manager) ledger(name string) (ledger, error) {
+	name = strings.TrimSpace(name)
+	if strings.EqualFold(name, DKP) {
+		return dkpLedger{players: m.players}, nil
+	}
+	i := slices.IndexFunc(m.currencies, func(c string) bool { return strings.EqualFold(c, name) })
+	if i < 0 || m.balances == nil {
+		return nil, ErrUnknownCurrency.Wrap(fmt.Errorf("currency %q", name))
+	}
+	return currencyLedger{name: m.currencies[i], store: m.balances}, nil
+}
+
+// Award adds amount of currency to the player playerID. Awarding DKP is
+// the same as AwardDKP.
+func (m *Manager) Award(ctx context.Context, currency, playerID string, amount int, reason string) error {
+	ctx, span := m.tracer.Start(ctx, "Manager.Award",
+		trace.WithAttributes(
+			attribute.String("currency", currency),
+			attribute.String("player_id", playerID),
+			attribute.Int("amount", amount),
+		),
+	)
+	defer span.End()
+
+	l, err := m.ledger(currency)
+	if err != nil {
+		return err
+	}
+	_, err = idempotency.Do(ctx, m.dedup, "dkp.award", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, m.change(ctx, l, awarded, playerID, amount, reason)
+	})
+	return err
+}
+
+// Deduct removes amount of currency from the player playerID. Deducting
+// DKP is the same as DeductDKP.
+func (m *Manager) Deduct(ctx context.Context, currency, playerID string, amount int, reason string) error {
+	ctx, span := m.tracer.Start(ctx, "Manager.Deduct",
+		trace.WithAttributes(
+			attribute.String("currency", currency),
+			attribute.String("player_id", playerID),
+			attribute.Int("amount", amount),
+		),
+	)
+	defer span.End()
+
+	l, err := m.ledger(currency)
+	if err != nil {
+		return err
+	}
+	_, err = idempotency.Do(ctx, m.dedup, "dkp.deduct", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, m.change(ctx, l, deducted, playerID, -amount, reason)
+	})
+	return err
+}
+
+// change applies delta to the player playerID in l and records it as a
+// change of kind c.
+func (m *Manager) change(ctx context.Context, l ledger, c change, playerID string, delta int, reason string) error {
+	verb := map[change]string{awarded: "awarding", deducted: "deducting"}[c]
+	if err := l.update(ctx, playerID, delta); err != nil {
+		return fmt.Errorf("%s %s: %w", verb, l.currency(), err)
+	}
+	if l.currency() == DKP {
+		if c == awarded {
+			m.metrics.DKPAwarded(ctx, delta)
+		} else {
+			m.metrics.DKPDeducted(ctx, -delta)
+		}
+	}
+
+	if err := m.events.Append(ctx, l.event(c, playerID, "", delta, reason)); err != nil {
+		m.logger.ErrorContext(ctx, "failed to append "+l.currency()+" change event", slog.Any("error", err))
+	}
+
+	amount := delta
+	if c == deducted {
+		amount = -delta
+	}
+	m.logger.InfoContext(ctx, l.currency()+" "+map[change]string{awarded: "awarded", deducted: "deducted"}[c],
+		slog.String("player_id", playerID),
+		slog.Int("amount", amount),
+		slog.String("reason", reason),
+	)
+	return nil
+}
+
+// Transfer moves amount of currency from the player fromID to the player
+// toID, recording a change on each. Like a deduction, it may leave the
+// sender with a negative balance.
+func (m *Manager) Transfer(ctx context.Context, currency, fromID, toID string, amount int, reason string) error {
+	ctx, span := m.tracer.Start(ctx, "Manager.Transfer",
+		trace.WithAttributes(
+			attribute.String("currency", currency),
+			attribute.String("from_player_id", fromID),
+			attribute.String("to_player_id", toID),
+			attribute.Int("amount", amount),
+		),
+	)
+	defer span.End()
+
+	l, err := m.ledger(currency)
+	if err != nil {
+		return err
+	}
+	if amount <= 0 || fromID == toID {
+		return ErrBadTransfer
+	}
+	_, err = idempotency.Do(ctx, m.dedup, "dkp.transfer", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, m.transfer(ctx, l, fromID, toID, amount, reason)
+	})
+	return err
+}
+
+func (m *Manager) transfer(ctx context.Context, l ledger, fromID, toID string, amount int, reason string) error {
+	if err := l.transfer(ctx, fromID, toID, amount); err != nil {
+		return fmt.Errorf("transferring %s: %w", l.currency(), err)
+	}
+	for _, e := range []event.Event{
+		l.event(transferred, fromID, toID, -amount, reason),
+		l.event(transferred, toID, fromID, amount, reason),
+	} {
+		if err := m.events.Append(ctx, e); err != nil {
+			m.logger.ErrorContext(ctx, "failed to append "+l.currency()+" transfer event", slog.Any("error", err))
+		}
+	}
+
+	m.logger.InfoContext(ctx, l.currency()+" transferred",
+		slog.String("from_player_id", fromID),
+		slog.String("to_player_id", toID),
+		slog.Int("amount", amount),
+		slog.String("reason", reason),
+	)
+	return nil
+}
+
+// Balance returns what the player playerID holds of currency.
+func (m *Manager) Balance(ctx context.Context, currency, playerID string) (int, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.Balance",
+		trace.WithAttributes(
+			attribute.String("currency", currency),
+			attribute.String("player_id", playerID),
+		),
+	)
+	defer span.End()
+
+	l, err := m.ledger(currency)
+	if err != nil {
+		return 0, err
+	}
+	if l, ok := l.(currencyLedger); ok {
+		return l.store.Get(ctx, playerID, l.name)
+	}
+	balances, err := l.balances(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("loading balances: %w", err)
+	}
+	return balances[playerID], nil
+}
+
+// Balances returns what every player holds of currency, by player ID.
+// Players missing from the map hold none.
+func (m *Manager) Balances(ctx context.Context, currency string) (map[string]int, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.Balances",
+		trace.WithAttributes(attribute.String("currency", currency)),
+	)
+	defer span.End()
+
+	l, err := m.ledger(currency)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := l.balances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading balances: %w", err)
+	}
+	return balances, nil
+}
+
+// Holding is what a player holds of one currency.
+type Holding struct {
+	Currency string
+	Balance  int
+}
+
+// Wallet returns what p holds of each currency, DKP first and then the
+// other currencies in configured order, including those p holds none of.
+func (m *Manager) Wallet(ctx context.Context, p *store.Player) ([]Holding, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.Wallet",
+		trace.WithAttributes(attribute.String("player_id", p.ID)),
+	)
+	defer span.End()
+
+	wallet := []Holding{{Currency: DKP, Balance: p.DKP}}
+	if m.balances == nil || len(m.currencies) == 0 {
+		return wallet, nil
+	}
+	held, err := m.balances.ListByPlayer(ctx, p.ID)
+	if err != nil {
+		return nil, fmt.Errorf("loading balances: %w", err)
+	}
+	for _, name := range m.currencies {
+		h := Holding{Currency: name}
+		if i := slices.IndexFunc(held, func(b store.Balance) bool { return b.Currency == name }); i >= 0 {
+			h.Balance = held[i].Balance
+		}
+		wallet = append(wallet, h)
+	}
+	return wallet, nil
+}
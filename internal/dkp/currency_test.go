@@ -0,0 +1,120 @@
+package dkp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+func TestManager_Currency(t *testing.T) {
+	players := storetest.NewPlayers()
+	mgr := dkp.NewManager(players, eventtest.NewStore(), slog.New(slog.DiscardHandler), testTP,
+		dkp.WithCurrencies(storetest.NewBalances(players), []string{"EP", "Raid tokens"}))
+
+	for name, want := range map[string]string{"dkp": dkp.DKP, "ep": "EP", " raid TOKENS ": "Raid tokens"} {
+		if got, err := mgr.Currency(name); err != nil || got != want {
+			t.Errorf("Currency(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := mgr.Currency("GP"); !errors.Is(err, dkp.ErrUnknownCurrency) {
+		t.Errorf("Currency(GP) error = %v, want ErrUnknownCurrency", err)
+	}
+	if got := mgr.Currencies(); !slices.Equal(got, []string{dkp.DKP, "EP", "Raid tokens"}) {
+		t.Errorf("Currencies() = %v", got)
+	}
+}
+
+func TestManager_AwardDeductTransfer(t *testing.T) {
+	ctx := context.Background()
+	players := storetest.NewPlayers()
+	balances := storetest.NewBalances(players)
+	es := eventtest.NewStore()
+	mgr := dkp.NewManager(players, es, slog.New(slog.DiscardHandler), testTP,
+		dkp.WithCurrencies(balances, []string{"EP"}))
+	frodo, _ := mgr.RegisterPlayer(ctx, "d1", "Frodo", store.Profile{})
+	sam, _ := mgr.RegisterPlayer(ctx, "d2", "Sam", store.Profile{})
+
+	if err := mgr.Award(ctx, "ep", frodo.ID, 30, "raid"); err != nil {
+		t.Fatalf("Award() error = %v", err)
+	}
+	if err := mgr.Deduct(ctx, "EP", frodo.ID, 5, "late"); err != nil {
+		t.Fatalf("Deduct() error = %v", err)
+	}
+	if err := mgr.Transfer(ctx, "EP", frodo.ID, sam.ID, 10, "gift"); err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+	balances.RequireBalance(t, "d1", "EP", 15)
+	balances.RequireBalance(t, "d2", "EP", 10)
+	players.RequireDKP(t, "d1", 0)
+
+	if err := mgr.Award(ctx, "GP", frodo.ID, 1, ""); !errors.Is(err, dkp.ErrUnknownCurrency) {
+		t.Errorf("Award(GP) error = %v, want ErrUnknownCurrency", err)
+	}
+	if err := mgr.Transfer(ctx, "EP", frodo.ID, frodo.ID, 1, ""); !errors.Is(err, dkp.ErrBadTransfer) {
+		t.Errorf("Transfer() to self error = %v, want ErrBadTransfer", err)
+	}
+	if err := mgr.Transfer(ctx, "EP", frodo.ID, sam.ID, 0, ""); !errors.Is(err, dkp.ErrBadTransfer) {
+		t.Errorf("Transfer() of nothing error = %v, want ErrBadTransfer", err)
+	}
+
+	es.RequireTypes(t, event.PlayerRegistered, event.PlayerRegistered,
+		event.CurrencyAwarded, event.CurrencyDeducted, event.CurrencyTransferred, event.CurrencyTransferred)
+	d := eventtest.Data[event.CurrencyChangeData](t, es.Last(t))
+	if d.PlayerID != sam.ID || d.Currency != "EP" || d.Amount != 10 || d.Counterparty != frodo.ID {
+		t.Errorf("last event = %+v, want Sam receiving 10 EP from Frodo", d)
+	}
+
+	if got, err := mgr.Balance(ctx, "ep", sam.ID); err != nil || got != 10 {
+		t.Errorf("Balance(EP) = %d, %v, want 10", got, err)
+	}
+	if got, err := mgr.Balances(ctx, "EP"); err != nil || got[frodo.ID] != 15 || got[sam.ID] != 10 {
+		t.Errorf("Balances(EP) = %v, %v", got, err)
+	}
+	p := players.Player(t, "d1")
+	wallet, err := mgr.Wallet(ctx, &p)
+	if want := []dkp.Holding{{Currency: dkp.DKP}, {Currency: "EP", Balance: 15}}; err != nil || !slices.Equal(wallet, want) {
+		t.Errorf("Wallet() = %+v, %v, want %+v", wallet, err, want)
+	}
+}
+
+func TestManager_Transfer_DKP(t *testing.T) {
+	ctx := context.Background()
+	players := storetest.NewPlayers()
+	es := eventtest.NewStore(eventtest.WithClock(clock.Real{}))
+	mgr := dkp.NewManager(players, es, slog.New(slog.DiscardHandler), testTP)
+	frodo, _ := mgr.RegisterPlayer(ctx, "d1", "Frodo", store.Profile{})
+	sam, _ := mgr.RegisterPlayer(ctx, "d2", "Sam", store.Profile{})
+	_ = mgr.AwardDKP(ctx, frodo.ID, 50, "seed")
+
+	if err := mgr.Transfer(ctx, "dkp", frodo.ID, sam.ID, 20, "gift"); err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+	players.RequireDKP(t, "d1", 30)
+	players.RequireDKP(t, "d2", 20)
+
+	// A transfer changed two players, so neither side may be undone.
+	if _, err := mgr.Undo(ctx, sam.ID, es.Last(t).ID, 0); !errors.Is(err, dkp.ErrTransferUndo) {
+		t.Errorf("Undo() of a transfer error = %v, want ErrTransferUndo", err)
+	}
+	if r, err := mgr.Undo(ctx, frodo.ID, "", 0); err != nil || r.Reason != "seed" {
+		t.Errorf("Undo() = %+v, %v, want the seed award undone", r, err)
+	}
+
+	// A transfer to an archived player gives the sender their DKP back.
+	if _, err := mgr.Archive(ctx, "d2", "inactive"); err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+	if err := mgr.Transfer(ctx, dkp.DKP, frodo.ID, sam.ID, 5, ""); !errors.Is(err, store.ErrPlayerArchived) {
+		t.Errorf("Transfer() to an archived player error = %v, want ErrPlayerArchived", err)
+	}
+	players.RequireDKP(t, "d1", -20)
+}
@@ -0,0 +1,122 @@
+package dkp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// changeTypes are the event types that change a player's DKP.
+var changeTypes = []event.Type{event.DKPAwarded, event.DKPDeducted, event.DKPAdjusted}
+
+// Change is a DKP change of a player with the balance it left.
+type Change struct {
+	EventID   string
+	Type      event.Type
+	Amount    int
+	Reason    string
+	CreatedAt time.Time
+	Balance   int
+}
+
+// History returns the DKP changes of p, oldest first. Balances are worked
+// back from p's current DKP, so they are right even if the player's first
+// changes predate the event log.
+func (m *Manager) History(ctx context.Context, p *store.Player) ([]Change, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.History",
+		trace.WithAttributes(attribute.String("player_id", p.ID)),
+	)
+	defer span.End()
+
+	events, err := m.events.Query(ctx, event.Query{Types: changeTypes, AggregateID: p.ID})
+	if err != nil {
+		return nil, fmt.Errorf("loading DKP history: %w", err)
+	}
+	// Queries return the newest events first.
+	changes := make([]Change, len(events))
+	balance := p.DKP
+	for i, e := range events {
+		var d event.DKPChangeData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+		}
+		changes[len(events)-1-i] = Change{
+			EventID:   e.ID,
+			Type:      e.Type,
+			Amount:    d.Amount,
+			Reason:    d.Reason,
+			CreatedAt: e.CreatedAt,
+			Balance:   balance,
+		}
+		balance -= d.Amount
+	}
+	return changes, nil
+}
+
+// Week sums the DKP changes of a week, which starts on Monday at midnight
+// UTC.
+type Week struct {
+	Start time.Time
+	// Awarded sums the changes that added DKP and Spent those that took it
+	// away, as a positive number.
+	Awarded int
+	Spent   int
+}
+
+// Net returns how much the DKP held by all players grew in the week.
+func (w Week) Net() int {
+	return w.Awarded - w.Spent
+}
+
+// Weekly returns the DKP changes of all players summed by week for the
+// last n weeks, the current one included, oldest first.
+func (m *Manager) Weekly(ctx context.Context, n int) ([]Week, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.Weekly",
+		trace.WithAttributes(attribute.Int("weeks", n)),
+	)
+	defer span.End()
+
+	if n <= 0 {
+		return nil, nil
+	}
+	weeks := make([]Week, n)
+	current := weekStart(m.clock.Now())
+	for i := range weeks {
+		weeks[i].Start = current.AddDate(0, 0, -7*(n-1-i))
+	}
+	events, err := m.events.Query(ctx, event.Query{Types: changeTypes, Since: weeks[0].Start})
+	if err != nil {
+		return nil, fmt.Errorf("querying DKP changes: %w", err)
+	}
+	for _, e := range events {
+		var d event.DKPChangeData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+		}
+		i := int(weekStart(e.CreatedAt).Sub(weeks[0].Start) / (7 * 24 * time.Hour))
+		if i < 0 || i >= n {
+			continue
+		}
+		if d.Amount > 0 {
+			weeks[i].Awarded += d.Amount
+		} else {
+			weeks[i].Spent -= d.Amount
+		}
+	}
+	return weeks, nil
+}
+
+// weekStart returns the start of the week of t: the Monday before it, or
+// of it, at midnight UTC.
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
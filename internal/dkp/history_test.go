@@ -0,0 +1,102 @@
+package dkp_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+func TestManager_History(t *testing.T) {
+	now := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+	repo := storetest.NewPlayers()
+	clk := &clock.Mock{T: now}
+	es := eventtest.NewStore(eventtest.WithClock(clk))
+	mgr := dkp.NewManager(repo, es, slog.Default(), testTP)
+	ctx := context.Background()
+
+	p, _ := mgr.RegisterPlayer(ctx, "d1", "Boromir", store.Profile{})
+	// The balance predates the event log.
+	p.DKP = 25
+	repo.Put(*p)
+	_ = mgr.AwardDKP(ctx, p.ID, 100, "raid")
+	clk.T = now.Add(time.Hour)
+	_ = mgr.DeductDKP(ctx, p.ID, 30, "sword")
+
+	p, _ = mgr.GetPlayer(ctx, "d1")
+	changes, err := mgr.History(ctx, p)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	want := []dkp.Change{
+		{EventID: "evt-2", Amount: 100, Reason: "raid", Balance: 125},
+		{EventID: "evt-3", Amount: -30, Reason: "sword", Balance: 95},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("History() = %+v, want %d changes", changes, len(want))
+	}
+	for i, w := range want {
+		c := changes[i]
+		if c.EventID != w.EventID || c.Amount != w.Amount || c.Reason != w.Reason || c.Balance != w.Balance {
+			t.Errorf("change %d = %+v, want %+v", i, c, w)
+		}
+	}
+	if !changes[1].CreatedAt.Equal(clk.T) {
+		t.Errorf("CreatedAt = %v, want %v", changes[1].CreatedAt, clk.T)
+	}
+}
+
+func TestManager_Weekly(t *testing.T) {
+	// Wednesday, in the week starting Monday 2025-06-16.
+	now := time.Date(2025, 6, 18, 12, 0, 0, 0, time.UTC)
+	at := &clock.Mock{T: now}
+	repo := storetest.NewPlayers()
+	es := eventtest.NewStore(eventtest.WithClock(at))
+	mgr := dkp.NewManager(repo, es, slog.Default(), testTP, dkp.WithClock(clock.Mock{T: now}))
+	ctx := context.Background()
+
+	p, _ := mgr.RegisterPlayer(ctx, "d1", "Boromir", store.Profile{})
+	for _, c := range []struct {
+		at     time.Time
+		amount int
+	}{
+		{time.Date(2025, 5, 30, 20, 0, 0, 0, time.UTC), 500}, // before the weeks shown
+		{time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC), 100},   // Monday midnight
+		{time.Date(2025, 6, 15, 23, 59, 0, 0, time.UTC), -40},
+		{time.Date(2025, 6, 16, 20, 0, 0, 0, time.UTC), 30},
+	} {
+		at.T = c.at
+		if c.amount > 0 {
+			_ = mgr.AwardDKP(ctx, p.ID, c.amount, "raid")
+		} else {
+			_ = mgr.DeductDKP(ctx, p.ID, -c.amount, "item")
+		}
+	}
+
+	weeks, err := mgr.Weekly(ctx, 3)
+	if err != nil {
+		t.Fatalf("Weekly() error = %v", err)
+	}
+	want := []dkp.Week{
+		{Start: time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC), Awarded: 100, Spent: 40},
+		{Start: time.Date(2025, 6, 16, 0, 0, 0, 0, time.UTC), Awarded: 30},
+	}
+	if len(weeks) != len(want) {
+		t.Fatalf("Weekly() = %+v, want %d weeks", weeks, len(want))
+	}
+	for i, w := range want {
+		if !weeks[i].Start.Equal(w.Start) || weeks[i].Awarded != w.Awarded || weeks[i].Spent != w.Spent {
+			t.Errorf("week %d = %+v, want %+v", i, weeks[i], w)
+		}
+	}
+	if weeks[1].Net() != 60 {
+		t.Errorf("Net() = %d, want 60", weeks[1].Net())
+	}
+}
@@ -0,0 +1,139 @@
+package dkp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+)
+
+var (
+	// ErrInvalidLootBan is returned by BanLoot for a ban without a
+	// positive duration.
+	ErrInvalidLootBan = derrors.New(derrors.Validation, "INVALID_LOOT_BAN", "a loot ban must have a positive duration")
+	// ErrNotLootBanned is returned by LiftLootBan for a player who is not
+	// banned from loot.
+	ErrNotLootBanned = derrors.New(derrors.Conflict, "NOT_LOOT_BANNED", "this player is not banned from loot")
+)
+
+// LootBan is a ban of a player from bidding on loot.
+type LootBan struct {
+	PlayerID      string    `json:"player_id"`
+	CharacterName string    `json:"character_name"`
+	Until         time.Time `json:"until"`
+	Reason        string    `json:"reason,omitempty"`
+}
+
+// BanLoot bans the player registered as discordID from bidding on loot
+// for d, replacing any ban they are under, and returns the ban.
+func (m *Manager) BanLoot(ctx context.Context, discordID string, d time.Duration, reason string) (*LootBan, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.BanLoot",
+		trace.WithAttributes(attribute.String("discord_id", discordID)),
+	)
+	defer span.End()
+
+	if d <= 0 {
+		return nil, ErrInvalidLootBan
+	}
+	return idempotency.Do(ctx, m.dedup, "dkp.loot_ban", func(ctx context.Context) (*LootBan, error) {
+		p, err := m.players.GetByDiscordID(ctx, discordID)
+		if err != nil {
+			return nil, err
+		}
+		ban := &LootBan{PlayerID: p.ID, CharacterName: p.CharacterName, Until: m.clock.Now().Add(d).UTC(), Reason: reason}
+		if err := m.appendLootBan(ctx, event.PlayerLootBanned, discordID, ban); err != nil {
+			return nil, err
+		}
+		m.logger.InfoContext(ctx, "player banned from loot",
+			slog.String("player_id", p.ID),
+			slog.Time("until", ban.Until),
+		)
+		return ban, nil
+	})
+}
+
+// LiftLootBan ends the loot ban of the player registered as discordID
+// early and returns the ban lifted.
+func (m *Manager) LiftLootBan(ctx context.Context, discordID string) (*LootBan, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.LiftLootBan",
+		trace.WithAttributes(attribute.String("discord_id", discordID)),
+	)
+	defer span.End()
+
+	return idempotency.Do(ctx, m.dedup, "dkp.loot_ban_lift", func(ctx context.Context) (*LootBan, error) {
+		p, err := m.players.GetByDiscordID(ctx, discordID)
+		if err != nil {
+			return nil, err
+		}
+		ban, err := m.ActiveLootBan(ctx, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		if ban == nil {
+			return nil, ErrNotLootBanned
+		}
+		ban.CharacterName = p.CharacterName
+		if err := m.appendLootBan(ctx, event.PlayerLootBanLifted, discordID, &LootBan{PlayerID: p.ID}); err != nil {
+			return nil, err
+		}
+		m.logger.InfoContext(ctx, "player loot ban lifted", slog.String("player_id", p.ID))
+		return ban, nil
+	})
+}
+
+func (m *Manager) appendLootBan(ctx context.Context, typ event.Type, discordID string, ban *LootBan) error {
+	data, _ := json.Marshal(event.PlayerLootBanData{DiscordID: discordID, Until: ban.Until, Reason: ban.Reason})
+	evt := event.Event{
+		AggregateID: ban.PlayerID,
+		Type:        typ,
+		Data:        data,
+		Version:     0,
+	}
+	if err := m.events.Append(ctx, evt); err != nil {
+		return fmt.Errorf("appending loot ban event: %w", err)
+	}
+	return nil
+}
+
+// ActiveLootBan returns the loot ban the player playerID is under, or nil
+// if they are not banned. Its CharacterName is not set.
+func (m *Manager) ActiveLootBan(ctx context.Context, playerID string) (*LootBan, error) {
+	events, err := m.events.Query(ctx, event.Query{
+		Types:       []event.Type{event.PlayerLootBanned, event.PlayerLootBanLifted},
+		AggregateID: playerID,
+		Limit:       1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading loot bans: %w", err)
+	}
+	if len(events) == 0 || events[0].Type == event.PlayerLootBanLifted {
+		return nil, nil
+	}
+	var d event.PlayerLootBanData
+	if err := json.Unmarshal(events[0].Data, &d); err != nil {
+		return nil, fmt.Errorf("decoding loot ban: %w", err)
+	}
+	if !d.Until.After(m.clock.Now()) {
+		return nil, nil
+	}
+	return &LootBan{PlayerID: playerID, Until: d.Until, Reason: d.Reason}, nil
+}
+
+// LootBan returns when the loot ban of the player playerID ends and why
+// they were banned, or the zero time if they are not banned. It lets the
+// Manager serve as the auction.LootBans of an auction.Manager.
+func (m *Manager) LootBan(ctx context.Context, playerID string) (time.Time, string, error) {
+	ban, err := m.ActiveLootBan(ctx, playerID)
+	if err != nil || ban == nil {
+		return time.Time{}, "", err
+	}
+	return ban.Until, ban.Reason, nil
+}
@@ -0,0 +1,78 @@
+package dkp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+func TestManager_BanLoot(t *testing.T) {
+	now := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+	clk := &clock.Mock{T: now}
+	repo := storetest.NewPlayers()
+	es := eventtest.NewStore(eventtest.WithClock(clk))
+	mgr := dkp.NewManager(repo, es, slog.Default(), testTP, dkp.WithClock(clk))
+	ctx := context.Background()
+	p, _ := mgr.RegisterPlayer(ctx, "d1", "Frodo", store.Profile{})
+
+	if _, err := mgr.BanLoot(ctx, "d1", 0, "none"); !errors.Is(err, dkp.ErrInvalidLootBan) {
+		t.Errorf("BanLoot(0) error = %v, want ErrInvalidLootBan", err)
+	}
+	if _, err := mgr.LiftLootBan(ctx, "d1"); !errors.Is(err, dkp.ErrNotLootBanned) {
+		t.Errorf("LiftLootBan() without a ban error = %v, want ErrNotLootBanned", err)
+	}
+
+	ban, err := mgr.BanLoot(ctx, "d1", 72*time.Hour, "ninja looting")
+	if err != nil {
+		t.Fatalf("BanLoot() error = %v", err)
+	}
+	if want := now.Add(72 * time.Hour); !ban.Until.Equal(want) || ban.CharacterName != "Frodo" {
+		t.Errorf("ban = %+v, want Frodo until %v", ban, want)
+	}
+	last := es.Last(t)
+	if last.Type != event.PlayerLootBanned || last.AggregateID != p.ID {
+		t.Fatalf("last event = %s on %s, want %s on %s", last.Type, last.AggregateID, event.PlayerLootBanned, p.ID)
+	}
+	if data := eventtest.Data[event.PlayerLootBanData](t, last); data.Reason != "ninja looting" {
+		t.Errorf("event data = %+v, want the reason", data)
+	}
+	if until, reason, err := mgr.LootBan(ctx, p.ID); err != nil || !until.Equal(ban.Until) || reason != "ninja looting" {
+		t.Errorf("LootBan() = %v, %q, %v, want the ban", until, reason, err)
+	}
+
+	// A ban ends by itself.
+	clk.T = now.Add(73 * time.Hour)
+	if until, _, err := mgr.LootBan(ctx, p.ID); err != nil || !until.IsZero() {
+		t.Errorf("LootBan() after it ended = %v, %v, want none", until, err)
+	}
+
+	// Or is lifted early.
+	if _, err := mgr.BanLoot(ctx, "d1", time.Hour, ""); err != nil {
+		t.Fatalf("BanLoot() again error = %v", err)
+	}
+	lifted, err := mgr.LiftLootBan(ctx, "d1")
+	if err != nil {
+		t.Fatalf("LiftLootBan() error = %v", err)
+	}
+	if !lifted.Until.Equal(clk.T.Add(time.Hour)) {
+		t.Errorf("lifted ban until %v, want the ban just given", lifted.Until)
+	}
+	if last := es.Last(t); last.Type != event.PlayerLootBanLifted {
+		t.Errorf("last event = %s, want %s", last.Type, event.PlayerLootBanLifted)
+	}
+	if until, _, err := mgr.LootBan(ctx, p.ID); err != nil || !until.IsZero() {
+		t.Errorf("LootBan() after lifting = %v, %v, want none", until, err)
+	}
+	if _, err := mgr.BanLoot(ctx, "nobody", time.Hour, ""); !errors.Is(err, store.ErrPlayerNotFound) {
+		t.Errorf("BanLoot() of an unregistered player error = %v, want ErrPlayerNotFound", err)
+	}
+}
@@ -5,34 +5,130 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
 )
 
+// Errors returned by Undo.
+var (
+	ErrNothingToUndo = derrors.New(derrors.NotFound, "NOTHING_TO_UNDO", "no DKP change to undo")
+	ErrAlreadyUndone = derrors.New(derrors.Conflict, "ALREADY_UNDONE", "this DKP change has already been undone")
+	ErrUndoExpired   = derrors.New(derrors.Conflict, "UNDO_EXPIRED", "this DKP change is too old to undo")
+	ErrNotUndoable   = derrors.New(derrors.Validation, "NOT_UNDOABLE", "an undo cannot itself be undone")
+	ErrTransferUndo  = derrors.New(derrors.Validation, "TRANSFER_NOT_UNDOABLE", "a transfer cannot be undone, transfer the DKP back instead")
+)
+
+// ErrInvalidProfile is returned for a player profile with an unknown role
+// or overly long fields.
+var ErrInvalidProfile = derrors.New(derrors.Validation, "INVALID_PROFILE", "role must be tank, healer, or dps, and class and spec at most 32 characters")
+
+// Raid roles a player profile may give.
+const (
+	RoleTank   = "tank"
+	RoleHealer = "healer"
+	RoleDPS    = "dps"
+)
+
+// maxProfileField is the longest class or spec accepted, in characters.
+const maxProfileField = 32
+
+// normalizeProfile trims p and lowercases its role, and reports whether
+// the result is valid.
+func normalizeProfile(p store.Profile) (store.Profile, error) {
+	p = store.Profile{
+		Class:    strings.TrimSpace(p.Class),
+		Role:     strings.ToLower(strings.TrimSpace(p.Role)),
+		Spec:     strings.TrimSpace(p.Spec),
+		Timezone: strings.TrimSpace(p.Timezone),
+	}
+	switch p.Role {
+	case "", RoleTank, RoleHealer, RoleDPS:
+	default:
+		return p, ErrInvalidProfile.Wrap(fmt.Errorf("role %q", p.Role))
+	}
+	if utf8.RuneCountInString(p.Class) > maxProfileField || utf8.RuneCountInString(p.Spec) > maxProfileField {
+		return p, ErrInvalidProfile
+	}
+	if p.Timezone != "" {
+		loc, err := clock.LoadLocation(p.Timezone)
+		if err != nil {
+			return p, derrors.New(ErrInvalidProfile.Kind, ErrInvalidProfile.Code,
+				fmt.Sprintf("unknown timezone %q: want a name such as Europe/Copenhagen", p.Timezone))
+		}
+		p.Timezone = loc.String()
+	}
+	return p, nil
+}
+
+// DefaultUndoWindow is how long after a change Undo may reverse it, unless
+// a window is given.
+const DefaultUndoWindow = 24 * time.Hour
+
 // Manager handles DKP operations.
 type Manager struct {
 	players store.PlayerRepository
 	events  event.Store
 	logger  *slog.Logger
 	tracer  trace.Tracer
+	dedup   *idempotency.Guard
+	metrics *metrics.Recorder
+	clock   clock.Clock
+
+	// balances holds the currencies besides DKP, named by currencies.
+	balances   store.BalanceRepository
+	currencies []string
+}
+
+// Option configures optional Manager collaborators.
+type Option func(*Manager)
+
+// WithIdempotency makes state-changing operations deduplicate on the
+// idempotency key carried in their context.
+func WithIdempotency(g *idempotency.Guard) Option {
+	return func(m *Manager) { m.dedup = g }
+}
+
+// WithMetrics records DKP awarded and deducted on r.
+func WithMetrics(r *metrics.Recorder) Option {
+	return func(m *Manager) { m.metrics = r }
+}
+
+// WithClock sets the clock Undo measures the age of changes with and
+// Weekly counts weeks back from.
+func WithClock(c clock.Clock) Option {
+	return func(m *Manager) { m.clock = c }
 }
 
 // NewManager returns a new DKP Manager.
-func NewManager(players store.PlayerRepository, events event.Store, logger *slog.Logger, tp trace.TracerProvider) *Manager {
-	return &Manager{
+func NewManager(players store.PlayerRepository, events event.Store, logger *slog.Logger, tp trace.TracerProvider, opts ...Option) *Manager {
+	m := &Manager{
 		players: players,
 		events:  events,
 		logger:  logger,
 		tracer:  tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/dkp"),
+		metrics: metrics.Nop(),
+		clock:   clock.Real{},
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
-// RegisterPlayer registers a new player character.
-func (m *Manager) RegisterPlayer(ctx context.Context, discordID, characterName string) (*store.Player, error) {
+// RegisterPlayer registers a new player character with profile, whose
+// fields may be empty.
+func (m *Manager) RegisterPlayer(ctx context.Context, discordID, characterName string, profile store.Profile) (*store.Player, error) {
 	ctx, span := m.tracer.Start(ctx, "Manager.RegisterPlayer",
 		trace.WithAttributes(
 			attribute.String("discord_id", discordID),
@@ -41,10 +137,21 @@ func (m *Manager) RegisterPlayer(ctx context.Context, discordID, characterName s
 	)
 	defer span.End()
 
+	return idempotency.Do(ctx, m.dedup, "dkp.register", func(ctx context.Context) (*store.Player, error) {
+		return m.registerPlayer(ctx, discordID, characterName, profile)
+	})
+}
+
+func (m *Manager) registerPlayer(ctx context.Context, discordID, characterName string, profile store.Profile) (*store.Player, error) {
+	profile, err := normalizeProfile(profile)
+	if err != nil {
+		return nil, err
+	}
 	p := &store.Player{
 		DiscordID:     discordID,
 		CharacterName: characterName,
 		DKP:           0,
+		Profile:       profile,
 	}
 	if err := m.players.Create(ctx, p); err != nil {
 		return nil, fmt.Errorf("creating player: %w", err)
@@ -53,6 +160,10 @@ func (m *Manager) RegisterPlayer(ctx context.Context, discordID, characterName s
 	data, _ := json.Marshal(event.PlayerRegisteredData{
 		DiscordID:     discordID,
 		CharacterName: characterName,
+		Class:         profile.Class,
+		Role:          profile.Role,
+		Spec:          profile.Spec,
+		Timezone:      profile.Timezone,
 	})
 	evt := event.Event{
 		AggregateID: p.ID,
@@ -71,41 +182,69 @@ func (m *Manager) RegisterPlayer(ctx context.Context, discordID, characterName s
 	return p, nil
 }
 
-// AwardDKP adds DKP to a player.
-func (m *Manager) AwardDKP(ctx context.Context, playerID string, amount int, reason string) error {
-	ctx, span := m.tracer.Start(ctx, "Manager.AwardDKP",
-		trace.WithAttributes(
-			attribute.String("player_id", playerID),
-			attribute.Int("amount", amount),
-		),
+// UpdateProfile replaces the profile of the player playerID and returns the
+// profile as stored, trimmed and with its role lowercased.
+func (m *Manager) UpdateProfile(ctx context.Context, playerID string, profile store.Profile) (store.Profile, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.UpdateProfile",
+		trace.WithAttributes(attribute.String("player_id", playerID)),
 	)
 	defer span.End()
 
-	if err := m.players.UpdateDKP(ctx, playerID, amount); err != nil {
-		return fmt.Errorf("awarding DKP: %w", err)
+	return idempotency.Do(ctx, m.dedup, "dkp.profile", func(ctx context.Context) (store.Profile, error) {
+		return m.updateProfile(ctx, playerID, profile)
+	})
+}
+
+func (m *Manager) updateProfile(ctx context.Context, playerID string, profile store.Profile) (store.Profile, error) {
+	profile, err := normalizeProfile(profile)
+	if err != nil {
+		return store.Profile{}, err
+	}
+	if err := m.players.UpdateProfile(ctx, playerID, profile); err != nil {
+		return store.Profile{}, fmt.Errorf("updating profile: %w", err)
 	}
 
-	data, _ := json.Marshal(event.DKPChangeData{
-		PlayerID: playerID,
-		Amount:   amount,
-		Reason:   reason,
+	data, _ := json.Marshal(event.PlayerProfileUpdatedData{
+		Class:    profile.Class,
+		Role:     profile.Role,
+		Spec:     profile.Spec,
+		Timezone: profile.Timezone,
 	})
 	evt := event.Event{
 		AggregateID: playerID,
-		Type:        event.DKPAwarded,
+		Type:        event.PlayerProfileUpdated,
 		Data:        data,
 		Version:     0,
 	}
 	if err := m.events.Append(ctx, evt); err != nil {
-		m.logger.ErrorContext(ctx, "failed to append DKP awarded event", slog.Any("error", err))
+		m.logger.ErrorContext(ctx, "failed to append profile updated event", slog.Any("error", err))
 	}
 
-	m.logger.InfoContext(ctx, "DKP awarded",
+	m.logger.InfoContext(ctx, "player profile updated",
 		slog.String("player_id", playerID),
-		slog.Int("amount", amount),
-		slog.String("reason", reason),
+		slog.String("role", profile.Role),
 	)
-	return nil
+	return profile, nil
+}
+
+// AwardDKP adds DKP to a player.
+func (m *Manager) AwardDKP(ctx context.Context, playerID string, amount int, reason string) error {
+	ctx, span := m.tracer.Start(ctx, "Manager.AwardDKP",
+		trace.WithAttributes(
+			attribute.String("player_id", playerID),
+			attribute.Int("amount", amount),
+		),
+	)
+	defer span.End()
+
+	_, err := idempotency.Do(ctx, m.dedup, "dkp.award", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, m.awardDKP(ctx, playerID, amount, reason)
+	})
+	return err
+}
+
+func (m *Manager) awardDKP(ctx context.Context, playerID string, amount int, reason string) error {
+	return m.change(ctx, dkpLedger{players: m.players}, awarded, playerID, amount, reason)
 }
 
 // DeductDKP removes DKP from a player.
@@ -118,31 +257,139 @@ func (m *Manager) DeductDKP(ctx context.Context, playerID string, amount int, re
 	)
 	defer span.End()
 
-	if err := m.players.UpdateDKP(ctx, playerID, -amount); err != nil {
-		return fmt.Errorf("deducting DKP: %w", err)
+	_, err := idempotency.Do(ctx, m.dedup, "dkp.deduct", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, m.deductDKP(ctx, playerID, amount, reason)
+	})
+	return err
+}
+
+func (m *Manager) deductDKP(ctx context.Context, playerID string, amount int, reason string) error {
+	return m.change(ctx, dkpLedger{players: m.players}, deducted, playerID, -amount, reason)
+}
+
+// Reversal describes a DKP change reversed by Undo.
+type Reversal struct {
+	// EventID identifies the reversed event.
+	EventID   string     `json:"event_id"`
+	Type      event.Type `json:"type"`
+	Amount    int        `json:"amount"`
+	Reason    string     `json:"reason"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Undo reverses a DKP change of a player by recording a compensating
+// adjustment. It reverses the event eventID or, if eventID is empty, the
+// player's most recent change not yet undone. Changes older than window,
+// or DefaultUndoWindow if window is not positive, cannot be undone, and
+// neither can transfers, which changed two players.
+func (m *Manager) Undo(ctx context.Context, playerID, eventID string, window time.Duration) (Reversal, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.Undo",
+		trace.WithAttributes(
+			attribute.String("player_id", playerID),
+			attribute.String("event_id", eventID),
+		),
+	)
+	defer span.End()
+
+	return idempotency.Do(ctx, m.dedup, "dkp.undo", func(ctx context.Context) (Reversal, error) {
+		return m.undo(ctx, playerID, eventID, window)
+	})
+}
+
+func (m *Manager) undo(ctx context.Context, playerID, eventID string, window time.Duration) (Reversal, error) {
+	if window <= 0 {
+		window = DefaultUndoWindow
+	}
+	events, err := m.events.Load(ctx, playerID)
+	if err != nil {
+		return Reversal{}, fmt.Errorf("loading DKP history: %w", err)
+	}
+
+	// Collect the changes and which of them were undone.
+	var changes []event.Event
+	data := make(map[string]event.DKPChangeData)
+	undone := make(map[string]bool)
+	for _, e := range events {
+		switch e.Type {
+		case event.DKPAwarded, event.DKPDeducted, event.DKPAdjusted:
+		default:
+			continue
+		}
+		var d event.DKPChangeData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return Reversal{}, fmt.Errorf("decoding event %s: %w", e.ID, err)
+		}
+		if d.Undoes != "" {
+			undone[d.Undoes] = true
+		}
+		changes = append(changes, e)
+		data[e.ID] = d
+	}
+
+	var target *event.Event
+	for i := len(changes) - 1; i >= 0; i-- {
+		e := &changes[i]
+		if eventID != "" {
+			if e.ID != eventID {
+				continue
+			}
+			switch {
+			case data[e.ID].Undoes != "":
+				return Reversal{}, ErrNotUndoable
+			case data[e.ID].Counterparty != "":
+				return Reversal{}, ErrTransferUndo
+			case undone[e.ID]:
+				return Reversal{}, ErrAlreadyUndone
+			}
+		} else if data[e.ID].Undoes != "" || data[e.ID].Counterparty != "" || undone[e.ID] {
+			continue
+		}
+		target = e
+		break
+	}
+	if target == nil && eventID != "" {
+		return Reversal{}, ErrNothingToUndo.Wrap(fmt.Errorf("player %s has no DKP change %s", playerID, eventID))
+	}
+	if target == nil {
+		return Reversal{}, ErrNothingToUndo
+	}
+	if age := m.clock.Now().Sub(target.CreatedAt); age > window {
+		return Reversal{}, ErrUndoExpired.Wrap(fmt.Errorf("event %s is %s old, the limit is %s", target.ID, age.Round(time.Minute), window))
+	}
+
+	orig := data[target.ID]
+	if err := m.players.UpdateDKP(ctx, playerID, -orig.Amount); err != nil {
+		return Reversal{}, fmt.Errorf("undoing DKP change: %w", err)
 	}
 
-	data, _ := json.Marshal(event.DKPChangeData{
+	payload, _ := json.Marshal(event.DKPChangeData{
 		PlayerID: playerID,
-		Amount:   -amount,
-		Reason:   reason,
+		Amount:   -orig.Amount,
+		Reason:   "undo: " + orig.Reason,
+		Undoes:   target.ID,
 	})
 	evt := event.Event{
 		AggregateID: playerID,
-		Type:        event.DKPDeducted,
-		Data:        data,
+		Type:        event.DKPAdjusted,
+		Data:        payload,
 		Version:     0,
 	}
 	if err := m.events.Append(ctx, evt); err != nil {
-		m.logger.ErrorContext(ctx, "failed to append DKP deducted event", slog.Any("error", err))
+		m.logger.ErrorContext(ctx, "failed to append DKP undo event", slog.Any("error", err))
 	}
 
-	m.logger.InfoContext(ctx, "DKP deducted",
+	m.logger.InfoContext(ctx, "DKP change undone",
 		slog.String("player_id", playerID),
-		slog.Int("amount", amount),
-		slog.String("reason", reason),
+		slog.String("event_id", target.ID),
+		slog.Int("amount", -orig.Amount),
 	)
-	return nil
+	return Reversal{
+		EventID:   target.ID,
+		Type:      target.Type,
+		Amount:    orig.Amount,
+		Reason:    orig.Reason,
+		CreatedAt: target.CreatedAt,
+	}, nil
 }
 
 // GetPlayer returns a player by Discord ID.
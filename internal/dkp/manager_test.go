@@ -2,109 +2,25 @@ package dkp_test
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
 	"go.opentelemetry.io/otel/trace/noop"
 
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
 )
 
 var testTP = noop.NewTracerProvider()
 
-// mockPlayerRepo implements store.PlayerRepository for testing.
-type mockPlayerRepo struct {
-	players map[string]*store.Player
-	err     error
-}
-
-func newMockPlayerRepo() *mockPlayerRepo {
-	return &mockPlayerRepo{players: make(map[string]*store.Player)}
-}
-
-func (m *mockPlayerRepo) Create(_ context.Context, p *store.Player) error {
-	if m.err != nil {
-		return m.err
-	}
-	p.ID = "test-id-" + p.DiscordID
-	m.players[p.DiscordID] = p
-	return nil
-}
-
-func (m *mockPlayerRepo) GetByDiscordID(_ context.Context, discordID string) (*store.Player, error) {
-	if m.err != nil {
-		return nil, m.err
-	}
-	p, ok := m.players[discordID]
-	if !ok {
-		return nil, fmt.Errorf("player not found")
-	}
-	return p, nil
-}
-
-func (m *mockPlayerRepo) GetByCharacterName(_ context.Context, name string) (*store.Player, error) {
-	for _, p := range m.players {
-		if p.CharacterName == name {
-			return p, nil
-		}
-	}
-	return nil, fmt.Errorf("player not found")
-}
-
-func (m *mockPlayerRepo) List(_ context.Context) ([]store.Player, error) {
-	result := make([]store.Player, 0, len(m.players))
-	for _, p := range m.players {
-		result = append(result, *p)
-	}
-	return result, nil
-}
-
-func (m *mockPlayerRepo) UpdateDKP(_ context.Context, id string, delta int) error {
-	if m.err != nil {
-		return m.err
-	}
-	for _, p := range m.players {
-		if p.ID == id {
-			p.DKP += delta
-			return nil
-		}
-	}
-	return fmt.Errorf("player %s not found", id)
-}
-
-// mockEventStore implements event.Store for testing.
-type mockEventStore struct {
-	events []event.Event
-}
-
-func (m *mockEventStore) Append(_ context.Context, events ...event.Event) error {
-	m.events = append(m.events, events...)
-	return nil
-}
-
-func (m *mockEventStore) Load(_ context.Context, aggregateID string) ([]event.Event, error) {
-	var result []event.Event
-	for _, e := range m.events {
-		if e.AggregateID == aggregateID {
-			result = append(result, e)
-		}
-	}
-	return result, nil
-}
-
-func (m *mockEventStore) LoadByType(_ context.Context, eventType event.Type) ([]event.Event, error) {
-	var result []event.Event
-	for _, e := range m.events {
-		if e.Type == eventType {
-			result = append(result, e)
-		}
-	}
-	return result, nil
-}
-
 func TestManager_RegisterPlayer(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -122,12 +38,12 @@ func TestManager_RegisterPlayer(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			repo := newMockPlayerRepo()
-			es := &mockEventStore{}
+			repo := storetest.NewPlayers()
+			es := eventtest.NewStore()
 			logger := slog.Default()
 			mgr := dkp.NewManager(repo, es, logger, testTP)
 
-			p, err := mgr.RegisterPlayer(context.Background(), tt.discordID, tt.characterName)
+			p, err := mgr.RegisterPlayer(context.Background(), tt.discordID, tt.characterName, store.Profile{})
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("RegisterPlayer() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -135,14 +51,75 @@ func TestManager_RegisterPlayer(t *testing.T) {
 				if p.CharacterName != tt.characterName {
 					t.Errorf("character = %q, want %q", p.CharacterName, tt.characterName)
 				}
-				if len(es.events) != 1 {
-					t.Errorf("events = %d, want 1", len(es.events))
+				if len(es.Events()) != 1 {
+					t.Errorf("events = %d, want 1", len(es.Events()))
 				}
 			}
 		})
 	}
 }
 
+func TestManager_UpdateProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile store.Profile
+		want    store.Profile
+		wantErr error
+	}{
+		{
+			name:    "normalized",
+			profile: store.Profile{Class: " Warrior ", Role: "Tank", Spec: "Protection"},
+			want:    store.Profile{Class: "Warrior", Role: dkp.RoleTank, Spec: "Protection"},
+		},
+		{
+			name:    "empty",
+			profile: store.Profile{},
+			want:    store.Profile{},
+		},
+		{
+			name:    "unknown role",
+			profile: store.Profile{Role: "bard"},
+			wantErr: dkp.ErrInvalidProfile,
+		},
+		{
+			name:    "long class",
+			profile: store.Profile{Class: strings.Repeat("x", 33)},
+			wantErr: dkp.ErrInvalidProfile,
+		},
+		{
+			name:    "timezone",
+			profile: store.Profile{Timezone: " Europe/Copenhagen "},
+			want:    store.Profile{Timezone: "Europe/Copenhagen"},
+		},
+		{
+			name:    "unknown timezone",
+			profile: store.Profile{Timezone: "Local"},
+			wantErr: dkp.ErrInvalidProfile,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := storetest.NewPlayers()
+			es := eventtest.NewStore()
+			mgr := dkp.NewManager(repo, es, slog.Default(), testTP)
+			p, _ := mgr.RegisterPlayer(context.Background(), "d1", "Gimli", store.Profile{})
+
+			got, err := mgr.UpdateProfile(context.Background(), p.ID, tt.profile)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateProfile() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got != tt.want || repo.Player(t, "d1").Profile != tt.want {
+				t.Errorf("profile = %+v, stored %+v, want %+v", got, repo.Player(t, "d1").Profile, tt.want)
+			}
+			es.RequireTypes(t, event.PlayerRegistered, event.PlayerProfileUpdated)
+		})
+	}
+}
+
 func TestManager_AwardDKP(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -167,50 +144,125 @@ func TestManager_AwardDKP(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			repo := newMockPlayerRepo()
-			es := &mockEventStore{}
+			repo := storetest.NewPlayers()
+			es := eventtest.NewStore()
 			logger := slog.Default()
 			mgr := dkp.NewManager(repo, es, logger, testTP)
 
 			// Register player first.
-			p, _ := mgr.RegisterPlayer(context.Background(), "d1", "Legolas")
+			p, _ := mgr.RegisterPlayer(context.Background(), "d1", "Legolas", store.Profile{})
 
 			err := mgr.AwardDKP(context.Background(), p.ID, tt.amount, tt.reason)
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("AwardDKP() error = %v, wantErr %v", err, tt.wantErr)
 			}
-			if !tt.wantErr && p.DKP != tt.wantDKP {
-				t.Errorf("DKP = %d, want %d", p.DKP, tt.wantDKP)
+			if !tt.wantErr {
+				repo.RequireDKP(t, "d1", tt.wantDKP)
 			}
 		})
 	}
 }
 
 func TestManager_DeductDKP(t *testing.T) {
-	repo := newMockPlayerRepo()
-	es := &mockEventStore{}
+	repo := storetest.NewPlayers()
+	es := eventtest.NewStore()
 	logger := slog.Default()
 	mgr := dkp.NewManager(repo, es, logger, testTP)
 
-	p, _ := mgr.RegisterPlayer(context.Background(), "d1", "Aragorn")
+	p, _ := mgr.RegisterPlayer(context.Background(), "d1", "Aragorn", store.Profile{})
 	_ = mgr.AwardDKP(context.Background(), p.ID, 100, "seed")
 
 	err := mgr.DeductDKP(context.Background(), p.ID, 30, "item purchased")
 	if err != nil {
 		t.Fatalf("DeductDKP() error: %v", err)
 	}
-	if p.DKP != 70 {
-		t.Errorf("DKP = %d, want 70", p.DKP)
+	repo.RequireDKP(t, "d1", 70)
+}
+
+func TestManager_Undo(t *testing.T) {
+	start := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		// undo lists the event IDs to undo in turn; "" undoes the latest.
+		undo     []string
+		after    time.Duration
+		wantDKP  int
+		wantCode string
+	}{
+		{name: "latest change", undo: []string{""}, wantDKP: 100},
+		{name: "walks back past undone changes", undo: []string{"", ""}, wantDKP: 0},
+		{name: "specific event", undo: []string{"evt-2"}, wantDKP: -30},
+		{name: "already undone", undo: []string{"evt-3", "evt-3"}, wantDKP: 100, wantCode: "ALREADY_UNDONE"},
+		{name: "undo of an undo", undo: []string{"", "evt-4"}, wantDKP: 100, wantCode: "NOT_UNDOABLE"},
+		{name: "unknown event", undo: []string{"evt-99"}, wantDKP: 70, wantCode: "NOTHING_TO_UNDO"},
+		{name: "not a DKP change", undo: []string{"evt-1"}, wantDKP: 70, wantCode: "NOTHING_TO_UNDO"},
+		{name: "nothing left", undo: []string{"", "", ""}, wantDKP: 0, wantCode: "NOTHING_TO_UNDO"},
+		{name: "outside window", undo: []string{""}, after: 25 * time.Hour, wantDKP: 70, wantCode: "UNDO_EXPIRED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := storetest.NewPlayers()
+			es := eventtest.NewStore(eventtest.WithClock(clock.Mock{T: start}))
+			mgr := dkp.NewManager(repo, es, slog.Default(), testTP, dkp.WithClock(clock.Mock{T: start.Add(tt.after)}))
+			ctx := context.Background()
+
+			// evt-1 registers, evt-2 awards 100, evt-3 deducts 30.
+			p, _ := mgr.RegisterPlayer(ctx, "d1", "Boromir", store.Profile{})
+			_ = mgr.AwardDKP(ctx, p.ID, 100, "raid")
+			_ = mgr.DeductDKP(ctx, p.ID, 30, "sword")
+
+			var err error
+			for _, id := range tt.undo {
+				if _, err = mgr.Undo(ctx, p.ID, id, 24*time.Hour); err != nil {
+					break
+				}
+			}
+			gotCode := ""
+			if err != nil {
+				gotCode = derrors.CodeOf(err)
+			}
+			if gotCode != tt.wantCode {
+				t.Fatalf("Undo() error = %v, want code %q", err, tt.wantCode)
+			}
+			repo.RequireDKP(t, "d1", tt.wantDKP)
+		})
+	}
+}
+
+func TestManager_Undo_RecordsAdjustment(t *testing.T) {
+	repo := storetest.NewPlayers()
+	es := eventtest.NewStore()
+	mgr := dkp.NewManager(repo, es, slog.Default(), testTP, dkp.WithClock(clock.Mock{}))
+	ctx := context.Background()
+
+	p, _ := mgr.RegisterPlayer(ctx, "d1", "Faramir", store.Profile{})
+	_ = mgr.DeductDKP(ctx, p.ID, 40, "bow")
+
+	r, err := mgr.Undo(ctx, p.ID, "", time.Hour)
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if r.EventID != "evt-2" || r.Type != event.DKPDeducted || r.Amount != -40 || r.Reason != "bow" {
+		t.Errorf("Undo() = %+v, want the deduction of 40 for bow", r)
+	}
+
+	last := es.Last(t)
+	d := eventtest.Data[event.DKPChangeData](t, last)
+	want := event.DKPChangeData{PlayerID: p.ID, Amount: 40, Reason: "undo: bow", Undoes: "evt-2"}
+	if last.Type != event.DKPAdjusted || d != want {
+		t.Errorf("recorded %s %+v, want %s %+v", last.Type, d, event.DKPAdjusted, want)
 	}
 }
 
 func TestManager_GetPlayer(t *testing.T) {
-	repo := newMockPlayerRepo()
-	es := &mockEventStore{}
+	repo := storetest.NewPlayers()
+	es := eventtest.NewStore()
 	logger := slog.Default()
 	mgr := dkp.NewManager(repo, es, logger, testTP)
 
-	_, _ = mgr.RegisterPlayer(context.Background(), "d-get", "Frodo")
+	_, _ = mgr.RegisterPlayer(context.Background(), "d-get", "Frodo", store.Profile{})
 
 	p, err := mgr.GetPlayer(context.Background(), "d-get")
 	if err != nil {
@@ -222,8 +274,8 @@ func TestManager_GetPlayer(t *testing.T) {
 }
 
 func TestManager_GetPlayer_NotFound(t *testing.T) {
-	repo := newMockPlayerRepo()
-	es := &mockEventStore{}
+	repo := storetest.NewPlayers()
+	es := eventtest.NewStore()
 	logger := slog.Default()
 	mgr := dkp.NewManager(repo, es, logger, testTP)
 
@@ -234,13 +286,13 @@ func TestManager_GetPlayer_NotFound(t *testing.T) {
 }
 
 func TestManager_ListPlayers(t *testing.T) {
-	repo := newMockPlayerRepo()
-	es := &mockEventStore{}
+	repo := storetest.NewPlayers()
+	es := eventtest.NewStore()
 	logger := slog.Default()
 	mgr := dkp.NewManager(repo, es, logger, testTP)
 
-	_, _ = mgr.RegisterPlayer(context.Background(), "d1", "Sam")
-	_, _ = mgr.RegisterPlayer(context.Background(), "d2", "Pippin")
+	_, _ = mgr.RegisterPlayer(context.Background(), "d1", "Sam", store.Profile{})
+	_, _ = mgr.RegisterPlayer(context.Background(), "d2", "Pippin", store.Profile{})
 
 	players, err := mgr.ListPlayers(context.Background())
 	if err != nil {
@@ -252,21 +304,21 @@ func TestManager_ListPlayers(t *testing.T) {
 }
 
 func TestManager_RegisterPlayer_RepoError(t *testing.T) {
-	repo := newMockPlayerRepo()
-	repo.err = fmt.Errorf("db error")
-	es := &mockEventStore{}
+	repo := storetest.NewPlayers()
+	repo.Fail("Create", errors.New("db error"))
+	es := eventtest.NewStore()
 	logger := slog.Default()
 	mgr := dkp.NewManager(repo, es, logger, testTP)
 
-	_, err := mgr.RegisterPlayer(context.Background(), "d1", "Boromir")
+	_, err := mgr.RegisterPlayer(context.Background(), "d1", "Boromir", store.Profile{})
 	if err == nil {
 		t.Fatal("expected error when repo returns error")
 	}
 }
 
 func TestManager_AwardDKP_PlayerNotFound(t *testing.T) {
-	repo := newMockPlayerRepo()
-	es := &mockEventStore{}
+	repo := storetest.NewPlayers()
+	es := eventtest.NewStore()
 	logger := slog.Default()
 	mgr := dkp.NewManager(repo, es, logger, testTP)
 
@@ -277,8 +329,8 @@ func TestManager_AwardDKP_PlayerNotFound(t *testing.T) {
 }
 
 func TestManager_DeductDKP_PlayerNotFound(t *testing.T) {
-	repo := newMockPlayerRepo()
-	es := &mockEventStore{}
+	repo := storetest.NewPlayers()
+	es := eventtest.NewStore()
 	logger := slog.Default()
 	mgr := dkp.NewManager(repo, es, logger, testTP)
 
@@ -0,0 +1,146 @@
+package dkp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// Drift is a player whose stored balance differs from the sum of their DKP
+// history.
+type Drift struct {
+	PlayerID      string
+	DiscordID     string
+	CharacterName string
+	// Stored is the balance of the players table and Computed the sum of
+	// the player's DKP change events.
+	Stored   int
+	Computed int
+	// Fixed reports whether the stored balance was set to Computed.
+	// Archived players are not fixed, as their DKP is frozen.
+	Fixed    bool
+	Archived bool
+}
+
+// Reconciliation reports a run of Reconcile.
+type Reconciliation struct {
+	Players int
+	Drifts  []Drift
+}
+
+// Unfixed returns how many drifts were left as they were.
+func (r Reconciliation) Unfixed() int {
+	n := 0
+	for _, d := range r.Drifts {
+		if !d.Fixed {
+			n++
+		}
+	}
+	return n
+}
+
+// Reconcile recomputes the balance of every player from their DKP history
+// and reports the players whose stored balance differs. Such drift is left
+// by a change whose balance update or event append failed, or by changes
+// that predate the event log. If fix is true, stored balances are set to
+// the computed ones, with the history taken as the record of truth.
+//
+// A player is only fixed if their drift is unchanged when read again, so
+// that a change made while Reconcile runs is not mistaken for drift.
+func (m *Manager) Reconcile(ctx context.Context, fix bool) (Reconciliation, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.Reconcile",
+		trace.WithAttributes(attribute.Bool("fix", fix)),
+	)
+	defer span.End()
+
+	players, err := m.players.List(ctx)
+	if err != nil {
+		return Reconciliation{}, fmt.Errorf("listing players: %w", err)
+	}
+	events, err := m.events.Query(ctx, event.Query{Types: changeTypes})
+	if err != nil {
+		return Reconciliation{}, fmt.Errorf("querying DKP changes: %w", err)
+	}
+	sums, err := sumChanges(events)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+
+	report := Reconciliation{Players: len(players)}
+	for _, p := range players {
+		if p.DKP == sums[p.ID] {
+			continue
+		}
+		d := Drift{
+			PlayerID:      p.ID,
+			DiscordID:     p.DiscordID,
+			CharacterName: p.CharacterName,
+			Stored:        p.DKP,
+			Computed:      sums[p.ID],
+			Archived:      p.Archived(),
+		}
+		if fix && !d.Archived {
+			if d.Fixed, err = m.fixDrift(ctx, d); err != nil {
+				return report, err
+			}
+		}
+		m.logger.WarnContext(ctx, "DKP balance drifted from history",
+			slog.String("player_id", d.PlayerID),
+			slog.Int("stored", d.Stored),
+			slog.Int("computed", d.Computed),
+			slog.Bool("fixed", d.Fixed),
+		)
+		report.Drifts = append(report.Drifts, d)
+	}
+	span.SetAttributes(attribute.Int("drifts", len(report.Drifts)))
+	return report, nil
+}
+
+// fixDrift sets the stored balance of the player of d to d.Computed, and
+// reports false without doing so if the player's drift has changed since d
+// was computed.
+func (m *Manager) fixDrift(ctx context.Context, d Drift) (bool, error) {
+	p, err := m.players.GetByDiscordID(ctx, d.DiscordID)
+	if err != nil {
+		return false, fmt.Errorf("rereading player %s: %w", d.PlayerID, err)
+	}
+	events, err := m.events.Query(ctx, event.Query{Types: changeTypes, AggregateID: d.PlayerID})
+	if err != nil {
+		return false, fmt.Errorf("rereading DKP history of %s: %w", d.PlayerID, err)
+	}
+	sums, err := sumChanges(events)
+	if err != nil {
+		return false, err
+	}
+	if p.DKP != d.Stored || sums[d.PlayerID] != d.Computed {
+		return false, nil
+	}
+	if err := m.players.UpdateDKP(ctx, d.PlayerID, d.Computed-d.Stored); err != nil {
+		if errors.Is(err, store.ErrPlayerArchived) {
+			return false, nil
+		}
+		return false, fmt.Errorf("fixing balance of %s: %w", d.PlayerID, err)
+	}
+	return true, nil
+}
+
+// sumChanges sums the amounts of DKP change events by aggregate.
+func sumChanges(events []event.Event) (map[string]int, error) {
+	sums := make(map[string]int)
+	for _, e := range events {
+		var d event.DKPChangeData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+		}
+		sums[e.AggregateID] += d.Amount
+	}
+	return sums, nil
+}
@@ -0,0 +1,70 @@
+package dkp_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+func TestManager_Reconcile(t *testing.T) {
+	archivedAt := time.Now()
+	repo := storetest.NewPlayers(
+		store.Player{ID: "p1", DiscordID: "d1", CharacterName: "Frodo", DKP: 100},
+		store.Player{ID: "p2", DiscordID: "d2", CharacterName: "Sam", DKP: 10},
+		store.Player{ID: "p3", DiscordID: "d3", CharacterName: "Merry", DKP: 5, ArchivedAt: &archivedAt},
+	)
+	es := eventtest.NewStore()
+	change := func(typ event.Type, playerID string, amount int) event.Event {
+		data, _ := json.Marshal(event.DKPChangeData{PlayerID: playerID, Amount: amount})
+		return event.Event{AggregateID: playerID, Type: typ, Data: data}
+	}
+	ctx := context.Background()
+	if err := es.Append(ctx,
+		change(event.DKPAwarded, "p1", 80),
+		change(event.DKPDeducted, "p1", -20),
+		change(event.DKPAwarded, "p2", 10),
+	); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	mgr := dkp.NewManager(repo, es, slog.New(slog.DiscardHandler), testTP)
+
+	report, err := mgr.Reconcile(ctx, false)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	want := []dkp.Drift{
+		{PlayerID: "p1", DiscordID: "d1", CharacterName: "Frodo", Stored: 100, Computed: 60},
+		{PlayerID: "p3", DiscordID: "d3", CharacterName: "Merry", Stored: 5, Computed: 0, Archived: true},
+	}
+	if report.Players != 3 || len(report.Drifts) != len(want) {
+		t.Fatalf("Reconcile() = %+v, want 3 players with drifts %+v", report, want)
+	}
+	for i, d := range report.Drifts {
+		if d != want[i] {
+			t.Errorf("drift %d = %+v, want %+v", i, d, want[i])
+		}
+	}
+	repo.RequireDKP(t, "d1", 100)
+
+	report, err = mgr.Reconcile(ctx, true)
+	if err != nil {
+		t.Fatalf("Reconcile(fix) error = %v", err)
+	}
+	if len(report.Drifts) != 2 || !report.Drifts[0].Fixed || report.Unfixed() != 1 {
+		t.Errorf("Reconcile(fix) = %+v, want Frodo fixed and the archived Merry left", report)
+	}
+	repo.RequireDKP(t, "d1", 60)
+	repo.RequireDKP(t, "d3", 5)
+
+	if report, err := mgr.Reconcile(ctx, false); err != nil || len(report.Drifts) != 1 {
+		t.Errorf("Reconcile() after fixing = %+v, %v, want only Merry", report, err)
+	}
+}
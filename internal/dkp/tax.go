@@ -0,0 +1,128 @@
+package dkp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// Tax charges amount DKP of tax on the auction auctionID to its winner
+// winnerID and shares it evenly among recipientIDs, and returns the
+// recipients awarded a share and the share. A recipient whose share cannot
+// be awarded is left out, and what is not shared is burned. Nothing is
+// shared if the tax is too small to give each recipient at least 1 DKP.
+func (m *Manager) Tax(ctx context.Context, auctionID, winnerID string, amount int, recipientIDs []string) (recipients []string, share int, err error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.Tax",
+		trace.WithAttributes(
+			attribute.String("auction_id", auctionID),
+			attribute.String("player_id", winnerID),
+			attribute.Int("amount", amount),
+		),
+	)
+	defer span.End()
+
+	l := dkpLedger{players: m.players}
+	if err := m.change(ctx, l, deducted, winnerID, -amount, fmt.Sprintf("tax on auction %s", auctionID)); err != nil {
+		return nil, 0, err
+	}
+	if len(recipientIDs) == 0 {
+		return nil, 0, nil
+	}
+	share = amount / len(recipientIDs)
+	if share == 0 {
+		return nil, 0, nil
+	}
+	for _, id := range recipientIDs {
+		if err := m.change(ctx, l, awarded, id, share, fmt.Sprintf("share of the tax on auction %s", auctionID)); err != nil {
+			m.logger.WarnContext(ctx, "sharing auction tax failed",
+				slog.String("auction_id", auctionID),
+				slog.String("player_id", id),
+				slog.Any("error", err),
+			)
+			continue
+		}
+		recipients = append(recipients, id)
+	}
+	return recipients, share, nil
+}
+
+// EconomyWeek is a Week with the DKP held at its end and the auction tax
+// charged in it.
+type EconomyWeek struct {
+	Week
+	// Supply is the DKP held by all players at the end of the week, or now
+	// for the current week.
+	Supply int
+	// Taxed is the auction tax charged in the week, of which Shared was
+	// shared among raid participants and the rest burned.
+	Taxed  int
+	Shared int
+}
+
+// Burned returns the auction tax burned in the week.
+func (w EconomyWeek) Burned() int {
+	return w.Taxed - w.Shared
+}
+
+// Inflation returns how much the DKP supply grew in the week, in percent
+// of the supply at its start, or zero if there was none.
+func (w EconomyWeek) Inflation() float64 {
+	start := w.Supply - w.Net()
+	if start <= 0 {
+		return 0
+	}
+	return float64(w.Net()) / float64(start) * 100
+}
+
+// Economy returns the DKP supply and auction tax of the last n weeks, the
+// current one included, oldest first. The supply is worked back from the
+// DKP players hold now.
+func (m *Manager) Economy(ctx context.Context, n int) ([]EconomyWeek, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.Economy",
+		trace.WithAttributes(attribute.Int("weeks", n)),
+	)
+	defer span.End()
+
+	weeks, err := m.Weekly(ctx, n)
+	if err != nil || len(weeks) == 0 {
+		return nil, err
+	}
+	players, err := m.players.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing players: %w", err)
+	}
+	supply := 0
+	for _, p := range players {
+		supply += p.DKP
+	}
+	economy := make([]EconomyWeek, len(weeks))
+	for i := len(weeks) - 1; i >= 0; i-- {
+		economy[i] = EconomyWeek{Week: weeks[i], Supply: supply}
+		supply -= weeks[i].Net()
+	}
+
+	taxes, err := m.events.Query(ctx, event.Query{Types: []event.Type{event.AuctionTaxed}, Since: weeks[0].Start})
+	if err != nil {
+		return nil, fmt.Errorf("querying auction taxes: %w", err)
+	}
+	for _, e := range taxes {
+		var d event.AuctionTaxedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+		}
+		i := int(weekStart(e.CreatedAt).Sub(weeks[0].Start) / (7 * 24 * time.Hour))
+		if i < 0 || i >= len(economy) {
+			continue
+		}
+		economy[i].Taxed += d.Amount
+		economy[i].Shared += d.Share * len(d.Recipients)
+	}
+	return economy, nil
+}
@@ -0,0 +1,75 @@
+package dkp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+func TestManager_Tax(t *testing.T) {
+	ctx := context.Background()
+	archivedAt := time.Now()
+	players := storetest.NewPlayers(
+		store.Player{ID: "p1", DiscordID: "d1", DKP: 100},
+		store.Player{ID: "p2", DiscordID: "d2"},
+		store.Player{ID: "p3", DiscordID: "d3", ArchivedAt: &archivedAt},
+	)
+	es := eventtest.NewStore()
+	mgr := dkp.NewManager(players, es, slog.New(slog.DiscardHandler), testTP)
+
+	recipients, share, err := mgr.Tax(ctx, "auction-1", "p1", 9, []string{"p2", "p3"})
+	if err != nil || share != 4 || !slices.Equal(recipients, []string{"p2"}) {
+		t.Fatalf("Tax() = %v, %d, %v, want 4 DKP shared with p2 only", recipients, share, err)
+	}
+	players.RequireDKP(t, "d1", 91)
+	players.RequireDKP(t, "d2", 4)
+	es.RequireTypes(t, event.DKPDeducted, event.DKPAwarded)
+
+	if recipients, share, err := mgr.Tax(ctx, "auction-2", "p1", 1, []string{"p2", "p3"}); err != nil || share != 0 || recipients != nil {
+		t.Errorf("Tax() too small to share = %v, %d, %v, want it burned", recipients, share, err)
+	}
+	if _, _, err := mgr.Tax(ctx, "auction-3", "p3", 5, nil); !errors.Is(err, store.ErrPlayerArchived) {
+		t.Errorf("Tax() of an archived winner error = %v, want ErrPlayerArchived", err)
+	}
+}
+
+func TestManager_Economy(t *testing.T) {
+	// Wednesday, in the week starting Monday 2025-06-16.
+	now := time.Date(2025, 6, 18, 12, 0, 0, 0, time.UTC)
+	at := &clock.Mock{T: time.Date(2025, 6, 10, 20, 0, 0, 0, time.UTC)}
+	players := storetest.NewPlayers()
+	es := eventtest.NewStore(eventtest.WithClock(at))
+	mgr := dkp.NewManager(players, es, slog.New(slog.DiscardHandler), testTP, dkp.WithClock(clock.Mock{T: now}))
+	ctx := context.Background()
+
+	p, _ := mgr.RegisterPlayer(ctx, "d1", "Boromir", store.Profile{})
+	_ = mgr.AwardDKP(ctx, p.ID, 200, "raid")
+	at.T = time.Date(2025, 6, 17, 20, 0, 0, 0, time.UTC)
+	_ = mgr.AwardDKP(ctx, p.ID, 60, "raid")
+	if _, _, err := mgr.Tax(ctx, "auction-1", p.ID, 10, nil); err != nil {
+		t.Fatalf("Tax() error = %v", err)
+	}
+	_ = es.Append(ctx, event.Event{AggregateID: "auction-1", Type: event.AuctionTaxed, Version: 3,
+		Data: []byte(`{"winner_id":"` + p.ID + `","rate":10,"amount":10}`)})
+
+	weeks, err := mgr.Economy(ctx, 2)
+	if err != nil || len(weeks) != 2 {
+		t.Fatalf("Economy() = %+v, %v, want 2 weeks", weeks, err)
+	}
+	if w := weeks[0]; w.Supply != 200 || w.Net() != 200 || w.Inflation() != 0 {
+		t.Errorf("first week = %+v, inflation %g, want a supply of 200 from nothing", w, w.Inflation())
+	}
+	if w := weeks[1]; w.Supply != 250 || w.Taxed != 10 || w.Burned() != 10 || w.Inflation() != 25 {
+		t.Errorf("second week = %+v, inflation %g, want 250 DKP, 10 burned, and 25%% inflation", w, w.Inflation())
+	}
+}
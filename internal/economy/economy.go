@@ -0,0 +1,248 @@
+// Package economy projects the DKP economy from the event log and the
+// players' balances for /dkp-economy: the DKP in circulation, how much flows
+// in as awards and out as loot each week, what auctions sell items for, and
+// how concentrated the DKP is, so that officers can tune award and decay
+// rates with data.
+package economy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/gdkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// week is the length of a week of the report.
+const week = 7 * 24 * time.Hour
+
+// lootTypes are the event types that record DKP spent on loot.
+var lootTypes = []event.Type{
+	event.AuctionClosed, event.AuctionBoughtOut, event.RaffleEntered, event.ItemCostAwarded,
+}
+
+// Supply reports the DKP supply and its changes by week.
+type Supply interface {
+	Economy(ctx context.Context, n int) ([]dkp.EconomyWeek, error)
+}
+
+// Players lists the registered players.
+type Players interface {
+	List(ctx context.Context) ([]store.Player, error)
+}
+
+// Week is the DKP economy of a week, which starts on Monday at midnight
+// UTC. Its Awarded DKP is the inflow.
+type Week struct {
+	dkp.EconomyWeek
+	// Loot is the DKP spent on loot in the week: the prices of items won
+	// in DKP auctions, raffle tickets, and items awarded at a fixed cost.
+	Loot int
+	// Sold is how many items DKP auctions sold in the week, for SoldFor
+	// DKP in all.
+	Sold    int
+	SoldFor int
+}
+
+// AveragePrice returns the average price of the items DKP auctions sold in
+// the week, or zero if they sold none.
+func (w Week) AveragePrice() float64 {
+	if w.Sold == 0 {
+		return 0
+	}
+	return float64(w.SoldFor) / float64(w.Sold)
+}
+
+// Other returns the DKP that left the economy in the week other than as
+// loot, such as by decay, penalties, and auction tax.
+func (w Week) Other() int {
+	return max(w.Spent-w.Loot, 0)
+}
+
+// sell adds an item a DKP auction sold for price.
+func (w *Week) sell(price int) {
+	w.Loot += price
+	w.Sold++
+	w.SoldFor += price
+}
+
+// Report is the DKP economy of the last weeks.
+type Report struct {
+	// Weeks are the weeks, the current one included, oldest first.
+	Weeks []Week
+	// Players is how many players are active, and Gini how concentrated
+	// their DKP is: 0 if they all hold the same, approaching 1 if one of
+	// them holds it all. Negative balances count as none.
+	Players int
+	Gini    float64
+}
+
+// Supply returns the DKP held by all players now.
+func (r *Report) Supply() int {
+	if len(r.Weeks) == 0 {
+		return 0
+	}
+	return r.Weeks[len(r.Weeks)-1].Supply
+}
+
+// Projection computes the DKP economy from the event log, the DKP supply,
+// and the players' balances.
+type Projection struct {
+	events  event.Store
+	supply  Supply
+	players Players
+	tracer  trace.Tracer
+}
+
+// NewProjection returns a Projection of events, the weekly DKP supply of
+// supply, and the balances of players.
+func NewProjection(events event.Store, supply Supply, players Players, tp trace.TracerProvider) *Projection {
+	return &Projection{
+		events:  events,
+		supply:  supply,
+		players: players,
+		tracer:  tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/economy"),
+	}
+}
+
+// Report returns the DKP economy of the last n weeks, the current one
+// included.
+func (p *Projection) Report(ctx context.Context, n int) (*Report, error) {
+	ctx, span := p.tracer.Start(ctx, "Projection.Report",
+		trace.WithAttributes(attribute.Int("weeks", n)),
+	)
+	defer span.End()
+
+	supply, err := p.supply.Economy(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{Weeks: make([]Week, len(supply))}
+	for i, w := range supply {
+		r.Weeks[i] = Week{EconomyWeek: w}
+	}
+	if len(r.Weeks) > 0 {
+		if err := p.loot(ctx, r.Weeks); err != nil {
+			return nil, err
+		}
+	}
+
+	players, err := p.players.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing players: %w", err)
+	}
+	var balances []int
+	for _, pl := range players {
+		if !pl.Archived() {
+			balances = append(balances, max(pl.DKP, 0))
+		}
+	}
+	r.Players, r.Gini = len(balances), Gini(balances)
+	return r, nil
+}
+
+// loot adds the DKP spent on loot and the items sold by DKP auctions to
+// weeks.
+func (p *Projection) loot(ctx context.Context, weeks []Week) error {
+	spent, err := p.events.Query(ctx, event.Query{Types: lootTypes, Since: weeks[0].Start})
+	if err != nil {
+		return fmt.Errorf("querying loot: %w", err)
+	}
+	auctions := make(map[string]bool)
+	for _, e := range spent {
+		if e.Type == event.AuctionClosed || e.Type == event.AuctionBoughtOut {
+			auctions[e.AggregateID] = false
+		}
+	}
+	inDKP, err := p.inDKP(ctx, auctions)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range spent {
+		i := int(e.CreatedAt.Sub(weeks[0].Start) / week)
+		if e.CreatedAt.Before(weeks[0].Start) || i >= len(weeks) {
+			continue
+		}
+		w := &weeks[i]
+		switch e.Type {
+		case event.AuctionClosed:
+			var d event.AuctionClosedData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return fmt.Errorf("decoding event %s: %w", e.ID, err)
+			}
+			if d.WinnerID != "" && inDKP[e.AggregateID] {
+				w.sell(d.Amount)
+			}
+		case event.AuctionBoughtOut:
+			var d event.AuctionBoughtOutData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return fmt.Errorf("decoding event %s: %w", e.ID, err)
+			}
+			if inDKP[e.AggregateID] {
+				w.sell(d.Amount)
+			}
+		case event.RaffleEntered:
+			var d event.RaffleEnteredData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return fmt.Errorf("decoding event %s: %w", e.ID, err)
+			}
+			w.Loot += d.Cost
+		case event.ItemCostAwarded:
+			var d event.ItemCostData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return fmt.Errorf("decoding event %s: %w", e.ID, err)
+			}
+			w.Loot += d.Cost
+		}
+	}
+	return nil
+}
+
+// inDKP sets which of the keys of auctions are auctions bid on in DKP,
+// rather than in gold or other points, and returns auctions.
+func (p *Projection) inDKP(ctx context.Context, auctions map[string]bool) (map[string]bool, error) {
+	if len(auctions) == 0 {
+		return auctions, nil
+	}
+	started, err := p.events.Query(ctx, event.Query{Types: []event.Type{event.AuctionStarted}})
+	if err != nil {
+		return nil, fmt.Errorf("querying auction starts: %w", err)
+	}
+	for _, e := range started {
+		if _, ok := auctions[e.AggregateID]; !ok {
+			continue
+		}
+		var d event.AuctionStartedData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+		}
+		auctions[e.AggregateID] = d.Points == "" && (d.RaidID == "" || d.RaidMode == gdkp.ModeDKP)
+	}
+	return auctions, nil
+}
+
+// Gini returns the Gini coefficient of balances, which must not be
+// negative: 0 if they are all equal, approaching 1 as one holds more of
+// their sum. It returns 0 for no balances or a sum of 0.
+func Gini(balances []int) float64 {
+	sorted := slices.Sorted(slices.Values(balances))
+	var sum, weighted float64
+	for i, b := range sorted {
+		sum += float64(b)
+		weighted += float64(i+1) * float64(b)
+	}
+	if sum == 0 {
+		return 0
+	}
+	n := float64(len(sorted))
+	return 2*weighted/(n*sum) - (n+1)/n
+}
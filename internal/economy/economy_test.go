@@ -0,0 +1,96 @@
+package economy_test
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/economy"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+// weeks reports two weeks starting on Monday, June 9, 2025.
+type weeks struct{}
+
+func (weeks) Economy(context.Context, int) ([]dkp.EconomyWeek, error) {
+	start := time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC)
+	return []dkp.EconomyWeek{
+		{Week: dkp.Week{Start: start, Awarded: 300, Spent: 100}, Supply: 500},
+		{Week: dkp.Week{Start: start.AddDate(0, 0, 7), Awarded: 200, Spent: 150}, Supply: 550},
+	}, nil
+}
+
+func TestProjection_Report(t *testing.T) {
+	ctx := context.Background()
+	monday := time.Date(2025, 6, 9, 20, 0, 0, 0, time.UTC)
+	events := eventtest.NewStore()
+	add := func(aggregateID string, typ event.Type, at time.Time, data any) {
+		t.Helper()
+		raw, _ := json.Marshal(data)
+		if err := events.Append(ctx, event.Event{AggregateID: aggregateID, Type: typ, Data: raw, CreatedAt: at}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("auction-1", event.AuctionStarted, monday, event.AuctionStartedData{ItemName: "Ashkandi"})
+	add("auction-1", event.AuctionClosed, monday, event.AuctionClosedData{WinnerID: "p1", Amount: 60})
+	add("auction-2", event.AuctionStarted, monday, event.AuctionStartedData{ItemName: "Onyxia Scale Cloak"})
+	add("auction-2", event.AuctionBoughtOut, monday.Add(time.Hour), event.AuctionBoughtOutData{BuyerID: "p2", Amount: 20})
+	add("auction-3", event.AuctionStarted, monday, event.AuctionStartedData{ItemName: "Tier Token", Points: "EP"})
+	add("auction-3", event.AuctionClosed, monday, event.AuctionClosedData{WinnerID: "p1", Amount: 500})
+	add("auction-4", event.AuctionStarted, monday, event.AuctionStartedData{ItemName: "Pet Rock"})
+	add("auction-4", event.AuctionClosed, monday, event.AuctionClosedData{})
+	add("raffle-1", event.RaffleEntered, monday.AddDate(0, 0, 7), event.RaffleEnteredData{PlayerID: "p2", Tickets: 3, Cost: 15})
+	add(event.ItemCostsAggregateID, event.ItemCostAwarded, monday.AddDate(0, 0, 8), event.ItemCostData{ItemName: "Band of Accuria", Cost: 90, PlayerID: "p1"})
+
+	archived := monday
+	players := storetest.NewPlayers(
+		store.Player{ID: "p1", DiscordID: "d1", DKP: 300},
+		store.Player{ID: "p2", DiscordID: "d2", DKP: 100},
+		store.Player{ID: "p3", DiscordID: "d3", DKP: -20},
+		store.Player{ID: "p4", DiscordID: "d4", DKP: 150, ArchivedAt: &archived},
+	)
+	r, err := economy.NewProjection(events, weeks{}, players, noop.NewTracerProvider()).Report(ctx, 2)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+
+	if len(r.Weeks) != 2 || r.Supply() != 550 {
+		t.Fatalf("Report() = %d weeks with %d DKP, want 2 with 550", len(r.Weeks), r.Supply())
+	}
+	// The EP auction and the one without a winner are not DKP sales.
+	if w := r.Weeks[0]; w.Loot != 80 || w.Sold != 2 || w.AveragePrice() != 40 || w.Other() != 20 {
+		t.Errorf("first week = loot %d, %d sold at %.1f, other %d; want 80, 2 at 40.0, other 20", w.Loot, w.Sold, w.AveragePrice(), w.Other())
+	}
+	if w := r.Weeks[1]; w.Loot != 105 || w.Sold != 0 || w.AveragePrice() != 0 || w.Other() != 45 {
+		t.Errorf("second week = loot %d, %d sold at %.1f, other %d; want 105, none, other 45", w.Loot, w.Sold, w.AveragePrice(), w.Other())
+	}
+	// Balances 0, 100, and 300 without the archived player.
+	if want := 0.5; r.Players != 3 || math.Abs(r.Gini-want) > 1e-9 {
+		t.Errorf("Report() = %d players with Gini %.3f, want 3 with %.3f", r.Players, r.Gini, want)
+	}
+}
+
+func TestGini(t *testing.T) {
+	for _, tt := range []struct {
+		balances []int
+		want     float64
+	}{
+		{nil, 0},
+		{[]int{0, 0}, 0},
+		{[]int{50, 50, 50, 50}, 0},
+		{[]int{0, 0, 0, 100}, 0.75},
+		{[]int{100, 0}, 0.5},
+	} {
+		if got := economy.Gini(tt.balances); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Gini(%v) = %.3f, want %.3f", tt.balances, got, tt.want)
+		}
+	}
+}
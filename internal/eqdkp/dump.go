@@ -0,0 +1,109 @@
+package eqdkp
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+)
+
+// ErrInvalidExport is returned by Parse for input that is not a usable
+// EQDKP Plus export.
+var ErrInvalidExport = derrors.New(derrors.Validation, "INVALID_EXPORT", "not a valid EQDKP Plus XML export")
+
+// Dump is the subset of an EQDKP Plus data export that the importer uses:
+// the points export with each player's items and adjustments, and the raid
+// list with attendees.
+type Dump struct {
+	Players []Player `xml:"players>player"`
+	Raids   []Raid   `xml:"raids>raid"`
+}
+
+// Player is a character with its balance, items, and adjustments.
+type Player struct {
+	ID     string `xml:"id"`
+	Name   string `xml:"name"`
+	Points []struct {
+		PoolID  string `xml:"multidkp_id"`
+		Current int    `xml:"points_current"`
+	} `xml:"points>multidkp_points"`
+	Items       []Item       `xml:"items>item"`
+	Adjustments []Adjustment `xml:"adjustments>adjustment"`
+}
+
+// Balance returns the player's current points in the first DKP pool.
+// EQDKP Plus supports several pools; the bot tracks a single balance.
+func (p Player) Balance() int {
+	if len(p.Points) == 0 {
+		return 0
+	}
+	return p.Points[0].Current
+}
+
+// Item is an item awarded to a player.
+type Item struct {
+	ID     string    `xml:"id"`
+	Name   string    `xml:"name"`
+	Value  int       `xml:"value"`
+	Date   Timestamp `xml:"date_timestamp"`
+	RaidID string    `xml:"raid_id"`
+}
+
+// Adjustment is a manual change to a player's points.
+type Adjustment struct {
+	Value  int       `xml:"value"`
+	Reason string    `xml:"reason"`
+	Date   Timestamp `xml:"date_timestamp"`
+}
+
+// Raid is a raid with the points it awarded to each attendee.
+type Raid struct {
+	ID        string    `xml:"id"`
+	Date      Timestamp `xml:"date_timestamp"`
+	EventName string    `xml:"event_name"`
+	Note      string    `xml:"note"`
+	Value     int       `xml:"value"`
+	Attendees []string  `xml:"raid_attendees>raid_attendee"`
+}
+
+// Timestamp is a Unix timestamp in seconds, as EQDKP Plus exports them.
+type Timestamp struct {
+	time.Time
+}
+
+// UnmarshalXML decodes a Unix timestamp element. Empty elements are left as
+// the zero time.
+func (t *Timestamp) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v string
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil
+	}
+	secs, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q: %w", v, err)
+	}
+	t.Time = time.Unix(secs, 0).UTC()
+	return nil
+}
+
+// Parse decodes an EQDKP Plus XML export.
+func Parse(r io.Reader) (*Dump, error) {
+	var d Dump
+	if err := xml.NewDecoder(r).Decode(&d); err != nil {
+		return nil, ErrInvalidExport.Wrap(fmt.Errorf("decoding EQDKP export: %w", err))
+	}
+	for i, p := range d.Players {
+		if p.ID == "" || p.Name == "" {
+			return nil, ErrInvalidExport.Wrap(fmt.Errorf("player %d has no id or name", i))
+		}
+	}
+	return &d, nil
+}
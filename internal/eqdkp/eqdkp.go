@@ -0,0 +1,265 @@
+// Package eqdkp imports players, balances, raids, and item awards from
+// EQDKP Plus exports so that established guilds can migrate to the bot
+// without losing their history.
+package eqdkp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// Actor is recorded on every event written by the importer.
+const Actor = "eqdkp-import"
+
+// PlaceholderPrefix prefixes the Discord ID given to imported characters
+// that could not be linked to a Discord user.
+const PlaceholderPrefix = "eqdkp:"
+
+// ErrAlreadyImported is returned when the event log already contains
+// events written by the importer.
+var ErrAlreadyImported = derrors.New(derrors.Conflict, "ALREADY_IMPORTED", "an EQDKP export has already been imported")
+
+// Report summarizes an import.
+type Report struct {
+	Players     int
+	Linked      int // matched to an already registered player
+	Created     int
+	Raids       int
+	Awards      int
+	Items       int
+	Adjustments int
+	// Unlinked lists the characters created with a placeholder Discord ID.
+	Unlinked []string
+}
+
+// Importer writes an EQDKP Plus export into the store and event log.
+type Importer struct {
+	players store.PlayerRepository
+	events  event.Store
+	logger  *slog.Logger
+	tracer  trace.Tracer
+}
+
+// NewImporter returns a new Importer.
+func NewImporter(players store.PlayerRepository, events event.Store, logger *slog.Logger, tp trace.TracerProvider) *Importer {
+	return &Importer{
+		players: players,
+		events:  events,
+		logger:  logger,
+		tracer:  tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/eqdkp"),
+	}
+}
+
+// Import maps the characters in d onto players and replays their raid
+// attendance, items, and adjustments as DKP events at their original times.
+// Each item also becomes a closed auction so that it appears in auction
+// history. If the replayed history does not add up to the exported balance,
+// the difference is recorded as a reconciliation adjustment.
+//
+// Characters are matched to registered players by name, case-insensitively.
+// Otherwise links, which maps character names to Discord user IDs, decides
+// the Discord ID of the new player, falling back to a placeholder.
+//
+// With dryRun set nothing is written and the report describes what would be
+// imported.
+func (im *Importer) Import(ctx context.Context, d *Dump, links map[string]string, dryRun bool) (Report, error) {
+	ctx, span := im.tracer.Start(ctx, "Importer.Import",
+		trace.WithAttributes(
+			attribute.Int("players", len(d.Players)),
+			attribute.Bool("dry_run", dryRun),
+		),
+	)
+	defer span.End()
+
+	var report Report
+
+	prior, err := im.events.Query(ctx, event.Query{Actor: Actor, Limit: 1})
+	if err != nil {
+		return report, fmt.Errorf("checking for a previous import: %w", err)
+	}
+	if len(prior) > 0 {
+		return report, ErrAlreadyImported
+	}
+
+	registered, err := im.players.List(ctx)
+	if err != nil {
+		return report, fmt.Errorf("listing players: %w", err)
+	}
+	byName := make(map[string]store.Player, len(registered))
+	for _, p := range registered {
+		byName[strings.ToLower(p.CharacterName)] = p
+	}
+	linksByName := make(map[string]string, len(links))
+	for name, id := range links {
+		linksByName[strings.ToLower(name)] = id
+	}
+
+	ctx = event.WithActor(ctx, Actor)
+	history, raids := buildHistory(d)
+	report.Players = len(d.Players)
+	report.Raids = raids
+
+	for _, src := range d.Players {
+		target, linked := byName[strings.ToLower(src.Name)]
+		if linked {
+			report.Linked++
+		} else {
+			report.Created++
+			target = store.Player{DiscordID: linksByName[strings.ToLower(src.Name)], CharacterName: src.Name}
+			if target.DiscordID == "" {
+				target.DiscordID = PlaceholderPrefix + src.ID
+				report.Unlinked = append(report.Unlinked, src.Name)
+			}
+		}
+
+		entries := history[src.ID]
+		for _, en := range entries {
+			switch en.typ {
+			case event.DKPAwarded:
+				report.Awards++
+			case event.DKPDeducted:
+				report.Items++
+			case event.DKPAdjusted:
+				report.Adjustments++
+			}
+		}
+		if dryRun {
+			continue
+		}
+
+		if err := im.importPlayer(ctx, &target, linked, src, entries); err != nil {
+			return report, fmt.Errorf("importing %s: %w", src.Name, err)
+		}
+	}
+
+	im.logger.InfoContext(ctx, "EQDKP import complete",
+		slog.Int("players", report.Players),
+		slog.Int("created", report.Created),
+		slog.Int("raids", report.Raids),
+		slog.Bool("dry_run", dryRun),
+	)
+	return report, nil
+}
+
+func (im *Importer) importPlayer(ctx context.Context, p *store.Player, exists bool, src Player, entries []entry) error {
+	if !exists {
+		if err := im.players.Create(ctx, p); err != nil {
+			return fmt.Errorf("creating player: %w", err)
+		}
+		data, _ := json.Marshal(event.PlayerRegisteredData{
+			DiscordID:     p.DiscordID,
+			CharacterName: p.CharacterName,
+		})
+		if err := im.events.Append(ctx, event.Event{AggregateID: p.ID, Type: event.PlayerRegistered, Data: data}); err != nil {
+			return fmt.Errorf("appending registration: %w", err)
+		}
+	}
+
+	sum := 0
+	dkpEvents := make([]event.Event, 0, len(entries)+1)
+	for _, en := range entries {
+		sum += en.amount
+		data, _ := json.Marshal(event.DKPChangeData{PlayerID: p.ID, Amount: en.amount, Reason: en.reason})
+		dkpEvents = append(dkpEvents, event.Event{AggregateID: p.ID, Type: en.typ, Data: data, CreatedAt: en.at})
+	}
+	if diff := src.Balance() - sum; diff != 0 {
+		data, _ := json.Marshal(event.DKPChangeData{PlayerID: p.ID, Amount: diff, Reason: "EQDKP balance reconciliation"})
+		dkpEvents = append(dkpEvents, event.Event{AggregateID: p.ID, Type: event.DKPAdjusted, Data: data})
+	}
+
+	var itemEvents []event.Event
+	for i, it := range src.Items {
+		id := it.ID
+		if id == "" {
+			id = fmt.Sprintf("%s-%d", src.ID, i)
+		}
+		aggregateID := "eqdkp-item-" + id
+		started, _ := json.Marshal(event.AuctionStartedData{ItemName: it.Name, StartedBy: Actor})
+		closed, _ := json.Marshal(event.AuctionClosedData{WinnerID: p.ID, Amount: it.Value})
+		itemEvents = append(itemEvents,
+			event.Event{AggregateID: aggregateID, Type: event.AuctionStarted, Data: started, Version: 1, CreatedAt: it.Date.Time},
+			event.Event{AggregateID: aggregateID, Type: event.AuctionClosed, Data: closed, Version: 2, CreatedAt: it.Date.Time},
+		)
+	}
+
+	if err := im.players.UpdateDKP(ctx, p.ID, src.Balance()); err != nil {
+		return fmt.Errorf("setting balance: %w", err)
+	}
+	if len(dkpEvents) > 0 {
+		if err := im.events.Append(ctx, dkpEvents...); err != nil {
+			return fmt.Errorf("appending DKP history: %w", err)
+		}
+	}
+	if len(itemEvents) > 0 {
+		if err := im.events.Append(ctx, itemEvents...); err != nil {
+			return fmt.Errorf("appending item awards: %w", err)
+		}
+	}
+	return nil
+}
+
+// entry is a DKP change derived from a dump.
+type entry struct {
+	typ    event.Type
+	amount int
+	reason string
+	at     time.Time
+}
+
+// history groups the DKP changes in a dump by EQDKP player ID, oldest
+// first, and counts the raids attended by at least one exported player.
+func buildHistory(d *Dump) (byPlayer map[string][]entry, raids int) {
+	byPlayer = make(map[string][]entry)
+	known := make(map[string]bool, len(d.Players))
+	for _, p := range d.Players {
+		known[p.ID] = true
+	}
+
+	for _, r := range d.Raids {
+		reason := "Raid: " + r.EventName
+		if r.Note != "" {
+			reason += " (" + r.Note + ")"
+		}
+		attended := false
+		for _, id := range r.Attendees {
+			if !known[id] {
+				continue
+			}
+			attended = true
+			byPlayer[id] = append(byPlayer[id], entry{event.DKPAwarded, r.Value, reason, r.Date.Time})
+		}
+		if attended {
+			raids++
+		}
+	}
+
+	for _, p := range d.Players {
+		for _, it := range p.Items {
+			byPlayer[p.ID] = append(byPlayer[p.ID], entry{event.DKPDeducted, -it.Value, "Item: " + it.Name, it.Date.Time})
+		}
+		for _, adj := range p.Adjustments {
+			byPlayer[p.ID] = append(byPlayer[p.ID], entry{event.DKPAdjusted, adj.Value, adj.Reason, adj.Date.Time})
+		}
+	}
+
+	// Versions are assigned in append order, so keep each player's history
+	// chronological.
+	for id := range byPlayer {
+		slices.SortStableFunc(byPlayer[id], func(a, b entry) int {
+			return a.at.Compare(b.at)
+		})
+	}
+	return byPlayer, raids
+}
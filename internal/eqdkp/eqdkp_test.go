@@ -0,0 +1,142 @@
+package eqdkp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/eqdkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+func parseTestdata(t *testing.T) *eqdkp.Dump {
+	t.Helper()
+	f, err := os.Open("testdata/export.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := eqdkp.Parse(f)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return d
+}
+
+func TestParse(t *testing.T) {
+	d := parseTestdata(t)
+
+	if len(d.Players) != 2 || len(d.Raids) != 1 {
+		t.Fatalf("got %d players and %d raids, want 2 and 1", len(d.Players), len(d.Raids))
+	}
+	g := d.Players[0]
+	if g.Name != "Gandalf" || g.Balance() != 45 {
+		t.Errorf("got %s with balance %d, want Gandalf with 45", g.Name, g.Balance())
+	}
+	if len(g.Items) != 1 || g.Items[0].Value != 30 || !g.Items[0].Date.Equal(time.Unix(1717110000, 0)) {
+		t.Errorf("got items %+v, want one 30 DKP item at 1717110000", g.Items)
+	}
+	if got := d.Raids[0].Attendees; len(got) != 3 {
+		t.Errorf("got attendees %v, want 3", got)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+	}{
+		{name: "malformed", xml: "<response><players>"},
+		{name: "player without name", xml: "<response><players><player><id>1</id></player></players></response>"},
+		{name: "bad timestamp", xml: "<response><raids><raid><date_timestamp>yesterday</date_timestamp></raid></raids></response>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := eqdkp.Parse(strings.NewReader(tt.xml)); err == nil {
+				t.Error("Parse() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestImporter_Import(t *testing.T) {
+	players := storetest.NewPlayers(store.Player{ID: "existing", DiscordID: "d-frodo", CharacterName: "frodo", DKP: 10})
+	events := eventtest.NewStore()
+	im := eqdkp.NewImporter(players, events, slog.Default(), noop.NewTracerProvider())
+	d := parseTestdata(t)
+
+	if _, err := im.Import(context.Background(), d, nil, true); err != nil {
+		t.Fatalf("dry run error = %v", err)
+	}
+	stored, _ := players.List(context.Background())
+	if len(events.Events()) != 0 || len(stored) != 1 {
+		t.Fatalf("dry run wrote %d events and %d players", len(events.Events()), len(stored)-1)
+	}
+
+	report, err := im.Import(context.Background(), d, nil, false)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if report.Linked != 1 || report.Created != 1 || report.Raids != 1 || report.Awards != 2 || report.Items != 1 || report.Adjustments != 1 {
+		t.Errorf("got report %+v", report)
+	}
+	if len(report.Unlinked) != 1 || report.Unlinked[0] != "Gandalf" {
+		t.Errorf("got unlinked %v, want [Gandalf]", report.Unlinked)
+	}
+
+	players.RequireDKP(t, eqdkp.PlaceholderPrefix+"1", 45)
+	players.RequireDKP(t, "d-frodo", 60)
+	if got := players.Player(t, eqdkp.PlaceholderPrefix+"1").CharacterName; got != "Gandalf" {
+		t.Errorf("placeholder player = %s, want Gandalf", got)
+	}
+
+	// Frodo attended one 40 DKP raid but has 50 in EQDKP, so the import
+	// records a +10 reconciliation.
+	var frodo []event.DKPChangeData
+	for _, e := range events.Events() {
+		if e.Actor != eqdkp.Actor {
+			t.Errorf("event %s has actor %q, want %q", e.Type, e.Actor, eqdkp.Actor)
+		}
+		if e.AggregateID == "existing" {
+			frodo = append(frodo, eventtest.Data[event.DKPChangeData](t, e))
+		}
+	}
+	if len(frodo) != 2 || frodo[0].Amount != 40 || frodo[1].Amount != 10 {
+		t.Errorf("got Frodo history %+v, want raid award of 40 and reconciliation of 10", frodo)
+	}
+
+	closed := event.Query{Types: []event.Type{event.AuctionClosed}}.Filter(events.Events())
+	if len(closed) != 1 || closed[0].AggregateID != "eqdkp-item-77" {
+		t.Errorf("got closed auctions %+v, want eqdkp-item-77", closed)
+	}
+
+	if _, err := im.Import(context.Background(), d, nil, false); !errors.Is(err, eqdkp.ErrAlreadyImported) {
+		t.Errorf("second Import() error = %v, want ErrAlreadyImported", err)
+	}
+}
+
+func TestImporter_ImportLinks(t *testing.T) {
+	players := storetest.NewPlayers()
+	im := eqdkp.NewImporter(players, eventtest.NewStore(), slog.Default(), noop.NewTracerProvider())
+
+	report, err := im.Import(context.Background(), parseTestdata(t), map[string]string{"GANDALF": "d-gandalf"}, false)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(report.Unlinked) != 1 || report.Unlinked[0] != "Frodo" {
+		t.Errorf("got unlinked %v, want [Frodo]", report.Unlinked)
+	}
+	if got := players.Player(t, "d-gandalf").CharacterName; got != "Gandalf" {
+		t.Errorf("player linked to d-gandalf = %s, want Gandalf", got)
+	}
+}
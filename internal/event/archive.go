@@ -0,0 +1,31 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Snapshot captures the state of an aggregate as of a given version so that
+// the events leading up to it can be moved out of the hot log.
+type Snapshot struct {
+	AggregateID string          `json:"aggregate_id" db:"aggregate_id"`
+	Version     int             `json:"version" db:"version"`
+	State       json.RawMessage `json:"state" db:"state"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
+
+// Archive moves events out of the hot event log into cold storage.
+type Archive interface {
+	// SaveSnapshot stores or replaces the snapshot for an aggregate.
+	SaveSnapshot(ctx context.Context, s Snapshot) error
+	// LoadSnapshot returns the latest snapshot for an aggregate.
+	LoadSnapshot(ctx context.Context, aggregateID string) (*Snapshot, error)
+	// ArchiveAggregate atomically moves events of aggregateID with a version
+	// up to and including upToVersion into the archive and returns how many
+	// events were moved.
+	ArchiveAggregate(ctx context.Context, aggregateID string, upToVersion int) (int, error)
+	// Archived returns the archived events of the given types, or of every
+	// type if none are given, oldest first.
+	Archived(ctx context.Context, types ...Type) ([]Event, error)
+}
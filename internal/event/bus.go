@@ -0,0 +1,138 @@
+package event
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+)
+
+// Handler receives events delivered by a Bus.
+type Handler func(ctx context.Context, e Event)
+
+// Bus is an in-process publish/subscribe hub for domain events. Components
+// such as projections and notification dispatchers subscribe to the event
+// types they care about instead of polling the store.
+// It is safe for concurrent use.
+type Bus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]subscription
+
+	// Queues of the subscriptions made with SubscribeQueue.
+	queues  config.EventBusConfig
+	metrics *metrics.Recorder
+	logger  *slog.Logger
+}
+
+type subscription struct {
+	types   map[Type]struct{}
+	handler Handler
+}
+
+// BusOption configures a Bus.
+type BusOption func(*Bus)
+
+// WithQueues sizes the queues of the subscriptions made with
+// SubscribeQueue as cfg says, and records their depth and overflows with
+// recorder. Without it each queue holds 256 events and drops the oldest
+// when full.
+func WithQueues(cfg config.EventBusConfig, recorder *metrics.Recorder, logger *slog.Logger) BusOption {
+	return func(b *Bus) {
+		b.queues = cfg
+		b.metrics = recorder
+		b.logger = logger
+	}
+}
+
+// NewBus returns an empty Bus.
+func NewBus(opts ...BusOption) *Bus {
+	b := &Bus{
+		subs: make(map[int]subscription),
+		queues: config.EventBusConfig{
+			Default: config.QueueConfig{Size: 256, Overflow: config.OverflowDropOldest, BlockTimeout: time.Second},
+		},
+		metrics: metrics.Nop(),
+		logger:  slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Subscribe registers fn for the given event types. When no types are given
+// fn receives every event. The returned function removes the subscription.
+//
+// fn is called by Publish, so it holds up the command appending the events
+// and the subscribers after it; it must return quickly. Subscribers that
+// do I/O should use SubscribeQueue.
+func (b *Bus) Subscribe(fn Handler, types ...Type) (unsubscribe func()) {
+	s := subscription{handler: fn}
+	if len(types) > 0 {
+		s.types = make(map[Type]struct{}, len(types))
+		for _, t := range types {
+			s.types[t] = struct{}{}
+		}
+	}
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = s
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		delete(b.subs, id)
+		b.mu.Unlock()
+	}
+}
+
+// Publish delivers events to all matching subscribers synchronously, in the
+// order given.
+func (b *Bus) Publish(ctx context.Context, events ...Event) {
+	b.mu.RLock()
+	subs := make([]subscription, 0, len(b.subs))
+	for _, s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.RUnlock()
+
+	for _, e := range events {
+		for _, s := range subs {
+			if s.types != nil {
+				if _, ok := s.types[e.Type]; !ok {
+					continue
+				}
+			}
+			s.handler(ctx, e)
+		}
+	}
+}
+
+// PublishingStore decorates a Store so that events are published to a Bus
+// after every successful Append.
+type PublishingStore struct {
+	Store
+	bus *Bus
+}
+
+// NewPublishingStore wraps s so that appended events are published to bus.
+func NewPublishingStore(s Store, bus *Bus) *PublishingStore {
+	return &PublishingStore{Store: s, bus: bus}
+}
+
+// Append persists events via the underlying store and, on success,
+// publishes them to the bus as stored, with the IDs, versions, and times
+// the store filled in.
+func (s *PublishingStore) Append(ctx context.Context, events ...Event) error {
+	if err := s.Store.Append(ctx, events...); err != nil {
+		return err
+	}
+	s.bus.Publish(ctx, events...)
+	return nil
+}
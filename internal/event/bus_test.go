@@ -0,0 +1,128 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+)
+
+type memStore struct {
+	events []event.Event
+	err    error
+}
+
+func (m *memStore) Append(_ context.Context, events ...event.Event) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.events = append(m.events, events...)
+	return nil
+}
+
+func (m *memStore) Load(_ context.Context, _ string) ([]event.Event, error) { return m.events, nil }
+
+func (m *memStore) LoadByType(_ context.Context, _ event.Type) ([]event.Event, error) {
+	return m.events, nil
+}
+
+func (m *memStore) Query(_ context.Context, _ event.Query) ([]event.Event, error) {
+	return m.events, nil
+}
+
+func TestBus_SubscribeFiltersByType(t *testing.T) {
+	bus := event.NewBus()
+
+	var all, bids []event.Type
+	bus.Subscribe(func(_ context.Context, e event.Event) { all = append(all, e.Type) })
+	bus.Subscribe(func(_ context.Context, e event.Event) { bids = append(bids, e.Type) }, event.AuctionBidPlaced)
+
+	bus.Publish(context.Background(),
+		event.Event{Type: event.AuctionStarted},
+		event.Event{Type: event.AuctionBidPlaced},
+		event.Event{Type: event.AuctionClosed},
+	)
+
+	if len(all) != 3 {
+		t.Errorf("unfiltered subscriber got %d events, want 3", len(all))
+	}
+	if len(bids) != 1 || bids[0] != event.AuctionBidPlaced {
+		t.Errorf("filtered subscriber got %v, want [%s]", bids, event.AuctionBidPlaced)
+	}
+}
+
+func TestBus_Unsubscribe(t *testing.T) {
+	bus := event.NewBus()
+
+	n := 0
+	unsubscribe := bus.Subscribe(func(_ context.Context, _ event.Event) { n++ })
+	bus.Publish(context.Background(), event.Event{Type: event.DKPAwarded})
+	unsubscribe()
+	bus.Publish(context.Background(), event.Event{Type: event.DKPAwarded})
+
+	if n != 1 {
+		t.Errorf("handler called %d times, want 1", n)
+	}
+}
+
+func TestPublishingStore_Append(t *testing.T) {
+	tests := []struct {
+		name          string
+		storeErr      error
+		wantPublished int
+	}{
+		{name: "publishes after successful append", wantPublished: 2},
+		{name: "does not publish on append error", storeErr: errors.New("db down"), wantPublished: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := event.NewBus()
+			published := 0
+			bus.Subscribe(func(_ context.Context, _ event.Event) { published++ })
+
+			s := event.NewPublishingStore(&memStore{err: tt.storeErr}, bus)
+			err := s.Append(context.Background(),
+				event.Event{AggregateID: "a1", Type: event.AuctionStarted, Version: 1},
+				event.Event{AggregateID: "a1", Type: event.AuctionBidPlaced, Version: 2},
+			)
+			if (err != nil) != (tt.storeErr != nil) {
+				t.Fatalf("Append() error = %v, want %v", err, tt.storeErr)
+			}
+			if published != tt.wantPublished {
+				t.Errorf("published %d events, want %d", published, tt.wantPublished)
+			}
+		})
+	}
+}
+
+func TestPublishingStore_PublishesStoredEvents(t *testing.T) {
+	at := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+	bus := event.NewBus()
+	var published []event.Event
+	bus.Subscribe(func(_ context.Context, e event.Event) { published = append(published, e) })
+
+	// The events are appended as a DKP change is, without an ID, version,
+	// actor, or time, which the store fills in.
+	s := event.NewPublishingStore(eventtest.NewStore(eventtest.WithClock(clock.Mock{T: at})), bus)
+	ctx := event.WithActor(context.Background(), "officer")
+	if err := s.Append(ctx, event.Event{AggregateID: "p1", Type: event.DKPAwarded}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if err := s.Append(ctx, event.Event{AggregateID: "p1", Type: event.DKPDeducted}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	if len(published) != 2 {
+		t.Fatalf("published %d events, want 2", len(published))
+	}
+	for i, e := range published {
+		if e.ID == "" || e.Version != i+1 || e.Actor != "officer" || !e.CreatedAt.Equal(at) {
+			t.Errorf("published event %d = %+v, want it as stored: version %d by officer at %s", i, e, i+1, at)
+		}
+	}
+}
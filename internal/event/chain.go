@@ -0,0 +1,148 @@
+package event
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// ChainHash returns the hash that links e to the preceding event of the same
+// aggregate, whose chain hash is prevHash ("" for the first event). Editing
+// any earlier event changes every later chain hash, which makes silent
+// modification of the ledger detectable. With a key the hash is an
+// HMAC-SHA256, which cannot be recomputed by someone who can write to the
+// database but does not hold the key.
+func ChainHash(key []byte, prevHash string, e Event) string {
+	msg := []byte(prevHash + "\n" + e.ContentHash())
+	if len(key) == 0 {
+		sum := sha256.Sum256(msg)
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(msg)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ChainHead is the latest event of an aggregate's hash chain, recorded by
+// the store on every append so that removed events can be detected.
+type ChainHead struct {
+	AggregateID string `db:"aggregate_id"`
+	Version     int    `db:"version"`
+	ChainHash   string `db:"chain_hash"`
+}
+
+// ChainProblem describes an event whose stored hashes do not match the
+// recomputed chain.
+type ChainProblem struct {
+	AggregateID string
+	Version     int
+	Reason      string
+}
+
+func (p ChainProblem) String() string {
+	return fmt.Sprintf("%s v%d: %s", p.AggregateID, p.Version, p.Reason)
+}
+
+// ChainReport summarizes a verification of the event log.
+type ChainReport struct {
+	Aggregates int
+	Verified   int
+	// Unchained counts events recorded before hash chaining was introduced.
+	Unchained int
+	// Unkeyed counts events chained before a chain key was configured.
+	Unkeyed  int
+	Problems []ChainProblem
+}
+
+// VerifyChain recomputes the hash chain of every aggregate in events with
+// key and reports any event whose stored hashes disagree. Events may be
+// given in any order; they are grouped by aggregate and sorted by version.
+//
+// Unchained events, and with a key events chained without one, are only
+// accepted before the first event of their aggregate chained the stronger
+// way. If heads is not nil it holds the recorded head of each aggregate's
+// chain: the events must end at the head, and an aggregate with chained
+// events must have one, so that removing or unchaining events is reported.
+func VerifyChain(key []byte, events []Event, heads map[string]ChainHead) ChainReport {
+	byAggregate := make(map[string][]Event)
+	for _, e := range events {
+		byAggregate[e.AggregateID] = append(byAggregate[e.AggregateID], e)
+	}
+	for id := range heads {
+		if _, ok := byAggregate[id]; !ok {
+			byAggregate[id] = nil
+		}
+	}
+	ids := make([]string, 0, len(byAggregate))
+	for id := range byAggregate {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	report := ChainReport{Aggregates: len(ids)}
+	for _, id := range ids {
+		agg := byAggregate[id]
+		sort.Slice(agg, func(i, j int) bool { return agg[i].Version < agg[j].Version })
+		problem := func(version int, reason string) {
+			report.Problems = append(report.Problems, ChainProblem{id, version, reason})
+		}
+
+		// strength is how the chain of the aggregate has been kept so
+		// far: 0 unchained, 1 chained without a key, 2 chained with it.
+		prev, strength := "", 0
+		for _, e := range agg {
+			if e.ChainHash == "" {
+				if strength > 0 {
+					problem(e.Version, "unchained event follows chained events")
+				} else {
+					report.Unchained++
+				}
+				prev = ""
+				continue
+			}
+			if e.PrevHash != prev {
+				problem(e.Version, "previous hash does not match the preceding event")
+				prev = e.ChainHash
+				continue
+			}
+			switch {
+			case e.ChainHash == ChainHash(key, prev, e):
+				report.Verified++
+				strength = max(strength, 2)
+			case len(key) > 0 && e.ChainHash == ChainHash(nil, prev, e):
+				if strength == 2 {
+					problem(e.Version, "event chained without the key follows keyed events")
+				} else {
+					report.Unkeyed++
+					strength = 1
+				}
+			default:
+				problem(e.Version, "event content does not match its chain hash")
+			}
+			prev = e.ChainHash
+		}
+
+		if heads == nil {
+			continue
+		}
+		head, ok := heads[id]
+		var last Event
+		if len(agg) > 0 {
+			last = agg[len(agg)-1]
+		}
+		switch {
+		case !ok && last.ChainHash != "":
+			problem(last.Version, "chained aggregate has no recorded head")
+		case !ok:
+		case last.Version < head.Version:
+			problem(head.Version, fmt.Sprintf("events after version %d were removed", last.Version))
+		case last.Version > head.Version:
+			problem(last.Version, fmt.Sprintf("events after the recorded head at version %d were not appended by the store", head.Version))
+		case last.ChainHash != head.ChainHash:
+			problem(last.Version, "last event does not match the recorded head")
+		}
+	}
+	return report
+}
@@ -0,0 +1,250 @@
+package event_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// chain links events the way a store does on Append.
+func chain(events []event.Event) []event.Event {
+	prev := ""
+	for i := range events {
+		events[i].PrevHash = prev
+		events[i].ChainHash = event.ChainHash(nil, prev, events[i])
+		prev = events[i].ChainHash
+	}
+	return events
+}
+
+func ledger() []event.Event {
+	at := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	return chain([]event.Event{
+		{AggregateID: "p1", Type: event.PlayerRegistered, Data: json.RawMessage(`{"character_name":"Gandalf"}`), Version: 1, CreatedAt: at},
+		{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{"player_id":"p1","amount":50}`), Version: 2, CreatedAt: at},
+		{AggregateID: "p1", Type: event.DKPDeducted, Data: json.RawMessage(`{"player_id":"p1","amount":-20}`), Version: 3, CreatedAt: at},
+	})
+}
+
+func TestVerifyChain(t *testing.T) {
+	tests := []struct {
+		name         string
+		mutate       func(events []event.Event)
+		wantProblems int
+		wantVerified int
+	}{
+		{
+			name:         "intact ledger",
+			mutate:       func([]event.Event) {},
+			wantVerified: 3,
+		},
+		{
+			name: "edited amount",
+			mutate: func(events []event.Event) {
+				events[1].Data = json.RawMessage(`{"player_id":"p1","amount":500}`)
+			},
+			wantProblems: 1,
+			wantVerified: 2,
+		},
+		{
+			name: "deleted event",
+			mutate: func(events []event.Event) {
+				events[1] = events[2]
+				events[2] = event.Event{}
+			},
+			wantProblems: 1,
+		},
+		{
+			name: "whitespace and key order are not tampering",
+			mutate: func(events []event.Event) {
+				events[1].Data = json.RawMessage(`{ "amount": 50, "player_id": "p1" }`)
+			},
+			wantVerified: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := ledger()
+			tt.mutate(events)
+
+			var kept []event.Event
+			for _, e := range events {
+				if e.AggregateID != "" {
+					kept = append(kept, e)
+				}
+			}
+
+			report := event.VerifyChain(nil, kept, nil)
+			if len(report.Problems) != tt.wantProblems {
+				t.Errorf("problems = %v, want %d", report.Problems, tt.wantProblems)
+			}
+			if tt.wantVerified > 0 && report.Verified != tt.wantVerified {
+				t.Errorf("verified = %d, want %d", report.Verified, tt.wantVerified)
+			}
+		})
+	}
+}
+
+func TestVerifyChain_LegacyEvents(t *testing.T) {
+	events := ledger()
+	events[0].PrevHash, events[0].ChainHash = "", ""
+	events[1].PrevHash = ""
+	events[1].ChainHash = event.ChainHash(nil, "", events[1])
+	events[2].PrevHash = events[1].ChainHash
+	events[2].ChainHash = event.ChainHash(nil, events[2].PrevHash, events[2])
+
+	report := event.VerifyChain(nil, events, nil)
+	if report.Unchained != 1 || report.Verified != 2 || len(report.Problems) != 0 {
+		t.Errorf("report = %+v, want 1 unchained, 2 verified, no problems", report)
+	}
+}
+
+var chainKey = []byte("0123456789abcdef0123456789abcdef")
+
+// rechain links events from start on with key, as someone editing the
+// database would after changing them.
+func rechain(key []byte, events []event.Event, start int) {
+	prev := ""
+	if start > 0 {
+		prev = events[start-1].ChainHash
+	}
+	for i := start; i < len(events); i++ {
+		events[i].PrevHash = prev
+		events[i].ChainHash = event.ChainHash(key, prev, events[i])
+		prev = events[i].ChainHash
+	}
+}
+
+func heads(events []event.Event) map[string]event.ChainHead {
+	last := events[len(events)-1]
+	return map[string]event.ChainHead{last.AggregateID: {AggregateID: last.AggregateID, Version: last.Version, ChainHash: last.ChainHash}}
+}
+
+func TestVerifyChain_Keyed(t *testing.T) {
+	tests := []struct {
+		name         string
+		mutate       func(events []event.Event)
+		wantProblems int
+		wantUnkeyed  int
+	}{
+		{
+			name:   "intact ledger",
+			mutate: func([]event.Event) {},
+		},
+		{
+			name: "edited and rechained without the key",
+			mutate: func(events []event.Event) {
+				events[1].Data = json.RawMessage(`{"player_id":"p1","amount":500}`)
+				rechain(nil, events, 1)
+			},
+			wantProblems: 2,
+		},
+		{
+			name: "edited and rechained with another key",
+			mutate: func(events []event.Event) {
+				events[1].Data = json.RawMessage(`{"player_id":"p1","amount":500}`)
+				rechain([]byte("another key"), events, 1)
+			},
+			wantProblems: 2,
+		},
+		{
+			name: "chained without the key before the key was set",
+			mutate: func(events []event.Event) {
+				rechain(nil, events, 0)
+				rechain(chainKey, events, 2)
+			},
+			wantUnkeyed: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := ledger()
+			rechain(chainKey, events, 0)
+			tt.mutate(events)
+
+			report := event.VerifyChain(chainKey, events, nil)
+			if len(report.Problems) != tt.wantProblems {
+				t.Errorf("problems = %v, want %d", report.Problems, tt.wantProblems)
+			}
+			if report.Unkeyed != tt.wantUnkeyed {
+				t.Errorf("unkeyed = %d, want %d", report.Unkeyed, tt.wantUnkeyed)
+			}
+		})
+	}
+}
+
+func TestVerifyChain_UnchainedAfterChained(t *testing.T) {
+	events := ledger()
+	events[2].PrevHash, events[2].ChainHash = "", ""
+
+	report := event.VerifyChain(nil, events, nil)
+	if len(report.Problems) != 1 || report.Problems[0].Version != 3 {
+		t.Errorf("problems = %v, want one for v3", report.Problems)
+	}
+}
+
+func TestVerifyChain_Heads(t *testing.T) {
+	tests := []struct {
+		name         string
+		mutate       func(events []event.Event) []event.Event
+		wantProblems int
+	}{
+		{
+			name:   "intact ledger",
+			mutate: func(events []event.Event) []event.Event { return events },
+		},
+		{
+			name:         "last event removed",
+			mutate:       func(events []event.Event) []event.Event { return events[:2] },
+			wantProblems: 1,
+		},
+		{
+			name: "every event unchained",
+			mutate: func(events []event.Event) []event.Event {
+				for i := range events {
+					events[i].PrevHash, events[i].ChainHash = "", ""
+				}
+				return events
+			},
+			wantProblems: 1,
+		},
+		{
+			name:         "every event removed",
+			mutate:       func([]event.Event) []event.Event { return nil },
+			wantProblems: 1,
+		},
+		{
+			name: "event inserted past the head",
+			mutate: func(events []event.Event) []event.Event {
+				events = append(events, event.Event{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{}`), Version: 4})
+				rechain(chainKey, events, 3)
+				return events
+			},
+			wantProblems: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := ledger()
+			rechain(chainKey, events, 0)
+			recorded := heads(events)
+
+			report := event.VerifyChain(chainKey, tt.mutate(events), recorded)
+			if len(report.Problems) != tt.wantProblems {
+				t.Errorf("problems = %v, want %d", report.Problems, tt.wantProblems)
+			}
+		})
+	}
+}
+
+func TestVerifyChain_ChainedWithoutHead(t *testing.T) {
+	report := event.VerifyChain(nil, ledger(), map[string]event.ChainHead{})
+	if len(report.Problems) != 1 {
+		t.Errorf("problems = %v, want one for the missing head", report.Problems)
+	}
+}
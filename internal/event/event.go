@@ -1,7 +1,11 @@
 package event
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -13,14 +17,110 @@ const (
 	AuctionBidPlaced Type = "auction.bid_placed"
 	AuctionClosed    Type = "auction.closed"
 	AuctionCanceled  Type = "auction.canceled"
+	AuctionBoughtOut Type = "auction.bought_out"
+	// AuctionRollStarted and AuctionRolled record the roll held for the
+	// item of an auction closed without bids.
+	AuctionRollStarted Type = "auction.roll_started"
+	AuctionRolled      Type = "auction.rolled"
+	// AuctionWinnerSkipped records a highest bidder passed over at close
+	// because they could no longer afford their bid.
+	AuctionWinnerSkipped Type = "auction.winner_skipped"
+	// AuctionPaused and AuctionResumed bracket a pause of an auction, during
+	// which it takes no bids and its countdown stands still.
+	AuctionPaused  Type = "auction.paused"
+	AuctionResumed Type = "auction.resumed"
+	// AuctionQueued records an auction started while the guild's limit of
+	// open auctions was reached. It is followed by AuctionStarted once a
+	// slot frees up.
+	AuctionQueued Type = "auction.queued"
+	// AuctionScheduled records an auction started to open at a later
+	// time. It is followed by AuctionStarted when that time comes, or by
+	// AuctionQueued if the guild's limit of open auctions is reached then.
+	AuctionScheduled Type = "auction.scheduled"
+	// AuctionAnnounced records a Discord message announcing an auction,
+	// which is kept up to date with the highest bid.
+	AuctionAnnounced Type = "auction.announced"
+	// AuctionTaxed records the tax charged to the winner of a DKP auction
+	// on top of their winning bid, and who it was shared among.
+	AuctionTaxed Type = "auction.taxed"
 
 	DKPAwarded  Type = "dkp.awarded"
 	DKPDeducted Type = "dkp.deducted"
 	DKPAdjusted Type = "dkp.adjusted"
 
+	// Currency events change the balances of the currencies configured
+	// besides DKP. A transfer records one event on each player: the
+	// sender's with a negative amount.
+	CurrencyAwarded     Type = "currency.awarded"
+	CurrencyDeducted    Type = "currency.deducted"
+	CurrencyTransferred Type = "currency.transferred"
+
 	PlayerRegistered Type = "player.registered"
+	// PlayerProfileUpdated records a player changing their class, role,
+	// or spec.
+	PlayerProfileUpdated Type = "player.profile_updated"
+	// PlayerArchived records an officer archiving a player, which freezes
+	// their DKP, and PlayerRestored undoing it.
+	PlayerArchived Type = "player.archived"
+	PlayerRestored Type = "player.restored"
+	// PlayerLootBanned records an officer banning a player from bidding on
+	// loot until a time, and PlayerLootBanLifted ending the ban early. A
+	// later ban replaces an earlier one.
+	PlayerLootBanned    Type = "player.loot_banned"
+	PlayerLootBanLifted Type = "player.loot_ban_lifted"
+
+	// GDKP raid events keep the gold ledger of GDKP raids, apart from
+	// DKP balances. They also record DKP raids, which group auctions held
+	// in DKP by the raid they were held in.
+	GDKPRaidStarted Type = "gdkp.raid_started"
+	GDKPRaidJoined  Type = "gdkp.raid_joined"
+	GDKPRaidEnded   Type = "gdkp.raid_ended"
+
+	// Calendar events record raids scheduled ahead of time and the
+	// members signing up for them.
+	RaidScheduled    Type = "calendar.raid_scheduled"
+	RaidSignedUp     Type = "calendar.signed_up"
+	RaidReminded     Type = "calendar.reminded"
+	RaidBonusAwarded Type = "calendar.bonus_awarded"
+	RaidComposed     Type = "calendar.composed"
+
+	// Bank events record the custody of items held by the guild bank:
+	// deposited, handed to an auction, and returned if the auction ends
+	// without a winner.
+	BankItemDeposited Type = "bank.item_deposited"
+	BankItemAuctioned Type = "bank.item_auctioned"
+	BankItemReturned  Type = "bank.item_returned"
+
+	// Raffle events record raffles of items for DKP: started, tickets
+	// bought by a player, and the winning ticket drawn.
+	RaffleStarted Type = "raffle.started"
+	RaffleEntered Type = "raffle.entered"
+	RaffleDrawn   Type = "raffle.drawn"
+
+	// Item cost events maintain the item cost table of fixed-price loot:
+	// the cost of an item set or changed, an item removed, and an item
+	// awarded to a player at its cost.
+	ItemCostSet     Type = "itemcost.set"
+	ItemCostRemoved Type = "itemcost.removed"
+	ItemCostAwarded Type = "itemcost.awarded"
+
+	// Dispute events record tickets members open with officers about loot
+	// or as feedback, and their resolution.
+	DisputeOpened   Type = "dispute.opened"
+	DisputeResolved Type = "dispute.resolved"
+
+	// AdminCommandRun records an operator changing the database with
+	// `dkpbot admin`, bypassing Discord. The events of the change itself
+	// follow it with the same actor.
+	AdminCommandRun Type = "admin.command_run"
 )
 
+// AdminAggregateID is the aggregate of AdminCommandRun events.
+const AdminAggregateID = "admin"
+
+// ItemCostsAggregateID is the aggregate of the item cost table's events.
+const ItemCostsAggregateID = "itemcosts"
+
 // Event represents a single domain event.
 type Event struct {
 	ID          string          `json:"id" db:"id"`
@@ -28,27 +128,113 @@ type Event struct {
 	Type        Type            `json:"type" db:"type"`
 	Data        json.RawMessage `json:"data" db:"data"`
 	Version     int             `json:"version" db:"version"`
+	Actor       string          `json:"actor,omitempty" db:"actor"`
 	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	// PrevHash and ChainHash link the event into its aggregate's hash
+	// chain. They are assigned by the store on Append; see ChainHash.
+	PrevHash  string `json:"prev_hash,omitempty" db:"prev_hash"`
+	ChainHash string `json:"chain_hash,omitempty" db:"chain_hash"`
 }
 
-// AuctionStartedData is the payload for AuctionStarted events.
+// AuctionStartedData is the payload for AuctionStarted, AuctionQueued, and
+// AuctionScheduled events.
 type AuctionStartedData struct {
-	ItemName  string        `json:"item_name"`
-	StartedBy string        `json:"started_by"`
-	MinBid    int           `json:"min_bid"`
-	Duration  time.Duration `json:"duration"`
+	ItemName  string `json:"item_name"`
+	StartedBy string `json:"started_by"`
+	MinBid    int    `json:"min_bid"`
+	// MinIncrement is how much a bid must exceed the highest bid by. It is
+	// zero in events recorded before increments were configurable.
+	MinIncrement int           `json:"min_increment,omitempty"`
+	Duration     time.Duration `json:"duration"`
+	// Buyout is the price at which a player may win the item at once, or
+	// zero if the auction has none.
+	Buyout int `json:"buyout,omitempty"`
+	// RaidID is the raid the auction is held in, or empty.
+	RaidID string `json:"raid_id,omitempty"`
+	// RaidMode is "dkp" for an auction held in a DKP raid. Otherwise the
+	// raid, if any, is a GDKP raid whose bids are in gold rather than DKP.
+	RaidMode string `json:"raid_mode,omitempty"`
+	// Points is the currency the auction is bid in besides DKP, such as
+	// "EP", or empty for DKP.
+	Points string `json:"points,omitempty"`
+	// Reserve is the hidden lowest price the item is sold at, or zero if
+	// the auction has none.
+	Reserve int `json:"reserve,omitempty"`
+	// StartsAt is when a scheduled auction opens, or zero if it was not
+	// scheduled.
+	StartsAt time.Time `json:"starts_at,omitzero"`
 }
 
 // BidPlacedData is the payload for AuctionBidPlaced events.
 type BidPlacedData struct {
 	PlayerID string `json:"player_id"`
 	Amount   int    `json:"amount"`
+	// Outbid is the player whose highest bid this bid displaced, if any.
+	Outbid string `json:"outbid,omitempty"`
+}
+
+// AuctionAnnouncedData is the payload for AuctionAnnounced events.
+type AuctionAnnouncedData struct {
+	ChannelID string `json:"channel_id"`
+	MessageID string `json:"message_id"`
 }
 
 // AuctionClosedData is the payload for AuctionClosed events.
 type AuctionClosedData struct {
 	WinnerID string `json:"winner_id"`
 	Amount   int    `json:"amount"`
+	// Roll is the winning roll of an auction won by rolling, or zero if it
+	// was won by bidding.
+	Roll int `json:"roll,omitempty"`
+	// ReserveNotMet is set if the auction closed without a winner because
+	// its highest bid was below its reserve.
+	ReserveNotMet bool `json:"reserve_not_met,omitempty"`
+}
+
+// AuctionTaxedData is the payload for AuctionTaxed events.
+type AuctionTaxedData struct {
+	WinnerID string `json:"winner_id"`
+	// Rate is the tax rate in percent, and Amount the tax it came to.
+	Rate   float64 `json:"rate"`
+	Amount int     `json:"amount"`
+	// Recipients are the players the tax was shared among, who were each
+	// awarded Share. The rest of Amount was burned.
+	Recipients []string `json:"recipients,omitempty"`
+	Share      int      `json:"share,omitempty"`
+}
+
+// AuctionBoughtOutData is the payload for AuctionBoughtOut events, which
+// close an auction won at its buyout price.
+type AuctionBoughtOutData struct {
+	BuyerID string `json:"buyer_id"`
+	Amount  int    `json:"amount"`
+}
+
+// AuctionWinnerSkippedData is the payload for AuctionWinnerSkipped events.
+type AuctionWinnerSkippedData struct {
+	PlayerID string `json:"player_id"`
+	// Amount is the skipped bid and DKP the player's balance at close.
+	Amount int `json:"amount"`
+	DKP    int `json:"dkp"`
+}
+
+// AuctionResumedData is the payload for AuctionResumed events.
+type AuctionResumedData struct {
+	// Paused is how long the pause lasted, by which the auction's end is
+	// pushed back.
+	Paused time.Duration `json:"paused"`
+}
+
+// AuctionRollStartedData is the payload for AuctionRollStarted events.
+type AuctionRollStartedData struct {
+	// Until is when the roll ends.
+	Until time.Time `json:"until"`
+}
+
+// AuctionRolledData is the payload for AuctionRolled events.
+type AuctionRolledData struct {
+	PlayerID string `json:"player_id"`
+	Roll     int    `json:"roll"`
 }
 
 // DKPChangeData is the payload for DKP events.
@@ -56,10 +242,276 @@ type DKPChangeData struct {
 	PlayerID string `json:"player_id"`
 	Amount   int    `json:"amount"`
 	Reason   string `json:"reason"`
+	// Undoes is the ID of the event a compensating adjustment reverses.
+	Undoes string `json:"undoes,omitempty"`
+	// Correction is set on an adjustment that set the player's balance,
+	// rather than changing it by an amount. Amount is still the change.
+	Correction *DKPCorrection `json:"correction,omitempty"`
+	// Merge is set on an adjustment that imported a balance from another
+	// guild's data.
+	Merge *DKPMerge `json:"merge,omitempty"`
+	// Counterparty is set on the adjustments of a transfer to the other
+	// player's ID: the recipient on the sender's, and the sender on the
+	// recipient's.
+	Counterparty string `json:"counterparty,omitempty"`
+}
+
+// CurrencyChangeData is the payload for currency events.
+type CurrencyChangeData struct {
+	PlayerID string `json:"player_id"`
+	Currency string `json:"currency"`
+	Amount   int    `json:"amount"`
+	Reason   string `json:"reason"`
+	// Counterparty is set on CurrencyTransferred events as on
+	// DKPChangeData.
+	Counterparty string `json:"counterparty,omitempty"`
+}
+
+// DKPCorrection records the balances around a correction and the officer
+// who made it.
+type DKPCorrection struct {
+	From    int    `json:"from"`
+	To      int    `json:"to"`
+	Officer string `json:"officer"`
+}
+
+// DKPMerge records where a balance imported by a guild merge came from.
+type DKPMerge struct {
+	// ID correlates the events of one merge.
+	ID string `json:"id"`
+	// Character and Balance are the character and their balance in the
+	// other guild, before conversion by Ratio.
+	Character string  `json:"character"`
+	Balance   int     `json:"balance"`
+	Ratio     float64 `json:"ratio"`
 }
 
 // PlayerRegisteredData is the payload for PlayerRegistered events.
 type PlayerRegisteredData struct {
 	DiscordID     string `json:"discord_id"`
 	CharacterName string `json:"character_name"`
+	Class         string `json:"class,omitempty"`
+	Role          string `json:"role,omitempty"`
+	Spec          string `json:"spec,omitempty"`
+	Timezone      string `json:"timezone,omitempty"`
+}
+
+// PlayerProfileUpdatedData is the payload for PlayerProfileUpdated events.
+type PlayerProfileUpdatedData struct {
+	Class    string `json:"class"`
+	Role     string `json:"role"`
+	Spec     string `json:"spec"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// PlayerArchivedData is the payload for PlayerArchived and PlayerRestored
+// events.
+type PlayerArchivedData struct {
+	DiscordID string `json:"discord_id"`
+	// DKP is the balance frozen by archiving or thawed by restoring.
+	DKP    int    `json:"dkp"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// PlayerLootBanData is the payload for PlayerLootBanned and
+// PlayerLootBanLifted events.
+type PlayerLootBanData struct {
+	DiscordID string `json:"discord_id"`
+	// Until is when the ban ends. It is zero for PlayerLootBanLifted.
+	Until  time.Time `json:"until,omitzero"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// GDKPRaidStartedData is the payload for GDKPRaidStarted events.
+type GDKPRaidStartedData struct {
+	Name string `json:"name"`
+	// Organizer is the Discord ID of the member who started the raid and
+	// is paid the organizer cut.
+	Organizer string `json:"organizer"`
+	// OrganizerCut is the percentage of the pot paid to the organizer.
+	OrganizerCut float64 `json:"organizer_cut"`
+	// Mode is "dkp" for a DKP raid, and empty for a GDKP raid.
+	Mode string `json:"mode,omitempty"`
+}
+
+// GDKPRaidJoinedData is the payload for GDKPRaidJoined events.
+type GDKPRaidJoinedData struct {
+	DiscordID string `json:"discord_id"`
+}
+
+// GDKPRaidEndedData is the payload for GDKPRaidEnded events, which record
+// how the pot was paid out.
+type GDKPRaidEndedData struct {
+	Pot int `json:"pot"`
+	// OrganizerCut is the gold paid to the organizer, including what was
+	// left over after splitting the rest evenly.
+	OrganizerCut int `json:"organizer_cut"`
+	// Share is the gold paid to each participant.
+	Share        int      `json:"share"`
+	Participants []string `json:"participants"`
+}
+
+// RaidScheduledData is the payload for RaidScheduled events.
+type RaidScheduledData struct {
+	Name     string    `json:"name"`
+	StartsAt time.Time `json:"starts_at"`
+	// ScheduledBy is the Discord ID of the member who scheduled the raid.
+	ScheduledBy string `json:"scheduled_by"`
+	// Tanks, Healers, and DPS are how many members of each raid role the
+	// raid needs. Zero means no quota.
+	Tanks   int `json:"tanks,omitempty"`
+	Healers int `json:"healers,omitempty"`
+	DPS     int `json:"dps,omitempty"`
+	// Size is how many members the raid takes. Zero means the sum of the
+	// quotas.
+	Size int `json:"size,omitempty"`
+}
+
+// RaidSignedUpData is the payload for RaidSignedUp events. A member's
+// latest signup replaces their earlier ones.
+type RaidSignedUpData struct {
+	DiscordID string `json:"discord_id"`
+	// Status is "accepted", "tentative", or "declined".
+	Status string `json:"status"`
+	// Role is the member's raid role as of signing up, if known.
+	Role string `json:"role,omitempty"`
+}
+
+// RaidRemindedData is the payload for RaidReminded events.
+type RaidRemindedData struct {
+	// Recipients are the Discord IDs of the members reminded.
+	Recipients []string `json:"recipients"`
+}
+
+// RaidBonusAwardedData is the payload for RaidBonusAwarded events, which
+// record the on-time bonus awarded to members who signed up and showed.
+type RaidBonusAwardedData struct {
+	Amount int `json:"amount"`
+	// PlayerIDs are the players awarded the bonus.
+	PlayerIDs []string `json:"player_ids"`
+}
+
+// RaidComposedData is the payload for RaidComposed events, which record the
+// roster officers confirmed for a raid. A later composition replaces an
+// earlier one.
+type RaidComposedData struct {
+	// Roster and Standby are the Discord IDs of the members picked for the
+	// raid and of those benched, who stand by to fill in.
+	Roster  []string `json:"roster"`
+	Standby []string `json:"standby"`
+}
+
+// BankItemDepositedData is the payload for BankItemDeposited events.
+type BankItemDepositedData struct {
+	ItemName string `json:"item_name"`
+	// DepositedBy is the Discord ID of the member who banked the item.
+	DepositedBy string `json:"deposited_by"`
+	Note        string `json:"note,omitempty"`
+}
+
+// BankItemAuctionData is the payload for BankItemAuctioned and
+// BankItemReturned events.
+type BankItemAuctionData struct {
+	AuctionID string `json:"auction_id"`
+}
+
+// RaffleStartedData is the payload for RaffleStarted events.
+type RaffleStartedData struct {
+	ItemName string `json:"item_name"`
+	// TicketCost is the DKP a ticket costs.
+	TicketCost int `json:"ticket_cost"`
+	// MaxTickets is how many tickets a player may buy, or 0 for no limit.
+	MaxTickets int `json:"max_tickets,omitempty"`
+	// StartedBy is the Discord ID of the member who started the raffle.
+	StartedBy string `json:"started_by"`
+}
+
+// RaffleEnteredData is the payload for RaffleEntered events, which record
+// tickets bought by a player. The DKP they cost is deducted separately.
+type RaffleEnteredData struct {
+	PlayerID  string `json:"player_id"`
+	DiscordID string `json:"discord_id"`
+	Tickets   int    `json:"tickets"`
+	// Cost is the DKP paid for the tickets.
+	Cost int `json:"cost"`
+}
+
+// RaffleDrawnData is the payload for RaffleDrawn events.
+type RaffleDrawnData struct {
+	// Ticket is the winning ticket, numbered from 1 in the order tickets
+	// were bought, out of Tickets sold. Both are 0 if none were sold.
+	Ticket  int `json:"ticket"`
+	Tickets int `json:"tickets"`
+	// WinnerID is the player holding the winning ticket, or empty.
+	WinnerID string `json:"winner_id,omitempty"`
+}
+
+// ItemCostData is the payload for item cost events.
+type ItemCostData struct {
+	ItemName string `json:"item_name"`
+	// Cost is the DKP the item costs, or 0 for a removed item.
+	Cost int `json:"cost,omitempty"`
+	// PlayerID is the player an ItemCostAwarded event awarded the item
+	// to. The DKP it cost is deducted separately.
+	PlayerID string `json:"player_id,omitempty"`
+}
+
+// DisputeOpenedData is the payload for DisputeOpened events. An anonymous
+// dispute records neither who opened it nor, as its actor, on whose
+// behalf.
+type DisputeOpenedData struct {
+	Reason string `json:"reason"`
+	// AuctionID is the auction disputed, or empty for feedback.
+	AuctionID string `json:"auction_id,omitempty"`
+	// OpenedBy is the Discord ID of the member who opened the dispute, or
+	// empty if it is anonymous.
+	OpenedBy  string `json:"opened_by,omitempty"`
+	Anonymous bool   `json:"anonymous,omitempty"`
+	// ThreadID is the private thread the dispute is discussed in.
+	ThreadID string `json:"thread_id"`
+}
+
+// DisputeResolvedData is the payload for DisputeResolved events.
+type DisputeResolvedData struct {
+	Resolution string `json:"resolution"`
+	// ResolvedBy is the Discord ID of the officer who resolved the dispute.
+	ResolvedBy string `json:"resolved_by"`
+}
+
+// AdminCommandData is the payload for AdminCommandRun events.
+type AdminCommandData struct {
+	// Command is the command line as the operator entered it.
+	Command string `json:"command"`
+	// User and Host are the operating system user who ran the command and
+	// the machine it ran on.
+	User string `json:"user,omitempty"`
+	Host string `json:"host,omitempty"`
+}
+
+// ContentHash returns a hex-encoded SHA-256 digest of the event's
+// identifying fields and payload. The store-assigned ID is excluded so that
+// the hash survives export and re-import into another deployment, and the
+// payload is canonicalized so that storage-level JSON normalization (such as
+// Postgres JSONB key reordering) does not change the digest.
+func (e Event) ContentHash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%d\n%s\n%s\n", e.AggregateID, e.Type, e.Version, e.Actor, e.CreatedAt.UTC().Format(time.RFC3339Nano))
+	h.Write(canonicalJSON(e.Data))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalJSON re-encodes data with sorted object keys and no insignificant
+// whitespace. Invalid JSON is returned unchanged.
+func canonicalJSON(data json.RawMessage) []byte {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return data
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return data
+	}
+	return out
 }
@@ -0,0 +1,231 @@
+// Package eventtest provides an in-memory event.Store for testing code that
+// appends and reads events without a database.
+package eventtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// Store is an in-memory event.Store. Like the Postgres store it assigns
+// each appended event an ID, "evt-1" for the first, and the next version
+// of its aggregate if its Version is 0, takes the actor from the context,
+// and fills these in on the appended events; it does not link the hash
+// chain.
+// Any method can be made to fail with Fail. It is safe for concurrent use.
+type Store struct {
+	clock clock.Clock
+
+	// seed holds the events given WithEvents until NewStore appends them.
+	seed []event.Event
+
+	mu     sync.Mutex
+	events []event.Event
+	errs   map[string]error
+	// appended, if set, is closed on the next append, to wake Await.
+	appended chan struct{}
+}
+
+var _ event.Store = (*Store)(nil)
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithClock stamps appended events with the time of clk. Without it they
+// keep the CreatedAt they were appended with. A *clock.Mock may be moved
+// between appends.
+func WithClock(clk clock.Clock) Option {
+	return func(s *Store) { s.clock = clk }
+}
+
+// WithEvents starts the store with events, filled in as Append fills them
+// in but without an actor. Events that already have a version keep it.
+func WithEvents(events ...event.Event) Option {
+	return func(s *Store) { s.seed = append(s.seed, events...) }
+}
+
+// NewStore returns a Store, empty unless WithEvents is given. It panics if
+// an event given WithEvents has an invalid aggregate ID.
+func NewStore(opts ...Option) *Store {
+	s := &Store{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := s.Append(context.Background(), s.seed...); err != nil {
+		panic(err)
+	}
+	s.seed = nil
+	return s
+}
+
+// Fail makes every later call of the named method, or of every method if
+// method is empty, return err. A nil err removes the failure.
+func (s *Store) Fail(method string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.errs == nil {
+		s.errs = make(map[string]error)
+	}
+	if err == nil {
+		delete(s.errs, method)
+		return
+	}
+	s.errs[method] = err
+}
+
+// failure returns the error injected for method, if any. s.mu must be
+// held.
+func (s *Store) failure(method string) error {
+	if err, ok := s.errs[method]; ok {
+		return err
+	}
+	return s.errs[""]
+}
+
+func (s *Store) Append(ctx context.Context, events ...event.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.failure("Append"); err != nil {
+		return err
+	}
+	for _, e := range events {
+		if err := event.ValidateAggregateID(e.AggregateID); err != nil {
+			return err
+		}
+	}
+	for i := range events {
+		e := &events[i]
+		if e.ID == "" {
+			e.ID = fmt.Sprintf("evt-%d", len(s.events)+1)
+		}
+		if e.Actor == "" {
+			e.Actor = event.ActorFromContext(ctx)
+		}
+		if e.Version == 0 {
+			for _, prev := range s.events {
+				if prev.AggregateID == e.AggregateID {
+					e.Version = max(e.Version, prev.Version)
+				}
+			}
+			e.Version++
+		}
+		if s.clock != nil {
+			e.CreatedAt = s.clock.Now()
+		}
+		s.events = append(s.events, *e)
+	}
+	if s.appended != nil {
+		close(s.appended)
+		s.appended = nil
+	}
+	return nil
+}
+
+func (s *Store) Load(_ context.Context, aggregateID string) ([]event.Event, error) {
+	return s.filter("Load", func(e event.Event) bool { return e.AggregateID == aggregateID })
+}
+
+func (s *Store) LoadByType(_ context.Context, eventType event.Type) ([]event.Event, error) {
+	return s.filter("LoadByType", func(e event.Event) bool { return e.Type == eventType })
+}
+
+func (s *Store) Query(_ context.Context, q event.Query) ([]event.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.failure("Query"); err != nil {
+		return nil, err
+	}
+	return q.Filter(s.events), nil
+}
+
+func (s *Store) filter(method string, match func(event.Event) bool) ([]event.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.failure(method); err != nil {
+		return nil, err
+	}
+	var events []event.Event
+	for _, e := range s.events {
+		if match(e) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// Events returns the events appended, in order.
+func (s *Store) Events() []event.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.events)
+}
+
+// Await waits until an event of type typ has been appended and returns the
+// first, for code that appends from its own goroutine. It fails t if none
+// is appended within a few seconds.
+func (s *Store) Await(t testing.TB, typ event.Type) event.Event {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		s.mu.Lock()
+		i := slices.IndexFunc(s.events, func(e event.Event) bool { return e.Type == typ })
+		var e event.Event
+		if i >= 0 {
+			e = s.events[i]
+		}
+		if s.appended == nil {
+			s.appended = make(chan struct{})
+		}
+		appended := s.appended
+		s.mu.Unlock()
+		if i >= 0 {
+			return e
+		}
+		select {
+		case <-appended:
+		case <-timeout:
+			t.Fatalf("no %s event was appended", typ)
+		}
+	}
+}
+
+// Last returns the event appended last, failing t if there is none.
+func (s *Store) Last(t testing.TB) event.Event {
+	t.Helper()
+	events := s.Events()
+	if len(events) == 0 {
+		t.Fatal("no events were appended")
+	}
+	return events[len(events)-1]
+}
+
+// RequireTypes fails t unless the events appended have the types want, in
+// order.
+func (s *Store) RequireTypes(t testing.TB, want ...event.Type) {
+	t.Helper()
+	var got []event.Type
+	for _, e := range s.Events() {
+		got = append(got, e.Type)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("event types = %v, want %v", got, want)
+	}
+}
+
+// Data decodes the data of e as a T, failing t if it does not decode.
+func Data[T any](t testing.TB, e event.Event) T {
+	t.Helper()
+	var data T
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		t.Fatalf("decoding %s event %s: %v", e.Type, e.ID, err)
+	}
+	return data
+}
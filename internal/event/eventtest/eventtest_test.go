@@ -0,0 +1,103 @@
+package eventtest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+)
+
+func TestStore(t *testing.T) {
+	clk := &clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	es := eventtest.NewStore(eventtest.WithClock(clk))
+	ctx := context.Background()
+
+	data, _ := json.Marshal(event.DKPChangeData{PlayerID: "p1", Amount: 10})
+	_ = es.Append(ctx, event.Event{AggregateID: "p1", Type: event.PlayerRegistered})
+	clk.T = clk.T.Add(time.Hour)
+	_ = es.Append(ctx,
+		event.Event{AggregateID: "p1", Type: event.DKPAwarded, Data: data},
+		event.Event{AggregateID: "p2", Type: event.PlayerRegistered},
+	)
+
+	es.RequireTypes(t, event.PlayerRegistered, event.DKPAwarded, event.PlayerRegistered)
+	last := es.Last(t)
+	if last.ID != "evt-3" || last.Version != 1 || !last.CreatedAt.Equal(clk.T) {
+		t.Errorf("last = %s v%d at %s, want evt-3 v1 at %s", last.ID, last.Version, last.CreatedAt, clk.T)
+	}
+
+	loaded, err := es.Load(ctx, "p1")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(loaded) != 2 || loaded[1].Version != 2 {
+		t.Fatalf("Load() = %+v, want versions 1 and 2", loaded)
+	}
+	if d := eventtest.Data[event.DKPChangeData](t, loaded[1]); d.Amount != 10 {
+		t.Errorf("Data() = %+v, want 10 DKP", d)
+	}
+
+	newest, err := es.Query(ctx, event.Query{Types: []event.Type{event.PlayerRegistered}})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(newest) != 2 || newest[0].AggregateID != "p2" {
+		t.Errorf("Query() = %+v, want both registrations, newest first", newest)
+	}
+}
+
+func TestStore_WithEvents(t *testing.T) {
+	at := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+	es := eventtest.NewStore(eventtest.WithEvents(
+		event.Event{AggregateID: "p1", Type: event.PlayerRegistered, CreatedAt: at},
+		event.Event{AggregateID: "auction-1", Type: event.AuctionBidPlaced, Version: 4},
+		event.Event{AggregateID: "p1", Type: event.DKPAwarded},
+	))
+
+	events := es.Events()
+	if len(events) != 3 {
+		t.Fatalf("Events() = %+v, want the 3 seeded events", events)
+	}
+	if e := events[0]; e.ID != "evt-1" || e.Version != 1 || !e.CreatedAt.Equal(at) {
+		t.Errorf("first event = %s v%d at %s, want evt-1 v1 at %s", e.ID, e.Version, e.CreatedAt, at)
+	}
+	if v := events[1].Version; v != 4 {
+		t.Errorf("second event version = %d, want the given 4", v)
+	}
+	if v := events[2].Version; v != 2 {
+		t.Errorf("third event version = %d, want 2", v)
+	}
+}
+
+func TestStore_Fail(t *testing.T) {
+	es := eventtest.NewStore()
+	ctx := context.Background()
+	errDown := errors.New("database down")
+
+	es.Fail("Append", errDown)
+	if err := es.Append(ctx, event.Event{AggregateID: "p1"}); !errors.Is(err, errDown) {
+		t.Errorf("Append() error = %v, want the injected error", err)
+	}
+	if len(es.Events()) != 0 {
+		t.Errorf("events = %d after a failed append, want 0", len(es.Events()))
+	}
+	if _, err := es.LoadByType(ctx, event.DKPAwarded); err != nil {
+		t.Errorf("LoadByType() error = %v, want only Append to fail", err)
+	}
+}
+
+func TestStore_Await(t *testing.T) {
+	es := eventtest.NewStore()
+	go func() {
+		_ = es.Append(context.Background(), event.Event{AggregateID: "p1", Type: event.PlayerRegistered})
+		_ = es.Append(context.Background(), event.Event{AggregateID: "p1", Type: event.DKPAwarded})
+	}()
+	if e := es.Await(t, event.DKPAwarded); e.Version != 2 {
+		t.Errorf("Await() = %+v, want version 2", e)
+	}
+}
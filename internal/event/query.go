@@ -0,0 +1,115 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Query selects events by type, aggregate, player, actor, and time range.
+// Zero-valued fields do not constrain the result.
+type Query struct {
+	// Types restricts results to the given event types.
+	Types []Type
+	// AggregateID restricts results to a single aggregate.
+	AggregateID string
+	// Player restricts results to the events about a player: those of its
+	// aggregate, and those whose data names it as player_id, winner_id, or
+	// buyer_id, such as its bids and wins on auction aggregates.
+	Player string
+	// Actor restricts results to events recorded on behalf of a Discord user.
+	Actor string
+	// Since and Until bound created_at; Since is inclusive, Until exclusive.
+	Since time.Time
+	Until time.Time
+	// Limit caps the number of events returned. Zero means no limit.
+	Limit int
+	// Offset skips that many matching events, for pagination.
+	Offset int
+}
+
+// Matches reports whether e satisfies every filter in q except Limit and
+// Offset. It is intended for in-memory Store implementations.
+func (q Query) Matches(e Event) bool {
+	if len(q.Types) > 0 {
+		found := false
+		for _, t := range q.Types {
+			if t == e.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.AggregateID != "" && e.AggregateID != q.AggregateID {
+		return false
+	}
+	if q.Player != "" && !names(e, q.Player) {
+		return false
+	}
+	if q.Actor != "" && e.Actor != q.Actor {
+		return false
+	}
+	if !q.Since.IsZero() && e.CreatedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.CreatedAt.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// Filter applies q to events given in append order and returns the matching
+// events newest first, honoring Offset and Limit. It is intended for
+// in-memory Store implementations.
+func (q Query) Filter(events []Event) []Event {
+	var result []Event
+	skipped := 0
+	for i := len(events) - 1; i >= 0; i-- {
+		if !q.Matches(events[i]) {
+			continue
+		}
+		if skipped < q.Offset {
+			skipped++
+			continue
+		}
+		result = append(result, events[i])
+		if q.Limit > 0 && len(result) == q.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// names reports whether e is about the player playerID, as Query.Player
+// defines it.
+func names(e Event, playerID string) bool {
+	if e.AggregateID == playerID {
+		return true
+	}
+	var data struct {
+		PlayerID string `json:"player_id"`
+		WinnerID string `json:"winner_id"`
+		BuyerID  string `json:"buyer_id"`
+	}
+	if json.Unmarshal(e.Data, &data) != nil {
+		return false
+	}
+	return data.PlayerID == playerID || data.WinnerID == playerID || data.BuyerID == playerID
+}
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the Discord ID of the user on whose
+// behalf events are being recorded.
+func WithActor(ctx context.Context, discordID string) context.Context {
+	return context.WithValue(ctx, actorKey{}, discordID)
+}
+
+// ActorFromContext returns the actor stored by WithActor, or "".
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
@@ -0,0 +1,135 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+func TestQuery_Matches(t *testing.T) {
+	at := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	e := event.Event{AggregateID: "p1", Type: event.DKPAwarded, Actor: "officer", CreatedAt: at}
+
+	tests := []struct {
+		name string
+		q    event.Query
+		want bool
+	}{
+		{name: "empty query", q: event.Query{}, want: true},
+		{name: "matching type", q: event.Query{Types: []event.Type{event.DKPDeducted, event.DKPAwarded}}, want: true},
+		{name: "other type", q: event.Query{Types: []event.Type{event.DKPDeducted}}, want: false},
+		{name: "other aggregate", q: event.Query{AggregateID: "p2"}, want: false},
+		{name: "player of aggregate", q: event.Query{Player: "p1"}, want: true},
+		{name: "other player", q: event.Query{Player: "p2"}, want: false},
+		{name: "other actor", q: event.Query{Actor: "someone"}, want: false},
+		{name: "since is inclusive", q: event.Query{Since: at}, want: true},
+		{name: "until is exclusive", q: event.Query{Until: at}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.q.Matches(e); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuery_MatchesPlayer(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{name: "bidder", data: `{"player_id":"p1","amount":10}`, want: true},
+		{name: "winner", data: `{"winner_id":"p1","amount":10}`, want: true},
+		{name: "buyer", data: `{"buyer_id":"p1","amount":10}`, want: true},
+		{name: "other player", data: `{"player_id":"p2","amount":10}`, want: false},
+		{name: "no player", data: `{"channel_id":"c1"}`, want: false},
+	}
+
+	q := event.Query{Player: "p1"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := event.Event{AggregateID: "a1", Type: event.AuctionBidPlaced, Data: []byte(tt.data)}
+			if got := q.Matches(e); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActorFromContext(t *testing.T) {
+	if got := event.ActorFromContext(context.Background()); got != "" {
+		t.Errorf("ActorFromContext(empty) = %q, want empty", got)
+	}
+	ctx := event.WithActor(context.Background(), "12345")
+	if got := event.ActorFromContext(ctx); got != "12345" {
+		t.Errorf("ActorFromContext() = %q, want %q", got, "12345")
+	}
+}
+
+func TestQuery_Filter(t *testing.T) {
+	var events []event.Event
+	for v := 1; v <= 5; v++ {
+		events = append(events, event.Event{AggregateID: "a1", Type: event.AuctionBidPlaced, Version: v})
+	}
+	events = append(events, event.Event{AggregateID: "a2", Type: event.AuctionBidPlaced, Version: 1})
+
+	tests := []struct {
+		name         string
+		q            event.Query
+		wantVersions []int
+	}{
+		{name: "newest first", q: event.Query{AggregateID: "a1"}, wantVersions: []int{5, 4, 3, 2, 1}},
+		{name: "limit", q: event.Query{AggregateID: "a1", Limit: 2}, wantVersions: []int{5, 4}},
+		{name: "offset and limit", q: event.Query{AggregateID: "a1", Offset: 2, Limit: 2}, wantVersions: []int{3, 2}},
+		{name: "offset past end", q: event.Query{AggregateID: "a1", Offset: 10}, wantVersions: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.q.Filter(events)
+			var versions []int
+			for _, e := range got {
+				versions = append(versions, e.Version)
+			}
+			if len(versions) != len(tt.wantVersions) {
+				t.Fatalf("versions = %v, want %v", versions, tt.wantVersions)
+			}
+			for i := range versions {
+				if versions[i] != tt.wantVersions[i] {
+					t.Fatalf("versions = %v, want %v", versions, tt.wantVersions)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAggregateID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{id: "auction-01JXSE6F0000000000000000", want: true},
+		{id: "0197722e-a1c0-7000-8000-000000000000", want: true},
+		{id: "guild_1.bank", want: true},
+		{id: ""},
+		{id: "auction:1"},
+		{id: "a b"},
+		{id: strings.Repeat("a", event.MaxAggregateIDLength+1)},
+	}
+	for _, tt := range tests {
+		err := event.ValidateAggregateID(tt.id)
+		if (err == nil) != tt.want {
+			t.Errorf("ValidateAggregateID(%q) error = %v, want valid %v", tt.id, err, tt.want)
+		}
+		if err != nil && !errors.Is(err, event.ErrInvalidAggregateID) {
+			t.Errorf("ValidateAggregateID(%q) error = %v, want ErrInvalidAggregateID", tt.id, err)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package event
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+)
+
+// SubscribeQueue registers fn for the given event types like Subscribe, but
+// Publish only adds the events to a bounded queue, from which a goroutine
+// of the subscription calls fn with ctx until ctx is done. A slow fn thus
+// holds up neither the commands appending events nor other subscribers.
+//
+// name identifies the subscriber in the event bus config, which sizes its
+// queue and says what happens to events published while it is full, and
+// in metrics. The returned function removes the subscription and stops its
+// goroutine.
+func (b *Bus) SubscribeQueue(ctx context.Context, name string, fn Handler, types ...Type) (unsubscribe func()) {
+	q := &queue{
+		name:    name,
+		cfg:     b.queues.Queue(name),
+		handler: fn,
+		metrics: b.metrics,
+		logger:  b.logger,
+		room:    make(chan struct{}),
+		ready:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if q.cfg.Overflow == config.OverflowSpill {
+		if err := q.openSpill(filepath.Join(b.queues.SpillDir, name+".jsonl")); err != nil {
+			b.logger.ErrorContext(ctx, "opening event spill file failed, dropping the oldest events instead",
+				slog.String("subscriber", name),
+				slog.Any("error", err),
+			)
+			q.cfg.Overflow = config.OverflowDropOldest
+		}
+	}
+
+	remove := b.Subscribe(q.push, types...)
+	go q.run(ctx)
+	q.signal()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			remove()
+			q.close()
+		})
+	}
+}
+
+// queue holds the events of a subscription made with SubscribeQueue until
+// they are handled.
+type queue struct {
+	name    string
+	cfg     config.QueueConfig
+	handler Handler
+	metrics *metrics.Recorder
+	logger  *slog.Logger
+
+	// ready wakes run after an event was queued. done is closed when the
+	// subscription is removed.
+	ready chan struct{}
+	done  chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+	events []Event
+	// room is closed, and replaced, when an event is taken from the full
+	// queue, to wake the publishers waiting for room.
+	room chan struct{}
+
+	// spill is the file events are spilled to with OverflowSpill. spilled
+	// of them are yet to be handled, starting at offset. Once an event is
+	// spilled, later events are spilled too until the file was handled, so
+	// that events are handled in the order they were published.
+	spill   *os.File
+	spilled int
+	offset  int64
+}
+
+// openSpill opens the spill file at path. Events spilled before a restart
+// are handled once the queue has caught up.
+func (q *queue) openSpill(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("creating spill directory: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Clean(path), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening spill file: %w", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("reading spill file: %w", err)
+	}
+	q.spill = f
+	q.spilled = bytes.Count(data, []byte("\n"))
+	return nil
+}
+
+// push queues e, or handles it as the overflow policy says when the queue
+// is full.
+func (q *queue) push(ctx context.Context, e Event) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var timeout <-chan time.Time
+	for {
+		if q.closed {
+			return
+		}
+		switch {
+		case q.spilled > 0:
+			q.spillEvent(ctx, e)
+		case len(q.events) < q.cfg.Size:
+			q.events = append(q.events, e)
+		case q.cfg.Overflow == config.OverflowSpill:
+			q.spillEvent(ctx, e)
+		case q.cfg.Overflow == config.OverflowBlock:
+			if timeout == nil {
+				t := time.NewTimer(q.cfg.BlockTimeout)
+				defer t.Stop()
+				timeout = t.C
+			}
+			room := q.room
+			q.mu.Unlock()
+			select {
+			case <-room:
+				q.mu.Lock()
+				continue
+			case <-timeout:
+			case <-ctx.Done():
+			case <-q.done:
+			}
+			q.mu.Lock()
+			q.drop(ctx, e)
+			return
+		default:
+			q.drop(ctx, q.events[0])
+			q.events = append(q.events[1:], e)
+		}
+		break
+	}
+	q.metrics.BusQueueDepth(ctx, q.name, len(q.events)+q.spilled)
+	q.signal()
+}
+
+// spillEvent appends e to the spill file, or drops it if that fails.
+func (q *queue) spillEvent(ctx context.Context, e Event) {
+	data, err := json.Marshal(e)
+	if err == nil {
+		_, err = q.spill.Write(append(data, '\n'))
+	}
+	if err != nil {
+		q.logger.ErrorContext(ctx, "spilling event failed",
+			slog.String("subscriber", q.name),
+			slog.Any("error", err),
+		)
+		q.drop(ctx, e)
+		return
+	}
+	q.spilled++
+	q.metrics.BusEventSpilled(ctx, q.name)
+}
+
+func (q *queue) drop(ctx context.Context, e Event) {
+	q.logger.WarnContext(ctx, "event queue full, dropping event",
+		slog.String("subscriber", q.name),
+		slog.String("aggregate_id", e.AggregateID),
+		slog.String("type", string(e.Type)),
+	)
+	q.metrics.BusEventDropped(ctx, q.name)
+}
+
+// signal wakes run, unless it was already woken.
+func (q *queue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// run handles the queued events until ctx is done or the subscription is
+// removed.
+func (q *queue) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.done:
+			return
+		case <-q.ready:
+		}
+		for {
+			events := q.take(ctx)
+			if len(events) == 0 {
+				break
+			}
+			for _, e := range events {
+				if ctx.Err() != nil {
+					return
+				}
+				q.handler(ctx, e)
+			}
+		}
+	}
+}
+
+// take returns the next events to handle: the oldest queued event, or once
+// none is queued, the next of the spilled events.
+func (q *queue) take(ctx context.Context) []Event {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil
+	}
+
+	var events []Event
+	switch {
+	case len(q.events) > 0:
+		events = []Event{q.events[0]}
+		if len(q.events) == q.cfg.Size {
+			close(q.room)
+			q.room = make(chan struct{})
+		}
+		q.events = q.events[1:]
+	case q.spilled > 0:
+		var err error
+		if events, err = q.readSpill(ctx); err != nil {
+			q.logger.ErrorContext(ctx, "reading spilled events failed, dropping them",
+				slog.String("subscriber", q.name),
+				slog.Int("events", q.spilled),
+				slog.Any("error", err),
+			)
+			q.spilled = 0
+		}
+		if q.spilled == 0 {
+			q.offset = 0
+			if err := q.spill.Truncate(0); err != nil {
+				q.logger.ErrorContext(ctx, "truncating spill file failed",
+					slog.String("subscriber", q.name),
+					slog.Any("error", err),
+				)
+			}
+		}
+	default:
+		return nil
+	}
+	q.metrics.BusQueueDepth(ctx, q.name, len(q.events)+q.spilled)
+	return events
+}
+
+// readSpill reads up to a queue's worth of the spilled events. Events that
+// cannot be decoded, such as one cut short by a crash, are skipped.
+func (q *queue) readSpill(ctx context.Context) ([]Event, error) {
+	r := bufio.NewReader(io.NewSectionReader(q.spill, q.offset, 1<<62))
+	var events []Event
+	for len(events) < q.cfg.Size && q.spilled > 0 {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return events, fmt.Errorf("reading spill file: %w", err)
+		}
+		q.offset += int64(len(line))
+		q.spilled--
+		var e Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			q.logger.ErrorContext(ctx, "decoding spilled event failed, skipping it",
+				slog.String("subscriber", q.name),
+				slog.Any("error", err),
+			)
+			q.metrics.BusEventDropped(ctx, q.name)
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// close stops the queue. Events still queued in memory are dropped; spilled
+// ones are kept for the next run.
+func (q *queue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	close(q.done)
+	if q.spill == nil {
+		return
+	}
+	if err := q.compactSpill(); err != nil {
+		q.logger.Error("compacting spill file failed", slog.String("subscriber", q.name), slog.Any("error", err))
+	}
+	_ = q.spill.Close()
+}
+
+// compactSpill drops the handled events from the spill file, so that they
+// are not handled again after a restart.
+func (q *queue) compactSpill() error {
+	if q.offset == 0 {
+		return nil
+	}
+	rest, err := io.ReadAll(io.NewSectionReader(q.spill, q.offset, 1<<62))
+	if err != nil {
+		return fmt.Errorf("reading spill file: %w", err)
+	}
+	if err := q.spill.Truncate(0); err != nil {
+		return fmt.Errorf("truncating spill file: %w", err)
+	}
+	if _, err := q.spill.Write(rest); err != nil {
+		return fmt.Errorf("writing spill file: %w", err)
+	}
+	q.offset = 0
+	return nil
+}
@@ -0,0 +1,174 @@
+package event_test
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+)
+
+func queueBus(t *testing.T, overflow string) *event.Bus {
+	t.Helper()
+	cfg := config.EventBusConfig{
+		Default:  config.QueueConfig{Size: 2, Overflow: overflow, BlockTimeout: 20 * time.Millisecond},
+		SpillDir: t.TempDir(),
+	}
+	return event.NewBus(event.WithQueues(cfg, metrics.Nop(), slog.Default()))
+}
+
+// gate is a handler that waits for open before handling each event, and
+// records the versions of the events it handled.
+type gate struct {
+	open    chan struct{}
+	mu      sync.Mutex
+	handled []int
+	done    chan struct{}
+	want    int
+}
+
+func newGate(want int) *gate {
+	return &gate{open: make(chan struct{}), done: make(chan struct{}), want: want}
+}
+
+func (g *gate) handle(ctx context.Context, e event.Event) {
+	select {
+	case <-g.open:
+	case <-ctx.Done():
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.handled = append(g.handled, e.Version)
+	if len(g.handled) == g.want {
+		close(g.done)
+	}
+}
+
+func (g *gate) wait(t *testing.T) []int {
+	t.Helper()
+	select {
+	case <-g.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for events to be handled")
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]int(nil), g.handled...)
+}
+
+func publish(bus *event.Bus, versions ...int) {
+	for _, v := range versions {
+		bus.Publish(context.Background(), event.Event{AggregateID: "a1", Type: event.DKPAwarded, Version: v})
+	}
+}
+
+func TestBus_SubscribeQueueDoesNotHoldUpPublish(t *testing.T) {
+	bus := queueBus(t, config.OverflowDropOldest)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The handler never returns until the test ends.
+	unsubscribe := bus.SubscribeQueue(ctx, "slow", func(ctx context.Context, _ event.Event) { <-ctx.Done() })
+	defer unsubscribe()
+	var fast int
+	bus.Subscribe(func(context.Context, event.Event) { fast++ })
+
+	published := make(chan struct{})
+	go func() {
+		publish(bus, 1, 2, 3, 4, 5, 6)
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish was held up by a slow queued subscriber")
+	}
+	if fast != 6 {
+		t.Errorf("other subscriber got %d events, want 6", fast)
+	}
+}
+
+func TestBus_SubscribeQueueOverflow(t *testing.T) {
+	tests := []struct {
+		overflow string
+		want     []int
+	}{
+		// The first event is taken by the handler, the queue holds two.
+		{overflow: config.OverflowDropOldest, want: []int{1, 4, 5}},
+		{overflow: config.OverflowBlock, want: []int{1, 2, 3}},
+		{overflow: config.OverflowSpill, want: []int{1, 2, 3, 4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.overflow, func(t *testing.T) {
+			bus := queueBus(t, tt.overflow)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			g := newGate(len(tt.want))
+			taken := make(chan struct{}, 1)
+			unsubscribe := bus.SubscribeQueue(ctx, "officers", func(ctx context.Context, e event.Event) {
+				select {
+				case taken <- struct{}{}:
+				default:
+				}
+				g.handle(ctx, e)
+			})
+			defer unsubscribe()
+
+			publish(bus, 1)
+			<-taken
+			// With OverflowBlock, 4 and 5 wait for room until they time out.
+			publish(bus, 2, 3, 4, 5)
+			close(g.open)
+
+			got := g.wait(t)
+			if len(got) != len(tt.want) {
+				t.Fatalf("handled %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("handled %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBus_SubscribeQueueSpillSurvivesRestart(t *testing.T) {
+	cfg := config.EventBusConfig{
+		Default:  config.QueueConfig{Size: 1, Overflow: config.OverflowSpill, BlockTimeout: time.Second},
+		SpillDir: t.TempDir(),
+	}
+	bus := event.NewBus(event.WithQueues(cfg, metrics.Nop(), slog.Default()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	blocked := make(chan struct{})
+	unsubscribe := bus.SubscribeQueue(ctx, "bank", func(ctx context.Context, _ event.Event) {
+		close(blocked)
+		<-ctx.Done()
+	})
+	publish(bus, 1)
+	<-blocked
+	// 2 is queued in memory, and 3 and 4 are spilled.
+	publish(bus, 2, 3, 4)
+	cancel()
+	unsubscribe()
+
+	// Only the spilled events are left after a restart.
+	bus = event.NewBus(event.WithQueues(cfg, metrics.Nop(), slog.Default()))
+	g := newGate(2)
+	close(g.open)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	unsubscribe = bus.SubscribeQueue(ctx, "bank", g.handle)
+	defer unsubscribe()
+
+	if got := g.wait(t); len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Errorf("handled %v after a restart, want [3 4]", got)
+	}
+}
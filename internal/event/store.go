@@ -1,13 +1,51 @@
 package event
 
-import "context"
+import (
+	"context"
+	"fmt"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+)
 
 // Store persists and retrieves events.
 type Store interface {
-	// Append persists one or more events atomically.
+	// Append persists one or more events atomically. An event with Version 0
+	// is assigned the next version of its aggregate. The store links each
+	// event into its aggregate's hash chain (see ChainHash). Events whose
+	// aggregate ID fails ValidateAggregateID are rejected. On success the
+	// events are updated in place to what was stored, with their ID,
+	// Version, Actor, CreatedAt, and hashes filled in, so that a caller
+	// passing a slice with events... sees them.
 	Append(ctx context.Context, events ...Event) error
 	// Load returns all events for an aggregate, ordered by version.
 	Load(ctx context.Context, aggregateID string) ([]Event, error)
 	// LoadByType returns events filtered by type.
 	LoadByType(ctx context.Context, eventType Type) ([]Event, error)
+	// Query returns events matching q, newest first.
+	Query(ctx context.Context, q Query) ([]Event, error)
+}
+
+// MaxAggregateIDLength is the longest aggregate ID a Store accepts.
+const MaxAggregateIDLength = 128
+
+// ErrInvalidAggregateID is returned by Append for an event whose aggregate
+// ID fails ValidateAggregateID.
+var ErrInvalidAggregateID = derrors.New(derrors.Validation, "INVALID_AGGREGATE_ID", "invalid aggregate ID")
+
+// ValidateAggregateID returns ErrInvalidAggregateID unless id is 1 to
+// MaxAggregateIDLength ASCII letters, digits, hyphens, underscores, and
+// dots. Colons are left out, as aggregate IDs are embedded in the custom
+// IDs of Discord buttons, whose parts colons separate.
+func ValidateAggregateID(id string) error {
+	if id == "" || len(id) > MaxAggregateIDLength {
+		return ErrInvalidAggregateID.Wrap(fmt.Errorf("aggregate ID of %d bytes, want 1 to %d", len(id), MaxAggregateIDLength))
+	}
+	for _, c := range []byte(id) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return ErrInvalidAggregateID.Wrap(fmt.Errorf("aggregate ID %q contains %q", id, c))
+		}
+	}
+	return nil
 }
@@ -0,0 +1,193 @@
+// Package eventio exports and imports the event log as newline-delimited
+// JSON, for moving a guild between deployments and for off-site audits.
+//
+// Every line is a Record: the event plus its content hash. Import verifies
+// each hash, validates aggregate versions, and refuses to overwrite history
+// that differs from what is already stored.
+package eventio
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// ErrConflict is returned when imported events disagree with events already
+// stored for the same aggregate and version.
+var ErrConflict = derrors.New(derrors.Conflict, "EVENT_CONFLICT", "conflicting event history")
+
+// Record is a single line of an export stream.
+type Record struct {
+	event.Event
+	Hash string `json:"hash"`
+}
+
+// ImportReport summarizes an import.
+type ImportReport struct {
+	// Read is the number of records in the stream.
+	Read int
+	// Imported is the number of events appended to the store.
+	Imported int
+	// Duplicates is the number of events already present with identical
+	// content; they are skipped so that an import can be re-run safely.
+	Duplicates int
+	// Aggregates is the number of distinct aggregates in the stream.
+	Aggregates int
+}
+
+// Export writes every event in s to w, oldest first, and returns the number
+// of events written.
+func Export(ctx context.Context, s event.Store, w io.Writer) (int, error) {
+	events, err := s.Query(ctx, event.Query{})
+	if err != nil {
+		return 0, fmt.Errorf("loading events: %w", err)
+	}
+	// Query returns newest first.
+	slices.Reverse(events)
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, e := range events {
+		if err := enc.Encode(Record{Event: e, Hash: e.ContentHash()}); err != nil {
+			return 0, fmt.Errorf("encoding event %s: %w", e.ID, err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, fmt.Errorf("flushing output: %w", err)
+	}
+	return len(events), nil
+}
+
+// Read reads the records of an export stream from r and returns their
+// events in stream order, verifying each record's hash.
+func Read(r io.Reader) ([]event.Event, error) {
+	var events []event.Event
+	dec := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var rec Record
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("record %d: decoding: %w", line, err)
+		}
+		if got := rec.ContentHash(); got != rec.Hash {
+			return nil, fmt.Errorf("record %d: hash mismatch for %s v%d (got %s, want %s)",
+				line, rec.AggregateID, rec.Version, got, rec.Hash)
+		}
+		if rec.AggregateID == "" {
+			return nil, fmt.Errorf("record %d: missing aggregate_id", line)
+		}
+		events = append(events, rec.Event)
+	}
+	return events, nil
+}
+
+// Import reads records from r and appends them to s. The whole stream is
+// validated before anything is written. With dryRun set nothing is written.
+func Import(ctx context.Context, s event.Store, r io.Reader, dryRun bool) (ImportReport, error) {
+	var report ImportReport
+
+	events, err := Read(r)
+	if err != nil {
+		return report, err
+	}
+	byAggregate := make(map[string][]event.Event)
+	var order []string
+	for _, e := range events {
+		if _, ok := byAggregate[e.AggregateID]; !ok {
+			order = append(order, e.AggregateID)
+		}
+		byAggregate[e.AggregateID] = append(byAggregate[e.AggregateID], e)
+	}
+	report.Read = len(events)
+	report.Aggregates = len(order)
+
+	// Validate every aggregate against itself and the store before
+	// writing, so that a bad stream leaves the store untouched.
+	pending := make(map[string][]event.Event, len(order))
+	for _, id := range order {
+		fresh, dups, err := plan(ctx, s, id, byAggregate[id])
+		if err != nil {
+			return report, err
+		}
+		pending[id] = fresh
+		report.Duplicates += dups
+	}
+
+	if dryRun {
+		for _, id := range order {
+			report.Imported += len(pending[id])
+		}
+		return report, nil
+	}
+
+	for _, id := range order {
+		events := pending[id]
+		if len(events) == 0 {
+			continue
+		}
+		if err := s.Append(ctx, events...); err != nil {
+			return report, fmt.Errorf("appending events for %s: %w", id, err)
+		}
+		report.Imported += len(events)
+	}
+	return report, nil
+}
+
+// plan validates the imported events of one aggregate and returns those not
+// yet present in the store in version order, together with the number of
+// identical duplicates. The new events must continue the stored history
+// without gaps, from the version after the last stored one.
+func plan(ctx context.Context, s event.Store, aggregateID string, imported []event.Event) ([]event.Event, int, error) {
+	seen := make(map[int]struct{}, len(imported))
+	for _, e := range imported {
+		if e.Version < 1 {
+			return nil, 0, fmt.Errorf("%s: version %d is not positive", aggregateID, e.Version)
+		}
+		if _, dup := seen[e.Version]; dup {
+			return nil, 0, fmt.Errorf("%s: version %d appears more than once", aggregateID, e.Version)
+		}
+		seen[e.Version] = struct{}{}
+	}
+
+	existing, err := s.Load(ctx, aggregateID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("loading existing events for %s: %w", aggregateID, err)
+	}
+	stored := make(map[int]event.Event, len(existing))
+	last := 0
+	for _, e := range existing {
+		stored[e.Version] = e
+		last = max(last, e.Version)
+	}
+
+	var fresh []event.Event
+	dups := 0
+	for _, e := range imported {
+		prev, ok := stored[e.Version]
+		if !ok {
+			e.ID = ""
+			fresh = append(fresh, e)
+			continue
+		}
+		if prev.ContentHash() != e.ContentHash() {
+			return nil, 0, fmt.Errorf("%w: %s version %d differs from the stored event", ErrConflict, aggregateID, e.Version)
+		}
+		dups++
+	}
+
+	slices.SortFunc(fresh, func(a, b event.Event) int { return cmp.Compare(a.Version, b.Version) })
+	for i, e := range fresh {
+		if want := last + 1 + i; e.Version != want {
+			return nil, 0, fmt.Errorf("%w: %s version %d does not continue the history at version %d", ErrConflict, aggregateID, e.Version, want)
+		}
+	}
+	return fresh, dups, nil
+}
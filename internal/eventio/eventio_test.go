@@ -0,0 +1,153 @@
+package eventio_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/eventio"
+)
+
+func sampleStore() *eventtest.Store {
+	at := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	return eventtest.NewStore(eventtest.WithEvents(
+		event.Event{ID: "1", AggregateID: "a1", Type: event.AuctionStarted, Data: json.RawMessage(`{"item_name":"Sword"}`), Version: 1, CreatedAt: at},
+		event.Event{ID: "2", AggregateID: "a1", Type: event.AuctionBidPlaced, Data: json.RawMessage(`{"player_id":"p1","amount":10}`), Version: 2, Actor: "u1", CreatedAt: at.Add(time.Minute)},
+		event.Event{ID: "3", AggregateID: "p1", Type: event.PlayerRegistered, Data: json.RawMessage(`{"character_name":"Gandalf"}`), Version: 1, CreatedAt: at.Add(2 * time.Minute)},
+	))
+}
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := sampleStore()
+
+	var buf bytes.Buffer
+	n, err := eventio.Export(ctx, src, &buf)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("Export() wrote %d events, want 3", n)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 3 {
+		t.Errorf("export has %d lines, want 3", lines)
+	}
+
+	dst := eventtest.NewStore()
+	report, err := eventio.Import(ctx, dst, bytes.NewReader(buf.Bytes()), false)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if report.Imported != 3 || report.Aggregates != 2 {
+		t.Errorf("report = %+v, want 3 imported across 2 aggregates", report)
+	}
+	if dst.Events()[0].Type != event.AuctionStarted {
+		t.Errorf("first imported event = %s, want oldest first", dst.Events()[0].Type)
+	}
+
+	// Re-importing the same stream is a no-op.
+	report, err = eventio.Import(ctx, dst, bytes.NewReader(buf.Bytes()), false)
+	if err != nil {
+		t.Fatalf("second Import() error = %v", err)
+	}
+	if report.Imported != 0 || report.Duplicates != 3 {
+		t.Errorf("re-import report = %+v, want 0 imported and 3 duplicates", report)
+	}
+}
+
+func TestImport_HashMismatch(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	if _, err := eventio.Export(ctx, sampleStore(), &buf); err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(buf.String(), `"amount":10`, `"amount":99`, 1)
+
+	dst := eventtest.NewStore()
+	if _, err := eventio.Import(ctx, dst, strings.NewReader(tampered), false); err == nil {
+		t.Fatal("Import() should reject a tampered record")
+	}
+	if n := len(dst.Events()); n != 0 {
+		t.Errorf("store has %d events after rejected import, want 0", n)
+	}
+}
+
+func TestImport_Conflict(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	if _, err := eventio.Export(ctx, sampleStore(), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := eventtest.NewStore(eventtest.WithEvents(
+		event.Event{AggregateID: "a1", Type: event.AuctionStarted, Data: json.RawMessage(`{"item_name":"Axe"}`), Version: 1},
+	))
+	_, err := eventio.Import(ctx, dst, bytes.NewReader(buf.Bytes()), false)
+	if !errors.Is(err, eventio.ErrConflict) {
+		t.Fatalf("Import() error = %v, want %v", err, eventio.ErrConflict)
+	}
+	if n := len(dst.Events()); n != 1 {
+		t.Errorf("store has %d events after conflicting import, want 1", n)
+	}
+}
+
+func TestImport_DuplicateVersionInStream(t *testing.T) {
+	e := event.Event{AggregateID: "a1", Type: event.AuctionStarted, Data: json.RawMessage(`{}`), Version: 1}
+	rec, _ := json.Marshal(eventio.Record{Event: e, Hash: e.ContentHash()})
+	stream := string(rec) + "\n" + string(rec) + "\n"
+
+	if _, err := eventio.Import(context.Background(), eventtest.NewStore(), strings.NewReader(stream), true); err == nil {
+		t.Fatal("Import() should reject duplicate versions within an aggregate")
+	}
+}
+
+func TestImport_VersionGaps(t *testing.T) {
+	at := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	stored := event.Event{AggregateID: "a1", Type: event.AuctionStarted, Data: json.RawMessage(`{}`), Version: 1, CreatedAt: at}
+	bid := func(version int) event.Event {
+		return event.Event{AggregateID: "a1", Type: event.AuctionBidPlaced, Data: json.RawMessage(`{"player_id":"p1","amount":10}`), Version: version, CreatedAt: at}
+	}
+
+	tests := []struct {
+		name     string
+		imported []event.Event
+		wantErr  bool
+	}{
+		{name: "continues the stored history", imported: []event.Event{stored, bid(3), bid(2)}},
+		{name: "gap after the stored history", imported: []event.Event{bid(3)}, wantErr: true},
+		{name: "gap within the stream", imported: []event.Event{bid(2), bid(4)}, wantErr: true},
+		{name: "version zero", imported: []event.Event{bid(0)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stream strings.Builder
+			for _, e := range tt.imported {
+				rec, _ := json.Marshal(eventio.Record{Event: e, Hash: e.ContentHash()})
+				stream.Write(rec)
+				stream.WriteString("\n")
+			}
+
+			dst := eventtest.NewStore(eventtest.WithEvents(stored))
+			report, err := eventio.Import(context.Background(), dst, strings.NewReader(stream.String()), false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Import() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if n := len(dst.Events()); n != 1 {
+					t.Errorf("store has %d events after rejected import, want 1", n)
+				}
+				return
+			}
+			if events := dst.Events(); report.Imported != 2 || events[1].Version != 2 || events[2].Version != 3 {
+				t.Errorf("imported %d events as %+v, want versions 2 and 3 in order", report.Imported, events[1:])
+			}
+		})
+	}
+}
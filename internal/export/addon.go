@@ -0,0 +1,131 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// Format selects how an export is encoded.
+type Format string
+
+const (
+	// CSV is a spreadsheet-friendly table with a header row.
+	CSV Format = "csv"
+	// MonolithDKP is a SavedVariables file for the MonolithDKP addon.
+	MonolithDKP Format = "monolithdkp"
+	// CommunityDKP is a SavedVariables file for the CommunityDKP addon.
+	CommunityDKP Format = "communitydkp"
+)
+
+// addon describes the SavedVariables file of an in-game addon.
+type addon struct {
+	file  string // named after the addon
+	table string // global holding the DKP table
+}
+
+var addons = map[Format]addon{
+	MonolithDKP:  {file: "MonolithDKP.lua", table: "MonDKP_DKPTable"},
+	CommunityDKP: {file: "CommunityDKP.lua", table: "CommDKP_DKPTable"},
+}
+
+// IsAddon reports whether f is an in-game addon format.
+func (f Format) IsAddon() bool {
+	_, ok := addons[f]
+	return ok
+}
+
+// Filename returns the file name for an export of kind in format f.
+func Filename(kind Kind, f Format) string {
+	if a, ok := addons[f]; ok {
+		return a.file
+	}
+	return string(kind) + ".csv"
+}
+
+// WriteAddon writes the standings as a Lua SavedVariables file for the
+// addon format f, so that players can copy it into their game client and
+// see balances in game. Lifetime totals are summed from the event log.
+func (x *Exporter) WriteAddon(ctx context.Context, w io.Writer, f Format) error {
+	ctx, span := x.tracer.Start(ctx, "Exporter.WriteAddon",
+		trace.WithAttributes(attribute.String("format", string(f))),
+	)
+	defer span.End()
+
+	a, ok := addons[f]
+	if !ok {
+		return derrors.New(derrors.Validation, "UNKNOWN_EXPORT", fmt.Sprintf("unknown addon format %q", f))
+	}
+
+	players, err := x.players.List(ctx)
+	if err != nil {
+		return fmt.Errorf("listing players: %w", err)
+	}
+	events, err := x.events.Query(ctx, event.Query{
+		Types: []event.Type{event.DKPAwarded, event.DKPDeducted, event.DKPAdjusted},
+	})
+	if err != nil {
+		return fmt.Errorf("querying DKP events: %w", err)
+	}
+	gained := make(map[string]int)
+	spent := make(map[string]int)
+	for _, e := range events {
+		var d event.DKPChangeData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			continue
+		}
+		if d.Amount > 0 {
+			gained[d.PlayerID] += d.Amount
+		} else {
+			spent[d.PlayerID] -= d.Amount
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s = {\n", a.table)
+	for _, p := range players {
+		b.WriteString("\t{\n")
+		fmt.Fprintf(&b, "\t\t[\"player\"] = %s,\n", luaQuote(p.CharacterName))
+		fmt.Fprintf(&b, "\t\t[\"dkp\"] = %d,\n", p.DKP)
+		fmt.Fprintf(&b, "\t\t[\"lifetime_gained\"] = %d,\n", gained[p.ID])
+		fmt.Fprintf(&b, "\t\t[\"lifetime_spent\"] = %d,\n", spent[p.ID])
+		b.WriteString("\t},\n")
+	}
+	b.WriteString("}\n")
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("writing addon table: %w", err)
+	}
+	return nil
+}
+
+// luaQuote quotes s as a Lua 5.1 string literal. Lua 5.1, which the game
+// client embeds, has no \x or \u escapes, so control bytes use \ddd.
+func luaQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,303 @@
+// Package export renders standings, DKP transactions, and auction results as
+// CSV for guilds that keep their accounts in spreadsheets.
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// Kind selects what an export contains.
+type Kind string
+
+const (
+	// Standings lists every player and their current balance.
+	Standings Kind = "standings"
+	// Transactions lists DKP awards, deductions, and adjustments.
+	Transactions Kind = "transactions"
+	// Auctions lists closed and canceled auctions with their winners.
+	Auctions Kind = "auctions"
+)
+
+// Kinds lists the supported export kinds.
+var Kinds = []Kind{Standings, Transactions, Auctions}
+
+// Range bounds an export by event time. Zero values are unbounded. Since is
+// inclusive and Until is exclusive. Standings ignore the range.
+type Range struct {
+	Since time.Time
+	Until time.Time
+}
+
+// dateLayout is the format accepted by ParseRange.
+const dateLayout = "2006-01-02"
+
+// ParseRange parses from and to as YYYY-MM-DD dates in UTC. Both days are
+// included; either may be empty to leave that end unbounded.
+func ParseRange(from, to string) (Range, error) {
+	var r Range
+	if from != "" {
+		t, err := time.Parse(dateLayout, from)
+		if err != nil {
+			return Range{}, derrors.New(derrors.Validation, "INVALID_DATE", fmt.Sprintf("invalid from date %q, want YYYY-MM-DD", from))
+		}
+		r.Since = t
+	}
+	if to != "" {
+		t, err := time.Parse(dateLayout, to)
+		if err != nil {
+			return Range{}, derrors.New(derrors.Validation, "INVALID_DATE", fmt.Sprintf("invalid to date %q, want YYYY-MM-DD", to))
+		}
+		r.Until = t.AddDate(0, 0, 1)
+	}
+	if !r.Since.IsZero() && !r.Until.IsZero() && !r.Since.Before(r.Until) {
+		return Range{}, derrors.New(derrors.Validation, "INVALID_DATE", fmt.Sprintf("from date %s is after to date %s", from, to))
+	}
+	return r, nil
+}
+
+// Exporter writes CSV exports from the player repository and event store.
+type Exporter struct {
+	players store.PlayerRepository
+	events  event.Store
+	archive event.Archive
+	tracer  trace.Tracer
+}
+
+// Option configures an Exporter.
+type Option func(*Exporter)
+
+// WithArchive includes in auction exports and ledgers the auctions whose
+// events the retention job moved to archive.
+func WithArchive(archive event.Archive) Option {
+	return func(x *Exporter) { x.archive = archive }
+}
+
+// NewExporter returns a new Exporter.
+func NewExporter(players store.PlayerRepository, events event.Store, tp trace.TracerProvider, opts ...Option) *Exporter {
+	x := &Exporter{
+		players: players,
+		events:  events,
+		tracer:  tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/export"),
+	}
+	for _, opt := range opts {
+		opt(x)
+	}
+	return x
+}
+
+// Write writes the export of the given kind to w as CSV with a header row.
+func (x *Exporter) Write(ctx context.Context, w io.Writer, kind Kind, r Range) error {
+	ctx, span := x.tracer.Start(ctx, "Exporter.Write",
+		trace.WithAttributes(attribute.String("kind", string(kind))),
+	)
+	defer span.End()
+
+	players, err := x.players.List(ctx)
+	if err != nil {
+		return fmt.Errorf("listing players: %w", err)
+	}
+
+	var records [][]string
+	switch kind {
+	case Standings:
+		records = standings(players)
+	case Transactions:
+		records, err = x.transactions(ctx, names(players), r)
+	case Auctions:
+		records, err = x.auctions(ctx, names(players), r)
+	default:
+		return derrors.New(derrors.Validation, "UNKNOWN_EXPORT", fmt.Sprintf("unknown export kind %q", kind))
+	}
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(records); err != nil {
+		return fmt.Errorf("writing csv: %w", err)
+	}
+	return nil
+}
+
+func standings(players []store.Player) [][]string {
+	records := [][]string{{"rank", "character_name", "discord_id", "player_id", "dkp"}}
+	for i, p := range players {
+		records = append(records, []string{
+			strconv.Itoa(i + 1),
+			text(p.CharacterName),
+			p.DiscordID,
+			p.ID,
+			strconv.Itoa(p.DKP),
+		})
+	}
+	return records
+}
+
+func (x *Exporter) transactions(ctx context.Context, names map[string]string, r Range) ([][]string, error) {
+	events, err := x.events.Query(ctx, event.Query{
+		Types: []event.Type{event.DKPAwarded, event.DKPDeducted, event.DKPAdjusted},
+		Since: r.Since,
+		Until: r.Until,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying DKP events: %w", err)
+	}
+
+	records := [][]string{{"time", "player_id", "character_name", "type", "amount", "reason", "actor"}}
+	for _, e := range oldestFirst(events) {
+		var d event.DKPChangeData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+		}
+		records = append(records, []string{
+			e.CreatedAt.UTC().Format(time.RFC3339),
+			d.PlayerID,
+			text(names[d.PlayerID]),
+			string(e.Type),
+			strconv.Itoa(d.Amount),
+			text(d.Reason),
+			e.Actor,
+		})
+	}
+	return records, nil
+}
+
+func (x *Exporter) auctions(ctx context.Context, names map[string]string, r Range) ([][]string, error) {
+	ended, err := x.query(ctx, event.Query{
+		Types: []event.Type{event.AuctionClosed, event.AuctionCanceled, event.AuctionBoughtOut},
+		Since: r.Since,
+		Until: r.Until,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying auction results: %w", err)
+	}
+
+	// Auctions may have started before the range, so look up items and
+	// raids without the lower bound.
+	started, err := x.query(ctx, event.Query{
+		Types: []event.Type{event.AuctionStarted},
+		Until: r.Until,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying auction starts: %w", err)
+	}
+	items := make(map[string]event.AuctionStartedData, len(started))
+	for _, e := range started {
+		var d event.AuctionStartedData
+		if err := json.Unmarshal(e.Data, &d); err == nil {
+			items[e.AggregateID] = d
+		}
+	}
+	// An archived auction whose start is missing is described by the
+	// snapshot the retention job took of it.
+	for _, e := range ended {
+		if _, ok := items[e.AggregateID]; ok || x.archive == nil {
+			continue
+		}
+		if snap, err := x.archive.LoadSnapshot(ctx, e.AggregateID); err == nil {
+			var state struct {
+				ItemName string `json:"item_name"`
+				RaidID   string `json:"raid_id"`
+			}
+			if err := json.Unmarshal(snap.State, &state); err == nil {
+				items[e.AggregateID] = event.AuctionStartedData{ItemName: state.ItemName, RaidID: state.RaidID}
+			}
+		}
+	}
+
+	records := [][]string{{"time", "auction_id", "item", "status", "winner_id", "winner", "amount", "raid_id"}}
+	for _, e := range oldestFirst(ended) {
+		record := []string{
+			e.CreatedAt.UTC().Format(time.RFC3339),
+			e.AggregateID,
+			text(items[e.AggregateID].ItemName),
+			"canceled",
+			"",
+			"",
+			"",
+			items[e.AggregateID].RaidID,
+		}
+		switch e.Type {
+		case event.AuctionClosed:
+			var d event.AuctionClosedData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+			}
+			record[3] = "closed"
+			if d.Roll > 0 {
+				record[3] = "rolled"
+			}
+			if d.WinnerID != "" {
+				record[4] = d.WinnerID
+				record[5] = text(names[d.WinnerID])
+				record[6] = strconv.Itoa(d.Amount)
+			}
+		case event.AuctionBoughtOut:
+			var d event.AuctionBoughtOutData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+			}
+			record[3] = "bought_out"
+			record[4] = d.BuyerID
+			record[5] = text(names[d.BuyerID])
+			record[6] = strconv.Itoa(d.Amount)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// query returns the events matching q, newest first, from the event store
+// and, with WithArchive, the archive.
+func (x *Exporter) query(ctx context.Context, q event.Query) ([]event.Event, error) {
+	events, err := x.events.Query(ctx, q)
+	if err != nil || x.archive == nil {
+		return events, err
+	}
+	archived, err := x.archive.Archived(ctx, q.Types...)
+	if err != nil {
+		return nil, err
+	}
+	events = append(events, q.Filter(archived)...)
+	slices.SortStableFunc(events, func(a, b event.Event) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return events, nil
+}
+
+// text escapes a cell of free text, such as a character name or reason,
+// that a spreadsheet would otherwise evaluate as a formula.
+func text(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func names(players []store.Player) map[string]string {
+	m := make(map[string]string, len(players))
+	for _, p := range players {
+		m[p.ID] = p.CharacterName
+	}
+	return m
+}
+
+// oldestFirst reverses the newest-first order returned by Store.Query, which
+// reads more naturally in a spreadsheet.
+func oldestFirst(events []event.Event) []event.Event {
+	slices.Reverse(events)
+	return events
+}
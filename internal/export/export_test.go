@@ -0,0 +1,288 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/export"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+func newExporter() *export.Exporter {
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 20, 0, 0, 0, time.UTC) }
+	players := storetest.NewPlayers(
+		store.Player{ID: "p1", DiscordID: "d1", CharacterName: "Gandalf", DKP: 150},
+		store.Player{ID: "p2", DiscordID: "d2", CharacterName: "Frodo", DKP: 40},
+	)
+	events := eventtest.NewStore(eventtest.WithEvents(
+		event.Event{AggregateID: "p1", Type: event.DKPAwarded, Actor: "officer", CreatedAt: day(1),
+			Data: json.RawMessage(`{"player_id":"p1","amount":100,"reason":"raid"}`)},
+		event.Event{AggregateID: "p2", Type: event.DKPDeducted, Actor: "officer", CreatedAt: day(2),
+			Data: json.RawMessage(`{"player_id":"p2","amount":-10,"reason":"late, again"}`)},
+		event.Event{AggregateID: "p1", Type: event.DKPAwarded, CreatedAt: day(10),
+			Data: json.RawMessage(`{"player_id":"p1","amount":50,"reason":"raid"}`)},
+		event.Event{AggregateID: "auction-1", Type: event.AuctionStarted, CreatedAt: day(1),
+			Data: json.RawMessage(`{"item_name":"Sword","min_bid":10,"raid_id":"raid-1"}`)},
+		event.Event{AggregateID: "auction-1", Type: event.AuctionClosed, CreatedAt: day(3),
+			Data: json.RawMessage(`{"winner_id":"p1","amount":60}`)},
+		event.Event{AggregateID: "auction-2", Type: event.AuctionStarted, CreatedAt: day(3),
+			Data: json.RawMessage(`{"item_name":"Shield","min_bid":5}`)},
+		event.Event{AggregateID: "auction-2", Type: event.AuctionCanceled, CreatedAt: day(4)},
+		event.Event{AggregateID: "auction-3", Type: event.AuctionStarted, CreatedAt: day(5),
+			Data: json.RawMessage(`{"item_name":"Ring","min_bid":5,"buyout":40}`)},
+		event.Event{AggregateID: "auction-3", Type: event.AuctionBoughtOut, CreatedAt: day(5),
+			Data: json.RawMessage(`{"buyer_id":"p2","amount":40}`)},
+	))
+	return export.NewExporter(players, events, noop.NewTracerProvider())
+}
+
+func TestExporter_Write(t *testing.T) {
+	firstWeek := export.Range{
+		Since: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+		Until: time.Date(2025, 6, 8, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name string
+		kind export.Kind
+		r    export.Range
+		want string
+	}{
+		{
+			name: "standings",
+			kind: export.Standings,
+			want: "rank,character_name,discord_id,player_id,dkp\n" +
+				"1,Gandalf,d1,p1,150\n" +
+				"2,Frodo,d2,p2,40\n",
+		},
+		{
+			name: "transactions in range oldest first",
+			kind: export.Transactions,
+			r:    firstWeek,
+			want: "time,player_id,character_name,type,amount,reason,actor\n" +
+				"2025-06-01T20:00:00Z,p1,Gandalf,dkp.awarded,100,raid,officer\n" +
+				"2025-06-02T20:00:00Z,p2,Frodo,dkp.deducted,-10,\"late, again\",officer\n",
+		},
+		{
+			name: "auction results",
+			kind: export.Auctions,
+			r:    export.Range{Since: time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)},
+			want: "time,auction_id,item,status,winner_id,winner,amount,raid_id\n" +
+				"2025-06-03T20:00:00Z,auction-1,Sword,closed,p1,Gandalf,60,raid-1\n" +
+				"2025-06-04T20:00:00Z,auction-2,Shield,canceled,,,,\n" +
+				"2025-06-05T20:00:00Z,auction-3,Ring,bought_out,p2,Frodo,40,\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := newExporter().Write(context.Background(), &buf, tt.kind, tt.r); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Write() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExporter_WriteLedger(t *testing.T) {
+	var buf bytes.Buffer
+	p := &store.Player{ID: "p1", DiscordID: "d1", CharacterName: "Gandalf", DKP: 150}
+	if err := newExporter().WriteLedger(context.Background(), &buf, p); err != nil {
+		t.Fatalf("WriteLedger() error = %v", err)
+	}
+	want := "time,type,amount,balance,currency,reason,item,auction_id,actor\n" +
+		"2025-06-01T20:00:00Z,dkp.awarded,100,100,DKP,raid,,,officer\n" +
+		"2025-06-03T20:00:00Z,auction.closed,60,,gold,,Sword,auction-1,\n" +
+		"2025-06-10T20:00:00Z,dkp.awarded,50,150,DKP,raid,,,\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteLedger() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExporter_WriteEscapesFormulas(t *testing.T) {
+	players := storetest.NewPlayers(
+		store.Player{ID: "p1", DiscordID: "d1", CharacterName: "=HYPERLINK(\"http://evil\")", DKP: 10},
+	)
+	events := eventtest.NewStore(eventtest.WithEvents(
+		event.Event{AggregateID: "p1", Type: event.DKPDeducted, CreatedAt: time.Date(2025, 6, 1, 20, 0, 0, 0, time.UTC),
+			Data: json.RawMessage(`{"player_id":"p1","amount":-10,"reason":"@SUM(A1)"}`)},
+		event.Event{AggregateID: "auction-1", Type: event.AuctionStarted, CreatedAt: time.Date(2025, 6, 1, 20, 0, 0, 0, time.UTC),
+			Data: json.RawMessage(`{"item_name":"+cmd|' /C calc'!A0"}`)},
+		event.Event{AggregateID: "auction-1", Type: event.AuctionCanceled, CreatedAt: time.Date(2025, 6, 2, 20, 0, 0, 0, time.UTC)},
+	))
+	x := export.NewExporter(players, events, noop.NewTracerProvider())
+
+	tests := []struct {
+		kind export.Kind
+		want string
+	}{
+		{kind: export.Standings, want: "1,\"'=HYPERLINK(\"\"http://evil\"\")\",d1,p1,10\n"},
+		{kind: export.Transactions, want: "2025-06-01T20:00:00Z,p1,\"'=HYPERLINK(\"\"http://evil\"\")\",dkp.deducted,-10,'@SUM(A1),\n"},
+		{kind: export.Auctions, want: "2025-06-02T20:00:00Z,auction-1,'+cmd|' /C calc'!A0,canceled,,,,\n"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := x.Write(context.Background(), &buf, tt.kind, export.Range{}); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if _, rows, _ := strings.Cut(buf.String(), "\n"); rows != tt.want {
+				t.Errorf("Write() rows =\n%s\nwant\n%s", rows, tt.want)
+			}
+		})
+	}
+}
+
+// memArchive holds the events and snapshots the retention job archived.
+type memArchive struct {
+	events    []event.Event
+	snapshots map[string]event.Snapshot
+}
+
+func (m *memArchive) SaveSnapshot(context.Context, event.Snapshot) error { return nil }
+
+func (m *memArchive) LoadSnapshot(_ context.Context, id string) (*event.Snapshot, error) {
+	s, ok := m.snapshots[id]
+	if !ok {
+		return nil, fmt.Errorf("no snapshot of %s", id)
+	}
+	return &s, nil
+}
+
+func (m *memArchive) ArchiveAggregate(context.Context, string, int) (int, error) { return 0, nil }
+
+func (m *memArchive) Archived(_ context.Context, types ...event.Type) ([]event.Event, error) {
+	var events []event.Event
+	for _, e := range m.events {
+		if (event.Query{Types: types}).Matches(e) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func TestExporter_WriteArchivedAuctions(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 20, 0, 0, 0, time.UTC) }
+	players := storetest.NewPlayers(store.Player{ID: "p1", CharacterName: "Gandalf"})
+	events := eventtest.NewStore(eventtest.WithEvents(
+		event.Event{AggregateID: "auction-3", Type: event.AuctionStarted, CreatedAt: day(5),
+			Data: json.RawMessage(`{"item_name":"Ring","min_bid":5}`)},
+		event.Event{AggregateID: "auction-3", Type: event.AuctionCanceled, CreatedAt: day(6)},
+	))
+	archive := &memArchive{
+		events: []event.Event{
+			{AggregateID: "auction-1", Type: event.AuctionStarted, CreatedAt: day(1),
+				Data: json.RawMessage(`{"item_name":"Sword","min_bid":10,"raid_id":"raid-1"}`)},
+			{AggregateID: "auction-1", Type: event.AuctionClosed, CreatedAt: day(2),
+				Data: json.RawMessage(`{"winner_id":"p1","amount":60}`)},
+			// The start of auction-2 is gone; its snapshot names the item.
+			{AggregateID: "auction-2", Type: event.AuctionClosed, CreatedAt: day(3),
+				Data: json.RawMessage(`{"winner_id":"p1","amount":30}`)},
+		},
+		snapshots: map[string]event.Snapshot{
+			"auction-2": {AggregateID: "auction-2", State: json.RawMessage(`{"id":"auction-2","item_name":"Shield","status":"closed"}`)},
+		},
+	}
+	x := export.NewExporter(players, events, noop.NewTracerProvider(), export.WithArchive(archive))
+
+	var buf bytes.Buffer
+	if err := x.Write(context.Background(), &buf, export.Auctions, export.Range{Since: day(2)}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := "time,auction_id,item,status,winner_id,winner,amount,raid_id\n" +
+		"2025-06-02T20:00:00Z,auction-1,Sword,closed,p1,Gandalf,60,raid-1\n" +
+		"2025-06-03T20:00:00Z,auction-2,Shield,closed,p1,Gandalf,30,\n" +
+		"2025-06-06T20:00:00Z,auction-3,Ring,canceled,,,,\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExporter_WriteUnknownKind(t *testing.T) {
+	err := newExporter().Write(context.Background(), &bytes.Buffer{}, "loot", export.Range{})
+	if err == nil || !strings.Contains(err.Error(), "unknown export kind") {
+		t.Errorf("Write() error = %v, want unknown export kind", err)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     export.Range
+		wantErr  bool
+	}{
+		{name: "unbounded"},
+		{
+			name: "to is inclusive",
+			from: "2025-06-01",
+			to:   "2025-06-30",
+			want: export.Range{
+				Since: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+				Until: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{name: "same day", from: "2025-06-01", to: "2025-06-01", want: export.Range{
+			Since: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+			Until: time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC),
+		}},
+		{name: "malformed", from: "06/01/2025", wantErr: true},
+		{name: "reversed", from: "2025-06-30", to: "2025-06-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := export.ParseRange(tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Since.Equal(tt.want.Since) || !got.Until.Equal(tt.want.Until) {
+				t.Errorf("ParseRange() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExporter_WriteAddon(t *testing.T) {
+	players := storetest.NewPlayers(
+		store.Player{ID: "p1", CharacterName: `Gan"dalf`, DKP: 90},
+	)
+	events := eventtest.NewStore(eventtest.WithEvents(
+		event.Event{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{"player_id":"p1","amount":100}`)},
+		event.Event{AggregateID: "p1", Type: event.DKPDeducted, Data: json.RawMessage(`{"player_id":"p1","amount":-10}`)},
+	))
+	x := export.NewExporter(players, events, noop.NewTracerProvider())
+
+	var buf bytes.Buffer
+	if err := x.WriteAddon(context.Background(), &buf, export.MonolithDKP); err != nil {
+		t.Fatalf("WriteAddon() error = %v", err)
+	}
+	want := "MonDKP_DKPTable = {\n" +
+		"\t{\n" +
+		"\t\t[\"player\"] = \"Gan\\\"dalf\",\n" +
+		"\t\t[\"dkp\"] = 90,\n" +
+		"\t\t[\"lifetime_gained\"] = 100,\n" +
+		"\t\t[\"lifetime_spent\"] = 10,\n" +
+		"\t},\n" +
+		"}\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteAddon() =\n%s\nwant\n%s", got, want)
+	}
+
+	if err := x.WriteAddon(context.Background(), &buf, export.CSV); err == nil {
+		t.Error("WriteAddon(CSV) error = nil, want error")
+	}
+}
@@ -0,0 +1,171 @@
+package export
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/gdkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// LedgerFilename is the file name of a player's ledger export.
+const LedgerFilename = "dkp-ledger.csv"
+
+// ledgerEntry is a row of a ledger with the time it is ordered by.
+type ledgerEntry struct {
+	at     time.Time
+	record []string
+}
+
+// WriteLedger writes the complete DKP and loot history of p to w as CSV
+// with a header row, oldest first: each DKP change with the balance it
+// left, and each auction p won with the price paid. Balances are worked
+// back from p's current DKP, as in the player's DKP history.
+func (x *Exporter) WriteLedger(ctx context.Context, w io.Writer, p *store.Player) error {
+	ctx, span := x.tracer.Start(ctx, "Exporter.WriteLedger",
+		trace.WithAttributes(attribute.String("player_id", p.ID)),
+	)
+	defer span.End()
+
+	changes, err := x.events.Query(ctx, event.Query{
+		Types:       []event.Type{event.DKPAwarded, event.DKPDeducted, event.DKPAdjusted},
+		AggregateID: p.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("querying DKP events: %w", err)
+	}
+	// Queries return the newest events first.
+	var entries []ledgerEntry
+	balance := p.DKP
+	for _, e := range changes {
+		var d event.DKPChangeData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return fmt.Errorf("decoding event %s: %w", e.ID, err)
+		}
+		entries = append(entries, ledgerEntry{at: e.CreatedAt, record: []string{
+			e.CreatedAt.UTC().Format(time.RFC3339),
+			string(e.Type),
+			strconv.Itoa(d.Amount),
+			strconv.Itoa(balance),
+			dkp.DKP,
+			text(d.Reason),
+			"",
+			"",
+			e.Actor,
+		}})
+		balance -= d.Amount
+	}
+
+	loot, err := x.loot(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, loot...)
+	slices.SortStableFunc(entries, func(a, b ledgerEntry) int {
+		return cmp.Compare(a.at.UnixNano(), b.at.UnixNano())
+	})
+
+	records := [][]string{{"time", "type", "amount", "balance", "currency", "reason", "item", "auction_id", "actor"}}
+	for _, e := range entries {
+		records = append(records, e.record)
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(records); err != nil {
+		return fmt.Errorf("writing csv: %w", err)
+	}
+	return nil
+}
+
+// loot returns the ledger entries of the auctions the player playerID won.
+// What a DKP auction cost is also among the player's DKP changes, so the
+// entries leave the balance empty.
+func (x *Exporter) loot(ctx context.Context, playerID string) ([]ledgerEntry, error) {
+	ended, err := x.query(ctx, event.Query{
+		Types: []event.Type{event.AuctionClosed, event.AuctionBoughtOut},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying auction results: %w", err)
+	}
+	won := make(map[string]int)
+	var wins []event.Event
+	for _, e := range ended {
+		var winner string
+		var amount int
+		switch e.Type {
+		case event.AuctionClosed:
+			var d event.AuctionClosedData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+			}
+			winner, amount = d.WinnerID, d.Amount
+		case event.AuctionBoughtOut:
+			var d event.AuctionBoughtOutData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+			}
+			winner, amount = d.BuyerID, d.Amount
+		}
+		if winner == playerID {
+			won[e.AggregateID] = amount
+			wins = append(wins, e)
+		}
+	}
+	if len(wins) == 0 {
+		return nil, nil
+	}
+
+	started, err := x.query(ctx, event.Query{Types: []event.Type{event.AuctionStarted}})
+	if err != nil {
+		return nil, fmt.Errorf("querying auction starts: %w", err)
+	}
+	items := make(map[string]event.AuctionStartedData, len(wins))
+	for _, e := range started {
+		if _, ok := won[e.AggregateID]; !ok {
+			continue
+		}
+		var d event.AuctionStartedData
+		if err := json.Unmarshal(e.Data, &d); err == nil {
+			items[e.AggregateID] = d
+		}
+	}
+
+	entries := make([]ledgerEntry, 0, len(wins))
+	for _, e := range wins {
+		item := items[e.AggregateID]
+		entries = append(entries, ledgerEntry{at: e.CreatedAt, record: []string{
+			e.CreatedAt.UTC().Format(time.RFC3339),
+			string(e.Type),
+			strconv.Itoa(won[e.AggregateID]),
+			"",
+			currency(item),
+			"",
+			text(item.ItemName),
+			e.AggregateID,
+			e.Actor,
+		}})
+	}
+	return entries, nil
+}
+
+// currency returns what the auction started with d was bid in.
+func currency(d event.AuctionStartedData) string {
+	switch {
+	case d.RaidID != "" && d.RaidMode != gdkp.ModeDKP:
+		return "gold"
+	case d.Points != "":
+		return d.Points
+	}
+	return dkp.DKP
+}
@@ -0,0 +1,439 @@
+// Package gdkp runs GDKP raids, in which items are auctioned for gold
+// rather than DKP. The gold a raid's auctions sell for forms its pot,
+// which is split evenly among the participants when the raid ends, after
+// the organizer's cut. Raids are kept as events in the event store, a gold
+// ledger apart from DKP balances.
+//
+// A raid may instead be a DKP raid, whose auctions are bid on in DKP as
+// usual. It only groups the auctions held during the raid, so that its
+// loot can be summarized; it has no pot to pay out.
+package gdkp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// Errors returned by raid operations.
+var (
+	ErrRaidInProgress = derrors.New(derrors.Conflict, "RAID_IN_PROGRESS", "a raid is already in progress")
+	ErrNoRaid         = derrors.New(derrors.NotFound, "NO_RAID", "no raid is in progress")
+	ErrAlreadyJoined  = derrors.New(derrors.Conflict, "ALREADY_JOINED", "you have already joined the raid")
+	ErrAuctionsOpen   = derrors.New(derrors.Conflict, "RAID_AUCTIONS_OPEN", "close or cancel the raid's auctions before ending it")
+	ErrInvalidCut     = derrors.New(derrors.Validation, "INVALID_CUT", "the organizer cut must be a percentage between 0 and 100")
+	ErrInvalidMode    = derrors.New(derrors.Validation, "INVALID_RAID_MODE", "the raid mode must be gdkp or dkp")
+)
+
+// Raid modes.
+const (
+	// ModeGDKP raids auction items for gold, which forms their pot.
+	ModeGDKP = "gdkp"
+	// ModeDKP raids auction items for DKP and have no pot.
+	ModeDKP = "dkp"
+)
+
+// Raid is a raid as recorded in its events.
+type Raid struct {
+	ID   string
+	Name string
+	// Mode is ModeGDKP or ModeDKP.
+	Mode string
+	// Organizer is the Discord ID of the member who started the raid.
+	Organizer string
+	// OrganizerCut is the percentage of the pot paid to the organizer.
+	OrganizerCut float64
+	// Participants are the Discord IDs of the members who joined, in the
+	// order they joined.
+	Participants []string
+	// JoinedAt maps each participant to when they joined.
+	JoinedAt  map[string]time.Time
+	StartedAt time.Time
+	Ended     bool
+	Version   int
+}
+
+// Gold reports whether the raid's auctions are bid on in gold.
+func (r *Raid) Gold() bool {
+	return r.Mode != ModeDKP
+}
+
+// Sale is an item won in one of a raid's auctions.
+type Sale struct {
+	AuctionID string
+	ItemName  string
+	// WinnerID is the player ID of the winner.
+	WinnerID string
+	// Amount is what the winner paid, in gold in a GDKP raid and in DKP
+	// in a DKP raid.
+	Amount int
+}
+
+// Pot is what a raid's auctions have raised: the gold of a GDKP raid, or
+// the DKP spent on the loot of a DKP raid.
+type Pot struct {
+	Sales []Sale
+	Total int
+	// Open counts the raid's auctions that are still open.
+	Open int
+}
+
+// Payout is how the pot of an ended raid is paid out.
+type Payout struct {
+	Raid *Raid
+	Pot  *Pot
+	// OrganizerCut is the gold paid to the organizer, including what was
+	// left over after splitting the rest evenly.
+	OrganizerCut int
+	// Share is the gold paid to each participant.
+	Share int
+}
+
+// Split divides pot into the organizer's cut of cutPercent, rounded down,
+// and even shares for participants. What cannot be split evenly, or all of
+// it without participants, goes to the organizer.
+func Split(pot int, cutPercent float64, participants int) (organizerCut, share int) {
+	organizerCut = int(math.Floor(float64(pot) * cutPercent / 100))
+	if participants <= 0 {
+		return pot, 0
+	}
+	rest := pot - organizerCut
+	share = rest / participants
+	return organizerCut + rest%participants, share
+}
+
+// Service starts, tracks, and ends raids. Only one raid is in progress at
+// a time.
+type Service struct {
+	events     event.Store
+	defaultCut float64
+	logger     *slog.Logger
+	tracer     trace.Tracer
+	clock      clock.Clock
+
+	// mu serializes changes, which version the raid's events.
+	mu sync.Mutex
+}
+
+// NewService returns a Service that records raids in events and cuts the
+// configured percentage for organizers of raids started without one.
+func NewService(events event.Store, cfg config.GDKPConfig, logger *slog.Logger, tp trace.TracerProvider, clk clock.Clock) *Service {
+	return &Service{
+		events:     events,
+		defaultCut: cfg.OrganizerCut,
+		logger:     logger,
+		tracer:     tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/gdkp"),
+		clock:      clk,
+	}
+}
+
+// Start starts a raid named name, organized by the member organizer, in
+// mode, which defaults to ModeGDKP. A negative cutPercent selects the
+// configured organizer cut. DKP raids have no organizer cut.
+func (s *Service) Start(ctx context.Context, name, organizer, mode string, cutPercent float64) (*Raid, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Start",
+		trace.WithAttributes(
+			attribute.String("raid.name", name),
+			attribute.String("raid.mode", mode),
+		),
+	)
+	defer span.End()
+
+	switch mode {
+	case "", ModeGDKP:
+		mode = ModeGDKP
+		if cutPercent < 0 {
+			cutPercent = s.defaultCut
+		}
+		if cutPercent > 100 {
+			return nil, ErrInvalidCut
+		}
+	case ModeDKP:
+		cutPercent = 0
+	default:
+		return nil, ErrInvalidMode
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.Active(ctx)
+	switch {
+	case err == nil:
+		return nil, ErrRaidInProgress
+	case !errors.Is(err, ErrNoRaid):
+		return nil, err
+	}
+
+	r := &Raid{
+		ID:           fmt.Sprintf("raid-%d", s.clock.Now().UnixNano()),
+		Name:         name,
+		Mode:         mode,
+		Organizer:    organizer,
+		OrganizerCut: cutPercent,
+		JoinedAt:     make(map[string]time.Time),
+		StartedAt:    s.clock.Now(),
+	}
+	started := event.GDKPRaidStartedData{
+		Name:         name,
+		Organizer:    organizer,
+		OrganizerCut: cutPercent,
+	}
+	if mode == ModeDKP {
+		started.Mode = ModeDKP
+	}
+	data, _ := json.Marshal(started)
+	if err := s.append(ctx, r, event.GDKPRaidStarted, data); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "raid started",
+		slog.String("raid_id", r.ID),
+		slog.String("name", name),
+		slog.String("mode", mode),
+		slog.Float64("organizer_cut", cutPercent),
+	)
+	return r, nil
+}
+
+// Active returns the raid in progress, or ErrNoRaid.
+func (s *Service) Active(ctx context.Context) (*Raid, error) {
+	started, err := s.events.LoadByType(ctx, event.GDKPRaidStarted)
+	if err != nil {
+		return nil, fmt.Errorf("loading raid started events: %w", err)
+	}
+	ended, err := s.events.LoadByType(ctx, event.GDKPRaidEnded)
+	if err != nil {
+		return nil, fmt.Errorf("loading raid ended events: %w", err)
+	}
+	done := make(map[string]bool, len(ended))
+	for _, e := range ended {
+		done[e.AggregateID] = true
+	}
+	// IDs embed the start time, so the newest raid sorts last.
+	var id string
+	for _, e := range started {
+		if !done[e.AggregateID] && e.AggregateID > id {
+			id = e.AggregateID
+		}
+	}
+	if id == "" {
+		return nil, ErrNoRaid
+	}
+	return s.Get(ctx, id)
+}
+
+// Get returns the raid raidID, ended or not.
+func (s *Service) Get(ctx context.Context, raidID string) (*Raid, error) {
+	events, err := s.events.Load(ctx, raidID)
+	if err != nil {
+		return nil, fmt.Errorf("loading raid events: %w", err)
+	}
+	if len(events) == 0 {
+		return nil, ErrNoRaid.Wrap(fmt.Errorf("raid %s", raidID))
+	}
+	return replay(events)
+}
+
+// Join adds the member discordID to the raid in progress.
+func (s *Service) Join(ctx context.Context, discordID string) (*Raid, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Join",
+		trace.WithAttributes(attribute.String("discord_id", discordID)),
+	)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.Active(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(r.Participants, discordID) {
+		return nil, ErrAlreadyJoined
+	}
+	data, _ := json.Marshal(event.GDKPRaidJoinedData{DiscordID: discordID})
+	if err := s.append(ctx, r, event.GDKPRaidJoined, data); err != nil {
+		return nil, err
+	}
+	r.Participants = append(r.Participants, discordID)
+	r.JoinedAt[discordID] = s.clock.Now()
+	return r, nil
+}
+
+// Pot returns the gold raised so far by the auctions of raidID.
+func (s *Service) Pot(ctx context.Context, raidID string) (*Pot, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Pot",
+		trace.WithAttributes(attribute.String("raid.id", raidID)),
+	)
+	defer span.End()
+
+	// Queued and scheduled auctions have yet to start but count as open.
+	var started []event.Event
+	for _, t := range []event.Type{event.AuctionScheduled, event.AuctionQueued, event.AuctionStarted} {
+		events, err := s.events.LoadByType(ctx, t)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s events: %w", t, err)
+		}
+		started = append(started, events...)
+	}
+	pot := &Pot{}
+	seen := make(map[string]bool)
+	for _, e := range started {
+		var d event.AuctionStartedData
+		if err := json.Unmarshal(e.Data, &d); err != nil || d.RaidID != raidID || seen[e.AggregateID] {
+			continue
+		}
+		seen[e.AggregateID] = true
+		events, err := s.events.Load(ctx, e.AggregateID)
+		if err != nil {
+			return nil, fmt.Errorf("loading auction %s: %w", e.AggregateID, err)
+		}
+		sale, open, err := sold(events)
+		if err != nil {
+			return nil, fmt.Errorf("auction %s: %w", e.AggregateID, err)
+		}
+		if open {
+			pot.Open++
+		}
+		if sale != nil {
+			sale.AuctionID, sale.ItemName = e.AggregateID, d.ItemName
+			pot.Sales = append(pot.Sales, *sale)
+			pot.Total += sale.Amount
+		}
+	}
+	span.SetAttributes(attribute.Int("pot", pot.Total))
+	return pot, nil
+}
+
+// sold returns the sale recorded by an auction's events, or nil if it
+// ended without a winner or is still open, as reported by open.
+func sold(events []event.Event) (sale *Sale, open bool, err error) {
+	for _, e := range events {
+		switch e.Type {
+		case event.AuctionClosed:
+			var d event.AuctionClosedData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, false, fmt.Errorf("decoding close event: %w", err)
+			}
+			if d.WinnerID == "" {
+				return nil, false, nil
+			}
+			return &Sale{WinnerID: d.WinnerID, Amount: d.Amount}, false, nil
+		case event.AuctionBoughtOut:
+			var d event.AuctionBoughtOutData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, false, fmt.Errorf("decoding buyout event: %w", err)
+			}
+			return &Sale{WinnerID: d.BuyerID, Amount: d.Amount}, false, nil
+		case event.AuctionCanceled:
+			return nil, false, nil
+		}
+	}
+	return nil, true, nil
+}
+
+// End ends the raid in progress and, for a GDKP raid, splits its pot. The
+// raid's auctions must all have ended.
+func (s *Service) End(ctx context.Context) (*Payout, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.End")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.Active(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pot, err := s.Pot(ctx, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	if pot.Open > 0 {
+		return nil, ErrAuctionsOpen
+	}
+
+	var cut, share int
+	if r.Gold() {
+		cut, share = Split(pot.Total, r.OrganizerCut, len(r.Participants))
+	}
+	data, _ := json.Marshal(event.GDKPRaidEndedData{
+		Pot:          pot.Total,
+		OrganizerCut: cut,
+		Share:        share,
+		Participants: r.Participants,
+	})
+	if err := s.append(ctx, r, event.GDKPRaidEnded, data); err != nil {
+		return nil, err
+	}
+	r.Ended = true
+
+	s.logger.InfoContext(ctx, "raid ended",
+		slog.String("raid_id", r.ID),
+		slog.Int("pot", pot.Total),
+		slog.Int("participants", len(r.Participants)),
+		slog.Int("share", share),
+	)
+	return &Payout{Raid: r, Pot: pot, OrganizerCut: cut, Share: share}, nil
+}
+
+// append records an event of type t on r.
+func (s *Service) append(ctx context.Context, r *Raid, t event.Type, data json.RawMessage) error {
+	e := event.Event{
+		AggregateID: r.ID,
+		Type:        t,
+		Data:        data,
+		Version:     r.Version + 1,
+	}
+	if err := s.events.Append(ctx, e); err != nil {
+		return fmt.Errorf("recording %s event: %w", t, err)
+	}
+	r.Version = e.Version
+	return nil
+}
+
+// replay reconstructs a raid from its events.
+func replay(events []event.Event) (*Raid, error) {
+	r := &Raid{ID: events[0].AggregateID, JoinedAt: make(map[string]time.Time)}
+	for _, e := range events {
+		switch e.Type {
+		case event.GDKPRaidStarted:
+			var d event.GDKPRaidStartedData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, fmt.Errorf("unmarshaling raid started event: %w", err)
+			}
+			r.Name, r.Organizer, r.OrganizerCut = d.Name, d.Organizer, d.OrganizerCut
+			r.Mode = ModeGDKP
+			if d.Mode == ModeDKP {
+				r.Mode = ModeDKP
+			}
+			r.StartedAt = e.CreatedAt
+		case event.GDKPRaidJoined:
+			var d event.GDKPRaidJoinedData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, fmt.Errorf("unmarshaling raid joined event: %w", err)
+			}
+			r.Participants = append(r.Participants, d.DiscordID)
+			r.JoinedAt[d.DiscordID] = e.CreatedAt
+		case event.GDKPRaidEnded:
+			r.Ended = true
+		}
+		r.Version = e.Version
+	}
+	return r, nil
+}
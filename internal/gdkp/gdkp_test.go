@@ -0,0 +1,167 @@
+package gdkp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/gdkp"
+)
+
+// appendAuction appends to es the events of an auction held in raidID,
+// ended by end, or left open if end is empty.
+func appendAuction(t *testing.T, es *eventtest.Store, id, raidID string, end event.Type, data any) {
+	t.Helper()
+	started, _ := json.Marshal(event.AuctionStartedData{ItemName: "Item " + id, RaidID: raidID})
+	events := []event.Event{{AggregateID: id, Type: event.AuctionStarted, Data: started}}
+	if end != "" {
+		ended, _ := json.Marshal(data)
+		events = append(events, event.Event{AggregateID: id, Type: end, Data: ended})
+	}
+	if err := es.Append(context.Background(), events...); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name         string
+		pot          int
+		cut          float64
+		participants int
+		wantCut      int
+		wantShare    int
+	}{
+		{name: "even", pot: 1000, cut: 10, participants: 3, wantCut: 100, wantShare: 300},
+		{name: "remainder to organizer", pot: 1000, cut: 10, participants: 4, wantCut: 100, wantShare: 225},
+		{name: "uneven", pot: 1001, cut: 0, participants: 4, wantCut: 1, wantShare: 250},
+		{name: "cut rounded down", pot: 999, cut: 5, participants: 1, wantCut: 49, wantShare: 950},
+		{name: "no participants", pot: 500, cut: 10, participants: 0, wantCut: 500, wantShare: 0},
+		{name: "empty pot", pot: 0, cut: 10, participants: 5, wantCut: 0, wantShare: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cut, share := gdkp.Split(tt.pot, tt.cut, tt.participants)
+			if cut != tt.wantCut || share != tt.wantShare {
+				t.Errorf("Split(%d, %g, %d) = %d, %d, want %d, %d", tt.pot, tt.cut, tt.participants, cut, share, tt.wantCut, tt.wantShare)
+			}
+			if cut+share*tt.participants != tt.pot {
+				t.Errorf("Split(%d, %g, %d) pays out %d", tt.pot, tt.cut, tt.participants, cut+share*tt.participants)
+			}
+		})
+	}
+}
+
+func TestService_Raid(t *testing.T) {
+	es := eventtest.NewStore()
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	svc := gdkp.NewService(es, config.GDKPConfig{OrganizerCut: 10}, slog.Default(), noop.NewTracerProvider(), clk)
+	ctx := context.Background()
+
+	if _, err := svc.Active(ctx); !errors.Is(err, gdkp.ErrNoRaid) {
+		t.Fatalf("Active() before a raid error = %v, want ErrNoRaid", err)
+	}
+	if _, err := svc.Start(ctx, "Naxx", "organizer", "", 150); !errors.Is(err, gdkp.ErrInvalidCut) {
+		t.Errorf("Start(cut 150) error = %v, want ErrInvalidCut", err)
+	}
+	r, err := svc.Start(ctx, "Naxx", "organizer", "", -1)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if r.OrganizerCut != 10 {
+		t.Errorf("OrganizerCut = %g, want the configured 10", r.OrganizerCut)
+	}
+	if _, err := svc.Start(ctx, "Naxx again", "organizer", "", -1); !errors.Is(err, gdkp.ErrRaidInProgress) {
+		t.Errorf("second Start() error = %v, want ErrRaidInProgress", err)
+	}
+
+	for _, id := range []string{"d1", "d2", "d3"} {
+		if _, err := svc.Join(ctx, id); err != nil {
+			t.Fatalf("Join(%s) error = %v", id, err)
+		}
+	}
+	if _, err := svc.Join(ctx, "d1"); !errors.Is(err, gdkp.ErrAlreadyJoined) {
+		t.Errorf("second Join(d1) error = %v, want ErrAlreadyJoined", err)
+	}
+
+	appendAuction(t, es, "a1", r.ID, event.AuctionClosed, event.AuctionClosedData{WinnerID: "p1", Amount: 700})
+	appendAuction(t, es, "a2", r.ID, event.AuctionBoughtOut, event.AuctionBoughtOutData{BuyerID: "p2", Amount: 300})
+	appendAuction(t, es, "a3", r.ID, event.AuctionClosed, event.AuctionClosedData{})
+	appendAuction(t, es, "a4", r.ID, event.AuctionCanceled, struct{}{})
+	appendAuction(t, es, "a5", "", event.AuctionClosed, event.AuctionClosedData{WinnerID: "p1", Amount: 5000})
+	appendAuction(t, es, "a6", r.ID, "", nil)
+
+	pot, err := svc.Pot(ctx, r.ID)
+	if err != nil {
+		t.Fatalf("Pot() error = %v", err)
+	}
+	if pot.Total != 1000 || len(pot.Sales) != 2 || pot.Open != 1 {
+		t.Errorf("Pot() = %+v, want 1000 gold from 2 sales and 1 open auction", pot)
+	}
+	if _, err := svc.End(ctx); !errors.Is(err, gdkp.ErrAuctionsOpen) {
+		t.Fatalf("End() with an open auction error = %v, want ErrAuctionsOpen", err)
+	}
+
+	if err := es.Append(ctx, event.Event{AggregateID: "a6", Type: event.AuctionCanceled, Data: json.RawMessage(`{}`)}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	p, err := svc.End(ctx)
+	if err != nil {
+		t.Fatalf("End() error = %v", err)
+	}
+	if p.OrganizerCut != 100 || p.Share != 300 || !slices.Equal(p.Raid.Participants, []string{"d1", "d2", "d3"}) {
+		t.Errorf("End() = cut %d, share %d, participants %v, want 100, 300, [d1 d2 d3]", p.OrganizerCut, p.Share, p.Raid.Participants)
+	}
+	if _, err := svc.Active(ctx); !errors.Is(err, gdkp.ErrNoRaid) {
+		t.Errorf("Active() after End error = %v, want ErrNoRaid", err)
+	}
+
+	ended, _ := es.LoadByType(ctx, event.GDKPRaidEnded)
+	if len(ended) != 1 || ended[0].Version != 5 {
+		t.Errorf("raid ended events = %+v, want one at version 5", ended)
+	}
+}
+
+func TestService_DKPRaid(t *testing.T) {
+	es := eventtest.NewStore()
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	svc := gdkp.NewService(es, config.GDKPConfig{OrganizerCut: 10}, slog.Default(), noop.NewTracerProvider(), clk)
+	ctx := context.Background()
+
+	if _, err := svc.Start(ctx, "Naxx", "organizer", "silver", -1); !errors.Is(err, gdkp.ErrInvalidMode) {
+		t.Errorf("Start(mode silver) error = %v, want ErrInvalidMode", err)
+	}
+	r, err := svc.Start(ctx, "Naxx", "organizer", gdkp.ModeDKP, -1)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if r.Gold() || r.OrganizerCut != 0 {
+		t.Errorf("DKP raid Gold() = %t, OrganizerCut = %g, want false, 0", r.Gold(), r.OrganizerCut)
+	}
+	if _, err := svc.Join(ctx, "d1"); err != nil {
+		t.Fatalf("Join() error = %v", err)
+	}
+	appendAuction(t, es, "a1", r.ID, event.AuctionClosed, event.AuctionClosedData{WinnerID: "p1", Amount: 70})
+
+	got, err := svc.Get(ctx, r.ID)
+	if err != nil || got.Mode != gdkp.ModeDKP {
+		t.Fatalf("Get() = %+v, %v, want a DKP raid", got, err)
+	}
+	p, err := svc.End(ctx)
+	if err != nil {
+		t.Fatalf("End() error = %v", err)
+	}
+	if p.Pot.Total != 70 || len(p.Pot.Sales) != 1 || p.OrganizerCut != 0 || p.Share != 0 {
+		t.Errorf("End() = pot %+v, cut %d, share %d, want 70 DKP from 1 sale and no split", p.Pot, p.OrganizerCut, p.Share)
+	}
+}
@@ -0,0 +1,137 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Discord access the bot needs to work in its guild.
+const (
+	// RequiredPermissions let the bot see channels and post auction
+	// announcements in them.
+	RequiredPermissions = discordgo.PermissionViewChannel | discordgo.PermissionSendMessages
+	// RequiredIntents fill the session state with the guild, its roles,
+	// and its channels, which the permission checks read.
+	RequiredIntents = discordgo.IntentsGuilds
+)
+
+// permissionNames names the permissions that checks report as missing.
+var permissionNames = []struct {
+	perm int64
+	name string
+}{
+	{discordgo.PermissionViewChannel, "View Channels"},
+	{discordgo.PermissionSendMessages, "Send Messages"},
+	{discordgo.PermissionEmbedLinks, "Embed Links"},
+	{discordgo.PermissionAttachFiles, "Attach Files"},
+}
+
+// DiscordChecker returns a Checker that fails unless the gateway is
+// connected, as reported by gateway, and the bot has the RequiredIntents
+// and RequiredPermissions in guildID. session returns the current Discord
+// session, or nil before one is opened. An empty guildID skips the guild
+// checks, for bots whose commands are registered globally.
+func DiscordChecker(gateway func(ctx context.Context) error, session func() *discordgo.Session, guildID string) Checker {
+	return Checker{
+		Name: "discord",
+		Check: func(ctx context.Context) error {
+			if err := gateway(ctx); err != nil {
+				return err
+			}
+			s := session()
+			if s == nil {
+				return errors.New("no discord session")
+			}
+			if s.Identify.Intents&RequiredIntents != RequiredIntents {
+				return fmt.Errorf("discord gateway intents %d lack the guilds intent", s.Identify.Intents)
+			}
+			if guildID == "" {
+				return nil
+			}
+			return CheckGuild(s.State, guildID)
+		},
+	}
+}
+
+// CheckGuild reports an error unless the user of st is a member of guildID
+// with the RequiredPermissions. st must hold the guild, its roles, and the
+// member, as a connected session's state does.
+func CheckGuild(st *discordgo.State, guildID string) error {
+	if st.User == nil {
+		return errors.New("discord session is not logged in")
+	}
+	g, err := st.Guild(guildID)
+	if err != nil {
+		return fmt.Errorf("bot is not a member of guild %s", guildID)
+	}
+	m, err := st.Member(guildID, st.User.ID)
+	if err != nil {
+		return fmt.Errorf("bot is not a member of guild %s", guildID)
+	}
+	if missing := RequiredPermissions &^ GuildPermissions(g, m); missing != 0 {
+		return fmt.Errorf("bot lacks the %s permissions in guild %s", PermissionNames(missing), guildID)
+	}
+	return nil
+}
+
+// CheckChannel reports an error unless the user of st has perms in
+// channelID. st must hold the channel and its guild, roles, and member.
+func CheckChannel(st *discordgo.State, channelID string, perms int64) error {
+	if st.User == nil {
+		return errors.New("discord session is not logged in")
+	}
+	granted, err := st.UserChannelPermissions(st.User.ID, channelID)
+	if err != nil {
+		return fmt.Errorf("channel %s: %w", channelID, err)
+	}
+	if missing := perms &^ granted; missing != 0 {
+		return fmt.Errorf("bot lacks the %s permissions in channel %s", PermissionNames(missing), channelID)
+	}
+	return nil
+}
+
+// GuildPermissions returns the guild-wide permissions of m in g: those of
+// the @everyone role and m's roles, or all for the owner and
+// administrators.
+func GuildPermissions(g *discordgo.Guild, m *discordgo.Member) int64 {
+	if m.User != nil && m.User.ID == g.OwnerID {
+		return discordgo.PermissionAll
+	}
+	var perms int64
+	for _, r := range g.Roles {
+		if r.ID == g.ID {
+			perms |= r.Permissions
+			continue
+		}
+		for _, id := range m.Roles {
+			if r.ID == id {
+				perms |= r.Permissions
+				break
+			}
+		}
+	}
+	if perms&discordgo.PermissionAdministrator != 0 {
+		return discordgo.PermissionAll
+	}
+	return perms
+}
+
+// PermissionNames lists the names of perms, such as "View Channels, Send
+// Messages". Permissions without a name are shown as a bit mask.
+func PermissionNames(perms int64) string {
+	var names []string
+	for _, p := range permissionNames {
+		if perms&p.perm != 0 {
+			names = append(names, p.name)
+			perms &^= p.perm
+		}
+	}
+	if perms != 0 {
+		names = append(names, fmt.Sprintf("0x%x", perms))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,115 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/health"
+)
+
+// newState returns the state of a bot that is a member of guild "g" with
+// the given role permissions. The @everyone role may view channels.
+func newState(t *testing.T, rolePerms int64) *discordgo.State {
+	t.Helper()
+	st := discordgo.NewState()
+	st.User = &discordgo.User{ID: "bot"}
+	err := st.GuildAdd(&discordgo.Guild{
+		ID:      "g",
+		OwnerID: "owner",
+		Roles: []*discordgo.Role{
+			{ID: "g", Permissions: discordgo.PermissionViewChannel},
+			{ID: "bots", Permissions: rolePerms},
+		},
+		Channels: []*discordgo.Channel{
+			{ID: "open", GuildID: "g"},
+			{ID: "muted", GuildID: "g", PermissionOverwrites: []*discordgo.PermissionOverwrite{
+				{ID: "bots", Type: discordgo.PermissionOverwriteTypeRole, Deny: discordgo.PermissionSendMessages},
+			}},
+		},
+	})
+	if err == nil {
+		err = st.MemberAdd(&discordgo.Member{GuildID: "g", User: &discordgo.User{ID: "bot"}, Roles: []string{"bots"}})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestCheckGuild(t *testing.T) {
+	tests := []struct {
+		name      string
+		rolePerms int64
+		guildID   string
+		wantErr   string
+	}{
+		{name: "has permissions", rolePerms: discordgo.PermissionSendMessages, guildID: "g"},
+		{name: "administrator", rolePerms: discordgo.PermissionAdministrator, guildID: "g"},
+		{name: "missing send messages", guildID: "g", wantErr: "lacks the Send Messages permissions"},
+		{name: "not a member", rolePerms: discordgo.PermissionSendMessages, guildID: "other", wantErr: "not a member of guild other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := health.CheckGuild(newState(t, tt.rolePerms), tt.guildID)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("CheckGuild() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckChannel(t *testing.T) {
+	st := newState(t, discordgo.PermissionSendMessages)
+
+	if err := health.CheckChannel(st, "open", health.RequiredPermissions); err != nil {
+		t.Errorf("CheckChannel(open) = %v, want nil", err)
+	}
+	err := health.CheckChannel(st, "muted", health.RequiredPermissions)
+	if err == nil || !strings.Contains(err.Error(), "lacks the Send Messages permissions in channel muted") {
+		t.Errorf("CheckChannel(muted) = %v, want missing Send Messages", err)
+	}
+}
+
+func TestDiscordChecker(t *testing.T) {
+	connected := func(context.Context) error { return nil }
+	session := &discordgo.Session{State: newState(t, discordgo.PermissionSendMessages)}
+
+	tests := []struct {
+		name    string
+		gateway func(context.Context) error
+		session *discordgo.Session
+		intents discordgo.Intent
+		wantErr string
+	}{
+		{name: "healthy", gateway: connected, session: session},
+		{name: "gateway down", gateway: func(context.Context) error { return errors.New("discord gateway not connected") }, session: session, wantErr: "not connected"},
+		{name: "no session", gateway: connected, wantErr: "no discord session"},
+		{name: "missing intent", gateway: connected, session: session, intents: discordgo.IntentsGuildMessages, wantErr: "lack the guilds intent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.session != nil {
+				tt.session.Identify.Intents = discordgo.IntentsAllWithoutPrivileged
+				if tt.intents != 0 {
+					tt.session.Identify.Intents = tt.intents
+				}
+			}
+			c := health.DiscordChecker(tt.gateway, func() *discordgo.Session { return tt.session }, "g")
+			err := c.Check(context.Background())
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Check() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPermissionNames(t *testing.T) {
+	got := health.PermissionNames(discordgo.PermissionViewChannel | discordgo.PermissionSendMessages | discordgo.PermissionBanMembers)
+	if want := "View Channels, Send Messages, 0x4"; got != want {
+		t.Errorf("PermissionNames() = %q, want %q", got, want)
+	}
+}
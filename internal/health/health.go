@@ -14,6 +14,7 @@ import (
 type Status struct {
 	Status    string            `json:"status"`
 	Checks    map[string]string `json:"checks,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
 	Timestamp string            `json:"timestamp"`
 }
 
@@ -23,11 +24,19 @@ type Checker struct {
 	Check func(ctx context.Context) error
 }
 
+// Detail is named information reported by the readiness endpoint whether or
+// not the service is ready, such as its role in a replica set.
+type Detail struct {
+	Name  string
+	Value func() string
+}
+
 // Handler provides HTTP health check endpoints.
 type Handler struct {
 	mu       sync.RWMutex
 	ready    bool
 	checkers []Checker
+	details  []Detail
 	clock    clock.Clock
 }
 
@@ -36,6 +45,13 @@ func NewHandler(clk clock.Clock, checkers ...Checker) *Handler {
 	return &Handler{checkers: checkers, clock: clk}
 }
 
+// AddDetail adds d to the readiness responses.
+func (h *Handler) AddDetail(d Detail) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.details = append(h.details, d)
+}
+
 // SetReady marks the service as ready to receive traffic.
 func (h *Handler) SetReady(ready bool) {
 	h.mu.Lock()
@@ -43,6 +59,13 @@ func (h *Handler) SetReady(ready bool) {
 	h.ready = ready
 }
 
+// Ready reports whether the service has been marked ready.
+func (h *Handler) Ready() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.ready
+}
+
 // LivenessHandler returns HTTP 200 if the process is alive.
 func (h *Handler) LivenessHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -58,11 +81,19 @@ func (h *Handler) ReadinessHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		h.mu.RLock()
 		ready := h.ready
+		var details map[string]string
+		if len(h.details) > 0 {
+			details = make(map[string]string, len(h.details))
+			for _, d := range h.details {
+				details[d.Name] = d.Value()
+			}
+		}
 		h.mu.RUnlock()
 
 		if !ready {
 			writeJSON(w, http.StatusServiceUnavailable, Status{
 				Status:    "not_ready",
+				Details:   details,
 				Timestamp: h.clock.Now().UTC().Format(time.RFC3339),
 			})
 			return
@@ -92,6 +123,7 @@ func (h *Handler) ReadinessHandler() http.HandlerFunc {
 		writeJSON(w, code, Status{
 			Status:    status,
 			Checks:    checks,
+			Details:   details,
 			Timestamp: h.clock.Now().UTC().Format(time.RFC3339),
 		})
 	}
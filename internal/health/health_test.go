@@ -96,3 +96,22 @@ func TestReadinessHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestReadinessHandler_Details(t *testing.T) {
+	for _, ready := range []bool{false, true} {
+		h := health.NewHandler(testClk)
+		h.AddDetail(health.Detail{Name: "role", Value: func() string { return "follower" }})
+		h.SetReady(ready)
+
+		rec := httptest.NewRecorder()
+		h.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		var s health.Status
+		if err := json.NewDecoder(rec.Body).Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		if s.Details["role"] != "follower" {
+			t.Errorf("ready=%v: details = %v, want role=follower", ready, s.Details)
+		}
+	}
+}
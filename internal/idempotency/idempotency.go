@@ -0,0 +1,162 @@
+// Package idempotency deduplicates state-changing operations so that
+// redelivered Discord interactions and double-clicks are applied only once.
+//
+// Callers attach a key (the interaction ID) to the context with WithKey.
+// Managers wrap each state change in Do, which reserves the key before the
+// change is applied and records its result afterwards. A repeated delivery
+// with the same key returns the recorded result without re-applying. Keys
+// are pruned once they are older than a TTL, by Run.
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// ErrInProgress is returned when an operation with the same key is still
+// being applied by another delivery.
+var ErrInProgress = derrors.New(derrors.Conflict, "IN_PROGRESS", "request is already being processed")
+
+type keyCtx struct{}
+
+// WithKey returns a context carrying an idempotency key.
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyCtx{}, key)
+}
+
+// KeyFromContext returns the key stored by WithKey, or "".
+func KeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(keyCtx{}).(string)
+	return key
+}
+
+// Guard applies operations at most once per idempotency key.
+// A nil *Guard runs every operation unconditionally.
+type Guard struct {
+	repo       store.IdempotencyRepository
+	staleAfter time.Duration
+}
+
+// Option configures a Guard.
+type Option func(*Guard)
+
+// WithStaleAfter lets Do take over a key that has been reserved for longer
+// than d without a recorded result, as happens when the replica applying
+// the operation crashes. d must exceed the longest an operation can run.
+// By default such a key stays in progress until it is pruned.
+func WithStaleAfter(d time.Duration) Option {
+	return func(g *Guard) { g.staleAfter = d }
+}
+
+// NewGuard returns a Guard backed by repo.
+func NewGuard(repo store.IdempotencyRepository, opts ...Option) *Guard {
+	g := &Guard{repo: repo}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Do runs fn unless the key in ctx was already used for op, in which case the
+// result recorded by the first run is returned instead. op scopes the key so
+// that one interaction may perform several distinct operations. When ctx has
+// no key, or g is nil, fn is always run.
+func Do[T any](ctx context.Context, g *Guard, op string, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+
+	key := KeyFromContext(ctx)
+	if g == nil || key == "" {
+		return fn(ctx)
+	}
+	key = op + ":" + key
+
+	prior, reserved, err := g.repo.Reserve(ctx, key, g.staleAfter)
+	if err != nil {
+		return zero, fmt.Errorf("reserving idempotency key: %w", err)
+	}
+	if !reserved {
+		if prior.Result == nil {
+			return zero, ErrInProgress
+		}
+		var result T
+		if err := json.Unmarshal(prior.Result, &result); err != nil {
+			return zero, fmt.Errorf("decoding recorded result: %w", err)
+		}
+		return result, nil
+	}
+
+	result, err := fn(ctx)
+	if err != nil {
+		// Let a later delivery retry the failed operation.
+		if releaseErr := g.repo.Release(ctx, key); releaseErr != nil {
+			return zero, errors.Join(err, fmt.Errorf("releasing idempotency key: %w", releaseErr))
+		}
+		return zero, err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return zero, fmt.Errorf("encoding result: %w", err)
+	}
+	if err := g.repo.Complete(ctx, key, data); err != nil {
+		return zero, fmt.Errorf("recording idempotency result: %w", err)
+	}
+	return result, nil
+}
+
+// Claim reserves the key in ctx for op and reports whether this caller got
+// it. Unlike Do it records no result, so it suits operations that several
+// replicas race to perform where exactly one should win. When ctx has no
+// key, or g is nil, Claim always succeeds.
+func (g *Guard) Claim(ctx context.Context, op string) (bool, error) {
+	key := KeyFromContext(ctx)
+	if g == nil || key == "" {
+		return true, nil
+	}
+	// A claim records no result, so it is never stale.
+	_, reserved, err := g.repo.Reserve(ctx, op+":"+key, 0)
+	if err != nil {
+		return false, fmt.Errorf("claiming idempotency key: %w", err)
+	}
+	return reserved, nil
+}
+
+// Prune deletes the keys reserved more than ttl ago. A delivery repeated
+// after that is applied again.
+func (g *Guard) Prune(ctx context.Context, ttl time.Duration) (int64, error) {
+	n, err := g.repo.Prune(ctx, ttl)
+	if err != nil {
+		return 0, fmt.Errorf("pruning idempotency keys: %w", err)
+	}
+	return n, nil
+}
+
+// Run prunes the keys older than ttl every interval on clk until ctx is
+// canceled. Failed prunes are logged and tried again at the next interval.
+func (g *Guard) Run(ctx context.Context, clk clock.Clock, ttl, interval time.Duration, logger *slog.Logger) {
+	ticker := clock.NewTicker(clk, interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+		n, err := g.Prune(ctx, ttl)
+		if err != nil {
+			logger.ErrorContext(ctx, "pruning idempotency keys failed", slog.Any("error", err))
+			continue
+		}
+		if n > 0 {
+			logger.InfoContext(ctx, "pruned idempotency keys", slog.Int64("keys", n))
+		}
+	}
+}
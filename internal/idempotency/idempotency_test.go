@@ -0,0 +1,264 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// memRepo implements store.IdempotencyRepository in memory.
+type memRepo struct {
+	mu    sync.Mutex
+	clock clock.Clock
+	keys  map[string]*store.IdempotencyRecord
+}
+
+func newMemRepo() *memRepo {
+	return newMemRepoAt(clock.Real{})
+}
+
+func newMemRepoAt(clk clock.Clock) *memRepo {
+	return &memRepo{clock: clk, keys: make(map[string]*store.IdempotencyRecord)}
+}
+
+func (r *memRepo) Reserve(_ context.Context, key string, staleAfter time.Duration) (*store.IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.clock.Now()
+	if rec, ok := r.keys[key]; ok {
+		if staleAfter <= 0 || rec.Result != nil || !rec.CreatedAt.Before(now.Add(-staleAfter)) {
+			return rec, false, nil
+		}
+	}
+	r.keys[key] = &store.IdempotencyRecord{Key: key, CreatedAt: now}
+	return nil, true, nil
+}
+
+func (r *memRepo) Complete(_ context.Context, key string, result []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[key].Result = result
+	return nil
+}
+
+func (r *memRepo) Release(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, key)
+	return nil
+}
+
+func (r *memRepo) Prune(_ context.Context, ttl time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for key, rec := range r.keys {
+		if rec.CreatedAt.Before(r.clock.Now().Add(-ttl)) {
+			delete(r.keys, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *memRepo) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.keys)
+}
+
+func TestDo_ReplaysRecordedResult(t *testing.T) {
+	g := idempotency.NewGuard(newMemRepo())
+	ctx := idempotency.WithKey(context.Background(), "interaction-1")
+
+	calls := 0
+	fn := func(context.Context) (int, error) {
+		calls++
+		return 42, nil
+	}
+
+	for range 3 {
+		got, err := idempotency.Do(ctx, g, "op", fn)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		if got != 42 {
+			t.Errorf("Do() = %d, want 42", got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times, want 1", calls)
+	}
+}
+
+func TestDo_ScopesKeyByOperation(t *testing.T) {
+	g := idempotency.NewGuard(newMemRepo())
+	ctx := idempotency.WithKey(context.Background(), "interaction-1")
+
+	calls := 0
+	fn := func(context.Context) (struct{}, error) {
+		calls++
+		return struct{}{}, nil
+	}
+
+	_, _ = idempotency.Do(ctx, g, "first", fn)
+	_, _ = idempotency.Do(ctx, g, "second", fn)
+	if calls != 2 {
+		t.Errorf("fn called %d times, want 2", calls)
+	}
+}
+
+func TestDo_ReleasesKeyOnError(t *testing.T) {
+	g := idempotency.NewGuard(newMemRepo())
+	ctx := idempotency.WithKey(context.Background(), "interaction-1")
+
+	errBoom := errors.New("boom")
+	if _, err := idempotency.Do(ctx, g, "op", func(context.Context) (int, error) { return 0, errBoom }); !errors.Is(err, errBoom) {
+		t.Fatalf("Do() error = %v, want %v", err, errBoom)
+	}
+
+	got, err := idempotency.Do(ctx, g, "op", func(context.Context) (int, error) { return 7, nil })
+	if err != nil {
+		t.Fatalf("retry Do() error = %v", err)
+	}
+	if got != 7 {
+		t.Errorf("retry Do() = %d, want 7", got)
+	}
+}
+
+func TestDo_InProgress(t *testing.T) {
+	repo := newMemRepo()
+	g := idempotency.NewGuard(repo)
+	ctx := idempotency.WithKey(context.Background(), "interaction-1")
+
+	// Simulate a concurrent delivery that reserved the key but has not
+	// completed yet.
+	_, _, _ = repo.Reserve(ctx, "op:interaction-1", 0)
+
+	_, err := idempotency.Do(ctx, g, "op", func(context.Context) (int, error) { return 1, nil })
+	if !errors.Is(err, idempotency.ErrInProgress) {
+		t.Errorf("Do() error = %v, want %v", err, idempotency.ErrInProgress)
+	}
+}
+
+func TestDo_ReclaimsStaleReservation(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC))
+	repo := newMemRepoAt(clk)
+	g := idempotency.NewGuard(repo, idempotency.WithStaleAfter(20*time.Minute))
+	ctx := idempotency.WithKey(context.Background(), "interaction-1")
+
+	// A replica reserved the key and crashed before completing.
+	_, _, _ = repo.Reserve(ctx, "op:interaction-1", 0)
+	fn := func(context.Context) (int, error) { return 1, nil }
+
+	clk.Advance(10 * time.Minute)
+	if _, err := idempotency.Do(ctx, g, "op", fn); !errors.Is(err, idempotency.ErrInProgress) {
+		t.Fatalf("Do() before stale_after error = %v, want %v", err, idempotency.ErrInProgress)
+	}
+
+	clk.Advance(15 * time.Minute)
+	got, err := idempotency.Do(ctx, g, "op", fn)
+	if err != nil {
+		t.Fatalf("Do() after stale_after error = %v", err)
+	}
+	if got != 1 {
+		t.Errorf("Do() = %d, want 1", got)
+	}
+
+	// A completed key is replayed however old it is.
+	clk.Advance(time.Hour)
+	if got, err := idempotency.Do(ctx, g, "op", func(context.Context) (int, error) { return 2, nil }); err != nil || got != 1 {
+		t.Errorf("Do() on a completed key = %d, %v, want the recorded 1", got, err)
+	}
+}
+
+func TestGuard_Run(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC))
+	repo := newMemRepoAt(clk)
+	g := idempotency.NewGuard(repo)
+	ctx := idempotency.WithKey(context.Background(), "interaction-1")
+
+	if _, err := idempotency.Do(ctx, g, "old", func(context.Context) (int, error) { return 1, nil }); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	clk.Advance(90 * time.Minute)
+	if _, err := idempotency.Do(ctx, g, "new", func(context.Context) (int, error) { return 1, nil }); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		g.Run(runCtx, clk, 2*time.Hour, time.Hour, slog.New(slog.DiscardHandler))
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	clk.BlockUntil(1)
+	clk.Advance(time.Hour)
+	deadline := time.Now().Add(5 * time.Second)
+	for repo.len() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d keys left after pruning, want 1", repo.len())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The pruned key is applied again.
+	calls := 0
+	_, _ = idempotency.Do(ctx, g, "old", func(context.Context) (int, error) { calls++; return 1, nil })
+	if calls != 1 {
+		t.Errorf("fn called %d times after pruning, want 1", calls)
+	}
+}
+
+func TestDo_WithoutKeyOrGuard(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		g    *idempotency.Guard
+	}{
+		{name: "no key", ctx: context.Background(), g: idempotency.NewGuard(newMemRepo())},
+		{name: "nil guard", ctx: idempotency.WithKey(context.Background(), "k"), g: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			fn := func(context.Context) (int, error) {
+				calls++
+				return calls, nil
+			}
+			_, _ = idempotency.Do(tt.ctx, tt.g, "op", fn)
+			_, _ = idempotency.Do(tt.ctx, tt.g, "op", fn)
+			if calls != 2 {
+				t.Errorf("fn called %d times, want 2", calls)
+			}
+		})
+	}
+}
+
+func TestGuard_Claim(t *testing.T) {
+	g := idempotency.NewGuard(newMemRepo())
+	ctx := idempotency.WithKey(context.Background(), "interaction-1")
+
+	if ok, err := g.Claim(ctx, "op"); err != nil || !ok {
+		t.Fatalf("first Claim() = %v, %v, want true", ok, err)
+	}
+	if ok, err := g.Claim(ctx, "op"); err != nil || ok {
+		t.Errorf("second Claim() = %v, %v, want false", ok, err)
+	}
+	if ok, _ := g.Claim(context.Background(), "op"); !ok {
+		t.Error("Claim() without a key = false, want true")
+	}
+}
@@ -0,0 +1,108 @@
+// Package ids generates the IDs of aggregates, such as auctions, so that
+// IDs created in quick succession never collide and cannot be guessed from
+// the time they were created.
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+)
+
+// Generator returns a new ID on each call. Implementations are safe for
+// concurrent use.
+type Generator interface {
+	NewID() string
+}
+
+// New returns the Generator for the strategy of cfg. Timestamps of ULIDs
+// are read from clk.
+func New(cfg config.IDsConfig, clk clock.Clock) (Generator, error) {
+	switch cfg.Strategy {
+	case config.IDStrategyULID:
+		return NewULID(clk), nil
+	case config.IDStrategyUUIDv7:
+		return UUIDv7{}, nil
+	default:
+		return nil, fmt.Errorf("unknown ID strategy %q", cfg.Strategy)
+	}
+}
+
+// crockford is the alphabet of ULIDs: Crockford's base32, which leaves
+// out I, L, O, and U.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID generates ULIDs: 26 characters that sort by the millisecond they
+// were generated in, followed by 80 random bits. IDs generated in the same
+// millisecond increment the random part of the last, so that they still
+// sort in the order they were generated.
+type ULID struct {
+	clock clock.Clock
+
+	// mu guards the millisecond and random part of the last ID.
+	mu      sync.Mutex
+	lastMS  uint64
+	lastHi  uint16
+	lastLow uint64
+}
+
+// NewULID returns a ULID generator reading timestamps from clk.
+func NewULID(clk clock.Clock) *ULID {
+	return &ULID{clock: clk}
+}
+
+// NewID returns a new ULID.
+func (g *ULID) NewID() string {
+	ms := uint64(g.clock.Now().UnixMilli())
+
+	g.mu.Lock()
+	hi, low := g.lastHi, g.lastLow
+	if ms > g.lastMS {
+		var entropy [10]byte
+		_, _ = rand.Read(entropy[:])
+		hi, low = binary.BigEndian.Uint16(entropy[:2]), binary.BigEndian.Uint64(entropy[2:])
+	} else {
+		// The clock stood still or went back: keep the last timestamp and
+		// count up from the last random part.
+		ms = g.lastMS
+		low++
+		if low == 0 {
+			hi++
+		}
+	}
+	g.lastMS, g.lastHi, g.lastLow = ms, hi, low
+	g.mu.Unlock()
+
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], ms<<16|uint64(hi))
+	binary.BigEndian.PutUint64(b[8:], low)
+	return encode(b)
+}
+
+// encode returns the 128 bits of b in Crockford's base32, most significant
+// first, as 26 characters of which the first carries 3 bits.
+func encode(b [16]byte) string {
+	hi, low := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var s [26]byte
+	for i := 25; i >= 0; i-- {
+		s[i] = crockford[low&31]
+		low = low>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(s[:])
+}
+
+// UUIDv7 generates version 7 UUIDs, which sort likewise and fit UUID
+// columns. They are timestamped by the system clock.
+type UUIDv7 struct{}
+
+// NewID returns a new version 7 UUID.
+func (UUIDv7) NewID() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
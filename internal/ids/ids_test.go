@@ -0,0 +1,87 @@
+package ids_test
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/ids"
+)
+
+func TestULID(t *testing.T) {
+	// The clock stands still, so every ID falls in the same millisecond.
+	gen := ids.NewULID(clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)})
+
+	var mu sync.Mutex
+	var got []string
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 100 {
+				id := gen.NewID()
+				mu.Lock()
+				got = append(got, id)
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool, len(got))
+	for _, id := range got {
+		if len(id) != 26 {
+			t.Errorf("NewID() = %q, want 26 characters", id)
+		}
+		if seen[id] {
+			t.Errorf("NewID() returned %q twice", id)
+		}
+		seen[id] = true
+	}
+
+	var ordered []string
+	for range 100 {
+		ordered = append(ordered, gen.NewID())
+	}
+	if !slices.IsSorted(ordered) {
+		t.Error("IDs generated in one millisecond do not sort in the order they were generated")
+	}
+}
+
+func TestULID_SortsByTime(t *testing.T) {
+	at := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	first := ids.NewULID(clock.Mock{T: at}).NewID()
+	second := ids.NewULID(clock.Mock{T: at.Add(time.Millisecond)}).NewID()
+	if first >= second {
+		t.Errorf("ULID at %v = %q, not before the one a millisecond later, %q", at, first, second)
+	}
+	// 2025-06-15T12:00:00Z is 1749988800000 ms after the epoch.
+	if want := "01JXSQBXG0"; first[:10] != want {
+		t.Errorf("ULID timestamp = %q, want prefix %q", first, want)
+	}
+}
+
+func TestNew(t *testing.T) {
+	gen, err := ids.New(config.IDsConfig{Strategy: config.IDStrategyUUIDv7}, clock.Real{})
+	if err != nil {
+		t.Fatalf("New(uuidv7) error = %v", err)
+	}
+	u, err := uuid.Parse(gen.NewID())
+	if err != nil {
+		t.Fatalf("NewID() is not a UUID: %v", err)
+	}
+	if u.Version() != 7 {
+		t.Errorf("UUID version = %d, want 7", u.Version())
+	}
+
+	if _, err := ids.New(config.IDsConfig{Strategy: config.IDStrategyULID}, clock.Real{}); err != nil {
+		t.Errorf("New(ulid) error = %v", err)
+	}
+	if _, err := ids.New(config.IDsConfig{Strategy: "serial"}, clock.Real{}); err == nil {
+		t.Error("New(serial) error = nil, want an error")
+	}
+}
@@ -0,0 +1,278 @@
+// Package itemcost keeps the item cost table of guilds that hand out loot
+// at fixed prices instead of auctioning it. Officers set the DKP each item
+// costs, and awarding an item to a player deducts its cost. The table is
+// kept as events in the event store.
+package itemcost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// Errors returned by Service.
+var (
+	ErrNoItemName      = derrors.New(derrors.Validation, "NO_ITEM_NAME", "name the item")
+	ErrInvalidCost     = derrors.New(derrors.Validation, "INVALID_ITEM_COST", "an item must cost a positive amount of DKP")
+	ErrUnknownItem     = derrors.New(derrors.NotFound, "UNKNOWN_ITEM_COST", "the item has no cost; set one with /itemcost set")
+	ErrInsufficientDKP = derrors.New(derrors.Validation, "INSUFFICIENT_DKP", "the player has too little DKP for this item")
+	ErrArchivedPlayer  = derrors.New(derrors.Validation, "ARCHIVED_PLAYER", "archived players cannot be awarded loot")
+)
+
+// DKP looks players up and changes their DKP.
+type DKP interface {
+	GetPlayer(ctx context.Context, discordID string) (*store.Player, error)
+	AwardDKP(ctx context.Context, playerID string, amount int, reason string) error
+	DeductDKP(ctx context.Context, playerID string, amount int, reason string) error
+}
+
+// Item is an item of the cost table and the DKP it costs.
+type Item struct {
+	Name string
+	Cost int
+}
+
+// Service maintains the item cost table and awards items at their cost.
+type Service struct {
+	events event.Store
+	dkp    DKP
+	logger *slog.Logger
+	tracer trace.Tracer
+
+	// mu serializes changes, which version the table's events.
+	mu sync.Mutex
+}
+
+// NewService returns a Service that records the item cost table in events
+// and charges awarded items through dkp.
+func NewService(events event.Store, dkp DKP, logger *slog.Logger, tp trace.TracerProvider) *Service {
+	return &Service{
+		events: events,
+		dkp:    dkp,
+		logger: logger,
+		tracer: tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/itemcost"),
+	}
+}
+
+// Set sets the cost of the item itemName to cost DKP, adding it to the
+// table or changing its cost. Names are matched regardless of case; a
+// changed item keeps the name it was given first.
+func (s *Service) Set(ctx context.Context, itemName string, cost int) (Item, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Set",
+		trace.WithAttributes(
+			attribute.String("item", itemName),
+			attribute.Int("cost", cost),
+		),
+	)
+	defer span.End()
+
+	itemName = strings.TrimSpace(itemName)
+	if itemName == "" {
+		return Item{}, ErrNoItemName
+	}
+	if cost <= 0 {
+		return Item{}, ErrInvalidCost
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.load(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+	if n := t.find(itemName); n >= 0 {
+		itemName = t.items[n].Name
+	}
+	item := Item{Name: itemName, Cost: cost}
+	data, _ := json.Marshal(event.ItemCostData{ItemName: item.Name, Cost: item.Cost})
+	if err := s.append(ctx, t, event.ItemCostSet, data); err != nil {
+		return Item{}, err
+	}
+	s.logger.InfoContext(ctx, "item cost set",
+		slog.String("item", item.Name),
+		slog.Int("cost", item.Cost),
+	)
+	return item, nil
+}
+
+// Remove removes the item itemName from the table and returns it.
+func (s *Service) Remove(ctx context.Context, itemName string) (Item, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Remove",
+		trace.WithAttributes(attribute.String("item", itemName)),
+	)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.load(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+	n := t.find(itemName)
+	if n < 0 {
+		return Item{}, ErrUnknownItem.Wrap(fmt.Errorf("item %q", itemName))
+	}
+	item := t.items[n]
+	data, _ := json.Marshal(event.ItemCostData{ItemName: item.Name})
+	if err := s.append(ctx, t, event.ItemCostRemoved, data); err != nil {
+		return Item{}, err
+	}
+	s.logger.InfoContext(ctx, "item cost removed", slog.String("item", item.Name))
+	return item, nil
+}
+
+// List returns the items of the table by name.
+func (s *Service) List(ctx context.Context) ([]Item, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.List")
+	defer span.End()
+
+	t, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := slices.Clone(t.items)
+	slices.SortFunc(items, func(a, b Item) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	return items, nil
+}
+
+// Cost returns the item itemName, matched regardless of case, with its
+// cost.
+func (s *Service) Cost(ctx context.Context, itemName string) (Item, error) {
+	t, err := s.load(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+	n := t.find(itemName)
+	if n < 0 {
+		return Item{}, ErrUnknownItem.Wrap(fmt.Errorf("item %q", itemName))
+	}
+	return t.items[n], nil
+}
+
+// Award awards the item itemName to the player of the member discordID,
+// deducting its cost from their DKP. It returns the player, with their
+// balance before the deduction, and the item.
+func (s *Service) Award(ctx context.Context, discordID, itemName string) (*store.Player, Item, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Award",
+		trace.WithAttributes(
+			attribute.String("discord_id", discordID),
+			attribute.String("item", itemName),
+		),
+	)
+	defer span.End()
+
+	p, err := s.dkp.GetPlayer(ctx, discordID)
+	if err != nil {
+		return nil, Item{}, fmt.Errorf("looking up player: %w", err)
+	}
+	if p.Archived() {
+		return nil, Item{}, ErrArchivedPlayer
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.load(ctx)
+	if err != nil {
+		return nil, Item{}, err
+	}
+	n := t.find(itemName)
+	if n < 0 {
+		return nil, Item{}, ErrUnknownItem.Wrap(fmt.Errorf("item %q", itemName))
+	}
+	item := t.items[n]
+	if p.DKP < item.Cost {
+		return nil, Item{}, ErrInsufficientDKP.Wrap(fmt.Errorf("%d DKP for an item costing %d", p.DKP, item.Cost))
+	}
+	if err := s.dkp.DeductDKP(ctx, p.ID, item.Cost, "Loot: "+item.Name); err != nil {
+		return nil, Item{}, fmt.Errorf("charging item: %w", err)
+	}
+	data, _ := json.Marshal(event.ItemCostData{ItemName: item.Name, Cost: item.Cost, PlayerID: p.ID})
+	if err := s.append(ctx, t, event.ItemCostAwarded, data); err != nil {
+		// The award was not recorded, so its cost is refunded.
+		if rerr := s.dkp.AwardDKP(ctx, p.ID, item.Cost, "Loot refunded: "+item.Name); rerr != nil {
+			s.logger.ErrorContext(ctx, "refunding item cost failed",
+				slog.String("player_id", p.ID),
+				slog.String("item", item.Name),
+				slog.Int("cost", item.Cost),
+				slog.Any("error", rerr),
+			)
+		}
+		return nil, Item{}, err
+	}
+	s.logger.InfoContext(ctx, "item awarded at fixed cost",
+		slog.String("player_id", p.ID),
+		slog.String("item", item.Name),
+		slog.Int("cost", item.Cost),
+	)
+	return p, item, nil
+}
+
+// table is the item cost table as recorded in its events.
+type table struct {
+	items   []Item
+	version int
+}
+
+// find returns the index of the item itemName, matched regardless of case,
+// or -1.
+func (t *table) find(itemName string) int {
+	itemName = strings.TrimSpace(itemName)
+	return slices.IndexFunc(t.items, func(i Item) bool { return strings.EqualFold(i.Name, itemName) })
+}
+
+// load replays the table's events.
+func (s *Service) load(ctx context.Context) (*table, error) {
+	events, err := s.events.Load(ctx, event.ItemCostsAggregateID)
+	if err != nil {
+		return nil, fmt.Errorf("loading item cost events: %w", err)
+	}
+	t := &table{}
+	for _, e := range events {
+		var d event.ItemCostData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+		}
+		n := t.find(d.ItemName)
+		switch {
+		case e.Type == event.ItemCostSet && n >= 0:
+			t.items[n].Cost = d.Cost
+		case e.Type == event.ItemCostSet:
+			t.items = append(t.items, Item{Name: d.ItemName, Cost: d.Cost})
+		case e.Type == event.ItemCostRemoved && n >= 0:
+			t.items = slices.Delete(t.items, n, n+1)
+		}
+		t.version = e.Version
+	}
+	return t, nil
+}
+
+// append records an event of type typ on the table.
+func (s *Service) append(ctx context.Context, t *table, typ event.Type, data json.RawMessage) error {
+	e := event.Event{
+		AggregateID: event.ItemCostsAggregateID,
+		Type:        typ,
+		Data:        data,
+		Version:     t.version + 1,
+	}
+	if err := s.events.Append(ctx, e); err != nil {
+		return fmt.Errorf("recording %s event: %w", typ, err)
+	}
+	t.version = e.Version
+	return nil
+}
@@ -0,0 +1,92 @@
+package itemcost_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/itemcost"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// ledger knows the players of members d1 and d2 and their DKP.
+type ledger struct {
+	dkp     map[string]int
+	reasons []string
+}
+
+func (l *ledger) GetPlayer(_ context.Context, discordID string) (*store.Player, error) {
+	id := "p" + discordID[1:]
+	if _, ok := l.dkp[id]; !ok {
+		return nil, store.ErrPlayerNotFound
+	}
+	return &store.Player{ID: id, DiscordID: discordID, DKP: l.dkp[id]}, nil
+}
+
+func (l *ledger) AwardDKP(_ context.Context, playerID string, amount int, _ string) error {
+	l.dkp[playerID] += amount
+	return nil
+}
+
+func (l *ledger) DeductDKP(_ context.Context, playerID string, amount int, reason string) error {
+	l.dkp[playerID] -= amount
+	l.reasons = append(l.reasons, reason)
+	return nil
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	dkp := &ledger{dkp: map[string]int{"p1": 100, "p2": 10}}
+	s := itemcost.NewService(eventtest.NewStore(), dkp, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+	if _, err := s.Set(ctx, " ", 10); !errors.Is(err, itemcost.ErrNoItemName) {
+		t.Errorf("Set() with no name error = %v, want ErrNoItemName", err)
+	}
+	if _, err := s.Set(ctx, "Onyxia Scale Cloak", 0); !errors.Is(err, itemcost.ErrInvalidCost) {
+		t.Errorf("Set() with no cost error = %v, want ErrInvalidCost", err)
+	}
+	for _, item := range []itemcost.Item{{"Onyxia Scale Cloak", 30}, {"Ashkandi", 80}, {"onyxia scale cloak", 40}} {
+		if _, err := s.Set(ctx, item.Name, item.Cost); err != nil {
+			t.Fatalf("Set(%s) error = %v", item.Name, err)
+		}
+	}
+	items, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	want := []itemcost.Item{{"Ashkandi", 80}, {"Onyxia Scale Cloak", 40}}
+	if len(items) != len(want) || items[0] != want[0] || items[1] != want[1] {
+		t.Errorf("List() = %v, want %v", items, want)
+	}
+
+	p, item, err := s.Award(ctx, "d1", "ONYXIA SCALE CLOAK")
+	if err != nil {
+		t.Fatalf("Award() error = %v", err)
+	}
+	if p.ID != "p1" || item.Cost != 40 || dkp.dkp["p1"] != 60 || dkp.reasons[0] != "Loot: Onyxia Scale Cloak" {
+		t.Errorf("Award() = %s, %v; DKP %v, reasons %q; want 40 deducted from p1", p.ID, item, dkp.dkp, dkp.reasons)
+	}
+	if _, _, err := s.Award(ctx, "d2", "Ashkandi"); !errors.Is(err, itemcost.ErrInsufficientDKP) {
+		t.Errorf("Award() beyond the balance error = %v, want ErrInsufficientDKP", err)
+	}
+	if _, _, err := s.Award(ctx, "d9", "Ashkandi"); !errors.Is(err, store.ErrPlayerNotFound) {
+		t.Errorf("Award() to an unregistered member error = %v, want ErrPlayerNotFound", err)
+	}
+	if _, _, err := s.Award(ctx, "d1", "Thunderfury"); !errors.Is(err, itemcost.ErrUnknownItem) {
+		t.Errorf("Award() of an item without a cost error = %v, want ErrUnknownItem", err)
+	}
+
+	if _, err := s.Remove(ctx, "ashkandi"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := s.Remove(ctx, "Ashkandi"); !errors.Is(err, itemcost.ErrUnknownItem) {
+		t.Errorf("second Remove() error = %v, want ErrUnknownItem", err)
+	}
+	if _, err := s.Cost(ctx, "Ashkandi"); !errors.Is(err, itemcost.ErrUnknownItem) {
+		t.Errorf("Cost() after Remove() error = %v, want ErrUnknownItem", err)
+	}
+}
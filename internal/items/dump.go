@@ -0,0 +1,221 @@
+package items
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// ErrInvalidDump is returned by Parse for input that is not a usable item
+// dump.
+var ErrInvalidDump = derrors.New(derrors.Validation, "INVALID_ITEM_DUMP", "not a valid item dump")
+
+// Dump formats accepted by Parse.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// dumpItem is an item as found in a dump. ID and Quality may be numbers or
+// strings, Icon an icon name or a URL, and Stats a list or a string of
+// stats separated by semicolons.
+type dumpItem struct {
+	ID      flexString `json:"id"`
+	Name    string     `json:"name"`
+	Quality flexString `json:"quality"`
+	Icon    string     `json:"icon"`
+	Slot    string     `json:"slot"`
+	Stats   flexList   `json:"stats"`
+}
+
+// flexString accepts a JSON string or number.
+type flexString string
+
+func (f *flexString) UnmarshalJSON(b []byte) error {
+	if bytes.HasPrefix(b, []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*f = flexString(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*f = flexString(n)
+	return nil
+}
+
+// flexList accepts a JSON array of strings or a string of items separated
+// by semicolons.
+type flexList []string
+
+func (f *flexList) UnmarshalJSON(b []byte) error {
+	if bytes.HasPrefix(b, []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*f = splitList(s)
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(b, &l); err != nil {
+		return err
+	}
+	*f = l
+	return nil
+}
+
+// splitList splits s at semicolons.
+func splitList(s string) flexList {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	return strings.Split(s, ";")
+}
+
+// Parse reads an item dump in format: a JSON array of objects, or CSV with
+// a header row, both with the fields id, name, quality, and icon, and
+// optionally slot and stats, the tooltip shown in auction embeds. Quality
+// is a name such as "epic" or a number from 0 (poor) to 7 (heirloom).
+// Icons that are not URLs are turned into URLs with iconURL, whose
+// "{icon}" is replaced by the lowercased icon name; without iconURL they
+// are dropped.
+func Parse(r io.Reader, format, iconURL string) ([]store.Item, error) {
+	var dump []dumpItem
+	switch format {
+	case FormatJSON:
+		if err := json.NewDecoder(r).Decode(&dump); err != nil {
+			return nil, ErrInvalidDump.Wrap(fmt.Errorf("decoding JSON: %w", err))
+		}
+	case FormatCSV:
+		var err error
+		if dump, err = parseCSV(r); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, derrors.New(derrors.Validation, "UNKNOWN_FORMAT", fmt.Sprintf("unknown item dump format %q, want csv or json", format))
+	}
+
+	items := make([]store.Item, 0, len(dump))
+	for n, d := range dump {
+		item := store.Item{
+			ID:   strings.TrimSpace(string(d.ID)),
+			Name: strings.TrimSpace(d.Name),
+		}
+		if item.ID == "" || item.Name == "" {
+			return nil, ErrInvalidDump.Wrap(fmt.Errorf("item %d has no id or name", n+1))
+		}
+		q, err := ParseQuality(string(d.Quality))
+		if err != nil {
+			return nil, ErrInvalidDump.Wrap(fmt.Errorf("item %s: %w", item.ID, err))
+		}
+		item.Quality = q
+		item.IconURL = iconFor(strings.TrimSpace(d.Icon), iconURL)
+		item.Slot = strings.TrimSpace(d.Slot)
+		item.Stats = statLines(d.Stats)
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func parseCSV(r io.Reader) ([]dumpItem, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, ErrInvalidDump.Wrap(fmt.Errorf("reading CSV header: %w", err))
+	}
+	col := make(map[string]int, len(header))
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := col["id"]; !ok {
+		return nil, ErrInvalidDump.Wrap(errors.New("CSV header has no id column"))
+	}
+	if _, ok := col["name"]; !ok {
+		return nil, ErrInvalidDump.Wrap(errors.New("CSV header has no name column"))
+	}
+	field := func(rec []string, name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return rec[i]
+		}
+		return ""
+	}
+
+	var dump []dumpItem
+	cr.FieldsPerRecord = -1
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return dump, nil
+		}
+		if err != nil {
+			return nil, ErrInvalidDump.Wrap(fmt.Errorf("reading CSV: %w", err))
+		}
+		dump = append(dump, dumpItem{
+			ID:      flexString(field(rec, "id")),
+			Name:    field(rec, "name"),
+			Quality: flexString(field(rec, "quality")),
+			Icon:    field(rec, "icon"),
+			Slot:    field(rec, "slot"),
+			Stats:   splitList(field(rec, "stats")),
+		})
+	}
+}
+
+// statLines joins the non-empty stats, one per line.
+func statLines(stats []string) string {
+	lines := make([]string, 0, len(stats))
+	for _, st := range stats {
+		if st = strings.TrimSpace(st); st != "" {
+			lines = append(lines, st)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// iconFor returns the URL of icon, which may already be one.
+func iconFor(icon, iconURL string) string {
+	switch {
+	case icon == "":
+		return ""
+	case strings.HasPrefix(icon, "https://") || strings.HasPrefix(icon, "http://"):
+		return icon
+	case iconURL == "":
+		return ""
+	}
+	return strings.ReplaceAll(iconURL, "{icon}", strings.ToLower(icon))
+}
+
+// ParseQuality normalizes a quality name or number to its name. An empty
+// quality stays empty.
+func ParseQuality(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return "", nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 || n >= len(qualities) {
+			return "", fmt.Errorf("quality %d out of range 0-%d", n, len(qualities)-1)
+		}
+		return qualities[n].name, nil
+	}
+	for _, q := range qualities {
+		if q.name == s {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown quality %q", s)
+}
@@ -0,0 +1,97 @@
+// Package items maintains the item catalog: the names, qualities, and icons
+// of the game's items, imported from data dumps. It backs /auction-start
+// autocomplete and the icons and colors of auction embeds.
+package items
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// qualities lists item qualities by their number in game data, with the
+// color the game shows their names in.
+var qualities = []struct {
+	name  string
+	color int
+}{
+	{"poor", 0x9d9d9d},
+	{"common", 0xffffff},
+	{"uncommon", 0x1eff00},
+	{"rare", 0x0070dd},
+	{"epic", 0xa335ee},
+	{"legendary", 0xff8000},
+	{"artifact", 0xe6cc80},
+	{"heirloom", 0x00ccff},
+}
+
+// Color returns the embed color of quality, or zero, Discord's default, for
+// an empty or unknown quality.
+func Color(quality string) int {
+	for _, q := range qualities {
+		if q.name == quality {
+			return q.color
+		}
+	}
+	return 0
+}
+
+// Catalog looks up and imports items.
+type Catalog struct {
+	repo   store.ItemRepository
+	logger *slog.Logger
+	tracer trace.Tracer
+}
+
+// NewCatalog returns a Catalog backed by repo.
+func NewCatalog(repo store.ItemRepository, logger *slog.Logger, tp trace.TracerProvider) *Catalog {
+	return &Catalog{
+		repo:   repo,
+		logger: logger,
+		tracer: tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/items"),
+	}
+}
+
+// Search returns up to limit items whose name contains query, best matches
+// first.
+func (c *Catalog) Search(ctx context.Context, query string, limit int) ([]store.Item, error) {
+	ctx, span := c.tracer.Start(ctx, "Catalog.Search",
+		trace.WithAttributes(attribute.String("query", query)),
+	)
+	defer span.End()
+
+	return c.repo.Search(ctx, query, limit)
+}
+
+// Lookup returns the item named name, ignoring case, or nil if the catalog
+// has none.
+func (c *Catalog) Lookup(ctx context.Context, name string) (*store.Item, error) {
+	ctx, span := c.tracer.Start(ctx, "Catalog.Lookup")
+	defer span.End()
+
+	item, err := c.repo.GetByName(ctx, name)
+	if errors.Is(err, store.ErrItemNotFound) {
+		return nil, nil
+	}
+	return item, err
+}
+
+// Import adds items to the catalog, replacing those with the same IDs.
+func (c *Catalog) Import(ctx context.Context, items []store.Item) error {
+	ctx, span := c.tracer.Start(ctx, "Catalog.Import",
+		trace.WithAttributes(attribute.Int("items", len(items))),
+	)
+	defer span.End()
+
+	if err := c.repo.Upsert(ctx, items); err != nil {
+		return fmt.Errorf("importing items: %w", err)
+	}
+	c.logger.InfoContext(ctx, "item catalog imported", slog.Int("items", len(items)))
+	return nil
+}
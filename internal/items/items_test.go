@@ -0,0 +1,129 @@
+package items_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/items"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+const iconURL = "https://wow.zamimg.com/images/wow/icons/large/{icon}.jpg"
+
+func TestParse(t *testing.T) {
+	want := []store.Item{
+		{ID: "19019", Name: "Thunderfury, Blessed Blade of the Windseeker", Quality: "legendary", IconURL: "https://wow.zamimg.com/images/wow/icons/large/inv_sword_39.jpg", Slot: "One-Hand", Stats: "+5 Agility\n+8 Stamina"},
+		{ID: "18814", Name: "Choker of the Fire Lord", Quality: "epic", IconURL: "https://example.com/choker.png", Slot: "Neck"},
+		{ID: "2589", Name: "Linen Cloth"},
+	}
+
+	tests := []struct {
+		name, format, input string
+	}{
+		{
+			name:   "json",
+			format: items.FormatJSON,
+			input: `[
+				{"id": 19019, "name": "Thunderfury, Blessed Blade of the Windseeker", "quality": 5, "icon": "INV_Sword_39", "slot": "One-Hand", "stats": ["+5 Agility", "+8 Stamina"]},
+				{"id": "18814", "name": "Choker of the Fire Lord", "quality": "Epic", "icon": "https://example.com/choker.png", "slot": "Neck", "stats": ""},
+				{"id": 2589, "name": "Linen Cloth"}
+			]`,
+		},
+		{
+			name:   "csv",
+			format: items.FormatCSV,
+			input: "ID,Name,Quality,Icon,Slot,Stats\n" +
+				"19019,\"Thunderfury, Blessed Blade of the Windseeker\",5,INV_Sword_39,One-Hand,+5 Agility; +8 Stamina\n" +
+				"18814,Choker of the Fire Lord,epic,https://example.com/choker.png,Neck\n" +
+				"2589,Linen Cloth,,\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := items.Parse(strings.NewReader(tt.input), tt.format, iconURL)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("Parse() = %+v, want %+v", got, want)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("item %d = %+v, want %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name, format, input, wantCode string
+	}{
+		{name: "malformed json", format: items.FormatJSON, input: `{"id": 1}`, wantCode: "INVALID_ITEM_DUMP"},
+		{name: "missing name", format: items.FormatJSON, input: `[{"id": 1}]`, wantCode: "INVALID_ITEM_DUMP"},
+		{name: "unknown quality", format: items.FormatJSON, input: `[{"id": 1, "name": "Rock", "quality": "shiny"}]`, wantCode: "INVALID_ITEM_DUMP"},
+		{name: "quality out of range", format: items.FormatCSV, input: "id,name,quality\n1,Rock,9\n", wantCode: "INVALID_ITEM_DUMP"},
+		{name: "csv without name column", format: items.FormatCSV, input: "id,title\n1,Rock\n", wantCode: "INVALID_ITEM_DUMP"},
+		{name: "unknown format", format: "xml", input: "<items/>", wantCode: "UNKNOWN_FORMAT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := items.Parse(strings.NewReader(tt.input), tt.format, "")
+			if err == nil || derrors.CodeOf(err) != tt.wantCode {
+				t.Errorf("Parse() error = %v, want code %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestParse_IconNamesWithoutTemplate(t *testing.T) {
+	got, err := items.Parse(strings.NewReader(`[{"id": 1, "name": "Rock", "icon": "inv_stone_01"}]`), items.FormatJSON, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].IconURL != "" {
+		t.Errorf("IconURL = %q, want none without an icon URL template", got[0].IconURL)
+	}
+}
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		quality string
+		want    int
+	}{
+		{"epic", 0xa335ee},
+		{"legendary", 0xff8000},
+		{"", 0},
+		{"shiny", 0},
+	}
+	for _, tt := range tests {
+		if got := items.Color(tt.quality); got != tt.want {
+			t.Errorf("Color(%q) = %#x, want %#x", tt.quality, got, tt.want)
+		}
+	}
+}
+
+func TestLinkResolver(t *testing.T) {
+	item := store.Item{ID: "19019", Name: "Thunderfury, Blessed Blade of the Windseeker", Slot: "One-Hand", Stats: "+5 Agility\n+8 Stamina"}
+
+	got, err := items.NewLinkResolver("https://www.wowhead.com/classic/item={id}").Resolve(context.Background(), item)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	want := items.Tooltip{URL: "https://www.wowhead.com/classic/item=19019", Slot: "One-Hand", Stats: []string{"+5 Agility", "+8 Stamina"}}
+	if got.URL != want.URL || got.Slot != want.Slot || !slices.Equal(got.Stats, want.Stats) {
+		t.Errorf("Resolve() = %+v, want %+v", got, want)
+	}
+
+	got, _ = items.NewLinkResolver("https://everquest.allakhazam.com/search.html?q={name}").Resolve(context.Background(), item)
+	if want := "https://everquest.allakhazam.com/search.html?q=Thunderfury%2C+Blessed+Blade+of+the+Windseeker"; got.URL != want {
+		t.Errorf("Resolve() URL = %q, want %q", got.URL, want)
+	}
+	if got, _ := items.NewLinkResolver("").Resolve(context.Background(), store.Item{ID: "1"}); got.URL != "" || got.Stats != nil {
+		t.Errorf("Resolve() without a template = %+v, want no link or stats", got)
+	}
+}
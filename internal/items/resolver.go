@@ -0,0 +1,54 @@
+package items
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// ItemResolver resolves catalog items to what a game database shows for
+// them. Each game can provide its own, for example one querying the
+// database's tooltip API.
+type ItemResolver interface {
+	Resolve(ctx context.Context, item store.Item) (Tooltip, error)
+}
+
+// Tooltip is what a game database shows for an item.
+type Tooltip struct {
+	// URL is the item's page in the database, or empty if unknown.
+	URL string
+	// Slot is where the item is equipped, or empty.
+	Slot string
+	// Stats are the item's stats, such as "+20 Stamina".
+	Stats []string
+}
+
+// LinkResolver links items to a database such as Wowhead or Allakhazam by
+// a URL template, and takes their tooltips from the catalog.
+type LinkResolver struct {
+	urlTemplate string
+}
+
+// NewLinkResolver returns a LinkResolver whose item URLs are urlTemplate
+// with "{id}" replaced by the item's ID and "{name}" by its name. With an
+// empty urlTemplate, items are not linked.
+func NewLinkResolver(urlTemplate string) *LinkResolver {
+	return &LinkResolver{urlTemplate: urlTemplate}
+}
+
+// Resolve returns the tooltip of item.
+func (r *LinkResolver) Resolve(_ context.Context, item store.Item) (Tooltip, error) {
+	t := Tooltip{Slot: item.Slot}
+	if item.Stats != "" {
+		t.Stats = strings.Split(item.Stats, "\n")
+	}
+	if r.urlTemplate != "" {
+		t.URL = strings.NewReplacer(
+			"{id}", url.PathEscape(item.ID),
+			"{name}", url.QueryEscape(item.Name),
+		).Replace(r.urlTemplate)
+	}
+	return t, nil
+}
@@ -0,0 +1,270 @@
+// Package jobs runs commands that can outlast a Discord interaction, such
+// as imports and exports, in the background. Discord accepts responses to
+// an interaction for 15 minutes; a job instead posts a message of its own,
+// keeps it up to date with its progress, and reports its outcome in it,
+// however long it takes.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/ids"
+)
+
+// maxMessageLength is the longest message Discord accepts.
+const maxMessageLength = 2000
+
+// reportTimeout bounds reporting the outcome of a job, which is done even
+// after its context is done.
+const reportTimeout = 30 * time.Second
+
+// interruptGrace is how long jobs canceled by Shutdown are given to report
+// that they were interrupted.
+const interruptGrace = 5 * time.Second
+
+// ErrShuttingDown is returned by Start once Shutdown was called.
+var ErrShuttingDown = derrors.New(derrors.Conflict, "JOBS_SHUTTING_DOWN", "the bot is restarting; try again in a minute")
+
+// Reporter posts and updates the message of a job.
+type Reporter interface {
+	// Post sends content and returns the ID of the message and a link to
+	// it.
+	Post(ctx context.Context, content string) (messageID, link string, err error)
+	// Edit replaces the content of the message messageID, and attaches f
+	// to it unless f is nil.
+	Edit(ctx context.Context, messageID, content string, f *File) error
+}
+
+// File is a file attached to the outcome of a job, such as an export.
+type File struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Result is the outcome of a job.
+type Result struct {
+	// Message describes the outcome, or why the job failed.
+	Message string
+	File    *File
+}
+
+// Task is the work of a job. It calls progress to report how far it got;
+// the job's message shows the latest report.
+type Task func(ctx context.Context, progress func(msg string)) (Result, error)
+
+// Job describes a job started by Runner.Start.
+type Job struct {
+	ID        string
+	Name      string
+	StartedBy string
+	StartedAt time.Time
+	// Link is the URL of the job's message.
+	Link string
+}
+
+// Runner runs jobs. It is safe for concurrent use.
+type Runner struct {
+	cfg    config.JobsConfig
+	ids    ids.Generator
+	clock  clock.Clock
+	logger *slog.Logger
+	tracer trace.Tracer
+
+	// ctx is canceled by Shutdown once its deadline passes, to interrupt
+	// the jobs still running.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mu guards closed, which Shutdown sets, so that no job is added to
+	// running once Shutdown waits for it.
+	mu      sync.Mutex
+	closed  bool
+	running sync.WaitGroup
+}
+
+// NewRunner returns a Runner of jobs with the timeout and progress
+// interval of cfg, identified by IDs from gen.
+func NewRunner(cfg config.JobsConfig, gen ids.Generator, clk clock.Clock, logger *slog.Logger, tp trace.TracerProvider) *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{
+		cfg:    cfg,
+		ids:    gen,
+		clock:  clk,
+		logger: logger,
+		tracer: tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/jobs"),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start posts the message of a job named name with rep and runs task in
+// the background, reporting its progress and outcome in that message. The
+// task's context carries the values of ctx, such as the actor of events,
+// but not its deadline; it is done when the job times out or is
+// interrupted by Shutdown. startedBy is the Discord ID of the member who
+// started the job.
+func (r *Runner) Start(ctx context.Context, name, startedBy string, rep Reporter, task Task) (Job, error) {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return Job{}, ErrShuttingDown
+	}
+	r.running.Add(1)
+	r.mu.Unlock()
+
+	job := Job{
+		ID:        "job-" + r.ids.NewID(),
+		Name:      name,
+		StartedBy: startedBy,
+		StartedAt: r.clock.Now(),
+	}
+	messageID, link, err := rep.Post(ctx, fmt.Sprintf("⏳ **%s** started by <@%s> (job `%s`)…", name, startedBy, job.ID))
+	if err != nil {
+		r.running.Done()
+		return Job{}, fmt.Errorf("posting message of job %s: %w", job.ID, err)
+	}
+	job.Link = link
+
+	go r.run(ctx, job, messageID, rep, task)
+	return job, nil
+}
+
+func (r *Runner) run(parent context.Context, job Job, messageID string, rep Reporter, task Task) {
+	defer r.running.Done()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(parent), r.cfg.Timeout)
+	defer cancel()
+	stop := context.AfterFunc(r.ctx, cancel)
+	defer stop()
+	ctx, span := r.tracer.Start(ctx, "Runner.run",
+		trace.WithAttributes(
+			attribute.String("job_id", job.ID),
+			attribute.String("job", job.Name),
+		),
+	)
+	defer span.End()
+
+	header := fmt.Sprintf("⏳ **%s** started by <@%s> (job `%s`)", job.Name, job.StartedBy, job.ID)
+	var (
+		mu       sync.Mutex
+		last     time.Time
+		finished bool
+	)
+	progress := func(msg string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if finished {
+			return
+		}
+		now := r.clock.Now()
+		if !last.IsZero() && now.Sub(last) < r.cfg.ProgressInterval {
+			return
+		}
+		last = now
+		if err := rep.Edit(ctx, messageID, truncate(header+"\n"+msg), nil); err != nil {
+			r.logger.WarnContext(ctx, "updating job progress failed",
+				slog.String("job_id", job.ID),
+				slog.Any("error", err),
+			)
+		}
+	}
+
+	res, err := task(ctx, progress)
+	// Later progress reports, from goroutines of the task, must not
+	// overwrite the outcome.
+	mu.Lock()
+	finished = true
+	mu.Unlock()
+
+	elapsed := r.clock.Now().Sub(job.StartedAt).Round(time.Second)
+	var content string
+	switch {
+	case err == nil:
+		content = fmt.Sprintf("✅ **%s** started by <@%s> finished in %s (job `%s`)", job.Name, job.StartedBy, elapsed, job.ID)
+	case r.ctx.Err() != nil:
+		content = fmt.Sprintf("⚠️ **%s** started by <@%s> was interrupted by a restart after %s (job `%s`). What it did so far is kept; run it again to finish.", job.Name, job.StartedBy, elapsed, job.ID)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		content = fmt.Sprintf("⚠️ **%s** started by <@%s> timed out after %s (job `%s`). What it did so far is kept.", job.Name, job.StartedBy, elapsed, job.ID)
+	default:
+		content = fmt.Sprintf("❌ **%s** started by <@%s> failed after %s (job `%s`)", job.Name, job.StartedBy, elapsed, job.ID)
+	}
+	if res.Message != "" {
+		content += "\n" + res.Message
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		r.logger.ErrorContext(ctx, "job failed",
+			slog.String("job_id", job.ID),
+			slog.String("job", job.Name),
+			slog.Duration("elapsed", elapsed),
+			slog.Any("error", err),
+		)
+	} else {
+		r.logger.InfoContext(ctx, "job finished",
+			slog.String("job_id", job.ID),
+			slog.String("job", job.Name),
+			slog.Duration("elapsed", elapsed),
+		)
+	}
+
+	rctx, rcancel := context.WithTimeout(context.WithoutCancel(ctx), reportTimeout)
+	defer rcancel()
+	if err := rep.Edit(rctx, messageID, truncate(content), res.File); err != nil {
+		r.logger.ErrorContext(ctx, "reporting job outcome failed",
+			slog.String("job_id", job.ID),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// Shutdown stops new jobs from starting and waits until the running ones
+// finish or ctx is done. Jobs still running then are interrupted, and given
+// a few seconds to say so in their messages.
+func (r *Runner) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+	r.cancel()
+	select {
+	case <-done:
+	case <-time.After(interruptGrace):
+	}
+	return ctx.Err()
+}
+
+// truncate shortens s to the length of a message.
+func truncate(s string) string {
+	if len(s) <= maxMessageLength {
+		return s
+	}
+	cut := maxMessageLength - len("…")
+	// Cut at a rune boundary.
+	for cut > 0 && s[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return s[:cut] + "…"
+}
@@ -0,0 +1,160 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/ids"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/jobs"
+)
+
+// reporter records the messages of a job.
+type reporter struct {
+	mu      sync.Mutex
+	content []string
+	file    *jobs.File
+	edited  chan struct{}
+}
+
+func newReporter() *reporter {
+	return &reporter{edited: make(chan struct{}, 16)}
+}
+
+func (r *reporter) Post(_ context.Context, content string) (string, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.content = append(r.content, content)
+	return "m1", "https://discord.com/channels/g1/c1/m1", nil
+}
+
+func (r *reporter) Edit(_ context.Context, messageID, content string, f *jobs.File) error {
+	r.mu.Lock()
+	r.content = append(r.content, content)
+	if f != nil {
+		r.file = f
+	}
+	r.mu.Unlock()
+	r.edited <- struct{}{}
+	return nil
+}
+
+func (r *reporter) messages() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.content...)
+}
+
+func newRunner(timeout time.Duration) *jobs.Runner {
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	cfg := config.JobsConfig{Timeout: timeout, ProgressInterval: time.Second, ReportTo: config.JobsReportChannel}
+	return jobs.NewRunner(cfg, ids.NewULID(clk), clk, slog.Default(), noop.NewTracerProvider())
+}
+
+func TestRunner_Start(t *testing.T) {
+	r := newRunner(time.Hour)
+	rep := newReporter()
+	ctx, cancel := context.WithCancel(event.WithActor(context.Background(), "officer"))
+
+	var actor string
+	job, err := r.Start(ctx, "EQDKP import", "officer", rep, func(ctx context.Context, progress func(string)) (jobs.Result, error) {
+		actor = event.ActorFromContext(ctx)
+		progress("Importing 10 characters…")
+		// The clock stands still, so this report is skipped.
+		progress("Importing 20 characters…")
+		return jobs.Result{Message: "10 characters imported", File: &jobs.File{Name: "report.csv"}}, ctx.Err()
+	})
+	// The interaction's deadline does not end the job.
+	cancel()
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if !strings.HasPrefix(job.ID, "job-") || job.Link == "" {
+		t.Errorf("job = %+v, want an ID and a link", job)
+	}
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	got := rep.messages()
+	if len(got) != 3 {
+		t.Fatalf("messages = %q, want posted, one progress report, and the outcome", got)
+	}
+	if !strings.Contains(got[1], "Importing 10 characters") {
+		t.Errorf("progress = %q, want the first report", got[1])
+	}
+	if !strings.Contains(got[2], "✅ **EQDKP import** started by <@officer> finished") || !strings.Contains(got[2], "10 characters imported") {
+		t.Errorf("outcome = %q, want it finished with the result", got[2])
+	}
+	if rep.file == nil || rep.file.Name != "report.csv" {
+		t.Errorf("file = %+v, want report.csv attached", rep.file)
+	}
+	if actor != "officer" {
+		t.Errorf("actor in job = %q, want officer", actor)
+	}
+}
+
+func TestRunner_Failed(t *testing.T) {
+	r := newRunner(time.Hour)
+	rep := newReporter()
+	_, err := r.Start(context.Background(), "Export", "officer", rep, func(context.Context, func(string)) (jobs.Result, error) {
+		return jobs.Result{Message: "Error exporting standings: no players"}, errors.New("no players")
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	<-rep.edited
+	if got := rep.messages(); !strings.Contains(got[len(got)-1], "❌ **Export**") || !strings.Contains(got[len(got)-1], "no players") {
+		t.Errorf("outcome = %q, want it failed with the reason", got[len(got)-1])
+	}
+}
+
+func TestRunner_TimedOut(t *testing.T) {
+	r := newRunner(time.Millisecond)
+	rep := newReporter()
+	_, err := r.Start(context.Background(), "Role sync", "officer", rep, func(ctx context.Context, _ func(string)) (jobs.Result, error) {
+		<-ctx.Done()
+		return jobs.Result{}, ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	<-rep.edited
+	if got := rep.messages(); !strings.Contains(got[len(got)-1], "timed out") {
+		t.Errorf("outcome = %q, want it timed out", got[len(got)-1])
+	}
+}
+
+func TestRunner_Shutdown(t *testing.T) {
+	r := newRunner(time.Hour)
+	rep := newReporter()
+	_, err := r.Start(context.Background(), "WCL import", "officer", rep, func(ctx context.Context, _ func(string)) (jobs.Result, error) {
+		<-ctx.Done()
+		return jobs.Result{}, ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := r.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want the deadline exceeded", err)
+	}
+	if got := rep.messages(); !strings.Contains(got[len(got)-1], "interrupted by a restart") {
+		t.Errorf("outcome = %q, want it interrupted", got[len(got)-1])
+	}
+
+	if _, err := r.Start(context.Background(), "Export", "officer", rep, nil); !errors.Is(err, jobs.ErrShuttingDown) {
+		t.Errorf("Start() after Shutdown error = %v, want ErrShuttingDown", err)
+	}
+}
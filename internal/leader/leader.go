@@ -1,5 +1,6 @@
-// Package leader provides Kubernetes Lease-based leader election
-// so that only one replica of the bot actively processes commands.
+// Package leader provides leader election so that only one replica of the
+// bot actively processes commands. The lock is a Kubernetes Lease or, for
+// deployments outside Kubernetes, a Redis key.
 package leader
 
 import (
@@ -8,6 +9,7 @@ import (
 	"log/slog"
 	"os"
 
+	"github.com/redis/go-redis/v9"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -44,12 +46,63 @@ var ClientFactory = func() (kubernetes.Interface, error) {
 	return client, nil
 }
 
-// Run starts leader election. The onStartedLeading callback is invoked when
-// this instance becomes the leader; it should block until ctx is done.
-// The onStoppedLeading callback runs when leadership is lost.
-// Run itself blocks until the election loop exits.
-func Run(ctx context.Context, cfg config.LeaderElectionConfig, logger *slog.Logger, onStartedLeading func(ctx context.Context), onStoppedLeading func()) error {
-	id := identity()
+// Elector runs a leader election. Run invokes onStartedLeading once this
+// instance holds the lock; it should block until its ctx is done, which
+// happens when leadership is lost or the parent ctx is canceled. Run returns
+// once leadership is lost or ctx is canceled, calling onStoppedLeading on the
+// way out; it does not campaign again, so callers should exit.
+type Elector interface {
+	Run(ctx context.Context, onStartedLeading func(ctx context.Context), onStoppedLeading func()) error
+}
+
+// New returns the Elector for the configured backend. It reports its state
+// on status.
+func New(cfg config.LeaderElectionConfig, status *Status, logger *slog.Logger) (Elector, error) {
+	switch cfg.Backend {
+	case config.LeaderBackendRedis:
+		nodes := make([]RedisNode, len(cfg.Redis.Addresses))
+		for i, addr := range cfg.Redis.Addresses {
+			nodes[i] = redis.NewClient(&redis.Options{
+				Addr:     addr,
+				Password: cfg.Redis.Password,
+				DB:       cfg.Redis.DB,
+			})
+		}
+		return NewRedis(cfg, nodes, status, logger), nil
+	case config.LeaderBackendKubernetes, "":
+		return NewKubernetes(cfg, status, logger), nil
+	default:
+		return nil, fmt.Errorf("unsupported leader election backend %q", cfg.Backend)
+	}
+}
+
+// Run starts leader election with the configured backend and blocks until
+// the election loop exits. See Elector for the callback contract.
+func Run(ctx context.Context, cfg config.LeaderElectionConfig, status *Status, logger *slog.Logger, onStartedLeading func(ctx context.Context), onStoppedLeading func()) error {
+	e, err := New(cfg, status, logger)
+	if err != nil {
+		return err
+	}
+	return e.Run(ctx, onStartedLeading, onStoppedLeading)
+}
+
+// Kubernetes elects a leader through a coordination.k8s.io Lease.
+type Kubernetes struct {
+	cfg    config.LeaderElectionConfig
+	status *Status
+	logger *slog.Logger
+}
+
+// NewKubernetes returns a Lease-based Elector. The cluster is reached
+// through ClientFactory.
+func NewKubernetes(cfg config.LeaderElectionConfig, status *Status, logger *slog.Logger) *Kubernetes {
+	return &Kubernetes{cfg: cfg, status: status, logger: logger}
+}
+
+// Run implements Elector.
+func (k *Kubernetes) Run(ctx context.Context, onStartedLeading func(ctx context.Context), onStoppedLeading func()) error {
+	cfg, status, logger := k.cfg, k.status, k.logger
+	id := status.Identity()
 	logger.Info("starting leader election",
 		slog.String("identity", id),
 		slog.String("lease", cfg.LeaseName),
@@ -81,13 +134,16 @@ func Run(ctx context.Context, cfg config.LeaderElectionConfig, logger *slog.Logg
 		Callbacks: leaderelection.LeaderCallbacks{
 			OnStartedLeading: func(ctx context.Context) {
 				logger.Info("acquired leadership", slog.String("identity", id))
+				status.Started(ctx)
 				onStartedLeading(ctx)
 			},
 			OnStoppedLeading: func() {
 				logger.Info("lost leadership", slog.String("identity", id))
+				status.Stopped(context.Background())
 				onStoppedLeading()
 			},
 			OnNewLeader: func(newID string) {
+				status.Observed(newID)
 				if newID == id {
 					return
 				}
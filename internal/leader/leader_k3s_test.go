@@ -12,8 +12,10 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/leader"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
 )
 
 // TestLeaderElection_K3s validates real Kubernetes Lease-based leader election
@@ -72,7 +74,7 @@ func TestLeaderElection_K3s(t *testing.T) {
 
 	errCh := make(chan error, 1)
 	go func() {
-		errCh <- leader.Run(leaderCtx, cfg, logger,
+		errCh <- leader.Run(leaderCtx, cfg, leader.NewStatus(clock.Real{}, metrics.Nop()), logger,
 			func(ctx context.Context) {
 				leaderAcquired.Store(true)
 				// Block until context is canceled (simulating the bot running).
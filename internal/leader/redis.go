@@ -0,0 +1,230 @@
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+)
+
+// RedisNode is the subset of a Redis client used by the Redis elector.
+// *redis.Client implements it.
+type RedisNode interface {
+	SetNX(ctx context.Context, key string, value any, expiration time.Duration) *redis.BoolCmd
+	Eval(ctx context.Context, script string, keys []string, args ...any) *redis.Cmd
+	Get(ctx context.Context, key string) *redis.StringCmd
+}
+
+// The scripts only touch the lock while it still holds this replica's
+// token, so that a lock which expired and was taken over is left alone.
+const (
+	renewScript   = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
+	releaseScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+)
+
+// tokenSep separates the identity in a lock token from its random suffix.
+const tokenSep = "/"
+
+// Redis elects a leader through a lock key with a TTL of the lease
+// duration, held on a majority of independent Redis nodes as in the Redlock
+// algorithm. The leader renews the TTL every retry period and gives up
+// leadership once it has failed to renew for the renew deadline, which is
+// shorter than the TTL, so it stops before another replica can take over.
+type Redis struct {
+	cfg    config.LeaderElectionConfig
+	nodes  []RedisNode
+	key    string
+	id     string
+	token  string
+	status *Status
+	logger *slog.Logger
+}
+
+// NewRedis returns a Redis-based Elector over nodes.
+func NewRedis(cfg config.LeaderElectionConfig, nodes []RedisNode, status *Status, logger *slog.Logger) *Redis {
+	key := cfg.Redis.Key
+	if key == "" {
+		key = cfg.LeaseName
+	}
+	id := status.Identity()
+	return &Redis{
+		cfg:   cfg,
+		nodes: nodes,
+		key:   key,
+		id:    id,
+		// The random suffix keeps tokens unique across restarts and
+		// replicas that share a hostname.
+		token:  id + tokenSep + rand.Text(),
+		status: status,
+		logger: logger,
+	}
+}
+
+// Run implements Elector.
+func (r *Redis) Run(ctx context.Context, onStartedLeading func(ctx context.Context), onStoppedLeading func()) error {
+	r.logger.Info("starting leader election",
+		slog.String("identity", r.id),
+		slog.String("backend", config.LeaderBackendRedis),
+		slog.String("key", r.key),
+		slog.Int("nodes", len(r.nodes)),
+	)
+	defer func() {
+		r.logger.Info("lost leadership", slog.String("identity", r.id))
+		r.status.Stopped(context.Background())
+		onStoppedLeading()
+	}()
+
+	ticker := time.NewTicker(r.cfg.RetryPeriod)
+	defer ticker.Stop()
+	for !r.acquire(ctx) {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+	r.logger.Info("acquired leadership", slog.String("identity", r.id))
+	r.status.Started(ctx)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		onStartedLeading(leaderCtx)
+	}()
+
+	r.hold(leaderCtx, ticker)
+	cancel()
+	<-done
+	r.release()
+	return nil
+}
+
+// hold renews the lock every tick until ctx is canceled or leadership is
+// lost.
+func (r *Redis) hold(ctx context.Context, ticker *time.Ticker) {
+	lastRenew := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		granted, denied, err := r.each(ctx, func(ctx context.Context, n RedisNode) (bool, error) {
+			v, err := n.Eval(ctx, renewScript, []string{r.key}, r.token, r.cfg.LeaseDuration.Milliseconds()).Int64()
+			return v == 1, err
+		})
+		switch {
+		case granted >= r.quorum() && r.validity(start) > 0:
+			lastRenew = start
+		case denied >= r.quorum():
+			r.logger.Warn("leader lock taken over", slog.String("key", r.key))
+			return
+		case time.Since(lastRenew) > r.cfg.RenewDeadline:
+			r.logger.Warn("failed to renew leader lock before the deadline", slog.Any("error", err))
+			return
+		default:
+			r.logger.Warn("failed to renew leader lock, retrying", slog.Any("error", err))
+		}
+	}
+}
+
+// acquire tries once to take the lock on a majority of nodes. A partial
+// acquisition is released again.
+func (r *Redis) acquire(ctx context.Context) bool {
+	start := time.Now()
+	granted, _, _ := r.each(ctx, func(ctx context.Context, n RedisNode) (bool, error) {
+		return n.SetNX(ctx, r.key, r.token, r.cfg.LeaseDuration).Result()
+	})
+	if granted >= r.quorum() && r.validity(start) > 0 {
+		return true
+	}
+	if granted > 0 {
+		r.release()
+	}
+	r.status.Observed(r.holder(ctx))
+	return false
+}
+
+// holder returns the identity in the lock token found on the first node
+// that has one, or "" if none does.
+func (r *Redis) holder(ctx context.Context) string {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.RetryPeriod)
+	defer cancel()
+	for _, n := range r.nodes {
+		token, err := n.Get(ctx, r.key).Result()
+		if err != nil || token == "" {
+			continue
+		}
+		if i := strings.LastIndex(token, tokenSep); i >= 0 {
+			return token[:i]
+		}
+		return token
+	}
+	return ""
+}
+
+// release deletes the lock from every node that still holds this token. It
+// runs after ctx is canceled, so it has its own deadline.
+func (r *Redis) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.cfg.RetryPeriod)
+	defer cancel()
+	_, _, _ = r.each(ctx, func(ctx context.Context, n RedisNode) (bool, error) {
+		v, err := n.Eval(ctx, releaseScript, []string{r.key}, r.token).Int64()
+		return v == 1, err
+	})
+}
+
+// quorum is the number of nodes that must hold the lock.
+func (r *Redis) quorum() int {
+	return len(r.nodes)/2 + 1
+}
+
+// validity returns how much of a lock taken or renewed at start is left,
+// allowing for clock drift between the nodes.
+func (r *Redis) validity(start time.Time) time.Duration {
+	drift := r.cfg.LeaseDuration/100 + 2*time.Millisecond
+	return r.cfg.LeaseDuration - time.Since(start) - drift
+}
+
+// each calls f on all nodes concurrently, bounded by the retry period, and
+// counts the nodes that answered true and false. Failed calls count as
+// neither.
+func (r *Redis) each(ctx context.Context, f func(context.Context, RedisNode) (bool, error)) (granted, denied int, err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.RetryPeriod)
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, n := range r.nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := f(ctx, n)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil && !errors.Is(err, redis.Nil):
+				errs = append(errs, err)
+			case ok:
+				granted++
+			default:
+				denied++
+			}
+		}()
+	}
+	wg.Wait()
+	return granted, denied, errors.Join(errs...)
+}
@@ -0,0 +1,215 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+)
+
+// fakeNode is an in-memory Redis node that understands the elector's
+// scripts. Keys do not expire.
+type fakeNode struct {
+	mu   sync.Mutex
+	vals map[string]string
+	down bool
+}
+
+func newFakeNode() *fakeNode {
+	return &fakeNode{vals: make(map[string]string)}
+}
+
+func (n *fakeNode) SetNX(_ context.Context, key string, value any, _ time.Duration) *redis.BoolCmd {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.down {
+		return redis.NewBoolResult(false, errors.New("connection refused"))
+	}
+	if _, ok := n.vals[key]; ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	n.vals[key] = value.(string)
+	return redis.NewBoolResult(true, nil)
+}
+
+func (n *fakeNode) Eval(_ context.Context, script string, keys []string, args ...any) *redis.Cmd {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.down {
+		return redis.NewCmdResult(nil, errors.New("connection refused"))
+	}
+	if n.vals[keys[0]] != args[0] {
+		return redis.NewCmdResult(int64(0), nil)
+	}
+	if script == releaseScript {
+		delete(n.vals, keys[0])
+	}
+	return redis.NewCmdResult(int64(1), nil)
+}
+
+func (n *fakeNode) Get(_ context.Context, key string) *redis.StringCmd {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.down {
+		return redis.NewStringResult("", errors.New("connection refused"))
+	}
+	v, ok := n.vals[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(v, nil)
+}
+
+func (n *fakeNode) set(key, value string, down bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.vals[key] = value
+	n.down = down
+}
+
+var testRedisConfig = config.LeaderElectionConfig{
+	Enabled:       true,
+	Backend:       config.LeaderBackendRedis,
+	LeaseName:     "dkpbot-leader",
+	LeaseDuration: 300 * time.Millisecond,
+	RenewDeadline: 100 * time.Millisecond,
+	RetryPeriod:   10 * time.Millisecond,
+}
+
+// campaign runs e in the background and returns a channel that receives
+// the leader context once e leads, and one closed when Run returns.
+func campaign(ctx context.Context, e *Redis) (leading chan context.Context, stopped chan struct{}) {
+	leading = make(chan context.Context, 1)
+	stopped = make(chan struct{})
+	go func() {
+		defer close(stopped)
+		_ = e.Run(ctx, func(ctx context.Context) {
+			leading <- ctx
+			<-ctx.Done()
+		}, func() {})
+	}()
+	return leading, stopped
+}
+
+func TestRedis_SingleLeader(t *testing.T) {
+	nodes := []RedisNode{newFakeNode(), newFakeNode(), newFakeNode()}
+	t.Setenv("POD_NAME", "dkpbot-0")
+	firstStatus := NewStatus(clock.Real{}, metrics.Nop())
+	first := NewRedis(testRedisConfig, nodes, firstStatus, slog.Default())
+	t.Setenv("POD_NAME", "dkpbot-1")
+	secondStatus := NewStatus(clock.Real{}, metrics.Nop())
+	second := NewRedis(testRedisConfig, nodes, secondStatus, slog.Default())
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	leading1, stopped1 := campaign(ctx1, first)
+	select {
+	case <-leading1:
+	case <-time.After(time.Second):
+		t.Fatal("first elector did not acquire leadership")
+	}
+
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	leading2, _ := campaign(ctx2, second)
+	select {
+	case <-leading2:
+		t.Fatal("second elector acquired leadership while the first held it")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if got := secondStatus.Snapshot(); got.Leader || got.Holder != "dkpbot-0" {
+		t.Errorf("second status = %+v, want follower of dkpbot-0", got)
+	}
+	if got := firstStatus.Snapshot(); !got.Leader || got.Holder != "dkpbot-0" {
+		t.Errorf("first status = %+v, want leader", got)
+	}
+
+	// Stepping down releases the lock for the other replica.
+	cancel1()
+	<-stopped1
+	select {
+	case <-leading2:
+	case <-time.After(time.Second):
+		t.Fatal("second elector did not take over after release")
+	}
+	if got := firstStatus.Snapshot(); got.Leader || got.Holder != "" {
+		t.Errorf("first status after stepping down = %+v, want follower", got)
+	}
+}
+
+func TestRedis_LosesLeadership(t *testing.T) {
+	tests := []struct {
+		name string
+		// breakNodes changes the nodes after leadership is acquired.
+		breakNodes func(nodes []*fakeNode, key string)
+	}{
+		{
+			name: "lock taken over",
+			breakNodes: func(nodes []*fakeNode, key string) {
+				for _, n := range nodes {
+					n.set(key, "other", false)
+				}
+			},
+		},
+		{
+			name: "majority of nodes unreachable",
+			breakNodes: func(nodes []*fakeNode, key string) {
+				nodes[0].set(key, "", true)
+				nodes[1].set(key, "", true)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakes := []*fakeNode{newFakeNode(), newFakeNode(), newFakeNode()}
+			nodes := make([]RedisNode, len(fakes))
+			for i, n := range fakes {
+				nodes[i] = n
+			}
+			e := NewRedis(testRedisConfig, nodes, NewStatus(clock.Real{}, metrics.Nop()), slog.Default())
+
+			leading, stopped := campaign(context.Background(), e)
+			var leaderCtx context.Context
+			select {
+			case leaderCtx = <-leading:
+			case <-time.After(time.Second):
+				t.Fatal("did not acquire leadership")
+			}
+
+			tt.breakNodes(fakes, e.key)
+			select {
+			case <-leaderCtx.Done():
+			case <-time.After(time.Second):
+				t.Fatal("leader context not canceled after losing the lock")
+			}
+			select {
+			case <-stopped:
+			case <-time.After(time.Second):
+				t.Fatal("Run did not return after losing leadership")
+			}
+		})
+	}
+}
+
+func TestRedis_MinorityCannotLead(t *testing.T) {
+	fakes := []*fakeNode{newFakeNode(), newFakeNode(), newFakeNode()}
+	fakes[1].set("dkpbot-leader", "other", false)
+	fakes[2].set("dkpbot-leader", "other", false)
+	e := NewRedis(testRedisConfig, []RedisNode{fakes[0], fakes[1], fakes[2]}, NewStatus(clock.Real{}, metrics.Nop()), slog.Default())
+
+	if e.acquire(context.Background()) {
+		t.Fatal("acquired the lock on a minority of nodes")
+	}
+	fakes[0].mu.Lock()
+	defer fakes[0].mu.Unlock()
+	if _, ok := fakes[0].vals["dkpbot-leader"]; ok {
+		t.Error("partial acquisition was not released")
+	}
+}
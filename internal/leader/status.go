@@ -0,0 +1,138 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+)
+
+// Snapshot is the leadership state of a replica at one point in time.
+type Snapshot struct {
+	// Identity is this replica's election identity.
+	Identity string `json:"identity"`
+	// Leader reports whether this replica holds the lock.
+	Leader bool `json:"leader"`
+	// Holder is the identity that holds the lock, if known.
+	Holder string `json:"holder,omitempty"`
+	// Since is when Leader last changed.
+	Since time.Time `json:"since"`
+	// FencingToken is the token this replica writes with while it leads.
+	FencingToken int64 `json:"fencing_token,omitempty"`
+}
+
+// Status tracks whether this replica leads and which identity holds the
+// lock, as reported by an Elector. It is safe for concurrent use.
+type Status struct {
+	mu       sync.RWMutex
+	identity string
+	leading  bool
+	holder   string
+	token    int64
+	since    time.Time
+	clock    clock.Clock
+	rec      *metrics.Recorder
+}
+
+// NewStatus returns a Status for this replica, which does not lead yet.
+// Changes are recorded on the dkpbot.leader gauge of rec.
+func NewStatus(clk clock.Clock, rec *metrics.Recorder) *Status {
+	s := &Status{identity: identity(), since: clk.Now(), clock: clk, rec: rec}
+	rec.Leadership(context.Background(), s.identity, false)
+	return s
+}
+
+// Identity returns this replica's election identity.
+func (s *Status) Identity() string {
+	return s.identity
+}
+
+// Started marks this replica as the leader. Callers that run without
+// leader election call it once at startup.
+func (s *Status) Started(ctx context.Context) {
+	s.set(ctx, true, s.identity)
+}
+
+// Stopped marks this replica as no longer leading.
+func (s *Status) Stopped(ctx context.Context) {
+	s.mu.RLock()
+	holder := s.holder
+	s.mu.RUnlock()
+	if holder == s.identity {
+		holder = ""
+	}
+	s.set(ctx, false, holder)
+}
+
+// SetFencingToken records the fencing token this replica acquired as the
+// leader. It is cleared when leadership stops.
+func (s *Status) SetFencingToken(token int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+}
+
+// Observed records that holder holds the lock.
+func (s *Status) Observed(holder string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.holder = holder
+}
+
+func (s *Status) set(ctx context.Context, leading bool, holder string) {
+	s.mu.Lock()
+	changed := s.leading != leading
+	s.leading, s.holder = leading, holder
+	if changed {
+		s.since = s.clock.Now()
+	}
+	if !leading {
+		s.token = 0
+	}
+	s.mu.Unlock()
+	if changed {
+		s.rec.Leadership(ctx, s.identity, leading)
+	}
+}
+
+// Snapshot returns the current state.
+func (s *Status) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Snapshot{
+		Identity:     s.identity,
+		Leader:       s.leading,
+		Holder:       s.holder,
+		Since:        s.since.UTC(),
+		FencingToken: s.token,
+	}
+}
+
+// Role returns "leader" or "follower", for readiness details.
+func (s *Status) Role() string {
+	if s.Snapshot().Leader {
+		return "leader"
+	}
+	return "follower"
+}
+
+// Holder returns the identity that holds the lock, or "unknown".
+func (s *Status) Holder() string {
+	if h := s.Snapshot().Holder; h != "" {
+		return h
+	}
+	return "unknown"
+}
+
+// Handler serves the Snapshot as JSON, for the /leaderz endpoint. It always
+// answers 200 so that followers can be told apart from failing replicas.
+func (s *Status) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.Snapshot())
+	}
+}
@@ -0,0 +1,85 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+)
+
+func TestStatus(t *testing.T) {
+	t.Setenv("POD_NAME", "dkpbot-0")
+	reader := sdkmetric.NewManualReader()
+	rec, err := metrics.New(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)), "guild-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStatus(clock.Real{}, rec)
+	ctx := context.Background()
+
+	gauge := func() int64 {
+		t.Helper()
+		var rm metricdata.ResourceMetrics
+		if err := reader.Collect(ctx, &rm); err != nil {
+			t.Fatal(err)
+		}
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				if m.Name != "dkpbot.leader" {
+					continue
+				}
+				dp := m.Data.(metricdata.Gauge[int64]).DataPoints[0]
+				if v, _ := dp.Attributes.Value(metrics.IdentityKey); v.AsString() != "dkpbot-0" {
+					t.Errorf("identity attribute = %q, want dkpbot-0", v.AsString())
+				}
+				return dp.Value
+			}
+		}
+		t.Fatal("dkpbot.leader not recorded")
+		return 0
+	}
+
+	tests := []struct {
+		name       string
+		change     func()
+		wantLeader bool
+		wantHolder string
+		wantGauge  int64
+	}{
+		{name: "initial", change: func() {}, wantHolder: "unknown"},
+		{name: "other replica leads", change: func() { s.Observed("dkpbot-1") }, wantHolder: "dkpbot-1"},
+		{name: "started", change: func() { s.Started(ctx) }, wantLeader: true, wantHolder: "dkpbot-0", wantGauge: 1},
+		{name: "stopped", change: func() { s.Stopped(ctx) }, wantHolder: "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change()
+
+			rr := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/leaderz", nil))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+			}
+			var got Snapshot
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Identity != "dkpbot-0" || got.Leader != tt.wantLeader {
+				t.Errorf("snapshot = %+v, want identity dkpbot-0 leader %v", got, tt.wantLeader)
+			}
+			if h := s.Holder(); h != tt.wantHolder {
+				t.Errorf("Holder() = %q, want %q", h, tt.wantHolder)
+			}
+			if g := gauge(); g != tt.wantGauge {
+				t.Errorf("dkpbot.leader = %d, want %d", g, tt.wantGauge)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+)
+
+// Stepdown errors.
+var (
+	ErrNotLeader    = derrors.New(derrors.Conflict, "NOT_LEADER", "this replica is not the leader")
+	ErrSteppingDown = derrors.New(derrors.Conflict, "STEPPING_DOWN", "this replica is already stepping down")
+)
+
+// Stepdown hands leadership over on request: it runs the registered drain
+// steps, such as finishing in-flight interactions, and then cancels the
+// election context so that the Elector releases the lock for another
+// replica.
+type Stepdown struct {
+	status  *Status
+	cancel  context.CancelFunc
+	timeout time.Duration
+	logger  *slog.Logger
+
+	mu       sync.Mutex
+	steps    []func(ctx context.Context) error
+	stepping bool
+}
+
+// NewStepdown returns a Stepdown and the context to run the Elector with.
+// The drain steps together may take up to timeout.
+func NewStepdown(ctx context.Context, status *Status, timeout time.Duration, logger *slog.Logger) (context.Context, *Stepdown) {
+	ctx, cancel := context.WithCancel(ctx)
+	return ctx, &Stepdown{status: status, cancel: cancel, timeout: timeout, logger: logger}
+}
+
+// OnStepdown registers a drain step. Steps run in registration order.
+func (s *Stepdown) OnStepdown(step func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps = append(s.steps, step)
+}
+
+// StepDown drains this replica and releases leadership. Failing steps are
+// logged and do not stop the handover. It returns ErrNotLeader if this
+// replica does not lead, and ErrSteppingDown if a stepdown is under way.
+func (s *Stepdown) StepDown(ctx context.Context) error {
+	if !s.status.Snapshot().Leader {
+		return ErrNotLeader
+	}
+	s.mu.Lock()
+	if s.stepping {
+		s.mu.Unlock()
+		return ErrSteppingDown
+	}
+	s.stepping = true
+	steps := s.steps
+	s.mu.Unlock()
+
+	s.logger.InfoContext(ctx, "stepping down", slog.String("identity", s.status.Identity()))
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.timeout)
+	defer cancel()
+
+	var errs []error
+	for _, step := range steps {
+		if err := step(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		s.logger.WarnContext(ctx, "draining before stepdown failed, releasing leadership anyway", slog.Any("error", err))
+	}
+	s.cancel()
+	return nil
+}
@@ -0,0 +1,49 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+)
+
+func TestStepdown(t *testing.T) {
+	status := NewStatus(clock.Real{}, metrics.Nop())
+	electionCtx, sd := NewStepdown(context.Background(), status, time.Second, slog.Default())
+
+	var ran []string
+	sd.OnStepdown(func(context.Context) error {
+		ran = append(ran, "drain")
+		return errors.New("drain timed out")
+	})
+	sd.OnStepdown(func(context.Context) error {
+		ran = append(ran, "flush")
+		return nil
+	})
+
+	ctx := context.Background()
+	if err := sd.StepDown(ctx); !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("StepDown() as follower = %v, want ErrNotLeader", err)
+	}
+	if len(ran) != 0 || electionCtx.Err() != nil {
+		t.Fatal("follower ran drain steps or canceled the election")
+	}
+
+	status.Started(ctx)
+	if err := sd.StepDown(ctx); err != nil {
+		t.Fatalf("StepDown() = %v", err)
+	}
+	if len(ran) != 2 || ran[0] != "drain" || ran[1] != "flush" {
+		t.Errorf("ran steps %v, want [drain flush] despite the failed drain", ran)
+	}
+	if electionCtx.Err() == nil {
+		t.Error("election context not canceled")
+	}
+	if err := sd.StepDown(ctx); !errors.Is(err, ErrSteppingDown) {
+		t.Errorf("second StepDown() = %v, want ErrSteppingDown", err)
+	}
+}
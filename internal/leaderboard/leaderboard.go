@@ -0,0 +1,245 @@
+// Package leaderboard posts a weekly DKP leaderboard: the standings, the
+// players who gained and lost the most DKP that week, how their ranks
+// changed, and who has attended raids the most weeks in a row.
+//
+// Each post saves the standings it showed as a snapshot, which the next post
+// compares against. Without a snapshot, as before the first post, last
+// week's standings are worked out by undoing the week's DKP events.
+package leaderboard
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// Week is the period a leaderboard covers.
+const Week = 7 * 24 * time.Hour
+
+// listSize is how many players each part of the leaderboard shows.
+const listSize = 5
+
+// Standing is a player's place in the standings.
+type Standing struct {
+	PlayerID string `json:"player_id"`
+	Name     string `json:"name"`
+	DKP      int    `json:"dkp"`
+	Rank     int    `json:"rank"`
+}
+
+// Rank orders players by DKP, most first and ties by name, and numbers
+// them from 1.
+func Rank(players []store.Player) []Standing {
+	standings := make([]Standing, 0, len(players))
+	for _, p := range players {
+		standings = append(standings, Standing{PlayerID: p.ID, Name: p.CharacterName, DKP: p.DKP})
+	}
+	return rank(standings)
+}
+
+func rank(standings []Standing) []Standing {
+	slices.SortStableFunc(standings, func(a, b Standing) int {
+		return cmp.Or(cmp.Compare(b.DKP, a.DKP), strings.Compare(a.Name, b.Name))
+	})
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+	return standings
+}
+
+// Rewind returns the standings as of since, given the DKP events appended
+// after it. Players registered after since are left out.
+func Rewind(players []store.Player, changes []event.Event, since time.Time) ([]Standing, error) {
+	net, err := netChanges(changes)
+	if err != nil {
+		return nil, err
+	}
+	var standings []Standing
+	for _, p := range players {
+		if p.CreatedAt.After(since) {
+			continue
+		}
+		standings = append(standings, Standing{PlayerID: p.ID, Name: p.CharacterName, DKP: p.DKP - net[p.ID]})
+	}
+	return rank(standings), nil
+}
+
+func netChanges(changes []event.Event) (map[string]int, error) {
+	net := make(map[string]int)
+	for _, e := range changes {
+		var d event.DKPChangeData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+		}
+		net[d.PlayerID] += d.Amount
+	}
+	return net, nil
+}
+
+// Streaks returns, for each player, how many weeks in a row up to now they
+// were awarded DKP for attendance, given DKPAwarded events. An award counts
+// as attendance if its reason mentions it, as Warcraft Logs imports do.
+func Streaks(awards []event.Event, now time.Time) (map[string]int, error) {
+	weeks := make(map[string]map[int]bool)
+	for _, e := range awards {
+		var d event.DKPChangeData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+		}
+		if !strings.Contains(strings.ToLower(d.Reason), "attendance") || e.CreatedAt.After(now) {
+			continue
+		}
+		if weeks[d.PlayerID] == nil {
+			weeks[d.PlayerID] = make(map[int]bool)
+		}
+		weeks[d.PlayerID][int(now.Sub(e.CreatedAt)/Week)] = true
+	}
+
+	streaks := make(map[string]int, len(weeks))
+	for id, attended := range weeks {
+		n := 0
+		for attended[n] {
+			n++
+		}
+		if n > 0 {
+			streaks[id] = n
+		}
+	}
+	return streaks, nil
+}
+
+// Entry is a player's line on the leaderboard.
+type Entry struct {
+	Standing
+	// PreviousRank is the player's rank last week, or 0 if they are new.
+	PreviousRank int
+	// Change is the DKP gained since last week, negative if lost.
+	Change int
+	// Streak is the number of weeks in a row the player attended.
+	Streak int
+}
+
+// Board is a weekly leaderboard.
+type Board struct {
+	// Since is when the previous standings were taken.
+	Since time.Time
+	// Entries are ordered by rank.
+	Entries []Entry
+}
+
+// Build compares the current standings with the previous ones. A player
+// missing from previous is new and their change is their DKP.
+func Build(current, previous []Standing, streaks map[string]int, since time.Time) Board {
+	before := make(map[string]Standing, len(previous))
+	for _, s := range previous {
+		before[s.PlayerID] = s
+	}
+	board := Board{Since: since, Entries: make([]Entry, 0, len(current))}
+	for _, s := range current {
+		entry := Entry{Standing: s, Change: s.DKP, Streak: streaks[s.PlayerID]}
+		if prev, ok := before[s.PlayerID]; ok {
+			entry.PreviousRank = prev.Rank
+			entry.Change = s.DKP - prev.DKP
+		}
+		board.Entries = append(board.Entries, entry)
+	}
+	return board
+}
+
+// Standings returns the board's standings, to be compared against next week.
+func (b Board) Standings() []Standing {
+	standings := make([]Standing, len(b.Entries))
+	for i, e := range b.Entries {
+		standings[i] = e.Standing
+	}
+	return standings
+}
+
+// Gainers returns up to n players who gained DKP, most first.
+func (b Board) Gainers(n int) []Entry {
+	return b.top(n, func(e Entry) bool { return e.Change > 0 }, func(x, y Entry) int { return cmp.Compare(y.Change, x.Change) })
+}
+
+// Losers returns up to n players who lost DKP, most first.
+func (b Board) Losers(n int) []Entry {
+	return b.top(n, func(e Entry) bool { return e.Change < 0 }, func(x, y Entry) int { return cmp.Compare(x.Change, y.Change) })
+}
+
+// LongestStreaks returns up to n players with an attendance streak of more
+// than one week, longest first.
+func (b Board) LongestStreaks(n int) []Entry {
+	return b.top(n, func(e Entry) bool { return e.Streak > 1 }, func(x, y Entry) int { return cmp.Compare(y.Streak, x.Streak) })
+}
+
+// top returns up to n entries matching keep ordered by compare, ties by rank.
+func (b Board) top(n int, keep func(Entry) bool, compare func(x, y Entry) int) []Entry {
+	var entries []Entry
+	for _, e := range b.Entries {
+		if keep(e) {
+			entries = append(entries, e)
+		}
+	}
+	slices.SortStableFunc(entries, compare)
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// Embed renders the board as a Discord embed.
+func (b Board) Embed() *discordgo.MessageEmbed {
+	var standings []string
+	for _, e := range b.Entries[:min(len(b.Entries), 2*listSize)] {
+		standings = append(standings, fmt.Sprintf("%d. **%s** %d DKP %s", e.Rank, e.Name, e.DKP, rankChange(e)))
+	}
+	var gainers, losers, streaks []string
+	for _, e := range b.Gainers(listSize) {
+		gainers = append(gainers, fmt.Sprintf("**%s** %+d", e.Name, e.Change))
+	}
+	for _, e := range b.Losers(listSize) {
+		losers = append(losers, fmt.Sprintf("**%s** %+d", e.Name, e.Change))
+	}
+	for _, e := range b.LongestStreaks(listSize) {
+		streaks = append(streaks, fmt.Sprintf("**%s** %d weeks", e.Name, e.Streak))
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       "Weekly leaderboard",
+		Description: fmt.Sprintf("Changes since <t:%d:D>.", b.Since.Unix()),
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Standings", Value: lines(standings, "No players are registered.")},
+			{Name: "Top gainers", Value: lines(gainers, "Nobody gained DKP."), Inline: true},
+			{Name: "Top losers", Value: lines(losers, "Nobody lost DKP."), Inline: true},
+			{Name: "Attendance streaks", Value: lines(streaks, "No streaks yet.")},
+		},
+	}
+}
+
+// rankChange describes how an entry's rank moved since last week.
+func rankChange(e Entry) string {
+	switch {
+	case e.PreviousRank == 0:
+		return "(new)"
+	case e.PreviousRank > e.Rank:
+		return fmt.Sprintf("(▲%d)", e.PreviousRank-e.Rank)
+	case e.PreviousRank < e.Rank:
+		return fmt.Sprintf("(▼%d)", e.Rank-e.PreviousRank)
+	default:
+		return "(–)"
+	}
+}
+
+func lines(l []string, empty string) string {
+	if len(l) == 0 {
+		return empty
+	}
+	return strings.Join(l, "\n")
+}
@@ -0,0 +1,221 @@
+package leaderboard_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/discordtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/leaderboard"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/settings"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+var now = time.Date(2025, 6, 16, 18, 0, 0, 0, time.UTC)
+
+type memEvents struct {
+	events []event.Event
+}
+
+func (m *memEvents) Append(_ context.Context, events ...event.Event) error {
+	m.events = append(m.events, events...)
+	return nil
+}
+
+func (m *memEvents) Load(context.Context, string) ([]event.Event, error) { return nil, nil }
+
+func (m *memEvents) LoadByType(context.Context, event.Type) ([]event.Event, error) { return nil, nil }
+
+func (m *memEvents) Query(_ context.Context, q event.Query) ([]event.Event, error) {
+	return q.Filter(m.events), nil
+}
+
+func (m *memEvents) change(typ event.Type, playerID string, amount int, reason string, at time.Time) {
+	data, _ := json.Marshal(event.DKPChangeData{PlayerID: playerID, Amount: amount, Reason: reason})
+	m.events = append(m.events, event.Event{AggregateID: playerID, Type: typ, Data: data, CreatedAt: at})
+}
+
+type memArchive struct {
+	snapshots map[string]event.Snapshot
+}
+
+func (m *memArchive) SaveSnapshot(_ context.Context, s event.Snapshot) error {
+	m.snapshots[s.AggregateID] = s
+	return nil
+}
+
+func (m *memArchive) LoadSnapshot(_ context.Context, id string) (*event.Snapshot, error) {
+	s, ok := m.snapshots[id]
+	if !ok {
+		return nil, errors.New("snapshot not found")
+	}
+	return &s, nil
+}
+
+func (m *memArchive) ArchiveAggregate(context.Context, string, int) (int, error) { return 0, nil }
+
+func (m *memArchive) Archived(context.Context, ...event.Type) ([]event.Event, error) { return nil, nil }
+
+type fixedPlayers []store.Player
+
+func (f fixedPlayers) List(context.Context) ([]store.Player, error) { return f, nil }
+
+func newPoster(players fixedPlayers, events *memEvents, archive *memArchive) *leaderboard.Poster {
+	return leaderboard.NewPoster(config.LeaderboardConfig{Weekday: "monday", Time: "18:00"},
+		players, events, archive, nil, "guild", nil, nil,
+		slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clock.Mock{T: now})
+}
+
+func TestPoster_Board(t *testing.T) {
+	old := now.Add(-30 * 24 * time.Hour)
+	players := fixedPlayers{
+		{ID: "p1", CharacterName: "Alice", DKP: 100, CreatedAt: old},
+		{ID: "p2", CharacterName: "Bob", DKP: 150, CreatedAt: old},
+		{ID: "p3", CharacterName: "Carol", DKP: 20, CreatedAt: old},
+		{ID: "p4", CharacterName: "Dave", DKP: 30, CreatedAt: now.Add(-time.Hour)},
+		{ID: "p5", CharacterName: "Eve", DKP: 500, CreatedAt: old, ArchivedAt: &old},
+	}
+	events := &memEvents{}
+	// Last week Alice led with 120 and Bob followed with 50.
+	events.change(event.DKPAwarded, "p2", 100, "Molten Core: attendance and 10 boss kills", now.Add(-2*24*time.Hour))
+	events.change(event.DKPDeducted, "p1", -20, "Item: Thunderfury", now.Add(-24*time.Hour))
+	events.change(event.DKPAwarded, "p4", 30, "Molten Core: attendance and 10 boss kills", now.Add(-time.Hour))
+	// Bob also attended the two weeks before, Carol only two weeks ago.
+	events.change(event.DKPAwarded, "p2", 10, "attendance", now.Add(-9*24*time.Hour))
+	events.change(event.DKPAwarded, "p2", 10, "attendance", now.Add(-16*24*time.Hour))
+	events.change(event.DKPAwarded, "p3", 10, "attendance", now.Add(-10*24*time.Hour))
+	events.change(event.DKPAwarded, "p3", 5, "bonus", now.Add(-3*24*time.Hour))
+
+	board, err := newPoster(players, events, &memArchive{snapshots: map[string]event.Snapshot{}}).Board(context.Background())
+	if err != nil {
+		t.Fatalf("Board() error = %v", err)
+	}
+
+	type row struct {
+		name                     string
+		rank, prev, change, days int
+	}
+	want := []row{
+		{"Bob", 1, 2, 100, 3},
+		{"Alice", 2, 1, -20, 0},
+		{"Dave", 3, 0, 30, 1},
+		{"Carol", 4, 3, 5, 0},
+	}
+	if len(board.Entries) != len(want) {
+		t.Fatalf("entries = %d, want %d", len(board.Entries), len(want))
+	}
+	for i, w := range want {
+		e := board.Entries[i]
+		if e.Name != w.name || e.Rank != w.rank || e.PreviousRank != w.prev || e.Change != w.change || e.Streak != w.days {
+			t.Errorf("entry %d = %+v, want %+v", i, e, w)
+		}
+	}
+	if !board.Since.Equal(now.Add(-leaderboard.Week)) {
+		t.Errorf("Since = %v, want a week ago", board.Since)
+	}
+
+	if g := board.Gainers(2); len(g) != 2 || g[0].Name != "Bob" || g[1].Name != "Dave" {
+		t.Errorf("Gainers(2) = %+v, want Bob, Dave", g)
+	}
+	if l := board.Losers(5); len(l) != 1 || l[0].Name != "Alice" {
+		t.Errorf("Losers(5) = %+v, want Alice", l)
+	}
+	if s := board.LongestStreaks(5); len(s) != 1 || s[0].Name != "Bob" {
+		t.Errorf("LongestStreaks(5) = %+v, want Bob", s)
+	}
+
+	embed := board.Embed()
+	for _, want := range []string{"1. **Bob** 150 DKP (▲1)", "2. **Alice** 100 DKP (▼1)", "**Dave** 30 DKP (new)"} {
+		if !strings.Contains(embed.Fields[0].Value, want) {
+			t.Errorf("standings %q do not contain %q", embed.Fields[0].Value, want)
+		}
+	}
+	if got := embed.Fields[3].Value; got != "**Bob** 3 weeks" {
+		t.Errorf("streaks = %q", got)
+	}
+}
+
+func TestPoster_Run(t *testing.T) {
+	svc := settings.NewService(storetest.NewGuildSettings(store.GuildSetting{GuildID: "guild", Key: settings.LeaderboardChannel, Value: "123456789012345678"}),
+		settings.Defaults(config.GuildDefaultsConfig{}), slog.New(slog.DiscardHandler))
+	session, rt := discordtest.NewSession()
+	archive := &memArchive{snapshots: map[string]event.Snapshot{}}
+	// The leaderboard is posted on Mondays at 18:00, an hour from now.
+	clk := clock.NewFakeClock(now.Add(-time.Hour))
+	p := leaderboard.NewPoster(config.LeaderboardConfig{Weekday: "monday", Time: "18:00"},
+		fixedPlayers{{ID: "p1", CharacterName: "Alice", DKP: 100, CreatedAt: now.Add(-30 * 24 * time.Hour)}},
+		&memEvents{}, archive, svc, "guild", func() *discordgo.Session { return session }, nil,
+		slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clk)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	clk.BlockUntil(1)
+	clk.Advance(59 * time.Minute)
+	if reqs := rt.Requests(); len(reqs) != 0 {
+		t.Fatalf("posted %+v before the scheduled time", reqs)
+	}
+	clk.Advance(time.Minute)
+	if reqs := rt.Await(t, 1); reqs[0].Path != "/api/v9/channels/123456789012345678/messages" {
+		t.Errorf("posted %+v, want a message in the leaderboard channel", reqs[0])
+	}
+
+	// Once posted, Run waits for next week's post.
+	clk.BlockUntil(1)
+	if _, ok := archive.snapshots[leaderboard.SnapshotID]; !ok {
+		t.Error("no snapshot of the posted standings")
+	}
+	clk.Advance(leaderboard.Week)
+	rt.Await(t, 2)
+}
+
+func TestPoster_BoardComparesWithSnapshot(t *testing.T) {
+	taken := now.Add(-leaderboard.Week)
+	state, _ := json.Marshal(map[string]any{
+		"taken_at": taken,
+		"standings": []leaderboard.Standing{
+			{PlayerID: "p1", Name: "Alice", DKP: 80, Rank: 1},
+			{PlayerID: "p2", Name: "Bob", DKP: 60, Rank: 2},
+		},
+	})
+	archive := &memArchive{snapshots: map[string]event.Snapshot{
+		leaderboard.SnapshotID: {AggregateID: leaderboard.SnapshotID, State: state},
+	}}
+	players := fixedPlayers{
+		{ID: "p1", CharacterName: "Alice", DKP: 70},
+		{ID: "p2", CharacterName: "Bob", DKP: 90},
+	}
+
+	board, err := newPoster(players, &memEvents{}, archive).Board(context.Background())
+	if err != nil {
+		t.Fatalf("Board() error = %v", err)
+	}
+	if !board.Since.Equal(taken) {
+		t.Errorf("Since = %v, want %v", board.Since, taken)
+	}
+	if e := board.Entries[0]; e.Name != "Bob" || e.PreviousRank != 2 || e.Change != 30 {
+		t.Errorf("first entry = %+v, want Bob up from 2 by 30", e)
+	}
+	if e := board.Entries[1]; e.Name != "Alice" || e.PreviousRank != 1 || e.Change != -10 {
+		t.Errorf("second entry = %+v, want Alice down from 1 by 10", e)
+	}
+}
+
+func TestBoard_EmbedEmpty(t *testing.T) {
+	embed := leaderboard.Build(nil, nil, nil, now).Embed()
+	if got := embed.Fields[0].Value; got != "No players are registered." {
+		t.Errorf("standings = %q", got)
+	}
+}
@@ -0,0 +1,188 @@
+package leaderboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/settings"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// SnapshotID is the aggregate ID the standings of the last post are saved
+// under.
+const SnapshotID = "leaderboard"
+
+// streakWeeks is how far back attendance streaks are counted.
+const streakWeeks = 52
+
+// Players lists the registered players.
+type Players interface {
+	List(ctx context.Context) ([]store.Player, error)
+}
+
+// snapshot is the state saved after each post.
+type snapshot struct {
+	TakenAt   time.Time  `json:"taken_at"`
+	Standings []Standing `json:"standings"`
+}
+
+// Poster posts the leaderboard of a guild on the configured schedule. Only
+// the leader should run it.
+type Poster struct {
+	cfg      config.LeaderboardConfig
+	players  Players
+	events   event.Store
+	archive  event.Archive
+	settings *settings.Service
+	guildID  string
+	session  func() *discordgo.Session
+	claims   *idempotency.Guard
+	logger   *slog.Logger
+	tracer   trace.Tracer
+	clock    clock.Clock
+}
+
+// NewPoster returns a Poster for guildID. session returns the current
+// Discord session, or nil while none is open. claims ensures each week is
+// posted once even if leadership changes hands around the posting time.
+func NewPoster(cfg config.LeaderboardConfig, players Players, events event.Store, archive event.Archive, svc *settings.Service, guildID string, session func() *discordgo.Session, claims *idempotency.Guard, logger *slog.Logger, tp trace.TracerProvider, clk clock.Clock) *Poster {
+	return &Poster{
+		cfg:      cfg,
+		players:  players,
+		events:   events,
+		archive:  archive,
+		settings: svc,
+		guildID:  guildID,
+		session:  session,
+		claims:   claims,
+		logger:   logger,
+		tracer:   tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/leaderboard"),
+		clock:    clk,
+	}
+}
+
+// Run posts the leaderboard at each scheduled time until ctx is done.
+func (p *Poster) Run(ctx context.Context) {
+	for {
+		next := p.cfg.Next(p.clock.Now(), p.settings.Location(ctx, p.guildID))
+		timer := clock.NewTimer(p.clock, next.Sub(p.clock.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		if err := p.Post(ctx); err != nil {
+			p.logger.ErrorContext(ctx, "posting leaderboard failed", slog.Any("error", err))
+		}
+	}
+}
+
+// Post posts the leaderboard in the leaderboard channel, unless none is set
+// or it was already posted this week, and saves the standings it shows.
+func (p *Poster) Post(ctx context.Context) error {
+	now := p.clock.Now().UTC()
+	year, week := now.ISOWeek()
+	ctx, span := p.tracer.Start(ctx, "Poster.Post",
+		trace.WithAttributes(attribute.String("week", fmt.Sprintf("%d-W%02d", year, week))),
+	)
+	defer span.End()
+
+	gs, err := p.settings.Get(ctx, p.guildID)
+	if err != nil {
+		return err
+	}
+	if gs.LeaderboardChannel == "" {
+		return nil
+	}
+	s := p.session()
+	if s == nil {
+		return fmt.Errorf("no Discord session is open")
+	}
+
+	board, err := p.Board(ctx)
+	if err != nil {
+		return err
+	}
+
+	claimed, err := p.claims.Claim(idempotency.WithKey(ctx, fmt.Sprintf("%d-W%02d", year, week)), "leaderboard.post")
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+	if _, err := s.ChannelMessageSendEmbed(gs.LeaderboardChannel, board.Embed(), discordgo.WithContext(ctx)); err != nil {
+		return fmt.Errorf("sending leaderboard: %w", err)
+	}
+
+	data, _ := json.Marshal(snapshot{TakenAt: now, Standings: board.Standings()})
+	if err := p.archive.SaveSnapshot(ctx, event.Snapshot{AggregateID: SnapshotID, State: data}); err != nil {
+		return err
+	}
+	p.logger.InfoContext(ctx, "leaderboard posted",
+		slog.String("channel_id", gs.LeaderboardChannel),
+		slog.Int("players", len(board.Entries)),
+	)
+	return nil
+}
+
+// Board builds the leaderboard of the players who are not archived as of
+// now, compared with the standings of the last post, or of a week ago if
+// there has been none.
+func (p *Poster) Board(ctx context.Context) (Board, error) {
+	now := p.clock.Now().UTC()
+	players, err := p.players.List(ctx)
+	if err != nil {
+		return Board{}, fmt.Errorf("listing players: %w", err)
+	}
+	players = slices.DeleteFunc(players, store.Player.Archived)
+
+	var last snapshot
+	if snap, loadErr := p.archive.LoadSnapshot(ctx, SnapshotID); loadErr == nil {
+		if err := json.Unmarshal(snap.State, &last); err != nil {
+			return Board{}, fmt.Errorf("decoding leaderboard snapshot: %w", err)
+		}
+	}
+	previous := last.Standings
+	since := last.TakenAt
+	if since.IsZero() {
+		since = now.Add(-Week)
+		changes, err := p.events.Query(ctx, event.Query{
+			Types: []event.Type{event.DKPAwarded, event.DKPDeducted, event.DKPAdjusted},
+			Since: since,
+		})
+		if err != nil {
+			return Board{}, fmt.Errorf("querying DKP events: %w", err)
+		}
+		if previous, err = Rewind(players, changes, since); err != nil {
+			return Board{}, err
+		}
+	}
+
+	awards, err := p.events.Query(ctx, event.Query{
+		Types: []event.Type{event.DKPAwarded},
+		Since: now.Add(-streakWeeks * Week),
+	})
+	if err != nil {
+		return Board{}, fmt.Errorf("querying DKP awards: %w", err)
+	}
+	streaks, err := Streaks(awards, now)
+	if err != nil {
+		return Board{}, err
+	}
+
+	return Build(Rank(players), previous, streaks, since), nil
+}
@@ -0,0 +1,336 @@
+// Package merge imports the members and balances of another guild when two
+// guilds merge. A merge is prepared as a plan that officers review, and
+// decide which characters named like a registered player are that player,
+// before it is applied. Every imported balance is recorded as a
+// dkp.adjusted event carrying the merge ID, so that the events of a merge
+// can be told apart and audited.
+package merge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// PlaceholderPrefix prefixes the Discord ID given to imported characters
+// without a Discord ID of their own.
+const PlaceholderPrefix = "merge:"
+
+// Reason is the reason recorded on the adjustments of a merge.
+const Reason = "guild merge"
+
+// planTTL is how long a prepared plan waits to be applied.
+const planTTL = time.Hour
+
+// Errors returned by Merger.
+var (
+	ErrInvalidRatio = derrors.New(derrors.Validation, "INVALID_RATIO", "the conversion ratio must be a positive number")
+	ErrNoMembers    = derrors.New(derrors.Validation, "NO_MEMBERS", "the file lists no characters")
+	ErrPlanNotFound = derrors.New(derrors.NotFound, "MERGE_NOT_FOUND", "this merge has expired or was already applied or canceled; run /guild-merge import again")
+)
+
+// Entry is a member of the other guild and how they are imported.
+type Entry struct {
+	Member
+	// Amount is the member's balance converted by the plan's ratio.
+	Amount int
+	// Target is the registered player the member is merged into, or nil
+	// if they are imported as a new player.
+	Target *store.Player
+	// Duplicate reports that Target was matched by character name only, so
+	// that the member may be a different player; Separate then imports
+	// them as a new player instead.
+	Duplicate bool
+	Separate  bool
+}
+
+// Merged reports whether the entry is merged into a registered player.
+func (e Entry) Merged() bool {
+	return e.Target != nil && !e.Separate
+}
+
+// Plan is a prepared merge.
+type Plan struct {
+	// ID correlates the events the merge records.
+	ID      string
+	Ratio   float64
+	Entries []Entry
+	created time.Time
+}
+
+// Duplicates returns the indexes of the entries matched by name only.
+func (p *Plan) Duplicates() []int {
+	var dups []int
+	for i, e := range p.Entries {
+		if e.Duplicate {
+			dups = append(dups, i)
+		}
+	}
+	return dups
+}
+
+// Total returns the DKP the plan imports.
+func (p *Plan) Total() int {
+	total := 0
+	for _, e := range p.Entries {
+		total += e.Amount
+	}
+	return total
+}
+
+func (p *Plan) clone() *Plan {
+	c := *p
+	c.Entries = slices.Clone(p.Entries)
+	return &c
+}
+
+// Report summarizes an applied merge.
+type Report struct {
+	Merged  int
+	Created int
+	DKP     int
+	// Skipped lists the characters not imported because the player they
+	// were merged into is archived.
+	Skipped []string
+}
+
+// Merger prepares and applies merges. Prepared plans are held in memory
+// until they are applied, canceled, or expire.
+type Merger struct {
+	players store.PlayerRepository
+	events  event.Store
+	logger  *slog.Logger
+	tracer  trace.Tracer
+	clock   clock.Clock
+
+	mu    sync.Mutex
+	plans map[string]*Plan
+}
+
+// NewMerger returns a new Merger.
+func NewMerger(players store.PlayerRepository, events event.Store, logger *slog.Logger, tp trace.TracerProvider, clk clock.Clock) *Merger {
+	return &Merger{
+		players: players,
+		events:  events,
+		logger:  logger,
+		tracer:  tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/merge"),
+		clock:   clk,
+		plans:   make(map[string]*Plan),
+	}
+}
+
+// Prepare plans the import of members, whose balances are multiplied by
+// ratio and rounded. A member is merged into the registered player with
+// their Discord ID or, failing that, their character name; others become
+// new players.
+func (m *Merger) Prepare(ctx context.Context, members []Member, ratio float64) (*Plan, error) {
+	ctx, span := m.tracer.Start(ctx, "Merger.Prepare",
+		trace.WithAttributes(
+			attribute.Int("members", len(members)),
+			attribute.Float64("ratio", ratio),
+		),
+	)
+	defer span.End()
+
+	if !(ratio > 0) || math.IsInf(ratio, 0) {
+		return nil, ErrInvalidRatio
+	}
+	if len(members) == 0 {
+		return nil, ErrNoMembers
+	}
+	registered, err := m.players.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing players: %w", err)
+	}
+	byDiscordID := make(map[string]*store.Player, len(registered))
+	byName := make(map[string]*store.Player, len(registered))
+	for i := range registered {
+		p := &registered[i]
+		byDiscordID[p.DiscordID] = p
+		if _, ok := byName[strings.ToLower(p.CharacterName)]; !ok {
+			byName[strings.ToLower(p.CharacterName)] = p
+		}
+	}
+
+	now := m.clock.Now()
+	plan := &Plan{
+		ID:      fmt.Sprintf("merge-%d", now.UnixNano()),
+		Ratio:   ratio,
+		Entries: make([]Entry, len(members)),
+		created: now,
+	}
+	for i, mem := range members {
+		e := Entry{Member: mem, Amount: int(math.Round(float64(mem.DKP) * ratio))}
+		if p, ok := byDiscordID[mem.DiscordID]; ok && mem.DiscordID != "" {
+			e.Target = p
+		} else if p, ok := byName[strings.ToLower(mem.Name)]; ok {
+			e.Target, e.Duplicate = p, true
+		}
+		plan.Entries[i] = e
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(now)
+	m.plans[plan.ID] = plan
+	return plan.clone(), nil
+}
+
+// Plan returns the prepared plan id.
+func (m *Merger) Plan(id string) (*Plan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(m.clock.Now())
+	p, ok := m.plans[id]
+	if !ok {
+		return nil, ErrPlanNotFound
+	}
+	return p.clone(), nil
+}
+
+// Separate decides, for each of the duplicates at indexes, whether it is
+// imported as a new player: those also in separate are, the others are
+// merged. It returns the updated plan.
+func (m *Merger) Separate(id string, indexes, separate []int) (*Plan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(m.clock.Now())
+	p, ok := m.plans[id]
+	if !ok {
+		return nil, ErrPlanNotFound
+	}
+	for _, i := range indexes {
+		if i >= 0 && i < len(p.Entries) && p.Entries[i].Duplicate {
+			p.Entries[i].Separate = slices.Contains(separate, i)
+		}
+	}
+	return p.clone(), nil
+}
+
+// Cancel discards the prepared plan id and reports whether there was one.
+func (m *Merger) Cancel(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.plans[id]
+	delete(m.plans, id)
+	return ok
+}
+
+// expire discards the plans prepared more than planTTL before now. The
+// caller must hold m.mu.
+func (m *Merger) expire(now time.Time) {
+	for id, p := range m.plans {
+		if now.Sub(p.created) > planTTL {
+			delete(m.plans, id)
+		}
+	}
+}
+
+// Apply applies the prepared plan id, which can only be applied once. The
+// actor of ctx is recorded on its events. If it fails part way, the report
+// covers the entries applied so far.
+func (m *Merger) Apply(ctx context.Context, id string) (Report, error) {
+	ctx, span := m.tracer.Start(ctx, "Merger.Apply",
+		trace.WithAttributes(attribute.String("merge_id", id)),
+	)
+	defer span.End()
+
+	m.mu.Lock()
+	m.expire(m.clock.Now())
+	plan, ok := m.plans[id]
+	delete(m.plans, id)
+	m.mu.Unlock()
+	if !ok {
+		return Report{}, ErrPlanNotFound
+	}
+
+	var report Report
+	for i, e := range plan.Entries {
+		if !e.Merged() {
+			if err := m.create(ctx, plan, i, e); err != nil {
+				return report, fmt.Errorf("importing %s: %w", e.Name, err)
+			}
+			report.Created++
+			report.DKP += e.Amount
+			continue
+		}
+		switch err := m.credit(ctx, plan, e, e.Target.ID); {
+		case errors.Is(err, store.ErrPlayerArchived):
+			report.Skipped = append(report.Skipped, e.Name)
+			continue
+		case err != nil:
+			return report, fmt.Errorf("importing %s: %w", e.Name, err)
+		}
+		report.Merged++
+		report.DKP += e.Amount
+	}
+
+	m.logger.InfoContext(ctx, "guild merge applied",
+		slog.String("merge_id", plan.ID),
+		slog.Int("merged", report.Merged),
+		slog.Int("created", report.Created),
+		slog.Int("dkp", report.DKP),
+	)
+	return report, nil
+}
+
+// create registers the member of the entry at index i of plan as a new
+// player and credits them.
+func (m *Merger) create(ctx context.Context, plan *Plan, i int, e Entry) error {
+	p := &store.Player{DiscordID: e.DiscordID, CharacterName: e.Name}
+	var err error = store.ErrPlayerExists
+	if p.DiscordID != "" {
+		err = m.players.Create(ctx, p)
+	}
+	// A member registered since the plan was prepared, or one without a
+	// Discord ID, gets a placeholder.
+	if errors.Is(err, store.ErrPlayerExists) {
+		p.DiscordID = PlaceholderPrefix + plan.ID + ":" + strconv.Itoa(i)
+		err = m.players.Create(ctx, p)
+	}
+	if err != nil {
+		return fmt.Errorf("creating player: %w", err)
+	}
+
+	data, _ := json.Marshal(event.PlayerRegisteredData{DiscordID: p.DiscordID, CharacterName: p.CharacterName})
+	if err := m.events.Append(ctx, event.Event{AggregateID: p.ID, Type: event.PlayerRegistered, Data: data}); err != nil {
+		m.logger.ErrorContext(ctx, "failed to append player registered event", slog.Any("error", err))
+	}
+	return m.credit(ctx, plan, e, p.ID)
+}
+
+// credit adds the amount of the entry to the player playerID.
+func (m *Merger) credit(ctx context.Context, plan *Plan, e Entry, playerID string) error {
+	if e.Amount == 0 {
+		return nil
+	}
+	if err := m.players.UpdateDKP(ctx, playerID, e.Amount); err != nil {
+		return err
+	}
+	data, _ := json.Marshal(event.DKPChangeData{
+		PlayerID: playerID,
+		Amount:   e.Amount,
+		Reason:   Reason,
+		Merge:    &event.DKPMerge{ID: plan.ID, Character: e.Name, Balance: e.DKP, Ratio: plan.Ratio},
+	})
+	if err := m.events.Append(ctx, event.Event{AggregateID: playerID, Type: event.DKPAdjusted, Data: data}); err != nil {
+		m.logger.ErrorContext(ctx, "failed to append guild merge adjustment", slog.Any("error", err))
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package merge_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/eventio"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/merge"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+func TestParse_CSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []merge.Member
+		wantErr bool
+	}{
+		{
+			name: "standings export",
+			in:   "rank,character_name,discord_id,player_id,dkp\n1,Frodo,111,p1,120\n2,Sam,eqdkp:7,p2,-5\n",
+			want: []merge.Member{{Name: "Frodo", DiscordID: "111", DKP: 120}, {Name: "Sam", DKP: -5}},
+		},
+		{
+			name: "spreadsheet with a byte order mark",
+			in:   "\xef\xbb\xbfName, Balance\nMerry, 40\n,\n",
+			want: []merge.Member{{Name: "Merry", DKP: 40}},
+		},
+		{name: "no dkp column", in: "name,class\nFrodo,Rogue\n", wantErr: true},
+		{name: "invalid dkp", in: "name,dkp\nFrodo,lots\n", wantErr: true},
+		{name: "empty", in: "\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := merge.Parse(strings.NewReader(tt.in))
+			if tt.wantErr {
+				if !errors.Is(err, merge.ErrInvalidFile) {
+					t.Errorf("Parse() error = %v, want ErrInvalidFile", err)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("Parse() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Events(t *testing.T) {
+	ctx := context.Background()
+	es := eventtest.NewStore(eventtest.WithClock(clock.Real{}))
+	appendData := func(aggregateID string, typ event.Type, data any) {
+		raw, _ := json.Marshal(data)
+		if err := es.Append(ctx, event.Event{AggregateID: aggregateID, Type: typ, Data: raw}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	appendData("p1", event.PlayerRegistered, event.PlayerRegisteredData{DiscordID: "111", CharacterName: "Frodo"})
+	appendData("p2", event.PlayerRegistered, event.PlayerRegisteredData{DiscordID: "eqdkp:2", CharacterName: "Sam"})
+	appendData("p1", event.DKPAwarded, event.DKPChangeData{PlayerID: "p1", Amount: 100})
+	appendData("p1", event.DKPDeducted, event.DKPChangeData{PlayerID: "p1", Amount: -30})
+	appendData("p2", event.DKPAdjusted, event.DKPChangeData{PlayerID: "p2", Amount: 15})
+	appendData("auction-1", event.AuctionStarted, event.AuctionStartedData{ItemName: "Sword"})
+
+	var buf bytes.Buffer
+	if _, err := eventio.Export(ctx, es, &buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	got, err := merge.Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := []merge.Member{{Name: "Frodo", DiscordID: "111", DKP: 70}, {Name: "Sam", DKP: 15}}
+	if !slices.Equal(got, want) {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMerger(t *testing.T) {
+	archivedAt := time.Now()
+	players := storetest.NewPlayers(
+		store.Player{ID: "p1", DiscordID: "111", CharacterName: "Frodo", DKP: 100},
+		store.Player{ID: "p2", DiscordID: "222", CharacterName: "Sam", DKP: 50},
+		store.Player{ID: "p3", DiscordID: "333", CharacterName: "Merry", DKP: 10, ArchivedAt: &archivedAt},
+	)
+	es := eventtest.NewStore()
+	m := merge.NewMerger(players, es, slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clock.Real{})
+	ctx := event.WithActor(context.Background(), "officer")
+
+	if _, err := m.Prepare(ctx, []merge.Member{{Name: "Frodo"}}, 0); !errors.Is(err, merge.ErrInvalidRatio) {
+		t.Errorf("Prepare() with ratio 0 error = %v, want ErrInvalidRatio", err)
+	}
+	plan, err := m.Prepare(ctx, []merge.Member{
+		{Name: "Frodo Baggins", DiscordID: "111", DKP: 41}, // the same member, by Discord ID
+		{Name: "sam", DKP: 20},                             // named like Sam
+		{Name: "Frodo", DKP: 30},                           // named like Frodo, but another player
+		{Name: "Merry", DKP: 8},                            // merged into an archived player
+		{Name: "Pippin", DiscordID: "444", DKP: 60},
+		{Name: "Bilbo", DKP: 0},
+	}, 0.5)
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if got := plan.Duplicates(); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Duplicates() = %v, want [1 2 3]", got)
+	}
+	if plan.Total() != 21+10+15+4+30 {
+		t.Errorf("Total() = %d, want 80", plan.Total())
+	}
+
+	if _, err := m.Separate(plan.ID, []int{1, 2}, []int{2}); err != nil {
+		t.Fatalf("Separate() error = %v", err)
+	}
+	report, err := m.Apply(ctx, plan.ID)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if report.Merged != 2 || report.Created != 3 || report.DKP != 21+10+15+30 || !slices.Equal(report.Skipped, []string{"Merry"}) {
+		t.Errorf("Apply() = %+v, want 2 merged, 3 created, 76 DKP, and Merry skipped", report)
+	}
+	players.RequireDKP(t, "111", 121)
+	players.RequireDKP(t, "222", 60)
+	players.RequireDKP(t, "333", 10)
+	players.RequireDKP(t, "444", 30)
+
+	var merged int
+	for _, e := range es.Events() {
+		if e.Type != event.DKPAdjusted {
+			continue
+		}
+		d := eventtest.Data[event.DKPChangeData](t, e)
+		if d.Merge == nil || d.Merge.ID != plan.ID || d.Merge.Ratio != 0.5 || d.Reason != merge.Reason {
+			t.Errorf("adjustment = %+v, want one recording merge %s", d, plan.ID)
+		}
+		merged++
+	}
+	// Bilbo, with no DKP, is registered without an adjustment.
+	if merged != 4 {
+		t.Errorf("adjustments = %d, want 4", merged)
+	}
+	list, _ := players.List(ctx)
+	var placeholders int
+	for _, p := range list {
+		if strings.HasPrefix(p.DiscordID, merge.PlaceholderPrefix+plan.ID) {
+			placeholders++
+		}
+	}
+	if placeholders != 2 {
+		t.Errorf("players with a placeholder Discord ID = %d, want Frodo and Bilbo", placeholders)
+	}
+
+	if _, err := m.Apply(ctx, plan.ID); !errors.Is(err, merge.ErrPlanNotFound) {
+		t.Errorf("second Apply() error = %v, want ErrPlanNotFound", err)
+	}
+}
@@ -0,0 +1,178 @@
+package merge
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/eventio"
+)
+
+// ErrInvalidFile is returned by Parse for data it cannot read.
+var ErrInvalidFile = derrors.New(derrors.Validation, "INVALID_MERGE_FILE", "the file is not a standings CSV or an event log export")
+
+// Member is a character of the other guild.
+type Member struct {
+	Name string
+	// DiscordID is the member's Discord user ID, or empty if the data has
+	// none.
+	DiscordID string
+	DKP       int
+}
+
+// Column names accepted in a CSV header, lowercased. The first of each
+// are those of /dkp-export standings.
+var (
+	nameColumns    = []string{"character_name", "character", "name"}
+	dkpColumns     = []string{"dkp", "balance"}
+	discordColumns = []string{"discord_id"}
+)
+
+// Parse reads the members of another guild from r, which holds either a
+// CSV file with a header row, such as /dkp-export standings writes, or an
+// event log written by `dkpbot export events`. A CSV file needs a
+// character name and a DKP column and may have a Discord ID column; the
+// balances of an event log are the sums of each player's DKP changes.
+func Parse(r io.Reader) ([]Member, error) {
+	br := bufio.NewReader(r)
+	// Spreadsheets often save CSV files with a byte order mark.
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		_, _ = br.Discard(3)
+	}
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return nil, ErrInvalidFile.Wrap(errors.New("the file is empty"))
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = br.Discard(1)
+		case '{':
+			return parseEvents(br)
+		default:
+			return parseCSV(br)
+		}
+	}
+}
+
+func parseCSV(r io.Reader) ([]Member, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, ErrInvalidFile.Wrap(fmt.Errorf("reading header: %w", err))
+	}
+	for i, h := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(h))
+	}
+	nameCol, dkpCol, discordCol := column(header, nameColumns), column(header, dkpColumns), column(header, discordColumns)
+	if nameCol < 0 || dkpCol < 0 {
+		return nil, ErrInvalidFile.Wrap(fmt.Errorf("the header needs a %s and a %s column", nameColumns[0], dkpColumns[0]))
+	}
+
+	var members []Member
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, ErrInvalidFile.Wrap(fmt.Errorf("line %d: %w", line, err))
+		}
+		m := Member{Name: field(rec, nameCol)}
+		if m.Name == "" {
+			continue
+		}
+		if m.DKP, err = strconv.Atoi(field(rec, dkpCol)); err != nil {
+			return nil, ErrInvalidFile.Wrap(fmt.Errorf("line %d: invalid DKP %q", line, field(rec, dkpCol)))
+		}
+		if id := field(rec, discordCol); snowflake(id) {
+			m.DiscordID = id
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// column returns the index of the first of names in header, or -1.
+func column(header, names []string) int {
+	for _, name := range names {
+		for i, h := range header {
+			if h == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// field returns the trimmed field i of rec, or "" if it has none.
+func field(rec []string, i int) string {
+	if i < 0 || i >= len(rec) {
+		return ""
+	}
+	return strings.TrimSpace(rec[i])
+}
+
+func parseEvents(r io.Reader) ([]Member, error) {
+	events, err := eventio.Read(r)
+	if err != nil {
+		return nil, ErrInvalidFile.Wrap(err)
+	}
+	var order []string
+	players := make(map[string]*Member)
+	sums := make(map[string]int)
+	for _, e := range events {
+		switch e.Type {
+		case event.PlayerRegistered:
+			var d event.PlayerRegisteredData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, ErrInvalidFile.Wrap(fmt.Errorf("decoding event %s: %w", e.ID, err))
+			}
+			if _, ok := players[e.AggregateID]; !ok {
+				order = append(order, e.AggregateID)
+			}
+			m := &Member{Name: d.CharacterName}
+			if snowflake(d.DiscordID) {
+				m.DiscordID = d.DiscordID
+			}
+			players[e.AggregateID] = m
+		case event.DKPAwarded, event.DKPDeducted, event.DKPAdjusted:
+			var d event.DKPChangeData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, ErrInvalidFile.Wrap(fmt.Errorf("decoding event %s: %w", e.ID, err))
+			}
+			sums[e.AggregateID] += d.Amount
+		}
+	}
+
+	members := make([]Member, 0, len(order))
+	for _, id := range order {
+		m := *players[id]
+		m.DKP = sums[id]
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// snowflake reports whether s looks like a Discord ID. Placeholder IDs,
+// such as those of imported characters nobody registered, do not.
+func snowflake(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package merge
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Actions prefixing the custom IDs of a preview's components, which are
+// followed by ":" and the merge ID. Those of SeparateAction are followed by
+// ":" and the page of duplicates the select menu covers.
+const (
+	SeparateAction = "guild-merge-separate"
+	ApplyAction    = "guild-merge-apply"
+	CancelAction   = "guild-merge-cancel"
+)
+
+// Discord allows five rows of components: the select menus of duplicates
+// take up to four, with 25 options each, and the buttons the last.
+const (
+	maxMenus   = 4
+	maxOptions = 25
+)
+
+// maxListed is how many duplicates a preview names.
+const maxListed = 20
+
+// Page returns the indexes of the duplicates of p the select menu page
+// covers.
+func Page(p *Plan, page int) []int {
+	dups := p.Duplicates()
+	if page < 0 || page*maxOptions >= len(dups) {
+		return nil
+	}
+	return dups[page*maxOptions : min((page+1)*maxOptions, len(dups))]
+}
+
+// Preview describes plan for officers to review. Characters named like a
+// registered player are merged into them unless selected in its menus; its
+// buttons apply or cancel the merge.
+func Preview(plan *Plan) *discordgo.MessageSend {
+	var before, byID, created int
+	for _, e := range plan.Entries {
+		before += e.DKP
+		switch {
+		case e.Target == nil:
+			created++
+		case !e.Duplicate:
+			byID++
+		}
+	}
+	dups := plan.Duplicates()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d characters with **%d DKP**, imported as **%d DKP** at a ratio of %s.\n",
+		len(plan.Entries), before, plan.Total(), strconv.FormatFloat(plan.Ratio, 'f', -1, 64))
+	fmt.Fprintf(&b, "%d merged into the player with their Discord ID, %d imported as new players.\n", byID, created)
+	if len(dups) > 0 {
+		fmt.Fprintf(&b, "\n%d characters are named like a registered player and are merged into them unless selected below, which imports them as new players instead:\n", len(dups))
+		for n, i := range dups {
+			if n == maxListed {
+				fmt.Fprintf(&b, "…and %d more\n", len(dups)-n)
+				break
+			}
+			e := plan.Entries[i]
+			how := "merged into"
+			if e.Separate {
+				how = "separate from"
+			}
+			fmt.Fprintf(&b, "**%s** (%+d DKP), %s <@%s>\n", e.Name, e.Amount, how, e.Target.DiscordID)
+		}
+		if len(dups) > maxMenus*maxOptions {
+			fmt.Fprintf(&b, "Only the first %d can be selected; the others are merged.\n", maxMenus*maxOptions)
+		}
+	}
+
+	var rows []discordgo.MessageComponent
+	for page := 0; page < maxMenus && page*maxOptions < len(dups); page++ {
+		indexes := Page(plan, page)
+		options := make([]discordgo.SelectMenuOption, 0, len(indexes))
+		for _, i := range indexes {
+			e := plan.Entries[i]
+			options = append(options, discordgo.SelectMenuOption{
+				Label:       truncate(e.Name),
+				Value:       strconv.Itoa(i),
+				Description: truncate(fmt.Sprintf("%+d DKP; not the registered %s", e.Amount, e.Target.CharacterName)),
+				Default:     e.Separate,
+			})
+		}
+		none := 0
+		rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				MenuType:    discordgo.StringSelectMenu,
+				CustomID:    fmt.Sprintf("%s:%s:%d", SeparateAction, plan.ID, page),
+				Placeholder: "Import as new players",
+				MinValues:   &none,
+				MaxValues:   len(options),
+				Options:     options,
+			},
+		}})
+	}
+	rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		discordgo.Button{Label: "Apply merge", Style: discordgo.SuccessButton, CustomID: ApplyAction + ":" + plan.ID},
+		discordgo.Button{Label: "Cancel", Style: discordgo.SecondaryButton, CustomID: CancelAction + ":" + plan.ID},
+	}})
+
+	return &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{{
+			Title:       "Guild merge preview",
+			Description: b.String(),
+		}},
+		Components: rows,
+	}
+}
+
+// truncate shortens s to the 100 characters a select menu option allows.
+func truncate(s string) string {
+	if r := []rune(s); len(r) > 100 {
+		return string(r[:99]) + "…"
+	}
+	return s
+}
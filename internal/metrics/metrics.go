@@ -0,0 +1,347 @@
+// Package metrics defines the bot's domain instruments: command handling,
+// auction lifecycle, and DKP flow. Every measurement is tagged with the
+// Discord guild it belongs to.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// Attribute keys shared by the instruments.
+const (
+	GuildKey   = attribute.Key("guild.id")
+	CommandKey = attribute.Key("command")
+	OutcomeKey = attribute.Key("outcome")
+	// MidAuctionKey marks gateway disconnects that happened while an
+	// auction was open.
+	MidAuctionKey = attribute.Key("auction.open")
+	// IdentityKey is the leader election identity of the replica.
+	IdentityKey = attribute.Key("leader.identity")
+	// DiscrepancyKey is how an auction's row disagreed with its events.
+	DiscrepancyKey = attribute.Key("discrepancy")
+	// SubscriberKey names a subscriber of the event bus.
+	SubscriberKey = attribute.Key("bus.subscriber")
+)
+
+// Command outcomes.
+const (
+	OutcomeOK    = "ok"
+	OutcomeError = "error"
+)
+
+// Auction outcomes recorded on the duration histogram.
+const (
+	OutcomeClosed   = "closed"
+	OutcomeCanceled = "canceled"
+	// OutcomeBoughtOut marks auctions a player won at the buyout price.
+	OutcomeBoughtOut = "bought_out"
+)
+
+type guildKey struct{}
+
+// WithGuild returns a copy of ctx that attributes measurements to guildID.
+func WithGuild(ctx context.Context, guildID string) context.Context {
+	return context.WithValue(ctx, guildKey{}, guildID)
+}
+
+// Recorder records domain metrics. A nil *Recorder is not valid; use Nop
+// when metrics are not wanted.
+type Recorder struct {
+	guild string
+
+	commands        metric.Int64Counter
+	commandDuration metric.Float64Histogram
+	commandPanics   metric.Int64Counter
+	commandTimeouts metric.Int64Counter
+	commandUsers    metric.Int64Counter
+	bids            metric.Int64Counter
+	bidsThrottled   metric.Int64Counter
+	bidQueueDepth   metric.Int64Histogram
+	bidQueueWait    metric.Float64Histogram
+	auctionsOpened  metric.Int64Counter
+	auctionsClosed  metric.Int64Counter
+	auctionsCancel  metric.Int64Counter
+	auctionDuration metric.Float64Histogram
+	discrepancies   metric.Int64Counter
+	dkpAwarded      metric.Int64Counter
+	dkpDeducted     metric.Int64Counter
+	disconnects     metric.Int64Counter
+	reconnects      metric.Int64Counter
+	downtime        metric.Float64Histogram
+	heartbeat       metric.Float64Histogram
+	leader          metric.Int64Gauge
+	busQueueDepth   metric.Int64Gauge
+	busDropped      metric.Int64Counter
+	busSpilled      metric.Int64Counter
+}
+
+// New creates the instruments on mp. Measurements whose context carries no
+// guild (see WithGuild) are attributed to defaultGuild.
+func New(mp metric.MeterProvider, defaultGuild string) (*Recorder, error) {
+	m := mp.Meter("github.com/jensholdgaard/discord-dkp-bot/internal/metrics")
+	r := &Recorder{guild: defaultGuild}
+
+	var err, e error
+	r.commands, e = m.Int64Counter("dkpbot.commands",
+		metric.WithDescription("Slash commands handled, by command and outcome."),
+		metric.WithUnit("{command}"))
+	err = errors.Join(err, e)
+	r.commandDuration, e = m.Float64Histogram("dkpbot.command.duration",
+		metric.WithDescription("Time taken to handle a slash command."),
+		metric.WithUnit("s"))
+	err = errors.Join(err, e)
+	r.commandPanics, e = m.Int64Counter("dkpbot.command.panics",
+		metric.WithDescription("Slash command handlers that panicked, by command."),
+		metric.WithUnit("{panic}"))
+	err = errors.Join(err, e)
+	r.commandTimeouts, e = m.Int64Counter("dkpbot.command.timeouts",
+		metric.WithDescription("Commands that failed because their deadline passed, by command."),
+		metric.WithUnit("{timeout}"))
+	err = errors.Join(err, e)
+	r.commandUsers, e = m.Int64Counter("dkpbot.command.users",
+		metric.WithDescription("Members using a command for the first time in a day (UTC), by command."),
+		metric.WithUnit("{user}"))
+	err = errors.Join(err, e)
+	r.bids, e = m.Int64Counter("dkpbot.bids",
+		metric.WithDescription("Bids accepted on auctions."),
+		metric.WithUnit("{bid}"))
+	err = errors.Join(err, e)
+	r.bidsThrottled, e = m.Int64Counter("dkpbot.bids.throttled",
+		metric.WithDescription("Bids rejected because the bidder's cooldown had not ended."),
+		metric.WithUnit("{bid}"))
+	err = errors.Join(err, e)
+	r.bidQueueDepth, e = m.Int64Histogram("dkpbot.bid_queue.depth",
+		metric.WithDescription("Bids queued on an auction as a bid joins its queue, that bid and any being placed included."),
+		metric.WithUnit("{bid}"),
+		metric.WithExplicitBucketBoundaries(1, 2, 4, 8, 16, 32, 64, 128))
+	err = errors.Join(err, e)
+	r.bidQueueWait, e = m.Float64Histogram("dkpbot.bid_queue.wait",
+		metric.WithDescription("Time a bid waited in its auction's queue for its turn."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5))
+	err = errors.Join(err, e)
+	r.auctionsOpened, e = m.Int64Counter("dkpbot.auctions.opened",
+		metric.WithDescription("Auctions started."),
+		metric.WithUnit("{auction}"))
+	err = errors.Join(err, e)
+	r.auctionsClosed, e = m.Int64Counter("dkpbot.auctions.closed",
+		metric.WithDescription("Auctions closed."),
+		metric.WithUnit("{auction}"))
+	err = errors.Join(err, e)
+	r.auctionsCancel, e = m.Int64Counter("dkpbot.auctions.canceled",
+		metric.WithDescription("Auctions canceled."),
+		metric.WithUnit("{auction}"))
+	err = errors.Join(err, e)
+	r.auctionDuration, e = m.Float64Histogram("dkpbot.auction.duration",
+		metric.WithDescription("Time from auction start to close or cancellation."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(30, 60, 120, 300, 600, 900, 1800, 3600))
+	err = errors.Join(err, e)
+	r.discrepancies, e = m.Int64Counter("dkpbot.auctions.discrepancies",
+		metric.WithDescription("Auctions whose row in the auctions table disagreed with the event log, by discrepancy."),
+		metric.WithUnit("{auction}"))
+	err = errors.Join(err, e)
+	r.dkpAwarded, e = m.Int64Counter("dkpbot.dkp.awarded",
+		metric.WithDescription("DKP awarded to players."),
+		metric.WithUnit("{dkp}"))
+	err = errors.Join(err, e)
+	r.dkpDeducted, e = m.Int64Counter("dkpbot.dkp.deducted",
+		metric.WithDescription("DKP deducted from players."),
+		metric.WithUnit("{dkp}"))
+	err = errors.Join(err, e)
+	r.disconnects, e = m.Int64Counter("dkpbot.gateway.disconnects",
+		metric.WithDescription("Discord gateway disconnects, by whether an auction was open."),
+		metric.WithUnit("{disconnect}"))
+	err = errors.Join(err, e)
+	r.reconnects, e = m.Int64Counter("dkpbot.gateway.reconnects",
+		metric.WithDescription("Successful Discord gateway reconnections."),
+		metric.WithUnit("{reconnect}"))
+	err = errors.Join(err, e)
+	r.downtime, e = m.Float64Histogram("dkpbot.gateway.downtime",
+		metric.WithDescription("Time from a gateway disconnect to the reconnection."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(1, 5, 15, 30, 60, 120, 300, 600))
+	err = errors.Join(err, e)
+	r.heartbeat, e = m.Float64Histogram("dkpbot.gateway.heartbeat_latency",
+		metric.WithDescription("Round trip time of Discord gateway heartbeats."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10))
+	err = errors.Join(err, e)
+	r.leader, e = m.Int64Gauge("dkpbot.leader",
+		metric.WithDescription("Whether this replica holds the leader lock (1) or not (0), by identity."),
+		metric.WithUnit("1"))
+	err = errors.Join(err, e)
+	r.busQueueDepth, e = m.Int64Gauge("dkpbot.bus.queue.depth",
+		metric.WithDescription("Events waiting for an event bus subscriber, spilled ones included, by subscriber."),
+		metric.WithUnit("{event}"))
+	err = errors.Join(err, e)
+	r.busDropped, e = m.Int64Counter("dkpbot.bus.dropped",
+		metric.WithDescription("Events an event bus subscriber's full queue dropped, by subscriber."),
+		metric.WithUnit("{event}"))
+	err = errors.Join(err, e)
+	r.busSpilled, e = m.Int64Counter("dkpbot.bus.spilled",
+		metric.WithDescription("Events an event bus subscriber's full queue spilled to disk, by subscriber."),
+		metric.WithUnit("{event}"))
+	err = errors.Join(err, e)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Nop returns a Recorder that discards all measurements.
+func Nop() *Recorder {
+	r, _ := New(noop.NewMeterProvider(), "")
+	return r
+}
+
+// CommandHandled records a handled slash command. A non-nil err marks the
+// outcome as an error.
+func (r *Recorder) CommandHandled(ctx context.Context, command string, err error, d time.Duration) {
+	outcome := OutcomeOK
+	if err != nil {
+		outcome = OutcomeError
+	}
+	attrs := metric.WithAttributes(r.guildAttr(ctx), CommandKey.String(command), OutcomeKey.String(outcome))
+	r.commands.Add(ctx, 1, attrs)
+	r.commandDuration.Record(ctx, d.Seconds(), attrs)
+}
+
+// CommandPanicked records a slash command handler that panicked. The
+// command is still recorded by CommandHandled as an error.
+func (r *Recorder) CommandPanicked(ctx context.Context, command string) {
+	r.commandPanics.Add(ctx, 1, metric.WithAttributes(r.guildAttr(ctx), CommandKey.String(command)))
+}
+
+// CommandTimedOut records a command that failed because its deadline
+// passed. The command is still recorded by CommandHandled as an error.
+func (r *Recorder) CommandTimedOut(ctx context.Context, command string) {
+	r.commandTimeouts.Add(ctx, 1, metric.WithAttributes(r.guildAttr(ctx), CommandKey.String(command)))
+}
+
+// CommandUser records a member's first use of command in a day, so that
+// the counts added up over a day are the command's daily users.
+func (r *Recorder) CommandUser(ctx context.Context, command string) {
+	r.commandUsers.Add(ctx, 1, metric.WithAttributes(r.guildAttr(ctx), CommandKey.String(command)))
+}
+
+// BidPlaced records an accepted bid.
+func (r *Recorder) BidPlaced(ctx context.Context) {
+	r.bids.Add(ctx, 1, metric.WithAttributes(r.guildAttr(ctx)))
+}
+
+// BidThrottled records a bid rejected by the bidder's cooldown.
+func (r *Recorder) BidThrottled(ctx context.Context) {
+	r.bidsThrottled.Add(ctx, 1, metric.WithAttributes(r.guildAttr(ctx)))
+}
+
+// BidQueued records a bid that joined its auction's queue with depth bids
+// in it.
+func (r *Recorder) BidQueued(ctx context.Context, depth int) {
+	r.bidQueueDepth.Record(ctx, int64(depth), metric.WithAttributes(r.guildAttr(ctx)))
+}
+
+// BidDequeued records a bid that waited d in its auction's queue.
+func (r *Recorder) BidDequeued(ctx context.Context, d time.Duration) {
+	r.bidQueueWait.Record(ctx, d.Seconds(), metric.WithAttributes(r.guildAttr(ctx)))
+}
+
+// AuctionOpened records a started auction.
+func (r *Recorder) AuctionOpened(ctx context.Context) {
+	r.auctionsOpened.Add(ctx, 1, metric.WithAttributes(r.guildAttr(ctx)))
+}
+
+// AuctionClosed records a closed auction that ran for d.
+func (r *Recorder) AuctionClosed(ctx context.Context, d time.Duration) {
+	r.auctionsClosed.Add(ctx, 1, metric.WithAttributes(r.guildAttr(ctx)))
+	r.auctionDuration.Record(ctx, d.Seconds(),
+		metric.WithAttributes(r.guildAttr(ctx), OutcomeKey.String(OutcomeClosed)))
+}
+
+// AuctionBoughtOut records an auction that was bought out after running
+// for d. It counts as closed.
+func (r *Recorder) AuctionBoughtOut(ctx context.Context, d time.Duration) {
+	r.auctionsClosed.Add(ctx, 1, metric.WithAttributes(r.guildAttr(ctx)))
+	r.auctionDuration.Record(ctx, d.Seconds(),
+		metric.WithAttributes(r.guildAttr(ctx), OutcomeKey.String(OutcomeBoughtOut)))
+}
+
+// AuctionCanceled records a canceled auction that ran for d.
+func (r *Recorder) AuctionCanceled(ctx context.Context, d time.Duration) {
+	r.auctionsCancel.Add(ctx, 1, metric.WithAttributes(r.guildAttr(ctx)))
+	r.auctionDuration.Record(ctx, d.Seconds(),
+		metric.WithAttributes(r.guildAttr(ctx), OutcomeKey.String(OutcomeCanceled)))
+}
+
+// AuctionDiscrepancy records an auction whose row in the auctions table
+// disagreed with the event log in the way kind describes.
+func (r *Recorder) AuctionDiscrepancy(ctx context.Context, kind string) {
+	r.discrepancies.Add(ctx, 1, metric.WithAttributes(r.guildAttr(ctx), DiscrepancyKey.String(kind)))
+}
+
+// DKPAwarded records amount DKP awarded.
+func (r *Recorder) DKPAwarded(ctx context.Context, amount int) {
+	r.dkpAwarded.Add(ctx, int64(amount), metric.WithAttributes(r.guildAttr(ctx)))
+}
+
+// DKPDeducted records amount DKP deducted.
+func (r *Recorder) DKPDeducted(ctx context.Context, amount int) {
+	r.dkpDeducted.Add(ctx, int64(amount), metric.WithAttributes(r.guildAttr(ctx)))
+}
+
+// GatewayDisconnected records a lost gateway connection. midAuction reports
+// whether an auction was open at the time.
+func (r *Recorder) GatewayDisconnected(ctx context.Context, midAuction bool) {
+	r.disconnects.Add(ctx, 1, metric.WithAttributes(r.guildAttr(ctx), MidAuctionKey.Bool(midAuction)))
+}
+
+// GatewayReconnected records a reconnection after downtime d.
+func (r *Recorder) GatewayReconnected(ctx context.Context, d time.Duration) {
+	attrs := metric.WithAttributes(r.guildAttr(ctx))
+	r.reconnects.Add(ctx, 1, attrs)
+	r.downtime.Record(ctx, d.Seconds(), attrs)
+}
+
+// HeartbeatLatency records a sampled gateway heartbeat round trip.
+func (r *Recorder) HeartbeatLatency(ctx context.Context, d time.Duration) {
+	r.heartbeat.Record(ctx, d.Seconds(), metric.WithAttributes(r.guildAttr(ctx)))
+}
+
+// Leadership records whether the replica with the given identity currently
+// leads.
+func (r *Recorder) Leadership(ctx context.Context, identity string, leading bool) {
+	var v int64
+	if leading {
+		v = 1
+	}
+	r.leader.Record(ctx, v, metric.WithAttributes(IdentityKey.String(identity)))
+}
+
+// BusQueueDepth records how many events wait for the event bus subscriber
+// named subscriber.
+func (r *Recorder) BusQueueDepth(ctx context.Context, subscriber string, depth int) {
+	r.busQueueDepth.Record(ctx, int64(depth), metric.WithAttributes(SubscriberKey.String(subscriber)))
+}
+
+// BusEventDropped records an event the queue of subscriber dropped.
+func (r *Recorder) BusEventDropped(ctx context.Context, subscriber string) {
+	r.busDropped.Add(ctx, 1, metric.WithAttributes(SubscriberKey.String(subscriber)))
+}
+
+// BusEventSpilled records an event the queue of subscriber spilled to disk.
+func (r *Recorder) BusEventSpilled(ctx context.Context, subscriber string) {
+	r.busSpilled.Add(ctx, 1, metric.WithAttributes(SubscriberKey.String(subscriber)))
+}
+
+func (r *Recorder) guildAttr(ctx context.Context) attribute.KeyValue {
+	if g, ok := ctx.Value(guildKey{}).(string); ok && g != "" {
+		return GuildKey.String(g)
+	}
+	return GuildKey.String(r.guild)
+}
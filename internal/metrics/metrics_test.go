@@ -0,0 +1,102 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+)
+
+func collect(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Aggregation {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	got := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			got[m.Name] = m.Data
+		}
+	}
+	return got
+}
+
+func TestRecorder(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	r, err := metrics.New(mp, "default-guild")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := metrics.WithGuild(context.Background(), "guild-1")
+	r.CommandHandled(ctx, "bid", nil, 20*time.Millisecond)
+	r.CommandHandled(ctx, "bid", errors.New("too low"), 5*time.Millisecond)
+	r.CommandPanicked(ctx, "bid")
+	r.CommandTimedOut(ctx, "bid")
+	r.CommandUser(ctx, "bid")
+	r.BidPlaced(ctx)
+	r.BidThrottled(ctx)
+	r.BidQueued(ctx, 3)
+	r.BidDequeued(ctx, 20*time.Millisecond)
+	r.AuctionOpened(ctx)
+	r.AuctionClosed(ctx, 5*time.Minute)
+	r.AuctionDiscrepancy(ctx, "missing")
+	r.DKPAwarded(ctx, 50)
+	r.DKPAwarded(context.Background(), 10)
+	r.DKPDeducted(ctx, 30)
+
+	got := collect(t, reader)
+
+	commands, ok := got["dkpbot.commands"].(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("dkpbot.commands missing or wrong type: %T", got["dkpbot.commands"])
+	}
+	if len(commands.DataPoints) != 2 {
+		t.Fatalf("dkpbot.commands has %d series, want 2 (ok and error)", len(commands.DataPoints))
+	}
+	for _, dp := range commands.DataPoints {
+		if v, _ := dp.Attributes.Value(metrics.GuildKey); v.AsString() != "guild-1" {
+			t.Errorf("guild attribute = %q, want guild-1", v.AsString())
+		}
+		if v, _ := dp.Attributes.Value(metrics.CommandKey); v.AsString() != "bid" {
+			t.Errorf("command attribute = %q, want bid", v.AsString())
+		}
+	}
+
+	awarded := got["dkpbot.dkp.awarded"].(metricdata.Sum[int64])
+	byGuild := map[string]int64{}
+	for _, dp := range awarded.DataPoints {
+		v, _ := dp.Attributes.Value(metrics.GuildKey)
+		byGuild[v.AsString()] = dp.Value
+	}
+	if byGuild["guild-1"] != 50 || byGuild["default-guild"] != 10 {
+		t.Errorf("dkp awarded by guild = %v, want guild-1=50 default-guild=10", byGuild)
+	}
+
+	duration := got["dkpbot.auction.duration"].(metricdata.Histogram[float64])
+	if len(duration.DataPoints) != 1 || duration.DataPoints[0].Sum != 300 {
+		t.Errorf("auction duration = %+v, want one 300s sample", duration.DataPoints)
+	}
+	if !duration.DataPoints[0].Attributes.HasValue(metrics.OutcomeKey) {
+		t.Error("auction duration missing outcome attribute")
+	}
+
+	for _, name := range []string{"dkpbot.command.duration", "dkpbot.command.panics", "dkpbot.command.timeouts", "dkpbot.command.users", "dkpbot.bids", "dkpbot.bids.throttled", "dkpbot.bid_queue.depth", "dkpbot.bid_queue.wait", "dkpbot.auctions.opened", "dkpbot.auctions.closed", "dkpbot.auctions.discrepancies", "dkpbot.dkp.deducted"} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("%s not recorded", name)
+		}
+	}
+}
+
+func TestNop(t *testing.T) {
+	r := metrics.Nop()
+	r.CommandHandled(context.Background(), "dkp", nil, time.Second)
+	r.AuctionCanceled(context.Background(), time.Minute)
+}
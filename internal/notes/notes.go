@@ -0,0 +1,125 @@
+// Package notes keeps the notes officers write on players with
+// /player-note, such as "loot ban until March". Notes are only shown to
+// officers. A note may record a loot ban, which stops the player bidding on
+// auctions until it ends.
+package notes
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// maxTextLength is the longest note accepted, so that a player's notes
+// fit in a Discord message.
+const maxTextLength = 300
+
+// Errors returned by Service.
+var (
+	ErrInvalidNote = derrors.New(derrors.Validation, "INVALID_NOTE", fmt.Sprintf("notes must be 1 to %d characters long", maxTextLength))
+	ErrPastLootBan = derrors.New(derrors.Validation, "PAST_LOOT_BAN", "a loot ban must end in the future")
+)
+
+// Service reads and writes notes on players.
+type Service struct {
+	repo    store.PlayerNoteRepository
+	players store.PlayerRepository
+	logger  *slog.Logger
+	tracer  trace.Tracer
+	clock   clock.Clock
+}
+
+// NewService returns a Service that stores notes in repo.
+func NewService(repo store.PlayerNoteRepository, players store.PlayerRepository, logger *slog.Logger, tp trace.TracerProvider, clk clock.Clock) *Service {
+	return &Service{
+		repo:    repo,
+		players: players,
+		logger:  logger,
+		tracer:  tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/notes"),
+		clock:   clk,
+	}
+}
+
+// Add records the note text by the officer author on the player
+// registered as discordID, and returns the player and the note. A non-zero
+// lootBanUntil, which must be in the future, bans the player from loot
+// until then.
+func (s *Service) Add(ctx context.Context, discordID, author, text string, lootBanUntil time.Time) (*store.Player, *store.PlayerNote, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Add",
+		trace.WithAttributes(attribute.String("discord_id", discordID)),
+	)
+	defer span.End()
+
+	text = strings.TrimSpace(text)
+	if text == "" || len(text) > maxTextLength {
+		return nil, nil, ErrInvalidNote
+	}
+	n := &store.PlayerNote{Author: author, Text: text}
+	if !lootBanUntil.IsZero() {
+		if !lootBanUntil.After(s.clock.Now()) {
+			return nil, nil, ErrPastLootBan
+		}
+		until := lootBanUntil.UTC()
+		n.LootBanUntil = &until
+	}
+	p, err := s.players.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return nil, nil, err
+	}
+	n.PlayerID = p.ID
+	if err := s.repo.Add(ctx, n); err != nil {
+		return nil, nil, err
+	}
+	s.logger.InfoContext(ctx, "player note added",
+		slog.String("player_id", p.ID),
+		slog.String("author", author),
+		slog.Bool("loot_ban", n.LootBanUntil != nil),
+	)
+	return p, n, nil
+}
+
+// List returns the player registered as discordID and the notes on them,
+// newest first.
+func (s *Service) List(ctx context.Context, discordID string) (*store.Player, []store.PlayerNote, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.List",
+		trace.WithAttributes(attribute.String("discord_id", discordID)),
+	)
+	defer span.End()
+
+	p, err := s.players.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return nil, nil, err
+	}
+	notes, err := s.repo.ListByPlayer(ctx, p.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, notes, nil
+}
+
+// LootBan returns when the loot ban of the player playerID ends, or the
+// zero time if they are not banned. The notes are for officers only, so it
+// gives no reason.
+func (s *Service) LootBan(ctx context.Context, playerID string) (time.Time, string, error) {
+	notes, err := s.repo.ListByPlayer(ctx, playerID)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	var until time.Time
+	now := s.clock.Now()
+	for _, n := range notes {
+		if n.LootBanUntil != nil && n.LootBanUntil.After(now) && n.LootBanUntil.After(until) {
+			until = *n.LootBanUntil
+		}
+	}
+	return until, "", nil
+}
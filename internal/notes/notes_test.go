@@ -0,0 +1,93 @@
+package notes_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/notes"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+// memNotes implements store.PlayerNoteRepository in memory.
+type memNotes struct {
+	notes []store.PlayerNote
+	clock clock.Clock
+}
+
+func (r *memNotes) Add(_ context.Context, n *store.PlayerNote) error {
+	n.CreatedAt = r.clock.Now()
+	r.notes = append([]store.PlayerNote{*n}, r.notes...)
+	return nil
+}
+
+func (r *memNotes) ListByPlayer(_ context.Context, playerID string) ([]store.PlayerNote, error) {
+	var out []store.PlayerNote
+	for _, n := range r.notes {
+		if n.PlayerID == playerID {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+func TestService(t *testing.T) {
+	now := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+	clk := clock.Mock{T: now}
+	players := storetest.NewPlayers(
+		store.Player{ID: "p1", DiscordID: "d1", CharacterName: "Frodo"},
+		store.Player{ID: "p2", DiscordID: "d2", CharacterName: "Sam"},
+	)
+	svc := notes.NewService(&memNotes{clock: clk}, players, slog.Default(), noop.NewTracerProvider(), clk)
+	ctx := context.Background()
+
+	if _, _, err := svc.Add(ctx, "d1", "officer-1", "  ", time.Time{}); !errors.Is(err, notes.ErrInvalidNote) {
+		t.Errorf("Add(empty) error = %v, want ErrInvalidNote", err)
+	}
+	if _, _, err := svc.Add(ctx, "d1", "officer-1", "Banned", now.Add(-time.Hour)); !errors.Is(err, notes.ErrPastLootBan) {
+		t.Errorf("Add(past ban) error = %v, want ErrPastLootBan", err)
+	}
+	if _, _, err := svc.Add(ctx, "d9", "officer-1", "Who?", time.Time{}); !errors.Is(err, store.ErrPlayerNotFound) {
+		t.Errorf("Add(unregistered) error = %v, want ErrPlayerNotFound", err)
+	}
+
+	ban := now.Add(30 * 24 * time.Hour)
+	for _, n := range []struct {
+		text  string
+		until time.Time
+	}{
+		{"Short ban", now.Add(24 * time.Hour)},
+		{"Loot ban until July", ban},
+		{"Great tank", time.Time{}},
+	} {
+		if _, _, err := svc.Add(ctx, "d1", "officer-1", n.text, n.until); err != nil {
+			t.Fatalf("Add(%q) error = %v", n.text, err)
+		}
+	}
+
+	p, list, err := svc.List(ctx, "d1")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if p.CharacterName != "Frodo" || len(list) != 3 || list[0].Text != "Great tank" || list[0].Author != "officer-1" {
+		t.Errorf("List() = %+v, %+v, want Frodo's three notes, newest first", p, list)
+	}
+
+	// The ban ending last counts.
+	if until, reason, err := svc.LootBan(ctx, "p1"); err != nil || !until.Equal(ban) || reason != "" {
+		t.Errorf("LootBan(p1) = %v, %q, %v, want %v without a reason", until, reason, err, ban)
+	}
+	if until, _, err := svc.LootBan(ctx, "p2"); err != nil || !until.IsZero() {
+		t.Errorf("LootBan(p2) = %v, %v, want none", until, err)
+	}
+	later := notes.NewService(&memNotes{clock: clk, notes: list}, players, slog.Default(), noop.NewTracerProvider(), clock.Mock{T: ban})
+	if until, _, err := later.LootBan(ctx, "p1"); err != nil || !until.IsZero() {
+		t.Errorf("LootBan(p1) once the bans ended = %v, %v, want none", until, err)
+	}
+}
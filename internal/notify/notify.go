@@ -0,0 +1,285 @@
+// Package notify sends players Discord messages about the domain events
+// published on the event bus, such as an auction starting for an item on
+// their wishlist or their bid being outbid.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/announce"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/settings"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// Wishlist finds the players wanting an item.
+type Wishlist interface {
+	Wishers(ctx context.Context, itemName string) ([]store.Player, error)
+}
+
+// Players lists the registered players.
+type Players interface {
+	List(ctx context.Context) ([]store.Player, error)
+}
+
+// Dispatcher turns published events into Discord messages.
+type Dispatcher struct {
+	wishlist Wishlist
+	session  func() *discordgo.Session
+	logger   *slog.Logger
+	tracer   trace.Tracer
+
+	// Set by WithOutbid.
+	events   event.Store
+	players  Players
+	settings *settings.Service
+	guildID  string
+}
+
+// Option configures a Dispatcher.
+type Option func(*Dispatcher)
+
+// WithOutbid keeps the messages announcing auctions up to date with the
+// highest bid, and tells players of guildID when they are outbid, as its
+// outbid_notifications setting says. Auctions are replayed from events.
+// Without settings, outbid players are sent direct messages.
+func WithOutbid(events event.Store, players Players, svc *settings.Service, guildID string) Option {
+	return func(d *Dispatcher) {
+		d.events = events
+		d.players = players
+		d.settings = svc
+		d.guildID = guildID
+	}
+}
+
+// NewDispatcher returns a Dispatcher that tells wishers when an auction for
+// their item starts. session returns the current Discord session, or nil
+// while none is open, in which case notifications are dropped.
+func NewDispatcher(wishlist Wishlist, session func() *discordgo.Session, logger *slog.Logger, tp trace.TracerProvider, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		wishlist: wishlist,
+		session:  session,
+		logger:   logger,
+		tracer:   tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/notify"),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run dispatches the events published on bus until ctx is done. Events are
+// queued by the bus as its "notify" subscriber, as sending messages must
+// not hold up the command that appended them.
+func (d *Dispatcher) Run(ctx context.Context, bus *event.Bus) {
+	types := []event.Type{event.AuctionStarted}
+	if d.events != nil {
+		types = append(types, event.AuctionBidPlaced)
+	}
+	unsubscribe := bus.SubscribeQueue(ctx, "notify", d.Dispatch, types...)
+	defer unsubscribe()
+	<-ctx.Done()
+}
+
+// Dispatch sends the messages for e. Failures are logged.
+func (d *Dispatcher) Dispatch(ctx context.Context, e event.Event) {
+	ctx, span := d.tracer.Start(ctx, "Dispatcher.Dispatch",
+		trace.WithAttributes(
+			attribute.String("event.type", string(e.Type)),
+			attribute.String("aggregate.id", e.AggregateID),
+		),
+	)
+	defer span.End()
+
+	switch e.Type {
+	case event.AuctionStarted:
+		d.auctionStarted(ctx, e)
+	case event.AuctionBidPlaced:
+		if d.events != nil {
+			d.bidPlaced(ctx, e)
+		}
+	}
+}
+
+// auctionStarted tells the players wanting the item of the auction started
+// by e, other than the officer who started it.
+func (d *Dispatcher) auctionStarted(ctx context.Context, e event.Event) {
+	var data event.AuctionStartedData
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		d.logger.ErrorContext(ctx, "decoding auction started event", slog.String("event_id", e.ID), slog.Any("error", err))
+		return
+	}
+	wishers, err := d.wishlist.Wishers(ctx, data.ItemName)
+	if err != nil {
+		d.logger.ErrorContext(ctx, "finding wishers", slog.String("item", data.ItemName), slog.Any("error", err))
+		return
+	}
+	if len(wishers) == 0 {
+		return
+	}
+	s := d.session()
+	if s == nil {
+		d.logger.WarnContext(ctx, "no discord session, dropping wishlist notifications",
+			slog.String("item", data.ItemName),
+			slog.Int("wishers", len(wishers)),
+		)
+		return
+	}
+
+	msg := fmt.Sprintf("An auction started for **%s**, which is on your wishlist. Bid with `/bid auction-id:%s amount:<dkp>`; the minimum bid is %d.",
+		data.ItemName, e.AggregateID, data.MinBid)
+	for _, p := range wishers {
+		if p.DiscordID == data.StartedBy {
+			continue
+		}
+		if err := sendDM(ctx, s, p.DiscordID, msg); err != nil {
+			// Players may have direct messages from server members off.
+			d.logger.WarnContext(ctx, "sending wishlist notification failed",
+				slog.String("player_id", p.ID),
+				slog.Any("error", err),
+			)
+		}
+	}
+}
+
+// bidPlaced shows the bid placed by e in the messages announcing its
+// auction, and tells the player it outbid.
+func (d *Dispatcher) bidPlaced(ctx context.Context, e event.Event) {
+	var data event.BidPlacedData
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		d.logger.ErrorContext(ctx, "decoding bid placed event", slog.String("event_id", e.ID), slog.Any("error", err))
+		return
+	}
+	events, err := d.events.Load(ctx, e.AggregateID)
+	if err != nil {
+		d.logger.ErrorContext(ctx, "loading auction", slog.String("auction_id", e.AggregateID), slog.Any("error", err))
+		return
+	}
+	a, err := auction.Replay(events)
+	if err != nil {
+		d.logger.ErrorContext(ctx, "replaying auction", slog.String("auction_id", e.AggregateID), slog.Any("error", err))
+		return
+	}
+	st := a.State()
+	players, err := d.players.List(ctx)
+	if err != nil {
+		d.logger.ErrorContext(ctx, "listing players", slog.Any("error", err))
+		return
+	}
+	byID := make(map[string]store.Player, len(players))
+	for _, p := range players {
+		byID[p.ID] = p
+	}
+	s := d.session()
+	if s == nil {
+		d.logger.WarnContext(ctx, "no discord session, dropping bid notifications", slog.String("auction_id", st.ID))
+		return
+	}
+
+	leader := fmt.Sprintf("**%s** with %d %s", byID[data.PlayerID].CharacterName, data.Amount, st.Currency())
+	for _, an := range st.Announcements {
+		if err := showHighestBid(ctx, s, an, leader); err != nil {
+			d.logger.WarnContext(ctx, "updating auction announcement failed",
+				slog.String("auction_id", st.ID),
+				slog.String("message_id", an.MessageID),
+				slog.Any("error", err),
+			)
+		}
+	}
+
+	outbid, ok := byID[data.Outbid]
+	if data.Outbid == "" || data.Outbid == data.PlayerID || !ok {
+		return
+	}
+	mode, text := config.OutbidDM, ""
+	if d.settings != nil {
+		gs, err := d.settings.Get(ctx, d.guildID)
+		if err != nil {
+			d.logger.WarnContext(ctx, "loading guild settings for outbid notification failed", slog.Any("error", err))
+		} else {
+			mode, text = gs.OutbidNotifications, gs.OutbidMessage
+		}
+	}
+	if mode == config.OutbidOff {
+		return
+	}
+	msg := announce.Outbid.Render(text, announce.Vars{
+		"item":     st.ItemName,
+		"id":       st.ID,
+		"amount":   strconv.Itoa(data.Amount),
+		"currency": st.Currency(),
+	})
+	if mode == config.OutbidChannel && len(st.Announcements) > 0 {
+		_, err := s.ChannelMessageSendComplex(st.Announcements[0].ChannelID, &discordgo.MessageSend{
+			Content:         fmt.Sprintf("<@%s> %s", outbid.DiscordID, msg),
+			AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{outbid.DiscordID}},
+		}, discordgo.WithContext(ctx))
+		if err == nil {
+			return
+		}
+		d.logger.WarnContext(ctx, "posting outbid notification failed, sending a direct message",
+			slog.String("player_id", outbid.ID),
+			slog.Any("error", err),
+		)
+	}
+	if err := sendDM(ctx, s, outbid.DiscordID, msg); err != nil {
+		d.logger.WarnContext(ctx, "sending outbid notification failed",
+			slog.String("player_id", outbid.ID),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// highestBidField names the embed field showing an auction's highest bid.
+const highestBidField = "Highest bid"
+
+// showHighestBid edits the message an to show leader as the highest bid in
+// its first embed.
+func showHighestBid(ctx context.Context, s *discordgo.Session, an auction.Announcement, leader string) error {
+	m, err := s.ChannelMessage(an.ChannelID, an.MessageID, discordgo.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("fetching message: %w", err)
+	}
+	if len(m.Embeds) == 0 {
+		return nil
+	}
+	embeds := m.Embeds
+	embed := embeds[0]
+	i := slices.IndexFunc(embed.Fields, func(f *discordgo.MessageEmbedField) bool { return f.Name == highestBidField })
+	if i < 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: highestBidField})
+		i = len(embed.Fields) - 1
+	}
+	embed.Fields[i].Value = leader
+	if _, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel: an.ChannelID,
+		ID:      an.MessageID,
+		Embeds:  &embeds,
+	}, discordgo.WithContext(ctx)); err != nil {
+		return fmt.Errorf("editing message: %w", err)
+	}
+	return nil
+}
+
+// sendDM sends msg to the Discord user userID.
+func sendDM(ctx context.Context, s *discordgo.Session, userID, msg string) error {
+	ch, err := s.UserChannelCreate(userID, discordgo.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("opening DM channel: %w", err)
+	}
+	if _, err := s.ChannelMessageSend(ch.ID, msg, discordgo.WithContext(ctx)); err != nil {
+		return fmt.Errorf("sending DM: %w", err)
+	}
+	return nil
+}
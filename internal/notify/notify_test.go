@@ -0,0 +1,255 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/notify"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/settings"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// dmTransport answers Discord REST calls as if they succeeded and records
+// the recipients and messages of direct messages, the channels of other
+// messages, and message edits. Fetched messages have one embed.
+type dmTransport struct {
+	mu         sync.Mutex
+	recipients []string
+	messages   []string
+	channels   []string
+	edits      []string
+}
+
+func (rt *dmTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	var resp string
+	switch {
+	case req.URL.Path == "/api/v9/users/@me/channels":
+		var data struct {
+			RecipientID string `json:"recipient_id"`
+		}
+		_ = json.Unmarshal(body, &data)
+		rt.recipients = append(rt.recipients, data.RecipientID)
+		resp = `{"id":"dm-` + data.RecipientID + `","type":1}`
+	case req.Method == http.MethodGet && strings.Contains(req.URL.Path, "/messages/"):
+		resp = `{"id":"m1","channel_id":"c1","embeds":[{"title":"Thunderfury"}]}`
+	case req.Method == http.MethodPatch:
+		rt.edits = append(rt.edits, string(body))
+		resp = `{"id":"m1"}`
+	case strings.HasSuffix(req.URL.Path, "/messages"):
+		var data struct {
+			Content string `json:"content"`
+		}
+		_ = json.Unmarshal(body, &data)
+		rt.messages = append(rt.messages, data.Content)
+		if channel := strings.TrimPrefix(req.URL.Path, "/api/v9/channels/"); !strings.HasPrefix(channel, "dm-") {
+			rt.channels = append(rt.channels, strings.TrimSuffix(channel, "/messages"))
+		}
+		resp = `{"id":"msg"}`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(resp)),
+		Request:    req,
+	}, nil
+}
+
+// fixedWishlist returns the same wishers for every item it knows.
+type fixedWishlist map[string][]store.Player
+
+func (w fixedWishlist) Wishers(_ context.Context, itemName string) ([]store.Player, error) {
+	return w[itemName], nil
+}
+
+func TestDispatcher_AuctionStarted(t *testing.T) {
+	wishlist := fixedWishlist{
+		"Thunderfury": {
+			{ID: "p1", DiscordID: "111", CharacterName: "Alice"},
+			{ID: "p2", DiscordID: "222", CharacterName: "Bob"},
+			{ID: "p3", DiscordID: "333", CharacterName: "Officer"},
+		},
+	}
+	started := func(item string) event.Event {
+		data, _ := json.Marshal(event.AuctionStartedData{ItemName: item, StartedBy: "333", MinBid: 50})
+		return event.Event{ID: "evt-1", AggregateID: "auction-1", Type: event.AuctionStarted, Data: data}
+	}
+
+	tests := []struct {
+		name       string
+		event      event.Event
+		session    bool
+		recipients []string
+	}{
+		{"wishers other than the starter", started("Thunderfury"), true, []string{"111", "222"}},
+		{"item nobody wants", started("Choker of the Fire Lord"), true, nil},
+		{"no session", started("Thunderfury"), false, nil},
+		{"other event type", event.Event{Type: event.AuctionClosed}, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &dmTransport{}
+			s, _ := discordgo.New("Bot token")
+			s.Client = &http.Client{Transport: rt}
+			session := func() *discordgo.Session {
+				if tt.session {
+					return s
+				}
+				return nil
+			}
+
+			d := notify.NewDispatcher(wishlist, session, slog.Default(), noop.NewTracerProvider())
+			d.Dispatch(context.Background(), tt.event)
+
+			if strings.Join(rt.recipients, ",") != strings.Join(tt.recipients, ",") {
+				t.Errorf("recipients = %v, want %v", rt.recipients, tt.recipients)
+			}
+			if len(rt.messages) != len(tt.recipients) {
+				t.Fatalf("sent %d messages, want %d", len(rt.messages), len(tt.recipients))
+			}
+			for _, msg := range rt.messages {
+				if !strings.Contains(msg, "**Thunderfury**") || !strings.Contains(msg, "auction-1") {
+					t.Errorf("message = %q, want the item and auction ID", msg)
+				}
+			}
+		})
+	}
+}
+
+// memEvents implements event.Store over a fixed list of events.
+type memEvents struct {
+	event.Store
+	events []event.Event
+}
+
+func (m memEvents) Load(_ context.Context, aggregateID string) ([]event.Event, error) {
+	var result []event.Event
+	for _, e := range m.events {
+		if e.AggregateID == aggregateID {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+// fixedPlayers lists the same players every time.
+type fixedPlayers []store.Player
+
+func (p fixedPlayers) List(context.Context) ([]store.Player, error) {
+	return p, nil
+}
+
+// noSettings stores no changed guild settings.
+type noSettings struct {
+	store.GuildSettingsRepository
+}
+
+func (noSettings) List(context.Context, string) ([]store.GuildSetting, error) {
+	return nil, nil
+}
+
+func TestDispatcher_BidPlaced(t *testing.T) {
+	players := fixedPlayers{
+		{ID: "p1", DiscordID: "111", CharacterName: "Alice"},
+		{ID: "p2", DiscordID: "222", CharacterName: "Bob"},
+	}
+	mk := func(v int, typ event.Type, data any) event.Event {
+		b, _ := json.Marshal(data)
+		return event.Event{ID: fmt.Sprintf("evt-%d", v), AggregateID: "auction-1", Type: typ, Data: b, Version: v}
+	}
+	events := memEvents{events: []event.Event{
+		mk(1, event.AuctionStarted, event.AuctionStartedData{ItemName: "Thunderfury", MinBid: 10, MinIncrement: 1}),
+		mk(2, event.AuctionAnnounced, event.AuctionAnnouncedData{ChannelID: "c1", MessageID: "m1"}),
+		mk(3, event.AuctionBidPlaced, event.BidPlacedData{PlayerID: "p1", Amount: 50}),
+		mk(4, event.AuctionBidPlaced, event.BidPlacedData{PlayerID: "p2", Amount: 60, Outbid: "p1"}),
+	}}
+
+	tests := []struct {
+		name       string
+		mode       string
+		event      event.Event
+		recipients []string
+		channels   []string
+		template   string
+		messages   []string
+	}{
+		{"first bid", config.OutbidDM, events.events[2], nil, nil, "", nil},
+		{"outbid by direct message", config.OutbidDM, events.events[3], []string{"111"}, nil, "",
+			[]string{"You were outbid on **Thunderfury**: the highest bid is now 60 DKP. Bid again with `/bid auction-id:auction-1 amount:<DKP>`."}},
+		{"outbid in channel", config.OutbidChannel, events.events[3], nil, []string{"c1"}, "",
+			[]string{"<@111> You were outbid on **Thunderfury**: the highest bid is now 60 DKP. Bid again with `/bid auction-id:auction-1 amount:<DKP>`."}},
+		{"outbid with the guild's message", config.OutbidDM, events.events[3], []string{"111"}, nil, "Überboten: {item} steht bei {amount} {currency}.",
+			[]string{"Überboten: Thunderfury steht bei 60 DKP."}},
+		{"outbid notifications off", config.OutbidOff, events.events[3], nil, nil, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &dmTransport{}
+			s, _ := discordgo.New("Bot token")
+			s.Client = &http.Client{Transport: rt}
+			svc := settings.NewService(noSettings{}, settings.Defaults(config.GuildDefaultsConfig{OutbidNotifications: tt.mode, OutbidMessage: tt.template}), slog.Default())
+
+			d := notify.NewDispatcher(fixedWishlist{}, func() *discordgo.Session { return s }, slog.Default(), noop.NewTracerProvider(),
+				notify.WithOutbid(events, players, svc, "guild-1"))
+			d.Dispatch(context.Background(), tt.event)
+
+			if len(rt.edits) != 1 || !strings.Contains(rt.edits[0], `"name":"Highest bid"`) {
+				t.Errorf("edits = %q, want the announcement to show the highest bid", rt.edits)
+			}
+			if strings.Join(rt.recipients, ",") != strings.Join(tt.recipients, ",") {
+				t.Errorf("DM recipients = %v, want %v", rt.recipients, tt.recipients)
+			}
+			if strings.Join(rt.channels, ",") != strings.Join(tt.channels, ",") {
+				t.Errorf("channel messages in %v, want %v", rt.channels, tt.channels)
+			}
+			if !slices.Equal(rt.messages, tt.messages) {
+				t.Errorf("messages = %q, want %q", rt.messages, tt.messages)
+			}
+		})
+	}
+}
+
+func TestDispatcher_Run(t *testing.T) {
+	rt := &dmTransport{}
+	s, _ := discordgo.New("Bot token")
+	s.Client = &http.Client{Transport: rt}
+	wishlist := fixedWishlist{"Thunderfury": {{ID: "p1", DiscordID: "111"}}}
+	d := notify.NewDispatcher(wishlist, func() *discordgo.Session { return s }, slog.Default(), noop.NewTracerProvider())
+
+	bus := event.NewBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx, bus)
+		close(done)
+	}()
+
+	// Run subscribes asynchronously; publish until the message arrives.
+	data, _ := json.Marshal(event.AuctionStartedData{ItemName: "Thunderfury"})
+	for sent := false; !sent; {
+		bus.Publish(ctx, event.Event{AggregateID: "auction-1", Type: event.AuctionStarted, Data: data})
+		rt.mu.Lock()
+		sent = len(rt.messages) > 0
+		rt.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,304 @@
+// Package onboard registers players with starting balances from a CSV
+// file, for guilds that kept their DKP in a spreadsheet before using the
+// bot. Rows that cannot be imported, such as those with an invalid Discord
+// ID or of players already registered, are reported by line and skipped,
+// so that officers can fix the file and import it again.
+package onboard
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// Actor is recorded on the events of imports run from the command line.
+const Actor = "player-import"
+
+// Reason is the reason recorded on the adjustment seeding a starting
+// balance.
+const Reason = "starting balance"
+
+// ErrInvalidFile is returned by Parse for data it cannot read.
+var ErrInvalidFile = derrors.New(derrors.Validation, "INVALID_PLAYER_FILE", "the file is not a CSV of players")
+
+// Column names accepted in the header, lowercased. The first of each are
+// those of /dkp-export standings.
+var (
+	nameColumns    = []string{"character_name", "character", "name"}
+	discordColumns = []string{"discord_id", "discord"}
+	dkpColumns     = []string{"dkp", "balance"}
+)
+
+// Row is a player to register.
+type Row struct {
+	// Line is the line of the file the row is on.
+	Line          int
+	DiscordID     string
+	CharacterName string
+	DKP           int
+}
+
+// RowError is a row that is not imported, and why.
+type RowError struct {
+	Line   int
+	Reason string
+}
+
+func (e RowError) String() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+// File is a parsed CSV file of players.
+type File struct {
+	Rows []Row
+	// Errors are the rows that could not be read, by line.
+	Errors []RowError
+}
+
+// Parse reads a CSV file with a header row and a character name, a Discord
+// ID, and a DKP column, in any order; a missing or empty DKP is a starting
+// balance of 0. Rows with an invalid Discord ID or DKP, or whose Discord ID
+// or character name appeared on an earlier row, are reported in the
+// file's Errors. Parse fails only if the file has no usable header.
+func Parse(r io.Reader) (*File, error) {
+	br := bufio.NewReader(r)
+	// Spreadsheets often save CSV files with a byte order mark.
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		_, _ = br.Discard(3)
+	}
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ErrInvalidFile.Wrap(errors.New("the file is empty"))
+	} else if err != nil {
+		return nil, ErrInvalidFile.Wrap(fmt.Errorf("reading header: %w", err))
+	}
+	for i, h := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(h))
+	}
+	nameCol, discordCol, dkpCol := column(header, nameColumns), column(header, discordColumns), column(header, dkpColumns)
+	if nameCol < 0 || discordCol < 0 {
+		return nil, ErrInvalidFile.Wrap(fmt.Errorf("the header needs a %s and a %s column", nameColumns[0], discordColumns[0]))
+	}
+
+	f := &File{}
+	names := make(map[string]int)
+	ids := make(map[string]int)
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var perr *csv.ParseError
+			if !errors.As(err, &perr) {
+				return nil, ErrInvalidFile.Wrap(err)
+			}
+			f.Errors = append(f.Errors, RowError{Line: perr.StartLine, Reason: perr.Err.Error()})
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		row := Row{Line: line, DiscordID: field(rec, discordCol), CharacterName: field(rec, nameCol)}
+		if row.CharacterName == "" && row.DiscordID == "" {
+			continue
+		}
+		if reason := row.parse(field(rec, dkpCol)); reason != "" {
+			f.Errors = append(f.Errors, RowError{Line: line, Reason: reason})
+			continue
+		}
+		if first, ok := ids[row.DiscordID]; ok {
+			f.Errors = append(f.Errors, RowError{Line: line, Reason: fmt.Sprintf("Discord ID %s is already on line %d", row.DiscordID, first)})
+			continue
+		}
+		if first, ok := names[strings.ToLower(row.CharacterName)]; ok {
+			f.Errors = append(f.Errors, RowError{Line: line, Reason: fmt.Sprintf("%s is already on line %d", row.CharacterName, first)})
+			continue
+		}
+		ids[row.DiscordID], names[strings.ToLower(row.CharacterName)] = line, line
+		f.Rows = append(f.Rows, row)
+	}
+	return f, nil
+}
+
+// parse validates r and sets its DKP from balance. It returns why r
+// cannot be imported, or "".
+func (r *Row) parse(balance string) string {
+	switch {
+	case r.CharacterName == "":
+		return "no character name"
+	case !Snowflake(r.DiscordID):
+		return fmt.Sprintf("invalid Discord ID %q", r.DiscordID)
+	}
+	if balance == "" {
+		return ""
+	}
+	dkp, err := strconv.Atoi(balance)
+	if err != nil {
+		return fmt.Sprintf("invalid DKP %q", balance)
+	}
+	r.DKP = dkp
+	return ""
+}
+
+// Snowflake reports whether s is a Discord user ID: a number of 17 to 20
+// digits.
+func Snowflake(s string) bool {
+	if len(s) < 17 || len(s) > 20 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// column returns the index of the first of names in header, or -1.
+func column(header, names []string) int {
+	for _, name := range names {
+		if i := slices.Index(header, name); i >= 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// field returns the trimmed field i of rec, or "" if it has none.
+func field(rec []string, i int) string {
+	if i < 0 || i >= len(rec) {
+		return ""
+	}
+	return strings.TrimSpace(rec[i])
+}
+
+// Report summarizes an import.
+type Report struct {
+	// Created is how many players were registered, with DKP starting DKP
+	// in all.
+	Created int
+	DKP     int
+	// Errors are the rows not imported, by line.
+	Errors []RowError
+}
+
+// Importer registers the players of a File.
+type Importer struct {
+	players store.PlayerRepository
+	events  event.Store
+	logger  *slog.Logger
+	tracer  trace.Tracer
+}
+
+// NewImporter returns a new Importer.
+func NewImporter(players store.PlayerRepository, events event.Store, logger *slog.Logger, tp trace.TracerProvider) *Importer {
+	return &Importer{
+		players: players,
+		events:  events,
+		logger:  logger,
+		tracer:  tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/onboard"),
+	}
+}
+
+// Import registers the players of f. Each is recorded as a
+// player.registered event and, unless their starting balance is 0, a
+// dkp.adjusted event seeding it, with the actor of ctx. Rows of players
+// whose Discord ID or character name is already registered are skipped
+// and reported with the rows f could not read.
+//
+// With dryRun set nothing is written and the report describes what would
+// be imported.
+func (im *Importer) Import(ctx context.Context, f *File, dryRun bool) (Report, error) {
+	ctx, span := im.tracer.Start(ctx, "Importer.Import",
+		trace.WithAttributes(
+			attribute.Int("rows", len(f.Rows)),
+			attribute.Bool("dry_run", dryRun),
+		),
+	)
+	defer span.End()
+
+	report := Report{Errors: slices.Clone(f.Errors)}
+	registered, err := im.players.List(ctx)
+	if err != nil {
+		return report, fmt.Errorf("listing players: %w", err)
+	}
+	byID := make(map[string]bool, len(registered))
+	byName := make(map[string]bool, len(registered))
+	for _, p := range registered {
+		byID[p.DiscordID] = true
+		byName[strings.ToLower(p.CharacterName)] = true
+	}
+
+	for _, row := range f.Rows {
+		switch {
+		case byID[row.DiscordID]:
+			report.Errors = append(report.Errors, RowError{Line: row.Line, Reason: fmt.Sprintf("Discord ID %s is already registered", row.DiscordID)})
+			continue
+		case byName[strings.ToLower(row.CharacterName)]:
+			report.Errors = append(report.Errors, RowError{Line: row.Line, Reason: fmt.Sprintf("%s is already registered", row.CharacterName)})
+			continue
+		}
+		if !dryRun {
+			err := im.register(ctx, row)
+			if errors.Is(err, store.ErrPlayerExists) {
+				// Registered since the players were listed.
+				report.Errors = append(report.Errors, RowError{Line: row.Line, Reason: fmt.Sprintf("Discord ID %s is already registered", row.DiscordID)})
+				continue
+			} else if err != nil {
+				return report, fmt.Errorf("line %d: importing %s: %w", row.Line, row.CharacterName, err)
+			}
+		}
+		report.Created++
+		report.DKP += row.DKP
+	}
+	slices.SortStableFunc(report.Errors, func(a, b RowError) int { return a.Line - b.Line })
+
+	im.logger.InfoContext(ctx, "player import complete",
+		slog.Int("created", report.Created),
+		slog.Int("dkp", report.DKP),
+		slog.Int("errors", len(report.Errors)),
+		slog.Bool("dry_run", dryRun),
+	)
+	return report, nil
+}
+
+// register creates the player of row and seeds their starting balance.
+func (im *Importer) register(ctx context.Context, row Row) error {
+	p := &store.Player{DiscordID: row.DiscordID, CharacterName: row.CharacterName}
+	if err := im.players.Create(ctx, p); err != nil {
+		return err
+	}
+	data, _ := json.Marshal(event.PlayerRegisteredData{DiscordID: p.DiscordID, CharacterName: p.CharacterName})
+	if err := im.events.Append(ctx, event.Event{AggregateID: p.ID, Type: event.PlayerRegistered, Data: data, Version: 1}); err != nil {
+		im.logger.ErrorContext(ctx, "failed to append player registered event", slog.Any("error", err))
+	}
+	if row.DKP == 0 {
+		return nil
+	}
+	if err := im.players.UpdateDKP(ctx, p.ID, row.DKP); err != nil {
+		return fmt.Errorf("seeding balance: %w", err)
+	}
+	data, _ = json.Marshal(event.DKPChangeData{PlayerID: p.ID, Amount: row.DKP, Reason: Reason})
+	if err := im.events.Append(ctx, event.Event{AggregateID: p.ID, Type: event.DKPAdjusted, Data: data}); err != nil {
+		im.logger.ErrorContext(ctx, "failed to append starting balance adjustment", slog.Any("error", err))
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package onboard_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/onboard"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+const (
+	frodo = "111111111111111111"
+	sam   = "222222222222222222"
+	merry = "333333333333333333"
+)
+
+func TestParse(t *testing.T) {
+	in := "\xef\xbb\xbfDKP, Name, Discord_ID\n" +
+		"120, Frodo, " + frodo + "\n" +
+		", Sam, " + sam + "\n" +
+		"10, Pippin, 12345\n" +
+		"lots, Merry, " + merry + "\n" +
+		"5, Gandalf, " + frodo + "\n" +
+		"5, frodo, 444444444444444444\n" +
+		",,\n" +
+		"7, , 555555555555555555\n"
+	f, err := onboard.Parse(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	wantRows := []onboard.Row{
+		{Line: 2, DiscordID: frodo, CharacterName: "Frodo", DKP: 120},
+		{Line: 3, DiscordID: sam, CharacterName: "Sam"},
+	}
+	if !slices.Equal(f.Rows, wantRows) {
+		t.Errorf("Rows = %+v, want %+v", f.Rows, wantRows)
+	}
+	wantErrors := []onboard.RowError{
+		{Line: 4, Reason: `invalid Discord ID "12345"`},
+		{Line: 5, Reason: `invalid DKP "lots"`},
+		{Line: 6, Reason: "Discord ID " + frodo + " is already on line 2"},
+		{Line: 7, Reason: "frodo is already on line 2"},
+		{Line: 9, Reason: "no character name"},
+	}
+	if !slices.Equal(f.Errors, wantErrors) {
+		t.Errorf("Errors = %+v, want %+v", f.Errors, wantErrors)
+	}
+
+	for _, in := range []string{"", "name,dkp\nFrodo,1\n"} {
+		if _, err := onboard.Parse(strings.NewReader(in)); !errors.Is(err, onboard.ErrInvalidFile) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidFile", in, err)
+		}
+	}
+}
+
+func TestImporter_Import(t *testing.T) {
+	ctx := event.WithActor(context.Background(), "officer")
+	players := storetest.NewPlayers(store.Player{DiscordID: merry, CharacterName: "Merry"})
+	events := eventtest.NewStore()
+	im := onboard.NewImporter(players, events, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+	f, err := onboard.Parse(strings.NewReader("character_name,discord_id,dkp\n" +
+		"Frodo," + frodo + ",120\n" +
+		"Sam," + sam + ",0\n" +
+		"Merry,444444444444444444,30\n" +
+		"Pippin,12,5\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	wantErrors := []onboard.RowError{
+		{Line: 4, Reason: "Merry is already registered"},
+		{Line: 5, Reason: `invalid Discord ID "12"`},
+	}
+
+	preview, err := im.Import(ctx, f, true)
+	if err != nil {
+		t.Fatalf("Import() dry run error = %v", err)
+	}
+	if preview.Created != 2 || preview.DKP != 120 || !slices.Equal(preview.Errors, wantErrors) {
+		t.Errorf("Import() dry run = %+v, want 2 players with 120 DKP and %+v", preview, wantErrors)
+	}
+	if n := len(events.Events()); n != 0 {
+		t.Fatalf("dry run appended %d events", n)
+	}
+
+	report, err := im.Import(ctx, f, false)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if report.Created != 2 || report.DKP != 120 || !slices.Equal(report.Errors, wantErrors) {
+		t.Errorf("Import() = %+v, want 2 players with 120 DKP and %+v", report, wantErrors)
+	}
+	players.RequireDKP(t, frodo, 120)
+	players.RequireDKP(t, sam, 0)
+	events.RequireTypes(t, event.PlayerRegistered, event.DKPAdjusted, event.PlayerRegistered)
+
+	var seed event.DKPChangeData
+	if err := json.Unmarshal(events.Events()[1].Data, &seed); err != nil {
+		t.Fatal(err)
+	}
+	if seed.Amount != 120 || seed.Reason != onboard.Reason {
+		t.Errorf("seed adjustment = %+v, want 120 DKP for %q", seed, onboard.Reason)
+	}
+
+	again, err := im.Import(ctx, f, false)
+	if err != nil {
+		t.Fatalf("Import() again error = %v", err)
+	}
+	if again.Created != 0 || len(again.Errors) != 4 {
+		t.Errorf("Import() again = %+v, want every row reported", again)
+	}
+}
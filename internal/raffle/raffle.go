@@ -0,0 +1,377 @@
+// Package raffle raffles items that are not worth an auction, such as
+// cosmetics or surplus drops, for DKP. Players buy tickets at a fixed DKP
+// cost, which is deducted as they enter, and an officer draws the winning
+// ticket with a cryptographically secure random number, so that every
+// ticket has the same chance. Each raffle is kept as events in the event
+// store.
+package raffle
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/ids"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// Errors returned by raffle operations.
+var (
+	ErrUnknownRaffle     = derrors.New(derrors.NotFound, "UNKNOWN_RAFFLE", "no such raffle")
+	ErrNoItemName        = derrors.New(derrors.Validation, "NO_ITEM_NAME", "name the item to raffle")
+	ErrInvalidCost       = derrors.New(derrors.Validation, "INVALID_TICKET_COST", "a ticket must cost a positive amount of DKP")
+	ErrInvalidTickets    = derrors.New(derrors.Validation, "INVALID_TICKETS", "buy at least one ticket")
+	ErrTooManyTickets    = derrors.New(derrors.Validation, "TOO_MANY_TICKETS", "that would exceed the tickets a player may buy in this raffle")
+	ErrInsufficientDKP   = derrors.New(derrors.Validation, "INSUFFICIENT_DKP", "insufficient DKP")
+	ErrArchivedPlayer    = derrors.New(derrors.Validation, "ARCHIVED_PLAYER", "archived players cannot enter raffles")
+	ErrRaffleDrawn       = derrors.New(derrors.Conflict, "RAFFLE_DRAWN", "the raffle was already drawn")
+	ErrNotRegistered     = derrors.New(derrors.NotFound, "NOT_REGISTERED", "register with /register to enter raffles")
+	errRandomUnavailable = derrors.New(derrors.Internal, "RANDOM_UNAVAILABLE", "drawing a ticket failed")
+)
+
+// DKP looks players up and changes their DKP.
+type DKP interface {
+	GetPlayer(ctx context.Context, discordID string) (*store.Player, error)
+	AwardDKP(ctx context.Context, playerID string, amount int, reason string) error
+	DeductDKP(ctx context.Context, playerID string, amount int, reason string) error
+}
+
+// Entry is the tickets a player bought in a raffle.
+type Entry struct {
+	PlayerID  string
+	DiscordID string
+	Tickets   int
+}
+
+// Raffle is a raffle as recorded in its events.
+type Raffle struct {
+	ID         string
+	ItemName   string
+	TicketCost int
+	// MaxTickets is how many tickets a player may buy, or 0 for no limit.
+	MaxTickets int
+	// StartedBy is the Discord ID of the member who started the raffle.
+	StartedBy string
+	StartedAt time.Time
+	// Entries hold the tickets of each player, in the order they first
+	// bought some. Tickets are numbered in the order they were bought.
+	Entries []Entry
+	// Sold is the number of tickets sold.
+	Sold int
+	// Drawn is set once the winning ticket was drawn. Winner is the player
+	// holding it, or empty if no tickets were sold.
+	Drawn   bool
+	Ticket  int
+	Winner  string
+	Version int
+
+	// owners holds the player of each ticket sold, in order.
+	owners []string
+}
+
+// Entry returns the tickets the player playerID bought, if any.
+func (r *Raffle) Entry(playerID string) Entry {
+	for _, e := range r.Entries {
+		if e.PlayerID == playerID {
+			return e
+		}
+	}
+	return Entry{PlayerID: playerID}
+}
+
+// Service runs raffles.
+type Service struct {
+	events event.Store
+	dkp    DKP
+	ids    ids.Generator
+	logger *slog.Logger
+	tracer trace.Tracer
+	clock  clock.Clock
+
+	// mu serializes changes, which version the raffle's events.
+	mu sync.Mutex
+}
+
+// NewService returns a Service that records raffles in events, identified
+// by IDs from gen, and charges tickets through dkp.
+func NewService(events event.Store, dkp DKP, gen ids.Generator, logger *slog.Logger, tp trace.TracerProvider, clk clock.Clock) *Service {
+	return &Service{
+		events: events,
+		dkp:    dkp,
+		ids:    gen,
+		logger: logger,
+		tracer: tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/raffle"),
+		clock:  clk,
+	}
+}
+
+// Start starts a raffle of the item itemName, on behalf of the member
+// startedBy, with tickets costing ticketCost DKP. maxTickets limits the
+// tickets a player may buy; 0 or less means no limit.
+func (s *Service) Start(ctx context.Context, itemName, startedBy string, ticketCost, maxTickets int) (*Raffle, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Start",
+		trace.WithAttributes(
+			attribute.String("item", itemName),
+			attribute.Int("ticket_cost", ticketCost),
+		),
+	)
+	defer span.End()
+
+	itemName = strings.TrimSpace(itemName)
+	if itemName == "" {
+		return nil, ErrNoItemName
+	}
+	if ticketCost <= 0 {
+		return nil, ErrInvalidCost
+	}
+	r := &Raffle{
+		ID:         "raffle-" + s.ids.NewID(),
+		ItemName:   itemName,
+		TicketCost: ticketCost,
+		MaxTickets: max(maxTickets, 0),
+		StartedBy:  startedBy,
+		StartedAt:  s.clock.Now(),
+	}
+	data, _ := json.Marshal(event.RaffleStartedData{
+		ItemName:   itemName,
+		TicketCost: ticketCost,
+		MaxTickets: r.MaxTickets,
+		StartedBy:  startedBy,
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.append(ctx, r, event.RaffleStarted, data); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "raffle started",
+		slog.String("raffle_id", r.ID),
+		slog.String("item", itemName),
+		slog.Int("ticket_cost", ticketCost),
+	)
+	return r, nil
+}
+
+// Get returns the raffle id.
+func (s *Service) Get(ctx context.Context, id string) (*Raffle, error) {
+	events, err := s.events.Load(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("loading raffle events: %w", err)
+	}
+	if len(events) == 0 || events[0].Type != event.RaffleStarted {
+		return nil, ErrUnknownRaffle.Wrap(fmt.Errorf("raffle %s", id))
+	}
+	return replay(events)
+}
+
+// Open returns the raffles not drawn yet, oldest first.
+func (s *Service) Open(ctx context.Context) ([]*Raffle, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Open")
+	defer span.End()
+
+	started, err := s.events.LoadByType(ctx, event.RaffleStarted)
+	if err != nil {
+		return nil, fmt.Errorf("loading raffle started events: %w", err)
+	}
+	var raffles []*Raffle
+	for _, e := range started {
+		r, err := s.Get(ctx, e.AggregateID)
+		if err != nil {
+			return nil, err
+		}
+		if !r.Drawn {
+			raffles = append(raffles, r)
+		}
+	}
+	slices.SortFunc(raffles, func(a, b *Raffle) int { return a.StartedAt.Compare(b.StartedAt) })
+	return raffles, nil
+}
+
+// Enter buys tickets in the raffle id for the player of the member
+// discordID, deducting their cost from the player's DKP. A player cannot
+// buy tickets they cannot afford.
+func (s *Service) Enter(ctx context.Context, id, discordID string, tickets int) (*Raffle, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Enter",
+		trace.WithAttributes(
+			attribute.String("raffle.id", id),
+			attribute.String("discord_id", discordID),
+			attribute.Int("tickets", tickets),
+		),
+	)
+	defer span.End()
+
+	if tickets <= 0 {
+		return nil, ErrInvalidTickets
+	}
+	p, err := s.dkp.GetPlayer(ctx, discordID)
+	if errors.Is(err, store.ErrPlayerNotFound) {
+		return nil, ErrNotRegistered
+	}
+	if err != nil {
+		return nil, fmt.Errorf("looking up player: %w", err)
+	}
+	if p.Archived() {
+		return nil, ErrArchivedPlayer
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r.Drawn {
+		return nil, ErrRaffleDrawn
+	}
+	if r.MaxTickets > 0 && r.Entry(p.ID).Tickets+tickets > r.MaxTickets {
+		return nil, ErrTooManyTickets
+	}
+	cost := tickets * r.TicketCost
+	if p.DKP < cost {
+		return nil, ErrInsufficientDKP
+	}
+
+	reason := fmt.Sprintf("%s: %d raffle tickets", r.ItemName, tickets)
+	if err := s.dkp.DeductDKP(ctx, p.ID, cost, reason); err != nil {
+		return nil, fmt.Errorf("charging raffle tickets: %w", err)
+	}
+	data, _ := json.Marshal(event.RaffleEnteredData{PlayerID: p.ID, DiscordID: discordID, Tickets: tickets, Cost: cost})
+	if err := s.append(ctx, r, event.RaffleEntered, data); err != nil {
+		// The tickets were not sold, so their cost is refunded.
+		if rerr := s.dkp.AwardDKP(ctx, p.ID, cost, r.ItemName+": raffle tickets refunded"); rerr != nil {
+			s.logger.ErrorContext(ctx, "refunding raffle tickets failed",
+				slog.String("raffle_id", r.ID),
+				slog.String("player_id", p.ID),
+				slog.Int("cost", cost),
+				slog.Any("error", rerr),
+			)
+		}
+		return nil, err
+	}
+	r.enter(Entry{PlayerID: p.ID, DiscordID: discordID, Tickets: tickets})
+
+	s.logger.InfoContext(ctx, "raffle tickets bought",
+		slog.String("raffle_id", r.ID),
+		slog.String("player_id", p.ID),
+		slog.Int("tickets", tickets),
+		slog.Int("cost", cost),
+	)
+	return r, nil
+}
+
+// Draw draws the winning ticket of the raffle id. Every ticket sold has the
+// same chance. A raffle without tickets is drawn without a winner.
+func (s *Service) Draw(ctx context.Context, id string) (*Raffle, error) {
+	ctx, span := s.tracer.Start(ctx, "Service.Draw",
+		trace.WithAttributes(attribute.String("raffle.id", id)),
+	)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r.Drawn {
+		return nil, ErrRaffleDrawn
+	}
+	var d event.RaffleDrawnData
+	if r.Sold > 0 {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(r.Sold)))
+		if err != nil {
+			return nil, errRandomUnavailable.Wrap(err)
+		}
+		d = event.RaffleDrawnData{Ticket: int(n.Int64()) + 1, Tickets: r.Sold}
+		d.WinnerID = r.owners[d.Ticket-1]
+	}
+	data, _ := json.Marshal(d)
+	if err := s.append(ctx, r, event.RaffleDrawn, data); err != nil {
+		return nil, err
+	}
+	r.Drawn, r.Ticket, r.Winner = true, d.Ticket, d.WinnerID
+
+	s.logger.InfoContext(ctx, "raffle drawn",
+		slog.String("raffle_id", r.ID),
+		slog.Int("ticket", d.Ticket),
+		slog.Int("tickets", d.Tickets),
+		slog.String("winner_id", d.WinnerID),
+	)
+	return r, nil
+}
+
+// enter adds the tickets bought in entry.
+func (r *Raffle) enter(entry Entry) {
+	for range entry.Tickets {
+		r.owners = append(r.owners, entry.PlayerID)
+	}
+	r.Sold += entry.Tickets
+	for n, e := range r.Entries {
+		if e.PlayerID == entry.PlayerID {
+			r.Entries[n].Tickets += entry.Tickets
+			return
+		}
+	}
+	r.Entries = append(r.Entries, entry)
+}
+
+// append records an event of type t on r.
+func (s *Service) append(ctx context.Context, r *Raffle, t event.Type, data json.RawMessage) error {
+	e := event.Event{
+		AggregateID: r.ID,
+		Type:        t,
+		Data:        data,
+		Version:     r.Version + 1,
+	}
+	if err := s.events.Append(ctx, e); err != nil {
+		return fmt.Errorf("recording %s event: %w", t, err)
+	}
+	r.Version = e.Version
+	return nil
+}
+
+// replay rebuilds a raffle from its events, oldest first.
+func replay(events []event.Event) (*Raffle, error) {
+	r := &Raffle{ID: events[0].AggregateID}
+	for _, e := range events {
+		switch e.Type {
+		case event.RaffleStarted:
+			var d event.RaffleStartedData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+			}
+			r.ItemName, r.TicketCost, r.MaxTickets = d.ItemName, d.TicketCost, d.MaxTickets
+			r.StartedBy, r.StartedAt = d.StartedBy, e.CreatedAt
+		case event.RaffleEntered:
+			var d event.RaffleEnteredData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+			}
+			r.enter(Entry{PlayerID: d.PlayerID, DiscordID: d.DiscordID, Tickets: d.Tickets})
+		case event.RaffleDrawn:
+			var d event.RaffleDrawnData
+			if err := json.Unmarshal(e.Data, &d); err != nil {
+				return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+			}
+			r.Drawn, r.Ticket, r.Winner = true, d.Ticket, d.WinnerID
+		}
+		r.Version = e.Version
+	}
+	return r, nil
+}
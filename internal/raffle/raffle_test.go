@@ -0,0 +1,176 @@
+package raffle_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/ids"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/raffle"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// ledger knows the players of members d1 to d3, d3 archived, and their
+// DKP.
+type ledger struct {
+	dkp map[string]int
+}
+
+func newLedger() *ledger {
+	return &ledger{dkp: map[string]int{"p1": 50, "p2": 25, "p3": 100}}
+}
+
+func (l *ledger) GetPlayer(_ context.Context, discordID string) (*store.Player, error) {
+	switch discordID {
+	case "d1", "d2", "d3":
+		id := "p" + discordID[1:]
+		p := &store.Player{ID: id, DiscordID: discordID, DKP: l.dkp[id]}
+		if discordID == "d3" {
+			archived := time.Now()
+			p.ArchivedAt = &archived
+		}
+		return p, nil
+	}
+	return nil, store.ErrPlayerNotFound
+}
+
+func (l *ledger) AwardDKP(_ context.Context, playerID string, amount int, _ string) error {
+	l.dkp[playerID] += amount
+	return nil
+}
+
+func (l *ledger) DeductDKP(_ context.Context, playerID string, amount int, _ string) error {
+	l.dkp[playerID] -= amount
+	return nil
+}
+
+func newService(dkp raffle.DKP) *raffle.Service {
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)}
+	return raffle.NewService(eventtest.NewStore(), dkp, ids.NewULID(clk), slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clk)
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	dkp := newLedger()
+	s := newService(dkp)
+
+	if _, err := s.Start(ctx, " ", "officer", 10, 0); !errors.Is(err, raffle.ErrNoItemName) {
+		t.Errorf("Start() with no name error = %v, want ErrNoItemName", err)
+	}
+	if _, err := s.Start(ctx, "Tabard of Flame", "officer", 0, 0); !errors.Is(err, raffle.ErrInvalidCost) {
+		t.Errorf("Start() with free tickets error = %v, want ErrInvalidCost", err)
+	}
+	r, err := s.Start(ctx, "Tabard of Flame", "officer", 10, 5)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	for _, tc := range []struct {
+		discordID string
+		tickets   int
+		wantErr   error
+	}{
+		{"d1", 3, nil},
+		{"d1", 3, raffle.ErrTooManyTickets},
+		{"d2", 3, raffle.ErrInsufficientDKP},
+		{"d2", 2, nil},
+		{"d2", 0, raffle.ErrInvalidTickets},
+		{"d3", 1, raffle.ErrArchivedPlayer},
+		{"d9", 1, raffle.ErrNotRegistered},
+	} {
+		_, err := s.Enter(ctx, r.ID, tc.discordID, tc.tickets)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("Enter(%s, %d) error = %v, want %v", tc.discordID, tc.tickets, err, tc.wantErr)
+		}
+	}
+	if dkp.dkp["p1"] != 20 || dkp.dkp["p2"] != 5 || dkp.dkp["p3"] != 100 {
+		t.Errorf("DKP = %v, want 30 deducted from p1 and 20 from p2", dkp.dkp)
+	}
+
+	got, err := s.Get(ctx, r.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Sold != 5 || got.Entry("p1").Tickets != 3 || got.Entry("p2").DiscordID != "d2" {
+		t.Errorf("Get() = %+v, want 3 tickets for p1 and 2 for p2", got)
+	}
+	if open, _ := s.Open(ctx); len(open) != 1 {
+		t.Errorf("Open() = %d raffles, want 1", len(open))
+	}
+
+	drawn, err := s.Draw(ctx, r.ID)
+	if err != nil {
+		t.Fatalf("Draw() error = %v", err)
+	}
+	// Tickets 1 to 3 are p1's, 4 and 5 p2's.
+	want := "p1"
+	if drawn.Ticket > 3 {
+		want = "p2"
+	}
+	if drawn.Ticket < 1 || drawn.Ticket > 5 || drawn.Winner != want {
+		t.Errorf("Draw() = ticket %d won by %q, want a ticket of 5 and its holder", drawn.Ticket, drawn.Winner)
+	}
+	if got, _ := s.Get(ctx, r.ID); !got.Drawn || got.Ticket != drawn.Ticket || got.Winner != drawn.Winner {
+		t.Errorf("Get() after Draw() = %+v, want the draw recorded", got)
+	}
+	if _, err := s.Draw(ctx, r.ID); !errors.Is(err, raffle.ErrRaffleDrawn) {
+		t.Errorf("second Draw() error = %v, want ErrRaffleDrawn", err)
+	}
+	if _, err := s.Enter(ctx, r.ID, "d1", 1); !errors.Is(err, raffle.ErrRaffleDrawn) {
+		t.Errorf("Enter() after Draw() error = %v, want ErrRaffleDrawn", err)
+	}
+	if open, _ := s.Open(ctx); len(open) != 0 {
+		t.Errorf("Open() after Draw() = %d raffles, want none", len(open))
+	}
+	if _, err := s.Draw(ctx, "raffle-0"); !errors.Is(err, raffle.ErrUnknownRaffle) {
+		t.Errorf("Draw() of an unknown raffle error = %v, want ErrUnknownRaffle", err)
+	}
+}
+
+func TestService_DrawWithoutTickets(t *testing.T) {
+	ctx := context.Background()
+	s := newService(newLedger())
+	r, err := s.Start(ctx, "Pet Rock", "officer", 1, 0)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	drawn, err := s.Draw(ctx, r.ID)
+	if err != nil || !drawn.Drawn || drawn.Winner != "" {
+		t.Errorf("Draw() = %+v, %v; want it drawn without a winner", drawn, err)
+	}
+}
+
+func TestService_DrawIsFair(t *testing.T) {
+	ctx := context.Background()
+	dkp := newLedger()
+	dkp.dkp["p1"], dkp.dkp["p2"] = 100, 100
+	s := newService(dkp)
+	wins := map[string]int{}
+	// Both players hold one ticket each time; the chance that either never
+	// wins in 100 draws is 2^-99.
+	for range 100 {
+		r, err := s.Start(ctx, "Pet Rock", "officer", 1, 0)
+		if err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+		for _, id := range []string{"d1", "d2"} {
+			if _, err := s.Enter(ctx, r.ID, id, 1); err != nil {
+				t.Fatalf("Enter(%s) error = %v", id, err)
+			}
+		}
+		drawn, err := s.Draw(ctx, r.ID)
+		if err != nil {
+			t.Fatalf("Draw() error = %v", err)
+		}
+		wins[drawn.Winner]++
+	}
+	if wins["p1"] == 0 || wins["p2"] == 0 {
+		t.Errorf("wins = %v, want both players to win some draws", wins)
+	}
+}
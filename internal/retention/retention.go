@@ -0,0 +1,154 @@
+// Package retention moves events of finished aggregates out of the hot
+// events table so that it stays small as a guild's history grows.
+//
+// Only auctions that are closed or canceled, and whose last event is older
+// than the configured maximum age, are archived. Before an auction's events
+// are moved its final state is written as a snapshot, so the auction can
+// still be inspected after archival.
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// Report summarizes an archival run.
+type Report struct {
+	// Candidates is the number of finished auctions older than the cutoff.
+	Candidates int
+	// Archived is the number of auctions whose events were moved.
+	Archived int
+	// Skipped is the number of candidates that failed a safety check.
+	Skipped int
+	// Events is the total number of events moved.
+	Events int
+}
+
+// Archiver applies the retention policy.
+type Archiver struct {
+	events  event.Store
+	archive event.Archive
+	maxAge  time.Duration
+	logger  *slog.Logger
+	tracer  trace.Tracer
+	clock   clock.Clock
+}
+
+// NewArchiver returns an Archiver that archives aggregates whose last event
+// is older than maxAge.
+func NewArchiver(events event.Store, archive event.Archive, maxAge time.Duration, logger *slog.Logger, tp trace.TracerProvider, clk clock.Clock) *Archiver {
+	return &Archiver{
+		events:  events,
+		archive: archive,
+		maxAge:  maxAge,
+		logger:  logger,
+		tracer:  tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/retention"),
+		clock:   clk,
+	}
+}
+
+// Run archives every eligible auction. With dryRun set it only reports what
+// would be archived.
+func (a *Archiver) Run(ctx context.Context, dryRun bool) (Report, error) {
+	cutoff := a.clock.Now().UTC().Add(-a.maxAge)
+	ctx, span := a.tracer.Start(ctx, "Archiver.Run",
+		trace.WithAttributes(
+			attribute.String("cutoff", cutoff.Format(time.RFC3339)),
+			attribute.Bool("dry_run", dryRun),
+		),
+	)
+	defer span.End()
+
+	finished, err := a.events.Query(ctx, event.Query{
+		Types: []event.Type{event.AuctionClosed, event.AuctionCanceled, event.AuctionBoughtOut},
+		Until: cutoff,
+	})
+	if err != nil {
+		return Report{}, fmt.Errorf("finding finished auctions: %w", err)
+	}
+
+	seen := make(map[string]struct{}, len(finished))
+	var report Report
+	for _, e := range finished {
+		if _, ok := seen[e.AggregateID]; ok {
+			continue
+		}
+		seen[e.AggregateID] = struct{}{}
+		report.Candidates++
+
+		moved, archiveErr := a.archiveAuction(ctx, e.AggregateID, cutoff, dryRun)
+		if archiveErr != nil {
+			a.logger.WarnContext(ctx, "skipping auction during archival",
+				slog.String("auction_id", e.AggregateID),
+				slog.Any("error", archiveErr),
+			)
+			report.Skipped++
+			continue
+		}
+		report.Archived++
+		report.Events += moved
+	}
+
+	a.logger.InfoContext(ctx, "archival run complete",
+		slog.Bool("dry_run", dryRun),
+		slog.Int("candidates", report.Candidates),
+		slog.Int("archived", report.Archived),
+		slog.Int("skipped", report.Skipped),
+		slog.Int("events", report.Events),
+	)
+	return report, nil
+}
+
+// archiveAuction verifies that an auction is safe to archive, snapshots it,
+// and moves its events.
+func (a *Archiver) archiveAuction(ctx context.Context, id string, cutoff time.Time, dryRun bool) (int, error) {
+	events, err := a.events.Load(ctx, id)
+	if err != nil {
+		return 0, fmt.Errorf("loading events: %w", err)
+	}
+	if len(events) == 0 {
+		return 0, fmt.Errorf("no events left to archive")
+	}
+
+	last := events[len(events)-1]
+	if !last.CreatedAt.Before(cutoff) {
+		return 0, fmt.Errorf("auction has events newer than the cutoff")
+	}
+
+	replayed, err := auction.Replay(events)
+	if err != nil {
+		return 0, fmt.Errorf("replaying auction: %w", err)
+	}
+	state := replayed.State()
+	if state.Status != "closed" && state.Status != "canceled" {
+		return 0, fmt.Errorf("auction is still open")
+	}
+
+	if dryRun {
+		return len(events), nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return 0, fmt.Errorf("encoding snapshot: %w", err)
+	}
+	if err := a.archive.SaveSnapshot(ctx, event.Snapshot{
+		AggregateID: id,
+		Version:     last.Version,
+		State:       data,
+	}); err != nil {
+		return 0, err
+	}
+
+	return a.archive.ArchiveAggregate(ctx, id, last.Version)
+}
@@ -0,0 +1,123 @@
+package retention_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/retention"
+)
+
+// mockArchive copies archived events out of the backing store, which
+// keeps them; the events still hot are those of the store not archived.
+type mockArchive struct {
+	store     *eventtest.Store
+	snapshots map[string]event.Snapshot
+	archived  []event.Event
+}
+
+func (m *mockArchive) SaveSnapshot(_ context.Context, s event.Snapshot) error {
+	m.snapshots[s.AggregateID] = s
+	return nil
+}
+
+func (m *mockArchive) LoadSnapshot(_ context.Context, aggregateID string) (*event.Snapshot, error) {
+	s := m.snapshots[aggregateID]
+	return &s, nil
+}
+
+func (m *mockArchive) ArchiveAggregate(ctx context.Context, aggregateID string, upToVersion int) (int, error) {
+	events, err := m.store.Load(ctx, aggregateID)
+	if err != nil {
+		return 0, err
+	}
+	moved := 0
+	for _, e := range events {
+		if e.Version <= upToVersion {
+			m.archived = append(m.archived, e)
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (m *mockArchive) Archived(_ context.Context, types ...event.Type) ([]event.Event, error) {
+	var events []event.Event
+	for _, e := range m.archived {
+		if (event.Query{Types: types}).Matches(e) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func auctionEvents(id string, at time.Time, terminal event.Type) []event.Event {
+	started, _ := json.Marshal(event.AuctionStartedData{ItemName: "Sword"})
+	events := []event.Event{
+		{AggregateID: id, Type: event.AuctionStarted, Data: started, Version: 1, CreatedAt: at},
+		{AggregateID: id, Type: event.AuctionBidPlaced, Data: json.RawMessage(`{"player_id":"p1","amount":10}`), Version: 2, CreatedAt: at},
+	}
+	if terminal != "" {
+		events = append(events, event.Event{AggregateID: id, Type: terminal, Data: json.RawMessage(`{}`), Version: 3, CreatedAt: at})
+	}
+	return events
+}
+
+func TestArchiver_Run(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-100 * 24 * time.Hour)
+	recent := now.Add(-time.Hour)
+
+	var events []event.Event
+	events = append(events, auctionEvents("old-closed", old, event.AuctionClosed)...)
+	events = append(events, auctionEvents("old-canceled", old, event.AuctionCanceled)...)
+	events = append(events, auctionEvents("old-open", old, "")...)
+	events = append(events, auctionEvents("recent-closed", recent, event.AuctionClosed)...)
+	es := eventtest.NewStore(eventtest.WithEvents(events...))
+
+	archive := &mockArchive{store: es, snapshots: make(map[string]event.Snapshot)}
+	a := retention.NewArchiver(es, archive, 90*24*time.Hour, slog.Default(), noop.NewTracerProvider(), clock.Mock{T: now})
+
+	report, err := a.Run(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if report.Archived != 2 {
+		t.Errorf("Archived = %d, want 2", report.Archived)
+	}
+	if report.Events != 6 {
+		t.Errorf("Events = %d, want 6", report.Events)
+	}
+	if _, ok := archive.snapshots["old-closed"]; !ok {
+		t.Error("expected snapshot for old-closed")
+	}
+	if hot := len(es.Events()) - len(archive.archived); hot != 5 {
+		t.Errorf("hot events = %d, want 5 (open and recent auctions)", hot)
+	}
+}
+
+func TestArchiver_Run_DryRun(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	es := eventtest.NewStore(eventtest.WithEvents(auctionEvents("old-closed", now.Add(-100*24*time.Hour), event.AuctionClosed)...))
+
+	archive := &mockArchive{store: es, snapshots: make(map[string]event.Snapshot)}
+	a := retention.NewArchiver(es, archive, 90*24*time.Hour, slog.Default(), noop.NewTracerProvider(), clock.Mock{T: now})
+
+	report, err := a.Run(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if report.Archived != 1 || report.Events != 3 {
+		t.Errorf("report = %+v, want 1 auction and 3 events", report)
+	}
+	if len(archive.archived) != 0 || len(archive.snapshots) != 0 {
+		t.Error("dry run must not modify the store")
+	}
+}
@@ -0,0 +1,266 @@
+// Package rolesync gives players the Discord roles their DKP earns them,
+// such as a role for the top 10 of the standings or one for balances of at
+// least 500, and takes the roles from players who no longer qualify.
+package rolesync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/leaderboard"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// settle is how long a sync waits after a DKP change for more, such as the
+// rest of a raid's awards.
+const settle = 10 * time.Second
+
+// triggers are the event types that can change who qualifies for a role.
+var triggers = []event.Type{
+	event.DKPAwarded, event.DKPDeducted, event.DKPAdjusted,
+	event.PlayerRegistered, event.PlayerArchived, event.PlayerRestored,
+}
+
+// Players lists the registered players.
+type Players interface {
+	List(ctx context.Context) ([]store.Player, error)
+}
+
+// Change is a role given to or taken from a player.
+type Change struct {
+	DiscordID     string
+	CharacterName string
+	RoleID        string
+	// Add reports whether the role is given rather than taken.
+	Add bool
+}
+
+// Qualified returns the roles of rules each player qualifies for, by
+// Discord ID. Ranks are those of the standings without archived players,
+// who qualify for none.
+func Qualified(rules []config.RoleRule, players []store.Player) map[string][]string {
+	active := slices.DeleteFunc(slices.Clone(players), store.Player.Archived)
+	ranks := make(map[string]int, len(active))
+	for _, st := range leaderboard.Rank(slices.Clone(active)) {
+		ranks[st.PlayerID] = st.Rank
+	}
+	qualified := make(map[string][]string, len(active))
+	for _, p := range active {
+		for _, r := range rules {
+			if r.TopRank > 0 && ranks[p.ID] <= r.TopRank || r.TopRank == 0 && p.DKP >= r.MinDKP {
+				qualified[p.DiscordID] = append(qualified[p.DiscordID], r.RoleID)
+			}
+		}
+	}
+	return qualified
+}
+
+// changes returns the changes that give p, whose member roles are has, the
+// roles of rules in want and take the others. Other roles are left alone.
+func changes(rules []config.RoleRule, p store.Player, has, want []string) []Change {
+	var cs []Change
+	for _, r := range rules {
+		switch in, wanted := slices.Contains(has, r.RoleID), slices.Contains(want, r.RoleID); {
+		case wanted && !in:
+			cs = append(cs, Change{DiscordID: p.DiscordID, CharacterName: p.CharacterName, RoleID: r.RoleID, Add: true})
+		case in && !wanted:
+			cs = append(cs, Change{DiscordID: p.DiscordID, CharacterName: p.CharacterName, RoleID: r.RoleID})
+		}
+	}
+	return cs
+}
+
+// Syncer synchronizes the roles of a guild's players. Only the leader
+// should run it.
+type Syncer struct {
+	cfg     config.RoleSyncConfig
+	players Players
+	guildID string
+	session func() *discordgo.Session
+	logger  *slog.Logger
+	tracer  trace.Tracer
+
+	// mu serializes syncs.
+	mu sync.Mutex
+}
+
+// NewSyncer returns a Syncer for guildID. session returns the current
+// Discord session, or nil while none is open.
+func NewSyncer(cfg config.RoleSyncConfig, players Players, guildID string, session func() *discordgo.Session, logger *slog.Logger, tp trace.TracerProvider) *Syncer {
+	return &Syncer{
+		cfg:     cfg,
+		players: players,
+		guildID: guildID,
+		session: session,
+		logger:  logger,
+		tracer:  tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/rolesync"),
+	}
+}
+
+// DryRun reports whether the config only allows logging role changes.
+func (s *Syncer) DryRun() bool {
+	return s.cfg.DryRun
+}
+
+// Run syncs roles at the configured interval, and shortly after the DKP
+// changes published on bus, until ctx is done.
+func (s *Syncer) Run(ctx context.Context, bus *event.Bus) {
+	changed := make(chan struct{}, 1)
+	unsubscribe := bus.Subscribe(func(context.Context, event.Event) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}, triggers...)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			if settled == nil {
+				settled = time.After(settle)
+			}
+			continue
+		case <-settled:
+			settled = nil
+		case <-ticker.C:
+		}
+		if _, err := s.Sync(ctx, false); err != nil {
+			s.logger.ErrorContext(ctx, "syncing roles failed", slog.Any("error", err))
+		}
+	}
+}
+
+// Sync gives and takes the synchronized roles of the registered players
+// who are members of the guild, and returns the changes. With dryRun, or
+// the dry_run config, the changes are only logged. If a change fails, the
+// changes made so far are returned with the error.
+func (s *Syncer) Sync(ctx context.Context, dryRun bool) ([]Change, error) {
+	dryRun = dryRun || s.cfg.DryRun
+	ctx, span := s.tracer.Start(ctx, "Syncer.Sync",
+		trace.WithAttributes(attribute.Bool("dry_run", dryRun)),
+	)
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess := s.session()
+	if sess == nil {
+		return nil, errors.New("no Discord session is open")
+	}
+	players, err := s.players.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing players: %w", err)
+	}
+	qualified := Qualified(s.cfg.Roles, players)
+
+	var planned []Change
+	for _, p := range players {
+		if !snowflake(p.DiscordID) {
+			continue
+		}
+		m, err := sess.GuildMember(s.guildID, p.DiscordID, discordgo.WithContext(ctx))
+		switch {
+		case unknownMember(err):
+			// The player left the guild.
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("fetching member %s: %w", p.DiscordID, err)
+		}
+		planned = append(planned, changes(s.cfg.Roles, p, m.Roles, qualified[p.DiscordID])...)
+	}
+	span.SetAttributes(attribute.Int("changes", len(planned)))
+
+	for n, c := range planned {
+		attrs := []any{
+			slog.String("discord_id", c.DiscordID),
+			slog.String("character", c.CharacterName),
+			slog.String("role_id", c.RoleID),
+			slog.Bool("add", c.Add),
+		}
+		if dryRun {
+			s.logger.InfoContext(ctx, "role change (dry run)", attrs...)
+			continue
+		}
+		if n > 0 && !pause(ctx, s.cfg.UpdateInterval) {
+			return planned[:n], ctx.Err()
+		}
+		if err := s.apply(ctx, sess, c); err != nil {
+			return planned[:n], fmt.Errorf("changing role %s of %s: %w", c.RoleID, c.CharacterName, err)
+		}
+		s.logger.InfoContext(ctx, "role changed", attrs...)
+	}
+	return planned, nil
+}
+
+// apply makes c. If Discord reports a rate limit, it is waited out and the
+// change retried once.
+func (s *Syncer) apply(ctx context.Context, sess *discordgo.Session, c Change) error {
+	call := sess.GuildMemberRoleRemove
+	if c.Add {
+		call = sess.GuildMemberRoleAdd
+	}
+	err := call(s.guildID, c.DiscordID, c.RoleID, discordgo.WithContext(ctx))
+	var limited *discordgo.RateLimitError
+	if !errors.As(err, &limited) {
+		return err
+	}
+	s.logger.WarnContext(ctx, "role change rate limited", slog.Duration("retry_after", limited.RetryAfter))
+	if !pause(ctx, limited.RetryAfter) {
+		return ctx.Err()
+	}
+	return call(s.guildID, c.DiscordID, c.RoleID, discordgo.WithContext(ctx))
+}
+
+// pause waits for d, and reports false if ctx is done first.
+func pause(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// unknownMember reports whether err is Discord's answer for a user who is
+// not a member of the guild.
+func unknownMember(err error) bool {
+	var restErr *discordgo.RESTError
+	return errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownMember
+}
+
+// snowflake reports whether id looks like a Discord ID. Placeholder IDs of
+// imported characters nobody registered do not.
+func snowflake(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package rolesync_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/rolesync"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+const (
+	topRole   = "100"
+	veteran   = "200"
+	otherRole = "300"
+)
+
+var rules = []config.RoleRule{{RoleID: topRole, TopRank: 2}, {RoleID: veteran, MinDKP: 50}}
+
+func TestQualified(t *testing.T) {
+	archivedAt := time.Now()
+	got := rolesync.Qualified(rules, []store.Player{
+		{ID: "p1", DiscordID: "1", CharacterName: "Frodo", DKP: 40},
+		{ID: "p2", DiscordID: "2", CharacterName: "Sam", DKP: 60},
+		{ID: "p3", DiscordID: "3", CharacterName: "Merry", DKP: 90, ArchivedAt: &archivedAt},
+		{ID: "p4", DiscordID: "4", CharacterName: "Pippin", DKP: 10},
+	})
+	want := map[string][]string{"1": {topRole}, "2": {topRole, veteran}}
+	if len(got) != len(want) {
+		t.Fatalf("Qualified() = %v, want %v", got, want)
+	}
+	for id, roles := range want {
+		if !slices.Equal(got[id], roles) {
+			t.Errorf("Qualified()[%s] = %v, want %v", id, got[id], roles)
+		}
+	}
+}
+
+// guildTransport answers Discord's member and role endpoints for members
+// with the given roles, and records role changes.
+type guildTransport struct {
+	mu      sync.Mutex
+	roles   map[string]string
+	changes []string
+}
+
+func (rt *guildTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	path := strings.TrimPrefix(req.URL.Path, "/api/v9/guilds/guild-1/members/")
+	status, body := http.StatusNoContent, ""
+	if req.Method == http.MethodGet {
+		roles, ok := rt.roles[path]
+		if ok {
+			status, body = http.StatusOK, `{"user":{"id":"`+path+`"},"roles":[`+roles+`]}`
+		} else {
+			status, body = http.StatusNotFound, `{"code":10007,"message":"Unknown Member"}`
+		}
+	} else {
+		rt.changes = append(rt.changes, req.Method+" "+path)
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestSyncer_Sync(t *testing.T) {
+	players := storetest.NewPlayers(
+		store.Player{ID: "p1", DiscordID: "1", CharacterName: "Frodo", DKP: 40},
+		store.Player{ID: "p2", DiscordID: "2", CharacterName: "Sam", DKP: 60},
+		store.Player{ID: "p3", DiscordID: "3", CharacterName: "Merry", DKP: 10},
+		store.Player{ID: "p4", DiscordID: "4", CharacterName: "Pippin", DKP: 5},       // left the guild
+		store.Player{ID: "p5", DiscordID: "eqdkp:5", CharacterName: "Bilbo", DKP: 30}, // never registered
+	)
+	rt := &guildTransport{roles: map[string]string{
+		"1": `"` + otherRole + `"`,
+		"2": `"` + topRole + `"`,
+		"3": `"` + topRole + `","` + veteran + `","` + otherRole + `"`,
+	}}
+	s, _ := discordgo.New("Bot token")
+	s.Client = &http.Client{Transport: rt}
+	cfg := config.RoleSyncConfig{Roles: rules, Interval: time.Hour}
+	syncer := rolesync.NewSyncer(cfg, players, "guild-1", func() *discordgo.Session { return s },
+		slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+	want := []rolesync.Change{
+		{DiscordID: "1", CharacterName: "Frodo", RoleID: topRole, Add: true},
+		{DiscordID: "2", CharacterName: "Sam", RoleID: veteran, Add: true},
+		{DiscordID: "3", CharacterName: "Merry", RoleID: topRole},
+		{DiscordID: "3", CharacterName: "Merry", RoleID: veteran},
+	}
+	changes, err := syncer.Sync(context.Background(), true)
+	if err != nil || !slices.Equal(changes, want) {
+		t.Fatalf("Sync(dry run) = %+v, %v, want %+v", changes, err, want)
+	}
+	if len(rt.changes) != 0 {
+		t.Errorf("dry run changed roles: %v", rt.changes)
+	}
+
+	if changes, err := syncer.Sync(context.Background(), false); err != nil || !slices.Equal(changes, want) {
+		t.Fatalf("Sync() = %+v, %v, want %+v", changes, err, want)
+	}
+	wantCalls := []string{"PUT 1/roles/100", "PUT 2/roles/200", "DELETE 3/roles/100", "DELETE 3/roles/200"}
+	if !slices.Equal(rt.changes, wantCalls) {
+		t.Errorf("role changes = %v, want %v", rt.changes, wantCalls)
+	}
+}
@@ -0,0 +1,148 @@
+package roster
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/settings"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// Players lists the registered players.
+type Players interface {
+	List(ctx context.Context) ([]store.Player, error)
+}
+
+// Reviewer finds the inactive players of a guild and, on the configured
+// schedule, proposes archiving them in the officer channel. Only the leader
+// should run it.
+type Reviewer struct {
+	cfg      config.RosterConfig
+	players  Players
+	events   event.Store
+	settings *settings.Service
+	guildID  string
+	session  func() *discordgo.Session
+	claims   *idempotency.Guard
+	logger   *slog.Logger
+	tracer   trace.Tracer
+	clock    clock.Clock
+}
+
+// NewReviewer returns a Reviewer for guildID. session returns the current
+// Discord session, or nil while none is open. claims ensures each week's
+// proposal is posted once even if leadership changes hands around the
+// posting time.
+func NewReviewer(cfg config.RosterConfig, players Players, events event.Store, svc *settings.Service, guildID string, session func() *discordgo.Session, claims *idempotency.Guard, logger *slog.Logger, tp trace.TracerProvider, clk clock.Clock) *Reviewer {
+	return &Reviewer{
+		cfg:      cfg,
+		players:  players,
+		events:   events,
+		settings: svc,
+		guildID:  guildID,
+		session:  session,
+		claims:   claims,
+		logger:   logger,
+		tracer:   tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/roster"),
+		clock:    clk,
+	}
+}
+
+// Weeks returns how many weeks without activity the weekly review looks
+// for, or zero if it is disabled.
+func (r *Reviewer) Weeks() int {
+	return r.cfg.InactiveWeeks
+}
+
+// Run posts a proposal at each scheduled time until ctx is done.
+func (r *Reviewer) Run(ctx context.Context) {
+	for {
+		next := r.cfg.Next(r.clock.Now(), r.settings.Location(ctx, r.guildID))
+		timer := clock.NewTimer(r.clock, next.Sub(r.clock.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		if err := r.Propose(ctx); err != nil {
+			r.logger.ErrorContext(ctx, "proposing roster cleanup failed", slog.Any("error", err))
+		}
+	}
+}
+
+// Propose posts a proposal to archive the players inactive for the
+// configured number of weeks in the officer channel, unless none is set,
+// nobody is inactive, or one was already posted this week.
+func (r *Reviewer) Propose(ctx context.Context) error {
+	year, week := r.clock.Now().UTC().ISOWeek()
+	ctx, span := r.tracer.Start(ctx, "Reviewer.Propose",
+		trace.WithAttributes(attribute.String("week", fmt.Sprintf("%d-W%02d", year, week))),
+	)
+	defer span.End()
+
+	gs, err := r.settings.Get(ctx, r.guildID)
+	if err != nil {
+		return err
+	}
+	if gs.OfficerChannel == "" {
+		return nil
+	}
+	s := r.session()
+	if s == nil {
+		return fmt.Errorf("no Discord session is open")
+	}
+
+	inactive, err := r.Inactive(ctx, r.cfg.InactiveWeeks)
+	if err != nil {
+		return err
+	}
+	if len(inactive) == 0 {
+		return nil
+	}
+
+	claimed, err := r.claims.Claim(idempotency.WithKey(ctx, fmt.Sprintf("%d-W%02d", year, week)), "roster.propose")
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+	if _, err := s.ChannelMessageSendComplex(gs.OfficerChannel, Proposal(inactive, r.cfg.InactiveWeeks), discordgo.WithContext(ctx)); err != nil {
+		return fmt.Errorf("sending roster proposal: %w", err)
+	}
+	r.logger.InfoContext(ctx, "roster cleanup proposed",
+		slog.String("channel_id", gs.OfficerChannel),
+		slog.Int("players", len(inactive)),
+	)
+	return nil
+}
+
+// Inactive returns the players who are not archived and have not taken
+// part for weeks, longest inactive first.
+func (r *Reviewer) Inactive(ctx context.Context, weeks int) ([]Inactive, error) {
+	ctx, span := r.tracer.Start(ctx, "Reviewer.Inactive",
+		trace.WithAttributes(attribute.Int("weeks", weeks)),
+	)
+	defer span.End()
+
+	players, err := r.players.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing players: %w", err)
+	}
+	activity, err := r.events.Query(ctx, event.Query{Types: activityTypes})
+	if err != nil {
+		return nil, fmt.Errorf("querying activity: %w", err)
+	}
+	return Find(players, activity, r.clock.Now().Add(-time.Duration(weeks)*Week))
+}
@@ -0,0 +1,130 @@
+// Package roster finds players who have stopped raiding and proposes
+// archiving them to officers.
+//
+// A player is inactive if no event shows them taking part since a cutoff:
+// they were not awarded or charged DKP, did not bid or roll on an auction,
+// and did not join a raid or sign up for one. Players registered after the
+// cutoff are never inactive.
+package roster
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// ArchiveAction prefixes the custom IDs of the proposal's buttons, which
+// are followed by ":" and the Discord ID of the player to archive.
+const ArchiveAction = "roster-archive"
+
+// Week is the unit inactivity is measured in.
+const Week = 7 * 24 * time.Hour
+
+// maxButtons is how many players a proposal offers to archive: Discord
+// allows five rows of five buttons.
+const maxButtons = 25
+
+// activityTypes are the event types that show a player taking part.
+var activityTypes = []event.Type{
+	event.DKPAwarded, event.DKPDeducted, event.DKPAdjusted,
+	event.AuctionBidPlaced, event.AuctionRolled,
+	event.GDKPRaidJoined, event.RaidSignedUp,
+}
+
+// participant names the player an activity event is about. DKP, bid, and
+// roll events name them by player ID; raid events by Discord ID.
+type participant struct {
+	PlayerID  string `json:"player_id"`
+	DiscordID string `json:"discord_id"`
+}
+
+// Inactive is a player found inactive.
+type Inactive struct {
+	Player store.Player
+	// LastActive is when the player last took part, or zero if they never
+	// did.
+	LastActive time.Time
+}
+
+// Find returns the players who are not archived and have not taken part
+// since since, given the activity events of all time, longest inactive
+// first and ties by name.
+func Find(players []store.Player, activity []event.Event, since time.Time) ([]Inactive, error) {
+	byDiscordID := make(map[string]string, len(players))
+	for _, p := range players {
+		byDiscordID[p.DiscordID] = p.ID
+	}
+	last := make(map[string]time.Time)
+	for _, e := range activity {
+		var d participant
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return nil, fmt.Errorf("decoding event %s: %w", e.ID, err)
+		}
+		id := d.PlayerID
+		if id == "" {
+			id = byDiscordID[d.DiscordID]
+		}
+		if e.CreatedAt.After(last[id]) {
+			last[id] = e.CreatedAt
+		}
+	}
+
+	var inactive []Inactive
+	for _, p := range players {
+		if p.Archived() || p.CreatedAt.After(since) || last[p.ID].After(since) {
+			continue
+		}
+		inactive = append(inactive, Inactive{Player: p, LastActive: last[p.ID]})
+	}
+	slices.SortFunc(inactive, func(a, b Inactive) int {
+		return cmp.Or(a.LastActive.Compare(b.LastActive), strings.Compare(a.Player.CharacterName, b.Player.CharacterName))
+	})
+	return inactive, nil
+}
+
+// Proposal returns the message proposing to archive the players found
+// inactive for weeks, with a button to archive each of the first 25.
+func Proposal(inactive []Inactive, weeks int) *discordgo.MessageSend {
+	shown := inactive[:min(len(inactive), maxButtons)]
+	lines := make([]string, 0, len(shown)+1)
+	var rows []discordgo.MessageComponent
+	for i, in := range shown {
+		active := "never active"
+		if !in.LastActive.IsZero() {
+			active = fmt.Sprintf("last active <t:%d:R>", in.LastActive.Unix())
+		}
+		lines = append(lines, fmt.Sprintf("**%s** — %d DKP, %s", in.Player.CharacterName, in.Player.DKP, active))
+
+		if i%5 == 0 {
+			rows = append(rows, discordgo.ActionsRow{})
+		}
+		row := rows[len(rows)-1].(discordgo.ActionsRow)
+		row.Components = append(row.Components, discordgo.Button{
+			Label:    "Archive " + in.Player.CharacterName,
+			Style:    discordgo.DangerButton,
+			CustomID: ArchiveAction + ":" + in.Player.DiscordID,
+		})
+		rows[len(rows)-1] = row
+	}
+	if n := len(inactive) - len(shown); n > 0 {
+		lines = append(lines, fmt.Sprintf("…and %d more", n))
+	}
+
+	return &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{{
+			Title: "Roster cleanup",
+			Description: fmt.Sprintf("%d players have had no attendance or DKP activity for %d weeks. "+
+				"Archiving a player freezes their DKP and keeps their history; `/roster-restore` brings them back.\n\n%s",
+				len(inactive), weeks, strings.Join(lines, "\n")),
+		}},
+		Components: rows,
+	}
+}
@@ -0,0 +1,159 @@
+package roster_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/discordtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/roster"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/settings"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+var now = time.Date(2025, 6, 16, 17, 0, 0, 0, time.UTC)
+
+type memEvents struct {
+	events []event.Event
+}
+
+func (m *memEvents) Append(_ context.Context, events ...event.Event) error {
+	m.events = append(m.events, events...)
+	return nil
+}
+
+func (m *memEvents) Load(context.Context, string) ([]event.Event, error) { return nil, nil }
+
+func (m *memEvents) LoadByType(context.Context, event.Type) ([]event.Event, error) { return nil, nil }
+
+func (m *memEvents) Query(_ context.Context, q event.Query) ([]event.Event, error) {
+	return q.Filter(m.events), nil
+}
+
+func (m *memEvents) add(typ event.Type, data any, at time.Time) {
+	raw, _ := json.Marshal(data)
+	m.events = append(m.events, event.Event{Type: typ, Data: raw, CreatedAt: at})
+}
+
+type fixedPlayers []store.Player
+
+func (f fixedPlayers) List(context.Context) ([]store.Player, error) { return f, nil }
+
+func TestReviewer_Inactive(t *testing.T) {
+	old := now.Add(-20 * roster.Week)
+	players := fixedPlayers{
+		{ID: "p1", DiscordID: "d1", CharacterName: "Alice", DKP: 100, CreatedAt: old},
+		{ID: "p2", DiscordID: "d2", CharacterName: "Bob", DKP: 50, CreatedAt: old},
+		{ID: "p3", DiscordID: "d3", CharacterName: "Carol", DKP: 20, CreatedAt: old},
+		{ID: "p4", DiscordID: "d4", CharacterName: "Dave", DKP: 10, CreatedAt: old},
+		{ID: "p5", DiscordID: "d5", CharacterName: "Erin", CreatedAt: now.Add(-roster.Week)},
+		{ID: "p6", DiscordID: "d6", CharacterName: "Frank", CreatedAt: old, ArchivedAt: &old},
+		{ID: "p7", DiscordID: "d7", CharacterName: "Grace", DKP: 5, CreatedAt: old},
+	}
+	events := &memEvents{}
+	// Alice was awarded DKP long ago, Bob bid recently, Carol joined a
+	// raid recently, Dave signed up recently, and Grace only rolled long
+	// before Alice's award.
+	events.add(event.DKPAwarded, event.DKPChangeData{PlayerID: "p1", Amount: 100}, now.Add(-6*roster.Week))
+	events.add(event.AuctionBidPlaced, event.BidPlacedData{PlayerID: "p2", Amount: 10}, now.Add(-roster.Week))
+	events.add(event.GDKPRaidJoined, event.GDKPRaidJoinedData{DiscordID: "d3"}, now.Add(-2*roster.Week))
+	events.add(event.RaidSignedUp, event.RaidSignedUpData{DiscordID: "d4"}, now.Add(-24*time.Hour))
+	events.add(event.AuctionRolled, event.AuctionRolledData{PlayerID: "p7"}, now.Add(-10*roster.Week))
+
+	r := roster.NewReviewer(config.RosterConfig{InactiveWeeks: 4, Weekday: "monday", Time: "17:00"},
+		players, events, nil, "guild", nil, nil,
+		slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clock.Mock{T: now})
+	inactive, err := r.Inactive(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("Inactive() error = %v", err)
+	}
+
+	var names []string
+	for _, in := range inactive {
+		names = append(names, in.Player.CharacterName)
+	}
+	if got, want := strings.Join(names, ","), "Grace,Alice"; got != want {
+		t.Fatalf("inactive = %s, want %s", got, want)
+	}
+	if !inactive[1].LastActive.Equal(now.Add(-6 * roster.Week)) {
+		t.Errorf("Alice last active %s, want 6 weeks ago", inactive[1].LastActive)
+	}
+}
+
+func TestReviewer_Run(t *testing.T) {
+	svc := settings.NewService(storetest.NewGuildSettings(store.GuildSetting{GuildID: "guild", Key: settings.OfficerChannel, Value: "123456789012345678"}),
+		settings.Defaults(config.GuildDefaultsConfig{}), slog.New(slog.DiscardHandler))
+	session, rt := discordtest.NewSession()
+	// The review is posted on Mondays at 17:00, a day from now.
+	clk := clock.NewFakeClock(now.Add(-24 * time.Hour))
+	r := roster.NewReviewer(config.RosterConfig{InactiveWeeks: 4, Weekday: "monday", Time: "17:00"},
+		fixedPlayers{{ID: "p1", DiscordID: "d1", CharacterName: "Alice", CreatedAt: now.Add(-20 * roster.Week)}},
+		&memEvents{}, svc, "guild", func() *discordgo.Session { return session }, nil,
+		slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clk)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+
+	clk.BlockUntil(1)
+	clk.Advance(23 * time.Hour)
+	if reqs := rt.Requests(); len(reqs) != 0 {
+		t.Fatalf("posted %+v before the scheduled time", reqs)
+	}
+	clk.Advance(time.Hour)
+	reqs := rt.Await(t, 1)
+	if reqs[0].Path != "/api/v9/channels/123456789012345678/messages" || !strings.Contains(reqs[0].Body, "Alice") {
+		t.Errorf("posted %+v, want a proposal naming Alice in the officer channel", reqs[0])
+	}
+
+	clk.BlockUntil(1)
+	clk.Advance(roster.Week)
+	rt.Await(t, 2)
+}
+
+func TestProposal(t *testing.T) {
+	var inactive []roster.Inactive
+	for i := range 27 {
+		inactive = append(inactive, roster.Inactive{
+			Player: store.Player{DiscordID: fmt.Sprintf("d%d", i), CharacterName: fmt.Sprintf("Player%d", i), DKP: i},
+		})
+	}
+	inactive[0].LastActive = now
+
+	msg := roster.Proposal(inactive, 4)
+
+	desc := msg.Embeds[0].Description
+	for _, want := range []string{
+		"27 players have had no attendance or DKP activity for 4 weeks.",
+		fmt.Sprintf("**Player0** — 0 DKP, last active <t:%d:R>", now.Unix()),
+		"**Player1** — 1 DKP, never active",
+		"…and 2 more",
+	} {
+		if !strings.Contains(desc, want) {
+			t.Errorf("description %q does not contain %q", desc, want)
+		}
+	}
+	if len(msg.Components) != 5 {
+		t.Fatalf("rows = %d, want 5", len(msg.Components))
+	}
+	for _, c := range msg.Components {
+		if n := len(c.(discordgo.ActionsRow).Components); n != 5 {
+			t.Errorf("row has %d buttons, want 5", n)
+		}
+	}
+	b := msg.Components[0].(discordgo.ActionsRow).Components[1].(discordgo.Button)
+	if b.CustomID != roster.ArchiveAction+":d1" || b.Label != "Archive Player1" {
+		t.Errorf("button = %q %q, want archive of d1", b.CustomID, b.Label)
+	}
+}
@@ -0,0 +1,119 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+)
+
+// GCP reads secrets from Google Secret Manager. A reference is a secret
+// name such as "dkpbot-discord-token", which reads the latest version, or
+// a name with a version such as "dkpbot-discord-token/versions/3".
+//
+// It authenticates as the workload's service account through the metadata
+// server, as on GKE with Workload Identity or on Compute Engine. The
+// GCE_METADATA_HOST environment variable overrides the metadata server
+// address, as in Google's client libraries.
+type GCP struct {
+	cfg      config.GCPSecretsConfig
+	client   *http.Client
+	metadata string
+	endpoint string
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewGCP returns a Google Secret Manager provider.
+func NewGCP(cfg config.GCPSecretsConfig, client *http.Client) *GCP {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	return &GCP{
+		cfg:      cfg,
+		client:   client,
+		metadata: "http://" + host,
+		endpoint: "https://secretmanager.googleapis.com",
+	}
+}
+
+// Fetch returns the payload of the referenced secret version.
+func (g *GCP) Fetch(ctx context.Context, ref string) (string, error) {
+	name := strings.Trim(ref, "/")
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	target := fmt.Sprintf("%s/v1/projects/%s/secrets/%s:access", g.endpoint, g.cfg.Project, name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	var resp struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := g.send(req, &resp); err != nil {
+		return "", fmt.Errorf("accessing secret %q: %w", ref, err)
+	}
+	data, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("decoding secret %q: %w", ref, err)
+	}
+	return string(data), nil
+}
+
+// accessToken returns a cached OAuth token for the default service account,
+// fetching a new one from the metadata server shortly before it expires.
+func (g *GCP) accessToken(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && time.Now().Before(g.expiry) {
+		return g.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		g.metadata+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := g.send(req, &resp); err != nil {
+		return "", fmt.Errorf("fetching access token from metadata server: %w", err)
+	}
+	g.token = resp.AccessToken
+	g.expiry = time.Now().Add(time.Duration(resp.ExpiresIn)*time.Second - time.Minute)
+	return g.token, nil
+}
+
+func (g *GCP) send(req *http.Request, out any) error {
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
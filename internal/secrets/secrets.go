@@ -0,0 +1,112 @@
+// Package secrets fetches credentials such as the Discord token from an
+// external secrets provider and keeps them current as they are rotated.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+)
+
+// Provider reads secrets from an external store.
+type Provider interface {
+	// Fetch returns the current value of the secret named by ref.
+	Fetch(ctx context.Context, ref string) (string, error)
+}
+
+// NewProvider returns the provider selected by cfg.
+func NewProvider(cfg config.SecretsConfig, client *http.Client) (Provider, error) {
+	switch cfg.Provider {
+	case config.SecretsVault:
+		return NewVault(cfg.Vault, client), nil
+	case config.SecretsGCP:
+		return NewGCP(cfg.GCP, client), nil
+	}
+	return nil, fmt.Errorf("unknown secrets provider %q", cfg.Provider)
+}
+
+// Secret is a value fetched from a Provider. It is safe for concurrent
+// use.
+type Secret struct {
+	name string
+	ref  string
+
+	mu    sync.RWMutex
+	value string
+}
+
+// Value returns the most recently fetched value.
+func (s *Secret) Value() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.value
+}
+
+// set stores value and reports whether it changed.
+func (s *Secret) set(value string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := s.value != value
+	s.value = value
+	return changed
+}
+
+// Rotator fetches a set of secrets and refetches them periodically.
+type Rotator struct {
+	provider Provider
+	logger   *slog.Logger
+	secrets  []*Secret
+}
+
+// NewRotator returns a Rotator that reads from provider.
+func NewRotator(provider Provider, logger *slog.Logger) *Rotator {
+	return &Rotator{provider: provider, logger: logger}
+}
+
+// Add registers the secret referenced by ref under name, which is used in
+// logs. Its value is empty until Refresh is called.
+func (r *Rotator) Add(name, ref string) *Secret {
+	s := &Secret{name: name, ref: ref}
+	r.secrets = append(r.secrets, s)
+	return s
+}
+
+// Refresh fetches every secret. Secrets that fail to fetch keep their
+// previous value, and the failures are returned together.
+func (r *Rotator) Refresh(ctx context.Context) error {
+	var errs []error
+	for _, s := range r.secrets {
+		value, err := r.provider.Fetch(ctx, s.ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("fetching %s: %w", s.name, err))
+			continue
+		}
+		if s.set(value) {
+			r.logger.InfoContext(ctx, "secret loaded", slog.String("secret", s.name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run refreshes the secrets every interval until ctx is canceled. Failed
+// refreshes are logged; the previous values stay in use.
+func (r *Rotator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Refresh(ctx); err != nil {
+				r.logger.WarnContext(ctx, "refreshing secrets failed, keeping previous values", slog.Any("error", err))
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+)
+
+func TestVault_Fetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/kv/data/dkpbot/discord" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, `{"data":{"data":{"token":"discord-secret"},"metadata":{"version":2}}}`)
+	}))
+	defer srv.Close()
+
+	v := NewVault(config.VaultConfig{Address: srv.URL, Mount: "kv", Token: "root"}, srv.Client())
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "dkpbot/discord#token", want: "discord-secret"},
+		{ref: "dkpbot/discord#password", wantErr: true},
+		{ref: "dkpbot/missing#token", wantErr: true},
+		{ref: "dkpbot/discord", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := v.Fetch(context.Background(), tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Fetch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVault_KubernetesLogin(t *testing.T) {
+	jwtPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(jwtPath, []byte("service-account-jwt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	logins := 0
+	valid := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/v1/auth/kubernetes/login" {
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["role"] != "dkpbot" || body["jwt"] != "service-account-jwt" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			logins++
+			valid = fmt.Sprintf("token-%d", logins)
+			_ = json.NewEncoder(w).Encode(map[string]any{"auth": map[string]string{"client_token": valid}})
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != valid {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = io.WriteString(w, `{"data":{"data":{"password":"db-secret"}}}`)
+	}))
+	defer srv.Close()
+
+	v := NewVault(config.VaultConfig{Address: srv.URL, Mount: "secret", Role: "dkpbot", AuthPath: "kubernetes"}, srv.Client())
+	v.tokenPath = jwtPath
+	ctx := context.Background()
+
+	if got, err := v.Fetch(ctx, "dkpbot/db#password"); err != nil || got != "db-secret" {
+		t.Fatalf("Fetch() = %q, %v", got, err)
+	}
+	// The login token expires; the next fetch logs in again.
+	mu.Lock()
+	valid = "expired"
+	mu.Unlock()
+	if got, err := v.Fetch(ctx, "dkpbot/db#password"); err != nil || got != "db-secret" {
+		t.Fatalf("Fetch() after expiry = %q, %v", got, err)
+	}
+	if logins != 2 {
+		t.Errorf("logged in %d times, want 2", logins)
+	}
+}
+
+func TestGCP_Fetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/service-accounts/default/token":
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = io.WriteString(w, `{"access_token":"oauth","expires_in":3600}`)
+		case "/v1/projects/raid/secrets/discord-token/versions/latest:access",
+			"/v1/projects/raid/secrets/discord-token/versions/3:access":
+			if r.Header.Get("Authorization") != "Bearer oauth" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			// "discord-secret", base64 encoded.
+			_, _ = io.WriteString(w, `{"payload":{"data":"ZGlzY29yZC1zZWNyZXQ="}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	g := NewGCP(config.GCPSecretsConfig{Project: "raid"}, srv.Client())
+	g.metadata, g.endpoint = srv.URL, srv.URL
+
+	for _, ref := range []string{"discord-token", "discord-token/versions/3"} {
+		got, err := g.Fetch(context.Background(), ref)
+		if err != nil {
+			t.Fatalf("Fetch(%q) error = %v", ref, err)
+		}
+		if got != "discord-secret" {
+			t.Errorf("Fetch(%q) = %q, want %q", ref, got, "discord-secret")
+		}
+	}
+	if _, err := g.Fetch(context.Background(), "missing"); err == nil {
+		t.Error("Fetch(missing) succeeded, want an error")
+	}
+}
+
+// mapProvider serves secrets from a map.
+type mapProvider struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (p *mapProvider) Fetch(_ context.Context, ref string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	v, ok := p.values[ref]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return v, nil
+}
+
+func TestRotator_Refresh(t *testing.T) {
+	p := &mapProvider{values: map[string]string{"token": "v1", "password": "p1"}}
+	r := NewRotator(p, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	token := r.Add("discord_token", "token")
+	password := r.Add("database_password", "password")
+	ctx := context.Background()
+
+	if err := r.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if token.Value() != "v1" || password.Value() != "p1" {
+		t.Fatalf("values = %q, %q, want v1, p1", token.Value(), password.Value())
+	}
+
+	// The token is rotated and the password becomes unreadable.
+	p.mu.Lock()
+	p.values["token"] = "v2"
+	delete(p.values, "password")
+	p.mu.Unlock()
+
+	if err := r.Refresh(ctx); err == nil {
+		t.Error("Refresh() succeeded, want an error for the missing password")
+	}
+	if token.Value() != "v2" {
+		t.Errorf("token = %q, want the rotated v2", token.Value())
+	}
+	if password.Value() != "p1" {
+		t.Errorf("password = %q, want the previous p1", password.Value())
+	}
+}
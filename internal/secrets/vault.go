@@ -0,0 +1,128 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+)
+
+// serviceAccountTokenPath is where Kubernetes mounts the pod's service
+// account token.
+const serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Vault reads secrets from a KV v2 secrets engine of HashiCorp Vault. A
+// reference has the form "path#key", for example "dkpbot/discord#token".
+type Vault struct {
+	cfg       config.VaultConfig
+	client    *http.Client
+	tokenPath string
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewVault returns a Vault provider. Without a configured token it logs in
+// with the Kubernetes auth method when it first fetches a secret, and again
+// once the token is rejected.
+func NewVault(cfg config.VaultConfig, client *http.Client) *Vault {
+	return &Vault{cfg: cfg, client: client, tokenPath: serviceAccountTokenPath, token: cfg.Token}
+}
+
+// Fetch returns the key of the secret at path.
+func (v *Vault) Fetch(ctx context.Context, ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok {
+		return "", fmt.Errorf("vault reference %q is not of the form path#key", ref)
+	}
+
+	var resp struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	target := strings.TrimRight(v.cfg.Address, "/") + "/v1/" + strings.Trim(v.cfg.Mount, "/") + "/data/" + strings.Trim(path, "/")
+	status, err := v.do(ctx, http.MethodGet, target, nil, &resp)
+	if (status == http.StatusForbidden || status == http.StatusUnauthorized) && v.cfg.Role != "" {
+		// The login token expired; log in again.
+		v.mu.Lock()
+		v.token = ""
+		v.mu.Unlock()
+		status, err = v.do(ctx, http.MethodGet, target, nil, &resp)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := resp.Data.Data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %q has no string key %q", path, key)
+	}
+	return value, nil
+}
+
+// do sends an authenticated request and decodes the JSON response into out.
+func (v *Vault) do(ctx context.Context, method, target string, body []byte, out any) (int, error) {
+	token, err := v.currentToken(ctx)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	return v.send(req, out)
+}
+
+func (v *Vault) send(req *http.Request, out any) (int, error) {
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("calling vault: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("vault %s %s: status %d", req.Method, req.URL.Path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, fmt.Errorf("decoding vault response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// currentToken returns the configured token, or logs in with the
+// Kubernetes auth method.
+func (v *Vault) currentToken(ctx context.Context) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.token != "" {
+		return v.token, nil
+	}
+
+	jwt, err := os.ReadFile(v.tokenPath)
+	if err != nil {
+		return "", fmt.Errorf("reading service account token: %w", err)
+	}
+	body, _ := json.Marshal(map[string]string{"role": v.cfg.Role, "jwt": strings.TrimSpace(string(jwt))})
+	target := strings.TrimRight(v.cfg.Address, "/") + "/v1/auth/" + strings.Trim(v.cfg.AuthPath, "/") + "/login"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if _, err := v.send(req, &resp); err != nil {
+		return "", fmt.Errorf("logging in to vault: %w", err)
+	}
+	v.token = resp.Auth.ClientToken
+	return v.token, nil
+}
@@ -0,0 +1,595 @@
+// Package settings resolves the per-guild settings officers change at
+// runtime with /settings. Changed settings are stored in the database; the
+// others keep the defaults from the config file.
+package settings
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/announce"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// ErrUnknownSetting is returned for keys that name no setting.
+var ErrUnknownSetting = derrors.New(derrors.Validation, "UNKNOWN_SETTING", "unknown setting")
+
+// Setting keys, as used by /settings and the store.
+const (
+	AuctionDuration     = "auction_duration"
+	MinIncrement        = "min_increment"
+	DecayRate           = "decay_rate"
+	UndoWindow          = "undo_window"
+	RollWindow          = "roll_window"
+	MaxOpenAuctions     = "max_open_auctions"
+	OutbidNotifications = "outbid_notifications"
+	ConfirmBidPercent   = "confirm_bid_percent"
+	BidCooldown         = "bid_cooldown"
+	BidWarCooldown      = "bid_war_cooldown"
+	LootMode            = "loot_mode"
+	AdminRoles          = "admin_roles"
+	LootChannel         = "loot_channel"
+	LeaderboardChannel  = "leaderboard_channel"
+	OfficerChannel      = "officer_channel"
+	Timezone            = "timezone"
+	StartedMessage      = "started_message"
+	OutbidMessage       = "outbid_message"
+	WinnerMessage       = "winner_message"
+	NoBidsMessage       = "no_bids_message"
+)
+
+// Settings are the effective settings of a guild.
+type Settings struct {
+	// AuctionDuration is used when an auction is started without one.
+	AuctionDuration time.Duration
+	// MinIncrement is how much a bid must exceed the highest bid by.
+	MinIncrement int
+	// DecayRate is the percentage of their balance players lose per decay
+	// period.
+	DecayRate float64
+	// UndoWindow is how long after a DKP change /dkp-undo may reverse it.
+	UndoWindow time.Duration
+	// RollWindow is how long players may roll for the item of an auction
+	// that closes without bids, or zero to close it without a winner.
+	RollWindow time.Duration
+	// MaxOpenAuctions is how many auctions may be open at once, further
+	// auctions being queued, or zero for no limit.
+	MaxOpenAuctions int
+	// OutbidNotifications is how players are told they were outbid:
+	// config.OutbidDM, config.OutbidChannel, or config.OutbidOff.
+	OutbidNotifications string
+	// ConfirmBidPercent is the percentage of their balance above which a
+	// bid must be confirmed before it is placed, or zero to place bids at
+	// once.
+	ConfirmBidPercent int
+	// BidCooldown is how long players wait between their bids on an
+	// auction, or zero for no wait.
+	BidCooldown time.Duration
+	// BidWarCooldown is how long players wait between their bids while
+	// trading bids with one other player in quick succession, or zero to
+	// wait only BidCooldown.
+	BidWarCooldown time.Duration
+	// LootMode is how loot is handed out: config.LootModeAuction,
+	// config.LootModeFixedPrice, or config.LootModeBoth.
+	LootMode string
+	// AdminRoles lists the IDs of roles whose members may use officer
+	// commands.
+	AdminRoles []string
+	// LootChannel is the ID of the channel auctions are announced in, or
+	// empty.
+	LootChannel string
+	// LeaderboardChannel is the ID of the channel the weekly leaderboard is
+	// posted in, or empty.
+	LeaderboardChannel string
+	// OfficerChannel is the ID of the channel proposals for officers are
+	// posted in, or empty.
+	OfficerChannel string
+	// Timezone is the timezone the weekly schedules run in and dates and
+	// times given to commands are read in, unless players set their own.
+	Timezone *time.Location
+	// StartedMessage, OutbidMessage, WinnerMessage, and NoBidsMessage are
+	// the templates of auction announcements; see package announce.
+	StartedMessage string
+	OutbidMessage  string
+	WinnerMessage  string
+	NoBidsMessage  string
+}
+
+// Defaults returns the settings configured in the config file.
+func Defaults(cfg config.GuildDefaultsConfig) Settings {
+	return Settings{
+		AuctionDuration:     cfg.AuctionDuration,
+		MinIncrement:        cfg.MinIncrement,
+		DecayRate:           cfg.DecayRate,
+		UndoWindow:          cfg.UndoWindow,
+		RollWindow:          cfg.RollWindow,
+		MaxOpenAuctions:     cfg.MaxOpenAuctions,
+		OutbidNotifications: cfg.OutbidNotifications,
+		ConfirmBidPercent:   cfg.ConfirmBidPercent,
+		BidCooldown:         cfg.BidCooldown,
+		BidWarCooldown:      cfg.BidWarCooldown,
+		LootMode:            cfg.LootMode,
+		AdminRoles:          slices.Clone(cfg.AdminRoles),
+		LootChannel:         cfg.LootChannel,
+		LeaderboardChannel:  cfg.LeaderboardChannel,
+		OfficerChannel:      cfg.OfficerChannel,
+		Timezone:            defaultTimezone(cfg.Timezone),
+		StartedMessage:      cfg.StartedMessage,
+		OutbidMessage:       cfg.OutbidMessage,
+		WinnerMessage:       cfg.WinnerMessage,
+		NoBidsMessage:       cfg.NoBidsMessage,
+	}
+}
+
+// defaultTimezone returns the timezone named name, or UTC if name names
+// none, as in configs that are not validated.
+func defaultTimezone(name string) *time.Location {
+	loc, err := clock.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Auctions reports whether loot may be auctioned. Guilds without a loot
+// mode auction.
+func (s Settings) Auctions() bool {
+	return s.LootMode != config.LootModeFixedPrice
+}
+
+// FixedPrices reports whether loot may be awarded at the prices of the
+// item cost table.
+func (s Settings) FixedPrices() bool {
+	return s.LootMode == config.LootModeFixedPrice || s.LootMode == config.LootModeBoth
+}
+
+// IsAdminRole reports whether role is one of the admin roles.
+func (s Settings) IsAdminRole(role string) bool {
+	return slices.Contains(s.AdminRoles, role)
+}
+
+// field describes one setting: how to parse a value into Settings and how
+// to format it back.
+type field struct {
+	key    string
+	help   string
+	parse  func(s *Settings, value string) error
+	format func(s Settings) string
+}
+
+var fields = []field{
+	{
+		key:  AuctionDuration,
+		help: "default auction duration, such as 5m or 1h30m",
+		parse: func(s *Settings, value string) error {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("want a positive duration such as 5m, got %q", value)
+			}
+			s.AuctionDuration = d
+			return nil
+		},
+		format: func(s Settings) string { return s.AuctionDuration.String() },
+	},
+	{
+		key:  MinIncrement,
+		help: "DKP a bid must exceed the highest bid by",
+		parse: func(s *Settings, value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return fmt.Errorf("want a whole number of at least 1, got %q", value)
+			}
+			s.MinIncrement = n
+			return nil
+		},
+		format: func(s Settings) string { return strconv.Itoa(s.MinIncrement) },
+	},
+	{
+		key:  DecayRate,
+		help: "percentage of their DKP players lose per decay period",
+		parse: func(s *Settings, value string) error {
+			f, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if err != nil || f < 0 || f > 100 {
+				return fmt.Errorf("want a percentage between 0 and 100, got %q", value)
+			}
+			s.DecayRate = f
+			return nil
+		},
+		format: func(s Settings) string { return strconv.FormatFloat(s.DecayRate, 'g', -1, 64) },
+	},
+	{
+		key:  UndoWindow,
+		help: "how long after a DKP change /dkp-undo may reverse it, such as 24h",
+		parse: func(s *Settings, value string) error {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("want a positive duration such as 24h, got %q", value)
+			}
+			s.UndoWindow = d
+			return nil
+		},
+		format: func(s Settings) string { return s.UndoWindow.String() },
+	},
+	{
+		key:  RollWindow,
+		help: "how long players may roll for an item nobody bid on, such as 1m, or 0 to not roll",
+		parse: func(s *Settings, value string) error {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return fmt.Errorf("want a duration such as 1m, or 0, got %q", value)
+			}
+			s.RollWindow = d
+			return nil
+		},
+		format: func(s Settings) string { return s.RollWindow.String() },
+	},
+	{
+		key:  MaxOpenAuctions,
+		help: "how many auctions may be open at once, queuing the rest, or 0 for no limit",
+		parse: func(s *Settings, value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return fmt.Errorf("want a whole number, or 0, got %q", value)
+			}
+			s.MaxOpenAuctions = n
+			return nil
+		},
+		format: func(s Settings) string { return strconv.Itoa(s.MaxOpenAuctions) },
+	},
+	{
+		key:  OutbidNotifications,
+		help: "how players are told they were outbid: dm, channel, or off",
+		parse: func(s *Settings, value string) error {
+			switch value {
+			case config.OutbidDM, config.OutbidChannel, config.OutbidOff:
+			default:
+				return fmt.Errorf("want dm, channel, or off, got %q", value)
+			}
+			s.OutbidNotifications = value
+			return nil
+		},
+		format: func(s Settings) string { return s.OutbidNotifications },
+	},
+	{
+		key:  ConfirmBidPercent,
+		help: "percentage of their balance above which players confirm a typed bid, such as 50, or 0 to never ask",
+		parse: func(s *Settings, value string) error {
+			n, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+			if err != nil || n < 0 || n > 100 {
+				return fmt.Errorf("want a percentage between 0 and 100, got %q", value)
+			}
+			s.ConfirmBidPercent = n
+			return nil
+		},
+		format: func(s Settings) string { return strconv.Itoa(s.ConfirmBidPercent) },
+	},
+	{
+		key:  BidCooldown,
+		help: "how long players wait between their bids on an auction, such as 3s, or 0 for no wait",
+		parse: func(s *Settings, value string) error {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return fmt.Errorf("want a duration such as 3s, or 0, got %q", value)
+			}
+			s.BidCooldown = d
+			return nil
+		},
+		format: func(s Settings) string { return s.BidCooldown.String() },
+	},
+	{
+		key:  BidWarCooldown,
+		help: "how long two players trading bids in quick succession wait between bids, such as 15s, or 0",
+		parse: func(s *Settings, value string) error {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return fmt.Errorf("want a duration such as 15s, or 0, got %q", value)
+			}
+			s.BidWarCooldown = d
+			return nil
+		},
+		format: func(s Settings) string { return s.BidWarCooldown.String() },
+	},
+	{
+		key:  LootMode,
+		help: "how loot is handed out: auction, fixed_price from the item cost table, or both",
+		parse: func(s *Settings, value string) error {
+			switch value {
+			case config.LootModeAuction, config.LootModeFixedPrice, config.LootModeBoth:
+			default:
+				return fmt.Errorf("want auction, fixed_price, or both, got %q", value)
+			}
+			s.LootMode = value
+			return nil
+		},
+		format: func(s Settings) string { return s.LootMode },
+	},
+	{
+		key:  AdminRoles,
+		help: "roles whose members may use officer commands, or none",
+		parse: func(s *Settings, value string) error {
+			var roles []string
+			if value != "none" {
+				for _, v := range strings.FieldsFunc(value, isListSeparator) {
+					id := strings.TrimSuffix(strings.TrimPrefix(v, "<@&"), ">")
+					if !isSnowflake(id) {
+						return fmt.Errorf("want role mentions or IDs, got %q", v)
+					}
+					if !slices.Contains(roles, id) {
+						roles = append(roles, id)
+					}
+				}
+			}
+			s.AdminRoles = roles
+			return nil
+		},
+		format: func(s Settings) string { return strings.Join(s.AdminRoles, ",") },
+	},
+	{
+		key:  LootChannel,
+		help: "channel auctions are announced in, or none",
+		parse: func(s *Settings, value string) error {
+			id, err := parseChannel(value)
+			if err != nil {
+				return err
+			}
+			s.LootChannel = id
+			return nil
+		},
+		format: func(s Settings) string { return s.LootChannel },
+	},
+	{
+		key:  LeaderboardChannel,
+		help: "channel the weekly leaderboard is posted in, or none",
+		parse: func(s *Settings, value string) error {
+			id, err := parseChannel(value)
+			if err != nil {
+				return err
+			}
+			s.LeaderboardChannel = id
+			return nil
+		},
+		format: func(s Settings) string { return s.LeaderboardChannel },
+	},
+	{
+		key:  OfficerChannel,
+		help: "channel proposals for officers, such as archiving inactive players, are posted in, or none",
+		parse: func(s *Settings, value string) error {
+			id, err := parseChannel(value)
+			if err != nil {
+				return err
+			}
+			s.OfficerChannel = id
+			return nil
+		},
+		format: func(s Settings) string { return s.OfficerChannel },
+	},
+	{
+		key:  Timezone,
+		help: "IANA timezone of the schedules and of dates and times given to commands, such as Europe/Copenhagen",
+		parse: func(s *Settings, value string) error {
+			loc, err := clock.LoadLocation(value)
+			if err != nil {
+				return fmt.Errorf("want an IANA timezone such as Europe/Copenhagen, got %q", value)
+			}
+			s.Timezone = loc
+			return nil
+		},
+		format: func(s Settings) string { return s.Timezone.String() },
+	},
+	messageField(StartedMessage, "announcement of an auction's start", announce.Started,
+		func(s *Settings) *string { return &s.StartedMessage }),
+	messageField(OutbidMessage, "message telling a player they were outbid", announce.Outbid,
+		func(s *Settings) *string { return &s.OutbidMessage }),
+	messageField(WinnerMessage, "announcement of an auction's winner", announce.Winner,
+		func(s *Settings) *string { return &s.WinnerMessage }),
+	messageField(NoBidsMessage, "announcement of an auction closed without bids", announce.NoBids,
+		func(s *Settings) *string { return &s.NoBidsMessage }),
+}
+
+// messageField describes the setting key holding the template tmpl of an
+// announcement, what, in the Settings field returned by text.
+func messageField(key, what string, tmpl announce.Template, text func(s *Settings) *string) field {
+	return field{
+		key:  key,
+		help: fmt.Sprintf("%s, with %s", what, tmpl.Placeholders),
+		parse: func(s *Settings, value string) error {
+			if err := tmpl.Check(value); err != nil {
+				return err
+			}
+			*text(s) = value
+			return nil
+		},
+		format: func(s Settings) string { return *text(&s) },
+	}
+}
+
+// parseChannel parses a channel mention or ID, or "none" for no channel.
+func parseChannel(value string) (string, error) {
+	if value == "none" {
+		return "", nil
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(value, "<#"), ">")
+	if !isSnowflake(id) {
+		return "", fmt.Errorf("want a channel mention or ID, got %q", value)
+	}
+	return id, nil
+}
+
+func lookup(key string) (field, error) {
+	for _, f := range fields {
+		if f.key == key {
+			return f, nil
+		}
+	}
+	return field{}, ErrUnknownSetting.Wrap(fmt.Errorf("key %q", key))
+}
+
+// Key names a setting.
+type Key struct {
+	Name        string
+	Description string
+}
+
+// Keys returns all settings, in display order.
+func Keys() []Key {
+	keys := make([]Key, len(fields))
+	for i, f := range fields {
+		keys[i] = Key{Name: f.key, Description: f.help}
+	}
+	return keys
+}
+
+// Entry is one setting of a guild, as shown by /settings.
+type Entry struct {
+	Key string
+	// Value is the setting in the form accepted by Set. Lists are comma
+	// separated and empty values are shown as "".
+	Value string
+	// Default reports whether the setting has its configured default.
+	Default bool
+	// UpdatedBy and UpdatedAt tell who last changed the setting, unless it
+	// has its default.
+	UpdatedBy string
+	UpdatedAt time.Time
+}
+
+// Service reads and changes guild settings.
+type Service struct {
+	repo     store.GuildSettingsRepository
+	defaults Settings
+	logger   *slog.Logger
+}
+
+// NewService returns a Service that stores changed settings in repo and
+// falls back to defaults for the others.
+func NewService(repo store.GuildSettingsRepository, defaults Settings, logger *slog.Logger) *Service {
+	return &Service{repo: repo, defaults: defaults, logger: logger}
+}
+
+// Get returns the effective settings of guildID. Stored values that no
+// longer parse are logged and replaced by their default.
+func (s *Service) Get(ctx context.Context, guildID string) (Settings, error) {
+	stored, err := s.repo.List(ctx, guildID)
+	if err != nil {
+		return Settings{}, fmt.Errorf("loading guild settings: %w", err)
+	}
+	return s.apply(ctx, guildID, stored), nil
+}
+
+// Location returns the timezone of guildID, or the default timezone if its
+// settings cannot be loaded.
+func (s *Service) Location(ctx context.Context, guildID string) *time.Location {
+	settings, err := s.Get(ctx, guildID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "using the default timezone", slog.String("guild_id", guildID), slog.Any("error", err))
+		return s.defaults.Timezone
+	}
+	return settings.Timezone
+}
+
+// apply returns the defaults overridden by stored.
+func (s *Service) apply(ctx context.Context, guildID string, stored []store.GuildSetting) Settings {
+	settings := s.defaults
+	settings.AdminRoles = slices.Clone(s.defaults.AdminRoles)
+	for _, st := range stored {
+		f, err := lookup(st.Key)
+		if err == nil {
+			err = f.parse(&settings, st.Value)
+		}
+		if err != nil {
+			s.logger.WarnContext(ctx, "ignoring invalid guild setting",
+				slog.String("guild_id", guildID),
+				slog.String("key", st.Key),
+				slog.Any("error", err),
+			)
+		}
+	}
+	return settings
+}
+
+// List returns every setting of guildID, in display order.
+func (s *Service) List(ctx context.Context, guildID string) ([]Entry, error) {
+	stored, err := s.repo.List(ctx, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("loading guild settings: %w", err)
+	}
+	settings := s.apply(ctx, guildID, stored)
+	entries := make([]Entry, len(fields))
+	for i, f := range fields {
+		entries[i] = Entry{Key: f.key, Value: f.format(settings), Default: true}
+		for _, st := range stored {
+			if st.Key == f.key {
+				entries[i].Default = false
+				entries[i].UpdatedBy, entries[i].UpdatedAt = st.UpdatedBy, st.UpdatedAt
+			}
+		}
+	}
+	return entries, nil
+}
+
+// Set changes key to value for guildID and returns the new settings.
+// Values are validated and stored in a normalized form, so that role and
+// channel mentions are stored as IDs.
+func (s *Service) Set(ctx context.Context, guildID, key, value, updatedBy string) (Settings, error) {
+	f, err := lookup(key)
+	if err != nil {
+		return Settings{}, err
+	}
+	settings, err := s.Get(ctx, guildID)
+	if err != nil {
+		return Settings{}, err
+	}
+	if err := f.parse(&settings, strings.TrimSpace(value)); err != nil {
+		return Settings{}, derrors.New(derrors.Validation, "INVALID_SETTING", fmt.Sprintf("invalid %s: %s", key, err))
+	}
+	err = s.repo.Set(ctx, &store.GuildSetting{
+		GuildID:   guildID,
+		Key:       key,
+		Value:     f.format(settings),
+		UpdatedBy: updatedBy,
+	})
+	if err != nil {
+		return Settings{}, err
+	}
+	s.logger.InfoContext(ctx, "guild setting changed",
+		slog.String("guild_id", guildID),
+		slog.String("key", key),
+		slog.String("value", f.format(settings)),
+		slog.String("updated_by", updatedBy),
+	)
+	return settings, nil
+}
+
+// Reset restores the default of key for guildID and returns the new
+// settings.
+func (s *Service) Reset(ctx context.Context, guildID, key string) (Settings, error) {
+	if _, err := lookup(key); err != nil {
+		return Settings{}, err
+	}
+	if err := s.repo.Delete(ctx, guildID, key); err != nil {
+		return Settings{}, err
+	}
+	s.logger.InfoContext(ctx, "guild setting reset",
+		slog.String("guild_id", guildID),
+		slog.String("key", key),
+	)
+	return s.Get(ctx, guildID)
+}
+
+func isListSeparator(r rune) bool {
+	return r == ',' || r == ' '
+}
+
+// isSnowflake reports whether id looks like a Discord ID.
+func isSnowflake(id string) bool {
+	_, err := strconv.ParseUint(id, 10, 64)
+	return err == nil
+}
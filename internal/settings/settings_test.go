@@ -0,0 +1,214 @@
+package settings_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/settings"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// memRepo implements store.GuildSettingsRepository in memory.
+type memRepo struct {
+	mu       sync.Mutex
+	settings map[[2]string]store.GuildSetting
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{settings: make(map[[2]string]store.GuildSetting)}
+}
+
+func (r *memRepo) List(_ context.Context, guildID string) ([]store.GuildSetting, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []store.GuildSetting
+	for k, s := range r.settings {
+		if k[0] == guildID {
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+func (r *memRepo) Set(_ context.Context, s *store.GuildSetting) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.settings[[2]string{s.GuildID, s.Key}] = *s
+	return nil
+}
+
+func (r *memRepo) Delete(_ context.Context, guildID, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.settings, [2]string{guildID, key})
+	return nil
+}
+
+func newService(repo store.GuildSettingsRepository) *settings.Service {
+	defaults := settings.Defaults(config.GuildDefaultsConfig{
+		AuctionDuration: 5 * time.Minute,
+		MinIncrement:    1,
+		UndoWindow:      24 * time.Hour,
+		AdminRoles:      []string{"100"},
+	})
+	return settings.NewService(repo, defaults, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestService_GetDefaults(t *testing.T) {
+	svc := newService(newMemRepo())
+
+	got, err := svc.Get(context.Background(), "g1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.AuctionDuration != 5*time.Minute || got.MinIncrement != 1 || !got.IsAdminRole("100") || got.LootChannel != "" {
+		t.Errorf("Get = %+v, want the defaults", got)
+	}
+}
+
+func TestService_Set(t *testing.T) {
+	tests := []struct {
+		key, value string
+		check      func(settings.Settings) bool
+	}{
+		{settings.AuctionDuration, "10m", func(s settings.Settings) bool { return s.AuctionDuration == 10*time.Minute }},
+		{settings.MinIncrement, "5", func(s settings.Settings) bool { return s.MinIncrement == 5 }},
+		{settings.DecayRate, "10%", func(s settings.Settings) bool { return s.DecayRate == 10 }},
+		{settings.UndoWindow, "2h", func(s settings.Settings) bool { return s.UndoWindow == 2*time.Hour }},
+		{settings.RollWindow, "45s", func(s settings.Settings) bool { return s.RollWindow == 45*time.Second }},
+		{settings.RollWindow, "0", func(s settings.Settings) bool { return s.RollWindow == 0 }},
+		{settings.MaxOpenAuctions, "3", func(s settings.Settings) bool { return s.MaxOpenAuctions == 3 }},
+		{settings.OutbidNotifications, "channel", func(s settings.Settings) bool { return s.OutbidNotifications == config.OutbidChannel }},
+		{settings.ConfirmBidPercent, "50%", func(s settings.Settings) bool { return s.ConfirmBidPercent == 50 }},
+		{settings.BidCooldown, "3s", func(s settings.Settings) bool { return s.BidCooldown == 3*time.Second }},
+		{settings.BidWarCooldown, "0", func(s settings.Settings) bool { return s.BidWarCooldown == 0 }},
+		{settings.LootMode, "both", func(s settings.Settings) bool { return s.Auctions() && s.FixedPrices() }},
+		{settings.LootMode, "fixed_price", func(s settings.Settings) bool { return !s.Auctions() && s.FixedPrices() }},
+		{settings.AdminRoles, "<@&200>, 300 <@&200>", func(s settings.Settings) bool { return slices.Equal(s.AdminRoles, []string{"200", "300"}) }},
+		{settings.AdminRoles, "none", func(s settings.Settings) bool { return len(s.AdminRoles) == 0 }},
+		{settings.LootChannel, "<#400>", func(s settings.Settings) bool { return s.LootChannel == "400" }},
+		{settings.LeaderboardChannel, "500", func(s settings.Settings) bool { return s.LeaderboardChannel == "500" }},
+		{settings.OfficerChannel, "<#600>", func(s settings.Settings) bool { return s.OfficerChannel == "600" }},
+		{settings.Timezone, "Europe/Copenhagen", func(s settings.Settings) bool { return s.Timezone.String() == "Europe/Copenhagen" }},
+		{settings.WinnerMessage, "**{winner}** takes {item} home for {amount}!", func(s settings.Settings) bool {
+			return s.WinnerMessage == "**{winner}** takes {item} home for {amount}!"
+		}},
+		{settings.NoBidsMessage, "Niemand wollte {item}.", func(s settings.Settings) bool { return s.NoBidsMessage == "Niemand wollte {item}." }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			svc := newService(newMemRepo())
+			ctx := context.Background()
+
+			got, err := svc.Set(ctx, "g1", tt.key, tt.value, "officer")
+			if err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			if !tt.check(got) {
+				t.Errorf("Set returned %+v", got)
+			}
+			// The stored value must read back the same.
+			got, err = svc.Get(ctx, "g1")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if !tt.check(got) {
+				t.Errorf("Get after Set = %+v", got)
+			}
+		})
+	}
+}
+
+func TestService_SetRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		key, value string
+		wantCode   string
+	}{
+		{settings.AuctionDuration, "five minutes", "INVALID_SETTING"},
+		{settings.AuctionDuration, "-5m", "INVALID_SETTING"},
+		{settings.MinIncrement, "0", "INVALID_SETTING"},
+		{settings.DecayRate, "150", "INVALID_SETTING"},
+		{settings.UndoWindow, "0s", "INVALID_SETTING"},
+		{settings.RollWindow, "-1m", "INVALID_SETTING"},
+		{settings.MaxOpenAuctions, "-1", "INVALID_SETTING"},
+		{settings.Timezone, "Local", "INVALID_SETTING"},
+		{settings.Timezone, "CEST+2", "INVALID_SETTING"},
+		{settings.OutbidNotifications, "email", "INVALID_SETTING"},
+		{settings.ConfirmBidPercent, "120", "INVALID_SETTING"},
+		{settings.BidCooldown, "-3s", "INVALID_SETTING"},
+		{settings.BidWarCooldown, "soon", "INVALID_SETTING"},
+		{settings.LootMode, "dice", "INVALID_SETTING"},
+		{settings.StartedMessage, "{item} is up, {winner}!", "INVALID_SETTING"},
+		{settings.OutbidMessage, " ", "INVALID_SETTING"},
+		{settings.AdminRoles, "@officers", "INVALID_SETTING"},
+		{settings.LootChannel, "#loot", "INVALID_SETTING"},
+		{"max_bid", "100", "UNKNOWN_SETTING"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			repo := newMemRepo()
+			svc := newService(repo)
+
+			_, err := svc.Set(context.Background(), "g1", tt.key, tt.value, "officer")
+			if derrors.KindOf(err) != derrors.Validation || derrors.CodeOf(err) != tt.wantCode {
+				t.Fatalf("Set error = %v, want a %s validation error", err, tt.wantCode)
+			}
+			if stored, _ := repo.List(context.Background(), "g1"); len(stored) != 0 {
+				t.Errorf("invalid value was stored: %+v", stored)
+			}
+		})
+	}
+}
+
+func TestService_ResetAndList(t *testing.T) {
+	svc := newService(newMemRepo())
+	ctx := context.Background()
+
+	if _, err := svc.Set(ctx, "g1", settings.MinIncrement, "5", "officer"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if _, err := svc.Set(ctx, "g2", settings.MinIncrement, "7", "officer"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	entries, err := svc.List(ctx, "g1")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(entries) != len(settings.Keys()) {
+		t.Fatalf("List returned %d entries, want %d", len(entries), len(settings.Keys()))
+	}
+	for _, e := range entries {
+		wantDefault := e.Key != settings.MinIncrement
+		if e.Default != wantDefault {
+			t.Errorf("%s: Default = %v, want %v", e.Key, e.Default, wantDefault)
+		}
+		if e.Key == settings.MinIncrement && (e.Value != "5" || e.UpdatedBy != "officer") {
+			t.Errorf("min_increment entry = %+v", e)
+		}
+	}
+
+	got, err := svc.Reset(ctx, "g1", settings.MinIncrement)
+	if err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if got.MinIncrement != 1 {
+		t.Errorf("MinIncrement after Reset = %d, want the default 1", got.MinIncrement)
+	}
+	if other, _ := svc.Get(ctx, "g2"); other.MinIncrement != 7 {
+		t.Errorf("Reset changed another guild: MinIncrement = %d", other.MinIncrement)
+	}
+	if _, err := svc.Reset(ctx, "g1", "max_bid"); !errors.Is(err, settings.ErrUnknownSetting) {
+		t.Errorf("Reset(unknown) error = %v, want ErrUnknownSetting", err)
+	}
+}
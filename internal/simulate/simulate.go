@@ -0,0 +1,284 @@
+// Package simulate load-tests the auction manager. Simulated players bid
+// concurrently on many open auctions, and the run reports the throughput
+// of bids, how often bids lost a race with another bid for the same
+// auction, and the latency of bids and of event appends, so that the
+// manager's locking can be judged before large guilds rely on it.
+//
+// The bids are planned from a seed: the same seed yields the same players,
+// balances, and sequence of bids. With more than one concurrent bidder the
+// order in which bids reach the manager, and so which are accepted, still
+// varies between runs.
+package simulate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/auction"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// Config sizes a simulation.
+type Config struct {
+	Auctions int
+	Players  int
+	// Bids is the number of bids placed in total.
+	Bids int
+	// Concurrency is the number of players bidding at the same time.
+	Concurrency int
+	Seed        uint64
+	// AppendLatency is added to every event append, to model the round
+	// trip to a database when simulating against an in-memory store.
+	AppendLatency time.Duration
+}
+
+// Validate reports a config that cannot be simulated.
+func (c Config) Validate() error {
+	switch {
+	case c.Auctions <= 0:
+		return errors.New("auctions must be positive")
+	case c.Players <= 0:
+		return errors.New("players must be positive")
+	case c.Bids <= 0:
+		return errors.New("bids must be positive")
+	case c.Concurrency <= 0:
+		return errors.New("concurrency must be positive")
+	case c.AppendLatency < 0:
+		return errors.New("append latency must not be negative")
+	}
+	return nil
+}
+
+// Report is the outcome of a simulation.
+type Report struct {
+	Bids     int
+	Accepted int
+	// Rejected counts the bids rejected, by error code.
+	Rejected map[string]int
+	// Contended counts the bids rejected as too low because another bid for
+	// the same auction was accepted after the bidder read the highest bid.
+	Contended int
+	// Elapsed is the time taken to place the bids.
+	Elapsed time.Duration
+	// BidLatency is the latency of Manager.PlaceBid, and AppendLatency that
+	// of the event appends made while bidding.
+	BidLatency    Latency
+	AppendLatency Latency
+}
+
+// Throughput returns the bids handled per second.
+func (r *Report) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Bids) / r.Elapsed.Seconds()
+}
+
+// Latency summarizes the durations of an operation.
+type Latency struct {
+	Count              int
+	P50, P95, P99, Max time.Duration
+}
+
+func (l Latency) String() string {
+	return fmt.Sprintf("p50 %s, p95 %s, p99 %s, max %s over %d",
+		l.P50.Round(time.Microsecond), l.P95.Round(time.Microsecond), l.P99.Round(time.Microsecond), l.Max.Round(time.Microsecond), l.Count)
+}
+
+// summarize returns the latency percentiles of ds, which it sorts.
+func summarize(ds []time.Duration) Latency {
+	if len(ds) == 0 {
+		return Latency{}
+	}
+	slices.Sort(ds)
+	at := func(p float64) time.Duration { return ds[int(p*float64(len(ds)-1))] }
+	return Latency{Count: len(ds), P50: at(0.50), P95: at(0.95), P99: at(0.99), Max: ds[len(ds)-1]}
+}
+
+// bid is a planned bid: the player raises the highest bid of the auction
+// by raise when it is their turn.
+type bid struct {
+	auction, player, raise int
+}
+
+// Simulator runs simulations against a store.
+type Simulator struct {
+	events  event.Store
+	players store.PlayerRepository
+	logger  *slog.Logger
+	tp      trace.TracerProvider
+}
+
+// New returns a Simulator that registers its players in players and
+// appends to events. Against a database, the players stay registered and
+// the auctions are canceled once the bids are placed, so only a scratch
+// database should be used.
+func New(events event.Store, players store.PlayerRepository, logger *slog.Logger, tp trace.TracerProvider) *Simulator {
+	return &Simulator{events: events, players: players, logger: logger, tp: tp}
+}
+
+// Run places cfg.Bids bids on cfg.Auctions auctions and reports how they
+// fared.
+func (s *Simulator) Run(ctx context.Context, cfg Config) (*Report, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewPCG(cfg.Seed, 0x5eed))
+
+	players, err := s.register(ctx, cfg, rng)
+	if err != nil {
+		return nil, err
+	}
+	timed := &timedStore{Store: s.events, latency: cfg.AppendLatency}
+	mgr := auction.NewManager(timed, s.players, s.logger, s.tp, clock.Real{})
+
+	var auctions []*auction.Auction
+	defer func() {
+		// Leave nothing open for a bot to recover.
+		for _, a := range auctions {
+			if err := mgr.CancelAuction(context.WithoutCancel(ctx), a.ID); err != nil {
+				s.logger.WarnContext(ctx, "canceling simulated auction", slog.String("auction_id", a.ID), slog.Any("error", err))
+			}
+		}
+	}()
+	for i := range cfg.Auctions {
+		a, err := mgr.StartAuction(ctx, fmt.Sprintf("Simulated item %d", i+1), "simulate", 1, 0, 0, time.Hour)
+		if err != nil {
+			return nil, fmt.Errorf("starting auction: %w", err)
+		}
+		if i > 0 && a.ID == auctions[i-1].ID {
+			return nil, fmt.Errorf("auction ID %s was assigned twice", a.ID)
+		}
+		auctions = append(auctions, a)
+	}
+
+	plan := make([]bid, cfg.Bids)
+	for i := range plan {
+		plan[i] = bid{auction: rng.IntN(cfg.Auctions), player: rng.IntN(cfg.Players), raise: 1 + rng.IntN(10)}
+	}
+	timed.reset()
+
+	var (
+		mu     sync.Mutex
+		report = &Report{Bids: cfg.Bids, Rejected: make(map[string]int)}
+		bidLat = make([]time.Duration, 0, cfg.Bids)
+		wg     sync.WaitGroup
+	)
+	start := time.Now()
+	for w := range cfg.Concurrency {
+		wg.Go(func() {
+			for i := w; i < len(plan) && ctx.Err() == nil; i += cfg.Concurrency {
+				b := plan[i]
+				a := auctions[b.auction]
+				highest := a.HighestBid()
+				amount := a.MinBid - 1 + b.raise
+				if highest != nil {
+					amount = highest.Amount + b.raise
+				}
+
+				began := time.Now()
+				err := mgr.PlaceBid(ctx, a.ID, players[b.player], amount)
+				took := time.Since(began)
+
+				mu.Lock()
+				bidLat = append(bidLat, took)
+				if err == nil {
+					report.Accepted++
+				} else {
+					report.Rejected[derrors.CodeOf(err)]++
+					if errors.Is(err, auction.ErrBidTooLow) && !sameBid(highest, a.HighestBid()) {
+						report.Contended++
+					}
+				}
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	report.Elapsed = time.Since(start)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	report.BidLatency = summarize(bidLat)
+	report.AppendLatency = summarize(timed.durations())
+	return report, nil
+}
+
+// register registers the simulated players with seeded balances, reusing
+// those registered by an earlier run with the same seed, and returns their
+// Discord IDs. The balances are sized so that prices reach them late in
+// the run, when the poorer players drop out.
+func (s *Simulator) register(ctx context.Context, cfg Config, rng *rand.Rand) ([]string, error) {
+	// Each bid raises the price by 5.5 on average.
+	budget := max(100, 11*cfg.Bids/cfg.Auctions)
+	ids := make([]string, cfg.Players)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("sim-%d-%d", cfg.Seed, i+1)
+		p := &store.Player{
+			DiscordID:     ids[i],
+			CharacterName: fmt.Sprintf("Sim%d-%d", cfg.Seed, i+1),
+			DKP:           budget/2 + rng.IntN(budget),
+		}
+		if err := s.players.Create(ctx, p); err != nil && !errors.Is(err, store.ErrPlayerExists) {
+			return nil, fmt.Errorf("registering simulated player: %w", err)
+		}
+	}
+	return ids, nil
+}
+
+// sameBid reports whether a and b are the same highest bid, or both none.
+func sameBid(a, b *auction.Bid) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.PlayerID == b.PlayerID && a.Amount == b.Amount
+}
+
+// timedStore records the latency of the appends to its Store, after
+// sleeping for latency.
+type timedStore struct {
+	event.Store
+	latency time.Duration
+
+	mu      sync.Mutex
+	appends []time.Duration
+}
+
+func (t *timedStore) Append(ctx context.Context, events ...event.Event) error {
+	start := time.Now()
+	if t.latency > 0 {
+		time.Sleep(t.latency)
+	}
+	err := t.Store.Append(ctx, events...)
+	took := time.Since(start)
+
+	t.mu.Lock()
+	t.appends = append(t.appends, took)
+	t.mu.Unlock()
+	return err
+}
+
+// reset forgets the appends recorded so far.
+func (t *timedStore) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.appends = nil
+}
+
+// durations returns the append latencies recorded.
+func (t *timedStore) durations() []time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.appends)
+}
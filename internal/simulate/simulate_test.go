@@ -0,0 +1,90 @@
+package simulate_test
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/simulate"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+func run(t *testing.T, cfg simulate.Config) (*simulate.Report, *eventtest.Store) {
+	t.Helper()
+	es := eventtest.NewStore()
+	sim := simulate.New(es, storetest.NewPlayers(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+	report, err := sim.Run(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	return report, es
+}
+
+func TestSimulator_Run(t *testing.T) {
+	cfg := simulate.Config{Auctions: 5, Players: 20, Bids: 500, Concurrency: 8, Seed: 7}
+	report, es := run(t, cfg)
+
+	rejected := 0
+	for _, n := range report.Rejected {
+		rejected += n
+	}
+	if report.Accepted+rejected != cfg.Bids {
+		t.Errorf("accepted %d + rejected %d, want %d bids", report.Accepted, rejected, cfg.Bids)
+	}
+	if report.Accepted == 0 {
+		t.Error("no bids were accepted")
+	}
+	// Each accepted bid is appended once.
+	if report.AppendLatency.Count != report.Accepted {
+		t.Errorf("appends = %d, want %d", report.AppendLatency.Count, report.Accepted)
+	}
+	if report.BidLatency.Count != cfg.Bids || report.BidLatency.P50 > report.BidLatency.Max {
+		t.Errorf("bid latency = %+v", report.BidLatency)
+	}
+	if report.Throughput() <= 0 {
+		t.Errorf("Throughput() = %f", report.Throughput())
+	}
+
+	canceled, _ := es.LoadByType(context.Background(), event.AuctionCanceled)
+	if len(canceled) != cfg.Auctions {
+		t.Errorf("canceled auctions = %d, want %d", len(canceled), cfg.Auctions)
+	}
+}
+
+func TestSimulator_Run_Deterministic(t *testing.T) {
+	cfg := simulate.Config{Auctions: 3, Players: 10, Bids: 200, Concurrency: 1, Seed: 42}
+	first, _ := run(t, cfg)
+	second, _ := run(t, cfg)
+
+	if first.Accepted != second.Accepted || !maps.Equal(first.Rejected, second.Rejected) {
+		t.Errorf("runs differ: %d accepted, rejected %v; then %d accepted, rejected %v",
+			first.Accepted, first.Rejected, second.Accepted, second.Rejected)
+	}
+	// A single bidder never races another.
+	if first.Contended != 0 {
+		t.Errorf("Contended = %d, want 0", first.Contended)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := simulate.Config{Auctions: 1, Players: 1, Bids: 1, Concurrency: 1}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	for _, c := range []simulate.Config{
+		{Players: 1, Bids: 1, Concurrency: 1},
+		{Auctions: 1, Bids: 1, Concurrency: 1},
+		{Auctions: 1, Players: 1, Concurrency: 1},
+		{Auctions: 1, Players: 1, Bids: 1},
+		{Auctions: 1, Players: 1, Bids: 1, Concurrency: 1, AppendLatency: -1},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded", c)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Package standings keeps the DKP standings in memory, so that /dkp-list
+// and the leaderboard do not list every player from the database each time
+// they are shown.
+//
+// The standings are a projection of the players store: they are rebuilt
+// shortly after the DKP events published on the event bus, after those
+// other replicas append as announced by the database, and every few minutes
+// for changes whose announcement was missed.
+package standings
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// settle is how long a rebuild waits after a DKP change for more, such as
+// the rest of a raid's awards.
+const settle = time.Second
+
+// refreshInterval is how often the standings are rebuilt without a change
+// being published.
+const refreshInterval = 5 * time.Minute
+
+// triggers are the event types that change the standings.
+var triggers = []event.Type{
+	event.DKPAwarded, event.DKPDeducted, event.DKPAdjusted,
+	event.PlayerRegistered, event.PlayerArchived, event.PlayerRestored,
+}
+
+// Players lists the registered players.
+type Players interface {
+	List(ctx context.Context) ([]store.Player, error)
+}
+
+// View is the standings as of a rebuild.
+type View struct {
+	// Players are the registered players, archived ones included, ordered
+	// by DKP.
+	Players []store.Player
+	// BuiltAt is when the players were listed.
+	BuiltAt time.Time
+	// Stale reports whether a DKP change was published since, which the
+	// next rebuild will show.
+	Stale bool
+}
+
+// Projection serves the standings from memory. It is safe for concurrent
+// use.
+type Projection struct {
+	players Players
+	logger  *slog.Logger
+	tracer  trace.Tracer
+	clock   clock.Clock
+
+	// mu guards the fields below. changes counts the changes published,
+	// and built the changes the view shows.
+	mu      sync.Mutex
+	view    View
+	changes uint64
+	built   uint64
+	// rebuild serializes rebuilds.
+	rebuild sync.Mutex
+}
+
+// NewProjection returns a Projection of players. The standings are first
+// built when they are asked for.
+func NewProjection(players Players, logger *slog.Logger, tp trace.TracerProvider, clk clock.Clock) *Projection {
+	return &Projection{
+		players: players,
+		logger:  logger,
+		tracer:  tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/standings"),
+		clock:   clk,
+	}
+}
+
+// Run rebuilds the standings shortly after the DKP changes published on
+// any of buses, and at a regular interval, until ctx is done.
+func (p *Projection) Run(ctx context.Context, buses ...*event.Bus) {
+	changed := make(chan struct{}, 1)
+	for _, bus := range buses {
+		unsubscribe := bus.Subscribe(func(context.Context, event.Event) {
+			p.mu.Lock()
+			p.changes++
+			p.mu.Unlock()
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}, triggers...)
+		defer unsubscribe()
+	}
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			if settled == nil {
+				settled = time.After(settle)
+			}
+			continue
+		case <-settled:
+			settled = nil
+		case <-ticker.C:
+		}
+		if _, err := p.Refresh(ctx); err != nil {
+			p.logger.ErrorContext(ctx, "rebuilding standings failed", slog.Any("error", err))
+		}
+	}
+}
+
+// Standings returns the standings, building them first if they never were.
+func (p *Projection) Standings(ctx context.Context) (View, error) {
+	p.mu.Lock()
+	view, built := p.current()
+	p.mu.Unlock()
+	if built {
+		return view, nil
+	}
+	return p.Refresh(ctx)
+}
+
+// List returns the registered players ordered by DKP, rebuilding the
+// standings first if they are stale, for callers that need them current.
+func (p *Projection) List(ctx context.Context) ([]store.Player, error) {
+	view, err := p.Standings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if view.Stale {
+		if view, err = p.Refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return view.Players, nil
+}
+
+// Refresh rebuilds the standings from the players store and returns them.
+func (p *Projection) Refresh(ctx context.Context) (View, error) {
+	ctx, span := p.tracer.Start(ctx, "Projection.Refresh")
+	defer span.End()
+
+	p.rebuild.Lock()
+	defer p.rebuild.Unlock()
+
+	// Changes published while the players are listed may be missed by the
+	// list, so only those published before count as shown.
+	p.mu.Lock()
+	changes := p.changes
+	p.mu.Unlock()
+
+	players, err := p.players.List(ctx)
+	if err != nil {
+		return View{}, fmt.Errorf("listing players: %w", err)
+	}
+	span.SetAttributes(attribute.Int("players", len(players)))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.view = View{Players: players, BuiltAt: p.clock.Now()}
+	p.built = changes
+	view, _ := p.current()
+	return view, nil
+}
+
+// current returns a copy of the view, which callers may change, and
+// whether it was built. p.mu must be held.
+func (p *Projection) current() (View, bool) {
+	view := p.view
+	view.Players = slices.Clone(view.Players)
+	view.Stale = p.changes != p.built
+	return view, !view.BuiltAt.IsZero()
+}
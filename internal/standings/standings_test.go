@@ -0,0 +1,92 @@
+package standings_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/standings"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+func TestProjection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	now := time.Date(2025, 6, 16, 17, 0, 0, 0, time.UTC)
+	players := storetest.NewPlayers(
+		store.Player{DiscordID: "d1", CharacterName: "Gandalf", DKP: 70},
+		store.Player{DiscordID: "d2", CharacterName: "Frodo", DKP: 25},
+	)
+	p := standings.NewProjection(players, slog.Default(), noop.NewTracerProvider(), clock.Mock{T: now})
+
+	dkpOf := func(view standings.View) map[string]int {
+		got := make(map[string]int)
+		for _, pl := range view.Players {
+			got[pl.CharacterName] = pl.DKP
+		}
+		return got
+	}
+
+	view, err := p.Standings(ctx)
+	if err != nil {
+		t.Fatalf("Standings: %v", err)
+	}
+	if got := dkpOf(view); len(got) != 2 || got["Frodo"] != 25 || view.Stale || !view.BuiltAt.Equal(now) {
+		t.Fatalf("first standings = %+v", view)
+	}
+
+	// The standings are served from memory until a change is published.
+	players.Put(store.Player{DiscordID: "d2", CharacterName: "Frodo", DKP: 90})
+	players.Fail("List", errors.New("db down"))
+	if view, err = p.Standings(ctx); err != nil || dkpOf(view)["Frodo"] != 25 {
+		t.Fatalf("cached standings = %+v, %v", view, err)
+	}
+	if _, err := p.Refresh(ctx); err == nil {
+		t.Fatal("Refresh with the store down succeeded")
+	}
+	players.Fail("List", nil)
+
+	// The change is published on the second bus, as those of other
+	// replicas are.
+	bus := event.NewBus()
+	go p.Run(ctx, event.NewBus(), bus)
+	// Publish until the subscription is in place and the change is seen.
+	for !view.Stale && dkpOf(view)["Frodo"] != 90 {
+		bus.Publish(ctx, event.Event{AggregateID: "player-d2", Type: event.DKPAwarded})
+		time.Sleep(10 * time.Millisecond)
+		if view, err = p.Standings(ctx); err != nil {
+			t.Fatalf("Standings: %v", err)
+		}
+	}
+	for deadline := time.Now().Add(5 * time.Second); view.Stale; {
+		if time.Now().After(deadline) {
+			t.Fatal("standings still stale after a change was published")
+		}
+		time.Sleep(10 * time.Millisecond)
+		if view, err = p.Standings(ctx); err != nil {
+			t.Fatalf("Standings: %v", err)
+		}
+	}
+	if got := dkpOf(view)["Frodo"]; got != 90 {
+		t.Errorf("Frodo's DKP after the change = %d, want 90", got)
+	}
+
+	// Callers may change what they are given.
+	view.Players[0].DKP = 0
+	listed, err := p.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	for _, pl := range listed {
+		if pl.DKP == 0 {
+			t.Errorf("List = %+v, changed by a caller", listed)
+		}
+	}
+}
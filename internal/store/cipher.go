@@ -0,0 +1,251 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// sealedPrefix marks a value sealed by a Cipher, and the version of its
+// format.
+const sealedPrefix = "enc:v1:"
+
+// Cipher encrypts what the database holds about players, for guilds whose
+// database is hosted by a shared provider: event payloads and snapshots,
+// the actors of events, and the Discord IDs of players. It uses AES-256-GCM
+// with subkeys of a single key. A nil *Cipher leaves everything in the
+// clear.
+//
+// Payloads are sealed with random nonces. Actors and Discord IDs are sealed
+// deterministically, so that the database can still look them up and keep
+// them unique; equal IDs have equal ciphertexts. Values stored before
+// encryption was enabled are read as they are, and LookupIDs lets lookups
+// match them.
+type Cipher struct {
+	aead     cipher.AEAD
+	nonceKey []byte
+}
+
+// NewCipher returns a Cipher for the base64-encoded 32-byte key, or nil if
+// key is empty.
+func NewCipher(key string) (*Cipher, error) {
+	if key == "" {
+		return nil, nil
+	}
+	secret, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("decoding encryption key: %w", err)
+	}
+	if len(secret) != 32 {
+		return nil, fmt.Errorf("encryption key is %d bytes, want 32", len(secret))
+	}
+	encKey, err := hkdf.Key(sha256.New, secret, nil, "dkpbot encryption", 32)
+	if err != nil {
+		return nil, fmt.Errorf("deriving encryption key: %w", err)
+	}
+	nonceKey, err := hkdf.Key(sha256.New, secret, nil, "dkpbot nonce", 32)
+	if err != nil {
+		return nil, fmt.Errorf("deriving nonce key: %w", err)
+	}
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead, nonceKey: nonceKey}, nil
+}
+
+func (c *Cipher) seal(nonce, plaintext, aad []byte) string {
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(c.aead.Seal(nonce, nonce, plaintext, aad))
+}
+
+// open returns the plaintext of the sealed value s. Values without
+// sealedPrefix are returned as they are.
+func (c *Cipher) open(s string, aad []byte) ([]byte, error) {
+	encoded, ok := strings.CutPrefix(s, sealedPrefix)
+	if !ok {
+		return []byte(s), nil
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return nil, fmt.Errorf("decrypting: malformed ciphertext")
+	}
+	n := c.aead.NonceSize()
+	plaintext, err := c.aead.Open(nil, sealed[:n], sealed[n:], aad)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting: %w", err)
+	}
+	return plaintext, nil
+}
+
+// SealID returns the Discord ID id sealed deterministically.
+func (c *Cipher) SealID(id string) string {
+	if c == nil || id == "" {
+		return id
+	}
+	mac := hmac.New(sha256.New, c.nonceKey)
+	mac.Write([]byte(id))
+	return c.seal(mac.Sum(nil)[:c.aead.NonceSize()], []byte(id), nil)
+}
+
+// OpenID returns the Discord ID sealed by SealID as s.
+func (c *Cipher) OpenID(s string) (string, error) {
+	if c == nil {
+		return s, nil
+	}
+	id, err := c.open(s, nil)
+	if err != nil {
+		return "", fmt.Errorf("opening Discord ID: %w", err)
+	}
+	return string(id), nil
+}
+
+// LookupIDs returns the forms the Discord ID id may be stored in: sealed,
+// and in the clear as before encryption was enabled.
+func (c *Cipher) LookupIDs(id string) []string {
+	if c == nil {
+		return []string{id}
+	}
+	return []string{c.SealID(id), id}
+}
+
+// OpenPlayer opens in place the Discord ID of p, and returns p.
+func (c *Cipher) OpenPlayer(p *Player) (*Player, error) {
+	id, err := c.OpenID(p.DiscordID)
+	if err != nil {
+		return nil, fmt.Errorf("player %s: %w", p.ID, err)
+	}
+	p.DiscordID = id
+	return p, nil
+}
+
+// OpenPlayers opens in place the Discord IDs of players.
+func (c *Cipher) OpenPlayers(players []Player) error {
+	for i := range players {
+		if _, err := c.OpenPlayer(&players[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OpenNotes opens in place the Discord IDs of the authors of notes.
+func (c *Cipher) OpenNotes(notes []PlayerNote) error {
+	for i := range notes {
+		author, err := c.OpenID(notes[i].Author)
+		if err != nil {
+			return fmt.Errorf("note %s: %w", notes[i].ID, err)
+		}
+		notes[i].Author = author
+	}
+	return nil
+}
+
+// SealData returns data sealed as a JSON string, which a JSONB column
+// accepts. aad binds it to its row, so that it cannot be moved to another.
+func (c *Cipher) SealData(data json.RawMessage, aad string) (json.RawMessage, error) {
+	if c == nil {
+		return data, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+	return json.Marshal(c.seal(nonce, data, []byte(aad)))
+}
+
+// OpenData returns the data sealed by SealData with aad. Data that is not
+// a sealed JSON string is returned as it is.
+func (c *Cipher) OpenData(data json.RawMessage, aad string) (json.RawMessage, error) {
+	if c == nil || !strings.HasPrefix(string(data), `"`+sealedPrefix) {
+		return data, nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("decoding sealed data: %w", err)
+	}
+	return c.open(s, []byte(aad))
+}
+
+// SealEvents returns copies of events, already chained, with their payloads
+// and actors sealed for storage. The chain hashes cover the clear events,
+// so the ledger verifies the same with or without encryption.
+func (c *Cipher) SealEvents(events []event.Event) ([]event.Event, error) {
+	if c == nil {
+		return events, nil
+	}
+	sealed := make([]event.Event, len(events))
+	for i, e := range events {
+		data, err := c.SealData(e.Data, eventAAD(e))
+		if err != nil {
+			return nil, fmt.Errorf("sealing event %s v%d: %w", e.AggregateID, e.Version, err)
+		}
+		e.Data, e.Actor = data, c.SealID(e.Actor)
+		sealed[i] = e
+	}
+	return sealed, nil
+}
+
+// OpenEvents opens in place the payloads and actors of events sealed by
+// SealEvents.
+func (c *Cipher) OpenEvents(events []event.Event) error {
+	if c == nil {
+		return nil
+	}
+	for i := range events {
+		e := &events[i]
+		data, err := c.OpenData(e.Data, eventAAD(*e))
+		if err != nil {
+			return fmt.Errorf("opening event %s v%d: %w", e.AggregateID, e.Version, err)
+		}
+		actor, err := c.OpenID(e.Actor)
+		if err != nil {
+			return fmt.Errorf("opening event %s v%d: %w", e.AggregateID, e.Version, err)
+		}
+		e.Data, e.Actor = data, actor
+	}
+	return nil
+}
+
+// SealSnapshot returns s with its state sealed for storage.
+func (c *Cipher) SealSnapshot(s event.Snapshot) (event.Snapshot, error) {
+	state, err := c.SealData(s.State, snapshotAAD(s))
+	if err != nil {
+		return s, fmt.Errorf("sealing snapshot of %s: %w", s.AggregateID, err)
+	}
+	s.State = state
+	return s, nil
+}
+
+// OpenSnapshot opens in place the state of s sealed by SealSnapshot.
+func (c *Cipher) OpenSnapshot(s *event.Snapshot) error {
+	state, err := c.OpenData(s.State, snapshotAAD(*s))
+	if err != nil {
+		return fmt.Errorf("opening snapshot of %s: %w", s.AggregateID, err)
+	}
+	s.State = state
+	return nil
+}
+
+// eventAAD binds a sealed payload to its event, which archiving moves
+// without changing.
+func eventAAD(e event.Event) string {
+	return fmt.Sprintf("%s\n%s\n%d", e.AggregateID, e.Type, e.Version)
+}
+
+// snapshotAAD binds a sealed state to its aggregate and version.
+func snapshotAAD(s event.Snapshot) string {
+	return fmt.Sprintf("%s\n%d", s.AggregateID, s.Version)
+}
@@ -0,0 +1,138 @@
+package store_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// testKey is a base64-encoded 32-byte key.
+var testKey = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+func newCipher(t *testing.T) *store.Cipher {
+	t.Helper()
+	c, err := store.NewCipher(testKey)
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	return c
+}
+
+func TestNewCipher(t *testing.T) {
+	if c, err := store.NewCipher(""); c != nil || err != nil {
+		t.Errorf("NewCipher(\"\") = %v, %v, want nil, nil", c, err)
+	}
+	for _, key := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := store.NewCipher(key); err == nil {
+			t.Errorf("NewCipher(%q) error = nil, want one", key)
+		}
+	}
+}
+
+func TestCipher_Events(t *testing.T) {
+	c := newCipher(t)
+	at := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	events := []event.Event{
+		{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{"discord_id":"123456789","amount":10}`), Version: 1, Actor: "987654321", CreatedAt: at},
+		// Events appended on no one's behalf have no actor.
+		{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{"amount":5}`), Version: 2, CreatedAt: at},
+	}
+	events[0].ChainHash = event.ChainHash(nil, "", events[0])
+	events[1].PrevHash = events[0].ChainHash
+	events[1].ChainHash = event.ChainHash(nil, events[1].PrevHash, events[1])
+
+	sealed, err := c.SealEvents(events)
+	if err != nil {
+		t.Fatalf("SealEvents() error = %v", err)
+	}
+	for i, e := range sealed {
+		if strings.Contains(string(e.Data), "amount") || !json.Valid(e.Data) {
+			t.Errorf("sealed data %d = %s, want a JSON string without the payload", i, e.Data)
+		}
+	}
+	if sealed[0].Actor == events[0].Actor || sealed[1].Actor != "" {
+		t.Errorf("sealed actors = %q, %q, want the first sealed and the second empty", sealed[0].Actor, sealed[1].Actor)
+	}
+	if string(events[0].Data) != `{"discord_id":"123456789","amount":10}` {
+		t.Errorf("SealEvents() changed its argument to %s", events[0].Data)
+	}
+
+	if err := c.OpenEvents(sealed); err != nil {
+		t.Fatalf("OpenEvents() error = %v", err)
+	}
+	for i := range events {
+		if string(sealed[i].Data) != string(events[i].Data) || sealed[i].Actor != events[i].Actor {
+			t.Errorf("opened event %d = %s by %q, want %s by %q", i, sealed[i].Data, sealed[i].Actor, events[i].Data, events[i].Actor)
+		}
+	}
+	// The chain covers the clear events.
+	if report := event.VerifyChain(nil, sealed, nil); len(report.Problems) > 0 {
+		t.Errorf("VerifyChain() problems = %v", report.Problems)
+	}
+
+	// A payload moved to another event does not open.
+	moved, _ := c.SealEvents(events)
+	moved[0].Data, moved[1].Data = moved[1].Data, moved[0].Data
+	if err := c.OpenEvents(moved); err == nil {
+		t.Error("OpenEvents() of swapped payloads error = nil, want one")
+	}
+}
+
+func TestCipher_IDs(t *testing.T) {
+	c := newCipher(t)
+	sealed := c.SealID("123456789")
+	if sealed == "123456789" || c.SealID("123456789") != sealed {
+		t.Errorf("SealID() = %q, want a sealed value equal for equal IDs", sealed)
+	}
+	if c.SealID("223456789") == sealed {
+		t.Error("SealID() is equal for different IDs")
+	}
+	for _, s := range []string{sealed, "123456789"} {
+		if id, err := c.OpenID(s); err != nil || id != "123456789" {
+			t.Errorf("OpenID(%q) = %q, %v, want 123456789", s, id, err)
+		}
+	}
+	if got := c.LookupIDs("123456789"); len(got) != 2 || got[0] != sealed || got[1] != "123456789" {
+		t.Errorf("LookupIDs() = %v, want the sealed and the clear ID", got)
+	}
+
+	other, err := store.NewCipher(base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210")))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	if _, err := other.OpenID(sealed); err == nil {
+		t.Error("OpenID() with another key error = nil, want one")
+	}
+}
+
+func TestCipher_OpenNotes(t *testing.T) {
+	c := newCipher(t)
+	notes := []store.PlayerNote{{ID: "1", Author: c.SealID("officer-1")}, {ID: "2", Author: "officer-2"}}
+	if err := c.OpenNotes(notes); err != nil {
+		t.Fatalf("OpenNotes() error = %v", err)
+	}
+	if notes[0].Author != "officer-1" || notes[1].Author != "officer-2" {
+		t.Errorf("OpenNotes() authors = %q, %q, want officer-1 and officer-2", notes[0].Author, notes[1].Author)
+	}
+}
+
+func TestCipher_Nil(t *testing.T) {
+	var c *store.Cipher
+	events := []event.Event{{AggregateID: "p1", Data: json.RawMessage(`{"amount":5}`), Actor: "123"}}
+	sealed, err := c.SealEvents(events)
+	if err != nil || string(sealed[0].Data) != `{"amount":5}` || sealed[0].Actor != "123" {
+		t.Errorf("nil SealEvents() = %+v, %v, want events unchanged", sealed, err)
+	}
+	if c.SealID("123") != "123" {
+		t.Error("nil SealID() changed the ID")
+	}
+	players := []store.Player{{DiscordID: "123"}}
+	if err := c.OpenPlayers(players); err != nil || players[0].DiscordID != "123" {
+		t.Errorf("nil OpenPlayers() = %v, %v, want players unchanged", players, err)
+	}
+}
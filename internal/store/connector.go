@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/lib/pq"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+)
+
+// Connector returns a database/sql connector for the Postgres database of
+// cfg. If cfg.PasswordFunc is set it is called for each new connection, so
+// that a password rotated by a secrets provider takes effect without a
+// restart; open connections keep working under the old password. Unless
+// cfg.TraceQueries is false, its connections record row counts on their
+// spans, as described at TraceOptions.
+func Connector(cfg config.DatabaseConfig) driver.Connector {
+	return connector{cfg: cfg}
+}
+
+type connector struct {
+	cfg config.DatabaseConfig
+}
+
+func (c connector) Connect(ctx context.Context) (driver.Conn, error) {
+	cfg := c.cfg
+	if cfg.PasswordFunc != nil {
+		cfg.Password = cfg.PasswordFunc()
+	}
+	pc, err := pq.NewConnector(cfg.DSN())
+	if err != nil {
+		return nil, err
+	}
+	conn, err := pc.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pc, ok := conn.(pqConn); ok && cfg.TraceQueries {
+		return tracedConn{pc}, nil
+	}
+	return conn, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
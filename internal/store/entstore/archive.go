@@ -0,0 +1,128 @@
+package entstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// EventArchive implements event.Archive using database/sql.
+type EventArchive struct {
+	db     *sql.DB
+	clock  clock.Clock
+	cipher *store.Cipher
+}
+
+// NewEventArchive returns a new EventArchive. Snapshots are sealed with
+// cipher, which may be nil.
+func NewEventArchive(db *sql.DB, clk clock.Clock, cipher *store.Cipher) *EventArchive {
+	return &EventArchive{db: db, clock: clk, cipher: cipher}
+}
+
+func (a *EventArchive) SaveSnapshot(ctx context.Context, s event.Snapshot) error {
+	s, err := a.cipher.SealSnapshot(s)
+	if err != nil {
+		return err
+	}
+	_, err = a.db.ExecContext(ctx,
+		`INSERT INTO event_snapshots (aggregate_id, version, state, created_at)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (aggregate_id) DO UPDATE
+		 SET version = EXCLUDED.version, state = EXCLUDED.state, created_at = EXCLUDED.created_at`,
+		s.AggregateID, s.Version, []byte(s.State), a.clock.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("saving snapshot: %w", err)
+	}
+	return nil
+}
+
+func (a *EventArchive) LoadSnapshot(ctx context.Context, aggregateID string) (*event.Snapshot, error) {
+	s := &event.Snapshot{}
+	var state []byte
+	err := a.db.QueryRowContext(ctx,
+		`SELECT aggregate_id, version, state, created_at FROM event_snapshots WHERE aggregate_id = $1`, aggregateID,
+	).Scan(&s.AggregateID, &s.Version, &state, &s.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("loading snapshot: %w", err)
+	}
+	s.State = json.RawMessage(state)
+	if err := a.cipher.OpenSnapshot(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (a *EventArchive) ArchiveAggregate(ctx context.Context, aggregateID string, upToVersion int) (int, error) {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO events_archive (id, aggregate_id, type, data, version, actor, created_at, prev_hash, chain_hash, archived_at)
+		 SELECT id, aggregate_id, type, data, version, actor, created_at, prev_hash, chain_hash, $3
+		 FROM events WHERE aggregate_id = $1 AND version <= $2`,
+		aggregateID, upToVersion, a.clock.Now().UTC(),
+	); err != nil {
+		return 0, fmt.Errorf("copying events to archive: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM events WHERE aggregate_id = $1 AND version <= $2`, aggregateID, upToVersion)
+	if err != nil {
+		return 0, fmt.Errorf("deleting archived events: %w", err)
+	}
+	n, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing archive: %w", err)
+	}
+	return int(n), nil
+}
+
+func (a *EventArchive) Archived(ctx context.Context, types ...event.Type) ([]event.Event, error) {
+	query := `SELECT id, aggregate_id, type, data, version, actor, created_at, prev_hash, chain_hash FROM events_archive`
+	var args []any
+	if len(types) > 0 {
+		names := make([]string, len(types))
+		for i, t := range types {
+			names[i] = string(t)
+		}
+		query += ` WHERE type = ANY($1)`
+		args = append(args, pq.Array(names))
+	}
+	query += ` ORDER BY created_at ASC, aggregate_id, version`
+
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("loading archived events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []event.Event
+	for rows.Next() {
+		var e event.Event
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.AggregateID, &e.Type, &data, &e.Version, &e.Actor, &e.CreatedAt, &e.PrevHash, &e.ChainHash); err != nil {
+			return nil, fmt.Errorf("scanning archived event row: %w", err)
+		}
+		e.Data = json.RawMessage(data)
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := a.cipher.OpenEvents(events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
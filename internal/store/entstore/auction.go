@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/ids"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
 )
 
@@ -13,21 +14,25 @@ import (
 type AuctionRepo struct {
 	db    *sql.DB
 	clock clock.Clock
+	ids   ids.Generator
 }
 
-// NewAuctionRepo returns a new AuctionRepo.
-func NewAuctionRepo(db *sql.DB, clk clock.Clock) *AuctionRepo {
-	return &AuctionRepo{db: db, clock: clk}
+// NewAuctionRepo returns a new AuctionRepo that gives the auctions it
+// creates IDs from gen.
+func NewAuctionRepo(db *sql.DB, clk clock.Clock, gen ids.Generator) *AuctionRepo {
+	return &AuctionRepo{db: db, clock: clk, ids: gen}
 }
 
 func (r *AuctionRepo) Create(ctx context.Context, a *store.Auction) error {
+	a.ID = r.ids.NewID()
 	a.CreatedAt = r.clock.Now().UTC()
 	a.Status = "open"
-	return r.db.QueryRowContext(ctx,
-		`INSERT INTO auctions (item_name, started_by, min_bid, status, created_at)
-		 VALUES ($1, $2, $3, $4, $5) RETURNING id`,
-		a.ItemName, a.StartedBy, a.MinBid, a.Status, a.CreatedAt,
-	).Scan(&a.ID)
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO auctions (id, item_name, started_by, min_bid, status, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		a.ID, a.ItemName, a.StartedBy, a.MinBid, a.Status, a.CreatedAt,
+	)
+	return err
 }
 
 func (r *AuctionRepo) GetByID(ctx context.Context, id string) (*store.Auction, error) {
@@ -37,7 +42,7 @@ func (r *AuctionRepo) GetByID(ctx context.Context, id string) (*store.Auction, e
 		 FROM auctions WHERE id = $1`, id,
 	).Scan(&a.ID, &a.ItemName, &a.StartedBy, &a.MinBid, &a.Status, &a.WinnerID, &a.WinAmount, &a.CreatedAt, &a.ClosedAt)
 	if err != nil {
-		return nil, fmt.Errorf("getting auction: %w", err)
+		return nil, store.Classify(err, "getting auction", store.ErrAuctionNotFound, nil)
 	}
 	return a, nil
 }
@@ -54,7 +59,7 @@ func (r *AuctionRepo) Close(ctx context.Context, id string, winnerID string, amo
 	}
 	n, _ := result.RowsAffected()
 	if n == 0 {
-		return fmt.Errorf("auction %s not found or already closed", id)
+		return store.ErrAuctionNotOpen.Wrap(fmt.Errorf("auction %s", id))
 	}
 	return nil
 }
@@ -70,7 +75,7 @@ func (r *AuctionRepo) Cancel(ctx context.Context, id string) error {
 	}
 	n, _ := result.RowsAffected()
 	if n == 0 {
-		return fmt.Errorf("auction %s not found or already closed", id)
+		return store.ErrAuctionNotOpen.Wrap(fmt.Errorf("auction %s", id))
 	}
 	return nil
 }
@@ -78,7 +83,7 @@ func (r *AuctionRepo) Cancel(ctx context.Context, id string) error {
 func (r *AuctionRepo) ListOpen(ctx context.Context) ([]store.Auction, error) {
 	rows, err := r.db.QueryContext(ctx,
 		`SELECT id, item_name, started_by, min_bid, status, winner_id, win_amount, created_at, closed_at
-		 FROM auctions WHERE status = 'open' ORDER BY created_at ASC`)
+		 FROM auctions WHERE status NOT IN ('closed', 'canceled') ORDER BY created_at ASC`)
 	if err != nil {
 		return nil, fmt.Errorf("listing open auctions: %w", err)
 	}
@@ -94,3 +99,18 @@ func (r *AuctionRepo) ListOpen(ctx context.Context) ([]store.Auction, error) {
 	}
 	return auctions, rows.Err()
 }
+
+func (r *AuctionRepo) Save(ctx context.Context, a *store.Auction) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO auctions (id, item_name, started_by, min_bid, status, winner_id, win_amount, created_at, closed_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 ON CONFLICT (id) DO UPDATE SET item_name = EXCLUDED.item_name, started_by = EXCLUDED.started_by,
+		     min_bid = EXCLUDED.min_bid, status = EXCLUDED.status, winner_id = EXCLUDED.winner_id,
+		     win_amount = EXCLUDED.win_amount, created_at = EXCLUDED.created_at, closed_at = EXCLUDED.closed_at`,
+		a.ID, a.ItemName, a.StartedBy, a.MinBid, a.Status, a.WinnerID, a.WinAmount, a.CreatedAt, a.ClosedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("saving auction %s: %w", a.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package entstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// BalanceRepo implements store.BalanceRepository using database/sql.
+type BalanceRepo struct {
+	db    *sql.DB
+	clock clock.Clock
+	fence *store.Fence
+}
+
+// NewBalanceRepo returns a new BalanceRepo. Updates are rejected once fence
+// has been superseded; fence may be nil.
+func NewBalanceRepo(db *sql.DB, clk clock.Clock, fence *store.Fence) *BalanceRepo {
+	return &BalanceRepo{db: db, clock: clk, fence: fence}
+}
+
+func (r *BalanceRepo) Get(ctx context.Context, playerID, currency string) (int, error) {
+	var balance int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT balance FROM player_balances WHERE player_id = $1 AND currency = $2`,
+		playerID, currency,
+	).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("getting %s balance: %w", currency, err)
+	}
+	return balance, nil
+}
+
+func (r *BalanceRepo) List(ctx context.Context, currency string) ([]store.Balance, error) {
+	return r.query(ctx, `SELECT player_id, currency, balance, updated_at FROM player_balances WHERE currency = $1 ORDER BY balance DESC`, currency)
+}
+
+func (r *BalanceRepo) ListByPlayer(ctx context.Context, playerID string) ([]store.Balance, error) {
+	return r.query(ctx, `SELECT player_id, currency, balance, updated_at FROM player_balances WHERE player_id = $1 ORDER BY currency`, playerID)
+}
+
+func (r *BalanceRepo) query(ctx context.Context, query string, arg string) ([]store.Balance, error) {
+	rows, err := r.db.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("listing balances: %w", err)
+	}
+	defer rows.Close()
+
+	var balances []store.Balance
+	for rows.Next() {
+		var b store.Balance
+		if err := rows.Scan(&b.PlayerID, &b.Currency, &b.Balance, &b.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning balance: %w", err)
+		}
+		balances = append(balances, b)
+	}
+	return balances, rows.Err()
+}
+
+func (r *BalanceRepo) Update(ctx context.Context, id, currency string, delta int) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		return r.add(ctx, tx, id, currency, delta)
+	})
+}
+
+func (r *BalanceRepo) Transfer(ctx context.Context, fromID, toID, currency string, amount int) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		if err := r.add(ctx, tx, fromID, currency, -amount); err != nil {
+			return err
+		}
+		return r.add(ctx, tx, toID, currency, amount)
+	})
+}
+
+// inTx runs fn in a transaction checked against the fence, and commits it
+// if fn succeeds.
+func (r *BalanceRepo) inTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := r.fence.Check(ctx, tx); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// add changes the balance of the player id in currency by delta, locking
+// the player's row so that they are not archived meanwhile.
+func (r *BalanceRepo) add(ctx context.Context, tx *sql.Tx, id, currency string, delta int) error {
+	var archived bool
+	err := tx.QueryRowContext(ctx, `SELECT archived_at IS NOT NULL FROM players WHERE id = $1 FOR UPDATE`, id).Scan(&archived)
+	switch {
+	case err != nil:
+		return store.Classify(err, "updating balance", store.ErrPlayerNotFound, nil)
+	case archived:
+		return store.ErrPlayerArchived.Wrap(fmt.Errorf("updating balance: player %s is archived", id))
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO player_balances (player_id, currency, balance, updated_at)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (player_id, currency) DO UPDATE
+		 SET balance = player_balances.balance + EXCLUDED.balance, updated_at = EXCLUDED.updated_at`,
+		id, currency, delta, r.clock.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("updating %s balance: %w", currency, err)
+	}
+	return nil
+}
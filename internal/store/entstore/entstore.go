@@ -13,11 +13,11 @@ import (
 	"fmt"
 
 	"github.com/XSAM/otelsql"
-	_ "github.com/lib/pq" // postgres driver
-	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 
 	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/ids"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
 )
 
@@ -32,30 +32,50 @@ func init() {
 
 // openEnt is the store.Driver for the "ent" backend.
 func openEnt(ctx context.Context, cfg config.DatabaseConfig, clk clock.Clock) (*store.Repositories, error) {
+	cipher, err := store.NewCipher(cfg.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	chainKey, err := store.ParseChainKey(cfg.ChainKey)
+	if err != nil {
+		return nil, err
+	}
+	gen, err := ids.New(cfg.IDs, clk)
+	if err != nil {
+		return nil, err
+	}
 	db, err := Connect(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
+	fence := store.NewFence(db)
+	events := NewEventStore(db, fence, cfg.AppendBatchSize, cipher, chainKey)
 	return &store.Repositories{
-		Players:  NewPlayerRepo(db, clk),
-		Auctions: NewAuctionRepo(db, clk),
-		Events:   NewEventStore(db),
-		Closer:   closerFunc(db.Close),
-		Ping:     db.PingContext,
+		Players:       NewPlayerRepo(db, clk, fence, cipher),
+		Auctions:      NewAuctionRepo(db, clk, gen),
+		Events:        events,
+		Idempotency:   NewIdempotencyRepo(db, clk),
+		GuildSettings: NewGuildSettingsRepo(db, clk),
+		Items:         NewItemRepo(db, clk),
+		Wishlists:     NewWishlistRepo(db, clk, cipher),
+		PlayerNotes:   NewPlayerNoteRepo(db, clk, cipher),
+		Usage:         NewUsageRepo(db, cipher),
+		Balances:      NewBalanceRepo(db, clk, fence),
+		Archive:       NewEventArchive(db, clk, cipher),
+		ChainHeads:    events.Heads,
+		Fence:         fence,
+		Closer:        closerFunc(db.Close),
+		Ping:          db.PingContext,
+		Schema:        func(ctx context.Context) error { return store.CheckSchema(ctx, db) },
+		Migrations:    func(ctx context.Context) error { return store.CheckSchemaVersion(ctx, db) },
+		Listen:        func(ctx context.Context, bus *event.Bus) error { return store.Listen(ctx, cfg, bus) },
 	}, nil
 }
 
 // Connect opens and verifies a Postgres connection via database/sql with OTEL
 // instrumentation. This is the connection style ent uses internally.
 func Connect(ctx context.Context, cfg config.DatabaseConfig) (*sql.DB, error) {
-	dsn := cfg.DSN()
-
-	db, err := otelsql.Open("postgres", dsn,
-		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("opening ent database: %w", err)
-	}
+	db := otelsql.OpenDB(store.Connector(cfg), store.TraceOptions(cfg)...)
 
 	if err := db.PingContext(ctx); err != nil {
 		closeErr := db.Close()
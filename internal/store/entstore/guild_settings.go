@@ -0,0 +1,65 @@
+package entstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// GuildSettingsRepo implements store.GuildSettingsRepository using
+// database/sql.
+type GuildSettingsRepo struct {
+	db    *sql.DB
+	clock clock.Clock
+}
+
+// NewGuildSettingsRepo returns a new GuildSettingsRepo.
+func NewGuildSettingsRepo(db *sql.DB, clk clock.Clock) *GuildSettingsRepo {
+	return &GuildSettingsRepo{db: db, clock: clk}
+}
+
+func (r *GuildSettingsRepo) List(ctx context.Context, guildID string) ([]store.GuildSetting, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT guild_id, key, value, updated_by, updated_at FROM guild_settings WHERE guild_id = $1 ORDER BY key`,
+		guildID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing guild settings: %w", err)
+	}
+	defer rows.Close()
+
+	var settings []store.GuildSetting
+	for rows.Next() {
+		var s store.GuildSetting
+		if err := rows.Scan(&s.GuildID, &s.Key, &s.Value, &s.UpdatedBy, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning guild setting: %w", err)
+		}
+		settings = append(settings, s)
+	}
+	return settings, rows.Err()
+}
+
+func (r *GuildSettingsRepo) Set(ctx context.Context, s *store.GuildSetting) error {
+	s.UpdatedAt = r.clock.Now().UTC()
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO guild_settings (guild_id, key, value, updated_by, updated_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (guild_id, key) DO UPDATE
+		 SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`,
+		s.GuildID, s.Key, s.Value, s.UpdatedBy, s.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("setting guild setting: %w", err)
+	}
+	return nil
+}
+
+func (r *GuildSettingsRepo) Delete(ctx context.Context, guildID, key string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM guild_settings WHERE guild_id = $1 AND key = $2`, guildID, key); err != nil {
+		return fmt.Errorf("deleting guild setting: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package entstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// IdempotencyRepo implements store.IdempotencyRepository using database/sql.
+type IdempotencyRepo struct {
+	db    *sql.DB
+	clock clock.Clock
+}
+
+// NewIdempotencyRepo returns a new IdempotencyRepo.
+func NewIdempotencyRepo(db *sql.DB, clk clock.Clock) *IdempotencyRepo {
+	return &IdempotencyRepo{db: db, clock: clk}
+}
+
+func (r *IdempotencyRepo) Reserve(ctx context.Context, key string, staleAfter time.Duration) (*store.IdempotencyRecord, bool, error) {
+	now := r.clock.Now().UTC()
+	// A zero cutoff is older than every claim, so none is taken over.
+	var staleBefore time.Time
+	if staleAfter > 0 {
+		staleBefore = now.Add(-staleAfter)
+	}
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO idempotency_keys (key, created_at) VALUES ($1, $2)
+		 ON CONFLICT (key) DO UPDATE SET created_at = EXCLUDED.created_at
+		 WHERE idempotency_keys.result IS NULL AND idempotency_keys.created_at < $3`,
+		key, now, staleBefore,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("reserving idempotency key: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 1 {
+		return nil, true, nil
+	}
+
+	rec := &store.IdempotencyRecord{}
+	err = r.db.QueryRowContext(ctx,
+		`SELECT key, result, created_at FROM idempotency_keys WHERE key = $1`, key,
+	).Scan(&rec.Key, &rec.Result, &rec.CreatedAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("getting idempotency key: %w", err)
+	}
+	return rec, false, nil
+}
+
+func (r *IdempotencyRepo) Complete(ctx context.Context, key string, result []byte) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE idempotency_keys SET result = $1 WHERE key = $2`, result, key); err != nil {
+		return fmt.Errorf("completing idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *IdempotencyRepo) Release(ctx context.Context, key string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1`, key); err != nil {
+		return fmt.Errorf("releasing idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *IdempotencyRepo) Prune(ctx context.Context, ttl time.Duration) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE created_at < $1`, r.clock.Now().UTC().Add(-ttl))
+	if err != nil {
+		return 0, fmt.Errorf("pruning idempotency keys: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,83 @@
+package entstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// ItemRepo implements store.ItemRepository using database/sql.
+type ItemRepo struct {
+	db    *sql.DB
+	clock clock.Clock
+}
+
+// NewItemRepo returns a new ItemRepo.
+func NewItemRepo(db *sql.DB, clk clock.Clock) *ItemRepo {
+	return &ItemRepo{db: db, clock: clk}
+}
+
+func (r *ItemRepo) Upsert(ctx context.Context, items []store.Item) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := r.clock.Now().UTC()
+	for i := range items {
+		items[i].UpdatedAt = now
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO items (id, name, quality, icon_url, slot, stats, updated_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)
+			 ON CONFLICT (id) DO UPDATE
+			 SET name = EXCLUDED.name, quality = EXCLUDED.quality, icon_url = EXCLUDED.icon_url, slot = EXCLUDED.slot, stats = EXCLUDED.stats, updated_at = EXCLUDED.updated_at`,
+			items[i].ID, items[i].Name, items[i].Quality, items[i].IconURL, items[i].Slot, items[i].Stats, now,
+		); err != nil {
+			return fmt.Errorf("upserting item %s: %w", items[i].ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing items: %w", err)
+	}
+	return nil
+}
+
+func (r *ItemRepo) Search(ctx context.Context, query string, limit int) ([]store.Item, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, name, quality, icon_url, slot, stats, updated_at FROM items
+		 WHERE strpos(lower(name), lower($1)) > 0
+		 ORDER BY strpos(lower(name), lower($1)) = 1 DESC, name
+		 LIMIT $2`,
+		query, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("searching items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []store.Item
+	for rows.Next() {
+		var it store.Item
+		if err := rows.Scan(&it.ID, &it.Name, &it.Quality, &it.IconURL, &it.Slot, &it.Stats, &it.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning item: %w", err)
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+func (r *ItemRepo) GetByName(ctx context.Context, name string) (*store.Item, error) {
+	var it store.Item
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, name, quality, icon_url, slot, stats, updated_at FROM items WHERE lower(name) = lower($1) ORDER BY id LIMIT 1`, name,
+	).Scan(&it.ID, &it.Name, &it.Quality, &it.IconURL, &it.Slot, &it.Stats, &it.UpdatedAt)
+	if err != nil {
+		return nil, store.Classify(err, "getting item by name", store.ErrItemNotFound, nil)
+	}
+	return &it, nil
+}
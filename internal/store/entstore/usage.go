@@ -0,0 +1,78 @@
+package entstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// UsageRepo implements store.UsageRepository using database/sql.
+type UsageRepo struct {
+	db     *sql.DB
+	cipher *store.Cipher
+}
+
+// NewUsageRepo returns a new UsageRepo. The Discord IDs of users are sealed
+// with cipher, which may be nil.
+func NewUsageRepo(db *sql.DB, cipher *store.Cipher) *UsageRepo {
+	return &UsageRepo{db: db, cipher: cipher}
+}
+
+func (r *UsageRepo) Record(ctx context.Context, command, userID string, failed bool, at time.Time) (bool, error) {
+	failures := 0
+	if failed {
+		failures = 1
+	}
+	var first bool
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO command_usage (day, command, user_id, uses, failures)
+		 VALUES ($1, $2, $3, 1, $4)
+		 ON CONFLICT (day, command, user_id) DO UPDATE
+		 SET uses = command_usage.uses + 1, failures = command_usage.failures + EXCLUDED.failures
+		 RETURNING uses = 1`,
+		day(at), command, r.cipher.SealID(userID), failures,
+	).Scan(&first)
+	if err != nil {
+		return false, fmt.Errorf("recording command usage: %w", err)
+	}
+	return first, nil
+}
+
+func (r *UsageRepo) Summary(ctx context.Context, since time.Time) ([]store.CommandUsage, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT command, sum(uses) AS uses, count(DISTINCT user_id) AS users, sum(failures) AS failures
+		 FROM command_usage WHERE day >= $1
+		 GROUP BY command ORDER BY uses DESC, command`,
+		day(since),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("summarizing command usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []store.CommandUsage
+	for rows.Next() {
+		var u store.CommandUsage
+		if err := rows.Scan(&u.Command, &u.Uses, &u.Users, &u.Failures); err != nil {
+			return nil, fmt.Errorf("scanning command usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+func (r *UsageRepo) Users(ctx context.Context, since time.Time) (int, error) {
+	var n int
+	if err := r.db.QueryRowContext(ctx, `SELECT count(DISTINCT user_id) FROM command_usage WHERE day >= $1`, day(since)).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting command users: %w", err)
+	}
+	return n, nil
+}
+
+// day formats the UTC day of t as a Postgres date.
+func day(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
@@ -0,0 +1,117 @@
+package entstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// WishlistRepo implements store.WishlistRepository using database/sql.
+type WishlistRepo struct {
+	db     *sql.DB
+	clock  clock.Clock
+	cipher *store.Cipher
+}
+
+// NewWishlistRepo returns a new WishlistRepo. The Discord IDs of wishers
+// are opened with cipher, which may be nil.
+func NewWishlistRepo(db *sql.DB, clk clock.Clock, cipher *store.Cipher) *WishlistRepo {
+	return &WishlistRepo{db: db, clock: clk, cipher: cipher}
+}
+
+func (r *WishlistRepo) Add(ctx context.Context, e *store.WishlistEntry) error {
+	e.CreatedAt = r.clock.Now().UTC()
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO wishlists (player_id, item_name, created_at) VALUES ($1, $2, $3)`,
+		e.PlayerID, e.ItemName, e.CreatedAt,
+	)
+	return store.Classify(err, "adding wishlist item", nil, store.ErrWishlisted)
+}
+
+func (r *WishlistRepo) Remove(ctx context.Context, playerID, itemName string) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM wishlists WHERE player_id = $1 AND lower(item_name) = lower($2)`, playerID, itemName)
+	if err != nil {
+		return fmt.Errorf("removing wishlist item: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return store.ErrNotWishlisted.Wrap(fmt.Errorf("item %q", itemName))
+	}
+	return nil
+}
+
+func (r *WishlistRepo) ListByPlayer(ctx context.Context, playerID string) ([]store.WishlistEntry, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT player_id, item_name, created_at FROM wishlists WHERE player_id = $1 ORDER BY created_at, item_name`,
+		playerID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing wishlist: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []store.WishlistEntry
+	for rows.Next() {
+		var e store.WishlistEntry
+		if err := rows.Scan(&e.PlayerID, &e.ItemName, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning wishlist entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *WishlistRepo) Wishers(ctx context.Context, itemName string) ([]store.Player, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT p.id, p.discord_id, p.character_name, p.dkp, p.class, p.role, p.spec, p.timezone, p.created_at, p.updated_at
+		 FROM players p JOIN wishlists w ON w.player_id = p.id
+		 WHERE lower(w.item_name) = lower($1) ORDER BY p.character_name`,
+		itemName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing wishers: %w", err)
+	}
+	defer rows.Close()
+
+	var players []store.Player
+	for rows.Next() {
+		var p store.Player
+		if err := rows.Scan(&p.ID, &p.DiscordID, &p.CharacterName, &p.DKP, &p.Class, &p.Role, &p.Spec, &p.Timezone, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning player row: %w", err)
+		}
+		players = append(players, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.cipher.OpenPlayers(players); err != nil {
+		return nil, err
+	}
+	return players, nil
+}
+
+func (r *WishlistRepo) Demand(ctx context.Context, limit int) ([]store.ItemDemand, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT min(item_name) AS item_name, count(*) AS players FROM wishlists
+		 GROUP BY lower(item_name) ORDER BY players DESC, item_name LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reporting wishlist demand: %w", err)
+	}
+	defer rows.Close()
+
+	var demand []store.ItemDemand
+	for rows.Next() {
+		var d store.ItemDemand
+		if err := rows.Scan(&d.ItemName, &d.Players); err != nil {
+			return nil, fmt.Errorf("scanning item demand: %w", err)
+		}
+		demand = append(demand, d)
+	}
+	return demand, rows.Err()
+}
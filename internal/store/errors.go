@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+)
+
+// Errors returned by repositories. Drivers wrap the database error with
+// Classify so that callers can match these with errors.Is.
+var (
+	ErrPlayerNotFound  = derrors.New(derrors.NotFound, "PLAYER_NOT_FOUND", "player not found")
+	ErrPlayerExists    = derrors.New(derrors.Conflict, "PLAYER_EXISTS", "this Discord user is already registered")
+	ErrPlayerArchived  = derrors.New(derrors.Conflict, "PLAYER_ARCHIVED", "this player is archived and their DKP is frozen")
+	ErrAuctionNotFound = derrors.New(derrors.NotFound, "AUCTION_NOT_FOUND", "auction not found")
+	ErrAuctionNotOpen  = derrors.New(derrors.Conflict, "AUCTION_NOT_OPEN", "auction not found or already closed")
+	ErrItemNotFound    = derrors.New(derrors.NotFound, "ITEM_NOT_FOUND", "item not found in the catalog")
+	ErrWishlisted      = derrors.New(derrors.Conflict, "WISHLISTED", "this item is already on your wishlist")
+	ErrNotWishlisted   = derrors.New(derrors.NotFound, "NOT_WISHLISTED", "this item is not on your wishlist")
+)
+
+// Postgres SQLSTATEs the store tells apart.
+const (
+	uniqueViolation      = "23505"
+	undefinedTable       = "42P01"
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// Postgres SQLSTATE classes of errors that retrying may get past: lost
+// connections, exhausted resources such as connection slots, and the
+// server shutting down or starting up.
+var transientClasses = []string{"08", "53", "57P"}
+
+// Classify wraps err, returned by the database during op, in the given
+// classified errors: a missing row becomes notFound and a unique constraint
+// violation becomes conflict. Either may be nil. Other errors are returned
+// unclassified, and so are treated as internal.
+func Classify(err error, op string, notFound, conflict *derrors.Error) error {
+	if err == nil {
+		return nil
+	}
+	wrapped := fmt.Errorf("%s: %w", op, err)
+	if notFound != nil && errors.Is(err, sql.ErrNoRows) {
+		return notFound.Wrap(wrapped)
+	}
+	var state interface{ SQLState() string }
+	if conflict != nil && errors.As(err, &state) && state.SQLState() == uniqueViolation {
+		return conflict.Wrap(wrapped)
+	}
+	return wrapped
+}
+
+// Transient reports whether err, returned by the database, is likely to go
+// away if the operation is retried later: a refused or lost connection, a
+// timeout, or a server that is overloaded, restarting, or aborted the
+// transaction for a conflict with another. Errors in the operation itself,
+// such as constraint violations, are not transient.
+func Transient(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, target := range []error{context.DeadlineExceeded, driver.ErrBadConn, sql.ErrConnDone, io.ErrUnexpectedEOF} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var state interface{ SQLState() string }
+	if !errors.As(err, &state) {
+		return false
+	}
+	code := state.SQLState()
+	if code == serializationFailure || code == deadlockDetected {
+		return true
+	}
+	for _, class := range transientClasses {
+		if strings.HasPrefix(code, class) {
+			return true
+		}
+	}
+	return false
+}
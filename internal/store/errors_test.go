@@ -0,0 +1,83 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// sqlStateError mimics the driver errors that expose a SQLSTATE.
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "pq: " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		want     error
+		wantKind derrors.Kind
+	}{
+		{name: "nil", err: nil},
+		{name: "no rows", err: sql.ErrNoRows, want: store.ErrPlayerNotFound, wantKind: derrors.NotFound},
+		{name: "unique violation", err: sqlStateError("23505"), want: store.ErrPlayerExists, wantKind: derrors.Conflict},
+		{name: "other database error", err: sqlStateError("08006"), wantKind: derrors.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := store.Classify(tt.err, "op", store.ErrPlayerNotFound, store.ErrPlayerExists)
+			if tt.err == nil {
+				if err != nil {
+					t.Fatalf("Classify(nil) = %v, want nil", err)
+				}
+				return
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Classify() = %v, want %v", err, tt.want)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Classify() = %v lost its cause %v", err, tt.err)
+			}
+			if got := derrors.KindOf(err); got != tt.wantKind {
+				t.Errorf("kind = %s, want %s", got, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, want: true},
+		{name: "timeout", err: fmt.Errorf("inserting events: %w", context.DeadlineExceeded), want: true},
+		{name: "connection closed", err: sql.ErrConnDone, want: true},
+		{name: "connection failure", err: sqlStateError("08006"), want: true},
+		{name: "too many connections", err: sqlStateError("53300"), want: true},
+		{name: "server shutting down", err: sqlStateError("57P01"), want: true},
+		{name: "serialization failure", err: sqlStateError("40001"), want: true},
+		{name: "unique violation", err: fmt.Errorf("inserting events: %w", sqlStateError("23505")), want: false},
+		{name: "fenced", err: store.ErrFenced, want: false},
+		{name: "other error", err: errors.New("invalid aggregate ID"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := store.Transient(tt.err); got != tt.want {
+				t.Errorf("Transient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
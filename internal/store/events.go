@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// eventColumns are the columns event stores insert, in argument order.
+var eventColumns = []string{"aggregate_id", "type", "data", "version", "actor", "created_at", "prev_hash", "chain_hash"}
+
+const (
+	// DefaultAppendBatchSize is the number of events inserted per statement
+	// unless database.append_batch_size says otherwise.
+	DefaultAppendBatchSize = 500
+	// MaxAppendBatchSize is the most events one statement can insert, as
+	// Postgres allows at most 65535 parameters per statement.
+	MaxAppendBatchSize = 65535 / 8
+)
+
+// InsertEventsQuery returns a multi-row INSERT of events into the events
+// table and its positional arguments. The events must already be chained.
+// The query returns the aggregate ID, version, and ID of each inserted row,
+// for ScanInsertedIDs.
+func InsertEventsQuery(events []event.Event) (string, []any) {
+	var b strings.Builder
+	b.WriteString("INSERT INTO events (" + strings.Join(eventColumns, ", ") + ") VALUES ")
+	args := make([]any, 0, len(events)*len(eventColumns))
+	for i, e := range events {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		n := len(args)
+		fmt.Fprintf(&b, "($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8)
+		args = append(args, e.AggregateID, e.Type, e.Data, e.Version, e.Actor, e.CreatedAt, e.PrevHash, e.ChainHash)
+	}
+	b.WriteString(" RETURNING aggregate_id, version, id")
+	return b.String(), args
+}
+
+// ScanInsertedIDs sets the ID of each of events from rows, the result of
+// the query InsertEventsQuery returned for them, and closes rows.
+func ScanInsertedIDs(rows *sql.Rows, events []event.Event) error {
+	defer rows.Close()
+	type key struct {
+		aggregateID string
+		version     int
+	}
+	ids := make(map[key]string, len(events))
+	for rows.Next() {
+		var k key
+		var id string
+		if err := rows.Scan(&k.aggregateID, &k.version, &id); err != nil {
+			return err
+		}
+		ids[k] = id
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i, e := range events {
+		events[i].ID = ids[key{e.AggregateID, e.Version}]
+	}
+	return nil
+}
+
+// ParseChainKey returns the base64-encoded 32-byte key event stores chain
+// events with, or nil if key is empty.
+func ParseChainKey(key string) ([]byte, error) {
+	if key == "" {
+		return nil, nil
+	}
+	secret, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("decoding chain key: %w", err)
+	}
+	if len(secret) != 32 {
+		return nil, fmt.Errorf("chain key is %d bytes, want 32", len(secret))
+	}
+	return secret, nil
+}
+
+// RecordChainHeads records in tx the last of events of each aggregate,
+// which must already be chained, as the head of the aggregate's hash chain.
+func RecordChainHeads(ctx context.Context, tx Execer, events []event.Event) error {
+	last := make(map[string]int)
+	var (
+		ids, hashes []string
+		versions    []int64
+	)
+	for _, e := range events {
+		i, ok := last[e.AggregateID]
+		if !ok {
+			i = len(ids)
+			last[e.AggregateID] = i
+			ids, versions, hashes = append(ids, e.AggregateID), append(versions, 0), append(hashes, "")
+		}
+		versions[i], hashes[i] = int64(e.Version), e.ChainHash
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO chain_heads (aggregate_id, version, chain_hash)
+		 SELECT * FROM unnest($1::text[], $2::int[], $3::text[])
+		 ON CONFLICT (aggregate_id) DO UPDATE SET version = EXCLUDED.version, chain_hash = EXCLUDED.chain_hash`,
+		pq.Array(ids), pq.Array(versions), pq.Array(hashes),
+	); err != nil {
+		return fmt.Errorf("recording chain heads: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package store_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+func TestInsertEventsQuery(t *testing.T) {
+	at := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	events := []event.Event{
+		{AggregateID: "a1", Type: event.AuctionStarted, Data: json.RawMessage(`{}`), Version: 1, Actor: "officer", CreatedAt: at, ChainHash: "h1"},
+		{AggregateID: "a1", Type: event.AuctionBidPlaced, Data: json.RawMessage(`{}`), Version: 2, Actor: "officer", CreatedAt: at, PrevHash: "h1", ChainHash: "h2"},
+	}
+
+	query, args := store.InsertEventsQuery(events)
+
+	want := "INSERT INTO events (aggregate_id, type, data, version, actor, created_at, prev_hash, chain_hash) VALUES " +
+		"($1, $2, $3, $4, $5, $6, $7, $8), ($9, $10, $11, $12, $13, $14, $15, $16) RETURNING aggregate_id, version, id"
+	if query != want {
+		t.Errorf("query = %q, want %q", query, want)
+	}
+	if len(args) != 16 {
+		t.Fatalf("got %d args, want 16", len(args))
+	}
+	if args[8] != "a1" || args[11] != 2 || args[14] != "h1" || args[15] != "h2" {
+		t.Errorf("second row args = %v, want aggregate a1, version 2, prev hash h1, chain hash h2", args[8:])
+	}
+}
+
+func TestMaxAppendBatchSize(t *testing.T) {
+	_, args := store.InsertEventsQuery(make([]event.Event, store.MaxAppendBatchSize))
+	if len(args) > 65535 {
+		t.Errorf("a full batch has %d parameters, more than Postgres allows", len(args))
+	}
+}
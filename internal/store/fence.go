@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+)
+
+// ErrFenced is returned for writes from a replica whose fencing token has
+// been superseded by a newer leader.
+var ErrFenced = derrors.New(derrors.Conflict, "FENCED", "this replica is no longer the leader")
+
+// Querier runs a single-row query. *sql.DB and *sql.Tx implement it, as do
+// their sqlx counterparts.
+type Querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Fence guards writes against a leader that lost its lease without
+// noticing, for example after a long pause. Every new leader acquires a
+// fencing token that is higher than any before it, and repositories check
+// inside their write transactions that the token is still the newest.
+//
+// Until a token is acquired, as on deployments without leader election,
+// writes are not fenced, unless Require was called. A nil *Fence never
+// rejects writes.
+type Fence struct {
+	db Querier
+
+	mu       sync.RWMutex
+	name     string
+	token    int64
+	required bool
+}
+
+// NewFence returns a Fence whose tokens are kept in db.
+func NewFence(db Querier) *Fence {
+	return &Fence{db: db}
+}
+
+// Require makes Check reject writes while no token is held. Deployments
+// with leader election call it at startup, so that a replica writes only
+// once it has become the leader.
+func (f *Fence) Require() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.required = true
+}
+
+// Acquire increments and takes the fencing token for the lease name.
+func (f *Fence) Acquire(ctx context.Context, name string) (int64, error) {
+	var token int64
+	err := f.db.QueryRowContext(ctx,
+		`INSERT INTO fencing_tokens (name, token) VALUES ($1, 1)
+		 ON CONFLICT (name) DO UPDATE SET token = fencing_tokens.token + 1
+		 RETURNING token`, name,
+	).Scan(&token)
+	if err != nil {
+		return 0, fmt.Errorf("acquiring fencing token: %w", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.name, f.token = name, token
+	return token, nil
+}
+
+// Token returns the held token, or 0 if none was acquired.
+func (f *Fence) Token() int64 {
+	if f == nil {
+		return 0
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.token
+}
+
+// Check returns ErrFenced if a newer token than the held one exists, or if
+// a token is required and none is held. Run
+// it inside the write transaction tx: the row stays share-locked until the
+// transaction ends, so a new leader cannot take over halfway through.
+func (f *Fence) Check(ctx context.Context, tx Querier) error {
+	if f == nil {
+		return nil
+	}
+	f.mu.RLock()
+	name, held, required := f.name, f.token, f.required
+	f.mu.RUnlock()
+	if held == 0 {
+		if required {
+			return ErrFenced.Wrap(errors.New("holding no fencing token"))
+		}
+		return nil
+	}
+
+	var current int64
+	err := tx.QueryRowContext(ctx,
+		`SELECT token FROM fencing_tokens WHERE name = $1 FOR SHARE`, name,
+	).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("checking fencing token: %w", err)
+	}
+	if current != held {
+		return ErrFenced.Wrap(fmt.Errorf("holding fencing token %d, current is %d", held, current))
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+func TestFence_Check(t *testing.T) {
+	ctx := context.Background()
+
+	// Without a token, and without a database to check it against, writes
+	// pass unless a token is required.
+	f := store.NewFence(nil)
+	if err := f.Check(ctx, nil); err != nil {
+		t.Errorf("Check() without a token = %v, want nil", err)
+	}
+	f.Require()
+	if err := f.Check(ctx, nil); !errors.Is(err, store.ErrFenced) {
+		t.Errorf("Check() without a required token = %v, want ErrFenced", err)
+	}
+
+	var nilFence *store.Fence
+	if err := nilFence.Check(ctx, nil); err != nil {
+		t.Errorf("nil Fence Check() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+)
+
+// EventsChannel is the Postgres notification channel on which event stores
+// announce the types of the events they append.
+const EventsChannel = "dkpbot_events"
+
+// appendNotice is the payload of a notification on EventsChannel.
+type appendNotice struct {
+	Types []event.Type `json:"types"`
+}
+
+// Execer runs a statement. *sql.Tx and *sqlx.Tx implement it.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// NotifyAppended announces on EventsChannel the types of events appended in
+// tx. Postgres delivers the notification when tx commits, and drops it if
+// tx rolls back.
+func NotifyAppended(ctx context.Context, tx Execer, events []event.Event) error {
+	var notice appendNotice
+	for _, e := range events {
+		if !slices.Contains(notice.Types, e.Type) {
+			notice.Types = append(notice.Types, e.Type)
+		}
+	}
+	payload, err := json.Marshal(notice)
+	if err != nil {
+		return fmt.Errorf("encoding append notice: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, EventsChannel, string(payload)); err != nil {
+		return fmt.Errorf("notifying appended events: %w", err)
+	}
+	return nil
+}
+
+// Listen publishes on bus the events appended by every process using the
+// database of cfg, this one included, as announced by NotifyAppended, until
+// ctx is done. The published events carry only their type, so bus is for
+// caches and projections that need to know that something changed, not for
+// handlers that act on the events themselves.
+//
+// The listening connection is reestablished after it is lost. Events
+// appended in the meantime are not announced, so callers should still
+// refresh now and then.
+func Listen(ctx context.Context, cfg config.DatabaseConfig, bus *event.Bus) error {
+	if cfg.PasswordFunc != nil {
+		cfg.Password = cfg.PasswordFunc()
+	}
+	l := pq.NewListener(cfg.DSN(), time.Second, time.Minute, nil)
+	defer l.Close()
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+
+	if err := l.Listen(EventsChannel); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("listening on %s: %w", EventsChannel, err)
+	}
+
+	// A quiet connection is pinged so that a lost one is noticed and
+	// reestablished.
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ping.C:
+			go func() { _ = l.Ping() }()
+		case n, ok := <-l.NotificationChannel():
+			if !ok {
+				return nil
+			}
+			// A nil notification follows a reconnect.
+			if n == nil {
+				continue
+			}
+			var notice appendNotice
+			if err := json.Unmarshal([]byte(n.Extra), &notice); err != nil {
+				continue
+			}
+			for _, t := range notice.Types {
+				bus.Publish(ctx, event.Event{Type: t})
+			}
+		}
+	}
+}
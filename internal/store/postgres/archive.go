@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// EventArchive implements event.Archive backed by Postgres.
+type EventArchive struct {
+	db     *sqlx.DB
+	clock  clock.Clock
+	cipher *store.Cipher
+}
+
+// NewEventArchive returns a new EventArchive. Snapshots are sealed with
+// cipher, which may be nil.
+func NewEventArchive(db *sqlx.DB, clk clock.Clock, cipher *store.Cipher) *EventArchive {
+	return &EventArchive{db: db, clock: clk, cipher: cipher}
+}
+
+func (a *EventArchive) SaveSnapshot(ctx context.Context, s event.Snapshot) error {
+	s, err := a.cipher.SealSnapshot(s)
+	if err != nil {
+		return err
+	}
+	_, err = a.db.ExecContext(ctx,
+		`INSERT INTO event_snapshots (aggregate_id, version, state, created_at)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (aggregate_id) DO UPDATE
+		 SET version = EXCLUDED.version, state = EXCLUDED.state, created_at = EXCLUDED.created_at`,
+		s.AggregateID, s.Version, s.State, a.clock.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("saving snapshot: %w", err)
+	}
+	return nil
+}
+
+func (a *EventArchive) LoadSnapshot(ctx context.Context, aggregateID string) (*event.Snapshot, error) {
+	var s event.Snapshot
+	err := a.db.GetContext(ctx, &s,
+		`SELECT aggregate_id, version, state, created_at FROM event_snapshots WHERE aggregate_id = $1`, aggregateID)
+	if err != nil {
+		return nil, fmt.Errorf("loading snapshot: %w", err)
+	}
+	if err := a.cipher.OpenSnapshot(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (a *EventArchive) ArchiveAggregate(ctx context.Context, aggregateID string, upToVersion int) (int, error) {
+	tx, err := a.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO events_archive (id, aggregate_id, type, data, version, actor, created_at, prev_hash, chain_hash, archived_at)
+		 SELECT id, aggregate_id, type, data, version, actor, created_at, prev_hash, chain_hash, $3
+		 FROM events WHERE aggregate_id = $1 AND version <= $2`,
+		aggregateID, upToVersion, a.clock.Now().UTC(),
+	); err != nil {
+		return 0, fmt.Errorf("copying events to archive: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM events WHERE aggregate_id = $1 AND version <= $2`, aggregateID, upToVersion)
+	if err != nil {
+		return 0, fmt.Errorf("deleting archived events: %w", err)
+	}
+	n, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing archive: %w", err)
+	}
+	return int(n), nil
+}
+
+func (a *EventArchive) Archived(ctx context.Context, types ...event.Type) ([]event.Event, error) {
+	query := `SELECT id, aggregate_id, type, data, version, actor, created_at, prev_hash, chain_hash FROM events_archive`
+	var args []any
+	if len(types) > 0 {
+		names := make([]string, len(types))
+		for i, t := range types {
+			names[i] = string(t)
+		}
+		query += ` WHERE type = ANY($1)`
+		args = append(args, pq.Array(names))
+	}
+	query += ` ORDER BY created_at ASC, aggregate_id, version`
+
+	var events []event.Event
+	if err := a.db.SelectContext(ctx, &events, query, args...); err != nil {
+		return nil, fmt.Errorf("loading archived events: %w", err)
+	}
+	if err := a.cipher.OpenEvents(events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,86 @@
+package postgres_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/postgres"
+)
+
+func TestEventArchive_ArchiveAggregate(t *testing.T) {
+	db := newTestDB(t)
+	es := postgres.NewEventStore(db, nil, 0, nil, nil)
+	archive := postgres.NewEventArchive(db, clock.Real{}, nil)
+	ctx := context.Background()
+
+	events := []event.Event{
+		{AggregateID: "a1", Type: event.AuctionStarted, Data: json.RawMessage(`{}`), Version: 1},
+		{AggregateID: "a1", Type: event.AuctionClosed, Data: json.RawMessage(`{}`), Version: 2},
+		{AggregateID: "a2", Type: event.AuctionStarted, Data: json.RawMessage(`{}`), Version: 1},
+	}
+	if err := es.Append(ctx, events...); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	if err := archive.SaveSnapshot(ctx, event.Snapshot{AggregateID: "a1", Version: 2, State: json.RawMessage(`{"status":"closed"}`)}); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+
+	n, err := archive.ArchiveAggregate(ctx, "a1", 2)
+	if err != nil {
+		t.Fatalf("ArchiveAggregate: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("ArchiveAggregate moved %d events, want 2", n)
+	}
+
+	remaining, err := es.Load(ctx, "a1")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("a1 still has %d hot events, want 0", len(remaining))
+	}
+	if other, _ := es.Load(ctx, "a2"); len(other) != 1 {
+		t.Errorf("a2 has %d hot events, want 1", len(other))
+	}
+
+	var archived int
+	if err := db.GetContext(ctx, &archived, `SELECT count(*) FROM events_archive WHERE aggregate_id = 'a1'`); err != nil {
+		t.Fatalf("counting archive: %v", err)
+	}
+	if archived != 2 {
+		t.Errorf("archive has %d events, want 2", archived)
+	}
+
+	snap, err := archive.LoadSnapshot(ctx, "a1")
+	if err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	if snap.Version != 2 {
+		t.Errorf("snapshot version = %d, want 2", snap.Version)
+	}
+
+	moved, err := archive.Archived(ctx, event.AuctionClosed)
+	if err != nil {
+		t.Fatalf("Archived: %v", err)
+	}
+	if len(moved) != 1 || moved[0].AggregateID != "a1" || moved[0].Type != event.AuctionClosed {
+		t.Errorf("Archived(%s) = %+v, want the close of a1", event.AuctionClosed, moved)
+	}
+	all, err := archive.Archived(ctx)
+	if err != nil {
+		t.Fatalf("Archived: %v", err)
+	}
+	heads, err := es.Heads(ctx)
+	if err != nil {
+		t.Fatalf("Heads: %v", err)
+	}
+	live, _ := es.Load(ctx, "a2")
+	if report := event.VerifyChain(nil, append(all, live...), heads); report.Verified != 3 || len(report.Problems) != 0 {
+		t.Errorf("chain report over archived and live events = %+v", report)
+	}
+}
@@ -3,15 +3,17 @@ package postgres_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/ids"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/store/postgres"
 )
 
 func TestAuctionRepo_CreateAndGetByID(t *testing.T) {
 	db := newTestDB(t)
-	repo := postgres.NewAuctionRepo(db, clock.Real{})
+	repo := postgres.NewAuctionRepo(db, clock.Real{}, ids.NewULID(clock.Real{}))
 	ctx := context.Background()
 
 	a := &store.Auction{
@@ -40,7 +42,7 @@ func TestAuctionRepo_CreateAndGetByID(t *testing.T) {
 
 func TestAuctionRepo_ListOpen(t *testing.T) {
 	db := newTestDB(t)
-	repo := postgres.NewAuctionRepo(db, clock.Real{})
+	repo := postgres.NewAuctionRepo(db, clock.Real{}, ids.NewULID(clock.Real{}))
 	ctx := context.Background()
 
 	for _, item := range []string{"Item1", "Item2"} {
@@ -62,8 +64,8 @@ func TestAuctionRepo_ListOpen(t *testing.T) {
 func TestAuctionRepo_Close(t *testing.T) {
 	db := newTestDB(t)
 	clk := clock.Real{}
-	auctionRepo := postgres.NewAuctionRepo(db, clk)
-	playerRepo := postgres.NewPlayerRepo(db, clk)
+	auctionRepo := postgres.NewAuctionRepo(db, clk, ids.NewULID(clk))
+	playerRepo := postgres.NewPlayerRepo(db, clk, nil, nil)
 	ctx := context.Background()
 
 	// Need a real player for the winner foreign key.
@@ -106,7 +108,7 @@ func TestAuctionRepo_Close(t *testing.T) {
 
 func TestAuctionRepo_Cancel(t *testing.T) {
 	db := newTestDB(t)
-	repo := postgres.NewAuctionRepo(db, clock.Real{})
+	repo := postgres.NewAuctionRepo(db, clock.Real{}, ids.NewULID(clock.Real{}))
 	ctx := context.Background()
 
 	a := &store.Auction{ItemName: "Shield", StartedBy: "gm", MinBid: 5}
@@ -140,3 +142,39 @@ func TestAuctionRepo_Cancel(t *testing.T) {
 		t.Error("expected error canceling an already-canceled auction")
 	}
 }
+
+func TestAuctionRepo_Save(t *testing.T) {
+	db := newTestDB(t)
+	repo := postgres.NewAuctionRepo(db, clock.Real{}, ids.NewULID(clock.Real{}))
+	ctx := context.Background()
+
+	// Rows saved by the auction projection carry the IDs of the event log.
+	created := time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)
+	a := &store.Auction{ID: "auction-1", ItemName: "Helm", StartedBy: "gm", MinBid: 10, Status: "paused", CreatedAt: created}
+	if err := repo.Save(ctx, a); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	open, err := repo.ListOpen(ctx)
+	if err != nil {
+		t.Fatalf("ListOpen: %v", err)
+	}
+	if len(open) != 1 || open[0].Status != "paused" || !open[0].CreatedAt.Equal(created) {
+		t.Fatalf("ListOpen = %+v, want the paused auction", open)
+	}
+
+	winner, amount, closed := "player-1", 40, created.Add(time.Hour)
+	a.Status, a.WinnerID, a.WinAmount, a.ClosedAt = "closed", &winner, &amount, &closed
+	if err := repo.Save(ctx, a); err != nil {
+		t.Fatalf("Save again: %v", err)
+	}
+	got, err := repo.GetByID(ctx, "auction-1")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Status != "closed" || got.WinnerID == nil || *got.WinnerID != winner || got.WinAmount == nil || *got.WinAmount != amount {
+		t.Errorf("saved row = %+v, want closed for player-1 at 40", got)
+	}
+	if open, _ := repo.ListOpen(ctx); len(open) != 0 {
+		t.Errorf("ListOpen returned %d after close, want 0", len(open))
+	}
+}
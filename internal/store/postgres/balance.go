@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// BalanceRepo implements store.BalanceRepository with sqlx.
+type BalanceRepo struct {
+	db    *sqlx.DB
+	clock clock.Clock
+	fence *store.Fence
+}
+
+// NewBalanceRepo returns a new BalanceRepo. Updates are rejected once fence
+// has been superseded; fence may be nil.
+func NewBalanceRepo(db *sqlx.DB, clk clock.Clock, fence *store.Fence) *BalanceRepo {
+	return &BalanceRepo{db: db, clock: clk, fence: fence}
+}
+
+func (r *BalanceRepo) Get(ctx context.Context, playerID, currency string) (int, error) {
+	var balance int
+	err := r.db.GetContext(ctx, &balance,
+		`SELECT balance FROM player_balances WHERE player_id = $1 AND currency = $2`,
+		playerID, currency,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("getting %s balance: %w", currency, err)
+	}
+	return balance, nil
+}
+
+func (r *BalanceRepo) List(ctx context.Context, currency string) ([]store.Balance, error) {
+	var balances []store.Balance
+	err := r.db.SelectContext(ctx, &balances,
+		`SELECT * FROM player_balances WHERE currency = $1 ORDER BY balance DESC`, currency)
+	if err != nil {
+		return nil, fmt.Errorf("listing %s balances: %w", currency, err)
+	}
+	return balances, nil
+}
+
+func (r *BalanceRepo) ListByPlayer(ctx context.Context, playerID string) ([]store.Balance, error) {
+	var balances []store.Balance
+	err := r.db.SelectContext(ctx, &balances,
+		`SELECT * FROM player_balances WHERE player_id = $1 ORDER BY currency`, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("listing player balances: %w", err)
+	}
+	return balances, nil
+}
+
+func (r *BalanceRepo) Update(ctx context.Context, id, currency string, delta int) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		return r.add(ctx, tx, id, currency, delta)
+	})
+}
+
+func (r *BalanceRepo) Transfer(ctx context.Context, fromID, toID, currency string, amount int) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		if err := r.add(ctx, tx, fromID, currency, -amount); err != nil {
+			return err
+		}
+		return r.add(ctx, tx, toID, currency, amount)
+	})
+}
+
+// inTx runs fn in a transaction checked against the fence, and commits it
+// if fn succeeds.
+func (r *BalanceRepo) inTx(ctx context.Context, fn func(*sqlx.Tx) error) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := r.fence.Check(ctx, tx); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// add changes the balance of the player id in currency by delta, locking
+// the player's row so that they are not archived meanwhile.
+func (r *BalanceRepo) add(ctx context.Context, tx *sqlx.Tx, id, currency string, delta int) error {
+	var archived bool
+	err := tx.QueryRowContext(ctx, `SELECT archived_at IS NOT NULL FROM players WHERE id = $1 FOR UPDATE`, id).Scan(&archived)
+	switch {
+	case err != nil:
+		return store.Classify(err, "updating balance", store.ErrPlayerNotFound, nil)
+	case archived:
+		return store.ErrPlayerArchived.Wrap(fmt.Errorf("updating balance: player %s is archived", id))
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO player_balances (player_id, currency, balance, updated_at)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (player_id, currency) DO UPDATE
+		 SET balance = player_balances.balance + EXCLUDED.balance, updated_at = EXCLUDED.updated_at`,
+		id, currency, delta, r.clock.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("updating %s balance: %w", currency, err)
+	}
+	return nil
+}
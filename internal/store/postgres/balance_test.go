@@ -0,0 +1,70 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/postgres"
+)
+
+func TestBalanceRepo(t *testing.T) {
+	db := newTestDB(t)
+	players := postgres.NewPlayerRepo(db, clock.Real{}, nil, nil)
+	repo := postgres.NewBalanceRepo(db, clock.Real{}, nil)
+	ctx := context.Background()
+
+	frodo := &store.Player{DiscordID: "d1", CharacterName: "Frodo"}
+	sam := &store.Player{DiscordID: "d2", CharacterName: "Sam"}
+	for _, p := range []*store.Player{frodo, sam} {
+		if err := players.Create(ctx, p); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	if got, err := repo.Get(ctx, frodo.ID, "EP"); err != nil || got != 0 {
+		t.Errorf("Get before any change = %d, %v, want 0", got, err)
+	}
+	if err := repo.Update(ctx, frodo.ID, "EP", 50); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := repo.Update(ctx, frodo.ID, "EP", -10); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := repo.Update(ctx, frodo.ID, "GP", 7); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := repo.Transfer(ctx, frodo.ID, sam.ID, "EP", 15); err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	list, err := repo.List(ctx, "EP")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 2 || list[0].PlayerID != frodo.ID || list[0].Balance != 25 || list[1].Balance != 15 {
+		t.Errorf("List = %+v, want Frodo with 25 and Sam with 15", list)
+	}
+	mine, err := repo.ListByPlayer(ctx, frodo.ID)
+	if err != nil {
+		t.Fatalf("ListByPlayer: %v", err)
+	}
+	if len(mine) != 2 || mine[0].Currency != "EP" || mine[1].Currency != "GP" || mine[1].Balance != 7 {
+		t.Errorf("ListByPlayer = %+v, want 25 EP and 7 GP", mine)
+	}
+
+	if err := players.SetArchived(ctx, sam.ID, true); err != nil {
+		t.Fatalf("SetArchived: %v", err)
+	}
+	if err := repo.Transfer(ctx, frodo.ID, sam.ID, "EP", 5); !errors.Is(err, store.ErrPlayerArchived) {
+		t.Errorf("Transfer to an archived player error = %v, want ErrPlayerArchived", err)
+	}
+	if got, _ := repo.Get(ctx, frodo.ID, "EP"); got != 25 {
+		t.Errorf("balance after a failed transfer = %d, want 25", got)
+	}
+	if err := repo.Update(ctx, "00000000-0000-0000-0000-000000000000", "EP", 5); !errors.Is(err, store.ErrPlayerNotFound) {
+		t.Errorf("Update of an unknown player error = %v, want ErrPlayerNotFound", err)
+	}
+}
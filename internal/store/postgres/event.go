@@ -3,63 +3,277 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 
 	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
 )
 
 // EventStore implements event.Store backed by Postgres.
 type EventStore struct {
-	db *sqlx.DB
+	db        *sqlx.DB
+	fence     *store.Fence
+	batchSize int
+	cipher    *store.Cipher
+	chainKey  []byte
 }
 
-// NewEventStore returns a new EventStore.
-func NewEventStore(db *sqlx.DB) *EventStore {
-	return &EventStore{db: db}
+// NewEventStore returns a new EventStore that inserts up to batchSize events
+// per statement, or store.DefaultAppendBatchSize if batchSize is not
+// positive. Appends are rejected once fence has been superseded; fence may
+// be nil. Payloads and actors are sealed with cipher, which may be nil.
+// Events are chained with chainKey, or without a key if it is nil.
+func NewEventStore(db *sqlx.DB, fence *store.Fence, batchSize int, cipher *store.Cipher, chainKey []byte) *EventStore {
+	if batchSize <= 0 {
+		batchSize = store.DefaultAppendBatchSize
+	}
+	return &EventStore{db: db, fence: fence, batchSize: min(batchSize, store.MaxAppendBatchSize), cipher: cipher, chainKey: chainKey}
 }
 
 func (s *EventStore) Append(ctx context.Context, events ...event.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	for _, e := range events {
+		if err := event.ValidateAggregateID(e.AggregateID); err != nil {
+			return err
+		}
+	}
+
 	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("beginning transaction: %w", err)
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	stmt, err := tx.PreparexContext(ctx,
-		`INSERT INTO events (aggregate_id, type, data, version) VALUES ($1, $2, $3, $4)`)
+	if err := s.fence.Check(ctx, tx); err != nil {
+		return err
+	}
+
+	// Events without a timestamp get the transaction time, which is fixed
+	// here so that it can be covered by the chain hash.
+	var now time.Time
+	if err := tx.QueryRowContext(ctx, `SELECT now()`).Scan(&now); err != nil {
+		return fmt.Errorf("reading transaction time: %w", err)
+	}
+
+	last, err := lastEvents(ctx, tx, events)
+	if err != nil {
+		return err
+	}
+
+	chained := make([]event.Event, len(events))
+	for i, e := range events {
+		prev := last[e.AggregateID]
+		if e.Actor == "" {
+			e.Actor = event.ActorFromContext(ctx)
+		}
+		if e.Version == 0 {
+			e.Version = prev.Version + 1
+		}
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = now
+		}
+		// Postgres stores microseconds; truncate so the hash matches on reload.
+		e.CreatedAt = e.CreatedAt.UTC().Truncate(time.Microsecond)
+		e.PrevHash = prev.ChainHash
+		e.ChainHash = event.ChainHash(s.chainKey, e.PrevHash, e)
+		chained[i] = e
+		last[e.AggregateID] = e
+	}
+
+	sealed, err := s.cipher.SealEvents(chained)
 	if err != nil {
-		return fmt.Errorf("preparing statement: %w", err)
+		return err
+	}
+	for batch := range slices.Chunk(sealed, s.batchSize) {
+		query, args := store.InsertEventsQuery(batch)
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err == nil {
+			err = store.ScanInsertedIDs(rows, batch)
+		}
+		if err != nil {
+			first, final := batch[0], batch[len(batch)-1]
+			return fmt.Errorf("inserting events (aggregate=%s, version=%d to aggregate=%s, version=%d): %w",
+				first.AggregateID, first.Version, final.AggregateID, final.Version, err)
+		}
+	}
+	for i := range chained {
+		chained[i].ID = sealed[i].ID
+	}
+
+	if err := store.RecordChainHeads(ctx, tx, chained); err != nil {
+		return err
 	}
-	defer stmt.Close()
+	if err := store.NotifyAppended(ctx, tx, chained); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	copy(events, chained)
+	return nil
+}
 
+// lastEvents returns the latest stored event of each aggregate in events,
+// with one query rather than one per aggregate.
+func lastEvents(ctx context.Context, tx *sqlx.Tx, events []event.Event) (map[string]event.Event, error) {
+	ids := make([]string, 0, len(events))
+	last := make(map[string]event.Event)
 	for _, e := range events {
-		if _, err := stmt.ExecContext(ctx, e.AggregateID, e.Type, e.Data, e.Version); err != nil {
-			return fmt.Errorf("inserting event (aggregate=%s, version=%d): %w", e.AggregateID, e.Version, err)
+		if _, ok := last[e.AggregateID]; !ok {
+			last[e.AggregateID] = event.Event{}
+			ids = append(ids, e.AggregateID)
 		}
 	}
 
-	return tx.Commit()
+	rows, err := tx.QueryContext(ctx,
+		`SELECT DISTINCT ON (aggregate_id) aggregate_id, version, chain_hash
+		 FROM events WHERE aggregate_id = ANY($1) ORDER BY aggregate_id, version DESC`,
+		pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("loading previous events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e event.Event
+		if err := rows.Scan(&e.AggregateID, &e.Version, &e.ChainHash); err != nil {
+			return nil, fmt.Errorf("scanning previous event: %w", err)
+		}
+		last[e.AggregateID] = e
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("loading previous events: %w", err)
+	}
+	return last, nil
 }
 
 func (s *EventStore) Load(ctx context.Context, aggregateID string) ([]event.Event, error) {
 	var events []event.Event
 	err := s.db.SelectContext(ctx, &events,
-		`SELECT id, aggregate_id, type, data, version, created_at
+		`SELECT id, aggregate_id, type, data, version, actor, created_at, prev_hash, chain_hash
 		 FROM events WHERE aggregate_id = $1 ORDER BY version ASC`, aggregateID)
 	if err != nil {
 		return nil, fmt.Errorf("loading events: %w", err)
 	}
+	if err := s.cipher.OpenEvents(events); err != nil {
+		return nil, err
+	}
 	return events, nil
 }
 
 func (s *EventStore) LoadByType(ctx context.Context, eventType event.Type) ([]event.Event, error) {
 	var events []event.Event
 	err := s.db.SelectContext(ctx, &events,
-		`SELECT id, aggregate_id, type, data, version, created_at
+		`SELECT id, aggregate_id, type, data, version, actor, created_at, prev_hash, chain_hash
 		 FROM events WHERE type = $1 ORDER BY created_at ASC`, eventType)
 	if err != nil {
 		return nil, fmt.Errorf("loading events by type: %w", err)
 	}
+	if err := s.cipher.OpenEvents(events); err != nil {
+		return nil, err
+	}
 	return events, nil
 }
+
+func (s *EventStore) Query(ctx context.Context, q event.Query) ([]event.Event, error) {
+	query, args := buildEventQuery(q, s.cipher)
+	var events []event.Event
+	if err := s.db.SelectContext(ctx, &events, query, args...); err != nil {
+		return nil, fmt.Errorf("querying events: %w", err)
+	}
+	if err := s.cipher.OpenEvents(events); err != nil {
+		return nil, err
+	}
+	return pagePlayerQuery(q, s.cipher, events), nil
+}
+
+// pagePlayerQuery applies q to the events, newest first, that
+// buildEventQuery selected for a query by player over payloads sealed with
+// c. Other queries were filtered and paged in SQL, and are returned as
+// they are.
+func pagePlayerQuery(q event.Query, c *store.Cipher, events []event.Event) []event.Event {
+	if q.Player == "" || c == nil {
+		return events
+	}
+	slices.Reverse(events)
+	return q.Filter(events)
+}
+
+// buildEventQuery translates an event.Query into a SELECT statement and its
+// positional arguments. The actor is matched sealed with c or in the clear.
+// Payloads sealed with c cannot be searched for a player, so a query by
+// player selects them all and leaves paging to pagePlayerQuery.
+func buildEventQuery(q event.Query, c *store.Cipher) (string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(q.Types) > 0 {
+		types := make([]string, len(q.Types))
+		for i, t := range q.Types {
+			types[i] = string(t)
+		}
+		conds = append(conds, "type = ANY("+arg(pq.Array(types))+")")
+	}
+	if q.AggregateID != "" {
+		conds = append(conds, "aggregate_id = "+arg(q.AggregateID))
+	}
+	if q.Player != "" {
+		p := arg(q.Player)
+		cond := "aggregate_id = " + p + " OR data->>'player_id' = " + p + " OR data->>'winner_id' = " + p + " OR data->>'buyer_id' = " + p
+		if c != nil {
+			cond += " OR jsonb_typeof(data) = 'string'"
+		}
+		conds = append(conds, "("+cond+")")
+	}
+	if q.Actor != "" {
+		conds = append(conds, "actor = ANY("+arg(pq.Array(c.LookupIDs(q.Actor)))+")")
+	}
+	if !q.Since.IsZero() {
+		conds = append(conds, "created_at >= "+arg(q.Since))
+	}
+	if !q.Until.IsZero() {
+		conds = append(conds, "created_at < "+arg(q.Until))
+	}
+
+	query := `SELECT id, aggregate_id, type, data, version, actor, created_at, prev_hash, chain_hash FROM events`
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY created_at DESC"
+	if q.Player != "" && c != nil {
+		return query, args
+	}
+	if q.Limit > 0 {
+		query += " LIMIT " + arg(q.Limit)
+	}
+	if q.Offset > 0 {
+		query += " OFFSET " + arg(q.Offset)
+	}
+	return query, args
+}
+
+// Heads returns the recorded head of every aggregate's hash chain.
+func (s *EventStore) Heads(ctx context.Context) (map[string]event.ChainHead, error) {
+	var heads []event.ChainHead
+	if err := s.db.SelectContext(ctx, &heads, `SELECT aggregate_id, version, chain_hash FROM chain_heads`); err != nil {
+		return nil, fmt.Errorf("loading chain heads: %w", err)
+	}
+	byAggregate := make(map[string]event.ChainHead, len(heads))
+	for _, h := range heads {
+		byAggregate[h.AggregateID] = h
+	}
+	return byAggregate, nil
+}
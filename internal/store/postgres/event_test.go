@@ -3,15 +3,19 @@ package postgres_test
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/store/postgres"
 )
 
 func TestEventStore_AppendAndLoad(t *testing.T) {
 	db := newTestDB(t)
-	es := postgres.NewEventStore(db)
+	es := postgres.NewEventStore(db, nil, 0, nil, nil)
 	ctx := context.Background()
 
 	aggID := "auction-001"
@@ -43,7 +47,7 @@ func TestEventStore_AppendAndLoad(t *testing.T) {
 
 func TestEventStore_LoadByType(t *testing.T) {
 	db := newTestDB(t)
-	es := postgres.NewEventStore(db)
+	es := postgres.NewEventStore(db, nil, 0, nil, nil)
 	ctx := context.Background()
 
 	events := []event.Event{
@@ -75,7 +79,7 @@ func TestEventStore_LoadByType(t *testing.T) {
 
 func TestEventStore_UniqueAggregateVersion(t *testing.T) {
 	db := newTestDB(t)
-	es := postgres.NewEventStore(db)
+	es := postgres.NewEventStore(db, nil, 0, nil, nil)
 	ctx := context.Background()
 
 	e := event.Event{
@@ -98,7 +102,7 @@ func TestEventStore_UniqueAggregateVersion(t *testing.T) {
 
 func TestEventStore_LoadEmpty(t *testing.T) {
 	db := newTestDB(t)
-	es := postgres.NewEventStore(db)
+	es := postgres.NewEventStore(db, nil, 0, nil, nil)
 	ctx := context.Background()
 
 	loaded, err := es.Load(ctx, "nonexistent")
@@ -109,3 +113,345 @@ func TestEventStore_LoadEmpty(t *testing.T) {
 		t.Errorf("expected empty slice, got %d events", len(loaded))
 	}
 }
+
+func TestEventStore_Query(t *testing.T) {
+	db := newTestDB(t)
+	es := postgres.NewEventStore(db, nil, 0, nil, nil)
+	ctx := event.WithActor(context.Background(), "officer-1")
+
+	events := []event.Event{
+		{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{}`), Version: 1},
+		{AggregateID: "p1", Type: event.DKPDeducted, Data: json.RawMessage(`{}`), Version: 2},
+		{AggregateID: "p2", Type: event.DKPAwarded, Data: json.RawMessage(`{}`), Version: 1, Actor: "officer-2"},
+		{AggregateID: "a1", Type: event.AuctionBidPlaced, Data: json.RawMessage(`{"player_id":"p2","amount":5}`), Version: 1},
+	}
+	if err := es.Append(ctx, events...); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		q    event.Query
+		want int
+	}{
+		{name: "no filter", q: event.Query{}, want: 4},
+		{name: "by type", q: event.Query{Types: []event.Type{event.DKPAwarded}}, want: 2},
+		{name: "by aggregate", q: event.Query{AggregateID: "p1"}, want: 2},
+		{name: "by player", q: event.Query{Player: "p2"}, want: 2},
+		{name: "by actor from context", q: event.Query{Actor: "officer-1"}, want: 3},
+		{name: "by explicit actor", q: event.Query{Actor: "officer-2"}, want: 1},
+		{name: "with limit", q: event.Query{Limit: 1}, want: 1},
+		{name: "until excludes everything", q: event.Query{Until: time.Unix(0, 0)}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := es.Query(context.Background(), tt.q)
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("Query returned %d events, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestEventStore_HashChain(t *testing.T) {
+	db := newTestDB(t)
+	es := postgres.NewEventStore(db, nil, 0, nil, nil)
+	ctx := context.Background()
+
+	// Version 0 asks the store to assign the next version.
+	for _, amount := range []int{50, 20, 30} {
+		e := event.Event{
+			AggregateID: "p1",
+			Type:        event.DKPAwarded,
+			Data:        json.RawMessage(fmt.Sprintf(`{"player_id":"p1","amount":%d}`, amount)),
+		}
+		if err := es.Append(ctx, e); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	loaded, err := es.Load(ctx, "p1")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded) != 3 || loaded[2].Version != 3 {
+		t.Fatalf("loaded %d events ending at v%d, want 3 ending at v3", len(loaded), loaded[len(loaded)-1].Version)
+	}
+	if report := event.VerifyChain(nil, loaded, nil); report.Verified != 3 || len(report.Problems) != 0 {
+		t.Fatalf("intact chain report = %+v", report)
+	}
+
+	if _, err := db.ExecContext(ctx,
+		`UPDATE events SET data = '{"player_id":"p1","amount":500}' WHERE aggregate_id = 'p1' AND version = 2`); err != nil {
+		t.Fatalf("tampering: %v", err)
+	}
+	loaded, err = es.Load(ctx, "p1")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if report := event.VerifyChain(nil, loaded, nil); len(report.Problems) != 1 {
+		t.Errorf("tampered chain problems = %v, want 1", report.Problems)
+	}
+}
+
+func TestEventStore_KeyedChainHeads(t *testing.T) {
+	db := newTestDB(t)
+	key := []byte("0123456789abcdef0123456789abcdef")
+	es := postgres.NewEventStore(db, nil, 0, nil, key)
+	ctx := context.Background()
+
+	for range 3 {
+		if err := es.Append(ctx, event.Event{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{"player_id":"p1","amount":10}`)}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	verify := func() event.ChainReport {
+		t.Helper()
+		heads, err := es.Heads(ctx)
+		if err != nil {
+			t.Fatalf("Heads: %v", err)
+		}
+		return event.VerifyChain(key, mustLoad(t, es, "p1"), heads)
+	}
+	if report := verify(); report.Verified != 3 || len(report.Problems) != 0 {
+		t.Fatalf("intact chain report = %+v", report)
+	}
+	if report := event.VerifyChain(nil, mustLoad(t, es, "p1"), nil); len(report.Problems) == 0 {
+		t.Error("keyed chain verified without the key")
+	}
+
+	if _, err := db.ExecContext(ctx, `DELETE FROM events WHERE aggregate_id = 'p1' AND version = 3`); err != nil {
+		t.Fatalf("truncating: %v", err)
+	}
+	if report := verify(); len(report.Problems) != 1 {
+		t.Errorf("truncated chain problems = %v, want 1", report.Problems)
+	}
+
+	if _, err := db.ExecContext(ctx, `UPDATE events SET prev_hash = '', chain_hash = '' WHERE aggregate_id = 'p1'`); err != nil {
+		t.Fatalf("unchaining: %v", err)
+	}
+	if report := verify(); len(report.Problems) != 1 {
+		t.Errorf("unchained aggregate problems = %v, want 1", report.Problems)
+	}
+}
+
+func mustLoad(t *testing.T, es *postgres.EventStore, aggregateID string) []event.Event {
+	t.Helper()
+	events, err := es.Load(context.Background(), aggregateID)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return events
+}
+
+func TestEventStore_AppendBatches(t *testing.T) {
+	db := newTestDB(t)
+	es := postgres.NewEventStore(db, nil, 2, nil, nil)
+	ctx := context.Background()
+
+	if err := es.Append(ctx, event.Event{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{}`)}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	// Five events across two aggregates take three statements, and the
+	// chain continues from the event stored before.
+	var events []event.Event
+	for i := range 5 {
+		events = append(events, event.Event{AggregateID: fmt.Sprintf("p%d", i%2+1), Type: event.DKPAwarded, Data: json.RawMessage(`{}`)})
+	}
+	if err := es.Append(ctx, events...); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	for id, want := range map[string]int{"p1": 4, "p2": 2} {
+		loaded, err := es.Load(ctx, id)
+		if err != nil {
+			t.Fatalf("Load(%s): %v", id, err)
+		}
+		if len(loaded) != want || loaded[len(loaded)-1].Version != want {
+			t.Errorf("%s: loaded %d events ending at v%d, want %d", id, len(loaded), loaded[len(loaded)-1].Version, want)
+		}
+		if report := event.VerifyChain(nil, loaded, nil); report.Verified != want || len(report.Problems) != 0 {
+			t.Errorf("%s: chain report = %+v", id, report)
+		}
+	}
+}
+
+func TestEventStore_AppendBatchRollsBack(t *testing.T) {
+	db := newTestDB(t)
+	es := postgres.NewEventStore(db, nil, 2, nil, nil)
+	ctx := context.Background()
+
+	// The third event collides with the first, so the second statement
+	// fails and the first statement's events are rolled back with it.
+	events := []event.Event{
+		{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{}`), Version: 1},
+		{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{}`), Version: 2},
+		{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{}`), Version: 1},
+	}
+	if err := es.Append(ctx, events...); err == nil {
+		t.Fatal("expected error for duplicate aggregate_id + version")
+	}
+
+	loaded, err := es.Load(ctx, "p1")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded) != 0 {
+		t.Errorf("loaded %d events after a failed append, want 0", len(loaded))
+	}
+}
+
+// BenchmarkEventStore_Append appends 1000 events, as an import does, one
+// per statement and in batches.
+func BenchmarkEventStore_Append(b *testing.B) {
+	db := newTestDB(b)
+	ctx := context.Background()
+
+	for _, size := range []int{1, 100, 500, 1000} {
+		b.Run(fmt.Sprintf("batch=%d", size), func(b *testing.B) {
+			es := postgres.NewEventStore(db, nil, size, nil, nil)
+			run := 0
+			for b.Loop() {
+				run++
+				events := make([]event.Event, 1000)
+				for i := range events {
+					events[i] = event.Event{
+						AggregateID: fmt.Sprintf("batch-%d-run-%d-player-%d", size, run, i%50),
+						Type:        event.DKPAwarded,
+						Data:        json.RawMessage(`{"amount":10}`),
+					}
+				}
+				if err := es.Append(ctx, events...); err != nil {
+					b.Fatalf("Append: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestEventStore_NotifiesAppends(t *testing.T) {
+	db, cfg := newTestDatabase(t)
+	es := postgres.NewEventStore(db, nil, 0, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := event.NewBus()
+	got := make(chan event.Type, 10)
+	bus.Subscribe(func(_ context.Context, e event.Event) { got <- e.Type })
+	listening := make(chan error, 1)
+	go func() { listening <- store.Listen(ctx, cfg, bus) }()
+
+	// Appends before the listener is in place are not announced, so
+	// append until one is.
+	deadline := time.After(10 * time.Second)
+	for {
+		e := event.Event{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{}`)}
+		if err := es.Append(ctx, e); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		select {
+		case typ := <-got:
+			if typ != event.DKPAwarded {
+				t.Errorf("announced type = %q, want %q", typ, event.DKPAwarded)
+			}
+			cancel()
+			if err := <-listening; err != nil {
+				t.Errorf("Listen: %v", err)
+			}
+			return
+		case <-time.After(100 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("no append announced")
+		}
+	}
+}
+
+func TestEventStore_Encrypted(t *testing.T) {
+	db := newTestDB(t)
+	cipher, err := store.NewCipher("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+	es := postgres.NewEventStore(db, nil, 0, cipher, nil)
+	ctx := event.WithActor(context.Background(), "123456789")
+
+	if err := es.Append(ctx, event.Event{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{"discord_id":"123456789","amount":10}`)}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	// The database holds neither the payload nor the actor in the clear.
+	var data, actor string
+	if err := db.QueryRowContext(ctx, `SELECT data::text, actor FROM events WHERE aggregate_id = 'p1'`).Scan(&data, &actor); err != nil {
+		t.Fatalf("reading stored event: %v", err)
+	}
+	if strings.Contains(data, "123456789") || actor == "123456789" {
+		t.Errorf("stored event = %s by %q, want it sealed", data, actor)
+	}
+
+	loaded, err := es.Query(ctx, event.Query{Actor: "123456789"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(loaded) != 1 || string(loaded[0].Data) != `{"discord_id":"123456789","amount":10}` || loaded[0].Actor != "123456789" {
+		t.Fatalf("Query by actor = %+v, want the event in the clear", loaded)
+	}
+	if report := event.VerifyChain(nil, loaded, nil); len(report.Problems) > 0 {
+		t.Errorf("VerifyChain problems = %v", report.Problems)
+	}
+
+	// A sealed payload cannot be searched in SQL but is still found by
+	// player once opened.
+	if err := es.Append(ctx,
+		event.Event{AggregateID: "a1", Type: event.AuctionBidPlaced, Data: json.RawMessage(`{"player_id":"p1","amount":5}`)},
+		event.Event{AggregateID: "a1", Type: event.AuctionBidPlaced, Data: json.RawMessage(`{"player_id":"p2","amount":6}`)},
+	); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	loaded, err = es.Query(ctx, event.Query{Player: "p1", Limit: 1})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].Type != event.AuctionBidPlaced {
+		t.Errorf("Query by player = %+v, want the bid of p1", loaded)
+	}
+}
+
+func TestEventStore_AppendFillsInEvents(t *testing.T) {
+	db := newTestDB(t)
+	bus := event.NewBus()
+	var published []event.Event
+	bus.Subscribe(func(_ context.Context, e event.Event) { published = append(published, e) })
+	es := event.NewPublishingStore(postgres.NewEventStore(db, nil, 0, nil, nil), bus)
+	ctx := event.WithActor(context.Background(), "officer-1")
+
+	events := []event.Event{
+		{AggregateID: "p1", Type: event.DKPAwarded, Data: json.RawMessage(`{"player_id":"p1","amount":10}`)},
+		{AggregateID: "p1", Type: event.DKPDeducted, Data: json.RawMessage(`{"player_id":"p1","amount":5}`)},
+	}
+	if err := es.Append(ctx, events...); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	loaded, err := es.Load(ctx, "p1")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(published) != 2 || len(loaded) != 2 {
+		t.Fatalf("published %d and loaded %d events, want 2", len(published), len(loaded))
+	}
+	for i, e := range published {
+		want := loaded[i]
+		if e.ID == "" || e.ID != want.ID || e.Version != want.Version || !e.CreatedAt.Equal(want.CreatedAt) || e.Actor != want.Actor || e.ChainHash != want.ChainHash {
+			t.Errorf("published event %d = %+v, want it as stored: %+v", i, e, want)
+		}
+		if events[i].ID != want.ID {
+			t.Errorf("appended event %d has ID %q, want the stored %q", i, events[i].ID, want.ID)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/postgres"
+)
+
+func TestFence_RejectsStaleLeader(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	oldFence, newFence := store.NewFence(db), store.NewFence(db)
+	oldEvents, newEvents := postgres.NewEventStore(db, oldFence, 0, nil, nil), postgres.NewEventStore(db, newFence, 0, nil, nil)
+	oldPlayers := postgres.NewPlayerRepo(db, clock.Real{}, oldFence, nil)
+
+	p := &store.Player{DiscordID: "d1", CharacterName: "Gandalf"}
+	if err := oldPlayers.Create(ctx, p); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if token, err := oldFence.Acquire(ctx, "dkpbot-leader"); err != nil || token != 1 {
+		t.Fatalf("Acquire() = %d, %v, want 1", token, err)
+	}
+	if err := oldEvents.Append(ctx, event.Event{AggregateID: p.ID, Type: event.DKPAwarded}); err != nil {
+		t.Fatalf("Append as leader: %v", err)
+	}
+
+	// Another replica takes over while the old leader is paused.
+	if token, err := newFence.Acquire(ctx, "dkpbot-leader"); err != nil || token != 2 {
+		t.Fatalf("Acquire() = %d, %v, want 2", token, err)
+	}
+	if err := oldEvents.Append(ctx, event.Event{AggregateID: p.ID, Type: event.DKPAwarded}); !errors.Is(err, store.ErrFenced) {
+		t.Errorf("Append from stale leader error = %v, want ErrFenced", err)
+	}
+	if err := oldPlayers.UpdateDKP(ctx, p.ID, 10); !errors.Is(err, store.ErrFenced) {
+		t.Errorf("UpdateDKP from stale leader error = %v, want ErrFenced", err)
+	}
+	if err := newEvents.Append(ctx, event.Event{AggregateID: p.ID, Type: event.DKPAwarded}); err != nil {
+		t.Errorf("Append as new leader: %v", err)
+	}
+
+	got, err := oldPlayers.GetByDiscordID(ctx, "d1")
+	if err != nil {
+		t.Fatalf("GetByDiscordID: %v", err)
+	}
+	if got.DKP != 0 {
+		t.Errorf("DKP = %d, want 0 after the rejected update", got.DKP)
+	}
+}
+
+func TestFence_RequireRejectsReplicaWithoutToken(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	fence := store.NewFence(db)
+	fence.Require()
+	events := postgres.NewEventStore(db, fence, 0, nil, nil)
+
+	if err := events.Append(ctx, event.Event{AggregateID: "p1", Type: event.DKPAwarded}); !errors.Is(err, store.ErrFenced) {
+		t.Errorf("Append before leading error = %v, want ErrFenced", err)
+	}
+	if _, err := fence.Acquire(ctx, "dkpbot-leader"); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if err := events.Append(ctx, event.Event{AggregateID: "p1", Type: event.DKPAwarded}); err != nil {
+		t.Errorf("Append as leader: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// GuildSettingsRepo implements store.GuildSettingsRepository with sqlx.
+type GuildSettingsRepo struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewGuildSettingsRepo returns a new GuildSettingsRepo.
+func NewGuildSettingsRepo(db *sqlx.DB, clk clock.Clock) *GuildSettingsRepo {
+	return &GuildSettingsRepo{db: db, clock: clk}
+}
+
+func (r *GuildSettingsRepo) List(ctx context.Context, guildID string) ([]store.GuildSetting, error) {
+	var settings []store.GuildSetting
+	err := r.db.SelectContext(ctx, &settings,
+		`SELECT guild_id, key, value, updated_by, updated_at FROM guild_settings WHERE guild_id = $1 ORDER BY key`,
+		guildID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing guild settings: %w", err)
+	}
+	return settings, nil
+}
+
+func (r *GuildSettingsRepo) Set(ctx context.Context, s *store.GuildSetting) error {
+	s.UpdatedAt = r.clock.Now().UTC()
+	_, err := r.db.NamedExecContext(ctx,
+		`INSERT INTO guild_settings (guild_id, key, value, updated_by, updated_at)
+		 VALUES (:guild_id, :key, :value, :updated_by, :updated_at)
+		 ON CONFLICT (guild_id, key) DO UPDATE
+		 SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`,
+		s,
+	)
+	if err != nil {
+		return fmt.Errorf("setting guild setting: %w", err)
+	}
+	return nil
+}
+
+func (r *GuildSettingsRepo) Delete(ctx context.Context, guildID, key string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM guild_settings WHERE guild_id = $1 AND key = $2`, guildID, key); err != nil {
+		return fmt.Errorf("deleting guild setting: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/postgres"
+)
+
+func TestGuildSettingsRepo_SetListDelete(t *testing.T) {
+	db := newTestDB(t)
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	repo := postgres.NewGuildSettingsRepo(db, clk)
+	ctx := context.Background()
+
+	for _, s := range []store.GuildSetting{
+		{GuildID: "g1", Key: "min_increment", Value: "5", UpdatedBy: "officer"},
+		{GuildID: "g1", Key: "auction_duration", Value: "10m", UpdatedBy: "officer"},
+		{GuildID: "g2", Key: "min_increment", Value: "2", UpdatedBy: "officer"},
+		{GuildID: "g1", Key: "min_increment", Value: "10", UpdatedBy: "leader"},
+	} {
+		if err := repo.Set(ctx, &s); err != nil {
+			t.Fatalf("Set(%s): %v", s.Key, err)
+		}
+	}
+
+	got, err := repo.List(ctx, "g1")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(got) != 2 || got[0].Key != "auction_duration" || got[1].Key != "min_increment" {
+		t.Fatalf("List = %+v, want auction_duration and min_increment", got)
+	}
+	if got[1].Value != "10" || got[1].UpdatedBy != "leader" || !got[1].UpdatedAt.Equal(clk.T) {
+		t.Errorf("min_increment = %+v, want the replaced value", got[1])
+	}
+
+	if err := repo.Delete(ctx, "g1", "min_increment"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	got, err = repo.List(ctx, "g1")
+	if err != nil {
+		t.Fatalf("List after Delete: %v", err)
+	}
+	if len(got) != 1 {
+		t.Errorf("List after Delete = %+v, want one setting", got)
+	}
+}
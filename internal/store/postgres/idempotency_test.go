@@ -0,0 +1,110 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/postgres"
+)
+
+func TestIdempotencyRepo_ReserveCompleteRelease(t *testing.T) {
+	db := newTestDB(t)
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	repo := postgres.NewIdempotencyRepo(db, clk)
+	ctx := context.Background()
+
+	_, reserved, err := repo.Reserve(ctx, "dkp.award:1", 0)
+	if err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if !reserved {
+		t.Fatal("first Reserve should succeed")
+	}
+
+	rec, reserved, err := repo.Reserve(ctx, "dkp.award:1", 0)
+	if err != nil {
+		t.Fatalf("second Reserve: %v", err)
+	}
+	if reserved {
+		t.Fatal("second Reserve should not succeed")
+	}
+	if rec.Result != nil {
+		t.Errorf("in-flight Result = %s, want nil", rec.Result)
+	}
+
+	if err := repo.Complete(ctx, "dkp.award:1", []byte(`{"ok":true}`)); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	rec, _, err = repo.Reserve(ctx, "dkp.award:1", 0)
+	if err != nil {
+		t.Fatalf("Reserve after Complete: %v", err)
+	}
+	if string(rec.Result) != `{"ok": true}` {
+		t.Errorf("Result = %s, want %s", rec.Result, `{"ok": true}`)
+	}
+
+	if err := repo.Release(ctx, "dkp.award:1"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if _, reserved, _ = repo.Reserve(ctx, "dkp.award:1", 0); !reserved {
+		t.Error("Reserve after Release should succeed")
+	}
+}
+
+func TestIdempotencyRepo_ReclaimsStaleReservation(t *testing.T) {
+	db := newTestDB(t)
+	at := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	if _, reserved, err := postgres.NewIdempotencyRepo(db, clock.Mock{T: at}).Reserve(ctx, "dkp.award:1", 0); err != nil || !reserved {
+		t.Fatalf("Reserve = %v, %v, want reserved", reserved, err)
+	}
+
+	later := postgres.NewIdempotencyRepo(db, clock.Mock{T: at.Add(10 * time.Minute)})
+	if _, reserved, _ := later.Reserve(ctx, "dkp.award:1", 20*time.Minute); reserved {
+		t.Error("Reserve within stale_after took over the reservation")
+	}
+
+	stale := postgres.NewIdempotencyRepo(db, clock.Mock{T: at.Add(30 * time.Minute)})
+	if _, reserved, _ := stale.Reserve(ctx, "dkp.award:1", 0); reserved {
+		t.Error("Reserve without stale_after took over the reservation")
+	}
+	if _, reserved, err := stale.Reserve(ctx, "dkp.award:1", 20*time.Minute); err != nil || !reserved {
+		t.Fatalf("Reserve after stale_after = %v, %v, want reserved", reserved, err)
+	}
+
+	// A completed key is never taken over.
+	if err := stale.Complete(ctx, "dkp.award:1", []byte(`1`)); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	much := postgres.NewIdempotencyRepo(db, clock.Mock{T: at.Add(24 * time.Hour)})
+	if rec, reserved, _ := much.Reserve(ctx, "dkp.award:1", 20*time.Minute); reserved || string(rec.Result) != "1" {
+		t.Errorf("Reserve on a completed key = %v, %+v, want its result", reserved, rec)
+	}
+}
+
+func TestIdempotencyRepo_Prune(t *testing.T) {
+	db := newTestDB(t)
+	at := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	_, _, _ = postgres.NewIdempotencyRepo(db, clock.Mock{T: at}).Reserve(ctx, "old", 0)
+	repo := postgres.NewIdempotencyRepo(db, clock.Mock{T: at.Add(48 * time.Hour)})
+	_, _, _ = repo.Reserve(ctx, "new", 0)
+
+	n, err := repo.Prune(ctx, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Prune deleted %d keys, want 1", n)
+	}
+	if _, reserved, _ := repo.Reserve(ctx, "old", 0); !reserved {
+		t.Error("pruned key is still reserved")
+	}
+	if _, reserved, _ := repo.Reserve(ctx, "new", 0); reserved {
+		t.Error("Prune deleted a key within the TTL")
+	}
+}
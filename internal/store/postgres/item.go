@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// ItemRepo implements store.ItemRepository with sqlx.
+type ItemRepo struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+// NewItemRepo returns a new ItemRepo.
+func NewItemRepo(db *sqlx.DB, clk clock.Clock) *ItemRepo {
+	return &ItemRepo{db: db, clock: clk}
+}
+
+func (r *ItemRepo) Upsert(ctx context.Context, items []store.Item) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := r.clock.Now().UTC()
+	for i := range items {
+		items[i].UpdatedAt = now
+		if _, err := tx.NamedExecContext(ctx,
+			`INSERT INTO items (id, name, quality, icon_url, slot, stats, updated_at)
+			 VALUES (:id, :name, :quality, :icon_url, :slot, :stats, :updated_at)
+			 ON CONFLICT (id) DO UPDATE
+			 SET name = EXCLUDED.name, quality = EXCLUDED.quality, icon_url = EXCLUDED.icon_url, slot = EXCLUDED.slot, stats = EXCLUDED.stats, updated_at = EXCLUDED.updated_at`,
+			items[i],
+		); err != nil {
+			return fmt.Errorf("upserting item %s: %w", items[i].ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing items: %w", err)
+	}
+	return nil
+}
+
+func (r *ItemRepo) Search(ctx context.Context, query string, limit int) ([]store.Item, error) {
+	var items []store.Item
+	err := r.db.SelectContext(ctx, &items,
+		`SELECT id, name, quality, icon_url, slot, stats, updated_at FROM items
+		 WHERE strpos(lower(name), lower($1)) > 0
+		 ORDER BY strpos(lower(name), lower($1)) = 1 DESC, name
+		 LIMIT $2`,
+		query, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("searching items: %w", err)
+	}
+	return items, nil
+}
+
+func (r *ItemRepo) GetByName(ctx context.Context, name string) (*store.Item, error) {
+	var item store.Item
+	err := r.db.GetContext(ctx, &item,
+		`SELECT id, name, quality, icon_url, slot, stats, updated_at FROM items WHERE lower(name) = lower($1) ORDER BY id LIMIT 1`, name)
+	if err != nil {
+		return nil, store.Classify(err, "getting item by name", store.ErrItemNotFound, nil)
+	}
+	return &item, nil
+}
@@ -0,0 +1,60 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/postgres"
+)
+
+func TestItemRepo_UpsertSearchGet(t *testing.T) {
+	db := newTestDB(t)
+	repo := postgres.NewItemRepo(db, clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)})
+	ctx := context.Background()
+
+	if err := repo.Upsert(ctx, []store.Item{
+		{ID: "19019", Name: "Thunderfury, Blessed Blade of the Windseeker", Quality: "legendary"},
+		{ID: "18814", Name: "Choker of the Fire Lord", Quality: "epic"},
+		{ID: "17076", Name: "Bonereaver's Edge", Quality: "epic"},
+	}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	// A later import replaces items by ID.
+	if err := repo.Upsert(ctx, []store.Item{
+		{ID: "18814", Name: "Choker of the Fire Lord", Quality: "epic", IconURL: "https://example.com/choker.jpg", Slot: "Neck", Stats: "+10 Stamina\n+7 Intellect"},
+	}); err != nil {
+		t.Fatalf("Upsert again: %v", err)
+	}
+
+	got, err := repo.Search(ctx, "th", 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	var ids []string
+	for _, it := range got {
+		ids = append(ids, it.ID)
+	}
+	// The prefix match comes first, although it sorts last by name.
+	if want := []string{"19019", "18814"}; !slices.Equal(ids, want) {
+		t.Errorf("Search(th) = %q, want %q", ids, want)
+	}
+
+	item, err := repo.GetByName(ctx, "choker of the fire lord")
+	if err != nil {
+		t.Fatalf("GetByName: %v", err)
+	}
+	if item.IconURL != "https://example.com/choker.jpg" {
+		t.Errorf("IconURL = %q, want the replaced URL", item.IconURL)
+	}
+	if item.Slot != "Neck" || item.Stats != "+10 Stamina\n+7 Intellect" {
+		t.Errorf("tooltip = %q, %q, want the imported slot and stats", item.Slot, item.Stats)
+	}
+	if _, err := repo.GetByName(ctx, "Ashbringer"); !errors.Is(err, store.ErrItemNotFound) {
+		t.Errorf("GetByName(unknown) error = %v, want ErrItemNotFound", err)
+	}
+}
@@ -0,0 +1,6 @@
+-- 002_event_actor.sql: Record the Discord user each event was issued by.
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS actor TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_events_actor ON events(actor);
+CREATE INDEX IF NOT EXISTS idx_events_created_at ON events(created_at);
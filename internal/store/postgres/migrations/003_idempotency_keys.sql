@@ -0,0 +1,7 @@
+-- 003_idempotency_keys.sql: Deduplicate redelivered Discord interactions.
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key        TEXT PRIMARY KEY,
+    result     JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
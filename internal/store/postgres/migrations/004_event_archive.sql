@@ -0,0 +1,21 @@
+-- 004_event_archive.sql: Cold storage for events of finished aggregates.
+
+CREATE TABLE IF NOT EXISTS events_archive (
+    id            UUID PRIMARY KEY,
+    aggregate_id  TEXT NOT NULL,
+    type          TEXT NOT NULL,
+    data          JSONB NOT NULL DEFAULT '{}',
+    version       INTEGER NOT NULL,
+    actor         TEXT NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL,
+    archived_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_archive_aggregate_id ON events_archive(aggregate_id);
+
+CREATE TABLE IF NOT EXISTS event_snapshots (
+    aggregate_id  TEXT PRIMARY KEY,
+    version       INTEGER NOT NULL,
+    state         JSONB NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,9 @@
+-- 005_event_hash_chain.sql: Per-aggregate hash chain over the event log.
+-- Events written before this migration keep empty hashes and are reported
+-- as unchained by `dkpbot verify-ledger`.
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS prev_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN IF NOT EXISTS chain_hash TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE events_archive ADD COLUMN IF NOT EXISTS prev_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE events_archive ADD COLUMN IF NOT EXISTS chain_hash TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+-- 006_fencing_tokens.sql: Fencing tokens for leader election. Each new
+-- leader increments the token of its lease, and writes are rejected while
+-- the writer holds an older token.
+
+CREATE TABLE IF NOT EXISTS fencing_tokens (
+    name  TEXT PRIMARY KEY,
+    token BIGINT NOT NULL
+);
@@ -0,0 +1,11 @@
+-- 007_guild_settings.sql: Per-guild settings that officers change at runtime.
+-- Keys without a row use the defaults from the config file.
+
+CREATE TABLE IF NOT EXISTS guild_settings (
+    guild_id   TEXT        NOT NULL,
+    key        TEXT        NOT NULL,
+    value      TEXT        NOT NULL,
+    updated_by TEXT        NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (guild_id, key)
+);
@@ -0,0 +1,12 @@
+-- 008_items.sql: Item catalog imported from game data dumps, used for
+-- /auction-start autocomplete and auction embeds.
+
+CREATE TABLE IF NOT EXISTS items (
+    id         TEXT PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    quality    TEXT        NOT NULL DEFAULT '',
+    icon_url   TEXT        NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_items_name ON items(lower(name));
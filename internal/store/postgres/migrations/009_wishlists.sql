@@ -0,0 +1,12 @@
+-- 009_wishlists.sql: Items players want, used to DM them when an auction
+-- for one starts and to report demand to officers. Item names are matched
+-- ignoring case.
+
+CREATE TABLE IF NOT EXISTS wishlists (
+    player_id  UUID        NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    item_name  TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wishlists_player_item ON wishlists(player_id, lower(item_name));
+CREATE INDEX IF NOT EXISTS idx_wishlists_item ON wishlists(lower(item_name));
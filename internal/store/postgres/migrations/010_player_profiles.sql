@@ -0,0 +1,6 @@
+-- 010_player_profiles.sql: The class, raid role, and spec players give with
+-- /register or /profile. Empty until a player sets them.
+
+ALTER TABLE players ADD COLUMN IF NOT EXISTS class TEXT NOT NULL DEFAULT '';
+ALTER TABLE players ADD COLUMN IF NOT EXISTS role  TEXT NOT NULL DEFAULT '';
+ALTER TABLE players ADD COLUMN IF NOT EXISTS spec  TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+-- 011_player_archive.sql: When a player was archived, as proposed for
+-- inactive players. Archived players keep their rows and history, but their
+-- DKP is frozen. NULL for active players.
+
+ALTER TABLE players ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
@@ -0,0 +1,11 @@
+-- 012_command_usage.sql: How often each member used each command or button
+-- per day, and how many of those uses failed, for /bot-stats.
+
+CREATE TABLE IF NOT EXISTS command_usage (
+    day      DATE    NOT NULL,
+    command  TEXT    NOT NULL,
+    user_id  TEXT    NOT NULL,
+    uses     INTEGER NOT NULL DEFAULT 0,
+    failures INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, command, user_id)
+);
@@ -0,0 +1,13 @@
+-- 013_player_balances.sql: Balances in the currencies configured besides
+-- DKP, such as EP, GP, or raid tokens. DKP stays in players.dkp. A player
+-- without a row holds none of that currency.
+
+CREATE TABLE IF NOT EXISTS player_balances (
+    player_id  UUID        NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    currency   TEXT        NOT NULL,
+    balance    INTEGER     NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (player_id, currency)
+);
+
+CREATE INDEX IF NOT EXISTS idx_player_balances_currency ON player_balances(currency, balance DESC);
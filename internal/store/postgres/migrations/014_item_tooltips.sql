@@ -0,0 +1,5 @@
+-- 014_item_tooltips.sql: Tooltip data of catalog items, shown in auction
+-- embeds. stats holds one stat per line.
+
+ALTER TABLE items ADD COLUMN IF NOT EXISTS slot  TEXT NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN IF NOT EXISTS stats TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,13 @@
+-- 015_player_notes.sql: Notes officers keep on players with /player-note,
+-- such as a loot ban, which stops the player bidding until loot_ban_until.
+
+CREATE TABLE IF NOT EXISTS player_notes (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    player_id      UUID        NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    author         TEXT        NOT NULL,
+    text           TEXT        NOT NULL,
+    loot_ban_until TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_player_notes_player ON player_notes(player_id, created_at DESC);
@@ -0,0 +1,5 @@
+-- 016_player_timezones.sql: The IANA timezone players give with /profile,
+-- which dates and times they type into commands are read in. Empty to use
+-- the timezone of the guild.
+
+ALTER TABLE players ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,13 @@
+-- 017_schema_migrations.sql: The migrations applied, so that a bot needing
+-- a newer schema than the database has reports itself not ready. The
+-- migrations before this one are idempotent and applied in order, so they
+-- are recorded with it; each later migration records its own version last.
+
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INTEGER PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO schema_migrations (version)
+SELECT generate_series(1, 17)
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,11 @@
+-- 018_auction_projection.sql: Key auctions by the aggregate IDs of their
+-- events, so that the table is kept as a projection of the event log and
+-- repaired from it at leader startup. Winners are not checked against
+-- players, as the log may name a player since merged away.
+
+ALTER TABLE auctions ALTER COLUMN id DROP DEFAULT;
+ALTER TABLE auctions ALTER COLUMN id TYPE TEXT;
+ALTER TABLE auctions ALTER COLUMN id SET DEFAULT gen_random_uuid()::text;
+ALTER TABLE auctions DROP CONSTRAINT IF EXISTS auctions_winner_id_fkey;
+
+INSERT INTO schema_migrations (version) VALUES (18);
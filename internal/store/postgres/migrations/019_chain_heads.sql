@@ -0,0 +1,23 @@
+-- 019_chain_heads.sql: The latest event of each aggregate's hash chain,
+-- kept by the event stores on every append, so that `dkpbot verify-ledger`
+-- reports events removed from the end of a chain or stripped of their
+-- hashes. Aggregates already chained get the head of their stored events.
+
+CREATE TABLE IF NOT EXISTS chain_heads (
+    aggregate_id TEXT PRIMARY KEY,
+    version      INTEGER NOT NULL,
+    chain_hash   TEXT NOT NULL
+);
+
+INSERT INTO chain_heads (aggregate_id, version, chain_hash)
+SELECT DISTINCT ON (aggregate_id) aggregate_id, version, chain_hash
+FROM (
+    SELECT aggregate_id, version, chain_hash FROM events
+    UNION ALL
+    SELECT aggregate_id, version, chain_hash FROM events_archive
+) stored
+WHERE chain_hash <> ''
+ORDER BY aggregate_id, version DESC
+ON CONFLICT (aggregate_id) DO NOTHING;
+
+INSERT INTO schema_migrations (version) VALUES (19);
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// PlayerNoteRepo implements store.PlayerNoteRepository with sqlx.
+type PlayerNoteRepo struct {
+	db     *sqlx.DB
+	clock  clock.Clock
+	cipher *store.Cipher
+}
+
+// NewPlayerNoteRepo returns a new PlayerNoteRepo. The Discord IDs of
+// authors are sealed with cipher, which may be nil.
+func NewPlayerNoteRepo(db *sqlx.DB, clk clock.Clock, cipher *store.Cipher) *PlayerNoteRepo {
+	return &PlayerNoteRepo{db: db, clock: clk, cipher: cipher}
+}
+
+func (r *PlayerNoteRepo) Add(ctx context.Context, n *store.PlayerNote) error {
+	n.CreatedAt = r.clock.Now().UTC()
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO player_notes (player_id, author, text, loot_ban_until, created_at)
+		 VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		n.PlayerID, r.cipher.SealID(n.Author), n.Text, n.LootBanUntil, n.CreatedAt,
+	).Scan(&n.ID)
+	if err != nil {
+		return fmt.Errorf("adding player note: %w", err)
+	}
+	return nil
+}
+
+func (r *PlayerNoteRepo) ListByPlayer(ctx context.Context, playerID string) ([]store.PlayerNote, error) {
+	var notes []store.PlayerNote
+	err := r.db.SelectContext(ctx, &notes,
+		`SELECT id, player_id, author, text, loot_ban_until, created_at FROM player_notes
+		 WHERE player_id = $1 ORDER BY created_at DESC, id`,
+		playerID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing player notes: %w", err)
+	}
+	if err := r.cipher.OpenNotes(notes); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
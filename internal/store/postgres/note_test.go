@@ -0,0 +1,88 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/postgres"
+)
+
+func TestPlayerNoteRepo(t *testing.T) {
+	db := newTestDB(t)
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	players := postgres.NewPlayerRepo(db, clk, nil, nil)
+	ctx := context.Background()
+
+	alice := &store.Player{DiscordID: "1", CharacterName: "Alice"}
+	if err := players.Create(ctx, alice); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	ban := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	first := &store.PlayerNote{PlayerID: alice.ID, Author: "officer-1", Text: "Late to raid"}
+	if err := postgres.NewPlayerNoteRepo(db, clk, nil).Add(ctx, first); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	later := postgres.NewPlayerNoteRepo(db, clock.Mock{T: clk.T.Add(time.Hour)}, nil)
+	second := &store.PlayerNote{PlayerID: alice.ID, Author: "officer-2", Text: "Loot ban until July", LootBanUntil: &ban}
+	if err := later.Add(ctx, second); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if first.ID == "" || !first.CreatedAt.Equal(clk.T) {
+		t.Errorf("added note = %+v, want its ID and creation time set", first)
+	}
+
+	notes, err := later.ListByPlayer(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("ListByPlayer: %v", err)
+	}
+	if len(notes) != 2 || notes[0].ID != second.ID || notes[1].ID != first.ID {
+		t.Fatalf("ListByPlayer = %+v, want the loot ban first", notes)
+	}
+	if notes[0].LootBanUntil == nil || !notes[0].LootBanUntil.Equal(ban) || notes[1].LootBanUntil != nil {
+		t.Errorf("loot bans = %v, %v, want %v and none", notes[0].LootBanUntil, notes[1].LootBanUntil, ban)
+	}
+}
+
+func TestPlayerNoteRepo_Encrypted(t *testing.T) {
+	db := newTestDB(t)
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	ctx := context.Background()
+
+	alice := &store.Player{DiscordID: "1", CharacterName: "Alice"}
+	if err := postgres.NewPlayerRepo(db, clk, nil, nil).Create(ctx, alice); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// A note written before encryption was enabled stays in the clear.
+	if err := postgres.NewPlayerNoteRepo(db, clk, nil).Add(ctx, &store.PlayerNote{PlayerID: alice.ID, Author: "officer-1", Text: "Before"}); err != nil {
+		t.Fatalf("Add in the clear: %v", err)
+	}
+	cipher, err := store.NewCipher("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+	repo := postgres.NewPlayerNoteRepo(db, clock.Mock{T: clk.T.Add(time.Hour)}, cipher)
+	if err := repo.Add(ctx, &store.PlayerNote{PlayerID: alice.ID, Author: "officer-2", Text: "After"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	var stored string
+	if err := db.QueryRowContext(ctx, `SELECT author FROM player_notes WHERE text = 'After'`).Scan(&stored); err != nil {
+		t.Fatalf("reading stored note: %v", err)
+	}
+	if stored == "officer-2" {
+		t.Error("stored author is in the clear, want it sealed")
+	}
+
+	notes, err := repo.ListByPlayer(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("ListByPlayer: %v", err)
+	}
+	if len(notes) != 2 || notes[0].Author != "officer-2" || notes[1].Author != "officer-1" {
+		t.Errorf("ListByPlayer = %+v, want the authors opened", notes)
+	}
+}
@@ -13,12 +13,22 @@ import (
 	"github.com/testcontainers/testcontainers-go"
 	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
 )
 
-// newTestDB starts a Postgres container, applies the migration, and returns
+// newTestDB starts a Postgres container, applies the migrations, and returns
 // a connected *sqlx.DB. The container is automatically terminated when the
 // test ends.
-func newTestDB(t *testing.T) *sqlx.DB {
+func newTestDB(t testing.TB) *sqlx.DB {
+	t.Helper()
+	db, _ := newTestDatabase(t)
+	return db
+}
+
+// newTestDatabase is like newTestDB, and also returns the config of the
+// database for code that opens its own connections.
+func newTestDatabase(t testing.TB) (*sqlx.DB, config.DatabaseConfig) {
 	t.Helper()
 	if testing.Short() {
 		t.Skip("skipping integration test in short mode")
@@ -26,13 +36,14 @@ func newTestDB(t *testing.T) *sqlx.DB {
 
 	ctx := context.Background()
 
-	// Locate migration file relative to this source file.
+	// Locate migration files relative to this source file. Glob returns
+	// them in lexical order, which matches their numeric prefixes.
 	_, thisFile, _, _ := runtime.Caller(0)
 	migrationDir := filepath.Join(filepath.Dir(thisFile), "migrations")
 
-	migrationSQL, err := os.ReadFile(filepath.Join(migrationDir, "001_initial.sql"))
+	migrationFiles, err := filepath.Glob(filepath.Join(migrationDir, "*.sql"))
 	if err != nil {
-		t.Fatalf("reading migration: %v", err)
+		t.Fatalf("listing migrations: %v", err)
 	}
 
 	ctr, err := tcpostgres.Run(ctx, "postgres:16.6-alpine",
@@ -55,6 +66,18 @@ func newTestDB(t *testing.T) *sqlx.DB {
 	if err != nil {
 		t.Fatalf("getting connection string: %v", err)
 	}
+	host, err := ctr.Host(ctx)
+	if err != nil {
+		t.Fatalf("getting container host: %v", err)
+	}
+	port, err := ctr.MappedPort(ctx, "5432/tcp")
+	if err != nil {
+		t.Fatalf("getting container port: %v", err)
+	}
+	cfg := config.DatabaseConfig{
+		Host: host, Port: port.Int(), User: "test", Password: "test",
+		DBName: "dkpbot_test", SSLMode: "disable", Driver: "sqlx",
+	}
 
 	db, err := sqlx.Connect("postgres", connStr)
 	if err != nil {
@@ -62,10 +85,16 @@ func newTestDB(t *testing.T) *sqlx.DB {
 	}
 	t.Cleanup(func() { db.Close() })
 
-	// Apply migration.
-	if _, err := db.ExecContext(ctx, string(migrationSQL)); err != nil {
-		t.Fatalf("applying migration: %v", err)
+	// Apply migrations.
+	for _, f := range migrationFiles {
+		migrationSQL, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatalf("reading migration %s: %v", filepath.Base(f), err)
+		}
+		if _, err := db.ExecContext(ctx, string(migrationSQL)); err != nil {
+			t.Fatalf("applying migration %s: %v", filepath.Base(f), err)
+		}
 	}
 
-	return db
+	return db, cfg
 }
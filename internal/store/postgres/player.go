@@ -3,8 +3,10 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 
 	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
@@ -12,41 +14,46 @@ import (
 
 // PlayerRepo implements store.PlayerRepository with sqlx.
 type PlayerRepo struct {
-	db    *sqlx.DB
-	clock clock.Clock
+	db     *sqlx.DB
+	clock  clock.Clock
+	fence  *store.Fence
+	cipher *store.Cipher
 }
 
-// NewPlayerRepo returns a new PlayerRepo.
-func NewPlayerRepo(db *sqlx.DB, clk clock.Clock) *PlayerRepo {
-	return &PlayerRepo{db: db, clock: clk}
+// NewPlayerRepo returns a new PlayerRepo. DKP updates are rejected once
+// fence has been superseded; fence may be nil. Discord IDs are sealed with
+// cipher, which may be nil.
+func NewPlayerRepo(db *sqlx.DB, clk clock.Clock, fence *store.Fence, cipher *store.Cipher) *PlayerRepo {
+	return &PlayerRepo{db: db, clock: clk, fence: fence, cipher: cipher}
 }
 
 func (r *PlayerRepo) Create(ctx context.Context, p *store.Player) error {
-	query := `INSERT INTO players (discord_id, character_name, dkp, created_at, updated_at)
-	           VALUES ($1, $2, $3, $4, $5)
+	query := `INSERT INTO players (discord_id, character_name, dkp, class, role, spec, timezone, created_at, updated_at)
+	           VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	           RETURNING id`
 	now := r.clock.Now().UTC()
 	p.CreatedAt = now
 	p.UpdatedAt = now
-	return r.db.QueryRowContext(ctx, query, p.DiscordID, p.CharacterName, p.DKP, p.CreatedAt, p.UpdatedAt).Scan(&p.ID)
+	err := r.db.QueryRowContext(ctx, query, r.cipher.SealID(p.DiscordID), p.CharacterName, p.DKP, p.Class, p.Role, p.Spec, p.Timezone, p.CreatedAt, p.UpdatedAt).Scan(&p.ID)
+	return store.Classify(err, "creating player", nil, store.ErrPlayerExists)
 }
 
 func (r *PlayerRepo) GetByDiscordID(ctx context.Context, discordID string) (*store.Player, error) {
 	var p store.Player
-	err := r.db.GetContext(ctx, &p, `SELECT * FROM players WHERE discord_id = $1`, discordID)
+	err := r.db.GetContext(ctx, &p, `SELECT * FROM players WHERE discord_id = ANY($1)`, pq.Array(r.cipher.LookupIDs(discordID)))
 	if err != nil {
-		return nil, fmt.Errorf("getting player by discord_id: %w", err)
+		return nil, store.Classify(err, "getting player by discord_id", store.ErrPlayerNotFound, nil)
 	}
-	return &p, nil
+	return r.cipher.OpenPlayer(&p)
 }
 
 func (r *PlayerRepo) GetByCharacterName(ctx context.Context, name string) (*store.Player, error) {
 	var p store.Player
 	err := r.db.GetContext(ctx, &p, `SELECT * FROM players WHERE character_name = $1`, name)
 	if err != nil {
-		return nil, fmt.Errorf("getting player by character_name: %w", err)
+		return nil, store.Classify(err, "getting player by character_name", store.ErrPlayerNotFound, nil)
 	}
-	return &p, nil
+	return r.cipher.OpenPlayer(&p)
 }
 
 func (r *PlayerRepo) List(ctx context.Context) ([]store.Player, error) {
@@ -55,12 +62,24 @@ func (r *PlayerRepo) List(ctx context.Context) ([]store.Player, error) {
 	if err != nil {
 		return nil, fmt.Errorf("listing players: %w", err)
 	}
+	if err := r.cipher.OpenPlayers(players); err != nil {
+		return nil, err
+	}
 	return players, nil
 }
 
 func (r *PlayerRepo) UpdateDKP(ctx context.Context, id string, delta int) error {
-	result, err := r.db.ExecContext(ctx,
-		`UPDATE players SET dkp = dkp + $1, updated_at = $2 WHERE id = $3`,
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := r.fence.Check(ctx, tx); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx,
+		`UPDATE players SET dkp = dkp + $1, updated_at = $2 WHERE id = $3 AND archived_at IS NULL`,
 		delta, r.clock.Now().UTC(), id,
 	)
 	if err != nil {
@@ -68,7 +87,69 @@ func (r *PlayerRepo) UpdateDKP(ctx context.Context, id string, delta int) error
 	}
 	n, _ := result.RowsAffected()
 	if n == 0 {
-		return fmt.Errorf("player %s not found", id)
+		// Tell an archived player from a missing one.
+		var archived bool
+		err := tx.QueryRowContext(ctx, `SELECT archived_at IS NOT NULL FROM players WHERE id = $1`, id).Scan(&archived)
+		switch {
+		case err != nil:
+			return store.Classify(err, "updating dkp", store.ErrPlayerNotFound, nil)
+		case archived:
+			return store.ErrPlayerArchived.Wrap(fmt.Errorf("updating dkp: player %s is archived", id))
+		}
+		return store.ErrPlayerNotFound.Wrap(fmt.Errorf("updating dkp: no player with id %s", id))
+	}
+	return tx.Commit()
+}
+
+func (r *PlayerRepo) UpdateProfile(ctx context.Context, id string, profile store.Profile) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := r.fence.Check(ctx, tx); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx,
+		`UPDATE players SET class = $1, role = $2, spec = $3, timezone = $4, updated_at = $5 WHERE id = $6`,
+		profile.Class, profile.Role, profile.Spec, profile.Timezone, r.clock.Now().UTC(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("updating profile: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return store.ErrPlayerNotFound.Wrap(fmt.Errorf("updating profile: no player with id %s", id))
+	}
+	return tx.Commit()
+}
+
+func (r *PlayerRepo) SetArchived(ctx context.Context, id string, archived bool) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := r.fence.Check(ctx, tx); err != nil {
+		return err
+	}
+	now := r.clock.Now().UTC()
+	var archivedAt *time.Time
+	if archived {
+		archivedAt = &now
+	}
+	result, err := tx.ExecContext(ctx,
+		`UPDATE players SET archived_at = $1, updated_at = $2 WHERE id = $3`,
+		archivedAt, now, id,
+	)
+	if err != nil {
+		return fmt.Errorf("archiving player: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return store.ErrPlayerNotFound.Wrap(fmt.Errorf("archiving player: no player with id %s", id))
 	}
-	return nil
+	return tx.Commit()
 }
@@ -2,6 +2,7 @@ package postgres_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
@@ -11,7 +12,7 @@ import (
 
 func TestPlayerRepo_CreateAndGet(t *testing.T) {
 	db := newTestDB(t)
-	repo := postgres.NewPlayerRepo(db, clock.Real{})
+	repo := postgres.NewPlayerRepo(db, clock.Real{}, nil, nil)
 	ctx := context.Background()
 
 	p := &store.Player{
@@ -52,7 +53,7 @@ func TestPlayerRepo_CreateAndGet(t *testing.T) {
 
 func TestPlayerRepo_List(t *testing.T) {
 	db := newTestDB(t)
-	repo := postgres.NewPlayerRepo(db, clock.Real{})
+	repo := postgres.NewPlayerRepo(db, clock.Real{}, nil, nil)
 	ctx := context.Background()
 
 	// Create two players.
@@ -81,7 +82,7 @@ func TestPlayerRepo_List(t *testing.T) {
 
 func TestPlayerRepo_UpdateDKP(t *testing.T) {
 	db := newTestDB(t)
-	repo := postgres.NewPlayerRepo(db, clock.Real{})
+	repo := postgres.NewPlayerRepo(db, clock.Real{}, nil, nil)
 	ctx := context.Background()
 
 	p := &store.Player{DiscordID: "d1", CharacterName: "DKPTest", DKP: 100}
@@ -119,7 +120,7 @@ func TestPlayerRepo_UpdateDKP(t *testing.T) {
 
 func TestPlayerRepo_UpdateDKP_NotFound(t *testing.T) {
 	db := newTestDB(t)
-	repo := postgres.NewPlayerRepo(db, clock.Real{})
+	repo := postgres.NewPlayerRepo(db, clock.Real{}, nil, nil)
 	ctx := context.Background()
 
 	err := repo.UpdateDKP(ctx, "00000000-0000-0000-0000-000000000000", 10)
@@ -127,3 +128,117 @@ func TestPlayerRepo_UpdateDKP_NotFound(t *testing.T) {
 		t.Fatal("expected error for nonexistent player")
 	}
 }
+
+func TestPlayerRepo_UpdateProfile(t *testing.T) {
+	db := newTestDB(t)
+	repo := postgres.NewPlayerRepo(db, clock.Real{}, nil, nil)
+	ctx := context.Background()
+
+	p := &store.Player{DiscordID: "d1", CharacterName: "ProfileTest", Profile: store.Profile{Class: "Priest"}}
+	if err := repo.Create(ctx, p); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	want := store.Profile{Class: "Priest", Role: "healer", Spec: "Holy", Timezone: "Europe/Copenhagen"}
+	if err := repo.UpdateProfile(ctx, p.ID, want); err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	got, err := repo.GetByDiscordID(ctx, "d1")
+	if err != nil {
+		t.Fatalf("GetByDiscordID: %v", err)
+	}
+	if got.Profile != want {
+		t.Errorf("Profile = %+v, want %+v", got.Profile, want)
+	}
+
+	if err := repo.UpdateProfile(ctx, "00000000-0000-0000-0000-000000000000", want); err == nil {
+		t.Fatal("expected error for nonexistent player")
+	}
+}
+
+func TestPlayerRepo_SetArchived(t *testing.T) {
+	db := newTestDB(t)
+	repo := postgres.NewPlayerRepo(db, clock.Real{}, nil, nil)
+	ctx := context.Background()
+
+	p := &store.Player{DiscordID: "d1", CharacterName: "ArchiveTest", DKP: 40}
+	if err := repo.Create(ctx, p); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repo.SetArchived(ctx, p.ID, true); err != nil {
+		t.Fatalf("SetArchived(true): %v", err)
+	}
+	got, err := repo.GetByDiscordID(ctx, "d1")
+	if err != nil {
+		t.Fatalf("GetByDiscordID: %v", err)
+	}
+	if !got.Archived() {
+		t.Error("player is not archived")
+	}
+	if err := repo.UpdateDKP(ctx, p.ID, 10); !errors.Is(err, store.ErrPlayerArchived) {
+		t.Errorf("UpdateDKP on an archived player: %v, want ErrPlayerArchived", err)
+	}
+
+	if err := repo.SetArchived(ctx, p.ID, false); err != nil {
+		t.Fatalf("SetArchived(false): %v", err)
+	}
+	if err := repo.UpdateDKP(ctx, p.ID, 10); err != nil {
+		t.Fatalf("UpdateDKP after restoring: %v", err)
+	}
+	got, _ = repo.GetByDiscordID(ctx, "d1")
+	if got.Archived() || got.DKP != 50 {
+		t.Errorf("restored player = archived %t, DKP %d; want active with 50", got.Archived(), got.DKP)
+	}
+
+	if err := repo.SetArchived(ctx, "00000000-0000-0000-0000-000000000000", true); !errors.Is(err, store.ErrPlayerNotFound) {
+		t.Errorf("SetArchived on a nonexistent player: %v, want ErrPlayerNotFound", err)
+	}
+}
+
+func TestPlayerRepo_Encrypted(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	// A player registered before encryption was enabled stays in the clear.
+	if err := postgres.NewPlayerRepo(db, clock.Real{}, nil, nil).Create(ctx, &store.Player{DiscordID: "discord-1", CharacterName: "Before"}); err != nil {
+		t.Fatalf("Create in the clear: %v", err)
+	}
+	cipher, err := store.NewCipher("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+	repo := postgres.NewPlayerRepo(db, clock.Real{}, nil, cipher)
+	if err := repo.Create(ctx, &store.Player{DiscordID: "discord-2", CharacterName: "After"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	var stored string
+	if err := db.QueryRowContext(ctx, `SELECT discord_id FROM players WHERE character_name = 'After'`).Scan(&stored); err != nil {
+		t.Fatalf("reading stored player: %v", err)
+	}
+	if stored == "discord-2" {
+		t.Error("stored Discord ID is in the clear, want it sealed")
+	}
+
+	for _, tc := range []struct{ discordID, name string }{{"discord-1", "Before"}, {"discord-2", "After"}} {
+		got, err := repo.GetByDiscordID(ctx, tc.discordID)
+		if err != nil {
+			t.Fatalf("GetByDiscordID(%s): %v", tc.discordID, err)
+		}
+		if got.CharacterName != tc.name || got.DiscordID != tc.discordID {
+			t.Errorf("GetByDiscordID(%s) = %s (%s), want %s", tc.discordID, got.CharacterName, got.DiscordID, tc.name)
+		}
+	}
+	players, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	for _, p := range players {
+		if p.DiscordID != "discord-1" && p.DiscordID != "discord-2" {
+			t.Errorf("List returned Discord ID %q, want it opened", p.DiscordID)
+		}
+	}
+	if err := repo.Create(ctx, &store.Player{DiscordID: "discord-2", CharacterName: "Again"}); !errors.Is(err, store.ErrPlayerExists) {
+		t.Errorf("Create of a registered Discord ID error = %v, want ErrPlayerExists", err)
+	}
+}
@@ -6,10 +6,11 @@ import (
 
 	"github.com/XSAM/otelsql"
 	"github.com/jmoiron/sqlx"
-	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 
 	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/ids"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
 )
 
@@ -24,38 +25,54 @@ func init() {
 
 // openSQLX is the store.Driver for the "sqlx" backend.
 func openSQLX(ctx context.Context, cfg config.DatabaseConfig, clk clock.Clock) (*store.Repositories, error) {
+	cipher, err := store.NewCipher(cfg.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	chainKey, err := store.ParseChainKey(cfg.ChainKey)
+	if err != nil {
+		return nil, err
+	}
+	gen, err := ids.New(cfg.IDs, clk)
+	if err != nil {
+		return nil, err
+	}
 	db, err := Connect(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
+	fence := store.NewFence(db)
+	events := NewEventStore(db, fence, cfg.AppendBatchSize, cipher, chainKey)
 	return &store.Repositories{
-		Players:  NewPlayerRepo(db, clk),
-		Auctions: NewAuctionRepo(db, clk),
-		Events:   NewEventStore(db),
-		Closer:   closerFunc(db.Close),
-		Ping:     db.PingContext,
+		Players:       NewPlayerRepo(db, clk, fence, cipher),
+		Auctions:      NewAuctionRepo(db, clk, gen),
+		Events:        events,
+		Idempotency:   NewIdempotencyRepo(db, clk),
+		GuildSettings: NewGuildSettingsRepo(db, clk),
+		Items:         NewItemRepo(db, clk),
+		Wishlists:     NewWishlistRepo(db, clk, cipher),
+		PlayerNotes:   NewPlayerNoteRepo(db, clk, cipher),
+		Usage:         NewUsageRepo(db, cipher),
+		Balances:      NewBalanceRepo(db, clk, fence),
+		Archive:       NewEventArchive(db, clk, cipher),
+		ChainHeads:    events.Heads,
+		Fence:         fence,
+		Closer:        closerFunc(db.Close),
+		Ping:          db.PingContext,
+		Schema:        func(ctx context.Context) error { return store.CheckSchema(ctx, db.DB) },
+		Migrations:    func(ctx context.Context) error { return store.CheckSchemaVersion(ctx, db.DB) },
+		Listen:        func(ctx context.Context, bus *event.Bus) error { return store.Listen(ctx, cfg, bus) },
 	}, nil
 }
 
 // Connect opens and verifies a Postgres connection with OTEL instrumentation.
 func Connect(ctx context.Context, cfg config.DatabaseConfig) (*sqlx.DB, error) {
-	dsn := cfg.DSN()
-
-	// Register the OTel-instrumented driver wrapping lib/pq.
-	driverName, err := otelsql.Register("postgres",
-		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("registering otel driver: %w", err)
-	}
-
-	db, err := sqlx.ConnectContext(ctx, driverName, dsn)
-	if err != nil {
-		return nil, fmt.Errorf("connecting to database: %w", err)
-	}
+	// Wrap lib/pq with the OTel-instrumented driver.
+	db := sqlx.NewDb(otelsql.OpenDB(store.Connector(cfg), store.TraceOptions(cfg)...), "postgres")
 
 	if err := db.PingContext(ctx); err != nil {
-		return nil, fmt.Errorf("pinging database: %w", err)
+		_ = db.Close()
+		return nil, fmt.Errorf("connecting to database: %w", err)
 	}
 
 	return db, nil
@@ -0,0 +1,98 @@
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+func TestCheckSchema(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	if err := store.CheckSchema(ctx, db.DB); err != nil {
+		t.Fatalf("CheckSchema after all migrations: %v", err)
+	}
+
+	// Undo parts of 005 and 007, as on a database that missed them.
+	if _, err := db.ExecContext(ctx, `DROP TABLE guild_settings; ALTER TABLE events DROP COLUMN chain_hash`); err != nil {
+		t.Fatal(err)
+	}
+	err := store.CheckSchema(ctx, db.DB)
+	if err == nil {
+		t.Fatal("CheckSchema succeeded with a table and column missing")
+	}
+	for _, want := range []string{"missing table guild_settings", "missing column events.chain_hash"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("CheckSchema() = %v, want it to report %q", err, want)
+		}
+	}
+}
+
+func TestCheckSchemaVersion(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	if err := store.CheckSchemaVersion(ctx, db.DB); err != nil {
+		t.Fatalf("CheckSchemaVersion after all migrations: %v", err)
+	}
+
+	// A newer schema is fine for this binary.
+	if _, err := db.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, store.SchemaVersion+1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CheckSchemaVersion(ctx, db.DB); err != nil {
+		t.Errorf("CheckSchemaVersion with a newer schema: %v", err)
+	}
+
+	// An older one is not.
+	if _, err := db.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version >= $1`, store.SchemaVersion); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("database schema is at migration %03d, but this binary needs %03d", store.SchemaVersion-1, store.SchemaVersion)
+	if err := store.CheckSchemaVersion(ctx, db.DB); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("CheckSchemaVersion() = %v, want %q", err, want)
+	}
+
+	if _, err := db.ExecContext(ctx, `DROP TABLE schema_migrations`); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CheckSchemaVersion(ctx, db.DB); err == nil || !strings.Contains(err.Error(), "no migrations are recorded") {
+		t.Errorf("CheckSchemaVersion() without the table = %v, want no migrations recorded", err)
+	}
+}
+
+// TestSchemaVersion checks that SchemaVersion names the last migration and
+// that the migrations after 017 record their versions.
+func TestSchemaVersion(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("migrations", "*.sql"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("listing migrations: %v, %v", files, err)
+	}
+	for _, f := range files {
+		version, err := strconv.Atoi(strings.SplitN(filepath.Base(f), "_", 2)[0])
+		if err != nil {
+			t.Fatalf("migration %s has no number: %v", f, err)
+		}
+		if version <= 17 {
+			continue
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record := fmt.Sprintf("INSERT INTO schema_migrations (version) VALUES (%d)", version); !strings.Contains(string(data), record) {
+			t.Errorf("migration %s does not record its version with %q", f, record)
+		}
+	}
+	last := filepath.Base(files[len(files)-1])
+	if want := fmt.Sprintf("%03d_", store.SchemaVersion); !strings.HasPrefix(last, want) {
+		t.Errorf("last migration is %s, but store.SchemaVersion is %d", last, store.SchemaVersion)
+	}
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// UsageRepo implements store.UsageRepository with sqlx.
+type UsageRepo struct {
+	db     *sqlx.DB
+	cipher *store.Cipher
+}
+
+// NewUsageRepo returns a new UsageRepo. The Discord IDs of users are sealed
+// with cipher, which may be nil.
+func NewUsageRepo(db *sqlx.DB, cipher *store.Cipher) *UsageRepo {
+	return &UsageRepo{db: db, cipher: cipher}
+}
+
+func (r *UsageRepo) Record(ctx context.Context, command, userID string, failed bool, at time.Time) (bool, error) {
+	failures := 0
+	if failed {
+		failures = 1
+	}
+	var first bool
+	err := r.db.QueryRowxContext(ctx,
+		`INSERT INTO command_usage (day, command, user_id, uses, failures)
+		 VALUES ($1, $2, $3, 1, $4)
+		 ON CONFLICT (day, command, user_id) DO UPDATE
+		 SET uses = command_usage.uses + 1, failures = command_usage.failures + EXCLUDED.failures
+		 RETURNING uses = 1`,
+		day(at), command, r.cipher.SealID(userID), failures,
+	).Scan(&first)
+	if err != nil {
+		return false, fmt.Errorf("recording command usage: %w", err)
+	}
+	return first, nil
+}
+
+func (r *UsageRepo) Summary(ctx context.Context, since time.Time) ([]store.CommandUsage, error) {
+	var usage []store.CommandUsage
+	err := r.db.SelectContext(ctx, &usage,
+		`SELECT command, sum(uses) AS uses, count(DISTINCT user_id) AS users, sum(failures) AS failures
+		 FROM command_usage WHERE day >= $1
+		 GROUP BY command ORDER BY uses DESC, command`,
+		day(since),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("summarizing command usage: %w", err)
+	}
+	return usage, nil
+}
+
+func (r *UsageRepo) Users(ctx context.Context, since time.Time) (int, error) {
+	var n int
+	if err := r.db.GetContext(ctx, &n, `SELECT count(DISTINCT user_id) FROM command_usage WHERE day >= $1`, day(since)); err != nil {
+		return 0, fmt.Errorf("counting command users: %w", err)
+	}
+	return n, nil
+}
+
+// day formats the UTC day of t as a Postgres date.
+func day(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
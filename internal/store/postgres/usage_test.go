@@ -0,0 +1,94 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/postgres"
+)
+
+func TestUsageRepo_RecordSummary(t *testing.T) {
+	db := newTestDB(t)
+	repo := postgres.NewUsageRepo(db, nil)
+	ctx := context.Background()
+	day1 := time.Date(2025, 6, 14, 23, 0, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Hour)
+
+	for _, u := range []struct {
+		command, user string
+		failed        bool
+		at            time.Time
+		first         bool
+	}{
+		{"bid", "u1", false, day1, true},
+		{"bid", "u1", true, day1, false},
+		{"bid", "u2", false, day1, true},
+		{"bid", "u1", false, day2, true},
+		{"dkp", "u3", false, day2, true},
+	} {
+		first, err := repo.Record(ctx, u.command, u.user, u.failed, u.at)
+		if err != nil {
+			t.Fatalf("Record(%s, %s): %v", u.command, u.user, err)
+		}
+		if first != u.first {
+			t.Errorf("Record(%s, %s, %s) first = %v, want %v", u.command, u.user, u.at, first, u.first)
+		}
+	}
+
+	got, err := repo.Summary(ctx, day1)
+	if err != nil {
+		t.Fatalf("Summary: %v", err)
+	}
+	want := []store.CommandUsage{
+		{Command: "bid", Uses: 4, Users: 2, Failures: 1},
+		{Command: "dkp", Uses: 1, Users: 1},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Summary = %+v, want %+v", got, want)
+	}
+
+	got, err = repo.Summary(ctx, day2)
+	if err != nil {
+		t.Fatalf("Summary since day 2: %v", err)
+	}
+	if len(got) != 2 || got[0].Uses != 1 || got[1].Uses != 1 {
+		t.Errorf("Summary since day 2 = %+v, want one use each", got)
+	}
+
+	if n, err := repo.Users(ctx, day1); err != nil || n != 3 {
+		t.Errorf("Users = %d, %v, want 3", n, err)
+	}
+}
+
+func TestUsageRepo_Encrypted(t *testing.T) {
+	db := newTestDB(t)
+	cipher, err := store.NewCipher("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+	repo := postgres.NewUsageRepo(db, cipher)
+	ctx := context.Background()
+	at := time.Date(2025, 6, 14, 20, 0, 0, 0, time.UTC)
+
+	for _, u := range []struct {
+		user  string
+		first bool
+	}{{"u1", true}, {"u1", false}, {"u2", true}} {
+		if first, err := repo.Record(ctx, "bid", u.user, false, at); err != nil || first != u.first {
+			t.Errorf("Record(%s) = %v, %v, want %v", u.user, first, err, u.first)
+		}
+	}
+
+	var clear int
+	if err := db.QueryRowContext(ctx, `SELECT count(*) FROM command_usage WHERE user_id IN ('u1', 'u2')`).Scan(&clear); err != nil {
+		t.Fatalf("reading stored usage: %v", err)
+	}
+	if clear != 0 {
+		t.Errorf("%d usage rows have the user in the clear, want them sealed", clear)
+	}
+	if n, err := repo.Users(ctx, at); err != nil || n != 2 {
+		t.Errorf("Users = %d, %v, want 2", n, err)
+	}
+}
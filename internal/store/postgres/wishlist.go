@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// WishlistRepo implements store.WishlistRepository with sqlx.
+type WishlistRepo struct {
+	db     *sqlx.DB
+	clock  clock.Clock
+	cipher *store.Cipher
+}
+
+// NewWishlistRepo returns a new WishlistRepo. The Discord IDs of wishers
+// are opened with cipher, which may be nil.
+func NewWishlistRepo(db *sqlx.DB, clk clock.Clock, cipher *store.Cipher) *WishlistRepo {
+	return &WishlistRepo{db: db, clock: clk, cipher: cipher}
+}
+
+func (r *WishlistRepo) Add(ctx context.Context, e *store.WishlistEntry) error {
+	e.CreatedAt = r.clock.Now().UTC()
+	_, err := r.db.NamedExecContext(ctx,
+		`INSERT INTO wishlists (player_id, item_name, created_at) VALUES (:player_id, :item_name, :created_at)`, e)
+	return store.Classify(err, "adding wishlist item", nil, store.ErrWishlisted)
+}
+
+func (r *WishlistRepo) Remove(ctx context.Context, playerID, itemName string) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM wishlists WHERE player_id = $1 AND lower(item_name) = lower($2)`, playerID, itemName)
+	if err != nil {
+		return fmt.Errorf("removing wishlist item: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return store.ErrNotWishlisted.Wrap(fmt.Errorf("item %q", itemName))
+	}
+	return nil
+}
+
+func (r *WishlistRepo) ListByPlayer(ctx context.Context, playerID string) ([]store.WishlistEntry, error) {
+	var entries []store.WishlistEntry
+	err := r.db.SelectContext(ctx, &entries,
+		`SELECT player_id, item_name, created_at FROM wishlists WHERE player_id = $1 ORDER BY created_at, item_name`,
+		playerID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing wishlist: %w", err)
+	}
+	return entries, nil
+}
+
+func (r *WishlistRepo) Wishers(ctx context.Context, itemName string) ([]store.Player, error) {
+	var players []store.Player
+	err := r.db.SelectContext(ctx, &players,
+		`SELECT p.* FROM players p JOIN wishlists w ON w.player_id = p.id
+		 WHERE lower(w.item_name) = lower($1) ORDER BY p.character_name`,
+		itemName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing wishers: %w", err)
+	}
+	if err := r.cipher.OpenPlayers(players); err != nil {
+		return nil, err
+	}
+	return players, nil
+}
+
+func (r *WishlistRepo) Demand(ctx context.Context, limit int) ([]store.ItemDemand, error) {
+	var demand []store.ItemDemand
+	err := r.db.SelectContext(ctx, &demand,
+		`SELECT min(item_name) AS item_name, count(*) AS players FROM wishlists
+		 GROUP BY lower(item_name) ORDER BY players DESC, item_name LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reporting wishlist demand: %w", err)
+	}
+	return demand, nil
+}
@@ -0,0 +1,73 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/postgres"
+)
+
+func TestWishlistRepo(t *testing.T) {
+	db := newTestDB(t)
+	clk := clock.Mock{T: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	players := postgres.NewPlayerRepo(db, clk, nil, nil)
+	repo := postgres.NewWishlistRepo(db, clk, nil)
+	ctx := context.Background()
+
+	alice := &store.Player{DiscordID: "1", CharacterName: "Alice"}
+	bob := &store.Player{DiscordID: "2", CharacterName: "Bob"}
+	for _, p := range []*store.Player{bob, alice} {
+		if err := players.Create(ctx, p); err != nil {
+			t.Fatalf("Create(%s): %v", p.CharacterName, err)
+		}
+	}
+
+	for _, e := range []store.WishlistEntry{
+		{PlayerID: alice.ID, ItemName: "Thunderfury"},
+		{PlayerID: alice.ID, ItemName: "Choker of the Fire Lord"},
+		{PlayerID: bob.ID, ItemName: "thunderfury"},
+	} {
+		if err := repo.Add(ctx, &e); err != nil {
+			t.Fatalf("Add(%s): %v", e.ItemName, err)
+		}
+	}
+	err := repo.Add(ctx, &store.WishlistEntry{PlayerID: alice.ID, ItemName: "THUNDERFURY"})
+	if !errors.Is(err, store.ErrWishlisted) {
+		t.Errorf("Add duplicate = %v, want ErrWishlisted", err)
+	}
+
+	list, err := repo.ListByPlayer(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("ListByPlayer: %v", err)
+	}
+	if len(list) != 2 || !list[0].CreatedAt.Equal(clk.T) {
+		t.Errorf("ListByPlayer = %+v, want two entries", list)
+	}
+
+	wishers, err := repo.Wishers(ctx, "THUNDERFURY")
+	if err != nil {
+		t.Fatalf("Wishers: %v", err)
+	}
+	if len(wishers) != 2 || wishers[0].CharacterName != "Alice" || wishers[1].CharacterName != "Bob" {
+		t.Errorf("Wishers = %+v, want Alice and Bob", wishers)
+	}
+
+	demand, err := repo.Demand(ctx, 10)
+	if err != nil {
+		t.Fatalf("Demand: %v", err)
+	}
+	if len(demand) != 2 || demand[0].Players != 2 || demand[1] != (store.ItemDemand{ItemName: "Choker of the Fire Lord", Players: 1}) {
+		t.Errorf("Demand = %+v, want Thunderfury by 2, then the choker by 1", demand)
+	}
+
+	if err := repo.Remove(ctx, bob.ID, "Thunderfury"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := repo.Remove(ctx, bob.ID, "Thunderfury"); !errors.Is(err, store.ErrNotWishlisted) {
+		t.Errorf("Remove again = %v, want ErrNotWishlisted", err)
+	}
+}
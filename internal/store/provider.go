@@ -15,10 +15,40 @@ type Repositories struct {
 	Players  PlayerRepository
 	Auctions AuctionRepository
 	Events   event.Store
+	// Idempotency records processed interaction IDs.
+	Idempotency IdempotencyRepository
+	// GuildSettings holds settings officers changed at runtime.
+	GuildSettings GuildSettingsRepository
+	// Items is the item catalog.
+	Items ItemRepository
+	// Wishlists holds the items players want.
+	Wishlists WishlistRepository
+	// PlayerNotes holds officers' notes on players.
+	PlayerNotes PlayerNoteRepository
+	// Usage counts the commands members use.
+	Usage UsageRepository
+	// Balances holds the players' balances in currencies other than DKP.
+	Balances BalanceRepository
+	// Archive holds snapshots and archived events of finished aggregates.
+	Archive event.Archive
+	// ChainHeads returns the recorded head of every aggregate's hash
+	// chain, for event.VerifyChain.
+	ChainHeads func(ctx context.Context) (map[string]event.ChainHead, error)
+	// Fence rejects writes once another replica has become the leader.
+	Fence *Fence
 	// Closer is called to release underlying resources (e.g. DB connection).
 	Closer io.Closer
 	// Ping checks the underlying connection health.
 	Ping func(ctx context.Context) error
+	// Schema reports tables and columns missing from the database, as
+	// after a migration was skipped.
+	Schema func(ctx context.Context) error
+	// Migrations reports an error unless the migrations this binary needs
+	// are recorded as applied.
+	Migrations func(ctx context.Context) error
+	// Listen publishes on bus the types of the events every replica
+	// appends, until ctx is done.
+	Listen func(ctx context.Context, bus *event.Bus) error
 }
 
 // Driver is a function that opens a connection and returns Repositories.
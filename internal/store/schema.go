@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// schema lists the tables and columns the Postgres repositories use, as
+// created by the migrations in internal/store/postgres/migrations.
+var schema = []struct {
+	table   string
+	columns []string
+}{
+	{"players", []string{"id", "discord_id", "character_name", "dkp", "created_at", "updated_at", "class", "role", "spec", "archived_at", "timezone"}},
+	{"auctions", []string{"id", "item_name", "started_by", "min_bid", "status", "winner_id", "win_amount", "created_at", "closed_at"}},
+	{"events", []string{"id", "aggregate_id", "type", "data", "version", "actor", "created_at", "prev_hash", "chain_hash"}},
+	{"idempotency_keys", []string{"key", "result", "created_at"}},
+	{"events_archive", []string{"id", "aggregate_id", "type", "data", "version", "actor", "created_at", "archived_at", "prev_hash", "chain_hash"}},
+	{"event_snapshots", []string{"aggregate_id", "version", "state", "created_at"}},
+	{"fencing_tokens", []string{"name", "token"}},
+	{"guild_settings", []string{"guild_id", "key", "value", "updated_by", "updated_at"}},
+	{"items", []string{"id", "name", "quality", "icon_url", "updated_at", "slot", "stats"}},
+	{"wishlists", []string{"player_id", "item_name", "created_at"}},
+	{"command_usage", []string{"day", "command", "user_id", "uses", "failures"}},
+	{"player_notes", []string{"id", "player_id", "author", "text", "loot_ban_until", "created_at"}},
+	{"player_balances", []string{"player_id", "currency", "balance", "updated_at"}},
+	{"schema_migrations", []string{"version", "applied_at"}},
+	{"chain_heads", []string{"aggregate_id", "version", "chain_hash"}},
+}
+
+// SchemaVersion is the number of the last migration in
+// internal/store/postgres/migrations, which this binary needs applied.
+const SchemaVersion = 19
+
+// CheckSchemaVersion reports an error unless the migrations recorded in
+// the schema_migrations table of db reach SchemaVersion. A newer schema is
+// accepted, as migrations only add to it, so that the previous binary keeps
+// working while a new one rolls out.
+func CheckSchemaVersion(ctx context.Context, db *sql.DB) error {
+	var version sql.NullInt64
+	err := db.QueryRowContext(ctx, `SELECT max(version) FROM schema_migrations`).Scan(&version)
+	var state interface{ SQLState() string }
+	switch {
+	case errors.As(err, &state) && state.SQLState() == undefinedTable:
+		return fmt.Errorf("no migrations are recorded, but this binary needs migration %03d: apply the migrations", SchemaVersion)
+	case err != nil:
+		return fmt.Errorf("reading schema version: %w", err)
+	case version.Int64 < SchemaVersion:
+		return fmt.Errorf("database schema is at migration %03d, but this binary needs %03d: apply the later migrations", version.Int64, SchemaVersion)
+	}
+	return nil
+}
+
+// CheckSchema reports the tables and columns the repositories use that are
+// missing from db, for example because a migration was not applied. It
+// looks only at the current schema of the connection.
+func CheckSchema(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx,
+		`SELECT table_name, column_name FROM information_schema.columns WHERE table_schema = current_schema()`)
+	if err != nil {
+		return fmt.Errorf("reading schema: %w", err)
+	}
+	defer rows.Close()
+
+	present := make(map[[2]string]bool)
+	tables := make(map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return fmt.Errorf("reading schema: %w", err)
+		}
+		present[[2]string{table, column}] = true
+		tables[table] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading schema: %w", err)
+	}
+
+	var problems []error
+	for _, t := range schema {
+		if !tables[t.table] {
+			problems = append(problems, fmt.Errorf("missing table %s", t.table))
+			continue
+		}
+		for _, c := range t.columns {
+			if !present[[2]string{t.table, c}] {
+				problems = append(problems, fmt.Errorf("missing column %s.%s", t.table, c))
+			}
+		}
+	}
+	return errors.Join(problems...)
+}
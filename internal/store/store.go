@@ -13,6 +13,36 @@ type Player struct {
 	DKP           int       `db:"dkp"`
 	CreatedAt     time.Time `db:"created_at"`
 	UpdatedAt     time.Time `db:"updated_at"`
+	// ArchivedAt is when the player was archived, or nil. Archived players
+	// keep their history, but their DKP is frozen and they may not bid.
+	ArchivedAt *time.Time `db:"archived_at"`
+	Profile
+}
+
+// Archived reports whether the player is archived.
+func (p Player) Archived() bool {
+	return p.ArchivedAt != nil
+}
+
+// Profile is what a player tells about their character. Any field may be
+// empty.
+type Profile struct {
+	Class string `db:"class"`
+	// Role is the raid role: "tank", "healer", or "dps".
+	Role string `db:"role"`
+	Spec string `db:"spec"`
+	// Timezone is the IANA name of the timezone, such as
+	// "Europe/Copenhagen", dates and times given by the player are read
+	// in, or empty to use the timezone of the guild.
+	Timezone string `db:"timezone"`
+}
+
+// Balance is what a player holds of a currency other than DKP.
+type Balance struct {
+	PlayerID  string    `db:"player_id"`
+	Currency  string    `db:"currency"`
+	Balance   int       `db:"balance"`
+	UpdatedAt time.Time `db:"updated_at"`
 }
 
 // Auction represents an auction record.
@@ -21,13 +51,91 @@ type Auction struct {
 	ItemName  string     `db:"item_name"`
 	StartedBy string     `db:"started_by"`
 	MinBid    int        `db:"min_bid"`
-	Status    string     `db:"status"` // "open", "closed", "canceled"
+	Status    string     `db:"status"` // as auction.State, such as "open" or "closed"
 	WinnerID  *string    `db:"winner_id"`
 	WinAmount *int       `db:"win_amount"`
 	CreatedAt time.Time  `db:"created_at"`
 	ClosedAt  *time.Time `db:"closed_at"`
 }
 
+// IdempotencyRecord is a reserved idempotency key and, once the guarded
+// operation has completed, its JSON-encoded result.
+type IdempotencyRecord struct {
+	Key       string    `db:"key"`
+	Result    []byte    `db:"result"` // nil while the operation is in flight
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// GuildSetting is a setting an officer changed for one guild, overriding
+// the default from the config file.
+type GuildSetting struct {
+	GuildID   string    `db:"guild_id"`
+	Key       string    `db:"key"`
+	Value     string    `db:"value"`
+	UpdatedBy string    `db:"updated_by"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Item is an entry of the item catalog, imported from game data.
+type Item struct {
+	// ID is the game's ID for the item.
+	ID   string `db:"id"`
+	Name string `db:"name"`
+	// Quality is the item's rarity, such as "epic", or empty if unknown.
+	Quality string `db:"quality"`
+	IconURL string `db:"icon_url"`
+	// Slot is where the item is equipped, such as "Head", or empty.
+	Slot string `db:"slot"`
+	// Stats are the item's tooltip stats, one per line, such as
+	// "+20 Stamina".
+	Stats     string    `db:"stats"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// WishlistEntry is an item a player wants.
+type WishlistEntry struct {
+	PlayerID  string    `db:"player_id"`
+	ItemName  string    `db:"item_name"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// PlayerNote is a note officers keep on a player, such as a loot ban.
+type PlayerNote struct {
+	ID       string `db:"id"`
+	PlayerID string `db:"player_id"`
+	// Author is the Discord ID of the officer who wrote the note.
+	Author string `db:"author"`
+	Text   string `db:"text"`
+	// LootBanUntil, if set, is when the loot ban the note records ends:
+	// until then the player may not bid on auctions.
+	LootBanUntil *time.Time `db:"loot_ban_until"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+// ItemDemand is the number of players wanting an item.
+type ItemDemand struct {
+	ItemName string `db:"item_name"`
+	Players  int    `db:"players"`
+}
+
+// CommandUsage sums the uses of a command, or of a button's action, over
+// a period.
+type CommandUsage struct {
+	Command string `db:"command"`
+	Uses    int    `db:"uses"`
+	// Users is the number of distinct members who used it.
+	Users    int `db:"users"`
+	Failures int `db:"failures"`
+}
+
+// FailureRate returns the fraction of uses that failed.
+func (c CommandUsage) FailureRate() float64 {
+	if c.Uses == 0 {
+		return 0
+	}
+	return float64(c.Failures) / float64(c.Uses)
+}
+
 // PlayerRepository defines player persistence operations.
 type PlayerRepository interface {
 	Create(ctx context.Context, p *Player) error
@@ -35,13 +143,124 @@ type PlayerRepository interface {
 	GetByCharacterName(ctx context.Context, name string) (*Player, error)
 	List(ctx context.Context) ([]Player, error)
 	UpdateDKP(ctx context.Context, id string, delta int) error
+	// UpdateProfile replaces the profile of the player id.
+	UpdateProfile(ctx context.Context, id string, profile Profile) error
+	// SetArchived archives the player id, or restores them if archived is
+	// false. UpdateDKP rejects archived players with ErrPlayerArchived.
+	SetArchived(ctx context.Context, id string, archived bool) error
 }
 
-// AuctionRepository defines auction persistence operations.
+// BalanceRepository defines persistence of the balances of currencies
+// other than DKP, which players.dkp holds. A player holds none of a
+// currency until it is first changed.
+type BalanceRepository interface {
+	// Get returns the balance of the player playerID in currency.
+	Get(ctx context.Context, playerID, currency string) (int, error)
+	// List returns the balances held in currency, highest first.
+	List(ctx context.Context, currency string) ([]Balance, error)
+	// ListByPlayer returns the balances of the player playerID, ordered by
+	// currency.
+	ListByPlayer(ctx context.Context, playerID string) ([]Balance, error)
+	// Update changes the balance of the player id in currency by delta. It
+	// rejects archived players with ErrPlayerArchived.
+	Update(ctx context.Context, id, currency string, delta int) error
+	// Transfer moves amount of currency from the player fromID to the
+	// player toID, or changes neither. Like Update, it rejects archived
+	// players.
+	Transfer(ctx context.Context, fromID, toID, currency string, amount int) error
+}
+
+// AuctionRepository defines auction persistence operations. The auctions
+// table is a projection of the auction events, keyed by their aggregate
+// IDs.
 type AuctionRepository interface {
 	Create(ctx context.Context, a *Auction) error
 	GetByID(ctx context.Context, id string) (*Auction, error)
 	Close(ctx context.Context, id string, winnerID string, amount int) error
 	Cancel(ctx context.Context, id string) error
+	// ListOpen returns the auctions that are neither closed nor canceled,
+	// oldest first.
 	ListOpen(ctx context.Context) ([]Auction, error)
+	// Save creates or replaces the auction a.ID.
+	Save(ctx context.Context, a *Auction) error
+}
+
+// IdempotencyRepository defines idempotency key persistence operations.
+type IdempotencyRepository interface {
+	// Reserve claims key. If key is already claimed it returns the existing
+	// record and false, unless staleAfter is positive and the claim is
+	// older than that without a result, in which case Reserve takes it
+	// over.
+	Reserve(ctx context.Context, key string, staleAfter time.Duration) (*IdempotencyRecord, bool, error)
+	// Complete stores the result of the operation guarded by key.
+	Complete(ctx context.Context, key string, result []byte) error
+	// Release deletes a reservation so that the operation can be retried.
+	Release(ctx context.Context, key string) error
+	// Prune deletes the keys claimed more than ttl ago and returns how
+	// many it deleted.
+	Prune(ctx context.Context, ttl time.Duration) (int64, error)
+}
+
+// GuildSettingsRepository defines per-guild settings persistence operations.
+type GuildSettingsRepository interface {
+	// List returns the settings changed for guildID, ordered by key.
+	List(ctx context.Context, guildID string) ([]GuildSetting, error)
+	// Set creates or replaces a setting.
+	Set(ctx context.Context, s *GuildSetting) error
+	// Delete removes a setting, restoring its default.
+	Delete(ctx context.Context, guildID, key string) error
+}
+
+// ItemRepository defines item catalog persistence operations.
+type ItemRepository interface {
+	// Upsert creates or replaces items by ID, all or none of them.
+	Upsert(ctx context.Context, items []Item) error
+	// Search returns up to limit items whose name contains query, ignoring
+	// case: names starting with query first, then by name.
+	Search(ctx context.Context, query string, limit int) ([]Item, error)
+	// GetByName returns the item named name, ignoring case, or
+	// ErrItemNotFound.
+	GetByName(ctx context.Context, name string) (*Item, error)
+}
+
+// UsageRepository defines command usage persistence operations. Uses are
+// counted per day (in UTC), command, and member.
+type UsageRepository interface {
+	// Record counts a use of command by userID on the day of at, as a
+	// failure if failed. It reports whether it was the member's first use
+	// of the command that day.
+	Record(ctx context.Context, command, userID string, failed bool, at time.Time) (bool, error)
+	// Summary returns the uses of each command from the day of since on,
+	// most used first and ties by command.
+	Summary(ctx context.Context, since time.Time) ([]CommandUsage, error)
+	// Users returns the number of distinct members who used any command
+	// from the day of since on.
+	Users(ctx context.Context, since time.Time) (int, error)
+}
+
+// WishlistRepository defines wishlist persistence operations. Item names
+// are matched ignoring case.
+type WishlistRepository interface {
+	// Add puts an item on a player's wishlist, or returns ErrWishlisted if
+	// it is already there.
+	Add(ctx context.Context, e *WishlistEntry) error
+	// Remove takes an item off a player's wishlist, or returns
+	// ErrNotWishlisted if it is not there.
+	Remove(ctx context.Context, playerID, itemName string) error
+	// ListByPlayer returns a player's wishlist, oldest first.
+	ListByPlayer(ctx context.Context, playerID string) ([]WishlistEntry, error)
+	// Wishers returns the players wanting itemName, ordered by character
+	// name.
+	Wishers(ctx context.Context, itemName string) ([]Player, error)
+	// Demand returns up to limit items by the number of players wanting
+	// them, most wanted first, then by name.
+	Demand(ctx context.Context, limit int) ([]ItemDemand, error)
+}
+
+// PlayerNoteRepository defines persistence of officers' notes on players.
+type PlayerNoteRepository interface {
+	// Add records a note, setting its ID and creation time.
+	Add(ctx context.Context, n *PlayerNote) error
+	// ListByPlayer returns the notes on the player playerID, newest first.
+	ListByPlayer(ctx context.Context, playerID string) ([]PlayerNote, error)
 }
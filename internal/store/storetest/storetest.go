@@ -0,0 +1,525 @@
+// Package storetest provides in-memory repositories for testing code that
+// depends on the store interfaces without a database.
+//
+// The fakes behave like the Postgres repositories where tests can observe
+// it: they return the store's sentinel errors and copies of the stored
+// records. Any method can be made to fail with Fail, and assertion helpers
+// check what was stored.
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// failures holds the errors injected with Fail, by method name.
+type failures struct {
+	mu   sync.Mutex
+	errs map[string]error
+}
+
+// Fail makes every later call of the named method, or of every method if
+// method is empty, return err. A nil err removes the failure.
+func (f *failures) Fail(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.errs == nil {
+		f.errs = make(map[string]error)
+	}
+	if err == nil {
+		delete(f.errs, method)
+		return
+	}
+	f.errs[method] = err
+}
+
+// failure returns the error injected for method, if any.
+func (f *failures) failure(method string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err, ok := f.errs[method]; ok {
+		return err
+	}
+	return f.errs[""]
+}
+
+// Players is an in-memory store.PlayerRepository. It is safe for
+// concurrent use.
+type Players struct {
+	failures
+
+	mu sync.Mutex
+	// players are kept in the order they were added, which List keeps.
+	players []*store.Player
+}
+
+var _ store.PlayerRepository = (*Players)(nil)
+
+// NewPlayers returns a Players holding players, as added by Put.
+func NewPlayers(players ...store.Player) *Players {
+	r := &Players{}
+	for _, p := range players {
+		r.Put(p)
+	}
+	return r
+}
+
+// Put stores p as is, replacing the player with the same Discord ID, if
+// any. It is for setting up tests: unlike Create it never fails. A player
+// without an ID is given one derived from their Discord ID.
+func (r *Players) Put(p store.Player) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p.ID == "" {
+		p.ID = playerID(p.DiscordID)
+	}
+	for i, q := range r.players {
+		if q.DiscordID == p.DiscordID {
+			r.players[i] = &p
+			return
+		}
+	}
+	r.players = append(r.players, &p)
+}
+
+// playerID returns the ID of a player derived from their Discord ID, with
+// the characters aggregate IDs may not hold, such as the colons of merge
+// placeholders, replaced by hyphens.
+func playerID(discordID string) string {
+	return "player-" + strings.Map(func(r rune) rune {
+		if event.ValidateAggregateID(string(r)) != nil {
+			return '-'
+		}
+		return r
+	}, discordID)
+}
+
+// Player returns the player registered as discordID, failing t if there is
+// none.
+func (r *Players) Player(t testing.TB, discordID string) store.Player {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := r.find(func(p *store.Player) bool { return p.DiscordID == discordID })
+	if p == nil {
+		t.Fatalf("player %s is not stored", discordID)
+	}
+	return *p
+}
+
+// RequireDKP fails t unless the player registered as discordID holds want
+// DKP.
+func (r *Players) RequireDKP(t testing.TB, discordID string, want int) {
+	t.Helper()
+	if got := r.Player(t, discordID).DKP; got != want {
+		t.Errorf("DKP of %s = %d, want %d", discordID, got, want)
+	}
+}
+
+// find returns the first player matching match, or nil. r.mu must be held.
+func (r *Players) find(match func(*store.Player) bool) *store.Player {
+	for _, p := range r.players {
+		if match(p) {
+			return p
+		}
+	}
+	return nil
+}
+
+// Create stores p, assigning its ID and creation time. A Discord ID that is
+// already registered fails with store.ErrPlayerExists.
+func (r *Players) Create(_ context.Context, p *store.Player) error {
+	if err := r.failure("Create"); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.find(func(q *store.Player) bool { return q.DiscordID == p.DiscordID }) != nil {
+		return store.ErrPlayerExists
+	}
+	now := time.Now().UTC()
+	p.ID = playerID(p.DiscordID)
+	p.CreatedAt, p.UpdatedAt = now, now
+	stored := *p
+	r.players = append(r.players, &stored)
+	return nil
+}
+
+func (r *Players) GetByDiscordID(_ context.Context, discordID string) (*store.Player, error) {
+	return r.get("GetByDiscordID", func(p *store.Player) bool { return p.DiscordID == discordID })
+}
+
+func (r *Players) GetByCharacterName(_ context.Context, name string) (*store.Player, error) {
+	return r.get("GetByCharacterName", func(p *store.Player) bool { return p.CharacterName == name })
+}
+
+func (r *Players) get(method string, match func(*store.Player) bool) (*store.Player, error) {
+	if err := r.failure(method); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := r.find(match)
+	if p == nil {
+		return nil, store.ErrPlayerNotFound
+	}
+	found := *p
+	return &found, nil
+}
+
+func (r *Players) List(context.Context) ([]store.Player, error) {
+	if err := r.failure("List"); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	players := make([]store.Player, len(r.players))
+	for i, p := range r.players {
+		players[i] = *p
+	}
+	return players, nil
+}
+
+func (r *Players) UpdateDKP(_ context.Context, id string, delta int) error {
+	return r.update("UpdateDKP", id, func(p *store.Player) error {
+		if p.Archived() {
+			return store.ErrPlayerArchived
+		}
+		p.DKP += delta
+		return nil
+	})
+}
+
+func (r *Players) UpdateProfile(_ context.Context, id string, profile store.Profile) error {
+	return r.update("UpdateProfile", id, func(p *store.Player) error {
+		p.Profile = profile
+		return nil
+	})
+}
+
+func (r *Players) SetArchived(_ context.Context, id string, archived bool) error {
+	return r.update("SetArchived", id, func(p *store.Player) error {
+		p.ArchivedAt = nil
+		if archived {
+			now := time.Now().UTC()
+			p.ArchivedAt = &now
+		}
+		return nil
+	})
+}
+
+// update applies change to the player id.
+func (r *Players) update(method, id string, change func(*store.Player) error) error {
+	if err := r.failure(method); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := r.find(func(p *store.Player) bool { return p.ID == id })
+	if p == nil {
+		return fmt.Errorf("%s %s: %w", method, id, store.ErrPlayerNotFound)
+	}
+	if err := change(p); err != nil {
+		return err
+	}
+	p.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// Balances is an in-memory store.BalanceRepository over the players of a
+// Players, which it checks exist and are not archived. It is safe for
+// concurrent use.
+type Balances struct {
+	failures
+
+	players *Players
+
+	mu       sync.Mutex
+	balances map[[2]string]int
+}
+
+var _ store.BalanceRepository = (*Balances)(nil)
+
+// NewBalances returns a Balances of the players of players, who hold
+// nothing yet.
+func NewBalances(players *Players) *Balances {
+	return &Balances{players: players, balances: make(map[[2]string]int)}
+}
+
+// RequireBalance fails t unless the player registered as discordID holds
+// want of currency.
+func (r *Balances) RequireBalance(t testing.TB, discordID, currency string, want int) {
+	t.Helper()
+	id := r.players.Player(t, discordID).ID
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if got := r.balances[[2]string{id, currency}]; got != want {
+		t.Errorf("%s of %s = %d, want %d", currency, discordID, got, want)
+	}
+}
+
+func (r *Balances) Get(_ context.Context, playerID, currency string) (int, error) {
+	if err := r.failure("Get"); err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.balances[[2]string{playerID, currency}], nil
+}
+
+func (r *Balances) List(_ context.Context, currency string) ([]store.Balance, error) {
+	return r.list("List", func(key [2]string) bool { return key[1] == currency }, func(a, b store.Balance) int {
+		if a.Balance != b.Balance {
+			return b.Balance - a.Balance
+		}
+		return strings.Compare(a.PlayerID, b.PlayerID)
+	})
+}
+
+func (r *Balances) ListByPlayer(_ context.Context, playerID string) ([]store.Balance, error) {
+	return r.list("ListByPlayer", func(key [2]string) bool { return key[0] == playerID }, func(a, b store.Balance) int {
+		return strings.Compare(a.Currency, b.Currency)
+	})
+}
+
+func (r *Balances) list(method string, match func([2]string) bool, cmp func(a, b store.Balance) int) ([]store.Balance, error) {
+	if err := r.failure(method); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var balances []store.Balance
+	for key, balance := range r.balances {
+		if match(key) {
+			balances = append(balances, store.Balance{PlayerID: key[0], Currency: key[1], Balance: balance})
+		}
+	}
+	slices.SortFunc(balances, cmp)
+	return balances, nil
+}
+
+func (r *Balances) Update(_ context.Context, id, currency string, delta int) error {
+	if err := r.failure("Update"); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.active(id); err != nil {
+		return err
+	}
+	r.balances[[2]string{id, currency}] += delta
+	return nil
+}
+
+func (r *Balances) Transfer(_ context.Context, fromID, toID, currency string, amount int) error {
+	if err := r.failure("Transfer"); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range []string{fromID, toID} {
+		if err := r.active(id); err != nil {
+			return err
+		}
+	}
+	r.balances[[2]string{fromID, currency}] -= amount
+	r.balances[[2]string{toID, currency}] += amount
+	return nil
+}
+
+// active returns an error unless the player id is stored and not
+// archived.
+func (r *Balances) active(id string) error {
+	r.players.mu.Lock()
+	defer r.players.mu.Unlock()
+	p := r.players.find(func(p *store.Player) bool { return p.ID == id })
+	switch {
+	case p == nil:
+		return fmt.Errorf("updating balance of %s: %w", id, store.ErrPlayerNotFound)
+	case p.Archived():
+		return store.ErrPlayerArchived
+	}
+	return nil
+}
+
+// Auctions is an in-memory store.AuctionRepository. It is safe for
+// concurrent use.
+type Auctions struct {
+	failures
+
+	mu       sync.Mutex
+	auctions map[string]store.Auction
+	nextID   int
+}
+
+var _ store.AuctionRepository = (*Auctions)(nil)
+
+// NewAuctions returns an Auctions holding auctions, as saved by Save.
+func NewAuctions(auctions ...store.Auction) *Auctions {
+	r := &Auctions{auctions: make(map[string]store.Auction)}
+	for _, a := range auctions {
+		r.auctions[a.ID] = a
+	}
+	return r
+}
+
+// Auction returns the auction id, failing t if there is none.
+func (r *Auctions) Auction(t testing.TB, id string) store.Auction {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.auctions[id]
+	if !ok {
+		t.Fatalf("auction %s is not stored", id)
+	}
+	return a
+}
+
+// Create stores a as open, assigning its ID and creation time.
+func (r *Auctions) Create(_ context.Context, a *store.Auction) error {
+	if err := r.failure("Create"); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	a.ID = fmt.Sprintf("auction-%d", r.nextID)
+	a.Status = "open"
+	a.CreatedAt = time.Now().UTC()
+	r.auctions[a.ID] = *a
+	return nil
+}
+
+func (r *Auctions) GetByID(_ context.Context, id string) (*store.Auction, error) {
+	if err := r.failure("GetByID"); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.auctions[id]
+	if !ok {
+		return nil, store.ErrAuctionNotFound
+	}
+	return &a, nil
+}
+
+func (r *Auctions) Close(_ context.Context, id string, winnerID string, amount int) error {
+	return r.finish("Close", id, func(a *store.Auction) {
+		a.Status = "closed"
+		a.WinnerID = &winnerID
+		a.WinAmount = &amount
+	})
+}
+
+func (r *Auctions) Cancel(_ context.Context, id string) error {
+	return r.finish("Cancel", id, func(a *store.Auction) { a.Status = "canceled" })
+}
+
+// finish applies change to the open auction id and records when it ended.
+func (r *Auctions) finish(method, id string, change func(*store.Auction)) error {
+	if err := r.failure(method); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.auctions[id]
+	if !ok || a.Status != "open" {
+		return store.ErrAuctionNotOpen.Wrap(fmt.Errorf("auction %s", id))
+	}
+	change(&a)
+	now := time.Now().UTC()
+	a.ClosedAt = &now
+	r.auctions[id] = a
+	return nil
+}
+
+func (r *Auctions) ListOpen(context.Context) ([]store.Auction, error) {
+	if err := r.failure("ListOpen"); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var open []store.Auction
+	for _, a := range r.auctions {
+		if a.Status != "closed" && a.Status != "canceled" {
+			open = append(open, a)
+		}
+	}
+	slices.SortFunc(open, func(a, b store.Auction) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return open, nil
+}
+
+func (r *Auctions) Save(_ context.Context, a *store.Auction) error {
+	if err := r.failure("Save"); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.auctions[a.ID] = *a
+	return nil
+}
+
+// GuildSettings is an in-memory store.GuildSettingsRepository. It is safe
+// for concurrent use.
+type GuildSettings struct {
+	failures
+
+	mu       sync.Mutex
+	settings map[[2]string]store.GuildSetting
+}
+
+var _ store.GuildSettingsRepository = (*GuildSettings)(nil)
+
+// NewGuildSettings returns a GuildSettings holding settings.
+func NewGuildSettings(settings ...store.GuildSetting) *GuildSettings {
+	r := &GuildSettings{settings: make(map[[2]string]store.GuildSetting)}
+	for _, s := range settings {
+		r.settings[[2]string{s.GuildID, s.Key}] = s
+	}
+	return r
+}
+
+func (r *GuildSettings) List(_ context.Context, guildID string) ([]store.GuildSetting, error) {
+	if err := r.failure("List"); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var settings []store.GuildSetting
+	for k, s := range r.settings {
+		if k[0] == guildID {
+			settings = append(settings, s)
+		}
+	}
+	slices.SortFunc(settings, func(a, b store.GuildSetting) int { return strings.Compare(a.Key, b.Key) })
+	return settings, nil
+}
+
+func (r *GuildSettings) Set(_ context.Context, s *store.GuildSetting) error {
+	if err := r.failure("Set"); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s.UpdatedAt = time.Now().UTC()
+	r.settings[[2]string{s.GuildID, s.Key}] = *s
+	return nil
+}
+
+func (r *GuildSettings) Delete(_ context.Context, guildID, key string) error {
+	if err := r.failure("Delete"); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.settings, [2]string{guildID, key})
+	return nil
+}
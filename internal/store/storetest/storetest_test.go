@@ -0,0 +1,112 @@
+package storetest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+)
+
+func TestPlayers(t *testing.T) {
+	repo := storetest.NewPlayers(store.Player{DiscordID: "d1", CharacterName: "Frodo", DKP: 10})
+	ctx := context.Background()
+
+	p, err := repo.GetByDiscordID(ctx, "d1")
+	if err != nil {
+		t.Fatalf("GetByDiscordID() error = %v", err)
+	}
+	if p.ID != "player-d1" {
+		t.Errorf("ID = %q, want player-d1", p.ID)
+	}
+	// Players returned are copies.
+	p.DKP = 1000
+	repo.RequireDKP(t, "d1", 10)
+
+	if err := repo.Create(ctx, &store.Player{DiscordID: "d1"}); !errors.Is(err, store.ErrPlayerExists) {
+		t.Errorf("Create() of a registered player error = %v, want ErrPlayerExists", err)
+	}
+	if _, err := repo.GetByCharacterName(ctx, "Sam"); !errors.Is(err, store.ErrPlayerNotFound) {
+		t.Errorf("GetByCharacterName() error = %v, want ErrPlayerNotFound", err)
+	}
+	if err := repo.UpdateDKP(ctx, "unknown", 5); !errors.Is(err, store.ErrPlayerNotFound) {
+		t.Errorf("UpdateDKP() of unknown player error = %v, want ErrPlayerNotFound", err)
+	}
+
+	_ = repo.SetArchived(ctx, p.ID, true)
+	if err := repo.UpdateDKP(ctx, p.ID, 5); !errors.Is(err, store.ErrPlayerArchived) {
+		t.Errorf("UpdateDKP() of archived player error = %v, want ErrPlayerArchived", err)
+	}
+}
+
+func TestPlayers_Fail(t *testing.T) {
+	repo := storetest.NewPlayers(store.Player{DiscordID: "d1"})
+	ctx := context.Background()
+	errDown := errors.New("database down")
+
+	repo.Fail("List", errDown)
+	if _, err := repo.List(ctx); !errors.Is(err, errDown) {
+		t.Errorf("List() error = %v, want the injected error", err)
+	}
+	if _, err := repo.GetByDiscordID(ctx, "d1"); err != nil {
+		t.Errorf("GetByDiscordID() error = %v, want only List to fail", err)
+	}
+
+	repo.Fail("", errDown)
+	if err := repo.UpdateDKP(ctx, "player-d1", 1); !errors.Is(err, errDown) {
+		t.Errorf("UpdateDKP() error = %v, want every method to fail", err)
+	}
+
+	repo.Fail("", nil)
+	repo.Fail("List", nil)
+	if _, err := repo.List(ctx); err != nil {
+		t.Errorf("List() error = %v after the failures were removed", err)
+	}
+}
+
+func TestBalances(t *testing.T) {
+	players := storetest.NewPlayers(store.Player{DiscordID: "d1"}, store.Player{DiscordID: "d2"})
+	repo := storetest.NewBalances(players)
+	ctx := context.Background()
+
+	if err := repo.Update(ctx, "player-d1", "EP", 30); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := repo.Transfer(ctx, "player-d1", "player-d2", "EP", 10); err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+	repo.RequireBalance(t, "d1", "EP", 20)
+	repo.RequireBalance(t, "d2", "EP", 10)
+	if list, _ := repo.List(ctx, "EP"); len(list) != 2 || list[0].PlayerID != "player-d1" {
+		t.Errorf("List() = %+v, want d1 first", list)
+	}
+
+	if err := repo.Update(ctx, "unknown", "EP", 5); !errors.Is(err, store.ErrPlayerNotFound) {
+		t.Errorf("Update() of unknown player error = %v, want ErrPlayerNotFound", err)
+	}
+	_ = players.SetArchived(ctx, "player-d2", true)
+	if err := repo.Transfer(ctx, "player-d1", "player-d2", "EP", 5); !errors.Is(err, store.ErrPlayerArchived) {
+		t.Errorf("Transfer() to archived player error = %v, want ErrPlayerArchived", err)
+	}
+	repo.RequireBalance(t, "d1", "EP", 20)
+}
+
+func TestGuildSettings(t *testing.T) {
+	repo := storetest.NewGuildSettings(store.GuildSetting{GuildID: "g1", Key: "officer_channel", Value: "c1"})
+	ctx := context.Background()
+
+	if err := repo.Set(ctx, &store.GuildSetting{GuildID: "g1", Key: "leaderboard_channel", Value: "c2"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	_ = repo.Set(ctx, &store.GuildSetting{GuildID: "g2", Key: "officer_channel", Value: "c3"})
+	list, err := repo.List(ctx, "g1")
+	if err != nil || len(list) != 2 || list[0].Key != "leaderboard_channel" {
+		t.Fatalf("List() = %+v, %v, want both settings of g1 by key", list, err)
+	}
+
+	_ = repo.Delete(ctx, "g1", "officer_channel")
+	if list, _ := repo.List(ctx, "g1"); len(list) != 1 {
+		t.Errorf("List() after Delete = %+v, want one setting", list)
+	}
+}
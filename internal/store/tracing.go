@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+)
+
+const storePackage = "github.com/jensholdgaard/discord-dkp-bot/internal/store"
+
+var tracer = otel.Tracer(storePackage)
+
+// TraceOptions returns the otelsql options for a connection to the database
+// of cfg. Unless cfg.TraceQueries is false, spans carry the statement with
+// its literals removed and the repository method that ran it, and the
+// connections of Connector add the rows a statement affected or returned.
+// Arguments, such as bid amounts and tokens, are never recorded.
+func TraceOptions(cfg config.DatabaseConfig) []otelsql.Option {
+	opts := []otelsql.Option{otelsql.WithAttributes(semconv.DBSystemPostgreSQL)}
+	if !cfg.TraceQueries {
+		return append(opts, otelsql.WithSpanOptions(otelsql.SpanOptions{DisableQuery: true}))
+	}
+	return append(opts,
+		// The statement is recorded sanitized by queryAttributes instead,
+		// and the rows span by countedRows.
+		otelsql.WithSpanOptions(otelsql.SpanOptions{DisableQuery: true, OmitRows: true}),
+		otelsql.WithAttributesGetter(queryAttributes),
+	)
+}
+
+func queryAttributes(_ context.Context, _ otelsql.Method, query string, _ []driver.NamedValue) []attribute.KeyValue {
+	if query == "" {
+		return nil
+	}
+	attrs := []attribute.KeyValue{semconv.DBQueryText(SanitizeQuery(query))}
+	if fn := repositoryMethod(); fn != "" {
+		attrs = append(attrs, semconv.CodeFunction(fn))
+	}
+	return attrs
+}
+
+// repositoryMethod returns the innermost function of the store packages on
+// the stack, such as "postgres.(*PlayerRepo).GetByDiscordID". It is called
+// from queryAttributes while otelsql starts a span, so the frames of
+// runtime.Callers, repositoryMethod, and queryAttributes are skipped.
+func repositoryMethod() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		f, more := frames.Next()
+		if strings.HasPrefix(f.Function, storePackage+".") || strings.HasPrefix(f.Function, storePackage+"/") {
+			return f.Function[strings.LastIndex(f.Function, "/")+1:]
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+var (
+	stringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numberLiteral = regexp.MustCompile(`([^\w$.])\d+(?:\.\d+)?\b`)
+	whitespace    = regexp.MustCompile(`\s+`)
+	extraRows     = regexp.MustCompile(`(?i)(VALUES \([^()]*\))(?:, \([^()]*\))+`)
+)
+
+// SanitizeQuery returns query with its whitespace collapsed, string and
+// number literals replaced by ?, and the rows of a multi-row VALUES list
+// after the first elided, so that a span neither leaks values written into
+// the statement nor carries thousands of placeholders.
+func SanitizeQuery(query string) string {
+	query = stringLiteral.ReplaceAllString(query, "?")
+	query = strings.TrimSpace(whitespace.ReplaceAllString(query, " "))
+	query = numberLiteral.ReplaceAllString(query, "${1}?")
+	return extraRows.ReplaceAllString(query, "${1}, ...")
+}
+
+// pqConn is the set of driver interfaces a lib/pq connection implements.
+type pqConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// tracedConn records on the spans of otelsql how many rows a statement
+// affected, and traces how many rows a query returned. Prepared statements
+// are passed through untouched.
+type tracedConn struct {
+	pqConn
+}
+
+func (c tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.pqConn.ExecContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("db.response.affected_rows", n))
+	}
+	return res, nil
+}
+
+func (c tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.pqConn.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := rows.(pqRows)
+	if !ok {
+		return rows, nil
+	}
+	_, span := tracer.Start(ctx, string(otelsql.MethodRows), trace.WithSpanKind(trace.SpanKindClient))
+	return &countedRows{pqRows: r, span: span}, nil
+}
+
+// pqRows is the set of driver interfaces a lib/pq result set implements.
+type pqRows interface {
+	driver.Rows
+	driver.RowsNextResultSet
+	driver.RowsColumnTypeScanType
+	driver.RowsColumnTypeDatabaseTypeName
+	driver.RowsColumnTypeLength
+	driver.RowsColumnTypePrecisionScale
+}
+
+// countedRows counts the rows read from a result set and records them on
+// its span when it is closed.
+type countedRows struct {
+	pqRows
+	span trace.Span
+	n    int64
+}
+
+func (r *countedRows) Next(dest []driver.Value) error {
+	err := r.pqRows.Next(dest)
+	switch {
+	case err == nil:
+		r.n++
+	case !errors.Is(err, io.EOF):
+		r.span.RecordError(err)
+		r.span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+func (r *countedRows) Close() error {
+	err := r.pqRows.Close()
+	if err != nil {
+		r.span.RecordError(err)
+		r.span.SetStatus(codes.Error, err.Error())
+	}
+	r.span.SetAttributes(attribute.Int64("db.response.returned_rows", r.n))
+	r.span.End()
+	return err
+}
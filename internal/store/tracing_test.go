@@ -0,0 +1,67 @@
+package store_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+func TestSanitizeQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "placeholders kept",
+			query: "SELECT id FROM players WHERE discord_id = $1 AND dkp >= $12",
+			want:  "SELECT id FROM players WHERE discord_id = $1 AND dkp >= $12",
+		},
+		{
+			name:  "whitespace collapsed",
+			query: "SELECT id\n\t\t FROM players\n\t\t WHERE id = $1",
+			want:  "SELECT id FROM players WHERE id = $1",
+		},
+		{
+			name:  "string literals replaced",
+			query: "UPDATE auctions SET status = 'closed', note = 'it''s 500' WHERE id = $1",
+			want:  "UPDATE auctions SET status = ?, note = ? WHERE id = $1",
+		},
+		{
+			name:  "number literals replaced",
+			query: "UPDATE players SET dkp = dkp + 250, ratio = 0.5 WHERE id = $1 LIMIT 1",
+			want:  "UPDATE players SET dkp = dkp + ?, ratio = ? WHERE id = $1 LIMIT ?",
+		},
+		{
+			name:  "digits in identifiers kept",
+			query: "SELECT sha256(data) FROM events_v2",
+			want:  "SELECT sha256(data) FROM events_v2",
+		},
+		{
+			name:  "single row values kept",
+			query: "INSERT INTO notes (player_id, body) VALUES ($1, $2)",
+			want:  "INSERT INTO notes (player_id, body) VALUES ($1, $2)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := store.SanitizeQuery(tt.query); got != tt.want {
+				t.Errorf("SanitizeQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeQuery_MultiRowInsert(t *testing.T) {
+	query, _ := store.InsertEventsQuery(make([]event.Event, 1000))
+
+	got := store.SanitizeQuery(query)
+
+	want := "VALUES ($1, $2, $3, $4, $5, $6, $7, $8), ... RETURNING aggregate_id, version, id"
+	if !strings.HasSuffix(got, want) {
+		t.Errorf("SanitizeQuery() = %q, want it to end in %q", got, want)
+	}
+}
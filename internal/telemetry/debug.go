@@ -0,0 +1,41 @@
+package telemetry
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/http/pprof"
+
+	"go.opentelemetry.io/contrib/zpages"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+)
+
+// newDebugHandler serves the runtime profiles under /debug/pprof/ and the
+// live and recently ended spans recorded by sp under /debug/tracez, behind
+// basic auth.
+func newDebugHandler(cfg config.DebugConfig, sp *zpages.SpanProcessor) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/tracez", zpages.NewTracezHandler(sp))
+	return basicAuth(cfg.Username, cfg.Password, mux)
+}
+
+func basicAuth(username, password string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		// Compare both even if the first fails, so timing does not reveal
+		// which one was wrong.
+		userOK := subtle.ConstantTimeCompare([]byte(u), []byte(username)) == 1
+		passOK := subtle.ConstantTimeCompare([]byte(p), []byte(password)) == 1
+		if !ok || !userOK || !passOK {
+			w.Header().Set("WWW-Authenticate", `Basic realm="dkpbot debug"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,80 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+)
+
+// CommandKey is the span attribute naming the slash command an interaction
+// span belongs to. Per-command sampling overrides match on it.
+const CommandKey = attribute.Key("command")
+
+// NewSampler returns a parent-based sampler that samples new traces at
+// cfg.Ratio, or at the ratio configured for the command named by the root
+// span's CommandKey attribute.
+func NewSampler(cfg config.SamplingConfig) sdktrace.Sampler {
+	s := commandSampler{
+		fallback: sdktrace.TraceIDRatioBased(cfg.Ratio),
+		commands: make(map[string]sdktrace.Sampler, len(cfg.Commands)),
+	}
+	for name, ratio := range cfg.Commands {
+		s.commands[name] = sdktrace.TraceIDRatioBased(ratio)
+	}
+	return sdktrace.ParentBased(s)
+}
+
+type commandSampler struct {
+	fallback sdktrace.Sampler
+	commands map[string]sdktrace.Sampler
+}
+
+func (s commandSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if len(s.commands) > 0 {
+		for _, kv := range p.Attributes {
+			if kv.Key != CommandKey {
+				continue
+			}
+			if cs, ok := s.commands[kv.Value.AsString()]; ok {
+				return cs.ShouldSample(p)
+			}
+			break
+		}
+	}
+	return s.fallback.ShouldSample(p)
+}
+
+func (s commandSampler) Description() string {
+	names := make([]string, 0, len(s.commands))
+	for name, cs := range s.commands {
+		names = append(names, name+"="+cs.Description())
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("CommandSampler{%s,commands:[%s]}", s.fallback.Description(), strings.Join(names, ","))
+}
+
+// levelHandler drops records below a minimum level before they reach the
+// wrapped handler.
+type levelHandler struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+func (h levelHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return l >= h.level.Level() && h.Handler.Enabled(ctx, l)
+}
+
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	return levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
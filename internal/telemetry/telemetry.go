@@ -4,13 +4,19 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"os"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/contrib/zpages"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
 	"go.opentelemetry.io/otel/propagation"
 	sdklog "go.opentelemetry.io/otel/sdk/log"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
@@ -28,9 +34,17 @@ type Provider struct {
 	MeterProvider  *sdkmetric.MeterProvider
 	LoggerProvider *sdklog.LoggerProvider
 	Logger         *slog.Logger
+	// MetricsHandler serves the Prometheus exposition format. It is nil
+	// unless Prometheus is enabled.
+	MetricsHandler http.Handler
+	// DebugHandler serves /debug/pprof/ and /debug/tracez. It is nil
+	// unless the debug endpoints are enabled.
+	DebugHandler http.Handler
 }
 
-// Setup initializes OpenTelemetry traces, metrics and logs.
+// Setup initializes OpenTelemetry traces, metrics and logs. With the stderr
+// exporter logs are written to stderr as text, and nothing is sent to a
+// collector.
 func Setup(ctx context.Context, cfg config.TelemetryConfig) (*Provider, error) {
 	res, err := resource.New(ctx,
 		resource.WithAttributes(
@@ -42,58 +56,96 @@ func Setup(ctx context.Context, cfg config.TelemetryConfig) (*Provider, error) {
 		return nil, fmt.Errorf("creating resource: %w", err)
 	}
 
-	traceOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.OTLPEndpoint)}
-	if cfg.Insecure {
-		traceOpts = append(traceOpts, otlptracehttp.WithInsecure())
+	otlp := cfg.Exporter != config.TelemetryExporterStderr
+
+	tpOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(NewSampler(cfg.Sampling)),
 	}
-	traceExp, err := otlptracehttp.New(ctx, traceOpts...)
-	if err != nil {
-		return nil, fmt.Errorf("creating trace exporter: %w", err)
+	if otlp {
+		traceOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.OTLPEndpoint)}
+		if cfg.Insecure {
+			traceOpts = append(traceOpts, otlptracehttp.WithInsecure())
+		}
+		traceExp, err := otlptracehttp.New(ctx, traceOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("creating trace exporter: %w", err)
+		}
+		tpOpts = append(tpOpts, sdktrace.WithBatcher(traceExp))
 	}
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(traceExp),
-		sdktrace.WithResource(res),
-	)
+	var debugHandler http.Handler
+	if cfg.Debug.Enabled {
+		// The zpages processor keeps recent spans in memory for
+		// /debug/tracez, independently of what is exported.
+		sp := zpages.NewSpanProcessor()
+		tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(sp))
+		debugHandler = newDebugHandler(cfg.Debug, sp)
+	}
+	tp := sdktrace.NewTracerProvider(tpOpts...)
 	otel.SetTracerProvider(tp)
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
 		propagation.TraceContext{},
 		propagation.Baggage{},
 	))
 
-	metricOpts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(cfg.OTLPEndpoint)}
-	if cfg.Insecure {
-		metricOpts = append(metricOpts, otlpmetrichttp.WithInsecure())
+	mpOpts := []sdkmetric.Option{sdkmetric.WithResource(res)}
+	if otlp {
+		metricOpts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(cfg.OTLPEndpoint)}
+		if cfg.Insecure {
+			metricOpts = append(metricOpts, otlpmetrichttp.WithInsecure())
+		}
+		metricExp, err := otlpmetrichttp.New(ctx, metricOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("creating metric exporter: %w", err)
+		}
+		var readerOpts []sdkmetric.PeriodicReaderOption
+		if cfg.MetricInterval > 0 {
+			readerOpts = append(readerOpts, sdkmetric.WithInterval(cfg.MetricInterval))
+		}
+		mpOpts = append(mpOpts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExp, readerOpts...)))
 	}
-	metricExp, err := otlpmetrichttp.New(ctx, metricOpts...)
-	if err != nil {
-		return nil, fmt.Errorf("creating metric exporter: %w", err)
+	var metricsHandler http.Handler
+	if cfg.Prometheus.Enabled {
+		// The Prometheus exporter is a pull-based reader on the same
+		// MeterProvider, so it exposes exactly the meters sent over OTLP.
+		registry := prometheus.NewRegistry()
+		promExp, err := otelprom.New(otelprom.WithRegisterer(registry))
+		if err != nil {
+			return nil, fmt.Errorf("creating prometheus exporter: %w", err)
+		}
+		mpOpts = append(mpOpts, sdkmetric.WithReader(promExp))
+		metricsHandler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
 	}
-	mp := sdkmetric.NewMeterProvider(
-		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExp)),
-		sdkmetric.WithResource(res),
-	)
+	mp := sdkmetric.NewMeterProvider(mpOpts...)
 	otel.SetMeterProvider(mp)
 
-	logOpts := []otlploghttp.Option{otlploghttp.WithEndpoint(cfg.OTLPEndpoint)}
-	if cfg.Insecure {
-		logOpts = append(logOpts, otlploghttp.WithInsecure())
+	lpOpts := []sdklog.LoggerProviderOption{sdklog.WithResource(res)}
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: cfg.SlogLevel()})
+	if otlp {
+		logOpts := []otlploghttp.Option{otlploghttp.WithEndpoint(cfg.OTLPEndpoint)}
+		if cfg.Insecure {
+			logOpts = append(logOpts, otlploghttp.WithInsecure())
+		}
+		logExp, err := otlploghttp.New(ctx, logOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("creating log exporter: %w", err)
+		}
+		lpOpts = append(lpOpts, sdklog.WithProcessor(sdklog.NewBatchProcessor(logExp)))
 	}
-	logExp, err := otlploghttp.New(ctx, logOpts...)
-	if err != nil {
-		return nil, fmt.Errorf("creating log exporter: %w", err)
+	lp := sdklog.NewLoggerProvider(lpOpts...)
+	if otlp {
+		handler = otelslog.NewHandler(cfg.ServiceName, otelslog.WithLoggerProvider(lp))
 	}
-	lp := sdklog.NewLoggerProvider(
-		sdklog.WithProcessor(sdklog.NewBatchProcessor(logExp)),
-		sdklog.WithResource(res),
-	)
 
-	logger := otelslog.NewLogger(cfg.ServiceName, otelslog.WithLoggerProvider(lp))
+	logger := slog.New(levelHandler{Handler: handler, level: cfg.SlogLevel()})
 
 	return &Provider{
 		TracerProvider: tp,
 		MeterProvider:  mp,
 		LoggerProvider: lp,
 		Logger:         logger,
+		MetricsHandler: metricsHandler,
+		DebugHandler:   debugHandler,
 	}, nil
 }
 
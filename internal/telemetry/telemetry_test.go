@@ -3,10 +3,16 @@ package telemetry_test
 import (
 	"context"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
 	"github.com/jensholdgaard/discord-dkp-bot/internal/telemetry"
 )
 
@@ -62,3 +68,144 @@ func TestLogWithTrace_WithSpan(t *testing.T) {
 	sc := trace.SpanFromContext(ctx).SpanContext()
 	_ = sc // validates no panic
 }
+
+func TestSetup_Prometheus(t *testing.T) {
+	tests := []struct {
+		name        string
+		enabled     bool
+		wantHandler bool
+	}{
+		{name: "disabled", enabled: false, wantHandler: false},
+		{name: "enabled", enabled: true, wantHandler: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := telemetry.Setup(context.Background(), config.TelemetryConfig{
+				ServiceName:  "dkpbot-test",
+				OTLPEndpoint: "127.0.0.1:1",
+				Insecure:     true,
+				Prometheus:   config.PrometheusConfig{Enabled: tt.enabled},
+			})
+			if err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+			// Nothing listens on the OTLP endpoint, so the final export
+			// during shutdown fails; that is expected here.
+			defer func() { _ = p.Shutdown(context.Background()) }()
+
+			if (p.MetricsHandler != nil) != tt.wantHandler {
+				t.Fatalf("MetricsHandler set = %v, want %v", p.MetricsHandler != nil, tt.wantHandler)
+			}
+			if !tt.wantHandler {
+				return
+			}
+
+			counter, _ := p.MeterProvider.Meter("test").Int64Counter("dkpbot.test.events")
+			counter.Add(context.Background(), 3)
+
+			rec := httptest.NewRecorder()
+			p.MetricsHandler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("got status %d", rec.Code)
+			}
+			if body := rec.Body.String(); !strings.Contains(body, "dkpbot_test_events_total{") || !strings.Contains(body, "} 3\n") {
+				t.Errorf("metrics output missing counter:\n%s", body)
+			}
+		})
+	}
+}
+
+func TestSetup_Stderr(t *testing.T) {
+	p, err := telemetry.Setup(context.Background(), config.TelemetryConfig{
+		ServiceName: "dkpbot-test",
+		Exporter:    config.TelemetryExporterStderr,
+		LogLevel:    "warn",
+	})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	// Nothing is exported, so shutting down has nothing to flush.
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if p.Logger.Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("info logs enabled, want only warnings and errors")
+	}
+}
+
+func TestSetup_Debug(t *testing.T) {
+	p, err := telemetry.Setup(context.Background(), config.TelemetryConfig{
+		ServiceName:  "dkpbot-test",
+		OTLPEndpoint: "127.0.0.1:1",
+		Insecure:     true,
+		Sampling:     config.SamplingConfig{Ratio: 1},
+		Debug:        config.DebugConfig{Enabled: true, Username: "ops", Password: "hunter2"},
+	})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	defer func() { _ = p.Shutdown(context.Background()) }()
+
+	_, span := p.TracerProvider.Tracer("test").Start(context.Background(), "raid-night")
+	span.End()
+
+	tests := []struct {
+		name     string
+		path     string
+		user     string
+		pass     string
+		wantCode int
+		wantBody string
+	}{
+		{name: "no credentials", path: "/debug/pprof/", wantCode: http.StatusUnauthorized},
+		{name: "wrong password", path: "/debug/pprof/", user: "ops", pass: "nope", wantCode: http.StatusUnauthorized},
+		{name: "pprof index", path: "/debug/pprof/", user: "ops", pass: "hunter2", wantCode: http.StatusOK, wantBody: "goroutine"},
+		{name: "tracez lists spans", path: "/debug/tracez", user: "ops", pass: "hunter2", wantCode: http.StatusOK, wantBody: "raid-night"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, tt.pass)
+			}
+			rec := httptest.NewRecorder()
+			p.DebugHandler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.wantCode)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body missing %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestNewSampler(t *testing.T) {
+	sampler := telemetry.NewSampler(config.SamplingConfig{
+		Ratio:    1,
+		Commands: map[string]float64{"dkp": 0},
+	})
+
+	tests := []struct {
+		name    string
+		command string
+		want    sdktrace.SamplingDecision
+	}{
+		{name: "default ratio", command: "bid", want: sdktrace.RecordAndSample},
+		{name: "command override", command: "dkp", want: sdktrace.Drop},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sampler.ShouldSample(sdktrace.SamplingParameters{
+				ParentContext: context.Background(),
+				TraceID:       trace.TraceID{1},
+				Name:          "InteractionCreate",
+				Attributes:    []attribute.KeyValue{telemetry.CommandKey.String(tt.command)},
+			})
+			if got.Decision != tt.want {
+				t.Errorf("ShouldSample() decision = %v, want %v", got.Decision, tt.want)
+			}
+		})
+	}
+}
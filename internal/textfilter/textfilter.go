@@ -0,0 +1,87 @@
+// Package textfilter refuses free text, such as reasons, item names, and
+// notes, that is too long or contains words a guild does not want recorded
+// or posted. Words are matched whole and regardless of case, also when
+// written with digits or symbols for letters, such as "n00b" for "noob".
+package textfilter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+)
+
+// Errors returned by Check.
+var (
+	ErrTooLong = derrors.New(derrors.Validation, "TEXT_TOO_LONG", "the text is too long")
+	ErrBlocked = derrors.New(derrors.Validation, "TEXT_BLOCKED", "the text contains words that are not allowed")
+)
+
+// leet maps the digits and symbols written for letters to the letters.
+var leet = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '@': 'a', '$': 's',
+}
+
+// Filter checks free text against the word list and length limit of a
+// TextFilterConfig.
+type Filter struct {
+	// phrases are the words refused, each split into its words.
+	phrases   [][]string
+	maxLength int
+}
+
+// New returns a Filter applying cfg.
+func New(cfg config.TextFilterConfig) *Filter {
+	f := &Filter{maxLength: cfg.MaxLength}
+	for _, w := range cfg.Words {
+		if words := split(w); len(words) > 0 {
+			f.phrases = append(f.phrases, words)
+		}
+	}
+	return f
+}
+
+// Check returns ErrTooLong if text is longer than the limit, or ErrBlocked
+// if it contains a refused word or phrase. The errors tell which, for the
+// officers; the messages shown to members do not repeat the word.
+func (f *Filter) Check(text string) error {
+	if n := utf8.RuneCountInString(text); f.maxLength > 0 && n > f.maxLength {
+		return ErrTooLong.Wrap(fmt.Errorf("%d characters, at most %d allowed", n, f.maxLength))
+	}
+	words := split(text)
+	for _, phrase := range f.phrases {
+		for i := 0; i+len(phrase) <= len(words); i++ {
+			if slices.Equal(words[i:i+len(phrase)], phrase) {
+				return ErrBlocked.Wrap(fmt.Errorf("contains %q", strings.Join(phrase, " ")))
+			}
+		}
+	}
+	return nil
+}
+
+// split returns the words of text, lowercased, separated by anything but
+// letters, digits, and the symbols of leet. In words with letters, digits
+// and symbols are replaced by the letters they are written for; numbers
+// are left alone.
+func split(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		_, ok := leet[r]
+		return !ok && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		if !strings.ContainsFunc(w, unicode.IsLetter) {
+			continue
+		}
+		words[i] = strings.Map(func(r rune) rune {
+			if l, ok := leet[r]; ok {
+				return l
+			}
+			return r
+		}, w)
+	}
+	return words
+}
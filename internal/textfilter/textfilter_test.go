@@ -0,0 +1,40 @@
+package textfilter_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/textfilter"
+)
+
+func TestFilter_Check(t *testing.T) {
+	f := textfilter.New(config.TextFilterConfig{Words: []string{"noob", "Ninja Looter"}, MaxLength: 40})
+
+	for _, tc := range []struct {
+		text string
+		want error
+	}{
+		{"Molten Core full clear", nil},
+		{"Noobish mistakes happen", nil},
+		{"Bonus for 100% attendance", nil},
+		{"what a NOOB", textfilter.ErrBlocked},
+		{"n00b!", textfilter.ErrBlocked},
+		{"typical ninja-looter behaviour", textfilter.ErrBlocked},
+		{"ninja loot", nil},
+		{strings.Repeat("a", 41), textfilter.ErrTooLong},
+		{strings.Repeat("ä", 40), nil},
+	} {
+		if err := f.Check(tc.text); !errors.Is(err, tc.want) {
+			t.Errorf("Check(%q) = %v, want %v", tc.text, err, tc.want)
+		}
+	}
+}
+
+func TestFilter_CheckDisabled(t *testing.T) {
+	f := textfilter.New(config.TextFilterConfig{})
+	if err := f.Check(strings.Repeat("noob ", 1000)); err != nil {
+		t.Errorf("Check() without words or limit = %v, want nil", err)
+	}
+}
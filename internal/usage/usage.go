@@ -0,0 +1,109 @@
+// Package usage counts the commands members use, so that officers can see
+// which features are used and which fail.
+//
+// Every interaction handled is recorded by day, command, and member: the
+// counts survive restarts and are kept by every replica of a warm-standby
+// deployment, which serves read-only commands too.
+package usage
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// Tracker records and reports command usage.
+type Tracker struct {
+	repo    store.UsageRepository
+	metrics *metrics.Recorder
+	logger  *slog.Logger
+	tracer  trace.Tracer
+	clock   clock.Clock
+}
+
+// NewTracker returns a Tracker that stores usage in repo and counts each
+// member's first use of a command in a day on recorder.
+func NewTracker(repo store.UsageRepository, recorder *metrics.Recorder, logger *slog.Logger, tp trace.TracerProvider, clk clock.Clock) *Tracker {
+	return &Tracker{
+		repo:    repo,
+		metrics: recorder,
+		logger:  logger,
+		tracer:  tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/usage"),
+		clock:   clk,
+	}
+}
+
+// Record counts a use of command by userID that failed with err, if not
+// nil. A command does not fail over its statistics: errors are logged.
+func (t *Tracker) Record(ctx context.Context, command, userID string, err error) {
+	ctx, span := t.tracer.Start(ctx, "Tracker.Record",
+		trace.WithAttributes(attribute.String("command", command)),
+	)
+	defer span.End()
+
+	first, rerr := t.repo.Record(ctx, command, userID, err != nil, t.clock.Now())
+	if rerr != nil {
+		t.logger.WarnContext(ctx, "recording command usage failed",
+			slog.String("command", command),
+			slog.Any("error", rerr),
+		)
+		return
+	}
+	if first {
+		t.metrics.CommandUser(ctx, command)
+	}
+}
+
+// Report is the command usage over a number of days.
+type Report struct {
+	// Since is the start of the first day, in UTC.
+	Since time.Time
+	Days  int
+	// Users is the number of distinct members who used any command.
+	Users    int
+	Commands []store.CommandUsage
+}
+
+// Uses returns the total uses of all commands.
+func (r Report) Uses() int {
+	var n int
+	for _, c := range r.Commands {
+		n += c.Uses
+	}
+	return n
+}
+
+// Failures returns the total failed uses of all commands.
+func (r Report) Failures() int {
+	var n int
+	for _, c := range r.Commands {
+		n += c.Failures
+	}
+	return n
+}
+
+// Report returns the usage over the last days days, today included.
+func (t *Tracker) Report(ctx context.Context, days int) (*Report, error) {
+	ctx, span := t.tracer.Start(ctx, "Tracker.Report",
+		trace.WithAttributes(attribute.Int("days", days)),
+	)
+	defer span.End()
+
+	since := t.clock.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	commands, err := t.repo.Summary(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	users, err := t.repo.Users(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	return &Report{Since: since, Days: days, Users: users, Commands: commands}, nil
+}
@@ -0,0 +1,160 @@
+package usage_test
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/clock"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/metrics"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/usage"
+)
+
+type use struct {
+	day           string
+	command, user string
+	failed        bool
+}
+
+// memUsage is an in-memory store.UsageRepository.
+type memUsage struct {
+	uses []use
+	err  error
+}
+
+func (m *memUsage) Record(_ context.Context, command, userID string, failed bool, at time.Time) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	u := use{day: at.UTC().Format(time.DateOnly), command: command, user: userID, failed: failed}
+	first := !slices.ContainsFunc(m.uses, func(v use) bool {
+		return v.day == u.day && v.command == command && v.user == userID
+	})
+	m.uses = append(m.uses, u)
+	return first, nil
+}
+
+func (m *memUsage) Summary(_ context.Context, since time.Time) ([]store.CommandUsage, error) {
+	byCommand := make(map[string]*store.CommandUsage)
+	users := make(map[[2]string]bool)
+	for _, u := range m.since(since) {
+		c, ok := byCommand[u.command]
+		if !ok {
+			c = &store.CommandUsage{Command: u.command}
+			byCommand[u.command] = c
+		}
+		c.Uses++
+		if u.failed {
+			c.Failures++
+		}
+		if !users[[2]string{u.command, u.user}] {
+			users[[2]string{u.command, u.user}] = true
+			c.Users++
+		}
+	}
+	var summary []store.CommandUsage
+	for _, c := range byCommand {
+		summary = append(summary, *c)
+	}
+	slices.SortFunc(summary, func(a, b store.CommandUsage) int {
+		return cmp.Or(b.Uses-a.Uses, cmp.Compare(a.Command, b.Command))
+	})
+	return summary, nil
+}
+
+func (m *memUsage) Users(_ context.Context, since time.Time) (int, error) {
+	users := make(map[string]bool)
+	for _, u := range m.since(since) {
+		users[u.user] = true
+	}
+	return len(users), nil
+}
+
+func (m *memUsage) since(since time.Time) []use {
+	var uses []use
+	for _, u := range m.uses {
+		if u.day >= since.UTC().Format(time.DateOnly) {
+			uses = append(uses, u)
+		}
+	}
+	return uses
+}
+
+func TestTracker(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	recorder, err := metrics.New(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)), "guild")
+	if err != nil {
+		t.Fatalf("metrics.New() error = %v", err)
+	}
+	repo := &memUsage{}
+	now := time.Date(2025, 6, 16, 17, 0, 0, 0, time.UTC)
+	clk := &clock.Mock{T: now.AddDate(0, 0, -10)}
+	tracker := usage.NewTracker(repo, recorder, slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clk)
+	ctx := context.Background()
+
+	// Outside the report's window.
+	tracker.Record(ctx, "dkp", "u1", nil)
+	clk.T = now
+	tracker.Record(ctx, "bid", "u1", nil)
+	tracker.Record(ctx, "bid", "u1", errors.New("too low"))
+	tracker.Record(ctx, "bid", "u2", nil)
+	tracker.Record(ctx, "dkp", "u3", nil)
+
+	report, err := tracker.Report(ctx, 7)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if want := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC); !report.Since.Equal(want) {
+		t.Errorf("Since = %s, want %s", report.Since, want)
+	}
+	if report.Users != 3 || report.Uses() != 4 || report.Failures() != 1 {
+		t.Errorf("report = %d users, %d uses, %d failures, want 3, 4, 1", report.Users, report.Uses(), report.Failures())
+	}
+	want := []store.CommandUsage{
+		{Command: "bid", Uses: 3, Users: 2, Failures: 1},
+		{Command: "dkp", Uses: 1, Users: 1},
+	}
+	if !slices.Equal(report.Commands, want) {
+		t.Errorf("Commands = %+v, want %+v", report.Commands, want)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	var users int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "dkpbot.command.users" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				users += dp.Value
+			}
+		}
+	}
+	// u1 used dkp ten days ago, then bid twice today.
+	if users != 4 {
+		t.Errorf("dkpbot.command.users = %d, want 4", users)
+	}
+}
+
+func TestTracker_RecordFailure(t *testing.T) {
+	repo := &memUsage{err: errors.New("database down")}
+	tracker := usage.NewTracker(repo, metrics.Nop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider(), clock.Mock{T: time.Now()})
+
+	// The failure is logged, not raised.
+	tracker.Record(context.Background(), "bid", "u1", nil)
+	if len(repo.uses) != 0 {
+		t.Errorf("uses = %d, want 0", len(repo.uses))
+	}
+}
@@ -0,0 +1,127 @@
+package wcl
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/idempotency"
+)
+
+// Award is the DKP a registered player earns from a report.
+type Award struct {
+	PlayerID      string
+	CharacterName string
+	// Class and Role are from the player's profile, and may be empty.
+	Class  string
+	Role   string
+	Amount int
+}
+
+// Plan is the set of awards a report would produce.
+type Plan struct {
+	Report *Report
+	Reason string
+	Awards []Award
+	// Unmatched lists report participants with no registered character.
+	Unmatched []string
+}
+
+// Attendance turns log reports into DKP awards.
+type Attendance struct {
+	client *Client
+	dkp    *dkp.Manager
+	cfg    config.WarcraftLogsConfig
+	logger *slog.Logger
+	tracer trace.Tracer
+}
+
+// NewAttendance returns a new Attendance.
+func NewAttendance(client *Client, dkpMgr *dkp.Manager, cfg config.WarcraftLogsConfig, logger *slog.Logger, tp trace.TracerProvider) *Attendance {
+	return &Attendance{
+		client: client,
+		dkp:    dkpMgr,
+		cfg:    cfg,
+		logger: logger,
+		tracer: tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/wcl"),
+	}
+}
+
+// Preview fetches the report at reportURL and matches its participants to
+// registered players by character name, case-insensitively. Each match is
+// awarded the attendance DKP plus the boss kill DKP per kill. Archived
+// players, whose DKP is frozen, are left unmatched.
+func (a *Attendance) Preview(ctx context.Context, reportURL string) (*Plan, error) {
+	ctx, span := a.tracer.Start(ctx, "Attendance.Preview")
+	defer span.End()
+
+	report, err := a.client.FetchReport(ctx, reportURL)
+	if err != nil {
+		return nil, err
+	}
+	players, err := a.dkp.ListPlayers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing players: %w", err)
+	}
+	byName := make(map[string]Award, len(players))
+	for _, p := range players {
+		if p.Archived() {
+			continue
+		}
+		byName[strings.ToLower(p.CharacterName)] = Award{PlayerID: p.ID, CharacterName: p.CharacterName, Class: p.Class, Role: p.Role}
+	}
+
+	amount := a.cfg.AttendanceDKP + a.cfg.BossKillDKP*len(report.Kills)
+	plan := &Plan{
+		Report: report,
+		Reason: fmt.Sprintf("%s: attendance and %d boss kills", report.Title, len(report.Kills)),
+	}
+	for _, name := range report.Players {
+		award, ok := byName[strings.ToLower(name)]
+		if !ok {
+			plan.Unmatched = append(plan.Unmatched, name)
+			continue
+		}
+		award.Amount = amount
+		plan.Awards = append(plan.Awards, award)
+	}
+	span.SetAttributes(
+		attribute.Int("awards", len(plan.Awards)),
+		attribute.Int("unmatched", len(plan.Unmatched)),
+	)
+	return plan, nil
+}
+
+// Apply awards the DKP in plan and returns the number of awards made. Each
+// award carries an idempotency key derived from the report code and player,
+// so when the DKP manager deduplicates, a report is only ever awarded once.
+func (a *Attendance) Apply(ctx context.Context, plan *Plan) (int, error) {
+	ctx, span := a.tracer.Start(ctx, "Attendance.Apply",
+		trace.WithAttributes(attribute.String("report", plan.Report.Code)),
+	)
+	defer span.End()
+
+	n := 0
+	for _, award := range plan.Awards {
+		if award.Amount <= 0 {
+			continue
+		}
+		awardCtx := idempotency.WithKey(ctx, "wcl:"+plan.Report.Code+":"+award.PlayerID)
+		if err := a.dkp.AwardDKP(awardCtx, award.PlayerID, award.Amount, plan.Reason); err != nil {
+			return n, fmt.Errorf("awarding %s: %w", award.CharacterName, err)
+		}
+		n++
+	}
+
+	a.logger.InfoContext(ctx, "applied log attendance",
+		slog.String("report", plan.Report.Code),
+		slog.Int("awards", n),
+	)
+	return n, nil
+}
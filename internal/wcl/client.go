@@ -0,0 +1,221 @@
+// Package wcl fetches raid reports from Warcraft Logs, or its sibling ESO
+// Logs, and turns their participants and boss kills into DKP awards.
+package wcl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+)
+
+// Report is the part of a log report relevant to attendance.
+type Report struct {
+	Code      string
+	Title     string
+	StartTime time.Time
+	// Players are the names of the player characters in the report.
+	Players []string
+	// Kills are the names of the bosses killed, one entry per kill.
+	Kills []string
+}
+
+// Client talks to the v2 GraphQL API of the site hosting a report. Sites
+// share the API, so the host of the report URL selects the site.
+type Client struct {
+	cfg    config.WarcraftLogsConfig
+	http   *http.Client
+	tracer trace.Tracer
+
+	mu     sync.Mutex
+	tokens map[string]token // by site origin
+}
+
+type token struct {
+	value   string
+	expires time.Time
+}
+
+// NewClient returns a new Client. httpClient may be nil to use
+// http.DefaultClient.
+func NewClient(cfg config.WarcraftLogsConfig, httpClient *http.Client, tp trace.TracerProvider) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		cfg:    cfg,
+		http:   httpClient,
+		tracer: tp.Tracer("github.com/jensholdgaard/discord-dkp-bot/internal/wcl"),
+		tokens: make(map[string]token),
+	}
+}
+
+// ParseReportURL splits a report URL such as
+// https://www.warcraftlogs.com/reports/a1B2c3D4#fight=last into the site
+// origin and the report code.
+func ParseReportURL(raw string) (origin, code string, err error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return "", "", derrors.New(derrors.Validation, "INVALID_REPORT_URL", fmt.Sprintf("invalid report URL %q", raw))
+	}
+	rest, ok := strings.CutPrefix(u.Path, "/reports/")
+	code, _, _ = strings.Cut(rest, "/")
+	if !ok || code == "" {
+		return "", "", derrors.New(derrors.Validation, "INVALID_REPORT_URL", fmt.Sprintf("URL %q is not a report URL", raw))
+	}
+	return u.Scheme + "://" + u.Host, code, nil
+}
+
+const reportQuery = `query($code: String!) {
+  reportData {
+    report(code: $code) {
+      title
+      startTime
+      fights(killType: Kills) { name kill }
+      masterData { actors(type: "Player") { name } }
+    }
+  }
+}`
+
+// FetchReport loads the report at reportURL.
+func (c *Client) FetchReport(ctx context.Context, reportURL string) (*Report, error) {
+	origin, code, err := ParseReportURL(reportURL)
+	if err != nil {
+		return nil, err
+	}
+	ctx, span := c.tracer.Start(ctx, "Client.FetchReport",
+		trace.WithAttributes(
+			attribute.String("origin", origin),
+			attribute.String("report", code),
+		),
+	)
+	defer span.End()
+
+	tok, err := c.token(ctx, origin)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"query":     reportQuery,
+		"variables": map[string]string{"code": code},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, origin+"/api/v2/client", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("building report request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+tok)
+
+	var resp struct {
+		Data struct {
+			ReportData struct {
+				Report *struct {
+					Title     string `json:"title"`
+					StartTime int64  `json:"startTime"`
+					Fights    []struct {
+						Name string `json:"name"`
+						Kill bool   `json:"kill"`
+					} `json:"fights"`
+					MasterData struct {
+						Actors []struct {
+							Name string `json:"name"`
+						} `json:"actors"`
+					} `json:"masterData"`
+				} `json:"report"`
+			} `json:"reportData"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := c.do(req, &resp); err != nil {
+		return nil, fmt.Errorf("fetching report %s: %w", code, err)
+	}
+	if len(resp.Errors) > 0 {
+		return nil, fmt.Errorf("fetching report %s: %s", code, resp.Errors[0].Message)
+	}
+	r := resp.Data.ReportData.Report
+	if r == nil {
+		return nil, derrors.New(derrors.NotFound, "REPORT_NOT_FOUND", fmt.Sprintf("report %s not found", code))
+	}
+
+	report := &Report{
+		Code:      code,
+		Title:     r.Title,
+		StartTime: time.UnixMilli(r.StartTime).UTC(),
+	}
+	seen := make(map[string]bool, len(r.MasterData.Actors))
+	for _, a := range r.MasterData.Actors {
+		if !seen[a.Name] {
+			seen[a.Name] = true
+			report.Players = append(report.Players, a.Name)
+		}
+	}
+	for _, f := range r.Fights {
+		if f.Kill {
+			report.Kills = append(report.Kills, f.Name)
+		}
+	}
+	return report, nil
+}
+
+// token returns a client-credentials access token for origin, reusing it
+// until shortly before it expires.
+func (c *Client) token(ctx context.Context, origin string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t, ok := c.tokens[origin]; ok && time.Now().Before(t.expires) {
+		return t.value, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, origin+"/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("building token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(c.cfg.ClientID, c.cfg.ClientSecret)
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := c.do(req, &resp); err != nil {
+		return "", fmt.Errorf("requesting access token: %w", err)
+	}
+	if resp.AccessToken == "" {
+		return "", fmt.Errorf("requesting access token: empty token")
+	}
+	c.tokens[origin] = token{
+		value:   resp.AccessToken,
+		expires: time.Now().Add(time.Duration(resp.ExpiresIn)*time.Second - time.Minute),
+	}
+	return resp.AccessToken, nil
+}
+
+func (c *Client) do(req *http.Request, v any) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package wcl_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/config"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/dkp"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/event/eventtest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store/storetest"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/wcl"
+)
+
+// newLogsServer fakes the token and GraphQL endpoints of a logs site.
+func newLogsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "tok", "expires_in": 3600})
+	})
+	mux.HandleFunc("POST /api/v2/client", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Variables struct {
+				Code string `json:"code"`
+			} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Variables.Code != "abc123" {
+			_, _ = w.Write([]byte(`{"data":{"reportData":{"report":null}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"reportData":{"report":{
+			"title": "Molten Core",
+			"startTime": 1717106400000,
+			"fights": [{"name":"Lucifron","kill":true},{"name":"Magmadar","kill":true}],
+			"masterData": {"actors": [{"name":"Gandalf"},{"name":"Frodo"},{"name":"Gandalf"},{"name":"Legolas"}]}
+		}}}}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestParseReportURL(t *testing.T) {
+	tests := []struct {
+		url        string
+		wantOrigin string
+		wantCode   string
+		wantErr    bool
+	}{
+		{url: "https://www.warcraftlogs.com/reports/a1B2c3D4", wantOrigin: "https://www.warcraftlogs.com", wantCode: "a1B2c3D4"},
+		{url: "https://classic.warcraftlogs.com/reports/xyz#fight=last", wantOrigin: "https://classic.warcraftlogs.com", wantCode: "xyz"},
+		{url: "https://www.esologs.com/reports/q9/", wantOrigin: "https://www.esologs.com", wantCode: "q9"},
+		{url: "https://www.warcraftlogs.com/character/eu/ragnaros/gandalf", wantErr: true},
+		{url: "not a url", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			origin, code, err := wcl.ParseReportURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReportURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if origin != tt.wantOrigin || code != tt.wantCode {
+				t.Errorf("ParseReportURL() = %q, %q, want %q, %q", origin, code, tt.wantOrigin, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestAttendance_PreviewAndApply(t *testing.T) {
+	srv := newLogsServer(t)
+	cfg := config.WarcraftLogsConfig{ClientID: "client", ClientSecret: "secret", AttendanceDKP: 10, BossKillDKP: 5}
+	tp := noop.NewTracerProvider()
+	players := storetest.NewPlayers(
+		store.Player{ID: "p1", DiscordID: "d1", CharacterName: "gandalf"},
+		store.Player{ID: "p2", DiscordID: "d2", CharacterName: "Frodo"},
+	)
+	events := eventtest.NewStore()
+	att := wcl.NewAttendance(wcl.NewClient(cfg, srv.Client(), tp), dkp.NewManager(players, events, slog.Default(), tp), cfg, slog.Default(), tp)
+
+	plan, err := att.Preview(context.Background(), srv.URL+"/reports/abc123")
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	if len(plan.Awards) != 2 || plan.Awards[0].Amount != 20 {
+		t.Errorf("got awards %+v, want 2 awards of 10 + 2x5", plan.Awards)
+	}
+	if len(plan.Unmatched) != 1 || plan.Unmatched[0] != "Legolas" {
+		t.Errorf("got unmatched %v, want [Legolas]", plan.Unmatched)
+	}
+	if n := len(events.Events()); n != 0 {
+		t.Fatalf("Preview() wrote %d events", n)
+	}
+
+	n, err := att.Apply(context.Background(), plan)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("applied %d awards, want 2", n)
+	}
+	players.RequireDKP(t, "d1", 20)
+	players.RequireDKP(t, "d2", 20)
+}
+
+func TestAttendance_PreviewUnknownReport(t *testing.T) {
+	srv := newLogsServer(t)
+	cfg := config.WarcraftLogsConfig{ClientID: "client", ClientSecret: "secret"}
+	tp := noop.NewTracerProvider()
+	att := wcl.NewAttendance(wcl.NewClient(cfg, srv.Client(), tp), dkp.NewManager(storetest.NewPlayers(), eventtest.NewStore(), slog.Default(), tp), cfg, slog.Default(), tp)
+
+	if _, err := att.Preview(context.Background(), srv.URL+"/reports/missing"); err == nil {
+		t.Error("Preview() error = nil, want not found")
+	}
+}
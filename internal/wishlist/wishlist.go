@@ -0,0 +1,112 @@
+// Package wishlist keeps the items players want. Players are told when an
+// auction for one of them starts, and officers see the demand per item to
+// plan which bosses to raid.
+package wishlist
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/items"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+)
+
+// MaxItems is how many items a wishlist holds.
+const MaxItems = 25
+
+// maxNameLength is the longest item name accepted, Discord's limit on
+// option choices.
+const maxNameLength = 100
+
+// Errors returned by Service.
+var (
+	ErrWishlistFull    = derrors.New(derrors.Validation, "WISHLIST_FULL", fmt.Sprintf("a wishlist holds at most %d items, remove one first", MaxItems))
+	ErrInvalidItemName = derrors.New(derrors.Validation, "INVALID_ITEM_NAME", fmt.Sprintf("item names must be 1 to %d characters long", maxNameLength))
+)
+
+// Service reads and changes wishlists.
+type Service struct {
+	repo    store.WishlistRepository
+	players store.PlayerRepository
+	items   *items.Catalog
+	logger  *slog.Logger
+}
+
+// NewService returns a Service that stores wishlists in repo. Item names
+// found in catalog, which may be nil, are stored as the catalog spells
+// them, so that they match the auctions started for them.
+func NewService(repo store.WishlistRepository, players store.PlayerRepository, catalog *items.Catalog, logger *slog.Logger) *Service {
+	return &Service{repo: repo, players: players, items: catalog, logger: logger}
+}
+
+// Add puts itemName on the wishlist of the player registered as discordID
+// and returns the name as stored.
+func (s *Service) Add(ctx context.Context, discordID, itemName string) (string, error) {
+	itemName = strings.TrimSpace(itemName)
+	if itemName == "" || len(itemName) > maxNameLength {
+		return "", ErrInvalidItemName
+	}
+	p, err := s.players.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return "", err
+	}
+	entries, err := s.repo.ListByPlayer(ctx, p.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) >= MaxItems {
+		return "", ErrWishlistFull
+	}
+	if s.items != nil {
+		item, err := s.items.Lookup(ctx, itemName)
+		if err != nil {
+			return "", err
+		}
+		if item != nil {
+			itemName = item.Name
+		}
+	}
+
+	if err := s.repo.Add(ctx, &store.WishlistEntry{PlayerID: p.ID, ItemName: itemName}); err != nil {
+		return "", err
+	}
+	s.logger.InfoContext(ctx, "item wishlisted",
+		slog.String("player_id", p.ID),
+		slog.String("item", itemName),
+	)
+	return itemName, nil
+}
+
+// Remove takes itemName off the wishlist of the player registered as
+// discordID.
+func (s *Service) Remove(ctx context.Context, discordID, itemName string) error {
+	p, err := s.players.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return err
+	}
+	return s.repo.Remove(ctx, p.ID, strings.TrimSpace(itemName))
+}
+
+// List returns the wishlist of the player registered as discordID, oldest
+// first.
+func (s *Service) List(ctx context.Context, discordID string) ([]store.WishlistEntry, error) {
+	p, err := s.players.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListByPlayer(ctx, p.ID)
+}
+
+// Wishers returns the players wanting itemName.
+func (s *Service) Wishers(ctx context.Context, itemName string) ([]store.Player, error) {
+	return s.repo.Wishers(ctx, strings.TrimSpace(itemName))
+}
+
+// Demand returns up to limit items by the number of players wanting them,
+// most wanted first.
+func (s *Service) Demand(ctx context.Context, limit int) ([]store.ItemDemand, error) {
+	return s.repo.Demand(ctx, limit)
+}
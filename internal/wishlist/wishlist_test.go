@@ -0,0 +1,143 @@
+package wishlist_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/jensholdgaard/discord-dkp-bot/internal/derrors"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/items"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/store"
+	"github.com/jensholdgaard/discord-dkp-bot/internal/wishlist"
+)
+
+// memPlayers implements the lookups of store.PlayerRepository used by the
+// service.
+type memPlayers struct {
+	store.PlayerRepository
+	players []store.Player
+}
+
+func (r *memPlayers) GetByDiscordID(_ context.Context, discordID string) (*store.Player, error) {
+	for _, p := range r.players {
+		if p.DiscordID == discordID {
+			return &p, nil
+		}
+	}
+	return nil, store.ErrPlayerNotFound
+}
+
+// memWishlists implements store.WishlistRepository in memory.
+type memWishlists struct {
+	entries []store.WishlistEntry
+}
+
+func (r *memWishlists) Add(_ context.Context, e *store.WishlistEntry) error {
+	for _, x := range r.entries {
+		if x.PlayerID == e.PlayerID && strings.EqualFold(x.ItemName, e.ItemName) {
+			return store.ErrWishlisted
+		}
+	}
+	r.entries = append(r.entries, *e)
+	return nil
+}
+
+func (r *memWishlists) Remove(_ context.Context, playerID, itemName string) error {
+	for n, x := range r.entries {
+		if x.PlayerID == playerID && strings.EqualFold(x.ItemName, itemName) {
+			r.entries = append(r.entries[:n], r.entries[n+1:]...)
+			return nil
+		}
+	}
+	return store.ErrNotWishlisted
+}
+
+func (r *memWishlists) ListByPlayer(_ context.Context, playerID string) ([]store.WishlistEntry, error) {
+	var out []store.WishlistEntry
+	for _, x := range r.entries {
+		if x.PlayerID == playerID {
+			out = append(out, x)
+		}
+	}
+	return out, nil
+}
+
+func (r *memWishlists) Wishers(context.Context, string) ([]store.Player, error) { return nil, nil }
+
+func (r *memWishlists) Demand(context.Context, int) ([]store.ItemDemand, error) { return nil, nil }
+
+// memItems implements the name lookup of store.ItemRepository.
+type memItems struct {
+	store.ItemRepository
+	items []store.Item
+}
+
+func (r memItems) GetByName(_ context.Context, name string) (*store.Item, error) {
+	for _, it := range r.items {
+		if strings.EqualFold(it.Name, name) {
+			return &it, nil
+		}
+	}
+	return nil, store.ErrItemNotFound
+}
+
+func TestService_Add(t *testing.T) {
+	full := make([]store.WishlistEntry, wishlist.MaxItems)
+	for n := range full {
+		full[n] = store.WishlistEntry{PlayerID: "p1", ItemName: fmt.Sprintf("Item %d", n)}
+	}
+
+	tests := []struct {
+		name     string
+		entries  []store.WishlistEntry
+		discord  string
+		item     string
+		want     string
+		wantCode string
+	}{
+		{name: "catalog spelling", discord: "111", item: "  thunderfury, blessed blade of the windseeker ", want: "Thunderfury, Blessed Blade of the Windseeker"},
+		{name: "item not in the catalog", discord: "111", item: "Onyxia Hide Backpack", want: "Onyxia Hide Backpack"},
+		{name: "empty name", discord: "111", item: " ", wantCode: "INVALID_ITEM_NAME"},
+		{name: "long name", discord: "111", item: strings.Repeat("x", 101), wantCode: "INVALID_ITEM_NAME"},
+		{name: "unregistered", discord: "999", item: "Thunderfury", wantCode: "PLAYER_NOT_FOUND"},
+		{name: "full", entries: full, discord: "111", item: "Thunderfury", wantCode: "WISHLIST_FULL"},
+		{
+			name:     "already wishlisted",
+			entries:  []store.WishlistEntry{{PlayerID: "p1", ItemName: "Thunderfury, Blessed Blade of the Windseeker"}},
+			discord:  "111",
+			item:     "THUNDERFURY, BLESSED BLADE OF THE WINDSEEKER",
+			wantCode: "WISHLISTED",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memWishlists{entries: tt.entries}
+			catalog := items.NewCatalog(memItems{items: []store.Item{
+				{ID: "19019", Name: "Thunderfury, Blessed Blade of the Windseeker"},
+			}}, slog.Default(), noop.NewTracerProvider())
+			players := &memPlayers{players: []store.Player{{ID: "p1", DiscordID: "111"}}}
+			svc := wishlist.NewService(repo, players, catalog, slog.Default())
+
+			got, err := svc.Add(context.Background(), tt.discord, tt.item)
+			if tt.wantCode != "" {
+				if err == nil || derrors.CodeOf(err) != tt.wantCode {
+					t.Fatalf("Add error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Add: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Add = %q, want %q", got, tt.want)
+			}
+			if len(repo.entries) != 1 || repo.entries[0] != (store.WishlistEntry{PlayerID: "p1", ItemName: tt.want}) {
+				t.Errorf("entries = %+v, want %q for p1", repo.entries, tt.want)
+			}
+		})
+	}
+}